@@ -0,0 +1,49 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/koderover/zadig/pkg/microservice/jobexecutor/executor"
+)
+
+var resultFile string
+
+func init() {
+	executeCmd.Flags().StringVar(&resultFile, "result-file", "", "file the job result is written to")
+
+	rootCmd.AddCommand(executeCmd)
+}
+
+var executeCmd = &cobra.Command{
+	Use:    "execute",
+	Short:  "run a job with the job executor, it is called by the agent for each job",
+	Hidden: true,
+	// the job log is already printed by the job executor
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+		defer stop()
+
+		return executor.ExecuteOnVM(ctx, resultFile)
+	},
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/vmagent"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+var (
+	serverURL         string
+	token             string
+	workDir           string
+	pollInterval      time.Duration
+	heartbeatInterval time.Duration
+)
+
+func init() {
+	rootCmd.Flags().StringVar(&serverURL, "server-url", "", "address of zadig, for example https://zadig.example.com")
+	rootCmd.Flags().StringVar(&token, "token", "", "token of the agent generated by zadig")
+	rootCmd.Flags().StringVar(&workDir, "work-dir", "$HOME/.zadig-agent", "dir holding the workspaces of the jobs")
+	rootCmd.Flags().DurationVar(&pollInterval, "poll-interval", 3*time.Second, "interval of polling jobs")
+	rootCmd.Flags().DurationVar(&heartbeatInterval, "heartbeat-interval", 10*time.Second, "interval of the heartbeats")
+}
+
+var rootCmd = &cobra.Command{
+	Use:   "zadig-agent",
+	Short: "zadig-agent runs the zadig jobs on the vm it is installed on.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if serverURL == "" || token == "" {
+			return fmt.Errorf("server-url and token are required")
+		}
+		log.Init(&log.Config{
+			Level:       config.LogLevel(),
+			Development: config.Mode() != setting.ReleaseMode,
+		})
+
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+		defer stop()
+
+		agent := vmagent.New(&vmagent.Config{
+			ServerURL:         serverURL,
+			Token:             token,
+			WorkDir:           os.ExpandEnv(workDir),
+			PollInterval:      pollInterval,
+			HeartbeatInterval: heartbeatInterval,
+		})
+		return agent.Run(ctx)
+	},
+}
+
+func Execute() error {
+	return rootCmd.Execute()
+}
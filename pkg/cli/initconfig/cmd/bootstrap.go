@@ -0,0 +1,271 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/shared/client/aslan"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	"github.com/koderover/zadig/pkg/shared/client/user"
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+// Bootstrap is the declarative configuration applied by init. The items are the request bodies of the
+// corresponding APIs, so every field the APIs accept can be declared, and ${VAR} in the file is expanded
+// from the environment to keep the secrets out of it.
+type Bootstrap struct {
+	Clusters   []map[string]interface{} `json:"clusters"`
+	Registries []map[string]interface{} `json:"registries"`
+	CodeHosts  []map[string]interface{} `json:"codehosts"`
+	IMApps     []map[string]interface{} `json:"im_apps"`
+	Projects   []map[string]interface{} `json:"projects"`
+	Admins     []*BootstrapAdmin        `json:"admins"`
+}
+
+// BootstrapAdmin is a system administrator, the user is created if the account does not exist
+type BootstrapAdmin struct {
+	Account  string `json:"account"`
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Phone    string `json:"phone"`
+	Password string `json:"password"`
+}
+
+// bootstrapResource applies the items of a kind, an item is created if find returns no id and updated
+// with updateMethod otherwise, existing resources are left as they are if updateMethod is empty
+type bootstrapResource struct {
+	kind         string
+	client       *httpclient.Client
+	url          string
+	find         func(item map[string]interface{}) (string, error)
+	updateMethod string
+}
+
+func applyBootstrapFile(file string) error {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	bootstrap := new(Bootstrap)
+	if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(content))), bootstrap); err != nil {
+		return fmt.Errorf("invalid bootstrap file: %s", err)
+	}
+
+	aslanClient := aslan.New(config.AslanServiceAddress()).Client
+	systemConfigClient := systemconfig.New().Client
+	resources := []struct {
+		resource *bootstrapResource
+		items    []map[string]interface{}
+	}{
+		{
+			resource: &bootstrapResource{
+				kind:         "cluster",
+				client:       aslanClient,
+				url:          "/cluster/clusters",
+				find:         findInList(aslanClient, "/cluster/clusters", "name"),
+				updateMethod: http.MethodPut,
+			},
+			items: bootstrap.Clusters,
+		},
+		{
+			resource: &bootstrapResource{
+				kind:         "registry",
+				client:       aslanClient,
+				url:          "/system/registry/namespaces",
+				find:         findInList(aslanClient, "/system/registry/namespaces", "reg_addr", "namespace"),
+				updateMethod: http.MethodPut,
+			},
+			items: bootstrap.Registries,
+		},
+		{
+			resource: &bootstrapResource{
+				kind:         "codehost",
+				client:       systemConfigClient,
+				url:          "/codehosts",
+				find:         findInList(systemConfigClient, "/codehosts/internal", "address", "namespace"),
+				updateMethod: http.MethodPatch,
+			},
+			items: bootstrap.CodeHosts,
+		},
+		{
+			resource: &bootstrapResource{
+				kind:         "im app",
+				client:       aslanClient,
+				url:          "/system/im_app",
+				find:         findInList(aslanClient, "/system/im_app", "type", "name"),
+				updateMethod: http.MethodPut,
+			},
+			items: bootstrap.IMApps,
+		},
+		{
+			// projects are only created, they are maintained in Zadig afterwards
+			resource: &bootstrapResource{
+				kind:   "project",
+				client: aslanClient,
+				url:    "/project/products",
+				find:   findByKey(aslanClient, "/project/products", "product_name"),
+			},
+			items: bootstrap.Projects,
+		},
+	}
+
+	for _, r := range resources {
+		for _, item := range r.items {
+			if err := r.resource.apply(item); err != nil {
+				return err
+			}
+		}
+	}
+	return applyBootstrapAdmins(bootstrap.Admins)
+}
+
+func (r *bootstrapResource) apply(item map[string]interface{}) error {
+	id, err := r.find(item)
+	if err != nil {
+		return fmt.Errorf("failed to find %s: %s", r.kind, err)
+	}
+	if id == "" {
+		if _, err := r.client.Post(r.url, httpclient.SetBody(item)); err != nil {
+			return fmt.Errorf("failed to create %s: %s", r.kind, err)
+		}
+		log.Infof("%s %s is created", r.kind, describeBootstrapItem(item))
+		return nil
+	}
+	if r.updateMethod == "" {
+		log.Infof("%s %s exists, skipped", r.kind, describeBootstrapItem(item))
+		return nil
+	}
+	if _, err := r.client.Request(r.updateMethod, fmt.Sprintf("%s/%s", r.url, id), httpclient.SetBody(item)); err != nil {
+		return fmt.Errorf("failed to update %s %s: %s", r.kind, id, err)
+	}
+	log.Infof("%s %s is updated", r.kind, describeBootstrapItem(item))
+	return nil
+}
+
+// findInList finds the item in the list of the url by the key fields and returns its id
+func findInList(client *httpclient.Client, url string, keys ...string) func(item map[string]interface{}) (string, error) {
+	var list []map[string]interface{}
+	return func(item map[string]interface{}) (string, error) {
+		if list == nil {
+			resp, err := client.Get(url)
+			if err != nil {
+				return "", err
+			}
+			list = make([]map[string]interface{}, 0)
+			if err := json.Unmarshal(resp.Body(), &list); err != nil {
+				return "", err
+			}
+		}
+		for _, existing := range list {
+			matched := true
+			for _, key := range keys {
+				if fmt.Sprint(existing[key]) != fmt.Sprint(item[key]) {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				return fmt.Sprint(existing["id"]), nil
+			}
+		}
+		return "", nil
+	}
+}
+
+// findByKey gets the item by the value of the key field and returns the value as its id
+func findByKey(client *httpclient.Client, url, key string) func(item map[string]interface{}) (string, error) {
+	return func(item map[string]interface{}) (string, error) {
+		name := fmt.Sprint(item[key])
+		if _, err := client.Get(fmt.Sprintf("%s/%s", url, name)); err != nil {
+			return "", nil
+		}
+		return name, nil
+	}
+}
+
+func describeBootstrapItem(item map[string]interface{}) string {
+	fields := make([]string, 0)
+	for _, key := range []string{"name", "product_name", "alias", "address", "reg_addr", "namespace"} {
+		if v, ok := item[key]; ok && fmt.Sprint(v) != "" {
+			fields = append(fields, fmt.Sprintf("%s=%v", key, v))
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+func applyBootstrapAdmins(admins []*BootstrapAdmin) error {
+	client := user.New()
+	for _, admin := range admins {
+		if admin.Account == "" {
+			return fmt.Errorf("account of admin is required")
+		}
+		resp, err := client.SearchUser(&user.SearchUserArgs{Account: admin.Account, IdentityType: "system"})
+		if err != nil {
+			return fmt.Errorf("failed to search user %s: %s", admin.Account, err)
+		}
+		uid := ""
+		if len(resp.Users) > 0 {
+			uid = resp.Users[0].UID
+		} else {
+			name := admin.Name
+			if name == "" {
+				name = admin.Account
+			}
+			created, err := client.CreateUser(&user.CreateUserArgs{
+				Name:     name,
+				Password: admin.Password,
+				Email:    admin.Email,
+				Phone:    admin.Phone,
+				Account:  admin.Account,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create user %s: %s", admin.Account, err)
+			}
+			uid = created.Uid
+			log.Infof("user %s is created", admin.Account)
+		}
+
+		roles, err := client.ListRoles("*", uid)
+		if err != nil {
+			return fmt.Errorf("failed to list roles of user %s: %s", admin.Account, err)
+		}
+		isAdmin := false
+		for _, role := range roles {
+			if role.Name == "admin" {
+				isAdmin = true
+				break
+			}
+		}
+		if isAdmin {
+			continue
+		}
+		if err := client.CreateUserRoleBinding(uid, "*", "admin"); err != nil {
+			return fmt.Errorf("failed to bind admin role to user %s: %s", admin.Account, err)
+		}
+		log.Infof("user %s is bound to the admin role", admin.Account)
+	}
+	return nil
+}
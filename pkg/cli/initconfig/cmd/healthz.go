@@ -17,19 +17,164 @@ limitations under the License.
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 
 	"github.com/koderover/zadig/pkg/config"
-	"github.com/koderover/zadig/pkg/shared/client/aslan"
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+	"github.com/koderover/zadig/pkg/tool/log"
 )
 
-func Healthz() error {
-	if err := checkAslanServiceHealth(); err != nil {
-		return fmt.Errorf("checkPolicyServiceHealth error:%s", err)
+const dependencyCheckTimeout = 5 * time.Second
+
+// dependency is a service which must be ready before the system config is initialized
+type dependency struct {
+	name string
+	// address is shown in the report, the dependency is skipped if it is empty
+	address string
+	check   func(ctx context.Context) error
+}
+
+type DependencyStatus struct {
+	Name     string `json:"name"`
+	Address  string `json:"address"`
+	Ready    bool   `json:"ready"`
+	Skipped  bool   `json:"skipped,omitempty"`
+	Attempts int    `json:"attempts"`
+	Error    string `json:"error,omitempty"`
+	// ReadyAfter is the time from the start of the wait until the dependency is ready
+	ReadyAfter string `json:"ready_after,omitempty"`
+}
+
+type ReadinessReport struct {
+	Ready        bool                `json:"ready"`
+	Elapsed      string              `json:"elapsed"`
+	Dependencies []*DependencyStatus `json:"dependencies"`
+}
+
+func (r *ReadinessReport) String() string {
+	content, _ := json.MarshalIndent(r, "", "  ")
+	return string(content)
+}
+
+func dependencies() []*dependency {
+	return []*dependency{
+		{name: "mongodb", address: redactURI(config.MongoURI()), check: checkMongoHealth},
+		{name: "aslan", address: config.AslanServiceAddress(), check: func(ctx context.Context) error {
+			return checkHTTPHealth(ctx, config.AslanServiceAddress()+"/api/health")
+		}},
+		{name: "hub-server", address: config.HubServerServiceAddress(), check: func(ctx context.Context) error {
+			return checkHTTPHealth(ctx, config.HubServerServiceAddress()+"/health")
+		}},
+		{name: "user", address: config.UserServiceAddress(), check: func(ctx context.Context) error {
+			return checkHTTPHealth(ctx, config.UserServiceAddress()+"/api/v1/healthz")
+		}},
 	}
-	return nil
 }
 
-func checkAslanServiceHealth() error {
-	return aslan.New(config.AslanServiceAddress()).Healthz()
+// redactURI removes the credentials from the uri so that it can be shown in the report
+func redactURI(uri string) string {
+	start := strings.Index(uri, "://")
+	if start < 0 {
+		return uri
+	}
+	start += len("://")
+	end := len(uri)
+	if i := strings.IndexAny(uri[start:], "/?"); i >= 0 {
+		end = start + i
+	}
+	if at := strings.LastIndex(uri[start:end], "@"); at >= 0 {
+		return uri[:start] + uri[start+at+1:]
+	}
+	return uri
+}
+
+func checkHTTPHealth(ctx context.Context, url string) error {
+	_, err := httpclient.Get(url, httpclient.SetContext(ctx))
+	return err
+}
+
+func checkMongoHealth(ctx context.Context) error {
+	uri := config.MongoURI()
+	// the errors of parsing the uri may contain it
+	redactErr := func(err error) error {
+		if err == nil {
+			return nil
+		}
+		return errors.New(strings.ReplaceAll(err.Error(), uri, redactURI(uri)))
+	}
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		return redactErr(err)
+	}
+	defer client.Disconnect(context.Background())
+	return redactErr(client.Ping(ctx, readpref.Primary()))
+}
+
+// WaitForDependencies checks the dependencies with exponential backoff until all of them are ready,
+// it gives up with the report of the last attempts after timeout, 0 means waiting forever
+func WaitForDependencies(timeout time.Duration) (*ReadinessReport, error) {
+	start := time.Now()
+	report := &ReadinessReport{}
+	deps := dependencies()
+	for _, dep := range deps {
+		status := &DependencyStatus{Name: dep.name, Address: dep.address}
+		if dep.address == "" {
+			status.Ready = true
+			status.Skipped = true
+		}
+		report.Dependencies = append(report.Dependencies, status)
+	}
+
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = time.Second
+	b.MaxInterval = 30 * time.Second
+	b.MaxElapsedTime = timeout
+	b.Reset()
+	for {
+		report.Ready = true
+		for i, dep := range deps {
+			status := report.Dependencies[i]
+			if status.Ready {
+				continue
+			}
+			status.Attempts++
+			ctx, cancel := context.WithTimeout(context.Background(), dependencyCheckTimeout)
+			err := dep.check(ctx)
+			cancel()
+			if err != nil {
+				report.Ready = false
+				status.Error = err.Error()
+				continue
+			}
+			status.Ready = true
+			status.Error = ""
+			status.ReadyAfter = time.Since(start).Round(time.Second).String()
+			log.Infof("dependency %s is ready after %d attempts", dep.name, status.Attempts)
+		}
+		report.Elapsed = time.Since(start).Round(time.Second).String()
+		if report.Ready {
+			return report, nil
+		}
+
+		next := b.NextBackOff()
+		if next == backoff.Stop {
+			return report, fmt.Errorf("dependencies are not ready in %s", timeout)
+		}
+		for _, status := range report.Dependencies {
+			if !status.Ready {
+				log.Warnf("dependency %s is not ready after %d attempts, retry in %s: %s", status.Name, status.Attempts, next.Round(time.Second), status.Error)
+			}
+		}
+		time.Sleep(next)
+	}
 }
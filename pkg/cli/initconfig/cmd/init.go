@@ -18,9 +18,11 @@ package cmd
 
 import (
 	_ "embed"
+	"fmt"
 	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 
 	"github.com/koderover/zadig/pkg/config"
 	"github.com/koderover/zadig/pkg/setting"
@@ -32,11 +34,16 @@ import (
 
 func init() {
 	rootCmd.AddCommand(initCmd)
+	initCmd.Flags().StringP("bootstrap-file", "f", "", "yaml file declaring the clusters, registries, code hosts, im apps, projects and admins to apply")
+	_ = viper.BindPFlag(setting.ENVBootstrapFile, initCmd.Flags().Lookup("bootstrap-file"))
+	initCmd.Flags().DurationVar(&dependencyTimeout, "timeout", 0, "time to wait for the dependencies to be ready, 0 means waiting forever")
 	log.Init(&log.Config{
 		Level: config.LogLevel(),
 	})
 }
 
+var dependencyTimeout time.Duration
+
 var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "init system config",
@@ -49,15 +56,12 @@ var initCmd = &cobra.Command{
 }
 
 func run() error {
-	for {
-		err := Healthz()
-		if err == nil {
-			break
-		}
-		log.Error(err)
-		time.Sleep(10 * time.Second)
+	report, err := WaitForDependencies(dependencyTimeout)
+	if err != nil {
+		fmt.Println(report)
+		return err
 	}
-	err := initSystemConfig()
+	err = initSystemConfig()
 	if err == nil {
 		log.Info("zadig init success")
 	}
@@ -83,6 +87,13 @@ func initSystemConfig() error {
 		}
 	}
 
+	if file := config.BootstrapFile(); file != "" {
+		if err := applyBootstrapFile(file); err != nil {
+			log.Errorf("apply bootstrap file %s err: %s", file, err)
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -0,0 +1,129 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/backup"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+	backupCmd.AddCommand(backupExportCmd)
+	backupCmd.AddCommand(backupRestoreCmd)
+
+	backupCmd.PersistentFlags().StringP("file", "f", "zadig-backup.tar.gz", "path of the backup archive")
+	backupCmd.PersistentFlags().StringP("passphrase", "p", "", "passphrase to encrypt the secrets in the backup archive")
+	backupCmd.PersistentFlags().String("aes-key", "", "aes key of the installation, it is read from etc/encryption/aes if empty")
+	backupRestoreCmd.Flags().Bool("overwrite", false, "replace the existing documents with the same id")
+	_ = viper.BindPFlag("backupFile", backupCmd.PersistentFlags().Lookup("file"))
+	_ = viper.BindPFlag("backupPassphrase", backupCmd.PersistentFlags().Lookup("passphrase"))
+	_ = viper.BindPFlag("backupAesKey", backupCmd.PersistentFlags().Lookup("aes-key"))
+	_ = viper.BindPFlag("backupOverwrite", backupRestoreCmd.Flags().Lookup("overwrite"))
+}
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "backup and restore the configuration of Zadig",
+	Long:  `backup exports the projects, workflows, environments metadata and integrations into an archive and restores it into another installation.`,
+}
+
+var backupExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "export the configuration into a backup archive",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return preRun()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runBackupExport(); err != nil {
+			log.Fatal(err)
+		}
+	},
+	PostRun: func(cmd *cobra.Command, args []string) {
+		if err := postRun(); err != nil {
+			fmt.Println(err)
+		}
+	},
+}
+
+var backupRestoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "restore the configuration from a backup archive",
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return preRun()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runBackupRestore(); err != nil {
+			log.Fatal(err)
+		}
+	},
+	PostRun: func(cmd *cobra.Command, args []string) {
+		if err := postRun(); err != nil {
+			fmt.Println(err)
+		}
+	},
+}
+
+func backupOptions() *backup.Options {
+	return &backup.Options{
+		Passphrase: viper.GetString("backupPassphrase"),
+		AesKey:     viper.GetString("backupAesKey"),
+		Operator:   "ua",
+		Overwrite:  viper.GetBool("backupOverwrite"),
+	}
+}
+
+func runBackupExport() error {
+	file, err := os.Create(viper.GetString("backupFile"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	manifest, err := backup.Export(context.Background(), file, backupOptions())
+	if err != nil {
+		return err
+	}
+	for _, c := range manifest.Collections {
+		log.Infof("Exported %d documents of %s", c.Count, c.Name)
+	}
+	log.Infof("Backup archive is written to %s", file.Name())
+	return nil
+}
+
+func runBackupRestore() error {
+	file, err := os.Open(viper.GetString("backupFile"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	result, err := backup.Restore(context.Background(), file, backupOptions())
+	if result != nil {
+		content, _ := json.MarshalIndent(result.Collections, "", "  ")
+		log.Infof("Restore result: %s", content)
+	}
+	return err
+}
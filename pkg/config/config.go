@@ -19,6 +19,8 @@ package config
 import (
 	"fmt"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 
@@ -45,6 +47,18 @@ func Mode() string {
 	return mode
 }
 
+// TrustedProxies returns the gateways whose X-Forwarded-For header is trusted when resolving the client ip,
+// the client ip is the remote address of the connection if it is empty
+func TrustedProxies() []string {
+	resp := make([]string, 0)
+	for _, proxy := range strings.Split(viper.GetString(setting.ENVTrustedProxies), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			resp = append(resp, proxy)
+		}
+	}
+	return resp
+}
+
 func LogLevel() string {
 	return "debug"
 }
@@ -234,6 +248,19 @@ func MongoDatabase() string {
 	return viper.GetString(setting.ENVAslanDBName)
 }
 
+// MongoReadPreference is the read preference of the list and statistics queries, e.g. secondaryPreferred
+func MongoReadPreference() string {
+	return viper.GetString(setting.ENVMongoDBReadPreference)
+}
+
+func MongoQueryTimeout() time.Duration {
+	return time.Duration(viper.GetInt64(setting.ENVMongoDBQueryTimeout)) * time.Second
+}
+
+func MongoSlowQueryThreshold() time.Duration {
+	return time.Duration(viper.GetInt64(setting.ENVMongoDBSlowQueryThreshold)) * time.Millisecond
+}
+
 func PolicyDatabase() string {
 	return MongoDatabase() + "_policy"
 }
@@ -273,3 +300,7 @@ func AdminPassword() string {
 func AdminEmail() string {
 	return viper.GetString(setting.ENVAdminEmail)
 }
+
+func BootstrapFile() string {
+	return viper.GetString(setting.ENVBootstrapFile)
+}
@@ -19,6 +19,7 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net"
 	"strconv"
 	"strings"
 
@@ -86,6 +87,14 @@ func MongoDatabase() string {
 	return configbase.MongoDatabase()
 }
 
+func TaskStorageBackend() string {
+	return viper.GetString(setting.ENVTaskStorageBackend)
+}
+
+func TaskStoragePostgresDSN() string {
+	return viper.GetString(setting.ENVTaskStoragePostgresDSN)
+}
+
 func HubServerAddress() string {
 	return configbase.HubServerServiceAddress()
 }
@@ -98,10 +107,20 @@ func ExecutorImage() string {
 	return viper.GetString(setting.ENVExecutorImage)
 }
 
+// WindowsExecutorImage is the image carrying the windows build of the jobexecutor
+func WindowsExecutorImage() string {
+	return viper.GetString(setting.ENVWindowsExecutorImage)
+}
+
 func KodespaceVersion() string {
 	return viper.GetString(setting.ENVKodespaceVersion)
 }
 
+// ZadigVersion is the version of the installed zadig, it is empty in the development builds
+func ZadigVersion() string {
+	return viper.GetString(setting.ENVZadigVersion)
+}
+
 // CleanIgnoredList is a list which will be ignored during environment cleanup.
 func CleanSkippedList() []string {
 	return strings.Split(viper.GetString(setting.CleanSkippedList), ",")
@@ -218,6 +237,44 @@ func ProxySocks5Addr() string {
 	return viper.GetString(setting.ProxySocks5Addr)
 }
 
+// HTTPCallbackDeniedCIDRs returns the networks the http callback jobs can not request. If it is not configured,
+// the loopback, link-local and private ranges and the service network of the cluster are denied.
+func HTTPCallbackDeniedCIDRs() []string {
+	cidrs := viper.GetString(setting.ENVHTTPCallbackDeniedCIDRs)
+	if cidrs == "" {
+		resp := []string{
+			"0.0.0.0/8", "127.0.0.0/8", "169.254.0.0/16", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10",
+			"::1/128", "fe80::/10", "fc00::/7",
+		}
+		if serviceCIDR := ClusterServiceCIDR(); serviceCIDR != "" {
+			resp = append(resp, serviceCIDR)
+		}
+		return resp
+	}
+
+	resp := make([]string, 0)
+	for _, cidr := range strings.Split(cidrs, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr != "" {
+			resp = append(resp, cidr)
+		}
+	}
+	return resp
+}
+
+// ClusterServiceCIDR returns the service network of the cluster aslan runs in, the /16 network of the kubernetes
+// service is taken if it is not configured
+func ClusterServiceCIDR() string {
+	if cidr := viper.GetString(setting.ENVClusterServiceCIDR); cidr != "" {
+		return cidr
+	}
+	ip := net.ParseIP(viper.GetString(setting.KubernetesServiceHost))
+	if ip == nil || ip.To4() == nil {
+		return ""
+	}
+	network := net.IPNet{IP: ip.Mask(net.CIDRMask(16, 32)), Mask: net.CIDRMask(16, 32)}
+	return network.String()
+}
+
 func JenkinsImage() string {
 	return viper.GetString(setting.JenkinsBuildImage)
 }
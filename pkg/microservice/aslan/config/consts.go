@@ -123,6 +123,7 @@ const (
 	StatusPrepare        Status = "prepare"
 	StatusReject         Status = "reject"
 	StatusWaitingApprove Status = "waitforapprove"
+	StatusWaitForQuota   Status = "waitforquota"
 	StatusDebugBefore    Status = "debug_before"
 	StatusDebugAfter     Status = "debug_after"
 )
@@ -132,7 +133,7 @@ func FailedStatus() []Status {
 }
 
 func InCompletedStatus() []Status {
-	return []Status{StatusCreated, StatusRunning, StatusWaiting, StatusQueued, StatusBlocked, QueueItemPending, StatusPrepare, StatusWaitingApprove}
+	return []Status{StatusCreated, StatusRunning, StatusWaiting, StatusQueued, StatusBlocked, QueueItemPending, StatusPrepare, StatusWaitingApprove, StatusWaitForQuota}
 }
 
 type TaskStatus string
@@ -176,6 +177,7 @@ type StepType string
 const (
 	StepTools             StepType = "tools"
 	StepShell             StepType = "shell"
+	StepPowerShell        StepType = "powershell"
 	StepGit               StepType = "git"
 	StepDockerBuild       StepType = "docker_build"
 	StepDeploy            StepType = "deploy"
@@ -191,6 +193,13 @@ const (
 	StepDistributeImage   StepType = "distribute_image"
 	StepDebugBefore       StepType = "debug_before"
 	StepDebugAfter        StepType = "debug_after"
+	StepRestoreCache      StepType = "restore_cache"
+	StepSaveCache         StepType = "save_cache"
+	StepServiceReady      StepType = "service_ready"
+	StepCoverage          StepType = "coverage"
+	StepPerformance       StepType = "performance"
+	StepUploadJobFiles    StepType = "upload_job_files"
+	StepDownloadJobFiles  StepType = "download_job_files"
 )
 
 type JobType string
@@ -202,6 +211,8 @@ const (
 	JobZadigDistributeImage JobType = "zadig-distribute-image"
 	JobZadigTesting         JobType = "zadig-test"
 	JobZadigScanning        JobType = "zadig-scanning"
+	JobZadigSonar           JobType = "zadig-sonar"
+	JobZadigPerformanceTest JobType = "zadig-performance-test"
 	JobCustomDeploy         JobType = "custom-deploy"
 	JobZadigDeploy          JobType = "zadig-deploy"
 	JobZadigHelmDeploy      JobType = "zadig-helm-deploy"
@@ -226,6 +237,11 @@ const (
 	JobMseGrayRelease       JobType = "mse-gray-release"
 	JobMseGrayOffline       JobType = "mse-gray-offline"
 	JobGuanceyunCheck       JobType = "guanceyun-check"
+	JobHarborReplication    JobType = "harbor-replication"
+	JobStaticDistribute     JobType = "static-distribute"
+	JobHTTPCallback         JobType = "http-callback"
+	JobFeatureFlag          JobType = "feature-flag"
+	JobFeatureFlagRollback  JobType = "feature-flag-rollback"
 )
 
 const (
@@ -249,6 +265,26 @@ const (
 	Reject  ApproveOrReject = "reject"
 )
 
+// ApprovalSource is where a native approval is operated from, it is recorded in the stage timeline
+type ApprovalSource string
+
+const (
+	ApprovalSourceZadig    ApprovalSource = "zadig"
+	ApprovalSourceOpenAPI  ApprovalSource = "openapi"
+	ApprovalSourceLark     ApprovalSource = "feishu"
+	ApprovalSourceDingTalk ApprovalSource = "dingtalk"
+)
+
+// JobResumePhase is the step an in-flight job is executing when the controller restarts
+type JobResumePhase string
+
+const (
+	// JobResumePhaseWaiting means the pod is created and the job is waiting for it to finish
+	JobResumePhaseWaiting JobResumePhase = "waiting"
+	// JobResumePhaseCollecting means the pod is finished and the job is collecting the outputs and logs
+	JobResumePhaseCollecting JobResumePhase = "collecting"
+)
+
 type DeploySourceType string
 
 const (
@@ -285,6 +321,13 @@ const (
 	DeployConfig DeployContent = "config"
 )
 
+type DeployStrategyType string
+
+const (
+	DeployStrategyRolling  DeployStrategyType = "rolling"
+	DeployStrategyRecreate DeployStrategyType = "recreate"
+)
+
 type StageType string
 
 const (
@@ -518,6 +561,8 @@ type ReleasePlanJobType string
 const (
 	JobText     ReleasePlanJobType = "text"
 	JobWorkflow ReleasePlanJobType = "workflow"
+	// JobReleaseTrain releases a set of services together through the environments stage by stage
+	JobReleaseTrain ReleasePlanJobType = "release_train"
 )
 
 type ReleasePlanJobStatus string
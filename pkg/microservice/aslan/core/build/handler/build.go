@@ -27,6 +27,7 @@ import (
 
 	buildservice "github.com/koderover/zadig/pkg/microservice/aslan/core/build/service"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
 	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
 	e "github.com/koderover/zadig/pkg/tool/errors"
 	"github.com/koderover/zadig/pkg/tool/log"
@@ -283,6 +284,13 @@ func DeleteBuildModule(c *gin.Context) {
 		return
 	}
 
+	if c.Query("force") != "true" {
+		if err := commonservice.CheckAssetReferences(commonservice.AssetTypeBuild, projectKey, name); err != nil {
+			ctx.Err = e.ErrAssetReferenced.AddErr(err)
+			return
+		}
+	}
+
 	ctx.Err = buildservice.DeleteBuild(name, projectKey, ctx.Logger)
 }
 
@@ -22,6 +22,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	buildservice "github.com/koderover/zadig/pkg/microservice/aslan/core/build/service"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
 	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
 	e "github.com/koderover/zadig/pkg/tool/errors"
 )
@@ -132,6 +133,13 @@ func OpenAPIDeleteBuildModule(c *gin.Context) {
 		return
 	}
 
+	if c.Query("force") != "true" {
+		if err := commonservice.CheckAssetReferences(commonservice.AssetTypeBuild, projectKey, buildName); err != nil {
+			ctx.Err = e.ErrAssetReferenced.AddErr(err)
+			return
+		}
+	}
+
 	ctx.Err = buildservice.DeleteBuild(buildName, projectKey, ctx.Logger)
 }
 
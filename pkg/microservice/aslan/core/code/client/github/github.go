@@ -154,3 +154,18 @@ func (c *Client) ListProjects(opt client.ListOpt) ([]*client.Project, error) {
 	}
 	return res, nil
 }
+
+func (c *Client) CompareCommits(opt client.CompareOpt) ([]*client.Commit, error) {
+	comparison, err := c.Client.CompareCommits(context.TODO(), opt.Namespace, opt.ProjectName, opt.Base, opt.Head)
+	if err != nil {
+		return nil, err
+	}
+	var res []*client.Commit
+	for _, o := range comparison.Commits {
+		res = append(res, &client.Commit{
+			ID:      o.GetSHA(),
+			Message: o.GetCommit().GetMessage(),
+		})
+	}
+	return res, nil
+}
@@ -164,3 +164,18 @@ func (c *Client) ListProjects(opt client.ListOpt) ([]*client.Project, error) {
 	}
 	return res, nil
 }
+
+func (c *Client) CompareCommits(opt client.CompareOpt) ([]*client.Commit, error) {
+	commits, err := c.Client.CompareCommits(opt.Namespace, opt.ProjectName, opt.Base, opt.Head)
+	if err != nil {
+		return nil, err
+	}
+	var res []*client.Commit
+	for _, o := range commits {
+		res = append(res, &client.Commit{
+			ID:      o.ID,
+			Message: o.Message,
+		})
+	}
+	return res, nil
+}
@@ -31,6 +31,18 @@ type CodeHostClient interface {
 	ListProjects(opt ListOpt) ([]*Project, error)
 }
 
+// CommitComparer is implemented by the code host clients which can list the commits between two refs
+type CommitComparer interface {
+	CompareCommits(opt CompareOpt) ([]*Commit, error)
+}
+
+type CompareOpt struct {
+	Namespace   string
+	ProjectName string
+	Base        string
+	Head        string
+}
+
 type ListOpt struct {
 	Namespace     string
 	NamespaceType string
@@ -54,6 +66,11 @@ type Tag struct {
 	Message    string `json:"message"`
 }
 
+type Commit struct {
+	ID      string `json:"id"`
+	Message string `json:"message"`
+}
+
 type PullRequest struct {
 	ID             int    `json:"id"`
 	TargetBranch   string `json:"targetBranch"`
@@ -0,0 +1,40 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workloadidentity"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+)
+
+// GetOIDCDiscovery serves the openid configuration of the job token issuer, no auth is required
+func GetOIDCDiscovery(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp = workloadidentity.GetDiscovery()
+}
+
+// GetOIDCJWKS serves the public keys verifying the job tokens, no auth is required
+func GetOIDCJWKS(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = workloadidentity.GetJWKS()
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// AgentRollout is the progress of a rolling upgrade of the hub-agents, the clusters are upgraded one by one and
+// the rollout stops at the first cluster which fails the health verification
+type AgentRollout struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty"    json:"id"`
+	Status          string             `bson:"status"           json:"status"`
+	ExpectedVersion string             `bson:"expected_version" json:"expected_version"`
+	CreatedBy       string             `bson:"created_by"       json:"created_by"`
+	StartTime       int64              `bson:"start_time"       json:"start_time"`
+	EndTime         int64              `bson:"end_time"         json:"end_time"`
+	// HealthTimeout is the seconds to wait for an upgraded agent to become healthy
+	HealthTimeout int64                  `bson:"health_timeout"   json:"health_timeout"`
+	Clusters      []*AgentRolloutCluster `bson:"clusters"         json:"clusters"`
+	// UpdateTime is refreshed by the replica running the rollout, a running rollout not refreshed for long
+	// is left by a stopped replica
+	UpdateTime int64 `bson:"update_time" json:"update_time"`
+	// Lock is set while the rollout is running, it is unique so that only one rollout runs at a time
+	Lock string `bson:"lock,omitempty" json:"-"`
+}
+
+type AgentRolloutCluster struct {
+	ClusterID   string `bson:"cluster_id"   json:"cluster_id"`
+	ClusterName string `bson:"cluster_name" json:"cluster_name"`
+	FromVersion string `bson:"from_version" json:"from_version"`
+	Status      string `bson:"status"       json:"status"`
+	Message     string `bson:"message"      json:"message"`
+	StartTime   int64  `bson:"start_time"   json:"start_time"`
+	EndTime     int64  `bson:"end_time"     json:"end_time"`
+}
+
+func (AgentRollout) TableName() string {
+	return "agent_rollout"
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+)
+
+// ApprovalRecord is the result of a stage approval, the records are append-only and chained by the hash of the
+// previous record so that a modified or deleted record can be detected
+type ApprovalRecord struct {
+	ID                  primitive.ObjectID  `bson:"_id,omitempty"         json:"-"`
+	Seq                 int64               `bson:"seq"                   json:"seq"`
+	ProjectName         string              `bson:"project_name"          json:"project_name"`
+	WorkflowName        string              `bson:"workflow_name"         json:"workflow_name"`
+	WorkflowDisplayName string              `bson:"workflow_display_name" json:"workflow_display_name"`
+	TaskID              int64               `bson:"task_id"               json:"task_id"`
+	TaskCreator         string              `bson:"task_creator"          json:"task_creator"`
+	StageName           string              `bson:"stage_name"            json:"stage_name"`
+	ApprovalType        config.ApprovalType `bson:"approval_type"         json:"approval_type"`
+	Status              config.Status       `bson:"status"                json:"status"`
+	StartTime           int64               `bson:"start_time"            json:"start_time"`
+	EndTime             int64               `bson:"end_time"              json:"end_time"`
+	Decisions           []*ApprovalDecision `bson:"decisions"             json:"decisions"`
+	CreateTime          int64               `bson:"create_time"           json:"create_time"`
+	PrevHash            string              `bson:"prev_hash"             json:"prev_hash"`
+	Hash                string              `bson:"hash"                  json:"hash"`
+}
+
+// ApprovalDecision is the decision of an approver, Action is empty if the approver did not decide
+type ApprovalDecision struct {
+	Approver   string                 `bson:"approver"    json:"approver"`
+	ApproverID string                 `bson:"approver_id" json:"approver_id"`
+	Action     config.ApproveOrReject `bson:"action"      json:"action"`
+	Comment    string                 `bson:"comment"     json:"comment"`
+	Time       int64                  `bson:"time"        json:"time"`
+}
+
+func (ApprovalRecord) TableName() string {
+	return "approval_record"
+}
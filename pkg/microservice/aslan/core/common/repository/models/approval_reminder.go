@@ -0,0 +1,35 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// ApprovalReminderRecord is the reminder state of a stage waiting for approval, it is kept in the db
+// so that the reminders are not sent again after aslan restarts, it is removed once the approval is done
+type ApprovalReminderRecord struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty"    json:"id"`
+	WorkflowName    string             `bson:"workflow_name"    json:"workflow_name"`
+	TaskID          int64              `bson:"task_id"          json:"task_id"`
+	StageName       string             `bson:"stage_name"       json:"stage_name"`
+	RemindCount     int                `bson:"remind_count"     json:"remind_count"`
+	LastRemindTime  int64              `bson:"last_remind_time" json:"last_remind_time"`
+	TimeoutNotified bool               `bson:"timeout_notified" json:"timeout_notified"`
+}
+
+func (ApprovalReminderRecord) TableName() string {
+	return "approval_reminder_record"
+}
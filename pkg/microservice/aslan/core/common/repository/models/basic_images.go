@@ -37,6 +37,8 @@ type BasicImage struct {
 	// New field since 1.12
 	// ImageType is the type of the image, currently only one type is supported called sonar
 	ImageType string `bson:"image_type" json:"image_type"`
+	// OS is windows for the windows images, the jobs using them are scheduled onto the windows nodes
+	OS string `bson:"os,omitempty" json:"os,omitempty"`
 }
 
 func (BasicImage) TableName() string {
@@ -54,6 +54,10 @@ type Build struct {
 	CacheEnable  bool               `bson:"cache_enable"   json:"cache_enable"`
 	CacheDirType types.CacheDirType `bson:"cache_dir_type" json:"cache_dir_type"`
 	CacheUserDir string             `bson:"cache_user_dir" json:"cache_user_dir"`
+	// CacheRules are the declarative caches saved and restored around the build scripts
+	CacheRules []*types.CacheRule `bson:"cache_rules,omitempty" json:"cache_rules,omitempty"`
+	// Coverage ingests the coverage report generated by the build scripts
+	Coverage *CoverageConfig `bson:"coverage,omitempty" json:"coverage,omitempty"`
 	// New since V1.10.0. Only to tell the webpage should the advanced settings be displayed
 	AdvancedSettingsModified bool      `bson:"advanced_setting_modified" json:"advanced_setting_modified"`
 	Outputs                  []*Output `bson:"outputs"                   json:"outputs"`
@@ -84,6 +88,15 @@ type PreBuild struct {
 	StrategyID string `bson:"strategy_id"                     json:"strategy_id"`
 	// UseHostDockerDaemon determines is dockerDaemon on host node is used in pod
 	UseHostDockerDaemon bool `bson:"use_host_docker_daemon" json:"use_host_docker_daemon"`
+	// SchedulingHints customizes where the job pod runs
+	SchedulingHints *SchedulingHints `bson:"scheduling_hints,omitempty" json:"scheduling_hints,omitempty"`
+	// ServiceContainers are the auxiliary services started alongside the job
+	ServiceContainers []*ServiceContainer `bson:"service_containers,omitempty" json:"service_containers,omitempty"`
+	// Infrastructure is kubernetes by default, builds on vm run on the vm agents having all the VMLabels
+	Infrastructure string   `bson:"infrastructure,omitempty" json:"infrastructure,omitempty"`
+	VMLabels       []string `bson:"vm_labels,omitempty"      json:"vm_labels,omitempty"`
+	// WorkloadIdentity issues the build pod an OIDC token instead of the static cloud credentials
+	WorkloadIdentity *WorkloadIdentity `bson:"workload_identity,omitempty" json:"workload_identity,omitempty"`
 
 	// TODO: Deprecated.
 	Namespace string `bson:"namespace"                       json:"namespace"`
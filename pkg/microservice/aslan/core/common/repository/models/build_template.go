@@ -39,6 +39,8 @@ type BuildTemplate struct {
 	CacheEnable              bool               `bson:"cache_enable"                  json:"cache_enable"`
 	CacheDirType             types.CacheDirType `bson:"cache_dir_type"                json:"cache_dir_type"`
 	CacheUserDir             string             `bson:"cache_user_dir"                json:"cache_user_dir"`
+	CacheRules               []*types.CacheRule `bson:"cache_rules,omitempty"         json:"cache_rules,omitempty"`
+	Coverage                 *CoverageConfig    `bson:"coverage,omitempty"            json:"coverage,omitempty"`
 	AdvancedSettingsModified bool               `bson:"advanced_setting_modified"     json:"advanced_setting_modified"`
 	Outputs                  []*Output          `bson:"outputs"                       json:"outputs"`
 }
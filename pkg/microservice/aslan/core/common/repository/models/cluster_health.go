@@ -0,0 +1,105 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// ClusterHealthStaleSeconds is the max age of a probe result that is still trusted by the scheduler,
+	// older results are treated as unknown and never block scheduling.
+	ClusterHealthStaleSeconds = 15 * 60
+	// ClusterLowCapacityRatio is the ratio of free allocatable resource under which a warning is raised.
+	ClusterLowCapacityRatio = 0.1
+)
+
+// ClusterHealth is the latest probe result of a registered cluster, one document per cluster.
+type ClusterHealth struct {
+	ID            primitive.ObjectID   `bson:"_id,omitempty"    json:"id"`
+	ClusterID     string               `bson:"cluster_id"       json:"cluster_id"`
+	ClusterName   string               `bson:"cluster_name"     json:"cluster_name"`
+	Reachable     bool                 `bson:"reachable"        json:"reachable"`
+	Error         string               `bson:"error"            json:"error"`
+	TotalNodes    int                  `bson:"total_nodes"      json:"total_nodes"`
+	ReadyNodes    int                  `bson:"ready_nodes"      json:"ready_nodes"`
+	PressureNodes []*NodePressure      `bson:"pressure_nodes"   json:"pressure_nodes"`
+	Capacity      *ClusterCapacityInfo `bson:"capacity"         json:"capacity"`
+	ProbeTime     int64                `bson:"probe_time"       json:"probe_time"`
+}
+
+type NodePressure struct {
+	NodeName   string   `bson:"node_name"  json:"node_name"`
+	Conditions []string `bson:"conditions" json:"conditions"`
+}
+
+// ClusterCapacityInfo cpu is counted in millicores and memory in bytes
+type ClusterCapacityInfo struct {
+	CPUAllocatable    int64 `bson:"cpu_allocatable"    json:"cpu_allocatable"`
+	CPURequested      int64 `bson:"cpu_requested"      json:"cpu_requested"`
+	MemoryAllocatable int64 `bson:"memory_allocatable" json:"memory_allocatable"`
+	MemoryRequested   int64 `bson:"memory_requested"   json:"memory_requested"`
+}
+
+func (ClusterHealth) TableName() string {
+	return "cluster_health"
+}
+
+func (h *ClusterHealth) IsStale() bool {
+	return time.Now().Unix()-h.ProbeTime > ClusterHealthStaleSeconds
+}
+
+// Schedulable decides whether new job pods can be scheduled onto the cluster.
+// A false return denies the scheduling, warnings are returned for degraded but usable clusters.
+func (h *ClusterHealth) Schedulable() (bool, []string) {
+	warnings := make([]string, 0)
+	if h.IsStale() {
+		return true, warnings
+	}
+	if !h.Reachable {
+		return false, []string{fmt.Sprintf("cluster %s is unreachable: %s", h.ClusterName, h.Error)}
+	}
+	if h.TotalNodes > 0 && h.ReadyNodes == 0 {
+		return false, []string{fmt.Sprintf("cluster %s has no ready node", h.ClusterName)}
+	}
+
+	if h.ReadyNodes < h.TotalNodes {
+		warnings = append(warnings, fmt.Sprintf("%d of %d nodes in cluster %s are not ready", h.TotalNodes-h.ReadyNodes, h.TotalNodes, h.ClusterName))
+	}
+	for _, node := range h.PressureNodes {
+		warnings = append(warnings, fmt.Sprintf("node %s in cluster %s reports %v", node.NodeName, h.ClusterName, node.Conditions))
+	}
+	if h.Capacity != nil {
+		if lowCapacity(h.Capacity.CPUAllocatable, h.Capacity.CPURequested) {
+			warnings = append(warnings, fmt.Sprintf("cluster %s is running low on cpu", h.ClusterName))
+		}
+		if lowCapacity(h.Capacity.MemoryAllocatable, h.Capacity.MemoryRequested) {
+			warnings = append(warnings, fmt.Sprintf("cluster %s is running low on memory", h.ClusterName))
+		}
+	}
+	return true, warnings
+}
+
+func lowCapacity(allocatable, requested int64) bool {
+	if allocatable <= 0 {
+		return false
+	}
+	return float64(allocatable-requested)/float64(allocatable) < ClusterLowCapacityRatio
+}
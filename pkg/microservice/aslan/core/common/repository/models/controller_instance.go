@@ -0,0 +1,32 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// ControllerInstance is an aslan instance running the workflow controller, the instances keep heartbeating
+// and the alive ones share the workflow tasks
+type ControllerInstance struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"  json:"id,omitempty"`
+	InstanceID    string             `bson:"instance_id"    json:"instance_id"`
+	StartTime     int64              `bson:"start_time"     json:"start_time"`
+	HeartbeatTime int64              `bson:"heartbeat_time" json:"heartbeat_time"`
+}
+
+func (ControllerInstance) TableName() string {
+	return "controller_instance"
+}
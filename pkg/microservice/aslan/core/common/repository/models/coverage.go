@@ -0,0 +1,54 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CoverageConfig configures the coverage report ingestion of a build or testing.
+// MinCoverage fails the job when the line coverage is below it, MaxRegression fails the job when
+// the coverage drops more than it from the latest coverage of BaseBranch, both are percentages.
+type CoverageConfig struct {
+	Enabled       bool    `bson:"enabled"        json:"enabled"`
+	ReportPath    string  `bson:"report_path"    json:"report_path"`
+	Format        string  `bson:"format"         json:"format"`
+	MinCoverage   float64 `bson:"min_coverage"   json:"min_coverage"`
+	MaxRegression float64 `bson:"max_regression" json:"max_regression"`
+	BaseBranch    string  `bson:"base_branch"    json:"base_branch"`
+}
+
+// CoverageRecord is the line coverage of a build or testing in one workflow task run
+type CoverageRecord struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"    json:"id"`
+	ProjectName   string             `bson:"project_name"     json:"project_name"`
+	Name          string             `bson:"name"             json:"name"`
+	ServiceName   string             `bson:"service_name"     json:"service_name"`
+	ServiceModule string             `bson:"service_module"   json:"service_module"`
+	Branch        string             `bson:"branch"           json:"branch"`
+	WorkflowName  string             `bson:"workflow_name"    json:"workflow_name"`
+	TaskID        int64              `bson:"task_id"          json:"task_id"`
+	JobName       string             `bson:"job_name"         json:"job_name"`
+	LinesCovered  int                `bson:"lines_covered"    json:"lines_covered"`
+	LinesTotal    int                `bson:"lines_total"      json:"lines_total"`
+	Coverage      float64            `bson:"coverage"         json:"coverage"`
+	CreateTime    int64              `bson:"create_time"      json:"create_time"`
+}
+
+func (CoverageRecord) TableName() string {
+	return "coverage_record"
+}
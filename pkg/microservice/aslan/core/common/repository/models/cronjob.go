@@ -23,6 +23,7 @@ import (
 type Cronjob struct {
 	ID              primitive.ObjectID `bson:"_id,omitempty"                       json:"id"`
 	Name            string             `bson:"name"                                json:"name"`
+	Description     string             `bson:"description,omitempty"               json:"description,omitempty"`
 	Type            string             `bson:"type"                                json:"type"`
 	Number          uint64             `bson:"number"                              json:"number"`
 	Frequency       string             `bson:"frequency"                           json:"frequency"`
@@ -38,6 +39,16 @@ type Cronjob struct {
 	EnvArgs         *EnvArgs           `bson:"env_args,omitempty"                  json:"env_args,omitempty"`
 	JobType         string             `bson:"job_type"                            json:"job_type"`
 	Enabled         bool               `bson:"enabled"                             json:"enabled"`
+	// Params overrides the params of the workflow v4 when the cron is fired
+	Params []*Param `bson:"params,omitempty"                    json:"params,omitempty"`
+	// ConcurrencyPolicy decides what to do when a previous scheduled run is still executing: queue, skip or cancel_previous
+	ConcurrencyPolicy string `bson:"concurrency_policy,omitempty"        json:"concurrency_policy,omitempty"`
+	// MisfirePolicy decides what to do with the fire time missed while the cron service was down: skip or fire_now
+	MisfirePolicy string `bson:"misfire_policy,omitempty"            json:"misfire_policy,omitempty"`
+	LastFireTime  int64  `bson:"last_fire_time,omitempty"            json:"last_fire_time,omitempty"`
+	// Timezone is the IANA name of the time zone the cron is scheduled in, empty means the server local time
+	Timezone    string `bson:"timezone,omitempty"                  json:"timezone,omitempty"`
+	NextRunTime string `bson:"-"                                   json:"next_run_time,omitempty"`
 }
 
 type EnvArgs struct {
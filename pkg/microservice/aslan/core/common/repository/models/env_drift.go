@@ -0,0 +1,63 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DriftDetectionConfig controls the periodic comparison between the desired manifests of the env and the live
+// cluster objects
+type DriftDetectionConfig struct {
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// AutoRevert reapplies the desired fields onto the drifted objects and recreates the missing ones
+	AutoRevert bool `bson:"auto_revert" json:"auto_revert"`
+	// IgnoredFields are the field paths never reported, spec.replicas of the workloads scaled by HPA is always ignored
+	IgnoredFields []string `bson:"ignored_fields" json:"ignored_fields"`
+}
+
+// EnvDrift is the latest drift detection result of an env, one document per env
+type EnvDrift struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ProductName string             `bson:"product_name"  json:"product_name"`
+	EnvName     string             `bson:"env_name"      json:"env_name"`
+	Production  bool               `bson:"production"    json:"production"`
+	Drifted     bool               `bson:"drifted"       json:"drifted"`
+	Services    []*ServiceDrift    `bson:"services"      json:"services"`
+	Error       string             `bson:"error"         json:"error"`
+	CheckTime   int64              `bson:"check_time"    json:"check_time"`
+}
+
+type ServiceDrift struct {
+	ServiceName string           `bson:"service_name" json:"service_name"`
+	Resources   []*ResourceDrift `bson:"resources"    json:"resources"`
+	Error       string           `bson:"error"        json:"error"`
+}
+
+type ResourceDrift struct {
+	Kind    string `bson:"kind"    json:"kind"`
+	Name    string `bson:"name"    json:"name"`
+	Missing bool   `bson:"missing" json:"missing"`
+	// Fields are the paths of the drifted fields, such as spec.template.spec.containers[0].image
+	Fields   []string `bson:"fields"   json:"fields"`
+	Reverted bool     `bson:"reverted" json:"reverted"`
+	Error    string   `bson:"error"    json:"error"`
+}
+
+func (EnvDrift) TableName() string {
+	return "env_drift"
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type FeatureFlagState struct {
+	Enabled bool `bson:"enabled"           json:"enabled"           yaml:"enabled"`
+	Rollout *int `bson:"rollout,omitempty" json:"rollout,omitempty" yaml:"rollout,omitempty"`
+}
+
+// FeatureFlagRecord is a change of a flag made by the feature flag jobs, the latest record not rolled back is restored
+// by the next rollback of the flag
+type FeatureFlagRecord struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty"      json:"id"`
+	ExternalSystemID string             `bson:"external_system_id" json:"external_system_id"`
+	Project          string             `bson:"project"            json:"project"`
+	Environment      string             `bson:"environment"        json:"environment"`
+	Flag             string             `bson:"flag"               json:"flag"`
+	Previous         *FeatureFlagState  `bson:"previous"           json:"previous"`
+	Current          *FeatureFlagState  `bson:"current"            json:"current"`
+	ProductName      string             `bson:"product_name"       json:"product_name"`
+	WorkflowName     string             `bson:"workflow_name"      json:"workflow_name"`
+	TaskID           int64              `bson:"task_id"            json:"task_id"`
+	RolledBack       bool               `bson:"rolled_back"        json:"rolled_back"`
+	CreateTime       int64              `bson:"create_time"        json:"create_time"`
+}
+
+func (FeatureFlagRecord) TableName() string {
+	return "feature_flag_record"
+}
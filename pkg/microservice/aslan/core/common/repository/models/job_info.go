@@ -25,6 +25,17 @@ type JobInfo struct {
 	Production bool `bson:"production" json:"production"`
 	// TargetEnv is the target environment for the deploy job
 	TargetEnv string `bson:"target_env" json:"target_env"`
+	// Images are the images deployed by the deploy job
+	Images []string `bson:"images,omitempty" json:"images,omitempty"`
+	// JobName and InputHash identify the runs of a freestyle like job with identical inputs, the commits and the
+	// parameters, a job is Flaky when its result differs from the last run with the same inputs
+	JobName   string `bson:"job_name,omitempty"   json:"job_name,omitempty"`
+	InputHash string `bson:"input_hash,omitempty" json:"input_hash,omitempty"`
+	Flaky     bool   `bson:"flaky,omitempty"      json:"flaky,omitempty"`
+	// FailureCategories are the categories of the failure of a failed job, see the failureclass package
+	FailureCategories []string `bson:"failure_categories,omitempty" json:"failure_categories,omitempty"`
+	// FailureReason is the last error line of the log of a failed job, the numbers in it are replaced by N
+	FailureReason string `bson:"failure_reason,omitempty" json:"failure_reason,omitempty"`
 }
 
 func (JobInfo) TableName() string {
@@ -0,0 +1,36 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// JobLogSummary is the llm generated root cause explanation of a failed job, one document per job of a task.
+type JobLogSummary struct {
+	ID                primitive.ObjectID `bson:"_id,omitempty"      json:"id"`
+	WorkflowName      string             `bson:"workflow_name"      json:"workflow_name"`
+	TaskID            int64              `bson:"task_id"            json:"task_id"`
+	JobName           string             `bson:"job_name"           json:"job_name"`
+	FailureCategories []string           `bson:"failure_categories" json:"failure_categories"`
+	Summary           string             `bson:"summary"            json:"summary"`
+	Provider          string             `bson:"provider"           json:"provider"`
+	CreatedBy         string             `bson:"created_by"         json:"created_by"`
+	CreateTime        int64              `bson:"create_time"        json:"create_time"`
+}
+
+func (JobLogSummary) TableName() string {
+	return "job_log_summary"
+}
@@ -44,6 +44,7 @@ type K8SCluster struct {
 	Cache                  types.Cache              `json:"cache"                     bson:"cache"`
 	ShareStorage           types.ShareStorage       `json:"share_storage"             bson:"share_storage"`
 	LastConnectionTime     int64                    `json:"last_connection_time"      bson:"last_connection_time"`
+	AgentVersion           string                   `json:"agent_version"             bson:"agent_version"`
 	UpdateHubagentErrorMsg string                   `json:"update_hubagent_error_msg" bson:"update_hubagent_error_msg"`
 	DindCfg                *DindCfg                 `json:"dind_cfg"                  bson:"dind_cfg"`
 
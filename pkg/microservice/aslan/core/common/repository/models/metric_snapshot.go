@@ -0,0 +1,53 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+// MetricSnapshotConfig defines the metrics snapshotted in a window before and after each deploy job of the env
+type MetricSnapshotConfig struct {
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// ObservabilityID refers to the prometheus or grafana integration
+	ObservabilityID string `bson:"observability_id" json:"observability_id"`
+	WindowMinutes   int    `bson:"window_minutes"   json:"window_minutes"`
+	// Blocking keeps the deploy jobs running until the snapshots after the deployments are taken, otherwise they are
+	// taken in the background and saved into the workflow tasks when the window ends
+	Blocking bool `bson:"blocking" json:"blocking"`
+	// Metrics are PromQL queries aggregated into one series, $namespace, $service and $window are replaced when querying,
+	// e.g. sum(rate(http_requests_total{namespace="$namespace",code=~"5.."}[$window]))
+	Metrics []*MetricQuery `bson:"metrics" json:"metrics"`
+}
+
+type MetricQuery struct {
+	Name  string `bson:"name"  json:"name"  yaml:"name"`
+	Query string `bson:"query" json:"query" yaml:"query"`
+}
+
+// MetricComparison is the metric snapshots of a deployment for regression triage
+type MetricComparison struct {
+	WindowMinutes int               `bson:"window_minutes" json:"window_minutes" yaml:"window_minutes"`
+	BeforeTime    int64             `bson:"before_time"    json:"before_time"    yaml:"before_time"`
+	AfterTime     int64             `bson:"after_time"     json:"after_time"     yaml:"after_time"`
+	Metrics       []*MetricSnapshot `bson:"metrics"        json:"metrics"        yaml:"metrics"`
+	Error         string            `bson:"error"          json:"error"          yaml:"error"`
+}
+
+// MetricSnapshot holds the values of a metric before and after the deployment, nil means no data
+type MetricSnapshot struct {
+	Name   string   `bson:"name"   json:"name"   yaml:"name"`
+	Before *float64 `bson:"before" json:"before" yaml:"before"`
+	After  *float64 `bson:"after"  json:"after"  yaml:"after"`
+	Error  string   `bson:"error"  json:"error"  yaml:"error"`
+}
@@ -69,6 +69,40 @@ type NotificationTask struct {
 	TestReports         []*TestSuite      `bson:"test_reports,omitempty"  json:"test_reports,omitempty"`
 
 	FirstCommented bool `json:"first_commented,omitempty" bson:"first_commented,omitempty"`
+	// Jobs is the status of the jobs of a custom workflow task
+	Jobs []*NotificationJob `bson:"jobs,omitempty" json:"jobs,omitempty"`
+	// GerritVotes overrides the label and the score voted on the gerrit change when the task finishes
+	GerritVotes []*GerritVote `bson:"gerrit_votes,omitempty" json:"gerrit_votes,omitempty"`
+}
+
+type NotificationJob struct {
+	Name   string        `bson:"name"   json:"name"`
+	Status config.Status `bson:"status" json:"status"`
+}
+
+func (j NotificationJob) StatusVerbose() string {
+	switch j.Status {
+	case config.StatusPassed:
+		return "成功"
+	case config.StatusRunning:
+		return "运行中"
+	case config.StatusFailed:
+		return "失败"
+	case config.StatusTimeout:
+		return "超时"
+	case config.StatusCancelled:
+		return "已取消"
+	case config.StatusSkipped:
+		return "跳过"
+	case config.StatusReject:
+		return "被拒绝"
+	case config.StatusWaitingApprove:
+		return "等待审批"
+	case "", config.StatusCreated, config.StatusPrepare, config.StatusWaiting, config.StatusQueued, config.StatusBlocked:
+		return "等待中"
+	default:
+		return "未知"
+	}
 }
 
 func (t NotificationTask) StatusVerbose() string {
@@ -144,7 +178,7 @@ func (n *Notification) CreateCommentBody() (comment string, err error) {
 			tmplSource = "触发的工作流：等待任务启动中"
 		} else {
 			tmplSource =
-				"|触发的工作流|状态| \n |---|---| \n {{range .Tasks}}|[{{.WorkflowDisplayName}}#{{.ID}}]({{$.BaseURI}}/v1/projects/detail/{{.ProductName}}/pipelines/custom/{{.WorkflowName}}/{{.ID}}?display_name={{.EncodedDisplayName}}) | {{if eq .StatusVerbose $.Success}} {+ {{.StatusVerbose}} +}{{else}}{- {{.StatusVerbose}} -}{{end}} | \n {{end}}"
+				"|触发的工作流|状态|任务状态| \n |---|---|---| \n {{range .Tasks}}|[{{.WorkflowDisplayName}}#{{.ID}}]({{$.BaseURI}}/v1/projects/detail/{{.ProductName}}/pipelines/custom/{{.WorkflowName}}/{{.ID}}?display_name={{.EncodedDisplayName}}) | {{if eq .StatusVerbose $.Success}} {+ {{.StatusVerbose}} +}{{else}}{- {{.StatusVerbose}} -}{{end}} | {{range .Jobs}}{{.Name}}: {{.StatusVerbose}} <br> {{end}} | \n {{end}}"
 		}
 	} else {
 		if len(n.Tasks) == 0 {
@@ -25,8 +25,10 @@ type Observability struct {
 	Host string             `json:"host" bson:"host" yaml:"host"`
 	// ConsoleHost is used for guanceyun console, Host is guanceyun OpenApi Addr
 	ConsoleHost string `json:"console_host" bson:"console_host" yaml:"console_host"`
-	// ApiKey is used for guanceyun
+	// ApiKey is used for guanceyun, it is the bearer token for prometheus and grafana
 	ApiKey string `json:"api_key" bson:"api_key" yaml:"api_key"`
+	// DatasourceUID is the uid of the prometheus datasource in grafana
+	DatasourceUID string `json:"datasource_uid" bson:"datasource_uid" yaml:"datasource_uid"`
 
 	UpdateTime int64 `json:"update_time" bson:"update_time" yaml:"update_time"`
 }
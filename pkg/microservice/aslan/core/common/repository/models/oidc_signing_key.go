@@ -0,0 +1,32 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// OIDCSigningKey is the RSA key signing the workload identity tokens of the jobs
+type OIDCSigningKey struct {
+	ID                  primitive.ObjectID `bson:"_id,omitempty"         json:"id,omitempty"`
+	KeyID               string             `bson:"key_id"                json:"key_id"`
+	PrivateKey          string             `bson:"-"                     json:"-"`
+	EncryptedPrivateKey string             `bson:"encrypted_private_key" json:"-"`
+	CreateTime          int64              `bson:"create_time"           json:"create_time"`
+}
+
+func (OIDCSigningKey) TableName() string {
+	return "oidc_signing_key"
+}
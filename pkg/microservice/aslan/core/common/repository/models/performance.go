@@ -0,0 +1,42 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+// PerformanceRecord is the result of a performance test job in one workflow task run
+type PerformanceRecord struct {
+	ID           primitive.ObjectID       `bson:"_id,omitempty"  json:"id"`
+	ProjectName  string                   `bson:"project_name"   json:"project_name"`
+	WorkflowName string                   `bson:"workflow_name"  json:"workflow_name"`
+	TaskID       int64                    `bson:"task_id"        json:"task_id"`
+	JobName      string                   `bson:"job_name"       json:"job_name"`
+	Tool         string                   `bson:"tool"           json:"tool"`
+	Metrics      *step.PerformanceMetrics `bson:"metrics"        json:"metrics"`
+	// BaselineTaskID is the task compared with, zero means no comparison
+	BaselineTaskID int64    `bson:"baseline_task_id" json:"baseline_task_id"`
+	Regressions    []string `bson:"regressions"      json:"regressions"`
+	CreateTime     int64    `bson:"create_time"      json:"create_time"`
+}
+
+func (PerformanceRecord) TableName() string {
+	return "performance_record"
+}
@@ -35,8 +35,13 @@ type PluginRepo struct {
 	PluginTemplates []*PluginTemplate  `bson:"plugin_templates"          json:"plugin_templates"         yaml:"plugin_templates"`
 	Status          string             `bson:"status"                    json:"status"                   yaml:"status"`
 	Error           string             `bson:"error"                     json:"error"                    yaml:"error"`
+	// RepoType is git by default, the versions of the plugin of an oci repo are the tags of the OCIRepo in the registry
+	RepoType   string `bson:"repo_type,omitempty"   json:"repo_type,omitempty"   yaml:"repo_type,omitempty"`
+	RegistryID string `bson:"registry_id,omitempty" json:"registry_id,omitempty" yaml:"registry_id,omitempty"`
+	OCIRepo    string `bson:"oci_repo,omitempty"    json:"oci_repo,omitempty"    yaml:"oci_repo,omitempty"`
 }
 
+// PluginTemplate is the descriptor of a version of a plugin
 type PluginTemplate struct {
 	Name        string    `bson:"name"             json:"name"             yaml:"name"`
 	IsOffical   bool      `bson:"is_offical"       json:"is_offical"       yaml:"is_offical"`
@@ -50,6 +55,14 @@ type PluginTemplate struct {
 	Envs        []*Env    `bson:"envs"             json:"envs"             yaml:"envs"`
 	Inputs      []*Param  `bson:"inputs"           json:"inputs"           yaml:"inputs"`
 	Outputs     []*Output `bson:"outputs"          json:"outputs"          yaml:"outputs"`
+	// ID identifies the plugin in its repo, it is the directory name of the plugin in git repos by default
+	ID     string `bson:"id"      json:"id"      yaml:"id"`
+	RepoID string `bson:"repo_id" json:"repo_id" yaml:"-"`
+	Icon   string `bson:"icon"    json:"icon"    yaml:"icon"`
+	// Docs is the markdown documentation of the plugin
+	Docs string `bson:"docs" json:"docs" yaml:"docs"`
+	// MinZadigVersion is the earliest zadig version the plugin works with
+	MinZadigVersion string `bson:"min_zadig_version" json:"min_zadig_version" yaml:"min_zadig_version"`
 }
 
 type Env struct {
@@ -75,6 +75,16 @@ type Product struct {
 	// New Since v1.19.0, env sleep configs
 	PreSleepStatus map[string]int `bson:"pre_sleep_status" json:"pre_sleep_status"`
 
+	// ChartVersionPins pins the chart version of helm services created from chart repos, independent of the service template
+	// key: service name, value: chart version
+	ChartVersionPins map[string]string `bson:"chart_version_pins,omitempty" json:"chart_version_pins,omitempty"`
+	// HelmDeployConfig controls the hooks and post-renderer used when deploying helm releases in the environment
+	HelmDeployConfig *HelmDeployConfig `bson:"helm_deploy_config,omitempty" json:"helm_deploy_config,omitempty"`
+	// DriftDetection controls the periodic comparison between the desired manifests and the live cluster objects
+	DriftDetection *DriftDetectionConfig `bson:"drift_detection,omitempty" json:"drift_detection,omitempty"`
+	// MetricSnapshot controls the metric snapshots taken before and after the deploy jobs
+	MetricSnapshot *MetricSnapshotConfig `bson:"metric_snapshot,omitempty" json:"metric_snapshot,omitempty"`
+
 	// For production environment
 	Production bool   `json:"production" bson:"production"`
 	Alias      string `json:"alias" bson:"alias"`
@@ -85,6 +95,7 @@ type NotificationEvent string
 const (
 	NotificationEventAnalyzerNoraml   NotificationEvent = "notification_event_analyzer_normal"
 	NotificationEventAnalyzerAbnormal NotificationEvent = "notification_event_analyzer_abnormal"
+	NotificationEventEnvDrift         NotificationEvent = "notification_event_env_drift"
 )
 
 type WebHookType string
@@ -179,6 +190,41 @@ type ProductShareEnv struct {
 	BaseEnv string `bson:"base_env" json:"base_env"`
 }
 
+type HelmDeployConfig struct {
+	DisableHooks bool `bson:"disable_hooks" json:"disable_hooks"`
+	// HookTimeout is the time in seconds to wait for each chart hook, 0 means the helm default
+	HookTimeout  int                    `bson:"hook_timeout"            json:"hook_timeout"`
+	PostRenderer *KustomizePostRenderer `bson:"post_renderer,omitempty" json:"post_renderer,omitempty"`
+}
+
+// KustomizePostRenderer applies kustomize patches to the rendered manifests of all the helm releases in the environment
+type KustomizePostRenderer struct {
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// Source: spock (patches stored in Zadig) or repo (patches stored in a git path)
+	Source        string                        `bson:"source"                    json:"source"`
+	Patches       []*KustomizePatch             `bson:"patches,omitempty"         json:"patches,omitempty"`
+	GitRepoConfig *templatemodels.GitRepoConfig `bson:"git_repo_config,omitempty" json:"git_repo_config,omitempty"`
+	// Path is the file or directory in git repo containing strategic merge patches
+	Path  string `bson:"path,omitempty"   json:"path,omitempty"`
+	IsDir bool   `bson:"is_dir,omitempty" json:"is_dir,omitempty"`
+}
+
+// KustomizePatch is a strategic merge patch or json 6902 patch, target is required for json 6902 patches
+type KustomizePatch struct {
+	Patch  string                `bson:"patch"            json:"patch"`
+	Target *KustomizePatchTarget `bson:"target,omitempty" json:"target,omitempty"`
+}
+
+type KustomizePatchTarget struct {
+	Group              string `bson:"group,omitempty"               json:"group,omitempty"`
+	Version            string `bson:"version,omitempty"             json:"version,omitempty"`
+	Kind               string `bson:"kind,omitempty"                json:"kind,omitempty"`
+	Name               string `bson:"name,omitempty"                json:"name,omitempty"`
+	Namespace          string `bson:"namespace,omitempty"           json:"namespace,omitempty"`
+	LabelSelector      string `bson:"label_selector,omitempty"      json:"label_selector,omitempty"`
+	AnnotationSelector string `bson:"annotation_selector,omitempty" json:"annotation_selector,omitempty"`
+}
+
 func (Product) TableName() string {
 	return "product"
 }
@@ -0,0 +1,33 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// ProjectCustomField is the default custom field configuration of the workflows in the project,
+// it is used when the workflow has no custom field configuration of its own
+type ProjectCustomField struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"  json:"id,omitempty"`
+	ProjectName string             `bson:"project_name"   json:"project_name"`
+	CustomField *CustomField       `bson:"custom_field"   json:"custom_field"`
+	UpdateBy    string             `bson:"update_by"      json:"update_by"`
+	UpdateTime  int64              `bson:"update_time"    json:"update_time"`
+}
+
+func (ProjectCustomField) TableName() string {
+	return "project_custom_field"
+}
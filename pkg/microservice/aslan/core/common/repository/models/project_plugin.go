@@ -0,0 +1,45 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+// ProjectPlugin restricts the plugins the workflows of the project can use, all the plugins are enabled
+// in the projects without it
+type ProjectPlugin struct {
+	ProjectName string           `bson:"project_name" json:"project_name"`
+	Plugins     []*EnabledPlugin `bson:"plugins"      json:"plugins"`
+	UpdateBy    string           `bson:"update_by"    json:"update_by"`
+	UpdateTime  int64            `bson:"update_time"  json:"update_time"`
+}
+
+type EnabledPlugin struct {
+	RepoID   string `bson:"repo_id"   json:"repo_id"`
+	PluginID string `bson:"plugin_id" json:"plugin_id"`
+}
+
+// Enabled returns true if the plugin of the repo is enabled in the project
+func (p *ProjectPlugin) Enabled(repoID, pluginID string) bool {
+	for _, plugin := range p.Plugins {
+		if plugin.RepoID == repoID && plugin.PluginID == pluginID {
+			return true
+		}
+	}
+	return false
+}
+
+func (ProjectPlugin) TableName() string {
+	return "project_plugin"
+}
@@ -0,0 +1,39 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+// ProjectResourceUsage is the resource occupied by the running job pods of a project in all the aslan instances
+type ProjectResourceUsage struct {
+	ProjectName string                   `bson:"project_name" json:"project_name"`
+	Jobs        int                      `bson:"jobs"         json:"jobs"`
+	CPU         int                      `bson:"cpu"          json:"cpu"`
+	Memory      int                      `bson:"memory"       json:"memory"`
+	Holders     []*ProjectResourceHolder `bson:"holders"      json:"holders"`
+}
+
+// ProjectResourceHolder is a job occupying the resource of the project
+type ProjectResourceHolder struct {
+	JobKey       string `bson:"job_key"       json:"job_key"`
+	WorkflowName string `bson:"workflow_name" json:"workflow_name"`
+	TaskID       int64  `bson:"task_id"       json:"task_id"`
+	CPU          int    `bson:"cpu"           json:"cpu"`
+	Memory       int    `bson:"memory"        json:"memory"`
+}
+
+func (ProjectResourceUsage) TableName() string {
+	return "project_resource_usage"
+}
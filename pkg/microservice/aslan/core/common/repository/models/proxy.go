@@ -38,6 +38,67 @@ type Proxy struct {
 	CreateTime             int64  `bson:"create_time"                  json:"create_time"`
 	UpdateTime             int64  `bson:"update_time"                  json:"update_time"`
 	UpdateBy               string `bson:"update_by"                    json:"update_by"`
+	// IntegrationProxies overrides the proxy of the integrations, the integrations without an override use this
+	// proxy if it is enabled, the repo proxy for the code hosts and the application proxy for the others
+	IntegrationProxies []*IntegrationProxy `bson:"integration_proxies" json:"integration_proxies"`
+}
+
+const (
+	ProxyIntegrationCodeHost = "codehost"
+	ProxyIntegrationRegistry = "registry"
+	ProxyIntegrationIM       = "im"
+	ProxyIntegrationWebhook  = "webhook"
+
+	// IntegrationProxyModeCustom uses the proxy of the override, IntegrationProxyModeDirect uses no proxy
+	IntegrationProxyModeCustom = "custom"
+	IntegrationProxyModeDirect = "direct"
+)
+
+type IntegrationProxy struct {
+	Integration  string `bson:"integration"   json:"integration"`
+	Mode         string `bson:"mode"          json:"mode"`
+	Type         string `bson:"type"          json:"type"`
+	Address      string `bson:"address"       json:"address"`
+	Port         int    `bson:"port"          json:"port"`
+	NeedPassword bool   `bson:"need_password" json:"need_password"`
+	Username     string `bson:"username"      json:"username"`
+	Password     string `bson:"password"      json:"password"`
+}
+
+func (p *IntegrationProxy) GetProxyURL() string {
+	proxy := &Proxy{
+		Type:         p.Type,
+		Address:      p.Address,
+		Port:         p.Port,
+		NeedPassword: p.NeedPassword,
+		Username:     p.Username,
+		Password:     p.Password,
+	}
+	return proxy.GetProxyURL()
+}
+
+// ResolveIntegrationProxy returns the proxy url and type used by the integration, the url is empty if no proxy is used.
+// The override of the integration wins, otherwise the code hosts use the repo proxy and the others the application proxy.
+func (p *Proxy) ResolveIntegrationProxy(integration string) (string, string) {
+	for _, override := range p.IntegrationProxies {
+		if override.Integration != integration {
+			continue
+		}
+		switch override.Mode {
+		case IntegrationProxyModeCustom:
+			return override.GetProxyURL(), override.Type
+		case IntegrationProxyModeDirect:
+			return "", ""
+		}
+	}
+	enabled := p.EnableApplicationProxy
+	if integration == ProxyIntegrationCodeHost {
+		enabled = p.EnableRepoProxy
+	}
+	if !enabled {
+		return "", ""
+	}
+	return p.GetProxyURL(), p.Type
 }
 
 func (Proxy) TableName() string {
@@ -84,6 +84,39 @@ type WorkflowReleaseJobSpec struct {
 	TaskID   int64         `bson:"task_id"       yaml:"task_id"                   json:"task_id"`
 }
 
+type ReleaseTrainReleaseJobSpec struct {
+	// Services are released together with their target versions in every stage
+	Services []*ReleaseTrainService `bson:"services"       yaml:"services"                   json:"services"`
+	// Stages are released one by one, e.g. test, staging and prod
+	Stages []*ReleaseTrainStage `bson:"stages"       yaml:"stages"                   json:"stages"`
+	// CurrentStage is the index of the stage being released
+	CurrentStage int `bson:"current_stage"       yaml:"current_stage"                   json:"current_stage"`
+	// the workflow tasks of the stages are created on behalf of the user who executes the train
+	ExecutorID      string `bson:"executor_id"       yaml:"executor_id"                   json:"executor_id"`
+	ExecutorAccount string `bson:"executor_account"       yaml:"executor_account"                   json:"executor_account"`
+}
+
+type ReleaseTrainService struct {
+	ServiceName   string `bson:"service_name"       yaml:"service_name"                   json:"service_name"`
+	ServiceModule string `bson:"service_module"       yaml:"service_module"                   json:"service_module"`
+	Version       string `bson:"version"       yaml:"version"                   json:"version"`
+	// Image is the image of the target version
+	Image string `bson:"image"       yaml:"image"                   json:"image"`
+}
+
+type ReleaseTrainStage struct {
+	Name string `bson:"name"       yaml:"name"                   json:"name"`
+	// WorkflowName is the workflow whose deploy jobs are used to deploy the services to the env of the stage
+	WorkflowName string `bson:"workflow_name"       yaml:"workflow_name"                   json:"workflow_name"`
+	Env          string `bson:"env"       yaml:"env"                   json:"env"`
+	// Approval is required before the train moves into the stage
+	Approval  *NativeApproval `bson:"approval"       yaml:"approval"                   json:"approval,omitempty"`
+	Status    config.Status   `bson:"status"       yaml:"status"                   json:"status"`
+	TaskID    int64           `bson:"task_id"       yaml:"task_id"                   json:"task_id"`
+	StartTime int64           `bson:"start_time"       yaml:"start_time"                   json:"start_time"`
+	EndTime   int64           `bson:"end_time"       yaml:"end_time"                   json:"end_time"`
+}
+
 type ReleasePlanLog struct {
 	ID         primitive.ObjectID `bson:"_id,omitempty"                  json:"id"`
 	PlanID     string             `bson:"plan_id"                    json:"plan_id"`
@@ -0,0 +1,39 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// RetainedJobPod is the pod of a failed job kept for debugging, it is removed after ExpireTime
+type RetainedJobPod struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ProjectName  string             `bson:"project_name"  json:"project_name"`
+	WorkflowName string             `bson:"workflow_name" json:"workflow_name"`
+	TaskID       int64              `bson:"task_id"       json:"task_id"`
+	JobName      string             `bson:"job_name"      json:"job_name"`
+	JobType      string             `bson:"job_type"      json:"job_type"`
+	K8sJobName   string             `bson:"k8s_job_name"  json:"k8s_job_name"`
+	PodName      string             `bson:"pod_name"      json:"pod_name"`
+	ClusterID    string             `bson:"cluster_id"    json:"cluster_id"`
+	Namespace    string             `bson:"namespace"     json:"namespace"`
+	CreateTime   int64              `bson:"create_time"   json:"create_time"`
+	ExpireTime   int64              `bson:"expire_time"   json:"expire_time"`
+}
+
+func (RetainedJobPod) TableName() string {
+	return "retained_job_pod"
+}
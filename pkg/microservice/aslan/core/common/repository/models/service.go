@@ -30,6 +30,7 @@ import (
 // Service template config has 3 types mainly.
 // 1. Kubernetes service, and yaml+config is held in aslan: type == "k8s"; source == "spock"; yaml != ""
 // 2. Kubernetes service, and yaml+config is held in gitlab: type == "k8s"; source == "gitlab"; src_path != ""
+// 3. Kubernetes service, and yaml is built from kustomize base and overlays in git repo: type == "k8s"; source == "kustomize"; kustomize_config != nil
 type Service struct {
 	ServiceName        string                           `bson:"service_name"                   json:"service_name"`
 	Type               string                           `bson:"type"                           json:"type"`
@@ -78,9 +79,22 @@ type Service struct {
 	EnvName            string                           `bson:"env_name,omitempty"             json:"env_name,omitempty"`
 	TemplateID         string                           `bson:"template_id,omitempty"          json:"template_id,omitempty"`
 	AutoSync           bool                             `bson:"auto_sync"                      json:"auto_sync"`
+	KustomizeConfig    *KustomizeConfig                 `bson:"kustomize_config,omitempty"     json:"kustomize_config,omitempty"`
 	Production         bool                             `bson:"-"                              json:"-"` // check current service data is production service
 }
 
+// KustomizeConfig defines the kustomization of the service, the git repo and root dir are specified by
+// codehost_id, repo_owner, repo_namespace, repo_name, branch_name and load_path of the service
+type KustomizeConfig struct {
+	// BasePath is the path of the base relative to the root dir, used in the environments without overlays
+	BasePath string `bson:"base_path" json:"base_path"`
+	// Overlays key: env name, value: path of the overlay relative to the root dir
+	Overlays map[string]string `bson:"overlays,omitempty" json:"overlays,omitempty"`
+	// OverlayYamls key: env name, value: yaml built from the overlay when the service is created or reloaded,
+	// the yaml built from the base is saved as the yaml of the service
+	OverlayYamls map[string]string `bson:"overlay_yamls,omitempty" json:"overlay_yamls,omitempty"`
+}
+
 type CreateFromRepo struct {
 	GitRepoConfig *templatemodels.GitRepoConfig `bson:"git_repo_config,omitempty"      json:"git_repo_config,omitempty"`
 	LoadPath      string                        `bson:"load_path,omitempty"            json:"load_path,omitempty"`
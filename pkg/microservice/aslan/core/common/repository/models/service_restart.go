@@ -0,0 +1,46 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ServiceRestartRecord is a sample of the container restarts of a service in an env, the samples are kept for a
+// limited period as a rolling store
+type ServiceRestartRecord struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ProductName string             `bson:"product_name"  json:"product_name"`
+	EnvName     string             `bson:"env_name"      json:"env_name"`
+	Production  bool               `bson:"production"    json:"production"`
+	ServiceName string             `bson:"service_name"  json:"service_name"`
+	// Restarts and OOMKills are the increases since the previous sample
+	Restarts int32 `bson:"restarts"  json:"restarts"`
+	OOMKills int32 `bson:"oom_kills" json:"oom_kills"`
+	// Pods are the restart counts of the pods, used to calculate the increase of the next sample
+	Pods []*PodRestartCount `bson:"pods" json:"-"`
+	Time int64              `bson:"time" json:"time"`
+}
+
+type PodRestartCount struct {
+	PodName  string `bson:"pod_name" json:"pod_name"`
+	Restarts int32  `bson:"restarts" json:"restarts"`
+}
+
+func (ServiceRestartRecord) TableName() string {
+	return "service_restart_record"
+}
@@ -24,7 +24,113 @@ type SystemSetting struct {
 	BuildConcurrency    int64              `bson:"build_concurrency" json:"build_concurrency"`
 	DefaultLogin        string             `bson:"default_login" json:"default_login"`
 	Theme               *Theme             `bson:"theme" json:"theme"`
+	MailNotify          *MailNotifyConfig  `bson:"mail_notify" json:"mail_notify"`
+	QueueAlert          *QueueAlertConfig  `bson:"queue_alert" json:"queue_alert"`
 	UpdateTime          int64              `bson:"update_time" json:"update_time"`
+
+	// QueueAlertScaledFrom is the workflow concurrency before the queue alert scaled warpdrive up, the
+	// concurrency is scaled back to it when the queue is idle. Zero means not scaled by the queue alert
+	QueueAlertScaledFrom int64 `bson:"queue_alert_scaled_from" json:"-"`
+
+	WebhookRateLimit *WebhookRateLimitConfig `bson:"webhook_rate_limit" json:"webhook_rate_limit"`
+	WorkflowTrash    *WorkflowTrashConfig    `bson:"workflow_trash"     json:"workflow_trash"`
+	TrustStore       *TrustStoreConfig       `bson:"trust_store"        json:"trust_store"`
+
+	TriggerIPAllowlist *TriggerIPAllowlistConfig `bson:"trigger_ip_allowlist" json:"trigger_ip_allowlist"`
+	JobPolicy          *JobPolicyConfig          `bson:"job_policy"           json:"job_policy"`
+}
+
+// JobPolicyConfig restricts the custom base images of the build and freestyle jobs and the clusters and namespaces
+// targeted by the deploy jobs, it is checked when the workflow is saved and when the job runs. An empty list
+// means no restriction
+type JobPolicyConfig struct {
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// AllowedImages are regular expressions matched against the registry and repository of the image without the tag,
+	// e.g. ^registry\.example\.com/base/.*$
+	AllowedImages []string `bson:"allowed_images" json:"allowed_images"`
+	// AllowedDeployTargets are the clusters and namespaces the deploy jobs may target
+	AllowedDeployTargets []*JobPolicyDeployTarget `bson:"allowed_deploy_targets" json:"allowed_deploy_targets"`
+}
+
+type JobPolicyDeployTarget struct {
+	ClusterID string `bson:"cluster_id" json:"cluster_id"`
+	// Namespaces are regular expressions matched against the namespace, empty means all the namespaces of the cluster
+	Namespaces []string `bson:"namespaces" json:"namespaces"`
+}
+
+// TriggerIPAllowlistConfig is the default allowlist of the webhooks, the general hooks, the registry hooks and the
+// open API creating the workflow tasks, the requests from other sources are rejected and audited. The hooks with
+// their own allowlist use it instead, the country restriction applies to all of them
+type TriggerIPAllowlistConfig struct {
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// CIDRs are the allowed CIDRs or IP addresses, all the addresses are allowed if it is empty
+	CIDRs []string `bson:"cidrs" json:"cidrs"`
+	// CountryHeader is the header carrying the ISO 3166 country code of the client set by the gateway, e.g.
+	// CF-IPCountry, it is only trusted on the requests from the gateways configured by TRUSTED_PROXIES
+	CountryHeader string `bson:"country_header" json:"country_header"`
+	// AllowedCountries are the ISO 3166 country codes allowed to trigger, all the countries are allowed if it is empty
+	AllowedCountries []string `bson:"allowed_countries" json:"allowed_countries"`
+}
+
+// TrustStoreConfig is the CA bundles trusted by the HTTP clients of the integrations in addition to the system roots
+type TrustStoreConfig struct {
+	CABundles []*CABundle `bson:"ca_bundles" json:"ca_bundles"`
+	// InsecureSkipVerify are the integrations which skip the verification of the server certificate
+	InsecureSkipVerify []string `bson:"insecure_skip_verify" json:"insecure_skip_verify"`
+}
+
+// CABundle is a PEM encoded bundle, Subjects and NotAfter are parsed from the certificates
+type CABundle struct {
+	Name        string   `bson:"name"        json:"name"`
+	Certificate string   `bson:"certificate" json:"certificate"`
+	Subjects    []string `bson:"subjects"    json:"subjects"`
+	NotAfter    int64    `bson:"not_after"   json:"not_after"`
+	UpdateBy    string   `bson:"update_by"   json:"update_by"`
+	UpdateTime  int64    `bson:"update_time" json:"update_time"`
+}
+
+// WorkflowTrashConfig is how long the deleted workflows are kept in the trash before they are purged
+type WorkflowTrashConfig struct {
+	RetentionDays int64 `bson:"retention_days" json:"retention_days"`
+}
+
+// WebhookRateLimitConfig limits the inbound webhook requests by token buckets, the rule of the source is used
+// before the default one whose source is empty
+type WebhookRateLimitConfig struct {
+	Enabled bool                    `bson:"enabled" json:"enabled"`
+	Rules   []*WebhookRateLimitRule `bson:"rules"   json:"rules"`
+}
+
+type WebhookRateLimitRule struct {
+	// Source is the code host of the webhook, general_hook or registry_hook
+	Source string `bson:"source" json:"source"`
+	// KeyBy is ip to limit each client, or hook to limit each hook of the source
+	KeyBy string `bson:"key_by" json:"key_by"`
+	// Rate is the requests allowed per second, Burst is the size of the bucket
+	Rate  float64 `bson:"rate"  json:"rate"`
+	Burst int64   `bson:"burst" json:"burst"`
+}
+
+// QueueAlertConfig alerts when the workflow tasks wait in the queue longer than the threshold and optionally
+// adds the CI capacity, the time units are minutes
+type QueueAlertConfig struct {
+	Enabled   bool  `bson:"enabled"   json:"enabled"`
+	Threshold int64 `bson:"threshold" json:"threshold"`
+	// Cooldown is the minimum interval between two alerts
+	Cooldown int64 `bson:"cooldown"  json:"cooldown"`
+	// AutoscalingWebhook is called with the queue status when the alert fires, e.g. to bump the cluster-autoscaler priority
+	AutoscalingWebhook string `bson:"autoscaling_webhook" json:"autoscaling_webhook"`
+	// ScaleWarpdrive raises the workflow concurrency and the warpdrive replicas by one on each alert until MaxConcurrency,
+	// and lowers them by one on each cooldown back to the concurrency before scaling when no task is waiting or running
+	ScaleWarpdrive bool  `bson:"scale_warpdrive" json:"scale_warpdrive"`
+	MaxConcurrency int64 `bson:"max_concurrency" json:"max_concurrency"`
+}
+
+// MailNotifyConfig is the system level html templates of the email notifications, written in go html/template.
+// An empty template means the built-in one.
+type MailNotifyConfig struct {
+	TaskTemplate     string `bson:"task_template"     json:"task_template"`
+	ApprovalTemplate string `bson:"approval_template" json:"approval_template"`
 }
 
 type Theme struct {
@@ -0,0 +1,35 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+// ShareStorageUsage is the disk usage of the dir of a workflow task in the share storage of a cluster,
+// it is refreshed by the garbage collection of the share storage
+type ShareStorageUsage struct {
+	ClusterID    string `bson:"cluster_id"    json:"cluster_id"`
+	WorkflowName string `bson:"workflow_name" json:"workflow_name"`
+	ProjectName  string `bson:"project_name"  json:"project_name"`
+	TaskID       int64  `bson:"task_id"       json:"task_id"`
+	// SizeInKiB is the disk usage of the task dir
+	SizeInKiB  int64 `bson:"size_in_kib"   json:"size_in_kib"`
+	Finished   bool  `bson:"finished"      json:"finished"`
+	EndTime    int64 `bson:"end_time"      json:"end_time"`
+	UpdateTime int64 `bson:"update_time"   json:"update_time"`
+}
+
+func (ShareStorageUsage) TableName() string {
+	return "share_storage_usage"
+}
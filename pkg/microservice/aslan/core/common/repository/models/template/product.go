@@ -76,10 +76,70 @@ type Product struct {
 	GlobalVariables            []*commontypes.ServiceVariableKV `bson:"global_variables,omitempty"          json:"global_variables,omitempty"`                       // New since 1.18.0 used to store global variables for test services
 	ProductionGlobalVariables  []*commontypes.ServiceVariableKV `bson:"production_global_variables,omitempty"          json:"production_global_variables,omitempty"` // New since 1.18.0 used to store global variables for production services
 	Public                     bool                             `bson:"public,omitempty"                    json:"public"`
+	ResourceQuota              *ProjectResourceQuota            `bson:"resource_quota,omitempty"            json:"resource_quota,omitempty"`
+	ImageBuildConfig           *ProjectImageBuildConfig         `bson:"image_build_config,omitempty"        json:"image_build_config,omitempty"`
+	MailNotify                 *ProjectMailNotifyConfig         `bson:"mail_notify,omitempty"               json:"mail_notify,omitempty"`
+	PromotionPolicy            *ProjectPromotionPolicy          `bson:"promotion_policy,omitempty"          json:"promotion_policy,omitempty"`
+	LogRetention               *ProjectLogRetention             `bson:"log_retention,omitempty"             json:"log_retention,omitempty"`
+	FlakyJobPolicy             *ProjectFlakyJobPolicy           `bson:"flaky_job_policy,omitempty"          json:"flaky_job_policy,omitempty"`
 	// created after 1.8.0, used to create default project admins
 	Admins []string `bson:"-" json:"admins"`
 }
 
+// ProjectResourceQuota limits the resources used by the job pods of a project at the same time,
+// zero value of a field means unlimited.
+type ProjectResourceQuota struct {
+	Enabled           bool  `bson:"enabled"             json:"enabled"`
+	MaxConcurrentJobs int   `bson:"max_concurrent_jobs" json:"max_concurrent_jobs"`
+	CPULimit          int   `bson:"cpu_limit"           json:"cpu_limit"`         // total cpu limit in millicores
+	MemoryLimit       int   `bson:"memory_limit"        json:"memory_limit"`      // total memory limit in Mi
+	MaxTaskDuration   int64 `bson:"max_task_duration"   json:"max_task_duration"` // max running time of a workflow task in minutes
+}
+
+// ProjectImageBuildConfig configures the registry-backed layer cache of the buildkit image builds in the project,
+// caches are pushed to CacheRepo under the namespace of the build registry.
+type ProjectImageBuildConfig struct {
+	LayerCacheEnabled bool   `bson:"layer_cache_enabled" json:"layer_cache_enabled"`
+	CacheRepo         string `bson:"cache_repo"          json:"cache_repo"`
+	CacheMode         string `bson:"cache_mode"          json:"cache_mode"` // min or max, same as the mode of buildkit --cache-to
+}
+
+// ProjectMailNotifyConfig is the project level config of the email notifications, Recipients are the email
+// addresses which always receive the notifications of the project, the templates override the system ones.
+type ProjectMailNotifyConfig struct {
+	Recipients       []string `bson:"recipients"        json:"recipients"`
+	TaskTemplate     string   `bson:"task_template"     json:"task_template"`
+	ApprovalTemplate string   `bson:"approval_template" json:"approval_template"`
+}
+
+// ProjectPromotionPolicy restricts the images deployed to the production environments of the project.
+// MutableTags are regular expressions of the tags which may be overwritten, such as latest or branch names,
+// RequirePromotion means the image must have been deployed to a non-production environment successfully.
+type ProjectPromotionPolicy struct {
+	Enabled           bool     `bson:"enabled"             json:"enabled"`
+	ForbidMutableTags bool     `bson:"forbid_mutable_tags" json:"forbid_mutable_tags"`
+	MutableTags       []string `bson:"mutable_tags"        json:"mutable_tags"`
+	RequirePromotion  bool     `bson:"require_promotion"   json:"require_promotion"`
+	// TwoPersonRule requires the approver of a production deploy stage to be neither the task creator nor the commit author,
+	// it is only enforced for the native approvals, not for the lark and dingtalk approvals
+	TwoPersonRule bool `bson:"two_person_rule" json:"two_person_rule"`
+}
+
+// ProjectLogRetention limits the job logs stored for the workflow tasks of the project, zero means no limit.
+// MaxJobLogSize is the max size in MB of the log stored for each job of a task, the middle of a larger log
+// is dropped with a notice. The logs of the tasks created more than RetentionDays ago are removed.
+type ProjectLogRetention struct {
+	MaxJobLogSize int64 `bson:"max_job_log_size" json:"max_job_log_size"`
+	RetentionDays int   `bson:"retention_days"   json:"retention_days"`
+}
+
+// ProjectFlakyJobPolicy decides how the flaky jobs of the project are handled, a job is flaky when its result
+// differs from the last run with the same commits and parameters. With AutoRetry, a failed job having a flaky run
+// in the last 30 days is retried once before its stage fails.
+type ProjectFlakyJobPolicy struct {
+	AutoRetry bool `bson:"auto_retry" json:"auto_retry"`
+}
+
 type ServiceInfo struct {
 	Name  string `bson:"name"  json:"name"`
 	Owner string `bson:"owner" json:"owner"`
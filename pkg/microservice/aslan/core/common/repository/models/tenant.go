@@ -0,0 +1,47 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// Tenant is an organization above the projects, the projects of a tenant share its quota
+type Tenant struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"  json:"id,omitempty"`
+	Name        string             `bson:"name"           json:"name"`
+	DisplayName string             `bson:"display_name"   json:"display_name"`
+	Description string             `bson:"description"    json:"description"`
+	Projects    []string           `bson:"projects"       json:"projects"`
+	Quota       *TenantQuota       `bson:"quota"          json:"quota"`
+	CreatedBy   string             `bson:"created_by"     json:"created_by"`
+	CreateTime  int64              `bson:"create_time"    json:"create_time"`
+	UpdatedBy   string             `bson:"updated_by"     json:"updated_by"`
+	UpdateTime  int64              `bson:"update_time"    json:"update_time"`
+}
+
+// TenantQuota is the aggregate quota of the projects of a tenant, 0 means unlimited
+type TenantQuota struct {
+	// ConcurrentTasks is the number of the workflow tasks running at the same time
+	ConcurrentTasks int `bson:"concurrent_tasks" json:"concurrent_tasks"`
+	// Environments is the number of the environments
+	Environments int `bson:"environments"     json:"environments"`
+	// StorageGi is the storage requested by the persistent volume claims of the environments in Gi
+	StorageGi int64 `bson:"storage_gi"       json:"storage_gi"`
+}
+
+func (Tenant) TableName() string {
+	return "tenant"
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	TestCaseStatusPassed  = "passed"
+	TestCaseStatusFailed  = "failed"
+	TestCaseStatusError   = "error"
+	TestCaseStatusSkipped = "skipped"
+)
+
+// TestCaseResult is the result of a single test case in one workflow task run,
+// it is parsed from the junit report uploaded by the testing job.
+type TestCaseResult struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty"  json:"id"`
+	ProjectName  string             `bson:"project_name"   json:"project_name"`
+	TestName     string             `bson:"test_name"      json:"test_name"`
+	WorkflowName string             `bson:"workflow_name"  json:"workflow_name"`
+	TaskID       int64              `bson:"task_id"        json:"task_id"`
+	JobName      string             `bson:"job_name"       json:"job_name"`
+	ClassName    string             `bson:"class_name"     json:"class_name"`
+	CaseName     string             `bson:"case_name"      json:"case_name"`
+	Status       string             `bson:"status"         json:"status"`
+	Duration     float64            `bson:"duration"       json:"duration"`
+	Message      string             `bson:"message"        json:"message"`
+	CreateTime   int64              `bson:"create_time"    json:"create_time"`
+}
+
+// TestRunStat is the aggregated case results of a test in one workflow task run
+type TestRunStat struct {
+	WorkflowName string `bson:"workflow_name" json:"workflow_name"`
+	TaskID       int64  `bson:"task_id"       json:"task_id"`
+	Total        int    `bson:"total"         json:"total"`
+	Passed       int    `bson:"passed"        json:"passed"`
+	Failed       int    `bson:"failed"        json:"failed"`
+	Errors       int    `bson:"errors"        json:"errors"`
+	Skipped      int    `bson:"skipped"       json:"skipped"`
+	CreateTime   int64  `bson:"create_time"   json:"create_time"`
+}
+
+func (TestCaseResult) TableName() string {
+	return "test_case_result"
+}
+
+// NewTestCaseResults flattens a junit test suite into case results
+func NewTestCaseResults(suite *TestSuite) []*TestCaseResult {
+	resp := make([]*TestCaseResult, 0, len(suite.TestCases))
+	for _, tc := range suite.TestCases {
+		result := &TestCaseResult{
+			ClassName: tc.ClassName,
+			CaseName:  tc.Name,
+			Status:    TestCaseStatusPassed,
+			Duration:  tc.Time,
+		}
+		switch {
+		case tc.Skipped != nil:
+			result.Status = TestCaseStatusSkipped
+		case tc.Failure != nil:
+			result.Status = TestCaseStatusFailed
+			result.Message = tc.Failure.Message
+		case tc.Error != nil:
+			result.Status = TestCaseStatusError
+			result.Message = tc.Error.Message
+		}
+		resp = append(resp, result)
+	}
+	return resp
+}
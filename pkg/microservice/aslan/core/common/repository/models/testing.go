@@ -39,9 +39,10 @@ type Testing struct {
 	// Junit 测试报告
 	TestResultPath string `bson:"test_result_path"         json:"test_result_path"`
 	// html 测试报告
-	TestReportPath string `bson:"test_report_path"         json:"test_report_path"`
-	Threshold      int    `bson:"threshold"                json:"threshold"`
-	TestType       string `bson:"test_type"                json:"test_type"`
+	TestReportPath string          `bson:"test_report_path"         json:"test_report_path"`
+	Threshold      int             `bson:"threshold"                json:"threshold"`
+	TestType       string          `bson:"test_type"                json:"test_type"`
+	Coverage       *CoverageConfig `bson:"coverage,omitempty"       json:"coverage,omitempty"`
 
 	// TODO: Deprecated.
 	Caches []string `bson:"caches"                   json:"caches"`
@@ -101,6 +102,10 @@ type PreTest struct {
 	EnableProxy bool   `bson:"enable_proxy"           json:"enable_proxy"`
 	ClusterID   string `bson:"cluster_id"             json:"cluster_id"`
 	StrategyID  string `bson:"strategy_id"            json:"strategy_id"`
+	// SchedulingHints customizes where the job pod runs
+	SchedulingHints *SchedulingHints `bson:"scheduling_hints,omitempty" json:"scheduling_hints,omitempty"`
+	// ServiceContainers are the auxiliary services started alongside the job
+	ServiceContainers []*ServiceContainer `bson:"service_containers,omitempty" json:"service_containers,omitempty"`
 	// TODO: Deprecated.
 	Namespace string `bson:"namespace"              json:"namespace"`
 }
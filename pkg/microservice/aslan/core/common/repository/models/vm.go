@@ -0,0 +1,94 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+)
+
+// VMAgentHeartbeatTimeout is the duration after the last heartbeat when a vm agent is regarded as offline
+const VMAgentHeartbeatTimeout = 2 * time.Minute
+
+// VMAgent is a self-hosted machine running the zadig agent, it picks up the jobs whose labels it has
+type VMAgent struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"  json:"id"`
+	Name        string             `bson:"name"           json:"name"`
+	Description string             `bson:"description"    json:"description"`
+	Labels      []string           `bson:"labels"         json:"labels"`
+	// TokenHash is the hex sha256 of the token, the token itself is only shown once and never stored
+	TokenHash string `bson:"token_hash"     json:"-"`
+	// the following are reported by the agent when it registers
+	Hostname      string `bson:"hostname"       json:"hostname"`
+	IP            string `bson:"ip"             json:"ip"`
+	Platform      string `bson:"platform"       json:"platform"`
+	Architecture  string `bson:"architecture"   json:"architecture"`
+	AgentVersion  string `bson:"agent_version"  json:"agent_version"`
+	LastHeartbeat int64  `bson:"last_heartbeat" json:"last_heartbeat"`
+	CreatedBy     string `bson:"created_by"     json:"created_by"`
+	CreateTime    int64  `bson:"create_time"    json:"create_time"`
+	UpdatedBy     string `bson:"updated_by"     json:"updated_by"`
+	UpdateTime    int64  `bson:"update_time"    json:"update_time"`
+}
+
+func (VMAgent) TableName() string {
+	return "vm_agent"
+}
+
+func (a *VMAgent) Online() bool {
+	return time.Since(time.Unix(a.LastHeartbeat, 0)) < VMAgentHeartbeatTimeout
+}
+
+// VMJob is a job of a workflow task queued for the vm agents, JobCtx is the job context of the job executor in yaml
+type VMJob struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	AgentID      string             `bson:"agent_id"      json:"agent_id"`
+	Labels       []string           `bson:"labels"        json:"labels"`
+	ProjectName  string             `bson:"project_name"  json:"project_name"`
+	WorkflowName string             `bson:"workflow_name" json:"workflow_name"`
+	TaskID       int64              `bson:"task_id"       json:"task_id"`
+	JobName      string             `bson:"job_name"      json:"job_name"`
+	JobType      string             `bson:"job_type"      json:"job_type"`
+	JobCtx       string             `bson:"job_ctx"       json:"job_ctx"`
+	Status       config.Status      `bson:"status"        json:"status"`
+	// Outputs are the job outputs in json reported by the agent
+	Outputs    string `bson:"outputs"     json:"outputs"`
+	Error      string `bson:"error"       json:"error"`
+	CreateTime int64  `bson:"create_time" json:"create_time"`
+	StartTime  int64  `bson:"start_time"  json:"start_time"`
+	EndTime    int64  `bson:"end_time"    json:"end_time"`
+}
+
+func (VMJob) TableName() string {
+	return "vm_job"
+}
+
+// VMJobLog is a chunk of the log streamed by the agent, the chunks are uploaded to the object storage when the job ends
+type VMJobLog struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	JobID      string             `bson:"job_id"        json:"job_id"`
+	Seq        int64              `bson:"seq"           json:"seq"`
+	Content    string             `bson:"content"       json:"content"`
+	CreateTime int64              `bson:"create_time"   json:"create_time"`
+}
+
+func (VMJobLog) TableName() string {
+	return "vm_job_log"
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// WebhookDelivery records a trigger event received from a code host and what the workflows did with it
+type WebhookDelivery struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"           json:"id"`
+	RequestID     string             `bson:"request_id"              json:"request_id"`
+	Source        string             `bson:"source"                  json:"source"`
+	Event         string             `bson:"event"                   json:"event"`
+	RequestURI    string             `bson:"request_uri"             json:"request_uri"`
+	ClientIP      string             `bson:"client_ip,omitempty"     json:"client_ip,omitempty"`
+	Headers       map[string]string  `bson:"headers"                 json:"headers"`
+	PayloadDigest string             `bson:"payload_digest"          json:"payload_digest"`
+	Payload       string             `bson:"payload,omitempty"       json:"payload,omitempty"`
+	Triggers      []*WebhookTrigger  `bson:"triggers"                json:"triggers"`
+	Error         string             `bson:"error"                   json:"error"`
+	RedeliveryOf  string             `bson:"redelivery_of,omitempty" json:"redelivery_of,omitempty"`
+	CreateTime    int64              `bson:"create_time"             json:"create_time"`
+}
+
+// WebhookTrigger is the result of a workflow trigger matching the repository of the event, TaskID is
+// empty if no task is created and Reason tells why
+type WebhookTrigger struct {
+	ProjectName  string `bson:"project_name"  json:"project_name"`
+	WorkflowName string `bson:"workflow_name" json:"workflow_name"`
+	HookName     string `bson:"hook_name"     json:"hook_name"`
+	TaskID       int64  `bson:"task_id"       json:"task_id"`
+	Reason       string `bson:"reason"        json:"reason"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_delivery"
+}
@@ -54,6 +54,8 @@ type WorkflowTask struct {
 	IsRestart           bool               `bson:"is_restart"                json:"is_restart"`
 	IsDebug             bool               `bson:"is_debug"                  json:"is_debug"`
 	ShareStorages       []*ShareStorage    `bson:"share_storages"            json:"share_storages"`
+	// LogExpired means the job logs of the task were removed by the log retention of the project
+	LogExpired bool `bson:"log_expired,omitempty" json:"log_expired,omitempty"`
 }
 
 func (WorkflowTask) TableName() string {
@@ -71,11 +73,24 @@ type StageTask struct {
 	Error     string        `bson:"error"         json:"error"`
 }
 
+// StageTimelineEvent is an approval operation on the stage, Latency is the seconds since the approval started
+type StageTimelineEvent struct {
+	Action   config.ApproveOrReject `bson:"action"        json:"action"`
+	UserID   string                 `bson:"user_id"       json:"user_id"`
+	UserName string                 `bson:"user_name"     json:"user_name"`
+	Comment  string                 `bson:"comment"       json:"comment"`
+	Source   config.ApprovalSource  `bson:"source"        json:"source"`
+	Time     int64                  `bson:"time"          json:"time"`
+	Latency  int64                  `bson:"latency"       json:"latency"`
+}
+
 type JobTask struct {
 	Name string `bson:"name"                json:"name"`
 	// jobTask unique id, unique in the workflow
 	Key        string `bson:"key"                 json:"key"`
 	K8sJobName string `bson:"k8s_job_name"        json:"k8s_job_name"`
+	// OutputKey is the key the outputs are written under, it is the Key if empty
+	OutputKey string `bson:"output_key,omitempty" json:"output_key,omitempty"`
 	// JobInfo contains the fields that make up the job task name, for frontend display
 	JobInfo          interface{}              `bson:"job_info"            json:"job_info"`
 	JobType          string                   `bson:"type"                json:"type"`
@@ -90,6 +105,38 @@ type JobTask struct {
 	BreakpointBefore bool                     `bson:"breakpoint_before"   json:"breakpoint_before"`
 	BreakpointAfter  bool                     `bson:"breakpoint_after"    json:"breakpoint_after"`
 	ServiceModules   []*WorkflowServiceModule `bson:"service_modules"     json:"service_modules"`
+
+	// FailureCategories classify the failure of a failed job, like compile_error or image_pull_error
+	FailureCategories []string `bson:"failure_categories,omitempty" json:"failure_categories,omitempty"`
+	// Flaky marks that the result of the job differs from the last run with the same commits and parameters,
+	// FlakyRetried marks that the job failed and is retried once by the flaky job policy of the project
+	Flaky        bool `bson:"flaky,omitempty"         json:"flaky,omitempty"`
+	FlakyRetried bool `bson:"flaky_retried,omitempty" json:"flaky_retried,omitempty"`
+
+	// Timeline records when the job entered each phase, only the jobs running in a pod have it
+	Timeline *JobTaskTimeline `bson:"timeline,omitempty" json:"timeline,omitempty"`
+	// ResumeState is the in-flight execution state, the job is re-adopted with it after the controller restarts
+	ResumeState *JobResumeState `bson:"resume_state,omitempty" json:"-"`
+}
+
+// JobResumeState is the in-flight execution state of the job, it is cleared when the job is finished
+type JobResumeState struct {
+	// Phase is the step the job is executing, see config.JobResumePhase
+	Phase     config.JobResumePhase `bson:"phase"      json:"phase"`
+	ClusterID string                `bson:"cluster_id" json:"cluster_id"`
+	Namespace string                `bson:"namespace"  json:"namespace"`
+	// AckTime is the last time the state was acknowledged by the controller
+	AckTime int64 `bson:"ack_time" json:"ack_time"`
+}
+
+// JobTaskTimeline is the time the job entered each phase, 0 means the phase was not reached.
+// The job waits for the resource quota after queued, the images are pulled after the pod is scheduled
+type JobTaskTimeline struct {
+	QueuedTime       int64 `bson:"queued_time"        json:"queued_time,omitempty"`
+	PodCreatedTime   int64 `bson:"pod_created_time"   json:"pod_created_time,omitempty"`
+	PodScheduledTime int64 `bson:"pod_scheduled_time" json:"pod_scheduled_time,omitempty"`
+	RunningTime      int64 `bson:"running_time"       json:"running_time,omitempty"`
+	CollectingTime   int64 `bson:"collecting_time"    json:"collecting_time,omitempty"`
 }
 
 type TaskJobInfo struct {
@@ -113,6 +160,8 @@ type WorkflowTaskPreview struct {
 	EndTime             int64           `bson:"end_time"              json:"end_time,omitempty"`
 	WorkflowArgs        *WorkflowV4     `bson:"workflow_args"         json:"-"`
 	Stages              []*StagePreview `bson:"stages"                json:"stages,omitempty"`
+	// CustomFields: values of the user-defined custom fields, keyed by the field key
+	CustomFields map[string]string `bson:"-"                     json:"custom_fields,omitempty"`
 }
 
 type StagePreview struct {
@@ -185,6 +234,14 @@ type JobTaskDeploySpec struct {
 	Timeout            int                             `bson:"timeout"                          json:"timeout"                             yaml:"timeout"`
 	ReplaceResources   []Resource                      `bson:"replace_resources"                json:"replace_resources"                   yaml:"replace_resources"`
 	RelatedPodLabels   []map[string]string             `bson:"-"                                json:"-"                                   yaml:"-"`
+	UpdateStrategy     *DeployUpdateStrategy           `bson:"update_strategy,omitempty"        json:"update_strategy,omitempty"           yaml:"update_strategy,omitempty"`
+	SkipUnchanged      bool                            `bson:"skip_unchanged"                   json:"skip_unchanged"                      yaml:"skip_unchanged"`
+	ConfirmProtected   bool                            `bson:"confirm_protected"                json:"confirm_protected"                   yaml:"confirm_protected"`
+	RestartCheck       *DeployRestartCheck             `bson:"restart_check,omitempty"          json:"restart_check,omitempty"             yaml:"restart_check,omitempty"`
+	HealthCheck        *DeployHealthCheck              `bson:"health_check,omitempty"           json:"health_check,omitempty"              yaml:"health_check,omitempty"`
+	MetricComparison   *MetricComparison               `bson:"metric_comparison,omitempty"      json:"metric_comparison,omitempty"         yaml:"metric_comparison,omitempty"`
+	// NoChanges is set when the deployment is skipped since nothing is changed
+	NoChanges bool `bson:"no_changes"                       json:"no_changes"                          yaml:"no_changes"`
 	// for compatibility
 	ServiceModule string `bson:"service_module"                   json:"service_module"                      yaml:"-"`
 	Image         string `bson:"image"                            json:"image"                               yaml:"-"`
@@ -202,6 +259,9 @@ type Resource struct {
 	Container   string `bson:"container"                         json:"container"                            yaml:"container"`
 	Origin      string `bson:"origin"                            json:"origin"                               yaml:"origin"`
 	PodOwnerUID string `bson:"pod_owner_uid"                     json:"pod_owner_uid"                        yaml:"pod_owner_uid"`
+	// ChangedConfigs are the changed ConfigMaps and Secrets used by the workload, like ConfigMap/name, the pods are
+	// restarted to make them take effect
+	ChangedConfigs []string `bson:"changed_configs,omitempty"         json:"changed_configs,omitempty"            yaml:"changed_configs,omitempty"`
 }
 
 type JobTaskHelmDeploySpec struct {
@@ -224,6 +284,11 @@ type JobTaskHelmDeploySpec struct {
 	ReleaseName        string                   `bson:"release_name"                     json:"release_name"                        yaml:"release_name"`
 	Timeout            int                      `bson:"timeout"                          json:"timeout"                             yaml:"timeout"`
 	ReplaceResources   []Resource               `bson:"replace_resources"                json:"replace_resources"                   yaml:"replace_resources"`
+	SkipUnchanged      bool                     `bson:"skip_unchanged"                   json:"skip_unchanged"                      yaml:"skip_unchanged"`
+	ConfirmProtected   bool                     `bson:"confirm_protected"                json:"confirm_protected"                   yaml:"confirm_protected"`
+	MetricComparison   *MetricComparison        `bson:"metric_comparison,omitempty"      json:"metric_comparison,omitempty"         yaml:"metric_comparison,omitempty"`
+	// NoChanges is set when the deployment is skipped since nothing is changed
+	NoChanges bool `bson:"no_changes"                       json:"no_changes"                          yaml:"no_changes"`
 }
 
 type JobTaskHelmChartDeploySpec struct {
@@ -314,6 +379,33 @@ type JobTaskCanaryDeploySpec struct {
 	Version            string  `bson:"version"                        json:"version"                       yaml:"version"`
 	Image              string  `bson:"image"                          json:"image"                         yaml:"image"`
 	Events             *Events `bson:"events"                         json:"events"                        yaml:"events"`
+
+	Analysis       *CanaryAnalysis       `bson:"analysis,omitempty"             json:"analysis,omitempty"            yaml:"analysis,omitempty"`
+	AnalysisResult *CanaryAnalysisResult `bson:"analysis_result,omitempty"      json:"analysis_result,omitempty"     yaml:"analysis_result,omitempty"`
+}
+
+const (
+	CanaryAnalysisPromoted   = "promoted"
+	CanaryAnalysisRolledBack = "rolled_back"
+)
+
+type CanaryAnalysisResult struct {
+	Decision string               `bson:"decision" json:"decision" yaml:"decision"`
+	Runs     []*CanaryAnalysisRun `bson:"runs"     json:"runs"     yaml:"runs"`
+}
+
+type CanaryAnalysisRun struct {
+	Time    int64                    `bson:"time"    json:"time"    yaml:"time"`
+	Passed  bool                     `bson:"passed"  json:"passed"  yaml:"passed"`
+	Metrics []*CanaryCriterionResult `bson:"metrics" json:"metrics" yaml:"metrics"`
+}
+
+type CanaryCriterionResult struct {
+	Name     string   `bson:"name"               json:"name"               yaml:"name"`
+	Canary   *float64 `bson:"canary,omitempty"   json:"canary,omitempty"   yaml:"canary,omitempty"`
+	Baseline *float64 `bson:"baseline,omitempty" json:"baseline,omitempty" yaml:"baseline,omitempty"`
+	Passed   bool     `bson:"passed"             json:"passed"             yaml:"passed"`
+	Reason   string   `bson:"reason,omitempty"   json:"reason,omitempty"   yaml:"reason,omitempty"`
 }
 
 type JobTaskCanaryReleaseSpec struct {
@@ -347,6 +439,9 @@ type JobTaskGrayReleaseSpec struct {
 	TotalReplica  int     `bson:"total_replica"         json:"total_replica"        yaml:"total_replica"`
 	GrayReplica   int     `bson:"gray_replica"          json:"gray_replica"         yaml:"gray_replica"`
 	Events        *Events `bson:"events"                json:"events"               yaml:"events"`
+
+	Analysis       *CanaryAnalysis       `bson:"analysis,omitempty"        json:"analysis,omitempty"        yaml:"analysis,omitempty"`
+	AnalysisResult *CanaryAnalysisResult `bson:"analysis_result,omitempty" json:"analysis_result,omitempty" yaml:"analysis_result,omitempty"`
 }
 
 type JobIstioReleaseSpec struct {
@@ -360,6 +455,7 @@ type JobIstioReleaseSpec struct {
 	Replicas          int64           `bson:"replicas"           json:"replicas"           yaml:"replicas"`
 	Targets           *IstioJobTarget `bson:"targets"            json:"targets"            yaml:"targets"`
 	Event             []*Event        `bson:"event"              json:"event"              yaml:"event"`
+	MirrorPercentage  int64           `bson:"mirror_percentage"  json:"mirror_percentage"  yaml:"mirror_percentage"`
 }
 
 type JobIstioRollbackSpec struct {
@@ -488,6 +584,74 @@ type JobTaskGuanceyunCheckSpec struct {
 	Monitors  []*GuanceyunMonitor `bson:"monitors" json:"monitors" yaml:"monitors"`
 }
 
+type JobTaskHarborReplicationSpec struct {
+	RegistryID       string `bson:"registry_id" json:"registry_id" yaml:"registry_id"`
+	PolicyName       string `bson:"policy_name" json:"policy_name" yaml:"policy_name"`
+	Timeout          int64  `bson:"timeout" json:"timeout" yaml:"timeout"`
+	RetentionProject string `bson:"retention_project" json:"retention_project" yaml:"retention_project"`
+	// the fields below are the progress of the executions in harbor
+	ExecutionID          int64  `bson:"execution_id" json:"execution_id" yaml:"execution_id"`
+	ExecutionStatus      string `bson:"execution_status" json:"execution_status" yaml:"execution_status"`
+	Total                int    `bson:"total" json:"total" yaml:"total"`
+	Succeed              int    `bson:"succeed" json:"succeed" yaml:"succeed"`
+	Failed               int    `bson:"failed" json:"failed" yaml:"failed"`
+	RetentionExecutionID int64  `bson:"retention_execution_id" json:"retention_execution_id" yaml:"retention_execution_id"`
+	RetentionStatus      string `bson:"retention_status" json:"retention_status" yaml:"retention_status"`
+}
+
+type JobTaskStaticDistributeSpec struct {
+	ObjectStorageID   string                        `bson:"object_storage_id"   json:"object_storage_id"   yaml:"object_storage_id"`
+	Targets           []*StaticDistributeTaskTarget `bson:"targets"             json:"targets"             yaml:"targets"`
+	CacheControl      string                        `bson:"cache_control"       json:"cache_control"       yaml:"cache_control"`
+	CacheControlRules []*CacheControlRule           `bson:"cache_control_rules" json:"cache_control_rules" yaml:"cache_control_rules"`
+	CDNPurge          *CDNPurgeConfig               `bson:"cdn_purge"           json:"cdn_purge"           yaml:"cdn_purge"`
+	PurgeID           string                        `bson:"purge_id"            json:"purge_id"            yaml:"purge_id"`
+}
+
+type StaticDistributeTaskTarget struct {
+	ServiceName   string `bson:"service_name"   json:"service_name"   yaml:"service_name"`
+	ServiceModule string `bson:"service_module" json:"service_module" yaml:"service_module"`
+	// SourceKey is the object key of the archived package in the default object storage
+	SourceKey  string `bson:"source_key"  json:"source_key"  yaml:"source_key"`
+	SourcePath string `bson:"source_path" json:"source_path" yaml:"source_path"`
+	DestPath   string `bson:"dest_path"   json:"dest_path"   yaml:"dest_path"`
+	FileCount  int    `bson:"file_count"  json:"file_count"  yaml:"file_count"`
+	Status     string `bson:"status"      json:"status"      yaml:"status"`
+}
+
+type JobTaskHTTPCallbackSpec struct {
+	HTTPCallbackJobSpec `bson:",inline" json:",inline" yaml:",inline"`
+	// the fields below are the result of the last request
+	Attempts     int    `bson:"attempts"      json:"attempts"      yaml:"attempts"`
+	StatusCode   int    `bson:"status_code"   json:"status_code"   yaml:"status_code"`
+	ResponseBody string `bson:"response_body" json:"response_body" yaml:"response_body"`
+}
+
+type JobTaskFeatureFlagSpec struct {
+	ExternalSystemID string                   `bson:"external_system_id" json:"external_system_id" yaml:"external_system_id"`
+	Provider         string                   `bson:"provider"           json:"provider"           yaml:"provider"`
+	Flags            []*FeatureFlagTaskChange `bson:"flags"              json:"flags"              yaml:"flags"`
+}
+
+type FeatureFlagTaskChange struct {
+	FeatureFlagChange `bson:",inline" json:",inline" yaml:",inline"`
+	// Previous is the state before the change, it is restored by the rollback jobs
+	Previous *FeatureFlagState `bson:"previous" json:"previous" yaml:"previous"`
+	Changed  bool              `bson:"changed"  json:"changed"  yaml:"changed"`
+}
+
+type JobTaskFeatureFlagRollbackSpec struct {
+	ExternalSystemID string                     `bson:"external_system_id" json:"external_system_id" yaml:"external_system_id"`
+	Provider         string                     `bson:"provider"           json:"provider"           yaml:"provider"`
+	Flags            []*FeatureFlagTaskRollback `bson:"flags"              json:"flags"              yaml:"flags"`
+}
+
+type FeatureFlagTaskRollback struct {
+	FeatureFlagTarget `bson:",inline" json:",inline" yaml:",inline"`
+	// RestoredTo is nil if the flag was never changed by the feature flag jobs
+	RestoredTo *FeatureFlagState `bson:"restored_to" json:"restored_to" yaml:"restored_to"`
+}
+
 type JobTaskMseGrayReleaseSpec struct {
 	Production         bool                  `bson:"production" json:"production" yaml:"production"`
 	GrayTag            string                `bson:"gray_tag" json:"gray_tag" yaml:"gray_tag"`
@@ -558,6 +722,8 @@ type StepTask struct {
 	Onfailure bool            `bson:"on_failure"     json:"on_failure"   yaml:"on_failure"`
 	// step input params,differ form steps
 	Spec interface{} `bson:"spec"           json:"spec"   yaml:"spec"`
+	// Inputs are passed to the step as environment variables
+	Inputs []*KeyVal `bson:"inputs,omitempty" json:"inputs,omitempty" yaml:"inputs,omitempty"`
 	// step output results,like testing results,differ form steps
 	Result interface{} `bson:"result"         json:"result"  yaml:"result"`
 }
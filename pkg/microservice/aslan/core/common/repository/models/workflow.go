@@ -132,7 +132,9 @@ type Schedule struct {
 	Cron            string              `bson:"cron"                          json:"cron"`
 	IsModified      bool                `bson:"-"                             json:"-"`
 	// 自由编排工作流的开关是放在schedule里面的
-	Enabled bool `bson:"enabled"                       json:"enabled"`
+	Enabled           bool   `bson:"enabled"                       json:"enabled"`
+	ConcurrencyPolicy string `bson:"concurrency_policy,omitempty"  json:"concurrency_policy,omitempty"`
+	Timezone          string `bson:"timezone,omitempty"            json:"timezone,omitempty"`
 }
 
 // TaskArgs 单服务工作流任务参数
@@ -329,11 +331,16 @@ type NotifyCtl struct {
 	WeChatWebHook   string   `bson:"weChat_webHook,omitempty"      yaml:"weChat_webHook,omitempty"      json:"weChat_webHook,omitempty"`
 	DingDingWebHook string   `bson:"dingding_webhook,omitempty"    yaml:"dingding_webhook,omitempty"    json:"dingding_webhook,omitempty"`
 	FeiShuWebHook   string   `bson:"feishu_webhook,omitempty"      yaml:"feishu_webhook,omitempty"      json:"feishu_webhook,omitempty"`
+	TeamsWebHook    string   `bson:"teams_webhook,omitempty"       yaml:"teams_webhook,omitempty"       json:"teams_webhook,omitempty"`
+	SlackWebHook    string   `bson:"slack_webhook,omitempty"       yaml:"slack_webhook,omitempty"       json:"slack_webhook,omitempty"`
 	AtMobiles       []string `bson:"at_mobiles,omitempty"          yaml:"at_mobiles,omitempty"          json:"at_mobiles,omitempty"`
 	WechatUserIDs   []string `bson:"wechat_user_ids,omitempty"     yaml:"wechat_user_ids,omitempty"     json:"wechat_user_ids,omitempty"`
 	LarkUserIDs     []string `bson:"lark_user_ids,omitempty"       yaml:"lark_user_ids,omitempty"       json:"lark_user_ids,omitempty"`
+	SlackUserIDs    []string `bson:"slack_user_ids,omitempty"      yaml:"slack_user_ids,omitempty"      json:"slack_user_ids,omitempty"`
 	IsAtAll         bool     `bson:"is_at_all,omitempty"           yaml:"is_at_all,omitempty"           json:"is_at_all,omitempty"`
 	NotifyTypes     []string `bson:"notify_type"                   yaml:"notify_type"                   json:"notify_type"`
+	// MailUsers are the users or user groups to send the email to when WebHookType is mail
+	MailUsers []*User `bson:"mail_users,omitempty"          yaml:"mail_users,omitempty"          json:"mail_users,omitempty"`
 }
 
 type TaskInfo struct {
@@ -376,6 +383,15 @@ type HookPayload struct {
 	DeliveryID     string `bson:"delivery_id"      json:"delivery_id,omitempty"`
 	CodehostID     int    `bson:"codehost_id"      json:"codehost_id"`
 	EventType      string `bson:"event_type"       json:"event_type"`
+
+	// RepoNamespace is the namespace of the repo, it differs from the owner for the gitlab repos in subgroups
+	RepoNamespace string `bson:"repo_namespace,omitempty" json:"repo_namespace,omitempty"`
+	// StageStatuses is the commit status of each stage last reported to the code host
+	StageStatuses map[string]string `bson:"stage_statuses,omitempty" json:"stage_statuses,omitempty"`
+	// GerritVotes are the labels voted on the gerrit change when the task finishes
+	GerritVotes []*GerritVote `bson:"gerrit_votes,omitempty" json:"gerrit_votes,omitempty"`
+	// CommitAuthors are the names, logins and emails of the author of the commit which triggers the task
+	CommitAuthors []string `bson:"commit_authors,omitempty" json:"commit_authors,omitempty"`
 }
 
 type TargetArgs struct {
@@ -0,0 +1,39 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WorkflowPolicy is a rego policy evaluated by opa against the workflow spec when the workflow is created or updated.
+// The module must be in a package under zadig.workflow and define the deny set of the violation messages
+type WorkflowPolicy struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Name        string             `bson:"name"          json:"name"`
+	Description string             `bson:"description"   json:"description"`
+	Rego        string             `bson:"rego"          json:"rego"`
+	Enabled     bool               `bson:"enabled"       json:"enabled"`
+	CreatedBy   string             `bson:"created_by"    json:"created_by"`
+	CreateTime  int64              `bson:"create_time"   json:"create_time"`
+	UpdatedBy   string             `bson:"updated_by"    json:"updated_by"`
+	UpdateTime  int64              `bson:"update_time"   json:"update_time"`
+}
+
+func (WorkflowPolicy) TableName() string {
+	return "workflow_policy"
+}
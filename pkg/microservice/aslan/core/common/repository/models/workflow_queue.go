@@ -33,6 +33,8 @@ type WorkflowQueue struct {
 	TaskCreator         string             `bson:"task_creator"                               json:"task_creator,omitempty"`
 	TaskRevoker         string             `bson:"task_revoker,omitempty"                     json:"task_revoker,omitempty"`
 	CreateTime          int64              `bson:"create_time"                                json:"create_time,omitempty"`
+	// InstanceID is the controller instance running the task
+	InstanceID string `bson:"instance_id,omitempty"                      json:"instance_id,omitempty"`
 }
 
 func (WorkflowQueue) TableName() string {
@@ -0,0 +1,33 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// WorkflowTaskStageTimeline records the approval operations of a stage of a workflow task for audit, it is kept
+// out of the task so that the operations are pushed one by one and never overwritten by the task updates
+type WorkflowTaskStageTimeline struct {
+	ID           primitive.ObjectID    `bson:"_id,omitempty" json:"id"`
+	WorkflowName string                `bson:"workflow_name" json:"workflow_name"`
+	TaskID       int64                 `bson:"task_id"       json:"task_id"`
+	StageName    string                `bson:"stage_name"    json:"stage_name"`
+	Events       []*StageTimelineEvent `bson:"events"        json:"events"`
+}
+
+func (WorkflowTaskStageTimeline) TableName() string {
+	return "workflow_task_stage_timeline"
+}
@@ -37,34 +37,56 @@ import (
 )
 
 type WorkflowV4 struct {
-	ID              primitive.ObjectID       `bson:"_id,omitempty"       yaml:"-"                   json:"id"`
-	Name            string                   `bson:"name"                yaml:"name"                json:"name"`
-	DisplayName     string                   `bson:"display_name"        yaml:"display_name"        json:"display_name"`
-	Category        setting.WorkflowCategory `bson:"category"            yaml:"category"            json:"category"`
-	KeyVals         []*KeyVal                `bson:"key_vals"            yaml:"key_vals"            json:"key_vals"`
-	Params          []*Param                 `bson:"params"              yaml:"params"              json:"params"`
-	Stages          []*WorkflowStage         `bson:"stages"              yaml:"stages"              json:"stages"`
-	Project         string                   `bson:"project"             yaml:"project"             json:"project"`
-	Description     string                   `bson:"description"         yaml:"description"         json:"description"`
-	CreatedBy       string                   `bson:"created_by"          yaml:"created_by"          json:"created_by"`
-	CreateTime      int64                    `bson:"create_time"         yaml:"create_time"         json:"create_time"`
-	UpdatedBy       string                   `bson:"updated_by"          yaml:"updated_by"          json:"updated_by"`
-	UpdateTime      int64                    `bson:"update_time"         yaml:"update_time"         json:"update_time"`
-	NotifyCtls      []*NotifyCtl             `bson:"notify_ctls"         yaml:"notify_ctls"         json:"notify_ctls"`
-	Debug           bool                     `bson:"debug"               yaml:"-"                   json:"debug"`
-	HookCtls        []*WorkflowV4Hook        `bson:"hook_ctl"            yaml:"-"                   json:"hook_ctl"`
-	JiraHookCtls    []*JiraHook              `bson:"jira_hook_ctls"      yaml:"-"                   json:"jira_hook_ctls"`
-	MeegoHookCtls   []*MeegoHook             `bson:"meego_hook_ctls"     yaml:"-"                   json:"meego_hook_ctls"`
-	GeneralHookCtls []*GeneralHook           `bson:"general_hook_ctls"   yaml:"-"                   json:"general_hook_ctls"`
-	NotificationID  string                   `bson:"notification_id"     yaml:"-"                   json:"notification_id"`
-	HookPayload     *HookPayload             `bson:"hook_payload"        yaml:"-"                   json:"hook_payload,omitempty"`
-	BaseName        string                   `bson:"base_name"           yaml:"-"                   json:"base_name"`
-	ShareStorages   []*ShareStorage          `bson:"share_storages"      yaml:"share_storages"      json:"share_storages"`
-	Hash            string                   `bson:"hash"                yaml:"hash"                json:"hash"`
+	ID               primitive.ObjectID       `bson:"_id,omitempty"       yaml:"-"                   json:"id"`
+	Name             string                   `bson:"name"                yaml:"name"                json:"name"`
+	DisplayName      string                   `bson:"display_name"        yaml:"display_name"        json:"display_name"`
+	Category         setting.WorkflowCategory `bson:"category"            yaml:"category"            json:"category"`
+	KeyVals          []*KeyVal                `bson:"key_vals"            yaml:"key_vals"            json:"key_vals"`
+	Params           []*Param                 `bson:"params"              yaml:"params"              json:"params"`
+	Stages           []*WorkflowStage         `bson:"stages"              yaml:"stages"              json:"stages"`
+	Project          string                   `bson:"project"             yaml:"project"             json:"project"`
+	Description      string                   `bson:"description"         yaml:"description"         json:"description"`
+	CreatedBy        string                   `bson:"created_by"          yaml:"created_by"          json:"created_by"`
+	CreateTime       int64                    `bson:"create_time"         yaml:"create_time"         json:"create_time"`
+	UpdatedBy        string                   `bson:"updated_by"          yaml:"updated_by"          json:"updated_by"`
+	UpdateTime       int64                    `bson:"update_time"         yaml:"update_time"         json:"update_time"`
+	NotifyCtls       []*NotifyCtl             `bson:"notify_ctls"         yaml:"notify_ctls"         json:"notify_ctls"`
+	Debug            bool                     `bson:"debug"               yaml:"-"                   json:"debug"`
+	HookCtls         []*WorkflowV4Hook        `bson:"hook_ctl"            yaml:"-"                   json:"hook_ctl"`
+	JiraHookCtls     []*JiraHook              `bson:"jira_hook_ctls"      yaml:"-"                   json:"jira_hook_ctls"`
+	MeegoHookCtls    []*MeegoHook             `bson:"meego_hook_ctls"     yaml:"-"                   json:"meego_hook_ctls"`
+	GeneralHookCtls  []*GeneralHook           `bson:"general_hook_ctls"   yaml:"-"                   json:"general_hook_ctls"`
+	RegistryHookCtls []*RegistryHook          `bson:"registry_hook_ctls"  yaml:"-"                   json:"registry_hook_ctls"`
+	NotificationID   string                   `bson:"notification_id"     yaml:"-"                   json:"notification_id"`
+	HookPayload      *HookPayload             `bson:"hook_payload"        yaml:"-"                   json:"hook_payload,omitempty"`
+	BaseName         string                   `bson:"base_name"           yaml:"-"                   json:"base_name"`
+	ShareStorages    []*ShareStorage          `bson:"share_storages"      yaml:"share_storages"      json:"share_storages"`
+	Hash             string                   `bson:"hash"                yaml:"hash"                json:"hash"`
 	// ConcurrencyLimit is the max number of concurrent runs of this workflow
 	// -1 means no limit
 	ConcurrencyLimit int          `bson:"concurrency_limit"   yaml:"concurrency_limit"   json:"concurrency_limit"`
 	CustomField      *CustomField `bson:"custom_field"        yaml:"-"                   json:"custom_field"`
+
+	// MeegoSync transitions the linked meego work items and comments on them after the task completes
+	MeegoSync *MeegoSyncConfig `bson:"meego_sync" yaml:"meego_sync" json:"meego_sync"`
+	// MeegoWorkItems are the meego work items linked to the task, they are set by the meego hook or the task args
+	MeegoWorkItems []*MeegoWorkItemLink `bson:"meego_work_items" yaml:"-" json:"meego_work_items,omitempty"`
+	// TriggerPause is set when all the triggers of the workflow are paused
+	TriggerPause *TriggerPause `bson:"trigger_pause" yaml:"-" json:"trigger_pause,omitempty"`
+}
+
+// TriggerPause records the triggers enabled before they were paused, only they are enabled on resume
+type TriggerPause struct {
+	PausedBy      string   `bson:"paused_by"      json:"paused_by"`
+	PauseTime     int64    `bson:"pause_time"     json:"pause_time"`
+	Reason        string   `bson:"reason"         json:"reason"`
+	Webhooks      []string `bson:"webhooks"       json:"webhooks"`
+	JiraHooks     []string `bson:"jira_hooks"     json:"jira_hooks"`
+	MeegoHooks    []string `bson:"meego_hooks"    json:"meego_hooks"`
+	GeneralHooks  []string `bson:"general_hooks"  json:"general_hooks"`
+	RegistryHooks []string `bson:"registry_hooks" json:"registry_hooks"`
+	// CronIDs are the ids of the enabled cronjobs
+	CronIDs []string `bson:"cron_ids" json:"cron_ids"`
 }
 
 func (w *WorkflowV4) UpdateHash() {
@@ -108,6 +130,17 @@ type Approval struct {
 	NativeApproval   *NativeApproval     `bson:"native_approval"             yaml:"native_approval,omitempty"     json:"native_approval,omitempty"`
 	LarkApproval     *LarkApproval       `bson:"lark_approval"               yaml:"lark_approval,omitempty"       json:"lark_approval,omitempty"`
 	DingTalkApproval *DingTalkApproval   `bson:"dingtalk_approval"           yaml:"dingtalk_approval,omitempty"   json:"dingtalk_approval,omitempty"`
+	Reminder         *ApprovalReminder   `bson:"reminder,omitempty"          yaml:"reminder,omitempty"            json:"reminder,omitempty"`
+}
+
+// ApprovalReminder reminds the pending approvers periodically and notifies the task creator
+// before the approval times out, the time units are minutes
+type ApprovalReminder struct {
+	Enabled  bool `bson:"enabled"                     yaml:"enabled"                    json:"enabled"`
+	Interval int  `bson:"interval"                    yaml:"interval"                   json:"interval"`
+	MaxCount int  `bson:"max_count"                   yaml:"max_count"                  json:"max_count"`
+	// TimeoutNoticeBefore: notify the task creator when the approval will time out within the minutes, 0 means no notice
+	TimeoutNoticeBefore int `bson:"timeout_notice_before"       yaml:"timeout_notice_before"      json:"timeout_notice_before"`
 }
 
 type NativeApproval struct {
@@ -117,6 +150,10 @@ type NativeApproval struct {
 	RejectOrApprove config.ApproveOrReject `bson:"reject_or_approve"           yaml:"-"                          json:"reject_or_approve"`
 	// InstanceCode: native approval instance code, save for working after restart aslan
 	InstanceCode string `bson:"instance_code"               yaml:"instance_code"              json:"instance_code"`
+	// LarkAppID: lark im app mongodb id, if set, the app sends the approvers a card to approve or reject in lark
+	LarkAppID string `bson:"lark_app_id,omitempty"       yaml:"lark_app_id,omitempty"      json:"lark_app_id,omitempty"`
+	// DingTalkAppID: dingtalk im app mongodb id, if set, the app sends the approvers a card to approve or reject in dingtalk
+	DingTalkAppID string `bson:"dingtalk_app_id,omitempty"   yaml:"dingtalk_app_id,omitempty"  json:"dingtalk_app_id,omitempty"`
 }
 
 type DingTalkApproval struct {
@@ -256,6 +293,38 @@ type FreestyleJobSpec struct {
 type ZadigBuildJobSpec struct {
 	DockerRegistryID string             `bson:"docker_registry_id"     yaml:"docker_registry_id"     json:"docker_registry_id"`
 	ServiceAndBuilds []*ServiceAndBuild `bson:"service_and_builds"     yaml:"service_and_builds"     json:"service_and_builds"`
+	UseBuildkit      bool               `bson:"use_buildkit"           yaml:"use_buildkit"           json:"use_buildkit"`
+	PublishPaths     []string           `bson:"publish_paths,omitempty" yaml:"publish_paths,omitempty" json:"publish_paths,omitempty"`
+	// Archs builds the image natively on the nodes of each arch and pushes the manifest list as the image,
+	// the image is built on the nodes of the build settings if it is empty
+	Archs []*BuildArch `bson:"archs,omitempty" yaml:"archs,omitempty" json:"archs,omitempty"`
+	// Versioning calculates the next semantic version of each build and exposes it as the VERSION output
+	Versioning *BuildVersioning `bson:"versioning,omitempty" yaml:"versioning,omitempty" json:"versioning,omitempty"`
+}
+
+const (
+	// BuildVersioningGitTag bumps the latest semver tag of the primary repo according to the conventional commit
+	// messages since the tag: major for breaking changes, minor for feat and patch for the others. The tag is not
+	// pushed, the version is suffixed with the build number instead, e.g. 1.3.0-build.2
+	BuildVersioningGitTag = "git_tag"
+	// BuildVersioningCounter increases the patch version by one on each build
+	BuildVersioningCounter = "counter"
+)
+
+type BuildVersioning struct {
+	Strategy string `bson:"strategy" yaml:"strategy" json:"strategy"`
+	// TagPrefix filters the semver tags of the repo and prefixes the version, e.g. v
+	TagPrefix string `bson:"tag_prefix" yaml:"tag_prefix" json:"tag_prefix"`
+	// InitialVersion is the version used when there is no version yet, 0.1.0 by default
+	InitialVersion string `bson:"initial_version" yaml:"initial_version" json:"initial_version"`
+	// UseAsImageTag tags the built images with the version instead of the default image tag rule
+	UseAsImageTag bool `bson:"use_as_image_tag" yaml:"use_as_image_tag" json:"use_as_image_tag"`
+}
+
+type BuildArch struct {
+	Arch string `bson:"arch"                  yaml:"arch"                  json:"arch"`
+	// StrategyID is the schedule strategy of the node pool of the arch, the one of the build by default
+	StrategyID string `bson:"strategy_id,omitempty" yaml:"strategy_id,omitempty" json:"strategy_id,omitempty"`
 }
 
 type ServiceAndBuild struct {
@@ -268,6 +337,8 @@ type ServiceAndBuild struct {
 	KeyVals          []*KeyVal           `bson:"key_vals"            yaml:"key_vals"         json:"key_vals"`
 	Repos            []*types.Repository `bson:"repos"               yaml:"repos"            json:"repos"`
 	ShareStorageInfo *ShareStorageInfo   `bson:"share_storage_info"   yaml:"share_storage_info"   json:"share_storage_info"`
+	// Version is calculated by the versioning of the job when the task is created
+	Version string `bson:"version,omitempty"   yaml:"-"                json:"version,omitempty"`
 }
 
 type ZadigDeployJobSpec struct {
@@ -275,6 +346,8 @@ type ZadigDeployJobSpec struct {
 	Production         bool   `bson:"production"               yaml:"production"                  json:"production"`
 	DeployType         string `bson:"deploy_type"              yaml:"deploy_type,omitempty"       json:"deploy_type"`
 	SkipCheckRunStatus bool   `bson:"skip_check_run_status"    yaml:"skip_check_run_status"       json:"skip_check_run_status"`
+	// SkipUnchanged skips deploying the services whose rendered manifests and images are the same as the live ones
+	SkipUnchanged bool `bson:"skip_unchanged"           yaml:"skip_unchanged"              json:"skip_unchanged"`
 	// fromjob/runtime, runtime 表示运行时输入，fromjob 表示从上游构建任务中获取
 	Source         config.DeploySourceType `bson:"source"     yaml:"source"     json:"source"`
 	DeployContents []config.DeployContent  `bson:"deploy_contents"     yaml:"deploy_contents"     json:"deploy_contents"`
@@ -284,6 +357,40 @@ type ZadigDeployJobSpec struct {
 	OriginJobName    string             `bson:"origin_job_name"      yaml:"origin_job_name"      json:"origin_job_name"`
 	ServiceAndImages []*ServiceAndImage `bson:"service_and_images"   yaml:"service_and_images"   json:"service_and_images"`
 	Services         []*DeployService   `bson:"services"             yaml:"services"             json:"services"`
+	// UpdateStrategy overrides how the workloads are updated, the strategy defined in the workloads is used if empty
+	UpdateStrategy *DeployUpdateStrategy `bson:"update_strategy,omitempty" yaml:"update_strategy,omitempty" json:"update_strategy,omitempty"`
+	// ConfirmProtected allows modifying the cluster resources protected in confirm mode
+	ConfirmProtected bool `bson:"confirm_protected" yaml:"confirm_protected" json:"confirm_protected"`
+	// RestartCheck fails the deployment if the containers restart too much right after the release
+	RestartCheck *DeployRestartCheck `bson:"restart_check,omitempty" yaml:"restart_check,omitempty" json:"restart_check,omitempty"`
+	// HealthCheck fails the deployment if the deployed workloads are not healthy after the release
+	HealthCheck *DeployHealthCheck `bson:"health_check,omitempty" yaml:"health_check,omitempty" json:"health_check,omitempty"`
+}
+
+type DeployHealthCheck struct {
+	// FailOnDegraded fails the deployment on the degraded workloads as well, such as the ones whose containers
+	// restarted during the deployment or whose hpa is not able to scale
+	FailOnDegraded bool `bson:"fail_on_degraded" yaml:"fail_on_degraded" json:"fail_on_degraded"`
+}
+
+type DeployRestartCheck struct {
+	// ObserveSeconds is how long the pods are observed after the workloads are ready
+	ObserveSeconds int `bson:"observe_seconds"  yaml:"observe_seconds"  json:"observe_seconds"`
+	// MaxRestarts is the max container restarts allowed since the deployment started
+	MaxRestarts   int32 `bson:"max_restarts"     yaml:"max_restarts"     json:"max_restarts"`
+	FailOnOOMKill bool  `bson:"fail_on_oom_kill" yaml:"fail_on_oom_kill" json:"fail_on_oom_kill"`
+}
+
+type DeployUpdateStrategy struct {
+	// Type, MaxSurge and MaxUnavailable only take effect on deployments,
+	// MaxSurge and MaxUnavailable are numbers or percentages like 25%
+	Type           config.DeployStrategyType `bson:"type"            yaml:"type"            json:"type"`
+	MaxSurge       string                    `bson:"max_surge"       yaml:"max_surge"       json:"max_surge"`
+	MaxUnavailable string                    `bson:"max_unavailable" yaml:"max_unavailable" json:"max_unavailable"`
+	// ForceRestart restarts the pods even if the workloads are not changed by the deployment, e.g. the image tag is unchanged
+	ForceRestart bool `bson:"force_restart"   yaml:"force_restart"   json:"force_restart"`
+	// RestartOnly restarts the pods of the selected service modules without changing anything
+	RestartOnly bool `bson:"restart_only"    yaml:"restart_only"    json:"restart_only"`
 }
 
 type ZadigHelmChartDeployJobSpec struct {
@@ -376,6 +483,7 @@ type ZadigTestingJobSpec struct {
 	TargetServices  []*ServiceTestTarget    `bson:"target_services"  yaml:"target_services"  json:"target_services"`
 	TestModules     []*TestModule           `bson:"test_modules"     yaml:"test_modules"     json:"test_modules"`
 	ServiceAndTests []*ServiceAndTest       `bson:"service_and_tests" yaml:"service_and_tests" json:"service_and_tests"`
+	PublishPaths    []string                `bson:"publish_paths,omitempty" yaml:"publish_paths,omitempty" json:"publish_paths,omitempty"`
 }
 
 type ServiceAndTest struct {
@@ -408,6 +516,36 @@ type ScanningModule struct {
 	ShareStorageInfo *ShareStorageInfo   `bson:"share_storage_info"   yaml:"share_storage_info"   json:"share_storage_info"`
 }
 
+// ZadigSonarJobSpec is a built-in sonarqube analysis, Parameter is the content of sonar-project.properties.
+// The job fails when CheckQualityGate is set and the gate is not passed, the gate result is posted back to
+// the pull request which triggered the workflow when PRDecoration is set.
+type ZadigSonarJobSpec struct {
+	Properties       *JobProperties      `bson:"properties"          yaml:"properties"          json:"properties"`
+	SonarID          string              `bson:"sonar_id"            yaml:"sonar_id"            json:"sonar_id"`
+	Repos            []*types.Repository `bson:"repos"               yaml:"repos"               json:"repos"`
+	PreScript        string              `bson:"pre_script"          yaml:"pre_script"          json:"pre_script"`
+	Parameter        string              `bson:"parameter"           yaml:"parameter"           json:"parameter"`
+	CheckQualityGate bool                `bson:"check_quality_gate"  yaml:"check_quality_gate"  json:"check_quality_gate"`
+	// GateTimeout is the max minutes to wait for the quality gate result
+	GateTimeout  int64 `bson:"gate_timeout"        yaml:"gate_timeout"        json:"gate_timeout"`
+	PRDecoration bool  `bson:"pr_decoration"       yaml:"pr_decoration"       json:"pr_decoration"`
+}
+
+// ZadigPerformanceTestJobSpec runs a k6 or JMeter load test, Script runs the test and writes the result to ResultPath.
+// The job fails when the error rate exceeds MaxErrorRate, or when the p95 latency or the throughput regresses more
+// than the given percentage from the run of the same job in BaselineTaskID.
+type ZadigPerformanceTestJobSpec struct {
+	Properties              *JobProperties      `bson:"properties"                 yaml:"properties"                 json:"properties"`
+	Tool                    string              `bson:"tool"                       yaml:"tool"                       json:"tool"`
+	Repos                   []*types.Repository `bson:"repos"                      yaml:"repos"                      json:"repos"`
+	Script                  string              `bson:"script"                     yaml:"script"                     json:"script"`
+	ResultPath              string              `bson:"result_path"                yaml:"result_path"                json:"result_path"`
+	BaselineTaskID          int64               `bson:"baseline_task_id"           yaml:"baseline_task_id"           json:"baseline_task_id"`
+	MaxErrorRate            float64             `bson:"max_error_rate"             yaml:"max_error_rate"             json:"max_error_rate"`
+	MaxLatencyRegression    float64             `bson:"max_latency_regression"     yaml:"max_latency_regression"     json:"max_latency_regression"`
+	MaxThroughputRegression float64             `bson:"max_throughput_regression"  yaml:"max_throughput_regression"  json:"max_throughput_regression"`
+}
+
 type BlueGreenDeployJobSpec struct {
 	ClusterID        string             `bson:"cluster_id"             json:"cluster_id"            yaml:"cluster_id"`
 	Namespace        string             `bson:"namespace"              json:"namespace"             yaml:"namespace"`
@@ -471,6 +609,29 @@ type CanaryDeployJobSpec struct {
 	Namespace        string          `bson:"namespace"              json:"namespace"             yaml:"namespace"`
 	DockerRegistryID string          `bson:"docker_registry_id"     json:"docker_registry_id"    yaml:"docker_registry_id"`
 	Targets          []*CanaryTarget `bson:"targets"                json:"targets"               yaml:"targets"`
+	// Analysis is optional, the canary is promoted or rolled back automatically by the analysis if it is set
+	Analysis *CanaryAnalysis `bson:"analysis,omitempty"     json:"analysis,omitempty"    yaml:"analysis,omitempty"`
+}
+
+// CanaryAnalysis compares the canary with the baseline by the prometheus metrics once the canary is ready
+type CanaryAnalysis struct {
+	ObservabilityID string `bson:"observability_id"  json:"observability_id"  yaml:"observability_id"`
+	// IntervalSeconds is the interval between two analysis runs, it is also the range of $interval in the queries
+	IntervalSeconds int `bson:"interval_seconds"  json:"interval_seconds"  yaml:"interval_seconds"`
+	Iterations      int `bson:"iterations"        json:"iterations"        yaml:"iterations"`
+	// MaxFailures is the failed runs allowed before the canary is rolled back
+	MaxFailures int                `bson:"max_failures"      json:"max_failures"      yaml:"max_failures"`
+	Criteria    []*CanaryCriterion `bson:"criteria"          json:"criteria"          yaml:"criteria"`
+}
+
+type CanaryCriterion struct {
+	Name string `bson:"name"                            json:"name"                            yaml:"name"`
+	// Query is run against both the canary and the baseline, $namespace, $workload and $interval are replaced before querying
+	Query string `bson:"query"                           json:"query"                           yaml:"query"`
+	// MaxValue is the max value allowed for the canary
+	MaxValue *float64 `bson:"max_value,omitempty"             json:"max_value,omitempty"             yaml:"max_value,omitempty"`
+	// MaxBaselineIncrease is the max increase allowed for the canary over the baseline, in percentage
+	MaxBaselineIncrease *float64 `bson:"max_baseline_increase,omitempty" json:"max_baseline_increase,omitempty" yaml:"max_baseline_increase,omitempty"`
 }
 
 type CanaryReleaseJobSpec struct {
@@ -499,6 +660,9 @@ type GrayReleaseJobSpec struct {
 	DeployTimeout int64                `bson:"deploy_timeout"         json:"deploy_timeout"        yaml:"deploy_timeout"`
 	GrayScale     int                  `bson:"gray_scale"             json:"gray_scale"            yaml:"gray_scale"`
 	Targets       []*GrayReleaseTarget `bson:"targets"                json:"targets"               yaml:"targets"`
+	// Analysis is optional, the gray workloads are compared with the origin ones after a partial release and the
+	// release is rolled back if the analysis fails
+	Analysis *CanaryAnalysis `bson:"analysis,omitempty"     json:"analysis,omitempty"    yaml:"analysis,omitempty"`
 }
 
 type GrayReleaseTarget struct {
@@ -567,6 +731,10 @@ type IstioJobSpec struct {
 	ReplicaPercentage int64             `bson:"replica_percentage" json:"replica_percentage" yaml:"replica_percentage"`
 	Weight            int64             `bson:"weight"             json:"weight"             yaml:"weight"`
 	Targets           []*IstioJobTarget `bson:"targets"            json:"targets"            yaml:"targets"`
+	// MirrorPercentage is the percentage of the traffic copied to the new version, the responses of the copies are
+	// discarded so users are not affected. It works along with the weight, set the weight to 0 to only mirror the traffic.
+	// The mirror is kept by the later release jobs that leave it 0, and removed on the full release or the rollback.
+	MirrorPercentage int64 `bson:"mirror_percentage,omitempty" json:"mirror_percentage,omitempty" yaml:"mirror_percentage,omitempty"`
 }
 
 type IstioRollBackJobSpec struct {
@@ -603,6 +771,26 @@ type MeegoTransitionJobSpec struct {
 	WorkItems           []*MeegoWorkItemTransition `bson:"work_items"            json:"work_items"            yaml:"work_items"`
 }
 
+type MeegoSyncConfig struct {
+	Enabled bool   `bson:"enabled"  json:"enabled"  yaml:"enabled"`
+	MeegoID string `bson:"meego_id" json:"meego_id" yaml:"meego_id"`
+	// PassedStateKey and FailedStateKey are the states the work items are transitioned to when the task
+	// passed or not, empty means the work items are not transitioned
+	PassedStateKey string `bson:"passed_state_key" json:"passed_state_key" yaml:"passed_state_key"`
+	FailedStateKey string `bson:"failed_state_key" json:"failed_state_key" yaml:"failed_state_key"`
+	// Comment appends a comment of the task result and the deployed services to the work items
+	Comment bool `bson:"comment" json:"comment" yaml:"comment"`
+}
+
+type MeegoWorkItemLink struct {
+	// MeegoID is the meego integration of the work item, empty means the one of the meego sync
+	MeegoID         string `bson:"meego_id"           json:"meego_id"           yaml:"meego_id"`
+	ProjectKey      string `bson:"project_key"        json:"project_key"        yaml:"project_key"`
+	WorkItemTypeKey string `bson:"work_item_type_key" json:"work_item_type_key" yaml:"work_item_type_key"`
+	ID              int64  `bson:"id"                 json:"id"                 yaml:"id"`
+	Name            string `bson:"name"               json:"name"               yaml:"name"`
+}
+
 type MeegoWorkItemTransition struct {
 	ID              int    `bson:"id"                json:"id"                yaml:"id"`
 	Name            string `bson:"name"              json:"name"              yaml:"name"`
@@ -640,6 +828,119 @@ type GuanceyunMonitor struct {
 	Url    string          `bson:"url,omitempty" json:"url,omitempty" yaml:"url,omitempty"`
 }
 
+type HarborReplicationJobSpec struct {
+	// RegistryID is the id of the harbor registry integration
+	RegistryID string `bson:"registry_id" json:"registry_id" yaml:"registry_id"`
+	PolicyName string `bson:"policy_name" json:"policy_name" yaml:"policy_name"`
+	// Timeout minute
+	Timeout int64 `bson:"timeout" json:"timeout" yaml:"timeout"`
+	// RetentionProject is the harbor project to run the tag retention after the replication, empty means no retention
+	RetentionProject string `bson:"retention_project" json:"retention_project" yaml:"retention_project"`
+}
+
+type StaticDistributeJobSpec struct {
+	// JobName is the build job whose archived packages are distributed
+	JobName string `bson:"job_name" json:"job_name" yaml:"job_name"`
+	// ObjectStorageID is the object storage integration which the files are uploaded to
+	ObjectStorageID string                    `bson:"object_storage_id" json:"object_storage_id" yaml:"object_storage_id"`
+	Targets         []*StaticDistributeTarget `bson:"targets"           json:"targets"           yaml:"targets"`
+	CacheControl    string                    `bson:"cache_control"     json:"cache_control"     yaml:"cache_control"`
+	// CacheControlRules override the cache control of the files matching the pattern, the first matched rule is used
+	CacheControlRules []*CacheControlRule `bson:"cache_control_rules" json:"cache_control_rules" yaml:"cache_control_rules"`
+	CDNPurge          *CDNPurgeConfig     `bson:"cdn_purge"           json:"cdn_purge"           yaml:"cdn_purge"`
+}
+
+type StaticDistributeTarget struct {
+	ServiceName   string `bson:"service_name"   json:"service_name"   yaml:"service_name"`
+	ServiceModule string `bson:"service_module" json:"service_module" yaml:"service_module"`
+	// SourcePath is the directory in the extracted package to upload, empty means the whole package
+	SourcePath string `bson:"source_path" json:"source_path" yaml:"source_path"`
+	DestPath   string `bson:"dest_path"   json:"dest_path"   yaml:"dest_path"`
+}
+
+type CacheControlRule struct {
+	// Pattern is matched against the file path relative to the dest path, in the syntax of path.Match
+	Pattern      string `bson:"pattern"       json:"pattern"       yaml:"pattern"`
+	CacheControl string `bson:"cache_control" json:"cache_control" yaml:"cache_control"`
+}
+
+// CDNPurgeConfig purges the cache of the cdn after the files are uploaded, CloudFront uses the credential
+// of the object storage.
+type CDNPurgeConfig struct {
+	Enabled        bool     `bson:"enabled"         json:"enabled"         yaml:"enabled"`
+	Provider       string   `bson:"provider"        json:"provider"        yaml:"provider"`
+	DistributionID string   `bson:"distribution_id" json:"distribution_id" yaml:"distribution_id"`
+	WebhookURL     string   `bson:"webhook_url"     json:"webhook_url"     yaml:"webhook_url"`
+	Paths          []string `bson:"paths"           json:"paths"           yaml:"paths"`
+}
+
+// HTTPCallbackJobSpec sends a request to the downstream system, the workflow variables in the url, headers
+// and body are rendered before the request is sent.
+type HTTPCallbackJobSpec struct {
+	Method  string `bson:"method"  json:"method"  yaml:"method"`
+	URL     string `bson:"url"     json:"url"     yaml:"url"`
+	Headers []*KV  `bson:"headers" json:"headers" yaml:"headers"`
+	Body    string `bson:"body"    json:"body"    yaml:"body"`
+	// Timeout is the timeout seconds of each request
+	Timeout int64 `bson:"timeout" json:"timeout" yaml:"timeout"`
+	// Retry is the retry times after the first request fails, the interval doubles from RetryInterval seconds
+	Retry              int                    `bson:"retry"                json:"retry"                yaml:"retry"`
+	RetryInterval      int64                  `bson:"retry_interval"       json:"retry_interval"       yaml:"retry_interval"`
+	InsecureSkipVerify bool                   `bson:"insecure_skip_verify" json:"insecure_skip_verify" yaml:"insecure_skip_verify"`
+	Assertion          *HTTPCallbackAssertion `bson:"assertion"            json:"assertion"            yaml:"assertion"`
+	// Outputs are extracted from the json response by the gjson path
+	Outputs []*HTTPCallbackOutput `bson:"outputs" json:"outputs" yaml:"outputs"`
+}
+
+// HTTPCallbackAssertion is checked against the response, the request is retried if it fails
+type HTTPCallbackAssertion struct {
+	// StatusCodes are the expected status codes, any 2xx status code is expected if it is empty
+	StatusCodes []int `bson:"status_codes" json:"status_codes" yaml:"status_codes"`
+	// BodyContains is a string the response body must contain
+	BodyContains string `bson:"body_contains" json:"body_contains" yaml:"body_contains"`
+	// JSONPath is a gjson path of the response whose value must equal to JSONValue
+	JSONPath  string `bson:"json_path"  json:"json_path"  yaml:"json_path"`
+	JSONValue string `bson:"json_value" json:"json_value" yaml:"json_value"`
+}
+
+type HTTPCallbackOutput struct {
+	Name     string `bson:"name"      json:"name"      yaml:"name"`
+	JSONPath string `bson:"json_path" json:"json_path" yaml:"json_path"`
+}
+
+type FeatureFlagJobSpec struct {
+	// ExternalSystemID is the id of the external system integration of the flag service, the credentials are set in
+	// its headers
+	ExternalSystemID string               `bson:"external_system_id" json:"external_system_id" yaml:"external_system_id"`
+	Provider         string               `bson:"provider"           json:"provider"           yaml:"provider"`
+	Flags            []*FeatureFlagChange `bson:"flags"              json:"flags"              yaml:"flags"`
+}
+
+type FeatureFlagChange struct {
+	// Project is the project of the flag in unleash
+	Project     string `bson:"project"     json:"project"     yaml:"project"`
+	Environment string `bson:"environment" json:"environment" yaml:"environment"`
+	Flag        string `bson:"flag"        json:"flag"        yaml:"flag"`
+	Enabled     bool   `bson:"enabled"     json:"enabled"     yaml:"enabled"`
+	// Rollout is the percentage of users the flag is enabled for, nil keeps the current rollout of the flag
+	Rollout *int `bson:"rollout,omitempty" json:"rollout,omitempty" yaml:"rollout,omitempty"`
+}
+
+// FeatureFlagRollbackJobSpec restores the flags to the states before they were last changed by the feature flag jobs
+type FeatureFlagRollbackJobSpec struct {
+	ExternalSystemID string               `bson:"external_system_id" json:"external_system_id" yaml:"external_system_id"`
+	Provider         string               `bson:"provider"           json:"provider"           yaml:"provider"`
+	Flags            []*FeatureFlagTarget `bson:"flags"              json:"flags"              yaml:"flags"`
+}
+
+type FeatureFlagTarget struct {
+	Project     string `bson:"project"     json:"project"     yaml:"project"`
+	Environment string `bson:"environment" json:"environment" yaml:"environment"`
+	Flag        string `bson:"flag"        json:"flag"        yaml:"flag"`
+}
+
+// MseGrayReleaseJobSpec has no traffic mirroring like IstioJobSpec, the traffic of the gray tag is routed by the MSE
+// governance rules which are managed in MSE rather than zadig.
 type MseGrayReleaseJobSpec struct {
 	Production         bool                     `bson:"production" json:"production" yaml:"production"`
 	GrayTag            string                   `bson:"gray_tag" json:"gray_tag" yaml:"gray_tag"`
@@ -725,10 +1026,12 @@ type JobProperties struct {
 	ClusterID       string              `bson:"cluster_id"             json:"cluster_id"            yaml:"cluster_id"`
 	StrategyID      string              `bson:"strategy_id"            json:"strategy_id"           yaml:"strategy_id"`
 	BuildOS         string              `bson:"build_os"               json:"build_os"              yaml:"build_os,omitempty"`
-	ImageFrom       string              `bson:"image_from"             json:"image_from"            yaml:"image_from,omitempty"`
-	ImageID         string              `bson:"image_id"               json:"image_id"              yaml:"image_id,omitempty"`
-	Namespace       string              `bson:"namespace"              json:"namespace"             yaml:"namespace"`
-	Envs            []*KeyVal           `bson:"envs"                   json:"envs"                  yaml:"envs"`
+	// OS is the os of the basic image, windows jobs run on the windows nodes
+	OS        string    `bson:"os,omitempty"           json:"os,omitempty"          yaml:"os,omitempty"`
+	ImageFrom string    `bson:"image_from"             json:"image_from"            yaml:"image_from,omitempty"`
+	ImageID   string    `bson:"image_id"               json:"image_id"              yaml:"image_id,omitempty"`
+	Namespace string    `bson:"namespace"              json:"namespace"             yaml:"namespace"`
+	Envs      []*KeyVal `bson:"envs"                   json:"envs"                  yaml:"envs"`
 	// log user-defined variables, shows in workflow task detail.
 	CustomEnvs          []*KeyVal            `bson:"custom_envs"            json:"custom_envs"           yaml:"custom_envs,omitempty"`
 	Params              []*Param             `bson:"params"                 json:"params"                yaml:"params"`
@@ -740,9 +1043,75 @@ type JobProperties struct {
 	CacheEnable         bool                 `bson:"cache_enable"           json:"cache_enable"          yaml:"cache_enable"`
 	CacheDirType        types.CacheDirType   `bson:"cache_dir_type"         json:"cache_dir_type"        yaml:"cache_dir_type"`
 	CacheUserDir        string               `bson:"cache_user_dir"         json:"cache_user_dir"        yaml:"cache_user_dir"`
+	CacheRules          []*types.CacheRule   `bson:"cache_rules,omitempty"  json:"cache_rules,omitempty" yaml:"cache_rules,omitempty"`
 	ShareStorageInfo    *ShareStorageInfo    `bson:"share_storage_info"     json:"share_storage_info"    yaml:"share_storage_info"`
 	ShareStorageDetails []*StorageDetail     `bson:"share_storage_details"  json:"share_storage_details" yaml:"-"`
 	UseHostDockerDaemon bool                 `bson:"use_host_docker_daemon,omitempty" json:"use_host_docker_daemon,omitempty" yaml:"use_host_docker_daemon"`
+	SchedulingHints     *SchedulingHints     `bson:"scheduling_hints,omitempty"      json:"scheduling_hints,omitempty"       yaml:"scheduling_hints,omitempty"`
+	ServiceContainers   []*ServiceContainer  `bson:"service_containers,omitempty"    json:"service_containers,omitempty"     yaml:"service_containers,omitempty"`
+	// RetainPodOnFailure keeps the job pod alive for RetainPodTTL minutes after the job fails so that the workspace can be inspected
+	RetainPodOnFailure bool  `bson:"retain_pod_on_failure,omitempty" json:"retain_pod_on_failure,omitempty" yaml:"retain_pod_on_failure,omitempty"`
+	RetainPodTTL       int64 `bson:"retain_pod_ttl,omitempty"        json:"retain_pod_ttl,omitempty"        yaml:"retain_pod_ttl,omitempty"`
+	// PublishPaths are the paths relative to the workspace uploaded to the object storage after the job, they can be browsed in the task
+	PublishPaths []string `bson:"publish_paths,omitempty" json:"publish_paths,omitempty" yaml:"publish_paths,omitempty"`
+	// FileOutputs are uploaded after the job succeeds, the later jobs get them by declaring FileInputs
+	FileOutputs []*JobFileOutput `bson:"file_outputs,omitempty" json:"file_outputs,omitempty" yaml:"file_outputs,omitempty"`
+	FileInputs  []*JobFileInput  `bson:"file_inputs,omitempty"  json:"file_inputs,omitempty"  yaml:"file_inputs,omitempty"`
+	// Infrastructure is kubernetes by default, jobs on vm are picked up by the vm agents having all the VMLabels
+	Infrastructure string   `bson:"infrastructure,omitempty" json:"infrastructure,omitempty" yaml:"infrastructure,omitempty"`
+	VMLabels       []string `bson:"vm_labels,omitempty"      json:"vm_labels,omitempty"      yaml:"vm_labels,omitempty"`
+	// WorkloadIdentity issues the job pod a short-lived OIDC token identifying the project, workflow and task
+	WorkloadIdentity *WorkloadIdentity `bson:"workload_identity,omitempty" json:"workload_identity,omitempty" yaml:"workload_identity,omitempty"`
+}
+
+// WorkloadIdentity lets the job authenticate to the cloud providers and registries trusting the zadig OIDC issuer
+// instead of the static credentials
+type WorkloadIdentity struct {
+	Enabled bool `bson:"enabled" json:"enabled" yaml:"enabled"`
+	// Audience is the aud claim of the token, it is sts.amazonaws.com if AWSRoleARN is set, otherwise the issuer
+	Audience string `bson:"audience" json:"audience" yaml:"audience"`
+	// AWSRoleARN is exported as AWS_ROLE_ARN along with AWS_WEB_IDENTITY_TOKEN_FILE so that the aws sdks assume the role
+	AWSRoleARN string `bson:"aws_role_arn" json:"aws_role_arn" yaml:"aws_role_arn"`
+}
+
+// JobFileOutput is a file or directory of the workspace passed to the later jobs of the workflow task
+type JobFileOutput struct {
+	Name string `bson:"name" json:"name" yaml:"name"`
+	Path string `bson:"path" json:"path" yaml:"path"`
+}
+
+// JobFileInput downloads the file output Name of the job JobName and extracts it into Path of the workspace,
+// an empty Path means the workspace itself
+type JobFileInput struct {
+	JobName string `bson:"job_name" json:"job_name" yaml:"job_name"`
+	Name    string `bson:"name"     json:"name"     yaml:"name"`
+	Path    string `bson:"path"     json:"path"     yaml:"path"`
+}
+
+// ServiceContainer is an auxiliary service like mysql or redis running alongside the job container in the job pod,
+// it is reachable via localhost and torn down together with the job pod.
+// The job steps wait until ReadinessPort accepts connections, or ReadinessPath returns 2xx if it is set.
+type ServiceContainer struct {
+	Name             string    `bson:"name"              json:"name"              yaml:"name"`
+	Image            string    `bson:"image"             json:"image"             yaml:"image"`
+	Command          []string  `bson:"command"           json:"command"           yaml:"command"`
+	Args             []string  `bson:"args"              json:"args"              yaml:"args"`
+	Envs             []*KeyVal `bson:"envs"              json:"envs"              yaml:"envs"`
+	ReadinessPort    int       `bson:"readiness_port"    json:"readiness_port"    yaml:"readiness_port"`
+	ReadinessPath    string    `bson:"readiness_path"    json:"readiness_path"    yaml:"readiness_path"`
+	ReadinessTimeout int64     `bson:"readiness_timeout" json:"readiness_timeout" yaml:"readiness_timeout"` // in seconds
+}
+
+// SchedulingHints are applied to the job pod on top of the schedule strategy of the cluster.
+// Tolerations is a yaml list of kubernetes tolerations, same as the one in ScheduleStrategy.
+// SpotNodeLabels select the spot/preemptible nodes, SpotMode preferred falls back to other nodes
+// when no spot node is available while required never does.
+type SchedulingHints struct {
+	NodeSelector      map[string]string          `bson:"node_selector"       json:"node_selector"       yaml:"node_selector"`
+	Tolerations       string                     `bson:"tolerations"         json:"tolerations"         yaml:"tolerations"`
+	PriorityClassName string                     `bson:"priority_class_name" json:"priority_class_name" yaml:"priority_class_name"`
+	SpotMode          string                     `bson:"spot_mode"           json:"spot_mode"           yaml:"spot_mode"`
+	SpotNodeLabels    []*NodeSelectorRequirement `bson:"spot_node_labels"    json:"spot_node_labels"    yaml:"spot_node_labels"`
 }
 
 type Step struct {
@@ -750,11 +1119,25 @@ type Step struct {
 	Timeout  int64           `bson:"timeout"        json:"timeout"          yaml:"timeout"`
 	StepType config.StepType `bson:"type"           json:"type"             yaml:"type"`
 	Spec     interface{}     `bson:"spec"           json:"spec"             yaml:"spec"`
-}
+	// Inputs are the variables only visible to the step
+	Inputs []*KeyVal `bson:"inputs"         json:"inputs"           yaml:"inputs"`
+	// Outputs are written by the shell step, they are visible to the following steps and are outputs of the job
+	Outputs []*Output `bson:"outputs"        json:"outputs"          yaml:"outputs"`
+}
+
+// output types, an output without type is a string
+const (
+	OutputTypeString  = "string"
+	OutputTypeNumber  = "number"
+	OutputTypeBoolean = "boolean"
+	OutputTypeJSON    = "json"
+	OutputTypeImage   = "image"
+)
 
 type Output struct {
 	Name        string `bson:"name"           json:"name"             yaml:"name"`
 	Description string `bson:"description"    json:"description"      yaml:"description"`
+	Type        string `bson:"type,omitempty" json:"type,omitempty"   yaml:"type,omitempty"`
 }
 
 type WorkflowV4Hook struct {
@@ -767,6 +1150,22 @@ type WorkflowV4Hook struct {
 	Repos               []*types.Repository `bson:"-"                         json:"repos,omitempty"`
 	IsManual            bool                `bson:"is_manual"                 json:"is_manual"`
 	WorkflowArg         *WorkflowV4         `bson:"workflow_arg"              json:"workflow_arg"`
+
+	// GerritVotes are the labels voted on the gerrit change when the triggered task finishes
+	GerritVotes []*GerritVote `bson:"gerrit_votes,omitempty" json:"gerrit_votes,omitempty"`
+	// RetestComment is the comment on a gerrit change triggering the workflow on the comment-added event,
+	// "retest" is used if it is empty
+	RetestComment string `bson:"retest_comment,omitempty" json:"retest_comment,omitempty"`
+	// IPAllowlist are the CIDRs or IP addresses allowed to trigger the hook, the global trigger allowlist is used
+	// if it is empty
+	IPAllowlist []string `bson:"ip_allowlist,omitempty" json:"ip_allowlist,omitempty"`
+}
+
+// GerritVote is the score of a gerrit label, e.g. Verified +1 when the task passed and -1 when it failed
+type GerritVote struct {
+	Label       string `bson:"label"        json:"label"`
+	PassedScore string `bson:"passed_score" json:"passed_score"`
+	FailedScore string `bson:"failed_score" json:"failed_score"`
 }
 
 type JiraHook struct {
@@ -774,6 +1173,20 @@ type JiraHook struct {
 	Enabled     bool        `bson:"enabled" json:"enabled"`
 	Description string      `bson:"description" json:"description"`
 	WorkflowArg *WorkflowV4 `bson:"workflow_arg" json:"workflow_arg"`
+
+	// JiraID is the jira integration used to run the JQL, the default jira integration is used if it is empty
+	JiraID string `bson:"jira_id,omitempty" json:"jira_id,omitempty"`
+	// JQL filters the issues triggering the workflow, the issue of the event must match it if it is set
+	JQL string `bson:"jql,omitempty" json:"jql,omitempty"`
+	// FieldMappings maps the fields of the event into the workflow params
+	FieldMappings []*JiraFieldMapping `bson:"field_mappings,omitempty" json:"field_mappings,omitempty"`
+}
+
+// JiraFieldMapping sets the value at the JSONPath of the jira webhook payload to the workflow param,
+// e.g. issue.fields.fixVersions.0.name or issue.fields.components.#.name, the values of an array are joined by commas
+type JiraFieldMapping struct {
+	JSONPath string `bson:"json_path" json:"json_path"`
+	Param    string `bson:"param"     json:"param"`
 }
 
 type MeegoHook struct {
@@ -791,6 +1204,29 @@ type GeneralHook struct {
 	Enabled     bool        `bson:"enabled" json:"enabled"`
 	Description string      `bson:"description" json:"description"`
 	WorkflowArg *WorkflowV4 `bson:"workflow_arg" json:"workflow_arg"`
+	// IPAllowlist are the CIDRs or IP addresses allowed to call the hook, the global trigger allowlist is used if
+	// it is empty
+	IPAllowlist []string `bson:"ip_allowlist,omitempty" json:"ip_allowlist,omitempty"`
+}
+
+// RegistryHook triggers the workflow when an image matching the tag pattern is pushed to the registry,
+// the repository, tag and full image of the pushed image can be mapped into the workflow params
+type RegistryHook struct {
+	Name        string `bson:"name"         json:"name"`
+	Enabled     bool   `bson:"enabled"      json:"enabled"`
+	Description string `bson:"description"  json:"description"`
+	// support harbor/acr/dockerhub
+	Source string `bson:"source"       json:"source"`
+	// Secret is compared with the auth header configured in harbor or the token in the query of the webhook url,
+	// it is generated when the hook is created without one
+	Secret string `bson:"secret"       json:"secret"`
+	// Repository is the repository of the image without the registry address, e.g. library/nginx
+	Repository  string      `bson:"repository"   json:"repository"`
+	TagPattern  string      `bson:"tag_pattern"  json:"tag_pattern"`
+	RepoParam   string      `bson:"repo_param"   json:"repo_param"`
+	TagParam    string      `bson:"tag_param"    json:"tag_param"`
+	ImageParam  string      `bson:"image_param"  json:"image_param"`
+	WorkflowArg *WorkflowV4 `bson:"workflow_arg" json:"workflow_arg"`
 }
 
 type Param struct {
@@ -850,4 +1286,23 @@ type CustomField struct {
 	DeployServiceComponent map[string]int `bson:"deploy_service_component,omitempty"  json:"deploy_service_component,omitempty"`
 	DeployEnv              map[string]int `bson:"deploy_env,omitempty"                json:"deploy_env,omitempty"`
 	TestResult             map[string]int `bson:"test_result,omitempty"               json:"test_result,omitempty"`
+	// UserDefined: free-form columns whose values are read from the variables of the task
+	UserDefined []*UserDefinedField `bson:"user_defined,omitempty"              json:"user_defined,omitempty"`
+}
+
+const (
+	CustomFieldTypeText   = "text"
+	CustomFieldTypeNumber = "number"
+	CustomFieldTypeLink   = "link"
+	CustomFieldTypeTime   = "time"
+)
+
+// UserDefinedField is a custom column of workflow history tasks, Source is a workflow variable
+// such as {{.workflow.params.version}} or {{.job.build.output.IMAGE}}
+type UserDefinedField struct {
+	Key     string `bson:"key"                                 json:"key"`
+	Label   string `bson:"label"                               json:"label"`
+	Type    string `bson:"type"                                json:"type"`
+	Source  string `bson:"source"                              json:"source"`
+	Enabled int    `bson:"enabled"                             json:"enabled"`
 }
@@ -0,0 +1,38 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// WorkflowV4Trash keeps a deleted workflow until ExpireTime, its tasks and counter are kept in place so that
+// the workflow can be restored
+type WorkflowV4Trash struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	WorkflowName string             `bson:"workflow_name" json:"workflow_name"`
+	DisplayName  string             `bson:"display_name"  json:"display_name"`
+	ProjectName  string             `bson:"project_name"  json:"project_name"`
+	Workflow     *WorkflowV4        `bson:"workflow"      json:"-"`
+	// TriggersPaused is true if the triggers were paused by the deletion, they are resumed on restore
+	TriggersPaused bool   `bson:"triggers_paused" json:"triggers_paused"`
+	DeletedBy      string `bson:"deleted_by"      json:"deleted_by"`
+	DeleteTime     int64  `bson:"delete_time"     json:"delete_time"`
+	ExpireTime     int64  `bson:"expire_time"     json:"expire_time"`
+}
+
+func (WorkflowV4Trash) TableName() string {
+	return "workflow_v4_trash"
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+// AgentRolloutLockRunning is the lock of the running rollout
+const AgentRolloutLockRunning = "running"
+
+type AgentRolloutColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewAgentRolloutColl() *AgentRolloutColl {
+	name := models.AgentRollout{}.TableName()
+	return &AgentRolloutColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *AgentRolloutColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *AgentRolloutColl) EnsureIndex(ctx context.Context) error {
+	mods := []mongo.IndexModel{
+		{
+			Keys:    bson.M{"lock": 1},
+			Options: options.Index().SetUnique(true).SetSparse(true),
+		},
+		{
+			Keys: bson.M{"start_time": -1},
+		},
+	}
+
+	_, err := c.Indexes().CreateMany(ctx, mods)
+	return err
+}
+
+// Create fails with a duplicate key error if another rollout is running
+func (c *AgentRolloutColl) Create(rollout *models.AgentRollout) error {
+	rollout.Lock = AgentRolloutLockRunning
+	res, err := c.InsertOne(context.TODO(), rollout)
+	if err != nil {
+		return err
+	}
+	rollout.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (c *AgentRolloutColl) GetLatest() (*models.AgentRollout, error) {
+	resp := new(models.AgentRollout)
+	opts := options.FindOne().SetSort(bson.M{"start_time": -1})
+	err := c.FindOne(context.TODO(), bson.M{}, opts).Decode(resp)
+	return resp, err
+}
+
+func (c *AgentRolloutColl) GetRunning() (*models.AgentRollout, error) {
+	resp := new(models.AgentRollout)
+	err := c.FindOne(context.TODO(), bson.M{"lock": AgentRolloutLockRunning}).Decode(resp)
+	return resp, err
+}
+
+// Update saves the progress of the running rollout, the lock is released once the rollout is not running.
+// A rollout finished as stale is not updated any more
+func (c *AgentRolloutColl) Update(rollout *models.AgentRollout) error {
+	change := bson.M{"$set": bson.M{
+		"status":      rollout.Status,
+		"end_time":    rollout.EndTime,
+		"clusters":    rollout.Clusters,
+		"update_time": rollout.UpdateTime,
+	}}
+	if rollout.Lock == "" {
+		change["$unset"] = bson.M{"lock": ""}
+	}
+	_, err := c.UpdateOne(context.TODO(), bson.M{"_id": rollout.ID, "lock": AgentRolloutLockRunning}, change)
+	return err
+}
+
+// FinishStale saves the finished progress of a stale rollout left by a stopped replica, it returns false if the
+// rollout has been refreshed since updateTime or finished in the meantime
+func (c *AgentRolloutColl) FinishStale(rollout *models.AgentRollout, updateTime int64) (bool, error) {
+	query := bson.M{"_id": rollout.ID, "lock": AgentRolloutLockRunning, "update_time": updateTime}
+	change := bson.M{
+		"$set": bson.M{
+			"status":      rollout.Status,
+			"end_time":    rollout.EndTime,
+			"clusters":    rollout.Clusters,
+			"update_time": rollout.UpdateTime,
+		},
+		"$unset": bson.M{"lock": ""},
+	}
+	res, err := c.UpdateOne(context.TODO(), query, change)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type ApprovalRecordColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewApprovalRecordColl() *ApprovalRecordColl {
+	name := models.ApprovalRecord{}.TableName()
+	return &ApprovalRecordColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *ApprovalRecordColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *ApprovalRecordColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			// the unique seq keeps the chain linear when the records are appended concurrently
+			Keys:    bson.D{bson.E{Key: "seq", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{bson.E{Key: "create_time", Value: 1}},
+			Options: options.Index().SetUnique(false),
+		},
+	}
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+func (c *ApprovalRecordColl) Create(args *models.ApprovalRecord) error {
+	_, err := c.InsertOne(context.TODO(), args)
+	return err
+}
+
+// GetLatest returns the record with the largest seq, it returns mongo.ErrNoDocuments if there is no record
+func (c *ApprovalRecordColl) GetLatest() (*models.ApprovalRecord, error) {
+	resp := new(models.ApprovalRecord)
+	err := c.FindOne(context.TODO(), bson.M{}, options.FindOne().SetSort(bson.D{{"seq", -1}})).Decode(resp)
+	return resp, err
+}
+
+func (c *ApprovalRecordColl) GetBySeq(seq int64) (*models.ApprovalRecord, error) {
+	resp := new(models.ApprovalRecord)
+	err := c.FindOne(context.TODO(), bson.M{"seq": seq}).Decode(resp)
+	return resp, err
+}
+
+// ListByCreateTime lists the records created in [startTime, endTime] ordered by seq
+func (c *ApprovalRecordColl) ListByCreateTime(startTime, endTime int64) ([]*models.ApprovalRecord, error) {
+	resp := make([]*models.ApprovalRecord, 0)
+	query := bson.M{"create_time": bson.M{"$gte": startTime, "$lte": endTime}}
+	cursor, err := c.Find(context.TODO(), query, options.Find().SetSort(bson.D{{"seq", 1}}))
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
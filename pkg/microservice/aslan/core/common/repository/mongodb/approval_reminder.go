@@ -0,0 +1,93 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type ApprovalReminderRecordColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewApprovalReminderRecordColl() *ApprovalReminderRecordColl {
+	name := models.ApprovalReminderRecord{}.TableName()
+	return &ApprovalReminderRecordColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *ApprovalReminderRecordColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *ApprovalReminderRecordColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "workflow_name", Value: 1},
+			bson.E{Key: "task_id", Value: 1},
+			bson.E{Key: "stage_name", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *ApprovalReminderRecordColl) Find(workflowName string, taskID int64, stageName string) (*models.ApprovalReminderRecord, error) {
+	query := bson.M{"workflow_name": workflowName, "task_id": taskID, "stage_name": stageName}
+	resp := &models.ApprovalReminderRecord{}
+	return resp, c.FindOne(context.TODO(), query).Decode(resp)
+}
+
+func (c *ApprovalReminderRecordColl) Upsert(args *models.ApprovalReminderRecord) error {
+	query := bson.M{"workflow_name": args.WorkflowName, "task_id": args.TaskID, "stage_name": args.StageName}
+	change := bson.M{"$set": bson.M{
+		"remind_count":     args.RemindCount,
+		"last_remind_time": args.LastRemindTime,
+		"timeout_notified": args.TimeoutNotified,
+	}}
+	_, err := c.UpdateOne(context.TODO(), query, change, options.Update().SetUpsert(true))
+	return err
+}
+
+func (c *ApprovalReminderRecordColl) List() ([]*models.ApprovalReminderRecord, error) {
+	resp := make([]*models.ApprovalReminderRecord, 0)
+	cursor, err := c.Collection.Find(context.TODO(), bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	return resp, cursor.All(context.TODO(), &resp)
+}
+
+func (c *ApprovalReminderRecordColl) DeleteByID(id primitive.ObjectID) error {
+	_, err := c.DeleteOne(context.TODO(), bson.M{"_id": id})
+	return err
+}
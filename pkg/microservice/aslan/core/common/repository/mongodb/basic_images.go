@@ -143,6 +143,7 @@ func (c *BasicImageColl) Update(id string, args *models.BasicImage) error {
 		"value":       args.Value,
 		"image_from":  args.ImageFrom,
 		"image_type":  args.ImageType,
+		"os":          args.OS,
 		"update_by":   args.UpdateBy,
 		"update_time": time.Now().Unix(),
 	}}
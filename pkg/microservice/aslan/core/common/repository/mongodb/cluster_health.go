@@ -0,0 +1,89 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type ClusterHealthColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewClusterHealthColl() *ClusterHealthColl {
+	name := models.ClusterHealth{}.TableName()
+	return &ClusterHealthColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *ClusterHealthColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *ClusterHealthColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys:    bson.M{"cluster_id": 1},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *ClusterHealthColl) Upsert(args *models.ClusterHealth) error {
+	if args == nil {
+		return errors.New("nil cluster health")
+	}
+
+	query := bson.M{"cluster_id": args.ClusterID}
+	opts := options.Replace().SetUpsert(true)
+	_, err := c.ReplaceOne(context.TODO(), query, args, opts)
+	return err
+}
+
+func (c *ClusterHealthColl) Get(clusterID string) (*models.ClusterHealth, error) {
+	resp := new(models.ClusterHealth)
+	query := bson.M{"cluster_id": clusterID}
+	return resp, c.FindOne(context.TODO(), query).Decode(resp)
+}
+
+func (c *ClusterHealthColl) List() ([]*models.ClusterHealth, error) {
+	resp := make([]*models.ClusterHealth, 0)
+	cursor, err := c.Find(context.TODO(), bson.M{}, options.Find().SetSort(bson.D{{"cluster_name", 1}}))
+	if err != nil {
+		return nil, err
+	}
+	return resp, cursor.All(context.TODO(), &resp)
+}
+
+func (c *ClusterHealthColl) Delete(clusterID string) error {
+	_, err := c.DeleteOne(context.TODO(), bson.M{"cluster_id": clusterID})
+	return err
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type ControllerInstanceColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewControllerInstanceColl() *ControllerInstanceColl {
+	name := models.ControllerInstance{}.TableName()
+	return &ControllerInstanceColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *ControllerInstanceColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *ControllerInstanceColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys:    bson.M{"instance_id": 1},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+// Heartbeat creates the instance if it does not exist and refreshes its heartbeat time
+func (c *ControllerInstanceColl) Heartbeat(instanceID string, startTime int64) error {
+	query := bson.M{"instance_id": instanceID}
+	change := bson.M{
+		"$set":         bson.M{"heartbeat_time": time.Now().Unix()},
+		"$setOnInsert": bson.M{"start_time": startTime},
+	}
+	_, err := c.UpdateOne(context.TODO(), query, change, options.Update().SetUpsert(true))
+	return err
+}
+
+// ListAlive lists the instances heartbeating after the time, the earliest started instance comes first
+func (c *ControllerInstanceColl) ListAlive(since int64) ([]*models.ControllerInstance, error) {
+	resp := make([]*models.ControllerInstance, 0)
+	query := bson.M{"heartbeat_time": bson.M{"$gte": since}}
+	opts := options.Find().SetSort(bson.D{{"start_time", 1}, {"instance_id", 1}})
+	cursor, err := c.Collection.Find(context.TODO(), query, opts)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+func (c *ControllerInstanceColl) DeleteExpired(before int64) error {
+	_, err := c.DeleteMany(context.TODO(), bson.M{"heartbeat_time": bson.M{"$lt": before}})
+	return err
+}
@@ -112,6 +112,23 @@ func (c *CounterColl) Find(name string) (*models.Counter, error) {
 	return counter, err
 }
 
+// FastForward moves the counter to the number if it is behind, the counter is never moved backward
+func (c *CounterColl) FastForward(counterName string, number int64) error {
+	query := bson.M{"_id": counterName, "seq": bson.M{"$lt": number}}
+	res, err := c.UpdateOne(context.TODO(), query, bson.M{"$set": bson.M{"seq": number}})
+	if err != nil {
+		return fmt.Errorf("[%s] FastForward %v error: %v", counterName, number, err)
+	}
+	if res.MatchedCount > 0 {
+		return nil
+	}
+	if _, err := c.Find(counterName); err != mongo.ErrNoDocuments {
+		return err
+	}
+	_, err = c.InsertOne(context.TODO(), &models.Counter{ID: counterName, Seq: number})
+	return err
+}
+
 func (c *CounterColl) UpsertCounter(counterName string, number int64) error {
 	query := bson.M{"_id": counterName}
 	change := bson.M{"$set": bson.M{
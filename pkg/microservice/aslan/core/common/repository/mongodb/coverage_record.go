@@ -0,0 +1,115 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type CoverageRecordListOption struct {
+	ProjectName   string
+	Name          string
+	ServiceName   string
+	ServiceModule string
+	Branch        string
+	Limit         int64
+}
+
+type CoverageRecordColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewCoverageRecordColl() *CoverageRecordColl {
+	name := models.CoverageRecord{}.TableName()
+	return &CoverageRecordColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *CoverageRecordColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *CoverageRecordColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "project_name", Value: 1},
+			bson.E{Key: "service_name", Value: 1},
+			bson.E{Key: "service_module", Value: 1},
+			bson.E{Key: "name", Value: 1},
+			bson.E{Key: "branch", Value: 1},
+			bson.E{Key: "create_time", Value: -1},
+		},
+		Options: options.Index().SetUnique(false),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *CoverageRecordColl) Create(args *models.CoverageRecord) error {
+	_, err := c.InsertOne(context.TODO(), args)
+	return err
+}
+
+func (c *CoverageRecordColl) List(opt *CoverageRecordListOption) ([]*models.CoverageRecord, error) {
+	query := bson.M{"project_name": opt.ProjectName}
+	if opt.Name != "" {
+		query["name"] = opt.Name
+	}
+	if opt.ServiceName != "" {
+		query["service_name"] = opt.ServiceName
+	}
+	if opt.ServiceModule != "" {
+		query["service_module"] = opt.ServiceModule
+	}
+	if opt.Branch != "" {
+		query["branch"] = opt.Branch
+	}
+	findOption := options.Find().SetSort(bson.D{{"create_time", -1}})
+	if opt.Limit > 0 {
+		findOption.SetLimit(opt.Limit)
+	}
+
+	resp := make([]*models.CoverageRecord, 0)
+	cursor, err := c.Find(context.TODO(), query, findOption)
+	if err != nil {
+		return nil, err
+	}
+	return resp, cursor.All(context.TODO(), &resp)
+}
+
+// FindLatest returns the latest coverage matching the option, nil if there is none
+func (c *CoverageRecordColl) FindLatest(opt *CoverageRecordListOption) (*models.CoverageRecord, error) {
+	opt.Limit = 1
+	records, err := c.List(opt)
+	if err != nil || len(records) == 0 {
+		return nil, err
+	}
+	return records[0], nil
+}
@@ -149,6 +149,15 @@ func (c *CronjobColl) Update(job *models.Cronjob) error {
 	return err
 }
 
+// UpdateLastFireTime records the time the cron service fired the job, it is used to find the misfires
+func (c *CronjobColl) UpdateLastFireTime(id primitive.ObjectID, fireTime int64) error {
+	query := bson.M{"_id": id}
+	change := bson.M{"$set": bson.M{"last_fire_time": fireTime}}
+
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	return err
+}
+
 func (c *CronjobColl) GetByID(id primitive.ObjectID) (*models.Cronjob, error) {
 	resp := new(models.Cronjob)
 	if id.IsZero() {
@@ -53,17 +53,30 @@ func (c *FavoriteColl) GetCollectionName() string {
 }
 
 func (c *FavoriteColl) EnsureIndex(ctx context.Context) error {
-	mod := mongo.IndexModel{
-		Keys: bson.D{
-			bson.E{Key: "user_id", Value: 1},
-			bson.E{Key: "product_name", Value: 1},
-			bson.E{Key: "type", Value: 1},
+	_, err := c.Indexes().CreateMany(ctx, c.IndexModels())
+	return err
+}
+
+// IndexModels are the indexes of the collection, they are audited at startup
+func (c *FavoriteColl) IndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				bson.E{Key: "user_id", Value: 1},
+				bson.E{Key: "product_name", Value: 1},
+				bson.E{Key: "type", Value: 1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys: bson.D{
+				bson.E{Key: "user_id", Value: 1},
+				bson.E{Key: "name", Value: 1},
+				bson.E{Key: "type", Value: 1},
+			},
+			Options: options.Index().SetUnique(false),
 		},
-		Options: options.Index().SetUnique(false),
 	}
-
-	_, err := c.Indexes().CreateOne(ctx, mod)
-	return err
 }
 
 func (c *FavoriteColl) Create(args *models.Favorite) error {
@@ -0,0 +1,93 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type FeatureFlagRecordColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewFeatureFlagRecordColl() *FeatureFlagRecordColl {
+	name := models.FeatureFlagRecord{}.TableName()
+	return &FeatureFlagRecordColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *FeatureFlagRecordColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *FeatureFlagRecordColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "external_system_id", Value: 1},
+			bson.E{Key: "project", Value: 1},
+			bson.E{Key: "environment", Value: 1},
+			bson.E{Key: "flag", Value: 1},
+			bson.E{Key: "create_time", Value: -1},
+		},
+		Options: options.Index().SetUnique(false),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *FeatureFlagRecordColl) Create(args *models.FeatureFlagRecord) error {
+	if args == nil {
+		return errors.New("nil feature flag record")
+	}
+
+	_, err := c.InsertOne(context.TODO(), args)
+	return err
+}
+
+// GetLatestNotRolledBack returns the latest change of the flag which is not rolled back yet
+func (c *FeatureFlagRecordColl) GetLatestNotRolledBack(externalSystemID, project, environment, flag string) (*models.FeatureFlagRecord, error) {
+	resp := new(models.FeatureFlagRecord)
+	query := bson.M{
+		"external_system_id": externalSystemID,
+		"project":            project,
+		"environment":        environment,
+		"flag":               flag,
+		"rolled_back":        false,
+	}
+	opts := options.FindOne().SetSort(bson.D{{"create_time", -1}})
+	return resp, c.FindOne(context.TODO(), query, opts).Decode(resp)
+}
+
+func (c *FeatureFlagRecordColl) MarkRolledBack(id primitive.ObjectID) error {
+	_, err := c.UpdateByID(context.TODO(), id, bson.M{"$set": bson.M{"rolled_back": true}})
+	return err
+}
@@ -51,6 +51,13 @@ func (c *JobInfoColl) EnsureIndex(ctx context.Context) error {
 			},
 			Options: options.Index().SetUnique(false),
 		},
+		{
+			Keys: bson.D{
+				bson.E{Key: "workflow_name", Value: 1},
+				bson.E{Key: "job_name", Value: 1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
 	}
 
 	_, err := c.Indexes().CreateMany(ctx, mod)
@@ -66,6 +73,117 @@ func (c *JobInfoColl) Create(ctx context.Context, args *models.JobInfo) error {
 	return err
 }
 
+// FindLastRunWithInput returns the last finished run of the job with the same inputs, nil if there is none
+func (c *JobInfoColl) FindLastRunWithInput(workflowName, jobName, inputHash string) (*models.JobInfo, error) {
+	query := bson.M{
+		"workflow_name": workflowName,
+		"job_name":      jobName,
+		"input_hash":    inputHash,
+		"status":        bson.M{"$in": []string{string(config.StatusPassed), string(config.StatusFailed), string(config.StatusTimeout)}},
+	}
+	opts := options.FindOne().SetSort(bson.D{{"_id", -1}})
+
+	resp := new(models.JobInfo)
+	err := c.FindOne(context.TODO(), query, opts).Decode(resp)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return resp, err
+}
+
+// CountFlakyRuns counts the flaky runs of the job started after the given time
+func (c *JobInfoColl) CountFlakyRuns(workflowName, jobName string, since int64) (int64, error) {
+	query := bson.M{
+		"workflow_name": workflowName,
+		"job_name":      jobName,
+		"flaky":         true,
+		"start_time":    bson.M{"$gte": since},
+	}
+	return c.CountDocuments(context.TODO(), query)
+}
+
+type FlakyJobStat struct {
+	WorkflowName        string `bson:"workflow_name"         json:"workflow_name"`
+	WorkflowDisplayName string `bson:"workflow_display_name" json:"workflow_display_name"`
+	JobName             string `bson:"job_name"              json:"job_name"`
+	JobType             string `bson:"type"                  json:"job_type"`
+	Total               int    `bson:"total"                 json:"total"`
+	Failure             int    `bson:"failure"               json:"failure"`
+	Flaky               int    `bson:"flaky"                 json:"flaky"`
+	LastFlakyTime       int64  `bson:"last_flaky_time"       json:"last_flaky_time"`
+}
+
+// ListFlakyJobStats counts the runs, the failures and the flaky runs of the jobs of the project having at least one
+// flaky run, the flakiest jobs come first
+func (c *JobInfoColl) ListFlakyJobStats(projectName string, startTime, endTime int64) ([]*FlakyJobStat, error) {
+	flaky := bson.M{"$cond": bson.A{"$flaky", 1, 0}}
+	failed := bson.M{"$cond": bson.A{bson.M{"$in": bson.A{"$status", bson.A{string(config.StatusFailed), string(config.StatusTimeout)}}}, 1, 0}}
+	pipeline := []bson.M{
+		{"$match": bson.M{
+			"product_name": projectName,
+			"input_hash":   bson.M{"$exists": true},
+			"start_time":   bson.M{"$gte": startTime, "$lt": endTime},
+		}},
+		{"$group": bson.M{
+			"_id":                   bson.M{"workflow_name": "$workflow_name", "job_name": "$job_name"},
+			"workflow_name":         bson.M{"$first": "$workflow_name"},
+			"workflow_display_name": bson.M{"$last": "$workflow_display_name"},
+			"job_name":              bson.M{"$first": "$job_name"},
+			"type":                  bson.M{"$first": "$type"},
+			"total":                 bson.M{"$sum": 1},
+			"failure":               bson.M{"$sum": failed},
+			"flaky":                 bson.M{"$sum": flaky},
+			"last_flaky_time":       bson.M{"$max": bson.M{"$cond": bson.A{"$flaky", "$start_time", 0}}},
+		}},
+		{"$match": bson.M{"flaky": bson.M{"$gt": 0}}},
+		{"$sort": bson.D{{"flaky", -1}, {"last_flaky_time", -1}}},
+	}
+
+	cursor, err := c.Aggregate(context.TODO(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]*FlakyJobStat, 0)
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+type FailureCategoryCount struct {
+	Category string `bson:"category" json:"category"`
+	JobType  string `bson:"type"     json:"job_type"`
+	Count    int    `bson:"count"    json:"count"`
+}
+
+// CountFailureCategories counts the failed jobs of the projects by the categories of the failures and the job types,
+// a job having several categories is counted for each of them
+func (c *JobInfoColl) CountFailureCategories(startTime, endTime int64, projectNames []string) ([]*FailureCategoryCount, error) {
+	match := bson.M{
+		"start_time":         bson.M{"$gte": startTime, "$lt": endTime},
+		"failure_categories": bson.M{"$exists": true},
+	}
+	if len(projectNames) != 0 {
+		match["product_name"] = bson.M{"$in": projectNames}
+	}
+	pipeline := []bson.M{
+		{"$match": match},
+		{"$unwind": "$failure_categories"},
+		{"$group": bson.M{
+			"_id":      bson.M{"category": "$failure_categories", "type": "$type"},
+			"category": bson.M{"$first": "$failure_categories"},
+			"type":     bson.M{"$first": "$type"},
+			"count":    bson.M{"$sum": 1},
+		}},
+	}
+
+	cursor, err := c.Aggregate(context.TODO(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]*FailureCategoryCount, 0)
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
 func (c *JobInfoColl) GetProductionDeployJobs(startTime, endTime int64, projectName string) ([]*models.JobInfo, error) {
 	query := bson.M{}
 	query["start_time"] = bson.M{"$gte": startTime, "$lt": endTime}
@@ -156,6 +274,33 @@ func (c *JobInfoColl) GetDeployJobs(startTime, endTime int64, projectName string
 	return resp, err
 }
 
+// ListPassedDeployedImages returns the images in the given list which have been deployed to a non-production
+// environment of the project by a passed deploy job
+func (c *JobInfoColl) ListPassedDeployedImages(projectName string, images []string) ([]string, error) {
+	query := bson.M{
+		"product_name": projectName,
+		"type": bson.M{"$in": []string{
+			string(config.JobZadigDeploy),
+			string(config.JobZadigHelmDeploy),
+		}},
+		"production": false,
+		"status":     string(config.StatusPassed),
+		"images":     bson.M{"$in": images},
+	}
+
+	resp := make([]string, 0)
+	deployed, err := c.Distinct(context.TODO(), "images", query)
+	if err != nil {
+		return nil, err
+	}
+	for _, image := range deployed {
+		if s, ok := image.(string); ok {
+			resp = append(resp, s)
+		}
+	}
+	return resp, nil
+}
+
 type JobInfoCoarseGrainedData struct {
 	StartTime   int64             `json:"start_time"`
 	EndTime     int64             `json:"end_time"`
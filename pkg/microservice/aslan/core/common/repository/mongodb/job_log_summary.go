@@ -0,0 +1,84 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type JobLogSummaryColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewJobLogSummaryColl() *JobLogSummaryColl {
+	name := models.JobLogSummary{}.TableName()
+	return &JobLogSummaryColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *JobLogSummaryColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *JobLogSummaryColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "workflow_name", Value: 1},
+			bson.E{Key: "task_id", Value: 1},
+			bson.E{Key: "job_name", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *JobLogSummaryColl) Upsert(args *models.JobLogSummary) error {
+	if args == nil {
+		return errors.New("nil job log summary")
+	}
+
+	query := bson.M{"workflow_name": args.WorkflowName, "task_id": args.TaskID, "job_name": args.JobName}
+	opts := options.Replace().SetUpsert(true)
+	_, err := c.ReplaceOne(context.TODO(), query, args, opts)
+	return err
+}
+
+func (c *JobLogSummaryColl) Find(workflowName, jobName string, taskID int64) (*models.JobLogSummary, error) {
+	resp := new(models.JobLogSummary)
+	query := bson.M{"workflow_name": workflowName, "task_id": taskID, "job_name": jobName}
+	return resp, c.FindOne(context.TODO(), query).Decode(resp)
+}
+
+func (c *JobLogSummaryColl) DeleteByTask(workflowName string, taskID int64) error {
+	_, err := c.DeleteMany(context.TODO(), bson.M{"workflow_name": workflowName, "task_id": taskID})
+	return err
+}
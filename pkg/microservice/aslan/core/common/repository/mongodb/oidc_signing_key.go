@@ -0,0 +1,89 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/tool/crypto"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type OIDCSigningKeyColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewOIDCSigningKeyColl() *OIDCSigningKeyColl {
+	name := models.OIDCSigningKey{}.TableName()
+	return &OIDCSigningKeyColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *OIDCSigningKeyColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *OIDCSigningKeyColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys:    bson.D{bson.E{Key: "key_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *OIDCSigningKeyColl) Create(args *models.OIDCSigningKey) error {
+	encryptedKey, err := crypto.AesEncrypt(args.PrivateKey)
+	if err != nil {
+		return err
+	}
+	args.EncryptedPrivateKey = encryptedKey
+
+	_, err = c.InsertOne(context.TODO(), args)
+	return err
+}
+
+// List lists the keys from the newest to the oldest
+func (c *OIDCSigningKeyColl) List() ([]*models.OIDCSigningKey, error) {
+	resp := make([]*models.OIDCSigningKey, 0)
+	cursor, err := c.Find(context.TODO(), bson.M{}, options.Find().SetSort(bson.D{{"create_time", -1}}))
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(context.TODO(), &resp); err != nil {
+		return nil, err
+	}
+
+	for _, key := range resp {
+		decryptedKey, err := crypto.AesDecrypt(key.EncryptedPrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		key.PrivateKey = decryptedKey
+	}
+	return resp, nil
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type PerformanceRecordListOption struct {
+	ProjectName  string
+	WorkflowName string
+	JobName      string
+	Limit        int64
+}
+
+type PerformanceRecordColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewPerformanceRecordColl() *PerformanceRecordColl {
+	name := models.PerformanceRecord{}.TableName()
+	return &PerformanceRecordColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *PerformanceRecordColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *PerformanceRecordColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "project_name", Value: 1},
+			bson.E{Key: "workflow_name", Value: 1},
+			bson.E{Key: "job_name", Value: 1},
+			bson.E{Key: "task_id", Value: -1},
+		},
+		Options: options.Index().SetUnique(false),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *PerformanceRecordColl) Create(args *models.PerformanceRecord) error {
+	_, err := c.InsertOne(context.TODO(), args)
+	return err
+}
+
+// Find returns the latest record of the job in the task, a retried job may have several records
+func (c *PerformanceRecordColl) Find(projectName, workflowName, jobName string, taskID int64) (*models.PerformanceRecord, error) {
+	query := bson.M{"project_name": projectName, "workflow_name": workflowName, "job_name": jobName, "task_id": taskID}
+	resp := &models.PerformanceRecord{}
+	opts := options.FindOne().SetSort(bson.D{{"create_time", -1}})
+	return resp, c.FindOne(context.TODO(), query, opts).Decode(resp)
+}
+
+func (c *PerformanceRecordColl) List(opt *PerformanceRecordListOption) ([]*models.PerformanceRecord, error) {
+	query := bson.M{"project_name": opt.ProjectName, "workflow_name": opt.WorkflowName}
+	if opt.JobName != "" {
+		query["job_name"] = opt.JobName
+	}
+	findOption := options.Find().SetSort(bson.D{{"task_id", -1}, {"create_time", -1}})
+	if opt.Limit > 0 {
+		findOption.SetLimit(opt.Limit)
+	}
+
+	resp := make([]*models.PerformanceRecord, 0)
+	cursor, err := c.Collection.Find(context.TODO(), query, findOption)
+	if err != nil {
+		return nil, err
+	}
+	return resp, cursor.All(context.TODO(), &resp)
+}
@@ -78,8 +78,21 @@ func (c *PluginRepoColl) Delete(id string) error {
 	return err
 }
 
+func (c *PluginRepoColl) Get(id string) (*models.PluginRepo, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	res := new(models.PluginRepo)
+	err = c.FindOne(context.TODO(), bson.M{"_id": oid}).Decode(res)
+	return res, err
+}
+
 func (c *PluginRepoColl) Upsert(pluginRepo *models.PluginRepo) error {
 	query := bson.M{"repo_name": pluginRepo.RepoName, "repo_owner": pluginRepo.RepoOwner, "branch": pluginRepo.Branch}
+	if pluginRepo.OCIRepo != "" {
+		query = bson.M{"registry_id": pluginRepo.RegistryID, "oci_repo": pluginRepo.OCIRepo}
+	}
 	change := bson.M{"$set": pluginRepo}
 	pluginRepo.UpdateTime = time.Now().Unix()
 
@@ -441,6 +441,66 @@ func (c *ProductColl) UpdateDeployStrategy(envName, productName string, deploySt
 	return err
 }
 
+func (c *ProductColl) UpdateChartVersionPins(envName, productName string, chartVersionPins map[string]string) error {
+	query := bson.M{
+		"env_name":     envName,
+		"product_name": productName,
+	}
+	change := bson.M{
+		"update_time":        time.Now().Unix(),
+		"chart_version_pins": chartVersionPins,
+	}
+
+	_, err := c.UpdateOne(context.TODO(), query, bson.M{"$set": change})
+
+	return err
+}
+
+func (c *ProductColl) UpdateHelmDeployConfig(envName, productName string, helmDeployConfig *models.HelmDeployConfig) error {
+	query := bson.M{
+		"env_name":     envName,
+		"product_name": productName,
+	}
+	change := bson.M{
+		"update_time":        time.Now().Unix(),
+		"helm_deploy_config": helmDeployConfig,
+	}
+
+	_, err := c.UpdateOne(context.TODO(), query, bson.M{"$set": change})
+
+	return err
+}
+
+func (c *ProductColl) UpdateDriftDetection(envName, productName string, driftDetection *models.DriftDetectionConfig) error {
+	query := bson.M{
+		"env_name":     envName,
+		"product_name": productName,
+	}
+	change := bson.M{
+		"update_time":     time.Now().Unix(),
+		"drift_detection": driftDetection,
+	}
+
+	_, err := c.UpdateOne(context.TODO(), query, bson.M{"$set": change})
+
+	return err
+}
+
+func (c *ProductColl) UpdateMetricSnapshot(envName, productName string, metricSnapshot *models.MetricSnapshotConfig) error {
+	query := bson.M{
+		"env_name":     envName,
+		"product_name": productName,
+	}
+	change := bson.M{
+		"update_time":     time.Now().Unix(),
+		"metric_snapshot": metricSnapshot,
+	}
+
+	_, err := c.UpdateOne(context.TODO(), query, bson.M{"$set": change})
+
+	return err
+}
+
 func (c *ProductColl) UpdateProductRecycleDay(envName, productName string, recycleDay int) error {
 	query := bson.M{"env_name": envName, "product_name": productName}
 
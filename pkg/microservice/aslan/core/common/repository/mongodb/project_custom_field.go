@@ -0,0 +1,74 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type ProjectCustomFieldColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewProjectCustomFieldColl() *ProjectCustomFieldColl {
+	name := models.ProjectCustomField{}.TableName()
+	return &ProjectCustomFieldColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *ProjectCustomFieldColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *ProjectCustomFieldColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys:    bson.M{"project_name": 1},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *ProjectCustomFieldColl) Find(projectName string) (*models.ProjectCustomField, error) {
+	resp := &models.ProjectCustomField{}
+	query := bson.M{"project_name": projectName}
+	return resp, c.FindOne(context.TODO(), query).Decode(resp)
+}
+
+func (c *ProjectCustomFieldColl) Upsert(args *models.ProjectCustomField) error {
+	query := bson.M{"project_name": args.ProjectName}
+	change := bson.M{"$set": bson.M{
+		"custom_field": args.CustomField,
+		"update_by":    args.UpdateBy,
+		"update_time":  args.UpdateTime,
+	}}
+	_, err := c.UpdateOne(context.TODO(), query, change, options.Update().SetUpsert(true))
+	return err
+}
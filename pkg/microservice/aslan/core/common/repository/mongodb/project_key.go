@@ -0,0 +1,118 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+// ProjectKeyField is a field holding the project key, ArrayField is set if the field is in the elements of an array
+type ProjectKeyField struct {
+	Collection string `json:"collection"`
+	ArrayField string `json:"array_field,omitempty"`
+	Field      string `json:"field"`
+}
+
+// ProjectKeyFields are the fields rewritten when a project key is renamed, the workflows are rewritten separately
+// since their jobs reference the project in the job specs, and the workflow tasks are rewritten by their store
+// since they may not be kept in mongodb
+var ProjectKeyFields = []*ProjectKeyField{
+	{Collection: "template_product", Field: "product_name"},
+	{Collection: "product", Field: "product_name"},
+	{Collection: "render_set", Field: "product_tmpl"},
+	{Collection: "template_service", Field: "product_name"},
+	{Collection: "production_template_service", Field: "product_name"},
+	{Collection: "module_build", Field: "product_name"},
+	{Collection: "module_testing", Field: "product_name"},
+	{Collection: "scanning", Field: "project_name"},
+	{Collection: "cronjob", Field: "product_name"},
+	{Collection: "cronjob", Field: "workflow_v4_args.project"},
+	{Collection: "workflow", Field: "product_tmpl_name"},
+	{Collection: "workflow_v3", Field: "project_name"},
+	{Collection: "workflow_queue", Field: "project_name"},
+	{Collection: "workflow_view", Field: "project_name"},
+	{Collection: "workflow_stat", Field: "product_name"},
+	{Collection: "workflow_v4_trash", Field: "project_name"},
+	{Collection: "workflow_v4_trash", Field: "workflow.project"},
+	{Collection: "pipeline_v2", Field: "product_name"},
+	{Collection: "pipeline_task_v2", Field: "product_name"},
+	{Collection: "pipeline_queue", Field: "product_name"},
+	{Collection: "delivery_version", Field: "product_name"},
+	{Collection: "env_resource", Field: "product_name"},
+	{Collection: "env_svc_depend", Field: "product_name"},
+	{Collection: "env_ai_analysis", Field: "project_name"},
+	{Collection: "services_in_external_env", Field: "product_name"},
+	{Collection: "favorite", Field: "product_name"},
+	{Collection: "job_info", Field: "product_name"},
+	{Collection: "private_key", Field: "project_name"},
+	{Collection: "project_cluster_relation", Field: "project_name"},
+	{Collection: "project_custom_field", Field: "project_name"},
+	{Collection: "project_plugin", Field: "project_name"},
+	{Collection: "project_group", ArrayField: "projects", Field: "project_key"},
+	{Collection: "variable_set", Field: "project_name"},
+	{Collection: "collaboration_mode", Field: "project_name"},
+	{Collection: "collaboration_instance", Field: "project_name"},
+	{Collection: "callback_request", Field: "project_name"},
+	{Collection: "coverage_record", Field: "project_name"},
+	{Collection: "performance_record", Field: "project_name"},
+	{Collection: "test_case_result", Field: "project_name"},
+	{Collection: "retained_job_pod", Field: "project_name"},
+	{Collection: "share_storage_usage", Field: "project_name"},
+	{Collection: "vm_job", Field: "project_name"},
+}
+
+func (f *ProjectKeyField) path() string {
+	if f.ArrayField == "" {
+		return f.Field
+	}
+	return f.ArrayField + "." + f.Field
+}
+
+// CountProjectKey returns the number of documents whose field is the project key
+func CountProjectKey(field *ProjectKeyField, key string) (int64, error) {
+	coll := mongotool.Database(config.MongoDatabase()).Collection(field.Collection)
+	return coll.CountDocuments(context.TODO(), bson.M{field.path(): key})
+}
+
+// RenameProjectKey rewrites the field of the documents from the old project key to the new one
+func RenameProjectKey(field *ProjectKeyField, oldKey, newKey string) (int64, error) {
+	coll := mongotool.Database(config.MongoDatabase()).Collection(field.Collection)
+	query := bson.M{field.path(): oldKey}
+	if field.ArrayField == "" {
+		res, err := coll.UpdateMany(context.TODO(), query, bson.M{"$set": bson.M{field.Field: newKey}})
+		if err != nil {
+			return 0, err
+		}
+		return res.ModifiedCount, nil
+	}
+
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{
+		Filters: []interface{}{bson.M{"elem." + field.Field: oldKey}},
+	})
+	change := bson.M{"$set": bson.M{field.ArrayField + ".$[elem]." + field.Field: newKey}}
+	res, err := coll.UpdateMany(context.TODO(), query, change, opts)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
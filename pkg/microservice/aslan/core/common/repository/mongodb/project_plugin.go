@@ -0,0 +1,75 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type ProjectPluginColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewProjectPluginColl() *ProjectPluginColl {
+	name := models.ProjectPlugin{}.TableName()
+	return &ProjectPluginColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *ProjectPluginColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *ProjectPluginColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys:    bson.M{"project_name": 1},
+		Options: options.Index().SetUnique(true),
+	}
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+// Find returns mongo.ErrNoDocuments if the plugins of the project are not restricted
+func (c *ProjectPluginColl) Find(projectName string) (*models.ProjectPlugin, error) {
+	resp := new(models.ProjectPlugin)
+	err := c.FindOne(context.TODO(), bson.M{"project_name": projectName}).Decode(resp)
+	return resp, err
+}
+
+func (c *ProjectPluginColl) Upsert(args *models.ProjectPlugin) error {
+	args.UpdateTime = time.Now().Unix()
+	_, err := c.UpdateOne(context.TODO(), bson.M{"project_name": args.ProjectName}, bson.M{"$set": args}, options.Update().SetUpsert(true))
+	return err
+}
+
+func (c *ProjectPluginColl) Delete(projectName string) error {
+	_, err := c.DeleteOne(context.TODO(), bson.M{"project_name": projectName})
+	return err
+}
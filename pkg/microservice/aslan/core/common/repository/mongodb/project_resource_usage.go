@@ -0,0 +1,117 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type ProjectResourceUsageColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewProjectResourceUsageColl() *ProjectResourceUsageColl {
+	name := models.ProjectResourceUsage{}.TableName()
+	return &ProjectResourceUsageColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *ProjectResourceUsageColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *ProjectResourceUsageColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys:    bson.M{"project_name": 1},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+// Acquire adds the holder to the usage of the project if the usage stays in the limits, zero means unlimited.
+// The check and the increment are done in one update so the instances never exceed the limits together.
+// A holder already in the usage is acquired again when its task is resumed, it is not counted twice.
+func (c *ProjectResourceUsageColl) Acquire(projectName string, holder *models.ProjectResourceHolder, maxJobs, cpuLimit, memoryLimit int) (bool, error) {
+	init := bson.M{"$setOnInsert": bson.M{"jobs": 0, "cpu": 0, "memory": 0, "holders": bson.A{}}}
+	_, err := c.UpdateOne(context.TODO(), bson.M{"project_name": projectName}, init, options.Update().SetUpsert(true))
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return false, err
+	}
+
+	query := bson.M{
+		"project_name":    projectName,
+		"holders.job_key": bson.M{"$ne": holder.JobKey},
+	}
+	if maxJobs > 0 {
+		query["jobs"] = bson.M{"$lte": maxJobs - 1}
+	}
+	if cpuLimit > 0 {
+		query["cpu"] = bson.M{"$lte": cpuLimit - holder.CPU}
+	}
+	if memoryLimit > 0 {
+		query["memory"] = bson.M{"$lte": memoryLimit - holder.Memory}
+	}
+	change := bson.M{
+		"$inc":  bson.M{"jobs": 1, "cpu": holder.CPU, "memory": holder.Memory},
+		"$push": bson.M{"holders": holder},
+	}
+	res, err := c.UpdateOne(context.TODO(), query, change)
+	if err != nil {
+		return false, err
+	}
+	if res.MatchedCount > 0 {
+		return true, nil
+	}
+
+	count, err := c.CountDocuments(context.TODO(), bson.M{"project_name": projectName, "holders.job_key": holder.JobKey})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Release removes the holder from the usage of the project, releasing a removed holder does nothing
+func (c *ProjectResourceUsageColl) Release(projectName string, holder *models.ProjectResourceHolder) error {
+	query := bson.M{"project_name": projectName, "holders.job_key": holder.JobKey}
+	change := bson.M{
+		"$inc":  bson.M{"jobs": -1, "cpu": -holder.CPU, "memory": -holder.Memory},
+		"$pull": bson.M{"holders": bson.M{"job_key": holder.JobKey}},
+	}
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	return err
+}
+
+func (c *ProjectResourceUsageColl) List() ([]*models.ProjectResourceUsage, error) {
+	resp := make([]*models.ProjectResourceUsage, 0)
+	cursor, err := c.Collection.Find(context.TODO(), bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
@@ -93,6 +93,19 @@ func (c *ProxyColl) List(args *ProxyArgs) ([]*models.Proxy, error) {
 	return res, err
 }
 
+// GetIntegrationProxyURL reads the proxy used by the integration from the database, it is empty if no proxy is used
+func (c *ProxyColl) GetIntegrationProxyURL(integration string) (string, error) {
+	proxies, err := c.List(&ProxyArgs{})
+	if err != nil {
+		return "", err
+	}
+	if len(proxies) == 0 {
+		return "", nil
+	}
+	proxyURL, _ := proxies[0].ResolveIntegrationProxy(integration)
+	return proxyURL, nil
+}
+
 func (c *ProxyColl) Create(args *models.Proxy) error {
 	if args == nil {
 		return errors.New("nil proxy info")
@@ -128,6 +141,7 @@ func (c *ProxyColl) Update(id string, args *models.Proxy) error {
 		"enable_application_proxy": args.EnableApplicationProxy,
 		"update_by":                args.UpdateBy,
 		"update_time":              time.Now().Unix(),
+		"integration_proxies":      args.IntegrationProxies,
 	}}
 	_, err = c.UpdateOne(context.TODO(), query, change)
 
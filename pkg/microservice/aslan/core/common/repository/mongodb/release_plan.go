@@ -19,6 +19,7 @@ package mongodb
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/pkg/errors"
 	"go.mongodb.org/mongo-driver/bson"
@@ -89,6 +90,122 @@ func (c *ReleasePlanColl) UpdateByID(ctx context.Context, idString string, args
 	return err
 }
 
+// UpdateStatusByID updates the status of the plan and the time of the status
+func (c *ReleasePlanColl) UpdateStatusByID(ctx context.Context, idString string, args *models.ReleasePlan) error {
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return fmt.Errorf("invalid id")
+	}
+
+	query := bson.M{"_id": id}
+	change := bson.M{"$set": bson.M{
+		"status":         args.Status,
+		"executing_time": args.ExecutingTime,
+		"success_time":   args.SuccessTime,
+	}}
+	_, err = c.UpdateOne(ctx, query, change)
+	return err
+}
+
+// UpdateReleaseJob updates the job of the plan in place. The approvals of the release train stages are left as they
+// are except for the result, the approvers record their votes on them concurrently.
+func (c *ReleasePlanColl) UpdateReleaseJob(ctx context.Context, idString string, job *models.ReleaseJob) error {
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return fmt.Errorf("invalid id")
+	}
+
+	query := bson.M{"_id": id, "jobs.id": job.ID}
+	set := bson.M{}
+	if job.Type != config.JobReleaseTrain {
+		set["jobs.$"] = job
+	} else {
+		spec := new(models.ReleaseTrainReleaseJobSpec)
+		if err := models.IToi(job.Spec, spec); err != nil {
+			return err
+		}
+		set["jobs.$.status"] = job.Status
+		set["jobs.$.spec.current_stage"] = spec.CurrentStage
+		for i, stage := range spec.Stages {
+			prefix := fmt.Sprintf("jobs.$.spec.stages.%d.", i)
+			set[prefix+"status"] = stage.Status
+			set[prefix+"task_id"] = stage.TaskID
+			set[prefix+"start_time"] = stage.StartTime
+			set[prefix+"end_time"] = stage.EndTime
+			if stage.Approval != nil {
+				set[prefix+"approval.reject_or_approve"] = stage.Approval.RejectOrApprove
+			}
+		}
+	}
+	_, err = c.UpdateOne(ctx, query, bson.M{"$set": set})
+	return err
+}
+
+// ClaimReleaseTrainStage moves the release train to the next stage and marks it as prepared if the train is still at
+// the stage in the status, false is returned if the train has been moved by others.
+func (c *ReleasePlanColl) ClaimReleaseTrainStage(ctx context.Context, idString, jobID string, stage int, status config.Status, nextStage int) (bool, error) {
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return false, fmt.Errorf("invalid id")
+	}
+
+	query := bson.M{
+		"_id": id,
+		"jobs": bson.M{"$elemMatch": bson.M{
+			"id":                 jobID,
+			"spec.current_stage": stage,
+			fmt.Sprintf("spec.stages.%d.status", stage): status,
+		}},
+	}
+	change := bson.M{"$set": bson.M{
+		"jobs.$.spec.current_stage":                            nextStage,
+		fmt.Sprintf("jobs.$.spec.stages.%d.status", nextStage): config.StatusPrepare,
+	}}
+	res, err := c.UpdateOne(ctx, query, change)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// RecordReleaseTrainVote records the vote of the approver on the stage of the release train if the train is still
+// waiting for the approval of the stage and the approver hasn't voted, false is returned otherwise.
+func (c *ReleasePlanColl) RecordReleaseTrainVote(ctx context.Context, idString, jobID string, stage int, user *models.User, updatedBy string) (bool, error) {
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return false, fmt.Errorf("invalid id")
+	}
+
+	query := bson.M{
+		"_id":    id,
+		"status": config.StatusExecuting,
+		"jobs": bson.M{"$elemMatch": bson.M{
+			"id":                 jobID,
+			"spec.current_stage": stage,
+			fmt.Sprintf("spec.stages.%d.status", stage): config.StatusWaitingApprove,
+		}},
+	}
+	prefix := fmt.Sprintf("jobs.$[job].spec.stages.%d.approval.approve_users.$[user].", stage)
+	change := bson.M{"$set": bson.M{
+		prefix + "reject_or_approve": user.RejectOrApprove,
+		prefix + "comment":           user.Comment,
+		prefix + "operation_time":    user.OperationTime,
+		"updated_by":                 updatedBy,
+		"update_time":                time.Now().Unix(),
+	}}
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{
+		Filters: []interface{}{
+			bson.M{"job.id": jobID},
+			bson.M{"user.user_id": user.UserID, "user.reject_or_approve": bson.M{"$in": bson.A{"", nil}}},
+		},
+	})
+	res, err := c.UpdateOne(ctx, query, change, opts)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
 func (c *ReleasePlanColl) DeleteByID(ctx context.Context, idString string) error {
 	id, err := primitive.ObjectIDFromHex(idString)
 	if err != nil {
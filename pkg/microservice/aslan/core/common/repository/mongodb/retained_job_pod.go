@@ -0,0 +1,115 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type RetainedJobPodListOption struct {
+	ProjectName  string
+	WorkflowName string
+	// ExpireBefore lists the pods expired before the given time if it is set
+	ExpireBefore int64
+}
+
+type RetainedJobPodColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewRetainedJobPodColl() *RetainedJobPodColl {
+	name := models.RetainedJobPod{}.TableName()
+	return &RetainedJobPodColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *RetainedJobPodColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *RetainedJobPodColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				bson.E{Key: "project_name", Value: 1},
+				bson.E{Key: "workflow_name", Value: 1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys: bson.D{
+				bson.E{Key: "expire_time", Value: 1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+	}
+
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+func (c *RetainedJobPodColl) Create(args *models.RetainedJobPod) error {
+	_, err := c.InsertOne(context.TODO(), args)
+	return err
+}
+
+func (c *RetainedJobPodColl) GetByID(idString string) (*models.RetainedJobPod, error) {
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return nil, err
+	}
+	resp := &models.RetainedJobPod{}
+	return resp, c.FindOne(context.TODO(), bson.M{"_id": id}).Decode(resp)
+}
+
+func (c *RetainedJobPodColl) List(opt *RetainedJobPodListOption) ([]*models.RetainedJobPod, error) {
+	query := bson.M{}
+	if opt.ProjectName != "" {
+		query["project_name"] = opt.ProjectName
+	}
+	if opt.WorkflowName != "" {
+		query["workflow_name"] = opt.WorkflowName
+	}
+	if opt.ExpireBefore > 0 {
+		query["expire_time"] = bson.M{"$lt": opt.ExpireBefore}
+	}
+
+	resp := make([]*models.RetainedJobPod, 0)
+	cursor, err := c.Find(context.TODO(), query, options.Find().SetSort(bson.D{{"create_time", -1}}))
+	if err != nil {
+		return nil, err
+	}
+	return resp, cursor.All(context.TODO(), &resp)
+}
+
+func (c *RetainedJobPodColl) DeleteByID(id primitive.ObjectID) error {
+	_, err := c.DeleteOne(context.TODO(), bson.M{"_id": id})
+	return err
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type ServiceRestartRecordListOption struct {
+	ProductName string
+	EnvName     string
+	ServiceName string
+	StartTime   int64
+	EndTime     int64
+}
+
+type ServiceRestartRecordColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewServiceRestartRecordColl() *ServiceRestartRecordColl {
+	name := models.ServiceRestartRecord{}.TableName()
+	return &ServiceRestartRecordColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *ServiceRestartRecordColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *ServiceRestartRecordColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				bson.E{Key: "product_name", Value: 1},
+				bson.E{Key: "env_name", Value: 1},
+				bson.E{Key: "service_name", Value: 1},
+				bson.E{Key: "time", Value: -1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys:    bson.M{"time": 1},
+			Options: options.Index().SetUnique(false),
+		},
+	}
+
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+func (c *ServiceRestartRecordColl) Create(args *models.ServiceRestartRecord) error {
+	if args == nil {
+		return errors.New("nil service restart record")
+	}
+
+	_, err := c.InsertOne(context.TODO(), args)
+	return err
+}
+
+// GetLatest returns the latest record of the service
+func (c *ServiceRestartRecordColl) GetLatest(productName, envName, serviceName string) (*models.ServiceRestartRecord, error) {
+	resp := new(models.ServiceRestartRecord)
+	query := bson.M{"product_name": productName, "env_name": envName, "service_name": serviceName}
+	opts := options.FindOne().SetSort(bson.D{{"time", -1}})
+	return resp, c.FindOne(context.TODO(), query, opts).Decode(resp)
+}
+
+func (c *ServiceRestartRecordColl) List(opt *ServiceRestartRecordListOption) ([]*models.ServiceRestartRecord, error) {
+	query := bson.M{"product_name": opt.ProductName, "env_name": opt.EnvName}
+	if opt.ServiceName != "" {
+		query["service_name"] = opt.ServiceName
+	}
+	timeQuery := bson.M{}
+	if opt.StartTime > 0 {
+		timeQuery["$gte"] = opt.StartTime
+	}
+	if opt.EndTime > 0 {
+		timeQuery["$lte"] = opt.EndTime
+	}
+	if len(timeQuery) > 0 {
+		query["time"] = timeQuery
+	}
+
+	resp := make([]*models.ServiceRestartRecord, 0)
+	opts := options.Find().SetSort(bson.D{{"time", 1}})
+	cursor, err := c.Find(context.TODO(), query, opts)
+	if err != nil {
+		return nil, err
+	}
+	return resp, cursor.All(context.TODO(), &resp)
+}
+
+// DeleteBefore removes the records sampled before the given time
+func (c *ServiceRestartRecordColl) DeleteBefore(before int64) error {
+	_, err := c.DeleteMany(context.TODO(), bson.M{"time": bson.M{"$lt": before}})
+	return err
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type ShareStorageUsageListOption struct {
+	ClusterID    string
+	WorkflowName string
+}
+
+type ShareStorageUsageColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewShareStorageUsageColl() *ShareStorageUsageColl {
+	name := models.ShareStorageUsage{}.TableName()
+	return &ShareStorageUsageColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *ShareStorageUsageColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *ShareStorageUsageColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "cluster_id", Value: 1},
+			bson.E{Key: "workflow_name", Value: 1},
+			bson.E{Key: "task_id", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+// ReplaceClusterUsages replaces all the usages of the cluster with the latest ones
+func (c *ShareStorageUsageColl) ReplaceClusterUsages(clusterID string, usages []*models.ShareStorageUsage) error {
+	if _, err := c.DeleteMany(context.TODO(), bson.M{"cluster_id": clusterID}); err != nil {
+		return err
+	}
+	if len(usages) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, 0, len(usages))
+	for _, usage := range usages {
+		docs = append(docs, usage)
+	}
+	_, err := c.InsertMany(context.TODO(), docs)
+	return err
+}
+
+func (c *ShareStorageUsageColl) List(opt *ShareStorageUsageListOption) ([]*models.ShareStorageUsage, error) {
+	query := bson.M{}
+	if opt.ClusterID != "" {
+		query["cluster_id"] = opt.ClusterID
+	}
+	if opt.WorkflowName != "" {
+		query["workflow_name"] = opt.WorkflowName
+	}
+
+	resp := make([]*models.ShareStorageUsage, 0)
+	cursor, err := c.Find(context.TODO(), query, options.Find().SetSort(bson.D{{"size_in_kib", -1}}))
+	if err != nil {
+		return nil, err
+	}
+	return resp, cursor.All(context.TODO(), &resp)
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type TaskIdempotencyColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewTaskIdempotencyColl() *TaskIdempotencyColl {
+	name := models.TaskIdempotency{}.TableName()
+	return &TaskIdempotencyColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *TaskIdempotencyColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *TaskIdempotencyColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				bson.E{Key: "workflow_name", Value: 1},
+				bson.E{Key: "key", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{bson.E{Key: "create_time", Value: 1}},
+			Options: options.Index().SetUnique(false),
+		},
+	}
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+// Reserve saves the key, it returns false if the key of the workflow has been reserved
+func (c *TaskIdempotencyColl) Reserve(args *models.TaskIdempotency) (bool, error) {
+	res, err := c.InsertOne(context.TODO(), args)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	args.ID = res.InsertedID.(primitive.ObjectID)
+	return true, nil
+}
+
+func (c *TaskIdempotencyColl) Find(workflowName, key string) (*models.TaskIdempotency, error) {
+	resp := new(models.TaskIdempotency)
+	err := c.FindOne(context.TODO(), bson.M{"workflow_name": workflowName, "key": key}).Decode(resp)
+	return resp, err
+}
+
+func (c *TaskIdempotencyColl) SetTaskID(id primitive.ObjectID, taskID int64) error {
+	_, err := c.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$set": bson.M{"task_id": taskID}})
+	return err
+}
+
+func (c *TaskIdempotencyColl) Delete(id primitive.ObjectID) error {
+	_, err := c.DeleteOne(context.TODO(), bson.M{"_id": id})
+	return err
+}
+
+// DeleteBefore removes the keys reserved before the unix time
+func (c *TaskIdempotencyColl) DeleteBefore(timestamp int64) error {
+	_, err := c.DeleteMany(context.TODO(), bson.M{"create_time": bson.M{"$lt": timestamp}})
+	return err
+}
@@ -556,6 +556,66 @@ func (c *ProductColl) UpdateGlobalVars(productName string, serviceVars []*types.
 	return err
 }
 
+func (c *ProductColl) UpdateResourceQuota(productName string, quota *template.ProjectResourceQuota) error {
+	query := bson.M{"product_name": productName}
+	change := bson.M{"$set": bson.M{
+		"resource_quota": quota,
+	}}
+
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	return err
+}
+
+func (c *ProductColl) UpdateImageBuildConfig(productName string, cfg *template.ProjectImageBuildConfig) error {
+	query := bson.M{"product_name": productName}
+	change := bson.M{"$set": bson.M{
+		"image_build_config": cfg,
+	}}
+
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	return err
+}
+
+func (c *ProductColl) UpdateMailNotifyConfig(productName string, cfg *template.ProjectMailNotifyConfig) error {
+	query := bson.M{"product_name": productName}
+	change := bson.M{"$set": bson.M{
+		"mail_notify": cfg,
+	}}
+
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	return err
+}
+
+func (c *ProductColl) UpdatePromotionPolicy(productName string, policy *template.ProjectPromotionPolicy) error {
+	query := bson.M{"product_name": productName}
+	change := bson.M{"$set": bson.M{
+		"promotion_policy": policy,
+	}}
+
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	return err
+}
+
+func (c *ProductColl) UpdateLogRetention(productName string, retention *template.ProjectLogRetention) error {
+	query := bson.M{"product_name": productName}
+	change := bson.M{"$set": bson.M{
+		"log_retention": retention,
+	}}
+
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	return err
+}
+
+func (c *ProductColl) UpdateFlakyJobPolicy(productName string, policy *template.ProjectFlakyJobPolicy) error {
+	query := bson.M{"product_name": productName}
+	change := bson.M{"$set": bson.M{
+		"flaky_job_policy": policy,
+	}}
+
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	return err
+}
+
 func (c *ProductColl) Delete(productName string) error {
 	query := bson.M{"product_name": productName}
 
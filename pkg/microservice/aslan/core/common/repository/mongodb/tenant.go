@@ -0,0 +1,115 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type TenantColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewTenantColl() *TenantColl {
+	name := models.Tenant{}.TableName()
+	return &TenantColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *TenantColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *TenantColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys:    bson.M{"name": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.M{"projects": 1},
+			Options: options.Index().SetUnique(false),
+		},
+	}
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+func (c *TenantColl) Create(args *models.Tenant) error {
+	args.CreateTime = time.Now().Unix()
+	args.UpdateTime = args.CreateTime
+	_, err := c.InsertOne(context.TODO(), args)
+	return err
+}
+
+func (c *TenantColl) Update(args *models.Tenant) error {
+	query := bson.M{"name": args.Name}
+	change := bson.M{"$set": bson.M{
+		"display_name": args.DisplayName,
+		"description":  args.Description,
+		"projects":     args.Projects,
+		"quota":        args.Quota,
+		"updated_by":   args.UpdatedBy,
+		"update_time":  time.Now().Unix(),
+	}}
+	res, err := c.UpdateOne(context.TODO(), query, change)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (c *TenantColl) Delete(name string) error {
+	_, err := c.DeleteOne(context.TODO(), bson.M{"name": name})
+	return err
+}
+
+func (c *TenantColl) Find(name string) (*models.Tenant, error) {
+	resp := new(models.Tenant)
+	err := c.FindOne(context.TODO(), bson.M{"name": name}).Decode(resp)
+	return resp, err
+}
+
+// FindByProject returns the tenant which the project belongs to
+func (c *TenantColl) FindByProject(projectName string) (*models.Tenant, error) {
+	resp := new(models.Tenant)
+	err := c.FindOne(context.TODO(), bson.M{"projects": projectName}).Decode(resp)
+	return resp, err
+}
+
+func (c *TenantColl) List() ([]*models.Tenant, error) {
+	resp := make([]*models.Tenant, 0)
+	cursor, err := c.Collection.Find(context.TODO(), bson.M{}, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type TestCaseResultListOption struct {
+	ProjectName string
+	TestName    string
+	ClassName   string
+	CaseName    string
+	// StartTime filters the results created no earlier than it, 0 means no limit
+	StartTime int64
+	Limit     int64
+}
+
+type TestCaseResultColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewTestCaseResultColl() *TestCaseResultColl {
+	name := models.TestCaseResult{}.TableName()
+	return &TestCaseResultColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *TestCaseResultColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *TestCaseResultColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				bson.E{Key: "project_name", Value: 1},
+				bson.E{Key: "test_name", Value: 1},
+				bson.E{Key: "create_time", Value: -1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys: bson.D{
+				bson.E{Key: "project_name", Value: 1},
+				bson.E{Key: "test_name", Value: 1},
+				bson.E{Key: "class_name", Value: 1},
+				bson.E{Key: "case_name", Value: 1},
+				bson.E{Key: "create_time", Value: -1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys: bson.D{
+				bson.E{Key: "project_name", Value: 1},
+				bson.E{Key: "workflow_name", Value: 1},
+				bson.E{Key: "task_id", Value: 1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+	}
+
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+func (c *TestCaseResultColl) BulkCreate(args []*models.TestCaseResult) error {
+	if len(args) == 0 {
+		return nil
+	}
+	var docs []interface{}
+	for _, arg := range args {
+		docs = append(docs, arg)
+	}
+	_, err := c.InsertMany(context.TODO(), docs)
+	return err
+}
+
+// DeleteByTask removes the results of a task run so that a retried job does not count twice
+func (c *TestCaseResultColl) DeleteByTask(projectName, workflowName, jobName string, taskID int64) error {
+	query := bson.M{"project_name": projectName, "workflow_name": workflowName, "job_name": jobName, "task_id": taskID}
+	_, err := c.DeleteMany(context.TODO(), query)
+	return err
+}
+
+func (c *TestCaseResultColl) List(opt *TestCaseResultListOption) ([]*models.TestCaseResult, error) {
+	query := bson.M{"project_name": opt.ProjectName, "test_name": opt.TestName}
+	if opt.ClassName != "" {
+		query["class_name"] = opt.ClassName
+	}
+	if opt.CaseName != "" {
+		query["case_name"] = opt.CaseName
+	}
+	if opt.StartTime > 0 {
+		query["create_time"] = bson.M{"$gte": opt.StartTime}
+	}
+	findOption := options.Find().SetSort(bson.D{{"create_time", -1}})
+	if opt.Limit > 0 {
+		findOption.SetLimit(opt.Limit)
+	}
+
+	resp := make([]*models.TestCaseResult, 0)
+	cursor, err := c.Find(context.TODO(), query, findOption)
+	if err != nil {
+		return nil, err
+	}
+	return resp, cursor.All(context.TODO(), &resp)
+}
+
+// ListRunStats aggregates the case results of a test by task run, the latest runs come first
+func (c *TestCaseResultColl) ListRunStats(projectName, testName string, limit int64) ([]*models.TestRunStat, error) {
+	countStatus := func(status string) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$status", status}}, 1, 0}}}
+	}
+	pipeline := []bson.M{
+		{
+			"$match": bson.M{"project_name": projectName, "test_name": testName},
+		},
+		{
+			"$group": bson.M{
+				"_id": bson.D{
+					{"workflow_name", "$workflow_name"},
+					{"task_id", "$task_id"},
+				},
+				"workflow_name": bson.M{"$first": "$workflow_name"},
+				"task_id":       bson.M{"$first": "$task_id"},
+				"create_time":   bson.M{"$min": "$create_time"},
+				"total":         bson.M{"$sum": 1},
+				"passed":        countStatus(models.TestCaseStatusPassed),
+				"failed":        countStatus(models.TestCaseStatusFailed),
+				"errors":        countStatus(models.TestCaseStatusError),
+				"skipped":       countStatus(models.TestCaseStatusSkipped),
+			},
+		},
+		{
+			"$sort": bson.M{"create_time": -1},
+		},
+	}
+	if limit > 0 {
+		pipeline = append(pipeline, bson.M{"$limit": limit})
+	}
+
+	cursor, err := c.Aggregate(context.TODO(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]*models.TestRunStat, 0)
+	return resp, cursor.All(context.TODO(), &resp)
+}
@@ -0,0 +1,324 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type VMAgentColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewVMAgentColl() *VMAgentColl {
+	name := models.VMAgent{}.TableName()
+	return &VMAgentColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *VMAgentColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *VMAgentColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys:    bson.M{"name": 1},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.M{"token_hash": 1},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+func (c *VMAgentColl) Create(args *models.VMAgent) error {
+	res, err := c.InsertOne(context.TODO(), args)
+	if err != nil {
+		return err
+	}
+	args.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (c *VMAgentColl) GetByID(idString string) (*models.VMAgent, error) {
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return nil, err
+	}
+	resp := &models.VMAgent{}
+	return resp, c.FindOne(context.TODO(), bson.M{"_id": id}).Decode(resp)
+}
+
+func (c *VMAgentColl) GetByTokenHash(tokenHash string) (*models.VMAgent, error) {
+	resp := &models.VMAgent{}
+	return resp, c.FindOne(context.TODO(), bson.M{"token_hash": tokenHash}).Decode(resp)
+}
+
+func (c *VMAgentColl) List() ([]*models.VMAgent, error) {
+	resp := make([]*models.VMAgent, 0)
+	cursor, err := c.Find(context.TODO(), bson.M{}, options.Find().SetSort(bson.D{{"name", 1}}))
+	if err != nil {
+		return nil, err
+	}
+	return resp, cursor.All(context.TODO(), &resp)
+}
+
+// ListByLabels lists the agents having all the given labels
+func (c *VMAgentColl) ListByLabels(labels []string) ([]*models.VMAgent, error) {
+	query := bson.M{}
+	if len(labels) > 0 {
+		query["labels"] = bson.M{"$all": labels}
+	}
+	resp := make([]*models.VMAgent, 0)
+	cursor, err := c.Find(context.TODO(), query)
+	if err != nil {
+		return nil, err
+	}
+	return resp, cursor.All(context.TODO(), &resp)
+}
+
+func (c *VMAgentColl) Update(id primitive.ObjectID, args *models.VMAgent) error {
+	args.ID = id
+	_, err := c.ReplaceOne(context.TODO(), bson.M{"_id": id}, args)
+	return err
+}
+
+// UpdateHeartbeat records the heartbeat of the agent, the host info is updated as well if it is set
+func (c *VMAgentColl) UpdateHeartbeat(id primitive.ObjectID, hostInfo *models.VMAgent) error {
+	change := bson.M{"last_heartbeat": time.Now().Unix()}
+	if hostInfo != nil {
+		change["hostname"] = hostInfo.Hostname
+		change["ip"] = hostInfo.IP
+		change["platform"] = hostInfo.Platform
+		change["architecture"] = hostInfo.Architecture
+		change["agent_version"] = hostInfo.AgentVersion
+	}
+	_, err := c.UpdateOne(context.TODO(), bson.M{"_id": id}, bson.M{"$set": change})
+	return err
+}
+
+func (c *VMAgentColl) DeleteByID(id primitive.ObjectID) error {
+	_, err := c.DeleteOne(context.TODO(), bson.M{"_id": id})
+	return err
+}
+
+type VMJobColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewVMJobColl() *VMJobColl {
+	name := models.VMJob{}.TableName()
+	return &VMJobColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *VMJobColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *VMJobColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				bson.E{Key: "status", Value: 1},
+				bson.E{Key: "create_time", Value: 1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys: bson.D{
+				bson.E{Key: "workflow_name", Value: 1},
+				bson.E{Key: "task_id", Value: 1},
+				bson.E{Key: "job_name", Value: 1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+	}
+
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+func (c *VMJobColl) Create(args *models.VMJob) error {
+	res, err := c.InsertOne(context.TODO(), args)
+	if err != nil {
+		return err
+	}
+	args.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (c *VMJobColl) GetByID(idString string) (*models.VMJob, error) {
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return nil, err
+	}
+	resp := &models.VMJob{}
+	return resp, c.FindOne(context.TODO(), bson.M{"_id": id}).Decode(resp)
+}
+
+// FindLatest finds the latest vm job of the job of the workflow task, a retried job has more than one
+func (c *VMJobColl) FindLatest(workflowName string, taskID int64, jobName string) (*models.VMJob, error) {
+	query := bson.M{"workflow_name": workflowName, "task_id": taskID, "job_name": jobName}
+	resp := &models.VMJob{}
+	opts := options.FindOne().SetSort(bson.D{{"create_time", -1}})
+	return resp, c.FindOne(context.TODO(), query, opts).Decode(resp)
+}
+
+// Assign hands the earliest queued job whose labels are all in the given labels over to the agent,
+// it returns nil if there is no such job
+func (c *VMJobColl) Assign(agentID string, labels []string) (*models.VMJob, error) {
+	if labels == nil {
+		labels = []string{}
+	}
+	query := bson.M{
+		"status": config.StatusQueued,
+		"labels": bson.M{"$not": bson.M{"$elemMatch": bson.M{"$nin": labels}}},
+	}
+	change := bson.M{"$set": bson.M{
+		"agent_id":   agentID,
+		"status":     config.StatusRunning,
+		"start_time": time.Now().Unix(),
+	}}
+	opts := options.FindOneAndUpdate().SetSort(bson.D{{"create_time", 1}}).SetReturnDocument(options.After)
+
+	resp := &models.VMJob{}
+	err := c.FindOneAndUpdate(context.TODO(), query, change, opts).Decode(resp)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	return resp, err
+}
+
+// ListByTask lists the jobs of the workflow task, including the retried ones
+func (c *VMJobColl) ListByTask(workflowName string, taskID int64) ([]*models.VMJob, error) {
+	resp := make([]*models.VMJob, 0)
+	cursor, err := c.Find(context.TODO(), bson.M{"workflow_name": workflowName, "task_id": taskID})
+	if err != nil {
+		return nil, err
+	}
+	return resp, cursor.All(context.TODO(), &resp)
+}
+
+// ListByAgent lists the jobs of the agent in the given status
+func (c *VMJobColl) ListByAgent(agentID string, status config.Status) ([]*models.VMJob, error) {
+	resp := make([]*models.VMJob, 0)
+	cursor, err := c.Find(context.TODO(), bson.M{"agent_id": agentID, "status": status})
+	if err != nil {
+		return nil, err
+	}
+	return resp, cursor.All(context.TODO(), &resp)
+}
+
+// Finish sets the final status of the job, it does nothing if the job has already ended,
+// it returns true if the status is changed
+func (c *VMJobColl) Finish(id primitive.ObjectID, status config.Status, outputs, errMsg string) (bool, error) {
+	query := bson.M{
+		"_id":    id,
+		"status": bson.M{"$in": []config.Status{config.StatusQueued, config.StatusRunning}},
+	}
+	change := bson.M{"$set": bson.M{
+		"status":   status,
+		"outputs":  outputs,
+		"error":    errMsg,
+		"end_time": time.Now().Unix(),
+	}}
+	res, err := c.UpdateOne(context.TODO(), query, change)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+type VMJobLogColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewVMJobLogColl() *VMJobLogColl {
+	name := models.VMJobLog{}.TableName()
+	return &VMJobLogColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *VMJobLogColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *VMJobLogColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "job_id", Value: 1},
+			bson.E{Key: "seq", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+// Append saves a chunk of the log, a chunk resent by the agent is ignored
+func (c *VMJobLogColl) Append(args *models.VMJobLog) error {
+	_, err := c.InsertOne(context.TODO(), args)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+// List lists the chunks of the log of the job after the given seq in order
+func (c *VMJobLogColl) List(jobID string, afterSeq int64) ([]*models.VMJobLog, error) {
+	query := bson.M{"job_id": jobID, "seq": bson.M{"$gt": afterSeq}}
+	resp := make([]*models.VMJobLog, 0)
+	cursor, err := c.Find(context.TODO(), query, options.Find().SetSort(bson.D{{"seq", 1}}))
+	if err != nil {
+		return nil, err
+	}
+	return resp, cursor.All(context.TODO(), &resp)
+}
+
+func (c *VMJobLogColl) DeleteByJobID(jobID string) error {
+	_, err := c.DeleteMany(context.TODO(), bson.M{"job_id": jobID})
+	return err
+}
@@ -0,0 +1,147 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type ListWebhookDeliveryOption struct {
+	Source       string
+	ProjectName  string
+	WorkflowName string
+	// Triggered only returns the deliveries that created at least one task
+	Triggered *bool
+	PageNum   int64
+	PageSize  int64
+}
+
+type WebhookDeliveryColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewWebhookDeliveryColl() *WebhookDeliveryColl {
+	name := models.WebhookDelivery{}.TableName()
+	return &WebhookDeliveryColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *WebhookDeliveryColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *WebhookDeliveryColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys:    bson.D{bson.E{Key: "create_time", Value: -1}},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys: bson.D{
+				bson.E{Key: "triggers.project_name", Value: 1},
+				bson.E{Key: "triggers.workflow_name", Value: 1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+	}
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+func (c *WebhookDeliveryColl) Create(args *models.WebhookDelivery) error {
+	res, err := c.InsertOne(context.TODO(), args)
+	if err != nil {
+		return err
+	}
+	args.ID = res.InsertedID.(primitive.ObjectID)
+	return nil
+}
+
+func (c *WebhookDeliveryColl) Get(id string) (*models.WebhookDelivery, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	resp := new(models.WebhookDelivery)
+	err = c.FindOne(context.TODO(), bson.M{"_id": oid}).Decode(resp)
+	return resp, err
+}
+
+// List returns the deliveries without the payload, the latest first
+func (c *WebhookDeliveryColl) List(opt *ListWebhookDeliveryOption) ([]*models.WebhookDelivery, int64, error) {
+	query := bson.M{}
+	if opt.Source != "" {
+		query["source"] = opt.Source
+	}
+	if opt.ProjectName != "" || opt.WorkflowName != "" {
+		trigger := bson.M{}
+		if opt.ProjectName != "" {
+			trigger["project_name"] = opt.ProjectName
+		}
+		if opt.WorkflowName != "" {
+			trigger["workflow_name"] = opt.WorkflowName
+		}
+		query["triggers"] = bson.M{"$elemMatch": trigger}
+	}
+	if opt.Triggered != nil {
+		if *opt.Triggered {
+			query["triggers.task_id"] = bson.M{"$gt": 0}
+		} else {
+			query["triggers.task_id"] = bson.M{"$not": bson.M{"$gt": 0}}
+		}
+	}
+
+	count, err := c.CountDocuments(context.TODO(), query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{"create_time", -1}}).
+		SetProjection(bson.M{"payload": 0})
+	if opt.PageNum > 0 && opt.PageSize > 0 {
+		opts.SetSkip((opt.PageNum - 1) * opt.PageSize).SetLimit(opt.PageSize)
+	}
+	cursor, err := c.Collection.Find(context.TODO(), query, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp := make([]*models.WebhookDelivery, 0)
+	if err := cursor.All(context.TODO(), &resp); err != nil {
+		return nil, 0, err
+	}
+	return resp, count, nil
+}
+
+// DeleteBefore removes the deliveries received before the unix time
+func (c *WebhookDeliveryColl) DeleteBefore(timestamp int64) error {
+	_, err := c.DeleteMany(context.TODO(), bson.M{"create_time": bson.M{"$lt": timestamp}})
+	return err
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type WorkflowPolicyColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewWorkflowPolicyColl() *WorkflowPolicyColl {
+	name := models.WorkflowPolicy{}.TableName()
+	return &WorkflowPolicyColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *WorkflowPolicyColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *WorkflowPolicyColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys:    bson.M{"name": 1},
+		Options: options.Index().SetUnique(true),
+	}
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+func (c *WorkflowPolicyColl) Create(args *models.WorkflowPolicy) error {
+	_, err := c.InsertOne(context.TODO(), args)
+	return err
+}
+
+func (c *WorkflowPolicyColl) Update(name string, args *models.WorkflowPolicy) error {
+	query := bson.M{"name": name}
+	change := bson.M{"$set": bson.M{
+		"description": args.Description,
+		"rego":        args.Rego,
+		"enabled":     args.Enabled,
+		"updated_by":  args.UpdatedBy,
+		"update_time": args.UpdateTime,
+	}}
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	return err
+}
+
+func (c *WorkflowPolicyColl) Delete(name string) error {
+	_, err := c.DeleteOne(context.TODO(), bson.M{"name": name})
+	return err
+}
+
+func (c *WorkflowPolicyColl) Find(name string) (*models.WorkflowPolicy, error) {
+	resp := new(models.WorkflowPolicy)
+	err := c.FindOne(context.TODO(), bson.M{"name": name}).Decode(resp)
+	return resp, err
+}
+
+// List lists the policies ordered by name, only the enabled ones are returned if enabledOnly is true
+func (c *WorkflowPolicyColl) List(enabledOnly bool) ([]*models.WorkflowPolicy, error) {
+	resp := make([]*models.WorkflowPolicy, 0)
+	query := bson.M{}
+	if enabledOnly {
+		query["enabled"] = true
+	}
+	cursor, err := c.Collection.Find(context.TODO(), query, options.Find().SetSort(bson.D{{"name", 1}}))
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
@@ -110,6 +110,48 @@ func (c *WorkflowQueueColl) Create(args *models.WorkflowQueue) error {
 	return err
 }
 
+// Claim sets the waiting task queued and assigns it to the controller instance, it returns false if the
+// task is not waiting anymore, e.g. it has been claimed by another instance
+func (c *WorkflowQueueColl) Claim(args *models.WorkflowQueue, instanceID string) (bool, error) {
+	if args == nil {
+		return false, errors.New("nil workflow queue")
+	}
+
+	query := bson.M{"task_id": args.TaskID, "workflow_name": args.WorkflowName, "create_time": args.CreateTime, "status": config.StatusWaiting}
+	change := bson.M{"$set": bson.M{
+		"status":      config.StatusQueued,
+		"instance_id": instanceID,
+	}}
+
+	res, err := c.UpdateOne(context.TODO(), query, change)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
+// Adopt atomically moves the task from its dead instance to the instance, it returns false if the task
+// has been adopted by another instance
+func (c *WorkflowQueueColl) Adopt(args *models.WorkflowQueue, instanceID string) (bool, error) {
+	if args == nil {
+		return false, errors.New("nil workflow queue")
+	}
+
+	query := bson.M{"task_id": args.TaskID, "workflow_name": args.WorkflowName, "create_time": args.CreateTime}
+	if args.InstanceID == "" {
+		query["instance_id"] = bson.M{"$exists": false}
+	} else {
+		query["instance_id"] = args.InstanceID
+	}
+	change := bson.M{"$set": bson.M{"instance_id": instanceID}}
+
+	res, err := c.UpdateOne(context.TODO(), query, change)
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount == 1, nil
+}
+
 func (c *WorkflowQueueColl) Update(args *models.WorkflowQueue) error {
 	if args == nil {
 		return errors.New("nil workflow queue")
@@ -0,0 +1,75 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type WorkflowTaskStageTimelineColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewWorkflowTaskStageTimelineColl() *WorkflowTaskStageTimelineColl {
+	name := models.WorkflowTaskStageTimeline{}.TableName()
+	return &WorkflowTaskStageTimelineColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *WorkflowTaskStageTimelineColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *WorkflowTaskStageTimelineColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "workflow_name", Value: 1},
+			bson.E{Key: "task_id", Value: 1},
+			bson.E{Key: "stage_name", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+	return err
+}
+
+// Push appends the event to the timeline of the stage in one update
+func (c *WorkflowTaskStageTimelineColl) Push(workflowName string, taskID int64, stageName string, event *models.StageTimelineEvent) error {
+	query := bson.M{"workflow_name": workflowName, "task_id": taskID, "stage_name": stageName}
+	change := bson.M{"$push": bson.M{"events": event}}
+	_, err := c.UpdateOne(context.TODO(), query, change, options.Update().SetUpsert(true))
+	return err
+}
+
+func (c *WorkflowTaskStageTimelineColl) ListByTask(workflowName string, taskID int64) ([]*models.WorkflowTaskStageTimeline, error) {
+	resp := make([]*models.WorkflowTaskStageTimeline, 0)
+	cursor, err := c.Find(context.TODO(), bson.M{"workflow_name": workflowName, "task_id": taskID})
+	if err != nil {
+		return nil, err
+	}
+	return resp, cursor.All(context.TODO(), &resp)
+}
@@ -45,13 +45,19 @@ type ListWorkflowTaskV4Option struct {
 
 type WorkflowTaskv4Coll struct {
 	*mongo.Collection
+	// readColl serves the task lists of the pages and the statistics with the configured read preference
+	readColl *mongo.Collection
 
 	coll string
 }
 
 func NewworkflowTaskv4Coll() *WorkflowTaskv4Coll {
 	name := models.WorkflowTask{}.TableName()
-	return &WorkflowTaskv4Coll{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+	return &WorkflowTaskv4Coll{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		readColl:   mongotool.ReadDatabase(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
 }
 
 func (c *WorkflowTaskv4Coll) GetCollectionName() string {
@@ -59,7 +65,14 @@ func (c *WorkflowTaskv4Coll) GetCollectionName() string {
 }
 
 func (c *WorkflowTaskv4Coll) EnsureIndex(ctx context.Context) error {
-	mod := []mongo.IndexModel{
+	_, err := c.Indexes().CreateMany(ctx, c.IndexModels())
+
+	return err
+}
+
+// IndexModels are the indexes of the collection, they are audited at startup
+func (c *WorkflowTaskv4Coll) IndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{
 		{
 			Keys: bson.D{
 				bson.E{Key: "task_id", Value: 1},
@@ -81,11 +94,29 @@ func (c *WorkflowTaskv4Coll) EnsureIndex(ctx context.Context) error {
 			},
 			Options: options.Index().SetUnique(false),
 		},
+		// the latest tasks of a workflow
+		{
+			Keys: bson.D{
+				bson.E{Key: "workflow_name", Value: 1},
+				bson.E{Key: "task_id", Value: -1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys: bson.D{
+				bson.E{Key: "workflow_name", Value: 1},
+				bson.E{Key: "create_time", Value: -1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys: bson.D{
+				bson.E{Key: "project_name", Value: 1},
+				bson.E{Key: "create_time", Value: -1},
+			},
+			Options: options.Index().SetUnique(false),
+		},
 	}
-
-	_, err := c.Indexes().CreateMany(ctx, mod)
-
-	return err
 }
 
 func (c *WorkflowTaskv4Coll) Create(obj *models.WorkflowTask) (string, error) {
@@ -201,6 +232,38 @@ func (c *WorkflowTaskv4Coll) InCompletedTasks() ([]*models.WorkflowTask, error)
 	return ret, nil
 }
 
+// ListLogExpiringTasks lists the completed tasks of the project created before the given time whose logs are not removed yet
+func (c *WorkflowTaskv4Coll) ListLogExpiringTasks(projectName string, before int64) ([]*models.WorkflowTask, error) {
+	ret := make([]*models.WorkflowTask, 0)
+	query := bson.M{
+		"project_name": projectName,
+		"create_time":  bson.M{"$lt": before},
+		"status":       bson.M{"$nin": config.InCompletedStatus()},
+		"log_expired":  bson.M{"$ne": true},
+	}
+
+	opt := options.Find().SetProjection(bson.M{"workflow_name": 1, "task_id": 1, "project_name": 1, "create_time": 1})
+	cursor, err := c.Collection.Find(context.TODO(), query, opt)
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &ret)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func (c *WorkflowTaskv4Coll) SetLogExpired(workflowName string, taskID int64) error {
+	query := bson.M{"workflow_name": workflowName, "task_id": taskID}
+	change := bson.M{"$set": bson.M{
+		"log_expired": true,
+	}}
+
+	_, err := c.UpdateOne(context.TODO(), query, change)
+	return err
+}
+
 func (c *WorkflowTaskv4Coll) Find(workflowName string, taskID int64) (*models.WorkflowTask, error) {
 	resp := new(models.WorkflowTask)
 	query := bson.M{"workflow_name": workflowName, "task_id": taskID}
@@ -256,6 +319,18 @@ func (c *WorkflowTaskv4Coll) Update(idString string, obj *models.WorkflowTask) e
 	return err
 }
 
+// UpdateJobMetricComparison saves the metric comparison of the deploy job identified by the job key
+func (c *WorkflowTaskv4Coll) UpdateJobMetricComparison(workflowName string, taskID int64, jobKey string, comparison *models.MetricComparison) error {
+	query := bson.M{"workflow_name": workflowName, "task_id": taskID}
+	change := bson.M{"$set": bson.M{"stages.$[].jobs.$[job].spec.metric_comparison": comparison}}
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{
+		Filters: []interface{}{bson.M{"job.key": jobKey}},
+	})
+
+	_, err := c.UpdateOne(context.TODO(), query, change, opts)
+	return err
+}
+
 func (c *WorkflowTaskv4Coll) DeleteByWorkflowName(workflowName string) error {
 	query := bson.M{"workflow_name": workflowName}
 	change := bson.M{"$set": bson.M{
@@ -319,7 +394,7 @@ func (c *WorkflowTaskv4Coll) ListByCursor(opt *ListWorkflowTaskV4Option) (*mongo
 		opts.SetSort(bson.D{{"create_time", -1}})
 	}
 
-	return c.Collection.Find(context.TODO(), query, opts)
+	return c.readColl.Find(context.TODO(), query, opts)
 }
 
 func (c *WorkflowTaskv4Coll) ListCreator(projectName, name string) ([]string, error) {
@@ -355,6 +430,41 @@ type WorkFlowTaskFilter struct {
 
 func (c *WorkflowTaskv4Coll) ListByFilter(filter *WorkFlowTaskFilter, pageNum, pageSize int64) ([]*models.WorkflowTask, int64, error) {
 	tasks := make([]*models.WorkflowTask, 0)
+	query := getWorkflowTaskFilterQuery(filter)
+
+	opt := options.Find()
+	if pageNum > 0 {
+		opt.SetSort(bson.D{{"create_time", -1}})
+		opt.SetSkip((pageNum - 1) * pageSize).
+			SetLimit(pageSize)
+	}
+
+	ctx, cancel := mongotool.QueryContext(context.TODO())
+	defer cancel()
+
+	count, err := c.readColl.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cursor, err := c.readColl.Find(ctx, query, opt)
+	if err != nil {
+		return nil, 0, err
+	}
+	err = cursor.All(ctx, &tasks)
+	if err != nil {
+		return nil, 0, err
+	}
+	return tasks, count, nil
+}
+
+// ListByFilterCursor returns a cursor over all the tasks matching the filter, the latest task first
+func (c *WorkflowTaskv4Coll) ListByFilterCursor(ctx context.Context, filter *WorkFlowTaskFilter) (*mongo.Cursor, error) {
+	opt := options.Find().SetSort(bson.D{{"create_time", -1}})
+	return c.readColl.Find(ctx, getWorkflowTaskFilterQuery(filter), opt)
+}
+
+func getWorkflowTaskFilterQuery(filter *WorkFlowTaskFilter) bson.M {
 	query := bson.M{}
 	if filter.StartTime > 0 {
 		query["create_time"] = bson.M{"$gte": filter.StartTime}
@@ -394,26 +504,78 @@ func (c *WorkflowTaskv4Coll) ListByFilter(filter *WorkFlowTaskFilter, pageNum, p
 			},
 		}
 	}
+	return query
+}
 
-	opt := options.Find()
-	if pageNum > 0 {
-		opt.SetSort(bson.D{{"create_time", -1}})
-		opt.SetSkip((pageNum - 1) * pageSize).
-			SetLimit(pageSize)
-	}
+type WorkflowTaskIDStat struct {
+	MaxTaskID int64 `bson:"max_task_id"`
+	TaskCount int64 `bson:"task_count"`
+}
 
-	count, err := c.CountDocuments(context.TODO(), query)
+// GetTaskIDStat returns the max task id and the count of the tasks of the workflow, the deleted and
+// archived tasks are counted since they keep their task ids
+func (c *WorkflowTaskv4Coll) GetTaskIDStat(workflowName string) (*WorkflowTaskIDStat, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{"workflow_name": workflowName}},
+		{"$group": bson.M{
+			"_id":         nil,
+			"max_task_id": bson.M{"$max": "$task_id"},
+			"task_count":  bson.M{"$sum": 1},
+		}},
+	}
+	cursor, err := c.Aggregate(context.TODO(), pipeline)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
+	}
+	resp := make([]*WorkflowTaskIDStat, 0)
+	if err := cursor.All(context.TODO(), &resp); err != nil {
+		return nil, err
 	}
+	if len(resp) == 0 {
+		return &WorkflowTaskIDStat{}, nil
+	}
+	return resp[0], nil
+}
 
-	cursor, err := c.Collection.Find(context.TODO(), query, opt)
-	if err != nil {
-		return nil, 0, err
+// ListDuplicateTaskIDs returns the task ids used by more than one task of the workflow
+func (c *WorkflowTaskv4Coll) ListDuplicateTaskIDs(workflowName string) ([]int64, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{"workflow_name": workflowName}},
+		{"$group": bson.M{"_id": "$task_id", "count": bson.M{"$sum": 1}}},
+		{"$match": bson.M{"count": bson.M{"$gt": 1}}},
+		{"$sort": bson.M{"_id": 1}},
 	}
-	err = cursor.All(context.TODO(), &tasks)
+	cursor, err := c.Aggregate(context.TODO(), pipeline)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
-	return tasks, count, nil
+	res := make([]struct {
+		TaskID int64 `bson:"_id"`
+	}, 0)
+	if err := cursor.All(context.TODO(), &res); err != nil {
+		return nil, err
+	}
+	resp := make([]int64, 0, len(res))
+	for _, item := range res {
+		resp = append(resp, item.TaskID)
+	}
+	return resp, nil
+}
+
+func (c *WorkflowTaskv4Coll) CountByProject(projectName string) (int64, error) {
+	return c.CountDocuments(context.TODO(), bson.M{"project_name": projectName})
+}
+
+func (c *WorkflowTaskv4Coll) RenameProject(oldKey, newKey string) (int64, error) {
+	var modified int64
+	for _, field := range []string{"workflow_args.project", "origin_workflow_args.project", "project_name"} {
+		res, err := c.UpdateMany(context.TODO(), bson.M{field: oldKey}, bson.M{"$set": bson.M{field: newKey}})
+		if err != nil {
+			return modified, err
+		}
+		if field == "project_name" {
+			modified = res.ModifiedCount
+		}
+	}
+	return modified, nil
 }
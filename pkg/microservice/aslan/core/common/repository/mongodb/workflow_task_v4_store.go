@@ -0,0 +1,114 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"errors"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+// WorkflowTaskV4Store is the storage of the custom workflow tasks, the tasks are kept in mongodb by default.
+// The implementations return mongo.ErrNoDocuments when the task is not found since the callers check it.
+type WorkflowTaskV4Store interface {
+	Create(obj *models.WorkflowTask) (string, error)
+	Update(idString string, obj *models.WorkflowTask) error
+	Find(workflowName string, taskID int64) (*models.WorkflowTask, error)
+	GetByID(idstring string) (*models.WorkflowTask, error)
+	GetLatest(workflowName string) (*models.WorkflowTask, error)
+	FindPreviousTask(workflowName, username string) (*models.WorkflowTask, error)
+	List(opt *ListWorkflowTaskV4Option) ([]*models.WorkflowTask, int64, error)
+	ListByFilter(filter *WorkFlowTaskFilter, pageNum, pageSize int64) ([]*models.WorkflowTask, int64, error)
+	// ForEach calls fn with the tasks of the option one by one, it stops at the first error of fn and
+	// ErrStopIteration stops it without an error
+	ForEach(ctx context.Context, opt *ListWorkflowTaskV4Option, fn func(*models.WorkflowTask) error) error
+	// ForEachByFilter calls fn with the tasks matching the filter one by one, the latest task first
+	ForEachByFilter(ctx context.Context, filter *WorkFlowTaskFilter, fn func(*models.WorkflowTask) error) error
+	FindTodoTasksByWorkflowName(workflowName string) ([]*models.WorkflowTask, error)
+	InCompletedTasks() ([]*models.WorkflowTask, error)
+	ListCreator(projectName, name string) ([]string, error)
+	ListLogExpiringTasks(projectName string, before int64) ([]*models.WorkflowTask, error)
+	SetLogExpired(workflowName string, taskID int64) error
+	ArchiveHistoryWorkflowTask(workflowName string, remain, remainDays int) error
+	DeleteByWorkflowName(workflowName string) error
+	GetTaskIDStat(workflowName string) (*WorkflowTaskIDStat, error)
+	ListDuplicateTaskIDs(workflowName string) ([]int64, error)
+	// CountByProject returns the number of the tasks of the project, including the archived and deleted ones
+	CountByProject(projectName string) (int64, error)
+	// RenameProject moves all the tasks of the project to the new project key, it returns the number of the tasks moved
+	RenameProject(oldKey, newKey string) (int64, error)
+}
+
+// ErrStopIteration is returned by the fn of ForEach to stop the iteration
+var ErrStopIteration = errors.New("stop iteration")
+
+var workflowTaskV4Store WorkflowTaskV4Store
+
+// SetWorkflowTaskV4Store replaces the mongodb storage of the custom workflow tasks, it is called at startup
+func SetWorkflowTaskV4Store(store WorkflowTaskV4Store) {
+	workflowTaskV4Store = store
+}
+
+func NewWorkflowTaskV4Store() WorkflowTaskV4Store {
+	if workflowTaskV4Store != nil {
+		return workflowTaskV4Store
+	}
+	return NewworkflowTaskv4Coll()
+}
+
+func (c *WorkflowTaskv4Coll) ForEach(ctx context.Context, opt *ListWorkflowTaskV4Option, fn func(*models.WorkflowTask) error) error {
+	cursor, err := c.ListByCursor(opt)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		task := new(models.WorkflowTask)
+		if err := cursor.Decode(task); err != nil {
+			return err
+		}
+		if err := fn(task); err != nil {
+			if err == ErrStopIteration {
+				return nil
+			}
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+func (c *WorkflowTaskv4Coll) ForEachByFilter(ctx context.Context, filter *WorkFlowTaskFilter, fn func(*models.WorkflowTask) error) error {
+	cursor, err := c.ListByFilterCursor(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		task := new(models.WorkflowTask)
+		if err := cursor.Decode(task); err != nil {
+			return err
+		}
+		if err := fn(task); err != nil {
+			if err == ErrStopIteration {
+				return nil
+			}
+			return err
+		}
+	}
+	return cursor.Err()
+}
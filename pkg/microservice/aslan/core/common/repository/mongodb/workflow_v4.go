@@ -61,7 +61,14 @@ func (c *WorkflowV4Coll) GetCollectionName() string {
 }
 
 func (c *WorkflowV4Coll) EnsureIndex(ctx context.Context) error {
-	mod := []mongo.IndexModel{
+	_, err := c.Indexes().CreateMany(ctx, c.IndexModels())
+
+	return err
+}
+
+// IndexModels are the indexes of the collection, they are audited at startup
+func (c *WorkflowV4Coll) IndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{
 		{
 			Keys: bson.D{
 				bson.E{Key: "project", Value: 1},
@@ -76,10 +83,12 @@ func (c *WorkflowV4Coll) EnsureIndex(ctx context.Context) error {
 			},
 			Options: options.Index().SetUnique(false),
 		},
+		// the workflows are found by name without the project
+		{
+			Keys:    bson.M{"name": 1},
+			Options: options.Index().SetUnique(false),
+		},
 	}
-	_, err := c.Indexes().CreateMany(ctx, mod)
-
-	return err
 }
 
 type WorkflowV4 struct {
@@ -306,6 +315,34 @@ func (c *WorkflowV4Coll) Update(idString string, obj *models.WorkflowV4) error {
 	return err
 }
 
+// ErrWorkflowV4Changed means the workflow is changed by others since it is read
+var ErrWorkflowV4Changed = errors.New("workflow is changed by others, please retry")
+
+// UpdateIfUnchanged updates the workflow only if its hash is still the one read from the db
+func (c *WorkflowV4Coll) UpdateIfUnchanged(idString, hash string, obj *models.WorkflowV4) error {
+	if obj == nil {
+		return fmt.Errorf("nil object")
+	}
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return fmt.Errorf("invalid id")
+	}
+	filter := bson.M{"_id": id, "hash": hash}
+	if hash == "" {
+		filter["hash"] = bson.M{"$in": bson.A{"", nil}}
+	}
+	obj.UpdateHash()
+
+	res, err := c.UpdateOne(context.TODO(), filter, bson.M{"$set": obj})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrWorkflowV4Changed
+	}
+	return nil
+}
+
 func (c *WorkflowV4Coll) DeleteByID(idString string) error {
 	id, err := primitive.ObjectIDFromHex(idString)
 	if err != nil {
@@ -0,0 +1,108 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type WorkflowV4TrashColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewWorkflowV4TrashColl() *WorkflowV4TrashColl {
+	name := models.WorkflowV4Trash{}.TableName()
+	return &WorkflowV4TrashColl{
+		Collection: mongotool.Database(config.MongoDatabase()).Collection(name),
+		coll:       name,
+	}
+}
+
+func (c *WorkflowV4TrashColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *WorkflowV4TrashColl) EnsureIndex(ctx context.Context) error {
+	mod := []mongo.IndexModel{
+		{
+			Keys:    bson.D{bson.E{Key: "workflow_name", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{bson.E{Key: "project_name", Value: 1}},
+			Options: options.Index().SetUnique(false),
+		},
+		{
+			Keys:    bson.D{bson.E{Key: "expire_time", Value: 1}},
+			Options: options.Index().SetUnique(false),
+		},
+	}
+	_, err := c.Indexes().CreateMany(ctx, mod)
+	return err
+}
+
+func (c *WorkflowV4TrashColl) Create(args *models.WorkflowV4Trash) error {
+	_, err := c.InsertOne(context.TODO(), args)
+	return err
+}
+
+func (c *WorkflowV4TrashColl) Find(workflowName string) (*models.WorkflowV4Trash, error) {
+	resp := new(models.WorkflowV4Trash)
+	err := c.FindOne(context.TODO(), bson.M{"workflow_name": workflowName}).Decode(resp)
+	return resp, err
+}
+
+// List returns the trashed workflows of the project, all of them if the project is empty
+func (c *WorkflowV4TrashColl) List(projectName string) ([]*models.WorkflowV4Trash, error) {
+	query := bson.M{}
+	if projectName != "" {
+		query["project_name"] = projectName
+	}
+	resp := make([]*models.WorkflowV4Trash, 0)
+	cursor, err := c.Collection.Find(context.TODO(), query, options.Find().SetSort(bson.D{{"delete_time", -1}}))
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+// ListExpired returns the trashed workflows expired before the unix time
+func (c *WorkflowV4TrashColl) ListExpired(timestamp int64) ([]*models.WorkflowV4Trash, error) {
+	resp := make([]*models.WorkflowV4Trash, 0)
+	cursor, err := c.Collection.Find(context.TODO(), bson.M{"expire_time": bson.M{"$lt": timestamp}})
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+func (c *WorkflowV4TrashColl) Delete(workflowName string) error {
+	_, err := c.DeleteOne(context.TODO(), bson.M{"workflow_name": workflowName})
+	return err
+}
@@ -48,16 +48,26 @@ func (c *WorkflowViewColl) GetCollectionName() string {
 }
 
 func (c *WorkflowViewColl) EnsureIndex(ctx context.Context) error {
-	mod := mongo.IndexModel{
-		Keys: bson.D{
-			bson.E{Key: "name", Value: 1},
-			bson.E{Key: "project_name", Value: 1},
+	_, err := c.Indexes().CreateMany(ctx, c.IndexModels())
+	return err
+}
+
+// IndexModels are the indexes of the collection, they are audited at startup
+func (c *WorkflowViewColl) IndexModels() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				bson.E{Key: "name", Value: 1},
+				bson.E{Key: "project_name", Value: 1},
+			},
+			Options: options.Index().SetUnique(true),
+		},
+		// the views are listed by project
+		{
+			Keys:    bson.M{"project_name": 1},
+			Options: options.Index().SetUnique(false),
 		},
-		Options: options.Index().SetUnique(true),
 	}
-
-	_, err := c.Indexes().CreateOne(ctx, mod)
-	return err
 }
 
 func (c *WorkflowViewColl) Create(args *models.WorkflowView) error {
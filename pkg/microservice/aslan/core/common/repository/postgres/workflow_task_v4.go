@@ -0,0 +1,561 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+)
+
+const workflowTaskV4Table = "workflow_task_v4"
+
+// the columns are the fields used by the queries, the task itself is kept as a bson document so that
+// it is decoded in the same way as the one in mongodb
+var workflowTaskV4Schema = []string{
+	`CREATE TABLE IF NOT EXISTS workflow_task_v4 (
+		id            VARCHAR(24) PRIMARY KEY,
+		workflow_name TEXT        NOT NULL,
+		project_name  TEXT        NOT NULL DEFAULT '',
+		task_id       BIGINT      NOT NULL,
+		task_creator  TEXT        NOT NULL DEFAULT '',
+		status        TEXT        NOT NULL DEFAULT '',
+		create_time   BIGINT      NOT NULL DEFAULT 0,
+		is_deleted    BOOLEAN     NOT NULL DEFAULT FALSE,
+		is_archived   BOOLEAN     NOT NULL DEFAULT FALSE,
+		log_expired   BOOLEAN     NOT NULL DEFAULT FALSE,
+		document      BYTEA       NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS workflow_task_v4_workflow_task_id ON workflow_task_v4 (workflow_name, task_id DESC)`,
+	`CREATE INDEX IF NOT EXISTS workflow_task_v4_workflow_create_time ON workflow_task_v4 (workflow_name, create_time DESC)`,
+	`CREATE INDEX IF NOT EXISTS workflow_task_v4_project_create_time ON workflow_task_v4 (project_name, create_time DESC)`,
+	`CREATE INDEX IF NOT EXISTS workflow_task_v4_status ON workflow_task_v4 (status)`,
+}
+
+const workflowTaskV4Columns = "id, is_deleted, is_archived, log_expired, document"
+
+// bsonRegistry decodes the embedded documents into bson.M as mongotool does
+var bsonRegistry = bson.NewRegistryBuilder().RegisterTypeMapEntry(bsontype.EmbeddedDocument, reflect.TypeOf(bson.M{})).Build()
+
+// WorkflowTaskV4Store keeps the custom workflow tasks in postgresql
+type WorkflowTaskV4Store struct {
+	db *sql.DB
+}
+
+var _ mongodb.WorkflowTaskV4Store = &WorkflowTaskV4Store{}
+
+func NewWorkflowTaskV4Store(dsn string) (*WorkflowTaskV4Store, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		return nil, fmt.Errorf("failed to connect to postgresql: %s", err)
+	}
+	for _, stmt := range workflowTaskV4Schema {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return nil, fmt.Errorf("failed to create table %s: %s", workflowTaskV4Table, err)
+		}
+	}
+	return &WorkflowTaskV4Store{db: db}, nil
+}
+
+// query builds the where clause, the ? in the conditions are replaced by the numbered placeholders
+type query struct {
+	conds []string
+	args  []interface{}
+}
+
+func (q *query) where(cond string, args ...interface{}) *query {
+	for _, arg := range args {
+		q.args = append(q.args, arg)
+		cond = strings.Replace(cond, "?", fmt.Sprintf("$%d", len(q.args)), 1)
+	}
+	q.conds = append(q.conds, cond)
+	return q
+}
+
+func (q *query) String() string {
+	if len(q.conds) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(q.conds, " AND ")
+}
+
+func (q *query) arg(arg interface{}) string {
+	q.args = append(q.args, arg)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+func statusArray(statuses []config.Status) interface{} {
+	resp := make([]string, 0, len(statuses))
+	for _, status := range statuses {
+		resp = append(resp, string(status))
+	}
+	return pq.Array(resp)
+}
+
+func encodeWorkflowTask(id primitive.ObjectID, obj *models.WorkflowTask) ([]byte, error) {
+	task := *obj
+	task.ID = id
+	return bson.Marshal(&task)
+}
+
+func scanWorkflowTask(scanner interface{ Scan(...interface{}) error }) (*models.WorkflowTask, error) {
+	var id string
+	var isDeleted, isArchived, logExpired bool
+	var document []byte
+	if err := scanner.Scan(&id, &isDeleted, &isArchived, &logExpired, &document); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, mongo.ErrNoDocuments
+		}
+		return nil, err
+	}
+	task := new(models.WorkflowTask)
+	if err := bson.UnmarshalWithRegistry(bsonRegistry, document, task); err != nil {
+		return nil, fmt.Errorf("failed to decode task %s: %s", id, err)
+	}
+	// the flags are updated in the columns only
+	task.IsDeleted = isDeleted
+	task.IsArchived = isArchived
+	task.LogExpired = logExpired
+	return task, nil
+}
+
+func (s *WorkflowTaskV4Store) findOne(q *query, orderBy string) (*models.WorkflowTask, error) {
+	stmt := "SELECT " + workflowTaskV4Columns + " FROM " + workflowTaskV4Table + q.String() + orderBy + " LIMIT 1"
+	return scanWorkflowTask(s.db.QueryRow(stmt, q.args...))
+}
+
+func (s *WorkflowTaskV4Store) forEach(ctx context.Context, stmt string, args []interface{}, fn func(*models.WorkflowTask) error) error {
+	rows, err := s.db.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		task, err := scanWorkflowTask(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(task); err != nil {
+			if err == mongodb.ErrStopIteration {
+				return nil
+			}
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (s *WorkflowTaskV4Store) list(q *query, suffix string) ([]*models.WorkflowTask, error) {
+	resp := make([]*models.WorkflowTask, 0)
+	stmt := "SELECT " + workflowTaskV4Columns + " FROM " + workflowTaskV4Table + q.String() + suffix
+	err := s.forEach(context.TODO(), stmt, q.args, func(task *models.WorkflowTask) error {
+		resp = append(resp, task)
+		return nil
+	})
+	return resp, err
+}
+
+func (s *WorkflowTaskV4Store) count(q *query) (int64, error) {
+	var count int64
+	err := s.db.QueryRow("SELECT COUNT(*) FROM "+workflowTaskV4Table+q.String(), q.args...).Scan(&count)
+	return count, err
+}
+
+func (s *WorkflowTaskV4Store) Create(obj *models.WorkflowTask) (string, error) {
+	if obj == nil {
+		return "", fmt.Errorf("nil object")
+	}
+	id := primitive.NewObjectID()
+	document, err := encodeWorkflowTask(id, obj)
+	if err != nil {
+		return "", err
+	}
+	_, err = s.db.Exec("INSERT INTO "+workflowTaskV4Table+
+		" (id, workflow_name, project_name, task_id, task_creator, status, create_time, is_deleted, is_archived, log_expired, document)"+
+		" VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)",
+		id.Hex(), obj.WorkflowName, obj.ProjectName, obj.TaskID, obj.TaskCreator, string(obj.Status), obj.CreateTime,
+		obj.IsDeleted, obj.IsArchived, obj.LogExpired, document)
+	if err != nil {
+		return "", err
+	}
+	return id.Hex(), nil
+}
+
+func (s *WorkflowTaskV4Store) Update(idString string, obj *models.WorkflowTask) error {
+	if obj == nil {
+		return fmt.Errorf("nil object")
+	}
+	id, err := primitive.ObjectIDFromHex(idString)
+	if err != nil {
+		return fmt.Errorf("invalid id")
+	}
+	document, err := encodeWorkflowTask(id, obj)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec("UPDATE "+workflowTaskV4Table+
+		" SET workflow_name = $2, project_name = $3, task_id = $4, task_creator = $5, status = $6, create_time = $7,"+
+		" is_deleted = $8, is_archived = $9, log_expired = $10, document = $11 WHERE id = $1",
+		id.Hex(), obj.WorkflowName, obj.ProjectName, obj.TaskID, obj.TaskCreator, string(obj.Status), obj.CreateTime,
+		obj.IsDeleted, obj.IsArchived, obj.LogExpired, document)
+	return err
+}
+
+func (s *WorkflowTaskV4Store) Find(workflowName string, taskID int64) (*models.WorkflowTask, error) {
+	q := new(query).where("workflow_name = ?", workflowName).where("task_id = ?", taskID)
+	return s.findOne(q, "")
+}
+
+func (s *WorkflowTaskV4Store) GetByID(idstring string) (*models.WorkflowTask, error) {
+	if _, err := primitive.ObjectIDFromHex(idstring); err != nil {
+		return nil, err
+	}
+	return s.findOne(new(query).where("id = ?", idstring), "")
+}
+
+func (s *WorkflowTaskV4Store) GetLatest(workflowName string) (*models.WorkflowTask, error) {
+	return s.findOne(new(query).where("workflow_name = ?", workflowName), " ORDER BY create_time DESC")
+}
+
+func (s *WorkflowTaskV4Store) FindPreviousTask(workflowName, username string) (*models.WorkflowTask, error) {
+	q := new(query).where("workflow_name = ?", workflowName).where("task_creator = ?", username)
+	return s.findOne(q, " ORDER BY create_time DESC")
+}
+
+func listOptionQuery(opt *mongodb.ListWorkflowTaskV4Option) *query {
+	q := new(query)
+	if opt.WorkflowNames != nil {
+		q.where("workflow_name = ANY(?)", pq.Array(opt.WorkflowNames))
+	} else if opt.WorkflowName != "" {
+		q.where("workflow_name = ?", opt.WorkflowName)
+	}
+	if opt.ProjectName != "" {
+		q.where("project_name = ?", opt.ProjectName)
+	}
+	if len(opt.ProjectNames) > 0 {
+		q.where("project_name = ANY(?)", pq.Array(opt.ProjectNames))
+	}
+	q.where("is_archived = FALSE").where("is_deleted = FALSE")
+	if opt.CreateTime > 0 {
+		if opt.BeforeCreatTime {
+			q.where("create_time <= ?", opt.CreateTime)
+		} else {
+			q.where("create_time >= ?", opt.CreateTime)
+		}
+	}
+	return q
+}
+
+func (s *WorkflowTaskV4Store) List(opt *mongodb.ListWorkflowTaskV4Option) ([]*models.WorkflowTask, int64, error) {
+	q := listOptionQuery(opt)
+	count, err := s.count(q)
+	if err != nil {
+		return nil, 0, err
+	}
+	suffix := ""
+	if opt.Limit > 0 {
+		suffix = fmt.Sprintf(" ORDER BY create_time DESC OFFSET %s LIMIT %s", q.arg(opt.Skip), q.arg(opt.Limit))
+	}
+	resp, err := s.list(q, suffix)
+	if err != nil {
+		return nil, 0, err
+	}
+	return resp, count, nil
+}
+
+func (s *WorkflowTaskV4Store) ForEach(ctx context.Context, opt *mongodb.ListWorkflowTaskV4Option, fn func(*models.WorkflowTask) error) error {
+	q := listOptionQuery(opt)
+	stmt := "SELECT " + workflowTaskV4Columns + " FROM " + workflowTaskV4Table + q.String()
+	if opt.IsSort {
+		stmt += " ORDER BY create_time DESC"
+	}
+	return s.forEach(ctx, stmt, q.args, fn)
+}
+
+// filterQuery returns the query of the filter, the services and envs of the jobs are matched by matchJobFilter
+func filterQuery(filter *mongodb.WorkFlowTaskFilter) *query {
+	q := new(query).
+		where("project_name = ?", filter.ProjectName).
+		where("workflow_name = ?", filter.WorkflowName).
+		where("is_archived = FALSE").
+		where("is_deleted = FALSE")
+	if filter.StartTime > 0 {
+		q.where("create_time >= ?", filter.StartTime).where("create_time <= ?", filter.EndTime)
+	}
+	if len(filter.Creator) > 0 {
+		q.where("task_creator = ANY(?)", pq.Array(filter.Creator))
+	}
+	if len(filter.Status) > 0 {
+		q.where("status = ANY(?)", pq.Array(filter.Status))
+	}
+	return q
+}
+
+// matchJobFilter matches the job of the filter as the elemMatch of the mongodb query does
+func matchJobFilter(task *models.WorkflowTask, filter *mongodb.WorkFlowTaskFilter) bool {
+	if len(filter.Service) == 0 && len(filter.Env) == 0 {
+		return true
+	}
+	if task.WorkflowArgs == nil {
+		return false
+	}
+	for _, stage := range task.WorkflowArgs.Stages {
+		for _, job := range stage.Jobs {
+			if job.Name != filter.JobName || job.Skipped {
+				continue
+			}
+			if len(filter.Env) > 0 {
+				spec := bson.M{}
+				if err := models.IToi(job.Spec, &spec); err == nil && containsString(filter.Env, fmt.Sprint(spec["env"])) {
+					return true
+				}
+				continue
+			}
+			for _, module := range job.ServiceModules {
+				if containsString(filter.Service, module.ServiceModule) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *WorkflowTaskV4Store) ListByFilter(filter *mongodb.WorkFlowTaskFilter, pageNum, pageSize int64) ([]*models.WorkflowTask, int64, error) {
+	q := filterQuery(filter)
+	if len(filter.Service) == 0 && len(filter.Env) == 0 {
+		count, err := s.count(q)
+		if err != nil {
+			return nil, 0, err
+		}
+		suffix := ""
+		if pageNum > 0 {
+			suffix = fmt.Sprintf(" ORDER BY create_time DESC OFFSET %s LIMIT %s", q.arg((pageNum-1)*pageSize), q.arg(pageSize))
+		}
+		tasks, err := s.list(q, suffix)
+		if err != nil {
+			return nil, 0, err
+		}
+		return tasks, count, nil
+	}
+
+	// the jobs are matched after the tasks are decoded, so the page is cut from the matched tasks
+	matched := make([]*models.WorkflowTask, 0)
+	if err := s.ForEachByFilter(context.TODO(), filter, func(task *models.WorkflowTask) error {
+		matched = append(matched, task)
+		return nil
+	}); err != nil {
+		return nil, 0, err
+	}
+	count := int64(len(matched))
+	if pageNum > 0 {
+		start := (pageNum - 1) * pageSize
+		if start > count {
+			start = count
+		}
+		end := start + pageSize
+		if end > count {
+			end = count
+		}
+		matched = matched[start:end]
+	}
+	return matched, count, nil
+}
+
+func (s *WorkflowTaskV4Store) ForEachByFilter(ctx context.Context, filter *mongodb.WorkFlowTaskFilter, fn func(*models.WorkflowTask) error) error {
+	q := filterQuery(filter)
+	stmt := "SELECT " + workflowTaskV4Columns + " FROM " + workflowTaskV4Table + q.String() + " ORDER BY create_time DESC"
+	return s.forEach(ctx, stmt, q.args, func(task *models.WorkflowTask) error {
+		if !matchJobFilter(task, filter) {
+			return nil
+		}
+		return fn(task)
+	})
+}
+
+func (s *WorkflowTaskV4Store) FindTodoTasksByWorkflowName(workflowName string) ([]*models.WorkflowTask, error) {
+	q := new(query).
+		where("status = ANY(?)", pq.Array([]string{"waiting", "queued", "created", "running", "blocked"})).
+		where("workflow_name = ?", workflowName).
+		where("is_deleted = FALSE").
+		where("is_archived = FALSE")
+	return s.list(q, " ORDER BY create_time ASC")
+}
+
+func (s *WorkflowTaskV4Store) InCompletedTasks() ([]*models.WorkflowTask, error) {
+	q := new(query).where("status = ANY(?)", statusArray(config.InCompletedStatus())).where("is_deleted = FALSE")
+	return s.list(q, " ORDER BY create_time ASC")
+}
+
+func (s *WorkflowTaskV4Store) ListCreator(projectName, name string) ([]string, error) {
+	rows, err := s.db.Query("SELECT DISTINCT task_creator FROM "+workflowTaskV4Table+
+		" WHERE project_name = $1 AND workflow_name = $2 AND task_creator <> ''", projectName, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	creators := make([]string, 0)
+	for rows.Next() {
+		var creator string
+		if err := rows.Scan(&creator); err != nil {
+			return nil, err
+		}
+		creators = append(creators, creator)
+	}
+	return creators, rows.Err()
+}
+
+func (s *WorkflowTaskV4Store) ListLogExpiringTasks(projectName string, before int64) ([]*models.WorkflowTask, error) {
+	q := new(query).
+		where("project_name = ?", projectName).
+		where("create_time < ?", before).
+		where("NOT (status = ANY(?))", statusArray(config.InCompletedStatus())).
+		where("log_expired = FALSE")
+	return s.list(q, "")
+}
+
+func (s *WorkflowTaskV4Store) SetLogExpired(workflowName string, taskID int64) error {
+	_, err := s.db.Exec("UPDATE "+workflowTaskV4Table+" SET log_expired = TRUE WHERE workflow_name = $1 AND task_id = $2", workflowName, taskID)
+	return err
+}
+
+func (s *WorkflowTaskV4Store) ArchiveHistoryWorkflowTask(workflowName string, remain, remainDays int) error {
+	if remain == 0 && remainDays == 0 {
+		return nil
+	}
+	q := new(query).where("workflow_name = ?", workflowName).where("is_deleted = FALSE").where("is_archived = FALSE")
+	count, err := s.count(q)
+	if err != nil {
+		return err
+	}
+	if remain > 0 {
+		q.where("task_id < ?", count-int64(remain)+1)
+	}
+	if remainDays > 0 {
+		q.where("create_time < ?", time.Now().AddDate(0, 0, -remainDays).Unix())
+	}
+	_, err = s.db.Exec("UPDATE "+workflowTaskV4Table+" SET is_archived = TRUE"+q.String(), q.args...)
+	return err
+}
+
+func (s *WorkflowTaskV4Store) DeleteByWorkflowName(workflowName string) error {
+	_, err := s.db.Exec("UPDATE "+workflowTaskV4Table+" SET is_deleted = TRUE, is_archived = TRUE WHERE workflow_name = $1", workflowName)
+	return err
+}
+
+func (s *WorkflowTaskV4Store) GetTaskIDStat(workflowName string) (*mongodb.WorkflowTaskIDStat, error) {
+	resp := new(mongodb.WorkflowTaskIDStat)
+	err := s.db.QueryRow("SELECT COALESCE(MAX(task_id), 0), COUNT(*) FROM "+workflowTaskV4Table+" WHERE workflow_name = $1", workflowName).
+		Scan(&resp.MaxTaskID, &resp.TaskCount)
+	return resp, err
+}
+
+func (s *WorkflowTaskV4Store) ListDuplicateTaskIDs(workflowName string) ([]int64, error) {
+	rows, err := s.db.Query("SELECT task_id FROM "+workflowTaskV4Table+
+		" WHERE workflow_name = $1 GROUP BY task_id HAVING COUNT(*) > 1 ORDER BY task_id", workflowName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	resp := make([]int64, 0)
+	for rows.Next() {
+		var taskID int64
+		if err := rows.Scan(&taskID); err != nil {
+			return nil, err
+		}
+		resp = append(resp, taskID)
+	}
+	return resp, rows.Err()
+}
+
+func (s *WorkflowTaskV4Store) CountByProject(projectName string) (int64, error) {
+	var count int64
+	err := s.db.QueryRow("SELECT COUNT(*) FROM "+workflowTaskV4Table+" WHERE project_name = $1", projectName).Scan(&count)
+	return count, err
+}
+
+// RenameProject rewrites the project key in the columns and the documents of the tasks in one transaction
+func (s *WorkflowTaskV4Store) RenameProject(oldKey, newKey string) (int64, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT "+workflowTaskV4Columns+" FROM "+workflowTaskV4Table+" WHERE project_name = $1", oldKey)
+	if err != nil {
+		return 0, err
+	}
+	tasks := make([]*models.WorkflowTask, 0)
+	for rows.Next() {
+		task, err := scanWorkflowTask(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		tasks = append(tasks, task)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, task := range tasks {
+		task.ProjectName = newKey
+		for _, args := range []*models.WorkflowV4{task.WorkflowArgs, task.OriginWorkflowArgs} {
+			if args != nil && args.Project == oldKey {
+				args.Project = newKey
+			}
+		}
+		document, err := encodeWorkflowTask(task.ID, task)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec("UPDATE "+workflowTaskV4Table+" SET project_name = $2, document = $3 WHERE id = $1",
+			task.ID.Hex(), newKey, document); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(tasks)), tx.Commit()
+}
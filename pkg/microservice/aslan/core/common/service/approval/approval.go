@@ -23,6 +23,7 @@ import (
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 )
 
 type ApproveMap struct {
@@ -32,6 +33,8 @@ type ApproveMap struct {
 
 type ApproveWithLock struct {
 	Approval *commonmodels.NativeApproval
+	// StartTime is when the stage started waiting for the approval, it is used for the latency of the operations
+	StartTime int64
 	sync.RWMutex
 }
 
@@ -101,3 +104,22 @@ func (c *ApproveWithLock) DoApproval(userName, userID, comment string, appvove b
 	}
 	return fmt.Errorf("user %s has no authority to Approve", userName)
 }
+
+// RecordTimeline pushes an approval operation to the timeline of the stage
+func (c *ApproveWithLock) RecordTimeline(workflowName string, taskID int64, stageName, userName, userID, comment string, approve bool, source config.ApprovalSource) error {
+	event := &commonmodels.StageTimelineEvent{
+		Action:   config.Reject,
+		UserID:   userID,
+		UserName: userName,
+		Comment:  comment,
+		Source:   source,
+		Time:     time.Now().Unix(),
+	}
+	if approve {
+		event.Action = config.Approve
+	}
+	if c.StartTime > 0 {
+		event.Latency = event.Time - c.StartTime
+	}
+	return commonrepo.NewWorkflowTaskStageTimelineColl().Push(workflowName, taskID, stageName, event)
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	configbase "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+)
+
+// the records appended by other aslan instances at the same time conflict on the unique seq, the append is retried
+// after a random backoff then
+const (
+	approvalRecordRetries    = 10
+	approvalRecordMaxBackoff = 200 * time.Millisecond
+)
+
+// approvalRecordLock only serializes the appends of this instance, the unique seq keeps the chain linear across
+// the instances
+var approvalRecordLock sync.Mutex
+
+// RecordApproval appends the result of the finished approval of the stage to the approval records
+func RecordApproval(workflowCtx *commonmodels.WorkflowTaskCtx, stage *commonmodels.StageTask) error {
+	record := &commonmodels.ApprovalRecord{
+		ProjectName:         workflowCtx.ProjectName,
+		WorkflowName:        workflowCtx.WorkflowName,
+		WorkflowDisplayName: workflowCtx.WorkflowDisplayName,
+		TaskID:              workflowCtx.TaskID,
+		TaskCreator:         workflowCtx.WorkflowTaskCreatorUsername,
+		StageName:           stage.Name,
+		ApprovalType:        stage.Approval.Type,
+		Status:              stage.Approval.Status,
+		StartTime:           stage.Approval.StartTime,
+		EndTime:             stage.Approval.EndTime,
+		Decisions:           getApprovalDecisions(stage.Approval),
+	}
+
+	approvalRecordLock.Lock()
+	defer approvalRecordLock.Unlock()
+	coll := commonrepo.NewApprovalRecordColl()
+	for i := 0; ; i++ {
+		record.Seq, record.PrevHash = 1, ""
+		latest, err := coll.GetLatest()
+		if err == nil {
+			record.Seq, record.PrevHash = latest.Seq+1, latest.Hash
+		} else if err != mongo.ErrNoDocuments {
+			return fmt.Errorf("failed to get the latest approval record: %s", err)
+		}
+		record.CreateTime = time.Now().Unix()
+		if record.Hash, err = ComputeApprovalRecordHash(record); err != nil {
+			return err
+		}
+		err = coll.Create(record)
+		if err == nil {
+			return nil
+		}
+		if !mongo.IsDuplicateKeyError(err) || i >= approvalRecordRetries {
+			return fmt.Errorf("failed to create approval record: %s", err)
+		}
+		time.Sleep(time.Duration(rand.Int63n(int64(approvalRecordMaxBackoff))))
+	}
+}
+
+// ComputeApprovalRecordHash returns the hex encoded HMAC-SHA256 of the JSON of the record with an empty hash, the
+// previous hash is a part of the JSON so that the records are chained. The key is the secret key of the system
+// which is not stored in mongodb, so the chain can not be rebuilt by the one who can only write the records.
+func ComputeApprovalRecordHash(record *commonmodels.ApprovalRecord) (string, error) {
+	secretKey := configbase.SecretKey()
+	if secretKey == "" {
+		return "", fmt.Errorf("the secret key to sign the approval records is not configured")
+	}
+	content := *record
+	content.Hash = ""
+	data, err := json.Marshal(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal approval record: %s", err)
+	}
+	mac := hmac.New(sha256.New, []byte(secretKey))
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// VerifyApprovalRecords checks the hash of each record and the links between the records which are continuous
+// records ordered by seq, prev is the record before the first one and it is nil if the first one is the first
+// record ever
+func VerifyApprovalRecords(prev *commonmodels.ApprovalRecord, records []*commonmodels.ApprovalRecord) error {
+	for _, record := range records {
+		hash, err := ComputeApprovalRecordHash(record)
+		if err != nil {
+			return err
+		}
+		if !hmac.Equal([]byte(hash), []byte(record.Hash)) {
+			return fmt.Errorf("the hash of approval record %d does not match its content", record.Seq)
+		}
+		switch {
+		case prev == nil && (record.Seq != 1 || record.PrevHash != ""):
+			return fmt.Errorf("the records before approval record %d are missing", record.Seq)
+		case prev != nil && record.Seq != prev.Seq+1:
+			return fmt.Errorf("the approval records between %d and %d are missing", prev.Seq, record.Seq)
+		case prev != nil && record.PrevHash != prev.Hash:
+			return fmt.Errorf("approval record %d is not chained to approval record %d", record.Seq, prev.Seq)
+		}
+		prev = record
+	}
+	return nil
+}
+
+func getApprovalDecisions(approval *commonmodels.Approval) []*commonmodels.ApprovalDecision {
+	decisions := make([]*commonmodels.ApprovalDecision, 0)
+	switch approval.Type {
+	case config.NativeApproval:
+		if approval.NativeApproval == nil {
+			break
+		}
+		for _, user := range approval.NativeApproval.ApproveUsers {
+			name := user.UserName
+			if user.Type == "group" {
+				name = user.GroupName
+			}
+			decisions = append(decisions, &commonmodels.ApprovalDecision{
+				Approver:   name,
+				ApproverID: user.UserID,
+				Action:     user.RejectOrApprove,
+				Comment:    user.Comment,
+				Time:       user.OperationTime,
+			})
+		}
+	case config.LarkApproval:
+		if approval.LarkApproval == nil {
+			break
+		}
+		users := append([]*commonmodels.LarkApprovalUser{}, approval.LarkApproval.ApproveUsers...)
+		for _, node := range approval.LarkApproval.ApprovalNodes {
+			users = append(users, node.ApproveUsers...)
+		}
+		for _, user := range users {
+			decisions = append(decisions, &commonmodels.ApprovalDecision{
+				Approver:   user.Name,
+				ApproverID: user.ID,
+				Action:     user.RejectOrApprove,
+				Comment:    user.Comment,
+				Time:       user.OperationTime,
+			})
+		}
+	case config.DingTalkApproval:
+		if approval.DingTalkApproval == nil {
+			break
+		}
+		for _, node := range approval.DingTalkApproval.ApprovalNodes {
+			for _, user := range node.ApproveUsers {
+				decisions = append(decisions, &commonmodels.ApprovalDecision{
+					Approver:   user.Name,
+					ApproverID: user.ID,
+					Action:     user.RejectOrApprove,
+					Comment:    user.Comment,
+					Time:       user.OperationTime,
+				})
+			}
+		}
+	}
+	return decisions
+}
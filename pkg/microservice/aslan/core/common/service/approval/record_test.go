@@ -0,0 +1,123 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package approval_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/spf13/viper"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/approval"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+// newApprovalRecords returns n records chained from the first record ever
+func newApprovalRecords(n int) []*commonmodels.ApprovalRecord {
+	records := make([]*commonmodels.ApprovalRecord, 0, n)
+	prevHash := ""
+	for i := 1; i <= n; i++ {
+		record := &commonmodels.ApprovalRecord{
+			Seq:          int64(i),
+			ProjectName:  "demo",
+			WorkflowName: "deploy",
+			TaskID:       int64(i),
+			StageName:    "approval",
+			ApprovalType: config.NativeApproval,
+			Status:       config.StatusPassed,
+			Decisions: []*commonmodels.ApprovalDecision{
+				{Approver: "admin", ApproverID: "1", Action: config.Approve, Time: int64(i)},
+			},
+			CreateTime: int64(i),
+			PrevHash:   prevHash,
+		}
+		hash, err := approval.ComputeApprovalRecordHash(record)
+		Expect(err).NotTo(HaveOccurred())
+		record.Hash = hash
+		prevHash = hash
+		records = append(records, record)
+	}
+	return records
+}
+
+var _ = Describe("Approval records", func() {
+	BeforeEach(func() {
+		viper.Set(setting.ENVSecretKey, "approval-record-secret")
+	})
+
+	AfterEach(func() {
+		viper.Set(setting.ENVSecretKey, "")
+	})
+
+	DescribeTable("verifying the chain",
+		func(mutate func(records []*commonmodels.ApprovalRecord) (*commonmodels.ApprovalRecord, []*commonmodels.ApprovalRecord), valid bool) {
+			prev, records := mutate(newApprovalRecords(5))
+			err := approval.VerifyApprovalRecords(prev, records)
+			if valid {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(HaveOccurred())
+			}
+		},
+		Entry("the whole chain", func(records []*commonmodels.ApprovalRecord) (*commonmodels.ApprovalRecord, []*commonmodels.ApprovalRecord) {
+			return nil, records
+		}, true),
+		Entry("a part of the chain", func(records []*commonmodels.ApprovalRecord) (*commonmodels.ApprovalRecord, []*commonmodels.ApprovalRecord) {
+			return records[1], records[2:]
+		}, true),
+		Entry("no records", func(records []*commonmodels.ApprovalRecord) (*commonmodels.ApprovalRecord, []*commonmodels.ApprovalRecord) {
+			return records[4], nil
+		}, true),
+		Entry("a modified status", func(records []*commonmodels.ApprovalRecord) (*commonmodels.ApprovalRecord, []*commonmodels.ApprovalRecord) {
+			records[2].Status = config.StatusReject
+			return nil, records
+		}, false),
+		Entry("a modified decision", func(records []*commonmodels.ApprovalRecord) (*commonmodels.ApprovalRecord, []*commonmodels.ApprovalRecord) {
+			records[3].Decisions[0].Approver = "someone"
+			return nil, records
+		}, false),
+		Entry("a record rehashed without the key", func(records []*commonmodels.ApprovalRecord) (*commonmodels.ApprovalRecord, []*commonmodels.ApprovalRecord) {
+			records[2].Status = config.StatusReject
+			viper.Set(setting.ENVSecretKey, "guessed-secret")
+			hash, err := approval.ComputeApprovalRecordHash(records[2])
+			Expect(err).NotTo(HaveOccurred())
+			records[2].Hash = hash
+			viper.Set(setting.ENVSecretKey, "approval-record-secret")
+			return nil, records
+		}, false),
+		Entry("a deleted record", func(records []*commonmodels.ApprovalRecord) (*commonmodels.ApprovalRecord, []*commonmodels.ApprovalRecord) {
+			return nil, append(records[:2], records[3:]...)
+		}, false),
+		Entry("the first records deleted", func(records []*commonmodels.ApprovalRecord) (*commonmodels.ApprovalRecord, []*commonmodels.ApprovalRecord) {
+			return nil, records[2:]
+		}, false),
+		Entry("a record chained to another one", func(records []*commonmodels.ApprovalRecord) (*commonmodels.ApprovalRecord, []*commonmodels.ApprovalRecord) {
+			records[2].PrevHash = records[0].Hash
+			hash, err := approval.ComputeApprovalRecordHash(records[2])
+			Expect(err).NotTo(HaveOccurred())
+			records[2].Hash = hash
+			return records[1], records[2:]
+		}, false),
+	)
+
+	It("requires the secret key", func() {
+		viper.Set(setting.ENVSecretKey, "")
+		_, err := approval.ComputeApprovalRecordHash(&commonmodels.ApprovalRecord{Seq: 1})
+		Expect(err).To(HaveOccurred())
+	})
+})
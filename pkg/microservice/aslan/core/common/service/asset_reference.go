@@ -0,0 +1,159 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+)
+
+type AssetType string
+
+const (
+	AssetTypeBuild    AssetType = "build"
+	AssetTypeTesting  AssetType = "testing"
+	AssetTypeScanning AssetType = "scanning"
+	AssetTypeRegistry AssetType = "registry"
+	AssetTypeCluster  AssetType = "cluster"
+)
+
+// AssetReference is a job of a workflow which uses the asset
+type AssetReference struct {
+	ProjectName         string         `json:"project_name"`
+	WorkflowName        string         `json:"workflow_name"`
+	WorkflowDisplayName string         `json:"workflow_display_name"`
+	JobName             string         `json:"job_name"`
+	JobType             config.JobType `json:"job_type"`
+}
+
+// jobAssetSpec collects the asset fields shared by the job specs, the fields missing in the spec of a job are empty
+type jobAssetSpec struct {
+	ClusterID        string `json:"cluster_id"`
+	DockerRegistryID string `json:"docker_registry_id"`
+	RegistryID       string `json:"registry_id"`
+	SourceRegistryID string `json:"source_registry_id"`
+	TargetRegistryID string `json:"target_registry_id"`
+	Properties       *struct {
+		ClusterID string `json:"cluster_id"`
+	} `json:"properties"`
+	ServiceAndBuilds []*struct {
+		BuildName string `json:"build_name"`
+	} `json:"service_and_builds"`
+	TestModules     []*commonmodels.TestModule     `json:"test_modules"`
+	ServiceAndTests []*commonmodels.ServiceAndTest `json:"service_and_tests"`
+	Scannings       []*commonmodels.ScanningModule `json:"scannings"`
+}
+
+// GetAssetReferences returns the workflow jobs using the asset, the name is the id of a registry or a cluster,
+// the project is required for the builds, testings and scannings
+func GetAssetReferences(assetType AssetType, projectName, name string) ([]*AssetReference, error) {
+	opt := &mongodb.ListWorkflowV4Option{}
+	switch assetType {
+	case AssetTypeBuild:
+		opt.ProjectName = projectName
+		opt.JobTypes = []config.JobType{config.JobZadigBuild}
+	case AssetTypeTesting:
+		opt.JobTypes = []config.JobType{config.JobZadigTesting}
+	case AssetTypeScanning:
+		opt.JobTypes = []config.JobType{config.JobZadigScanning}
+	case AssetTypeRegistry, AssetTypeCluster:
+	default:
+		return nil, fmt.Errorf("unsupported asset type %s", assetType)
+	}
+	workflows, _, err := mongodb.NewWorkflowV4Coll().List(opt, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflows, err: %s", err)
+	}
+
+	resp := make([]*AssetReference, 0)
+	for _, workflow := range workflows {
+		for _, stage := range workflow.Stages {
+			for _, job := range stage.Jobs {
+				spec := new(jobAssetSpec)
+				if err := commonmodels.IToi(job.Spec, spec); err != nil {
+					return nil, fmt.Errorf("failed to decode job %s of workflow %s, err: %s", job.Name, workflow.Name, err)
+				}
+				if !spec.references(assetType, workflow.Project, projectName, name) {
+					continue
+				}
+				resp = append(resp, &AssetReference{
+					ProjectName:         workflow.Project,
+					WorkflowName:        workflow.Name,
+					WorkflowDisplayName: workflow.DisplayName,
+					JobName:             job.Name,
+					JobType:             job.JobType,
+				})
+			}
+		}
+	}
+	return resp, nil
+}
+
+func (s *jobAssetSpec) references(assetType AssetType, workflowProject, projectName, name string) bool {
+	switch assetType {
+	case AssetTypeBuild:
+		if workflowProject != projectName {
+			return false
+		}
+		for _, build := range s.ServiceAndBuilds {
+			if build.BuildName == name {
+				return true
+			}
+		}
+	case AssetTypeTesting:
+		modules := append([]*commonmodels.TestModule{}, s.TestModules...)
+		for _, test := range s.ServiceAndTests {
+			modules = append(modules, &test.TestModule)
+		}
+		for _, module := range modules {
+			if module.Name == name && module.ProjectName == projectName {
+				return true
+			}
+		}
+	case AssetTypeScanning:
+		for _, scanning := range s.Scannings {
+			if scanning.Name == name && scanning.ProjectName == projectName {
+				return true
+			}
+		}
+	case AssetTypeRegistry:
+		return name != "" && (s.DockerRegistryID == name || s.RegistryID == name || s.SourceRegistryID == name || s.TargetRegistryID == name)
+	case AssetTypeCluster:
+		return name != "" && (s.ClusterID == name || (s.Properties != nil && s.Properties.ClusterID == name))
+	}
+	return false
+}
+
+// CheckAssetReferences returns an error listing the workflows if the asset is still used
+func CheckAssetReferences(assetType AssetType, projectName, name string) error {
+	references, err := GetAssetReferences(assetType, projectName, name)
+	if err != nil {
+		return err
+	}
+	if len(references) == 0 {
+		return nil
+	}
+	usages := make([]string, 0, len(references))
+	for _, reference := range references {
+		usages = append(usages, fmt.Sprintf("%s/%s(%s)", reference.ProjectName, reference.WorkflowName, reference.JobName))
+	}
+	return fmt.Errorf("被以下工作流任务引用，请解除引用之后再做删除: %s", strings.Join(usages, ", "))
+}
@@ -0,0 +1,501 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/scrypt"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	templatemodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	codehostmodels "github.com/koderover/zadig/pkg/microservice/systemconfig/core/codehost/repository/models"
+	"github.com/koderover/zadig/pkg/tool/crypto"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+const (
+	archiveVersion = 1
+	manifestFile   = "manifest.json"
+	collectionDir  = "collections"
+	// keyCheckText is encrypted by the backup key into the manifest to verify the passphrase on restore
+	keyCheckText = "zadig-configuration-backup"
+
+	kdfScrypt = "scrypt"
+	// the scrypt parameters recommended for interactive logins
+	scryptN       = 32768
+	scryptR       = 8
+	scryptP       = 1
+	saltLength    = 16
+	backupKeySize = 32
+)
+
+// collection is a collection of the archive, secretFields are the dot paths of the plaintext secrets
+// and encryptedFields are the paths of the secrets encrypted by the aes key of the installation,
+// both are encrypted by the backup key in the archive
+type collection struct {
+	name            string
+	secretFields    []string
+	encryptedFields []string
+}
+
+var collections = []*collection{
+	// projects, services and environments
+	{name: templatemodels.Product{}.TableName()},
+	{name: models.Service{}.TableName()},
+	{name: "production_template_service"},
+	{name: models.Product{}.TableName()},
+	{name: models.ProjectClusterRelation{}.TableName()},
+	// workflows and their modules
+	{name: models.WorkflowV4{}.TableName()},
+	{name: models.WorkflowView{}.TableName()},
+	{name: models.Build{}.TableName()},
+	{name: models.Testing{}.TableName()},
+	{name: models.Scanning{}.TableName()},
+	{name: models.Cronjob{}.TableName()},
+	// templates
+	{name: models.BuildTemplate{}.TableName()},
+	{name: models.WorkflowV4Template{}.TableName()},
+	{name: models.DockerfileTemplate{}.TableName()},
+	{name: models.YamlTemplate{}.TableName()},
+	{name: models.Chart{}.TableName()},
+	{name: models.BasicImage{}.TableName()},
+	// integrations
+	{name: codehostmodels.CodeHost{}.TableName(), secretFields: []string{"access_token", "refresh_token", "password", "client_secret", "ssh_key", "private_access_token"}},
+	{name: models.K8SCluster{}.TableName(), secretFields: []string{"kube_config"}},
+	{name: models.RegistryNamespace{}.TableName(), secretFields: []string{"secret_key"}},
+	{name: models.S3Storage{}.TableName(), encryptedFields: []string{"encryptedSk"}},
+	{name: models.HelmRepo{}.TableName(), secretFields: []string{"password"}},
+	{name: models.JenkinsIntegration{}.TableName(), secretFields: []string{"password"}},
+	{name: models.SonarIntegration{}.TableName(), secretFields: []string{"token"}},
+	{name: models.ProjectManagement{}.TableName(), secretFields: []string{"jira_token", "meego_plugin_secret"}},
+	{name: models.ExternalSystem{}.TableName(), secretFields: []string{"api_token"}},
+	{name: models.IMApp{}.TableName(), secretFields: []string{"app_secret", "encrypt_key", "dingtalk_app_secret", "dingtalk_aes_key", "dingtalk_token"}},
+	{name: models.Observability{}.TableName(), secretFields: []string{"api_key"}},
+	{name: models.LLMIntegration{}.TableName(), secretFields: []string{"token"}},
+	{name: models.DBInstance{}.TableName(), secretFields: []string{"password"}},
+	{name: models.ConfigurationManagement{}.TableName(), secretFields: []string{"auth_config.token", "auth_config.password"}},
+	{name: models.PrivateKey{}.TableName(), secretFields: []string{"private_key"}},
+	{name: models.Proxy{}.TableName(), secretFields: []string{"password"}},
+}
+
+func getCollection(name string) *collection {
+	for _, c := range collections {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+type Manifest struct {
+	Version     int                  `json:"version"`
+	CreateTime  int64                `json:"create_time"`
+	CreatedBy   string               `json:"created_by"`
+	KDF         *KDF                 `json:"kdf"`
+	KeyCheck    string               `json:"key_check"`
+	Collections []*CollectionSummary `json:"collections"`
+}
+
+// KDF is how the backup key is derived from the passphrase, the salt is base64 encoded
+type KDF struct {
+	Algorithm string `json:"algorithm"`
+	Salt      string `json:"salt"`
+	N         int    `json:"n"`
+	R         int    `json:"r"`
+	P         int    `json:"p"`
+}
+
+func newKDF() (*KDF, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %s", err)
+	}
+	return &KDF{
+		Algorithm: kdfScrypt,
+		Salt:      base64.StdEncoding.EncodeToString(salt),
+		N:         scryptN,
+		R:         scryptR,
+		P:         scryptP,
+	}, nil
+}
+
+// deriveKey derives the aes key of the archive from the passphrase
+func (k *KDF) deriveKey(passphrase string) (string, error) {
+	if k == nil || k.Algorithm != kdfScrypt {
+		return "", errors.New("unsupported key derivation of the backup archive")
+	}
+	salt, err := base64.StdEncoding.DecodeString(k.Salt)
+	if err != nil || len(salt) == 0 {
+		return "", errors.New("invalid salt of the backup archive")
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, k.N, k.R, k.P, backupKeySize)
+	if err != nil {
+		return "", fmt.Errorf("failed to derive the backup key: %s", err)
+	}
+	return string(key), nil
+}
+
+type CollectionSummary struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Options are the options of both the export and the restore, the secrets in the archive are encrypted
+// by a key derived from Passphrase, AesKey is the aes key of the installation and the one mounted
+// into the service is used if it is empty
+type Options struct {
+	Passphrase string
+	AesKey     string
+	Operator   string
+	// Overwrite replaces the documents with the same id on restore, they are skipped otherwise
+	Overwrite bool
+}
+
+func (o *Options) validate() error {
+	if o.Passphrase == "" {
+		return errors.New("passphrase is required")
+	}
+	return nil
+}
+
+func (o *Options) decryptByInstallKey(src string) (string, error) {
+	if o.AesKey == "" {
+		return crypto.AesDecrypt(src)
+	}
+	return crypto.AesDecrypt(src, o.AesKey)
+}
+
+func (o *Options) encryptByInstallKey(src string) (string, error) {
+	if o.AesKey == "" {
+		return crypto.AesEncrypt(src)
+	}
+	return crypto.AesEncryptByKey(src, o.AesKey)
+}
+
+// Export writes the configuration collections into w as a gzipped tar archive, a manifest followed by
+// one file per collection holding a canonical extended json document per line
+func Export(ctx context.Context, w io.Writer, opt *Options) (*Manifest, error) {
+	if err := opt.validate(); err != nil {
+		return nil, err
+	}
+	kdf, err := newKDF()
+	if err != nil {
+		return nil, err
+	}
+	backupKey, err := kdf.deriveKey(opt.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	keyCheck, err := crypto.AesEncryptByKey(keyCheckText, backupKey)
+	if err != nil {
+		return nil, err
+	}
+	manifest := &Manifest{
+		Version:    archiveVersion,
+		CreateTime: time.Now().Unix(),
+		CreatedBy:  opt.Operator,
+		KDF:        kdf,
+		KeyCheck:   keyCheck,
+	}
+
+	contents := make([]*bytes.Buffer, 0, len(collections))
+	for _, c := range collections {
+		buf, count, err := exportCollection(ctx, c, backupKey, opt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export collection %s: %s", c.name, err)
+		}
+		contents = append(contents, buf)
+		manifest.Collections = append(manifest.Collections, &CollectionSummary{Name: c.name, Count: count})
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	manifestContent, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeTarFile(tw, manifestFile, manifestContent); err != nil {
+		return nil, err
+	}
+	for i, c := range collections {
+		if err := writeTarFile(tw, path.Join(collectionDir, c.name+".json"), contents[i].Bytes()); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func exportCollection(ctx context.Context, c *collection, backupKey string, opt *Options) (*bytes.Buffer, int, error) {
+	cursor, err := mongotool.Database(config.MongoDatabase()).Collection(c.name).Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	buf := new(bytes.Buffer)
+	count := 0
+	for cursor.Next(ctx) {
+		doc := bson.M{}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, 0, err
+		}
+		for _, field := range c.secretFields {
+			if err := transformField(doc, field, func(v string) (string, error) {
+				return crypto.AesEncryptByKey(v, backupKey)
+			}); err != nil {
+				return nil, 0, fmt.Errorf("failed to encrypt %s: %s", field, err)
+			}
+		}
+		for _, field := range c.encryptedFields {
+			if err := transformField(doc, field, func(v string) (string, error) {
+				plain, err := opt.decryptByInstallKey(v)
+				if err != nil {
+					return "", err
+				}
+				return crypto.AesEncryptByKey(plain, backupKey)
+			}); err != nil {
+				return nil, 0, fmt.Errorf("failed to re-encrypt %s: %s", field, err)
+			}
+		}
+		line, err := bson.MarshalExtJSON(doc, true, false)
+		if err != nil {
+			return nil, 0, err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+		count++
+	}
+	return buf, count, cursor.Err()
+}
+
+func writeTarFile(tw *tar.Writer, name string, content []byte) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(content)),
+		ModTime: time.Now(),
+	}); err != nil {
+		return err
+	}
+	_, err := tw.Write(content)
+	return err
+}
+
+type RestoreResult struct {
+	Manifest    *Manifest                  `json:"manifest"`
+	Collections []*RestoreCollectionResult `json:"collections"`
+	// Ignored are the files of the archive which are not known collections of this version
+	Ignored []string `json:"ignored"`
+}
+
+type RestoreCollectionResult struct {
+	Name     string `json:"name"`
+	Inserted int    `json:"inserted"`
+	Replaced int    `json:"replaced"`
+	Skipped  int    `json:"skipped"`
+}
+
+// Restore reads an archive created by Export and writes the documents into the collections, the secrets are
+// decrypted by the passphrase and the installation encrypted ones are encrypted by the aes key of this installation
+func Restore(ctx context.Context, r io.Reader, opt *Options) (*RestoreResult, error) {
+	if err := opt.validate(); err != nil {
+		return nil, err
+	}
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup archive: %s", err)
+	}
+	defer gr.Close()
+	tr := tar.NewReader(gr)
+
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup archive: %s", err)
+	}
+	if hdr.Name != manifestFile {
+		return nil, fmt.Errorf("invalid backup archive: %s must be the first file", manifestFile)
+	}
+	manifest := new(Manifest)
+	if err := json.NewDecoder(tr).Decode(manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %s", err)
+	}
+	if manifest.Version > archiveVersion {
+		return nil, fmt.Errorf("backup archive version %d is not supported", manifest.Version)
+	}
+	backupKey, err := manifest.KDF.deriveKey(opt.Passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if check, err := crypto.AesDecrypt(manifest.KeyCheck, backupKey); err != nil || check != keyCheckText {
+		return nil, errors.New("the passphrase does not match the backup archive")
+	}
+
+	result := &RestoreResult{Manifest: manifest}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, fmt.Errorf("invalid backup archive: %s", err)
+		}
+		c := getCollection(strings.TrimSuffix(path.Base(hdr.Name), ".json"))
+		if path.Dir(hdr.Name) != collectionDir || c == nil {
+			result.Ignored = append(result.Ignored, hdr.Name)
+			continue
+		}
+		collectionResult, err := restoreCollection(ctx, c, tr, backupKey, opt)
+		if err != nil {
+			return result, fmt.Errorf("failed to restore collection %s: %s", c.name, err)
+		}
+		result.Collections = append(result.Collections, collectionResult)
+	}
+	return result, nil
+}
+
+func restoreCollection(ctx context.Context, c *collection, r io.Reader, backupKey string, opt *Options) (*RestoreCollectionResult, error) {
+	coll := mongotool.Database(config.MongoDatabase()).Collection(c.name)
+	result := &RestoreCollectionResult{Name: c.name}
+
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return result, err
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			doc := bson.M{}
+			if err := bson.UnmarshalExtJSON(line, true, &doc); err != nil {
+				return result, err
+			}
+			for _, field := range c.secretFields {
+				if err := transformField(doc, field, func(v string) (string, error) {
+					return crypto.AesDecrypt(v, backupKey)
+				}); err != nil {
+					return result, fmt.Errorf("failed to decrypt %s: %s", field, err)
+				}
+			}
+			for _, field := range c.encryptedFields {
+				if err := transformField(doc, field, func(v string) (string, error) {
+					plain, err := crypto.AesDecrypt(v, backupKey)
+					if err != nil {
+						return "", err
+					}
+					return opt.encryptByInstallKey(plain)
+				}); err != nil {
+					return result, fmt.Errorf("failed to re-encrypt %s: %s", field, err)
+				}
+			}
+			if err := restoreDocument(ctx, coll, doc, opt.Overwrite, result); err != nil {
+				return result, err
+			}
+		}
+		if err == io.EOF {
+			return result, nil
+		}
+	}
+}
+
+func restoreDocument(ctx context.Context, coll *mongo.Collection, doc bson.M, overwrite bool, result *RestoreCollectionResult) error {
+	if !overwrite {
+		_, err := coll.InsertOne(ctx, doc)
+		if mongo.IsDuplicateKeyError(err) {
+			result.Skipped++
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		result.Inserted++
+		return nil
+	}
+
+	res, err := coll.ReplaceOne(ctx, bson.M{"_id": doc["_id"]}, doc, options.Replace().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+	if res.UpsertedCount > 0 {
+		result.Inserted++
+	} else {
+		result.Replaced++
+	}
+	return nil
+}
+
+// transformField replaces the non-empty string at the dot path of the document by the result of fn,
+// missing fields and fields of other types are left as they are
+func transformField(doc interface{}, field string, fn func(string) (string, error)) error {
+	key, rest, nested := strings.Cut(field, ".")
+	transform := func(v interface{}) (interface{}, error) {
+		if nested {
+			return v, transformField(v, rest, fn)
+		}
+		s, ok := v.(string)
+		if !ok || s == "" {
+			return v, nil
+		}
+		return fn(s)
+	}
+
+	switch d := doc.(type) {
+	case bson.M:
+		if v, ok := d[key]; ok {
+			nv, err := transform(v)
+			if err != nil {
+				return err
+			}
+			d[key] = nv
+		}
+	case primitive.D:
+		for i := range d {
+			if d[i].Key != key {
+				continue
+			}
+			nv, err := transform(d[i].Value)
+			if err != nil {
+				return err
+			}
+			d[i].Value = nv
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2023 The KodeRover Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dingtalk
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	configbase "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/shared/client/user"
+	"github.com/koderover/zadig/pkg/tool/dingtalk"
+)
+
+// ApprovalAction is carried by the approve and reject buttons of the card sent to an approver of a native
+// approval, it is signed with the secret of the dingtalk app so that the link can not be forged
+type ApprovalAction struct {
+	WorkflowName string `json:"workflow_name"`
+	TaskID       int64  `json:"task_id"`
+	StageName    string `json:"stage_name"`
+	UserID       string `json:"user_id"`
+	Approve      bool   `json:"approve"`
+	ExpireAt     int64  `json:"expire_at"`
+}
+
+func signApprovalAction(secret string, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// NewApprovalActionURL returns the link of a button of the approval card
+func NewApprovalActionURL(appKey, secret string, action *ApprovalAction) (string, error) {
+	data, err := json.Marshal(action)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	token := payload + "." + signApprovalAction(secret, payload)
+	return fmt.Sprintf("%s/api/aslan/system/dingtalk/%s/approval?token=%s",
+		configbase.SystemAddress(), url.PathEscape(appKey), url.QueryEscape(token)), nil
+}
+
+// ParseApprovalAction verifies the signature and the expiration of the token of an approval card button
+func ParseApprovalAction(secret, token string) (*ApprovalAction, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 || !hmac.Equal([]byte(parts[1]), []byte(signApprovalAction(secret, parts[0]))) {
+		return nil, errors.New("invalid approval token")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.New("invalid approval token")
+	}
+	action := &ApprovalAction{}
+	if err := json.Unmarshal(data, action); err != nil {
+		return nil, errors.New("invalid approval token")
+	}
+	if time.Now().Unix() > action.ExpireAt {
+		return nil, errors.New("approval token expired")
+	}
+	return action, nil
+}
+
+// GetUserIDByZadigUser finds the dingtalk user of the zadig user by the mobile
+func GetUserIDByZadigUser(client *dingtalk.Client, uid string) (string, error) {
+	info, err := user.New().GetUserByID(uid)
+	if err != nil {
+		return "", errors.Wrapf(err, "get user %s", uid)
+	}
+	if info.Phone == "" {
+		return "", errors.Errorf("user %s has no mobile", info.Name)
+	}
+	resp, err := client.GetUserIDByMobile(info.Phone)
+	if err != nil {
+		return "", errors.Wrap(err, "get userID by mobile")
+	}
+	return resp.UserID, nil
+}
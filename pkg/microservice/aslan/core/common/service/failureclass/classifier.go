@@ -0,0 +1,103 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package failureclass tags the failed jobs with the categories of their failures by matching the rules against
+// the error, the log and the kubernetes events of the job. More rules can be registered with Register.
+package failureclass
+
+import (
+	"bufio"
+	"bytes"
+	"sort"
+	"sync"
+)
+
+const (
+	CategoryCompileError   = "compile_error"
+	CategoryTestFailure    = "test_failure"
+	CategoryImagePullError = "image_pull_error"
+	CategoryQuota          = "quota"
+	CategoryInfraTimeout   = "infra_timeout"
+	// CategoryUnknown is given to the failed jobs matching no rule
+	CategoryUnknown = "unknown"
+)
+
+// Input is what is known about a failed job, Status is the status of the job task
+type Input struct {
+	Status string
+	Error  string
+	Log    []byte
+	Events []*Event
+}
+
+// Event is a kubernetes event of the job pod
+type Event struct {
+	Type    string
+	Reason  string
+	Message string
+}
+
+// Rule tags a failed job with the category when it matches
+type Rule interface {
+	Category() string
+	Match(input *Input) bool
+}
+
+var (
+	mu    sync.RWMutex
+	rules = defaultRules()
+)
+
+// Register adds a rule, the categories of all the matching rules are given to a failed job
+func Register(rule Rule) {
+	mu.Lock()
+	defer mu.Unlock()
+	rules = append(rules, rule)
+}
+
+// Classify returns the sorted categories of the failed job, CategoryUnknown is returned if no rule matches
+func Classify(input *Input) []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	categories := make(map[string]bool)
+	for _, rule := range rules {
+		if !categories[rule.Category()] && rule.Match(input) {
+			categories[rule.Category()] = true
+		}
+	}
+	if len(categories) == 0 {
+		return []string{CategoryUnknown}
+	}
+	resp := make([]string, 0, len(categories))
+	for category := range categories {
+		resp = append(resp, category)
+	}
+	sort.Strings(resp)
+	return resp
+}
+
+// logLines calls f for each line of the log until it returns true
+func logLines(log []byte, f func(line []byte) bool) bool {
+	scanner := bufio.NewScanner(bytes.NewReader(log))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if f(scanner.Bytes()) {
+			return true
+		}
+	}
+	return false
+}
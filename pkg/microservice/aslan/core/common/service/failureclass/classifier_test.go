@@ -0,0 +1,59 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package failureclass
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestFailureClass(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Failure Classification Suite")
+}
+
+type jobNameRule struct{}
+
+func (jobNameRule) Category() string { return "custom" }
+
+func (jobNameRule) Match(input *Input) bool { return input.Error == "custom failure" }
+
+var _ = Describe("Classify", func() {
+	DescribeTable("categories of a failed job",
+		func(input *Input, expected []string) {
+			Expect(Classify(input)).To(Equal(expected))
+		},
+		Entry("go compile error", &Input{Status: "failed", Log: []byte("# app\n./main.go:10:2: undefined: foo\n")}, []string{CategoryCompileError}),
+		Entry("maven compile error", &Input{Status: "failed", Log: []byte("[ERROR] COMPILATION ERROR :\n")}, []string{CategoryCompileError}),
+		Entry("go test failure", &Input{Status: "failed", Log: []byte("=== RUN   TestA\n--- FAIL: TestA (0.00s)\nFAIL\tapp\t0.01s\n")}, []string{CategoryTestFailure}),
+		Entry("maven test failure", &Input{Status: "failed", Log: []byte("Tests run: 12, Failures: 2, Errors: 0, Skipped: 0\n")}, []string{CategoryTestFailure}),
+		Entry("passed tests", &Input{Status: "failed", Log: []byte("Tests run: 12, Failures: 0, Errors: 0, Skipped: 0\n")}, []string{CategoryUnknown}),
+		Entry("image pull event", &Input{Status: "failed", Events: []*Event{{Type: "Warning", Reason: "ErrImagePull", Message: "rpc error"}}}, []string{CategoryImagePullError}),
+		Entry("resource quota", &Input{Status: "failed", Error: "job build requests 4000m cpu and 8192Mi memory, which exceeds the resource quota of project demo"}, []string{CategoryQuota}),
+		Entry("scheduling without memory", &Input{Status: "failed", Events: []*Event{{Type: "Warning", Reason: "FailedScheduling", Message: "0/3 nodes are available: 3 Insufficient memory."}}}, []string{CategoryQuota}),
+		Entry("scheduling for other reasons", &Input{Status: "failed", Events: []*Event{{Type: "Warning", Reason: "FailedScheduling", Message: "node(s) had taints"}}}, []string{CategoryUnknown}),
+		Entry("timeout", &Input{Status: "timeout", Error: "wait job ready timeout"}, []string{CategoryInfraTimeout}),
+		Entry("several categories", &Input{Status: "timeout", Log: []byte("--- FAIL: TestA (0.00s)\n")}, []string{CategoryInfraTimeout, CategoryTestFailure}),
+	)
+
+	It("applies the registered rules", func() {
+		Register(jobNameRule{})
+		Expect(Classify(&Input{Status: "failed", Error: "custom failure"})).To(Equal([]string{"custom"}))
+	})
+})
@@ -0,0 +1,143 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package failureclass
+
+import (
+	"regexp"
+	"strings"
+)
+
+// PatternRule matches the job when its status is one of Statuses, or its error matches one of ErrorPatterns, or
+// a log line matches one of LogPatterns, or an event has one of EventReasons and its message matches
+// EventMessagePattern if it is set
+type PatternRule struct {
+	Name                string
+	Statuses            []string
+	ErrorPatterns       []*regexp.Regexp
+	LogPatterns         []*regexp.Regexp
+	EventReasons        []string
+	EventMessagePattern *regexp.Regexp
+}
+
+func (r *PatternRule) Category() string {
+	return r.Name
+}
+
+func (r *PatternRule) Match(input *Input) bool {
+	for _, status := range r.Statuses {
+		if input.Status == status {
+			return true
+		}
+	}
+	for _, pattern := range r.ErrorPatterns {
+		if input.Error != "" && pattern.MatchString(input.Error) {
+			return true
+		}
+	}
+	for _, event := range input.Events {
+		for _, reason := range r.EventReasons {
+			if strings.EqualFold(event.Reason, reason) && (r.EventMessagePattern == nil || r.EventMessagePattern.MatchString(event.Message)) {
+				return true
+			}
+		}
+	}
+	if len(r.LogPatterns) == 0 || len(input.Log) == 0 {
+		return false
+	}
+	return logLines(input.Log, func(line []byte) bool {
+		for _, pattern := range r.LogPatterns {
+			if pattern.Match(line) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func patterns(exprs ...string) []*regexp.Regexp {
+	resp := make([]*regexp.Regexp, 0, len(exprs))
+	for _, expr := range exprs {
+		resp = append(resp, regexp.MustCompile(expr))
+	}
+	return resp
+}
+
+func defaultRules() []Rule {
+	return []Rule{
+		&PatternRule{
+			Name: CategoryCompileError,
+			LogPatterns: patterns(
+				`^\S+\.(go|java|kt|scala|c|cc|cpp|h|cs|rs|ts|tsx|swift):\d+(:\d+)?:?\s.*\berror\b`,
+				`(?i)\bcompilation (failed|terminated|error)\b`,
+				`\[ERROR\] COMPILATION ERROR`,
+				`\berror\[E\d+\]`,
+				`\berror (TS|CS)\d+\b`,
+				`(?i)\bcannot find symbol\b`,
+				`\bundefined: \w+`,
+				`\bSyntaxError\b`,
+				`^make(\[\d+\])?: \*\*\*`,
+			),
+		},
+		&PatternRule{
+			Name: CategoryTestFailure,
+			LogPatterns: patterns(
+				`^--- FAIL: `,
+				`^FAIL\s+\S+`,
+				`Tests run: \d+, Failures: [1-9]`,
+				`Tests run: \d+, Failures: \d+, Errors: [1-9]`,
+				`(?i)\b[1-9]\d* (tests? )?(failed|failing)\b`,
+				`(?i)\bThere (was|were) \d+ failures?\b`,
+				`\bAssertionError\b`,
+				`(?i)\btest suite failed\b`,
+			),
+		},
+		&PatternRule{
+			Name:          CategoryImagePullError,
+			ErrorPatterns: patterns(`ErrImagePull|ImagePullBackOff|InvalidImageName`, `(?i)failed to pull image`),
+			LogPatterns: patterns(
+				`(?i)\bpull access denied\b`,
+				`(?i)\bmanifest (for \S+ )?unknown\b`,
+				`(?i)\bfailed to pull image\b`,
+				`(?i)\btoomanyrequests\b.*\bpull\b`,
+			),
+			EventReasons: []string{"ErrImagePull", "ImagePullBackOff", "InvalidImageName"},
+		},
+		&PatternRule{
+			Name: CategoryQuota,
+			ErrorPatterns: patterns(
+				`(?i)exceeds the resource quota`,
+				`(?i)exceeded quota`,
+				`(?i)\bInsufficient (cpu|memory|ephemeral-storage)\b`,
+				`\bOOMKilled\b`,
+			),
+			LogPatterns:         patterns(`(?i)\bno space left on device\b`, `(?i)\bout of memory\b`, `\bOOMKilled\b`),
+			EventReasons:        []string{"FailedScheduling", "FailedCreate", "OOMKilling", "Evicted"},
+			EventMessagePattern: regexp.MustCompile(`(?i)insufficient|exceeded quota|memory|ephemeral-storage|out of`),
+		},
+		&PatternRule{
+			Name:     CategoryInfraTimeout,
+			Statuses: []string{"timeout"},
+			ErrorPatterns: patterns(
+				`(?i)\btime(d)? ?out\b`,
+				`(?i)\bdeadline exceeded\b`,
+				`(?i)\bis not schedulable\b`,
+			),
+			LogPatterns:  patterns(`(?i)\b(i/o|connection|dial tcp \S+:) timeout\b`, `(?i)\bcontext deadline exceeded\b`),
+			EventReasons: []string{"NodeNotReady", "FailedMount", "FailedAttachVolume"},
+		},
+	}
+}
@@ -17,9 +17,11 @@ limitations under the License.
 package imnotify
 
 import (
+	"fmt"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 	"github.com/koderover/zadig/pkg/tool/httpclient"
-	"github.com/koderover/zadig/pkg/tool/log"
 )
 
 type IMNotifyType string
@@ -48,10 +50,12 @@ func (w *IMNotifyService) SendMessageRequest(uri string, message interface{}) ([
 	c := httpclient.New()
 
 	// 使用代理
-	proxies, _ := w.proxyColl.List(&mongodb.ProxyArgs{})
-	if len(proxies) != 0 && proxies[0].EnableApplicationProxy {
-		c.SetProxy(proxies[0].GetProxyURL())
-		log.Infof("send im notify message is using proxy:%s\n", proxies[0].GetProxyURL())
+	proxyURL, err := w.proxyColl.GetIntegrationProxyURL(models.ProxyIntegrationIM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the im proxy: %s", err)
+	}
+	if proxyURL != "" {
+		c.SetProxy(proxyURL)
 	}
 
 	res, err := c.Post(uri, httpclient.SetBody(message))
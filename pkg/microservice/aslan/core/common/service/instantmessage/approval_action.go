@@ -0,0 +1,101 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instantmessage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+// pendingApproval is a native approval stage waiting in the task and the approvers who have not operated yet
+type pendingApproval struct {
+	StageName string
+	Approvers string
+}
+
+// getPendingApprovals returns the native approval stages waiting in the task. The message only lists the
+// approvers, they approve in zadig after login so that a group member can not operate as another approver.
+func getPendingApprovals(task *models.WorkflowTask) []*pendingApproval {
+	resp := make([]*pendingApproval, 0)
+	for _, stage := range task.Stages {
+		if stage.Status != config.StatusWaitingApprove || stage.Approval == nil || stage.Approval.Type != config.NativeApproval || stage.Approval.NativeApproval == nil {
+			continue
+		}
+		approvers := make([]string, 0)
+		for _, user := range stage.Approval.NativeApproval.ApproveUsers {
+			if user.RejectOrApprove != "" {
+				continue
+			}
+			if user.Type == "group" {
+				approvers = append(approvers, user.GroupName)
+				continue
+			}
+			approvers = append(approvers, user.UserName)
+		}
+		if len(approvers) == 0 {
+			continue
+		}
+		resp = append(resp, &pendingApproval{
+			StageName: stage.Name,
+			Approvers: strings.Join(approvers, ", "),
+		})
+	}
+	return resp
+}
+
+// ApprovalCardActionLark is the action of the buttons of the native approval card sent by a lark app
+const ApprovalCardActionLark = "workflow_native_approval"
+
+// ApprovalCardActionValue is carried by the approve and reject buttons of the card sent to an approver, the
+// callback is only trusted after its signature is verified and the lark user is checked to be the approver
+type ApprovalCardActionValue struct {
+	Action       string `json:"action"`
+	WorkflowName string `json:"workflow_name"`
+	TaskID       int64  `json:"task_id"`
+	StageName    string `json:"stage_name"`
+	UserID       string `json:"user_id"`
+	Approve      bool   `json:"approve"`
+}
+
+// NewNativeApprovalLarkCard builds the card sent to the approver by the lark app of the native approval
+func NewNativeApprovalLarkCard(task *models.WorkflowTask, stageName, userID string) *LarkCard {
+	lc := NewLarkCard()
+	lc.SetConfig(true)
+	lc.SetHeader(feishuHeaderTemplateTurquoise, fmt.Sprintf("工作流 %s #%d 等待您审批", task.WorkflowDisplayName, task.TaskID), feiShuTagText)
+	lc.AddI18NElementsZhcnFeild(fmt.Sprintf("**审批阶段**：%s \n", stageName), true)
+	lc.AddI18NElementsZhcnFeild(fmt.Sprintf("**执行用户**：%s \n", task.TaskCreator), false)
+
+	newValue := func(approve bool) *ApprovalCardActionValue {
+		return &ApprovalCardActionValue{
+			Action:       ApprovalCardActionLark,
+			WorkflowName: task.WorkflowName,
+			TaskID:       task.TaskID,
+			StageName:    stageName,
+			UserID:       userID,
+			Approve:      approve,
+		}
+	}
+	lc.AddI18NElementsZhcnActions(
+		&Action{Tag: feishuTagButton, Text: TextElem{Content: "通过", Tag: feiShuTagText}, Type: "primary", Value: newValue(true)},
+		&Action{Tag: feishuTagButton, Text: TextElem{Content: "拒绝", Tag: feiShuTagText}, Type: "danger", Value: newValue(false)},
+		&Action{Tag: feishuTagButton, Text: TextElem{Content: "查看详情", Tag: feiShuTagText}, Type: "default", URL: getWorkflowTaskDetailURL(task)},
+	)
+	return lc
+}
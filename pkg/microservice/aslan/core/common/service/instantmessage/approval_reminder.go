@@ -0,0 +1,156 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instantmessage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+type approveMessageField struct {
+	Name  string
+	Value string
+}
+
+// PendingApprover is an approver who has not approved or rejected yet, the contacts are used to
+// reach the approver directly and are empty when they are unknown
+type PendingApprover struct {
+	Name       string
+	Email      string
+	Phone      string
+	LarkUserID string
+}
+
+// approveMessageRecipients are the persons emailed or mentioned by the message besides the ones in the notify settings
+type approveMessageRecipients struct {
+	Emails      []string
+	Phones      []string
+	LarkUserIDs []string
+}
+
+// SendWorkflowTaskApproveReminder reminds the pending approvers of the stage through the channels
+// subscribing the waiting for approval status, the approvers are emailed and mentioned directly
+func (w *Service) SendWorkflowTaskApproveReminder(task *models.WorkflowTask, stageName string, approvers []*PendingApprover, remindCount int) error {
+	title := fmt.Sprintf("工作流 %s #%d 审批提醒（第 %d 次）", task.WorkflowDisplayName, task.TaskID, remindCount)
+	names := make([]string, 0, len(approvers))
+	recipients := &approveMessageRecipients{}
+	for _, approver := range approvers {
+		names = append(names, approver.Name)
+		if approver.Email != "" {
+			recipients.Emails = append(recipients.Emails, approver.Email)
+		}
+		if approver.Phone != "" {
+			recipients.Phones = append(recipients.Phones, approver.Phone)
+		}
+		if approver.LarkUserID != "" {
+			recipients.LarkUserIDs = append(recipients.LarkUserIDs, approver.LarkUserID)
+		}
+	}
+	fields := []*approveMessageField{
+		{Name: "审批阶段", Value: stageName},
+		{Name: "待审批人", Value: strings.Join(names, "、")},
+	}
+	return w.sendWorkflowTaskApproveMessage(task, title, fields, "立即审批", recipients)
+}
+
+// SendWorkflowTaskApproveTimeoutNotification tells the task creator that the approval of the stage is about to time out
+func (w *Service) SendWorkflowTaskApproveTimeoutNotification(task *models.WorkflowTask, stageName string, remaining time.Duration) error {
+	title := fmt.Sprintf("工作流 %s #%d 审批即将超时", task.WorkflowDisplayName, task.TaskID)
+	fields := []*approveMessageField{
+		{Name: "审批阶段", Value: stageName},
+		{Name: "执行用户", Value: task.TaskCreator},
+		{Name: "剩余时间", Value: remaining.Round(time.Minute).String()},
+	}
+	recipients := &approveMessageRecipients{}
+	if task.TaskCreatorEmail != "" {
+		recipients.Emails = []string{task.TaskCreatorEmail}
+	}
+	if task.TaskCreatorPhone != "" {
+		recipients.Phones = []string{task.TaskCreatorPhone}
+	}
+	return w.sendWorkflowTaskApproveMessage(task, title, fields, "点击查看更多信息", recipients)
+}
+
+func (w *Service) sendWorkflowTaskApproveMessage(task *models.WorkflowTask, title string, fields []*approveMessageField, buttonContent string, recipients *approveMessageRecipients) error {
+	workflow, err := w.workflowV4Coll.Find(task.WorkflowName)
+	if err != nil {
+		return fmt.Errorf("failed to find workflowv4, err: %s", err)
+	}
+	detailURL := getWorkflowTaskDetailURL(task)
+
+	for _, notify := range workflow.NotifyCtls {
+		if !notify.Enabled || !sets.NewString(notify.NotifyTypes...).Has(string(config.StatusWaitingApprove)) {
+			continue
+		}
+
+		switch {
+		case isSummaryNotifyType(notify.WebHookType):
+			summary := newTaskSummary(task, true)
+			summary.Title = title
+			descriptions := make([]string, 0, len(fields))
+			for _, field := range fields {
+				descriptions = append(descriptions, fmt.Sprintf("%s：%s", field.Name, field.Value))
+			}
+			summary.Description = strings.Join(descriptions, "；")
+			summary.MailTo = recipients.Emails
+			err = w.sendTaskSummary(notify, summary, true)
+		case notify.WebHookType == feiShuType:
+			lc := NewLarkCard()
+			lc.SetConfig(true)
+			lc.SetHeader(feishuHeaderTemplateTurquoise, title, feiShuTagText)
+			for idx, field := range fields {
+				lc.AddI18NElementsZhcnFeild(fmt.Sprintf("**%s**：%s \n", field.Name, field.Value), idx == 0)
+			}
+			lc.AddI18NElementsZhcnAction(buttonContent, detailURL)
+			atContent := getNotifyAtContent(notify)
+			for _, userID := range recipients.LarkUserIDs {
+				atContent += fmt.Sprintf("<at user_id=\"%s\"></at>", userID)
+			}
+			if err = w.sendFeishuMessage(notify.FeiShuWebHook, lc); err == nil {
+				err = w.sendFeishuMessageOfSingleType("", notify.FeiShuWebHook, atContent)
+			}
+		default:
+			content := fmt.Sprintf("#### %s \n", title)
+			for _, field := range fields {
+				content += fmt.Sprintf("**%s**：%s \n", field.Name, field.Value)
+			}
+			content += getNotifyAtContent(notify)
+			atMobiles := append([]string{}, notify.AtMobiles...)
+			if len(recipients.Phones) > 0 {
+				atMobiles = append(atMobiles, recipients.Phones...)
+				content += fmt.Sprintf("@%s \n", strings.Join(recipients.Phones, "@"))
+			}
+			content += fmt.Sprintf("[%s](%s)", buttonContent, detailURL)
+			if notify.WebHookType == dingDingType {
+				err = w.sendDingDingMessage(notify.DingDingWebHook, title, content, atMobiles, notify.IsAtAll)
+			} else {
+				err = w.SendWeChatWorkMessage(weChatTextTypeMarkdown, notify.WeChatWebHook, content)
+			}
+		}
+		if err != nil {
+			log.Errorf("failed to send approval reminder of workflow %s task %d, err: %s", task.WorkflowName, task.TaskID, err)
+		}
+	}
+	return nil
+}
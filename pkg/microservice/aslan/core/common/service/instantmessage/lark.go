@@ -75,7 +75,9 @@ type Action struct {
 	Tag  string   `json:"tag"`
 	Text TextElem `json:"text"`
 	Type string   `json:"type"`
-	URL  string   `json:"url"`
+	URL  string   `json:"url,omitempty"`
+	// Value is sent back to the event url of the app when the button of a card sent by the app is clicked
+	Value interface{} `json:"value,omitempty"`
 }
 
 type ZhCn struct {
@@ -179,6 +181,19 @@ func (lc *LarkCard) AddI18NElementsZhcnAction(content, url string) {
 	lc.I18NElements.ZhCn = append(lc.I18NElements.ZhCn, zhcnElem)
 }
 
+func (lc *LarkCard) AddI18NElementsZhcnActions(actions ...*Action) {
+	if lc.I18NElements == nil {
+		lc.I18NElements = &I18NElements{
+			ZhCn: make([]*ZhCn, 0),
+		}
+	}
+	zhcnElem := &ZhCn{
+		Actions: actions,
+		Tag:     feishuTagAction,
+	}
+	lc.I18NElements.ZhCn = append(lc.I18NElements.ZhCn, zhcnElem)
+}
+
 func (w *Service) sendFeishuMessage(uri string, lcMsg *LarkCard) error {
 	message := LarkCardReq{
 		MsgType: feishuCardType,
@@ -0,0 +1,177 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instantmessage
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html/template"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	templatemodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	"github.com/koderover/zadig/pkg/shared/client/user"
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/tool/mail"
+)
+
+const mailType = "mail"
+
+//go:embed mail_task.html
+var defaultMailTaskTemplate string
+
+//go:embed mail_approval.html
+var defaultMailApprovalTemplate string
+
+// sendWorkflowTaskMail sends the summary of the task by email, approval means the task is waiting for approval
+func (w *Service) sendWorkflowTaskMail(notify *models.NotifyCtl, summary *taskSummary, approval bool) error {
+	emailHost, err := systemconfig.New().GetEmailHost()
+	if err != nil {
+		return fmt.Errorf("failed to get email host: %s", err)
+	}
+
+	task := summary.workflowTask
+	var projectConfig *templatemodels.ProjectMailNotifyConfig
+	if project, err := templaterepo.NewProductColl().Find(task.ProjectName); err == nil {
+		projectConfig = project.MailNotify
+	}
+	recipients := summary.MailTo
+	if len(recipients) == 0 {
+		recipients = getMailRecipients(notify, task, projectConfig, approval)
+	}
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	body, err := renderMailTemplate(getMailTemplate(projectConfig, approval), summary)
+	if err != nil {
+		return err
+	}
+	for _, recipient := range recipients {
+		err := mail.SendEmail(&mail.EmailParams{
+			From:     emailHost.UserName,
+			To:       recipient,
+			Subject:  summary.Title,
+			Host:     emailHost.Name,
+			UserName: emailHost.UserName,
+			Password: emailHost.Password,
+			Port:     emailHost.Port,
+			Body:     body,
+		})
+		if err != nil {
+			log.Errorf("failed to send email to %s, err: %s", recipient, err)
+		}
+	}
+	return nil
+}
+
+// getMailRecipients collects the email addresses of the notify users, the project recipients and,
+// for an approval, the approvers of the stage waiting for approval
+func getMailRecipients(notify *models.NotifyCtl, task *models.WorkflowTask, projectConfig *templatemodels.ProjectMailNotifyConfig, approval bool) []string {
+	users := append([]*models.User{}, notify.MailUsers...)
+	if approval {
+		for _, stage := range task.Stages {
+			if stage.Approval == nil || stage.Approval.Status != config.StatusWaitingApprove || stage.Approval.NativeApproval == nil {
+				continue
+			}
+			users = append(users, stage.Approval.NativeApproval.ApproveUsers...)
+		}
+	}
+
+	userIDs := sets.NewString()
+	for _, u := range users {
+		if u.Type == "group" {
+			group, err := user.New().GetGroupDetailedInfo(u.GroupID)
+			if err != nil {
+				log.Warnf("failed to find users of group %s, err: %s", u.GroupName, err)
+				continue
+			}
+			userIDs.Insert(group.UIDs...)
+			continue
+		}
+		userIDs.Insert(u.UserID)
+	}
+
+	recipients := sets.NewString()
+	if projectConfig != nil {
+		recipients.Insert(projectConfig.Recipients...)
+	}
+	for _, uid := range userIDs.List() {
+		info, err := user.New().GetUserByID(uid)
+		if err != nil {
+			log.Warnf("failed to find user %s, err: %s", uid, err)
+			continue
+		}
+		if info.Email == "" {
+			log.Warnf("email of user %s is empty", info.Name)
+			continue
+		}
+		recipients.Insert(info.Email)
+	}
+	return recipients.List()
+}
+
+// getMailTemplate returns the template of the project, then the system one, then the built-in one
+func getMailTemplate(projectConfig *templatemodels.ProjectMailNotifyConfig, approval bool) string {
+	if projectConfig != nil {
+		if approval && projectConfig.ApprovalTemplate != "" {
+			return projectConfig.ApprovalTemplate
+		}
+		if !approval && projectConfig.TaskTemplate != "" {
+			return projectConfig.TaskTemplate
+		}
+	}
+	if systemSetting, err := mongodb.NewSystemSettingColl().Get(); err == nil && systemSetting.MailNotify != nil {
+		if approval && systemSetting.MailNotify.ApprovalTemplate != "" {
+			return systemSetting.MailNotify.ApprovalTemplate
+		}
+		if !approval && systemSetting.MailNotify.TaskTemplate != "" {
+			return systemSetting.MailNotify.TaskTemplate
+		}
+	}
+	if approval {
+		return defaultMailApprovalTemplate
+	}
+	return defaultMailTaskTemplate
+}
+
+func renderMailTemplate(tplContent string, data *taskSummary) (string, error) {
+	tmpl, err := template.New("mail").Parse(tplContent)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse email template: %s", err)
+	}
+	buf := new(bytes.Buffer)
+	if err := tmpl.Execute(buf, data); err != nil {
+		return "", fmt.Errorf("failed to render email template: %s", err)
+	}
+	return buf.String(), nil
+}
+
+// ValidateMailTemplate checks that the custom email template can be parsed, an empty template is valid
+func ValidateMailTemplate(tplContent string) error {
+	if tplContent == "" {
+		return nil
+	}
+	_, err := template.New("mail").Parse(tplContent)
+	return err
+}
@@ -38,6 +38,7 @@ import (
 	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/tool/httpclient"
 	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/tool/truststore"
 	"github.com/koderover/zadig/pkg/types"
 )
 
@@ -56,26 +57,26 @@ const (
 )
 
 type Service struct {
-	proxyColl          *mongodb.ProxyColl
 	workflowColl       *mongodb.WorkflowColl
 	pipelineColl       *mongodb.PipelineColl
 	testingColl        *mongodb.TestingColl
 	testTaskStatColl   *mongodb.TestTaskStatColl
 	workflowV4Coll     *mongodb.WorkflowV4Coll
-	workflowTaskV4Coll *mongodb.WorkflowTaskv4Coll
+	workflowTaskV4Coll mongodb.WorkflowTaskV4Store
 	scanningColl       *mongodb.ScanningColl
+	proxyColl          *mongodb.ProxyColl
 }
 
 func NewWeChatClient() *Service {
 	return &Service{
-		proxyColl:          mongodb.NewProxyColl(),
 		workflowColl:       mongodb.NewWorkflowColl(),
 		pipelineColl:       mongodb.NewPipelineColl(),
 		testingColl:        mongodb.NewTestingColl(),
 		testTaskStatColl:   mongodb.NewTestTaskStatColl(),
 		workflowV4Coll:     mongodb.NewWorkflowV4Coll(),
-		workflowTaskV4Coll: mongodb.NewworkflowTaskv4Coll(),
+		workflowTaskV4Coll: mongodb.NewWorkflowTaskV4Store(),
 		scanningColl:       mongodb.NewScanningColl(),
+		proxyColl:          mongodb.NewProxyColl(),
 	}
 }
 
@@ -92,12 +93,17 @@ type wechatNotification struct {
 
 func (w *Service) SendMessageRequest(uri string, message interface{}) ([]byte, error) {
 	c := httpclient.New()
+	if tlsConfig := truststore.TLSConfig(truststore.IntegrationWebhook); tlsConfig != nil {
+		c.SetTLSClientConfig(tlsConfig)
+	}
 
 	// 使用代理
-	proxies, _ := w.proxyColl.List(&mongodb.ProxyArgs{})
-	if len(proxies) != 0 && proxies[0].EnableApplicationProxy {
-		c.SetProxy(proxies[0].GetProxyURL())
-		fmt.Printf("send message is using proxy:%s\n", proxies[0].GetProxyURL())
+	proxyAddr, err := w.proxyColl.GetIntegrationProxyURL(models.ProxyIntegrationIM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the im proxy: %s", err)
+	}
+	if proxyAddr != "" {
+		c.SetProxy(proxyAddr)
 	}
 
 	res, err := c.Post(uri, httpclient.SetBody(message))
@@ -0,0 +1,129 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instantmessage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+const slackType = "slack"
+
+// SlackMessage is the payload of a Slack incoming webhook, Text is shown in the notifications
+type SlackMessage struct {
+	Text   string        `json:"text"`
+	Blocks []*SlackBlock `json:"blocks"`
+}
+
+type SlackBlock struct {
+	Type     string         `json:"type"`
+	Text     *SlackText     `json:"text,omitempty"`
+	Fields   []*SlackText   `json:"fields,omitempty"`
+	Elements []*SlackButton `json:"elements,omitempty"`
+}
+
+type SlackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// SlackButton is a button in an actions block
+type SlackButton struct {
+	Type  string     `json:"type"`
+	Text  *SlackText `json:"text"`
+	URL   string     `json:"url"`
+	Style string     `json:"style,omitempty"`
+}
+
+func newSlackMessage(summary *taskSummary, notify *models.NotifyCtl, approval bool) *SlackMessage {
+	icon := "👍"
+	if !summary.Passed {
+		icon = "⚠️"
+	}
+	title := fmt.Sprintf("%s %s", icon, summary.Title)
+	message := &SlackMessage{
+		Text: title,
+		Blocks: []*SlackBlock{
+			{Type: "header", Text: &SlackText{Type: "plain_text", Text: title}},
+			{Type: "section", Fields: []*SlackText{
+				{Type: "mrkdwn", Text: fmt.Sprintf("*执行用户*\n%s", summary.Task.TaskCreator)},
+				{Type: "mrkdwn", Text: fmt.Sprintf("*项目名称*\n%s", summary.Task.ProjectName)},
+				{Type: "mrkdwn", Text: fmt.Sprintf("*开始时间*\n%s", summary.StartTime)},
+				{Type: "mrkdwn", Text: fmt.Sprintf("*持续时间*\n%s", summary.Duration)},
+			}},
+		},
+	}
+
+	buttonContent := "点击查看更多信息"
+	if approval {
+		buttonContent = "立即审批"
+		details := make([]string, 0)
+		if summary.StageName != "" {
+			details = append(details, fmt.Sprintf("*审批阶段*：%s", summary.StageName))
+		}
+		if summary.Description != "" {
+			details = append(details, fmt.Sprintf("*审批说明*：%s", summary.Description))
+		}
+		if len(details) > 0 {
+			message.Blocks = append(message.Blocks, &SlackBlock{Type: "section", Text: &SlackText{Type: "mrkdwn", Text: strings.Join(details, "\n")}})
+		}
+	}
+	if len(summary.Jobs) > 0 {
+		jobLines := make([]string, 0, len(summary.Jobs))
+		for _, job := range summary.Jobs {
+			jobLines = append(jobLines, fmt.Sprintf("*%s*: %s    *状态*: %s", job.Type, job.Name, job.Status))
+		}
+		message.Blocks = append(message.Blocks,
+			&SlackBlock{Type: "divider"},
+			&SlackBlock{Type: "section", Text: &SlackText{Type: "mrkdwn", Text: strings.Join(jobLines, "\n")}},
+		)
+	}
+	if mention := getSlackMentions(notify); mention != "" {
+		message.Blocks = append(message.Blocks, &SlackBlock{Type: "section", Text: &SlackText{Type: "mrkdwn", Text: mention}})
+	}
+	message.Blocks = append(message.Blocks, &SlackBlock{
+		Type: "actions",
+		Elements: []*SlackButton{{
+			Type:  "button",
+			Text:  &SlackText{Type: "plain_text", Text: buttonContent},
+			URL:   summary.DetailURL,
+			Style: "primary",
+		}},
+	})
+	return message
+}
+
+func getSlackMentions(notify *models.NotifyCtl) string {
+	if notify.IsAtAll {
+		return "<!channel>"
+	}
+	mentions := make([]string, 0, len(notify.SlackUserIDs))
+	for _, id := range notify.SlackUserIDs {
+		mentions = append(mentions, fmt.Sprintf("<@%s>", id))
+	}
+	return strings.Join(mentions, " ")
+}
+
+func (w *Service) sendSlackMessage(uri string, message *SlackMessage) error {
+	if uri == "" {
+		return fmt.Errorf("slack webhook is empty")
+	}
+	_, err := w.SendMessageRequest(uri, message)
+	return err
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instantmessage
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	configbase "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+type jobSummary struct {
+	Name     string
+	Type     string
+	Status   string
+	Duration string
+}
+
+// taskView is the part of the workflow task exposed to the notification templates, the project mail
+// templates are user defined so they must not reach the task arguments and variables
+type taskView struct {
+	ProjectName         string
+	WorkflowName        string
+	WorkflowDisplayName string
+	TaskID              int64
+	TaskCreator         string
+	Status              string
+}
+
+// taskSummary is the structured content of a workflow task notification, it is rendered by the
+// email templates and converted to the Teams and Slack cards
+type taskSummary struct {
+	Task        *taskView
+	Title       string
+	Passed      bool
+	StatusText  string
+	StartTime   string
+	Duration    string
+	DetailURL   string
+	Jobs        []*jobSummary
+	StageName   string
+	Description string
+	// MailTo overrides the email recipients computed from the notify settings
+	MailTo []string
+
+	// workflowTask is kept unexported so that it can't be rendered by the templates
+	workflowTask *models.WorkflowTask
+}
+
+// isSummaryNotifyType returns true for the channels built from the task summary instead of the markdown content
+func isSummaryNotifyType(webHookType string) bool {
+	return webHookType == mailType || webHookType == teamsType || webHookType == slackType
+}
+
+// sendWorkflowTaskSummary sends the summary of the task, approval means the task is waiting for approval
+func (w *Service) sendWorkflowTaskSummary(notify *models.NotifyCtl, task *models.WorkflowTask, approval bool) error {
+	return w.sendTaskSummary(notify, newTaskSummary(task, approval), approval)
+}
+
+func (w *Service) sendTaskSummary(notify *models.NotifyCtl, summary *taskSummary, approval bool) error {
+	switch notify.WebHookType {
+	case mailType:
+		return w.sendWorkflowTaskMail(notify, summary, approval)
+	case teamsType:
+		return w.sendTeamsMessage(notify.TeamsWebHook, newTeamsCard(summary, approval))
+	case slackType:
+		return w.sendSlackMessage(notify.SlackWebHook, newSlackMessage(summary, notify, approval))
+	}
+	return fmt.Errorf("unsupported notify type %s", notify.WebHookType)
+}
+
+func newTaskSummary(task *models.WorkflowTask, approval bool) *taskSummary {
+	summary := &taskSummary{
+		Task: &taskView{
+			ProjectName:         task.ProjectName,
+			WorkflowName:        task.WorkflowName,
+			WorkflowDisplayName: task.WorkflowDisplayName,
+			TaskID:              task.TaskID,
+			TaskCreator:         task.TaskCreator,
+			Status:              string(task.Status),
+		},
+		workflowTask: task,
+		StartTime:    time.Unix(task.StartTime, 0).Format("2006-01-02 15:04:05"),
+		Duration:     (time.Duration(time.Now().Unix()-task.StartTime) * time.Second).String(),
+		DetailURL:    getWorkflowTaskDetailURL(task),
+	}
+	if approval {
+		for _, stage := range task.Stages {
+			if stage.Approval != nil && stage.Approval.Status == config.StatusWaitingApprove {
+				summary.StageName = stage.Name
+				summary.Description = stage.Approval.Description
+			}
+		}
+		summary.Passed = true
+		summary.StatusText = "等待审批"
+		summary.Title = fmt.Sprintf("工作流 %s #%d 等待审批", task.WorkflowDisplayName, task.TaskID)
+		return summary
+	}
+
+	summary.Passed = task.Status == config.StatusPassed
+	summary.StatusText, _ = getWorkflowTaskTplExec("{{ taskStatus .Task.Status }}", &workflowTaskNotification{Task: task})
+	for _, stage := range task.Stages {
+		for _, job := range stage.Jobs {
+			jobNotification := &jobTaskNotification{Job: job}
+			js := &jobSummary{Name: job.Name}
+			js.Type, _ = getJobTaskTplExec("{{ jobType .Job.JobType }}", jobNotification)
+			js.Status, _ = getJobTaskTplExec("{{ taskStatus .Job.Status }}", jobNotification)
+			if job.StartTime > 0 && job.EndTime >= job.StartTime {
+				js.Duration = (time.Duration(job.EndTime-job.StartTime) * time.Second).String()
+			}
+			summary.Jobs = append(summary.Jobs, js)
+		}
+	}
+	summary.Title = fmt.Sprintf("工作流 %s #%d %s", task.WorkflowDisplayName, task.TaskID, summary.StatusText)
+	return summary
+}
+
+func getWorkflowTaskDetailURL(task *models.WorkflowTask) string {
+	return fmt.Sprintf("%s/v1/projects/detail/%s/pipelines/custom/%s/%d?display_name=%s",
+		configbase.SystemAddress(), task.ProjectName, task.WorkflowName, task.TaskID, url.PathEscape(task.WorkflowDisplayName))
+}
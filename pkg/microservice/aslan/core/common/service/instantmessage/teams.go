@@ -0,0 +1,130 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package instantmessage
+
+import (
+	"fmt"
+)
+
+const (
+	teamsType = "msteams"
+
+	teamsAdaptiveCardContentType = "application/vnd.microsoft.card.adaptive"
+	teamsAdaptiveCardSchema      = "http://adaptivecards.io/schemas/adaptive-card.json"
+	teamsAdaptiveCardVersion     = "1.4"
+)
+
+// TeamsMessage is the payload of a Microsoft Teams incoming webhook carrying an adaptive card
+type TeamsMessage struct {
+	Type        string             `json:"type"`
+	Attachments []*TeamsAttachment `json:"attachments"`
+}
+
+type TeamsAttachment struct {
+	ContentType string             `json:"contentType"`
+	Content     *TeamsAdaptiveCard `json:"content"`
+}
+
+type TeamsAdaptiveCard struct {
+	Schema  string          `json:"$schema"`
+	Type    string          `json:"type"`
+	Version string          `json:"version"`
+	Body    []*TeamsElement `json:"body"`
+	Actions []*TeamsAction  `json:"actions,omitempty"`
+}
+
+// TeamsElement is a TextBlock or a FactSet of the adaptive card
+type TeamsElement struct {
+	Type      string       `json:"type"`
+	Text      string       `json:"text,omitempty"`
+	Size      string       `json:"size,omitempty"`
+	Weight    string       `json:"weight,omitempty"`
+	Color     string       `json:"color,omitempty"`
+	Wrap      bool         `json:"wrap,omitempty"`
+	Separator bool         `json:"separator,omitempty"`
+	Facts     []*TeamsFact `json:"facts,omitempty"`
+}
+
+type TeamsFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+type TeamsAction struct {
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+func newTeamsCard(summary *taskSummary, approval bool) *TeamsMessage {
+	color := "Good"
+	if !summary.Passed {
+		color = "Attention"
+	}
+	card := &TeamsAdaptiveCard{
+		Schema:  teamsAdaptiveCardSchema,
+		Type:    "AdaptiveCard",
+		Version: teamsAdaptiveCardVersion,
+		Body: []*TeamsElement{
+			{Type: "TextBlock", Text: summary.Title, Size: "Medium", Weight: "Bolder", Color: color, Wrap: true},
+			{Type: "FactSet", Facts: []*TeamsFact{
+				{Title: "执行用户", Value: summary.Task.TaskCreator},
+				{Title: "项目名称", Value: summary.Task.ProjectName},
+				{Title: "开始时间", Value: summary.StartTime},
+				{Title: "持续时间", Value: summary.Duration},
+			}},
+		},
+	}
+
+	buttonContent := "点击查看更多信息"
+	if approval {
+		buttonContent = "立即审批"
+		if summary.StageName != "" {
+			card.Body[1].Facts = append(card.Body[1].Facts, &TeamsFact{Title: "审批阶段", Value: summary.StageName})
+		}
+		if summary.Description != "" {
+			card.Body[1].Facts = append(card.Body[1].Facts, &TeamsFact{Title: "审批说明", Value: summary.Description})
+		}
+	}
+	if len(summary.Jobs) > 0 {
+		jobFacts := make([]*TeamsFact, 0, len(summary.Jobs))
+		for _, job := range summary.Jobs {
+			jobFacts = append(jobFacts, &TeamsFact{
+				Title: fmt.Sprintf("%s: %s", job.Type, job.Name),
+				Value: job.Status,
+			})
+		}
+		card.Body = append(card.Body, &TeamsElement{Type: "FactSet", Separator: true, Facts: jobFacts})
+	}
+	card.Actions = []*TeamsAction{{Type: "Action.OpenUrl", Title: buttonContent, URL: summary.DetailURL}}
+
+	return &TeamsMessage{
+		Type: "message",
+		Attachments: []*TeamsAttachment{{
+			ContentType: teamsAdaptiveCardContentType,
+			Content:     card,
+		}},
+	}
+}
+
+func (w *Service) sendTeamsMessage(uri string, message *TeamsMessage) error {
+	if uri == "" {
+		return fmt.Errorf("teams webhook is empty")
+	}
+	_, err := w.SendMessageRequest(uri, message)
+	return err
+}
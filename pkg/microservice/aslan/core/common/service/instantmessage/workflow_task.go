@@ -58,6 +58,12 @@ func (w *Service) SendWorkflowTaskAproveNotifications(workflowName string, taskI
 		if !notify.Enabled {
 			continue
 		}
+		if isSummaryNotifyType(notify.WebHookType) {
+			if err := w.sendWorkflowTaskSummary(notify, task, true); err != nil {
+				log.Errorf("failed to send %s notification, err: %s", notify.WebHookType, err)
+			}
+			continue
+		}
 		title, content, larkCard, err := w.getApproveNotificationContent(notify, task)
 		if err != nil {
 			errMsg := fmt.Sprintf("failed to get notification content, err: %s", err)
@@ -103,6 +109,12 @@ func (w *Service) SendWorkflowTaskNotifications(task *models.WorkflowTask) error
 		}
 		statusSets := sets.NewString(notify.NotifyTypes...)
 		if statusSets.Has(string(task.Status)) || (statusChanged && statusSets.Has(string(config.StatusChanged))) {
+			if isSummaryNotifyType(notify.WebHookType) {
+				if err := w.sendWorkflowTaskSummary(notify, task, false); err != nil {
+					log.Errorf("failed to send %s notification, err: %s", notify.WebHookType, err)
+				}
+				continue
+			}
 			title, content, larkCard, err := w.getNotificationContent(notify, task)
 			if err != nil {
 				errMsg := fmt.Sprintf("failed to get notification content, err: %s", err)
@@ -146,6 +158,9 @@ func (w *Service) getApproveNotificationContent(notify *models.NotifyCtl, task *
 		if err != nil {
 			return "", "", nil, err
 		}
+		for _, approval := range getPendingApprovals(task) {
+			content += fmt.Sprintf("\n\n**%s 待审批人**：%s", approval.StageName, approval.Approvers)
+		}
 		return title, content, nil, nil
 	}
 
@@ -156,6 +171,9 @@ func (w *Service) getApproveNotificationContent(notify *models.NotifyCtl, task *
 		feildExecContent, _ := getWorkflowTaskTplExec(feildContent, workflowNotification)
 		lc.AddI18NElementsZhcnFeild(feildExecContent, idx == 0)
 	}
+	for _, approval := range getPendingApprovals(task) {
+		lc.AddI18NElementsZhcnFeild(fmt.Sprintf("**%s 待审批人**：%s", approval.StageName, approval.Approvers), true)
+	}
 	workflowDetailURL, _ = getWorkflowTaskTplExec(workflowDetailURL, workflowNotification)
 	lc.AddI18NElementsZhcnAction(buttonContent, workflowDetailURL)
 	return "", "", lc, nil
@@ -152,11 +152,49 @@ func GetLocalTestSuite(pipelineName, serviceName, testType string, taskID int64,
 }
 
 func GetWorkflowV4LocalTestSuite(workflowName, jobName string, taskID int64, log *zap.SugaredLogger) (*commonmodels.TestReport, error) {
-	testReport := new(commonmodels.TestReport)
-	workflowTask, err := mongodb.NewworkflowTaskv4Coll().Find(workflowName, taskID)
+	workflowTask, err := mongodb.NewWorkflowTaskV4Store().Find(workflowName, taskID)
 	if err != nil {
-		return testReport, fmt.Errorf("cannot find workflow task, workflow name: %s, task id: %d", workflowName, taskID)
+		return new(commonmodels.TestReport), fmt.Errorf("cannot find workflow task, workflow name: %s, task id: %d", workflowName, taskID)
+	}
+	return NewLocalTestSuiteLoader().Load(workflowTask, jobName, log)
+}
+
+// LocalTestSuiteLoader loads the junit test suites of the testing jobs in the workflow tasks, the default
+// storage is looked up once so that the suites of many tasks are loaded without querying it again
+type LocalTestSuiteLoader struct {
+	storage *s3.S3
+	client  *s3tool.Client
+	err     error
+}
+
+func NewLocalTestSuiteLoader() *LocalTestSuiteLoader {
+	return &LocalTestSuiteLoader{}
+}
+
+func (l *LocalTestSuiteLoader) getClient() (*s3.S3, *s3tool.Client, error) {
+	if l.client != nil || l.err != nil {
+		return l.storage, l.client, l.err
+	}
+	l.storage, l.err = s3.FindDefaultS3()
+	if l.err != nil {
+		l.err = fmt.Errorf("GetLocalTestSuite FindDefaultS3 err: %v", l.err)
+		return nil, nil, l.err
+	}
+	forcedPathStyle := true
+	if l.storage.Provider == setting.ProviderSourceAli {
+		forcedPathStyle = false
+	}
+	l.client, l.err = s3tool.NewClient(l.storage.Endpoint, l.storage.Ak, l.storage.Sk, l.storage.Region, l.storage.Insecure, forcedPathStyle)
+	if l.err != nil {
+		l.err = fmt.Errorf("failed to create s3 client for download, error: %+v", l.err)
 	}
+	return l.storage, l.client, l.err
+}
+
+// Load returns the test report of the testing job in the task
+func (l *LocalTestSuiteLoader) Load(workflowTask *commonmodels.WorkflowTask, jobName string, log *zap.SugaredLogger) (*commonmodels.TestReport, error) {
+	testReport := new(commonmodels.TestReport)
+	workflowName, taskID := workflowTask.WorkflowName, workflowTask.TaskID
 	var jobTask *commonmodels.JobTask
 	for _, stage := range workflowTask.Stages {
 		for _, job := range stage.Jobs {
@@ -195,36 +233,25 @@ func GetWorkflowV4LocalTestSuite(workflowName, jobName string, taskID int64, log
 		return testReport, fmt.Errorf("unmashal step spec error: %v", err)
 	}
 
-	var s3Storage *s3.S3
-	var filename string
-	if s3Storage, err = s3.FindDefaultS3(); err == nil {
-		filename, err = util.GenerateTmpFile()
-		defer func() {
-			_ = os.Remove(filename)
-		}()
-		if err != nil {
-			log.Errorf("GetLocalTestSuite GenerateTmpFile err:%v", err)
-		}
-		objectKey := filepath.Join(stepSpec.S3DestDir, stepSpec.FileName)
-		forcedPathStyle := true
-		if s3Storage.Provider == setting.ProviderSourceAli {
-			forcedPathStyle = false
-		}
-		client, err := s3tool.NewClient(s3Storage.Endpoint, s3Storage.Ak, s3Storage.Sk, s3Storage.Region, s3Storage.Insecure, forcedPathStyle)
-		if err != nil {
-			log.Errorf("Failed to create s3 client for download, error: %+v", err)
-			return testReport, fmt.Errorf("failed to create s3 client for download, error: %+v", err)
-		}
-		if err = client.Download(s3Storage.Bucket, objectKey, filename); err != nil {
-			if strings.Contains(err.Error(), "NoSuchKey") {
-				return testReport, fmt.Errorf("getLocalTestSuite s3 Download err: %v", err)
-			}
-			log.Errorf("GetLocalTestSuite s3 Download err:%v", err)
+	s3Storage, client, err := l.getClient()
+	if err != nil {
+		log.Errorf("GetLocalTestSuite get s3 client err: %v", err)
+		return testReport, err
+	}
+	filename, err := util.GenerateTmpFile()
+	defer func() {
+		_ = os.Remove(filename)
+	}()
+	if err != nil {
+		log.Errorf("GetLocalTestSuite GenerateTmpFile err:%v", err)
+	}
+	objectKey := filepath.Join(stepSpec.S3DestDir, stepSpec.FileName)
+	if err = client.Download(s3Storage.Bucket, objectKey, filename); err != nil {
+		if strings.Contains(err.Error(), "NoSuchKey") {
 			return testReport, fmt.Errorf("getLocalTestSuite s3 Download err: %v", err)
 		}
-	} else {
-		log.Errorf("GetLocalTestSuite FindDefaultS3 err:%v", err)
-		return testReport, fmt.Errorf("GetLocalTestSuite FindDefaultS3 err: %v", err)
+		log.Errorf("GetLocalTestSuite s3 Download err:%v", err)
+		return testReport, fmt.Errorf("getLocalTestSuite s3 Download err: %v", err)
 	}
 
 	b, err := ioutil.ReadFile(filename)
@@ -0,0 +1,120 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package jobpolicy checks the base images of the build and freestyle jobs and the targets of the deploy jobs
+// against the job policy configured by the administrators
+package jobpolicy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+// GetPolicy returns the job policy, nil is returned if the policy is not enabled
+func GetPolicy() (*models.JobPolicyConfig, error) {
+	systemSetting, err := commonrepo.NewSystemSettingColl().Get()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job policy: %v", err)
+	}
+	if systemSetting.JobPolicy == nil || !systemSetting.JobPolicy.Enabled {
+		return nil, nil
+	}
+	return systemSetting.JobPolicy, nil
+}
+
+// Validate checks the patterns of the policy
+func Validate(policy *models.JobPolicyConfig) error {
+	for _, pattern := range policy.AllowedImages {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid image pattern %s: %v", pattern, err)
+		}
+	}
+	for _, target := range policy.AllowedDeployTargets {
+		if target.ClusterID == "" {
+			return fmt.Errorf("cluster of the deploy target can not be empty")
+		}
+		for _, pattern := range target.Namespaces {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("invalid namespace pattern %s: %v", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// CheckJobImage checks the base image of a build or freestyle job, the built-in images provided by zadig are
+// always allowed
+func CheckJobImage(jobName, image, imageFrom string) error {
+	if imageFrom != setting.ImageFromCustom || image == "" {
+		return nil
+	}
+	policy, err := GetPolicy()
+	if err != nil || policy == nil || len(policy.AllowedImages) == 0 {
+		return err
+	}
+	repo := imageRepository(image)
+	if matchAny(policy.AllowedImages, repo) {
+		return nil
+	}
+	return fmt.Errorf("job policy: image %s of job %s is not in the allowed images", image, jobName)
+}
+
+// CheckDeployTarget checks the cluster and namespace targeted by a deploy job
+func CheckDeployTarget(jobName, clusterID, namespace string) error {
+	policy, err := GetPolicy()
+	if err != nil || policy == nil || len(policy.AllowedDeployTargets) == 0 {
+		return err
+	}
+	if clusterID == "" {
+		clusterID = setting.LocalClusterID
+	}
+	for _, target := range policy.AllowedDeployTargets {
+		if target.ClusterID != clusterID {
+			continue
+		}
+		if len(target.Namespaces) == 0 || matchAny(target.Namespaces, namespace) {
+			return nil
+		}
+	}
+	return fmt.Errorf("job policy: namespace %s of cluster %s targeted by job %s is not in the allowed deploy targets", namespace, clusterID, jobName)
+}
+
+// imageRepository removes the tag and the digest of the image, e.g. registry.example.com/base/go:1.20 is
+// registry.example.com/base/go
+func imageRepository(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image
+}
+
+// matchAny returns true if s fully matches one of the patterns, e.g. prod does not match prod-.*
+func matchAny(patterns []string, s string) bool {
+	for _, pattern := range patterns {
+		if matched, err := regexp.MatchString("^(?:"+pattern+")$", s); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
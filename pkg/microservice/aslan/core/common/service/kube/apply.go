@@ -71,6 +71,8 @@ type ResourceApplyParam struct {
 	InjectSecrets    bool
 	SharedEnvHandler SharedEnvHandler
 	Uninstall        bool
+	// ConfirmProtected allows modifying the resources protected in confirm mode, resources in skip mode are never modified
+	ConfirmProtected bool
 }
 
 func DeploymentSelectorLabelExists(resourceName, namespace string, informer informers.SharedInformerFactory, log *zap.SugaredLogger) bool {
@@ -247,6 +249,16 @@ func CreateOrPatchResource(applyParam *ResourceApplyParam, log *zap.SugaredLogge
 		return nil, err
 	}
 
+	apiReader, err := kubeclient.GetKubeAPIReader(config.HubServerAddress(), productInfo.ClusterID)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to init k8s api reader")
+	}
+	// uninstalling has been confirmed by the user already
+	curResources, resources, err = excludeProtectedResources(namespace, applyParam.ConfirmProtected || applyParam.Uninstall, apiReader, curResources, resources, log)
+	if err != nil {
+		return nil, err
+	}
+
 	if applyParam.Uninstall {
 		if !commonutil.ServiceDeployed(applyParam.ServiceName, productInfo.ServiceDeployStrategy) {
 			return nil, nil
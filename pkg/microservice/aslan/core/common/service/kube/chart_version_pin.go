@@ -0,0 +1,91 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	templatemodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonutil "github.com/koderover/zadig/pkg/microservice/aslan/core/common/util"
+	"github.com/koderover/zadig/pkg/setting"
+	helmtool "github.com/koderover/zadig/pkg/tool/helmclient"
+)
+
+// GetChartRepoCreation returns the chart repo info of the service created from chart repo
+func GetChartRepoCreation(svcTemp *commonmodels.Service) (*commonmodels.CreateFromChartRepo, error) {
+	if svcTemp == nil || svcTemp.Source != setting.SourceFromChartRepo {
+		return nil, fmt.Errorf("service is not created from chart repo")
+	}
+	bs, err := json.Marshal(svcTemp.CreateFrom)
+	if err != nil {
+		return nil, err
+	}
+	ret := &commonmodels.CreateFromChartRepo{}
+	if err = json.Unmarshal(bs, ret); err != nil {
+		return nil, err
+	}
+	if ret.ChartRepoName == "" || ret.ChartName == "" {
+		return nil, fmt.Errorf("invalid chart repo info of service: %s", svcTemp.ServiceName)
+	}
+	return ret, nil
+}
+
+// PreparePinnedChart downloads the chart version pinned in the environment for the service and returns the chart info to deploy with
+// the original chart info is returned if no version is pinned or the pinned version equals to the one in service template
+// the returned bool is true if the chart should be installed from the downloaded chart instead of the service template
+func PreparePinnedChart(product *commonmodels.Product, svcTemp *commonmodels.Service, chartInfo *templatemodels.ServiceRender) (*templatemodels.ServiceRender, bool, error) {
+	if svcTemp == nil || svcTemp.HelmChart == nil || chartInfo == nil {
+		return chartInfo, false, nil
+	}
+	pinnedVersion := product.ChartVersionPins[svcTemp.ServiceName]
+	if pinnedVersion == "" || pinnedVersion == svcTemp.HelmChart.Version || svcTemp.Source != setting.SourceFromChartRepo {
+		return chartInfo, false, nil
+	}
+
+	creation, err := GetChartRepoCreation(svcTemp)
+	if err != nil {
+		return nil, false, err
+	}
+	chartRepo, err := commonrepo.NewHelmRepoColl().Find(&commonrepo.HelmRepoFindOption{RepoName: creation.ChartRepoName})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to query chart-repo info, productName: %s, repoName: %s", product.ProductName, creation.ChartRepoName)
+	}
+
+	chartRef := fmt.Sprintf("%s/%s", creation.ChartRepoName, creation.ChartName)
+	localPath := config.LocalServicePathWithRevision(svcTemp.ProductName, svcTemp.ServiceName, pinnedVersion, product.Production)
+	// remove local file to untar
+	_ = os.RemoveAll(localPath)
+
+	hClient, err := helmtool.NewClient()
+	if err != nil {
+		return nil, false, err
+	}
+	err = hClient.DownloadChart(commonutil.GeneHelmRepo(chartRepo), chartRef, pinnedVersion, localPath, true)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to download chart, chartName: %s, chartRepo: %+v, version: %s, err: %s", creation.ChartName, chartRepo.RepoName, pinnedVersion, err)
+	}
+
+	pinnedChart := *chartInfo
+	pinnedChart.ChartName = creation.ChartName
+	pinnedChart.ChartVersion = pinnedVersion
+	return &pinnedChart, true, nil
+}
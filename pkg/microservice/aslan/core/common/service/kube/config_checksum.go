@@ -0,0 +1,177 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
+
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/types"
+	"github.com/koderover/zadig/pkg/util"
+)
+
+type renderedConfigs struct {
+	// checksums of the ConfigMaps and Secrets, keyed by Kind/Name
+	checksums map[string]string
+	// the ConfigMaps and Secrets referenced by the pods of the workloads, keyed by Kind/Name
+	references map[string][]string
+}
+
+// FindConfigChangedWorkloads compares the ConfigMaps and Secrets rendered in the current and the updated service yaml,
+// and returns the Deployments and StatefulSets using the changed ones, the pods of which are not restarted by kubernetes
+// when only the configs are changed. The result is keyed by Kind/Name of the workloads, with the changed configs as values.
+func FindConfigChangedWorkloads(currentYaml, updatedYaml string) (map[string][]string, error) {
+	current, err := parseRenderedConfigs(currentYaml)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := parseRenderedConfigs(updatedYaml)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make(map[string][]string)
+	for workload, configs := range updated.references {
+		for _, config := range configs {
+			checksum, ok := updated.checksums[config]
+			if !ok {
+				// the config is not managed by the service
+				continue
+			}
+			if current.checksums[config] != checksum {
+				resp[workload] = append(resp[workload], config)
+			}
+		}
+	}
+	return resp, nil
+}
+
+func parseRenderedConfigs(yamlContent string) (*renderedConfigs, error) {
+	resp := &renderedConfigs{
+		checksums:  make(map[string]string),
+		references: make(map[string][]string),
+	}
+	for _, yamlStr := range util.SplitYaml(yamlContent) {
+		resKind := new(types.KubeResourceKind)
+		if err := yaml.Unmarshal([]byte(yamlStr), &resKind); err != nil {
+			return nil, fmt.Errorf("unmarshal ResourceKind error: %v", err)
+		}
+		decoder := yamlutil.NewYAMLOrJSONDecoder(bytes.NewReader([]byte(yamlStr)), 5*1024*1024)
+		key := resKind.Kind + "/" + resKind.Metadata.Name
+
+		switch resKind.Kind {
+		case setting.ConfigMap:
+			cm := &corev1.ConfigMap{}
+			if err := decoder.Decode(cm); err != nil {
+				return nil, fmt.Errorf("unmarshal ConfigMap error: %v", err)
+			}
+			resp.checksums[key] = configChecksum(cm.Data, cm.BinaryData)
+		case setting.Secret:
+			secret := &corev1.Secret{}
+			if err := decoder.Decode(secret); err != nil {
+				return nil, fmt.Errorf("unmarshal Secret error: %v", err)
+			}
+			resp.checksums[key] = configChecksum(secret.Data, secret.StringData)
+		case setting.Deployment:
+			deployment := &appsv1.Deployment{}
+			if err := decoder.Decode(deployment); err != nil {
+				return nil, fmt.Errorf("unmarshal Deployment error: %v", err)
+			}
+			resp.references[key] = podConfigReferences(&deployment.Spec.Template.Spec)
+		case setting.StatefulSet:
+			statefulSet := &appsv1.StatefulSet{}
+			if err := decoder.Decode(statefulSet); err != nil {
+				return nil, fmt.Errorf("unmarshal StatefulSet error: %v", err)
+			}
+			resp.references[key] = podConfigReferences(&statefulSet.Spec.Template.Spec)
+		}
+	}
+	return resp, nil
+}
+
+func configChecksum(data ...interface{}) string {
+	// the keys of maps are sorted by json, so the checksum is stable
+	content, _ := json.Marshal(data)
+	hash := sha256.Sum256(content)
+	return hex.EncodeToString(hash[:])
+}
+
+// podConfigReferences returns the ConfigMaps and Secrets mounted as volumes or used as envs by the pod
+func podConfigReferences(spec *corev1.PodSpec) []string {
+	refSet := make(map[string]bool)
+	resp := make([]string, 0)
+	add := func(kind, name string) {
+		key := kind + "/" + name
+		if name != "" && !refSet[key] {
+			refSet[key] = true
+			resp = append(resp, key)
+		}
+	}
+
+	for _, volume := range spec.Volumes {
+		if volume.ConfigMap != nil {
+			add(setting.ConfigMap, volume.ConfigMap.Name)
+		}
+		if volume.Secret != nil {
+			add(setting.Secret, volume.Secret.SecretName)
+		}
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				if source.ConfigMap != nil {
+					add(setting.ConfigMap, source.ConfigMap.Name)
+				}
+				if source.Secret != nil {
+					add(setting.Secret, source.Secret.Name)
+				}
+			}
+		}
+	}
+
+	containers := append([]corev1.Container{}, spec.InitContainers...)
+	containers = append(containers, spec.Containers...)
+	for _, container := range containers {
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.ConfigMapRef != nil {
+				add(setting.ConfigMap, envFrom.ConfigMapRef.Name)
+			}
+			if envFrom.SecretRef != nil {
+				add(setting.Secret, envFrom.SecretRef.Name)
+			}
+		}
+		for _, env := range container.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if env.ValueFrom.ConfigMapKeyRef != nil {
+				add(setting.ConfigMap, env.ValueFrom.ConfigMapKeyRef.Name)
+			}
+			if env.ValueFrom.SecretKeyRef != nil {
+				add(setting.Secret, env.ValueFrom.SecretKeyRef.Name)
+			}
+		}
+	}
+	return resp
+}
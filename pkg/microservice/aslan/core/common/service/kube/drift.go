@@ -0,0 +1,306 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+// the metadata fields other than these are maintained by the api server and never compared
+var driftComparedMetadata = []string{"labels", "annotations"}
+
+type ManifestDriftOption struct {
+	Namespace     string
+	Manifest      string
+	IgnoredFields []string
+	// Labels and Annotations are added to the missing resources recreated in revert, the same as the ones added
+	// when deploying
+	Labels      map[string]string
+	Annotations map[string]string
+	Revert      bool
+
+	APIReader  client.Reader
+	KubeClient client.Client
+}
+
+// DetectManifestDrift compares the resources in the manifest with the live objects. Only the fields set in the
+// manifest are compared, so that the fields defaulted by the api server or added by the controllers are not
+// reported as drift.
+func DetectManifestDrift(option *ManifestDriftOption) ([]*commonmodels.ResourceDrift, error) {
+	desiredResources, err := manifestToUnstructured(option.Manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the desired manifest: %s", err)
+	}
+
+	scaledWorkloads, err := listHPAScaledWorkloads(option.Namespace, option.APIReader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the hpa: %s", err)
+	}
+
+	ret := make([]*commonmodels.ResourceDrift, 0)
+	for _, desired := range desiredResources {
+		if desired.GetNamespace() == "" {
+			desired.SetNamespace(option.Namespace)
+		}
+		ignoredFields := option.IgnoredFields
+		// the replicas of the workloads scaled by hpa are managed by the hpa controller
+		hpaScaled := scaledWorkloads.Has(desired.GetKind() + "/" + desired.GetName())
+		if hpaScaled {
+			ignoredFields = append([]string{"spec.replicas"}, ignoredFields...)
+		}
+
+		live := &unstructured.Unstructured{}
+		live.SetGroupVersionKind(desired.GroupVersionKind())
+		err := option.APIReader.Get(context.TODO(), client.ObjectKey{Namespace: desired.GetNamespace(), Name: desired.GetName()}, live)
+		if err != nil && !apierrors.IsNotFound(err) {
+			ret = append(ret, &commonmodels.ResourceDrift{
+				Kind:  desired.GetKind(),
+				Name:  desired.GetName(),
+				Error: err.Error(),
+			})
+			continue
+		}
+
+		drift := &commonmodels.ResourceDrift{
+			Kind: desired.GetKind(),
+			Name: desired.GetName(),
+		}
+		if apierrors.IsNotFound(err) {
+			drift.Missing = true
+		} else {
+			drift.Fields = diffDesiredFields(desired.Object, live.Object, ignoredFields)
+			if len(drift.Fields) == 0 {
+				continue
+			}
+		}
+
+		if option.Revert {
+			if hpaScaled && !drift.Missing {
+				unstructured.RemoveNestedField(desired.Object, "spec", "replicas")
+			}
+			if err := revertResourceDrift(desired, live, drift.Missing, option); err != nil {
+				drift.Error = fmt.Sprintf("failed to revert: %s", err)
+			} else {
+				drift.Reverted = true
+			}
+		}
+		ret = append(ret, drift)
+	}
+	return ret, nil
+}
+
+func revertResourceDrift(desired, live *unstructured.Unstructured, missing bool, option *ManifestDriftOption) error {
+	if missing {
+		desired.SetLabels(mergeStringMap(desired.GetLabels(), option.Labels))
+		desired.SetAnnotations(mergeStringMap(desired.GetAnnotations(), option.Annotations))
+		return option.KubeClient.Create(context.TODO(), desired)
+	}
+	// the drift is never reverted without confirmation
+	skip, err := CheckProtectedObject(live, live.GetKind(), false)
+	if err != nil {
+		return err
+	}
+	if skip {
+		return fmt.Errorf("%s/%s is protected by %s", live.GetKind(), live.GetName(), setting.ProtectedResourceKey)
+	}
+	// the desired fields are merged into the live object so that the fields not managed by zadig are kept,
+	// and the update fails on conflicts with the changes made after the detection
+	live.Object = mergeDesiredFields(desired.Object, live.Object).(map[string]interface{})
+	return option.KubeClient.Update(context.TODO(), live)
+}
+
+// listHPAScaledWorkloads returns the workloads targeted by the hpa in the namespace, keyed by Kind/Name
+func listHPAScaledWorkloads(namespace string, reader client.Reader) (sets.String, error) {
+	hpaList := &autoscalingv1.HorizontalPodAutoscalerList{}
+	if err := reader.List(context.TODO(), hpaList, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	resp := sets.NewString()
+	for _, hpa := range hpaList.Items {
+		resp.Insert(hpa.Spec.ScaleTargetRef.Kind + "/" + hpa.Spec.ScaleTargetRef.Name)
+	}
+	return resp, nil
+}
+
+func mergeStringMap(base, added map[string]string) map[string]string {
+	if base == nil {
+		base = make(map[string]string)
+	}
+	for k, v := range added {
+		base[k] = v
+	}
+	return base
+}
+
+func diffDesiredFields(desired, live map[string]interface{}, ignoredFields []string) []string {
+	fields := make([]string, 0)
+	for key, value := range desired {
+		switch key {
+		case "apiVersion", "kind", "status":
+			continue
+		case "metadata":
+			desiredMeta, _ := value.(map[string]interface{})
+			liveMeta, _ := live[key].(map[string]interface{})
+			for _, metaKey := range driftComparedMetadata {
+				if v, ok := desiredMeta[metaKey]; ok {
+					diffValue("metadata."+metaKey, v, liveMeta[metaKey], ignoredFields, &fields)
+				}
+			}
+		default:
+			diffValue(key, value, live[key], ignoredFields, &fields)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func diffValue(path string, desired, live interface{}, ignoredFields []string, fields *[]string) {
+	if fieldIgnored(path, ignoredFields) || isZeroValue(desired) && isZeroValue(live) {
+		return
+	}
+
+	switch desiredValue := desired.(type) {
+	case map[string]interface{}:
+		liveValue, ok := live.(map[string]interface{})
+		if !ok {
+			*fields = append(*fields, path)
+			return
+		}
+		for key, value := range desiredValue {
+			diffValue(path+"."+key, value, liveValue[key], ignoredFields, fields)
+		}
+	case []interface{}:
+		// the elements appended by the controllers, such as the image pull secrets, are allowed in the live list
+		liveValue, ok := live.([]interface{})
+		if !ok || len(liveValue) < len(desiredValue) {
+			*fields = append(*fields, path)
+			return
+		}
+		for i, value := range desiredValue {
+			diffValue(fmt.Sprintf("%s[%d]", path, i), value, liveValue[i], ignoredFields, fields)
+		}
+	default:
+		if !scalarEqual(desired, live) {
+			*fields = append(*fields, path)
+		}
+	}
+}
+
+func fieldIgnored(path string, ignoredFields []string) bool {
+	for _, field := range ignoredFields {
+		if path == field || strings.HasPrefix(path, field+".") || strings.HasPrefix(path, field+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// isZeroValue treats the empty values the same as the missing ones since the api server drops the omitempty fields
+func isZeroValue(v interface{}) bool {
+	switch value := v.(type) {
+	case nil:
+		return true
+	case string:
+		return value == ""
+	case bool:
+		return !value
+	case map[string]interface{}:
+		return len(value) == 0
+	case []interface{}:
+		return len(value) == 0
+	default:
+		f, ok := toFloat(v)
+		return ok && f == 0
+	}
+}
+
+func scalarEqual(desired, live interface{}) bool {
+	if df, ok := toFloat(desired); ok {
+		lf, ok := toFloat(live)
+		return ok && df == lf
+	}
+	if fmt.Sprintf("%v", desired) == fmt.Sprintf("%v", live) {
+		return true
+	}
+	// quantities are normalized by the api server, such as 1000m to 1
+	ds, ok1 := desired.(string)
+	ls, ok2 := live.(string)
+	if !ok1 || !ok2 {
+		return false
+	}
+	dq, err := resource.ParseQuantity(ds)
+	if err != nil {
+		return false
+	}
+	lq, err := resource.ParseQuantity(ls)
+	return err == nil && dq.Cmp(lq) == 0
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch value := v.(type) {
+	case int:
+		return float64(value), true
+	case int32:
+		return float64(value), true
+	case int64:
+		return float64(value), true
+	case float64:
+		return value, true
+	}
+	return 0, false
+}
+
+func mergeDesiredFields(desired, live interface{}) interface{} {
+	switch desiredValue := desired.(type) {
+	case map[string]interface{}:
+		liveValue, ok := live.(map[string]interface{})
+		if !ok {
+			return desired
+		}
+		for key, value := range desiredValue {
+			if key == "status" {
+				continue
+			}
+			liveValue[key] = mergeDesiredFields(value, liveValue[key])
+		}
+		return liveValue
+	case []interface{}:
+		liveValue, ok := live.([]interface{})
+		if !ok || len(liveValue) < len(desiredValue) {
+			return desired
+		}
+		for i, value := range desiredValue {
+			liveValue[i] = mergeDesiredFields(value, liveValue[i])
+		}
+		return liveValue
+	default:
+		return desired
+	}
+}
@@ -0,0 +1,273 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/kube/wrapper"
+	"github.com/koderover/zadig/pkg/tool/kube/getter"
+)
+
+const (
+	HealthStatusHealthy   = "healthy"
+	HealthStatusDegraded  = "degraded"
+	HealthStatusUnhealthy = "unhealthy"
+
+	DefaultRestartWindow = 30 * time.Minute
+)
+
+type WorkloadHealth struct {
+	Kind            string `json:"kind"`
+	Name            string `json:"name"`
+	Status          string `json:"status"`
+	Replicas        int32  `json:"replicas"`
+	ReadyReplicas   int32  `json:"ready_replicas"`
+	UpdatedReplicas int32  `json:"updated_replicas"`
+	// UnreadyPods are the running pods not passing the readiness probes
+	UnreadyPods []string `json:"unready_pods"`
+	// RecentRestarts counts the containers restarted within the restart window
+	RecentRestarts int32      `json:"recent_restarts"`
+	HPA            *HPAHealth `json:"hpa,omitempty"`
+	Messages       []string   `json:"messages"`
+}
+
+type HPAHealth struct {
+	Name            string `json:"name"`
+	MinReplicas     int32  `json:"min_replicas"`
+	MaxReplicas     int32  `json:"max_replicas"`
+	CurrentReplicas int32  `json:"current_replicas"`
+	DesiredReplicas int32  `json:"desired_replicas"`
+	AbleToScale     bool   `json:"able_to_scale"`
+	ScalingActive   bool   `json:"scaling_active"`
+	// ScalingLimited is true if the desired replicas are capped by the max replicas
+	ScalingLimited bool `json:"scaling_limited"`
+}
+
+// WorstHealthStatus returns the worse one of the two health status
+func WorstHealthStatus(a, b string) string {
+	rank := map[string]int{HealthStatusHealthy: 0, HealthStatusDegraded: 1, HealthStatusUnhealthy: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+// EvaluateManifestHealth evaluates the live health of the deployments and statefulsets defined in the manifest.
+// A workload is unhealthy if not all the replicas are ready, and degraded if its containers restarted within the
+// restart window or its hpa is not able to scale as desired.
+func EvaluateManifestHealth(namespace, manifest string, restartWindow time.Duration, kubeClient client.Client) ([]*WorkloadHealth, error) {
+	resources, err := manifestToUnstructured(manifest)
+	if err != nil {
+		return nil, err
+	}
+	workloads := make([]*WorkloadResource, 0, len(resources))
+	for _, u := range resources {
+		workloads = append(workloads, &WorkloadResource{Type: u.GetKind(), Name: u.GetName()})
+	}
+	return EvaluateWorkloadsHealth(namespace, workloads, restartWindow, kubeClient)
+}
+
+// EvaluateWorkloadsHealth evaluates the live health of the deployments and statefulsets among the workloads, the
+// other kinds are ignored
+func EvaluateWorkloadsHealth(namespace string, workloads []*WorkloadResource, restartWindow time.Duration, kubeClient client.Client) ([]*WorkloadHealth, error) {
+	ret := make([]*WorkloadHealth, 0)
+	var hpas []*autoscalingv2.HorizontalPodAutoscaler
+	hpaListed := false
+	for _, workload := range workloads {
+		var health *WorkloadHealth
+		switch workload.Type {
+		case setting.Deployment:
+			deploy, found, err := getter.GetDeployment(namespace, workload.Name, kubeClient)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get deployment %s: %s", workload.Name, err)
+			}
+			if !found {
+				ret = append(ret, missingWorkloadHealth(setting.Deployment, workload.Name))
+				continue
+			}
+			health = deploymentHealth(deploy)
+			health.evaluatePods(namespace, deploy.Spec.Selector, restartWindow, kubeClient)
+		case setting.StatefulSet:
+			sts, found, err := getter.GetStatefulSet(namespace, workload.Name, kubeClient)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get statefulset %s: %s", workload.Name, err)
+			}
+			if !found {
+				ret = append(ret, missingWorkloadHealth(setting.StatefulSet, workload.Name))
+				continue
+			}
+			health = statefulSetHealth(sts)
+			health.evaluatePods(namespace, sts.Spec.Selector, restartWindow, kubeClient)
+		default:
+			continue
+		}
+
+		if !hpaListed {
+			hpaListed = true
+			// autoscaling/v2 is not served before k8s 1.23, hpa status is left empty in that case
+			hpas, _ = getter.ListHorizontalPodAutoscalers(namespace, nil, kubeClient)
+		}
+		for _, hpa := range hpas {
+			if hpa.Spec.ScaleTargetRef.Kind == health.Kind && hpa.Spec.ScaleTargetRef.Name == health.Name {
+				health.evaluateHPA(hpa)
+				break
+			}
+		}
+		ret = append(ret, health)
+	}
+	return ret, nil
+}
+
+func missingWorkloadHealth(kind, name string) *WorkloadHealth {
+	return &WorkloadHealth{
+		Kind:     kind,
+		Name:     name,
+		Status:   HealthStatusUnhealthy,
+		Messages: []string{"workload not found"},
+	}
+}
+
+func deploymentHealth(deploy *appsv1.Deployment) *WorkloadHealth {
+	health := &WorkloadHealth{
+		Kind:            setting.Deployment,
+		Name:            deploy.Name,
+		Status:          HealthStatusHealthy,
+		Replicas:        1,
+		ReadyReplicas:   deploy.Status.ReadyReplicas,
+		UpdatedReplicas: deploy.Status.UpdatedReplicas,
+		UnreadyPods:     make([]string, 0),
+		Messages:        make([]string, 0),
+	}
+	if deploy.Spec.Replicas != nil {
+		health.Replicas = *deploy.Spec.Replicas
+	}
+	health.evaluateReplicas()
+	return health
+}
+
+func statefulSetHealth(sts *appsv1.StatefulSet) *WorkloadHealth {
+	health := &WorkloadHealth{
+		Kind:            setting.StatefulSet,
+		Name:            sts.Name,
+		Status:          HealthStatusHealthy,
+		Replicas:        1,
+		ReadyReplicas:   sts.Status.ReadyReplicas,
+		UpdatedReplicas: sts.Status.UpdatedReplicas,
+		UnreadyPods:     make([]string, 0),
+		Messages:        make([]string, 0),
+	}
+	if sts.Spec.Replicas != nil {
+		health.Replicas = *sts.Spec.Replicas
+	}
+	health.evaluateReplicas()
+	return health
+}
+
+func (h *WorkloadHealth) mark(status, message string) {
+	h.Status = WorstHealthStatus(h.Status, status)
+	h.Messages = append(h.Messages, message)
+}
+
+func (h *WorkloadHealth) evaluateReplicas() {
+	if h.ReadyReplicas < h.Replicas {
+		h.mark(HealthStatusUnhealthy, fmt.Sprintf("%d/%d replicas are ready", h.ReadyReplicas, h.Replicas))
+	}
+}
+
+func (h *WorkloadHealth) evaluatePods(namespace string, selector *metav1.LabelSelector, restartWindow time.Duration, kubeClient client.Client) {
+	labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		h.Messages = append(h.Messages, fmt.Sprintf("invalid selector: %s", err))
+		return
+	}
+	pods, err := getter.ListPods(namespace, labelSelector, kubeClient)
+	if err != nil {
+		h.Messages = append(h.Messages, fmt.Sprintf("failed to list pods: %s", err))
+		return
+	}
+
+	since := time.Now().Add(-restartWindow)
+	for _, pod := range pods {
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		if pod.Status.Phase == corev1.PodRunning && !wrapper.Pod(pod).Ready() {
+			h.UnreadyPods = append(h.UnreadyPods, pod.Name)
+		}
+		for _, cs := range podContainerStatuses(pod) {
+			if cs.State.Waiting != nil {
+				switch cs.State.Waiting.Reason {
+				case "CrashLoopBackOff", "ImagePullBackOff", "ErrImagePull", "CreateContainerConfigError":
+					h.mark(HealthStatusUnhealthy, fmt.Sprintf("pod %s container %s: %s", pod.Name, cs.Name, cs.State.Waiting.Reason))
+				}
+			}
+			if cs.RestartCount > 0 && cs.LastTerminationState.Terminated != nil && cs.LastTerminationState.Terminated.FinishedAt.Time.After(since) {
+				h.RecentRestarts++
+			}
+		}
+	}
+
+	if len(h.UnreadyPods) > 0 {
+		h.mark(HealthStatusUnhealthy, fmt.Sprintf("pods not passing readiness probes: %v", h.UnreadyPods))
+	}
+	if h.RecentRestarts > 0 {
+		h.mark(HealthStatusDegraded, fmt.Sprintf("%d containers restarted in the last %s", h.RecentRestarts, restartWindow))
+	}
+}
+
+func (h *WorkloadHealth) evaluateHPA(hpa *autoscalingv2.HorizontalPodAutoscaler) {
+	hpaHealth := &HPAHealth{
+		Name:            hpa.Name,
+		MinReplicas:     1,
+		MaxReplicas:     hpa.Spec.MaxReplicas,
+		CurrentReplicas: hpa.Status.CurrentReplicas,
+		DesiredReplicas: hpa.Status.DesiredReplicas,
+		AbleToScale:     true,
+		ScalingActive:   true,
+	}
+	if hpa.Spec.MinReplicas != nil {
+		hpaHealth.MinReplicas = *hpa.Spec.MinReplicas
+	}
+	for _, condition := range hpa.Status.Conditions {
+		switch condition.Type {
+		case autoscalingv2.AbleToScale:
+			hpaHealth.AbleToScale = condition.Status != corev1.ConditionFalse
+		case autoscalingv2.ScalingActive:
+			hpaHealth.ScalingActive = condition.Status != corev1.ConditionFalse
+		case autoscalingv2.ScalingLimited:
+			// scaling is limited by the min replicas as well, which is not a problem
+			hpaHealth.ScalingLimited = condition.Status == corev1.ConditionTrue && hpaHealth.DesiredReplicas >= hpaHealth.MaxReplicas
+		}
+	}
+	h.HPA = hpaHealth
+
+	if !hpaHealth.AbleToScale || !hpaHealth.ScalingActive {
+		h.mark(HealthStatusDegraded, fmt.Sprintf("hpa %s is not able to scale", hpa.Name))
+	}
+	if hpaHealth.ScalingLimited {
+		h.mark(HealthStatusDegraded, fmt.Sprintf("hpa %s reaches the max replicas %d", hpa.Name, hpaHealth.MaxReplicas))
+	}
+}
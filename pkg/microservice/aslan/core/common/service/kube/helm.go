@@ -31,6 +31,7 @@ import (
 	"github.com/pkg/errors"
 	"helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/releaseutil"
+	"helm.sh/helm/v3/pkg/storage/driver"
 	versionedclient "istio.io/client-go/pkg/clientset/versioned"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -70,6 +71,9 @@ type ReleaseInstallParam struct {
 	Timeout        int
 	DryRun         bool
 	Production     bool
+	DeployConfig   *commonmodels.HelmDeployConfig
+	// ConfirmProtected allows upgrading the release owning the resources protected in confirm mode
+	ConfirmProtected bool
 }
 
 func GetValidMatchData(spec *commonmodels.ImagePathSpec) map[string]string {
@@ -129,6 +133,10 @@ func InstallOrUpgradeHelmChartWithValues(param *ReleaseInstallParam, isRetry boo
 	if param.Timeout > 0 {
 		chartSpec.Timeout = time.Second * time.Duration(param.Timeout)
 	}
+	helmOptions, err := applyHelmDeployConfig(param.DeployConfig, chartSpec)
+	if err != nil {
+		return fmt.Errorf("failed to apply helm deploy config, err: %s", err)
+	}
 
 	// If the target environment is a shared environment and a sub env, we need to clear the deployed K8s Service.
 	ctx := context.TODO()
@@ -137,13 +145,17 @@ func InstallOrUpgradeHelmChartWithValues(param *ReleaseInstallParam, isRetry boo
 		return fmt.Errorf("failed to ensure deleting pre-created K8s Services for product %q in namespace %q: %s", param.ProductName, param.Namespace, err)
 	}
 
+	if err := checkProtectedRelease(param, helmClient); err != nil {
+		return err
+	}
+
 	helmClient, err = helmClient.Clone()
 	if err != nil {
 		return fmt.Errorf("failed to clone helm client: %s", err)
 	}
 
 	var release *release.Release
-	release, err = helmClient.InstallOrUpgradeChart(ctx, chartSpec, nil)
+	release, err = helmClient.InstallOrUpgradeChart(ctx, chartSpec, helmOptions)
 	if err != nil {
 		err = errors.WithMessagef(
 			err,
@@ -159,6 +171,21 @@ func InstallOrUpgradeHelmChartWithValues(param *ReleaseInstallParam, isRetry boo
 	return err
 }
 
+// checkProtectedRelease checks the live objects of the installed release before helm modifies them
+func checkProtectedRelease(param *ReleaseInstallParam, helmClient *helmtool.HelmClient) error {
+	if param.DryRun || helmClient.KubeClient() == nil {
+		return nil
+	}
+	rel, err := helmClient.GetRelease(param.ReleaseName)
+	if err != nil {
+		if errors.Is(err, driver.ErrReleaseNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to get release %s: %s", param.ReleaseName, err)
+	}
+	return CheckProtectedRelease(param.Namespace, rel.Manifest, param.ConfirmProtected, helmClient.KubeClient())
+}
+
 // GeneMergedValues generate values.yaml used to install or upgrade helm chart, like param in after option -f
 // If fullValues is set to true, full values yaml content will be returned, this case is used to preview values when running workflows
 func GeneMergedValues(productSvc *commonmodels.ProductService, renderSet *commonmodels.RenderSet, images []string, fullValues bool) (string, error) {
@@ -231,7 +258,7 @@ func GeneMergedValues(productSvc *commonmodels.ProductService, renderSet *common
 
 // UpgradeHelmRelease upgrades helm release with some specific images
 func UpgradeHelmRelease(product *commonmodels.Product, renderSet *commonmodels.RenderSet, productSvc *commonmodels.ProductService,
-	svcTemp *commonmodels.Service, images []string, timeout int) error {
+	svcTemp *commonmodels.Service, images []string, timeout int, confirmProtected bool) error {
 	chartInfoMap := renderSet.GetChartRenderMap()
 	chartDeployInfoMap := renderSet.GetChartDeployRenderMap()
 
@@ -240,6 +267,7 @@ func UpgradeHelmRelease(product *commonmodels.Product, renderSet *commonmodels.R
 		releaseName              string
 		replacedMergedValuesYaml string
 		chartInfo                *templatemodels.ServiceRender
+		chartPinned              bool
 	)
 	if productSvc.FromZadig() {
 		releaseName = util.GeneReleaseName(svcTemp.GetReleaseNaming(), svcTemp.ProductName, product.Namespace, product.EnvName, svcTemp.ServiceName)
@@ -248,6 +276,10 @@ func UpgradeHelmRelease(product *commonmodels.Product, renderSet *commonmodels.R
 		if err != nil {
 			return fmt.Errorf("failed to gene merged values, err: %s", err)
 		}
+		chartInfo, chartPinned, err = PreparePinnedChart(product, svcTemp, chartInfo)
+		if err != nil {
+			return fmt.Errorf("failed to prepare pinned chart, err: %s", err)
+		}
 	} else {
 		releaseName = productSvc.ReleaseName
 		chartInfo = chartDeployInfoMap[productSvc.ReleaseName]
@@ -292,16 +324,18 @@ func UpgradeHelmRelease(product *commonmodels.Product, renderSet *commonmodels.R
 	}
 
 	param := &ReleaseInstallParam{
-		ProductName:  svcTemp.ProductName,
-		Namespace:    product.Namespace,
-		ReleaseName:  releaseName,
-		MergedValues: replacedMergedValuesYaml,
-		RenderChart:  chartInfo,
-		ServiceObj:   svcTemp,
-		Timeout:      timeout,
-		Production:   product.Production,
-	}
-	if !productSvc.FromZadig() {
+		ProductName:      svcTemp.ProductName,
+		Namespace:        product.Namespace,
+		ReleaseName:      releaseName,
+		MergedValues:     replacedMergedValuesYaml,
+		RenderChart:      chartInfo,
+		ServiceObj:       svcTemp,
+		Timeout:          timeout,
+		Production:       product.Production,
+		DeployConfig:     product.HelmDeployConfig,
+		ConfirmProtected: confirmProtected,
+	}
+	if !productSvc.FromZadig() || chartPinned {
 		param.IsChartInstall = true
 	}
 
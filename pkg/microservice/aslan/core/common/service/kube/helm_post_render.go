@@ -0,0 +1,104 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"fmt"
+	"time"
+
+	helmclient "github.com/mittwald/go-helm-client"
+	kustomizetypes "sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/resid"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	fsservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/fs"
+	"github.com/koderover/zadig/pkg/setting"
+	helmtool "github.com/koderover/zadig/pkg/tool/helmclient"
+)
+
+// applyHelmDeployConfig applies the hooks settings of the environment to the chart spec and builds the post-renderer
+func applyHelmDeployConfig(deployConfig *commonmodels.HelmDeployConfig, chartSpec *helmclient.ChartSpec) (*helmclient.GenericHelmOptions, error) {
+	if deployConfig == nil {
+		return nil, nil
+	}
+
+	chartSpec.DisableHooks = deployConfig.DisableHooks
+	// helm uses the same timeout to wait for hooks and resources, use the larger one
+	if hookTimeout := time.Second * time.Duration(deployConfig.HookTimeout); hookTimeout > chartSpec.Timeout {
+		chartSpec.Timeout = hookTimeout
+	}
+
+	postRenderer, err := BuildKustomizePostRenderer(deployConfig.PostRenderer)
+	if err != nil {
+		return nil, err
+	}
+	if postRenderer == nil {
+		return nil, nil
+	}
+	return &helmclient.GenericHelmOptions{PostRenderer: postRenderer}, nil
+}
+
+// BuildKustomizePostRenderer builds the kustomize post-renderer with patches stored in Zadig or in git repo
+// nil is returned if the post-renderer is disabled
+func BuildKustomizePostRenderer(cfg *commonmodels.KustomizePostRenderer) (*helmtool.KustomizePostRenderer, error) {
+	if cfg == nil || !cfg.Enabled {
+		return nil, nil
+	}
+
+	patches := make([]kustomizetypes.Patch, 0)
+	switch cfg.Source {
+	case setting.SourceFromZadig:
+		for _, patch := range cfg.Patches {
+			kustomizePatch := kustomizetypes.Patch{Patch: patch.Patch}
+			if patch.Target != nil {
+				kustomizePatch.Target = &kustomizetypes.Selector{
+					ResId: resid.ResId{
+						Gvk: resid.Gvk{
+							Group:   patch.Target.Group,
+							Version: patch.Target.Version,
+							Kind:    patch.Target.Kind,
+						},
+						Name:      patch.Target.Name,
+						Namespace: patch.Target.Namespace,
+					},
+					LabelSelector:      patch.Target.LabelSelector,
+					AnnotationSelector: patch.Target.AnnotationSelector,
+				}
+			}
+			patches = append(patches, kustomizePatch)
+		}
+	case setting.SourceFromGitRepo:
+		if cfg.GitRepoConfig == nil || cfg.Path == "" {
+			return nil, fmt.Errorf("git repo and path are required for post-renderer patches from git repo")
+		}
+		getter, err := fsservice.GetTreeGetter(cfg.GitRepoConfig.CodehostID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tree getter, err: %s", err)
+		}
+		yamls, err := getter.GetYAMLContents(cfg.GitRepoConfig.GetNamespace(), cfg.GitRepoConfig.Repo, cfg.Path, cfg.GitRepoConfig.Branch, cfg.IsDir, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get post-renderer patches from %s/%s: %s, err: %s", cfg.GitRepoConfig.Repo, cfg.GitRepoConfig.Branch, cfg.Path, err)
+		}
+		for _, patch := range yamls {
+			patches = append(patches, kustomizetypes.Patch{Patch: patch})
+		}
+	default:
+		return nil, fmt.Errorf("unsupported post-renderer source: %s", cfg.Source)
+	}
+
+	return helmtool.NewKustomizePostRenderer(patches)
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/27149chen/afero"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	fsservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/fs"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/kustomize"
+)
+
+// GetServiceTemplateYaml returns the yaml of the service template used in the environment
+// the yaml of kustomize services is the one built from the overlay of the environment when the service is
+// created or reloaded, or the one built from the base if there is no overlay
+func GetServiceTemplateYaml(svcTmpl *commonmodels.Service, envName string) string {
+	if svcTmpl.Source != setting.SourceFromKustomize || svcTmpl.KustomizeConfig == nil {
+		return svcTmpl.Yaml
+	}
+	if overlayYaml, ok := svcTmpl.KustomizeConfig.OverlayYamls[envName]; ok {
+		return overlayYaml
+	}
+	return svcTmpl.Yaml
+}
+
+// BuildKustomizeService downloads the kustomize root dir of the service from git repo and builds the base and
+// the overlays, the yamls built from the overlays are keyed by env name
+func BuildKustomizeService(svc *commonmodels.Service) (string, map[string]string, error) {
+	if svc.KustomizeConfig == nil {
+		return "", nil, fmt.Errorf("kustomize config of service %s is empty", svc.ServiceName)
+	}
+
+	fsTree, err := fsservice.DownloadFilesFromSource(&fsservice.DownloadFromSourceArgs{
+		CodehostID: svc.CodehostID,
+		Owner:      svc.RepoOwner,
+		Namespace:  svc.RepoNamespace,
+		Repo:       svc.RepoName,
+		Path:       svc.LoadPath,
+		Branch:     svc.BranchName,
+	}, func(afero.Fs) (string, error) {
+		return "", nil
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to download kustomize files of service %s, err: %s", svc.ServiceName, err)
+	}
+
+	// files are stored under the base name of the root dir
+	rootDir := strings.Trim(svc.LoadPath, "/")
+	if rootDir != "" && rootDir != "." {
+		rootDir = filepath.Base(rootDir)
+	} else {
+		rootDir = ""
+	}
+	baseYaml, err := kustomize.Build(fsTree, filepath.Join(rootDir, svc.KustomizeConfig.BasePath))
+	if err != nil {
+		return "", nil, err
+	}
+	overlayYamls := make(map[string]string)
+	for envName, overlay := range svc.KustomizeConfig.Overlays {
+		if overlay == "" {
+			continue
+		}
+		overlayYamls[envName], err = kustomize.Build(fsTree, filepath.Join(rootDir, overlay))
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to build the overlay of env %s, err: %s", envName, err)
+		}
+	}
+	return baseYaml, overlayYamls, nil
+}
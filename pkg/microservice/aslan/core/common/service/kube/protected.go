@@ -0,0 +1,187 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+// ProtectedResource is a live object carrying the setting.ProtectedResourceKey annotation or label.
+type ProtectedResource struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	Mode string `json:"mode"`
+}
+
+// ProtectionMode returns how zadig should treat the object when modifying it, an empty string means it is not protected.
+// The annotation takes precedence over the label.
+func ProtectionMode(obj metav1.Object) string {
+	value, ok := obj.GetAnnotations()[setting.ProtectedResourceKey]
+	if !ok {
+		value = obj.GetLabels()[setting.ProtectedResourceKey]
+	}
+	switch value = strings.TrimSpace(value); value {
+	case "", "false":
+		return ""
+	case setting.ProtectedResourceSkip:
+		return setting.ProtectedResourceSkip
+	default:
+		return setting.ProtectedResourceConfirm
+	}
+}
+
+// CheckProtectedObject is checked before modifying a live object outside of the manifest applying. It returns true if
+// the object is protected in skip mode and must be left untouched, and an error if it is protected in confirm mode
+// while the modification is not confirmed.
+func CheckProtectedObject(obj metav1.Object, kind string, confirmed bool) (bool, error) {
+	switch ProtectionMode(obj) {
+	case setting.ProtectedResourceSkip:
+		return true, nil
+	case setting.ProtectedResourceConfirm:
+		if !confirmed {
+			return false, ConfirmRequiredError([]*ProtectedResource{{Kind: kind, Name: obj.GetName(), Mode: setting.ProtectedResourceConfirm}})
+		}
+	}
+	return false, nil
+}
+
+// CheckProtectedRelease returns an error if the live objects of the helm release are protected. Helm upgrades the
+// release as a whole, so the release owning the objects protected in skip mode can not be upgraded.
+func CheckProtectedRelease(namespace, manifest string, confirmed bool, apiReader client.Reader) error {
+	protected, err := ListProtectedResources(namespace, manifest, apiReader)
+	if err != nil {
+		return err
+	}
+	skipped := make([]string, 0)
+	for _, resource := range protected {
+		if resource.Mode == setting.ProtectedResourceSkip {
+			skipped = append(skipped, fmt.Sprintf("%s/%s", resource.Kind, resource.Name))
+		}
+	}
+	if len(skipped) > 0 {
+		return fmt.Errorf("the following resources are protected by %s and never modified, the release owning them can not be upgraded: %s", setting.ProtectedResourceKey, strings.Join(skipped, ", "))
+	}
+	if !confirmed {
+		return ConfirmRequiredError(protected)
+	}
+	return nil
+}
+
+// ListProtectedResources returns the protected objects among the resources defined in manifest which already exist in the namespace.
+func ListProtectedResources(namespace, manifest string, apiReader client.Reader) ([]*ProtectedResource, error) {
+	resources, err := manifestToUnstructured(manifest)
+	if err != nil {
+		return nil, err
+	}
+	return listProtectedResources(namespace, resources, apiReader)
+}
+
+func listProtectedResources(namespace string, resources []*unstructured.Unstructured, apiReader client.Reader) ([]*ProtectedResource, error) {
+	ret := make([]*ProtectedResource, 0)
+	visited := sets.NewString()
+	for _, u := range resources {
+		key := fmt.Sprintf("%s/%s", u.GetKind(), u.GetName())
+		if visited.Has(key) {
+			continue
+		}
+		visited.Insert(key)
+
+		live := &unstructured.Unstructured{}
+		live.SetGroupVersionKind(u.GroupVersionKind())
+		ns := u.GetNamespace()
+		if ns == "" {
+			ns = namespace
+		}
+		err := apiReader.Get(context.TODO(), client.ObjectKey{Namespace: ns, Name: u.GetName()}, live)
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to get %s: %s", key, err)
+		}
+		if mode := ProtectionMode(live); mode != "" {
+			ret = append(ret, &ProtectedResource{Kind: u.GetKind(), Name: u.GetName(), Mode: mode})
+		}
+	}
+	return ret, nil
+}
+
+// ConfirmRequiredError returns the error reported when protected resources requiring confirmation are about to be modified,
+// nil is returned if there is no such resource.
+func ConfirmRequiredError(protected []*ProtectedResource) error {
+	names := make([]string, 0)
+	for _, resource := range protected {
+		if resource.Mode == setting.ProtectedResourceConfirm {
+			names = append(names, fmt.Sprintf("%s/%s", resource.Kind, resource.Name))
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	return fmt.Errorf("the following resources are protected by %s and require confirmation before being modified: %s", setting.ProtectedResourceKey, strings.Join(names, ", "))
+}
+
+// excludeProtectedResources removes the resources protected in skip mode from both lists so that they are neither
+// updated nor deleted. Resources protected in confirm mode are kept only if the operation is confirmed.
+func excludeProtectedResources(namespace string, confirmed bool, apiReader client.Reader, curResources, resources []*unstructured.Unstructured, log *zap.SugaredLogger) ([]*unstructured.Unstructured, []*unstructured.Unstructured, error) {
+	all := make([]*unstructured.Unstructured, 0, len(curResources)+len(resources))
+	all = append(all, curResources...)
+	all = append(all, resources...)
+	protected, err := listProtectedResources(namespace, all, apiReader)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !confirmed {
+		if err := ConfirmRequiredError(protected); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	skipped := sets.NewString()
+	for _, resource := range protected {
+		if resource.Mode == setting.ProtectedResourceSkip {
+			key := fmt.Sprintf("%s/%s", resource.Kind, resource.Name)
+			skipped.Insert(key)
+			log.Infof("resource %s in namespace %s is protected, skip modifying it", key, namespace)
+		}
+	}
+	if skipped.Len() == 0 {
+		return curResources, resources, nil
+	}
+
+	filter := func(items []*unstructured.Unstructured) []*unstructured.Unstructured {
+		ret := make([]*unstructured.Unstructured, 0, len(items))
+		for _, u := range items {
+			if !skipped.Has(fmt.Sprintf("%s/%s", u.GetKind(), u.GetName())) {
+				ret = append(ret, u)
+			}
+		}
+		return ret
+	}
+	return filter(curResources), filter(resources), nil
+}
@@ -381,7 +381,8 @@ func FetchCurrentAppliedYaml(option *GeneSvcYamlOption) (string, int, error) {
 		return "", 0, errors.Wrapf(err, "failed to find renderset for %s/%s", productInfo.ProductName, productInfo.EnvName)
 	}
 
-	fullRenderedYaml, err := RenderServiceYaml(prodSvcTemplate.Yaml, option.ProductName, option.ServiceName, usedRenderset)
+	svcTemplateYaml := GetServiceTemplateYaml(prodSvcTemplate, productInfo.EnvName)
+	fullRenderedYaml, err := RenderServiceYaml(svcTemplateYaml, option.ProductName, option.ServiceName, usedRenderset)
 	if err != nil {
 		return "", 0, err
 	}
@@ -392,7 +393,8 @@ func FetchCurrentAppliedYaml(option *GeneSvcYamlOption) (string, int, error) {
 }
 
 func fetchImportedManifests(option *GeneSvcYamlOption, productInfo *models.Product, serviceTmp *models.Service, renderset *commonmodels.RenderSet) (string, []*WorkloadResource, error) {
-	fullRenderedYaml, err := RenderServiceYaml(serviceTmp.Yaml, option.ProductName, option.ServiceName, renderset)
+	svcTemplateYaml := GetServiceTemplateYaml(serviceTmp, productInfo.EnvName)
+	fullRenderedYaml, err := RenderServiceYaml(svcTemplateYaml, option.ProductName, option.ServiceName, renderset)
 	if err != nil {
 		return "", nil, err
 	}
@@ -569,7 +571,8 @@ func GenerateRenderedYaml(option *GeneSvcYamlOption) (string, int, []*WorkloadRe
 		},
 	}}
 
-	fullRenderedYaml, err := RenderServiceYaml(latestSvcTemplate.Yaml, option.ProductName, option.ServiceName, usedRenderset)
+	svcTemplateYaml := GetServiceTemplateYaml(latestSvcTemplate, productInfo.EnvName)
+	fullRenderedYaml, err := RenderServiceYaml(svcTemplateYaml, option.ProductName, option.ServiceName, usedRenderset)
 	if err != nil {
 		return "", 0, nil, err
 	}
@@ -611,8 +614,9 @@ func RenderEnvService(prod *commonmodels.Product, render *commonmodels.RenderSet
 }
 
 func RenderEnvServiceWithTempl(prod *commonmodels.Product, render *commonmodels.RenderSet, service *commonmodels.ProductService, svcTmpl *commonmodels.Service) (yaml string, err error) {
+	svcTemplateYaml := GetServiceTemplateYaml(svcTmpl, prod.EnvName)
 	// Note only the keys in TemplateService.ServiceVar can work
-	parsedYaml, err := RenderServiceYaml(svcTmpl.Yaml, prod.ProductName, svcTmpl.ServiceName, render)
+	parsedYaml, err := RenderServiceYaml(svcTemplateYaml, prod.ProductName, svcTmpl.ServiceName, render)
 	if err != nil {
 		log.Errorf("failed to render service yaml, err: %s", err)
 		return "", err
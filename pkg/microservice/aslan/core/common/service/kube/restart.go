@@ -0,0 +1,120 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/kube/getter"
+)
+
+const oomKilledReason = "OOMKilled"
+
+// ListManifestPods lists the pods of the deployments and statefulsets defined in the manifest
+func ListManifestPods(namespace, manifest string, kubeClient client.Client) ([]*corev1.Pod, error) {
+	resources, err := manifestToUnstructured(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*corev1.Pod, 0)
+	for _, u := range resources {
+		var selector *metav1.LabelSelector
+		switch u.GetKind() {
+		case setting.Deployment:
+			deploy, found, err := getter.GetDeployment(namespace, u.GetName(), kubeClient)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get deployment %s: %s", u.GetName(), err)
+			}
+			if !found {
+				continue
+			}
+			selector = deploy.Spec.Selector
+		case setting.StatefulSet:
+			sts, found, err := getter.GetStatefulSet(namespace, u.GetName(), kubeClient)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get statefulset %s: %s", u.GetName(), err)
+			}
+			if !found {
+				continue
+			}
+			selector = sts.Spec.Selector
+		default:
+			continue
+		}
+
+		labelSelector, err := metav1.LabelSelectorAsSelector(selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selector of %s %s: %s", u.GetKind(), u.GetName(), err)
+		}
+		pods, err := getter.ListPods(namespace, labelSelector, kubeClient)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pods of %s %s: %s", u.GetKind(), u.GetName(), err)
+		}
+		ret = append(ret, pods...)
+	}
+	return ret, nil
+}
+
+// PodRestartCount returns the sum of the restart counts of all the containers in the pod
+func PodRestartCount(pod *corev1.Pod) int32 {
+	var count int32
+	for _, cs := range podContainerStatuses(pod) {
+		count += cs.RestartCount
+	}
+	return count
+}
+
+// PodOOMKills returns the number of containers in the pod last terminated by OOM killer after the given time
+func PodOOMKills(pod *corev1.Pod, since time.Time) int32 {
+	var count int32
+	for _, cs := range podContainerStatuses(pod) {
+		terminated := cs.LastTerminationState.Terminated
+		if terminated != nil && terminated.Reason == oomKilledReason && terminated.FinishedAt.Time.After(since) {
+			count++
+		}
+	}
+	return count
+}
+
+// RestartIncrease returns the restarts increased since the previous restart counts keyed by the pod name,
+// all the restarts of the pods not in previous are counted
+func RestartIncrease(pods []*corev1.Pod, previous map[string]int32) int32 {
+	var increase int32
+	for _, pod := range pods {
+		count := PodRestartCount(pod)
+		if prev, ok := previous[pod.Name]; ok {
+			count -= prev
+		}
+		if count > 0 {
+			increase += count
+		}
+	}
+	return increase
+}
+
+func podContainerStatuses(pod *corev1.Pod) []corev1.ContainerStatus {
+	statuses := make([]corev1.ContainerStatus, 0, len(pod.Status.InitContainerStatuses)+len(pod.Status.ContainerStatuses))
+	statuses = append(statuses, pod.Status.InitContainerStatuses...)
+	return append(statuses, pod.Status.ContainerStatuses...)
+}
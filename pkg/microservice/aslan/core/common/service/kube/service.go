@@ -51,6 +51,7 @@ import (
 	"github.com/koderover/zadig/pkg/tool/kube/multicluster"
 	"github.com/koderover/zadig/pkg/tool/log"
 	"github.com/koderover/zadig/pkg/types"
+	"github.com/koderover/zadig/pkg/util"
 )
 
 func GetKubeAPIReader(clusterID string) (client.Reader, error) {
@@ -353,6 +354,7 @@ func (s *Service) GetYaml(id, agentImage, aslanURL, hubURI string, useDeployment
 			DindStorageClassName: dindSCName,
 			DindStorageSizeInGiB: dindStorageSizeInGiB,
 			ScheduleWorkflow:     scheduleWorkflow,
+			HubAgentVersion:      util.ExtractImageTag(agentImage),
 		})
 	} else {
 		err = YamlTemplateForNamespace.Execute(buffer, TemplateSchema{
@@ -369,6 +371,7 @@ func (s *Service) GetYaml(id, agentImage, aslanURL, hubURI string, useDeployment
 			DindEnablePV:         dindEnablePV,
 			DindStorageClassName: dindSCName,
 			DindStorageSizeInGiB: dindStorageSizeInGiB,
+			HubAgentVersion:      util.ExtractImageTag(agentImage),
 		})
 	}
 
@@ -666,6 +669,7 @@ type TemplateSchema struct {
 	DindStorageClassName string
 	DindStorageSizeInGiB int
 	ScheduleWorkflow     bool
+	HubAgentVersion      string
 }
 
 const (
@@ -783,6 +787,8 @@ spec:
           value: "{{.AslanBaseAddr}}"
         - name: SCHEDULE_WORKFLOW
           value: "{{.ScheduleWorkflow}}"
+        - name: HUB_AGENT_VERSION
+          value: "{{.HubAgentVersion}}"
         resources:
           limits:
             cpu: 1000m
@@ -945,6 +951,8 @@ spec:
           value: "{{.HubServerBaseAddr}}"
         - name: ASLAN_BASE_ADDR
           value: "{{.AslanBaseAddr}}"
+        - name: HUB_AGENT_VERSION
+          value: "{{.HubAgentVersion}}"
         resources:
           limits:
             cpu: 1000m
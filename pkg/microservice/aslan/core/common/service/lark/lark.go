@@ -25,6 +25,7 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/client/user"
 	"github.com/koderover/zadig/pkg/tool/lark"
 )
 
@@ -187,6 +188,23 @@ func GetLarkUserID(approvalID, queryType, queryValue string) (string, error) {
 	return cli.GetUserOpenIDByEmailOrMobile(lark.QueryTypeEmail, queryValue)
 }
 
+// GetUserOpenIDByZadigUser finds the lark user of the zadig user by the mobile, then by the email of the zadig user
+func GetUserOpenIDByZadigUser(client *lark.Client, uid string) (string, error) {
+	info, err := user.New().GetUserByID(uid)
+	if err != nil {
+		return "", errors.Wrapf(err, "get user %s", uid)
+	}
+	if info.Phone != "" {
+		if openID, err := client.GetUserOpenIDByEmailOrMobile(lark.QueryTypeMobile, info.Phone); err == nil {
+			return openID, nil
+		}
+	}
+	if info.Email != "" {
+		return client.GetUserOpenIDByEmailOrMobile(lark.QueryTypeEmail, info.Email)
+	}
+	return "", errors.Errorf("lark user of %s not found by the mobile or the email", info.Name)
+}
+
 var (
 	once                   sync.Once
 	larkApprovalManagerMap *ApprovalManagerMap
@@ -30,10 +30,13 @@ import (
 	"github.com/pkg/errors"
 	"github.com/tidwall/gjson"
 
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 	"github.com/koderover/zadig/pkg/tool/log"
 )
 
+const eventTypeCardActionTrigger = "card.action.trigger"
+
 type CallbackData struct {
 	UUID  string          `json:"uuid"`
 	Event json.RawMessage `json:"event"`
@@ -71,10 +74,33 @@ type ApprovalTaskEvent struct {
 }
 
 type EventHandlerResponse struct {
-	Challenge string `json:"challenge"`
+	Challenge string `json:"challenge,omitempty"`
+	// Toast is shown to the user clicking the button of a card
+	Toast *CardToast `json:"toast,omitempty"`
+}
+
+type CardToast struct {
+	Type    string `json:"type"`
+	Content string `json:"content"`
+}
+
+// CardActionEvent is the callback of a button of an interactive card sent by the app, the operator is the lark user
+// who clicked the button
+type CardActionEvent struct {
+	Operator struct {
+		OpenID string `json:"open_id"`
+		UserID string `json:"user_id"`
+	} `json:"operator"`
+	Action struct {
+		Tag   string          `json:"tag"`
+		Value json.RawMessage `json:"value"`
+	} `json:"action"`
 }
 
-func EventHandler(appID, sign, ts, nonce, body string) (*EventHandlerResponse, error) {
+// CardActionHandler handles the card callbacks of the app, it is called after the signature of the request is verified
+type CardActionHandler func(app *models.IMApp, event *CardActionEvent) (*CardToast, error)
+
+func EventHandler(appID, sign, ts, nonce, body string, cardActionHandler CardActionHandler) (*EventHandlerResponse, error) {
 	log.Infof("LarkEventHandler: new request approval received")
 	larkAppInfo, err := mongodb.NewIMAppColl().GetLarkByAppID(context.Background(), appID)
 	if err != nil {
@@ -101,6 +127,10 @@ func EventHandler(appID, sign, ts, nonce, body string) (*EventHandlerResponse, e
 		return nil, errors.New("check sign failed")
 	}
 
+	if gjson.Get(raw, "header.event_type").String() == eventTypeCardActionTrigger {
+		return handleCardAction(larkAppInfo, raw, cardActionHandler)
+	}
+
 	callback := &CallbackData{}
 	err = json.Unmarshal([]byte(raw), callback)
 	if err != nil {
@@ -138,6 +168,26 @@ func EventHandler(appID, sign, ts, nonce, body string) (*EventHandlerResponse, e
 	return nil, nil
 }
 
+func handleCardAction(app *models.IMApp, raw string, cardActionHandler CardActionHandler) (*EventHandlerResponse, error) {
+	callback := &struct {
+		Event *CardActionEvent `json:"event"`
+	}{}
+	if err := json.Unmarshal([]byte(raw), callback); err != nil || callback.Event == nil {
+		log.Errorf("unmarshal card action failed: %v", err)
+		return nil, errors.New("invalid card action")
+	}
+	if cardActionHandler == nil {
+		return nil, nil
+	}
+	toast, err := cardActionHandler(app, callback.Event)
+	if err != nil {
+		// lark only shows the toast of a successful response
+		log.Errorf("handle card action of user %s failed: %v", callback.Event.Operator.OpenID, err)
+		return &EventHandlerResponse{Toast: &CardToast{Type: "error", Content: err.Error()}}, nil
+	}
+	return &EventHandlerResponse{Toast: toast}, nil
+}
+
 func larkDecrypt(encrypt string, key string) (string, error) {
 	buf, err := base64.StdEncoding.DecodeString(encrypt)
 	if err != nil {
@@ -19,6 +19,7 @@ func GetLLMClient(ctx context.Context, name string) (llm.ILLM, error) {
 		Name:    llmIntegration.Name,
 		Token:   llmIntegration.Token,
 		BaseURL: llmIntegration.BaseURL,
+		Model:   llmIntegration.Model,
 	}
 	if llmIntegration.EnableProxy {
 		llmConfig.Proxy = config.ProxyHTTPSAddr()
@@ -40,3 +41,17 @@ func GetLLMClient(ctx context.Context, name string) (llm.ILLM, error) {
 func GetDefaultLLMClient(ctx context.Context) (llm.ILLM, error) {
 	return GetLLMClient(ctx, "openai")
 }
+
+// GetConfiguredLLMClient returns the client of the llm integration configured in the system,
+// no matter it is a public api or a self-hosted model
+func GetConfiguredLLMClient(ctx context.Context) (llm.ILLM, error) {
+	llmIntegrations, err := commonrepo.NewLLMIntegrationColl().FindAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Could not list the llm integrations: %w", err)
+	}
+	if len(llmIntegrations) == 0 {
+		return nil, fmt.Errorf("no llm integration is configured")
+	}
+
+	return GetLLMClient(ctx, llmIntegrations[0].Name)
+}
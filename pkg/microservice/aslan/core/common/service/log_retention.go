@@ -0,0 +1,113 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	s3service "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/s3"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/log"
+	s3tool "github.com/koderover/zadig/pkg/tool/s3"
+)
+
+// CleanExpiredTaskLogs removes the job logs of the workflow tasks exceeding the log retention of their projects
+func CleanExpiredTaskLogs() {
+	projects, err := templaterepo.NewProductColl().List()
+	if err != nil {
+		log.Errorf("failed to list projects: %s", err)
+		return
+	}
+
+	var (
+		storage *s3service.S3
+		client  *s3tool.Client
+	)
+	for _, project := range projects {
+		if project.LogRetention == nil || project.LogRetention.RetentionDays <= 0 {
+			continue
+		}
+		before := time.Now().AddDate(0, 0, -project.LogRetention.RetentionDays).Unix()
+		tasks, err := commonrepo.NewWorkflowTaskV4Store().ListLogExpiringTasks(project.ProductName, before)
+		if err != nil {
+			log.Errorf("failed to list the tasks with expired logs of project %s: %s", project.ProductName, err)
+			continue
+		}
+		if len(tasks) == 0 {
+			continue
+		}
+
+		if client == nil {
+			storage, err = s3service.FindDefaultS3()
+			if err != nil {
+				log.Errorf("failed to find the default storage: %s", err)
+				return
+			}
+			forcedPathStyle := true
+			if storage.Provider == setting.ProviderSourceAli {
+				forcedPathStyle = false
+			}
+			client, err = s3tool.NewClient(storage.Endpoint, storage.Ak, storage.Sk, storage.Region, storage.Insecure, forcedPathStyle)
+			if err != nil {
+				log.Errorf("failed to create s3 client: %s", err)
+				return
+			}
+		}
+
+		removed := 0
+		for _, task := range tasks {
+			// the task is only marked expired when all its logs are gone, so the failed ones are retried next round
+			if err := removeTaskLogs(storage, client, task.WorkflowName, task.TaskID); err != nil {
+				log.Errorf("failed to remove the logs of task %s #%d: %s", task.WorkflowName, task.TaskID, err)
+				continue
+			}
+			if err := commonrepo.NewWorkflowTaskV4Store().SetLogExpired(task.WorkflowName, task.TaskID); err != nil {
+				log.Errorf("failed to set the logs of task %s #%d expired: %s", task.WorkflowName, task.TaskID, err)
+				continue
+			}
+			removed++
+		}
+		log.Infof("removed the expired logs of %d/%d tasks of project %s", removed, len(tasks), project.ProductName)
+	}
+}
+
+// removeTaskLogs removes the job logs of the task in the object storage, the logs of the vm jobs and the log summaries in mongo
+func removeTaskLogs(storage *s3service.S3, client *s3tool.Client, workflowName string, taskID int64) error {
+	prefix := storage.GetObjectPath(fmt.Sprintf("%s/%d/log", strings.ToLower(workflowName), taskID)) + "/"
+	if err := client.RemoveFilesWithPrefix(storage.Bucket, prefix); err != nil {
+		return err
+	}
+
+	vmJobs, err := commonrepo.NewVMJobColl().ListByTask(workflowName, taskID)
+	if err != nil {
+		return fmt.Errorf("failed to list the vm jobs: %s", err)
+	}
+	for _, vmJob := range vmJobs {
+		if err := commonrepo.NewVMJobLogColl().DeleteByJobID(vmJob.ID.Hex()); err != nil {
+			return fmt.Errorf("failed to delete the logs of vm job %s: %s", vmJob.ID.Hex(), err)
+		}
+	}
+
+	if err := commonrepo.NewJobLogSummaryColl().DeleteByTask(workflowName, taskID); err != nil {
+		return fmt.Errorf("failed to delete the log summaries: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	"github.com/koderover/zadig/pkg/util"
+)
+
+// DefaultMutableTags are used when no mutable tag is configured in the promotion policy
+var DefaultMutableTags = []string{"^latest$", "^(master|main|dev|develop)$"}
+
+// GetPromotionPolicy returns the promotion policy of the project, nil is returned if the policy is not enabled
+func GetPromotionPolicy(projectName string) (*template.ProjectPromotionPolicy, error) {
+	project, err := templaterepo.NewProductColl().Find(projectName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find project %s: %v", projectName, err)
+	}
+	if project.PromotionPolicy == nil || !project.PromotionPolicy.Enabled {
+		return nil, nil
+	}
+	return project.PromotionPolicy, nil
+}
+
+// CheckImageMutableTags returns the violations of the images which use a mutable tag
+func CheckImageMutableTags(policy *template.ProjectPromotionPolicy, images []string) ([]string, error) {
+	violations := make([]string, 0)
+	if policy == nil || !policy.ForbidMutableTags {
+		return violations, nil
+	}
+	patterns := policy.MutableTags
+	if len(patterns) == 0 {
+		patterns = DefaultMutableTags
+	}
+	regs := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		reg, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mutable tag pattern %s: %v", pattern, err)
+		}
+		regs = append(regs, reg)
+	}
+
+	for _, image := range images {
+		tag, err := util.ParseImageTag(image)
+		if err != nil {
+			violations = append(violations, fmt.Sprintf("the tag of image %s can't be parsed: %v", image, err))
+			continue
+		}
+		// the image pinned by a digest is immutable
+		if tag == "" {
+			continue
+		}
+		for _, reg := range regs {
+			if reg.MatchString(tag) {
+				violations = append(violations, fmt.Sprintf("image %s uses mutable tag %s", image, tag))
+				break
+			}
+		}
+	}
+	return violations, nil
+}
+
+// CheckImagePromotion returns the violations of the images which have not been deployed to any non-production
+// environment of the project, an image passes if it is running in a non-production environment or it has been
+// deployed by a passed deploy job.
+func CheckImagePromotion(projectName string, images []string) ([]string, error) {
+	violations := make([]string, 0)
+	if len(images) == 0 {
+		return violations, nil
+	}
+
+	passed := sets.NewString()
+	envs, err := commonrepo.NewProductColl().List(&commonrepo.ProductListOptions{
+		Name:       projectName,
+		Production: util.GetBoolPointer(false),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list non-production envs of project %s: %v", projectName, err)
+	}
+	for _, env := range envs {
+		for _, svc := range env.GetServiceMap() {
+			for _, container := range svc.Containers {
+				passed.Insert(container.Image)
+			}
+		}
+	}
+	deployed, err := commonrepo.NewJobInfoColl().ListPassedDeployedImages(projectName, images)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployed images of project %s: %v", projectName, err)
+	}
+	passed.Insert(deployed...)
+
+	for _, image := range images {
+		if !passed.Has(image) {
+			violations = append(violations, fmt.Sprintf("image %s has not been deployed to any non-production environment", image))
+		}
+	}
+	return violations, nil
+}
+
+// CheckPromotionPolicy checks the images to be deployed to a production environment of the project,
+// all the violations of the promotion policy are returned in one error.
+func CheckPromotionPolicy(projectName, envName string, images []string) error {
+	policy, err := GetPromotionPolicy(projectName)
+	if err != nil || policy == nil {
+		return err
+	}
+
+	violations, err := CheckImageMutableTags(policy, images)
+	if err != nil {
+		return err
+	}
+	if policy.RequirePromotion {
+		promotionViolations, err := CheckImagePromotion(projectName, images)
+		if err != nil {
+			return err
+		}
+		violations = append(violations, promotionViolations...)
+	}
+	return NewPromotionPolicyError(envName, violations)
+}
+
+// NewPromotionPolicyError returns nil if there is no violation
+func NewPromotionPolicyError(envName string, violations []string) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	return fmt.Errorf("promotion policy of production env %s is violated: %s", envName, strings.Join(violations, "; "))
+}
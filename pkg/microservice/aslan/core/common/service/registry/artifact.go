@@ -0,0 +1,82 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package registry
+
+import (
+	"fmt"
+
+	"github.com/docker/distribution"
+	// registers the oci manifest so that the artifacts pushed by oras and the like can be read
+	_ "github.com/docker/distribution/manifest/ocischema"
+	"go.uber.org/zap"
+)
+
+// ArtifactOption points to a repository of OCI artifacts in a docker v2 registry, the repo is under the namespace
+// of the endpoint
+type ArtifactOption struct {
+	Endpoint
+	Repo        string
+	EnableHTTPS bool
+	CustomCert  string
+}
+
+func (o ArtifactOption) repoName() string {
+	if o.Namespace == "" {
+		return o.Repo
+	}
+	return o.Namespace + "/" + o.Repo
+}
+
+func (o ArtifactOption) client(log *zap.SugaredLogger) (*authClient, error) {
+	s := &v2RegistryService{EnableHTTPS: o.EnableHTTPS, CustomCert: o.CustomCert}
+	return s.createClient(o.Endpoint, log)
+}
+
+// ListArtifactTags lists the tags of the repository
+func ListArtifactTags(option ArtifactOption, log *zap.SugaredLogger) ([]string, error) {
+	cli, err := option.client(log)
+	if err != nil {
+		return nil, err
+	}
+	return cli.listTags(option.repoName())
+}
+
+// GetArtifactLayer returns the content of the layer of the media type in the manifest of the tag
+func GetArtifactLayer(option ArtifactOption, tag, mediaType string, log *zap.SugaredLogger) ([]byte, error) {
+	cli, err := option.client(log)
+	if err != nil {
+		return nil, err
+	}
+	repo, err := cli.getRepository(option.repoName())
+	if err != nil {
+		return nil, err
+	}
+	manifestService, err := repo.Manifests(cli.ctx)
+	if err != nil {
+		return nil, err
+	}
+	m, err := manifestService.Get(cli.ctx, "", distribution.WithTag(tag))
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range m.References() {
+		if ref.MediaType == mediaType {
+			return repo.Blobs(cli.ctx).Get(cli.ctx, ref.Digest)
+		}
+	}
+	return nil, fmt.Errorf("no %s layer found in %s:%s", mediaType, option.repoName(), tag)
+}
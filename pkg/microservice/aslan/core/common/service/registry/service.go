@@ -55,6 +55,7 @@ import (
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 )
 
 type Endpoint struct {
@@ -133,8 +134,21 @@ func (s *v2RegistryService) createClient(ep Endpoint, logger *zap.SugaredLogger)
 		tlsConfig.InsecureSkipVerify = true
 	}
 
+	proxyFunc := http.ProxyFromEnvironment
+	proxyAddr, err := mongodb.NewProxyColl().GetIntegrationProxyURL(commonmodels.ProxyIntegrationRegistry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the registry proxy: %s", err)
+	}
+	if proxyAddr != "" {
+		proxyURL, err := url.Parse(proxyAddr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid registry proxy: %s", err)
+		}
+		proxyFunc = http.ProxyURL(proxyURL)
+	}
+
 	base := &http.Transport{
-		Proxy:               http.ProxyFromEnvironment,
+		Proxy:               proxyFunc,
 		DialContext:         direct.DialContext,
 		TLSHandshakeTimeout: 10 * time.Second,
 		TLSClientConfig:     tlsConfig,
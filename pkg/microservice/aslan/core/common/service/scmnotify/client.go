@@ -31,6 +31,7 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/gitee"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/github"
 	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
 	"github.com/koderover/zadig/pkg/tool/gerrit"
@@ -141,7 +142,7 @@ func (c *Client) Comment(notify *models.Notification) error {
 			}
 
 			if !skip {
-				if e := cli.SetReview(
+				if e := cli.SetReviewLabels(
 					notify.RepoName,
 					notify.PrID,
 					fmt.Sprintf(""+
@@ -150,8 +151,7 @@ func (c *Client) Comment(notify *models.Notification) error {
 						emoji,
 						workflowURL,
 					),
-					notify.Label,
-					score,
+					getGerritReviewLabels(notify, task, score),
 					notify.Revision,
 				); e != nil {
 					c.logger.Warnf("failed to set review %v %v %v", task, notify, e)
@@ -193,3 +193,58 @@ func (c *Client) Comment(notify *models.Notification) error {
 
 	return nil
 }
+
+// getGerritReviewLabels returns the votes configured for the task, or the label of the notification with the default score
+func getGerritReviewLabels(notify *models.Notification, task *models.NotificationTask, score string) map[string]string {
+	if len(task.GerritVotes) == 0 {
+		if notify.Label == "" {
+			return nil
+		}
+		return map[string]string{notify.Label: score}
+	}
+	labels := make(map[string]string)
+	for _, vote := range task.GerritVotes {
+		switch task.Status {
+		case config.TaskStatusPass:
+			labels[vote.Label] = vote.PassedScore
+		case config.TaskStatusTimeout, config.TaskStatusFailed:
+			labels[vote.Label] = vote.FailedScore
+		default:
+			labels[vote.Label] = "0"
+		}
+	}
+	return labels
+}
+
+// CommentPR posts a new comment to a pull request, owner is the namespace of the repo
+func (c *Client) CommentPR(codehostID int, owner, repo string, prID int, comment string) error {
+	codeHostDetail, err := systemconfig.New().GetCodeHost(codehostID)
+	if err != nil {
+		return errors.Wrapf(err, "codehost %d not found to comment", codehostID)
+	}
+
+	switch strings.ToLower(codeHostDetail.Type) {
+	case setting.SourceFromGithub:
+		cli := github.NewClient(codeHostDetail.AccessToken, config.ProxyHTTPSAddr(), codeHostDetail.EnableProxy)
+		if _, err := cli.CreateIssueComment(context.Background(), owner, repo, prID, comment); err != nil {
+			return fmt.Errorf("failed to comment github due to %s/%s/%d %v", owner, repo, prID, err)
+		}
+	case setting.SourceFromGitlab:
+		cli, err := gitlabtool.NewClient(codeHostDetail.ID, codeHostDetail.Address, codeHostDetail.AccessToken, config.ProxyHTTPSAddr(), codeHostDetail.EnableProxy)
+		if err != nil {
+			return fmt.Errorf("create gitlab client failed err: %v", err)
+		}
+		projectID := strings.TrimLeft(owner+"/"+repo, "/")
+		if _, _, err := cli.Notes.CreateMergeRequestNote(projectID, prID, &gitlab.CreateMergeRequestNoteOptions{Body: &comment}); err != nil {
+			return fmt.Errorf("failed to comment gitlab due to %s/%d %v", projectID, prID, err)
+		}
+	case setting.SourceFromGitee, setting.SourceFromGiteeEE:
+		cli := gitee.NewClient(codeHostDetail.ID, codeHostDetail.AccessToken, config.ProxyHTTPSAddr(), codeHostDetail.EnableProxy, codeHostDetail.Address)
+		if _, err := cli.CreateMergeRequestComment(context.Background(), owner, repo, int32(prID), giteeClient.PullRequestCommentPostParam{Body: comment}); err != nil {
+			return fmt.Errorf("failed to comment gitee due to %s/%s/%d %v", owner, repo, prID, err)
+		}
+	default:
+		return fmt.Errorf("codehost type %s not supported to comment", codeHostDetail.Type)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scmnotify
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/xanzy/go-gitlab"
+	"go.uber.org/zap"
+
+	configbase "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	gitlabtool "github.com/koderover/zadig/pkg/tool/git/gitlab"
+)
+
+// UpdateGitlabCommitStatusForWorkflowV4 reports the status of each stage of a merge request task as a commit status
+// of the merge request, only the stages whose status changed since the last report are sent
+func (s *Service) UpdateGitlabCommitStatusForWorkflowV4(task *models.WorkflowTask, logger *zap.SugaredLogger) error {
+	hook := task.WorkflowArgs.HookPayload
+	if hook == nil || !hook.IsPr || hook.CommitID == "" || hook.RepoNamespace == "" {
+		return nil
+	}
+
+	states := make(map[string]gitlab.BuildStateValue)
+	for _, stage := range task.Stages {
+		state := getGitlabBuildState(stage.Status, task.Status)
+		if hook.StageStatuses[stage.Name] != string(state) {
+			states[stage.Name] = state
+		}
+	}
+	if len(states) == 0 {
+		return nil
+	}
+
+	codeHostDetail, err := systemconfig.New().GetCodeHost(hook.CodehostID)
+	if err != nil {
+		return fmt.Errorf("failed to get codehost %d, err: %s", hook.CodehostID, err)
+	}
+	if strings.ToLower(codeHostDetail.Type) != setting.SourceFromGitlab {
+		return nil
+	}
+	cli, err := gitlabtool.NewClient(codeHostDetail.ID, codeHostDetail.Address, codeHostDetail.AccessToken, config.ProxyHTTPSAddr(), codeHostDetail.EnableProxy)
+	if err != nil {
+		return fmt.Errorf("failed to create gitlab client, err: %s", err)
+	}
+
+	if hook.StageStatuses == nil {
+		hook.StageStatuses = make(map[string]string)
+	}
+	projectID := fmt.Sprintf("%s/%s", hook.RepoNamespace, hook.Repo)
+	targetURL := fmt.Sprintf("%s/v1/projects/detail/%s/pipelines/custom/%s/%d?display_name=%s",
+		configbase.SystemAddress(), task.ProjectName, task.WorkflowName, task.TaskID, url.PathEscape(task.WorkflowDisplayName))
+	for _, stage := range task.Stages {
+		state, ok := states[stage.Name]
+		if !ok {
+			continue
+		}
+		name := fmt.Sprintf("zadig/%s/%s", getDisplayName(task.WorkflowArgs), stage.Name)
+		description := fmt.Sprintf("Stage [%s] of task #%d is %s.", stage.Name, task.TaskID, state)
+		_, _, err := cli.Commits.SetCommitStatus(projectID, hook.CommitID, &gitlab.SetCommitStatusOptions{
+			State:       state,
+			Name:        &name,
+			TargetURL:   &targetURL,
+			Description: &description,
+		})
+		if err != nil {
+			logger.Warnf("failed to set commit status of stage %s for %s@%s, err: %s", stage.Name, projectID, hook.CommitID, err)
+			continue
+		}
+		hook.StageStatuses[stage.Name] = string(state)
+	}
+	return nil
+}
+
+// getGitlabBuildState converts the status of a stage to a gitlab commit state, the stages which never
+// ran are reported as canceled once the task is done
+func getGitlabBuildState(status, taskStatus config.Status) gitlab.BuildStateValue {
+	switch status {
+	case config.StatusPassed, config.StatusSkipped:
+		return gitlab.Success
+	case config.StatusFailed, config.StatusTimeout, config.StatusReject:
+		return gitlab.Failed
+	case config.StatusCancelled:
+		return gitlab.Canceled
+	}
+	switch taskStatus {
+	case config.StatusPassed, config.StatusFailed, config.StatusTimeout, config.StatusCancelled, config.StatusReject:
+		return gitlab.Canceled
+	}
+	if status == config.StatusRunning {
+		return gitlab.Running
+	}
+	return gitlab.Pending
+}
+
+func getNotificationJobs(task *models.WorkflowTask) []*models.NotificationJob {
+	jobs := make([]*models.NotificationJob, 0)
+	for _, stage := range task.Stages {
+		for _, job := range stage.Jobs {
+			jobs = append(jobs, &models.NotificationJob{Name: job.Name, Status: job.Status})
+		}
+	}
+	return jobs
+}
+
+func notificationJobsChanged(origin, current []*models.NotificationJob) bool {
+	if len(origin) != len(current) {
+		return true
+	}
+	for i := range origin {
+		if origin[i].Name != current[i].Name || origin[i].Status != current[i].Status {
+			return true
+		}
+	}
+	return false
+}
@@ -495,14 +495,21 @@ func (s *Service) UpdateWebhookCommentForWorkflowV4(task *models.WorkflowTask, l
 	var shouldComment bool
 
 	status := convertTaskStatusToNotificationTaskStatus(task.Status)
+	jobs := getNotificationJobs(task)
+	var gerritVotes []*models.GerritVote
+	if task.WorkflowArgs.HookPayload != nil {
+		gerritVotes = task.WorkflowArgs.HookPayload.GerritVotes
+	}
 	for _, nTask := range notification.Tasks {
 		if nTask.ID == task.TaskID {
-			shouldComment = nTask.Status != status
+			shouldComment = nTask.Status != status || notificationJobsChanged(nTask.Jobs, jobs)
 			scmTask := &models.NotificationTask{
 				ProductName:         task.ProjectName,
 				WorkflowName:        task.WorkflowName,
 				WorkflowDisplayName: task.WorkflowDisplayName,
 				ID:                  task.TaskID,
+				Jobs:                jobs,
+				GerritVotes:         gerritVotes,
 
 				Status: status,
 			}
@@ -521,6 +528,8 @@ func (s *Service) UpdateWebhookCommentForWorkflowV4(task *models.WorkflowTask, l
 			ID:                  task.TaskID,
 			WorkflowDisplayName: task.WorkflowDisplayName,
 			Status:              status,
+			Jobs:                jobs,
+			GerritVotes:         gerritVotes,
 		})
 		shouldComment = true
 	}
@@ -0,0 +1,109 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
+	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
+	"github.com/koderover/zadig/pkg/tool/kube/getter"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+// Gi is the number of bytes of the storage quota unit
+const Gi int64 = 1 << 30
+
+// ListTenantEnvironments returns the environments of the projects of the tenant
+func ListTenantEnvironments(tenant *commonmodels.Tenant) ([]*commonmodels.Product, error) {
+	if len(tenant.Projects) == 0 {
+		return []*commonmodels.Product{}, nil
+	}
+	return commonrepo.NewProductColl().List(&commonrepo.ProductListOptions{
+		InProjects:    tenant.Projects,
+		ExcludeStatus: []string{setting.ProductStatusDeleting},
+	})
+}
+
+// GetTenantStorageUsage returns the storage in bytes requested by the persistent volume claims of the environments,
+// a namespace shared by several environments is counted once
+func GetTenantStorageUsage(envs []*commonmodels.Product) (int64, error) {
+	var usage int64
+	counted := make(map[string]bool)
+	for _, env := range envs {
+		key := env.ClusterID + "/" + env.Namespace
+		if counted[key] {
+			continue
+		}
+		counted[key] = true
+
+		kubeClient, err := kubeclient.GetKubeClient(config.HubServerAddress(), env.ClusterID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get kube client of cluster %s: %s", env.ClusterID, err)
+		}
+		pvcs, err := getter.ListPvcs(env.Namespace, nil, kubeClient)
+		if err != nil {
+			return 0, fmt.Errorf("failed to list pvcs of namespace %s: %s", env.Namespace, err)
+		}
+		for _, pvc := range pvcs {
+			if storage, ok := pvc.Spec.Resources.Requests[corev1.ResourceStorage]; ok {
+				usage += storage.Value()
+			}
+		}
+	}
+	return usage, nil
+}
+
+// CheckTenantEnvironmentQuota returns an error if the tenant of the project has used up its environment or storage quota
+func CheckTenantEnvironmentQuota(projectName string) error {
+	tenant, err := commonrepo.NewTenantColl().FindByProject(projectName)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find the tenant of project %s: %s", projectName, err)
+	}
+	if tenant.Quota == nil || (tenant.Quota.Environments <= 0 && tenant.Quota.StorageGi <= 0) {
+		return nil
+	}
+
+	envs, err := ListTenantEnvironments(tenant)
+	if err != nil {
+		return fmt.Errorf("failed to list the environments of tenant %s: %s", tenant.Name, err)
+	}
+	if tenant.Quota.Environments > 0 && len(envs) >= tenant.Quota.Environments {
+		return fmt.Errorf("tenant %s has reached its quota of %d environments", tenant.Name, tenant.Quota.Environments)
+	}
+	if tenant.Quota.StorageGi > 0 {
+		usage, err := GetTenantStorageUsage(envs)
+		if err != nil {
+			// the clusters may be unreachable, the storage quota is not applied in this case
+			log.Warnf("failed to get the storage usage of tenant %s, err: %s", tenant.Name, err)
+			return nil
+		}
+		if usage >= tenant.Quota.StorageGi*Gi {
+			return fmt.Errorf("tenant %s has used up its storage quota of %dGi", tenant.Name, tenant.Quota.StorageGi)
+		}
+	}
+	return nil
+}
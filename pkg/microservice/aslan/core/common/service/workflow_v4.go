@@ -48,6 +48,19 @@ func DeleteWorkflowV4sByProjectName(projectName string, log *zap.SugaredLogger)
 			errList = multierror.Append(errList, fmt.Errorf("productName %s workflowV4 delete %s error: %s", projectName, workflowV4.Name, err))
 		}
 	}
+	trashes, err := mongodb.NewWorkflowV4TrashColl().List(projectName)
+	if err != nil {
+		errList = multierror.Append(errList, fmt.Errorf("productName %s list trashed workflowV4s error: %s", projectName, err))
+	}
+	for _, trash := range trashes {
+		if err := PurgeWorkflowV4(trash.Workflow, log); err != nil {
+			errList = multierror.Append(errList, fmt.Errorf("productName %s trashed workflowV4 purge %s error: %s", projectName, trash.WorkflowName, err))
+			continue
+		}
+		if err := mongodb.NewWorkflowV4TrashColl().Delete(trash.WorkflowName); err != nil {
+			errList = multierror.Append(errList, fmt.Errorf("productName %s trashed workflowV4 delete %s error: %s", projectName, trash.WorkflowName, err))
+		}
+	}
 	if err := errList.ErrorOrNil(); err != nil {
 		log.Error(err)
 		return err
@@ -74,7 +87,17 @@ func DeleteWorkflowV4(name string, logger *zap.SugaredLogger) error {
 		logger.Errorf("Failed to delete WorkflowV4: %s, the error is: %v", name, err)
 		return e.ErrDeleteWorkflow.AddErr(err)
 	}
-	err = ProcessWebhook(nil, workflow.HookCtls, webhook.WorkflowV4Prefix+workflow.Name, logger)
+	if err := mongodb.NewWorkflowV4Coll().DeleteByID(workflow.ID.Hex()); err != nil {
+		logger.Errorf("Failed to delete WorkflowV4: %s, the error is: %v", name, err)
+		return e.ErrDeleteWorkflow.AddErr(err)
+	}
+	return PurgeWorkflowV4(workflow, logger)
+}
+
+// PurgeWorkflowV4 removes the webhooks, cronjobs, tasks and counter of the deleted workflow
+func PurgeWorkflowV4(workflow *commonmodels.WorkflowV4, logger *zap.SugaredLogger) error {
+	name := workflow.Name
+	err := ProcessWebhook(nil, workflow.HookCtls, webhook.WorkflowV4Prefix+workflow.Name, logger)
 	if err != nil {
 		log.Errorf("Failed to process webhook, err: %s", err)
 	}
@@ -94,11 +117,7 @@ func DeleteWorkflowV4(name string, logger *zap.SugaredLogger) error {
 		log.Errorf("Failed to delete cronjob for workflowV4 %s, error: %s", workflow.Name, err)
 	}
 
-	if err := mongodb.NewWorkflowV4Coll().DeleteByID(workflow.ID.Hex()); err != nil {
-		logger.Errorf("Failed to delete WorkflowV4: %s, the error is: %v", name, err)
-		return e.ErrDeleteWorkflow.AddErr(err)
-	}
-	if err := mongodb.NewworkflowTaskv4Coll().DeleteByWorkflowName(name); err != nil {
+	if err := mongodb.NewWorkflowTaskV4Store().DeleteByWorkflowName(name); err != nil {
 		logger.Errorf("Failed to delete WorkflowV4 task: %s, the error is: %v", name, err)
 		return e.ErrDeleteWorkflow.AddErr(err)
 	}
@@ -0,0 +1,221 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflowcontroller
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/instantmessage"
+	"github.com/koderover/zadig/pkg/shared/client/user"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+// RemindPendingApprovals scans the waiting approvals, reminds the pending approvers and notifies
+// the task creator when an approval is about to time out, the reminder records are kept in the db
+// and removed once the approvals are done
+func RemindPendingApprovals() {
+	tasks, err := mongodb.NewWorkflowTaskV4Store().InCompletedTasks()
+	if err != nil {
+		log.Errorf("failed to list incompleted workflow tasks, err: %s", err)
+		return
+	}
+
+	coll := mongodb.NewApprovalReminderRecordColl()
+	now := time.Now().Unix()
+	waitingKeys := sets.NewString()
+	for _, task := range tasks {
+		if task.Status != config.StatusWaitingApprove {
+			continue
+		}
+		for _, stage := range task.Stages {
+			if stage.Status != config.StatusWaitingApprove || stage.Approval == nil || stage.Approval.Reminder == nil || !stage.Approval.Reminder.Enabled {
+				continue
+			}
+			waitingKeys.Insert(approvalReminderKey(task.WorkflowName, task.TaskID, stage.Name))
+			record, err := coll.Find(task.WorkflowName, task.TaskID, stage.Name)
+			if err == mongo.ErrNoDocuments {
+				record = &commonmodels.ApprovalReminderRecord{
+					WorkflowName:   task.WorkflowName,
+					TaskID:         task.TaskID,
+					StageName:      stage.Name,
+					LastRemindTime: stage.Approval.StartTime,
+				}
+			} else if err != nil {
+				log.Errorf("failed to find approval reminder record of workflow %s task %d stage %s, err: %s", task.WorkflowName, task.TaskID, stage.Name, err)
+				continue
+			}
+			remindApproval(coll, task, stage, record, now)
+		}
+	}
+
+	records, err := coll.List()
+	if err != nil {
+		log.Errorf("failed to list approval reminder records, err: %s", err)
+		return
+	}
+	for _, record := range records {
+		if waitingKeys.Has(approvalReminderKey(record.WorkflowName, record.TaskID, record.StageName)) {
+			continue
+		}
+		if err := coll.DeleteByID(record.ID); err != nil {
+			log.Errorf("failed to delete approval reminder record %s, err: %s", record.ID.Hex(), err)
+		}
+	}
+}
+
+func approvalReminderKey(workflowName string, taskID int64, stageName string) string {
+	return fmt.Sprintf("%s-%d-%s", workflowName, taskID, stageName)
+}
+
+// remindApproval saves the record before sending the messages, so a failure of the db never ends up
+// sending the same reminder every minute
+func remindApproval(coll *mongodb.ApprovalReminderRecordColl, task *commonmodels.WorkflowTask, stage *commonmodels.StageTask, record *commonmodels.ApprovalReminderRecord, now int64) {
+	reminder := stage.Approval.Reminder
+	remind := reminder.Interval > 0 && (reminder.MaxCount <= 0 || record.RemindCount < reminder.MaxCount) &&
+		now-record.LastRemindTime >= int64(reminder.Interval)*60
+	if remind {
+		record.RemindCount++
+		record.LastRemindTime = now
+	}
+
+	var remaining int64
+	timeout := getApprovalTimeout(stage.Approval)
+	if reminder.TimeoutNoticeBefore > 0 && timeout > 0 && !record.TimeoutNotified {
+		remaining = stage.Approval.StartTime + int64(timeout)*60 - now
+	}
+	notifyTimeout := remaining > 0 && remaining <= int64(reminder.TimeoutNoticeBefore)*60
+	if notifyTimeout {
+		record.TimeoutNotified = true
+	}
+
+	if !remind && !notifyTimeout {
+		return
+	}
+	if err := coll.Upsert(record); err != nil {
+		log.Errorf("failed to save approval reminder record of workflow %s task %d stage %s, err: %s", task.WorkflowName, task.TaskID, stage.Name, err)
+		return
+	}
+
+	if remind {
+		if err := instantmessage.NewWeChatClient().SendWorkflowTaskApproveReminder(task, stage.Name, getPendingApprovers(stage.Approval), record.RemindCount); err != nil {
+			log.Errorf("failed to remind approvers of workflow %s task %d stage %s, err: %s", task.WorkflowName, task.TaskID, stage.Name, err)
+		}
+	}
+	if notifyTimeout {
+		if err := instantmessage.NewWeChatClient().SendWorkflowTaskApproveTimeoutNotification(task, stage.Name, time.Duration(remaining)*time.Second); err != nil {
+			log.Errorf("failed to send approval timeout notification of workflow %s task %d stage %s, err: %s", task.WorkflowName, task.TaskID, stage.Name, err)
+		}
+	}
+}
+
+// getApprovalTimeout returns the timeout of the approval in minutes
+func getApprovalTimeout(approval *commonmodels.Approval) int {
+	switch approval.Type {
+	case config.NativeApproval:
+		if approval.NativeApproval != nil {
+			return approval.NativeApproval.Timeout
+		}
+	case config.LarkApproval:
+		if approval.LarkApproval != nil {
+			return approval.LarkApproval.Timeout
+		}
+	case config.DingTalkApproval:
+		if approval.DingTalkApproval != nil {
+			return approval.DingTalkApproval.Timeout
+		}
+	}
+	return 0
+}
+
+// getPendingApprovers returns the approvers who have not approved or rejected yet, the Zadig users
+// of the native approvals are looked up for their email and phone, the members of a group are listed
+func getPendingApprovers(approval *commonmodels.Approval) []*instantmessage.PendingApprover {
+	approvers := make([]*instantmessage.PendingApprover, 0)
+	switch approval.Type {
+	case config.NativeApproval:
+		if approval.NativeApproval == nil {
+			break
+		}
+		userIDs := sets.NewString()
+		for _, u := range approval.NativeApproval.ApproveUsers {
+			if u.RejectOrApprove != "" {
+				continue
+			}
+			if u.Type != "group" {
+				userIDs.Insert(u.UserID)
+				continue
+			}
+			group, err := user.New().GetGroupDetailedInfo(u.GroupID)
+			if err != nil {
+				log.Warnf("failed to find users of group %s, err: %s", u.GroupName, err)
+				approvers = append(approvers, &instantmessage.PendingApprover{Name: u.GroupName})
+				continue
+			}
+			userIDs.Insert(group.UIDs...)
+		}
+		// a member of a group may have approved as a single user already
+		for _, u := range approval.NativeApproval.ApproveUsers {
+			if u.Type != "group" && u.RejectOrApprove != "" {
+				userIDs.Delete(u.UserID)
+			}
+		}
+		for _, uid := range userIDs.List() {
+			info, err := user.New().GetUserByID(uid)
+			if err != nil {
+				log.Warnf("failed to find user %s, err: %s", uid, err)
+				continue
+			}
+			approvers = append(approvers, &instantmessage.PendingApprover{Name: info.Name, Email: info.Email, Phone: info.Phone})
+		}
+	case config.LarkApproval:
+		if approval.LarkApproval == nil {
+			break
+		}
+		for _, node := range approval.LarkApproval.ApprovalNodes {
+			if node.RejectOrApprove != "" {
+				continue
+			}
+			for _, u := range node.ApproveUsers {
+				if u.RejectOrApprove == "" {
+					approvers = append(approvers, &instantmessage.PendingApprover{Name: u.Name, LarkUserID: u.ID})
+				}
+			}
+		}
+	case config.DingTalkApproval:
+		if approval.DingTalkApproval == nil {
+			break
+		}
+		for _, node := range approval.DingTalkApproval.ApprovalNodes {
+			if node.RejectOrApprove != "" {
+				continue
+			}
+			for _, u := range node.ApproveUsers {
+				if u.RejectOrApprove == "" {
+					approvers = append(approvers, &instantmessage.PendingApprover{Name: u.Name})
+				}
+			}
+		}
+	}
+	return approvers
+}
@@ -0,0 +1,324 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflowcontroller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/msg_queue"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller/jobcontroller"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+const (
+	instanceHeartbeatInterval = 10 * time.Second
+	// an instance is considered dead if it has not heartbeated for the seconds
+	instanceExpiration = 30
+	// virtual nodes of each instance on the hash ring, more nodes distribute the workflows more evenly
+	instanceVirtualNodes = 100
+)
+
+var (
+	instanceStartTime = time.Now().Unix()
+	instanceID        = getInstanceID()
+
+	clusterMutex sync.RWMutex
+	clusterRing  *hashRing
+)
+
+func getInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "aslan"
+	}
+	// the container may restart in the same pod, so the start time is added to tell the instances apart
+	return fmt.Sprintf("%s-%d", hostname, time.Now().UnixNano())
+}
+
+// hashRing shards the workflows among the alive instances by consistent hashing on the workflow name,
+// so only the workflows of a joining or leaving instance are moved
+type hashRing struct {
+	leader    string
+	instances sets.String
+	hashes    []uint32
+	nodes     map[uint32]string
+}
+
+func newHashRing(instances []string) *hashRing {
+	ring := &hashRing{
+		instances: sets.NewString(instances...),
+		nodes:     make(map[uint32]string),
+	}
+	if len(instances) > 0 {
+		ring.leader = instances[0]
+	}
+	for _, instance := range instances {
+		for i := 0; i < instanceVirtualNodes; i++ {
+			hash := crc32.ChecksumIEEE([]byte(fmt.Sprintf("%s#%d", instance, i)))
+			ring.nodes[hash] = instance
+			ring.hashes = append(ring.hashes, hash)
+		}
+	}
+	sort.Slice(ring.hashes, func(i, j int) bool { return ring.hashes[i] < ring.hashes[j] })
+	return ring
+}
+
+func (r *hashRing) owner(key string) string {
+	if len(r.hashes) == 0 {
+		return ""
+	}
+	hash := crc32.ChecksumIEEE([]byte(key))
+	idx := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= hash })
+	if idx == len(r.hashes) {
+		idx = 0
+	}
+	return r.nodes[r.hashes[idx]]
+}
+
+// IsLeader returns true if the instance is the earliest started alive instance, the leader runs the
+// singleton jobs such as the cron jobs and the cleanup of the tasks left by dead instances
+func IsLeader() bool {
+	clusterMutex.RLock()
+	defer clusterMutex.RUnlock()
+	return clusterRing != nil && clusterRing.leader == instanceID
+}
+
+// OwnsWorkflow returns true if the tasks of the workflow should be run by the instance
+func OwnsWorkflow(workflowName string) bool {
+	clusterMutex.RLock()
+	defer clusterMutex.RUnlock()
+	return clusterRing != nil && clusterRing.owner(workflowName) == instanceID
+}
+
+func isInstanceAlive(id string) bool {
+	clusterMutex.RLock()
+	defer clusterMutex.RUnlock()
+	return clusterRing != nil && clusterRing.instances.Has(id)
+}
+
+// RunClusterHeartbeat keeps the instance alive in the cluster and refreshes the members
+func RunClusterHeartbeat() {
+	for {
+		heartbeat()
+		time.Sleep(instanceHeartbeatInterval)
+	}
+}
+
+func heartbeat() {
+	coll := commonrepo.NewControllerInstanceColl()
+	if err := coll.Heartbeat(instanceID, instanceStartTime); err != nil {
+		log.Errorf("failed to heartbeat controller instance %s, err: %s", instanceID, err)
+		return
+	}
+	now := time.Now().Unix()
+	instances, err := coll.ListAlive(now - instanceExpiration)
+	if err != nil {
+		log.Errorf("failed to list alive controller instances, err: %s", err)
+		return
+	}
+	ids := make([]string, 0, len(instances))
+	for _, instance := range instances {
+		ids = append(ids, instance.InstanceID)
+	}
+
+	clusterMutex.Lock()
+	if clusterRing == nil || !clusterRing.instances.Equal(sets.NewString(ids...)) {
+		log.Infof("controller instances changed: %v", ids)
+	}
+	clusterRing = newHashRing(ids)
+	clusterMutex.Unlock()
+
+	leader := IsLeader()
+	consumeControlMessages(leader)
+	adoptOrphanedTasks()
+	if leader {
+		releaseOrphanedQuotas()
+		if err := coll.DeleteExpired(now - instanceExpiration*10); err != nil {
+			log.Warnf("failed to delete expired controller instances, err: %s", err)
+		}
+	}
+}
+
+const (
+	controlTypeCancel  = "cancel"
+	controlTypeApprove = "approve"
+)
+
+// workflowControlMessage forwards an operation on a task to the instance running it, the operations rely on
+// the in-memory state of the running task
+type workflowControlMessage struct {
+	Type         string                `json:"type"`
+	WorkflowName string                `json:"workflow_name"`
+	TaskID       int64                 `json:"task_id"`
+	StageName    string                `json:"stage_name,omitempty"`
+	UserName     string                `json:"user_name,omitempty"`
+	UserID       string                `json:"user_id,omitempty"`
+	Comment      string                `json:"comment,omitempty"`
+	Approve      bool                  `json:"approve,omitempty"`
+	Source       config.ApprovalSource `json:"source,omitempty"`
+	CreateTime   int64                 `json:"create_time"`
+}
+
+func sendControlMessage(message *workflowControlMessage) error {
+	message.CreateTime = time.Now().Unix()
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return commonrepo.NewMsgQueueCommonColl().Create(&msg_queue.MsgQueueCommon{
+		Payload:   string(payload),
+		QueueType: setting.TopicWorkflowV4Control,
+	})
+}
+
+// consumeControlMessages handles the forwarded operations on the tasks running in the instance, the leader
+// deletes the expired messages whose tasks are not running in any instance
+func consumeControlMessages(leader bool) {
+	coll := commonrepo.NewMsgQueueCommonColl()
+	msgs, err := coll.List(&commonrepo.ListMsgQueueCommonOption{QueueType: setting.TopicWorkflowV4Control})
+	if err != nil {
+		log.Errorf("failed to list workflow control messages, err: %s", err)
+		return
+	}
+	for _, msg := range msgs {
+		message := &workflowControlMessage{}
+		if err := json.Unmarshal([]byte(msg.Payload), message); err != nil {
+			_ = coll.Delete(msg.ID)
+			continue
+		}
+		if _, ok := cancelChannelMap.Load(fmt.Sprintf("%s-%d", message.WorkflowName, message.TaskID)); ok {
+			handleControlMessage(message)
+			_ = coll.Delete(msg.ID)
+			continue
+		}
+		if leader && time.Now().Unix()-message.CreateTime > instanceExpiration*10 {
+			_ = coll.Delete(msg.ID)
+		}
+	}
+}
+
+func handleControlMessage(message *workflowControlMessage) {
+	switch message.Type {
+	case controlTypeCancel:
+		value, ok := cancelChannelMap.Load(fmt.Sprintf("%s-%d", message.WorkflowName, message.TaskID))
+		if !ok {
+			return
+		}
+		if f, ok := value.(context.CancelFunc); ok {
+			log.Infof("task %s:%d is cancelled through another instance", message.WorkflowName, message.TaskID)
+			f()
+		}
+	case controlTypeApprove:
+		if err := ApproveStage(message.WorkflowName, message.StageName, message.UserName, message.UserID, message.Comment, message.TaskID, message.Approve, message.Source); err != nil {
+			log.Errorf("failed to approve task %s:%d through another instance, err: %s", message.WorkflowName, message.TaskID, err)
+		}
+	}
+}
+
+// isTaskWaitingApprove checks the stored task since the approval may be held by another instance
+func isTaskWaitingApprove(workflowName, stageName string, taskID int64) bool {
+	task, err := commonrepo.NewWorkflowTaskV4Store().Find(workflowName, taskID)
+	if err != nil || task.Status != config.StatusWaitingApprove {
+		return false
+	}
+	for _, stage := range task.Stages {
+		if stage.Name == stageName {
+			return stage.Approval != nil && stage.Approval.Enabled && stage.Status == config.StatusWaitingApprove
+		}
+	}
+	return false
+}
+
+// releaseOrphanedQuotas releases the project resource quota held by the jobs of the tasks no longer queued
+func releaseOrphanedQuotas() {
+	tasks, err := commonrepo.NewWorkflowQueueColl().List(&commonrepo.ListWorfklowQueueOption{})
+	if err != nil {
+		log.Errorf("failed to list queue tasks, err: %s", err)
+		return
+	}
+	queued := sets.NewString()
+	for _, task := range tasks {
+		queued.Insert(fmt.Sprintf("%s-%d", task.WorkflowName, task.TaskID))
+	}
+	jobcontroller.ReleaseOrphanedQuotas(queued)
+}
+
+// adoptOrphanedTasks takes over the tasks run by the dead instances, every task is adopted by the instance
+// owning its workflow and resumed from the persisted state
+func adoptOrphanedTasks() {
+	tasks, err := commonrepo.NewWorkflowQueueColl().List(&commonrepo.ListWorfklowQueueOption{})
+	if err != nil {
+		log.Errorf("failed to list queue tasks, err: %s", err)
+		return
+	}
+	for _, task := range tasks {
+		if task.Status != config.StatusQueued && task.Status != config.StatusRunning && task.Status != config.StatusWaitingApprove {
+			continue
+		}
+		if isInstanceAlive(task.InstanceID) || !OwnsWorkflow(task.WorkflowName) {
+			continue
+		}
+		adopted, err := commonrepo.NewWorkflowQueueColl().Adopt(task, instanceID)
+		if err != nil {
+			log.Errorf("failed to adopt task %s:%d, err: %s", task.WorkflowName, task.TaskID, err)
+			continue
+		}
+		if !adopted {
+			continue
+		}
+		log.Infof("adopt task %s:%d left by instance %s", task.WorkflowName, task.TaskID, task.InstanceID)
+		resumeWorkflowTask(task)
+	}
+}
+
+// resumeWorkflowTask runs the adopted task again, the passed stages and jobs are skipped and the in-flight
+// jobs are re-adopted
+func resumeWorkflowTask(q *commonmodels.WorkflowQueue) {
+	logger := log.SugaredLogger()
+	workflowTask, err := commonrepo.NewWorkflowTaskV4Store().Find(q.WorkflowName, q.TaskID)
+	if err != nil {
+		logger.Errorf("%s:%d get workflow task error: %v", q.WorkflowName, q.TaskID, err)
+		return
+	}
+	if workflowTask.Status == config.StatusPassed || statusFailed(workflowTask.Status) {
+		// the task was finished before the queue was updated
+		if err := Remove(q); err != nil {
+			logger.Errorf("failed to remove finished task %s:%d from queue, err: %s", q.WorkflowName, q.TaskID, err)
+		}
+		return
+	}
+	jobConcurrency := 1
+	if sysSetting, err := commonrepo.NewSystemSettingColl().Get(); err == nil {
+		jobConcurrency = int(sysSetting.BuildConcurrency)
+	}
+	ctl := NewWorkflowController(workflowTask, logger)
+	ctl.resumed = true
+	go ctl.Run(context.Background(), jobConcurrency)
+}
@@ -0,0 +1,240 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/util"
+	"github.com/koderover/zadig/pkg/tool/kube/updater"
+	"github.com/koderover/zadig/pkg/tool/prometheus"
+)
+
+const (
+	defaultCanaryAnalysisIntervalSeconds = 60
+	defaultCanaryAnalysisIterations      = 5
+)
+
+// canaryAnalyzer compares the canary workload with the baseline workload by the prometheus metrics
+type canaryAnalyzer struct {
+	analysis  *commonmodels.CanaryAnalysis
+	namespace string
+	canary    string
+	baseline  string
+	result    *commonmodels.CanaryAnalysisResult
+	events    *commonmodels.Events
+	ack       func()
+}
+
+// run analyzes the canary every interval until all the iterations are done or the failures are more than allowed.
+// The returned reason is empty if the analysis passes, and cancelled is true if ctx is done before it ends.
+func (a *canaryAnalyzer) run(ctx context.Context) (reason string, cancelled bool) {
+	info, err := mongodb.NewObservabilityColl().GetByID(context.Background(), a.analysis.ObservabilityID)
+	if err != nil {
+		return fmt.Sprintf("failed to get observability %s: %s", a.analysis.ObservabilityID, err), false
+	}
+	client, err := util.NewPrometheusClient(info)
+	if err != nil {
+		return fmt.Sprintf("failed to init prometheus client: %s", err), false
+	}
+
+	interval := a.analysis.IntervalSeconds
+	if interval <= 0 {
+		interval = defaultCanaryAnalysisIntervalSeconds
+	}
+	iterations := a.analysis.Iterations
+	if iterations <= 0 {
+		iterations = defaultCanaryAnalysisIterations
+	}
+	a.events.Info(fmt.Sprintf("start analyzing deployment: %s against %s, %d runs every %ds", a.canary, a.baseline, iterations, interval))
+	a.ack()
+
+	failures := 0
+	for i := 0; i < iterations; i++ {
+		select {
+		case <-ctx.Done():
+			return "", true
+		case <-time.After(time.Duration(interval) * time.Second):
+		}
+
+		run := a.analyzeOnce(client, interval)
+		a.result.Runs = append(a.result.Runs, run)
+		if !run.Passed {
+			failures++
+			a.events.Info(fmt.Sprintf("canary analysis run %d failed, %d failures so far", i+1, failures))
+		}
+		a.ack()
+		if failures > a.analysis.MaxFailures {
+			return fmt.Sprintf("canary analysis failed %d times, more than the %d failures allowed", failures, a.analysis.MaxFailures), false
+		}
+	}
+	return "", false
+}
+
+// analyzeOnce checks all the criteria over the last interval, the canary passes a criterion only if both the canary and
+// the baseline values needed are found
+func (a *canaryAnalyzer) analyzeOnce(client *prometheus.Client, interval int) *commonmodels.CanaryAnalysisRun {
+	now := time.Now()
+	run := &commonmodels.CanaryAnalysisRun{Time: now.Unix(), Passed: true}
+	for _, criterion := range a.analysis.Criteria {
+		result := &commonmodels.CanaryCriterionResult{Name: criterion.Name}
+		run.Metrics = append(run.Metrics, result)
+
+		canary, err := a.queryWorkload(client, criterion.Query, a.canary, interval, now)
+		if err != nil {
+			result.Reason = fmt.Sprintf("failed to query the canary: %s", err)
+			run.Passed = false
+			continue
+		}
+		result.Canary = canary
+		if criterion.MaxBaselineIncrease != nil {
+			baseline, err := a.queryWorkload(client, criterion.Query, a.baseline, interval, now)
+			if err != nil {
+				result.Reason = fmt.Sprintf("failed to query the baseline: %s", err)
+				run.Passed = false
+				continue
+			}
+			result.Baseline = baseline
+		}
+
+		result.Passed, result.Reason = checkCanaryCriterion(criterion, *result.Canary, result.Baseline)
+		if !result.Passed {
+			run.Passed = false
+		}
+	}
+	return run
+}
+
+// queryWorkload replaces the placeholders in the query with the workload and returns the value, no data is an error
+// since the criteria can't be evaluated without it
+func (a *canaryAnalyzer) queryWorkload(client *prometheus.Client, query, workload string, interval int, ts time.Time) (*float64, error) {
+	query = strings.NewReplacer(
+		"$namespace", a.namespace,
+		"$workload", workload,
+		"$interval", fmt.Sprintf("%ds", interval),
+	).Replace(query)
+	value, found, err := client.QueryValue(query, ts)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("no data found for workload %s", workload)
+	}
+	return &value, nil
+}
+
+// analyze compares the canary with the baseline after the canary is ready, the canary is promoted if the analysis
+// passes, otherwise it is rolled back and the job fails
+func (c *CanaryDeployJobCtl) analyze(ctx context.Context) {
+	c.job.Status = config.StatusRunning
+	c.jobTaskSpec.AnalysisResult = &commonmodels.CanaryAnalysisResult{}
+	analyzer := &canaryAnalyzer{
+		analysis:  c.jobTaskSpec.Analysis,
+		namespace: c.jobTaskSpec.Namespace,
+		canary:    c.jobTaskSpec.CanaryWorkloadName,
+		baseline:  c.jobTaskSpec.WorkloadName,
+		result:    c.jobTaskSpec.AnalysisResult,
+		events:    c.jobTaskSpec.Events,
+		ack:       c.ack,
+	}
+	reason, cancelled := analyzer.run(ctx)
+	if cancelled {
+		c.job.Status = config.StatusCancelled
+		c.deleteCanary()
+		return
+	}
+	if reason != "" {
+		c.rollback(reason)
+		return
+	}
+	c.promote(ctx)
+}
+
+func checkCanaryCriterion(criterion *commonmodels.CanaryCriterion, canary float64, baseline *float64) (bool, string) {
+	if criterion.MaxValue != nil && canary > *criterion.MaxValue {
+		return false, fmt.Sprintf("canary value %g is greater than %g", canary, *criterion.MaxValue)
+	}
+	if criterion.MaxBaselineIncrease != nil && baseline != nil {
+		// no increase in percentage can be computed over a zero baseline, such as the error rate of a healthy
+		// baseline, the canary is bounded by the max value then, or has to be zero as well if it is not set
+		if *baseline <= 0 {
+			if criterion.MaxValue == nil && canary > *baseline {
+				return false, fmt.Sprintf("canary value %g is greater than the baseline value %g", canary, *baseline)
+			}
+			return true, ""
+		}
+		if canary > *baseline*(1+*criterion.MaxBaselineIncrease/100) {
+			return false, fmt.Sprintf("canary value %g is more than %g%% greater than the baseline value %g", canary, *criterion.MaxBaselineIncrease, *baseline)
+		}
+	}
+	return true, ""
+}
+
+// promote updates the image of the baseline deployment and deletes the canary deployment
+func (c *CanaryDeployJobCtl) promote(ctx context.Context) {
+	c.jobTaskSpec.AnalysisResult.Decision = commonmodels.CanaryAnalysisPromoted
+	c.jobTaskSpec.Events.Info("canary analysis passed, promoting the canary")
+	c.ack()
+
+	if err := updater.UpdateDeploymentImage(c.jobTaskSpec.Namespace, c.jobTaskSpec.WorkloadName, c.jobTaskSpec.ContainerName, c.jobTaskSpec.Image, c.kubeClient); err != nil {
+		msg := fmt.Sprintf("update deployment: %s image error: %v", c.jobTaskSpec.WorkloadName, err)
+		logError(c.job, msg, c.logger)
+		c.jobTaskSpec.Events.Error(msg)
+		return
+	}
+	// DeployTimeout has been converted to seconds by the wait
+	status, err := waitDeploymentReady(ctx, c.jobTaskSpec.WorkloadName, c.jobTaskSpec.Namespace, c.jobTaskSpec.DeployTimeout, c.kubeClient, c.logger)
+	if err != nil {
+		c.job.Status = status
+		c.jobTaskSpec.Events.Error(err.Error())
+		return
+	}
+	if !c.deleteCanary() {
+		return
+	}
+	c.job.Status = config.StatusPassed
+	c.jobTaskSpec.Events.Info(fmt.Sprintf("deployment: %s promoted successfully", c.jobTaskSpec.WorkloadName))
+}
+
+// rollback deletes the canary deployment and fails the job, the baseline deployment is left untouched
+func (c *CanaryDeployJobCtl) rollback(reason string) {
+	c.jobTaskSpec.AnalysisResult.Decision = commonmodels.CanaryAnalysisRolledBack
+	c.jobTaskSpec.Events.Info("rolling back the canary: " + reason)
+	c.ack()
+	if !c.deleteCanary() {
+		return
+	}
+	logError(c.job, reason, c.logger)
+	c.jobTaskSpec.Events.Error(reason)
+}
+
+func (c *CanaryDeployJobCtl) deleteCanary() bool {
+	if err := updater.DeleteDeploymentAndWaitWithTimeout(c.jobTaskSpec.Namespace, c.jobTaskSpec.CanaryWorkloadName, time.Duration(c.jobTaskSpec.DeployTimeout)*time.Second, c.kubeClient); err != nil {
+		msg := fmt.Sprintf("delete canary deployment %s error: %v", c.jobTaskSpec.CanaryWorkloadName, err)
+		logError(c.job, msg, c.logger)
+		c.jobTaskSpec.Events.Error(msg)
+		return false
+	}
+	c.jobTaskSpec.Events.Info(fmt.Sprintf("canary deployment: %s deleted", c.jobTaskSpec.CanaryWorkloadName))
+	return true
+}
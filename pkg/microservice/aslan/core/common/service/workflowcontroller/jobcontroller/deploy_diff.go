@@ -0,0 +1,161 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"reflect"
+
+	"helm.sh/helm/v3/pkg/release"
+	"sigs.k8s.io/yaml"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
+	helmtool "github.com/koderover/zadig/pkg/tool/helmclient"
+	"github.com/koderover/zadig/pkg/util"
+)
+
+// skipUnchanged marks the job as no changes if it is set to skip the unchanged services and nothing is changed,
+// the deployment goes on if the live state can not be compared
+func (c *DeployJobCtl) skipUnchanged(currentYaml, updatedYaml string, resources []*kube.WorkloadResource, withImages bool) bool {
+	if !c.jobTaskSpec.SkipUnchanged {
+		return false
+	}
+	// the pods are expected to be restarted
+	if c.jobTaskSpec.UpdateStrategy != nil && c.jobTaskSpec.UpdateStrategy.ForceRestart {
+		return false
+	}
+	unchanged, err := c.serviceUnchanged(currentYaml, updatedYaml, resources, withImages)
+	if err != nil {
+		c.logger.Warnf("failed to compare service %s with the live state: %v", c.jobTaskSpec.ServiceName, err)
+		return false
+	}
+	if unchanged {
+		c.logger.Infof("service %s is not changed, skip deploying it", c.jobTaskSpec.ServiceName)
+		c.jobTaskSpec.NoChanges = true
+		c.job.Status = config.StatusPassed
+	}
+	return unchanged
+}
+
+// serviceUnchanged checks whether the rendered service yaml and the images to deploy are the same as the live ones,
+// currentYaml and updatedYaml are empty for the services not managed by zadig, only the images are compared then.
+// The rendered resources are compared with the live objects, so that the changes made in the cluster, such as the
+// images edited manually, are reverted by the deployment.
+func (c *DeployJobCtl) serviceUnchanged(currentYaml, updatedYaml string, resources []*kube.WorkloadResource, withImages bool) (bool, error) {
+	// the render info saved in the env is updated by the deployment
+	if currentYaml != updatedYaml {
+		return false, nil
+	}
+	if updatedYaml != "" {
+		drifts, err := kube.DetectManifestDrift(&kube.ManifestDriftOption{
+			Namespace: c.namespace,
+			Manifest:  updatedYaml,
+			APIReader: c.kubeClient,
+		})
+		if err != nil {
+			return false, err
+		}
+		if len(drifts) > 0 {
+			return false, nil
+		}
+	}
+	if !withImages {
+		return true, nil
+	}
+
+	deployments, statefulSets, cronJobs, betaCronJobs, err := kube.FetchSelectedWorkloads(c.namespace, resources, c.kubeClient, c.clientSet)
+	if err != nil {
+		return false, err
+	}
+	liveImages := make(map[string]string)
+	for _, deploy := range deployments {
+		for _, container := range deploy.Spec.Template.Spec.Containers {
+			liveImages[container.Name] = container.Image
+		}
+	}
+	for _, sts := range statefulSets {
+		for _, container := range sts.Spec.Template.Spec.Containers {
+			liveImages[container.Name] = container.Image
+		}
+	}
+	for _, cron := range cronJobs {
+		for _, container := range cron.Spec.JobTemplate.Spec.Template.Spec.Containers {
+			liveImages[container.Name] = container.Image
+		}
+	}
+	for _, cron := range betaCronJobs {
+		for _, container := range cron.Spec.JobTemplate.Spec.Template.Spec.Containers {
+			liveImages[container.Name] = container.Image
+		}
+	}
+	for _, serviceModule := range c.jobTaskSpec.ServiceAndImages {
+		if liveImages[serviceModule.ServiceModule] != serviceModule.Image {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// releaseUnchanged checks whether the chart and the values of the helm release are the same as the ones to deploy,
+// the values are compared in the same way as the values difference shown before running the workflow.
+func (c *HelmDeployJobCtl) releaseUnchanged(env *commonmodels.Product, renderSet *commonmodels.RenderSet, productService *commonmodels.ProductService,
+	svcTemplate *commonmodels.Service, images []string, curRevision int64) (bool, error) {
+	if svcTemplate.Revision != curRevision {
+		return false, nil
+	}
+
+	latestValues, err := kube.GeneMergedValues(productService, renderSet, images, true)
+	if err != nil {
+		return false, err
+	}
+
+	helmClient, err := helmtool.NewClientFromNamespace(env.ClusterID, env.Namespace)
+	if err != nil {
+		return false, err
+	}
+	releaseName := util.GeneReleaseName(svcTemplate.GetReleaseNaming(), env.ProductName, env.Namespace, env.EnvName, svcTemplate.ServiceName)
+	rel, err := helmClient.GetRelease(releaseName)
+	if err != nil {
+		return false, err
+	}
+	// a failed or pending release is always upgraded again
+	if rel.Info == nil || rel.Info.Status != release.StatusDeployed {
+		return false, nil
+	}
+	currentValues, err := helmClient.GetReleaseValues(releaseName, true)
+	if err != nil {
+		return false, err
+	}
+	return valuesEqual(currentValues, latestValues)
+}
+
+func valuesEqual(current map[string]interface{}, latestYaml string) (bool, error) {
+	// marshal the current values to yaml first, so that both sides are decoded in the same way
+	currentYaml, err := yaml.Marshal(current)
+	if err != nil {
+		return false, err
+	}
+	currentValues, latestValues := make(map[string]interface{}), make(map[string]interface{})
+	if err := yaml.Unmarshal(currentYaml, &currentValues); err != nil {
+		return false, err
+	}
+	if err := yaml.Unmarshal([]byte(latestYaml), &latestValues); err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(currentValues, latestValues), nil
+}
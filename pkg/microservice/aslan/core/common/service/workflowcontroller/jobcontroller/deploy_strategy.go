@@ -0,0 +1,305 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/yaml"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/kube/getter"
+	"github.com/koderover/zadig/pkg/tool/kube/updater"
+	"github.com/koderover/zadig/pkg/util"
+)
+
+func workloadKey(kind, name string) string {
+	return kind + "/" + name
+}
+
+// deploymentStrategy returns the deployment strategy overridden by the job, nil if it is not overridden
+func deploymentStrategy(strategy *commonmodels.DeployUpdateStrategy) *appsv1.DeploymentStrategy {
+	if strategy == nil {
+		return nil
+	}
+	switch {
+	case strategy.Type == config.DeployStrategyRecreate:
+		return &appsv1.DeploymentStrategy{Type: appsv1.RecreateDeploymentStrategyType}
+	case strategy.Type == config.DeployStrategyRolling || strategy.MaxSurge != "" || strategy.MaxUnavailable != "":
+		resp := &appsv1.DeploymentStrategy{
+			Type:          appsv1.RollingUpdateDeploymentStrategyType,
+			RollingUpdate: &appsv1.RollingUpdateDeployment{},
+		}
+		if strategy.MaxSurge != "" {
+			maxSurge := intstr.Parse(strategy.MaxSurge)
+			resp.RollingUpdate.MaxSurge = &maxSurge
+		}
+		if strategy.MaxUnavailable != "" {
+			maxUnavailable := intstr.Parse(strategy.MaxUnavailable)
+			resp.RollingUpdate.MaxUnavailable = &maxUnavailable
+		}
+		return resp
+	}
+	return nil
+}
+
+// strategyPatch returns the spec.strategy to apply, the rolling update parameters are removed explicitly for
+// the recreate strategy since they are not allowed by it
+func strategyPatch(strategy *appsv1.DeploymentStrategy) map[string]interface{} {
+	patch := map[string]interface{}{"type": string(strategy.Type)}
+	if strategy.Type == appsv1.RecreateDeploymentStrategyType {
+		patch["rollingUpdate"] = nil
+	} else if strategy.RollingUpdate != nil {
+		rollingUpdate := make(map[string]interface{})
+		if strategy.RollingUpdate.MaxSurge != nil {
+			rollingUpdate["maxSurge"] = strategy.RollingUpdate.MaxSurge
+		}
+		if strategy.RollingUpdate.MaxUnavailable != nil {
+			rollingUpdate["maxUnavailable"] = strategy.RollingUpdate.MaxUnavailable
+		}
+		patch["rollingUpdate"] = rollingUpdate
+	}
+	return patch
+}
+
+// injectUpdateStrategy sets the update strategy overridden by the job to the deployments in the rendered yaml.
+// The strategy is not saved in the service template, the next deployment applies the strategy of the template again.
+func injectUpdateStrategy(yamlContent string, strategy *appsv1.DeploymentStrategy) (string, error) {
+	if strategy == nil {
+		return yamlContent, nil
+	}
+	docs := util.SplitYaml(yamlContent)
+	for i, doc := range docs {
+		obj := make(map[string]interface{})
+		if err := yaml.Unmarshal([]byte(doc), &obj); err != nil {
+			return "", fmt.Errorf("failed to unmarshal the rendered yaml: %v", err)
+		}
+		if obj["kind"] != setting.Deployment {
+			continue
+		}
+		spec, ok := obj["spec"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		spec["strategy"] = strategyPatch(strategy)
+		content, err := yaml.Marshal(obj)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal the deployment: %v", err)
+		}
+		docs[i] = string(content)
+	}
+	return util.JoinYamls(docs), nil
+}
+
+// recordPodTemplates returns the pod templates of the existing workloads, which are used to find out the workloads
+// whose pods are not recreated by the deployment.
+func (c *DeployJobCtl) recordPodTemplates(resources []*kube.WorkloadResource) map[string]*corev1.PodTemplateSpec {
+	templates := make(map[string]*corev1.PodTemplateSpec)
+	for _, resource := range resources {
+		switch resource.Type {
+		case setting.Deployment:
+			deploy, found, err := getter.GetDeployment(c.namespace, resource.Name, c.kubeClient)
+			if err != nil || !found {
+				continue
+			}
+			templates[workloadKey(setting.Deployment, resource.Name)] = &deploy.Spec.Template
+		case setting.StatefulSet:
+			sts, found, err := getter.GetStatefulSet(c.namespace, resource.Name, c.kubeClient)
+			if err != nil || !found {
+				continue
+			}
+			templates[workloadKey(setting.StatefulSet, resource.Name)] = &sts.Spec.Template
+		}
+	}
+	return templates
+}
+
+// overrideUpdateStrategy overrides the update strategy of the existing deployments which are updated without
+// applying the rendered yaml, the original strategies are restored by restoreUpdateStrategies after the rollout.
+func (c *DeployJobCtl) overrideUpdateStrategy(resources []*kube.WorkloadResource) error {
+	strategy := deploymentStrategy(c.jobTaskSpec.UpdateStrategy)
+	if strategy == nil {
+		return nil
+	}
+	if c.originalStrategies == nil {
+		c.originalStrategies = make(map[string]appsv1.DeploymentStrategy)
+	}
+	for _, resource := range resources {
+		if resource.Type != setting.Deployment {
+			continue
+		}
+		deploy, found, err := getter.GetDeployment(c.namespace, resource.Name, c.kubeClient)
+		if err != nil || !found {
+			continue
+		}
+		skip, err := c.checkProtectedWorkload(deploy, setting.Deployment)
+		if err != nil {
+			return err
+		}
+		if skip {
+			continue
+		}
+		if _, ok := c.originalStrategies[resource.Name]; !ok {
+			c.originalStrategies[resource.Name] = deploy.Spec.Strategy
+		}
+		if err := updater.UpdateDeploymentStrategy(c.namespace, resource.Name, strategyPatch(strategy), c.kubeClient); err != nil {
+			return fmt.Errorf("failed to update the strategy of %s/deployments/%s: %v", c.namespace, resource.Name, err)
+		}
+	}
+	return nil
+}
+
+// restoreUpdateStrategies restores the strategies of the deployments overridden by overrideUpdateStrategy
+func (c *DeployJobCtl) restoreUpdateStrategies() {
+	for name, strategy := range c.originalStrategies {
+		if err := updater.UpdateDeploymentStrategy(c.namespace, name, strategyPatch(&strategy), c.kubeClient); err != nil {
+			c.logger.Warnf("failed to restore the strategy of %s/deployments/%s: %v", c.namespace, name, err)
+		}
+	}
+	c.originalStrategies = nil
+}
+
+// restartUnchangedWorkloads restarts the replaced workloads whose pod template is not changed by the deployment, so that
+// the pods are recreated if force restart is set even if the image tag is unchanged, or if the configs they use are changed.
+func (c *DeployJobCtl) restartUnchangedWorkloads(templates map[string]*corev1.PodTemplateSpec, changedConfigs map[string][]string) error {
+	forceRestart := c.jobTaskSpec.UpdateStrategy != nil && c.jobTaskSpec.UpdateStrategy.ForceRestart
+	restarted := make(map[string]bool)
+	for i, resource := range c.jobTaskSpec.ReplaceResources {
+		key := workloadKey(resource.Kind, resource.Name)
+		configs := changedConfigs[key]
+		if len(configs) > 0 {
+			c.jobTaskSpec.ReplaceResources[i].ChangedConfigs = configs
+		}
+		if !forceRestart && len(configs) == 0 {
+			continue
+		}
+		template, ok := templates[key]
+		if !ok || restarted[key] {
+			continue
+		}
+		switch resource.Kind {
+		case setting.Deployment:
+			deploy, found, err := getter.GetDeployment(c.namespace, resource.Name, c.kubeClient)
+			if err != nil || !found || !apiequality.Semantic.DeepEqual(&deploy.Spec.Template, template) {
+				continue
+			}
+			skip, err := c.checkProtectedWorkload(deploy, setting.Deployment)
+			if err != nil {
+				return err
+			}
+			if skip {
+				continue
+			}
+			if err := updater.RestartDeployment(c.namespace, resource.Name, c.kubeClient); err != nil {
+				return err
+			}
+		case setting.StatefulSet:
+			sts, found, err := getter.GetStatefulSet(c.namespace, resource.Name, c.kubeClient)
+			if err != nil || !found || !apiequality.Semantic.DeepEqual(&sts.Spec.Template, template) {
+				continue
+			}
+			skip, err := c.checkProtectedWorkload(sts, setting.StatefulSet)
+			if err != nil {
+				return err
+			}
+			if skip {
+				continue
+			}
+			if err := updater.RestartStatefulSet(c.namespace, resource.Name, c.kubeClient); err != nil {
+				return err
+			}
+		default:
+			continue
+		}
+		restarted[key] = true
+	}
+	return nil
+}
+
+// restartServiceModules restarts the workloads of the selected service modules without changing them
+func (c *DeployJobCtl) restartServiceModules(resources []*kube.WorkloadResource) error {
+	modules := make(map[string]bool)
+	for _, serviceModule := range c.jobTaskSpec.ServiceAndImages {
+		modules[serviceModule.ServiceModule] = true
+	}
+	containsModule := func(containers []string) bool {
+		for _, container := range containers {
+			if modules[container] {
+				return true
+			}
+		}
+		return false
+	}
+
+	deployments, statefulSets, _, _, err := kube.FetchSelectedWorkloads(c.namespace, resources, c.kubeClient, c.clientSet)
+	if err != nil {
+		return err
+	}
+	for _, deploy := range deployments {
+		containers := make([]string, 0)
+		for _, container := range deploy.Spec.Template.Spec.Containers {
+			containers = append(containers, container.Name)
+		}
+		if !containsModule(containers) {
+			continue
+		}
+		skip, err := c.checkProtectedWorkload(deploy, setting.Deployment)
+		if err != nil {
+			return err
+		}
+		if skip {
+			continue
+		}
+		if err := updater.RestartDeployment(c.namespace, deploy.Name, c.kubeClient); err != nil {
+			return err
+		}
+		c.jobTaskSpec.ReplaceResources = append(c.jobTaskSpec.ReplaceResources, commonmodels.Resource{Kind: setting.Deployment, Name: deploy.Name})
+		c.jobTaskSpec.RelatedPodLabels = append(c.jobTaskSpec.RelatedPodLabels, deploy.Spec.Template.Labels)
+	}
+	for _, sts := range statefulSets {
+		containers := make([]string, 0)
+		for _, container := range sts.Spec.Template.Spec.Containers {
+			containers = append(containers, container.Name)
+		}
+		if !containsModule(containers) {
+			continue
+		}
+		skip, err := c.checkProtectedWorkload(sts, setting.StatefulSet)
+		if err != nil {
+			return err
+		}
+		if skip {
+			continue
+		}
+		if err := updater.RestartStatefulSet(c.namespace, sts.Name, c.kubeClient); err != nil {
+			return err
+		}
+		c.jobTaskSpec.ReplaceResources = append(c.jobTaskSpec.ReplaceResources, commonmodels.Resource{Kind: setting.StatefulSet, Name: sts.Name})
+		c.jobTaskSpec.RelatedPodLabels = append(c.jobTaskSpec.RelatedPodLabels, sts.Spec.Template.Labels)
+	}
+	if len(c.jobTaskSpec.ReplaceResources) == 0 {
+		return fmt.Errorf("no workload of service %s is found in env %s", c.jobTaskSpec.ServiceName, c.jobTaskSpec.Env)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	crClient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/failureclass"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/kube/getter"
+)
+
+const (
+	// failureLogTailSize is the size of the log tail kept to find the failure reason, the errors are usually
+	// printed at the end of the log
+	failureLogTailSize  = 64 * 1024
+	maxFailureReasonLen = 200
+)
+
+var (
+	failureLinePattern = regexp.MustCompile(`(?i)\b(error|fatal|failed|failure|exception|panic)\b`)
+	ansiPattern        = regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]`)
+	// the prefixes varying between the runs are removed so that the same reasons are aggregated together
+	timestampPattern = regexp.MustCompile(`^\[?\d{4}[-/]\d{2}[-/]\d{2}[ T]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?\]?\s*`)
+	numberPattern    = regexp.MustCompile(`\b\d+\b`)
+)
+
+// getLogTail returns the last failureLogTailSize bytes of the log starting at a line break
+func getLogTail(content []byte) []byte {
+	if len(content) <= failureLogTailSize {
+		return content
+	}
+	tail := content[len(content)-failureLogTailSize:]
+	if i := bytes.IndexByte(tail, '\n'); i >= 0 {
+		tail = tail[i+1:]
+	}
+	return append([]byte{}, tail...)
+}
+
+// getFailureReason returns the last error line of the log tail, or the error of the job if no line looks like an
+// error. The numbers are replaced so that the reasons of different runs can be aggregated.
+func getFailureReason(jobError string, logTail []byte) string {
+	reason := ""
+	scanner := bufio.NewScanner(bytes.NewReader(logTail))
+	scanner.Buffer(make([]byte, 0, 64*1024), failureLogTailSize)
+	for scanner.Scan() {
+		line := strings.TrimSpace(ansiPattern.ReplaceAllString(scanner.Text(), ""))
+		if line != "" && failureLinePattern.MatchString(line) {
+			reason = line
+		}
+	}
+	if reason == "" {
+		reason = strings.TrimSpace(jobError)
+	}
+	if reason == "" {
+		return ""
+	}
+
+	reason = timestampPattern.ReplaceAllString(reason, "")
+	reason = numberPattern.ReplaceAllString(reason, "N")
+	if runes := []rune(reason); len(runes) > maxFailureReasonLen {
+		reason = string(runes[:maxFailureReasonLen]) + "..."
+	}
+	return reason
+}
+
+// classifyFailure tags the failed or timed out job with the categories of its failure
+func classifyFailure(job *commonmodels.JobTask, logTail []byte, events []*failureclass.Event) {
+	job.FailureCategories = nil
+	if job.Status != config.StatusFailed && job.Status != config.StatusTimeout {
+		return
+	}
+	job.FailureCategories = failureclass.Classify(&failureclass.Input{
+		Status: string(job.Status),
+		Error:  job.Error,
+		Log:    logTail,
+		Events: events,
+	})
+}
+
+// listPodEvents returns the warning events of the pods of the kubernetes job, they are lost once the job is deleted
+func listPodEvents(namespace string, jobLabel *JobLabel, kubeClient crClient.Client) []*failureclass.Event {
+	pods, err := getter.ListPods(namespace, labels.Set(getJobLabels(jobLabel)).AsSelector(), kubeClient)
+	if err != nil {
+		return nil
+	}
+	resp := make([]*failureclass.Event, 0)
+	for _, pod := range pods {
+		selector := fields.Set{"involvedObject.name": pod.Name, "involvedObject.kind": setting.Pod}.AsSelector()
+		events, err := getter.ListEvents(namespace, selector, kubeClient)
+		if err != nil {
+			continue
+		}
+		for _, event := range events {
+			if event.Type != "Warning" {
+				continue
+			}
+			resp = append(resp, &failureclass.Event{Type: event.Type, Reason: event.Reason, Message: event.Message})
+		}
+		// the reason of a killed container is not always reported as an event
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.State.Terminated != nil && status.State.Terminated.Reason == "OOMKilled" {
+				resp = append(resp, &failureclass.Event{Type: "Warning", Reason: "OOMKilling", Message: "container " + status.Name + " is OOMKilled out of memory"})
+			}
+		}
+	}
+	return resp
+}
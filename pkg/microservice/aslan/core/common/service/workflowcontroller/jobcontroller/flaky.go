@@ -0,0 +1,102 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+// flakyLookbackDays is how long a flaky run makes the job retried by the flaky job policy
+const flakyLookbackDays = 30
+
+// taskScopedEnvKeys are the variables differing between the tasks even if the inputs of the job are the same
+var taskScopedEnvKeys = map[string]bool{
+	"TASK_ID":   true,
+	"TASK_URL":  true,
+	"BUILD_URL": true,
+	"IMAGE":     true,
+	"PKG_FILE":  true,
+}
+
+// getJobInputHash hashes the commits of the repos and the variables of the job, the credentials and the variables
+// scoped to the task are skipped
+func getJobInputHash(spec *commonmodels.JobTaskFreestyleSpec) string {
+	inputs := make([]string, 0)
+	for _, env := range spec.Properties.Envs {
+		if env.IsCredential || taskScopedEnvKeys[env.Key] {
+			continue
+		}
+		inputs = append(inputs, "env:"+env.Key+"="+env.Value)
+	}
+	for _, stepTask := range spec.Steps {
+		if stepTask.StepType != config.StepGit {
+			continue
+		}
+		gitSpec := &step.StepGitSpec{}
+		if err := commonmodels.IToiYaml(stepTask.Spec, gitSpec); err != nil {
+			continue
+		}
+		for _, repo := range gitSpec.Repos {
+			prs := make([]string, 0, len(repo.PRs))
+			for _, pr := range repo.PRs {
+				prs = append(prs, fmt.Sprint(pr))
+			}
+			inputs = append(inputs, fmt.Sprintf("repo:%s/%s@%s,%s,%d,%s,%s", repo.RepoOwner, repo.RepoName, repo.Branch, repo.Tag, repo.PR, strings.Join(prs, ","), repo.CommitID))
+		}
+	}
+	sort.Strings(inputs)
+
+	sum := sha256.Sum256([]byte(strings.Join(inputs, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// isFlakyRun checks the last finished run of the job with the same inputs, the run is flaky if one of them passed
+// and the other failed
+func isFlakyRun(info *commonmodels.JobInfo) (bool, error) {
+	last, err := mongodb.NewJobInfoColl().FindLastRunWithInput(info.WorkflowName, info.JobName, info.InputHash)
+	if err != nil || last == nil {
+		return false, err
+	}
+	passed := func(status string) bool { return status == string(config.StatusPassed) }
+	return passed(last.Status) != passed(info.Status), nil
+}
+
+// shouldRetryFlakyJob returns true if the failed job is not retried yet, the flaky job policy of the project
+// enables the auto retry and the job had a flaky run recently
+func shouldRetryFlakyJob(job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx) bool {
+	if job.FlakyRetried || (job.Status != config.StatusFailed && job.Status != config.StatusTimeout) {
+		return false
+	}
+	project, err := templaterepo.NewProductColl().Find(workflowCtx.ProjectName)
+	if err != nil || project.FlakyJobPolicy == nil || !project.FlakyJobPolicy.AutoRetry {
+		return false
+	}
+	since := time.Now().AddDate(0, 0, -flakyLookbackDays).Unix()
+	count, err := mongodb.NewJobInfoColl().CountFlakyRuns(workflowCtx.WorkflowName, job.Name, since)
+	return err == nil && count > 0
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"encoding/json"
+
+	networkingv1alpha3 "istio.io/api/networking/v1alpha3"
+	"istio.io/client-go/pkg/apis/networking/v1alpha3"
+)
+
+// ZadigIstioVirtualServiceLastAppliedMirror keeps the mirror of the given virtual service before the release
+const ZadigIstioVirtualServiceLastAppliedMirror = "last-applied-mirror"
+
+type istioMirror struct {
+	Mirror           *networkingv1alpha3.Destination `json:"mirror,omitempty"`
+	MirrorPercentage *networkingv1alpha3.Percent     `json:"mirror_percentage,omitempty"`
+}
+
+// setIstioMirror copies the given percentage of the traffic to the duplicate subset. The mirror on the route is kept
+// if the percentage is 0, so a mirror set by a former release job stays until the full release or the rollback.
+func setIstioMirror(route *networkingv1alpha3.HTTPRoute, host string, port *networkingv1alpha3.PortSelector, percentage int64) {
+	if percentage <= 0 {
+		return
+	}
+	route.Mirror = &networkingv1alpha3.Destination{
+		Host:   host,
+		Subset: ZadigIstioLabelDuplicate,
+		Port:   port,
+	}
+	route.MirrorPercentage = &networkingv1alpha3.Percent{Value: float64(percentage)}
+}
+
+// removeIstioMirror stops mirroring to the duplicate subset when all the traffic is routed to it, otherwise every
+// request would be served by the duplicate twice. A mirror to somewhere else is left to the restore.
+func removeIstioMirror(route *networkingv1alpha3.HTTPRoute, host string) {
+	if route.Mirror == nil || route.Mirror.Host != host || route.Mirror.Subset != ZadigIstioLabelDuplicate {
+		return
+	}
+	route.Mirror = nil
+	route.MirrorPercentage = nil
+}
+
+func saveLastAppliedMirror(vs *v1alpha3.VirtualService) error {
+	mirrorByte, err := json.Marshal(&istioMirror{
+		Mirror:           vs.Spec.Http[0].Mirror,
+		MirrorPercentage: vs.Spec.Http[0].MirrorPercentage,
+	})
+	if err != nil {
+		return err
+	}
+	vs.Annotations[ZadigIstioVirtualServiceLastAppliedMirror] = string(mirrorByte)
+	return nil
+}
+
+// restoreLastAppliedMirror restores the mirror saved before the release, virtual services released before the mirror
+// was supported have no such annotation and no mirror set by zadig
+func restoreLastAppliedMirror(vs *v1alpha3.VirtualService) error {
+	mirror := &istioMirror{}
+	if mirrorInfo, ok := vs.Annotations[ZadigIstioVirtualServiceLastAppliedMirror]; ok {
+		if err := json.Unmarshal([]byte(mirrorInfo), mirror); err != nil {
+			return err
+		}
+		delete(vs.Annotations, ZadigIstioVirtualServiceLastAppliedMirror)
+	} else {
+		mirror.Mirror = vs.Spec.Http[0].Mirror
+		mirror.MirrorPercentage = vs.Spec.Http[0].MirrorPercentage
+	}
+	vs.Spec.Http[0].Mirror = mirror.Mirror
+	vs.Spec.Http[0].MirrorPercentage = mirror.MirrorPercentage
+	return nil
+}
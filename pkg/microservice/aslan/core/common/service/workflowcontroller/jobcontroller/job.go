@@ -33,6 +33,7 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/jobpolicy"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/util"
 	"github.com/koderover/zadig/pkg/util/rand"
 )
@@ -94,6 +95,16 @@ func initJobCtl(job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTas
 		jobCtl = NewMseGrayOfflineJobCtl(job, workflowCtx, ack, logger)
 	case string(config.JobGuanceyunCheck):
 		jobCtl = NewGuanceyunCheckJobCtl(job, workflowCtx, ack, logger)
+	case string(config.JobHarborReplication):
+		jobCtl = NewHarborReplicationJobCtl(job, workflowCtx, ack, logger)
+	case string(config.JobFeatureFlag):
+		jobCtl = NewFeatureFlagJobCtl(job, workflowCtx, ack, logger)
+	case string(config.JobFeatureFlagRollback):
+		jobCtl = NewFeatureFlagRollbackJobCtl(job, workflowCtx, ack, logger)
+	case string(config.JobStaticDistribute):
+		jobCtl = NewStaticDistributeJobCtl(job, workflowCtx, ack, logger)
+	case string(config.JobHTTPCallback):
+		jobCtl = NewHTTPCallbackJobCtl(job, workflowCtx, ack, logger)
 	default:
 		jobCtl = NewFreestyleJobCtl(job, workflowCtx, ack, logger)
 	}
@@ -105,6 +116,11 @@ func runJob(ctx context.Context, job *commonmodels.JobTask, workflowCtx *commonm
 	if job.Status == config.StatusPassed {
 		return
 	}
+	// the job was started before the workflow controller restarted
+	if isJobInFlight(job) {
+		resumeJob(ctx, job, workflowCtx, logger, ack)
+		return
+	}
 	// render global variables for every job.
 	workflowCtx.GlobalContextEach(func(k, v string) bool {
 		b, _ := json.Marshal(job)
@@ -115,6 +131,7 @@ func runJob(ctx context.Context, job *commonmodels.JobTask, workflowCtx *commonm
 		}
 		return true
 	})
+	renderJobExpressions(job, workflowCtx, logger)
 	job.Status = config.StatusPrepare
 	job.StartTime = time.Now().Unix()
 	job.K8sJobName = getJobName(workflowCtx.WorkflowName, workflowCtx.TaskID)
@@ -130,7 +147,12 @@ func runJob(ctx context.Context, job *commonmodels.JobTask, workflowCtx *commonm
 			job.Status = config.StatusFailed
 			job.Error = errMsg
 		}
+		job.ResumeState = nil
 		job.EndTime = time.Now().Unix()
+		// the jobs not classified by their controllers are classified by the error
+		if len(job.FailureCategories) == 0 {
+			classifyFailure(job, nil, nil)
+		}
 		logger.Infof("finish job: %s,status: %s", job.Name, job.Status)
 		ack()
 		logger.Infof("updating job info into db...")
@@ -253,6 +275,15 @@ func jobStatusFailed(status config.Status) bool {
 	return false
 }
 
+// checkDeployTarget fails the job if the cluster and namespace it writes to are not allowed by the job policy
+func checkDeployTarget(job *commonmodels.JobTask, clusterID, namespace string, logger *zap.SugaredLogger) error {
+	if err := jobpolicy.CheckDeployTarget(job.Name, clusterID, namespace); err != nil {
+		logError(job, err.Error(), logger)
+		return err
+	}
+	return nil
+}
+
 func logError(job *commonmodels.JobTask, msg string, logger *zap.SugaredLogger) {
 	logger.Error(msg)
 	job.Status = config.StatusFailed
@@ -305,3 +336,17 @@ func getMatchedRegistries(image string, registries []*commonmodels.RegistryNames
 	}
 	return resp
 }
+
+// renderJobExpressions evaluates the expressions referring to the outputs of the previous jobs, such as
+// {{.job.build.output.IMAGE | replace ":" "-"}}, the plain references have been replaced before
+func renderJobExpressions(job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, logger *zap.SugaredLogger) {
+	variables := make(map[string]string)
+	workflowCtx.GlobalContextEach(func(k, v string) bool {
+		variables[strings.TrimSuffix(strings.TrimPrefix(k, "{{."), "}}")] = strings.Trim(v, "\n")
+		return true
+	})
+	b, _ := json.Marshal(job)
+	if err := json.Unmarshal([]byte(util.RenderExpressions(string(b), variables)), &job); err != nil {
+		logger.Errorf("unmarshal job error: %v", err)
+	}
+}
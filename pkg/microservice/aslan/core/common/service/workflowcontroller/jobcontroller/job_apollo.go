@@ -113,5 +113,6 @@ func (c *ApolloJobCtl) SaveInfo(ctx context.Context) error {
 		EndTime:             c.job.EndTime,
 		Duration:            c.job.EndTime - c.job.StartTime,
 		Status:              string(c.job.Status),
+		FailureCategories:   c.job.FailureCategories,
 	})
 }
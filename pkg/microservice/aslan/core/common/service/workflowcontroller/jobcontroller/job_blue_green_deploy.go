@@ -76,6 +76,10 @@ func (c *BlueGreenDeployJobCtl) Run(ctx context.Context) {
 
 func (c *BlueGreenDeployJobCtl) run(ctx context.Context) error {
 	var err error
+	if err := checkDeployTarget(c.job, c.jobTaskSpec.ClusterID, c.jobTaskSpec.Namespace, c.logger); err != nil {
+		c.jobTaskSpec.Events.Error(err.Error())
+		return err
+	}
 	c.kubeClient, err = kubeclient.GetKubeClient(config.HubServerAddress(), c.jobTaskSpec.ClusterID)
 	if err != nil {
 		msg := fmt.Sprintf("can't init k8s client: %v", err)
@@ -256,5 +260,6 @@ func (c *BlueGreenDeployJobCtl) SaveInfo(ctx context.Context) error {
 		EndTime:             c.job.EndTime,
 		Duration:            c.job.EndTime - c.job.StartTime,
 		Status:              string(c.job.Status),
+		FailureCategories:   c.job.FailureCategories,
 	})
 }
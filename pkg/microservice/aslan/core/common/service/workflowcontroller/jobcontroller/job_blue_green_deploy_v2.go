@@ -94,6 +94,10 @@ func (c *BlueGreenDeployV2JobCtl) run(ctx context.Context) error {
 	}
 	c.namespace = env.Namespace
 	clusterID := env.ClusterID
+	if err := checkDeployTarget(c.job, clusterID, env.Namespace, c.logger); err != nil {
+		c.jobTaskSpec.Events.Error(err.Error())
+		return err
+	}
 
 	c.kubeClient, err = kubeclient.GetKubeClient(config.HubServerAddress(), clusterID)
 	if err != nil {
@@ -245,5 +249,6 @@ func (c *BlueGreenDeployV2JobCtl) SaveInfo(ctx context.Context) error {
 		EndTime:             c.job.EndTime,
 		Duration:            c.job.EndTime - c.job.StartTime,
 		Status:              string(c.job.Status),
+		FailureCategories:   c.job.FailureCategories,
 	})
 }
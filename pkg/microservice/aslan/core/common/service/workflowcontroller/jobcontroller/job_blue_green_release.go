@@ -113,6 +113,9 @@ func (c *BlueGreenReleaseJobCtl) Run(ctx context.Context) {
 	c.job.Status = config.StatusRunning
 	c.ack()
 
+	if err := checkDeployTarget(c.job, c.jobTaskSpec.ClusterID, c.jobTaskSpec.Namespace, c.logger); err != nil {
+		return
+	}
 	var err error
 	c.kubeClient, err = kubeclient.GetKubeClient(config.HubServerAddress(), c.jobTaskSpec.ClusterID)
 	if err != nil {
@@ -163,5 +166,6 @@ func (c *BlueGreenReleaseJobCtl) SaveInfo(ctx context.Context) error {
 		EndTime:             c.job.EndTime,
 		Duration:            c.job.EndTime - c.job.StartTime,
 		Status:              string(c.job.Status),
+		FailureCategories:   c.job.FailureCategories,
 	})
 }
@@ -160,6 +160,10 @@ func (c *BlueGreenReleaseV2JobCtl) run(ctx context.Context) error {
 	c.namespace = env.Namespace
 	c.jobTaskSpec.Namespace = env.Namespace
 	clusterID := env.ClusterID
+	if err := checkDeployTarget(c.job, clusterID, env.Namespace, c.logger); err != nil {
+		c.jobTaskSpec.Events.Error(err.Error())
+		return err
+	}
 
 	c.kubeClient, err = kubeclient.GetKubeClient(config.HubServerAddress(), clusterID)
 	if err != nil {
@@ -288,5 +292,6 @@ func (c *BlueGreenReleaseV2JobCtl) SaveInfo(ctx context.Context) error {
 		EndTime:             c.job.EndTime,
 		Duration:            c.job.EndTime - c.job.StartTime,
 		Status:              string(c.job.Status),
+		FailureCategories:   c.job.FailureCategories,
 	})
 }
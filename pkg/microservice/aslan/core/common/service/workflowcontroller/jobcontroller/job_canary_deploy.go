@@ -76,10 +76,17 @@ func (c *CanaryDeployJobCtl) Run(ctx context.Context) {
 		return
 	}
 	c.wait(ctx)
+	if c.job.Status == config.StatusPassed && c.jobTaskSpec.Analysis != nil {
+		c.analyze(ctx)
+	}
 }
 
 func (c *CanaryDeployJobCtl) run(ctx context.Context) error {
 	var err error
+	if err := checkDeployTarget(c.job, c.jobTaskSpec.ClusterID, c.jobTaskSpec.Namespace, c.logger); err != nil {
+		c.jobTaskSpec.Events.Error(err.Error())
+		return err
+	}
 	c.kubeClient, err = kubeclient.GetKubeClient(config.HubServerAddress(), c.jobTaskSpec.ClusterID)
 	if err != nil {
 		msg := fmt.Sprintf("can't init k8s client: %v", err)
@@ -197,5 +204,6 @@ func (c *CanaryDeployJobCtl) SaveInfo(ctx context.Context) error {
 		EndTime:             c.job.EndTime,
 		Duration:            c.job.EndTime - c.job.StartTime,
 		Status:              string(c.job.Status),
+		FailureCategories:   c.job.FailureCategories,
 	})
 }
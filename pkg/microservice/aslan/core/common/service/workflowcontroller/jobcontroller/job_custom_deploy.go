@@ -80,6 +80,9 @@ func (c *CustomDeployJobCtl) Run(ctx context.Context) {
 
 func (c *CustomDeployJobCtl) run(ctx context.Context) error {
 	var err error
+	if err := checkDeployTarget(c.job, c.jobTaskSpec.ClusterID, c.jobTaskSpec.Namespace, c.logger); err != nil {
+		return err
+	}
 	if c.jobTaskSpec.ClusterID != "" {
 		c.kubeClient, err = kubeclient.GetKubeClient(config.HubServerAddress(), c.jobTaskSpec.ClusterID)
 		if err != nil {
@@ -318,5 +321,6 @@ func (c *CustomDeployJobCtl) SaveInfo(ctx context.Context) error {
 		EndTime:             c.job.EndTime,
 		Duration:            c.job.EndTime - c.job.StartTime,
 		Status:              string(c.job.Status),
+		FailureCategories:   c.job.FailureCategories,
 	})
 }
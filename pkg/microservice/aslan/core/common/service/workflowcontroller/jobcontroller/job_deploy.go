@@ -34,6 +34,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -43,6 +44,7 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/jobpolicy"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
 	commontypes "github.com/koderover/zadig/pkg/microservice/aslan/core/common/types"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/util"
@@ -68,6 +70,10 @@ type DeployJobCtl struct {
 	istioClient *versionedclient.Clientset
 	jobTaskSpec *commonmodels.JobTaskDeploySpec
 	ack         func()
+
+	metricSnapshotter *metricSnapshotter
+	// the strategies of the deployments overridden for the rollout of the job, keyed by name
+	originalStrategies map[string]appsv1.DeploymentStrategy
 }
 
 func NewDeployJobCtl(job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, ack func(), logger *zap.SugaredLogger) *DeployJobCtl {
@@ -92,13 +98,25 @@ func (c *DeployJobCtl) Run(ctx context.Context) {
 	c.ack()
 	c.preRun()
 	if err := c.run(ctx); err != nil {
+		c.restoreUpdateStrategies()
 		return
 	}
-	if c.jobTaskSpec.SkipCheckRunStatus {
+	if c.jobTaskSpec.SkipCheckRunStatus || c.jobTaskSpec.NoChanges {
+		c.restoreUpdateStrategies()
 		c.job.Status = config.StatusPassed
 		return
 	}
 	c.wait(ctx)
+	c.restoreUpdateStrategies()
+	if c.job.Status == config.StatusPassed && c.jobTaskSpec.RestartCheck != nil {
+		c.verifyRestarts(ctx)
+	}
+	if c.job.Status == config.StatusPassed && c.jobTaskSpec.HealthCheck != nil {
+		c.verifyHealth()
+	}
+	if c.job.Status == config.StatusPassed && c.metricSnapshotter != nil {
+		c.metricSnapshotter.finish(ctx, c.job, c.workflowCtx, c.ack, c.logger)
+	}
 }
 
 func (c *DeployJobCtl) preRun() {
@@ -127,9 +145,14 @@ func (c *DeployJobCtl) run(ctx context.Context) error {
 		logError(c.job, msg, c.logger)
 		return errors.New(msg)
 	}
+	if err := jobpolicy.CheckDeployTarget(c.job.Name, env.ClusterID, env.Namespace); err != nil {
+		logError(c.job, err.Error(), c.logger)
+		return err
+	}
 
 	c.namespace = env.Namespace
 	c.jobTaskSpec.ClusterID = env.ClusterID
+	c.metricSnapshotter, c.jobTaskSpec.MetricComparison = startMetricSnapshot(env, c.jobTaskSpec.ServiceName)
 
 	c.restConfig, err = kubeclient.GetRESTConfig(config.HubServerAddress(), c.jobTaskSpec.ClusterID)
 	if err != nil {
@@ -211,23 +234,51 @@ func (c *DeployJobCtl) run(ctx context.Context) error {
 		c.jobTaskSpec.YamlContent = updatedYaml
 		c.ack()
 
+		templates := c.recordPodTemplates(resources)
+		if c.jobTaskSpec.UpdateStrategy != nil && c.jobTaskSpec.UpdateStrategy.RestartOnly {
+			err := c.overrideUpdateStrategy(resources)
+			if err == nil {
+				err = c.restartServiceModules(resources)
+			}
+			if err != nil {
+				logError(c.job, err.Error(), c.logger)
+				return err
+			}
+			return nil
+		}
+
 		currentYaml, _, err := kube.FetchCurrentAppliedYaml(option)
 		if err != nil {
 			msg := fmt.Sprintf("get current service yaml error: %v", err)
 			logError(c.job, msg, c.logger)
 			return errors.New(msg)
 		}
+		if c.skipUnchanged(currentYaml, updatedYaml, resources, len(containers) > 0) {
+			return nil
+		}
 
 		// if not only deploy image, we will redeploy service
+		var changedConfigs map[string][]string
 		if !onlyDeployImage(c.jobTaskSpec.DeployContents) {
-			if err := c.updateSystemService(env, currentYaml, updatedYaml, c.jobTaskSpec.VariableKVs, revision, containers, updateRevision); err != nil {
-				logError(c.job, err.Error(), c.logger)
-				return err
+			// kubernetes does not restart the pods when only the configs they use are changed
+			changedConfigs, err = kube.FindConfigChangedWorkloads(currentYaml, updatedYaml)
+			if err != nil {
+				msg := fmt.Sprintf("compare service configs error: %v", err)
+				logError(c.job, msg, c.logger)
+				return errors.New(msg)
+			}
+			err = c.updateSystemService(env, currentYaml, updatedYaml, c.jobTaskSpec.VariableKVs, revision, containers, updateRevision)
+		} else {
+			// if only deploy image, we only patch image.
+			err = c.overrideUpdateStrategy(resources)
+			if err == nil {
+				err = c.updateServiceModuleImages(ctx, resources, env)
 			}
-			return nil
 		}
-		// if only deploy image, we only patch image.
-		if err := c.updateServiceModuleImages(ctx, resources, env); err != nil {
+		if err == nil {
+			err = c.restartUnchangedWorkloads(templates, changedConfigs)
+		}
+		if err != nil {
 			logError(c.job, err.Error(), c.logger)
 			return err
 		}
@@ -251,7 +302,25 @@ func (c *DeployJobCtl) run(ctx context.Context) error {
 		return errors.New(msg)
 	}
 
-	if err := c.updateServiceModuleImages(ctx, []*kube.WorkloadResource{{Type: serviceInfo.WorkloadType, Name: c.jobTaskSpec.ServiceName}}, env); err != nil {
+	resources := []*kube.WorkloadResource{{Type: serviceInfo.WorkloadType, Name: c.jobTaskSpec.ServiceName}}
+	templates := c.recordPodTemplates(resources)
+	if c.jobTaskSpec.UpdateStrategy != nil && c.jobTaskSpec.UpdateStrategy.RestartOnly {
+		err = c.overrideUpdateStrategy(resources)
+		if err == nil {
+			err = c.restartServiceModules(resources)
+		}
+	} else if c.skipUnchanged("", "", resources, true) {
+		return nil
+	} else {
+		err = c.overrideUpdateStrategy(resources)
+		if err == nil {
+			err = c.updateServiceModuleImages(ctx, resources, env)
+		}
+		if err == nil {
+			err = c.restartUnchangedWorkloads(templates, nil)
+		}
+	}
+	if err != nil {
 		logError(c.job, err.Error(), c.logger)
 		return err
 	}
@@ -271,15 +340,21 @@ func (c *DeployJobCtl) updateSystemService(env *commonmodels.Product, currentYam
 		}
 	}
 
+	// the strategy overridden by the job is only applied to this rollout
+	appliedYaml, err := injectUpdateStrategy(updatedYaml, deploymentStrategy(c.jobTaskSpec.UpdateStrategy))
+	if err != nil {
+		return err
+	}
 	unstructuredList, err := kube.CreateOrPatchResource(&kube.ResourceApplyParam{
 		ServiceName:         c.jobTaskSpec.ServiceName,
 		CurrentResourceYaml: currentYaml,
-		UpdateResourceYaml:  updatedYaml,
+		UpdateResourceYaml:  appliedYaml,
 		Informer:            c.informer,
 		KubeClient:          c.kubeClient,
 		AddZadigLabel:       addZadigLabel,
 		InjectSecrets:       true,
 		SharedEnvHandler:    nil,
+		ConfirmProtected:    c.jobTaskSpec.ConfirmProtected,
 		ProductInfo:         env}, c.logger)
 
 	if err != nil {
@@ -335,6 +410,14 @@ L:
 	for _, deploy := range deployments {
 		for _, container := range deploy.Spec.Template.Spec.Containers {
 			if container.Name == serviceModule.ServiceModule {
+				skip, err := c.checkProtectedWorkload(deploy, setting.Deployment)
+				if err != nil {
+					return err
+				}
+				if skip {
+					replaced = true
+					break L
+				}
 				err = updater.UpdateDeploymentImage(deploy.Namespace, deploy.Name, serviceModule.ServiceModule, serviceModule.Image, c.kubeClient)
 				if err != nil {
 					return fmt.Errorf("failed to update container image in %s/deployments/%s/%s: %v", env.Namespace, deploy.Name, container.Name, err)
@@ -355,6 +438,14 @@ Loop:
 	for _, sts := range statefulSets {
 		for _, container := range sts.Spec.Template.Spec.Containers {
 			if container.Name == serviceModule.ServiceModule {
+				skip, err := c.checkProtectedWorkload(sts, setting.StatefulSet)
+				if err != nil {
+					return err
+				}
+				if skip {
+					replaced = true
+					break Loop
+				}
 				err = updater.UpdateStatefulSetImage(sts.Namespace, sts.Name, serviceModule.ServiceModule, serviceModule.Image, c.kubeClient)
 				if err != nil {
 					return fmt.Errorf("failed to update container image in %s/statefulsets/%s/%s: %v", env.Namespace, sts.Name, container.Name, err)
@@ -375,6 +466,14 @@ CronLoop:
 	for _, cron := range cronJobs {
 		for _, container := range cron.Spec.JobTemplate.Spec.Template.Spec.Containers {
 			if container.Name == serviceModule.ServiceModule {
+				skip, err := c.checkProtectedWorkload(cron, setting.CronJob)
+				if err != nil {
+					return err
+				}
+				if skip {
+					replaced = true
+					break CronLoop
+				}
 				err = updater.UpdateCronJobImage(cron.Namespace, cron.Name, serviceModule.ServiceModule, serviceModule.Image, c.kubeClient, false)
 				if err != nil {
 					return fmt.Errorf("failed to update container image in %s/cronJob/%s/%s: %v", env.Namespace, cron.Name, container.Name, err)
@@ -395,6 +494,14 @@ BetaCronLoop:
 	for _, cron := range betaCronJobs {
 		for _, container := range cron.Spec.JobTemplate.Spec.Template.Spec.Containers {
 			if container.Name == serviceModule.ServiceModule {
+				skip, err := c.checkProtectedWorkload(cron, setting.CronJob)
+				if err != nil {
+					return err
+				}
+				if skip {
+					replaced = true
+					break BetaCronLoop
+				}
 				err = updater.UpdateCronJobImage(cron.Namespace, cron.Name, serviceModule.ServiceModule, serviceModule.Image, c.kubeClient, true)
 				if err != nil {
 					return fmt.Errorf("failed to update container image in %s/cronJobBeta/%s/%s: %v", env.Namespace, cron.Name, container.Name, err)
@@ -421,6 +528,16 @@ BetaCronLoop:
 	return nil
 }
 
+// checkProtectedWorkload returns true if the workload is protected in skip mode, and an error if it is protected in
+// confirm mode while the deployment is not confirmed
+func (c *DeployJobCtl) checkProtectedWorkload(obj metav1.Object, kind string) (bool, error) {
+	skip, err := kube.CheckProtectedObject(obj, kind, c.jobTaskSpec.ConfirmProtected)
+	if skip {
+		c.logger.Infof("%s/%s is protected, skip updating it", kind, obj.GetName())
+	}
+	return skip, err
+}
+
 func (c *DeployJobCtl) updateServiceModuleImages(ctx context.Context, resources []*kube.WorkloadResource, env *commonmodels.Product) error {
 	errList := new(multierror.Error)
 	wg := sync.WaitGroup{}
@@ -624,6 +741,96 @@ func (c *DeployJobCtl) wait(ctx context.Context) {
 	}
 }
 
+// verifyRestarts observes the pods of the deployed workloads for a while, the job fails if the containers restart
+// more than allowed or are killed by OOM killer since the deployment started
+func (c *DeployJobCtl) verifyRestarts(ctx context.Context) {
+	check := c.jobTaskSpec.RestartCheck
+	deployStart := time.Unix(c.job.StartTime, 0)
+
+	pods, err := c.listRelatedPods()
+	if err != nil {
+		logError(c.job, fmt.Sprintf("list pods error: %v", err), c.logger)
+		return
+	}
+	// the restarts of the pods created by the deployment are all counted
+	baseline := make(map[string]int32)
+	for _, pod := range pods {
+		if pod.CreationTimestamp.Time.Before(deployStart) {
+			baseline[pod.Name] = kube.PodRestartCount(pod)
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		c.job.Status = config.StatusCancelled
+		return
+	case <-time.After(time.Duration(check.ObserveSeconds) * time.Second):
+	}
+
+	pods, err = c.listRelatedPods()
+	if err != nil {
+		logError(c.job, fmt.Sprintf("list pods error: %v", err), c.logger)
+		return
+	}
+	restarts := kube.RestartIncrease(pods, baseline)
+	var oomKills int32
+	for _, pod := range pods {
+		oomKills += kube.PodOOMKills(pod, deployStart)
+	}
+
+	if restarts > check.MaxRestarts {
+		logError(c.job, fmt.Sprintf("%d container restarts observed after the deployment, more than the %d allowed", restarts, check.MaxRestarts), c.logger)
+		return
+	}
+	if check.FailOnOOMKill && oomKills > 0 {
+		logError(c.job, fmt.Sprintf("%d containers are killed by OOM killer after the deployment", oomKills), c.logger)
+	}
+}
+
+// verifyHealth evaluates the health of the deployed workloads after they are ready, the job fails if any of them is
+// unhealthy, or degraded if it is set to fail on degraded workloads
+func (c *DeployJobCtl) verifyHealth() {
+	workloads := make([]*kube.WorkloadResource, 0)
+	for _, resource := range c.jobTaskSpec.ReplaceResources {
+		workloads = append(workloads, &kube.WorkloadResource{Type: resource.Kind, Name: resource.Name})
+	}
+	// only the restarts since the deployment started are taken into account
+	restartWindow := time.Since(time.Unix(c.job.StartTime, 0))
+	healths, err := kube.EvaluateWorkloadsHealth(c.namespace, workloads, restartWindow, c.kubeClient)
+	if err != nil {
+		logError(c.job, fmt.Sprintf("evaluate workload health error: %v", err), c.logger)
+		return
+	}
+	messages := make([]string, 0)
+	for _, health := range healths {
+		if health.Status == kube.HealthStatusUnhealthy ||
+			health.Status == kube.HealthStatusDegraded && c.jobTaskSpec.HealthCheck.FailOnDegraded {
+			messages = append(messages, fmt.Sprintf("%s/%s is %s: %s", health.Kind, health.Name, health.Status, strings.Join(health.Messages, "; ")))
+		}
+	}
+	if len(messages) > 0 {
+		logError(c.job, fmt.Sprintf("the deployed workloads are not healthy, %s", strings.Join(messages, ", ")), c.logger)
+	}
+}
+
+func (c *DeployJobCtl) listRelatedPods() ([]*corev1.Pod, error) {
+	ret := make([]*corev1.Pod, 0)
+	podNames := sets.NewString()
+	for _, label := range c.jobTaskSpec.RelatedPodLabels {
+		pods, err := getter.ListPods(c.namespace, labels.Set(label).AsSelector(), c.kubeClient)
+		if err != nil {
+			return nil, err
+		}
+		for _, pod := range pods {
+			if !podNames.Has(pod.Name) {
+				podNames.Insert(pod.Name)
+				ret = append(ret, pod)
+			}
+		}
+	}
+	return ret, nil
+}
+
 func (c *DeployJobCtl) timeout() int {
 	if c.jobTaskSpec.Timeout == 0 {
 		c.jobTaskSpec.Timeout = setting.DeployTimeout
@@ -633,8 +840,10 @@ func (c *DeployJobCtl) timeout() int {
 
 func (c *DeployJobCtl) SaveInfo(ctx context.Context) error {
 	modules := make([]string, 0)
+	images := make([]string, 0)
 	for _, module := range c.jobTaskSpec.ServiceAndImages {
 		modules = append(modules, module.ServiceModule)
+		images = append(images, module.Image)
 	}
 	moduleList := strings.Join(modules, ",")
 	return commonrepo.NewJobInfoColl().Create(context.TODO(), &commonmodels.JobInfo{
@@ -647,11 +856,13 @@ func (c *DeployJobCtl) SaveInfo(ctx context.Context) error {
 		EndTime:             c.job.EndTime,
 		Duration:            c.job.EndTime - c.job.StartTime,
 		Status:              string(c.job.Status),
+		FailureCategories:   c.job.FailureCategories,
 
 		ServiceType:   c.jobTaskSpec.ServiceType,
 		ServiceName:   c.jobTaskSpec.ServiceName,
 		ServiceModule: moduleList,
 		TargetEnv:     c.jobTaskSpec.Env,
 		Production:    c.jobTaskSpec.Production,
+		Images:        images,
 	})
 }
@@ -0,0 +1,226 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/tool/featureflag"
+)
+
+type FeatureFlagJobCtl struct {
+	job         *commonmodels.JobTask
+	workflowCtx *commonmodels.WorkflowTaskCtx
+	logger      *zap.SugaredLogger
+	jobTaskSpec *commonmodels.JobTaskFeatureFlagSpec
+	ack         func()
+}
+
+func NewFeatureFlagJobCtl(job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, ack func(), logger *zap.SugaredLogger) *FeatureFlagJobCtl {
+	jobTaskSpec := &commonmodels.JobTaskFeatureFlagSpec{}
+	if err := commonmodels.IToi(job.Spec, jobTaskSpec); err != nil {
+		logger.Error(err)
+	}
+	job.Spec = jobTaskSpec
+	return &FeatureFlagJobCtl{
+		job:         job,
+		workflowCtx: workflowCtx,
+		logger:      logger,
+		ack:         ack,
+		jobTaskSpec: jobTaskSpec,
+	}
+}
+
+func (c *FeatureFlagJobCtl) Clean(ctx context.Context) {}
+
+// Run changes the flags one by one, every change is recorded before the next one so the flags changed before a failure
+// can still be rolled back
+func (c *FeatureFlagJobCtl) Run(ctx context.Context) {
+	c.job.Status = config.StatusRunning
+	c.ack()
+
+	client, err := newFeatureFlagClient(c.jobTaskSpec.ExternalSystemID, c.jobTaskSpec.Provider)
+	if err != nil {
+		logError(c.job, err.Error(), c.logger)
+		return
+	}
+
+	for _, change := range c.jobTaskSpec.Flags {
+		if ctx.Err() != nil {
+			c.job.Status = config.StatusCancelled
+			return
+		}
+
+		flag := &featureflag.Flag{Project: change.Project, Environment: change.Environment, Name: change.Flag}
+		previous, err := client.GetState(flag)
+		if err != nil {
+			logError(c.job, fmt.Sprintf("get state of flag %s error: %v", change.Flag, err), c.logger)
+			return
+		}
+		current := &featureflag.State{Enabled: change.Enabled, Rollout: previous.Rollout}
+		if change.Rollout != nil {
+			current.Rollout = change.Rollout
+		}
+		if err := client.SetState(flag, current); err != nil {
+			logError(c.job, fmt.Sprintf("set state of flag %s error: %v", change.Flag, err), c.logger)
+			return
+		}
+
+		change.Previous = toFeatureFlagState(previous)
+		change.Changed = true
+		c.ack()
+		err = mongodb.NewFeatureFlagRecordColl().Create(&commonmodels.FeatureFlagRecord{
+			ExternalSystemID: c.jobTaskSpec.ExternalSystemID,
+			Project:          change.Project,
+			Environment:      change.Environment,
+			Flag:             change.Flag,
+			Previous:         change.Previous,
+			Current:          toFeatureFlagState(current),
+			ProductName:      c.workflowCtx.ProjectName,
+			WorkflowName:     c.workflowCtx.WorkflowName,
+			TaskID:           c.workflowCtx.TaskID,
+			CreateTime:       time.Now().Unix(),
+		})
+		if err != nil {
+			logError(c.job, fmt.Sprintf("record the change of flag %s error: %v", change.Flag, err), c.logger)
+			return
+		}
+	}
+	c.job.Status = config.StatusPassed
+}
+
+func (c *FeatureFlagJobCtl) SaveInfo(ctx context.Context) error {
+	return mongodb.NewJobInfoColl().Create(context.TODO(), &commonmodels.JobInfo{
+		Type:                c.job.JobType,
+		WorkflowName:        c.workflowCtx.WorkflowName,
+		WorkflowDisplayName: c.workflowCtx.WorkflowDisplayName,
+		TaskID:              c.workflowCtx.TaskID,
+		ProductName:         c.workflowCtx.ProjectName,
+		StartTime:           c.job.StartTime,
+		EndTime:             c.job.EndTime,
+		Duration:            c.job.EndTime - c.job.StartTime,
+		Status:              string(c.job.Status),
+		FailureCategories:   c.job.FailureCategories,
+	})
+}
+
+type FeatureFlagRollbackJobCtl struct {
+	job         *commonmodels.JobTask
+	workflowCtx *commonmodels.WorkflowTaskCtx
+	logger      *zap.SugaredLogger
+	jobTaskSpec *commonmodels.JobTaskFeatureFlagRollbackSpec
+	ack         func()
+}
+
+func NewFeatureFlagRollbackJobCtl(job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, ack func(), logger *zap.SugaredLogger) *FeatureFlagRollbackJobCtl {
+	jobTaskSpec := &commonmodels.JobTaskFeatureFlagRollbackSpec{}
+	if err := commonmodels.IToi(job.Spec, jobTaskSpec); err != nil {
+		logger.Error(err)
+	}
+	job.Spec = jobTaskSpec
+	return &FeatureFlagRollbackJobCtl{
+		job:         job,
+		workflowCtx: workflowCtx,
+		logger:      logger,
+		ack:         ack,
+		jobTaskSpec: jobTaskSpec,
+	}
+}
+
+func (c *FeatureFlagRollbackJobCtl) Clean(ctx context.Context) {}
+
+// Run restores the flags to the states before their latest changes, the flags never changed by the feature flag jobs
+// are skipped
+func (c *FeatureFlagRollbackJobCtl) Run(ctx context.Context) {
+	c.job.Status = config.StatusRunning
+	c.ack()
+
+	client, err := newFeatureFlagClient(c.jobTaskSpec.ExternalSystemID, c.jobTaskSpec.Provider)
+	if err != nil {
+		logError(c.job, err.Error(), c.logger)
+		return
+	}
+
+	for _, target := range c.jobTaskSpec.Flags {
+		if ctx.Err() != nil {
+			c.job.Status = config.StatusCancelled
+			return
+		}
+
+		record, err := mongodb.NewFeatureFlagRecordColl().GetLatestNotRolledBack(c.jobTaskSpec.ExternalSystemID, target.Project, target.Environment, target.Flag)
+		if err == mongo.ErrNoDocuments {
+			c.logger.Infof("flag %s was not changed by the feature flag jobs, skip the rollback", target.Flag)
+			continue
+		}
+		if err != nil {
+			logError(c.job, fmt.Sprintf("find the change of flag %s error: %v", target.Flag, err), c.logger)
+			return
+		}
+
+		flag := &featureflag.Flag{Project: target.Project, Environment: target.Environment, Name: target.Flag}
+		if err := client.SetState(flag, &featureflag.State{Enabled: record.Previous.Enabled, Rollout: record.Previous.Rollout}); err != nil {
+			logError(c.job, fmt.Sprintf("restore state of flag %s error: %v", target.Flag, err), c.logger)
+			return
+		}
+		if err := mongodb.NewFeatureFlagRecordColl().MarkRolledBack(record.ID); err != nil {
+			logError(c.job, fmt.Sprintf("mark the change of flag %s rolled back error: %v", target.Flag, err), c.logger)
+			return
+		}
+		target.RestoredTo = record.Previous
+		c.ack()
+	}
+	c.job.Status = config.StatusPassed
+}
+
+func (c *FeatureFlagRollbackJobCtl) SaveInfo(ctx context.Context) error {
+	return mongodb.NewJobInfoColl().Create(context.TODO(), &commonmodels.JobInfo{
+		Type:                c.job.JobType,
+		WorkflowName:        c.workflowCtx.WorkflowName,
+		WorkflowDisplayName: c.workflowCtx.WorkflowDisplayName,
+		TaskID:              c.workflowCtx.TaskID,
+		ProductName:         c.workflowCtx.ProjectName,
+		StartTime:           c.job.StartTime,
+		EndTime:             c.job.EndTime,
+		Duration:            c.job.EndTime - c.job.StartTime,
+		Status:              string(c.job.Status),
+		FailureCategories:   c.job.FailureCategories,
+	})
+}
+
+func newFeatureFlagClient(externalSystemID, provider string) (featureflag.Client, error) {
+	system, err := mongodb.NewExternalSystemColl().GetByID(externalSystemID)
+	if err != nil {
+		return nil, fmt.Errorf("find external system %s error: %v", externalSystemID, err)
+	}
+	headers := make(map[string]string)
+	for _, header := range system.Headers {
+		headers[header.Key] = fmt.Sprint(header.Value)
+	}
+	return featureflag.NewClient(provider, system.Server, headers)
+}
+
+func toFeatureFlagState(state *featureflag.State) *commonmodels.FeatureFlagState {
+	return &commonmodels.FeatureFlagState{Enabled: state.Enabled, Rollout: state.Rollout}
+}
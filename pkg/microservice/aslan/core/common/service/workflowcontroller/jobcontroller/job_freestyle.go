@@ -35,13 +35,19 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/failureclass"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/jobpolicy"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller/stepcontroller"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workloadidentity"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/multicluster/service"
 	"github.com/koderover/zadig/pkg/setting"
 	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
 	"github.com/koderover/zadig/pkg/tool/dockerhost"
 	krkubeclient "github.com/koderover/zadig/pkg/tool/kube/client"
+	"github.com/koderover/zadig/pkg/tool/kube/getter"
 	"github.com/koderover/zadig/pkg/tool/kube/informer"
 	"github.com/koderover/zadig/pkg/tool/kube/updater"
+	"github.com/koderover/zadig/pkg/types/job"
 )
 
 const (
@@ -61,6 +67,10 @@ type FreestyleJobCtl struct {
 	paths       *string
 	jobTaskSpec *commonmodels.JobTaskFreestyleSpec
 	ack         func()
+	// logTail is the tail of the job log, the failure reason is parsed from it
+	logTail []byte
+	// podEvents are the warning events of the job pods, the failure is classified with them and the log
+	podEvents []*failureclass.Event
 }
 
 func NewFreestyleJobCtl(job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, ack func(), logger *zap.SugaredLogger) *FreestyleJobCtl {
@@ -83,16 +93,77 @@ func NewFreestyleJobCtl(job *commonmodels.JobTask, workflowCtx *commonmodels.Wor
 func (c *FreestyleJobCtl) Clean(ctx context.Context) {}
 
 func (c *FreestyleJobCtl) Run(ctx context.Context) {
+	c.runOnce(ctx)
+	classifyFailure(c.job, c.logTail, c.podEvents)
+	if ctx.Err() != nil || !shouldRetryFlakyJob(c.job, c.workflowCtx) {
+		return
+	}
+
+	// the failed attempt is recorded before the retry so that a passed retry is detected as flaky
+	c.logger.Infof("job %s failed and is known to be flaky, retrying it once", c.job.Name)
+	c.job.EndTime = time.Now().Unix()
+	if err := c.SaveInfo(ctx); err != nil {
+		c.logger.Errorf("failed to save the info of the failed attempt of job %s: %s", c.job.Name, err)
+	}
+	c.job.FlakyRetried = true
+	c.job.Status, c.job.Error = config.StatusPrepare, ""
+	c.job.StartTime, c.job.EndTime = time.Now().Unix(), 0
+	c.job.K8sJobName = getJobName(c.workflowCtx.WorkflowName, c.workflowCtx.TaskID)
+	c.logTail, c.podEvents = nil, nil
+	c.ack()
+	c.runOnce(ctx)
+	classifyFailure(c.job, c.logTail, c.podEvents)
+}
+
+func (c *FreestyleJobCtl) runOnce(ctx context.Context) {
+	if c.jobTaskSpec.Properties.Infrastructure == setting.JobVMInfrastructure {
+		c.runOnVM(ctx)
+		return
+	}
 	if err := c.prepare(ctx); err != nil {
 		return
 	}
+	c.job.Timeline = &commonmodels.JobTaskTimeline{QueuedTime: time.Now().Unix()}
+	release, err := c.acquireQuota(ctx)
+	if err != nil {
+		return
+	}
+	defer release()
 	if err := c.run(ctx); err != nil {
 		return
 	}
+	c.job.Timeline.PodCreatedTime = time.Now().Unix()
+	setJobResumeState(c.job, config.JobResumePhaseWaiting, c.jobTaskSpec.Properties.ClusterID, c.jobTaskSpec.Properties.Namespace, c.ack)
 	c.wait(ctx)
 	c.complete(ctx)
 }
 
+// Resume re-adopts the kubernetes job created before the controller restarted
+func (c *FreestyleJobCtl) Resume(ctx context.Context) bool {
+	state := c.job.ResumeState
+	if state == nil || c.jobTaskSpec.Properties.Infrastructure == setting.JobVMInfrastructure {
+		return false
+	}
+	c.jobTaskSpec.Properties.ClusterID = state.ClusterID
+	if err := c.initKubeClients(); err != nil {
+		c.logger.Errorf("failed to get the kube clients of cluster %s, err: %s", state.ClusterID, err)
+		return false
+	}
+	if _, found, err := getter.GetJob(state.Namespace, c.job.K8sJobName, c.kubeclient); err != nil || !found {
+		c.logger.Warnf("kubernetes job %s is not found, err: %v", c.job.K8sJobName, err)
+		return false
+	}
+	if err := c.initInformer(); err != nil {
+		c.logger.Error(err)
+		return false
+	}
+	if state.Phase == config.JobResumePhaseWaiting {
+		c.wait(ctx)
+	}
+	c.complete(ctx)
+	return true
+}
+
 func (c *FreestyleJobCtl) prepare(ctx context.Context) error {
 	for _, env := range c.jobTaskSpec.Properties.Envs {
 		if strings.HasPrefix(env.Value, "{{.job") && strings.HasSuffix(env.Value, "}}") {
@@ -111,6 +182,20 @@ func (c *FreestyleJobCtl) prepare(ctx context.Context) error {
 	if c.jobTaskSpec.Properties.ClusterID == "" {
 		c.jobTaskSpec.Properties.ClusterID = setting.LocalClusterID
 	}
+	// refuse to schedule onto an unhealthy cluster
+	check := service.CheckClusterSchedulable(c.jobTaskSpec.Properties.ClusterID)
+	for _, warning := range check.Warnings {
+		c.logger.Warnf("job %s: %s", c.job.Name, warning)
+	}
+	if !check.Schedulable {
+		err := fmt.Errorf("cluster %s is not schedulable: %s", c.jobTaskSpec.Properties.ClusterID, strings.Join(check.Warnings, "; "))
+		logError(c.job, err.Error(), c.logger)
+		return err
+	}
+	if err := jobpolicy.CheckJobImage(c.job.Name, getBaseImage(c.jobTaskSpec.Properties.BuildOS, c.jobTaskSpec.Properties.ImageFrom), c.jobTaskSpec.Properties.ImageFrom); err != nil {
+		logError(c.job, err.Error(), c.logger)
+		return err
+	}
 	// init step configration.
 	if err := stepcontroller.PrepareSteps(ctx, c.workflowCtx, &c.jobTaskSpec.Properties.Paths, c.job.Name, c.jobTaskSpec.Steps, c.logger); err != nil {
 		logError(c.job, err.Error(), c.logger)
@@ -120,9 +205,22 @@ func (c *FreestyleJobCtl) prepare(ctx context.Context) error {
 	return nil
 }
 
-func (c *FreestyleJobCtl) run(ctx context.Context) error {
-	// get kube client
-	hubServerAddr := config.HubServerAddress()
+func (c *FreestyleJobCtl) acquireQuota(ctx context.Context) (func(), error) {
+	spec := getResourceLimitSpec(c.jobTaskSpec.Properties.ResourceRequest, c.jobTaskSpec.Properties.ResReqSpec)
+	release, err := acquireProjectQuota(ctx, c.job, c.workflowCtx, spec, c.ack, c.logger)
+	if err != nil {
+		if ctx.Err() != nil {
+			c.job.Status = config.StatusCancelled
+			c.job.Error = err.Error()
+			return nil, err
+		}
+		logError(c.job, err.Error(), c.logger)
+		return nil, err
+	}
+	return release, nil
+}
+
+func (c *FreestyleJobCtl) initKubeClients() error {
 	switch c.jobTaskSpec.Properties.ClusterID {
 	case setting.LocalClusterID:
 		c.jobTaskSpec.Properties.Namespace = zadigconfig.Namespace()
@@ -133,9 +231,8 @@ func (c *FreestyleJobCtl) run(ctx context.Context) error {
 	default:
 		c.jobTaskSpec.Properties.Namespace = setting.AttachedClusterNamespace
 
-		crClient, clientset, restConfig, apiServer, err := GetK8sClients(hubServerAddr, c.jobTaskSpec.Properties.ClusterID)
+		crClient, clientset, restConfig, apiServer, err := GetK8sClients(config.HubServerAddress(), c.jobTaskSpec.Properties.ClusterID)
 		if err != nil {
-			logError(c.job, err.Error(), c.logger)
 			return err
 		}
 		c.kubeclient = crClient
@@ -143,6 +240,29 @@ func (c *FreestyleJobCtl) run(ctx context.Context) error {
 		c.restConfig = restConfig
 		c.apiServer = apiServer
 	}
+	return nil
+}
+
+func (c *FreestyleJobCtl) initInformer() error {
+	clientSet, err := kubeclient.GetKubeClientSet(config.HubServerAddress(), c.jobTaskSpec.Properties.ClusterID)
+	if err != nil {
+		return errors.Wrap(err, "get kube client set")
+	}
+	informer, err := informer.NewInformer(c.jobTaskSpec.Properties.ClusterID, c.jobTaskSpec.Properties.Namespace, clientSet)
+	if err != nil {
+		return errors.Wrap(err, "get informer")
+	}
+	c.informer = informer
+	return nil
+}
+
+func (c *FreestyleJobCtl) run(ctx context.Context) error {
+	// get kube client
+	hubServerAddr := config.HubServerAddress()
+	if err := c.initKubeClients(); err != nil {
+		logError(c.job, err.Error(), c.logger)
+		return err
+	}
 
 	// decide which docker host to use.
 	// TODO: do not use code in warpdrive moudule, should move to a public place
@@ -172,7 +292,13 @@ func (c *FreestyleJobCtl) run(ctx context.Context) error {
 
 	c.jobTaskSpec.Properties.DockerHost = dockerHost
 
-	jobCtxBytes, err := yaml.Marshal(BuildJobExcutorContext(c.jobTaskSpec, c.job, c.workflowCtx, c.logger))
+	jobCtx := BuildJobExcutorContext(c.jobTaskSpec, c.job, c.workflowCtx, c.logger)
+	if err := setJobWorkloadIdentity(jobCtx, c.jobTaskSpec, c.job, c.workflowCtx); err != nil {
+		msg := fmt.Sprintf("failed to issue the workload identity token: %v", err)
+		logError(c.job, msg, c.logger)
+		return errors.New(msg)
+	}
+	jobCtxBytes, err := yaml.Marshal(jobCtx)
 	if err != nil {
 		msg := fmt.Sprintf("cannot Jobexcutor.Context data: %v", err)
 		logError(c.job, msg, c.logger)
@@ -229,15 +355,9 @@ func (c *FreestyleJobCtl) run(ctx context.Context) error {
 	}
 
 	// set informer when job and cm have been created
-	clientSet, err := kubeclient.GetKubeClientSet(config.HubServerAddress(), c.jobTaskSpec.Properties.ClusterID)
-	if err != nil {
-		return errors.Wrap(err, "get kube client set")
-	}
-	informer, err := informer.NewInformer(c.jobTaskSpec.Properties.ClusterID, c.jobTaskSpec.Properties.Namespace, clientSet)
-	if err != nil {
-		return errors.Wrap(err, "get informer")
+	if err := c.initInformer(); err != nil {
+		return err
 	}
-	c.informer = informer
 	c.logger.Infof("succeed to create job %s", c.job.K8sJobName)
 	return nil
 }
@@ -250,6 +370,7 @@ func (c *FreestyleJobCtl) wait(ctx context.Context) {
 		c.job.Error = err.Error()
 	}
 	if c.job.Status == config.StatusRunning {
+		recordPodTimeline(c.job, c.jobTaskSpec.Properties.Namespace, c.kubeclient, c.logger)
 		c.ack()
 	} else {
 		return
@@ -258,6 +379,8 @@ func (c *FreestyleJobCtl) wait(ctx context.Context) {
 }
 
 func (c *FreestyleJobCtl) complete(ctx context.Context) {
+	getJobTimeline(c.job).CollectingTime = time.Now().Unix()
+	setJobResumeState(c.job, config.JobResumePhaseCollecting, c.jobTaskSpec.Properties.ClusterID, c.jobTaskSpec.Properties.Namespace, c.ack)
 	jobLabel := &JobLabel{
 		JobType: string(c.job.JobType),
 		JobName: c.job.K8sJobName,
@@ -265,6 +388,14 @@ func (c *FreestyleJobCtl) complete(ctx context.Context) {
 
 	// 清理用户取消和超时的任务
 	defer func() {
+		if c.job.Status == config.StatusFailed && c.jobTaskSpec.Properties.RetainPodOnFailure {
+			err := retainJobPod(c.job, c.workflowCtx, &c.jobTaskSpec.Properties, jobLabel)
+			if err == nil {
+				c.logger.Infof("pod of job %s is retained for %d minutes", c.job.K8sJobName, getRetainPodTTL(&c.jobTaskSpec.Properties))
+				return
+			}
+			c.logger.Errorf("failed to retain the pod of job %s: %s", c.job.K8sJobName, err)
+		}
 		go func() {
 			if err := ensureDeleteJob(c.jobTaskSpec.Properties.Namespace, jobLabel, c.kubeclient); err != nil {
 				c.logger.Error(err)
@@ -275,13 +406,19 @@ func (c *FreestyleJobCtl) complete(ctx context.Context) {
 		}()
 	}()
 
+	if c.job.Status == config.StatusFailed || c.job.Status == config.StatusTimeout {
+		c.podEvents = listPodEvents(c.jobTaskSpec.Properties.Namespace, jobLabel, c.kubeclient)
+	}
+
 	// get job outputs info from pod terminate message.
 	if err := getJobOutputFromConfigMap(c.jobTaskSpec.Properties.Namespace, c.job.Name, c.job, c.workflowCtx, c.informer); err != nil {
 		c.logger.Error(err)
 		c.job.Status, c.job.Error = config.StatusFailed, errors.Wrap(err, "get job outputs").Error()
 	}
 
-	if err := saveContainerLog(c.jobTaskSpec.Properties.Namespace, c.jobTaskSpec.Properties.ClusterID, c.workflowCtx.WorkflowName, c.job.Name, c.workflowCtx.TaskID, jobLabel, c.kubeclient); err != nil {
+	logTail, err := saveContainerLog(c.jobTaskSpec.Properties.Namespace, c.jobTaskSpec.Properties.ClusterID, c.workflowCtx.ProjectName, c.workflowCtx.WorkflowName, c.job.Name, c.workflowCtx.TaskID, jobLabel, c.kubeclient)
+	c.logTail = logTail
+	if err != nil {
 		c.logger.Error(err)
 		if c.job.Error == "" {
 			c.job.Error = err.Error()
@@ -321,11 +458,48 @@ func BuildJobExcutorContext(jobTaskSpec *commonmodels.JobTaskFreestyleSpec, job
 		Steps:         jobTaskSpec.Steps,
 		Paths:         jobTaskSpec.Properties.Paths,
 		ConfigMapName: job.K8sJobName,
+		RetainPodTTL:  getRetainPodTTL(&jobTaskSpec.Properties),
+
+		HasServiceContainers: len(jobTaskSpec.Properties.ServiceContainers) > 0,
+	}
+}
+
+// setJobWorkloadIdentity passes the OIDC token of the job in the secret envs, the aws sdks pick it up by the web
+// identity envs if the role is set
+func setJobWorkloadIdentity(jobCtx *JobContext, jobTaskSpec *commonmodels.JobTaskFreestyleSpec, jobTask *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx) error {
+	identity := jobTaskSpec.Properties.WorkloadIdentity
+	if identity == nil || !identity.Enabled {
+		return nil
+	}
+
+	audience := identity.Audience
+	if audience == "" && identity.AWSRoleARN != "" {
+		audience = workloadidentity.AWSAudience
+	}
+	token, err := workloadidentity.IssueJobToken(&workloadidentity.JobClaims{
+		Project:     workflowCtx.ProjectName,
+		Workflow:    workflowCtx.WorkflowName,
+		TaskID:      workflowCtx.TaskID,
+		Job:         jobTask.Name,
+		TaskCreator: workflowCtx.WorkflowTaskCreatorUsername,
+	}, audience, time.Duration(jobTaskSpec.Properties.Timeout)*time.Minute)
+	if err != nil {
+		return err
 	}
+
+	jobCtx.SecretEnvs = append(jobCtx.SecretEnvs, fmt.Sprintf("%s=%s", job.OIDCTokenEnv, token))
+	jobCtx.Envs = append(jobCtx.Envs, fmt.Sprintf("%s=%s", job.OIDCTokenFileEnv, job.OIDCTokenFile))
+	if identity.AWSRoleARN != "" {
+		jobCtx.Envs = append(jobCtx.Envs,
+			fmt.Sprintf("AWS_ROLE_ARN=%s", identity.AWSRoleARN),
+			fmt.Sprintf("AWS_WEB_IDENTITY_TOKEN_FILE=%s", job.OIDCTokenFile),
+		)
+	}
+	return nil
 }
 
 func (c *FreestyleJobCtl) SaveInfo(ctx context.Context) error {
-	return mongodb.NewJobInfoColl().Create(context.TODO(), &commonmodels.JobInfo{
+	info := &commonmodels.JobInfo{
 		Type:                c.job.JobType,
 		WorkflowName:        c.workflowCtx.WorkflowName,
 		WorkflowDisplayName: c.workflowCtx.WorkflowDisplayName,
@@ -335,5 +509,34 @@ func (c *FreestyleJobCtl) SaveInfo(ctx context.Context) error {
 		EndTime:             c.job.EndTime,
 		Duration:            c.job.EndTime - c.job.StartTime,
 		Status:              string(c.job.Status),
-	})
+		FailureCategories:   c.job.FailureCategories,
+	}
+	// the service of a build job is recorded for the per service statistics
+	if c.job.JobType == string(config.JobZadigBuild) {
+		for _, env := range c.jobTaskSpec.Properties.Envs {
+			switch env.Key {
+			case "SERVICE_NAME":
+				info.ServiceName = env.Value
+			case "SERVICE_MODULE":
+				info.ServiceModule = env.Value
+			}
+		}
+	}
+	if c.job.Status == config.StatusFailed || c.job.Status == config.StatusTimeout {
+		info.FailureReason = getFailureReason(c.job.Error, c.logTail)
+	}
+	if c.job.Status == config.StatusPassed || c.job.Status == config.StatusFailed || c.job.Status == config.StatusTimeout {
+		info.JobName = c.job.Name
+		info.InputHash = getJobInputHash(c.jobTaskSpec)
+		flaky, err := isFlakyRun(info)
+		if err != nil {
+			c.logger.Warnf("failed to check if job %s is flaky: %s", c.job.Name, err)
+		}
+		if flaky {
+			info.Flaky = true
+			c.job.Flaky = true
+			c.ack()
+		}
+	}
+	return mongodb.NewJobInfoColl().Create(context.TODO(), info)
 }
@@ -25,6 +25,7 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/jobpolicy"
 	"github.com/koderover/zadig/pkg/setting"
 	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
 	"github.com/koderover/zadig/pkg/tool/kube/getter"
@@ -65,6 +66,10 @@ func (c *GrayReleaseJobCtl) Run(ctx context.Context) {
 	c.job.Status = config.StatusRunning
 	c.ack()
 
+	if err := jobpolicy.CheckDeployTarget(c.job.Name, c.jobTaskSpec.ClusterID, c.jobTaskSpec.Namespace); err != nil {
+		c.Errorf("%v", err)
+		return
+	}
 	var err error
 	c.kubeClient, err = kubeclient.GetKubeClient(config.HubServerAddress(), c.jobTaskSpec.ClusterID)
 	if err != nil {
@@ -136,7 +141,7 @@ func (c *GrayReleaseJobCtl) Run(ctx context.Context) {
 			return
 		}
 		c.jobTaskSpec.Events.Info(fmt.Sprintf("origin deployment: %s replica set to %d", c.jobTaskSpec.WorkloadName, leftReplica))
-		c.job.Status = config.StatusPassed
+		c.finish(ctx)
 		return
 	}
 
@@ -205,9 +210,69 @@ func (c *GrayReleaseJobCtl) Run(ctx context.Context) {
 		return
 	}
 	c.jobTaskSpec.Events.Info(fmt.Sprintf("deployment: %s replica set to %d", c.jobTaskSpec.WorkloadName, leftReplica))
+	c.finish(ctx)
+}
+
+// finish passes the partial release, or compares the gray deployment with the origin one first if the analysis is
+// set and rolls the release back if it fails
+func (c *GrayReleaseJobCtl) finish(ctx context.Context) {
+	if c.jobTaskSpec.Analysis == nil {
+		c.job.Status = config.StatusPassed
+		return
+	}
+	c.jobTaskSpec.AnalysisResult = &commonmodels.CanaryAnalysisResult{}
+	analyzer := &canaryAnalyzer{
+		analysis:  c.jobTaskSpec.Analysis,
+		namespace: c.jobTaskSpec.Namespace,
+		canary:    c.jobTaskSpec.GrayWorkloadName,
+		baseline:  c.jobTaskSpec.WorkloadName,
+		result:    c.jobTaskSpec.AnalysisResult,
+		events:    c.jobTaskSpec.Events,
+		ack:       c.ack,
+	}
+	// the gray deployment is kept on cancel, it is left to the gray rollback job like a failed release
+	reason, cancelled := analyzer.run(ctx)
+	if cancelled {
+		c.job.Status = config.StatusCancelled
+		return
+	}
+	if reason != "" {
+		c.rollback(ctx, reason)
+		return
+	}
+	c.jobTaskSpec.AnalysisResult.Decision = commonmodels.CanaryAnalysisPromoted
+	c.jobTaskSpec.Events.Info("gray release analysis passed")
 	c.job.Status = config.StatusPassed
 }
 
+// rollback restores the replicas of the origin deployment, whose image is untouched until the full release, deletes
+// the gray deployment and fails the job
+func (c *GrayReleaseJobCtl) rollback(ctx context.Context, reason string) {
+	c.jobTaskSpec.AnalysisResult.Decision = commonmodels.CanaryAnalysisRolledBack
+	c.jobTaskSpec.Events.Info("rolling back the gray release: " + reason)
+	c.ack()
+
+	if err := updater.ScaleDeployment(c.jobTaskSpec.Namespace, c.jobTaskSpec.WorkloadName, c.jobTaskSpec.TotalReplica, c.kubeClient); err != nil {
+		c.Errorf("restore origin deployment: %s replica failed: %v", c.jobTaskSpec.WorkloadName, err)
+		return
+	}
+	// DeployTimeout has been converted to seconds by the wait
+	if status, err := waitDeploymentReady(ctx, c.jobTaskSpec.WorkloadName, c.jobTaskSpec.Namespace, c.jobTaskSpec.DeployTimeout, c.kubeClient, c.logger); err != nil {
+		c.logger.Error(err)
+		c.job.Status = status
+		c.job.Error = err.Error()
+		c.jobTaskSpec.Events.Error(err.Error())
+		return
+	}
+	c.jobTaskSpec.Events.Info(fmt.Sprintf("origin deployment: %s replica restored to %d", c.jobTaskSpec.WorkloadName, c.jobTaskSpec.TotalReplica))
+	if err := updater.DeleteDeploymentAndWaitWithTimeout(c.jobTaskSpec.Namespace, c.jobTaskSpec.GrayWorkloadName, time.Duration(c.jobTaskSpec.DeployTimeout)*time.Second, c.kubeClient); err != nil {
+		c.Errorf("delete gray deployment %s error: %v", c.jobTaskSpec.GrayWorkloadName, err)
+		return
+	}
+	c.jobTaskSpec.Events.Info(fmt.Sprintf("gray release deployment: %s was deleted", c.jobTaskSpec.GrayWorkloadName))
+	c.Errorf("%s", reason)
+}
+
 func (c *GrayReleaseJobCtl) Errorf(format string, a ...any) {
 	errMsg := fmt.Sprintf(format, a...)
 	logError(c.job, errMsg, c.logger)
@@ -234,5 +299,6 @@ func (c *GrayReleaseJobCtl) SaveInfo(ctx context.Context) error {
 		EndTime:             c.job.EndTime,
 		Duration:            c.job.EndTime - c.job.StartTime,
 		Status:              string(c.job.Status),
+		FailureCategories:   c.job.FailureCategories,
 	})
 }
@@ -0,0 +1,210 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/tool/harbor"
+)
+
+type HarborReplicationJobCtl struct {
+	job         *commonmodels.JobTask
+	workflowCtx *commonmodels.WorkflowTaskCtx
+	logger      *zap.SugaredLogger
+	jobTaskSpec *commonmodels.JobTaskHarborReplicationSpec
+	ack         func()
+}
+
+func NewHarborReplicationJobCtl(job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, ack func(), logger *zap.SugaredLogger) *HarborReplicationJobCtl {
+	jobTaskSpec := &commonmodels.JobTaskHarborReplicationSpec{}
+	if err := commonmodels.IToi(job.Spec, jobTaskSpec); err != nil {
+		logger.Error(err)
+	}
+	job.Spec = jobTaskSpec
+	return &HarborReplicationJobCtl{
+		job:         job,
+		workflowCtx: workflowCtx,
+		logger:      logger,
+		ack:         ack,
+		jobTaskSpec: jobTaskSpec,
+	}
+}
+
+func (c *HarborReplicationJobCtl) Clean(ctx context.Context) {}
+
+func (c *HarborReplicationJobCtl) Run(ctx context.Context) {
+	c.job.Status = config.StatusRunning
+	c.ack()
+
+	registry, err := mongodb.NewRegistryNamespaceColl().Find(&mongodb.FindRegOps{ID: c.jobTaskSpec.RegistryID})
+	if err != nil {
+		logError(c.job, fmt.Sprintf("find registry %s error: %v", c.jobTaskSpec.RegistryID, err), c.logger)
+		return
+	}
+	insecure := registry.AdvancedSetting != nil && !registry.AdvancedSetting.TLSEnabled
+	client := harbor.NewClient(registry.RegAddr, registry.AccessKey, registry.SecretKey, insecure)
+
+	policies, err := client.ListReplicationPolicies(c.jobTaskSpec.PolicyName)
+	if err != nil {
+		logError(c.job, fmt.Sprintf("list replication policies error: %v", err), c.logger)
+		return
+	}
+	var policy *harbor.ReplicationPolicy
+	for _, p := range policies {
+		// the name query of harbor is a fuzzy match
+		if p.Name == c.jobTaskSpec.PolicyName {
+			policy = p
+			break
+		}
+	}
+	if policy == nil {
+		logError(c.job, fmt.Sprintf("replication policy %s not found", c.jobTaskSpec.PolicyName), c.logger)
+		return
+	}
+
+	executionID, err := client.StartReplication(policy.ID)
+	if err != nil {
+		logError(c.job, fmt.Sprintf("start replication error: %v", err), c.logger)
+		return
+	}
+	c.jobTaskSpec.ExecutionID = executionID
+	c.jobTaskSpec.ExecutionStatus = harbor.ExecutionStatusInProgress
+	c.ack()
+
+	timeout := time.After(time.Duration(c.jobTaskSpec.Timeout) * time.Minute)
+	if !c.waitReplication(ctx, client, timeout) {
+		return
+	}
+
+	if c.jobTaskSpec.RetentionProject == "" {
+		c.job.Status = config.StatusPassed
+		return
+	}
+	c.runRetention(ctx, client, timeout)
+}
+
+// waitReplication polls the replication execution until it is finished, the job status is set if it is not succeeded
+func (c *HarborReplicationJobCtl) waitReplication(ctx context.Context, client *harbor.Client, timeout <-chan time.Time) bool {
+	executionID := c.jobTaskSpec.ExecutionID
+	for {
+		select {
+		case <-ctx.Done():
+			if err := client.StopReplicationExecution(executionID); err != nil {
+				c.logger.Errorf("stop replication execution error: %v", err)
+			}
+			c.job.Status = config.StatusCancelled
+			return false
+		case <-timeout:
+			if err := client.StopReplicationExecution(executionID); err != nil {
+				c.logger.Errorf("stop replication execution error: %v", err)
+			}
+			c.job.Status = config.StatusTimeout
+			return false
+		case <-time.After(time.Second * 10):
+		}
+
+		execution, err := client.GetReplicationExecution(executionID)
+		if err != nil {
+			logError(c.job, fmt.Sprintf("get replication execution error: %v", err), c.logger)
+			return false
+		}
+		c.jobTaskSpec.ExecutionStatus = execution.Status
+		c.jobTaskSpec.Total = execution.Total
+		c.jobTaskSpec.Succeed = execution.Succeed
+		c.jobTaskSpec.Failed = execution.Failed
+		c.ack()
+
+		switch execution.Status {
+		case harbor.ExecutionStatusInProgress:
+		case harbor.ExecutionStatusSucceed:
+			return true
+		default:
+			logError(c.job, fmt.Sprintf("replication execution %d %s: %s", executionID, execution.Status, execution.StatusText), c.logger)
+			return false
+		}
+	}
+}
+
+// runRetention runs the tag retention policy of the project after the images are replicated
+func (c *HarborReplicationJobCtl) runRetention(ctx context.Context, client *harbor.Client, timeout <-chan time.Time) {
+	retentionID, err := client.GetProjectRetentionID(c.jobTaskSpec.RetentionProject)
+	if err != nil {
+		logError(c.job, fmt.Sprintf("get retention of project %s error: %v", c.jobTaskSpec.RetentionProject, err), c.logger)
+		return
+	}
+	executionID, err := client.StartRetention(retentionID, false)
+	if err != nil {
+		logError(c.job, fmt.Sprintf("start retention error: %v", err), c.logger)
+		return
+	}
+	c.jobTaskSpec.RetentionExecutionID = executionID
+	c.jobTaskSpec.RetentionStatus = harbor.RetentionStatusRunning
+	c.ack()
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.job.Status = config.StatusCancelled
+			return
+		case <-timeout:
+			c.job.Status = config.StatusTimeout
+			return
+		case <-time.After(time.Second * 10):
+		}
+
+		execution, err := client.GetRetentionExecution(retentionID, executionID)
+		if err != nil {
+			logError(c.job, fmt.Sprintf("get retention execution error: %v", err), c.logger)
+			return
+		}
+		c.jobTaskSpec.RetentionStatus = execution.Status
+		c.ack()
+
+		switch execution.Status {
+		case harbor.RetentionStatusRunning:
+		case harbor.RetentionStatusSucceed:
+			c.job.Status = config.StatusPassed
+			return
+		default:
+			logError(c.job, fmt.Sprintf("retention execution %d %s", executionID, execution.Status), c.logger)
+			return
+		}
+	}
+}
+
+func (c *HarborReplicationJobCtl) SaveInfo(ctx context.Context) error {
+	return mongodb.NewJobInfoColl().Create(context.TODO(), &commonmodels.JobInfo{
+		Type:                c.job.JobType,
+		WorkflowName:        c.workflowCtx.WorkflowName,
+		WorkflowDisplayName: c.workflowCtx.WorkflowDisplayName,
+		TaskID:              c.workflowCtx.TaskID,
+		ProductName:         c.workflowCtx.ProjectName,
+		StartTime:           c.job.StartTime,
+		EndTime:             c.job.EndTime,
+		Duration:            c.job.EndTime - c.job.StartTime,
+		Status:              string(c.job.Status),
+		FailureCategories:   c.job.FailureCategories,
+	})
+}
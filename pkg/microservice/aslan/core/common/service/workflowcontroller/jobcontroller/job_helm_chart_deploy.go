@@ -30,6 +30,7 @@ import (
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/jobpolicy"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
 	"github.com/koderover/zadig/pkg/setting"
 )
@@ -80,6 +81,10 @@ func (c *HelmChartDeployJobCtl) Run(ctx context.Context) {
 		logError(c.job, msg, c.logger)
 		return
 	}
+	if err := jobpolicy.CheckDeployTarget(c.job.Name, productInfo.ClusterID, productInfo.Namespace); err != nil {
+		logError(c.job, err.Error(), c.logger)
+		return
+	}
 
 	c.namespace = productInfo.Namespace
 	c.jobTaskSpec.ClusterID = productInfo.ClusterID
@@ -133,7 +138,7 @@ func (c *HelmChartDeployJobCtl) Run(ctx context.Context) {
 
 	done := make(chan bool)
 	go func(chan bool) {
-		if err = kube.UpgradeHelmRelease(productInfo, renderSet, productChartService, nil, nil, timeOut); err != nil {
+		if err = kube.UpgradeHelmRelease(productInfo, renderSet, productChartService, nil, nil, timeOut, false); err != nil {
 			err = errors.WithMessagef(
 				err,
 				"failed to upgrade helm chart %s/%s",
@@ -181,6 +186,7 @@ func (c *HelmChartDeployJobCtl) SaveInfo(ctx context.Context) error {
 		EndTime:             c.job.EndTime,
 		Duration:            c.job.EndTime - c.job.StartTime,
 		Status:              string(c.job.Status),
+		FailureCategories:   c.job.FailureCategories,
 
 		ServiceType: setting.HelmDeployType,
 		TargetEnv:   c.jobTaskSpec.Env,
@@ -32,6 +32,7 @@ import (
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/jobpolicy"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/repository"
 	"github.com/koderover/zadig/pkg/setting"
@@ -91,9 +92,15 @@ func (c *HelmDeployJobCtl) Run(ctx context.Context) {
 		logError(c.job, msg, c.logger)
 		return
 	}
+	if err := jobpolicy.CheckDeployTarget(c.job.Name, productInfo.ClusterID, productInfo.Namespace); err != nil {
+		logError(c.job, err.Error(), c.logger)
+		return
+	}
 
 	c.namespace = productInfo.Namespace
 	c.jobTaskSpec.ClusterID = productInfo.ClusterID
+	snapshotter, metricComparison := startMetricSnapshot(productInfo, c.jobTaskSpec.ServiceName)
+	c.jobTaskSpec.MetricComparison = metricComparison
 
 	updateServiceRevision := false
 	if slices.Contains(c.jobTaskSpec.DeployContents, config.DeployConfig) && c.jobTaskSpec.UpdateConfig {
@@ -197,6 +204,18 @@ func (c *HelmDeployJobCtl) Run(ctx context.Context) {
 	c.jobTaskSpec.UserSuppliedValue = c.jobTaskSpec.VariableYaml
 	c.ack()
 
+	if c.jobTaskSpec.SkipUnchanged {
+		unchanged, err := c.releaseUnchanged(productInfo, renderSet, productService, svcTemplate, param.Images, curProdSvcRevision)
+		if err != nil {
+			c.logger.Warnf("failed to compare helm release of service %s with the live state: %v", c.jobTaskSpec.ServiceName, err)
+		} else if unchanged {
+			c.logger.Infof("helm release of service %s is not changed, skip deploying it", c.jobTaskSpec.ServiceName)
+			c.jobTaskSpec.NoChanges = true
+			c.job.Status = config.StatusPassed
+			return
+		}
+	}
+
 	c.logger.Infof("start helm deploy, productName %s serviceName %s namespace %s, images %v variableYaml %s overrideValues: %s updateServiceRevision %v",
 		c.workflowCtx.ProjectName, c.jobTaskSpec.ServiceName, c.namespace, images, variableYaml, chartInfo.OverrideValues, updateServiceRevision)
 
@@ -204,7 +223,7 @@ func (c *HelmDeployJobCtl) Run(ctx context.Context) {
 
 	done := make(chan bool)
 	go func(chan bool) {
-		if err = kube.UpgradeHelmRelease(productInfo, renderSet, productService, svcTemplate, param.Images, param.Timeout); err != nil {
+		if err = kube.UpgradeHelmRelease(productInfo, renderSet, productService, svcTemplate, param.Images, param.Timeout, c.jobTaskSpec.ConfirmProtected); err != nil {
 			err = errors.WithMessagef(
 				err,
 				"failed to upgrade helm chart %s/%s",
@@ -232,6 +251,9 @@ func (c *HelmDeployJobCtl) Run(ctx context.Context) {
 	}
 
 	c.job.Status = config.StatusPassed
+	if snapshotter != nil {
+		snapshotter.finish(ctx, c.job, c.workflowCtx, c.ack, c.logger)
+	}
 }
 
 func (c *HelmDeployJobCtl) timeout() int {
@@ -243,8 +265,10 @@ func (c *HelmDeployJobCtl) timeout() int {
 
 func (c *HelmDeployJobCtl) SaveInfo(ctx context.Context) error {
 	modules := make([]string, 0)
+	images := make([]string, 0)
 	for _, module := range c.jobTaskSpec.ImageAndModules {
 		modules = append(modules, module.ServiceModule)
+		images = append(images, module.Image)
 	}
 	moduleList := strings.Join(modules, ",")
 	return commonrepo.NewJobInfoColl().Create(context.TODO(), &commonmodels.JobInfo{
@@ -257,12 +281,14 @@ func (c *HelmDeployJobCtl) SaveInfo(ctx context.Context) error {
 		EndTime:             c.job.EndTime,
 		Duration:            c.job.EndTime - c.job.StartTime,
 		Status:              string(c.job.Status),
+		FailureCategories:   c.job.FailureCategories,
 
 		ServiceType:   c.jobTaskSpec.ServiceType,
 		ServiceName:   c.jobTaskSpec.ServiceName,
 		TargetEnv:     c.jobTaskSpec.Env,
 		ServiceModule: moduleList,
 		Production:    c.jobTaskSpec.IsProduction,
+		Images:        images,
 	})
 }
 
@@ -0,0 +1,300 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/tidwall/gjson"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/tool/truststore"
+	"github.com/koderover/zadig/pkg/types/job"
+)
+
+const (
+	// http callback job outputs key
+	HTTPSTATUSCODEKEY = "STATUS_CODE"
+
+	// the response body larger than it is truncated in the job task
+	maxHTTPCallbackResponseSize = 4096
+	// the response body larger than it fails the request, it is read for the assertion and the outputs
+	maxHTTPCallbackReadSize = 1 << 20
+	// the interval between the retries is no less than it so that the downstream system is not flooded
+	minHTTPCallbackRetryInterval = 5 * time.Second
+)
+
+type HTTPCallbackJobCtl struct {
+	job         *commonmodels.JobTask
+	workflowCtx *commonmodels.WorkflowTaskCtx
+	logger      *zap.SugaredLogger
+	jobTaskSpec *commonmodels.JobTaskHTTPCallbackSpec
+	ack         func()
+}
+
+func NewHTTPCallbackJobCtl(job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, ack func(), logger *zap.SugaredLogger) *HTTPCallbackJobCtl {
+	jobTaskSpec := &commonmodels.JobTaskHTTPCallbackSpec{}
+	if err := commonmodels.IToi(job.Spec, jobTaskSpec); err != nil {
+		logger.Error(err)
+	}
+	job.Spec = jobTaskSpec
+	return &HTTPCallbackJobCtl{
+		job:         job,
+		workflowCtx: workflowCtx,
+		logger:      logger,
+		ack:         ack,
+		jobTaskSpec: jobTaskSpec,
+	}
+}
+
+func (c *HTTPCallbackJobCtl) Clean(ctx context.Context) {}
+
+func (c *HTTPCallbackJobCtl) Run(ctx context.Context) {
+	c.job.Status = config.StatusRunning
+	c.ack()
+
+	if c.jobTaskSpec.Body != "" && !json.Valid([]byte(c.jobTaskSpec.Body)) {
+		logError(c.job, fmt.Sprintf("the rendered body is not a valid json: %s", c.jobTaskSpec.Body), c.logger)
+		return
+	}
+
+	tlsConfig := truststore.TLSConfig(truststore.IntegrationWebhook)
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	}
+	tlsConfig.InsecureSkipVerify = tlsConfig.InsecureSkipVerify || c.jobTaskSpec.InsecureSkipVerify
+	proxy := http.ProxyFromEnvironment
+	proxyAddr, err := mongodb.NewProxyColl().GetIntegrationProxyURL(commonmodels.ProxyIntegrationWebhook)
+	if err != nil {
+		logError(c.job, fmt.Sprintf("failed to get the webhook proxy: %s", err), c.logger)
+		return
+	}
+	if proxyAddr != "" {
+		proxyURL, err := url.Parse(proxyAddr)
+		if err != nil {
+			logError(c.job, fmt.Sprintf("invalid webhook proxy: %s", err), c.logger)
+			return
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+	deniedNetworks := getHTTPCallbackDeniedNetworks(c.logger)
+	if err := deniedNetworks.checkHost(ctx, c.jobTaskSpec.URL); err != nil {
+		logError(c.job, err.Error(), c.logger)
+		return
+	}
+	// the resolved address is checked again on dialing in case the dns record changes after the check
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			return deniedNetworks.checkIP(net.ParseIP(host))
+		},
+	}
+	client := &http.Client{
+		Timeout: time.Duration(c.jobTaskSpec.Timeout) * time.Second,
+		Transport: &http.Transport{
+			Proxy:           proxy,
+			DialContext:     dialer.DialContext,
+			TLSClientConfig: tlsConfig,
+		},
+	}
+	interval := time.Duration(c.jobTaskSpec.RetryInterval) * time.Second
+	if interval < minHTTPCallbackRetryInterval {
+		interval = minHTTPCallbackRetryInterval
+	}
+	for attempt := 0; attempt <= c.jobTaskSpec.Retry; attempt++ {
+		if attempt > 0 {
+			c.logger.Warnf("http callback job %s failed: %s, retry after %s", c.job.Name, err, interval)
+			select {
+			case <-ctx.Done():
+				c.job.Status = config.StatusCancelled
+				return
+			case <-time.After(interval):
+			}
+			interval *= 2
+		}
+
+		c.jobTaskSpec.Attempts = attempt + 1
+		var body []byte
+		body, err = c.request(ctx, client)
+		c.ack()
+		if err == nil {
+			c.writeOutputs(body)
+			c.job.Status = config.StatusPassed
+			return
+		}
+		if ctx.Err() != nil {
+			c.job.Status = config.StatusCancelled
+			return
+		}
+	}
+	logError(c.job, fmt.Sprintf("http callback failed after %d attempts: %s", c.jobTaskSpec.Attempts, err), c.logger)
+}
+
+// request sends the request and checks the response by the assertion, the response body is returned
+func (c *HTTPCallbackJobCtl) request(ctx context.Context, client *http.Client) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, c.jobTaskSpec.Method, c.jobTaskSpec.URL, bytes.NewReader([]byte(c.jobTaskSpec.Body)))
+	if err != nil {
+		return nil, err
+	}
+	if c.jobTaskSpec.Body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for _, header := range c.jobTaskSpec.Headers {
+		req.Header.Set(header.Key, header.Value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPCallbackReadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxHTTPCallbackReadSize {
+		return nil, fmt.Errorf("response body exceeds %d bytes", maxHTTPCallbackReadSize)
+	}
+
+	c.jobTaskSpec.StatusCode = resp.StatusCode
+	c.jobTaskSpec.ResponseBody = string(body)
+	if len(body) > maxHTTPCallbackResponseSize {
+		c.jobTaskSpec.ResponseBody = string(body[:maxHTTPCallbackResponseSize]) + "..."
+	}
+	return body, checkHTTPCallbackAssertion(c.jobTaskSpec.Assertion, resp.StatusCode, body)
+}
+
+// httpCallbackDeniedNetworks are the networks the http callback jobs can not request, so that the jobs can not
+// reach the cloud metadata service or the zadig services in the cluster
+type httpCallbackDeniedNetworks []*net.IPNet
+
+func getHTTPCallbackDeniedNetworks(logger *zap.SugaredLogger) httpCallbackDeniedNetworks {
+	resp := make(httpCallbackDeniedNetworks, 0)
+	for _, cidr := range config.HTTPCallbackDeniedCIDRs() {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			logger.Errorf("invalid denied cidr %s of the http callback jobs: %s", cidr, err)
+			continue
+		}
+		resp = append(resp, network)
+	}
+	return resp
+}
+
+func (n httpCallbackDeniedNetworks) checkIP(ip net.IP) error {
+	if ip == nil {
+		return fmt.Errorf("invalid ip address")
+	}
+	for _, network := range n {
+		if network.Contains(ip) {
+			return fmt.Errorf("address %s is in the denied network %s", ip, network)
+		}
+	}
+	return nil
+}
+
+// checkHost checks the addresses of the url host, the request is denied if the host can not be resolved
+func (n httpCallbackDeniedNetworks) checkHost(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid url %s: %s", rawURL, err)
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Errorf("url %s is not allowed, failed to resolve the host: %s", rawURL, err)
+	}
+	for _, addr := range addrs {
+		if err := n.checkIP(addr.IP); err != nil {
+			return fmt.Errorf("url %s is not allowed: %s", rawURL, err)
+		}
+	}
+	return nil
+}
+
+func checkHTTPCallbackAssertion(assertion *commonmodels.HTTPCallbackAssertion, statusCode int, body []byte) error {
+	if assertion == nil || len(assertion.StatusCodes) == 0 {
+		if statusCode < 200 || statusCode >= 300 {
+			return fmt.Errorf("unexpected status code %d", statusCode)
+		}
+	} else {
+		expected := false
+		for _, code := range assertion.StatusCodes {
+			if code == statusCode {
+				expected = true
+				break
+			}
+		}
+		if !expected {
+			return fmt.Errorf("status code %d is not in %v", statusCode, assertion.StatusCodes)
+		}
+	}
+	if assertion == nil {
+		return nil
+	}
+
+	if assertion.BodyContains != "" && !strings.Contains(string(body), assertion.BodyContains) {
+		return fmt.Errorf("response body does not contain %q", assertion.BodyContains)
+	}
+	if assertion.JSONPath != "" {
+		if value := gjson.GetBytes(body, assertion.JSONPath).String(); value != assertion.JSONValue {
+			return fmt.Errorf("value of %s in the response is %q, expected %q", assertion.JSONPath, value, assertion.JSONValue)
+		}
+	}
+	return nil
+}
+
+func (c *HTTPCallbackJobCtl) writeOutputs(body []byte) {
+	c.workflowCtx.GlobalContextSet(job.GetJobOutputKey(c.job.Key, HTTPSTATUSCODEKEY), strconv.Itoa(c.jobTaskSpec.StatusCode))
+	for _, output := range c.jobTaskSpec.Outputs {
+		c.workflowCtx.GlobalContextSet(job.GetJobOutputKey(c.job.Key, output.Name), gjson.GetBytes(body, output.JSONPath).String())
+	}
+}
+
+func (c *HTTPCallbackJobCtl) SaveInfo(ctx context.Context) error {
+	return mongodb.NewJobInfoColl().Create(context.TODO(), &commonmodels.JobInfo{
+		Type:                c.job.JobType,
+		WorkflowName:        c.workflowCtx.WorkflowName,
+		WorkflowDisplayName: c.workflowCtx.WorkflowDisplayName,
+		TaskID:              c.workflowCtx.TaskID,
+		ProductName:         c.workflowCtx.ProjectName,
+		StartTime:           c.job.StartTime,
+		EndTime:             c.job.EndTime,
+		Duration:            c.job.EndTime - c.job.StartTime,
+		Status:              string(c.job.Status),
+		FailureCategories:   c.job.FailureCategories,
+	})
+}
@@ -31,6 +31,7 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/jobpolicy"
 	"github.com/koderover/zadig/pkg/setting"
 	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
 	"github.com/koderover/zadig/pkg/tool/kube/getter"
@@ -90,6 +91,10 @@ func (c *IstioReleaseJobCtl) Run(ctx context.Context) {
 	c.job.Status = config.StatusRunning
 	c.ack()
 
+	if err := jobpolicy.CheckDeployTarget(c.job.Name, c.jobTaskSpec.ClusterID, c.jobTaskSpec.Namespace); err != nil {
+		c.Errorf("%v", err)
+		return
+	}
 	var err error
 	c.kubeClient, err = kubeclient.GetKubeClient(config.HubServerAddress(), c.jobTaskSpec.ClusterID)
 	if err != nil {
@@ -291,7 +296,12 @@ func (c *IstioReleaseJobCtl) Run(ctx context.Context) {
 				return
 			}
 			vs.Annotations[ZadigIstioVirtualServiceLastAppliedRoutes] = string(routeByte)
+			if err := saveLastAppliedMirror(vs); err != nil {
+				c.Errorf("failed to parse mirror information in virtual service, error: %s", err)
+				return
+			}
 
+			setIstioMirror(vs.Spec.Http[0], c.jobTaskSpec.Targets.Host, vs.Spec.Http[0].Route[0].Destination.Port, c.jobTaskSpec.MirrorPercentage)
 			vs.Spec.Http[0].Route = newHTTPRoutingRules
 			c.Infof("Modifying Virtual Service: %s", c.jobTaskSpec.Targets.VirtualServiceName)
 			c.ack()
@@ -325,6 +335,7 @@ func (c *IstioReleaseJobCtl) Run(ctx context.Context) {
 			httpRoutes = append(httpRoutes, &networkingv1alpha3.HTTPRoute{
 				Route: newHTTPRoutingRules,
 			})
+			setIstioMirror(httpRoutes[0], c.jobTaskSpec.Targets.Host, nil, c.jobTaskSpec.MirrorPercentage)
 
 			// create zadig's own virtual service
 			zadigVirtualService := &v1alpha3.VirtualService{
@@ -379,6 +390,11 @@ func (c *IstioReleaseJobCtl) Run(ctx context.Context) {
 			},
 			Weight: int32(c.jobTaskSpec.Weight),
 		})
+		if c.jobTaskSpec.Weight == 100 {
+			removeIstioMirror(vs.Spec.Http[0], c.jobTaskSpec.Targets.Host)
+		} else {
+			setIstioMirror(vs.Spec.Http[0], c.jobTaskSpec.Targets.Host, vs.Spec.Http[0].Route[0].Destination.Port, c.jobTaskSpec.MirrorPercentage)
+		}
 		vs.Spec.Http[0].Route = newHTTPRoutingRules
 		c.Infof("Modifying Virtual Service: %s", c.jobTaskSpec.Targets.VirtualServiceName)
 		c.ack()
@@ -450,6 +466,10 @@ func (c *IstioReleaseJobCtl) Run(ctx context.Context) {
 					return
 				}
 				modifiedVS.Spec.Http[0].Route = route
+				if err := restoreLastAppliedMirror(modifiedVS); err != nil {
+					c.Errorf("failed to get the last applied mirror info, error: %s", err)
+					return
+				}
 				c.Infof("switching the queries back to the original workload on virtual service: %s", vs.Name)
 				c.ack()
 				_, err = istioClient.VirtualServices(c.jobTaskSpec.Namespace).Update(context.TODO(), modifiedVS, v1.UpdateOptions{})
@@ -532,5 +552,6 @@ func (c *IstioReleaseJobCtl) SaveInfo(ctx context.Context) error {
 		EndTime:             c.job.EndTime,
 		Duration:            c.job.EndTime - c.job.StartTime,
 		Status:              string(c.job.Status),
+		FailureCategories:   c.job.FailureCategories,
 	})
 }
@@ -68,6 +68,9 @@ func (c *IstioRollbackJobCtl) Run(ctx context.Context) {
 	c.job.Status = config.StatusRunning
 	c.ack()
 
+	if err := checkDeployTarget(c.job, c.jobTaskSpec.ClusterID, c.jobTaskSpec.Namespace, c.logger); err != nil {
+		return
+	}
 	var err error
 
 	// initialize istio client
@@ -137,6 +140,10 @@ func (c *IstioRollbackJobCtl) Run(ctx context.Context) {
 
 		c.logger.Infof("rolling back virtual service: %s", vs.Name)
 		vs.Spec.Http[0].Route = route
+		if err := restoreLastAppliedMirror(vs); err != nil {
+			logError(c.job, fmt.Sprintf("failed to unmarshal original mirror information, error: %s", err), c.logger)
+			return
+		}
 		_, err = istioClient.VirtualServices(c.jobTaskSpec.Namespace).Update(context.TODO(), vs, v1.UpdateOptions{})
 		if err != nil {
 			logError(c.job, fmt.Sprintf("update virtual service: %s failed, error: %s", vs.Name, err), c.logger)
@@ -215,5 +222,6 @@ func (c *IstioRollbackJobCtl) SaveInfo(ctx context.Context) error {
 		EndTime:             c.job.EndTime,
 		Duration:            c.job.EndTime - c.job.StartTime,
 		Status:              string(c.job.Status),
+		FailureCategories:   c.job.FailureCategories,
 	})
 }
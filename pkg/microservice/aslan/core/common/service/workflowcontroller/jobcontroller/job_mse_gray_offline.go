@@ -86,6 +86,9 @@ func (c *MseGrayOfflineJobCtl) Run(ctx context.Context) {
 	}
 	c.jobTaskSpec.Namespace = env.Namespace
 	clusterID := env.ClusterID
+	if err := checkDeployTarget(c.job, clusterID, env.Namespace, c.logger); err != nil {
+		return
+	}
 
 	c.restConfig, err = kubeclient.GetRESTConfig(config.HubServerAddress(), clusterID)
 	if err != nil {
@@ -297,5 +300,6 @@ func (c *MseGrayOfflineJobCtl) SaveInfo(ctx context.Context) error {
 		EndTime:             c.job.EndTime,
 		Duration:            c.job.EndTime - c.job.StartTime,
 		Status:              string(c.job.Status),
+		FailureCategories:   c.job.FailureCategories,
 	})
 }
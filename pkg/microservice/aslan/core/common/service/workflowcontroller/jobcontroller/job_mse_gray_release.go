@@ -90,6 +90,9 @@ func (c *MseGrayReleaseJobCtl) Run(ctx context.Context) {
 	}
 	c.namespace = env.Namespace
 	clusterID := env.ClusterID
+	if err := checkDeployTarget(c.job, clusterID, env.Namespace, c.logger); err != nil {
+		return
+	}
 
 	c.restConfig, err = kubeclient.GetRESTConfig(config.HubServerAddress(), clusterID)
 	if err != nil {
@@ -279,5 +282,6 @@ func (c *MseGrayReleaseJobCtl) SaveInfo(ctx context.Context) error {
 		EndTime:             c.job.EndTime,
 		Duration:            c.job.EndTime - c.job.StartTime,
 		Status:              string(c.job.Status),
+		FailureCategories:   c.job.FailureCategories,
 	})
 }
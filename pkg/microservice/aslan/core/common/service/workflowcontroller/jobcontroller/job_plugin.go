@@ -82,9 +82,11 @@ func (c *PluginJobCtl) Clean(ctx context.Context) {}
 
 func (c *PluginJobCtl) Run(ctx context.Context) {
 	c.prepare(ctx)
+	c.job.Timeline = &commonmodels.JobTaskTimeline{QueuedTime: time.Now().Unix()}
 	if err := c.run(ctx); err != nil {
 		return
 	}
+	c.job.Timeline.PodCreatedTime = time.Now().Unix()
 	c.wait(ctx)
 	c.complete(ctx)
 }
@@ -156,6 +158,7 @@ func (c *PluginJobCtl) wait(ctx context.Context) {
 		c.logger.Errorf("wait job start error: %v", err)
 	}
 	if c.job.Status == config.StatusRunning {
+		recordPodTimeline(c.job, c.jobTaskSpec.Properties.Namespace, c.kubeclient, c.logger)
 		c.ack()
 	} else {
 		return
@@ -165,6 +168,7 @@ func (c *PluginJobCtl) wait(ctx context.Context) {
 }
 
 func (c *PluginJobCtl) complete(ctx context.Context) {
+	getJobTimeline(c.job).CollectingTime = time.Now().Unix()
 	jobLabel := &JobLabel{
 		JobType: string(c.job.JobType),
 		JobName: c.job.K8sJobName,
@@ -185,7 +189,7 @@ func (c *PluginJobCtl) complete(ctx context.Context) {
 		c.job.Error = err.Error()
 	}
 
-	if err := saveContainerLog(c.jobTaskSpec.Properties.Namespace, c.jobTaskSpec.Properties.ClusterID, c.workflowCtx.WorkflowName, c.job.Name, c.workflowCtx.TaskID, jobLabel, c.kubeclient); err != nil {
+	if _, err := saveContainerLog(c.jobTaskSpec.Properties.Namespace, c.jobTaskSpec.Properties.ClusterID, c.workflowCtx.ProjectName, c.workflowCtx.WorkflowName, c.job.Name, c.workflowCtx.TaskID, jobLabel, c.kubeclient); err != nil {
 		c.logger.Error(err)
 		if c.job.Error == "" {
 			c.job.Error = err.Error()
@@ -205,5 +209,6 @@ func (c *PluginJobCtl) SaveInfo(ctx context.Context) error {
 		EndTime:             c.job.EndTime,
 		Duration:            c.job.EndTime - c.job.StartTime,
 		Status:              string(c.job.Status),
+		FailureCategories:   c.job.FailureCategories,
 	})
 }
@@ -0,0 +1,208 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/cdn"
+	s3tool "github.com/koderover/zadig/pkg/tool/s3"
+	fsutil "github.com/koderover/zadig/pkg/util/fs"
+)
+
+type StaticDistributeJobCtl struct {
+	job         *commonmodels.JobTask
+	workflowCtx *commonmodels.WorkflowTaskCtx
+	logger      *zap.SugaredLogger
+	jobTaskSpec *commonmodels.JobTaskStaticDistributeSpec
+	ack         func()
+}
+
+func NewStaticDistributeJobCtl(job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, ack func(), logger *zap.SugaredLogger) *StaticDistributeJobCtl {
+	jobTaskSpec := &commonmodels.JobTaskStaticDistributeSpec{}
+	if err := commonmodels.IToi(job.Spec, jobTaskSpec); err != nil {
+		logger.Error(err)
+	}
+	job.Spec = jobTaskSpec
+	return &StaticDistributeJobCtl{
+		job:         job,
+		workflowCtx: workflowCtx,
+		logger:      logger,
+		ack:         ack,
+		jobTaskSpec: jobTaskSpec,
+	}
+}
+
+func (c *StaticDistributeJobCtl) Clean(ctx context.Context) {}
+
+func (c *StaticDistributeJobCtl) Run(ctx context.Context) {
+	c.job.Status = config.StatusRunning
+	c.ack()
+
+	sourceStorage, err := mongodb.NewS3StorageColl().FindDefault()
+	if err != nil {
+		logError(c.job, fmt.Sprintf("find default object storage error: %v", err), c.logger)
+		return
+	}
+	destStorage, err := mongodb.NewS3StorageColl().Find(c.jobTaskSpec.ObjectStorageID)
+	if err != nil {
+		logError(c.job, fmt.Sprintf("find object storage %s error: %v", c.jobTaskSpec.ObjectStorageID, err), c.logger)
+		return
+	}
+	sourceClient, err := newS3Client(sourceStorage)
+	if err != nil {
+		logError(c.job, fmt.Sprintf("create s3 client of the default object storage error: %v", err), c.logger)
+		return
+	}
+	destClient, err := newS3Client(destStorage)
+	if err != nil {
+		logError(c.job, fmt.Sprintf("create s3 client of object storage %s error: %v", destStorage.Bucket, err), c.logger)
+		return
+	}
+
+	for _, target := range c.jobTaskSpec.Targets {
+		select {
+		case <-ctx.Done():
+			c.job.Status = config.StatusCancelled
+			return
+		default:
+		}
+		target.Status = string(config.StatusRunning)
+		c.ack()
+		sourceKey := path.Join(sourceStorage.Subfolder, target.SourceKey)
+		if err := c.distribute(target, sourceClient, sourceStorage.Bucket, sourceKey, destClient, destStorage); err != nil {
+			target.Status = string(config.StatusFailed)
+			logError(c.job, fmt.Sprintf("distribute %s/%s error: %v", target.ServiceName, target.ServiceModule, err), c.logger)
+			return
+		}
+		target.Status = string(config.StatusPassed)
+		c.ack()
+	}
+
+	if purge := c.jobTaskSpec.CDNPurge; purge != nil && purge.Enabled {
+		paths := purge.Paths
+		if len(paths) == 0 {
+			for _, target := range c.jobTaskSpec.Targets {
+				paths = append(paths, path.Join("/", target.DestPath, "*"))
+			}
+		}
+		purgeID, err := cdn.Purge(&cdn.PurgeArgs{
+			Provider:       purge.Provider,
+			DistributionID: purge.DistributionID,
+			AK:             destStorage.Ak,
+			SK:             destStorage.Sk,
+			WebhookURL:     purge.WebhookURL,
+			Paths:          paths,
+		})
+		if err != nil {
+			logError(c.job, fmt.Sprintf("purge cdn cache error: %v", err), c.logger)
+			return
+		}
+		c.jobTaskSpec.PurgeID = purgeID
+	}
+	c.job.Status = config.StatusPassed
+}
+
+// distribute downloads the archived package, extracts it if it is a tarball and uploads the files to the dest storage
+func (c *StaticDistributeJobCtl) distribute(target *commonmodels.StaticDistributeTaskTarget, sourceClient *s3tool.Client, sourceBucket, sourceKey string, destClient *s3tool.Client, destStorage *commonmodels.S3Storage) error {
+	tmpDir, err := os.MkdirTemp("", "static-distribute")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	packageFile := filepath.Join(tmpDir, path.Base(sourceKey))
+	if err := sourceClient.Download(sourceBucket, sourceKey, packageFile); err != nil {
+		return fmt.Errorf("download package %s, make sure the file archive of the build is configured: %v", sourceKey, err)
+	}
+	destDir := path.Join(destStorage.Subfolder, target.DestPath)
+	if !strings.HasSuffix(packageFile, ".tar.gz") && !strings.HasSuffix(packageFile, ".tgz") {
+		target.FileCount = 1
+		name := filepath.Base(packageFile)
+		return destClient.UploadWithOption(destStorage.Bucket, packageFile, path.Join(destDir, name), &s3tool.UploadOption{
+			CacheControl: c.getCacheControl(name),
+		})
+	}
+
+	extractDir := filepath.Join(tmpDir, "extract")
+	if err := os.MkdirAll(extractDir, 0755); err != nil {
+		return err
+	}
+	if err := fsutil.Untar(packageFile, extractDir); err != nil {
+		return fmt.Errorf("extract package %s: %v", sourceKey, err)
+	}
+	srcDir := filepath.Join(extractDir, target.SourcePath)
+	if !fsutil.IsSubPath(extractDir, srcDir) {
+		return fmt.Errorf("source path %s is out of the package", target.SourcePath)
+	}
+	return fs.WalkDir(os.DirFS(srcDir), ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if err := destClient.UploadWithOption(destStorage.Bucket, filepath.Join(srcDir, p), path.Join(destDir, p), &s3tool.UploadOption{
+			CacheControl: c.getCacheControl(p),
+		}); err != nil {
+			return err
+		}
+		target.FileCount++
+		return nil
+	})
+}
+
+func (c *StaticDistributeJobCtl) getCacheControl(file string) string {
+	for _, rule := range c.jobTaskSpec.CacheControlRules {
+		if matched, _ := path.Match(rule.Pattern, file); matched {
+			return rule.CacheControl
+		}
+	}
+	return c.jobTaskSpec.CacheControl
+}
+
+func newS3Client(storage *commonmodels.S3Storage) (*s3tool.Client, error) {
+	forcedPathStyle := true
+	if storage.Provider == setting.ProviderSourceAli {
+		forcedPathStyle = false
+	}
+	return s3tool.NewClient(storage.Endpoint, storage.Ak, storage.Sk, storage.Region, storage.Insecure, forcedPathStyle)
+}
+
+func (c *StaticDistributeJobCtl) SaveInfo(ctx context.Context) error {
+	return mongodb.NewJobInfoColl().Create(context.TODO(), &commonmodels.JobInfo{
+		Type:                c.job.JobType,
+		WorkflowName:        c.workflowCtx.WorkflowName,
+		WorkflowDisplayName: c.workflowCtx.WorkflowDisplayName,
+		TaskID:              c.workflowCtx.TaskID,
+		ProductName:         c.workflowCtx.ProjectName,
+		StartTime:           c.job.StartTime,
+		EndTime:             c.job.EndTime,
+		Duration:            c.job.EndTime - c.job.StartTime,
+		Status:              string(c.job.Status),
+		FailureCategories:   c.job.FailureCategories,
+	})
+}
@@ -127,7 +127,7 @@ func (c *WorkflowTriggerJobCtl) Run(ctx context.Context) {
 				return
 			default:
 				for task, event := range runningTasks {
-					t, err := mongodb.NewworkflowTaskv4Coll().Find(task.WorkflowName, task.TaskID)
+					t, err := mongodb.NewWorkflowTaskV4Store().Find(task.WorkflowName, task.TaskID)
 					if err != nil {
 						logError(c.job, fmt.Sprintf("get workflow task %s-%d err: %v", task.WorkflowName, task.TaskID, err), c.logger)
 						return
@@ -173,5 +173,6 @@ func (c *WorkflowTriggerJobCtl) SaveInfo(ctx context.Context) error {
 		EndTime:             c.job.EndTime,
 		Duration:            c.job.EndTime - c.job.StartTime,
 		Status:              string(c.job.Status),
+		FailureCategories:   c.job.FailureCategories,
 	})
 }
@@ -39,6 +39,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -48,6 +49,7 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/multicluster/service"
 	"github.com/koderover/zadig/pkg/microservice/warpdrive/core/service/types/task"
@@ -259,6 +261,99 @@ func addNodeAffinity(clusterConfig *commonmodels.AdvancedConfig, strategyID stri
 	}
 }
 
+// buildServiceContainers generates the sidecar containers of the auxiliary services,
+// they share the network of the job container and are removed with the job pod.
+func buildServiceContainers(serviceContainers []*commonmodels.ServiceContainer) []corev1.Container {
+	containers := make([]corev1.Container, 0)
+	for _, service := range serviceContainers {
+		envs := make([]corev1.EnvVar, 0)
+		for _, kv := range service.Envs {
+			envs = append(envs, corev1.EnvVar{Name: kv.Key, Value: kv.Value})
+		}
+		container := corev1.Container{
+			Name:            fmt.Sprintf("service-%s", service.Name),
+			Image:           service.Image,
+			ImagePullPolicy: corev1.PullIfNotPresent,
+			Command:         service.Command,
+			Args:            service.Args,
+			Env:             envs,
+		}
+		if service.ReadinessPort > 0 {
+			handler := corev1.ProbeHandler{TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(service.ReadinessPort)}}
+			if service.ReadinessPath != "" {
+				handler = corev1.ProbeHandler{HTTPGet: &corev1.HTTPGetAction{Path: service.ReadinessPath, Port: intstr.FromInt(service.ReadinessPort)}}
+			}
+			container.ReadinessProbe = &corev1.Probe{ProbeHandler: handler, PeriodSeconds: 2}
+		}
+		containers = append(containers, container)
+	}
+	return containers
+}
+
+// applySchedulingHints merges the per job scheduling hints into the pod spec generated from the cluster schedule strategy
+func applySchedulingHints(podSpec *corev1.PodSpec, hints *commonmodels.SchedulingHints) {
+	if hints == nil {
+		return
+	}
+	if len(hints.NodeSelector) > 0 {
+		if podSpec.NodeSelector == nil {
+			podSpec.NodeSelector = make(map[string]string)
+		}
+		for k, v := range hints.NodeSelector {
+			podSpec.NodeSelector[k] = v
+		}
+	}
+	if hints.Tolerations != "" {
+		tolerations := make([]corev1.Toleration, 0)
+		if err := yaml.Unmarshal([]byte(hints.Tolerations), &tolerations); err != nil {
+			log.Errorf("failed to parse job toleration config, err: %s", err)
+		} else {
+			podSpec.Tolerations = append(podSpec.Tolerations, tolerations...)
+		}
+	}
+	if hints.PriorityClassName != "" {
+		podSpec.PriorityClassName = hints.PriorityClassName
+	}
+	if len(hints.SpotNodeLabels) == 0 {
+		return
+	}
+
+	matchExpressions := make([]corev1.NodeSelectorRequirement, 0)
+	for _, nodeLabel := range hints.SpotNodeLabels {
+		matchExpressions = append(matchExpressions, corev1.NodeSelectorRequirement{
+			Key:      nodeLabel.Key,
+			Operator: nodeLabel.Operator,
+			Values:   nodeLabel.Value,
+		})
+	}
+	if podSpec.Affinity == nil {
+		podSpec.Affinity = &corev1.Affinity{}
+	}
+	if podSpec.Affinity.NodeAffinity == nil {
+		podSpec.Affinity.NodeAffinity = &corev1.NodeAffinity{}
+	}
+	nodeAffinity := podSpec.Affinity.NodeAffinity
+	switch hints.SpotMode {
+	case setting.RequiredSchedule:
+		if nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil || len(nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms) == 0 {
+			nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{{MatchExpressions: matchExpressions}},
+			}
+			return
+		}
+		// node selector terms are ORed, so the spot requirements are added to every term
+		terms := nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+		for i := range terms {
+			terms[i].MatchExpressions = append(terms[i].MatchExpressions, matchExpressions...)
+		}
+	case setting.PreferredSchedule:
+		nodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution = append(nodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution, corev1.PreferredSchedulingTerm{
+			Weight:     100,
+			Preference: corev1.NodeSelectorTerm{MatchExpressions: matchExpressions},
+		})
+	}
+}
+
 func buildPlainJob(jobName string, resReq setting.Request, resReqSpec setting.RequestSpec, jobTask *commonmodels.JobTask, jobTaskSpec *commonmodels.JobTaskPluginSpec, workflowCtx *commonmodels.WorkflowTaskCtx) (*batchv1.Job, error) {
 	collectJobOutput := `OLD_IFS=$IFS
 export IFS=","
@@ -383,6 +478,12 @@ echo $result > %s
 		},
 	}
 	setJobShareStorages(job, workflowCtx, jobTaskSpec.Properties.ShareStorageDetails, targetCluster)
+	applySchedulingHints(&job.Spec.Template.Spec, jobTaskSpec.Properties.SchedulingHints)
+	if len(jobTaskSpec.Properties.ServiceContainers) > 0 {
+		job.Spec.Template.Spec.Containers = append(job.Spec.Template.Spec.Containers, buildServiceContainers(jobTaskSpec.Properties.ServiceContainers)...)
+		// the job executor stops the service containers through the shared process namespace when the job ends
+		job.Spec.Template.Spec.ShareProcessNamespace = util.GetBoolPointer(true)
+	}
 	ensureVolumeMounts(job)
 	return job, nil
 }
@@ -433,7 +534,7 @@ func buildJob(jobType, jobImage, jobName, clusterID, currentNamespace string, re
 			// in case finished zombie job not cleaned up by zadig
 			TTLSecondsAfterFinished: int32Ptr(3600),
 			// in case zombie job never stop
-			ActiveDeadlineSeconds: int64Ptr(jobTaskSpec.Properties.Timeout*60 + 3600),
+			ActiveDeadlineSeconds: int64Ptr(jobTaskSpec.Properties.Timeout*60 + 3600 + getRetainPodTTL(&jobTaskSpec.Properties)*60),
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels: labels,
@@ -480,6 +581,9 @@ func buildJob(jobType, jobImage, jobName, clusterID, currentNamespace string, re
 	}
 
 	setJobShareStorages(job, workflowCtx, jobTaskSpec.Properties.ShareStorageDetails, targetCluster)
+	if jobTaskSpec.Properties.OS == setting.OSWindows {
+		setWindowsJob(job, jobTask)
+	}
 
 	if jobTaskSpec.Properties.CacheEnable && jobTaskSpec.Properties.Cache.MediumType == commontypes.NFSMedium {
 		volumeName := "build-cache"
@@ -496,6 +600,10 @@ func buildJob(jobType, jobImage, jobName, clusterID, currentNamespace string, re
 		if jobTaskSpec.Properties.CacheDirType == commontypes.WorkspaceCacheDir {
 			mountPath = workflowCtx.Workspace
 		}
+		// declarative caches are archived into the cache dir instead of mounting it onto the build dirs
+		if len(jobTaskSpec.Properties.CacheRules) > 0 {
+			mountPath = commontypes.BuildCacheMountDir
+		}
 
 		job.Spec.Template.Spec.Containers[0].VolumeMounts = append(job.Spec.Template.Spec.Containers[0].VolumeMounts, corev1.VolumeMount{
 			Name:      volumeName,
@@ -508,8 +616,12 @@ func buildJob(jobType, jobImage, jobName, clusterID, currentNamespace string, re
 }
 
 func BuildCleanJob(jobName, clusterID, workflowName string, taskID int64) (*batchv1.Job, error) {
+	return BuildShareStorageJob(jobName, clusterID, fmt.Sprintf("rm -rf %s", commontypes.GetShareStorageSubPathPrefix(workflowName, taskID)))
+}
+
+// BuildShareStorageJob builds a job running the script in the root of the share storage of the cluster
+func BuildShareStorageJob(jobName, clusterID, script string) (*batchv1.Job, error) {
 	workspace := "/workspace"
-	shareStorageDir := commontypes.GetShareStorageSubPathPrefix(workflowName, taskID)
 	image := strings.ReplaceAll(config.ReaperImage(), "${BuildOS}", "focal")
 	targetCluster, err := service.GetCluster(clusterID, log.SugaredLogger())
 	if err != nil {
@@ -538,7 +650,7 @@ func BuildCleanJob(jobName, clusterID, workflowName string, taskID int64) (*batc
 							Image:           image,
 							WorkingDir:      workspace,
 							Command:         []string{"/bin/sh", "-c"},
-							Args:            []string{fmt.Sprintf("rm -rf %s", shareStorageDir)},
+							Args:            []string{script},
 
 							TerminationMessagePolicy: corev1.TerminationMessageReadFile,
 							TerminationMessagePath:   job.JobTerminationFile,
@@ -946,6 +1058,16 @@ func waitJobEndByCheckingConfigMap(ctx context.Context, taskTimeout <-chan time.
 				xl.Errorf(errMsg)
 				return config.StatusFailed, errMsg
 			}
+			// the result written by the job executor comes first, the pod may fail after the job ends when
+			// the service containers exit with a non-zero code on being stopped
+			if status, ok := cm.Data[commontypes.JobResultKey]; ok {
+				switch commontypes.JobStatus(status) {
+				case commontypes.JobFail:
+					return config.StatusFailed, ""
+				default:
+					return config.StatusPassed, ""
+				}
+			}
 			// pod is still running
 			switch {
 			case job.Status.Active != 0:
@@ -985,14 +1107,6 @@ func waitJobEndByCheckingConfigMap(ctx context.Context, taskTimeout <-chan time.
 			case job.Status.Failed != 0:
 				return config.StatusFailed, ""
 			}
-			if status, ok := cm.Data[commontypes.JobResultKey]; ok {
-				switch commontypes.JobStatus(status) {
-				case commontypes.JobFail:
-					return config.StatusFailed, ""
-				default:
-					return config.StatusPassed, ""
-				}
-			}
 		}
 
 		time.Sleep(time.Second * 1)
@@ -1027,7 +1141,7 @@ func getJobOutputFromTerminalMsg(namespace, containerName string, jobTask *commo
 			}
 		}
 	}
-	writeOutputs(outputs, jobTask.Key, workflowCtx)
+	writeOutputs(outputs, getOutputKey(jobTask), workflowCtx)
 	return nil
 }
 
@@ -1046,10 +1160,17 @@ func getJobOutputFromConfigMap(namespace, containerName string, jobTask *commonm
 		return errors.Wrap(err, "unmarshal outputs")
 	}
 
-	writeOutputs(outputs, jobTask.Key, workflowCtx)
+	writeOutputs(outputs, getOutputKey(jobTask), workflowCtx)
 	return nil
 }
 
+func getOutputKey(jobTask *commonmodels.JobTask) string {
+	if jobTask.OutputKey != "" {
+		return jobTask.OutputKey
+	}
+	return jobTask.Key
+}
+
 func writeOutputs(outputs []*job.JobOutput, outputKey string, workflowCtx *commonmodels.WorkflowTaskCtx) {
 	// write jobs output info to globalcontext so other job can use like this {{.job.jobKey.output.outputName}}
 	outputsMap := make(map[string]*job.JobOutput)
@@ -1075,19 +1196,20 @@ func getTagFromImageName(imageName string) string {
 	return "latest"
 }
 
-func saveContainerLog(namespace, clusterID, workflowName, jobName string, taskID int64, jobLabel *JobLabel, kubeClient crClient.Client) error {
+// saveContainerLog uploads the log of the job container and returns the tail of it
+func saveContainerLog(namespace, clusterID, projectName, workflowName, jobName string, taskID int64, jobLabel *JobLabel, kubeClient crClient.Client) ([]byte, error) {
 	selector := labels.Set(getJobLabels(jobLabel)).AsSelector()
 	pods, err := getter.ListPods(namespace, selector, kubeClient)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if len(pods) < 1 {
-		return fmt.Errorf("no pod found with selector: %s", selector)
+		return nil, fmt.Errorf("no pod found with selector: %s", selector)
 	}
 
 	if len(pods[0].Status.ContainerStatuses) < 1 {
-		return fmt.Errorf("no cotainer statuses : %s", selector)
+		return nil, fmt.Errorf("no cotainer statuses : %s", selector)
 	}
 
 	buf := new(bytes.Buffer)
@@ -1099,11 +1221,23 @@ func saveContainerLog(namespace, clusterID, workflowName, jobName string, taskID
 	clientSet, err := kubeclient.GetClientset(config.HubServerAddress(), clusterID)
 	if err != nil {
 		log.Errorf("saveContainerLog, get client set error: %s", err)
-		return err
+		return nil, err
 	}
 
 	if err := containerlog.GetContainerLogs(namespace, pods[0].Name, pods[0].Spec.Containers[0].Name, false, int64(0), buf, clientSet); err != nil {
-		return fmt.Errorf("failed to get container logs: %s", err)
+		return nil, fmt.Errorf("failed to get container logs: %s", err)
+	}
+	logTail := getLogTail(buf.Bytes())
+	return logTail, uploadJobLog(buf, projectName, workflowName, jobName, taskID)
+}
+
+// uploadJobLog saves the log of the job to the default object storage, it is truncated according to the log retention of the project
+func uploadJobLog(buf *bytes.Buffer, projectName, workflowName, jobName string, taskID int64) error {
+	if project, err := templaterepo.NewProductColl().Find(projectName); err == nil && project.LogRetention != nil {
+		if content, truncated := truncateLog(buf.Bytes(), project.LogRetention.MaxJobLogSize); truncated {
+			log.Infof("log of job %s exceeds %d MB and is truncated", jobName, project.LogRetention.MaxJobLogSize)
+			buf = bytes.NewBuffer(content)
+		}
 	}
 
 	store, err := commonrepo.NewS3StorageColl().FindDefault()
@@ -1148,6 +1282,33 @@ func saveContainerLog(namespace, clusterID, workflowName, jobName string, taskID
 	return nil
 }
 
+const logTruncatedNotice = "\n\n========== [zadig] %d bytes of the log are truncated, the log exceeds the limit of %d MB of the project ==========\n\n"
+
+// truncateLog keeps the head and the tail of the log within the given size in MB and replaces the middle of it with
+// a notice, the tail is kept since the errors are usually printed at the end. It returns false if nothing is dropped.
+func truncateLog(content []byte, maxSizeMB int64) ([]byte, bool) {
+	maxSize := maxSizeMB * 1024 * 1024
+	if maxSize <= 0 || int64(len(content)) <= maxSize {
+		return content, false
+	}
+
+	head := maxSize / 2
+	tailStart := int64(len(content)) - (maxSize - head)
+	// cut at the line breaks so that no line is broken
+	if i := bytes.LastIndexByte(content[:head], '\n'); i > 0 {
+		head = int64(i + 1)
+	}
+	if i := bytes.IndexByte(content[tailStart:], '\n'); i >= 0 && tailStart+int64(i+1) < int64(len(content)) {
+		tailStart += int64(i + 1)
+	}
+
+	buf := new(bytes.Buffer)
+	buf.Write(content[:head])
+	buf.WriteString(fmt.Sprintf(logTruncatedNotice, tailStart-head, maxSizeMB))
+	buf.Write(content[tailStart:])
+	return buf.Bytes(), true
+}
+
 func GetObjectPath(subFolder, name string) string {
 	// target should not be started with /
 	if subFolder != "" {
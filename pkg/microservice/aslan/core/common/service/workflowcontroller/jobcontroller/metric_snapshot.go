@@ -0,0 +1,144 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/util"
+	"github.com/koderover/zadig/pkg/tool/prometheus"
+)
+
+const defaultMetricSnapshotWindowMinutes = 5
+
+// metricSnapshotter snapshots the metrics configured in the env before and after a deployment
+type metricSnapshotter struct {
+	client     *prometheus.Client
+	replacer   *strings.Replacer
+	queries    []*commonmodels.MetricQuery
+	window     time.Duration
+	blocking   bool
+	comparison *commonmodels.MetricComparison
+}
+
+// newMetricSnapshotter returns nil if the metric snapshot is not enabled in the env
+func newMetricSnapshotter(env *commonmodels.Product, serviceName string) (*metricSnapshotter, error) {
+	cfg := env.MetricSnapshot
+	if cfg == nil || !cfg.Enabled || len(cfg.Metrics) == 0 {
+		return nil, nil
+	}
+
+	info, err := mongodb.NewObservabilityColl().GetByID(context.Background(), cfg.ObservabilityID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get observability %s: %s", cfg.ObservabilityID, err)
+	}
+	client, err := util.NewPrometheusClient(info)
+	if err != nil {
+		return nil, err
+	}
+
+	windowMinutes := cfg.WindowMinutes
+	if windowMinutes <= 0 {
+		windowMinutes = defaultMetricSnapshotWindowMinutes
+	}
+	return &metricSnapshotter{
+		client:   client,
+		replacer: strings.NewReplacer("$namespace", env.Namespace, "$service", serviceName, "$window", fmt.Sprintf("%dm", windowMinutes)),
+		queries:  cfg.Metrics,
+		window:   time.Duration(windowMinutes) * time.Minute,
+		blocking: cfg.Blocking,
+		comparison: &commonmodels.MetricComparison{
+			WindowMinutes: windowMinutes,
+			Metrics:       make([]*commonmodels.MetricSnapshot, 0, len(cfg.Metrics)),
+		},
+	}, nil
+}
+
+// before queries the metrics over the window ending now
+func (s *metricSnapshotter) before() {
+	now := time.Now()
+	s.comparison.BeforeTime = now.Unix()
+	for _, query := range s.queries {
+		snapshot := &commonmodels.MetricSnapshot{Name: query.Name}
+		value, found, err := s.client.QueryValue(s.replacer.Replace(query.Query), now)
+		if err != nil {
+			snapshot.Error = err.Error()
+		} else if found {
+			snapshot.Before = &value
+		}
+		s.comparison.Metrics = append(s.comparison.Metrics, snapshot)
+	}
+}
+
+// after waits for a window and queries the metrics over it, nothing is queried if ctx is done while waiting
+func (s *metricSnapshotter) after(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(s.window):
+	}
+
+	now := time.Now()
+	s.comparison.AfterTime = now.Unix()
+	for i, query := range s.queries {
+		snapshot := s.comparison.Metrics[i]
+		value, found, err := s.client.QueryValue(s.replacer.Replace(query.Query), now)
+		if err != nil {
+			snapshot.Error = err.Error()
+		} else if found {
+			snapshot.After = &value
+		}
+	}
+}
+
+// finish takes the snapshots after the deployment. The job is blocked until the window ends if the snapshot is
+// blocking, otherwise the snapshots are taken in the background and saved into the workflow task, so that they are
+// kept even if the task has been saved for the last time.
+func (s *metricSnapshotter) finish(ctx context.Context, job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, ack func(), logger *zap.SugaredLogger) {
+	if s.blocking {
+		ack()
+		s.after(ctx)
+		return
+	}
+	go func() {
+		s.after(context.Background())
+		if err := mongodb.NewworkflowTaskv4Coll().UpdateJobMetricComparison(workflowCtx.WorkflowName, workflowCtx.TaskID, job.Key, s.comparison); err != nil {
+			logger.Errorf("failed to save the metric comparison of job %s: %s", job.Name, err)
+		}
+	}()
+}
+
+// startMetricSnapshot takes the snapshots before the deployment, the returned comparison is nil if the metric snapshot is
+// not enabled in the env, and holds the error if the snapshotter fails to be initialized
+func startMetricSnapshot(env *commonmodels.Product, serviceName string) (*metricSnapshotter, *commonmodels.MetricComparison) {
+	s, err := newMetricSnapshotter(env, serviceName)
+	if err != nil {
+		return nil, &commonmodels.MetricComparison{Error: err.Error()}
+	}
+	if s == nil {
+		return nil, nil
+	}
+	s.before()
+	return s, s.comparison
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+const quotaRetryInterval = 3 * time.Second
+
+// GetProjectResourceQuota returns the enabled resource quota of the project, nil means unlimited.
+func GetProjectResourceQuota(projectName string) *template.ProjectResourceQuota {
+	project, err := templaterepo.NewProductColl().Find(projectName)
+	if err != nil || project.ResourceQuota == nil || !project.ResourceQuota.Enabled {
+		return nil
+	}
+	return project.ResourceQuota
+}
+
+// acquireProjectQuota blocks until the job pod fits into the resource quota of the project,
+// the job stays in waitforquota status while waiting. The returned function releases the quota.
+// The usage is shared by all the aslan instances through mongodb.
+func acquireProjectQuota(ctx context.Context, job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, spec setting.RequestSpec, ack func(), logger *zap.SugaredLogger) (func(), error) {
+	projectName := workflowCtx.ProjectName
+	holder := &commonmodels.ProjectResourceHolder{
+		JobKey:       fmt.Sprintf("%s-%d-%s", workflowCtx.WorkflowName, workflowCtx.TaskID, job.Name),
+		WorkflowName: workflowCtx.WorkflowName,
+		TaskID:       workflowCtx.TaskID,
+		CPU:          spec.CpuLimit,
+		Memory:       spec.MemoryLimit,
+	}
+	release := func() {
+		if err := commonrepo.NewProjectResourceUsageColl().Release(projectName, holder); err != nil {
+			logger.Errorf("failed to release resource quota of project %s, err: %s", projectName, err)
+		}
+	}
+
+	quota := GetProjectResourceQuota(projectName)
+	if quota == nil {
+		return func() {}, nil
+	}
+	if err := checkJobFitsQuota(quota, spec); err != nil {
+		return nil, fmt.Errorf("job %s %s of project %s", job.Name, err, projectName)
+	}
+
+	waiting := false
+	for {
+		acquired, err := commonrepo.NewProjectResourceUsageColl().Acquire(projectName, holder, quota.MaxConcurrentJobs, quota.CPULimit, quota.MemoryLimit)
+		if err != nil {
+			logger.Errorf("failed to acquire resource quota of project %s, err: %s", projectName, err)
+		}
+		if acquired {
+			if waiting {
+				logger.Infof("job %s got resource quota of project %s", job.Name, projectName)
+				job.Status = config.StatusPrepare
+				ack()
+			}
+			return release, nil
+		}
+		if !waiting {
+			waiting = true
+			logger.Infof("job %s is waiting for resource quota of project %s", job.Name, projectName)
+			job.Status = config.StatusWaitForQuota
+			ack()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("job %s cancelled while waiting for resource quota", job.Name)
+		case <-time.After(quotaRetryInterval):
+			// the quota may have been changed while waiting
+			if latest := GetProjectResourceQuota(projectName); latest != nil {
+				quota = latest
+			} else {
+				quota = &template.ProjectResourceQuota{}
+			}
+		}
+	}
+}
+
+// checkJobFitsQuota returns an error if the job pod alone exceeds the quota, such a job would wait forever
+func checkJobFitsQuota(quota *template.ProjectResourceQuota, spec setting.RequestSpec) error {
+	if (quota.CPULimit > 0 && spec.CpuLimit > quota.CPULimit) || (quota.MemoryLimit > 0 && spec.MemoryLimit > quota.MemoryLimit) {
+		return fmt.Errorf("requests %dm cpu and %dMi memory, which exceeds the resource quota", spec.CpuLimit, spec.MemoryLimit)
+	}
+	return nil
+}
+
+// ReleaseOrphanedQuotas releases the quota held by the jobs whose tasks are no longer in the queue, the jobs
+// of an instance dying while running them never release the quota themselves
+func ReleaseOrphanedQuotas(queuedTasks sets.String) {
+	coll := commonrepo.NewProjectResourceUsageColl()
+	usages, err := coll.List()
+	if err != nil {
+		log.Errorf("failed to list project resource usages, err: %s", err)
+		return
+	}
+	for _, usage := range usages {
+		for _, holder := range usage.Holders {
+			if queuedTasks.Has(fmt.Sprintf("%s-%d", holder.WorkflowName, holder.TaskID)) {
+				continue
+			}
+			log.Infof("release resource quota of project %s held by orphaned job %s", usage.ProjectName, holder.JobKey)
+			if err := coll.Release(usage.ProjectName, holder); err != nil {
+				log.Errorf("failed to release resource quota of project %s, err: %s", usage.ProjectName, err)
+			}
+		}
+	}
+}
+
+// getResourceLimitSpec returns the resource spec a job pod will be limited to
+func getResourceLimitSpec(resReq setting.Request, resReqSpec setting.RequestSpec) setting.RequestSpec {
+	switch resReq {
+	case setting.HighRequest:
+		return setting.HighRequestSpec
+	case setting.MediumRequest:
+		return setting.MediumRequestSpec
+	case setting.LowRequest:
+		return setting.LowRequestSpec
+	case setting.MinRequest:
+		return setting.MinRequestSpec
+	case setting.DefineRequest:
+		return resReqSpec
+	default:
+		return setting.DefaultRequestSpec
+	}
+}
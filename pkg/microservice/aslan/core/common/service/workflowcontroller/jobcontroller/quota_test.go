@@ -0,0 +1,52 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+var _ = DescribeTable("getResourceLimitSpec",
+	func(req setting.Request, reqSpec, expected setting.RequestSpec) {
+		Expect(getResourceLimitSpec(req, reqSpec)).To(Equal(expected))
+	},
+	Entry("high", setting.HighRequest, setting.RequestSpec{}, setting.HighRequestSpec),
+	Entry("medium", setting.MediumRequest, setting.RequestSpec{}, setting.MediumRequestSpec),
+	Entry("low", setting.LowRequest, setting.RequestSpec{}, setting.LowRequestSpec),
+	Entry("min", setting.MinRequest, setting.RequestSpec{}, setting.MinRequestSpec),
+	Entry("defined", setting.DefineRequest, setting.RequestSpec{CpuLimit: 3000, MemoryLimit: 3072}, setting.RequestSpec{CpuLimit: 3000, MemoryLimit: 3072}),
+	Entry("unknown falls back to the default", setting.Request("unknown"), setting.RequestSpec{CpuLimit: 3000}, setting.DefaultRequestSpec),
+)
+
+var _ = DescribeTable("checkJobFitsQuota",
+	func(quota template.ProjectResourceQuota, spec setting.RequestSpec, fits bool) {
+		err := checkJobFitsQuota(&quota, spec)
+		if fits {
+			Expect(err).NotTo(HaveOccurred())
+		} else {
+			Expect(err).To(HaveOccurred())
+		}
+	},
+	Entry("unlimited quota", template.ProjectResourceQuota{Enabled: true, MaxConcurrentJobs: 1}, setting.HighRequestSpec, true),
+	Entry("within the limits", template.ProjectResourceQuota{Enabled: true, CPULimit: 4000, MemoryLimit: 8192}, setting.LowRequestSpec, true),
+	Entry("cpu exceeds the limit", template.ProjectResourceQuota{Enabled: true, CPULimit: 4000}, setting.MediumRequestSpec, false),
+	Entry("memory exceeds the limit", template.ProjectResourceQuota{Enabled: true, MemoryLimit: 8192}, setting.MediumRequestSpec, false),
+)
@@ -0,0 +1,79 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+// ResumableJobCtl is implemented by the jobs that can be re-adopted after the controller restarts
+type ResumableJobCtl interface {
+	// Resume continues the job from its resume state, it returns false if the job can not be re-adopted
+	Resume(ctx context.Context) bool
+}
+
+func setJobResumeState(job *commonmodels.JobTask, phase config.JobResumePhase, clusterID, namespace string, ack func()) {
+	job.ResumeState = &commonmodels.JobResumeState{
+		Phase:     phase,
+		ClusterID: clusterID,
+		Namespace: namespace,
+		AckTime:   time.Now().Unix(),
+	}
+	ack()
+}
+
+// isJobInFlight returns true if the job was started by the previous controller, the jobs waiting for the
+// resource quota have not done anything yet and are run again
+func isJobInFlight(job *commonmodels.JobTask) bool {
+	return job.Status == config.StatusPrepare || job.Status == config.StatusRunning
+}
+
+// resumeJob re-adopts the job that was in-flight when the controller restarted, the jobs that can not be
+// re-adopted are failed since they may have taken effect already
+func resumeJob(ctx context.Context, job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, logger *zap.SugaredLogger, ack func()) {
+	logger.Infof("resume job: %s,status: %s", job.Name, job.Status)
+	jobCtl := initJobCtl(job, workflowCtx, logger, ack)
+	defer func() {
+		if err := recover(); err != nil {
+			errMsg := fmt.Sprintf("job: %s panic: %v", job.Name, err)
+			logger.Errorf(errMsg)
+			debug.PrintStack()
+			job.Status = config.StatusFailed
+			job.Error = errMsg
+		}
+		job.ResumeState = nil
+		job.EndTime = time.Now().Unix()
+		logger.Infof("finish resumed job: %s,status: %s", job.Name, job.Status)
+		ack()
+		if err := jobCtl.SaveInfo(ctx); err != nil {
+			logger.Errorf("update job info: %s into db error: %v", job.Name, err)
+		}
+	}()
+
+	if resumable, ok := jobCtl.(ResumableJobCtl); ok && job.ResumeState != nil && resumable.Resume(ctx) {
+		return
+	}
+	logError(job, fmt.Sprintf("job %s was interrupted by the restart of the workflow controller and can not be resumed", job.Name), logger)
+}
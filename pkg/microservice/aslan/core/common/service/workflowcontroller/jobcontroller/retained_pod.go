@@ -0,0 +1,113 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/labels"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
+	"github.com/koderover/zadig/pkg/tool/kube/getter"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+const (
+	defaultRetainPodTTL = 60
+	maxRetainPodTTL     = 24 * 60
+)
+
+// getRetainPodTTL returns the minutes the pod of the failed job is kept, zero means the pod is not retained
+func getRetainPodTTL(properties *commonmodels.JobProperties) int64 {
+	if !properties.RetainPodOnFailure {
+		return 0
+	}
+	if properties.RetainPodTTL <= 0 {
+		return defaultRetainPodTTL
+	}
+	if properties.RetainPodTTL > maxRetainPodTTL {
+		return maxRetainPodTTL
+	}
+	return properties.RetainPodTTL
+}
+
+// retainJobPod records the pod of the failed job instead of deleting it, the pod is cleaned up when it expires
+func retainJobPod(job *commonmodels.JobTask, workflowCtx *commonmodels.WorkflowTaskCtx, properties *commonmodels.JobProperties, jobLabel *JobLabel) error {
+	kubeClient, err := kubeclient.GetKubeClient(config.HubServerAddress(), properties.ClusterID)
+	if err != nil {
+		return fmt.Errorf("failed to get kube client: %s", err)
+	}
+	pods, err := getter.ListPods(properties.Namespace, labels.Set(getJobLabels(jobLabel)).AsSelector(), kubeClient)
+	if err != nil {
+		return fmt.Errorf("failed to list pods of job %s: %s", job.K8sJobName, err)
+	}
+	if len(pods) == 0 {
+		return fmt.Errorf("no pod found for job %s", job.K8sJobName)
+	}
+
+	now := time.Now()
+	return commonrepo.NewRetainedJobPodColl().Create(&commonmodels.RetainedJobPod{
+		ProjectName:  workflowCtx.ProjectName,
+		WorkflowName: workflowCtx.WorkflowName,
+		TaskID:       workflowCtx.TaskID,
+		JobName:      job.Name,
+		JobType:      job.JobType,
+		K8sJobName:   job.K8sJobName,
+		PodName:      pods[0].Name,
+		ClusterID:    properties.ClusterID,
+		Namespace:    properties.Namespace,
+		CreateTime:   now.Unix(),
+		ExpireTime:   now.Add(time.Duration(getRetainPodTTL(properties)) * time.Minute).Unix(),
+	})
+}
+
+// CleanRetainedJobPod deletes the retained job pod together with its job context and removes the record
+func CleanRetainedJobPod(pod *commonmodels.RetainedJobPod) error {
+	kubeClient, err := kubeclient.GetKubeClient(config.HubServerAddress(), pod.ClusterID)
+	if err != nil {
+		return fmt.Errorf("failed to get kube client: %s", err)
+	}
+	jobLabel := &JobLabel{
+		JobType: pod.JobType,
+		JobName: pod.K8sJobName,
+	}
+	if err := ensureDeleteJob(pod.Namespace, jobLabel, kubeClient); err != nil {
+		return fmt.Errorf("failed to delete job %s: %s", pod.K8sJobName, err)
+	}
+	if err := ensureDeleteConfigMap(pod.Namespace, jobLabel, kubeClient); err != nil {
+		return fmt.Errorf("failed to delete the configmap of job %s: %s", pod.K8sJobName, err)
+	}
+	return commonrepo.NewRetainedJobPodColl().DeleteByID(pod.ID)
+}
+
+// CleanExpiredRetainedJobPods cleans up the retained job pods whose TTL is reached
+func CleanExpiredRetainedJobPods() {
+	pods, err := commonrepo.NewRetainedJobPodColl().List(&commonrepo.RetainedJobPodListOption{ExpireBefore: time.Now().Unix()})
+	if err != nil {
+		log.Errorf("failed to list expired retained job pods: %s", err)
+		return
+	}
+	for _, pod := range pods {
+		if err := CleanRetainedJobPod(pod); err != nil {
+			log.Errorf("failed to clean retained pod %s of task %s #%d: %s", pod.PodName, pod.WorkflowName, pod.TaskID, err)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	crClient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/tool/kube/getter"
+)
+
+func getJobTimeline(jobTask *commonmodels.JobTask) *commonmodels.JobTaskTimeline {
+	if jobTask.Timeline == nil {
+		jobTask.Timeline = &commonmodels.JobTaskTimeline{}
+	}
+	return jobTask.Timeline
+}
+
+// recordPodTimeline reads the scheduled time and the container started time of the job pod, the time
+// between them is spent on the init containers and pulling the images
+func recordPodTimeline(jobTask *commonmodels.JobTask, namespace string, kubeClient crClient.Client, logger *zap.SugaredLogger) {
+	timeline := getJobTimeline(jobTask)
+	defer func() {
+		if timeline.RunningTime == 0 {
+			timeline.RunningTime = time.Now().Unix()
+		}
+	}()
+
+	pods, err := getter.ListPods(namespace, labels.Set(getJobLabels(&JobLabel{JobName: jobTask.K8sJobName})).AsSelector(), kubeClient)
+	if err != nil {
+		logger.Warnf("failed to list pods of job %s, err: %s", jobTask.K8sJobName, err)
+		return
+	}
+	for _, pod := range pods {
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionTrue {
+				timeline.PodScheduledTime = condition.LastTransitionTime.Unix()
+			}
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.State.Running == nil {
+				continue
+			}
+			startedAt := status.State.Running.StartedAt.Unix()
+			if timeline.RunningTime == 0 || startedAt < timeline.RunningTime {
+				timeline.RunningTime = startedAt
+			}
+		}
+	}
+}
@@ -37,6 +37,10 @@ type JobContext struct {
 	Paths string `yaml:"paths"`
 	// ConfigMapName save the name of the configmap in which the jobContext resides
 	ConfigMapName string `yaml:"config_map_name"`
+	// RetainPodTTL is the minutes the job pod is kept alive after the job fails, zero means the pod exits at once
+	RetainPodTTL int64 `yaml:"retain_pod_ttl"`
+	// HasServiceContainers tells the job executor to stop the service containers when the job ends so that the pod completes
+	HasServiceContainers bool `yaml:"has_service_containers"`
 
 	Steps   []*commonmodels.StepTask `yaml:"steps"`
 	Outputs []string                 `yaml:"outputs"`
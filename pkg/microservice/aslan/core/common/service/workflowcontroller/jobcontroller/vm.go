@@ -0,0 +1,192 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller/stepcontroller"
+	"github.com/koderover/zadig/pkg/types/job"
+)
+
+// runOnVM queues the job for the vm agents having all the labels of the job and waits for the result reported by the agent
+func (c *FreestyleJobCtl) runOnVM(ctx context.Context) {
+	for _, env := range c.jobTaskSpec.Properties.Envs {
+		if strings.HasPrefix(env.Value, "{{.job") && strings.HasSuffix(env.Value, "}}") {
+			env.Value = ""
+		}
+	}
+	if c.jobTaskSpec.Properties.Timeout <= 0 {
+		c.jobTaskSpec.Properties.Timeout = 600
+	}
+	// pods are not retained on vm
+	c.jobTaskSpec.Properties.RetainPodOnFailure = false
+	if err := stepcontroller.PrepareSteps(ctx, c.workflowCtx, &c.jobTaskSpec.Properties.Paths, c.job.Name, c.jobTaskSpec.Steps, c.logger); err != nil {
+		logError(c.job, err.Error(), c.logger)
+		return
+	}
+	c.ack()
+
+	jobCtxBytes, err := yaml.Marshal(BuildJobExcutorContext(c.jobTaskSpec, c.job, c.workflowCtx, c.logger))
+	if err != nil {
+		logError(c.job, fmt.Sprintf("cannot Jobexcutor.Context data: %v", err), c.logger)
+		return
+	}
+	vmJob := &commonmodels.VMJob{
+		Labels:       c.jobTaskSpec.Properties.VMLabels,
+		ProjectName:  c.workflowCtx.ProjectName,
+		WorkflowName: c.workflowCtx.WorkflowName,
+		TaskID:       c.workflowCtx.TaskID,
+		JobName:      c.job.Name,
+		JobType:      c.job.JobType,
+		JobCtx:       string(jobCtxBytes),
+		Status:       config.StatusQueued,
+		CreateTime:   time.Now().Unix(),
+	}
+	if err := commonrepo.NewVMJobColl().Create(vmJob); err != nil {
+		logError(c.job, fmt.Sprintf("failed to queue the job for vm agents: %s", err), c.logger)
+		return
+	}
+	if !hasOnlineVMAgent(c.jobTaskSpec.Properties.VMLabels) {
+		c.logger.Warnf("no online vm agent has the labels %v, job %s waits in the queue", c.jobTaskSpec.Properties.VMLabels, c.job.Name)
+	}
+
+	vmJob = c.waitVMJob(ctx, vmJob)
+	c.completeVMJob(ctx, vmJob)
+}
+
+// waitVMJob waits for the result of the job, the timeout of the job starts when an agent picks it up,
+// the time spent in the queue is not counted.
+func (c *FreestyleJobCtl) waitVMJob(ctx context.Context, vmJob *commonmodels.VMJob) *commonmodels.VMJob {
+	jobColl := commonrepo.NewVMJobColl()
+	// a nil channel blocks until the timer is started
+	var taskTimeout <-chan time.Time
+	ticker := time.NewTicker(3 * time.Second)
+	defer ticker.Stop()
+
+	// finish marks the job as ended so that the agent stops running it
+	finish := func(status config.Status, errMsg string) *commonmodels.VMJob {
+		if _, err := jobColl.Finish(vmJob.ID, status, "", errMsg); err != nil {
+			c.logger.Errorf("failed to finish vm job %s: %s", vmJob.ID.Hex(), err)
+		}
+		c.job.Status, c.job.Error = status, errMsg
+		return vmJob
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return finish(config.StatusCancelled, "")
+		case <-taskTimeout:
+			return finish(config.StatusTimeout, "")
+		case <-ticker.C:
+		}
+
+		latest, err := jobColl.GetByID(vmJob.ID.Hex())
+		if err != nil {
+			c.logger.Errorf("failed to get vm job %s: %s", vmJob.ID.Hex(), err)
+			continue
+		}
+		vmJob = latest
+		switch vmJob.Status {
+		case config.StatusQueued:
+		case config.StatusRunning:
+			agent, err := commonrepo.NewVMAgentColl().GetByID(vmJob.AgentID)
+			if err != nil || !agent.Online() {
+				return finish(config.StatusFailed, fmt.Sprintf("vm agent %s is offline", vmJob.AgentID))
+			}
+			if taskTimeout == nil {
+				timeout := time.Duration(c.jobTaskSpec.Properties.Timeout) * time.Minute
+				if vmJob.StartTime > 0 {
+					timeout -= time.Since(time.Unix(vmJob.StartTime, 0))
+				}
+				taskTimeout = time.After(timeout)
+			}
+			if c.job.Status != config.StatusRunning {
+				c.job.Status = config.StatusRunning
+				c.ack()
+			}
+		default:
+			c.job.Status, c.job.Error = vmJob.Status, vmJob.Error
+			return vmJob
+		}
+	}
+}
+
+func (c *FreestyleJobCtl) completeVMJob(ctx context.Context, vmJob *commonmodels.VMJob) {
+	if c.job.Status == config.StatusPassed && vmJob.Outputs != "" {
+		outputs := []*job.JobOutput{}
+		if err := json.Unmarshal([]byte(vmJob.Outputs), &outputs); err != nil {
+			c.logger.Error(err)
+			c.job.Status, c.job.Error = config.StatusFailed, fmt.Sprintf("unmarshal outputs: %s", err)
+		} else {
+			writeOutputs(outputs, getOutputKey(c.job), c.workflowCtx)
+		}
+	}
+
+	if err := saveVMJobLog(vmJob, c.workflowCtx.ProjectName, c.workflowCtx.WorkflowName, c.job.Name, c.workflowCtx.TaskID); err != nil {
+		c.logger.Error(err)
+		if c.job.Error == "" {
+			c.job.Error = err.Error()
+		}
+		return
+	}
+	if err := stepcontroller.SummarizeSteps(ctx, c.workflowCtx, &c.jobTaskSpec.Properties.Paths, c.job.Name, c.jobTaskSpec.Steps, c.logger); err != nil {
+		c.logger.Error(err)
+		c.job.Error = err.Error()
+	}
+}
+
+// saveVMJobLog uploads the log streamed by the agent to the object storage like the log of the job pods
+func saveVMJobLog(vmJob *commonmodels.VMJob, projectName, workflowName, jobName string, taskID int64) error {
+	logColl := commonrepo.NewVMJobLogColl()
+	chunks, err := logColl.List(vmJob.ID.Hex(), 0)
+	if err != nil {
+		return fmt.Errorf("failed to list the log of vm job %s: %s", vmJob.ID.Hex(), err)
+	}
+	buf := new(bytes.Buffer)
+	for _, chunk := range chunks {
+		buf.WriteString(chunk.Content)
+	}
+	if err := uploadJobLog(buf, projectName, workflowName, jobName, taskID); err != nil {
+		return err
+	}
+	return logColl.DeleteByJobID(vmJob.ID.Hex())
+}
+
+func hasOnlineVMAgent(labels []string) bool {
+	agents, err := commonrepo.NewVMAgentColl().ListByLabels(labels)
+	if err != nil {
+		return false
+	}
+	for _, agent := range agents {
+		if agent.Online() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobcontroller
+
+import (
+	"fmt"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+const (
+	windowsOSLabel     = "kubernetes.io/os"
+	windowsTaintKey    = "os"
+	windowsExecutorDir = "C:\\app"
+)
+
+// setWindowsJob schedules the job pod onto the windows nodes, the executor is copied from the windows executor image
+// and booted with powershell. The linux paths of the volume mounts are resolved against the system drive by windows.
+func setWindowsJob(job *batchv1.Job, jobTask *commonmodels.JobTask) {
+	podSpec := &job.Spec.Template.Spec
+	if podSpec.NodeSelector == nil {
+		podSpec.NodeSelector = make(map[string]string)
+	}
+	podSpec.NodeSelector[windowsOSLabel] = "windows"
+	podSpec.Tolerations = append(podSpec.Tolerations, corev1.Toleration{
+		Key:      windowsTaintKey,
+		Operator: corev1.TolerationOpEqual,
+		Value:    "windows",
+		Effect:   corev1.TaintEffectNoSchedule,
+	})
+
+	for i := range podSpec.InitContainers {
+		if podSpec.InitContainers[i].Name != "executor-resource-init" {
+			continue
+		}
+		podSpec.InitContainers[i].Image = config.WindowsExecutorImage()
+		podSpec.InitContainers[i].Command = []string{"powershell", "-NoProfile", "-Command"}
+		podSpec.InitContainers[i].Args = []string{fmt.Sprintf("Copy-Item -Path %s\\* -Destination %s -Recurse", windowsExecutorDir, ExecutorVolumePath)}
+	}
+
+	scripts := []string{fmt.Sprintf("New-Item -ItemType Directory -Force -Path %sdebug | Out-Null", ZadigContextDir)}
+	if jobTask.BreakpointBefore {
+		scripts = append(scripts, fmt.Sprintf("New-Item -ItemType File -Force -Path %sdebug/breakpoint_before | Out-Null", ZadigContextDir))
+	}
+	if jobTask.BreakpointAfter {
+		scripts = append(scripts, fmt.Sprintf("New-Item -ItemType File -Force -Path %sdebug/breakpoint_after | Out-Null", ZadigContextDir))
+	}
+	scripts = append(scripts, fmt.Sprintf("& %s.exe", JobExecutorFile), "exit $LASTEXITCODE")
+	podSpec.Containers[0].Command = []string{"powershell", "-NoProfile", "-Command"}
+	podSpec.Containers[0].Args = []string{strings.Join(scripts, "; ")}
+}
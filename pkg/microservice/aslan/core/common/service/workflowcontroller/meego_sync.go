@@ -0,0 +1,180 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflowcontroller
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"go.uber.org/zap"
+
+	configbase "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/tool/meego"
+)
+
+// syncMeegoWorkItems transitions the meego work items linked to the finished task to the configured state
+// and comments the task result on them, failures are logged and never affect the task
+func syncMeegoWorkItems(task *commonmodels.WorkflowTask, logger *zap.SugaredLogger) {
+	if task.WorkflowArgs == nil || task.WorkflowArgs.MeegoSync == nil || !task.WorkflowArgs.MeegoSync.Enabled {
+		return
+	}
+	syncConfig := task.WorkflowArgs.MeegoSync
+	workItems := getLinkedMeegoWorkItems(task, syncConfig.MeegoID)
+	if len(workItems) == 0 {
+		return
+	}
+
+	meegoInfo, err := commonrepo.NewProjectManagementColl().GetMeegoByID(syncConfig.MeegoID)
+	if err != nil {
+		logger.Errorf("failed to find meego integration %s, err: %s", syncConfig.MeegoID, err)
+		return
+	}
+	client, err := meego.NewClient(meegoInfo.MeegoHost, meegoInfo.MeegoPluginID, meegoInfo.MeegoPluginSecret, meegoInfo.MeegoUserKey)
+	if err != nil {
+		logger.Errorf("failed to create meego client, err: %s", err)
+		return
+	}
+
+	targetStateKey := syncConfig.FailedStateKey
+	if task.Status == config.StatusPassed {
+		targetStateKey = syncConfig.PassedStateKey
+	}
+	comment := getMeegoSyncComment(task)
+	for _, item := range workItems {
+		if targetStateKey != "" {
+			if err := transitionMeegoWorkItem(client, item, targetStateKey); err != nil {
+				logger.Errorf("failed to transition meego work item %d to %s, err: %s", item.ID, targetStateKey, err)
+			}
+		}
+		if syncConfig.Comment {
+			if _, err := client.Comment(item.ProjectKey, item.WorkItemTypeKey, item.ID, comment); err != nil {
+				logger.Errorf("failed to comment on meego work item %d, err: %s", item.ID, err)
+			}
+		}
+	}
+}
+
+// getLinkedMeegoWorkItems returns the work items of the triggering meego event and of the meego transition jobs
+// of the task which belong to the given meego integration
+func getLinkedMeegoWorkItems(task *commonmodels.WorkflowTask, meegoID string) []*commonmodels.MeegoWorkItemLink {
+	resp := make([]*commonmodels.MeegoWorkItemLink, 0)
+	seen := make(map[int64]bool)
+	add := func(item *commonmodels.MeegoWorkItemLink) {
+		if item.ID == 0 || seen[item.ID] || (item.MeegoID != "" && item.MeegoID != meegoID) {
+			return
+		}
+		seen[item.ID] = true
+		resp = append(resp, item)
+	}
+
+	for _, item := range task.WorkflowArgs.MeegoWorkItems {
+		add(item)
+	}
+	for _, stage := range task.Stages {
+		for _, job := range stage.Jobs {
+			if job.JobType != string(config.JobMeegoTransition) {
+				continue
+			}
+			spec := &commonmodels.MeegoTransitionSpec{}
+			if err := commonmodels.IToi(job.Spec, spec); err != nil {
+				continue
+			}
+			for _, workItem := range spec.WorkItems {
+				add(&commonmodels.MeegoWorkItemLink{
+					MeegoID:         spec.MeegoID,
+					ProjectKey:      spec.ProjectKey,
+					WorkItemTypeKey: spec.WorkItemTypeKey,
+					ID:              int64(workItem.ID),
+					Name:            workItem.Name,
+				})
+			}
+		}
+	}
+	return resp
+}
+
+// transitionMeegoWorkItem moves the work item to the target state through the connection from its current state,
+// nothing is done if the work item is already in the target state
+func transitionMeegoWorkItem(client *meego.Client, item *commonmodels.MeegoWorkItemLink, targetStateKey string) error {
+	workItem, err := client.GetWorkItem(item.ProjectKey, item.WorkItemTypeKey, int(item.ID))
+	if err != nil {
+		return err
+	}
+	currentStateKey := ""
+	if workItem.WorkItemStatus != nil {
+		currentStateKey = workItem.WorkItemStatus.StateKey
+	}
+	if currentStateKey == targetStateKey {
+		return nil
+	}
+
+	connections, _, err := client.GetWorkFlowInfo(item.ProjectKey, item.WorkItemTypeKey, int(item.ID))
+	if err != nil {
+		return err
+	}
+	for _, connection := range connections {
+		if connection.SourceStateKey == currentStateKey && connection.TargetStateKey == targetStateKey {
+			return client.StatusTransition(item.ProjectKey, item.WorkItemTypeKey, int(item.ID), connection.TransitionID)
+		}
+	}
+	return fmt.Errorf("no transition from state %s to %s", currentStateKey, targetStateKey)
+}
+
+func getMeegoSyncComment(task *commonmodels.WorkflowTask) string {
+	icon, status := "❌", "失败"
+	switch task.Status {
+	case config.StatusPassed:
+		icon, status = "✅", "成功"
+	case config.StatusCancelled:
+		status = "已取消"
+	case config.StatusTimeout:
+		status = "超时"
+	case config.StatusReject:
+		status = "被拒绝"
+	}
+	link := fmt.Sprintf("%s/v1/projects/detail/%s/pipelines/custom/%s/%d?display_name=%s",
+		configbase.SystemAddress(), task.ProjectName, task.WorkflowName, task.TaskID, url.PathEscape(task.WorkflowDisplayName))
+	lines := []string{fmt.Sprintf("%s Zadig 工作流执行%s: %s", icon, status, link)}
+
+	for _, stage := range task.Stages {
+		for _, job := range stage.Jobs {
+			switch job.JobType {
+			case string(config.JobZadigDeploy):
+				spec := &commonmodels.JobTaskDeploySpec{}
+				if err := commonmodels.IToi(job.Spec, spec); err != nil {
+					continue
+				}
+				for _, module := range spec.ServiceAndImages {
+					lines = append(lines, fmt.Sprintf("环境 %s 服务 %s/%s 部署镜像 %s", spec.Env, spec.ServiceName, module.ServiceModule, module.Image))
+				}
+			case string(config.JobZadigHelmDeploy):
+				spec := &commonmodels.JobTaskHelmDeploySpec{}
+				if err := commonmodels.IToi(job.Spec, spec); err != nil {
+					continue
+				}
+				for _, module := range spec.ImageAndModules {
+					lines = append(lines, fmt.Sprintf("环境 %s 服务 %s/%s 部署镜像 %s", spec.Env, spec.ServiceName, module.ServiceModule, module.Image))
+				}
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -73,7 +73,7 @@ type CancelMessage struct {
 // CreateTask 接受create task请求, 保存task到数据库, 发送task到queue
 func CreateTask(t *commonmodels.WorkflowTask) error {
 	t.Status = config.StatusWaiting
-	if _, err := commonrepo.NewworkflowTaskv4Coll().Create(t); err != nil {
+	if _, err := commonrepo.NewWorkflowTaskV4Store().Create(t); err != nil {
 		log.Errorf("create workflow task v4 error: %v", err)
 		return err
 	}
@@ -82,7 +82,7 @@ func CreateTask(t *commonmodels.WorkflowTask) error {
 
 func UpdateTask(t *commonmodels.WorkflowTask) error {
 	t.Status = config.StatusWaiting
-	if err := commonrepo.NewworkflowTaskv4Coll().Update(t.ID.Hex(), t); err != nil {
+	if err := commonrepo.NewWorkflowTaskV4Store().Update(t.ID.Hex(), t); err != nil {
 		log.Errorf("update workflow task v4 %s error: %v", t.WorkflowName, err)
 		return err
 	}
@@ -102,6 +102,9 @@ func Push(t *commonmodels.WorkflowTask) error {
 }
 
 func InitWorkflowController() {
+	// join the cluster first so the tasks of the other alive instances are kept
+	heartbeat()
+	go RunClusterHeartbeat()
 	InitQueue()
 	go WorfklowTaskSender()
 }
@@ -111,14 +114,28 @@ func InitQueue() error {
 
 	// 从数据库查找未完成的任务
 	// status = created, running
-	tasks, err := commonrepo.NewworkflowTaskv4Coll().InCompletedTasks()
+	tasks, err := commonrepo.NewWorkflowTaskV4Store().InCompletedTasks()
 	if err != nil {
 		log.Errorf("find [InCompletedTasks] error: %v", err)
 		return err
 	}
 
+	queueTasks, err := commonrepo.NewWorkflowQueueColl().List(&commonrepo.ListWorfklowQueueOption{})
+	if err != nil {
+		log.Errorf("list queue workflow task error: %v", err)
+		return err
+	}
+	queueTaskMap := make(map[string]*commonmodels.WorkflowQueue)
+	for _, t := range queueTasks {
+		queueTaskMap[fmt.Sprintf("%s-%d", t.WorkflowName, t.TaskID)] = t
+	}
+
 	for _, task := range tasks {
-		// 如果 Queue 重新初始化, 取消所有 running tasks
+		// the waiting tasks are run by the sender, the running tasks of the dead instances are adopted and resumed
+		if _, ok := queueTaskMap[fmt.Sprintf("%s-%d", task.WorkflowName, task.TaskID)]; ok {
+			continue
+		}
+		// the task without queue can not be resumed
 		if err := CancelWorkflowTask(setting.DefaultTaskRevoker, task.WorkflowName, task.TaskID, log); err != nil {
 			log.Errorf("[CancelRunningTask] error: %v", err)
 			continue
@@ -152,7 +169,16 @@ func WorfklowTaskSender() {
 			continue
 		}
 		var t *commonmodels.WorkflowQueue
+		tenantTasks := newTenantTaskCounter()
 		for _, task := range waitingTasks {
+			// the workflows are sharded among the controller instances
+			if !OwnsWorkflow(task.WorkflowName) {
+				continue
+			}
+			// the task waits until the tenant of its project has a free slot
+			if !tenantTasks.available(task.ProjectName) {
+				continue
+			}
 			workflow, err := commonrepo.NewWorkflowV4Coll().Find(task.WorkflowName)
 			if err != nil {
 				log.Errorf("WorkflowV4 Queue: find workflow %s error: %v", task.WorkflowName, err)
@@ -295,15 +321,20 @@ func ParallelRunningAndQueuedTasks(currentTask *commonmodels.WorkflowQueue) bool
 func updateQueueAndRunTask(t *commonmodels.WorkflowQueue, jobConcurrency int) error {
 	logger := log.SugaredLogger()
 	// 更新队列状态为TaskQueued
-	workflowTask, err := commonrepo.NewworkflowTaskv4Coll().Find(t.WorkflowName, t.TaskID)
+	workflowTask, err := commonrepo.NewWorkflowTaskV4Store().Find(t.WorkflowName, t.TaskID)
 	if err != nil {
 		logger.Errorf("%s:%d get workflow task error: %v", t.WorkflowName, t.TaskID, err)
 		return fmt.Errorf("%s:%d get workflow task error: %v", t.WorkflowName, t.TaskID, err)
 	}
 	workflowTask.Status = config.StatusQueued
-	if success := UpdateQueue(workflowTask); !success {
-		logger.Errorf("%s:%d update t status error", t.WorkflowName, t.TaskID)
-		return fmt.Errorf("%s:%d update t status error", t.WorkflowName, t.TaskID)
+	// claim the task so it will not be run by another controller instance
+	claimed, err := commonrepo.NewWorkflowQueueColl().Claim(t, instanceID)
+	if err != nil {
+		logger.Errorf("%s:%d update t status error: %v", t.WorkflowName, t.TaskID, err)
+		return fmt.Errorf("%s:%d update t status error: %v", t.WorkflowName, t.TaskID, err)
+	}
+	if !claimed {
+		return fmt.Errorf("%s:%d has been claimed by another instance", t.WorkflowName, t.TaskID)
 	}
 	ctx := context.Background()
 	go NewWorkflowController(workflowTask, logger).Run(ctx, jobConcurrency)
@@ -0,0 +1,199 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflowcontroller
+
+import (
+	"sync"
+	"time"
+
+	configbase "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
+	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+	"github.com/koderover/zadig/pkg/tool/kube/updater"
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/tool/metrics"
+)
+
+// QueueAlertPayload is posted to the autoscaling webhook when the queue alert fires
+type QueueAlertPayload struct {
+	WaitingTasks        int                    `json:"waiting_tasks"`
+	LongestWaitSeconds  int64                  `json:"longest_wait_seconds"`
+	ThresholdSeconds    int64                  `json:"threshold_seconds"`
+	WorkflowConcurrency int64                  `json:"workflow_concurrency"`
+	Tasks               []*QueueAlertTaskBrief `json:"tasks"`
+	AlertTime           int64                  `json:"alert_time"`
+}
+
+type QueueAlertTaskBrief struct {
+	WorkflowName string `json:"workflow_name"`
+	ProjectName  string `json:"project_name"`
+	TaskID       int64  `json:"task_id"`
+	WaitSeconds  int64  `json:"wait_seconds"`
+}
+
+var (
+	lastQueueAlertTime int64
+	lastQueueScaleTime int64
+	queueAlertMutex    sync.Mutex
+)
+
+// CheckQueueWaitTime updates the queue wait metric, and fires the alert when the tasks waiting for the CI
+// capacity have waited longer than the threshold. Tasks blocked by the concurrency limit of their own
+// workflow are not counted since more capacity does not help them
+func CheckQueueWaitTime() {
+	queueAlertMutex.Lock()
+	defer queueAlertMutex.Unlock()
+
+	now := time.Now().Unix()
+	waitingTasks := make([]*commonmodels.WorkflowQueue, 0)
+	var longestWait int64
+	for _, task := range PendingTasks() {
+		if task.Status == config.StatusBlocked {
+			continue
+		}
+		waitingTasks = append(waitingTasks, task)
+		if wait := now - task.CreateTime; wait > longestWait {
+			longestWait = wait
+		}
+	}
+	metrics.SetQueueWaitSeconds(longestWait)
+
+	sysSetting, err := commonrepo.NewSystemSettingColl().Get()
+	if err != nil {
+		log.Errorf("failed to get system setting, err: %s", err)
+		return
+	}
+	alertConfig := sysSetting.QueueAlert
+	if alertConfig == nil || !alertConfig.Enabled || alertConfig.Threshold <= 0 {
+		return
+	}
+	threshold := alertConfig.Threshold * 60
+	if longestWait < threshold {
+		if alertConfig.ScaleWarpdrive && len(waitingTasks) == 0 {
+			scaleDownWorkflowConcurrency(sysSetting, now, alertConfig.Cooldown*60)
+		}
+		return
+	}
+	if now-lastQueueAlertTime < alertConfig.Cooldown*60 {
+		return
+	}
+	lastQueueAlertTime = now
+
+	metrics.QueueWaitAlerts.Inc()
+	log.Warnf("[QueueAlert] %d workflow tasks are waiting in the queue, the longest has waited %ds, threshold: %ds", len(waitingTasks), longestWait, threshold)
+
+	if alertConfig.AutoscalingWebhook != "" {
+		payload := &QueueAlertPayload{
+			WaitingTasks:        len(waitingTasks),
+			LongestWaitSeconds:  longestWait,
+			ThresholdSeconds:    threshold,
+			WorkflowConcurrency: sysSetting.WorkflowConcurrency,
+			Tasks:               make([]*QueueAlertTaskBrief, 0, len(waitingTasks)),
+			AlertTime:           now,
+		}
+		for _, task := range waitingTasks {
+			payload.Tasks = append(payload.Tasks, &QueueAlertTaskBrief{
+				WorkflowName: task.WorkflowName,
+				ProjectName:  task.ProjectName,
+				TaskID:       task.TaskID,
+				WaitSeconds:  now - task.CreateTime,
+			})
+		}
+		if _, err := httpclient.Post(alertConfig.AutoscalingWebhook, httpclient.SetBody(payload)); err != nil {
+			log.Errorf("[QueueAlert] failed to call the autoscaling webhook, err: %s", err)
+		}
+	}
+
+	if alertConfig.ScaleWarpdrive {
+		if err := scaleUpWorkflowConcurrency(sysSetting, alertConfig.MaxConcurrency); err != nil {
+			log.Errorf("[QueueAlert] failed to scale warpdrive, err: %s", err)
+		}
+	}
+}
+
+// scaleUpWorkflowConcurrency adds one to the workflow concurrency, the concurrency before scaling is recorded
+// so that it is scaled back when the queue is idle
+func scaleUpWorkflowConcurrency(sysSetting *commonmodels.SystemSetting, maxConcurrency int64) error {
+	concurrency := sysSetting.WorkflowConcurrency + 1
+	if concurrency > maxConcurrency {
+		log.Infof("[QueueAlert] workflow concurrency %d has reached the max %d", sysSetting.WorkflowConcurrency, maxConcurrency)
+		return nil
+	}
+	if sysSetting.QueueAlertScaledFrom == 0 {
+		if err := commonrepo.NewSystemSettingColl().UpdateQueueAlertScaledFrom(sysSetting.WorkflowConcurrency); err != nil {
+			return err
+		}
+	}
+	if err := ScaleWarpdrive(concurrency, sysSetting.BuildConcurrency); err != nil {
+		return err
+	}
+	lastQueueScaleTime = time.Now().Unix()
+	log.Infof("[QueueAlert] workflow concurrency is scaled from %d to %d", sysSetting.WorkflowConcurrency, concurrency)
+	return nil
+}
+
+// scaleDownWorkflowConcurrency removes one from the workflow concurrency scaled up by the queue alert once per
+// cooldown, it only happens when no task is running since scaling warpdrive down may break the running tasks
+func scaleDownWorkflowConcurrency(sysSetting *commonmodels.SystemSetting, now, cooldown int64) {
+	scaledFrom := sysSetting.QueueAlertScaledFrom
+	if scaledFrom <= 0 || now-lastQueueScaleTime < cooldown {
+		return
+	}
+	if sysSetting.WorkflowConcurrency <= scaledFrom {
+		if err := commonrepo.NewSystemSettingColl().UpdateQueueAlertScaledFrom(0); err != nil {
+			log.Errorf("[QueueAlert] failed to reset the scaled concurrency, err: %s", err)
+		}
+		return
+	}
+	if len(RunningTasks()) > 0 {
+		return
+	}
+	pipelineTasks, err := commonrepo.NewQueueColl().List(&commonrepo.ListQueueOption{})
+	if err != nil || len(pipelineTasks) > 0 {
+		return
+	}
+
+	concurrency := sysSetting.WorkflowConcurrency - 1
+	if err := ScaleWarpdrive(concurrency, sysSetting.BuildConcurrency); err != nil {
+		log.Errorf("[QueueAlert] failed to scale warpdrive down, err: %s", err)
+		return
+	}
+	lastQueueScaleTime = now
+	if concurrency <= scaledFrom {
+		if err := commonrepo.NewSystemSettingColl().UpdateQueueAlertScaledFrom(0); err != nil {
+			log.Errorf("[QueueAlert] failed to reset the scaled concurrency, err: %s", err)
+		}
+	}
+	log.Infof("[QueueAlert] workflow concurrency is scaled down from %d to %d", sysSetting.WorkflowConcurrency, concurrency)
+}
+
+// ScaleWarpdrive updates the concurrency setting and scales the warpdrive deployment, where the workflow concurrency happens
+func ScaleWarpdrive(workflowConcurrency, buildConcurrency int64) error {
+	if err := commonrepo.NewSystemSettingColl().UpdateConcurrencySetting(workflowConcurrency, buildConcurrency); err != nil {
+		return err
+	}
+	kubeClient, err := kubeclient.GetKubeClient(config.HubServerAddress(), setting.LocalClusterID)
+	if err != nil {
+		return e.ErrScaleService.AddErr(err)
+	}
+	return updater.ScaleDeployment(config.Namespace(), configbase.WarpDriveServiceName(), int(workflowConcurrency), kubeClient)
+}
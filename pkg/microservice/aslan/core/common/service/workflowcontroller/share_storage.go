@@ -0,0 +1,224 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflowcontroller
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/rand"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller/jobcontroller"
+	"github.com/koderover/zadig/pkg/setting"
+	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
+	"github.com/koderover/zadig/pkg/tool/kube/containerlog"
+	"github.com/koderover/zadig/pkg/tool/kube/getter"
+	"github.com/koderover/zadig/pkg/tool/kube/updater"
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+// GCShareStorage refreshes the usage of the task dirs in the share storage of every cluster and removes the dirs
+// of the finished tasks which exceed the retention, then the dirs of the oldest finished tasks until the quota is met
+func GCShareStorage() {
+	logger := log.SugaredLogger()
+	clusters, err := commonrepo.NewK8SClusterColl().FindConnectedClusters()
+	if err != nil {
+		logger.Errorf("failed to list clusters for share storage gc: %s", err)
+		return
+	}
+	for _, cluster := range clusters {
+		if cluster.ShareStorage.MediumType != types.NFSMedium || cluster.ShareStorage.NFSProperties.PVC == "" {
+			continue
+		}
+		if err := gcClusterShareStorage(cluster, logger); err != nil {
+			logger.Errorf("failed to gc share storage of cluster %s: %s", cluster.Name, err)
+		}
+	}
+}
+
+func gcClusterShareStorage(cluster *commonmodels.K8SCluster, logger *zap.SugaredLogger) error {
+	clusterID := cluster.ID.Hex()
+	output, err := runShareStorageJob(clusterID, fmt.Sprintf("du -sk %s/* 2>/dev/null || true", types.GetShareStorageRoot()), logger)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	usages := make([]*commonmodels.ShareStorageUsage, 0)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		workflowName, taskID, ok := types.ParseShareStorageTaskDir(fields[1])
+		if !ok {
+			continue
+		}
+		usage := &commonmodels.ShareStorageUsage{
+			ClusterID:    clusterID,
+			WorkflowName: workflowName,
+			TaskID:       taskID,
+			SizeInKiB:    size,
+			UpdateTime:   now,
+		}
+		task, err := commonrepo.NewWorkflowTaskV4Store().Find(workflowName, taskID)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// the task has been deleted, nothing will use its dir any more
+			usage.Finished = true
+		} else if err != nil {
+			// the dir is kept since it can't be told whether the task is still running
+			logger.Warnf("failed to find task %s #%d for share storage gc: %s", workflowName, taskID, err)
+		} else {
+			usage.ProjectName = task.ProjectName
+			usage.EndTime = task.EndTime
+			usage.Finished = !isTaskInCompleted(task.Status)
+		}
+		usages = append(usages, usage)
+	}
+
+	removed := pickShareStorageDirsToRemove(usages, cluster.ShareStorage.RetentionHours, cluster.ShareStorage.QuotaInMiB, now)
+	if len(removed) > 0 {
+		dirs := make([]string, 0, len(removed))
+		for _, usage := range removed {
+			// the names are checked by ParseShareStorageTaskDir, quote them anyway as they go to the shell
+			dirs = append(dirs, fmt.Sprintf("'%s'", types.GetShareStorageSubPathPrefix(usage.WorkflowName, usage.TaskID)))
+		}
+		if _, err := runShareStorageJob(clusterID, fmt.Sprintf("rm -rf %s", strings.Join(dirs, " ")), logger); err != nil {
+			return err
+		}
+		logger.Infof("%d task dirs removed from share storage of cluster %s", len(dirs), cluster.Name)
+
+		kept := make([]*commonmodels.ShareStorageUsage, 0, len(usages))
+		for _, usage := range usages {
+			if _, ok := removed[path.Join(usage.WorkflowName, strconv.FormatInt(usage.TaskID, 10))]; !ok {
+				kept = append(kept, usage)
+			}
+		}
+		usages = kept
+	}
+	return commonrepo.NewShareStorageUsageColl().ReplaceClusterUsages(clusterID, usages)
+}
+
+// pickShareStorageDirsToRemove returns the finished tasks exceeding the retention, then the oldest finished tasks
+// until the total usage is within the quota, keyed by workflow name and task id
+func pickShareStorageDirsToRemove(usages []*commonmodels.ShareStorageUsage, retentionHours int, quotaInMiB int64, now int64) map[string]*commonmodels.ShareStorageUsage {
+	resp := make(map[string]*commonmodels.ShareStorageUsage)
+	var total int64
+	finished := make([]*commonmodels.ShareStorageUsage, 0)
+	for _, usage := range usages {
+		if usage.Finished && usage.EndTime+int64(retentionHours)*3600 <= now {
+			resp[path.Join(usage.WorkflowName, strconv.FormatInt(usage.TaskID, 10))] = usage
+			continue
+		}
+		total += usage.SizeInKiB
+		if usage.Finished {
+			finished = append(finished, usage)
+		}
+	}
+	if quotaInMiB <= 0 {
+		return resp
+	}
+
+	sort.SliceStable(finished, func(i, j int) bool {
+		return finished[i].EndTime < finished[j].EndTime
+	})
+	for _, usage := range finished {
+		if total <= quotaInMiB*1024 {
+			break
+		}
+		resp[path.Join(usage.WorkflowName, strconv.FormatInt(usage.TaskID, 10))] = usage
+		total -= usage.SizeInKiB
+	}
+	return resp
+}
+
+func isTaskInCompleted(status config.Status) bool {
+	for _, s := range config.InCompletedStatus() {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// runShareStorageJob runs the script in the root of the share storage of the cluster and returns its output
+func runShareStorageJob(clusterID, script string, logger *zap.SugaredLogger) (string, error) {
+	jobName := fmt.Sprintf("share-storage-%s", rand.String(8))
+	namespace := setting.AttachedClusterNamespace
+	if clusterID == setting.LocalClusterID || clusterID == "" {
+		namespace = config.Namespace()
+	}
+	kubeClient, err := kubeclient.GetKubeClient(config.HubServerAddress(), clusterID)
+	if err != nil {
+		return "", fmt.Errorf("can't init k8s client: %v", err)
+	}
+	kubeApiServer, err := kubeclient.GetKubeAPIReader(config.HubServerAddress(), clusterID)
+	if err != nil {
+		return "", fmt.Errorf("can't init k8s api reader: %v", err)
+	}
+	clientSet, err := kubeclient.GetClientset(config.HubServerAddress(), clusterID)
+	if err != nil {
+		return "", fmt.Errorf("can't init k8s clientset: %v", err)
+	}
+	job, err := jobcontroller.BuildShareStorageJob(jobName, clusterID, script)
+	if err != nil {
+		return "", fmt.Errorf("build share storage job error: %v", err)
+	}
+	job.Namespace = namespace
+	if err := updater.CreateJob(job, kubeClient); err != nil {
+		return "", fmt.Errorf("create job error: %v", err)
+	}
+	defer func() {
+		if err := updater.DeleteJobAndWait(namespace, jobName, kubeClient); err != nil {
+			logger.Errorf("delete job error: %v", err)
+		}
+	}()
+
+	status := jobcontroller.WaitPlainJobEnd(context.Background(), 30, namespace, jobName, kubeClient, kubeApiServer, logger)
+	if status != config.StatusPassed {
+		return "", fmt.Errorf("share storage job %s finished with status %s", jobName, status)
+	}
+	pods, err := getter.ListPods(namespace, labels.Set{"job-name": jobName}.AsSelector(), kubeClient)
+	if err != nil || len(pods) == 0 {
+		return "", fmt.Errorf("failed to find pod of job %s: %v", jobName, err)
+	}
+	buf := new(bytes.Buffer)
+	if err := containerlog.GetContainerLogs(namespace, pods[0].Name, pods[0].Spec.Containers[0].Name, false, 0, buf, clientSet); err != nil {
+		return "", fmt.Errorf("failed to get logs of job %s: %v", jobName, err)
+	}
+	return buf.String(), nil
+}
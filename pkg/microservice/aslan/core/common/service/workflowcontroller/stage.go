@@ -79,13 +79,33 @@ func RunStages(ctx context.Context, stages []*commonmodels.StageTask, workflowCt
 	}
 }
 
-func ApproveStage(workflowName, stageName, userName, userID, comment string, taskID int64, approve bool) error {
+func ApproveStage(workflowName, stageName, userName, userID, comment string, taskID int64, approve bool, source config.ApprovalSource) error {
 	approveKey := fmt.Sprintf("%s-%d-%s", workflowName, taskID, stageName)
 	approveWithL, ok := approvalservice.GlobalApproveMap.GetApproval(approveKey)
 	if !ok {
-		return fmt.Errorf("workflow %s ID %d stage %s do not need approve", workflowName, taskID, stageName)
+		if !isTaskWaitingApprove(workflowName, stageName, taskID) {
+			return fmt.Errorf("workflow %s ID %d stage %s do not need approve", workflowName, taskID, stageName)
+		}
+		// the task is running in another controller instance
+		return sendControlMessage(&workflowControlMessage{
+			Type:         controlTypeApprove,
+			WorkflowName: workflowName,
+			TaskID:       taskID,
+			StageName:    stageName,
+			UserName:     userName,
+			UserID:       userID,
+			Comment:      comment,
+			Approve:      approve,
+			Source:       source,
+		})
+	}
+	if err := approveWithL.DoApproval(userName, userID, comment, approve); err != nil {
+		return err
 	}
-	return approveWithL.DoApproval(userName, userID, comment, approve)
+	if err := approveWithL.RecordTimeline(workflowName, taskID, stageName, userName, userID, comment, approve, source); err != nil {
+		log.Errorf("failed to record the approval of workflow %s task %d stage %s: %v", workflowName, taskID, stageName, err)
+	}
+	return nil
 }
 
 func waitForApprove(ctx context.Context, stage *commonmodels.StageTask, workflowCtx *commonmodels.WorkflowTaskCtx, logger *zap.SugaredLogger, ack func()) (err error) {
@@ -109,6 +129,9 @@ func waitForApprove(ctx context.Context, stage *commonmodels.StageTask, workflow
 		} else {
 			stage.Approval.Status = stage.Status
 		}
+		if recordErr := approvalservice.RecordApproval(workflowCtx, stage); recordErr != nil {
+			logger.Errorf("failed to record the approval of stage %s, err: %s", stage.Name, recordErr)
+		}
 	}()
 	// workflowCtx.SetStatus contain ack() function, so we don't need to call ack() here
 	stage.Status = config.StatusWaitingApprove
@@ -139,7 +162,7 @@ func waitForNativeApprove(ctx context.Context, stage *commonmodels.StageTask, wo
 		approval.Timeout = 60
 	}
 	approveKey := fmt.Sprintf("%s-%d-%s", workflowCtx.WorkflowName, workflowCtx.TaskID, stage.Name)
-	approveWithL := &approvalservice.ApproveWithLock{Approval: approval}
+	approveWithL := &approvalservice.ApproveWithLock{Approval: approval, StartTime: stage.Approval.StartTime}
 	approvalservice.GlobalApproveMap.SetApproval(approveKey, approveWithL)
 	defer func() {
 		approvalservice.GlobalApproveMap.DeleteApproval(approveKey)
@@ -148,6 +171,12 @@ func waitForNativeApprove(ctx context.Context, stage *commonmodels.StageTask, wo
 	if err := instantmessage.NewWeChatClient().SendWorkflowTaskAproveNotifications(workflowCtx.WorkflowName, workflowCtx.TaskID); err != nil {
 		logger.Errorf("send approve notification failed, error: %v", err)
 	}
+	if approval.LarkAppID != "" {
+		sendNativeApprovalLarkCards(approval, stage.Name, workflowCtx, logger)
+	}
+	if approval.DingTalkAppID != "" {
+		sendNativeApprovalDingTalkCards(approval, stage.Name, workflowCtx, logger)
+	}
 
 	timeout := time.After(time.Duration(approval.Timeout) * time.Minute)
 	latestApproveCount := 0
@@ -178,6 +207,86 @@ func waitForNativeApprove(ctx context.Context, stage *commonmodels.StageTask, wo
 	}
 }
 
+// sendNativeApprovalLarkCards sends the approvers who have not operated a card to approve or reject in lark,
+// user groups are not expanded, their members approve in zadig.
+func sendNativeApprovalLarkCards(approval *commonmodels.NativeApproval, stageName string, workflowCtx *commonmodels.WorkflowTaskCtx, logger *zap.SugaredLogger) {
+	client, err := larkservice.GetLarkClientByIMAppID(approval.LarkAppID)
+	if err != nil {
+		logger.Errorf("get lark client of native approval failed, error: %v", err)
+		return
+	}
+	task := &commonmodels.WorkflowTask{
+		WorkflowName:        workflowCtx.WorkflowName,
+		WorkflowDisplayName: workflowCtx.WorkflowDisplayName,
+		ProjectName:         workflowCtx.ProjectName,
+		TaskID:              workflowCtx.TaskID,
+		TaskCreator:         workflowCtx.WorkflowTaskCreatorUsername,
+	}
+	for _, user := range approval.ApproveUsers {
+		if user.Type == "group" || user.UserID == "" || user.RejectOrApprove != "" {
+			continue
+		}
+		openID, err := larkservice.GetUserOpenIDByZadigUser(client, user.UserID)
+		if err != nil {
+			logger.Warnf("find lark user of approver %s failed, error: %v", user.UserName, err)
+			continue
+		}
+		if err := client.SendCardToUser(openID, instantmessage.NewNativeApprovalLarkCard(task, stageName, user.UserID)); err != nil {
+			logger.Errorf("send lark approval card to %s failed, error: %v", user.UserName, err)
+		}
+	}
+}
+
+func sendNativeApprovalDingTalkCards(approval *commonmodels.NativeApproval, stageName string, workflowCtx *commonmodels.WorkflowTaskCtx, logger *zap.SugaredLogger) {
+	app, err := mongodb.NewIMAppColl().GetByID(context.Background(), approval.DingTalkAppID)
+	if err != nil {
+		logger.Errorf("get dingtalk app of native approval failed, error: %v", err)
+		return
+	}
+	client := dingtalk.NewClient(app.DingTalkAppKey, app.DingTalkAppSecret)
+	expireAt := time.Now().Add(time.Duration(approval.Timeout) * time.Minute).Unix()
+	for _, user := range approval.ApproveUsers {
+		if user.Type == "group" || user.UserID == "" || user.RejectOrApprove != "" {
+			continue
+		}
+		dingTalkUserID, err := dingservice.GetUserIDByZadigUser(client, user.UserID)
+		if err != nil {
+			logger.Warnf("find dingtalk user of approver %s failed, error: %v", user.UserName, err)
+			continue
+		}
+		action := &dingservice.ApprovalAction{
+			WorkflowName: workflowCtx.WorkflowName,
+			TaskID:       workflowCtx.TaskID,
+			StageName:    stageName,
+			UserID:       user.UserID,
+			ExpireAt:     expireAt,
+		}
+		rejectURL, err := dingservice.NewApprovalActionURL(app.DingTalkAppKey, app.DingTalkAppSecret, action)
+		if err != nil {
+			logger.Errorf("generate dingtalk approval link failed, error: %v", err)
+			continue
+		}
+		action.Approve = true
+		approveURL, err := dingservice.NewApprovalActionURL(app.DingTalkAppKey, app.DingTalkAppSecret, action)
+		if err != nil {
+			logger.Errorf("generate dingtalk approval link failed, error: %v", err)
+			continue
+		}
+		card := &dingtalk.ActionCard{
+			Title: fmt.Sprintf("工作流 %s #%d 等待您审批", workflowCtx.WorkflowDisplayName, workflowCtx.TaskID),
+			Text: fmt.Sprintf("### 工作流 %s #%d 等待您审批\n\n**审批阶段**：%s\n\n**执行用户**：%s",
+				workflowCtx.WorkflowDisplayName, workflowCtx.TaskID, stageName, workflowCtx.WorkflowTaskCreatorUsername),
+			ActionTitle1: "通过",
+			ActionURL1:   fmt.Sprintf("dingtalk://dingtalkclient/page/link?pc_slide=false&url=%s", url.QueryEscape(approveURL)),
+			ActionTitle2: "拒绝",
+			ActionURL2:   fmt.Sprintf("dingtalk://dingtalkclient/page/link?pc_slide=false&url=%s", url.QueryEscape(rejectURL)),
+		}
+		if err := client.SendActionCard([]string{dingTalkUserID}, card); err != nil {
+			logger.Errorf("send dingtalk approval card to %s failed, error: %v", user.UserName, err)
+		}
+	}
+}
+
 func waitForLarkApprove(ctx context.Context, stage *commonmodels.StageTask, workflowCtx *commonmodels.WorkflowTaskCtx, logger *zap.SugaredLogger, ack func()) error {
 	log.Infof("waitForLarkApprove start")
 	approval := stage.Approval.LarkApproval
@@ -73,7 +73,7 @@ func instantiateStepCtl(step *commonmodels.StepTask, workflowCtx *commonmodels.W
 	switch step.StepType {
 	case config.StepGit:
 		stepCtl, err = NewGitCtl(step, logger)
-	case config.StepShell:
+	case config.StepShell, config.StepPowerShell:
 		stepCtl, err = NewShellCtl(step, logger)
 	case config.StepDockerBuild:
 		stepCtl, err = NewDockerBuildCtl(step, logger)
@@ -82,7 +82,7 @@ func instantiateStepCtl(step *commonmodels.StepTask, workflowCtx *commonmodels.W
 	case config.StepArchive:
 		stepCtl, err = NewArchiveCtl(step, logger)
 	case config.StepJunitReport:
-		stepCtl, err = NewJunitReportCtl(step, logger)
+		stepCtl, err = NewJunitReportCtl(step, workflowCtx, logger)
 	case config.StepTarArchive:
 		stepCtl, err = NewTarArchiveCtl(step, logger)
 	case config.StepSonarCheck:
@@ -91,6 +91,16 @@ func instantiateStepCtl(step *commonmodels.StepTask, workflowCtx *commonmodels.W
 		stepCtl, err = NewDistributeCtl(step, workflowCtx, jobName, logger)
 	case config.StepDebugBefore, config.StepDebugAfter:
 		stepCtl, err = NewDebugCtl()
+	case config.StepRestoreCache, config.StepSaveCache:
+		stepCtl, err = NewCacheCtl(step, logger)
+	case config.StepCoverage:
+		stepCtl, err = NewCoverageCtl(step, workflowCtx, logger)
+	case config.StepPerformance:
+		stepCtl, err = NewPerformanceCtl(step, workflowCtx, logger)
+	case config.StepServiceReady:
+		stepCtl, err = NewServiceReadyCtl()
+	case config.StepUploadJobFiles, config.StepDownloadJobFiles:
+		stepCtl, err = NewJobFileCtl()
 	default:
 		logger.Errorf("unknown step type: %s", step.StepType)
 		return stepCtl, fmt.Errorf("unknown step type: %s", step.StepType)
@@ -0,0 +1,150 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stepcontroller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
+	s3tool "github.com/koderover/zadig/pkg/tool/s3"
+	"github.com/koderover/zadig/pkg/types/step"
+	"github.com/koderover/zadig/pkg/util"
+)
+
+type coverageCtl struct {
+	step         *commonmodels.StepTask
+	coverageSpec *step.StepCoverageSpec
+	workflowCtx  *commonmodels.WorkflowTaskCtx
+	log          *zap.SugaredLogger
+}
+
+func NewCoverageCtl(stepTask *commonmodels.StepTask, workflowCtx *commonmodels.WorkflowTaskCtx, log *zap.SugaredLogger) (*coverageCtl, error) {
+	yamlString, err := yaml.Marshal(stepTask.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("marshal coverage spec error: %v", err)
+	}
+	coverageSpec := &step.StepCoverageSpec{}
+	if err := yaml.Unmarshal(yamlString, &coverageSpec); err != nil {
+		return nil, fmt.Errorf("unmarshal coverage spec error: %v", err)
+	}
+	stepTask.Spec = coverageSpec
+	return &coverageCtl{coverageSpec: coverageSpec, workflowCtx: workflowCtx, log: log, step: stepTask}, nil
+}
+
+func (s *coverageCtl) PreRun(ctx context.Context) error {
+	if s.coverageSpec.S3Storage == nil {
+		modelS3, err := commonrepo.NewS3StorageColl().FindDefault()
+		if err != nil {
+			return err
+		}
+		s.coverageSpec.S3Storage = modelS3toS3(modelS3)
+	}
+	s.setBaseCoverage()
+	s.step.Spec = s.coverageSpec
+	return nil
+}
+
+// setBaseCoverage sets the latest coverage of the base branch recorded for the same service module for the
+// regression gate, the gate is skipped if there is none
+func (s *coverageCtl) setBaseCoverage() {
+	spec := s.coverageSpec
+	if spec.MaxRegression <= 0 || spec.BaseBranch == "" || (!spec.IsPR && spec.BaseBranch == spec.Branch) || s.workflowCtx == nil {
+		return
+	}
+	base, err := commonrepo.NewCoverageRecordColl().FindLatest(&commonrepo.CoverageRecordListOption{
+		ProjectName:   s.workflowCtx.ProjectName,
+		Name:          spec.Name,
+		ServiceName:   spec.ServiceName,
+		ServiceModule: spec.ServiceModule,
+		Branch:        spec.BaseBranch,
+	})
+	if err != nil {
+		s.log.Warnf("failed to find coverage of base branch %s for %s: %s", spec.BaseBranch, spec.Name, err)
+	}
+	if base != nil {
+		spec.BaseCoverage = base.Coverage
+		spec.HasBase = true
+	}
+}
+
+// AfterRun records the coverage summary uploaded by the job, a missing summary means the report was not parsed.
+// Pull request builds are not recorded, the coverage of the branch is the one after the merge
+func (s *coverageCtl) AfterRun(ctx context.Context) error {
+	storage := s.coverageSpec.S3Storage
+	if storage == nil || s.workflowCtx == nil || s.coverageSpec.IsPR {
+		return nil
+	}
+	forcedPathStyle := true
+	if storage.Provider == setting.ProviderSourceAli {
+		forcedPathStyle = false
+	}
+	client, err := s3tool.NewClient(storage.Endpoint, storage.Ak, storage.Sk, storage.Region, storage.Insecure, forcedPathStyle)
+	if err != nil {
+		s.log.Errorf("failed to create s3 client, err: %s", err)
+		return err
+	}
+	filename, err := util.GenerateTmpFile()
+	if err != nil {
+		return err
+	}
+	defer os.Remove(filename)
+
+	objectKey := path.Join(storage.Subfolder, s.coverageSpec.S3DestDir, step.CoverageSummaryFile)
+	if err := client.DownloadWithOption(storage.Bucket, objectKey, filename, &s3tool.DownloadOption{IgnoreNotExistError: true, RetryNum: 2}); err != nil {
+		s.log.Errorf("failed to download coverage summary, err: %s", err)
+		return err
+	}
+	content, err := os.ReadFile(filename)
+	if err != nil || len(content) == 0 {
+		return nil
+	}
+	summary := &step.CoverageSummary{}
+	if err := json.Unmarshal(content, summary); err != nil {
+		s.log.Errorf("failed to unmarshal coverage summary, err: %s", err)
+		return err
+	}
+
+	record := &commonmodels.CoverageRecord{
+		ProjectName:   s.workflowCtx.ProjectName,
+		Name:          s.coverageSpec.Name,
+		ServiceName:   s.coverageSpec.ServiceName,
+		ServiceModule: s.coverageSpec.ServiceModule,
+		Branch:        s.coverageSpec.Branch,
+		WorkflowName:  s.workflowCtx.WorkflowName,
+		TaskID:        s.workflowCtx.TaskID,
+		JobName:       s.step.JobName,
+		LinesCovered:  summary.LinesCovered,
+		LinesTotal:    summary.LinesTotal,
+		Coverage:      summary.Coverage,
+		CreateTime:    time.Now().Unix(),
+	}
+	if err := commonrepo.NewCoverageRecordColl().Create(record); err != nil {
+		s.log.Errorf("failed to save coverage record, err: %s", err)
+		return err
+	}
+	return nil
+}
@@ -41,10 +41,11 @@ import (
 type junitReportCtl struct {
 	step            *commonmodels.StepTask
 	junitReportSpec *step.StepJunitReportSpec
+	workflowCtx     *commonmodels.WorkflowTaskCtx
 	log             *zap.SugaredLogger
 }
 
-func NewJunitReportCtl(stepTask *commonmodels.StepTask, log *zap.SugaredLogger) (*junitReportCtl, error) {
+func NewJunitReportCtl(stepTask *commonmodels.StepTask, workflowCtx *commonmodels.WorkflowTaskCtx, log *zap.SugaredLogger) (*junitReportCtl, error) {
 	yamlString, err := yaml.Marshal(stepTask.Spec)
 	if err != nil {
 		return nil, fmt.Errorf("marshal junit report spec error: %v", err)
@@ -54,7 +55,7 @@ func NewJunitReportCtl(stepTask *commonmodels.StepTask, log *zap.SugaredLogger)
 		return nil, fmt.Errorf("unmarshal junit report spec error: %v", err)
 	}
 	stepTask.Spec = junitReportSpec
-	return &junitReportCtl{junitReportSpec: junitReportSpec, log: log, step: stepTask}, nil
+	return &junitReportCtl{junitReportSpec: junitReportSpec, workflowCtx: workflowCtx, log: log, step: stepTask}, nil
 }
 
 func (s *junitReportCtl) PreRun(ctx context.Context) error {
@@ -120,6 +121,8 @@ func (s *junitReportCtl) AfterRun(ctx context.Context) error {
 		log.Error("uploadTaskData testSuite unmarshal it report xml error: %v", err)
 		return err
 	}
+	s.saveCaseResults(testReport)
+
 	totalCaseNum := testReport.Tests
 	if totalCaseNum != 0 {
 		testTaskStat.TestCaseNum = totalCaseNum
@@ -133,3 +136,27 @@ func (s *junitReportCtl) AfterRun(ctx context.Context) error {
 	}
 	return nil
 }
+
+// saveCaseResults stores the structured case results for the trend, flaky detection and history of the test
+func (s *junitReportCtl) saveCaseResults(testReport *commonmodels.TestSuite) {
+	if s.workflowCtx == nil {
+		return
+	}
+	coll := commonrepo.NewTestCaseResultColl()
+	if err := coll.DeleteByTask(s.workflowCtx.ProjectName, s.workflowCtx.WorkflowName, s.step.JobName, s.workflowCtx.TaskID); err != nil {
+		s.log.Warnf("failed to clean case results of test %s: %s", s.junitReportSpec.TestName, err)
+	}
+	now := time.Now().Unix()
+	results := commonmodels.NewTestCaseResults(testReport)
+	for _, result := range results {
+		result.ProjectName = s.workflowCtx.ProjectName
+		result.TestName = s.junitReportSpec.TestName
+		result.WorkflowName = s.workflowCtx.WorkflowName
+		result.TaskID = s.workflowCtx.TaskID
+		result.JobName = s.step.JobName
+		result.CreateTime = now
+	}
+	if err := coll.BulkCreate(results); err != nil {
+		s.log.Errorf("failed to save case results of test %s: %s", s.junitReportSpec.TestName, err)
+	}
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stepcontroller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v2"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
+	s3tool "github.com/koderover/zadig/pkg/tool/s3"
+	"github.com/koderover/zadig/pkg/types/step"
+	"github.com/koderover/zadig/pkg/util"
+)
+
+type performanceCtl struct {
+	step            *commonmodels.StepTask
+	performanceSpec *step.StepPerformanceSpec
+	workflowCtx     *commonmodels.WorkflowTaskCtx
+	log             *zap.SugaredLogger
+}
+
+func NewPerformanceCtl(stepTask *commonmodels.StepTask, workflowCtx *commonmodels.WorkflowTaskCtx, log *zap.SugaredLogger) (*performanceCtl, error) {
+	yamlString, err := yaml.Marshal(stepTask.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("marshal performance spec error: %v", err)
+	}
+	performanceSpec := &step.StepPerformanceSpec{}
+	if err := yaml.Unmarshal(yamlString, &performanceSpec); err != nil {
+		return nil, fmt.Errorf("unmarshal performance spec error: %v", err)
+	}
+	stepTask.Spec = performanceSpec
+	return &performanceCtl{performanceSpec: performanceSpec, workflowCtx: workflowCtx, log: log, step: stepTask}, nil
+}
+
+func (s *performanceCtl) PreRun(ctx context.Context) error {
+	if s.performanceSpec.S3Storage == nil {
+		modelS3, err := commonrepo.NewS3StorageColl().FindDefault()
+		if err != nil {
+			return err
+		}
+		s.performanceSpec.S3Storage = modelS3toS3(modelS3)
+	}
+	s.step.Spec = s.performanceSpec
+	return nil
+}
+
+// AfterRun records the performance summary uploaded by the job, a missing summary means the result was not parsed
+func (s *performanceCtl) AfterRun(ctx context.Context) error {
+	storage := s.performanceSpec.S3Storage
+	if storage == nil || s.workflowCtx == nil {
+		return nil
+	}
+	forcedPathStyle := true
+	if storage.Provider == setting.ProviderSourceAli {
+		forcedPathStyle = false
+	}
+	client, err := s3tool.NewClient(storage.Endpoint, storage.Ak, storage.Sk, storage.Region, storage.Insecure, forcedPathStyle)
+	if err != nil {
+		s.log.Errorf("failed to create s3 client, err: %s", err)
+		return err
+	}
+	filename, err := util.GenerateTmpFile()
+	if err != nil {
+		return err
+	}
+	defer os.Remove(filename)
+
+	objectKey := path.Join(storage.Subfolder, s.performanceSpec.S3DestDir, step.PerformanceSummaryFile)
+	if err := client.DownloadWithOption(storage.Bucket, objectKey, filename, &s3tool.DownloadOption{IgnoreNotExistError: true, RetryNum: 2}); err != nil {
+		s.log.Errorf("failed to download performance summary, err: %s", err)
+		return err
+	}
+	content, err := os.ReadFile(filename)
+	if err != nil || len(content) == 0 {
+		return nil
+	}
+	summary := &step.PerformanceSummary{}
+	if err := json.Unmarshal(content, summary); err != nil {
+		s.log.Errorf("failed to unmarshal performance summary, err: %s", err)
+		return err
+	}
+
+	record := &commonmodels.PerformanceRecord{
+		ProjectName:  s.workflowCtx.ProjectName,
+		WorkflowName: s.workflowCtx.WorkflowName,
+		TaskID:       s.workflowCtx.TaskID,
+		JobName:      s.step.JobName,
+		Tool:         summary.Tool,
+		Metrics:      summary.Metrics,
+		Regressions:  summary.Regressions,
+		CreateTime:   time.Now().Unix(),
+	}
+	if s.performanceSpec.Baseline != nil {
+		record.BaselineTaskID = s.performanceSpec.BaselineTaskID
+	}
+	if err := commonrepo.NewPerformanceRecordColl().Create(record); err != nil {
+		s.log.Errorf("failed to save performance record, err: %s", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stepcontroller
+
+import (
+	"context"
+)
+
+// serviceReadyCtl has nothing to prepare, the service containers are checked inside the job pod
+type serviceReadyCtl struct{}
+
+func NewServiceReadyCtl() (*serviceReadyCtl, error) {
+	return &serviceReadyCtl{}, nil
+}
+
+func (c *serviceReadyCtl) PreRun(ctx context.Context) error {
+	return nil
+}
+
+func (c *serviceReadyCtl) AfterRun(ctx context.Context) error {
+	return nil
+}
@@ -18,13 +18,23 @@ package stepcontroller
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"os"
+	"path"
+	"strings"
 
 	"go.uber.org/zap"
 	"gopkg.in/yaml.v2"
 
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/scmnotify"
+	"github.com/koderover/zadig/pkg/setting"
+	s3tool "github.com/koderover/zadig/pkg/tool/s3"
+	"github.com/koderover/zadig/pkg/tool/sonar"
 	"github.com/koderover/zadig/pkg/types/step"
+	"github.com/koderover/zadig/pkg/util"
 )
 
 type sonarCheckCtl struct {
@@ -47,9 +57,85 @@ func NewSonarCheckCtl(stepTask *commonmodels.StepTask, log *zap.SugaredLogger) (
 }
 
 func (s *sonarCheckCtl) PreRun(ctx context.Context) error {
+	if s.sonarCheckSpec.S3DestDir != "" && s.sonarCheckSpec.S3Storage == nil {
+		modelS3, err := commonrepo.NewS3StorageColl().FindDefault()
+		if err != nil {
+			return err
+		}
+		s.sonarCheckSpec.S3Storage = modelS3toS3(modelS3)
+	}
+	s.step.Spec = s.sonarCheckSpec
 	return nil
 }
 
+// AfterRun decorates the pull request with the quality gate result archived by the job
 func (s *sonarCheckCtl) AfterRun(ctx context.Context) error {
+	decoration := s.sonarCheckSpec.PRDecoration
+	storage := s.sonarCheckSpec.S3Storage
+	if decoration == nil || decoration.PR == 0 || storage == nil {
+		return nil
+	}
+
+	forcedPathStyle := true
+	if storage.Provider == setting.ProviderSourceAli {
+		forcedPathStyle = false
+	}
+	client, err := s3tool.NewClient(storage.Endpoint, storage.Ak, storage.Sk, storage.Region, storage.Insecure, forcedPathStyle)
+	if err != nil {
+		s.log.Errorf("failed to create s3 client, err: %s", err)
+		return err
+	}
+	filename, err := util.GenerateTmpFile()
+	if err != nil {
+		return err
+	}
+	defer os.Remove(filename)
+
+	objectKey := path.Join(storage.Subfolder, s.sonarCheckSpec.S3DestDir, step.SonarResultFile)
+	if err := client.DownloadWithOption(storage.Bucket, objectKey, filename, &s3tool.DownloadOption{IgnoreNotExistError: true, RetryNum: 2}); err != nil {
+		s.log.Errorf("failed to download sonar result, err: %s", err)
+		return err
+	}
+	content, err := os.ReadFile(filename)
+	if err != nil || len(content) == 0 {
+		return nil
+	}
+	result := &sonar.QualityGateResult{}
+	if err := json.Unmarshal(content, result); err != nil {
+		s.log.Errorf("failed to unmarshal sonar result, err: %s", err)
+		return err
+	}
+
+	if err := scmnotify.NewClient().CommentPR(decoration.CodehostID, decoration.RepoNamespace, decoration.RepoName, decoration.PR, sonarResultComment(result)); err != nil {
+		s.log.Errorf("failed to decorate pr %d of %s/%s, err: %s", decoration.PR, decoration.RepoNamespace, decoration.RepoName, err)
+		return err
+	}
 	return nil
 }
+
+func sonarResultComment(result *sonar.QualityGateResult) string {
+	emoji := "✅"
+	if result.Status != sonar.QualityGateOK && result.Status != sonar.QualityGateNone {
+		emoji = "❌"
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("### SonarQube Quality Gate %s %s\n\n", emoji, result.Status))
+	if len(result.Conditions) > 0 {
+		sb.WriteString("| Metric | Status | Threshold | Actual |\n| --- | --- | --- | --- |\n")
+		for _, condition := range result.Conditions {
+			sb.WriteString(fmt.Sprintf("| %s | %s | %s %s | %s |\n", condition.MetricKey, condition.Status, condition.Comparator, condition.ErrorThreshold, condition.ActualValue))
+		}
+		sb.WriteString("\n")
+	}
+	if result.TotalIssues > 0 {
+		sb.WriteString(fmt.Sprintf("**%d open issue(s)**, the most severe ones:\n\n", result.TotalIssues))
+		for _, issue := range result.Issues {
+			sb.WriteString(fmt.Sprintf("- `%s` %s: %s (%s:%d)\n", issue.Severity, issue.Type, issue.Message, issue.Component, issue.Line))
+		}
+		sb.WriteString("\n")
+	}
+	if result.Link != "" {
+		sb.WriteString(fmt.Sprintf("[View the analysis on SonarQube](%s)\n", result.Link))
+	}
+	return sb.String()
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflowcontroller
+
+import (
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+// tenantTaskCounter counts the running and queued tasks of the tenants to apply their concurrent task quota
+type tenantTaskCounter struct {
+	projectTenants map[string]*commonmodels.Tenant
+	running        map[string]int
+}
+
+func newTenantTaskCounter() *tenantTaskCounter {
+	counter := &tenantTaskCounter{
+		projectTenants: make(map[string]*commonmodels.Tenant),
+		running:        make(map[string]int),
+	}
+	tenants, err := commonrepo.NewTenantColl().List()
+	if err != nil {
+		log.Errorf("WorkflowV4 Queue: list tenants error: %v", err)
+		return counter
+	}
+	for _, tenant := range tenants {
+		if tenant.Quota == nil || tenant.Quota.ConcurrentTasks <= 0 {
+			continue
+		}
+		for _, project := range tenant.Projects {
+			counter.projectTenants[project] = tenant
+		}
+	}
+	if len(counter.projectTenants) == 0 {
+		return counter
+	}
+	for _, t := range RunningAndQueuedTasks() {
+		if tenant, ok := counter.projectTenants[t.ProjectName]; ok {
+			counter.running[tenant.Name]++
+		}
+	}
+	return counter
+}
+
+// available returns false if the tenant of the project has reached its concurrent task quota
+func (c *tenantTaskCounter) available(projectName string) bool {
+	tenant, ok := c.projectTenants[projectName]
+	if !ok {
+		return true
+	}
+	return c.running[tenant.Name] < tenant.Quota.ConcurrentTasks
+}
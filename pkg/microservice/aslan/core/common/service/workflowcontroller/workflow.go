@@ -49,6 +49,8 @@ type workflowCtl struct {
 	clusterIDMutex     sync.RWMutex
 	logger             *zap.SugaredLogger
 	ack                func()
+	// resumed means the task is adopted from a dead controller instance
+	resumed bool
 }
 
 func NewWorkflowController(workflowTask *commonmodels.WorkflowTask, logger *zap.SugaredLogger) *workflowCtl {
@@ -61,7 +63,7 @@ func NewWorkflowController(workflowTask *commonmodels.WorkflowTask, logger *zap.
 }
 
 func CancelWorkflowTask(userName, workflowName string, taskID int64, logger *zap.SugaredLogger) error {
-	t, err := commonrepo.NewworkflowTaskv4Coll().Find(workflowName, taskID)
+	t, err := commonrepo.NewWorkflowTaskV4Store().Find(workflowName, taskID)
 	if err != nil {
 		logger.Errorf("[%s] task: %s:%d not found", userName, workflowName, taskID)
 		return err
@@ -83,7 +85,7 @@ func CancelWorkflowTask(userName, workflowName string, taskID int64, logger *zap
 
 	logger.Infof("[%s] CancelRunningTask %s:%d", userName, taskID, taskID)
 
-	if err := commonrepo.NewworkflowTaskv4Coll().Update(t.ID.Hex(), t); err != nil {
+	if err := commonrepo.NewWorkflowTaskV4Store().Update(t.ID.Hex(), t); err != nil {
 		logger.Errorf("[%s] update task: %s:%d error: %v", userName, workflowName, taskID, err)
 		return err
 	}
@@ -98,7 +100,11 @@ func CancelWorkflowTask(userName, workflowName string, taskID int64, logger *zap
 
 	value, ok := cancelChannelMap.Load(fmt.Sprintf("%s-%d", workflowName, taskID))
 	if !ok {
-		logger.Errorf("no mactched task found, id: %d, workflow name: %s", taskID, workflowName)
+		// the task may be running in another controller instance
+		logger.Infof("no mactched task found in the instance, id: %d, workflow name: %s", taskID, workflowName)
+		if err := sendControlMessage(&workflowControlMessage{Type: controlTypeCancel, WorkflowName: workflowName, TaskID: taskID}); err != nil {
+			logger.Errorf("failed to send cancel message of task %s:%d, err: %s", workflowName, taskID, err)
+		}
 		return nil
 	}
 	if f, ok := value.(context.CancelFunc); ok {
@@ -125,7 +131,9 @@ func (c *workflowCtl) Run(ctx context.Context, concurrency int) {
 	defer removeWorkflowTaskInMap(c.workflowTask.WorkflowName, c.workflowTask.TaskID)
 
 	c.workflowTask.Status = config.StatusRunning
-	c.workflowTask.StartTime = time.Now().Unix()
+	if !c.resumed {
+		c.workflowTask.StartTime = time.Now().Unix()
+	}
 	c.ack()
 	c.logger.Infof("start workflow: %s,status: %s", c.workflowTask.WorkflowName, c.workflowTask.Status)
 	defer func() {
@@ -168,8 +176,18 @@ func (c *workflowCtl) Run(ctx context.Context, concurrency int) {
 	if err := scmnotify.NewService().UpdateGitCheckForWorkflowV4(c.workflowTask.WorkflowArgs, c.workflowTask.TaskID, c.logger); err != nil {
 		log.Warnf("Failed to update github check status for custom workflow %s, taskID: %d the error is: %s", c.workflowTask.WorkflowName, c.workflowTask.TaskID, err)
 	}
-	RunStages(ctx, c.workflowTask.Stages, workflowCtx, concurrency, c.logger, c.ack)
+	runCtx := ctx
+	if quota := jobcontroller.GetProjectResourceQuota(c.workflowTask.ProjectName); quota != nil && quota.MaxTaskDuration > 0 {
+		var cancelRun context.CancelFunc
+		runCtx, cancelRun = context.WithTimeout(ctx, time.Duration(quota.MaxTaskDuration)*time.Minute)
+		defer cancelRun()
+	}
+	RunStages(runCtx, c.workflowTask.Stages, workflowCtx, concurrency, c.logger, c.ack)
 	updateworkflowStatus(c.workflowTask)
+	if runCtx.Err() == context.DeadlineExceeded {
+		c.workflowTask.Status = config.StatusTimeout
+		c.workflowTask.Error = "task exceeded the max duration of the project resource quota"
+	}
 }
 
 func updateworkflowStatus(workflow *commonmodels.WorkflowTask) {
@@ -211,7 +229,7 @@ func updateworkflowStatus(workflow *commonmodels.WorkflowTask) {
 }
 
 func (c *workflowCtl) updateWorkflowTask() {
-	taskInColl, err := commonrepo.NewworkflowTaskv4Coll().Find(c.workflowTask.WorkflowName, c.workflowTask.TaskID)
+	taskInColl, err := commonrepo.NewWorkflowTaskV4Store().Find(c.workflowTask.WorkflowName, c.workflowTask.TaskID)
 	if err != nil {
 		c.logger.Errorf("find workflow task v4 %s failed,error: %v", c.workflowTask.WorkflowName, err)
 		return
@@ -233,8 +251,16 @@ func (c *workflowCtl) updateWorkflowTask() {
 	if success := UpdateQueue(c.workflowTask); !success {
 		c.logger.Errorf("%s:%d update t status error", c.workflowTask.WorkflowName, c.workflowTask.TaskID)
 	}
+	// the reported stage statuses are saved in the task args, so the commit statuses are updated before saving the task
+	if err := scmnotify.NewService().UpdateGitlabCommitStatusForWorkflowV4(c.workflowTask, c.logger); err != nil {
+		c.logger.Warnf("failed to update gitlab commit status for custom workflow %s, taskID: %d the error is: %s", c.workflowTask.WorkflowName, c.workflowTask.TaskID, err)
+	}
+	// Updating the comment in the git repository, the comment is only updated when the status of the task or its jobs changed
+	if err := scmnotify.NewService().UpdateWebhookCommentForWorkflowV4(c.workflowTask, c.logger); err != nil {
+		log.Warnf("Failed to update comment for custom workflow %s, taskID: %d the error is: %s", c.workflowTask.WorkflowName, c.workflowTask.TaskID, err)
+	}
 	// TODO update workflow task
-	if err := commonrepo.NewworkflowTaskv4Coll().Update(c.workflowTask.ID.Hex(), c.workflowTask); err != nil {
+	if err := commonrepo.NewWorkflowTaskV4Store().Update(c.workflowTask.ID.Hex(), c.workflowTask); err != nil {
 		c.logger.Errorf("update workflow task v4 failed,error: %v", err)
 	}
 
@@ -243,6 +269,7 @@ func (c *workflowCtl) updateWorkflowTask() {
 		if err := instantmessage.NewWeChatClient().SendWorkflowTaskNotifications(c.workflowTask); err != nil {
 			c.logger.Errorf("send workflow task notification failed, error: %v", err)
 		}
+		syncMeegoWorkItems(c.workflowTask, c.logger)
 		q := ConvertTaskToQueue(c.workflowTask)
 		if err := Remove(q); err != nil {
 			c.logger.Errorf("remove queue task: %s:%d error: %v", c.workflowTask.WorkflowName, c.workflowTask.TaskID, err)
@@ -252,13 +279,9 @@ func (c *workflowCtl) updateWorkflowTask() {
 			c.logger.Errorf("get workflow task retention strategy error: %s", err)
 			result = commonmodels.DefaultWorkflowTaskRetention
 		}
-		if err = commonrepo.NewworkflowTaskv4Coll().ArchiveHistoryWorkflowTask(c.workflowTask.WorkflowName, result.Retention.MaxItems, result.Retention.MaxDays); err != nil {
+		if err = commonrepo.NewWorkflowTaskV4Store().ArchiveHistoryWorkflowTask(c.workflowTask.WorkflowName, result.Retention.MaxItems, result.Retention.MaxDays); err != nil {
 			c.logger.Errorf("ArchiveHistoryWorkflowTask error: %v", err)
 		}
-		// Updating the comment in the git repository, this will not cause the function to return error if this function call fails
-		if err := scmnotify.NewService().UpdateWebhookCommentForWorkflowV4(c.workflowTask, c.logger); err != nil {
-			log.Warnf("Failed to update comment for custom workflow %s, taskID: %d the error is: %s", c.workflowTask.WorkflowName, c.workflowTask.TaskID, err)
-		}
 		if err := scmnotify.NewService().CompleteGitCheckForWorkflowV4(c.workflowTask.WorkflowArgs, c.workflowTask.TaskID, c.workflowTask.Status, c.logger); err != nil {
 			log.Warnf("Failed to update github check status for custom workflow %s, taskID: %d the error is: %s", c.workflowTask.WorkflowName, c.workflowTask.TaskID, err)
 		}
@@ -270,6 +293,10 @@ func (c *workflowCtl) updateWorkflowTask() {
 
 func (c *workflowCtl) CleanShareStorage() {
 	for clusterID := range c.workflowTask.ClusterIDMap {
+		// the task dir is kept for the retention and removed by the garbage collection
+		if cluster, err := commonrepo.NewK8SClusterColl().Get(clusterID); err == nil && cluster.ShareStorage.RetentionHours > 0 {
+			continue
+		}
 		cleanJobName := fmt.Sprintf("clean-%s", rand.String(8))
 		namespace := setting.AttachedClusterNamespace
 		if clusterID == setting.LocalClusterID || clusterID == "" {
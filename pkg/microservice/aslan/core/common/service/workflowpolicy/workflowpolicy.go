@@ -0,0 +1,168 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package workflowpolicy evaluates the rego policies uploaded by the administrators against the workflow specs.
+// The policies are stored in mongodb and loaded into the opa server as modules under the zadig.workflow package,
+// they are uploaded again if the opa server lost them, e.g. after a restart.
+package workflowpolicy
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/picket/client/opa"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+const (
+	// PackagePrefix is the package the modules of the workflow policies must be in
+	PackagePrefix = "zadig.workflow"
+	// policyIDPrefix is the prefix of the ids of the modules in the opa server
+	policyIDPrefix = "zadig-workflow-policy-"
+	// evalTimeout bounds the upload and the evaluation of each policy
+	evalTimeout = 5 * time.Second
+)
+
+var (
+	policyNameRegexp    = regexp.MustCompile("^[a-z0-9]([a-z0-9-_]*[a-z0-9])?$")
+	policyPackageRegexp = regexp.MustCompile(`(?m)^package\s+zadig\.workflow\.([A-Za-z_][A-Za-z0-9_]*)\s*$`)
+
+	// forbiddenBuiltinRegexp matches the builtins reaching the network or exposing the runtime of the opa server,
+	// which also makes the authorization decisions
+	forbiddenBuiltinRegexp = regexp.MustCompile(`\b(http\.send|net\.lookup_ip_addr|opa\.runtime)\b`)
+)
+
+// Validate checks the name and the package of the policy, the module is compiled when it is uploaded by Save
+func Validate(policy *models.WorkflowPolicy) error {
+	if !policyNameRegexp.MatchString(policy.Name) {
+		return fmt.Errorf("invalid policy name %s, only lowercase letters, digits, - and _ are allowed", policy.Name)
+	}
+	if !policyPackageRegexp.MatchString(policy.Rego) {
+		return fmt.Errorf("the module of policy %s must be in a package under %s, e.g. package %s.require_approval", policy.Name, PackagePrefix, PackagePrefix)
+	}
+	if builtin := forbiddenBuiltinRegexp.FindString(policy.Rego); builtin != "" {
+		return fmt.Errorf("builtin %s is not allowed in policy %s", builtin, policy.Name)
+	}
+	return nil
+}
+
+// Save uploads the module of the policy to the opa server, the compile errors are returned
+func Save(policy *models.WorkflowPolicy) error {
+	ctx, cancel := context.WithTimeout(context.Background(), evalTimeout)
+	defer cancel()
+	if err := opa.NewDefault().PutPolicy(ctx, policyIDPrefix+policy.Name, policy.Rego); err != nil {
+		return fmt.Errorf("failed to upload policy %s: %v", policy.Name, err)
+	}
+	return nil
+}
+
+// Remove deletes the module of the policy from the opa server
+func Remove(name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), evalTimeout)
+	defer cancel()
+	if err := opa.NewDefault().DeletePolicy(ctx, policyIDPrefix+name); err != nil {
+		return fmt.Errorf("failed to delete policy %s: %v", name, err)
+	}
+	return nil
+}
+
+type policyResult struct {
+	Result *struct {
+		Deny []interface{} `json:"deny"`
+	} `json:"result"`
+}
+
+// Evaluate evaluates the enabled policies against the workflow and returns the violations. The policies failing
+// to be loaded or evaluated are skipped and logged, so that a broken policy or opa server does not block the saves
+func Evaluate(workflow *models.WorkflowV4) ([]string, error) {
+	policies, err := commonrepo.NewWorkflowPolicyColl().List(true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflow policies: %v", err)
+	}
+	if len(policies) == 0 {
+		return nil, nil
+	}
+
+	input, err := toInput(workflow)
+	if err != nil {
+		return nil, err
+	}
+
+	client := opa.NewDefault()
+	violations := make([]string, 0)
+	for _, policy := range policies {
+		matches := policyPackageRegexp.FindStringSubmatch(policy.Rego)
+		if matches == nil {
+			log.Warnf("skip invalid workflow policy %s: no package under %s", policy.Name, PackagePrefix)
+			continue
+		}
+		pkg := PackagePrefix + "." + matches[1]
+		deny, err := evaluatePolicy(client, policy, pkg, input)
+		if err != nil {
+			log.Warnf("skip workflow policy %s on workflow %s: %v", policy.Name, workflow.Name, err)
+			continue
+		}
+		for _, msg := range deny {
+			violations = append(violations, fmt.Sprintf("%s: %v", pkg, msg))
+		}
+	}
+	sort.Strings(violations)
+	return violations, nil
+}
+
+// evaluatePolicy evaluates the package of the policy, the module is uploaded again if the package is undefined
+func evaluatePolicy(client *opa.Client, policy *models.WorkflowPolicy, pkg string, input interface{}) ([]interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), evalTimeout)
+	defer cancel()
+
+	res := &policyResult{}
+	if err := client.EvaluateData(ctx, pkg, input, res); err != nil {
+		return nil, err
+	}
+	if res.Result == nil {
+		if err := client.PutPolicy(ctx, policyIDPrefix+policy.Name, policy.Rego); err != nil {
+			return nil, fmt.Errorf("failed to upload the policy: %v", err)
+		}
+		if err := client.EvaluateData(ctx, pkg, input, res); err != nil {
+			return nil, err
+		}
+		if res.Result == nil {
+			return nil, fmt.Errorf("package %s is undefined", pkg)
+		}
+	}
+	return res.Result.Deny, nil
+}
+
+// toInput converts the workflow to the input of opa, the job specs decoded from yaml may have keys which can
+// not be encoded to json
+func toInput(workflow *models.WorkflowV4) (interface{}, error) {
+	b, err := yaml.Marshal(workflow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal workflow %s: %v", workflow.Name, err)
+	}
+	var input interface{}
+	if err := yaml.Unmarshal(b, &input); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal workflow %s: %v", workflow.Name, err)
+	}
+	return input, nil
+}
@@ -0,0 +1,215 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package workloadidentity makes zadig an OIDC issuer of the jobs, the cloud providers and registries trusting
+// the issuer exchange the job tokens for their short-lived credentials like the kubernetes service account tokens.
+package workloadidentity
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+
+	configbase "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+)
+
+const (
+	// AWSAudience is the audience the aws sts accepts by default
+	AWSAudience = "sts.amazonaws.com"
+
+	signingAlgorithm = "RS256"
+	signingKeyBits   = 2048
+
+	minTokenTTL = 10 * time.Minute
+	maxTokenTTL = 6 * time.Hour
+)
+
+var (
+	signingKey   *rsa.PrivateKey
+	signingKeyID string
+	signingMu    sync.Mutex
+)
+
+// JobClaims identifies the job, the subject is project:<project>:workflow:<workflow>:job:<job> so that the
+// trust policies match the workflows by the subject patterns
+type JobClaims struct {
+	jwt.StandardClaims
+	Project     string `json:"project"`
+	Workflow    string `json:"workflow"`
+	TaskID      int64  `json:"task_id"`
+	Job         string `json:"job"`
+	TaskCreator string `json:"task_creator,omitempty"`
+}
+
+type Discovery struct {
+	Issuer                           string   `json:"issuer"`
+	JWKSURI                          string   `json:"jwks_uri"`
+	ResponseTypesSupported           []string `json:"response_types_supported"`
+	SubjectTypesSupported            []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+	ClaimsSupported                  []string `json:"claims_supported"`
+}
+
+type JWKS struct {
+	Keys []*JWK `json:"keys"`
+}
+
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+}
+
+// Issuer is served by the public aslan apis, the path must match the routes of the discovery and the keys
+func Issuer() string {
+	return strings.TrimSuffix(configbase.SystemAddress(), "/") + "/api/aslan/oidc"
+}
+
+func GetDiscovery() *Discovery {
+	return &Discovery{
+		Issuer:                           Issuer(),
+		JWKSURI:                          Issuer() + "/jwks",
+		ResponseTypesSupported:           []string{"id_token"},
+		SubjectTypesSupported:            []string{"public"},
+		IDTokenSigningAlgValuesSupported: []string{signingAlgorithm},
+		ClaimsSupported:                  []string{"sub", "aud", "exp", "iat", "iss", "nbf", "jti", "project", "workflow", "task_id", "job", "task_creator"},
+	}
+}
+
+// GetJWKS returns the public keys of all the signing keys so that the tokens signed by any instance are verified
+func GetJWKS() (*JWKS, error) {
+	keys, err := mongodb.NewOIDCSigningKeyColl().List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the signing keys, error: %s", err)
+	}
+
+	resp := &JWKS{Keys: make([]*JWK, 0, len(keys))}
+	for _, key := range keys {
+		privateKey, err := parsePrivateKey(key.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse signing key %s, error: %s", key.KeyID, err)
+		}
+		resp.Keys = append(resp.Keys, &JWK{
+			KeyType:   "RSA",
+			Use:       "sig",
+			Algorithm: signingAlgorithm,
+			KeyID:     key.KeyID,
+			N:         base64.RawURLEncoding.EncodeToString(privateKey.N.Bytes()),
+			E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(privateKey.E)).Bytes()),
+		})
+	}
+	return resp, nil
+}
+
+// IssueJobToken signs the token of the job, it expires with the job timeout within [10m, 6h]
+func IssueJobToken(claims *JobClaims, audience string, ttl time.Duration) (string, error) {
+	key, keyID, err := getSigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	if ttl < minTokenTTL {
+		ttl = minTokenTTL
+	}
+	if ttl > maxTokenTTL {
+		ttl = maxTokenTTL
+	}
+	if audience == "" {
+		audience = Issuer()
+	}
+
+	now := time.Now()
+	claims.Issuer = Issuer()
+	claims.Subject = fmt.Sprintf("project:%s:workflow:%s:job:%s", claims.Project, claims.Workflow, claims.Job)
+	claims.Audience = audience
+	claims.IssuedAt = now.Unix()
+	claims.NotBefore = now.Unix()
+	claims.ExpiresAt = now.Add(ttl).Unix()
+	claims.Id = fmt.Sprintf("%s:%s:%d:%s:%d", claims.Project, claims.Workflow, claims.TaskID, claims.Job, now.UnixNano())
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+	return token.SignedString(key)
+}
+
+// getSigningKey loads the newest signing key, a key is generated on the first use
+func getSigningKey() (*rsa.PrivateKey, string, error) {
+	signingMu.Lock()
+	defer signingMu.Unlock()
+
+	if signingKey != nil {
+		return signingKey, signingKeyID, nil
+	}
+
+	coll := mongodb.NewOIDCSigningKeyColl()
+	keys, err := coll.List()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list the signing keys, error: %s", err)
+	}
+	if len(keys) > 0 {
+		key, err := parsePrivateKey(keys[0].PrivateKey)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to parse signing key %s, error: %s", keys[0].KeyID, err)
+		}
+		signingKey, signingKeyID = key, keys[0].KeyID
+		return signingKey, signingKeyID, nil
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, signingKeyBits)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate the signing key, error: %s", err)
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(publicKey)
+	keyID := hex.EncodeToString(sum[:8])
+	err = coll.Create(&models.OIDCSigningKey{
+		KeyID:      keyID,
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		CreateTime: time.Now().Unix(),
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to save the signing key, error: %s", err)
+	}
+	signingKey, signingKeyID = key, keyID
+	return signingKey, signingKeyID, nil
+}
+
+func parsePrivateKey(data string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, fmt.Errorf("invalid pem data")
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
@@ -19,9 +19,12 @@ package types
 import (
 	"bytes"
 	"fmt"
+	"strconv"
 
 	"gopkg.in/yaml.v3"
 	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/util/jsonschema"
 )
 
 type ServiceVariableKVType string
@@ -31,15 +34,17 @@ const (
 	ServiceVariableKVTypeString  ServiceVariableKVType = "string"
 	ServiceVariableKVTypeEnum    ServiceVariableKVType = "enum"
 	ServiceVariableKVTypeYaml    ServiceVariableKVType = "yaml"
+	ServiceVariableKVTypeNumber  ServiceVariableKVType = "number"
 )
 
 // This kv will aggregate complicated struct to the first layer of key
 type ServiceVariableKV struct {
-	Key     string                `bson:"key"      yaml:"key"      json:"key"`
-	Value   interface{}           `bson:"value"    yaml:"value"    json:"value"`
-	Type    ServiceVariableKVType `bson:"type"     yaml:"type"     json:"type"`
-	Options []string              `bson:"options"  yaml:"options"  json:"options"`
-	Desc    string                `bson:"desc"     yaml:"desc"     json:"desc"`
+	Key      string                `bson:"key"      yaml:"key"      json:"key"`
+	Value    interface{}           `bson:"value"    yaml:"value"    json:"value"`
+	Type     ServiceVariableKVType `bson:"type"     yaml:"type"     json:"type"`
+	Options  []string              `bson:"options"  yaml:"options"  json:"options"`
+	Desc     string                `bson:"desc"     yaml:"desc"     json:"desc"`
+	Required bool                  `bson:"required" yaml:"required" json:"required"`
 }
 
 type RenderVariableKV struct {
@@ -167,6 +172,12 @@ func ServiceVariableKVToYaml(kvs []*ServiceVariableKV) (string, error) {
 				return "", fmt.Errorf("invaild value for enum, key: %v, value: %v, options: %v", kv.Key, kv.Value, kv.Options)
 			}
 			node = addValueToNode(kv.Key, kv.Value, node)
+		case ServiceVariableKVTypeNumber:
+			v, err := parseNumberValue(kv.Value)
+			if err != nil {
+				return "", fmt.Errorf("invaild value for number, key: %v, value: %v", kv.Key, kv.Value)
+			}
+			node = addValueToNode(kv.Key, v, node)
 		default:
 			node = addValueToNode(kv.Key, kv.Value, node)
 		}
@@ -259,13 +270,14 @@ func snippetToKV(key string, snippet *yaml.Node, origKV *ServiceVariableKV) (*Se
 			retKV.Value = snippet.Value
 		}
 	} else {
-		// origKV is not nil, inherit type, options, and desc from it
+		// origKV is not nil, inherit type, options, desc and required from it
 		retKV = &ServiceVariableKV{
-			Key:     origKV.Key,
-			Value:   origKV.Value,
-			Type:    origKV.Type,
-			Options: origKV.Options,
-			Desc:    origKV.Desc,
+			Key:      origKV.Key,
+			Value:    origKV.Value,
+			Type:     origKV.Type,
+			Options:  origKV.Options,
+			Desc:     origKV.Desc,
+			Required: origKV.Required,
 		}
 
 		// validate key
@@ -287,6 +299,10 @@ func snippetToKV(key string, snippet *yaml.Node, origKV *ServiceVariableKV) (*Se
 			if !optionSet.Has(snippet.Value) {
 				return nil, fmt.Errorf("key %s: invalid value: %v, valid options: %v", retKV.Key, snippet.Value, origKV.Options)
 			}
+		case ServiceVariableKVTypeNumber:
+			if _, err := parseNumberValue(snippet.Value); err != nil {
+				return nil, fmt.Errorf("key %s: invalid value: %s for number", retKV.Key, snippet.Value)
+			}
 		}
 
 		// convert snippet to value
@@ -485,12 +501,105 @@ func ValidateRenderVariables(globalVariables []*GlobalVariableKV, renderVariable
 			kv.Type = globalVariable.Type
 			kv.Options = globalVariable.Options
 			kv.Desc = globalVariable.Desc
+			kv.Required = globalVariable.Required
 		}
 	}
 
 	return nil
 }
 
+// ValidateVariableKVsBySchema validates the variables against the variable schema declared in the service template,
+// the type, options and required of the template take priority over the ones carried by the variables.
+// Variables which are not declared in the template or reference global variables are not checked.
+func ValidateVariableKVsBySchema(schema []*ServiceVariableKV, kvs []*RenderVariableKV) error {
+	schemaMap := make(map[string]*ServiceVariableKV, len(schema))
+	for _, kv := range schema {
+		schemaMap[kv.Key] = kv
+	}
+
+	for _, kv := range kvs {
+		define, ok := schemaMap[kv.Key]
+		if !ok || kv.UseGlobalVariable {
+			continue
+		}
+
+		if isEmptyVariableValue(kv.Value) {
+			if define.Required {
+				return fmt.Errorf("key %s: value is required", kv.Key)
+			}
+			continue
+		}
+
+		switch define.Type {
+		case ServiceVariableKVTypeBoolean:
+			v := fmt.Sprintf("%v", kv.Value)
+			if v != "true" && v != "false" {
+				return fmt.Errorf("key %s: invalid value: %v for boolean", kv.Key, kv.Value)
+			}
+		case ServiceVariableKVTypeEnum:
+			if !sets.NewString(define.Options...).Has(fmt.Sprintf("%v", kv.Value)) {
+				return fmt.Errorf("key %s: invalid value: %v, valid options: %v", kv.Key, kv.Value, define.Options)
+			}
+		case ServiceVariableKVTypeNumber:
+			if _, err := parseNumberValue(kv.Value); err != nil {
+				return fmt.Errorf("key %s: invalid value: %v for number", kv.Key, kv.Value)
+			}
+		case ServiceVariableKVTypeYaml:
+			value, ok := kv.Value.(string)
+			if !ok {
+				return fmt.Errorf("key %s: invalid value: %v for yaml", kv.Key, kv.Value)
+			}
+			if err := yaml.Unmarshal([]byte(value), &yaml.Node{}); err != nil {
+				return fmt.Errorf("key %s: invalid yaml, err: %w", kv.Key, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ValidateServiceVariableSchema validates the variable schema declared in the service template
+func ValidateServiceVariableSchema(kvs []*ServiceVariableKV) error {
+	keySet := sets.NewString()
+	for _, kv := range kvs {
+		if keySet.Has(kv.Key) {
+			return fmt.Errorf("duplicated key: %s", kv.Key)
+		}
+		keySet.Insert(kv.Key)
+
+		if kv.Type == ServiceVariableKVTypeEnum && len(kv.Options) == 0 {
+			return fmt.Errorf("key %s: options of enum can not be empty", kv.Key)
+		}
+	}
+	return nil
+}
+
+func isEmptyVariableValue(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	if v, ok := value.(string); ok {
+		return v == ""
+	}
+	return false
+}
+
+// parseNumberValue converts the value of number variables, which is float64 if decoded from json and string
+// if decoded from yaml
+func parseNumberValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case int, int32, int64, float32, float64:
+		return v, nil
+	case string:
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return i, nil
+		}
+		return strconv.ParseFloat(v, 64)
+	default:
+		return nil, fmt.Errorf("invalid number: %v", value)
+	}
+}
+
 func ServiceToRenderVariableKVs(ServiceVariables []*ServiceVariableKV) []*RenderVariableKV {
 	ret := []*RenderVariableKV{}
 	for _, kv := range ServiceVariables {
@@ -545,6 +654,7 @@ func UpdateGlobalVariableKVs(serviceName string, globalVariables []*GlobalVariab
 		renderVariableKV.Type = globalVariableKV.Type
 		renderVariableKV.Options = globalVariableKV.Options
 		renderVariableKV.Desc = globalVariableKV.Desc
+		renderVariableKV.Required = globalVariableKV.Required
 	}
 
 	// check render vairaible diff
@@ -611,11 +721,12 @@ func UpdateRenderVariable(global []*GlobalVariableKV, render []*RenderVariableKV
 			if kv.UseGlobalVariable {
 				retKV := &RenderVariableKV{
 					ServiceVariableKV: ServiceVariableKV{
-						Key:     globalKV.Key,
-						Value:   globalKV.Value,
-						Type:    globalKV.Type,
-						Options: globalKV.Options,
-						Desc:    globalKV.Desc,
+						Key:      globalKV.Key,
+						Value:    globalKV.Value,
+						Type:     globalKV.Type,
+						Options:  globalKV.Options,
+						Desc:     globalKV.Desc,
+						Required: globalKV.Required,
 					},
 					UseGlobalVariable: true,
 				}
@@ -677,3 +788,48 @@ type GlobalVariables struct {
 	Variables           []*ServiceVariableKV `json:"variables"`
 	ProductionVariables []*ServiceVariableKV `json:"production_variables"`
 }
+
+// ServiceVariableKVsToSchema converts the variable schema declared in the service template to JSON Schema,
+// which is used to render the typed form of the variables in env creation and deploy job config
+func ServiceVariableKVsToSchema(kvs []*ServiceVariableKV) *jsonschema.Schema {
+	schema := &jsonschema.Schema{
+		Schema:     jsonschema.Draft,
+		Type:       "object",
+		Properties: make(map[string]*jsonschema.Schema, len(kvs)),
+	}
+
+	for _, kv := range kvs {
+		property := &jsonschema.Schema{
+			Title:       kv.Key,
+			Description: kv.Desc,
+			Default:     kv.Value,
+		}
+		switch kv.Type {
+		case ServiceVariableKVTypeBoolean:
+			property.Type = "boolean"
+			if v, ok := kv.Value.(string); ok {
+				property.Default = v == "true"
+			}
+		case ServiceVariableKVTypeNumber:
+			property.Type = "number"
+			if v, err := parseNumberValue(kv.Value); err == nil {
+				property.Default = v
+			}
+		case ServiceVariableKVTypeEnum:
+			property.Type = "string"
+			for _, option := range kv.Options {
+				property.Enum = append(property.Enum, option)
+			}
+		default:
+			// yaml variables are edited as the yaml text
+			property.Type = "string"
+		}
+		schema.Properties[kv.Key] = property
+
+		if kv.Required {
+			schema.Required = append(schema.Required, kv.Key)
+		}
+	}
+
+	return schema
+}
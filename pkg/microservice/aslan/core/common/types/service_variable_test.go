@@ -167,6 +167,21 @@ strBool: true
 boolStr: true
 strBool: false
 `
+
+	schemaKVs = []*types.ServiceVariableKV{
+		{
+			Key:      "replicas",
+			Value:    "1",
+			Type:     types.ServiceVariableKVTypeNumber,
+			Required: true,
+		},
+		{
+			Key:     "logLevel",
+			Value:   "info",
+			Type:    types.ServiceVariableKVTypeEnum,
+			Options: []string{"debug", "info"},
+		},
+	}
 )
 
 var _ = Describe("Service", func() {
@@ -256,4 +271,34 @@ var _ = Describe("Service", func() {
 			}
 		})
 	})
+
+	Context("validate variables by schema", func() {
+		It("accepts the values matching the schema", func() {
+			values := []*types.RenderVariableKV{
+				{ServiceVariableKV: types.ServiceVariableKV{Key: "replicas", Value: float64(3)}},
+				{ServiceVariableKV: types.ServiceVariableKV{Key: "logLevel", Value: "debug"}},
+			}
+			Expect(types.ValidateVariableKVsBySchema(schemaKVs, values)).To(Succeed())
+		})
+
+		It("rejects the invalid values", func() {
+			Expect(types.ValidateVariableKVsBySchema(schemaKVs, []*types.RenderVariableKV{
+				{ServiceVariableKV: types.ServiceVariableKV{Key: "replicas", Value: ""}},
+			})).ShouldNot(Succeed())
+			Expect(types.ValidateVariableKVsBySchema(schemaKVs, []*types.RenderVariableKV{
+				{ServiceVariableKV: types.ServiceVariableKV{Key: "replicas", Value: "three"}},
+			})).ShouldNot(Succeed())
+			Expect(types.ValidateVariableKVsBySchema(schemaKVs, []*types.RenderVariableKV{
+				{ServiceVariableKV: types.ServiceVariableKV{Key: "logLevel", Value: "warn"}},
+			})).ShouldNot(Succeed())
+		})
+
+		It("converts the schema to json schema", func() {
+			schema := types.ServiceVariableKVsToSchema(schemaKVs)
+			Expect(schema.Required).To(Equal([]string{"replicas"}))
+			Expect(schema.Properties["replicas"].Type).To(Equal("number"))
+			Expect(schema.Properties["replicas"].Default).To(Equal(int64(1)))
+			Expect(schema.Properties["logLevel"].Enum).To(Equal([]interface{}{"debug", "info"}))
+		})
+	})
 })
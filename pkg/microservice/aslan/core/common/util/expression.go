@@ -0,0 +1,181 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	gotemplate "text/template"
+
+	"github.com/blang/semver/v4"
+	"github.com/tidwall/gjson"
+)
+
+var (
+	expressionRegex       = regexp.MustCompile(`\{\{[^{}]+\}\}`)
+	plainVariableRefRegex = regexp.MustCompile(`^\s*\.[^\s|()]+\s*$`)
+)
+
+// ExpressionFuncs are the functions supported in the variable expressions, the value being transformed is always the
+// last argument so the functions can be chained by pipes, such as {{.workflow.params.branch | replace "/" "-" | lower}}
+var ExpressionFuncs = gotemplate.FuncMap{
+	"default": func(def, value string) string {
+		if strings.TrimSpace(value) == "" {
+			return def
+		}
+		return value
+	},
+	"trim":  strings.TrimSpace,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"replace": func(old, new, value string) string {
+		return strings.ReplaceAll(value, old, new)
+	},
+	// substr returns the runes in [start, end), a negative end means the end of the value
+	"substr": func(start, end int, value string) string {
+		runes := []rune(value)
+		if end < 0 || end > len(runes) {
+			end = len(runes)
+		}
+		if start < 0 {
+			start = 0
+		}
+		if start >= end {
+			return ""
+		}
+		return string(runes[start:end])
+	},
+	// jsonPath returns the value at the gjson path, such as "image.tags.0"
+	"jsonPath": func(path, value string) (string, error) {
+		if !gjson.Valid(value) {
+			return "", fmt.Errorf("invalid json: %s", value)
+		}
+		return gjson.Get(value, path).String(), nil
+	},
+	// semverBump bumps the major, minor or patch of the version, the prefix v is kept
+	"semverBump": func(part, value string) (string, error) {
+		prefix := ""
+		if strings.HasPrefix(value, "v") {
+			prefix = "v"
+		}
+		version, err := semver.Parse(strings.TrimPrefix(value, "v"))
+		if err != nil {
+			return "", fmt.Errorf("invalid semver %s: %s", value, err)
+		}
+		switch part {
+		case "major":
+			err = version.IncrementMajor()
+		case "minor":
+			err = version.IncrementMinor()
+		case "patch":
+			err = version.IncrementPatch()
+		default:
+			return "", fmt.Errorf("unknown semver part %s, should be major, minor or patch", part)
+		}
+		if err != nil {
+			return "", err
+		}
+		version.Pre = nil
+		version.Build = nil
+		return prefix + version.String(), nil
+	},
+}
+
+// RenderExpressions evaluates the expressions such as {{upper .workflow.params.env}} in the json with the variables, the
+// keys of the variables are the names without braces, such as workflow.params.env. The expressions referring to unknown
+// variables or failed to evaluate are left as they are, since they may be rendered later or belong to other templates
+// such as the helm charts.
+func RenderExpressions(jsonValue string, variables map[string]string) string {
+	return expressionRegex.ReplaceAllStringFunc(jsonValue, func(expression string) string {
+		// the expression is a part of a json string, so the quotes in it are escaped
+		raw := ""
+		if err := json.Unmarshal([]byte(`"`+expression+`"`), &raw); err != nil {
+			return expression
+		}
+		inner := strings.TrimSuffix(strings.TrimPrefix(raw, "{{"), "}}")
+		if plainVariableRefRegex.MatchString(inner) {
+			return expression
+		}
+		source, ok := substituteVariables(inner, variables)
+		if !ok {
+			return expression
+		}
+
+		tmpl, err := gotemplate.New("expression").Funcs(ExpressionFuncs).Parse("{{" + source + "}}")
+		if err != nil {
+			return expression
+		}
+		buf := new(bytes.Buffer)
+		if err := tmpl.Execute(buf, nil); err != nil {
+			return expression
+		}
+		rendered, err := json.Marshal(buf.String())
+		if err != nil {
+			return expression
+		}
+		return string(rendered[1 : len(rendered)-1])
+	})
+}
+
+// substituteVariables replaces the variable references outside the string literals with the quoted values, it returns
+// false if the expression refers to no variable or refers to an unknown variable
+func substituteVariables(expression string, variables map[string]string) (string, bool) {
+	var (
+		out        strings.Builder
+		referenced bool
+		quote      rune
+		prev       = ' '
+		runes      = []rune(expression)
+	)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case quote != 0:
+			out.WriteRune(c)
+			if c == '\\' && quote == '"' && i+1 < len(runes) {
+				i++
+				out.WriteRune(runes[i])
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '`':
+			quote = c
+			out.WriteRune(c)
+		case c == '.' && strings.ContainsRune(" \t(|", prev):
+			end := i + 1
+			for end < len(runes) && !strings.ContainsRune(" \t()|\"`", runes[end]) {
+				end++
+			}
+			value, ok := variables[string(runes[i+1:end])]
+			if !ok {
+				return "", false
+			}
+			out.WriteString(strconv.Quote(value))
+			referenced = true
+			i = end - 1
+			c = runes[i]
+		default:
+			out.WriteRune(c)
+		}
+		prev = c
+	}
+	return out.String(), referenced
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var expressionVariables = map[string]string{
+	"workflow.params.env":    "prod",
+	"workflow.params.branch": "Feature/Login",
+	"workflow.params.tag":    "",
+	"job.build.commitid":     "abcdef123456",
+	"job.build.output":       `{"image": {"tag": "v1"}}`,
+	"job.build.version":      "v1.2.3",
+	"job.build.quote":        "a",
+}
+
+// renderExpression renders the expression as the value of a json field, as the workflow args are rendered
+func renderExpression(expression string) string {
+	jsonValue, err := json.Marshal(map[string]string{"value": expression})
+	Expect(err).NotTo(HaveOccurred())
+	resp := make(map[string]string)
+	Expect(json.Unmarshal([]byte(RenderExpressions(string(jsonValue), expressionVariables)), &resp)).To(Succeed())
+	return resp["value"]
+}
+
+var _ = DescribeTable("RenderExpressions",
+	func(expression, expected string) {
+		Expect(renderExpression(expression)).To(Equal(expected))
+	},
+	Entry("function", "app:{{upper .workflow.params.env}}", "app:PROD"),
+	Entry("pipes", `{{.workflow.params.branch | replace "/" "-" | lower}}`, "feature-login"),
+	Entry("default of an empty value", `{{default "latest" .workflow.params.tag}}`, "latest"),
+	Entry("default of a value", `{{default "latest" .workflow.params.env}}`, "prod"),
+	Entry("substr", "{{substr 0 7 .job.build.commitid}}", "abcdef1"),
+	Entry("substr to the end", "{{substr 6 -1 .job.build.commitid}}", "123456"),
+	Entry("jsonPath", `{{jsonPath "image.tag" .job.build.output}}`, "v1"),
+	Entry("semverBump", `{{semverBump "minor" .job.build.version}}`, "v1.3.0"),
+	Entry("quotes in the result", `{{replace "a" "\"" .job.build.quote}}`, `"`),
+	Entry("several expressions", "{{lower .workflow.params.env}}-{{trim .workflow.params.tag}}", "prod-"),
+	Entry("plain variable is left to the variable rendering", "{{.workflow.params.env}}", "{{.workflow.params.env}}"),
+	Entry("unknown variable is left as it is", "{{upper .workflow.params.unknown}}", "{{upper .workflow.params.unknown}}"),
+	Entry("unknown function is left as it is", "{{shout .workflow.params.env}}", "{{shout .workflow.params.env}}"),
+	Entry("failed function is left as it is", `{{semverBump "build" .job.build.version}}`, `{{semverBump "build" .job.build.version}}`),
+	Entry("expression without a variable is left as it is", `{{upper "prod"}}`, `{{upper "prod"}}`),
+)
+
+var _ = DescribeTable("substituteVariables",
+	func(expression, expected string, ok bool) {
+		source, referenced := substituteVariables(expression, expressionVariables)
+		Expect(referenced).To(Equal(ok))
+		if ok {
+			Expect(source).To(Equal(expected))
+		}
+	},
+	Entry("argument", "upper .workflow.params.env", `upper "prod"`, true),
+	Entry("piped value", ".workflow.params.env|upper", `"prod"|upper`, true),
+	Entry("parenthesized value", "lower (.workflow.params.env)", `lower ("prod")`, true),
+	Entry("dots in the string literals", `replace "." "-" .workflow.params.env`, `replace "." "-" "prod"`, true),
+	Entry("escaped quote in the string literals", `replace "\" .x" "-" .workflow.params.env`, `replace "\" .x" "-" "prod"`, true),
+	Entry("raw string literals", "replace `.x` \"-\" .workflow.params.env", "replace `.x` \"-\" \"prod\"", true),
+	Entry("value with quotes", "jsonPath \"image\" .job.build.output", `jsonPath "image" "{\"image\": {\"tag\": \"v1\"}}"`, true),
+	Entry("no variable", `upper "prod"`, "", false),
+	Entry("unknown variable", "upper .workflow.params.unknown", "", false),
+)
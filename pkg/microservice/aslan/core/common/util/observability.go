@@ -0,0 +1,44 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/tool/prometheus"
+)
+
+const (
+	ObservabilityTypePrometheus = "prometheus"
+	ObservabilityTypeGrafana    = "grafana"
+)
+
+// NewPrometheusClient returns the client querying the prometheus integration, or the prometheus datasource of the grafana integration
+func NewPrometheusClient(info *commonmodels.Observability) (*prometheus.Client, error) {
+	switch info.Type {
+	case ObservabilityTypePrometheus:
+		return prometheus.NewClient(info.Host, info.ApiKey), nil
+	case ObservabilityTypeGrafana:
+		if info.DatasourceUID == "" {
+			return nil, fmt.Errorf("datasource uid of grafana %s is not set", info.Name)
+		}
+		return prometheus.NewClient(prometheus.GrafanaDatasourceProxyURL(info.Host, info.DatasourceUID), info.ApiKey), nil
+	default:
+		return nil, fmt.Errorf("observability %s is not prometheus or grafana", info.Name)
+	}
+}
@@ -17,11 +17,15 @@ limitations under the License.
 package handler
 
 import (
+	"fmt"
+	"strconv"
+
 	"github.com/gin-gonic/gin"
 
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	cronservice "github.com/koderover/zadig/pkg/microservice/aslan/core/cron/service"
 	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
 )
 
 func CleanJobCronJob(c *gin.Context) {
@@ -75,6 +79,11 @@ type cronjobResp struct {
 	TestArgs       *commonmodels.TestTaskArgs     `json:"test_args,omitempty"`
 	JobType        string                         `json:"job_type"`
 	Enabled        bool                           `json:"enabled"`
+	// ConcurrencyPolicy and MisfirePolicy are only supported by the workflow v4 cronjob
+	ConcurrencyPolicy string `json:"concurrency_policy,omitempty"`
+	MisfirePolicy     string `json:"misfire_policy,omitempty"`
+	LastFireTime      int64  `json:"last_fire_time,omitempty"`
+	Timezone          string `json:"timezone,omitempty"`
 }
 
 func ListActiveCronjobFailsafe(c *gin.Context) {
@@ -85,21 +94,25 @@ func ListActiveCronjobFailsafe(c *gin.Context) {
 	cronjobList, err := cronservice.ListActiveCronjobFailsafe()
 	for _, cronjob := range cronjobList {
 		resp = append(resp, &cronjobResp{
-			ID:             cronjob.ID.Hex(),
-			Name:           cronjob.Name,
-			Type:           cronjob.Type,
-			Number:         cronjob.Number,
-			Frequency:      cronjob.Frequency,
-			Time:           cronjob.Time,
-			Cron:           cronjob.Cron,
-			ProductName:    cronjob.ProductName,
-			MaxFailure:     cronjob.MaxFailure,
-			TaskArgs:       cronjob.TaskArgs,
-			WorkflowArgs:   cronjob.WorkflowArgs,
-			WorkflowV4Args: cronjob.WorkflowV4Args,
-			TestArgs:       cronjob.TestArgs,
-			JobType:        cronjob.JobType,
-			Enabled:        cronjob.Enabled,
+			ID:                cronjob.ID.Hex(),
+			Name:              cronjob.Name,
+			Type:              cronjob.Type,
+			Number:            cronjob.Number,
+			Frequency:         cronjob.Frequency,
+			Time:              cronjob.Time,
+			Cron:              cronjob.Cron,
+			ProductName:       cronjob.ProductName,
+			MaxFailure:        cronjob.MaxFailure,
+			TaskArgs:          cronjob.TaskArgs,
+			WorkflowArgs:      cronjob.WorkflowArgs,
+			WorkflowV4Args:    cronjob.WorkflowV4Args,
+			TestArgs:          cronjob.TestArgs,
+			JobType:           cronjob.JobType,
+			Enabled:           cronjob.Enabled,
+			ConcurrencyPolicy: cronjob.ConcurrencyPolicy,
+			MisfirePolicy:     cronjob.MisfirePolicy,
+			LastFireTime:      cronjob.LastFireTime,
+			Timezone:          cronjob.Timezone,
 		})
 	}
 	ctx.Resp = resp
@@ -114,21 +127,25 @@ func ListActiveCronjob(c *gin.Context) {
 	cronjobList, err := cronservice.ListActiveCronjob()
 	for _, cronjob := range cronjobList {
 		resp = append(resp, &cronjobResp{
-			ID:             cronjob.ID.Hex(),
-			Name:           cronjob.Name,
-			Type:           cronjob.Type,
-			Number:         cronjob.Number,
-			Frequency:      cronjob.Frequency,
-			Time:           cronjob.Time,
-			Cron:           cronjob.Cron,
-			ProductName:    cronjob.ProductName,
-			MaxFailure:     cronjob.MaxFailure,
-			TaskArgs:       cronjob.TaskArgs,
-			WorkflowArgs:   cronjob.WorkflowArgs,
-			WorkflowV4Args: cronjob.WorkflowV4Args,
-			TestArgs:       cronjob.TestArgs,
-			JobType:        cronjob.JobType,
-			Enabled:        cronjob.Enabled,
+			ID:                cronjob.ID.Hex(),
+			Name:              cronjob.Name,
+			Type:              cronjob.Type,
+			Number:            cronjob.Number,
+			Frequency:         cronjob.Frequency,
+			Time:              cronjob.Time,
+			Cron:              cronjob.Cron,
+			ProductName:       cronjob.ProductName,
+			MaxFailure:        cronjob.MaxFailure,
+			TaskArgs:          cronjob.TaskArgs,
+			WorkflowArgs:      cronjob.WorkflowArgs,
+			WorkflowV4Args:    cronjob.WorkflowV4Args,
+			TestArgs:          cronjob.TestArgs,
+			JobType:           cronjob.JobType,
+			Enabled:           cronjob.Enabled,
+			ConcurrencyPolicy: cronjob.ConcurrencyPolicy,
+			MisfirePolicy:     cronjob.MisfirePolicy,
+			LastFireTime:      cronjob.LastFireTime,
+			Timezone:          cronjob.Timezone,
 		})
 	}
 	ctx.Resp = resp
@@ -146,23 +163,98 @@ func ListCronjob(c *gin.Context) {
 	cronjobList, err := cronservice.ListCronjob(name, pType)
 	for _, cronjob := range cronjobList {
 		resp = append(resp, &cronjobResp{
-			ID:             cronjob.ID.Hex(),
-			Name:           cronjob.Name,
-			Type:           cronjob.Type,
-			Number:         cronjob.Number,
-			Frequency:      cronjob.Frequency,
-			Time:           cronjob.Time,
-			Cron:           cronjob.Cron,
-			ProductName:    cronjob.ProductName,
-			MaxFailure:     cronjob.MaxFailure,
-			TaskArgs:       cronjob.TaskArgs,
-			WorkflowArgs:   cronjob.WorkflowArgs,
-			WorkflowV4Args: cronjob.WorkflowV4Args,
-			TestArgs:       cronjob.TestArgs,
-			JobType:        cronjob.JobType,
-			Enabled:        cronjob.Enabled,
+			ID:                cronjob.ID.Hex(),
+			Name:              cronjob.Name,
+			Type:              cronjob.Type,
+			Number:            cronjob.Number,
+			Frequency:         cronjob.Frequency,
+			Time:              cronjob.Time,
+			Cron:              cronjob.Cron,
+			ProductName:       cronjob.ProductName,
+			MaxFailure:        cronjob.MaxFailure,
+			TaskArgs:          cronjob.TaskArgs,
+			WorkflowArgs:      cronjob.WorkflowArgs,
+			WorkflowV4Args:    cronjob.WorkflowV4Args,
+			TestArgs:          cronjob.TestArgs,
+			JobType:           cronjob.JobType,
+			Enabled:           cronjob.Enabled,
+			ConcurrencyPolicy: cronjob.ConcurrencyPolicy,
+			MisfirePolicy:     cronjob.MisfirePolicy,
+			LastFireTime:      cronjob.LastFireTime,
+			Timezone:          cronjob.Timezone,
 		})
 	}
 	ctx.Resp = resp
 	ctx.Err = err
 }
+
+type updateCronjobFireTimeReq struct {
+	LastFireTime int64 `json:"last_fire_time"`
+}
+
+func UpdateCronjobFireTime(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	// the api is called by the cron service without a token, which is granted the admin access
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	args := new(updateCronjobFireTimeReq)
+	if err := c.BindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc(err.Error())
+		return
+	}
+
+	ctx.Err = cronservice.UpdateCronjobFireTime(c.Param("id"), args.LastFireTime)
+}
+
+func ListWorkflowV4TodoTasks(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	ctx.Resp, ctx.Err = cronservice.ListWorkflowV4TodoTasks(c.Param("name"))
+}
+
+func CancelWorkflowV4Task(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	taskID, err := strconv.ParseInt(c.Param("taskID"), 10, 64)
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid task id")
+		return
+	}
+
+	ctx.Err = cronservice.CancelWorkflowV4Task(c.Param("name"), taskID, ctx.Logger)
+}
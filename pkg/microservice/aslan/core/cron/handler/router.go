@@ -39,5 +39,8 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		cronjob.GET("/failsafe", ListActiveCronjobFailsafe)
 		cronjob.GET("", ListActiveCronjob)
 		cronjob.GET("/type/:type/name/:name", ListCronjob)
+		cronjob.PUT("/:id/fire", UpdateCronjobFireTime)
+		cronjob.GET("/workflow/v4/:name/task", ListWorkflowV4TodoTasks)
+		cronjob.DELETE("/workflow/v4/:name/task/:taskID", CancelWorkflowV4Task)
 	}
 }
@@ -17,11 +17,15 @@ limitations under the License.
 package service
 
 import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.uber.org/zap"
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
 )
 
 func DisableCronjob(name, ptype string, log *zap.SugaredLogger) error {
@@ -103,3 +107,37 @@ func ListCronjob(name, jobType string) ([]*commonmodels.Cronjob, error) {
 		ParentType: jobType,
 	})
 }
+
+func UpdateCronjobFireTime(id string, fireTime int64) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return e.ErrInvalidParam.AddDesc("invalid cronjob id")
+	}
+	return commonrepo.NewCronjobColl().UpdateLastFireTime(oid, fireTime)
+}
+
+// ListWorkflowV4TodoTasks returns the ids of the unfinished tasks of the workflow started by the cron trigger,
+// the cron service checks them before firing a job with the concurrency policy
+func ListWorkflowV4TodoTasks(workflowName string) ([]int64, error) {
+	tasks, err := commonrepo.NewWorkflowTaskV4Store().FindTodoTasksByWorkflowName(workflowName)
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]int64, 0, len(tasks))
+	for _, task := range tasks {
+		// the tasks started by users or other triggers are not affected by the concurrency policy of the cron
+		if task.TaskCreator != setting.CronTaskCreator {
+			continue
+		}
+		resp = append(resp, task.TaskID)
+	}
+	return resp, nil
+}
+
+func CancelWorkflowV4Task(workflowName string, taskID int64, log *zap.SugaredLogger) error {
+	if err := workflowcontroller.CancelWorkflowTask(setting.CronTaskCreator, workflowName, taskID, log); err != nil {
+		log.Errorf("Failed to cancel task %d of workflow %s, the error is: %v", taskID, workflowName, err)
+		return e.ErrCancelTask.AddErr(err)
+	}
+	return nil
+}
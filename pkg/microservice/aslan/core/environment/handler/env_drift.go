@@ -0,0 +1,167 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/environment/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+func GetEnvDrift(c *gin.Context) {
+	getEnvDrift(c, false)
+}
+
+func GetProductionEnvDrift(c *gin.Context) {
+	getEnvDrift(c, true)
+}
+
+func getEnvDrift(c *gin.Context, production bool) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	envName := c.Param("name")
+	projectKey := c.Query("projectName")
+	if !envActionPermitted(ctx, projectKey, envName, production, false) {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.GetEnvDrift(projectKey, envName, production)
+}
+
+func CheckEnvDrift(c *gin.Context) {
+	checkEnvDrift(c, false)
+}
+
+func CheckProductionEnvDrift(c *gin.Context) {
+	checkEnvDrift(c, true)
+}
+
+// checkEnvDrift runs the drift detection at once, the drifted resources are reverted if the query revert is true
+func checkEnvDrift(c *gin.Context, production bool) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	envName := c.Param("name")
+	projectKey := c.Query("projectName")
+	revert := c.Query("revert") == "true"
+	if revert {
+		internalhandler.InsertOperationLog(c, ctx.UserName, projectKey, "回滚", "环境-配置漂移", envName, "", ctx.Logger)
+	}
+
+	if !envActionPermitted(ctx, projectKey, envName, production, revert) {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.CheckEnvDrift(projectKey, envName, production, revert, ctx.Logger)
+}
+
+func UpdateEnvDriftDetection(c *gin.Context) {
+	updateEnvDriftDetection(c, false)
+}
+
+func UpdateProductionEnvDriftDetection(c *gin.Context) {
+	updateEnvDriftDetection(c, true)
+}
+
+func updateEnvDriftDetection(c *gin.Context, production bool) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	envName := c.Param("name")
+	projectKey := c.Query("projectName")
+
+	args := new(commonmodels.DriftDetectionConfig)
+	data, err := c.GetRawData()
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	if err = json.Unmarshal(data, args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, projectKey, "更新", "环境-配置漂移检测", envName, string(data), ctx.Logger)
+
+	if !envActionPermitted(ctx, projectKey, envName, production, true) {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	ctx.Err = service.UpdateEnvDriftDetection(projectKey, envName, args, production, ctx.Logger)
+}
+
+// envActionPermitted checks the view permission of the env, or the edit config permission if edit is true
+func envActionPermitted(ctx *internalhandler.Context, projectKey, envName string, production, edit bool) bool {
+	if ctx.Resources.IsSystemAdmin {
+		return true
+	}
+	projectAuthInfo, ok := ctx.Resources.ProjectAuthInfo[projectKey]
+	if !ok {
+		return false
+	}
+	if projectAuthInfo.IsProjectAdmin {
+		return true
+	}
+
+	if production {
+		if edit {
+			return projectAuthInfo.ProductionEnv.EditConfig
+		}
+		return projectAuthInfo.ProductionEnv.View
+	}
+
+	if edit {
+		if projectAuthInfo.Env.EditConfig {
+			return true
+		}
+		permitted, err := internalhandler.GetCollaborationModePermission(ctx.UserID, projectKey, types.ResourceTypeEnvironment, envName, types.EnvActionEditConfig)
+		return err == nil && permitted
+	}
+	if projectAuthInfo.Env.View {
+		return true
+	}
+	permitted, err := internalhandler.GetCollaborationModePermission(ctx.UserID, projectKey, types.ResourceTypeEnvironment, envName, types.EnvActionView)
+	return err == nil && permitted
+}
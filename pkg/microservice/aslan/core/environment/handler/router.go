@@ -169,6 +169,15 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		production.DELETE("/kube/:name/pods/:podName", DeletePod)
 
 		production.GET("/environments/:name/helm/releases", ListProductionReleases)
+		production.PUT("/environments/:name/helm/chartVersionPins", UpdateProductionChartVersionPins)
+		production.GET("/environments/:name/helm/chartVersionPins/preview", PreviewProductionChartVersionUpgrade)
+		production.PUT("/environments/:name/helm/deployConfig", UpdateProductionHelmDeployConfig)
+		production.GET("/environments/:name/drift", GetProductionEnvDrift)
+		production.POST("/environments/:name/drift", CheckProductionEnvDrift)
+		production.PUT("/environments/:name/drift/config", UpdateProductionEnvDriftDetection)
+		production.GET("/environments/:name/health", GetProductionEnvHealth)
+		production.GET("/environments/:name/restarts", GetProductionServiceRestartTrends)
+		production.PUT("/environments/:name/metricSnapshot", UpdateProductionEnvMetricSnapshot)
 		production.DELETE("/environments/:name/helm/releases", DeleteProductionHelmReleases)
 		production.GET("/environments/:name/helm/values", GetProductionChartValues)
 		production.GET("/environments/:name/workloads", ListWorkloadsInEnv)
@@ -244,6 +253,15 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		environments.GET("/:name/workloads", ListWorkloadsInEnv)
 
 		environments.GET("/:name/helm/releases", ListReleases)
+		environments.PUT("/:name/helm/chartVersionPins", UpdateChartVersionPins)
+		environments.GET("/:name/helm/chartVersionPins/preview", PreviewChartVersionUpgrade)
+		environments.PUT("/:name/helm/deployConfig", UpdateHelmDeployConfig)
+		environments.GET("/:name/drift", GetEnvDrift)
+		environments.POST("/:name/drift", CheckEnvDrift)
+		environments.PUT("/:name/drift/config", UpdateEnvDriftDetection)
+		environments.GET("/:name/health", GetEnvHealth)
+		environments.GET("/:name/restarts", GetServiceRestartTrends)
+		environments.PUT("/:name/metricSnapshot", UpdateEnvMetricSnapshot)
 		environments.GET("/:name/helm/values", GetChartValues)
 		environments.GET("/:name/helm/charts", GetChartInfos)
 		environments.GET("/:name/helm/images", GetImageInfos)
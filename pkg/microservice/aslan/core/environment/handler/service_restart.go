@@ -0,0 +1,63 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/environment/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func GetServiceRestartTrends(c *gin.Context) {
+	getServiceRestartTrends(c, false)
+}
+
+func GetProductionServiceRestartTrends(c *gin.Context) {
+	getServiceRestartTrends(c, true)
+}
+
+// getServiceRestartTrends returns the container restarts and OOM kills of the services in the last hours, 24 by default
+func getServiceRestartTrends(c *gin.Context, production bool) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	envName := c.Param("name")
+	projectKey := c.Query("projectName")
+	if !envActionPermitted(ctx, projectKey, envName, production, false) {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	hours, err := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid hours")
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.GetServiceRestartTrends(projectKey, envName, c.Query("serviceName"), production, hours)
+}
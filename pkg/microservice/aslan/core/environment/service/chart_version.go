@@ -0,0 +1,221 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"sort"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/repository"
+	commonutil "github.com/koderover/zadig/pkg/microservice/aslan/core/common/util"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	helmtool "github.com/koderover/zadig/pkg/tool/helmclient"
+	"github.com/koderover/zadig/pkg/util"
+)
+
+type ChartVersionPinArgs struct {
+	// Pins key: service name, value: chart version, an empty version removes the pin
+	Pins map[string]string `json:"pins"`
+}
+
+type ChartVersionUpgradePreview struct {
+	ServiceName     string `json:"service_name"`
+	ReleaseName     string `json:"release_name"`
+	ChartName       string `json:"chart_name"`
+	DeployedVersion string `json:"deployed_version"`
+	TemplateVersion string `json:"template_version"`
+	PinnedVersion   string `json:"pinned_version"`
+	TargetVersion   string `json:"target_version"`
+	Changed         bool   `json:"changed"`
+}
+
+func UpdateChartVersionPins(projectName, envName string, args *ChartVersionPinArgs, production bool, log *zap.SugaredLogger) error {
+	prod, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: envName, Production: util.GetBoolPointer(production)})
+	if err != nil {
+		return e.ErrGetEnv.AddErr(err)
+	}
+	if prod.Source != setting.SourceFromHelm {
+		return e.ErrInvalidParam.AddDesc("chart versions can only be pinned in helm environments")
+	}
+
+	templateSvcs, err := repository.ListMaxRevisionsServices(projectName, production)
+	if err != nil {
+		return fmt.Errorf("failed to list service templates for project: %s, err: %s", projectName, err)
+	}
+	templateSvcMap := make(map[string]*commonmodels.Service)
+	for _, svc := range templateSvcs {
+		templateSvcMap[svc.ServiceName] = svc
+	}
+
+	prodSvcMap := prod.GetServiceMap()
+	pins := make(map[string]string)
+	for serviceName, version := range args.Pins {
+		if version == "" {
+			continue
+		}
+		if _, ok := prodSvcMap[serviceName]; !ok {
+			return e.ErrInvalidParam.AddDesc(fmt.Sprintf("service %s is not deployed in environment %s", serviceName, envName))
+		}
+		creation, err := kube.GetChartRepoCreation(templateSvcMap[serviceName])
+		if err != nil {
+			return e.ErrInvalidParam.AddDesc(fmt.Sprintf("chart version of service %s can't be pinned: %s", serviceName, err))
+		}
+		if err = validateChartVersion(creation.ChartRepoName, creation.ChartName, version); err != nil {
+			return e.ErrInvalidParam.AddErr(err)
+		}
+		pins[serviceName] = version
+	}
+
+	if err = commonrepo.NewProductColl().UpdateChartVersionPins(envName, projectName, pins); err != nil {
+		log.Errorf("failed to update chart version pins of env %s/%s, err: %s", projectName, envName, err)
+		return e.ErrUpdateEnv.AddErr(err)
+	}
+	return nil
+}
+
+// validateChartVersion checks whether the chart version exists in the chart repo
+func validateChartVersion(chartRepoName, chartName, version string) error {
+	chartRepo, err := commonrepo.NewHelmRepoColl().Find(&commonrepo.HelmRepoFindOption{RepoName: chartRepoName})
+	if err != nil {
+		return fmt.Errorf("failed to query chart-repo info, repoName: %s", chartRepoName)
+	}
+	hClient, err := helmtool.NewClient()
+	if err != nil {
+		return err
+	}
+
+	repoEntry := commonutil.GeneHelmRepo(chartRepo)
+	if helmtool.IsOCIRepo(repoEntry) {
+		versions, err := hClient.ListOCIChartVersions(repoEntry, chartName)
+		if err != nil {
+			return fmt.Errorf("failed to list versions of chart %s, err: %s", chartName, err)
+		}
+		if !sets.NewString(versions...).Has(version) {
+			return fmt.Errorf("version %s of chart %s is not found in chart repo %s", version, chartName, chartRepoName)
+		}
+		return nil
+	}
+
+	index, err := hClient.FetchIndexYaml(repoEntry)
+	if err != nil {
+		return fmt.Errorf("failed to fetch index.yaml of chart repo %s, err: %s", chartRepoName, err)
+	}
+	if _, err = index.Get(chartName, version); err != nil {
+		return fmt.Errorf("version %s of chart %s is not found in chart repo %s", version, chartName, chartRepoName)
+	}
+	return nil
+}
+
+// PreviewChartVersionUpgrade shows the chart version diffs between the deployed releases and the charts to be deployed
+func PreviewChartVersionUpgrade(projectName, envName string, production bool, log *zap.SugaredLogger) ([]*ChartVersionUpgradePreview, error) {
+	prod, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: envName, Production: util.GetBoolPointer(production)})
+	if err != nil {
+		return nil, e.ErrGetEnv.AddErr(err)
+	}
+
+	restConfig, err := kube.GetRESTConfig(prod.ClusterID)
+	if err != nil {
+		log.Errorf("GetRESTConfig error: %s", err)
+		return nil, fmt.Errorf("failed to get k8s rest config, err: %s", err)
+	}
+	helmClient, err := helmtool.NewClientFromRestConf(restConfig, prod.Namespace)
+	if err != nil {
+		log.Errorf("[%s][%s] NewClientFromRestConf error: %s", envName, projectName, err)
+		return nil, fmt.Errorf("failed to init helm client, err: %s", err)
+	}
+	releases, err := listReleaseInNamespace(helmClient, &ReleaseFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list release, err: %s", err)
+	}
+	deployedVersions := make(map[string]string)
+	for _, re := range releases {
+		if re.Chart != nil && re.Chart.Metadata != nil {
+			deployedVersions[re.Name] = re.Chart.Metadata.Version
+		}
+	}
+
+	svcToReleaseNameMap, err := commonutil.GetServiceNameToReleaseNameMap(prod)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build release-service map: %s", err)
+	}
+	templateSvcs, err := repository.ListMaxRevisionsServices(projectName, production)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list service templates for project: %s, err: %s", projectName, err)
+	}
+	templateSvcMap := make(map[string]*commonmodels.Service)
+	for _, svc := range templateSvcs {
+		templateSvcMap[svc.ServiceName] = svc
+	}
+
+	ret := make([]*ChartVersionUpgradePreview, 0)
+	for serviceName := range prod.GetServiceMap() {
+		templateSvc := templateSvcMap[serviceName]
+		if templateSvc == nil || templateSvc.HelmChart == nil {
+			continue
+		}
+		preview := &ChartVersionUpgradePreview{
+			ServiceName:     serviceName,
+			ReleaseName:     svcToReleaseNameMap[serviceName],
+			ChartName:       templateSvc.HelmChart.Name,
+			TemplateVersion: templateSvc.HelmChart.Version,
+			TargetVersion:   templateSvc.HelmChart.Version,
+		}
+		preview.DeployedVersion = deployedVersions[preview.ReleaseName]
+		if pinnedVersion := prod.ChartVersionPins[serviceName]; pinnedVersion != "" && templateSvc.Source == setting.SourceFromChartRepo {
+			preview.PinnedVersion = pinnedVersion
+			preview.TargetVersion = pinnedVersion
+		}
+		preview.Changed = preview.DeployedVersion != preview.TargetVersion
+		ret = append(ret, preview)
+	}
+
+	renderSet, err := commonrepo.NewRenderSetColl().Find(&commonrepo.RenderSetFindOption{
+		Name:     prod.Render.Name,
+		Revision: prod.Render.Revision,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find renderset: %s:%v, err: %s", prod.Render.Name, prod.Render.Revision, err)
+	}
+	chartDeployInfoMap := renderSet.GetChartDeployRenderMap()
+	for releaseName := range prod.GetChartServiceMap() {
+		chartInfo := chartDeployInfoMap[releaseName]
+		if chartInfo == nil {
+			continue
+		}
+		ret = append(ret, &ChartVersionUpgradePreview{
+			ServiceName:     releaseName,
+			ReleaseName:     releaseName,
+			ChartName:       chartInfo.ChartName,
+			DeployedVersion: deployedVersions[releaseName],
+			TemplateVersion: chartInfo.ChartVersion,
+			TargetVersion:   chartInfo.ChartVersion,
+			Changed:         deployedVersions[releaseName] != chartInfo.ChartVersion,
+		})
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].ServiceName < ret[j].ServiceName
+	})
+	return ret, nil
+}
@@ -0,0 +1,331 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	configbase "github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/imnotify"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
+	commonutil "github.com/koderover/zadig/pkg/microservice/aslan/core/common/util"
+	"github.com/koderover/zadig/pkg/setting"
+	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	helmtool "github.com/koderover/zadig/pkg/tool/helmclient"
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/util"
+)
+
+// DetectEnvDrift runs the drift detection of all the envs enabling it, it is run periodically by the cron job
+func DetectEnvDrift() {
+	envs, err := commonrepo.NewProductColl().List(&commonrepo.ProductListOptions{
+		ExcludeStatus: []string{setting.ProductStatusCreating, setting.ProductStatusUpdating, setting.ProductStatusDeleting},
+	})
+	if err != nil {
+		log.Errorf("[EnvDrift] failed to list envs, err: %s", err)
+		return
+	}
+
+	for _, env := range envs {
+		if env.DriftDetection == nil || !env.DriftDetection.Enabled || env.IsSleeping() {
+			continue
+		}
+		if _, err := detectEnvDrift(env, env.DriftDetection.AutoRevert); err != nil {
+			log.Errorf("[EnvDrift] failed to detect drift of env %s/%s, err: %s", env.ProductName, env.EnvName, err)
+		}
+	}
+}
+
+// CheckEnvDrift runs the drift detection of the env at once, the drifted resources are reverted if revert is true
+func CheckEnvDrift(projectName, envName string, production, revert bool, log *zap.SugaredLogger) (*commonmodels.EnvDrift, error) {
+	env, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: envName, Production: util.GetBoolPointer(production)})
+	if err != nil {
+		return nil, e.ErrGetEnv.AddErr(err)
+	}
+	if env.IsSleeping() {
+		return nil, e.ErrInvalidParam.AddDesc("environment is sleeping")
+	}
+
+	resp, err := detectEnvDrift(env, revert)
+	if err != nil {
+		log.Errorf("failed to detect drift of env %s/%s, err: %s", projectName, envName, err)
+		return nil, e.ErrInternalError.AddErr(err)
+	}
+	return resp, nil
+}
+
+func GetEnvDrift(projectName, envName string, production bool) (*commonmodels.EnvDrift, error) {
+	env, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: envName, Production: util.GetBoolPointer(production)})
+	if err != nil {
+		return nil, e.ErrGetEnv.AddErr(err)
+	}
+
+	resp, err := commonrepo.NewEnvDriftColl().Get(projectName, envName)
+	// the result left by a deleted env with the same name is not returned
+	if err == mongo.ErrNoDocuments || err == nil && resp.CheckTime < env.CreateTime {
+		return &commonmodels.EnvDrift{
+			ProductName: projectName,
+			EnvName:     envName,
+			Production:  production,
+			Services:    make([]*commonmodels.ServiceDrift, 0),
+		}, nil
+	}
+	if err != nil {
+		return nil, e.ErrInternalError.AddErr(err)
+	}
+	return resp, nil
+}
+
+func UpdateEnvDriftDetection(projectName, envName string, args *commonmodels.DriftDetectionConfig, production bool, log *zap.SugaredLogger) error {
+	env, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: envName, Production: util.GetBoolPointer(production)})
+	if err != nil {
+		return e.ErrGetEnv.AddErr(err)
+	}
+	if env.Source != setting.SourceFromZadig && env.Source != setting.SourceFromHelm {
+		return e.ErrInvalidParam.AddDesc("drift detection is only supported in k8s yaml and helm environments")
+	}
+
+	if err = commonrepo.NewProductColl().UpdateDriftDetection(envName, projectName, args); err != nil {
+		log.Errorf("failed to update drift detection of env %s/%s, err: %s", projectName, envName, err)
+		return e.ErrUpdateEnv.AddErr(err)
+	}
+	return nil
+}
+
+func detectEnvDrift(env *commonmodels.Product, revert bool) (*commonmodels.EnvDrift, error) {
+	prevDrift, _ := commonrepo.NewEnvDriftColl().Get(env.ProductName, env.EnvName)
+
+	resp := &commonmodels.EnvDrift{
+		ProductName: env.ProductName,
+		EnvName:     env.EnvName,
+		Production:  env.Production,
+		Services:    make([]*commonmodels.ServiceDrift, 0),
+		CheckTime:   time.Now().Unix(),
+	}
+	var ignoredFields []string
+	if env.DriftDetection != nil {
+		ignoredFields = env.DriftDetection.IgnoredFields
+	}
+
+	apiReader, err := kubeclient.GetKubeAPIReader(config.HubServerAddress(), env.ClusterID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kube api reader: %s", err)
+	}
+	kubeClient, err := kubeclient.GetKubeClient(config.HubServerAddress(), env.ClusterID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kube client: %s", err)
+	}
+
+	manifests, errs, err := desiredEnvManifests(env)
+	if err != nil {
+		resp.Error = err.Error()
+	}
+	for serviceName, manifest := range manifests {
+		serviceDrift := &commonmodels.ServiceDrift{ServiceName: serviceName}
+		option := &kube.ManifestDriftOption{
+			Namespace:     env.Namespace,
+			Manifest:      manifest.manifest,
+			IgnoredFields: ignoredFields,
+			Labels:        manifest.labels,
+			Annotations:   manifest.annotations,
+			Revert:        revert,
+			APIReader:     apiReader,
+			KubeClient:    kubeClient,
+		}
+		serviceDrift.Resources, err = kube.DetectManifestDrift(option)
+		if err != nil {
+			serviceDrift.Error = err.Error()
+		}
+		if len(serviceDrift.Resources) > 0 || serviceDrift.Error != "" {
+			resp.Services = append(resp.Services, serviceDrift)
+		}
+	}
+	for serviceName, err := range errs {
+		resp.Services = append(resp.Services, &commonmodels.ServiceDrift{ServiceName: serviceName, Error: err.Error()})
+	}
+
+	for _, svc := range resp.Services {
+		for _, resource := range svc.Resources {
+			if resourceDrifted(resource) {
+				resp.Drifted = true
+			}
+		}
+	}
+
+	if err := commonrepo.NewEnvDriftColl().Upsert(resp); err != nil {
+		return nil, fmt.Errorf("failed to save the drift: %s", err)
+	}
+
+	// notify only when the drifted resources change so that the same drift is not notified again and again
+	if driftSignature(resp) != "" && driftSignature(resp) != driftSignature(prevDrift) {
+		if err := envDriftNotification(env, resp); err != nil {
+			log.Errorf("[EnvDrift] failed to send drift notification of env %s/%s, err: %s", env.ProductName, env.EnvName, err)
+		}
+	}
+	return resp, nil
+}
+
+type desiredManifest struct {
+	manifest    string
+	labels      map[string]string
+	annotations map[string]string
+}
+
+// desiredEnvManifests returns the desired manifests of the services in the env, the rendered yaml for the k8s yaml
+// services and the release manifest for the helm services, which are keyed by the service name
+func desiredEnvManifests(env *commonmodels.Product) (map[string]*desiredManifest, map[string]error, error) {
+	manifests := make(map[string]*desiredManifest)
+	errs := make(map[string]error)
+
+	if env.Source == setting.SourceFromHelm {
+		releaseNames, err := commonutil.GetReleaseNameToServiceNameMap(env)
+		if err != nil {
+			return manifests, errs, err
+		}
+		helmClient, err := helmtool.NewClientFromNamespace(env.ClusterID, env.Namespace)
+		if err != nil {
+			return manifests, errs, err
+		}
+		deployed := sets.NewString(env.GetProductSvcNames()...)
+		for releaseName, serviceName := range releaseNames {
+			if !deployed.Has(serviceName) {
+				continue
+			}
+			release, err := helmClient.GetRelease(releaseName)
+			if err != nil {
+				errs[serviceName] = fmt.Errorf("failed to get release %s: %s", releaseName, err)
+				continue
+			}
+			manifests[serviceName] = &desiredManifest{
+				manifest: release.Manifest,
+				// helm adopts the recreated resources only with these metadata
+				labels: map[string]string{"app.kubernetes.io/managed-by": "Helm"},
+				annotations: map[string]string{
+					"meta.helm.sh/release-name":      releaseName,
+					"meta.helm.sh/release-namespace": env.Namespace,
+				},
+			}
+		}
+		return manifests, errs, nil
+	}
+
+	for _, svc := range env.GetServiceMap() {
+		if svc.Type != setting.K8SDeployType {
+			continue
+		}
+		manifest, _, err := kube.FetchCurrentAppliedYaml(&kube.GeneSvcYamlOption{
+			ProductName: env.ProductName,
+			EnvName:     env.EnvName,
+			ServiceName: svc.ServiceName,
+		})
+		if err != nil {
+			errs[svc.ServiceName] = fmt.Errorf("failed to render service yaml: %s", err)
+			continue
+		}
+		manifests[svc.ServiceName] = &desiredManifest{
+			manifest: manifest,
+			labels:   kube.GetPredefinedLabels(env.ProductName, svc.ServiceName),
+		}
+	}
+	return manifests, errs, nil
+}
+
+// resourceDrifted tells whether the resource is still drifted, the ones failed to be compared are not counted
+func resourceDrifted(resource *commonmodels.ResourceDrift) bool {
+	return (resource.Missing || len(resource.Fields) > 0) && !resource.Reverted
+}
+
+// driftSignature identifies the drifted resources which are not reverted
+func driftSignature(drift *commonmodels.EnvDrift) string {
+	if drift == nil {
+		return ""
+	}
+	resources := make([]string, 0)
+	for _, svc := range drift.Services {
+		for _, resource := range svc.Resources {
+			if !resourceDrifted(resource) {
+				continue
+			}
+			resources = append(resources, fmt.Sprintf("%s/%s/%s:%s", svc.ServiceName, resource.Kind, resource.Name, strings.Join(resource.Fields, ",")))
+		}
+	}
+	return strings.Join(sets.NewString(resources...).List(), ";")
+}
+
+func envDriftNotification(env *commonmodels.Product, drift *commonmodels.EnvDrift) error {
+	title := fmt.Sprintf("%s / %s 环境配置漂移", env.ProductName, env.EnvName)
+	lines := make([]string, 0)
+	for _, svc := range drift.Services {
+		for _, resource := range svc.Resources {
+			if !resourceDrifted(resource) {
+				continue
+			}
+			detail := strings.Join(resource.Fields, ", ")
+			if resource.Missing {
+				detail = "资源不存在"
+			}
+			lines = append(lines, fmt.Sprintf("- %s %s/%s: %s", svc.ServiceName, resource.Kind, resource.Name, detail))
+		}
+	}
+	envDetailURL := fmt.Sprintf("%s/v1/projects/detail/%s/envs/detail?envName=%s", configbase.SystemAddress(), env.ProductName, env.EnvName)
+
+	imnotifyClient := imnotify.NewIMNotifyClient()
+	for _, notifyConfig := range env.NotificationConfigs {
+		if !sets.NewString(notificationEventsToStrings(notifyConfig.Events)...).Has(string(commonmodels.NotificationEventEnvDrift)) {
+			continue
+		}
+
+		var err error
+		switch imnotify.IMNotifyType(notifyConfig.WebHookType) {
+		case imnotify.IMNotifyTypeLark:
+			lc := imnotify.NewLarkCard()
+			lc.SetConfig(true)
+			lc.SetHeader(imnotify.GetColorTemplateWithStatus(config.StatusFailed), title, "plain_text")
+			lc.AddI18NElementsZhcnFeild(strings.Join(lines, "\n"), true)
+			lc.AddI18NElementsZhcnAction("点击查看更多信息", envDetailURL)
+			err = imnotifyClient.SendFeishuMessage(notifyConfig.WebHookURL, lc)
+		case imnotify.IMNotifyTypeDingDing:
+			content := fmt.Sprintf("### %s \n%s \n\n[点击查看更多信息](%s)", title, strings.Join(lines, "\n"), envDetailURL)
+			err = imnotifyClient.SendDingDingMessage(notifyConfig.WebHookURL, title, content, nil, false)
+		case imnotify.IMNotifyTypeWeChat:
+			content := fmt.Sprintf("### %s \n%s \n\n[点击查看更多信息](%s)", title, strings.Join(lines, "\n"), envDetailURL)
+			err = imnotifyClient.SendWeChatWorkMessage(imnotify.WeChatTextTypeMarkdown, notifyConfig.WebHookURL, content)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func notificationEventsToStrings(events []commonmodels.NotificationEvent) []string {
+	ret := make([]string, 0, len(events))
+	for _, event := range events {
+		ret = append(ret, string(event))
+	}
+	return ret
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
+	"github.com/koderover/zadig/pkg/setting"
+	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/util"
+)
+
+type EnvHealthResp struct {
+	ProductName string           `json:"product_name"`
+	EnvName     string           `json:"env_name"`
+	Status      string           `json:"status"`
+	Services    []*ServiceHealth `json:"services"`
+	CheckTime   int64            `json:"check_time"`
+}
+
+type ServiceHealth struct {
+	ServiceName string                 `json:"service_name"`
+	Status      string                 `json:"status"`
+	Workloads   []*kube.WorkloadHealth `json:"workloads"`
+	Error       string                 `json:"error"`
+}
+
+// GetEnvHealth evaluates the live health of all the services in the env in one call,
+// the env status is the worst status of its services
+func GetEnvHealth(projectName, envName string, production bool, log *zap.SugaredLogger) (*EnvHealthResp, error) {
+	env, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: envName, Production: util.GetBoolPointer(production)})
+	if err != nil {
+		return nil, e.ErrGetEnv.AddErr(err)
+	}
+	if env.Source != setting.SourceFromZadig && env.Source != setting.SourceFromHelm {
+		return nil, e.ErrInvalidParam.AddDesc("health check is only supported in k8s yaml and helm environments")
+	}
+
+	kubeClient, err := kubeclient.GetKubeClient(config.HubServerAddress(), env.ClusterID)
+	if err != nil {
+		return nil, e.ErrGetEnv.AddErr(err)
+	}
+
+	resp := &EnvHealthResp{
+		ProductName: projectName,
+		EnvName:     envName,
+		Status:      kube.HealthStatusHealthy,
+		Services:    make([]*ServiceHealth, 0),
+		CheckTime:   time.Now().Unix(),
+	}
+	if env.IsSleeping() {
+		return resp, nil
+	}
+
+	manifests, errs, err := desiredEnvManifests(env)
+	if err != nil {
+		log.Errorf("failed to get the manifests of env %s/%s, err: %s", projectName, envName, err)
+		return nil, e.ErrGetEnv.AddErr(err)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for serviceName, manifest := range manifests {
+		wg.Add(1)
+		go func(serviceName, manifest string) {
+			defer wg.Done()
+			health := &ServiceHealth{ServiceName: serviceName, Status: kube.HealthStatusHealthy}
+			workloads, err := kube.EvaluateManifestHealth(env.Namespace, manifest, kube.DefaultRestartWindow, kubeClient)
+			health.Workloads = workloads
+			if err != nil {
+				health.Status, health.Error = kube.HealthStatusUnhealthy, err.Error()
+			}
+			for _, workload := range health.Workloads {
+				health.Status = kube.WorstHealthStatus(health.Status, workload.Status)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			resp.Services = append(resp.Services, health)
+		}(serviceName, manifest.manifest)
+	}
+	wg.Wait()
+
+	for serviceName, err := range errs {
+		resp.Services = append(resp.Services, &ServiceHealth{ServiceName: serviceName, Status: kube.HealthStatusUnhealthy, Error: err.Error()})
+	}
+	for _, svc := range resp.Services {
+		resp.Status = kube.WorstHealthStatus(resp.Status, svc.Status)
+	}
+	sort.Slice(resp.Services, func(i, j int) bool {
+		return resp.Services[i].ServiceName < resp.Services[j].ServiceName
+	})
+	return resp, nil
+}
@@ -338,7 +338,7 @@ func UpdateMultipleK8sEnv(args []*UpdateEnv, envNames []string, productName, req
 
 // TODO need optimize
 // cvm and k8s yaml projects should not be handled together
-func updateProductImpl(updateRevisionSvcs []string, deployStrategy map[string]string, existedProd, updateProd *commonmodels.Product, renderSet *commonmodels.RenderSet, filter svcUpgradeFilter, log *zap.SugaredLogger) (err error) {
+func updateProductImpl(updateRevisionSvcs []string, deployStrategy map[string]string, existedProd, updateProd *commonmodels.Product, renderSet *commonmodels.RenderSet, filter svcUpgradeFilter, confirmProtected bool, log *zap.SugaredLogger) (err error) {
 	oldProductRender := existedProd.Render
 	updateProd.Render = &commonmodels.RenderInfo{
 		Name:        renderSet.Name,
@@ -488,7 +488,7 @@ func updateProductImpl(updateRevisionSvcs []string, deployStrategy map[string]st
 						updateProd,
 						service,
 						existedServices[service.ServiceName],
-						renderSet, oldProductRender, !updateProd.Production, inf, kubeClient, istioClient, log)
+						renderSet, oldProductRender, !updateProd.Production, confirmProtected, inf, kubeClient, istioClient, log)
 					if errUpsertService != nil {
 						service.Error = errUpsertService.Error()
 					} else {
@@ -600,6 +600,9 @@ func UpdateCVMProduct(envName, productName, user, requestID string, log *zap.Sug
 // CreateProduct create a new product with its dependent stacks
 func CreateProduct(user, requestID string, args *commonmodels.Product, log *zap.SugaredLogger) (err error) {
 	log.Infof("[%s][P:%s] CreateProduct", args.EnvName, args.ProductName)
+	if err := commonservice.CheckTenantEnvironmentQuota(args.ProductName); err != nil {
+		return e.ErrCreateEnv.AddErr(err)
+	}
 	creator := getCreatorBySource(args.Source)
 	args.UpdateBy = user
 	return creator.Create(user, requestID, args, log)
@@ -2492,7 +2495,7 @@ func restartRelatedWorkloads(env *commonmodels.Product, service *commonmodels.Pr
 
 // upsertService
 func upsertService(env *commonmodels.Product, service *commonmodels.ProductService, prevSvc *commonmodels.ProductService,
-	renderSet *commonmodels.RenderSet, preRenderInfo *commonmodels.RenderInfo, addLabel bool, informer informers.SharedInformerFactory,
+	renderSet *commonmodels.RenderSet, preRenderInfo *commonmodels.RenderInfo, addLabel, confirmProtected bool, informer informers.SharedInformerFactory,
 	kubeClient client.Client, istioClient versionedclient.Interface, log *zap.SugaredLogger) ([]*unstructured.Unstructured, error) {
 	isUpdate := prevSvc == nil
 	errList := &multierror.Error{
@@ -2558,6 +2561,7 @@ func upsertService(env *commonmodels.Product, service *commonmodels.ProductServi
 		Uninstall:           false,
 		AddZadigLabel:       addLabel,
 		SharedEnvHandler:    EnsureUpdateZadigService,
+		ConfirmProtected:    confirmProtected,
 	}
 
 	return kube.CreateOrPatchResource(resourceApplyParam, log)
@@ -2723,6 +2727,15 @@ func buildInstallParam(defaultValues string, productInfo *commonmodels.Product,
 		}
 		ret.ServiceObj = serviceObj
 		ret.ReleaseName = util.GeneReleaseName(serviceObj.GetReleaseNaming(), serviceObj.ProductName, namespace, envName, serviceObj.ServiceName)
+
+		pinnedChart, chartPinned, err := kube.PreparePinnedChart(productInfo, serviceObj, renderChart)
+		if err != nil {
+			return nil, err
+		}
+		if chartPinned {
+			ret.RenderChart = pinnedChart
+			ret.IsChartInstall = true
+		}
 	} else {
 		serviceObj := &commonmodels.Service{
 			ServiceName: renderChart.ReleaseName,
@@ -2766,6 +2779,7 @@ func buildInstallParam(defaultValues string, productInfo *commonmodels.Product,
 	}
 	ret.MergedValues = mergedValues
 	ret.Production = productInfo.Production
+	ret.DeployConfig = productInfo.HelmDeployConfig
 	return ret, nil
 }
 
@@ -28,6 +28,7 @@ import (
 	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
 	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/render"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/repository"
 	commontypes "github.com/koderover/zadig/pkg/microservice/aslan/core/common/types"
 	"github.com/koderover/zadig/pkg/setting"
 	e "github.com/koderover/zadig/pkg/tool/errors"
@@ -342,6 +343,17 @@ func createSingleYamlProduct(templateProduct *templatemodels.Product, requestID,
 
 	for _, svg := range arg.Services {
 		for _, sv := range svg {
+			svcTemplate, err := repository.QueryTemplateService(&commonrepo.ServiceFindOption{
+				ProductName: templateProduct.ProductName,
+				ServiceName: sv.ServiceName,
+			}, arg.Production)
+			if err != nil {
+				return fmt.Errorf("failed to find service template, svcName: %s, err: %w", sv.ServiceName, err)
+			}
+			if err := commontypes.ValidateVariableKVsBySchema(svcTemplate.ServiceVariableKVs, sv.VariableKVs); err != nil {
+				return e.ErrCreateEnv.AddErr(fmt.Errorf("invalid variables of service %s, err: %w", sv.ServiceName, err))
+			}
+
 			variableYaml, err := commontypes.RenderVariableKVToYaml(sv.VariableKVs)
 			if err != nil {
 				return fmt.Errorf("failed to convert render variable kvs to yaml, svcName: %s, err: %w", sv.ServiceName, err)
@@ -95,6 +95,12 @@ type ProductResp struct {
 	ShareEnvEnable  bool   `json:"share_env_enable"`
 	ShareEnvIsBase  bool   `json:"share_env_is_base"`
 	ShareEnvBaseEnv string `json:"share_env_base_env"`
+
+	DriftDetection *commonmodels.DriftDetectionConfig `json:"drift_detection"`
+	// Drifted is true if the live objects differ from the desired manifests in the latest drift detection
+	Drifted bool `json:"drifted"`
+
+	MetricSnapshot *commonmodels.MetricSnapshotConfig `json:"metric_snapshot"`
 }
 
 type ProductParams struct {
@@ -361,6 +361,11 @@ func updateK8sProduct(exitedProd *commonmodels.Product, user, requestID string,
 			log.Errorf("the following services are modified since last update: %s", data)
 			return fmt.Errorf("the following services are modified since last update: %s", data)
 		}
+
+		if err := checkProtectedResources(exitedProd, updateRevisionSvc); err != nil {
+			log.Error(err)
+			return err
+		}
 	}
 
 	err = ensureKubeEnv(exitedProd.Namespace, exitedProd.RegistryID, map[string]string{setting.ProductLabel: productName}, exitedProd.ShareEnv.Enable, kubeClient, log)
@@ -415,6 +420,11 @@ func updateK8sProduct(exitedProd *commonmodels.Product, user, requestID string,
 				log.Error(fmtErr)
 				return e.ErrUpdateEnv.AddErr(fmtErr)
 			}
+
+			err = commontypes.ValidateVariableKVsBySchema(svcTemplate.ServiceVariableKVs, svc.OverrideYaml.RenderVariableKVs)
+			if err != nil {
+				return e.ErrUpdateEnv.AddErr(fmt.Errorf("invalid variables of service %s, err: %w", svc.ServiceName, err))
+			}
 		} else {
 			globalVariables, svc.OverrideYaml.RenderVariableKVs, err = commontypes.UpdateGlobalVariableKVs(svc.ServiceName, globalVariables, svc.OverrideYaml.RenderVariableKVs, curSvcRender.OverrideYaml.RenderVariableKVs)
 			if err != nil {
@@ -506,7 +516,7 @@ func updateK8sProduct(exitedProd *commonmodels.Product, user, requestID string,
 
 	go func() {
 		productErrMsg := ""
-		err = updateProductImpl(updateRevisionSvc, deployStrategy, exitedProd, updateProd, renderSet, filter, log)
+		err = updateProductImpl(updateRevisionSvc, deployStrategy, exitedProd, updateProd, renderSet, filter, force, log)
 		if err != nil {
 			productErrMsg = err.Error()
 			log.Errorf("[%s][P:%s] failed to update product %#v", envName, productName, err)
@@ -527,6 +537,37 @@ func updateK8sProduct(exitedProd *commonmodels.Product, user, requestID string,
 	return nil
 }
 
+// checkProtectedResources returns an error if any of the services to be updated owns resources protected in confirm mode
+func checkProtectedResources(env *commonmodels.Product, serviceNames []string) error {
+	apiReader, err := kubeclient.GetKubeAPIReader(config.HubServerAddress(), env.ClusterID)
+	if err != nil {
+		return e.ErrUpdateEnv.AddErr(err)
+	}
+
+	svcMap := env.GetServiceMap()
+	protected := make([]*kube.ProtectedResource, 0)
+	for _, serviceName := range serviceNames {
+		svc, ok := svcMap[serviceName]
+		if !ok || svc.Type != setting.K8SDeployType {
+			continue
+		}
+		manifest, _, err := kube.FetchCurrentAppliedYaml(&kube.GeneSvcYamlOption{
+			ProductName: env.ProductName,
+			EnvName:     env.EnvName,
+			ServiceName: serviceName,
+		})
+		if err != nil {
+			return e.ErrUpdateEnv.AddErr(fmt.Errorf("failed to fetch current yaml of service %s: %s", serviceName, err))
+		}
+		resources, err := kube.ListProtectedResources(env.Namespace, manifest, apiReader)
+		if err != nil {
+			return e.ErrUpdateEnv.AddErr(fmt.Errorf("failed to check protected resources of service %s: %s", serviceName, err))
+		}
+		protected = append(protected, resources...)
+	}
+	return kube.ConfirmRequiredError(protected)
+}
+
 func updateCVMProduct(exitedProd *commonmodels.Product, user, requestID string, log *zap.SugaredLogger) error {
 	envName, productName := exitedProd.EnvName, exitedProd.ProductName
 	var serviceNames []string
@@ -572,7 +613,7 @@ func updateCVMProduct(exitedProd *commonmodels.Product, user, requestID string,
 
 	go func() {
 		productErrMsg := ""
-		err = updateProductImpl(serviceNames, nil, exitedProd, updateProd, renderSet, nil, log)
+		err = updateProductImpl(serviceNames, nil, exitedProd, updateProd, renderSet, nil, false, log)
 		if err != nil {
 			productErrMsg = err.Error()
 			log.Errorf("[%s][P:%s] failed to update product %#v", envName, productName, err)
@@ -0,0 +1,51 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/util"
+)
+
+func UpdateHelmDeployConfig(projectName, envName string, args *commonmodels.HelmDeployConfig, production bool, log *zap.SugaredLogger) error {
+	prod, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: envName, Production: util.GetBoolPointer(production)})
+	if err != nil {
+		return e.ErrGetEnv.AddErr(err)
+	}
+	if prod.Source != setting.SourceFromHelm {
+		return e.ErrInvalidParam.AddDesc("helm deploy config can only be set in helm environments")
+	}
+	if args.HookTimeout < 0 {
+		return e.ErrInvalidParam.AddDesc("hook timeout can't be negative")
+	}
+	// make sure the patches are valid before saving
+	if _, err = kube.BuildKustomizePostRenderer(args.PostRenderer); err != nil {
+		return e.ErrInvalidParam.AddErr(err)
+	}
+
+	if err = commonrepo.NewProductColl().UpdateHelmDeployConfig(envName, projectName, args); err != nil {
+		log.Errorf("failed to update helm deploy config of env %s/%s, err: %s", projectName, envName, err)
+		return e.ErrUpdateEnv.AddErr(err)
+	}
+	return nil
+}
@@ -181,6 +181,18 @@ func (k *K8sService) updateService(args *SvcOptArgs) error {
 	if err != nil {
 		return e.ErrUpdateEnv.AddErr(fmt.Errorf("failed to update global variable, err: %s", err))
 	}
+	svcTemplate, err := repository.QueryTemplateService(&commonrepo.ServiceFindOption{
+		ServiceName: svc.ServiceName,
+		ProductName: svc.ProductName,
+		Revision:    svc.Revision,
+	}, exitedProd.Production)
+	if err != nil {
+		return e.ErrUpdateService.AddErr(fmt.Errorf("failed to find service, err: %s", err))
+	}
+	err = commontypes.ValidateVariableKVsBySchema(svcTemplate.ServiceVariableKVs, args.ServiceRev.VariableKVs)
+	if err != nil {
+		return e.ErrUpdateEnv.AddErr(fmt.Errorf("invalid variables, err: %s", err))
+	}
 	args.ServiceRev.VariableYaml, err = commontypes.RenderVariableKVToYaml(args.ServiceRev.VariableKVs)
 	if err != nil {
 		return e.ErrUpdateEnv.AddErr(fmt.Errorf("failed to convert render variable to yaml, err: %s", err))
@@ -261,7 +273,7 @@ func (k *K8sService) updateService(args *SvcOptArgs) error {
 			exitedProd,
 			svc,
 			currentProductSvc,
-			curRenderset, preRevision, !exitedProd.Production, inf, kubeClient, istioClient, k.log)
+			curRenderset, preRevision, !exitedProd.Production, args.ServiceRev.ConfirmProtected, inf, kubeClient, istioClient, k.log)
 
 		if err != nil {
 			k.log.Error(err)
@@ -592,7 +604,7 @@ func (k *K8sService) createGroup(username string, product *commonmodels.Product,
 		updatableServiceNameList = append(updatableServiceNameList, group[i].ServiceName)
 		go func(svc *commonmodels.ProductService) {
 			defer wg.Done()
-			items, err := upsertService(prod, svc, svc, renderSet, nil, !prod.Production, informer, kubeClient, istioClient, k.log)
+			items, err := upsertService(prod, svc, svc, renderSet, nil, !prod.Production, false, informer, kubeClient, istioClient, k.log)
 			if err != nil {
 				lock.Lock()
 				switch e := err.(type) {
@@ -0,0 +1,76 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonutil "github.com/koderover/zadig/pkg/microservice/aslan/core/common/util"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/util"
+)
+
+// UpdateEnvMetricSnapshot updates the metrics snapshotted before and after the deploy jobs of the env
+func UpdateEnvMetricSnapshot(projectName, envName string, args *commonmodels.MetricSnapshotConfig, production bool, log *zap.SugaredLogger) error {
+	if _, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: envName, Production: util.GetBoolPointer(production)}); err != nil {
+		return e.ErrGetEnv.AddErr(err)
+	}
+	if err := validateMetricSnapshotConfig(args); err != nil {
+		return e.ErrInvalidParam.AddErr(err)
+	}
+
+	if err := commonrepo.NewProductColl().UpdateMetricSnapshot(envName, projectName, args); err != nil {
+		log.Errorf("failed to update metric snapshot of env %s/%s, err: %s", projectName, envName, err)
+		return e.ErrUpdateEnv.AddErr(err)
+	}
+	return nil
+}
+
+func validateMetricSnapshotConfig(args *commonmodels.MetricSnapshotConfig) error {
+	if !args.Enabled {
+		return nil
+	}
+	if args.WindowMinutes < 0 {
+		return fmt.Errorf("window minutes can not be negative")
+	}
+
+	info, err := commonrepo.NewObservabilityColl().GetByID(context.Background(), args.ObservabilityID)
+	if err != nil {
+		return fmt.Errorf("failed to get observability %s: %s", args.ObservabilityID, err)
+	}
+	if _, err := commonutil.NewPrometheusClient(info); err != nil {
+		return err
+	}
+
+	names := sets.NewString()
+	for _, metric := range args.Metrics {
+		if metric.Name == "" || metric.Query == "" {
+			return fmt.Errorf("name and query of the metrics can not be empty")
+		}
+		if names.Has(metric.Name) {
+			return fmt.Errorf("duplicated metric: %s", metric.Name)
+		}
+		names.Insert(metric.Name)
+	}
+	return nil
+}
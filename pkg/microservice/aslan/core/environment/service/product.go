@@ -239,6 +239,13 @@ func buildProductResp(envName string, prod *commonmodels.Product, log *zap.Sugar
 		ShareEnvEnable:  prod.ShareEnv.Enable,
 		ShareEnvIsBase:  prod.ShareEnv.IsBase,
 		ShareEnvBaseEnv: prod.ShareEnv.BaseEnv,
+		DriftDetection:  prod.DriftDetection,
+		MetricSnapshot:  prod.MetricSnapshot,
+	}
+	if prod.DriftDetection != nil && prod.DriftDetection.Enabled {
+		if drift, err := commonrepo.NewEnvDriftColl().Get(prod.ProductName, prod.EnvName); err == nil && drift.CheckTime >= prod.CreateTime {
+			prodResp.Drifted = drift.Drifted
+		}
 	}
 
 	serviceMap := prod.GetServiceMap()
@@ -557,7 +557,7 @@ func RestartService(envName string, args *SvcOptArgs, log *zap.SugaredLogger) (e
 				productObj,
 				productService,
 				productService,
-				newRender, oldRenderInfo, !productObj.Production, inf, kubeClient, istioClient, log)
+				newRender, oldRenderInfo, !productObj.Production, false, inf, kubeClient, istioClient, log)
 		} else {
 			err = restartRelatedWorkloads(productObj, productService, newRender, kubeClient, log)
 		}
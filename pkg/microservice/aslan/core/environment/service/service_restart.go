@@ -0,0 +1,164 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"sort"
+	"time"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
+	"github.com/koderover/zadig/pkg/setting"
+	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/util"
+)
+
+// serviceRestartRetention is how long the restart samples are kept
+const serviceRestartRetention = 7 * 24 * time.Hour
+
+type ServiceRestartTrend struct {
+	ServiceName string                               `json:"service_name"`
+	Restarts    int32                                `json:"restarts"`
+	OOMKills    int32                                `json:"oom_kills"`
+	Points      []*commonmodels.ServiceRestartRecord `json:"points"`
+}
+
+// CollectServiceRestarts samples the container restarts and OOM kills of the services in the k8s yaml and helm envs,
+// it is run periodically by the cron job
+func CollectServiceRestarts() {
+	envs, err := commonrepo.NewProductColl().List(&commonrepo.ProductListOptions{
+		ExcludeStatus: []string{setting.ProductStatusCreating, setting.ProductStatusDeleting},
+	})
+	if err != nil {
+		log.Errorf("[ServiceRestart] failed to list envs, err: %s", err)
+		return
+	}
+
+	for _, env := range envs {
+		if env.Source != setting.SourceFromZadig && env.Source != setting.SourceFromHelm || env.IsSleeping() {
+			continue
+		}
+		collectEnvServiceRestarts(env)
+	}
+
+	if err := commonrepo.NewServiceRestartRecordColl().DeleteBefore(time.Now().Add(-serviceRestartRetention).Unix()); err != nil {
+		log.Errorf("[ServiceRestart] failed to clean expired records, err: %s", err)
+	}
+}
+
+func collectEnvServiceRestarts(env *commonmodels.Product) {
+	kubeClient, err := kubeclient.GetKubeClient(config.HubServerAddress(), env.ClusterID)
+	if err != nil {
+		log.Errorf("[ServiceRestart] failed to get kube client of env %s/%s, err: %s", env.ProductName, env.EnvName, err)
+		return
+	}
+	manifests, _, err := desiredEnvManifests(env)
+	if err != nil {
+		log.Errorf("[ServiceRestart] failed to get manifests of env %s/%s, err: %s", env.ProductName, env.EnvName, err)
+		return
+	}
+
+	now := time.Now()
+	for serviceName, manifest := range manifests {
+		pods, err := kube.ListManifestPods(env.Namespace, manifest.manifest, kubeClient)
+		if err != nil {
+			log.Errorf("[ServiceRestart] failed to list pods of service %s in env %s/%s, err: %s", serviceName, env.ProductName, env.EnvName, err)
+			continue
+		}
+
+		record := &commonmodels.ServiceRestartRecord{
+			ProductName: env.ProductName,
+			EnvName:     env.EnvName,
+			Production:  env.Production,
+			ServiceName: serviceName,
+			Pods:        make([]*commonmodels.PodRestartCount, 0, len(pods)),
+			Time:        now.Unix(),
+		}
+		for _, pod := range pods {
+			record.Pods = append(record.Pods, &commonmodels.PodRestartCount{PodName: pod.Name, Restarts: kube.PodRestartCount(pod)})
+		}
+
+		// the first sample only records the restart counts as the baseline
+		prev, err := commonrepo.NewServiceRestartRecordColl().GetLatest(env.ProductName, env.EnvName, serviceName)
+		if err == nil && prev.Time >= env.CreateTime {
+			prevRestarts := make(map[string]int32)
+			for _, pod := range prev.Pods {
+				prevRestarts[pod.PodName] = pod.Restarts
+			}
+			record.Restarts = kube.RestartIncrease(pods, prevRestarts)
+			for _, pod := range pods {
+				record.OOMKills += kube.PodOOMKills(pod, time.Unix(prev.Time, 0))
+			}
+		}
+
+		if err := commonrepo.NewServiceRestartRecordColl().Create(record); err != nil {
+			log.Errorf("[ServiceRestart] failed to save restart record of service %s in env %s/%s, err: %s", serviceName, env.ProductName, env.EnvName, err)
+		}
+	}
+}
+
+// GetServiceRestartTrends returns the restart samples of the services in the env within the last hours,
+// all the services are returned if serviceName is empty
+func GetServiceRestartTrends(projectName, envName, serviceName string, production bool, hours int) ([]*ServiceRestartTrend, error) {
+	env, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: envName, Production: util.GetBoolPointer(production)})
+	if err != nil {
+		return nil, e.ErrGetEnv.AddErr(err)
+	}
+	if hours <= 0 {
+		hours = 24
+	}
+
+	// the records left by a deleted env with the same name are not returned
+	startTime := time.Now().Add(-time.Duration(hours) * time.Hour).Unix()
+	if startTime < env.CreateTime {
+		startTime = env.CreateTime
+	}
+	records, err := commonrepo.NewServiceRestartRecordColl().List(&commonrepo.ServiceRestartRecordListOption{
+		ProductName: projectName,
+		EnvName:     envName,
+		ServiceName: serviceName,
+		StartTime:   startTime,
+	})
+	if err != nil {
+		return nil, e.ErrInternalError.AddErr(err)
+	}
+
+	trendMap := make(map[string]*ServiceRestartTrend)
+	for _, record := range records {
+		trend, ok := trendMap[record.ServiceName]
+		if !ok {
+			trend = &ServiceRestartTrend{ServiceName: record.ServiceName, Points: make([]*commonmodels.ServiceRestartRecord, 0)}
+			trendMap[record.ServiceName] = trend
+		}
+		trend.Restarts += record.Restarts
+		trend.OOMKills += record.OOMKills
+		trend.Points = append(trend.Points, record)
+	}
+
+	resp := make([]*ServiceRestartTrend, 0, len(trendMap))
+	for _, trend := range trendMap {
+		resp = append(resp, trend)
+	}
+	sort.Slice(resp, func(i, j int) bool {
+		return resp[i].ServiceName < resp[j].ServiceName
+	})
+	return resp, nil
+}
@@ -58,6 +58,7 @@ type SvcRevision struct {
 	UpdateServiceTmpl bool                            `json:"update_service_tmpl"`
 	VariableYaml      string                          `json:"variable_yaml"`
 	VariableKVs       []*commontypes.RenderVariableKV `json:"variable_kvs"`
+	ConfirmProtected  bool                            `json:"confirm_protected"`
 }
 
 type ProductIngressInfo struct {
@@ -1,13 +1,16 @@
 package handler
 
 import (
+	"fmt"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/log/service/ai"
 	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
 	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/types"
 )
 
 func AIAnalyzeBuildLog(c *gin.Context) {
@@ -30,3 +33,59 @@ func AIAnalyzeBuildLog(c *gin.Context) {
 	args.Log = string(data)
 	ctx.Resp, ctx.Err = ai.AnalyzeBuildLog(args, c.Query("projectName"), c.Param("workflowName"), c.Param("jobName"), taskID, ctx.Logger)
 }
+
+// AISummarizeJobFailure explains the root cause of a failed job, set refresh to regenerate the cached summary.
+// It is a POST since a summary which is not cached yet costs a call to the llm.
+func AISummarizeJobFailure(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	taskID, err := strconv.ParseInt(c.Param("taskID"), 10, 64)
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid task id")
+		return
+	}
+
+	workflowName := c.Param("workflowName")
+	w, err := commonrepo.NewWorkflowV4Coll().Find(workflowName)
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc(fmt.Sprintf("workflow %s not found", workflowName))
+		return
+	}
+
+	// authorization check
+	if !ctx.Resources.IsSystemAdmin {
+		if _, ok := ctx.Resources.ProjectAuthInfo[w.Project]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+
+		if !ctx.Resources.ProjectAuthInfo[w.Project].IsProjectAdmin &&
+			!ctx.Resources.ProjectAuthInfo[w.Project].Workflow.View {
+			// check if the permission is given by collaboration mode
+			permitted, err := internalhandler.GetCollaborationModePermission(ctx.UserID, w.Project, types.ResourceTypeWorkflow, workflowName, types.WorkflowActionView)
+			if err != nil || !permitted {
+				ctx.UnAuthorized = true
+				return
+			}
+		}
+	}
+
+	// regenerating the summary costs a call to the llm, it requires the edit permission of the workflow
+	refresh, _ := strconv.ParseBool(c.Query("refresh"))
+	if refresh && !ctx.Resources.IsSystemAdmin && !ctx.Resources.ProjectAuthInfo[w.Project].IsProjectAdmin &&
+		!ctx.Resources.ProjectAuthInfo[w.Project].Workflow.Edit {
+		permitted, err := internalhandler.GetCollaborationModePermission(ctx.UserID, w.Project, types.ResourceTypeWorkflow, workflowName, types.WorkflowActionEdit)
+		if err != nil || !permitted {
+			ctx.UnAuthorized = true
+			return
+		}
+	}
+	ctx.Resp, ctx.Err = ai.SummarizeJobFailure(workflowName, c.Param("jobName"), taskID, refresh, ctx.UserName, ctx.Logger)
+}
@@ -36,7 +36,12 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		log.GET("/v3/workflow/:workflowName/tasks/:taskId", GetWorkflowBuildV3JobContainerLogs)
 		log.GET("/scanning/:id/task/:scan_id", GetScanningContainerLogs)
 		log.GET("/v4/workflow/:workflowName/tasks/:taskID/jobs/:jobName", GetWorkflowV4JobContainerLogs)
+		log.GET("/v4/workflow/:workflowName/tasks/:taskID/jobs/:jobName/sections", ListWorkflowV4JobLogSections)
+		log.GET("/v4/workflow/:workflowName/tasks/:taskID/jobs/:jobName/sections/:section", GetWorkflowV4JobLogSection)
+		log.GET("/v4/workflow/:workflowName/tasks/:taskID/search", SearchWorkflowV4TaskLogs)
+		log.GET("/v4/workflow/:workflowName/tasks/:taskID/download", DownloadWorkflowV4TaskLogs)
 		log.POST("/ai/workflow/:workflowName/tasks/:taskID/jobs/:jobName", AIAnalyzeBuildLog)
+		log.POST("/ai/workflow/:workflowName/tasks/:taskID/jobs/:jobName/summary", AISummarizeJobFailure)
 	}
 
 	sse := router.Group("sse")
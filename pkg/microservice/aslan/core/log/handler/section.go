@@ -0,0 +1,91 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	logservice "github.com/koderover/zadig/pkg/microservice/aslan/core/log/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func ListWorkflowV4JobLogSections(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	taskID, err := strconv.ParseInt(c.Param("taskID"), 10, 64)
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid task id")
+		return
+	}
+	ctx.Resp, ctx.Err = logservice.ListWorkflowV4JobLogSections(strings.ToLower(c.Param("workflowName")), c.Param("jobName"), taskID, ctx.Logger)
+}
+
+func GetWorkflowV4JobLogSection(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	taskID, err := strconv.ParseInt(c.Param("taskID"), 10, 64)
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid task id")
+		return
+	}
+	ctx.Resp, ctx.Err = logservice.GetWorkflowV4JobLogSection(strings.ToLower(c.Param("workflowName")), c.Param("jobName"), c.Param("section"), taskID, ctx.Logger)
+}
+
+func SearchWorkflowV4TaskLogs(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	taskID, err := strconv.ParseInt(c.Param("taskID"), 10, 64)
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid task id")
+		return
+	}
+	ctx.Resp, ctx.Err = logservice.SearchWorkflowV4TaskLogs(strings.ToLower(c.Param("workflowName")), c.Query("keyword"), taskID, ctx.Logger)
+}
+
+func DownloadWorkflowV4TaskLogs(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+
+	taskID, err := strconv.ParseInt(c.Param("taskID"), 10, 64)
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid task id")
+		internalhandler.JSONResponse(c, ctx)
+		return
+	}
+	workflowName := strings.ToLower(c.Param("workflowName"))
+	logs, err := logservice.ListWorkflowV4TaskLogs(workflowName, taskID, ctx.Logger)
+	if err != nil {
+		ctx.Err = err
+		internalhandler.JSONResponse(c, ctx)
+		return
+	}
+	c.Writer.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%d-logs.zip"`, workflowName, taskID))
+	c.Writer.Header().Set("Content-Type", "application/zip")
+	c.Status(http.StatusOK)
+	// the response has been written, the error can only be logged
+	if err := logs.WriteZip(c.Writer); err != nil {
+		ctx.Logger.Errorf("failed to download logs of workflow %s task %d, err: %s", workflowName, taskID, err)
+	}
+}
@@ -0,0 +1,117 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	logservice "github.com/koderover/zadig/pkg/microservice/aslan/core/log/service"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/util"
+)
+
+// summaryLogLines is kept small so that self-hosted models with a short context window can handle the prompt
+const summaryLogLines = 200
+
+// SummarizeJobFailure explains the root cause of a failed job with the configured llm, the result is
+// cached per task and only regenerated when refresh is set.
+func SummarizeJobFailure(workflowName, jobName string, taskID int64, refresh bool, userName string, logger *zap.SugaredLogger) (*commonmodels.JobLogSummary, error) {
+	if !refresh {
+		if summary, err := commonrepo.NewJobLogSummaryColl().Find(workflowName, jobName, taskID); err == nil {
+			return summary, nil
+		}
+	}
+
+	task, err := commonrepo.NewWorkflowTaskV4Store().Find(workflowName, taskID)
+	if err != nil {
+		logger.Errorf("failed to find workflow task %s #%d, the error is: %v", workflowName, taskID, err)
+		return nil, e.ErrGetTask.AddErr(err)
+	}
+	var jobTask *commonmodels.JobTask
+	for _, stage := range task.Stages {
+		for _, job := range stage.Jobs {
+			if job.Name == jobName {
+				jobTask = job
+			}
+		}
+	}
+	if jobTask == nil {
+		return nil, e.ErrNotFound.AddDesc(fmt.Sprintf("job %s not found in task %s #%d", jobName, workflowName, taskID))
+	}
+	if jobTask.Status != config.StatusFailed && jobTask.Status != config.StatusTimeout {
+		return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("job %s is %s, only failed jobs can be summarized", jobName, jobTask.Status))
+	}
+
+	if !summaryLimiter.allow(userName) {
+		return nil, e.ErrTooManyRequests.AddDesc(fmt.Sprintf("at most %d summaries can be generated in %s", summaryRateLimit, summaryRateWindow))
+	}
+
+	ctx := context.Background()
+	client, err := service.GetConfiguredLLMClient(ctx)
+	if err != nil {
+		logger.Errorf("failed to get llm client, the error is: %+v", err)
+		return nil, e.ErrInvalidParam.AddErr(err)
+	}
+
+	// some jobs, like the deploy jobs, have no container log, the summary is then based on the job error only
+	jobLog, err := logservice.GetWorkflowV4JobContainerLogs(strings.ToLower(workflowName), jobName, taskID, logger)
+	if err != nil {
+		logger.Warnf("failed to get the log of job %s, the error is: %v", jobName, err)
+	}
+
+	answer, err := client.GetCompletion(ctx, buildJobFailureSummaryPrompt(task, jobTask, jobLog))
+	if err != nil {
+		logger.Errorf("failed to get answer from ai: %v, the error is: %+v", client.GetName(), err)
+		return nil, e.ErrInternalError.AddErr(err)
+	}
+
+	summary := &commonmodels.JobLogSummary{
+		WorkflowName:      workflowName,
+		TaskID:            taskID,
+		JobName:           jobName,
+		FailureCategories: jobTask.FailureCategories,
+		Summary:           answer,
+		Provider:          client.GetName(),
+		CreatedBy:         userName,
+		CreateTime:        time.Now().Unix(),
+	}
+	if err := commonrepo.NewJobLogSummaryColl().Upsert(summary); err != nil {
+		logger.Errorf("failed to cache the log summary of job %s, the error is: %v", jobName, err)
+	}
+	return summary, nil
+}
+
+// buildJobFailureSummaryPrompt builds the prompt with the credentials in the error and the log masked
+func buildJobFailureSummaryPrompt(task *commonmodels.WorkflowTask, job *commonmodels.JobTask, jobLog string) string {
+	info := fmt.Sprintf("任务名称: %s; 任务类型: %s; 任务状态: %s", job.Name, job.JobType, job.Status)
+	if job.Error != "" {
+		info += fmt.Sprintf("; 错误信息: %s", maskJobCredentials(task, job, job.Error))
+	}
+	if len(job.FailureCategories) > 0 {
+		info += fmt.Sprintf("; 失败分类: %s", strings.Join(job.FailureCategories, ","))
+	}
+	return fmt.Sprintf("%s; %s; 日志数据: \"\"\"%s\"\"\"", JobFailureSummaryPrompt, info, util.RemoveExtraSpaces(maskJobCredentials(task, job, splitBuildLogByRowNum(jobLog, summaryLogLines))))
+}
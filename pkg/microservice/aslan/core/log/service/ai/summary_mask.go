@@ -0,0 +1,154 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ai
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+const (
+	// summaryRateLimit is the number of the summaries a user can generate in summaryRateWindow, the cached
+	// summaries are not counted
+	summaryRateLimit  = 5
+	summaryRateWindow = time.Minute
+)
+
+// credentialPatterns match the credentials commonly printed by the scripts and the tools, the whole match or the
+// named group value is masked
+var credentialPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+	regexp.MustCompile(`(?i)(?:password|passwd|pwd|secret|token|api[_-]?key|access[_-]?key|secret[_-]?key|credential)s?["']?\s*[:=]\s*["']?(?P<value>[^\s"',;]+)`),
+	regexp.MustCompile(`(?i)(?:authorization|proxy-authorization)\s*:\s*(?P<value>.+)`),
+	regexp.MustCompile(`(?i)\b(?:bearer|basic)\s+(?P<value>[A-Za-z0-9._~+/=-]{8,})`),
+	regexp.MustCompile(`://[^/\s:@]+:(?P<value>[^/\s@]+)@`),
+	regexp.MustCompile(`\b(?P<value>AKIA[0-9A-Z]{16})\b`),
+	regexp.MustCompile(`\b(?P<value>gh[pousr]_[A-Za-z0-9]{36,})\b`),
+	regexp.MustCompile(`\b(?P<value>glpat-[A-Za-z0-9_-]{20,})\b`),
+}
+
+// maskJobCredentials masks the values of the credential params and envs of the job and the task, and the
+// credentials matching the common patterns, before the log is sent to the llm
+func maskJobCredentials(task *commonmodels.WorkflowTask, job *commonmodels.JobTask, content string) string {
+	values := make([]string, 0)
+	for _, param := range task.Params {
+		if param.IsCredential && param.Value != "" {
+			values = append(values, param.Value)
+		}
+	}
+	values = append(values, credentialValues(job.Spec)...)
+	// the longer values first so that a value containing another one is masked entirely
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	for _, value := range values {
+		content = strings.ReplaceAll(content, value, setting.MaskValue)
+	}
+
+	for _, pattern := range credentialPatterns {
+		content = maskPattern(pattern, content)
+	}
+	return content
+}
+
+// maskPattern masks the value group of the matches of the pattern, or the whole matches if it has no value group
+func maskPattern(pattern *regexp.Regexp, content string) string {
+	group := pattern.SubexpIndex("value")
+	var b strings.Builder
+	last := 0
+	for _, loc := range pattern.FindAllStringSubmatchIndex(content, -1) {
+		start, end := loc[0], loc[1]
+		if group >= 0 {
+			start, end = loc[2*group], loc[2*group+1]
+		}
+		if start < 0 {
+			continue
+		}
+		b.WriteString(content[last:start])
+		b.WriteString(setting.MaskValue)
+		last = end
+	}
+	b.WriteString(content[last:])
+	return b.String()
+}
+
+// credentialValues collects the values of the items marked with is_credential in the job spec, e.g. the envs of
+// the freestyle jobs and the builds
+func credentialValues(spec interface{}) []string {
+	b, err := yaml.Marshal(spec)
+	if err != nil {
+		return nil
+	}
+	var doc interface{}
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil
+	}
+	values := make([]string, 0)
+	var walk func(node interface{})
+	walk = func(node interface{}) {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			if credential, _ := n["is_credential"].(bool); credential {
+				if value, _ := n["value"].(string); value != "" {
+					values = append(values, value)
+				}
+			}
+			for _, child := range n {
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range n {
+				walk(child)
+			}
+		}
+	}
+	walk(doc)
+	return values
+}
+
+// summaryRateLimiter limits the summaries generated by each user in the window
+type summaryRateLimiter struct {
+	sync.Mutex
+	requests map[string][]time.Time
+}
+
+var summaryLimiter = &summaryRateLimiter{requests: make(map[string][]time.Time)}
+
+func (l *summaryRateLimiter) allow(user string) bool {
+	l.Lock()
+	defer l.Unlock()
+
+	now := time.Now()
+	recent := make([]time.Time, 0, summaryRateLimit)
+	for _, t := range l.requests[user] {
+		if now.Sub(t) < summaryRateWindow {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= summaryRateLimit {
+		l.requests[user] = recent
+		return false
+	}
+	l.requests[user] = append(recent, now)
+	return true
+}
@@ -4,3 +4,8 @@ package ai
 const BuildLogAnalysisPrompt = `你是一个资深devops开发专家，我会提供一份用三重引号分割的构建过程中产生的日志数据，你需要按照要求生成对该日志的分析报告，在分析报告中，你需要根据输入的日志数据来分析此次构建的整体效率，
 并重点分析日志中出现的异常问题，异常问题需要提供出现异常的位置，异常的原因，并提供高质量的异常解决方案；你的回答需要符合text格式，同时你的回答中不要复述我的问题，直接回答你的分析报告即可。
 `
+
+// JobFailureSummaryPrompt asks for a short root cause explanation of a failed job
+const JobFailureSummaryPrompt = `你是一个资深devops开发专家，我会提供一个执行失败的工作流任务的信息以及用三重引号分割的该任务最后的日志数据，你需要总结出任务失败的根本原因，
+回答包括三部分：失败原因（一句话概括）、关键日志（引用最能说明问题的日志行）、修复建议（不超过三条）；你的回答需要符合text格式并尽量简洁，同时你的回答中不要复述我的问题。
+`
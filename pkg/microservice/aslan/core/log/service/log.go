@@ -56,6 +56,9 @@ func GetWorkflowBuildJobContainerLogs(pipelineName, serviceName, buildType strin
 }
 
 func GetWorkflowV4JobContainerLogs(workflowName, jobName string, taskID int64, log *zap.SugaredLogger) (string, error) {
+	if task, err := commonrepo.NewWorkflowTaskV4Store().Find(workflowName, taskID); err == nil && task.LogExpired {
+		return "", fmt.Errorf("the logs of task %s #%d are removed by the log retention of the project", workflowName, taskID)
+	}
 	buildJobNamePrefix := jobName
 	buildLog, err := getContainerLogFromS3(workflowName, buildJobNamePrefix, taskID, log)
 	if err != nil {
@@ -87,24 +90,8 @@ func getContainerLogFromS3(pipelineName, filenamePrefix string, taskID int64, lo
 		_ = os.Remove(tempFile)
 	}()
 
-	storage, err := s3service.FindDefaultS3()
+	storage, client, err := getTaskLogStorage(pipelineName, taskID, log)
 	if err != nil {
-		log.Errorf("GetContainerLogFromS3 FindDefaultS3 err:%v", err)
-		return "", err
-	}
-
-	if storage.Subfolder != "" {
-		storage.Subfolder = fmt.Sprintf("%s/%s/%d/%s", storage.Subfolder, pipelineName, taskID, "log")
-	} else {
-		storage.Subfolder = fmt.Sprintf("%s/%d/%s", pipelineName, taskID, "log")
-	}
-	forcedPathStyle := true
-	if storage.Provider == setting.ProviderSourceAli {
-		forcedPathStyle = false
-	}
-	client, err := s3tool.NewClient(storage.Endpoint, storage.Ak, storage.Sk, storage.Region, storage.Insecure, forcedPathStyle)
-	if err != nil {
-		log.Errorf("Failed to create s3 client, the error is: %+v", err)
 		return "", err
 	}
 	fullPath := storage.GetObjectPath(fileName)
@@ -125,6 +112,31 @@ func getContainerLogFromS3(pipelineName, filenamePrefix string, taskID int64, lo
 	return string(containerLog), nil
 }
 
+// getTaskLogStorage returns the storage whose subfolder is the log directory of the task
+func getTaskLogStorage(pipelineName string, taskID int64, log *zap.SugaredLogger) (*s3service.S3, *s3tool.Client, error) {
+	storage, err := s3service.FindDefaultS3()
+	if err != nil {
+		log.Errorf("GetContainerLogFromS3 FindDefaultS3 err:%v", err)
+		return nil, nil, err
+	}
+
+	if storage.Subfolder != "" {
+		storage.Subfolder = fmt.Sprintf("%s/%s/%d/%s", storage.Subfolder, pipelineName, taskID, "log")
+	} else {
+		storage.Subfolder = fmt.Sprintf("%s/%d/%s", pipelineName, taskID, "log")
+	}
+	forcedPathStyle := true
+	if storage.Provider == setting.ProviderSourceAli {
+		forcedPathStyle = false
+	}
+	client, err := s3tool.NewClient(storage.Endpoint, storage.Ak, storage.Sk, storage.Region, storage.Insecure, forcedPathStyle)
+	if err != nil {
+		log.Errorf("Failed to create s3 client, the error is: %+v", err)
+		return nil, nil, err
+	}
+	return storage, client, nil
+}
+
 func GetCurrentContainerLogs(podName, containerName, envName, productName string, tailLines int64, log *zap.SugaredLogger) (string, error) {
 	env, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: productName, EnvName: envName})
 	if err != nil {
@@ -0,0 +1,195 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"archive/zip"
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"go.uber.org/zap"
+
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	s3service "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/s3"
+	"github.com/koderover/zadig/pkg/tool/errors"
+	s3tool "github.com/koderover/zadig/pkg/tool/s3"
+	"github.com/koderover/zadig/pkg/types/job"
+)
+
+// maxLogSearchResults limits the matched lines returned by a search
+const maxLogSearchResults = 1000
+
+type LogSearchResult struct {
+	JobName string `json:"job_name"`
+	Section string `json:"section"`
+	Line    int    `json:"line"`
+	Content string `json:"content"`
+}
+
+func ListWorkflowV4JobLogSections(workflowName, jobName string, taskID int64, log *zap.SugaredLogger) ([]*job.LogSection, error) {
+	content, err := GetWorkflowV4JobContainerLogs(workflowName, jobName, taskID, log)
+	if err != nil {
+		return nil, err
+	}
+	return job.ParseLogSections(strings.Split(content, "\n")), nil
+}
+
+func GetWorkflowV4JobLogSection(workflowName, jobName, section string, taskID int64, log *zap.SugaredLogger) (string, error) {
+	content, err := GetWorkflowV4JobContainerLogs(workflowName, jobName, taskID, log)
+	if err != nil {
+		return "", err
+	}
+	lines := strings.Split(content, "\n")
+	for _, s := range job.ParseLogSections(lines) {
+		if s.Name == section {
+			// the markers are not included
+			start, end := s.StartLine, s.EndLine
+			if end > start && strings.HasPrefix(strings.TrimSpace(lines[end-1]), job.LogSectionEndMarker) {
+				end--
+			}
+			return strings.Join(lines[start:end], "\n"), nil
+		}
+	}
+	return "", errors.ErrNotFound.AddDesc(fmt.Sprintf("section %s not found in the log of job %s", section, jobName))
+}
+
+// SearchWorkflowV4TaskLogs searches the keyword case-insensitively in the logs of all the finished jobs of the task,
+// the logs are read line by line from the storage
+func SearchWorkflowV4TaskLogs(workflowName, keyword string, taskID int64, log *zap.SugaredLogger) ([]*LogSearchResult, error) {
+	resp := make([]*LogSearchResult, 0)
+	if keyword == "" {
+		return resp, nil
+	}
+	keyword = strings.ToLower(keyword)
+
+	logs, err := ListWorkflowV4TaskLogs(workflowName, taskID, log)
+	if err != nil {
+		return nil, err
+	}
+	for _, jobName := range logs.JobNames {
+		body, err := logs.open(jobName)
+		if err != nil {
+			return nil, err
+		}
+		resp, err = searchLog(body, jobName, keyword, resp)
+		_ = body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the log of job %s: %s", jobName, err)
+		}
+		if len(resp) >= maxLogSearchResults {
+			return resp, nil
+		}
+	}
+	return resp, nil
+}
+
+func searchLog(r io.Reader, jobName, keyword string, resp []*LogSearchResult) ([]*LogSearchResult, error) {
+	reader := bufio.NewReader(r)
+	tracker := &job.LogSectionTracker{}
+	for lineNo := 1; ; lineNo++ {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return resp, err
+		}
+		if line == "" && err == io.EOF {
+			return resp, nil
+		}
+		line = strings.TrimSuffix(line, "\n")
+		section := tracker.Next(line)
+		if strings.Contains(strings.ToLower(line), keyword) {
+			resp = append(resp, &LogSearchResult{
+				JobName: jobName,
+				Section: section,
+				Line:    lineNo,
+				Content: line,
+			})
+			if len(resp) >= maxLogSearchResults {
+				return resp, nil
+			}
+		}
+		if err == io.EOF {
+			return resp, nil
+		}
+	}
+}
+
+// TaskLogs are the saved logs of the finished jobs of a task, the log files are streamed from the storage
+type TaskLogs struct {
+	JobNames []string
+
+	storage *s3service.S3
+	client  *s3tool.Client
+}
+
+// ListWorkflowV4TaskLogs lists the jobs whose logs have been saved
+func ListWorkflowV4TaskLogs(workflowName string, taskID int64, log *zap.SugaredLogger) (*TaskLogs, error) {
+	if task, err := commonrepo.NewWorkflowTaskV4Store().Find(workflowName, taskID); err == nil && task.LogExpired {
+		return nil, fmt.Errorf("the logs of task %s #%d are removed by the log retention of the project", workflowName, taskID)
+	}
+	storage, client, err := getTaskLogStorage(workflowName, taskID, log)
+	if err != nil {
+		return nil, err
+	}
+	files, err := client.ListFiles(storage.Bucket, storage.GetObjectPath("")+"/", false)
+	if err != nil {
+		return nil, err
+	}
+	resp := &TaskLogs{JobNames: make([]string, 0, len(files)), storage: storage, client: client}
+	for _, file := range files {
+		name := path.Base(file)
+		if strings.HasSuffix(name, ".log") {
+			resp.JobNames = append(resp.JobNames, strings.TrimSuffix(name, ".log"))
+		}
+	}
+	return resp, nil
+}
+
+func (t *TaskLogs) open(jobName string) (io.ReadCloser, error) {
+	obj, err := t.client.GetFile(t.storage.Bucket, t.storage.GetObjectPath(jobName+".log"), &s3tool.DownloadOption{RetryNum: 3})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the log of job %s: %s", jobName, err)
+	}
+	return obj.Body, nil
+}
+
+// WriteZip copies the logs to w as a zip file
+func (t *TaskLogs) WriteZip(w io.Writer) error {
+	zipWriter := zip.NewWriter(w)
+	for _, jobName := range t.JobNames {
+		if err := t.writeZipFile(zipWriter, jobName); err != nil {
+			return err
+		}
+	}
+	return zipWriter.Close()
+}
+
+func (t *TaskLogs) writeZipFile(zipWriter *zip.Writer, jobName string) error {
+	body, err := t.open(jobName)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	file, err := zipWriter.Create(jobName + ".log")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(file, body)
+	return err
+}
@@ -255,7 +255,7 @@ func WorkflowTaskV4ContainerLogStream(ctx context.Context, streamChan chan inter
 		return
 	}
 	log.Debugf("Start to get task container log.")
-	task, err := commonrepo.NewworkflowTaskv4Coll().Find(options.PipelineName, options.TaskID)
+	task, err := commonrepo.NewWorkflowTaskV4Store().Find(options.PipelineName, options.TaskID)
 	if err != nil {
 		log.Errorf("Failed to find workflow %s taskID %s: %v", options.PipelineName, options.TaskID, err)
 		return
@@ -276,6 +276,10 @@ func WorkflowTaskV4ContainerLogStream(ctx context.Context, streamChan chan inter
 				fallthrough
 			case string(config.JobZadigScanning):
 				fallthrough
+			case string(config.JobZadigSonar):
+				fallthrough
+			case string(config.JobZadigPerformanceTest):
+				fallthrough
 			case string(config.JobZadigDistributeImage):
 				fallthrough
 			case string(config.JobBuild):
@@ -284,6 +288,10 @@ func WorkflowTaskV4ContainerLogStream(ctx context.Context, streamChan chan inter
 					log.Errorf("Failed to parse job spec: %v", err)
 					return
 				}
+				if jobSpec.Properties.Infrastructure == setting.JobVMInfrastructure {
+					vmJobLogStream(ctx, streamChan, options.PipelineName, options.TaskID, job.Name, log)
+					return
+				}
 				options.ClusterID = jobSpec.Properties.ClusterID
 			case string(config.JobPlugin):
 				jobSpec := &commonmodels.JobTaskPluginSpec{}
@@ -0,0 +1,68 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+)
+
+// vmJobLogStream streams the log chunks sent by the vm agent until the job ends,
+// the chunks are removed after the job ends and the log is read from the object storage then
+func vmJobLogStream(ctx context.Context, streamChan chan interface{}, workflowName string, taskID int64, jobName string, log *zap.SugaredLogger) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var seq int64
+	for {
+		select {
+		case <-ctx.Done():
+			log.Infof("Connection is closed, vm job log stream stopped")
+			return
+		case <-ticker.C:
+		}
+
+		vmJob, err := commonrepo.NewVMJobColl().FindLatest(workflowName, taskID, jobName)
+		if err != nil {
+			// the job may not be queued yet
+			continue
+		}
+		chunks, err := commonrepo.NewVMJobLogColl().List(vmJob.ID.Hex(), seq)
+		if err != nil {
+			log.Errorf("failed to list the log of vm job %s: %s", vmJob.ID.Hex(), err)
+			return
+		}
+		for _, chunk := range chunks {
+			for _, line := range strings.SplitAfter(chunk.Content, "\n") {
+				if line = strings.TrimRight(line, "\n"); line != "" {
+					streamChan <- line
+				}
+			}
+			seq = chunk.Seq
+		}
+		if len(chunks) == 0 && vmJob.Status != config.StatusQueued && vmJob.Status != config.StatusRunning {
+			log.Infof("vm job %s ended, vm job log stream stopped", vmJob.ID.Hex())
+			return
+		}
+	}
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/multicluster/service"
 	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
 	e "github.com/koderover/zadig/pkg/tool/errors"
@@ -120,6 +121,13 @@ func DeleteCluster(c *gin.Context) {
 	ctx := internalhandler.NewContext(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
 
+	if c.Query("force") != "true" {
+		if err := commonservice.CheckAssetReferences(commonservice.AssetTypeCluster, "", c.Param("id")); err != nil {
+			ctx.Err = e.ErrAssetReferenced.AddErr(err)
+			return
+		}
+	}
+
 	ctx.Err = service.DeleteCluster(ctx.UserName, c.Param("id"), ctx.Logger)
 }
 
@@ -191,6 +199,39 @@ func UpgradeAgent(c *gin.Context) {
 	ctx.Err = service.UpgradeAgent(c.Param("id"), ctx.Logger)
 }
 
+func GetAgentRollout(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = service.GetAgentRollout(ctx.Logger)
+}
+
+func StartAgentRollout(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	// authorization check
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	args := new(service.AgentRolloutArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "升级", "集群管理-agent", strings.Join(args.ClusterIDs, ","), "", ctx.Logger)
+
+	ctx.Resp, ctx.Err = service.StartAgentRollout(args, ctx.UserName, ctx.Logger)
+}
+
 func CheckEphemeralContainers(c *gin.Context) {
 	ctx := internalhandler.NewContext(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
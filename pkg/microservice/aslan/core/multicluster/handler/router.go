@@ -28,6 +28,8 @@ func (*Router) Inject(router *gin.RouterGroup) {
 	{
 		Agent.GET("/:id/agent.yaml", GetClusterYaml("/api/hub"))
 		Agent.GET("/:id/upgrade", UpgradeAgent)
+		Agent.GET("/rollout", GetAgentRollout)
+		Agent.POST("/rollout", StartAgentRollout)
 	}
 
 	Cluster := router.Group("clusters")
@@ -41,6 +43,15 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		Cluster.GET("/:id/strategy/references", GetClusterStrategyReferences)
 		Cluster.PUT("/:id/disconnect", DisconnectCluster)
 		Cluster.PUT("/:id/reconnect", ReconnectCluster)
+
+		Cluster.GET("/:id/health", GetClusterHealth)
+		Cluster.POST("/:id/health/probe", ProbeClusterHealth)
+		Cluster.GET("/:id/health/schedulable", CheckClusterSchedulable)
+	}
+
+	health := router.Group("health")
+	{
+		health.GET("", ListClusterHealth)
 	}
 
 	bundles := router.Group("bundle-resources")
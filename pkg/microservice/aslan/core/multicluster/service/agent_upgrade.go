@@ -0,0 +1,328 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/kube/multicluster"
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/util"
+	"github.com/koderover/zadig/pkg/util/ginzap"
+)
+
+const (
+	defaultAgentHealthTimeout = 5 * time.Minute
+	agentHealthPollInterval   = 5 * time.Second
+	// agentRolloutStaleMargin is added to the health timeout, a running rollout not refreshed for longer is left
+	// by a stopped replica and is failed
+	agentRolloutStaleMargin = 10 * time.Minute
+)
+
+const (
+	AgentRolloutStatusRunning   = "running"
+	AgentRolloutStatusSucceeded = "succeeded"
+	AgentRolloutStatusFailed    = "failed"
+
+	AgentUpgradeStatusPending   = "pending"
+	AgentUpgradeStatusUpgrading = "upgrading"
+	AgentUpgradeStatusSucceeded = "succeeded"
+	AgentUpgradeStatusFailed    = "failed"
+	AgentUpgradeStatusSkipped   = "skipped"
+)
+
+type AgentRolloutArgs struct {
+	// ClusterIDs are upgraded in order, all the clusters with version skew are upgraded if it is empty
+	ClusterIDs []string `json:"cluster_ids"`
+	// HealthTimeout is the seconds to wait for an upgraded agent to reconnect with the expected version
+	HealthTimeout int64 `json:"health_timeout"`
+}
+
+// ExpectedAgentVersion is the tag of the hub-agent image shipped with the running zadig
+func ExpectedAgentVersion() string {
+	return util.ExtractImageTag(config.HubAgentImage())
+}
+
+// isAgentCluster returns true if the cluster is connected by a hub-agent, the local cluster and the
+// kubeconfig clusters have no agent
+func isAgentCluster(cluster *commonmodels.K8SCluster) bool {
+	return !cluster.Local && cluster.ID.Hex() != setting.LocalClusterID && cluster.Type != setting.KubeConfigClusterType
+}
+
+// GetAgentVersionSkew returns a warning if the agent of the cluster does not run the expected version,
+// agents before the version reporting are reported with an unknown version.
+func GetAgentVersionSkew(cluster *commonmodels.K8SCluster) string {
+	if !isAgentCluster(cluster) {
+		return ""
+	}
+	expected := ExpectedAgentVersion()
+	if expected == "" {
+		return ""
+	}
+	if cluster.AgentVersion == "" {
+		return fmt.Sprintf("agent version is unknown, expected %s", expected)
+	}
+	if cluster.AgentVersion != expected {
+		return fmt.Sprintf("agent version %s differs from the expected %s", cluster.AgentVersion, expected)
+	}
+	return ""
+}
+
+// GetAgentRollout returns the progress of the latest rollout, it is nil if no rollout has been started
+func GetAgentRollout(logger *zap.SugaredLogger) (*commonmodels.AgentRollout, error) {
+	rollout, err := commonrepo.NewAgentRolloutColl().GetLatest()
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		logger.Errorf("failed to get the agent rollout, err: %s", err)
+		return nil, e.ErrUpgradeAgent.AddErr(err)
+	}
+	if rollout.Lock != "" {
+		if err := finishStaleAgentRollout(rollout); err != nil {
+			logger.Errorf("failed to finish the stale agent rollout, err: %s", err)
+		}
+	}
+	return rollout, nil
+}
+
+// StartAgentRollout starts the rolling upgrade in the background, only one rollout can run at a time
+func StartAgentRollout(args *AgentRolloutArgs, username string, logger *zap.SugaredLogger) (*commonmodels.AgentRollout, error) {
+	expected := ExpectedAgentVersion()
+	if expected == "" {
+		return nil, e.ErrUpgradeAgent.AddDesc("the version of the hub-agent image is unknown")
+	}
+
+	clusters, err := commonrepo.NewK8SClusterColl().List(&commonrepo.ClusterListOpts{IDs: args.ClusterIDs})
+	if err != nil {
+		logger.Errorf("failed to list clusters, err: %s", err)
+		return nil, e.ErrUpgradeAgent.AddErr(err)
+	}
+	clusterMap := make(map[string]*commonmodels.K8SCluster)
+	for _, cluster := range clusters {
+		clusterMap[cluster.ID.Hex()] = cluster
+	}
+
+	// keep the order given by the user
+	ordered := make([]*commonmodels.K8SCluster, 0, len(clusters))
+	if len(args.ClusterIDs) > 0 {
+		seen := sets.NewString()
+		for _, id := range args.ClusterIDs {
+			if seen.Has(id) {
+				continue
+			}
+			seen.Insert(id)
+			cluster, ok := clusterMap[id]
+			if !ok {
+				return nil, e.ErrUpgradeAgent.AddDesc(fmt.Sprintf("cluster %s not found", id))
+			}
+			ordered = append(ordered, cluster)
+		}
+	} else {
+		for _, cluster := range clusters {
+			if GetAgentVersionSkew(cluster) != "" {
+				ordered = append(ordered, cluster)
+			}
+		}
+	}
+	if len(ordered) == 0 {
+		return nil, e.ErrUpgradeAgent.AddDesc("no cluster needs to be upgraded")
+	}
+
+	healthTimeout := args.HealthTimeout
+	if healthTimeout <= 0 {
+		healthTimeout = int64(defaultAgentHealthTimeout / time.Second)
+	}
+	now := time.Now().Unix()
+	rollout := &commonmodels.AgentRollout{
+		Status:          AgentRolloutStatusRunning,
+		ExpectedVersion: expected,
+		CreatedBy:       username,
+		StartTime:       now,
+		HealthTimeout:   healthTimeout,
+		UpdateTime:      now,
+	}
+	for _, cluster := range ordered {
+		rollout.Clusters = append(rollout.Clusters, &commonmodels.AgentRolloutCluster{
+			ClusterID:   cluster.ID.Hex(),
+			ClusterName: cluster.Name,
+			FromVersion: cluster.AgentVersion,
+			Status:      AgentUpgradeStatusPending,
+		})
+	}
+
+	coll := commonrepo.NewAgentRolloutColl()
+	if running, err := coll.GetRunning(); err == nil {
+		if err := finishStaleAgentRollout(running); err != nil {
+			logger.Errorf("failed to finish the stale agent rollout, err: %s", err)
+		}
+	}
+	if err := coll.Create(rollout); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, e.ErrUpgradeAgent.AddDesc("another rollout is running")
+		}
+		logger.Errorf("failed to create the agent rollout, err: %s", err)
+		return nil, e.ErrUpgradeAgent.AddErr(err)
+	}
+
+	resp := *rollout
+	resp.Clusters = make([]*commonmodels.AgentRolloutCluster, 0, len(rollout.Clusters))
+	for _, item := range rollout.Clusters {
+		c := *item
+		resp.Clusters = append(resp.Clusters, &c)
+	}
+	go runAgentRollout(rollout, time.Duration(healthTimeout)*time.Second)
+
+	return &resp, nil
+}
+
+// finishStaleAgentRollout fails the running rollout if the replica running it has stopped
+func finishStaleAgentRollout(rollout *commonmodels.AgentRollout) error {
+	staleAfter := time.Duration(rollout.HealthTimeout)*time.Second + agentRolloutStaleMargin
+	if time.Since(time.Unix(rollout.UpdateTime, 0)) < staleAfter {
+		return nil
+	}
+	updateTime := rollout.UpdateTime
+	now := time.Now().Unix()
+	for _, item := range rollout.Clusters {
+		switch item.Status {
+		case AgentUpgradeStatusUpgrading:
+			item.Status = AgentUpgradeStatusFailed
+			item.Message = "the rollout was interrupted"
+			item.EndTime = now
+		case AgentUpgradeStatusPending:
+			item.Status = AgentUpgradeStatusSkipped
+			item.Message = "skipped since the rollout was interrupted"
+		}
+	}
+	rollout.Status = AgentRolloutStatusFailed
+	rollout.EndTime = now
+	rollout.UpdateTime = now
+	finished, err := commonrepo.NewAgentRolloutColl().FinishStale(rollout, updateTime)
+	if err != nil {
+		return err
+	}
+	if finished {
+		rollout.Lock = ""
+		log.Warnf("[AgentRollout] the rollout started at %d was interrupted", rollout.StartTime)
+	}
+	return nil
+}
+
+func runAgentRollout(rollout *commonmodels.AgentRollout, timeout time.Duration) {
+	logger := ginzap.WithContext(&gin.Context{}).Sugar()
+	status := AgentRolloutStatusSucceeded
+	for _, item := range rollout.Clusters {
+		if status == AgentRolloutStatusFailed {
+			updateAgentRolloutCluster(rollout, item, AgentUpgradeStatusSkipped, "skipped since a previous cluster failed")
+			continue
+		}
+
+		updateAgentRolloutCluster(rollout, item, AgentUpgradeStatusUpgrading, "")
+		err := UpgradeAgent(item.ClusterID, logger)
+		if err == nil {
+			err = waitForAgentHealthy(item.ClusterID, rollout.ExpectedVersion, timeout)
+		}
+		if err != nil {
+			log.Errorf("[AgentRollout] failed to upgrade the agent of cluster %s, err: %s", item.ClusterName, err)
+			updateAgentRolloutCluster(rollout, item, AgentUpgradeStatusFailed, err.Error())
+			status = AgentRolloutStatusFailed
+			continue
+		}
+		log.Infof("[AgentRollout] the agent of cluster %s is upgraded to %s", item.ClusterName, rollout.ExpectedVersion)
+		updateAgentRolloutCluster(rollout, item, AgentUpgradeStatusSucceeded, "")
+	}
+
+	rollout.Status = status
+	rollout.EndTime = time.Now().Unix()
+	rollout.Lock = ""
+	saveAgentRollout(rollout)
+}
+
+// waitForAgentHealthy waits until the agent reconnects to the hub server with the expected version,
+// clusters without agent are verified by the upgrade itself
+func waitForAgentHealthy(clusterID, expected string, timeout time.Duration) error {
+	cluster, err := commonrepo.NewK8SClusterColl().Get(clusterID)
+	if err != nil {
+		return err
+	}
+	if !isAgentCluster(cluster) {
+		return nil
+	}
+
+	hubClient, err := multicluster.NewHubClient(config.HubServerAddress())
+	if err != nil {
+		return fmt.Errorf("failed to create hub-server client: %s", err)
+	}
+
+	var lastErr error
+	err = wait.PollImmediate(agentHealthPollInterval, timeout, func() (bool, error) {
+		cluster, err := commonrepo.NewK8SClusterColl().Get(clusterID)
+		if err != nil {
+			lastErr = err
+			return false, nil
+		}
+		if cluster.Status != setting.Normal {
+			lastErr = fmt.Errorf("cluster status is %s", cluster.Status)
+			return false, nil
+		}
+		if cluster.AgentVersion != expected {
+			lastErr = fmt.Errorf("agent version is %q", cluster.AgentVersion)
+			return false, nil
+		}
+		if err := hubClient.HasSession(clusterID); err != nil {
+			lastErr = fmt.Errorf("agent is not connected: %s", err)
+			return false, nil
+		}
+		return true, nil
+	})
+	if err != nil && lastErr != nil {
+		return fmt.Errorf("agent is not healthy after %s: %s", timeout, lastErr)
+	}
+	return err
+}
+
+func updateAgentRolloutCluster(rollout *commonmodels.AgentRollout, item *commonmodels.AgentRolloutCluster, status, message string) {
+	item.Status = status
+	item.Message = message
+	switch status {
+	case AgentUpgradeStatusUpgrading:
+		item.StartTime = time.Now().Unix()
+	case AgentUpgradeStatusSucceeded, AgentUpgradeStatusFailed:
+		item.EndTime = time.Now().Unix()
+	}
+	saveAgentRollout(rollout)
+}
+
+func saveAgentRollout(rollout *commonmodels.AgentRollout) {
+	rollout.UpdateTime = time.Now().Unix()
+	if err := commonrepo.NewAgentRolloutColl().Update(rollout); err != nil {
+		log.Errorf("[AgentRollout] failed to save the progress of the rollout, err: %s", err)
+	}
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+const clusterProbeTimeout = 30 * time.Second
+
+// pressureConditions are the node conditions which mean the node is under resource pressure when true
+var pressureConditions = []corev1.NodeConditionType{
+	corev1.NodeMemoryPressure,
+	corev1.NodeDiskPressure,
+	corev1.NodePIDPressure,
+}
+
+type ClusterScheduleCheck struct {
+	ClusterID   string   `json:"cluster_id"`
+	Schedulable bool     `json:"schedulable"`
+	Warnings    []string `json:"warnings"`
+}
+
+// ProbeClusterHealth probes all the registered clusters and saves the results into mongodb
+func ProbeClusterHealth() {
+	clusters, err := commonrepo.NewK8SClusterColl().List(nil)
+	if err != nil {
+		log.Errorf("[ClusterHealth] failed to list clusters, err: %s", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, cluster := range clusters {
+		wg.Add(1)
+		go func(cluster *commonmodels.K8SCluster) {
+			defer wg.Done()
+			health := probeCluster(cluster)
+			if err := commonrepo.NewClusterHealthColl().Upsert(health); err != nil {
+				log.Errorf("[ClusterHealth] failed to save health of cluster %s, err: %s", cluster.Name, err)
+			}
+		}(cluster)
+	}
+	wg.Wait()
+}
+
+func ProbeOneClusterHealth(clusterID string) (*commonmodels.ClusterHealth, error) {
+	cluster, err := commonrepo.NewK8SClusterColl().Get(clusterID)
+	if err != nil {
+		return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("cluster %s not found", clusterID))
+	}
+	health := probeCluster(cluster)
+	if err := commonrepo.NewClusterHealthColl().Upsert(health); err != nil {
+		return nil, e.ErrInternalError.AddErr(err)
+	}
+	return health, nil
+}
+
+func ListClusterHealth() ([]*commonmodels.ClusterHealth, error) {
+	resp, err := commonrepo.NewClusterHealthColl().List()
+	if err != nil {
+		return nil, e.ErrInternalError.AddErr(err)
+	}
+	return resp, nil
+}
+
+func GetClusterHealth(clusterID string) (*commonmodels.ClusterHealth, error) {
+	resp, err := commonrepo.NewClusterHealthColl().Get(clusterID)
+	if err != nil {
+		return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("no health record found for cluster %s", clusterID))
+	}
+	return resp, nil
+}
+
+// CheckClusterSchedulable is used by the workflow scheduler to decide whether a job can be put onto the cluster.
+// A cluster without any probe result is considered schedulable.
+func CheckClusterSchedulable(clusterID string) *ClusterScheduleCheck {
+	resp := &ClusterScheduleCheck{
+		ClusterID:   clusterID,
+		Schedulable: true,
+		Warnings:    make([]string, 0),
+	}
+	health, err := commonrepo.NewClusterHealthColl().Get(clusterID)
+	if err != nil {
+		return resp
+	}
+	resp.Schedulable, resp.Warnings = health.Schedulable()
+	return resp
+}
+
+func probeCluster(cluster *commonmodels.K8SCluster) *commonmodels.ClusterHealth {
+	health := &commonmodels.ClusterHealth{
+		ClusterID:     cluster.ID.Hex(),
+		ClusterName:   cluster.Name,
+		PressureNodes: make([]*commonmodels.NodePressure, 0),
+		Capacity:      &commonmodels.ClusterCapacityInfo{},
+		ProbeTime:     time.Now().Unix(),
+	}
+	if cluster.Disconnected {
+		health.Error = "cluster is disconnected"
+		return health
+	}
+
+	clientset, err := kubeclient.GetKubeClientSet(config.HubServerAddress(), cluster.ID.Hex())
+	if err != nil {
+		health.Error = fmt.Sprintf("failed to get kube client: %s", err)
+		return health
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), clusterProbeTimeout)
+	defer cancel()
+
+	nodes, err := clientset.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		health.Error = fmt.Sprintf("failed to list nodes: %s", err)
+		return health
+	}
+	health.Reachable = true
+	health.TotalNodes = len(nodes.Items)
+
+	for _, node := range nodes.Items {
+		pressures := make([]string, 0)
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == corev1.NodeReady && condition.Status == corev1.ConditionTrue {
+				health.ReadyNodes++
+				continue
+			}
+			for _, pressure := range pressureConditions {
+				if condition.Type == pressure && condition.Status == corev1.ConditionTrue {
+					pressures = append(pressures, string(condition.Type))
+				}
+			}
+		}
+		if len(pressures) > 0 {
+			health.PressureNodes = append(health.PressureNodes, &commonmodels.NodePressure{
+				NodeName:   node.Name,
+				Conditions: pressures,
+			})
+		}
+		if node.Spec.Unschedulable {
+			continue
+		}
+		health.Capacity.CPUAllocatable += node.Status.Allocatable.Cpu().MilliValue()
+		health.Capacity.MemoryAllocatable += node.Status.Allocatable.Memory().Value()
+	}
+
+	pods, err := clientset.CoreV1().Pods("").List(ctx, metav1.ListOptions{
+		FieldSelector: "status.phase!=Succeeded,status.phase!=Failed",
+	})
+	if err != nil {
+		// nodes are reachable, capacity is just incomplete
+		log.Warnf("[ClusterHealth] failed to list pods in cluster %s, err: %s", cluster.Name, err)
+		return health
+	}
+	for _, pod := range pods.Items {
+		for _, container := range pod.Spec.Containers {
+			health.Capacity.CPURequested += container.Resources.Requests.Cpu().MilliValue()
+			health.Capacity.MemoryRequested += container.Resources.Requests.Memory().Value()
+		}
+	}
+	return health
+}
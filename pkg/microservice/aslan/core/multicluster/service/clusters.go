@@ -75,6 +75,8 @@ type K8SCluster struct {
 	LastConnectionTime     int64                    `json:"last_connection_time"`
 	UpdateHubagentErrorMsg string                   `json:"update_hubagent_error_msg"`
 	DindCfg                *commonmodels.DindCfg    `json:"dind_cfg"`
+	AgentVersion           string                   `json:"agent_version"`
+	AgentVersionSkew       string                   `json:"agent_version_skew"`
 
 	// new field in 1.14, intended to enable kubeconfig for cluster management
 	Type       string `json:"type"` // either agent or kubeconfig supported
@@ -224,6 +226,8 @@ func ListClusters(ids []string, projectName string, logger *zap.SugaredLogger) (
 			KubeConfig:             c.KubeConfig,
 			Type:                   c.Type,
 			ShareStorage:           c.ShareStorage,
+			AgentVersion:           c.AgentVersion,
+			AgentVersionSkew:       GetAgentVersionSkew(c),
 		}
 
 		// compatibility for the data before 1.14, since type is a new field since 1.14
@@ -551,6 +555,10 @@ func DeleteCluster(username, clusterID string, logger *zap.SugaredLogger) error
 		logger.Errorf("Failed to delete projectClusterRelation err:%s", err)
 	}
 
+	if err = commonrepo.NewClusterHealthColl().Delete(clusterID); err != nil {
+		logger.Errorf("Failed to delete cluster health err:%s", err)
+	}
+
 	return s.DeleteCluster(username, clusterID, logger)
 }
 
@@ -0,0 +1,148 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	projectservice "github.com/koderover/zadig/pkg/microservice/aslan/core/project/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+// @Summary Get project flaky job policy
+// @Description Get how the flaky jobs of the project are handled
+// @Tags 	project
+// @Accept 	json
+// @Produce json
+// @Param 	name	path		string							true	"project name"
+// @Success 200 	{object} 	template.ProjectFlakyJobPolicy
+// @Router /api/aslan/project/products/{name}/flakyJobPolicy [get]
+func GetProjectFlakyJobPolicy(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	projectKey := c.Param("name")
+	if projectKey == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("productName can not be null!")
+		return
+	}
+
+	if !ctx.Resources.IsSystemAdmin {
+		if _, ok := ctx.Resources.ProjectAuthInfo[projectKey]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+	}
+
+	ctx.Resp, ctx.Err = projectservice.GetProjectFlakyJobPolicy(projectKey)
+}
+
+// @Summary Update project flaky job policy
+// @Description Update how the flaky jobs of the project are handled
+// @Tags 	project
+// @Accept 	json
+// @Produce json
+// @Param 	name	path		string							true	"project name"
+// @Param 	body 	body 		template.ProjectFlakyJobPolicy 	true 	"body"
+// @Success 200
+// @Router /api/aslan/project/products/{name}/flakyJobPolicy [put]
+func UpdateProjectFlakyJobPolicy(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	projectKey := c.Param("name")
+	if projectKey == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("productName can not be null!")
+		return
+	}
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, projectKey, "更新", "项目不稳定任务策略", projectKey, "", ctx.Logger)
+
+	args := new(template.ProjectFlakyJobPolicy)
+	if err := c.BindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid flaky job policy json args")
+		return
+	}
+
+	if !ctx.Resources.IsSystemAdmin {
+		if _, ok := ctx.Resources.ProjectAuthInfo[projectKey]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+		if !ctx.Resources.ProjectAuthInfo[projectKey].IsProjectAdmin {
+			ctx.UnAuthorized = true
+			return
+		}
+	}
+
+	ctx.Err = projectservice.UpdateProjectFlakyJobPolicy(projectKey, args)
+}
+
+// @Summary List project flaky jobs
+// @Description List the jobs of the project whose results differ between the runs with the same inputs
+// @Tags 	project
+// @Accept 	json
+// @Produce json
+// @Param 	name		path		string						true	"project name"
+// @Param 	startTime	query		int							false	"start time"
+// @Param 	endTime		query		int							false	"end time"
+// @Success 200 		{array} 	projectservice.FlakyJob
+// @Router /api/aslan/project/products/{name}/flakyJobs [get]
+func ListProjectFlakyJobs(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	projectKey := c.Param("name")
+	if projectKey == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("productName can not be null!")
+		return
+	}
+
+	if !ctx.Resources.IsSystemAdmin {
+		if _, ok := ctx.Resources.ProjectAuthInfo[projectKey]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+	}
+
+	startTime, _ := strconv.ParseInt(c.Query("startTime"), 10, 64)
+	endTime, _ := strconv.ParseInt(c.Query("endTime"), 10, 64)
+	ctx.Resp, ctx.Err = projectservice.ListProjectFlakyJobs(projectKey, startTime, endTime)
+}
@@ -365,6 +365,36 @@ func DeleteProductTemplate(c *gin.Context) {
 	ctx.Err = projectservice.DeleteProductTemplate(ctx.UserName, projectKey, ctx.RequestID, isDelete, ctx.Logger)
 }
 
+func RenameProjectKey(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	args := new(projectservice.RenameProjectKeyArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	projectKey := c.Param("name")
+	if !args.DryRun {
+		internalhandler.InsertOperationLog(c, ctx.UserName, projectKey, "修改项目标识", "项目管理-项目", fmt.Sprintf("%s -> %s", projectKey, args.NewKey), "", ctx.Logger)
+	}
+
+	// authorization checks
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	ctx.Resp, ctx.Err = projectservice.RenameProjectKey(projectKey, args, ctx.Logger)
+}
+
 func ListTemplatesHierachy(c *gin.Context) {
 	ctx := internalhandler.NewContext(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
@@ -45,6 +45,7 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		product.PUT("", UpdateProject)
 		product.PUT("/:name/type", TransferProject)
 		product.DELETE("/:name", DeleteProductTemplate)
+		product.POST("/:name/rename", RenameProjectKey)
 
 		product.GET("/:name/globalVariables", GetGlobalVariables)
 		product.PUT("/:name/globalVariables", UpdateGlobalVariables)
@@ -53,6 +54,20 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		product.GET("/:name/productionGlobalVariables", GetProductionGlobalVariables)
 		product.PUT("/:name/productionGlobalVariables", UpdateProductionGlobalVariables)
 		product.GET("/:name/productionGlobalVariableCandidates", GetProductionGlobalVariableCandidates)
+
+		product.GET("/:name/resourceQuota", GetProjectResourceQuota)
+		product.PUT("/:name/resourceQuota", UpdateProjectResourceQuota)
+		product.GET("/:name/imageBuildConfig", GetProjectImageBuildConfig)
+		product.PUT("/:name/imageBuildConfig", UpdateProjectImageBuildConfig)
+		product.GET("/:name/mailNotify", GetProjectMailNotifyConfig)
+		product.PUT("/:name/mailNotify", UpdateProjectMailNotifyConfig)
+		product.GET("/:name/promotionPolicy", GetProjectPromotionPolicy)
+		product.PUT("/:name/promotionPolicy", UpdateProjectPromotionPolicy)
+		product.GET("/:name/logRetention", GetProjectLogRetention)
+		product.PUT("/:name/logRetention", UpdateProjectLogRetention)
+		product.GET("/:name/flakyJobPolicy", GetProjectFlakyJobPolicy)
+		product.PUT("/:name/flakyJobPolicy", UpdateProjectFlakyJobPolicy)
+		product.GET("/:name/flakyJobs", ListProjectFlakyJobs)
 	}
 
 	group := router.Group("group")
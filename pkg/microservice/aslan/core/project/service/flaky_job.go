@@ -0,0 +1,76 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func GetProjectFlakyJobPolicy(projectName string) (*template.ProjectFlakyJobPolicy, error) {
+	project, err := templaterepo.NewProductColl().Find(projectName)
+	if err != nil {
+		return nil, e.ErrNotFound.AddDesc(fmt.Sprintf("project %s not found", projectName))
+	}
+	if project.FlakyJobPolicy == nil {
+		return &template.ProjectFlakyJobPolicy{}, nil
+	}
+	return project.FlakyJobPolicy, nil
+}
+
+func UpdateProjectFlakyJobPolicy(projectName string, policy *template.ProjectFlakyJobPolicy) error {
+	if _, err := templaterepo.NewProductColl().Find(projectName); err != nil {
+		return e.ErrNotFound.AddDesc(fmt.Sprintf("project %s not found", projectName))
+	}
+	if err := templaterepo.NewProductColl().UpdateFlakyJobPolicy(projectName, policy); err != nil {
+		return e.ErrInternalError.AddErr(err)
+	}
+	return nil
+}
+
+type FlakyJob struct {
+	*commonrepo.FlakyJobStat
+	// FlakyRate is the ratio of the flaky runs to all the runs of the job
+	FlakyRate float64 `json:"flaky_rate"`
+}
+
+// ListProjectFlakyJobs returns the jobs of the project having flaky runs in the time range, the last 30 days by
+// default, the flakiest jobs come first
+func ListProjectFlakyJobs(projectName string, startTime, endTime int64) ([]*FlakyJob, error) {
+	if startTime == 0 && endTime == 0 {
+		endTime = time.Now().Unix()
+		startTime = time.Unix(endTime, 0).AddDate(0, 0, -30).Unix()
+	}
+	stats, err := commonrepo.NewJobInfoColl().ListFlakyJobStats(projectName, startTime, endTime)
+	if err != nil {
+		return nil, e.ErrInternalError.AddErr(err)
+	}
+	resp := make([]*FlakyJob, 0, len(stats))
+	for _, stat := range stats {
+		job := &FlakyJob{FlakyJobStat: stat}
+		if stat.Total > 0 {
+			job.FlakyRate = float64(stat.Flaky) / float64(stat.Total)
+		}
+		resp = append(resp, job)
+	}
+	return resp, nil
+}
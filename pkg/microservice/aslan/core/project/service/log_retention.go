@@ -0,0 +1,49 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func GetProjectLogRetention(projectName string) (*template.ProjectLogRetention, error) {
+	project, err := templaterepo.NewProductColl().Find(projectName)
+	if err != nil {
+		return nil, e.ErrNotFound.AddDesc(fmt.Sprintf("project %s not found", projectName))
+	}
+	if project.LogRetention == nil {
+		return &template.ProjectLogRetention{}, nil
+	}
+	return project.LogRetention, nil
+}
+
+func UpdateProjectLogRetention(projectName string, retention *template.ProjectLogRetention) error {
+	if retention.MaxJobLogSize < 0 || retention.RetentionDays < 0 {
+		return e.ErrInvalidParam.AddDesc("the log size limit and the retention days can not be negative")
+	}
+	if _, err := templaterepo.NewProductColl().Find(projectName); err != nil {
+		return e.ErrNotFound.AddDesc(fmt.Sprintf("project %s not found", projectName))
+	}
+	if err := templaterepo.NewProductColl().UpdateLogRetention(projectName, retention); err != nil {
+		return e.ErrInternalError.AddErr(err)
+	}
+	return nil
+}
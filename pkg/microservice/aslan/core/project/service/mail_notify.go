@@ -0,0 +1,59 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"net/mail"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/instantmessage"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func GetProjectMailNotifyConfig(projectName string) (*template.ProjectMailNotifyConfig, error) {
+	project, err := templaterepo.NewProductColl().Find(projectName)
+	if err != nil {
+		return nil, e.ErrNotFound.AddDesc(fmt.Sprintf("project %s not found", projectName))
+	}
+	if project.MailNotify == nil {
+		return &template.ProjectMailNotifyConfig{Recipients: make([]string, 0)}, nil
+	}
+	return project.MailNotify, nil
+}
+
+func UpdateProjectMailNotifyConfig(projectName string, cfg *template.ProjectMailNotifyConfig) error {
+	for _, recipient := range cfg.Recipients {
+		if _, err := mail.ParseAddress(recipient); err != nil {
+			return e.ErrInvalidParam.AddDesc(fmt.Sprintf("invalid email address %s", recipient))
+		}
+	}
+	if err := instantmessage.ValidateMailTemplate(cfg.TaskTemplate); err != nil {
+		return e.ErrInvalidParam.AddDesc("invalid task template: " + err.Error())
+	}
+	if err := instantmessage.ValidateMailTemplate(cfg.ApprovalTemplate); err != nil {
+		return e.ErrInvalidParam.AddDesc("invalid approval template: " + err.Error())
+	}
+	if _, err := templaterepo.NewProductColl().Find(projectName); err != nil {
+		return e.ErrNotFound.AddDesc(fmt.Sprintf("project %s not found", projectName))
+	}
+	if err := templaterepo.NewProductColl().UpdateMailNotifyConfig(projectName, cfg); err != nil {
+		return e.ErrInternalError.AddErr(err)
+	}
+	return nil
+}
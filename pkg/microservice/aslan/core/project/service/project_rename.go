@@ -0,0 +1,332 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	"github.com/koderover/zadig/pkg/shared/client/user"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+var projectKeyRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+type RenameProjectKeyArgs struct {
+	NewKey string `json:"new_key"`
+	DryRun bool   `json:"dry_run"`
+}
+
+type RenameProjectKeyReport struct {
+	OldKey    string               `json:"old_key"`
+	NewKey    string               `json:"new_key"`
+	DryRun    bool                 `json:"dry_run"`
+	Rewrites  []*ProjectKeyRewrite `json:"rewrites"`
+	Workflows []string             `json:"workflows"`
+	// Tasks is the number of the workflow tasks of the project, TasksModified is the number moved to the new key
+	Tasks         int64    `json:"tasks"`
+	TasksModified int64    `json:"tasks_modified"`
+	Warnings      []string `json:"warnings"`
+	// RollbackErrors are the failures of rolling back a failed rename, the listed documents keep the new key
+	RollbackErrors []string `json:"rollback_errors,omitempty"`
+}
+
+// ProjectKeyRewrite is the number of documents of a field holding the project key, Conflicts are the documents
+// already holding the new key
+type ProjectKeyRewrite struct {
+	*commonrepo.ProjectKeyField
+	Matched   int64 `json:"matched"`
+	Modified  int64 `json:"modified"`
+	Conflicts int64 `json:"conflicts"`
+}
+
+// RenameProjectKey rewrites the project key in all the documents referencing the project. Each step is rolled back
+// in the reverse order if a later one fails, the dry run only reports the documents to rewrite.
+// Projects with environments can not be renamed: the resources of the environments are labeled with the project
+// key in the clusters and the helm releases are named after it, they are not moved by the rename.
+func RenameProjectKey(oldKey string, args *RenameProjectKeyArgs, logger *zap.SugaredLogger) (*RenameProjectKeyReport, error) {
+	newKey := args.NewKey
+	if !projectKeyRegex.MatchString(newKey) {
+		return nil, e.ErrInvalidParam.AddDesc("project key can only contain lowercase letters, digits and hyphens")
+	}
+	if newKey == oldKey {
+		return nil, e.ErrInvalidParam.AddDesc("the new project key is the same as the old one")
+	}
+	if _, err := templaterepo.NewProductColl().Find(oldKey); err != nil {
+		return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("project %s not found", oldKey))
+	}
+	if _, err := templaterepo.NewProductColl().Find(newKey); err == nil {
+		return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("project %s already exists", newKey))
+	}
+	queues, err := commonrepo.NewWorkflowQueueColl().List(&commonrepo.ListWorfklowQueueOption{})
+	if err != nil {
+		return nil, e.ErrInternalError.AddErr(err)
+	}
+	for _, queue := range queues {
+		if queue.ProjectName == oldKey {
+			return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("workflow %s of the project is running, please retry after the tasks are done", queue.WorkflowName))
+		}
+	}
+	// the resources of the environments are labeled and managed by the project key in the clusters
+	envs, err := commonrepo.NewProductColl().List(&commonrepo.ProductListOptions{Name: oldKey})
+	if err != nil {
+		return nil, e.ErrInternalError.AddErr(err)
+	}
+	if len(envs) > 0 {
+		envNames := make([]string, 0, len(envs))
+		for _, env := range envs {
+			envNames = append(envNames, env.EnvName)
+		}
+		return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("renaming a project with environments is not supported since the resources in the clusters are labeled with the project key, please delete the environments %s of project %s first", strings.Join(envNames, ", "), oldKey))
+	}
+
+	report := &RenameProjectKeyReport{
+		OldKey:    oldKey,
+		NewKey:    newKey,
+		DryRun:    args.DryRun,
+		Rewrites:  make([]*ProjectKeyRewrite, 0, len(commonrepo.ProjectKeyFields)),
+		Workflows: make([]string, 0),
+		Warnings:  make([]string, 0),
+	}
+	conflicts := int64(0)
+	for _, field := range commonrepo.ProjectKeyFields {
+		rewrite := &ProjectKeyRewrite{ProjectKeyField: field}
+		if rewrite.Matched, err = commonrepo.CountProjectKey(field, oldKey); err != nil {
+			return nil, e.ErrInternalError.AddErr(fmt.Errorf("failed to count %s.%s, err: %s", field.Collection, field.Field, err))
+		}
+		if rewrite.Conflicts, err = commonrepo.CountProjectKey(field, newKey); err != nil {
+			return nil, e.ErrInternalError.AddErr(fmt.Errorf("failed to count %s.%s, err: %s", field.Collection, field.Field, err))
+		}
+		conflicts += rewrite.Conflicts
+		report.Rewrites = append(report.Rewrites, rewrite)
+	}
+	taskStore := commonrepo.NewWorkflowTaskV4Store()
+	if report.Tasks, err = taskStore.CountByProject(oldKey); err != nil {
+		return nil, e.ErrInternalError.AddErr(fmt.Errorf("failed to count the workflow tasks, err: %s", err))
+	}
+	taskConflicts, err := taskStore.CountByProject(newKey)
+	if err != nil {
+		return nil, e.ErrInternalError.AddErr(fmt.Errorf("failed to count the workflow tasks, err: %s", err))
+	}
+	conflicts += taskConflicts
+	workflows, err := listWorkflowsReferencingProject(oldKey)
+	if err != nil {
+		return nil, e.ErrInternalError.AddErr(err)
+	}
+	for _, workflow := range workflows {
+		report.Workflows = append(report.Workflows, workflow.Name)
+	}
+	if conflicts > 0 {
+		return report, e.ErrInvalidParam.AddDesc(fmt.Sprintf("%d documents already reference the project %s", conflicts, newKey))
+	}
+	if args.DryRun {
+		return report, nil
+	}
+
+	// workflows are rewritten first, they are found by the old key and would be missed once the key is renamed
+	if err := renameWorkflowsProjectKey(workflows, oldKey, newKey); err != nil {
+		logger.Errorf("failed to rename the project key of the workflows, err: %s", err)
+		report.RollbackErrors = rollbackProjectKey(workflows, nil, false, oldKey, newKey, logger)
+		return report, e.ErrInternalError.AddErr(err)
+	}
+	if report.TasksModified, err = taskStore.RenameProject(oldKey, newKey); err != nil {
+		logger.Errorf("failed to rename the project key of the workflow tasks, err: %s", err)
+		report.RollbackErrors = rollbackProjectKey(workflows, nil, true, oldKey, newKey, logger)
+		return report, e.ErrInternalError.AddErr(err)
+	}
+	for i, rewrite := range report.Rewrites {
+		if rewrite.Matched == 0 {
+			continue
+		}
+		if rewrite.Modified, err = commonrepo.RenameProjectKey(rewrite.ProjectKeyField, oldKey, newKey); err != nil {
+			logger.Errorf("failed to rename the project key of %s.%s, err: %s", rewrite.Collection, rewrite.Field, err)
+			report.RollbackErrors = rollbackProjectKey(workflows, report.Rewrites[:i], true, oldKey, newKey, logger)
+			return report, e.ErrInternalError.AddErr(err)
+		}
+	}
+	// the roles are moved at last, the bindings of the users and groups move with the roles
+	if err := user.New().RenameProjectRoles(oldKey, newKey); err != nil {
+		logger.Errorf("failed to move the roles of project %s to %s, err: %s", oldKey, newKey, err)
+		report.RollbackErrors = rollbackProjectKey(workflows, report.Rewrites, true, oldKey, newKey, logger)
+		return report, e.ErrInternalError.AddErr(fmt.Errorf("failed to move the roles of the project: %s", err))
+	}
+	if err := commonrepo.NewCounterColl().Rename("product:"+oldKey, "product:"+newKey); err != nil {
+		logger.Warnf("failed to rename the counter of project %s, err: %s", oldKey, err)
+		report.Warnings = append(report.Warnings, fmt.Sprintf("failed to rename the task counter of the project: %s", err))
+	}
+	return report, nil
+}
+
+// rollbackProjectKey restores the old key in the reverse order, the failures are returned so that the documents
+// left with the new key are reported
+func rollbackProjectKey(workflows []*commonmodels.WorkflowV4, rewrites []*ProjectKeyRewrite, tasks bool, oldKey, newKey string, logger *zap.SugaredLogger) []string {
+	resp := make([]string, 0)
+	for i := len(rewrites) - 1; i >= 0; i-- {
+		if rewrites[i].Matched == 0 {
+			continue
+		}
+		if _, err := commonrepo.RenameProjectKey(rewrites[i].ProjectKeyField, newKey, oldKey); err != nil {
+			logger.Errorf("failed to roll back the project key of %s.%s, err: %s", rewrites[i].Collection, rewrites[i].Field, err)
+			resp = append(resp, fmt.Sprintf("failed to roll back %s.%s: %s", rewrites[i].Collection, rewrites[i].Field, err))
+		}
+	}
+	if tasks {
+		if _, err := commonrepo.NewWorkflowTaskV4Store().RenameProject(newKey, oldKey); err != nil {
+			logger.Errorf("failed to roll back the project key of the workflow tasks, err: %s", err)
+			resp = append(resp, fmt.Sprintf("failed to roll back the workflow tasks: %s", err))
+		}
+	}
+	if err := renameWorkflowsProjectKey(workflows, newKey, oldKey); err != nil {
+		logger.Errorf("failed to roll back the project key of the workflows, err: %s", err)
+		resp = append(resp, fmt.Sprintf("failed to roll back the workflows: %s", err))
+	}
+	return resp
+}
+
+// listWorkflowsReferencingProject returns the workflows of the project and the ones whose jobs use the testings,
+// scannings or workflows of the project
+func listWorkflowsReferencingProject(projectKey string) ([]*commonmodels.WorkflowV4, error) {
+	owned, _, err := commonrepo.NewWorkflowV4Coll().List(&commonrepo.ListWorkflowV4Option{ProjectName: projectKey}, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflows, err: %s", err)
+	}
+	others, _, err := commonrepo.NewWorkflowV4Coll().List(&commonrepo.ListWorkflowV4Option{
+		JobTypes: []config.JobType{config.JobZadigTesting, config.JobZadigScanning, config.JobWorkflowTrigger},
+	}, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflows, err: %s", err)
+	}
+
+	resp := owned
+	for _, workflow := range others {
+		if workflow.Project == projectKey {
+			continue
+		}
+		changed, err := rewriteWorkflowProjectKey(workflow, projectKey, projectKey)
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			resp = append(resp, workflow)
+		}
+	}
+	return resp, nil
+}
+
+func renameWorkflowsProjectKey(workflows []*commonmodels.WorkflowV4, oldKey, newKey string) error {
+	for _, workflow := range workflows {
+		changed, err := rewriteWorkflowProjectKey(workflow, oldKey, newKey)
+		if err != nil {
+			return err
+		}
+		if !changed {
+			continue
+		}
+		if err := commonrepo.NewWorkflowV4Coll().Update(workflow.ID.Hex(), workflow); err != nil {
+			return fmt.Errorf("failed to update workflow %s, err: %s", workflow.Name, err)
+		}
+	}
+	return nil
+}
+
+// rewriteWorkflowProjectKey replaces the project key of the workflow, its hooks and the specs of its jobs, it
+// returns true if the workflow references the old key
+func rewriteWorkflowProjectKey(workflow *commonmodels.WorkflowV4, oldKey, newKey string) (bool, error) {
+	if workflow == nil {
+		return false, nil
+	}
+	changed := false
+	replace := func(key *string) {
+		if *key == oldKey {
+			*key = newKey
+			changed = true
+		}
+	}
+	replace(&workflow.Project)
+
+	hookArgs := make([]*commonmodels.WorkflowV4, 0)
+	for _, hook := range workflow.HookCtls {
+		hookArgs = append(hookArgs, hook.WorkflowArg)
+	}
+	for _, hook := range workflow.JiraHookCtls {
+		hookArgs = append(hookArgs, hook.WorkflowArg)
+	}
+	for _, hook := range workflow.MeegoHookCtls {
+		hookArgs = append(hookArgs, hook.WorkflowArg)
+	}
+	for _, hook := range workflow.GeneralHookCtls {
+		hookArgs = append(hookArgs, hook.WorkflowArg)
+	}
+	for _, hook := range workflow.RegistryHookCtls {
+		hookArgs = append(hookArgs, hook.WorkflowArg)
+	}
+	for _, arg := range hookArgs {
+		hookChanged, err := rewriteWorkflowProjectKey(arg, oldKey, newKey)
+		if err != nil {
+			return false, err
+		}
+		changed = changed || hookChanged
+	}
+
+	for _, stage := range workflow.Stages {
+		for _, job := range stage.Jobs {
+			switch job.JobType {
+			case config.JobZadigTesting:
+				spec := new(commonmodels.ZadigTestingJobSpec)
+				if err := commonmodels.IToi(job.Spec, spec); err != nil {
+					return false, err
+				}
+				for _, module := range spec.TestModules {
+					replace(&module.ProjectName)
+				}
+				for _, module := range spec.ServiceAndTests {
+					replace(&module.ProjectName)
+				}
+				job.Spec = spec
+			case config.JobZadigScanning:
+				spec := new(commonmodels.ZadigScanningJobSpec)
+				if err := commonmodels.IToi(job.Spec, spec); err != nil {
+					return false, err
+				}
+				for _, module := range spec.Scannings {
+					replace(&module.ProjectName)
+				}
+				job.Spec = spec
+			case config.JobWorkflowTrigger:
+				spec := new(commonmodels.WorkflowTriggerJobSpec)
+				if err := commonmodels.IToi(job.Spec, spec); err != nil {
+					return false, err
+				}
+				for _, info := range spec.FixedWorkflowList {
+					replace(&info.ProjectName)
+				}
+				for _, info := range spec.ServiceTriggerWorkflow {
+					replace(&info.ProjectName)
+				}
+				job.Spec = spec
+			}
+		}
+	}
+	return changed, nil
+}
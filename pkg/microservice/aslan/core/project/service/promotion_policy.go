@@ -0,0 +1,53 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func GetProjectPromotionPolicy(projectName string) (*template.ProjectPromotionPolicy, error) {
+	project, err := templaterepo.NewProductColl().Find(projectName)
+	if err != nil {
+		return nil, e.ErrNotFound.AddDesc(fmt.Sprintf("project %s not found", projectName))
+	}
+	if project.PromotionPolicy == nil {
+		return &template.ProjectPromotionPolicy{MutableTags: commonservice.DefaultMutableTags}, nil
+	}
+	return project.PromotionPolicy, nil
+}
+
+func UpdateProjectPromotionPolicy(projectName string, policy *template.ProjectPromotionPolicy) error {
+	for _, pattern := range policy.MutableTags {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return e.ErrInvalidParam.AddDesc(fmt.Sprintf("invalid mutable tag pattern %s: %s", pattern, err))
+		}
+	}
+	if _, err := templaterepo.NewProductColl().Find(projectName); err != nil {
+		return e.ErrNotFound.AddDesc(fmt.Sprintf("project %s not found", projectName))
+	}
+	if err := templaterepo.NewProductColl().UpdatePromotionPolicy(projectName, policy); err != nil {
+		return e.ErrInternalError.AddErr(err)
+	}
+	return nil
+}
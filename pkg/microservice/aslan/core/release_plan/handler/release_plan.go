@@ -216,3 +216,22 @@ func ApproveReleasePlan(c *gin.Context) {
 
 	ctx.Err = service.ApproveReleasePlan(ctx, c.Param("id"), req)
 }
+
+func ApproveReleaseTrainStage(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+	if err != nil {
+
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	req := new(service.ApproveRequest)
+	if err := c.ShouldBindJSON(req); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc(err.Error())
+		return
+	}
+
+	ctx.Err = service.ApproveReleaseTrainStage(ctx, c.Param("id"), c.Param("jobID"), req)
+}
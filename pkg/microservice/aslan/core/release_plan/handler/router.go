@@ -33,6 +33,7 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		v1.POST("/:id/execute", ExecuteReleaseJob)
 		v1.POST("/:id/status/:status", UpdateReleaseJobStatus)
 		v1.POST("/:id/approve", ApproveReleasePlan)
+		v1.POST("/:id/train/:jobID/approve", ApproveReleaseTrainStage)
 	}
 }
 
@@ -23,6 +23,7 @@ import (
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow"
 	"github.com/koderover/zadig/pkg/shared/client/user"
 	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
@@ -47,6 +48,8 @@ func NewReleaseJobExecutor(c *ExecuteReleaseJobContext, args *ExecuteReleaseJobA
 		return NewTextReleaseJobExecutor(c, args)
 	case config.JobWorkflow:
 		return NewWorkflowReleaseJobExecutor(c, args)
+	case config.JobReleaseTrain:
+		return NewReleaseTrainReleaseJobExecutor(c, args)
 	default:
 		return nil, errors.Errorf("invalid release job type: %s", args.Type)
 	}
@@ -131,19 +134,8 @@ func (e *WorkflowReleaseJobExecutor) Execute(plan *models.ReleasePlan) error {
 		}
 		// check workflow execute permission
 		ctx := e.Ctx
-		if !ctx.AuthResources.IsSystemAdmin {
-			if _, ok := ctx.AuthResources.ProjectAuthInfo[spec.Workflow.Project]; !ok {
-				return ErrPermissionDenied
-			}
-
-			if !ctx.AuthResources.ProjectAuthInfo[spec.Workflow.Project].IsProjectAdmin &&
-				!ctx.AuthResources.ProjectAuthInfo[spec.Workflow.Project].Workflow.Execute {
-				// check if the permission is given by collaboration mode
-				permitted, err := internalhandler.GetCollaborationModePermission(ctx.UserID, spec.Workflow.Project, types.ResourceTypeWorkflow, spec.Workflow.Name, types.WorkflowActionRun)
-				if err != nil || !permitted {
-					return ErrPermissionDenied
-				}
-			}
+		if err := checkWorkflowExecutePermission(ctx, spec.Workflow.Project, spec.Workflow.Name); err != nil {
+			return err
 		}
 
 		result, err := workflow.CreateWorkflowTaskV4(&workflow.CreateWorkflowTaskV4Args{
@@ -165,3 +157,84 @@ func (e *WorkflowReleaseJobExecutor) Execute(plan *models.ReleasePlan) error {
 	}
 	return errors.Errorf("job %s not found", e.ID)
 }
+
+func checkWorkflowExecutePermission(ctx *ExecuteReleaseJobContext, project, workflowName string) error {
+	if ctx.AuthResources.IsSystemAdmin {
+		return nil
+	}
+	if _, ok := ctx.AuthResources.ProjectAuthInfo[project]; !ok {
+		return ErrPermissionDenied
+	}
+
+	if !ctx.AuthResources.ProjectAuthInfo[project].IsProjectAdmin &&
+		!ctx.AuthResources.ProjectAuthInfo[project].Workflow.Execute {
+		// check if the permission is given by collaboration mode
+		permitted, err := internalhandler.GetCollaborationModePermission(ctx.UserID, project, types.ResourceTypeWorkflow, workflowName, types.WorkflowActionRun)
+		if err != nil || !permitted {
+			return ErrPermissionDenied
+		}
+	}
+	return nil
+}
+
+type ReleaseTrainReleaseJobExecutor struct {
+	ID  string
+	Ctx *ExecuteReleaseJobContext
+}
+
+func NewReleaseTrainReleaseJobExecutor(c *ExecuteReleaseJobContext, args *ExecuteReleaseJobArgs) (ReleaseJobExecutor, error) {
+	return &ReleaseTrainReleaseJobExecutor{
+		ID:  args.ID,
+		Ctx: c,
+	}, nil
+}
+
+// Execute starts the release train from the first stage, or retries the failed stage.
+func (e *ReleaseTrainReleaseJobExecutor) Execute(plan *models.ReleasePlan) error {
+	spec := new(models.ReleaseTrainReleaseJobSpec)
+	for _, job := range plan.Jobs {
+		if job.ID != e.ID {
+			continue
+		}
+		if err := models.IToi(job.Spec, spec); err != nil {
+			return errors.Wrap(err, "invalid spec")
+		}
+		switch job.Status {
+		case config.ReleasePlanJobStatusTodo:
+			spec.CurrentStage = 0
+			for _, stage := range spec.Stages {
+				resetReleaseTrainStage(stage)
+			}
+		case config.ReleasePlanJobStatusFailed:
+			if spec.CurrentStage >= len(spec.Stages) {
+				return errors.Errorf("job %s has no stage to retry", job.Name)
+			}
+			resetReleaseTrainStage(spec.Stages[spec.CurrentStage])
+		default:
+			return errors.Errorf("job %s status %s can't execute", job.Name, job.Status)
+		}
+
+		ctx := e.Ctx
+		for _, stage := range spec.Stages {
+			workflowInfo, err := mongodb.NewWorkflowV4Coll().Find(stage.WorkflowName)
+			if err != nil {
+				return errors.Wrapf(err, "find workflow %s", stage.WorkflowName)
+			}
+			if err := checkWorkflowExecutePermission(ctx, workflowInfo.Project, workflowInfo.Name); err != nil {
+				return err
+			}
+		}
+
+		spec.ExecutorID = ctx.UserID
+		spec.ExecutorAccount = ctx.Account
+		if err := startReleaseTrainStage(spec, ctx.UserName, nil); err != nil {
+			return errors.Wrapf(err, "start stage %s", spec.Stages[spec.CurrentStage].Name)
+		}
+		job.Spec = spec
+		job.Status = config.ReleasePlanJobStatusRunning
+		job.ExecutedBy = ctx.UserName
+		job.ExecutedTime = time.Now().Unix()
+		return nil
+	}
+	return errors.Errorf("job %s not found", e.ID)
+}
@@ -41,6 +41,12 @@ func lintReleaseJob(_type config.ReleasePlanJobType, spec interface{}) error {
 			return fmt.Errorf("invalid workflow spec: %v", err)
 		}
 		return lintWorkflow(w.Workflow)
+	case config.JobReleaseTrain:
+		t := new(models.ReleaseTrainReleaseJobSpec)
+		if err := models.IToi(spec, t); err != nil {
+			return fmt.Errorf("invalid release train spec: %v", err)
+		}
+		return lintReleaseTrain(t)
 	default:
 		return fmt.Errorf("invalid release job type: %s", _type)
 	}
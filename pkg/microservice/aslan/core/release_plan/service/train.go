@@ -0,0 +1,309 @@
+/*
+ * Copyright 2023 The KodeRover Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	approvalservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/approval"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow"
+	"github.com/koderover/zadig/pkg/shared/handler"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+func lintReleaseTrain(spec *models.ReleaseTrainReleaseJobSpec) error {
+	if len(spec.Services) == 0 {
+		return errors.New("release train must have services")
+	}
+	serviceSets := sets.NewString()
+	for _, service := range spec.Services {
+		if service.ServiceName == "" || service.ServiceModule == "" || service.Image == "" {
+			return errors.New("service name, service module and image of the release train services are required")
+		}
+		key := service.ServiceName + "/" + service.ServiceModule
+		if serviceSets.Has(key) {
+			return errors.Errorf("duplicated service %s", key)
+		}
+		serviceSets.Insert(key)
+	}
+	if len(spec.Stages) == 0 {
+		return errors.New("release train must have stages")
+	}
+	for _, stage := range spec.Stages {
+		if stage.Name == "" || stage.WorkflowName == "" || stage.Env == "" {
+			return errors.New("name, workflow and env of the release train stages are required")
+		}
+		if _, err := generateReleaseTrainWorkflow(spec.Services, stage); err != nil {
+			return errors.Wrapf(err, "stage %s", stage.Name)
+		}
+		if stage.Approval == nil {
+			continue
+		}
+		if stage.Approval.NeededApprovers <= 0 {
+			return errors.Errorf("stage %s needs at least one approver", stage.Name)
+		}
+		if len(stage.Approval.ApproveUsers) < stage.Approval.NeededApprovers {
+			return errors.Errorf("approve users of stage %s should not less than needed approvers", stage.Name)
+		}
+	}
+	return nil
+}
+
+// generateReleaseTrainWorkflow sets the deploy jobs of the stage workflow to deploy the train services to the stage env.
+func generateReleaseTrainWorkflow(services []*models.ReleaseTrainService, stage *models.ReleaseTrainStage) (*models.WorkflowV4, error) {
+	workflowInfo, err := mongodb.NewWorkflowV4Coll().Find(stage.WorkflowName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "find workflow %s", stage.WorkflowName)
+	}
+	found := false
+	for _, workflowStage := range workflowInfo.Stages {
+		for _, job := range workflowStage.Jobs {
+			if job.JobType != config.JobZadigDeploy {
+				continue
+			}
+			spec := new(models.ZadigDeployJobSpec)
+			if err := models.IToi(job.Spec, spec); err != nil {
+				return nil, errors.Wrapf(err, "invalid deploy job %s", job.Name)
+			}
+			spec.Env = stage.Env
+			spec.Source = config.SourceRuntime
+			if !lo.Contains(spec.DeployContents, config.DeployImage) {
+				spec.DeployContents = append(spec.DeployContents, config.DeployImage)
+			}
+			spec.ServiceAndImages = make([]*models.ServiceAndImage, 0, len(services))
+			for _, service := range services {
+				spec.ServiceAndImages = append(spec.ServiceAndImages, &models.ServiceAndImage{
+					ServiceName:   service.ServiceName,
+					ServiceModule: service.ServiceModule,
+					Image:         service.Image,
+				})
+			}
+			job.Spec = spec
+			found = true
+		}
+	}
+	if !found {
+		return nil, errors.Errorf("workflow %s has no deploy job", stage.WorkflowName)
+	}
+	return workflowInfo, nil
+}
+
+// errReleaseTrainStageClaimed means the stage has been started by others, the train must not be saved then.
+var errReleaseTrainStageClaimed = errors.New("release train stage has been claimed")
+
+// startReleaseTrainStage creates the workflow task of the current stage, or waits for the approval of the stage.
+// The claim is called right before the task is created if it is set, the stage is not started if it returns false.
+func startReleaseTrainStage(spec *models.ReleaseTrainReleaseJobSpec, userName string, claim func() (bool, error)) error {
+	stage := spec.Stages[spec.CurrentStage]
+	if stage.Approval != nil && stage.Approval.RejectOrApprove != config.Approve {
+		stage.Status = config.StatusWaitingApprove
+		return nil
+	}
+
+	workflowInfo, err := generateReleaseTrainWorkflow(spec.Services, stage)
+	if err != nil {
+		stage.Status = config.StatusFailed
+		return err
+	}
+	if claim != nil {
+		claimed, err := claim()
+		if err != nil {
+			return errors.Wrap(err, "failed to claim the stage")
+		}
+		if !claimed {
+			return errReleaseTrainStageClaimed
+		}
+	}
+	result, err := workflow.CreateWorkflowTaskV4(&workflow.CreateWorkflowTaskV4Args{
+		Name:    userName,
+		Account: spec.ExecutorAccount,
+		UserID:  spec.ExecutorID,
+	}, workflowInfo, log.SugaredLogger().With("source", "release train"))
+	if err != nil {
+		stage.Status = config.StatusFailed
+		return errors.Wrapf(err, "failed to create workflow task %s", stage.WorkflowName)
+	}
+	stage.TaskID = result.TaskID
+	stage.Status = config.StatusPrepare
+	stage.StartTime = time.Now().Unix()
+	return nil
+}
+
+func resetReleaseTrainStage(stage *models.ReleaseTrainStage) {
+	stage.Status = ""
+	stage.TaskID = 0
+	stage.StartTime = 0
+	stage.EndTime = 0
+	if stage.Approval == nil {
+		return
+	}
+	stage.Approval.RejectOrApprove = ""
+	for _, user := range stage.Approval.ApproveUsers {
+		user.RejectOrApprove = ""
+		user.Comment = ""
+		user.OperationTime = 0
+	}
+}
+
+// updateReleaseTrainReleaseJob syncs the status of the running stage task, starts the stage once it is approved and
+// moves the train to the next stage when the task passed. The stage is claimed in the database before its task is
+// created, false is returned if the train is not changed or has been moved by others, the job must not be saved then.
+func updateReleaseTrainReleaseJob(planID string, job *models.ReleaseJob, log *zap.SugaredLogger) bool {
+	spec := new(models.ReleaseTrainReleaseJobSpec)
+	if err := models.IToi(job.Spec, spec); err != nil {
+		log.Errorf("convert spec error: %v", err)
+		return false
+	}
+	job.Spec = spec
+
+	if spec.CurrentStage >= len(spec.Stages) {
+		job.Status = config.ReleasePlanJobStatusDone
+		return true
+	}
+	stage := spec.Stages[spec.CurrentStage]
+	claimedStage, claimedStatus := spec.CurrentStage, stage.Status
+	claim := func() (bool, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+		defer cancel()
+		return mongodb.NewReleasePlanColl().ClaimReleaseTrainStage(ctx, planID, job.ID, claimedStage, claimedStatus, spec.CurrentStage)
+	}
+
+	if stage.Status == config.StatusWaitingApprove {
+		// the votes are recorded by the approvers, the approval is decided here from all of them
+		approved, _, err := (&approvalservice.ApproveWithLock{Approval: stage.Approval}).IsApproval()
+		if err != nil {
+			stage.Status = config.StatusReject
+			stage.EndTime = time.Now().Unix()
+			job.Status = config.ReleasePlanJobStatusFailed
+			return true
+		}
+		if !approved {
+			return false
+		}
+		return startReleaseTrainJobStage(job, spec, claim, log)
+	}
+
+	task, err := mongodb.NewWorkflowTaskV4Store().Find(stage.WorkflowName, stage.TaskID)
+	if err != nil {
+		log.Errorf("find task %s-%d error: %v", stage.WorkflowName, stage.TaskID, err)
+		return false
+	}
+	stage.Status = task.Status
+	if lo.Contains(config.FailedStatus(), task.Status) {
+		stage.EndTime = time.Now().Unix()
+		job.Status = config.ReleasePlanJobStatusFailed
+		return true
+	}
+	if task.Status != config.StatusPassed {
+		return true
+	}
+
+	stage.EndTime = time.Now().Unix()
+	spec.CurrentStage++
+	if spec.CurrentStage >= len(spec.Stages) {
+		job.Status = config.ReleasePlanJobStatusDone
+		return true
+	}
+	return startReleaseTrainJobStage(job, spec, claim, log)
+}
+
+func startReleaseTrainJobStage(job *models.ReleaseJob, spec *models.ReleaseTrainReleaseJobSpec, claim func() (bool, error), log *zap.SugaredLogger) bool {
+	err := startReleaseTrainStage(spec, job.ExecutedBy, claim)
+	if err == errReleaseTrainStageClaimed {
+		return false
+	}
+	if err != nil {
+		log.Errorf("start release train %s stage %s error: %v", job.Name, spec.Stages[spec.CurrentStage].Name, err)
+		job.Status = config.ReleasePlanJobStatusFailed
+	}
+	return true
+}
+
+// ApproveReleaseTrainStage records the vote of the user on the current stage of the release train, the task of the
+// stage is created by the watcher once the stage is approved.
+func ApproveReleaseTrainStage(c *handler.Context, planID, jobID string, req *ApproveRequest) error {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+	plan, err := mongodb.NewReleasePlanColl().GetByID(ctx, planID)
+	if err != nil {
+		return errors.Wrap(err, "get plan")
+	}
+	if plan.Status != config.StatusExecuting {
+		return errors.Errorf("plan status is %s, can not approve", plan.Status)
+	}
+
+	job, ok := lo.Find(plan.Jobs, func(job *models.ReleaseJob) bool { return job.ID == jobID })
+	if !ok {
+		return errors.Errorf("job %s not found", jobID)
+	}
+	if job.Type != config.JobReleaseTrain || job.Status != config.ReleasePlanJobStatusRunning {
+		return errors.Errorf("job %s is not a running release train", job.Name)
+	}
+	spec := new(models.ReleaseTrainReleaseJobSpec)
+	if err := models.IToi(job.Spec, spec); err != nil {
+		return errors.Wrap(err, "invalid spec")
+	}
+	if spec.CurrentStage >= len(spec.Stages) || spec.Stages[spec.CurrentStage].Status != config.StatusWaitingApprove {
+		return errors.Errorf("release train %s is not waiting for approval", job.Name)
+	}
+	stage := spec.Stages[spec.CurrentStage]
+
+	approveWithL := &approvalservice.ApproveWithLock{Approval: stage.Approval}
+	if err = approveWithL.DoApproval(c.UserName, c.UserID, req.Comment, req.Approve); err != nil {
+		return errors.Wrap(err, "do approval")
+	}
+	user, _ := lo.Find(stage.Approval.ApproveUsers, func(user *models.User) bool { return user.UserID == c.UserID })
+	// only the vote is saved in place, the plan may be saved by the watcher at the same time
+	recorded, err := mongodb.NewReleasePlanColl().RecordReleaseTrainVote(ctx, planID, jobID, spec.CurrentStage, user, c.UserName)
+	if err != nil {
+		return errors.Wrap(err, "record approval")
+	}
+	if !recorded {
+		return errors.Errorf("release train %s is not waiting for the approval of %s", job.Name, c.UserName)
+	}
+	detail := fmt.Sprintf("阶段 %s 审批通过", stage.Name)
+	if !req.Approve {
+		detail = fmt.Sprintf("阶段 %s 审批被拒绝", stage.Name)
+	}
+
+	go func() {
+		if err := mongodb.NewReleasePlanLogColl().Create(&models.ReleasePlanLog{
+			PlanID:     planID,
+			Username:   c.UserName,
+			Account:    c.Account,
+			Verb:       VerbUpdate,
+			TargetName: job.Name,
+			TargetType: TargetTypeApproval,
+			Detail:     detail,
+			CreatedAt:  time.Now().Unix(),
+		}); err != nil {
+			log.Errorf("create release plan log error: %v", err)
+		}
+	}()
+
+	return nil
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller"
 	"github.com/koderover/zadig/pkg/tool/log"
 )
 
@@ -34,6 +35,10 @@ func WatchExecutingWorkflow() {
 	log := log.SugaredLogger().With("service", "WatchExecutingWorkflow")
 	for {
 		time.Sleep(time.Second * 3)
+		// the tasks of the release trains are created here, only the leader syncs the plans to avoid duplicate ones
+		if !workflowcontroller.IsLeader() {
+			continue
+		}
 		t := time.Now()
 		list, _, err := mongodb.NewReleasePlanColl().ListByOptions(&mongodb.ListReleasePlanOption{
 			Status: config.StatusExecuting,
@@ -66,6 +71,9 @@ func updatePlanWorkflowReleaseJob(plan *models.ReleasePlan, log *zap.SugaredLogg
 	if plan.Status != config.StatusExecuting {
 		return
 	}
+	// only the synced jobs are saved in place, the others and the votes on the release trains may be changed by
+	// the users at the same time
+	syncedJobs := make([]*models.ReleaseJob, 0)
 	for _, job := range plan.Jobs {
 		if job.Status == config.ReleasePlanJobStatusRunning && job.Type == config.JobWorkflow {
 			spec := new(models.WorkflowReleaseJobSpec)
@@ -73,7 +81,7 @@ func updatePlanWorkflowReleaseJob(plan *models.ReleasePlan, log *zap.SugaredLogg
 				log.Errorf("convert spec error: %v", err)
 				continue
 			}
-			task, err := mongodb.NewworkflowTaskv4Coll().Find(spec.Workflow.Name, spec.TaskID)
+			task, err := mongodb.NewWorkflowTaskV4Store().Find(spec.Workflow.Name, spec.TaskID)
 			if err != nil {
 				log.Errorf("find task %s-%d error: %v", spec.Workflow.Name, spec.TaskID, err)
 				continue
@@ -85,15 +93,27 @@ func updatePlanWorkflowReleaseJob(plan *models.ReleasePlan, log *zap.SugaredLogg
 			if task.Status == config.StatusPassed {
 				job.Status = config.ReleasePlanJobStatusDone
 			}
-			if checkReleasePlanJobsAllDone(plan) {
-				plan.ExecutingTime = time.Now().Unix()
-				plan.SuccessTime = time.Now().Unix()
-				plan.Status = config.StatusSuccess
+			syncedJobs = append(syncedJobs, job)
+		}
+		if job.Status == config.ReleasePlanJobStatusRunning && job.Type == config.JobReleaseTrain {
+			if updateReleaseTrainReleaseJob(plan.ID.Hex(), job, log) {
+				syncedJobs = append(syncedJobs, job)
 			}
 		}
 	}
-	if err := mongodb.NewReleasePlanColl().UpdateByID(ctx, plan.ID.Hex(), plan); err != nil {
-		log.Errorf("update plan %s error: %v", plan.ID.Hex(), err)
+	for _, job := range syncedJobs {
+		if err := mongodb.NewReleasePlanColl().UpdateReleaseJob(ctx, plan.ID.Hex(), job); err != nil {
+			log.Errorf("update plan %s job %s error: %v", plan.ID.Hex(), job.Name, err)
+			return
+		}
+	}
+	if len(syncedJobs) > 0 && checkReleasePlanJobsAllDone(plan) {
+		plan.ExecutingTime = time.Now().Unix()
+		plan.SuccessTime = time.Now().Unix()
+		plan.Status = config.StatusSuccess
+		if err := mongodb.NewReleasePlanColl().UpdateStatusByID(ctx, plan.ID.Hex(), plan); err != nil {
+			log.Errorf("update plan %s error: %v", plan.ID.Hex(), err)
+		}
 	}
 	return
 }
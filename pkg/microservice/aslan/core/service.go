@@ -26,6 +26,7 @@ import (
 	newgoCron "github.com/go-co-op/gocron"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/hashicorp/go-multierror"
+	"go.mongodb.org/mongo-driver/mongo"
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
@@ -39,9 +40,12 @@ import (
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/ai"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/postgres"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/webhook"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller/jobcontroller"
 	environmentservice "github.com/koderover/zadig/pkg/microservice/aslan/core/environment/service"
 	labelMongodb "github.com/koderover/zadig/pkg/microservice/aslan/core/label/repository/mongodb"
 	multiclusterservice "github.com/koderover/zadig/pkg/microservice/aslan/core/multicluster/service"
@@ -49,6 +53,7 @@ import (
 	systemrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/system/repository/mongodb"
 	systemservice "github.com/koderover/zadig/pkg/microservice/aslan/core/system/service"
 	templateservice "github.com/koderover/zadig/pkg/microservice/aslan/core/templatestore/service"
+	workflowwebhook "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/webhook"
 	workflowservice "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow"
 	hubserverconfig "github.com/koderover/zadig/pkg/microservice/hubserver/config"
 	"github.com/koderover/zadig/pkg/microservice/hubserver/core/repository/mongodb"
@@ -142,6 +147,7 @@ func Start(ctx context.Context) {
 	InitializeConfigFeatureGates()
 
 	systemservice.SetProxyConfig()
+	systemservice.SetTrustStore()
 
 	workflowservice.InitPipelineController()
 	// update offical plugins
@@ -174,7 +180,7 @@ var Scheduler *newgoCron.Scheduler
 func initCron() {
 	Scheduler = newgoCron.NewScheduler(time.Local)
 
-	Scheduler.Every(5).Minutes().Do(func() {
+	Scheduler.Every(5).Minutes().Do(leaderOnly(func() {
 		log.Infof("[CRONJOB] updating tokens for gitlab....")
 		codehostList, err := mongodb2.NewCodehostColl().List(&mongodb2.ListArgs{
 			Source: "gitlab",
@@ -191,11 +197,73 @@ func initCron() {
 			}
 		}
 		log.Infof("[CRONJOB] gitlab token updated....")
-	})
+	}))
+
+	Scheduler.Every(5).Minutes().Do(leaderOnly(func() {
+		log.Infof("[CRONJOB] probing cluster health....")
+		multiclusterservice.ProbeClusterHealth()
+	}))
+
+	Scheduler.Every(10).Minutes().Do(leaderOnly(func() {
+		log.Infof("[CRONJOB] detecting environment drift....")
+		environmentservice.DetectEnvDrift()
+	}))
+
+	Scheduler.Every(5).Minutes().Do(leaderOnly(func() {
+		environmentservice.CollectServiceRestarts()
+	}))
+
+	Scheduler.Every(1).Minutes().Do(leaderOnly(func() {
+		workflowcontroller.RemindPendingApprovals()
+	}))
+
+	Scheduler.Every(1).Minutes().Do(leaderOnly(func() {
+		workflowcontroller.CheckQueueWaitTime()
+	}))
+
+	Scheduler.Every(5).Minutes().Do(leaderOnly(func() {
+		jobcontroller.CleanExpiredRetainedJobPods()
+	}))
+
+	Scheduler.Every(1).Hours().Do(leaderOnly(func() {
+		log.Infof("[CRONJOB] cleaning expired task logs....")
+		commonservice.CleanExpiredTaskLogs()
+	}))
+
+	Scheduler.Every(1).Hours().Do(leaderOnly(func() {
+		log.Infof("[CRONJOB] collecting share storage garbage....")
+		workflowcontroller.GCShareStorage()
+	}))
+
+	Scheduler.Every(1).Hours().Do(leaderOnly(func() {
+		log.Infof("[CRONJOB] cleaning expired webhook deliveries....")
+		workflowwebhook.CleanExpiredWebhookDeliveries()
+	}))
+
+	Scheduler.Every(1).Hours().Do(leaderOnly(func() {
+		workflowservice.CleanExpiredTaskIdempotencyKeys()
+	}))
+
+	Scheduler.Every(1).Hours().Do(leaderOnly(func() {
+		workflowservice.CleanExpiredWorkflowV4Trash()
+	}))
+
+	// every instance reloads the trust store since it may be updated through another instance
+	Scheduler.Every(1).Minutes().Do(systemservice.SetTrustStore)
 
 	Scheduler.StartAsync()
 }
 
+// leaderOnly runs the cron job in the leader of the controller instances so that it runs once in the cluster
+func leaderOnly(job func()) func() {
+	return func() {
+		if !workflowcontroller.IsLeader() {
+			return
+		}
+		job()
+	}
+}
+
 func initService() {
 	errors := new(multierror.Error)
 
@@ -356,6 +424,21 @@ func initDatabase() {
 	if err := mongotool.Ping(ctx); err != nil {
 		panic(fmt.Errorf("failed to connect to mongo, error: %s", err))
 	}
+	if err := mongotool.SetQueryOptions(&mongotool.QueryOptions{
+		ReadPreference:     configbase.MongoReadPreference(),
+		Timeout:            configbase.MongoQueryTimeout(),
+		SlowQueryThreshold: configbase.MongoSlowQueryThreshold(),
+	}); err != nil {
+		log.Warnf("failed to set the mongo query options, the defaults are used, error: %s", err)
+	}
+
+	if config.TaskStorageBackend() == setting.TaskStorageBackendPostgres {
+		store, err := postgres.NewWorkflowTaskV4Store(config.TaskStoragePostgresDSN())
+		if err != nil {
+			log.Panicf("Failed to open the workflow task storage, error: %s", err)
+		}
+		commonrepo.SetWorkflowTaskV4Store(store)
+	}
 
 	idxCtx, idxCancel := context.WithTimeout(ctx, 10*time.Minute)
 	defer idxCancel()
@@ -364,11 +447,14 @@ func initDatabase() {
 	for _, r := range []indexer{
 		// aslan related db index
 		template.NewProductColl(),
+		commonrepo.NewApprovalReminderRecordColl(),
 		commonrepo.NewBasicImageColl(),
 		commonrepo.NewBuildColl(),
 		commonrepo.NewCallbackRequestColl(),
 		commonrepo.NewConfigurationManagementColl(),
 		commonrepo.NewCounterColl(),
+		commonrepo.NewTenantColl(),
+		commonrepo.NewCoverageRecordColl(),
 		commonrepo.NewCronjobColl(),
 		commonrepo.NewDeliveryActivityColl(),
 		commonrepo.NewDeliveryArtifactColl(),
@@ -388,11 +474,20 @@ func initDatabase() {
 		commonrepo.NewInstallColl(),
 		commonrepo.NewItReportColl(),
 		commonrepo.NewK8SClusterColl(),
+		commonrepo.NewClusterHealthColl(),
+		commonrepo.NewJobLogSummaryColl(),
 		commonrepo.NewNotificationColl(),
 		commonrepo.NewNotifyColl(),
+		commonrepo.NewPerformanceRecordColl(),
+		commonrepo.NewRetainedJobPodColl(),
+		commonrepo.NewShareStorageUsageColl(),
+		commonrepo.NewVMAgentColl(),
+		commonrepo.NewVMJobColl(),
+		commonrepo.NewVMJobLogColl(),
 		commonrepo.NewPipelineColl(),
 		commonrepo.NewPrivateKeyColl(),
 		commonrepo.NewProductColl(),
+		commonrepo.NewProjectCustomFieldColl(),
 		commonrepo.NewProxyColl(),
 		commonrepo.NewQueueColl(),
 		commonrepo.NewRegistryNamespaceColl(),
@@ -405,6 +500,7 @@ func initDatabase() {
 		commonrepo.NewSubscriptionColl(),
 		commonrepo.NewSystemSettingColl(),
 		commonrepo.NewTaskColl(),
+		commonrepo.NewTestCaseResultColl(),
 		commonrepo.NewTestTaskStatColl(),
 		commonrepo.NewTestingColl(),
 		commonrepo.NewWebHookColl(),
@@ -419,12 +515,23 @@ func initDatabase() {
 		commonrepo.NewProjectClusterRelationColl(),
 		commonrepo.NewEnvResourceColl(),
 		commonrepo.NewEnvSvcDependColl(),
+		commonrepo.NewEnvDriftColl(),
+		commonrepo.NewServiceRestartRecordColl(),
+		commonrepo.NewFeatureFlagRecordColl(),
 		commonrepo.NewBuildTemplateColl(),
 		commonrepo.NewScanningColl(),
 		commonrepo.NewWorkflowV4Coll(),
 		commonrepo.NewworkflowTaskv4Coll(),
 		commonrepo.NewWorkflowQueueColl(),
+		commonrepo.NewControllerInstanceColl(),
+		commonrepo.NewProjectResourceUsageColl(),
+		commonrepo.NewWorkflowTaskStageTimelineColl(),
+		commonrepo.NewAgentRolloutColl(),
 		commonrepo.NewPluginRepoColl(),
+		commonrepo.NewProjectPluginColl(),
+		commonrepo.NewWebhookDeliveryColl(),
+		commonrepo.NewTaskIdempotencyColl(),
+		commonrepo.NewWorkflowV4TrashColl(),
 		commonrepo.NewWorkflowViewColl(),
 		commonrepo.NewWorkflowV4TemplateColl(),
 		commonrepo.NewVariableSetColl(),
@@ -435,6 +542,8 @@ func initDatabase() {
 		commonrepo.NewLLMIntegrationColl(),
 		commonrepo.NewReleasePlanColl(),
 		commonrepo.NewReleasePlanLogColl(),
+		commonrepo.NewApprovalRecordColl(),
+		commonrepo.NewWorkflowPolicyColl(),
 
 		// msg queue
 		commonrepo.NewMsgQueueCommonColl(),
@@ -452,6 +561,8 @@ func initDatabase() {
 
 		// user related db index
 		userdb.NewUserSettingColl(),
+		commonrepo.NewOIDCSigningKeyColl(),
+		userdb.NewLDAPGroupRoleMappingColl(),
 
 		// env AI analysis related db index
 		ai.NewEnvAIAnalysisColl(),
@@ -468,6 +579,9 @@ func initDatabase() {
 			if err := r.EnsureIndex(idxCtx); err != nil {
 				panic(fmt.Errorf("failed to create index for %s, error: %s", r.GetCollectionName(), err))
 			}
+			if auditor, ok := r.(indexAuditor); ok {
+				auditIndexes(idxCtx, auditor)
+			}
 		}(r)
 	}
 
@@ -489,6 +603,25 @@ type indexer interface {
 	GetCollectionName() string
 }
 
+// auditIndexes logs the index drift of the collection, e.g. the indexes created by hand or left by old versions
+func auditIndexes(ctx context.Context, r indexAuditor) {
+	drift, err := mongotool.AuditIndexes(ctx, r.GetCollectionName(), r.Indexes(), r.IndexModels())
+	if err != nil {
+		log.Warnf("failed to audit the indexes of %s, error: %s", r.GetCollectionName(), err)
+		return
+	}
+	if drift.HasDrift() {
+		log.Warnf("index drift of %s, missing: %v, unexpected: %v", drift.Collection, drift.Missing, drift.Unexpected)
+	}
+}
+
+// indexAuditor is an indexer whose indexes are compared with the ones in the db at startup
+type indexAuditor interface {
+	indexer
+	Indexes() mongo.IndexView
+	IndexModels() []mongo.IndexModel
+}
+
 // InitializeConfigFeatureGates initialize feature gates for the old config service module.
 // Currently, the function of this part is unknown. But we will keep it just to make sure.
 func InitializeConfigFeatureGates() error {
@@ -365,6 +365,57 @@ func UpdateServiceVariable(c *gin.Context) {
 	ctx.Err = svcservice.UpdateServiceVariables(servceTmplObjectargs)
 }
 
+// @Summary Get service variable schema
+// @Description Get the JSON Schema of the variables declared in the service template
+// @Tags 	service
+// @Accept 	json
+// @Produce json
+// @Param 	name		path		string		true	"service name"
+// @Param 	projectName	query		string		true	"project name"
+// @Param 	production	query		bool		false	"is production service"
+// @Param 	revision	query		int			false	"service revision"
+// @Success 200 		{object} 	jsonschema.Schema
+// @Router /api/aslan/service/services/{name}/variable/schema [get]
+func GetServiceVariableSchema(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	projectName := c.Query("projectName")
+	production := c.Query("production") == "true"
+
+	// the schema is used to render the variable form in env creation and deploy job config
+	permitted := false
+	if ctx.Resources.IsSystemAdmin {
+		permitted = true
+	} else if projectAuthInfo, ok := ctx.Resources.ProjectAuthInfo[projectName]; ok {
+		if projectAuthInfo.IsProjectAdmin ||
+			projectAuthInfo.Service.View ||
+			projectAuthInfo.ProductionService.View ||
+			projectAuthInfo.Env.EditConfig ||
+			projectAuthInfo.Workflow.Edit ||
+			projectAuthInfo.Workflow.Execute {
+			permitted = true
+		}
+	}
+	if !permitted {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	revision, err := strconv.ParseInt(c.DefaultQuery("revision", "0"), 10, 64)
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid revision number")
+		return
+	}
+	ctx.Resp, ctx.Err = svcservice.GetServiceVariableSchema(c.Param("name"), projectName, revision, production)
+}
+
 func UpdateServiceHealthCheckStatus(c *gin.Context) {
 	ctx, err := internalhandler.NewContextWithAuthorization(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
@@ -42,7 +42,9 @@ import (
 	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
 	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/fs"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/notify"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/repository"
 	commontypes "github.com/koderover/zadig/pkg/microservice/aslan/core/common/types"
 	commonutil "github.com/koderover/zadig/pkg/microservice/aslan/core/common/util"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/environment/service"
@@ -56,6 +58,7 @@ import (
 	"github.com/koderover/zadig/pkg/tool/log"
 	"github.com/koderover/zadig/pkg/types"
 	"github.com/koderover/zadig/pkg/util"
+	"github.com/koderover/zadig/pkg/util/jsonschema"
 	yamlutil "github.com/koderover/zadig/pkg/util/yaml"
 )
 
@@ -682,6 +685,10 @@ func CreateServiceTemplate(userName string, args *commonmodels.Service, force bo
 		}
 	}
 
+	if err := fillKustomizeServiceYaml(args); err != nil {
+		return nil, e.ErrCreateTemplate.AddErr(err)
+	}
+
 	// fill serviceVars and variableYaml and serviceVariableKVs
 	err := fillServiceVariable(args, serviceTmpl)
 	if err != nil {
@@ -753,6 +760,10 @@ func CreateProductionServiceTemplate(userName string, args *commonmodels.Service
 		}
 	}
 
+	if err := fillKustomizeServiceYaml(args); err != nil {
+		return nil, e.ErrCreateTemplate.AddErr(err)
+	}
+
 	// fill serviceVars and variableYaml and serviceVariableKVs
 	err := fillServiceVariable(args, serviceTmpl)
 	if err != nil {
@@ -912,6 +923,9 @@ func UpdateServiceVariables(args *commonservice.ServiceTmplObject) error {
 	if currentService.Type != setting.K8SDeployType {
 		return e.ErrUpdateService.AddErr(fmt.Errorf("invalid service type: %v", currentService.Type))
 	}
+	if err := commontypes.ValidateServiceVariableSchema(args.ServiceVariableKVs); err != nil {
+		return e.ErrUpdateService.AddErr(err)
+	}
 
 	currentService.VariableYaml = args.VariableYaml
 	currentService.ServiceVariableKVs = args.ServiceVariableKVs
@@ -943,6 +957,25 @@ func UpdateServiceVariables(args *commonservice.ServiceTmplObject) error {
 	return nil
 }
 
+// GetServiceVariableSchema returns the JSON Schema of the variables declared in the k8s yaml service template
+func GetServiceVariableSchema(serviceName, productName string, revision int64, production bool) (*jsonschema.Schema, error) {
+	svcTemplate, err := repository.QueryTemplateService(&commonrepo.ServiceFindOption{
+		ProductName: productName,
+		ServiceName: serviceName,
+		Revision:    revision,
+	}, production)
+	if err != nil {
+		return nil, e.ErrGetService.AddErr(fmt.Errorf("failed to get service info, err: %s", err))
+	}
+	if svcTemplate.Type != setting.K8SDeployType {
+		return nil, e.ErrGetService.AddErr(fmt.Errorf("invalid service type: %v", svcTemplate.Type))
+	}
+
+	schema := commontypes.ServiceVariableKVsToSchema(svcTemplate.ServiceVariableKVs)
+	schema.Title = serviceName
+	return schema, nil
+}
+
 func UpdateServiceHealthCheckStatus(args *commonservice.ServiceTmplObject) error {
 	currentService, err := commonrepo.NewServiceColl().Find(&commonrepo.ServiceFindOption{
 		ProductName: args.ProductName,
@@ -1413,6 +1446,29 @@ func ensureServiceTmpl(userName string, args *commonmodels.Service, log *zap.Sug
 	return nil
 }
 
+// fillKustomizeServiceYaml builds the base of kustomize service as the yaml of service template, which is used to
+// extract the variables and containers of the service, the overlays are built and saved in the same revision so
+// that the environments are rendered without pulling the git repo
+func fillKustomizeServiceYaml(args *commonmodels.Service) error {
+	if args.Source != setting.SourceFromKustomize {
+		return nil
+	}
+	if args.Type != setting.K8SDeployType {
+		return fmt.Errorf("kustomize services are only supported in k8s yaml projects")
+	}
+	if args.KustomizeConfig == nil || args.CodehostID == 0 || args.RepoName == "" || args.BranchName == "" {
+		return fmt.Errorf("git repo and kustomize config are required for kustomize services")
+	}
+
+	baseYaml, overlayYamls, err := kube.BuildKustomizeService(args)
+	if err != nil {
+		return err
+	}
+	args.Yaml = baseYaml
+	args.KustomizeConfig.OverlayYamls = overlayYamls
+	return nil
+}
+
 func createGerritWebhookByService(codehostID int, serviceName, repoName, branchName string) error {
 	detail, err := systemconfig.New().GetCodeHost(codehostID)
 	if err != nil {
@@ -41,6 +41,9 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		quality.POST("/buildLatestTenMeasure", GetLatestTenBuildMeasure)
 		quality.POST("/buildTenDurationMeasure", GetTenDurationMeasure)
 		quality.POST("/buildTrend", GetBuildTrendMeasure)
+		quality.GET("/service/build", GetServiceBuildStat)
+		quality.GET("/service/build/trend", GetServiceBuildTrend)
+		quality.GET("/failureCategories", GetFailureCategoryStat)
 		//testStat
 		quality.POST("/initTestStat", InitTestStat)
 		quality.POST("/testAverageMeasure", GetTestAverageMeasure)
@@ -0,0 +1,156 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/stat/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+type serviceBuildStatReq struct {
+	StartTime     int64    `form:"start_time,default=0"`
+	EndTime       int64    `form:"end_time,default=0"`
+	Projects      []string `form:"projects"`
+	ServiceName   string   `form:"service_name"`
+	ServiceModule string   `form:"service_module"`
+	Top           int      `form:"top,default=0"`
+}
+
+// setDefaultTimeRange ends the range now and starts it 12 weeks before the end if they are not given
+func (r *serviceBuildStatReq) setDefaultTimeRange() {
+	if r.EndTime == 0 {
+		r.EndTime = time.Now().Unix()
+	}
+	if r.StartTime == 0 {
+		r.StartTime = time.Unix(r.EndTime, 0).AddDate(0, 0, -84).Unix()
+	}
+}
+
+// authorizedProjects returns the projects of the request the user can view the workflows of, all of them are
+// returned if no project is requested. The failure reasons contain the logs of the jobs
+func authorizedProjects(ctx *internalhandler.Context, projects []string) ([]string, error) {
+	if ctx.Resources.IsSystemAdmin {
+		return projects, nil
+	}
+	allowed, found, err := internalhandler.ListAuthorizedProjectsByResourceAndVerb(ctx.UserID, types.ResourceTypeWorkflow, types.WorkflowActionView)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return []string{}, nil
+	}
+	if len(projects) == 0 {
+		return allowed, nil
+	}
+	return sets.NewString(projects...).Intersection(sets.NewString(allowed...)).List(), nil
+}
+
+// GetServiceBuildStat returns the average build time, the failure rate and the top failure reasons of each service module
+func GetServiceBuildStat(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	args := new(serviceBuildStatReq)
+	if err := c.ShouldBindQuery(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	args.setDefaultTimeRange()
+
+	requested := len(args.Projects) > 0
+	args.Projects, err = authorizedProjects(ctx, args.Projects)
+	if err != nil {
+		ctx.Err = e.ErrInternalError.AddErr(err)
+		return
+	}
+	// the service reads an empty project list as all the projects
+	if len(args.Projects) == 0 && (requested || !ctx.Resources.IsSystemAdmin) {
+		ctx.Resp = make([]*service.ServiceBuildStat, 0)
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.GetServiceBuildStats(args.StartTime, args.EndTime, args.Projects, args.Top, ctx.Logger)
+}
+
+// GetFailureCategoryStat counts the failed jobs of the projects by the categories of the failures
+func GetFailureCategoryStat(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(serviceBuildStatReq)
+	if err := c.ShouldBindQuery(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	args.setDefaultTimeRange()
+
+	ctx.Resp, ctx.Err = service.GetFailureCategoryStats(args.StartTime, args.EndTime, args.Projects, ctx.Logger)
+}
+
+// GetServiceBuildTrend returns the weekly build metrics of a service module of a project
+func GetServiceBuildTrend(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	args := new(serviceBuildStatReq)
+	if err := c.ShouldBindQuery(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	if len(args.Projects) != 1 || args.ServiceName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("one project and the service_name are required")
+		return
+	}
+	args.setDefaultTimeRange()
+
+	// authorization check
+	if !ctx.Resources.IsSystemAdmin {
+		projectKey := args.Projects[0]
+		if _, ok := ctx.Resources.ProjectAuthInfo[projectKey]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+
+		if !ctx.Resources.ProjectAuthInfo[projectKey].IsProjectAdmin &&
+			!ctx.Resources.ProjectAuthInfo[projectKey].Workflow.View {
+			ctx.UnAuthorized = true
+			return
+		}
+	}
+
+	ctx.Resp, ctx.Err = service.GetServiceBuildWeeklyTrend(args.StartTime, args.EndTime, args.Projects[0], args.ServiceName, args.ServiceModule, args.Top, ctx.Logger)
+}
@@ -74,7 +74,7 @@ type BuildStatColl struct {
 
 func NewBuildStatColl() *BuildStatColl {
 	name := models.BuildStat{}.TableName()
-	return &BuildStatColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+	return &BuildStatColl{Collection: mongotool.ReadDatabase(config.MongoDatabase()).Collection(name), coll: name}
 }
 
 func (c *BuildStatColl) GetCollectionName() string {
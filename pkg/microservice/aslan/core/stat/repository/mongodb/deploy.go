@@ -85,7 +85,7 @@ type DeployStatColl struct {
 
 func NewDeployStatColl() *DeployStatColl {
 	name := models.DeployStat{}.TableName()
-	return &DeployStatColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+	return &DeployStatColl{Collection: mongotool.ReadDatabase(config.MongoDatabase()).Collection(name), coll: name}
 }
 
 func (c *DeployStatColl) GetCollectionName() string {
@@ -44,7 +44,7 @@ type TestStatColl struct {
 
 func NewTestStatColl() *TestStatColl {
 	name := models.TestStat{}.TableName()
-	return &TestStatColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+	return &TestStatColl{Collection: mongotool.ReadDatabase(config.MongoDatabase()).Collection(name), coll: name}
 }
 
 func (c *TestStatColl) GetCollectionName() string {
@@ -211,24 +211,20 @@ func getTaskDateMap(productName string, startTimestamp int64) (map[string][]inte
 		}
 	}
 	v4Option := &commonmongodb.ListWorkflowTaskV4Option{ProjectName: productName, CreateTime: startTimestamp}
-	v4Cursor, err := commonmongodb.NewworkflowTaskv4Coll().ListByCursor(v4Option)
-	if err != nil {
-		return taskDateMap, fmt.Errorf("workflow v4 list err:%v", err)
-	}
-	for v4Cursor.Next(context.Background()) {
-		var workflowTask commonmodels.WorkflowTask
-		if err := v4Cursor.Decode(&workflowTask); err != nil {
-			return taskDateMap, fmt.Errorf("decode workflow v4 task err:%v", err)
-		}
+	err = commonmongodb.NewWorkflowTaskV4Store().ForEach(context.Background(), v4Option, func(workflowTask *commonmodels.WorkflowTask) error {
 		time := time.Unix(workflowTask.CreateTime, 0)
 		date := time.Format(config.Date)
 		if _, isExist := taskDateMap[date]; isExist {
-			taskDateMap[date] = append(taskDateMap[date], &workflowTask)
+			taskDateMap[date] = append(taskDateMap[date], workflowTask)
 		} else {
 			tasks := make([]interface{}, 0)
-			tasks = append(tasks, &workflowTask)
+			tasks = append(tasks, workflowTask)
 			taskDateMap[date] = tasks
 		}
+		return nil
+	})
+	if err != nil {
+		return taskDateMap, fmt.Errorf("workflow v4 list err:%v", err)
 	}
 	if len(taskDateMap) == 0 {
 		localTime := time.Now().AddDate(0, 0, -1).In(time.Local)
@@ -372,17 +368,9 @@ type buildStatLatestTen struct {
 }
 
 func GetLatestTenBuildMeasure(productNames []string, log *zap.SugaredLogger) ([]*buildStatLatestTen, error) {
-	cursor, err := commonmongodb.NewworkflowTaskv4Coll().ListByCursor(&commonmongodb.ListWorkflowTaskV4Option{ProjectNames: productNames, IsSort: true})
-	if err != nil {
-		log.Errorf("list workflow v4 err: %v", err)
-		return nil, fmt.Errorf("list workflow v4 err: %v", err)
-	}
 	latestPipelines := make([]*buildStatLatestTen, 0)
-	for cursor.Next(context.Background()) {
-		var workflowTask commonmodels.WorkflowTask
-		if err := cursor.Decode(&workflowTask); err != nil {
-			return nil, fmt.Errorf("decode workflow v4 task err:%v", err)
-		}
+	v4Option := &commonmongodb.ListWorkflowTaskV4Option{ProjectNames: productNames, IsSort: true}
+	err := commonmongodb.NewWorkflowTaskV4Store().ForEach(context.Background(), v4Option, func(workflowTask *commonmodels.WorkflowTask) error {
 		containBuild := false
 		for _, stage := range workflowTask.Stages {
 			for _, job := range stage.Jobs {
@@ -393,7 +381,7 @@ func GetLatestTenBuildMeasure(productNames []string, log *zap.SugaredLogger) ([]
 			}
 		}
 		if !containBuild {
-			continue
+			return nil
 		}
 		latestPipelines = append(latestPipelines, &buildStatLatestTen{
 			PipelineInfo: &models.PipelineInfo{
@@ -409,8 +397,13 @@ func GetLatestTenBuildMeasure(productNames []string, log *zap.SugaredLogger) ([]
 			CreateTime:  workflowTask.CreateTime,
 		})
 		if len(latestPipelines) >= config.LatestDay {
-			break
+			return commonmongodb.ErrStopIteration
 		}
+		return nil
+	})
+	if err != nil {
+		log.Errorf("list workflow v4 err: %v", err)
+		return nil, fmt.Errorf("list workflow v4 err: %v", err)
 	}
 	latestTenTasks, err := commonmongodb.NewTaskColl().ListAllTasks(&commonmongodb.ListAllTaskOption{Type: config.WorkflowType, Limit: config.LatestDay, Skip: 0, ProductNames: productNames})
 	if err != nil {
@@ -491,7 +484,7 @@ type TaskPreview struct {
 
 func getTaskDetail(taskType, workflowName string, taskID int64) (*TaskPreview, error) {
 	if config.PipelineType(taskType) == config.WorkflowTypeV4 {
-		task, err := commonmongodb.NewworkflowTaskv4Coll().Find(workflowName, taskID)
+		task, err := commonmongodb.NewWorkflowTaskV4Store().Find(workflowName, taskID)
 		if err != nil {
 			return nil, errors.Errorf("find workflow v4 task err:%v", err)
 		}
@@ -0,0 +1,71 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"sort"
+
+	"go.uber.org/zap"
+
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+// FailureCategoryStat is the number of the failed jobs of a category, Ratio is its share of all the classified
+// failures and JobTypes breaks the count down by the job type
+type FailureCategoryStat struct {
+	Category string         `json:"category"`
+	Count    int            `json:"count"`
+	Ratio    float64        `json:"ratio"`
+	JobTypes map[string]int `json:"job_types"`
+}
+
+func GetFailureCategoryStats(start, end int64, projects []string, log *zap.SugaredLogger) ([]*FailureCategoryStat, error) {
+	counts, err := commonrepo.NewJobInfoColl().CountFailureCategories(start, end, projects)
+	if err != nil {
+		log.Errorf("failed to count the failure categories, err: %s", err)
+		return nil, e.ErrInternalError.AddErr(err)
+	}
+
+	stats := make(map[string]*FailureCategoryStat)
+	total := 0
+	for _, count := range counts {
+		stat, ok := stats[count.Category]
+		if !ok {
+			stat = &FailureCategoryStat{Category: count.Category, JobTypes: make(map[string]int)}
+			stats[count.Category] = stat
+		}
+		stat.Count += count.Count
+		stat.JobTypes[count.JobType] += count.Count
+		total += count.Count
+	}
+
+	resp := make([]*FailureCategoryStat, 0, len(stats))
+	for _, stat := range stats {
+		if total > 0 {
+			stat.Ratio = float64(stat.Count) / float64(total)
+		}
+		resp = append(resp, stat)
+	}
+	sort.Slice(resp, func(i, j int) bool {
+		if resp[i].Count != resp[j].Count {
+			return resp[i].Count > resp[j].Count
+		}
+		return resp[i].Category < resp[j].Category
+	})
+	return resp, nil
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2022 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const (
+	defaultTopFailureReasons = 5
+	// maxServiceBuildTrendWeeks bounds the weeks of the trend, about two years
+	maxServiceBuildTrendWeeks = 104
+)
+
+// ServiceBuildStat is the build metrics of a service module, the durations are in seconds
+type ServiceBuildStat struct {
+	ProjectName       string                `json:"project_name"`
+	ServiceName       string                `json:"service_name"`
+	ServiceModule     string                `json:"service_module"`
+	Total             int                   `json:"total"`
+	Success           int                   `json:"success"`
+	Failure           int                   `json:"failure"`
+	Timeout           int                   `json:"timeout"`
+	FailureRate       float64               `json:"failure_rate"`
+	AverageBuildTime  int                   `json:"average_build_time"`
+	TopFailureReasons []*FailureReasonCount `json:"top_failure_reasons"`
+}
+
+type FailureReasonCount struct {
+	Reason string `json:"reason"`
+	Count  int    `json:"count"`
+}
+
+type ServiceBuildWeeklyStat struct {
+	WeekStartDate string `json:"week_start_date"`
+	*ServiceBuildStat
+}
+
+type ServiceBuildTrend struct {
+	ProjectName   string                    `json:"project_name"`
+	ServiceName   string                    `json:"service_name"`
+	ServiceModule string                    `json:"service_module"`
+	Weekly        []*ServiceBuildWeeklyStat `json:"weekly"`
+}
+
+// serviceBuildCounter accumulates the build jobs of a service module
+type serviceBuildCounter struct {
+	stat     *ServiceBuildStat
+	duration int64
+	reasons  map[string]int
+}
+
+func newServiceBuildCounter(projectName, serviceName, serviceModule string) *serviceBuildCounter {
+	return &serviceBuildCounter{
+		stat: &ServiceBuildStat{
+			ProjectName:       projectName,
+			ServiceName:       serviceName,
+			ServiceModule:     serviceModule,
+			TopFailureReasons: make([]*FailureReasonCount, 0),
+		},
+		reasons: make(map[string]int),
+	}
+}
+
+func (c *serviceBuildCounter) add(job *commonmodels.JobInfo) {
+	switch job.Status {
+	case string(config.StatusPassed):
+		c.stat.Success++
+	case string(config.StatusFailed):
+		c.stat.Failure++
+	case string(config.StatusTimeout):
+		c.stat.Timeout++
+	default:
+		// the cancelled builds are neither successes nor failures
+		return
+	}
+	c.stat.Total++
+	c.duration += job.Duration
+	if job.FailureReason != "" {
+		c.reasons[job.FailureReason]++
+	}
+}
+
+func (c *serviceBuildCounter) result(top int) *ServiceBuildStat {
+	if c.stat.Total > 0 {
+		c.stat.AverageBuildTime = int(c.duration / int64(c.stat.Total))
+		c.stat.FailureRate = float64(c.stat.Failure+c.stat.Timeout) / float64(c.stat.Total)
+	}
+	reasons := make([]*FailureReasonCount, 0, len(c.reasons))
+	for reason, count := range c.reasons {
+		reasons = append(reasons, &FailureReasonCount{Reason: reason, Count: count})
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if reasons[i].Count != reasons[j].Count {
+			return reasons[i].Count > reasons[j].Count
+		}
+		return reasons[i].Reason < reasons[j].Reason
+	})
+	if len(reasons) > top {
+		reasons = reasons[:top]
+	}
+	c.stat.TopFailureReasons = reasons
+	return c.stat
+}
+
+func serviceBuildKey(job *commonmodels.JobInfo) string {
+	return job.ProductName + "/" + job.ServiceName + "/" + job.ServiceModule
+}
+
+// GetServiceBuildStats aggregates the build jobs of the projects by service module, the service modules with the
+// highest failure rate come first. The build jobs saved without the service are skipped.
+func GetServiceBuildStats(start, end int64, projects []string, top int, log *zap.SugaredLogger) ([]*ServiceBuildStat, error) {
+	if top <= 0 {
+		top = defaultTopFailureReasons
+	}
+	jobs, err := commonrepo.NewJobInfoColl().GetBuildTrend(start, end, projects)
+	if err != nil {
+		log.Errorf("failed to get build jobs, err: %s", err)
+		return nil, e.ErrInternalError.AddErr(err)
+	}
+
+	counters := make(map[string]*serviceBuildCounter)
+	for _, job := range jobs {
+		if job.ServiceName == "" {
+			continue
+		}
+		key := serviceBuildKey(job)
+		if _, ok := counters[key]; !ok {
+			counters[key] = newServiceBuildCounter(job.ProductName, job.ServiceName, job.ServiceModule)
+		}
+		counters[key].add(job)
+	}
+
+	resp := make([]*ServiceBuildStat, 0, len(counters))
+	for _, counter := range counters {
+		if stat := counter.result(top); stat.Total > 0 {
+			resp = append(resp, stat)
+		}
+	}
+	sort.Slice(resp, func(i, j int) bool {
+		if resp[i].FailureRate != resp[j].FailureRate {
+			return resp[i].FailureRate > resp[j].FailureRate
+		}
+		return resp[i].ProjectName+"/"+resp[i].ServiceName+"/"+resp[i].ServiceModule <
+			resp[j].ProjectName+"/"+resp[j].ServiceName+"/"+resp[j].ServiceModule
+	})
+	return resp, nil
+}
+
+// GetServiceBuildWeeklyTrend returns the weekly build metrics of a service module, the weeks start from the start
+// time and the weeks without any build are kept with empty metrics
+func GetServiceBuildWeeklyTrend(start, end int64, project, serviceName, serviceModule string, top int, log *zap.SugaredLogger) (*ServiceBuildTrend, error) {
+	if top <= 0 {
+		top = defaultTopFailureReasons
+	}
+	if end <= start {
+		return nil, e.ErrInvalidParam.AddDesc("the end time must be after the start time")
+	}
+	if time.Unix(start, 0).AddDate(0, 0, 7*maxServiceBuildTrendWeeks).Unix() < end {
+		return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("the range can not be longer than %d weeks", maxServiceBuildTrendWeeks))
+	}
+	jobs, err := commonrepo.NewJobInfoColl().GetBuildTrend(start, end, []string{project})
+	if err != nil {
+		log.Errorf("failed to get build jobs of project %s, err: %s", project, err)
+		return nil, e.ErrInternalError.AddErr(err)
+	}
+
+	weeks := make([]*serviceBuildCounter, 0)
+	weekStarts := make([]int64, 0)
+	for weekStart := start; weekStart < end; weekStart = time.Unix(weekStart, 0).AddDate(0, 0, 7).Unix() {
+		weekStarts = append(weekStarts, weekStart)
+		weeks = append(weeks, newServiceBuildCounter(project, serviceName, serviceModule))
+	}
+	for _, job := range jobs {
+		if job.ServiceName != serviceName || (serviceModule != "" && job.ServiceModule != serviceModule) {
+			continue
+		}
+		// the week of the job is the last week starting before it
+		i := sort.Search(len(weekStarts), func(i int) bool { return weekStarts[i] > job.StartTime }) - 1
+		if i >= 0 {
+			weeks[i].add(job)
+		}
+	}
+
+	resp := &ServiceBuildTrend{
+		ProjectName:   project,
+		ServiceName:   serviceName,
+		ServiceModule: serviceModule,
+		Weekly:        make([]*ServiceBuildWeeklyStat, 0, len(weeks)),
+	}
+	for i, week := range weeks {
+		resp.Weekly = append(resp.Weekly, &ServiceBuildWeeklyStat{
+			WeekStartDate:    time.Unix(weekStarts[i], 0).Format("2006-01-02"),
+			ServiceBuildStat: week.result(top),
+		})
+	}
+	return resp, nil
+}
@@ -0,0 +1,94 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/system/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func ExportConfiguration(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	// authorization checks
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	args := new(service.ExportConfigurationArgs)
+	if err := c.BindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid export args")
+		return
+	}
+
+	content, fileName, err := service.ExportConfiguration(args, ctx.UserName, ctx.Logger)
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "导出", "系统配置-备份", fileName, "", ctx.Logger)
+	c.Writer.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, fileName))
+	c.Data(http.StatusOK, "application/octet-stream", content)
+}
+
+func RestoreConfiguration(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	// authorization checks
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("backup archive is required")
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	defer file.Close()
+
+	overwrite := c.PostForm("overwrite") == "true"
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "恢复", "系统配置-备份", fileHeader.Filename, fmt.Sprintf("overwrite:%t", overwrite), ctx.Logger)
+
+	ctx.Resp, ctx.Err = service.RestoreConfiguration(file, c.PostForm("passphrase"), overwrite, ctx.UserName, ctx.Logger)
+}
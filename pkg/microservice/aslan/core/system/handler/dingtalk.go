@@ -20,6 +20,7 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/dingtalk"
+	workflowservice "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow"
 	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
 	"github.com/koderover/zadig/pkg/tool/log"
 )
@@ -56,3 +57,11 @@ func DingTalkEventHandler(c *gin.Context) {
 	ctx.Resp, ctx.Err = dingtalk.EventHandler(c.Param("ak"), body,
 		c.Query("signature"), c.Query("timestamp"), c.Query("nonce"))
 }
+
+func DingTalkApprovalAction(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	message, err := workflowservice.HandleDingTalkApprovalAction(c.Param("ak"), c.Query("token"), ctx.Logger)
+	ctx.Resp, ctx.Err = map[string]string{"message": message}, err
+}
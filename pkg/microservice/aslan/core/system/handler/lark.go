@@ -19,7 +19,9 @@ package handler
 import (
 	"github.com/gin-gonic/gin"
 
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/lark"
+	workflowservice "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow"
 	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
 )
 
@@ -60,5 +62,8 @@ func LarkEventHandler(c *gin.Context) {
 		c.Param("id"),
 		c.GetHeader("X-Lark-Signature"),
 		c.GetHeader("X-Lark-Request-Timestamp"),
-		c.GetHeader("X-Lark-Request-Nonce"), string(body))
+		c.GetHeader("X-Lark-Request-Nonce"), string(body),
+		func(app *commonmodels.IMApp, event *lark.CardActionEvent) (*lark.CardToast, error) {
+			return workflowservice.HandleLarkApprovalCardAction(app, event, ctx.Logger)
+		})
 }
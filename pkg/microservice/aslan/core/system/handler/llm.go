@@ -31,6 +31,7 @@ type CreateLLMIntegrationRequest struct {
 	Name        string `json:"name"`
 	Token       string `json:"token"`
 	BaseURL     string `json:"base_url"`
+	Model       string `json:"model"`
 	EnableProxy bool   `json:"enable_proxy"`
 }
 
@@ -174,6 +175,7 @@ func convertLLMArgToModel(args *CreateLLMIntegrationRequest) *commonmodels.LLMIn
 		Name:        args.Name,
 		Token:       args.Token,
 		BaseURL:     args.BaseURL,
+		Model:       args.Model,
 		EnableProxy: args.EnableProxy,
 	}
 }
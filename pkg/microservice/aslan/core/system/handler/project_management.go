@@ -17,6 +17,7 @@
 package handler
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -228,13 +229,18 @@ func GetJiraAllStatus(c *gin.Context) {
 func HandleJiraEvent(c *gin.Context) {
 	ctx := internalhandler.NewContext(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
+	payload, err := c.GetRawData()
+	if err != nil {
+		ctx.Err = err
+		return
+	}
 	event := new(jira.Event)
-	if err := c.ShouldBindJSON(event); err != nil {
+	if err := json.Unmarshal(payload, event); err != nil {
 		ctx.Err = err
 		return
 	}
 
-	ctx.Err = service.HandleJiraHookEvent(c.Param("workflowName"), c.Param("hookName"), event, ctx.Logger)
+	ctx.Err = service.HandleJiraHookEvent(c.Param("workflowName"), c.Param("hookName"), event, payload, ctx.Logger)
 }
 
 func HandleMeegoEvent(c *gin.Context) {
@@ -183,6 +183,38 @@ func UpdateRegistryNamespace(c *gin.Context) {
 	ctx.Err = service.UpdateRegistryNamespace(ctx.UserName, c.Param("id"), args, ctx.Logger)
 }
 
+func RotateRegistryCredential(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "更新", "系统设置-Registry-凭证轮换", fmt.Sprintf("registry ID:%s", c.Param("id")), "", ctx.Logger)
+
+	// authorization checks
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	args := new(service.RotateRegistryCredentialArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	if err := args.Validate(); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.RotateRegistryCredential(ctx.UserName, c.Param("id"), args, ctx.Logger)
+}
+
 func DeleteRegistryNamespace(c *gin.Context) {
 	ctx, err := internalhandler.NewContextWithAuthorization(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
@@ -202,6 +234,13 @@ func DeleteRegistryNamespace(c *gin.Context) {
 		return
 	}
 
+	if c.Query("force") != "true" {
+		if err := commonservice.CheckAssetReferences(commonservice.AssetTypeRegistry, "", c.Param("id")); err != nil {
+			ctx.Err = e.ErrAssetReferenced.AddErr(err)
+			return
+		}
+	}
+
 	ctx.Err = service.DeleteRegistryNamespace(c.Param("id"), ctx.Logger)
 }
 
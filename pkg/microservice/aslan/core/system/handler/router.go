@@ -63,6 +63,7 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		registry.GET("/namespaces", ListRegistryNamespaces)
 		registry.POST("/namespaces", CreateRegistryNamespace)
 		registry.PUT("/namespaces/:id", UpdateRegistryNamespace)
+		registry.POST("/namespaces/:id/rotate", RotateRegistryCredential)
 
 		registry.DELETE("/namespaces/:id", DeleteRegistryNamespace)
 		registry.GET("/release/repos", ListAllRepos)
@@ -113,6 +114,13 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		capacity.POST("/clean", CleanCache)
 	}
 
+	// usage and garbage collection of the share storage of the clusters
+	shareStorage := router.Group("sharestorage")
+	{
+		shareStorage.GET("/usage", GetShareStorageUsage)
+		shareStorage.POST("/gc", ShareStorageGC)
+	}
+
 	// workflow concurrency settings
 	concurrency := router.Group("concurrency")
 	{
@@ -220,6 +228,85 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		theme.PUT("", UpdateThemeInfo)
 	}
 
+	// ---------------------------------------------------------------------------------------
+	// email notification templates
+	// ---------------------------------------------------------------------------------------
+	mailNotify := router.Group("mailNotify")
+	{
+		mailNotify.GET("", GetMailNotifySetting)
+		mailNotify.PUT("", UpdateMailNotifySetting)
+	}
+
+	// ---------------------------------------------------------------------------------------
+	// queue wait time alert
+	// ---------------------------------------------------------------------------------------
+	queueAlert := router.Group("queueAlert")
+	{
+		queueAlert.GET("", GetQueueAlertSetting)
+		queueAlert.PUT("", UpdateQueueAlertSetting)
+	}
+
+	webhookRateLimit := router.Group("webhookRateLimit")
+	{
+		webhookRateLimit.GET("", GetWebhookRateLimitSetting)
+		webhookRateLimit.PUT("", UpdateWebhookRateLimitSetting)
+	}
+
+	triggerIPAllowlist := router.Group("triggerIPAllowlist")
+	{
+		triggerIPAllowlist.GET("", GetTriggerIPAllowlistSetting)
+		triggerIPAllowlist.PUT("", UpdateTriggerIPAllowlistSetting)
+	}
+
+	jobPolicy := router.Group("jobPolicy")
+	{
+		jobPolicy.GET("", GetJobPolicySetting)
+		jobPolicy.PUT("", UpdateJobPolicySetting)
+	}
+
+	workflowPolicies := router.Group("workflowPolicies")
+	{
+		workflowPolicies.GET("", ListWorkflowPolicies)
+		workflowPolicies.POST("", CreateWorkflowPolicy)
+		workflowPolicies.GET("/:name", GetWorkflowPolicy)
+		workflowPolicies.PUT("/:name", UpdateWorkflowPolicy)
+		workflowPolicies.DELETE("/:name", DeleteWorkflowPolicy)
+	}
+
+	workflowTrash := router.Group("workflowTrash")
+	{
+		workflowTrash.GET("", GetWorkflowTrashSetting)
+		workflowTrash.PUT("", UpdateWorkflowTrashSetting)
+	}
+
+	trustStore := router.Group("truststore")
+	{
+		trustStore.GET("", GetTrustStoreSetting)
+		trustStore.PUT("", UpdateTrustStoreSetting)
+	}
+
+	backup := router.Group("backup")
+	{
+		backup.POST("/export", ExportConfiguration)
+		backup.POST("/restore", RestoreConfiguration)
+	}
+
+	tenant := router.Group("tenant", isSystemAdmin)
+	{
+		tenant.GET("", ListTenants)
+		tenant.POST("", CreateTenant)
+		tenant.GET("/usage", ListTenantUsages)
+		tenant.GET("/:name", GetTenant)
+		tenant.PUT("/:name", UpdateTenant)
+		tenant.DELETE("/:name", DeleteTenant)
+		tenant.GET("/:name/usage", GetTenantUsage)
+	}
+
+	diagnostics := router.Group("diagnostics", isSystemAdmin)
+	{
+		diagnostics.GET("", GetSystemDiagnostics)
+	}
+
 	// ---------------------------------------------------------------------------------------
 	// external system API
 	// ---------------------------------------------------------------------------------------
@@ -293,6 +380,7 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		dingtalk.GET("/:id/department/:department_id", GetDingTalkDepartment)
 		dingtalk.GET("/:id/user", GetDingTalkUserID)
 		dingtalk.POST("/:ak/webhook", DingTalkEventHandler)
+		dingtalk.GET("/:ak/approval", DingTalkApprovalAction)
 	}
 
 	pm := router.Group("project_management")
@@ -0,0 +1,79 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/system/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+)
+
+// @Summary Get Share Storage Usage
+// @Description Get the usage of the share storage of the clusters grouped by workflow
+// @Tags 	system
+// @Accept 	json
+// @Produce json
+// @Param 	clusterId	query		string								false	"cluster id"
+// @Success 200 		{array} 	service.ShareStorageUsageReport
+// @Router /api/aslan/system/sharestorage/usage [get]
+func GetShareStorageUsage(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	// authorization checks
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.GetShareStorageUsage(c.Query("clusterId"))
+}
+
+// @Summary Share Storage Garbage Collection
+// @Description Refresh the usage of the share storage and remove the task dirs exceeding the retention or the quota
+// @Tags 	system
+// @Accept 	json
+// @Produce json
+// @Success 200
+// @Router /api/aslan/system/sharestorage/gc [post]
+func ShareStorageGC(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	// authorization checks
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	service.TriggerShareStorageGC()
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/backup"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+type ExportConfigurationArgs struct {
+	Passphrase string `json:"passphrase"`
+}
+
+// ExportConfiguration returns the configuration archive and its file name
+func ExportConfiguration(args *ExportConfigurationArgs, userName string, logger *zap.SugaredLogger) ([]byte, string, error) {
+	if args.Passphrase == "" {
+		return nil, "", e.ErrInvalidParam.AddDesc("passphrase is required")
+	}
+
+	buf := new(bytes.Buffer)
+	manifest, err := backup.Export(context.Background(), buf, &backup.Options{Passphrase: args.Passphrase, Operator: userName})
+	if err != nil {
+		logger.Errorf("failed to export the configuration, err: %s", err)
+		return nil, "", e.ErrBackupConfiguration.AddErr(err)
+	}
+	return buf.Bytes(), fmt.Sprintf("zadig-backup-%s.tar.gz", time.Unix(manifest.CreateTime, 0).Format("20060102150405")), nil
+}
+
+func RestoreConfiguration(archive io.Reader, passphrase string, overwrite bool, userName string, logger *zap.SugaredLogger) (*backup.RestoreResult, error) {
+	if passphrase == "" {
+		return nil, e.ErrInvalidParam.AddDesc("passphrase is required")
+	}
+
+	result, err := backup.Restore(context.Background(), archive, &backup.Options{Passphrase: passphrase, Operator: userName, Overwrite: overwrite})
+	if err != nil {
+		logger.Errorf("failed to restore the configuration, err: %s", err)
+		return result, e.ErrRestoreConfiguration.AddErr(err)
+	}
+	logger.Infof("configuration backup created at %d is restored by %s", result.Manifest.CreateTime, userName)
+	return result, nil
+}
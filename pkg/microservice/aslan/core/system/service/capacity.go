@@ -320,7 +320,7 @@ func handleWorkflowTaskV4Retention(dryRun bool, batch int, option *commonrepo.Li
 	var removeIds []string
 
 	for {
-		staleTasks, _, err := commonrepo.NewworkflowTaskv4Coll().List(option)
+		staleTasks, _, err := commonrepo.NewWorkflowTaskV4Store().List(option)
 		if err != nil {
 			return 0, err
 		}
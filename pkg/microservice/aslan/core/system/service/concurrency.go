@@ -21,14 +21,9 @@ import (
 
 	"go.uber.org/zap"
 
-	configbase "github.com/koderover/zadig/pkg/config"
-	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller"
 	workflowservice "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow"
-	"github.com/koderover/zadig/pkg/setting"
-	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
-	e "github.com/koderover/zadig/pkg/tool/errors"
-	"github.com/koderover/zadig/pkg/tool/kube/updater"
 )
 
 func GetWorkflowConcurrency() (*WorkflowConcurrencySettings, error) {
@@ -48,16 +43,14 @@ func UpdateWorkflowConcurrency(workflowConcurrency, buildConcurrency int64, log
 	if len(tasks) > 0 {
 		return errors.New("workflow settings must be set when NO task is running")
 	}
-	// first update system configuration table
-	err := commonrepo.NewSystemSettingColl().UpdateConcurrencySetting(workflowConcurrency, buildConcurrency)
-	if err != nil {
-		log.Errorf("Failed to update system settings, the error is: %s", err)
+	// the concurrency set by the admin is not scaled back by the queue alert
+	if err := commonrepo.NewSystemSettingColl().UpdateQueueAlertScaledFrom(0); err != nil {
+		log.Errorf("Failed to reset the scaled concurrency of the queue alert, the error is: %s", err)
 		return err
 	}
-	// then we update the warpdrive deployment, where the workflow concurrency happens
-	kubeClient, err := kubeclient.GetKubeClient(config.HubServerAddress(), setting.LocalClusterID)
-	if err != nil {
-		return e.ErrScaleService.AddErr(err)
+	if err := workflowcontroller.ScaleWarpdrive(workflowConcurrency, buildConcurrency); err != nil {
+		log.Errorf("Failed to update workflow concurrency, the error is: %s", err)
+		return err
 	}
-	return updater.ScaleDeployment(config.Namespace(), configbase.WarpDriveServiceName(), int(workflowConcurrency), kubeClient)
+	return nil
 }
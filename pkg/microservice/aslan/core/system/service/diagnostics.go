@@ -0,0 +1,287 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/s3"
+	"github.com/koderover/zadig/pkg/setting"
+	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
+	"github.com/koderover/zadig/pkg/tool/kube/multicluster"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+	s3tool "github.com/koderover/zadig/pkg/tool/s3"
+)
+
+type DiagnosticStatus string
+
+const (
+	DiagnosticStatusOK      DiagnosticStatus = "ok"
+	DiagnosticStatusWarning DiagnosticStatus = "warning"
+	DiagnosticStatusError   DiagnosticStatus = "error"
+
+	diagnosticCheckTimeout = 10 * time.Second
+	// mongoLatencyWarning is the ping latency above which the database is reported as slow
+	mongoLatencyWarning = 500 * time.Millisecond
+)
+
+const (
+	DiagnosticCategoryMongoDB       = "mongodb"
+	DiagnosticCategoryObjectStorage = "object_storage"
+	DiagnosticCategoryCluster       = "cluster"
+	DiagnosticCategoryIMApp         = "im_app"
+	DiagnosticCategoryWebhook       = "webhook"
+)
+
+// DiagnosticsReport is the result of the system self-diagnostics, Status is the worst status of all the checks
+type DiagnosticsReport struct {
+	Status    DiagnosticStatus   `json:"status"`
+	CheckTime int64              `json:"check_time"`
+	Checks    []*DiagnosticCheck `json:"checks"`
+}
+
+type DiagnosticCheck struct {
+	Category string           `json:"category"`
+	Name     string           `json:"name"`
+	Target   string           `json:"target"`
+	Status   DiagnosticStatus `json:"status"`
+	// Latency is the duration of the check in milliseconds
+	Latency int64  `json:"latency"`
+	Message string `json:"message"`
+}
+
+type diagnosticFunc func(ctx context.Context) []*DiagnosticCheck
+
+// GetSystemDiagnostics runs all the checks concurrently and returns the report
+func GetSystemDiagnostics(logger *zap.SugaredLogger) *DiagnosticsReport {
+	funcs := []diagnosticFunc{
+		diagnoseMongoDB,
+		diagnoseObjectStorage,
+		diagnoseClusters,
+		diagnoseIMApps,
+		diagnoseWebhook,
+	}
+
+	results := make([][]*DiagnosticCheck, len(funcs))
+	wg := sync.WaitGroup{}
+	for i, f := range funcs {
+		wg.Add(1)
+		go func(i int, f diagnosticFunc) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), diagnosticCheckTimeout)
+			defer cancel()
+			results[i] = f(ctx)
+		}(i, f)
+	}
+	wg.Wait()
+
+	report := &DiagnosticsReport{
+		Status:    DiagnosticStatusOK,
+		CheckTime: time.Now().Unix(),
+		Checks:    make([]*DiagnosticCheck, 0),
+	}
+	for _, checks := range results {
+		for _, check := range checks {
+			if check.Status == DiagnosticStatusError {
+				report.Status = DiagnosticStatusError
+				logger.Warnf("diagnostic check %s/%s failed: %s", check.Category, check.Name, check.Message)
+			} else if check.Status == DiagnosticStatusWarning && report.Status == DiagnosticStatusOK {
+				report.Status = DiagnosticStatusWarning
+			}
+			report.Checks = append(report.Checks, check)
+		}
+	}
+	return report
+}
+
+// runDiagnosticCheck times the check function and fills the status from its error
+func runDiagnosticCheck(check *DiagnosticCheck, f func() error) *DiagnosticCheck {
+	start := time.Now()
+	err := f()
+	check.Latency = time.Since(start).Milliseconds()
+	if err != nil {
+		check.Status = DiagnosticStatusError
+		check.Message = err.Error()
+		return check
+	}
+	if check.Status == "" {
+		check.Status = DiagnosticStatusOK
+	}
+	return check
+}
+
+func diagnoseMongoDB(ctx context.Context) []*DiagnosticCheck {
+	check := runDiagnosticCheck(&DiagnosticCheck{
+		Category: DiagnosticCategoryMongoDB,
+		Name:     config.MongoDatabase(),
+	}, func() error {
+		return mongotool.Ping(ctx)
+	})
+	if check.Status == DiagnosticStatusOK && time.Duration(check.Latency)*time.Millisecond > mongoLatencyWarning {
+		check.Status = DiagnosticStatusWarning
+		check.Message = fmt.Sprintf("ping latency %dms exceeds %s", check.Latency, mongoLatencyWarning)
+	}
+	return []*DiagnosticCheck{check}
+}
+
+func diagnoseObjectStorage(_ context.Context) []*DiagnosticCheck {
+	storage, err := commonrepo.NewS3StorageColl().FindDefault()
+	if err != nil {
+		return []*DiagnosticCheck{{
+			Category: DiagnosticCategoryObjectStorage,
+			Name:     "default",
+			Status:   DiagnosticStatusWarning,
+			Message:  fmt.Sprintf("no default object storage found: %s", err),
+		}}
+	}
+
+	s3Storage := &s3.S3{S3Storage: storage}
+	return []*DiagnosticCheck{runDiagnosticCheck(&DiagnosticCheck{
+		Category: DiagnosticCategoryObjectStorage,
+		Name:     storage.Bucket,
+		Target:   storage.Endpoint,
+	}, func() error {
+		forcedPathStyle := true
+		if s3Storage.Provider == setting.ProviderSourceAli {
+			forcedPathStyle = false
+		}
+		client, err := s3tool.NewClient(s3Storage.Endpoint, s3Storage.Ak, s3Storage.Sk, s3Storage.Region, s3Storage.Insecure, forcedPathStyle)
+		if err != nil {
+			return fmt.Errorf("failed to create s3 client: %s", err)
+		}
+		return client.ValidateBucket(s3Storage.Bucket)
+	})}
+}
+
+// diagnoseClusters checks the hub-server session of the agent clusters and the api server of all the clusters
+func diagnoseClusters(_ context.Context) []*DiagnosticCheck {
+	clusters, err := commonrepo.NewK8SClusterColl().List(nil)
+	if err != nil {
+		return []*DiagnosticCheck{{
+			Category: DiagnosticCategoryCluster,
+			Status:   DiagnosticStatusError,
+			Message:  fmt.Sprintf("failed to list clusters: %s", err),
+		}}
+	}
+
+	checks := make([]*DiagnosticCheck, len(clusters))
+	wg := sync.WaitGroup{}
+	for i, cluster := range clusters {
+		wg.Add(1)
+		go func(i int, cluster *commonmodels.K8SCluster) {
+			defer wg.Done()
+			checks[i] = diagnoseCluster(cluster)
+		}(i, cluster)
+	}
+	wg.Wait()
+	return checks
+}
+
+func diagnoseCluster(cluster *commonmodels.K8SCluster) *DiagnosticCheck {
+	clusterID := cluster.ID.Hex()
+	check := &DiagnosticCheck{
+		Category: DiagnosticCategoryCluster,
+		Name:     cluster.Name,
+		Target:   clusterID,
+	}
+	if cluster.Status == setting.Disconnected {
+		check.Status = DiagnosticStatusWarning
+		check.Message = "cluster is disconnected"
+		return check
+	}
+
+	return runDiagnosticCheck(check, func() error {
+		if !cluster.Local && cluster.Type != setting.KubeConfigClusterType {
+			hubClient, err := multicluster.NewHubClient(config.HubServerAddress())
+			if err != nil {
+				return fmt.Errorf("failed to create hub-server client: %s", err)
+			}
+			if err := hubClient.HasSession(clusterID); err != nil {
+				return fmt.Errorf("agent is not connected to hub-server: %s", err)
+			}
+		}
+
+		discoveryClient, err := kubeclient.GetDiscoveryClient(config.HubServerAddress(), clusterID)
+		if err != nil {
+			return fmt.Errorf("failed to create kube client: %s", err)
+		}
+		version, err := discoveryClient.ServerVersion()
+		if err != nil {
+			return fmt.Errorf("failed to reach the api server: %s", err)
+		}
+		check.Message = version.GitVersion
+		return nil
+	})
+}
+
+func diagnoseIMApps(ctx context.Context) []*DiagnosticCheck {
+	apps, err := commonrepo.NewIMAppColl().List(ctx, "")
+	if err != nil {
+		return []*DiagnosticCheck{{
+			Category: DiagnosticCategoryIMApp,
+			Status:   DiagnosticStatusError,
+			Message:  fmt.Sprintf("failed to list im apps: %s", err),
+		}}
+	}
+
+	checks := make([]*DiagnosticCheck, 0, len(apps))
+	for _, app := range apps {
+		app := app
+		checks = append(checks, runDiagnosticCheck(&DiagnosticCheck{
+			Category: DiagnosticCategoryIMApp,
+			Name:     app.Name,
+			Target:   app.Type,
+		}, func() error {
+			return ValidateIMApp(app, nil)
+		}))
+	}
+	return checks
+}
+
+// diagnoseWebhook checks that the webhook address exposed to the code hosts can be reached, any response
+// other than a server error means the address is routed to zadig
+func diagnoseWebhook(ctx context.Context) []*DiagnosticCheck {
+	webhookURL := config.WebHookURL()
+	return []*DiagnosticCheck{runDiagnosticCheck(&DiagnosticCheck{
+		Category: DiagnosticCategoryWebhook,
+		Name:     "webhook",
+		Target:   webhookURL,
+	}, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, webhookURL, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+		}
+		return nil
+	})}
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/jobpolicy"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func GetJobPolicySetting(logger *zap.SugaredLogger) (*models.JobPolicyConfig, error) {
+	systemSetting, err := commonrepo.NewSystemSettingColl().Get()
+	if err != nil {
+		logger.Errorf("failed to get system setting, err: %s", err)
+		return nil, e.ErrInternalError.AddErr(err)
+	}
+	if systemSetting.JobPolicy == nil {
+		return &models.JobPolicyConfig{
+			AllowedImages:        make([]string, 0),
+			AllowedDeployTargets: make([]*models.JobPolicyDeployTarget, 0),
+		}, nil
+	}
+	return systemSetting.JobPolicy, nil
+}
+
+func UpdateJobPolicySetting(args *models.JobPolicyConfig, logger *zap.SugaredLogger) error {
+	if err := jobpolicy.Validate(args); err != nil {
+		return e.ErrInvalidParam.AddErr(err)
+	}
+	if err := commonrepo.NewSystemSettingColl().UpdateJobPolicySetting(args); err != nil {
+		logger.Errorf("failed to update job policy setting, err: %s", err)
+		return e.ErrInternalError.AddErr(err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/instantmessage"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func GetMailNotifySetting(logger *zap.SugaredLogger) (*models.MailNotifyConfig, error) {
+	systemSetting, err := commonrepo.NewSystemSettingColl().Get()
+	if err != nil {
+		logger.Errorf("failed to get system setting, err: %s", err)
+		return nil, e.ErrInternalError.AddErr(err)
+	}
+	if systemSetting.MailNotify == nil {
+		return &models.MailNotifyConfig{}, nil
+	}
+	return systemSetting.MailNotify, nil
+}
+
+func UpdateMailNotifySetting(args *models.MailNotifyConfig, logger *zap.SugaredLogger) error {
+	if err := instantmessage.ValidateMailTemplate(args.TaskTemplate); err != nil {
+		return e.ErrInvalidParam.AddDesc("invalid task template: " + err.Error())
+	}
+	if err := instantmessage.ValidateMailTemplate(args.ApprovalTemplate); err != nil {
+		return e.ErrInvalidParam.AddDesc("invalid approval template: " + err.Error())
+	}
+	if err := commonrepo.NewSystemSettingColl().UpdateMailNotifySetting(args); err != nil {
+		logger.Errorf("failed to update mail notify setting, err: %s", err)
+		return e.ErrInternalError.AddErr(err)
+	}
+	return nil
+}
@@ -19,9 +19,11 @@ package service
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonutil "github.com/koderover/zadig/pkg/microservice/aslan/core/common/util"
 	e "github.com/koderover/zadig/pkg/tool/errors"
 	"github.com/koderover/zadig/pkg/tool/guanceyun"
 )
@@ -64,6 +66,8 @@ func ValidateObservability(args *models.Observability) error {
 	switch args.Type {
 	case "guanceyun":
 		return validateGuanceyun(args)
+	case commonutil.ObservabilityTypePrometheus, commonutil.ObservabilityTypeGrafana:
+		return validatePrometheus(args)
 	default:
 		return errors.New("invalid observability type")
 	}
@@ -73,3 +77,12 @@ func validateGuanceyun(args *models.Observability) error {
 	_, _, err := guanceyun.NewClient(args.Host, args.ApiKey).ListMonitor("", 1, 1)
 	return err
 }
+
+func validatePrometheus(args *models.Observability) error {
+	client, err := commonutil.NewPrometheusClient(args)
+	if err != nil {
+		return err
+	}
+	_, _, err = client.QueryValue("vector(1)", time.Now())
+	return err
+}
@@ -24,6 +24,7 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/samber/lo"
+	"github.com/tidwall/gjson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/zap"
 
@@ -218,7 +219,9 @@ func SearchJiraProjectIssuesWithJQL(id, project, jql, summary string) ([]*jira.I
 	return jira.NewJiraClientWithAuthType(info.JiraHost, info.JiraUser, info.JiraToken, info.JiraPersonalAccessToken, info.JiraAuthType).Issue.SearchByJQL(jql, true)
 }
 
-func HandleJiraHookEvent(workflowName, hookName string, event *jira.Event, logger *zap.SugaredLogger) error {
+// HandleJiraHookEvent creates a workflow task for the jira event if the issue matches the JQL of the hook,
+// payload is the raw body of the event where the fields mapped into the workflow params are read
+func HandleJiraHookEvent(workflowName, hookName string, event *jira.Event, payload []byte, logger *zap.SugaredLogger) error {
 	if event.Issue == nil || event.Issue.Key == "" {
 		logger.Errorf("HandleJiraHookEvent: nil issue or issue key, skip")
 		return nil
@@ -246,6 +249,19 @@ func HandleJiraHookEvent(workflowName, hookName string, event *jira.Event, logge
 		logger.Error(errMsg)
 		return errors.New(errMsg)
 	}
+	if jiraHook.JQL != "" {
+		matched, err := matchJiraHookJQL(jiraHook, event.Issue.Key)
+		if err != nil {
+			errMsg := fmt.Sprintf("HandleJiraHookEvent: failed to search issue %s with jql: %s", event.Issue.Key, err)
+			logger.Error(errMsg)
+			return errors.New(errMsg)
+		}
+		if !matched {
+			logger.Infof("HandleJiraHookEvent: issue %s does not match the jql of hook %s, skip", event.Issue.Key, hookName)
+			return nil
+		}
+	}
+	setJiraHookParams(jiraHook, payload)
 	taskInfo, err := workflow.CreateWorkflowTaskV4ByBuildInTrigger(setting.JiraHookTaskCreator, jiraHook.WorkflowArg, logger)
 	if err != nil {
 		errMsg := fmt.Sprintf("HandleJiraHookEvent: failed to create workflow task: %s", err)
@@ -260,7 +276,7 @@ func HandleJiraHookEvent(workflowName, hookName string, event *jira.Event, logge
 	go func() {
 		for {
 			time.Sleep(5 * time.Second)
-			task, err := mongodb.NewworkflowTaskv4Coll().Find(taskInfo.WorkflowName, taskInfo.TaskID)
+			task, err := mongodb.NewWorkflowTaskV4Store().Find(taskInfo.WorkflowName, taskInfo.TaskID)
 			if err != nil {
 				log.Errorf("HandleJiraHookEventWaiter: failed to find task %s-%d, err: %v", taskInfo.WorkflowName, taskInfo.TaskID, err)
 				return
@@ -323,6 +339,13 @@ func HandleMeegoHookEvent(workflowName, hookName string, event *meego.GeneralWeb
 		logger.Error(errMsg)
 		return errors.New(errMsg)
 	}
+	meegoHook.WorkflowArg.MeegoWorkItems = []*models.MeegoWorkItemLink{{
+		MeegoID:         meegoHook.MeegoID,
+		ProjectKey:      event.Payload.ProjectKey,
+		WorkItemTypeKey: event.Payload.WorkItemTypeKey,
+		ID:              event.Payload.ID,
+		Name:            event.Payload.Name,
+	}}
 	taskInfo, err := workflow.CreateWorkflowTaskV4ByBuildInTrigger(setting.MeegoHookTaskCreator, meegoHook.WorkflowArg, logger)
 	if err != nil {
 		errMsg := fmt.Sprintf("HandleMeegoHookEvent: failed to create workflow task: %s", err)
@@ -334,6 +357,10 @@ func HandleMeegoHookEvent(workflowName, hookName string, event *meego.GeneralWeb
 		"workflow", workflowName,
 		"hook", hookName,
 	).Infof("HandleMeegoHookEvent: create workflow success")
+	// the work item is commented by the meego sync of the workflow after the task completes
+	if meegoSync := workflowInfo.MeegoSync; meegoSync != nil && meegoSync.Enabled && meegoSync.Comment && meegoSync.MeegoID == meegoHook.MeegoID {
+		return nil
+	}
 	go func() {
 		meegoInfo, err := mongodb.NewProjectManagementColl().GetMeegoByID(meegoHook.MeegoID)
 		if err != nil {
@@ -347,7 +374,7 @@ func HandleMeegoHookEvent(workflowName, hookName string, event *meego.GeneralWeb
 		}
 		for {
 			time.Sleep(5 * time.Second)
-			task, err := mongodb.NewworkflowTaskv4Coll().Find(taskInfo.WorkflowName, taskInfo.TaskID)
+			task, err := mongodb.NewWorkflowTaskV4Store().Find(taskInfo.WorkflowName, taskInfo.TaskID)
 			if err != nil {
 				log.Errorf("HandleMeegoHookEventWaiter: failed to find task %s-%d, err: %v", taskInfo.WorkflowName, taskInfo.TaskID, err)
 				return
@@ -393,3 +420,49 @@ func checkType(_type string) error {
 	}
 	return nil
 }
+
+// matchJiraHookJQL checks if the issue is found by the JQL of the hook
+func matchJiraHookJQL(hook *models.JiraHook, issueKey string) (bool, error) {
+	var info *models.ProjectManagement
+	var err error
+	if hook.JiraID != "" {
+		info, err = mongodb.NewProjectManagementColl().GetJiraByID(hook.JiraID)
+	} else {
+		info, err = mongodb.NewProjectManagementColl().GetJira()
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "get jira info")
+	}
+	jql := fmt.Sprintf(`issueKey = "%s" AND (%s)`, issueKey, hook.JQL)
+	issues, err := jira.NewJiraClientWithAuthType(info.JiraHost, info.JiraUser, info.JiraToken, info.JiraPersonalAccessToken, info.JiraAuthType).Issue.SearchByJQL(jql, false)
+	if err != nil {
+		return false, err
+	}
+	return len(issues) > 0, nil
+}
+
+// setJiraHookParams sets the values at the json paths of the field mappings to the workflow params,
+// the params of the missing fields are not changed
+func setJiraHookParams(hook *models.JiraHook, payload []byte) {
+	values := make(map[string]string)
+	for _, mapping := range hook.FieldMappings {
+		result := gjson.GetBytes(payload, mapping.JSONPath)
+		if !result.Exists() {
+			continue
+		}
+		if result.IsArray() {
+			items := make([]string, 0)
+			for _, item := range result.Array() {
+				items = append(items, item.String())
+			}
+			values[mapping.Param] = strings.Join(items, ",")
+			continue
+		}
+		values[mapping.Param] = result.String()
+	}
+	for _, param := range hook.WorkflowArg.Params {
+		if value, ok := values[param.Name]; ok {
+			param.Value = value
+		}
+	}
+}
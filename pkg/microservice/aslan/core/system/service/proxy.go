@@ -17,11 +17,13 @@ limitations under the License.
 package service
 
 import (
+	"fmt"
 	"net/http"
 	"net/url"
 	"time"
 
 	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	conf "github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
@@ -31,24 +33,55 @@ import (
 
 func SetProxyConfig() {
 	proxies, err := commonrepo.NewProxyColl().List(&commonrepo.ProxyArgs{})
-	if err != nil || len(proxies) == 0 {
+	if err != nil {
 		return
 	}
-
-	if !proxies[0].EnableRepoProxy {
+	if len(proxies) == 0 {
 		conf.SetProxy("", "", "")
 		return
 	}
 
-	url := proxies[0].GetProxyURL()
-
-	if proxies[0].Type == "http" {
+	// the code hosts use the repo proxy
+	url, proxyType := proxies[0].ResolveIntegrationProxy(commonmodels.ProxyIntegrationCodeHost)
+	switch {
+	case url == "":
+		conf.SetProxy("", "", "")
+	case proxyType == "http":
 		conf.SetProxy(url, url, "")
-	} else if proxies[0].Type == "socks5" {
+	case proxyType == "socks5":
 		conf.SetProxy(url, "", url)
 	}
 }
 
+func validateIntegrationProxies(proxy *commonmodels.Proxy) error {
+	integrations := sets.NewString()
+	for _, override := range proxy.IntegrationProxies {
+		switch override.Integration {
+		case commonmodels.ProxyIntegrationCodeHost, commonmodels.ProxyIntegrationRegistry, commonmodels.ProxyIntegrationIM, commonmodels.ProxyIntegrationWebhook:
+		default:
+			return fmt.Errorf("unsupported integration %q", override.Integration)
+		}
+		if integrations.Has(override.Integration) {
+			return fmt.Errorf("duplicated proxy of integration %s", override.Integration)
+		}
+		integrations.Insert(override.Integration)
+
+		switch override.Mode {
+		case commonmodels.IntegrationProxyModeDirect:
+		case commonmodels.IntegrationProxyModeCustom:
+			if override.Type != "http" && override.Type != "socks5" {
+				return fmt.Errorf("proxy type of integration %s must be http or socks5", override.Integration)
+			}
+			if override.Address == "" || override.Port <= 0 {
+				return fmt.Errorf("proxy address and port of integration %s are required", override.Integration)
+			}
+		default:
+			return fmt.Errorf("proxy mode of integration %s must be custom or direct", override.Integration)
+		}
+	}
+	return nil
+}
+
 func ListProxies(log *zap.SugaredLogger) ([]*commonmodels.Proxy, error) {
 	resp, err := commonrepo.NewProxyColl().List(&commonrepo.ProxyArgs{})
 	if err != nil {
@@ -68,6 +101,10 @@ func GetProxy(id string, log *zap.SugaredLogger) (*commonmodels.Proxy, error) {
 }
 
 func CreateProxy(args *commonmodels.Proxy, log *zap.SugaredLogger) error {
+	if err := validateIntegrationProxies(args); err != nil {
+		return e.ErrCreateProxy.AddErr(err)
+	}
+
 	err := commonrepo.NewProxyColl().Create(args)
 	if err != nil {
 		log.Errorf("Proxy.Create error: %v", err)
@@ -81,6 +118,10 @@ func CreateProxy(args *commonmodels.Proxy, log *zap.SugaredLogger) error {
 }
 
 func UpdateProxy(id string, args *commonmodels.Proxy, log *zap.SugaredLogger) error {
+	if err := validateIntegrationProxies(args); err != nil {
+		return e.ErrUpdateProxy.AddErr(err)
+	}
+
 	err := commonrepo.NewProxyColl().Update(id, args)
 	if err != nil {
 		log.Errorf("Proxy.Update %s error: %v", id, err)
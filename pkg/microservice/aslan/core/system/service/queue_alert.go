@@ -0,0 +1,61 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"net/url"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func GetQueueAlertSetting(logger *zap.SugaredLogger) (*models.QueueAlertConfig, error) {
+	systemSetting, err := commonrepo.NewSystemSettingColl().Get()
+	if err != nil {
+		logger.Errorf("failed to get system setting, err: %s", err)
+		return nil, e.ErrInternalError.AddErr(err)
+	}
+	if systemSetting.QueueAlert == nil {
+		return &models.QueueAlertConfig{}, nil
+	}
+	return systemSetting.QueueAlert, nil
+}
+
+func UpdateQueueAlertSetting(args *models.QueueAlertConfig, logger *zap.SugaredLogger) error {
+	if args.Enabled && args.Threshold <= 0 {
+		return e.ErrInvalidParam.AddDesc("threshold must be greater than 0")
+	}
+	if args.Cooldown < 0 {
+		return e.ErrInvalidParam.AddDesc("cooldown can not be negative")
+	}
+	if args.AutoscalingWebhook != "" {
+		if u, err := url.Parse(args.AutoscalingWebhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return e.ErrInvalidParam.AddDesc("invalid autoscaling webhook")
+		}
+	}
+	if args.ScaleWarpdrive && args.MaxConcurrency <= 0 {
+		return e.ErrInvalidParam.AddDesc("max concurrency must be greater than 0 when scaling warpdrive")
+	}
+	if err := commonrepo.NewSystemSettingColl().UpdateQueueAlertSetting(args); err != nil {
+		logger.Errorf("failed to update queue alert setting, err: %s", err)
+		return e.ErrInternalError.AddErr(err)
+	}
+	return nil
+}
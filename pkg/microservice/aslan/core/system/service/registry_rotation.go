@@ -0,0 +1,200 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/registry"
+	"github.com/koderover/zadig/pkg/setting"
+	kubeclient "github.com/koderover/zadig/pkg/shared/kube/client"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/kube/getter"
+)
+
+type RotateRegistryCredentialArgs struct {
+	AccessKey string `json:"access_key"`
+	SecretKey string `json:"secret_key"`
+	// TestImage is an image in the namespace of the registry, in the format of name:tag, it is pulled
+	// with the new credential before the credential is switched
+	TestImage string `json:"test_image"`
+}
+
+type RegistryRotationResult struct {
+	RegistryID string                    `json:"registry_id"`
+	Secrets    []*RegistrySecretRotation `json:"secrets"`
+	Failed     int                       `json:"failed"`
+}
+
+type RegistrySecretRotation struct {
+	ProjectName string `json:"project_name"`
+	EnvName     string `json:"env_name"`
+	ClusterID   string `json:"cluster_id"`
+	Namespace   string `json:"namespace"`
+	SecretName  string `json:"secret_name"`
+	Error       string `json:"error,omitempty"`
+}
+
+func (args *RotateRegistryCredentialArgs) Validate() error {
+	if args.AccessKey == "" || args.SecretKey == "" {
+		return fmt.Errorf("access key and secret key are required")
+	}
+	name, tag, found := strings.Cut(args.TestImage, ":")
+	if !found || name == "" || tag == "" {
+		return fmt.Errorf("test image must be in the format of name:tag")
+	}
+	return nil
+}
+
+// RotateRegistryCredential verifies that the new credential can pull the test image, then saves it and
+// updates the image pull secrets in place in all the environment namespaces. The secrets keep their names
+// so that the workloads and the running deployments referencing them are not affected.
+func RotateRegistryCredential(username, id string, args *RotateRegistryCredentialArgs, log *zap.SugaredLogger) (*RegistryRotationResult, error) {
+	reg, err := commonrepo.NewRegistryNamespaceColl().Find(&commonrepo.FindRegOps{ID: id})
+	if err != nil {
+		return nil, e.ErrFindRegistry.AddErr(err)
+	}
+
+	reg.AccessKey = args.AccessKey
+	reg.SecretKey = args.SecretKey
+	if err := verifyRegistryPullAccess(reg, args.TestImage, log); err != nil {
+		log.Errorf("failed to verify the new credential of registry %s, err: %s", reg.RegAddr, err)
+		return nil, e.ErrRotateRegistryCredential.AddDesc(fmt.Sprintf("failed to pull the test image with the new credential: %s", err))
+	}
+
+	reg.UpdateBy = username
+	if err := commonrepo.NewRegistryNamespaceColl().Update(id, reg); err != nil {
+		log.Errorf("failed to update registry %s, err: %s", reg.RegAddr, err)
+		return nil, e.ErrRotateRegistryCredential.AddErr(err)
+	}
+
+	// the real credential differs from the stored one for some cloud providers
+	realReg, _, err := commonservice.FindRegistryById(id, true, log)
+	if err != nil {
+		return nil, e.ErrRotateRegistryCredential.AddErr(err)
+	}
+
+	result, err := propagateRegistryCredential(realReg, log)
+	if err != nil {
+		return nil, e.ErrRotateRegistryCredential.AddErr(err)
+	}
+	if err := SyncDinDForRegistries(); err != nil {
+		log.Warnf("failed to sync dind for registries, err: %s", err)
+	}
+	return result, nil
+}
+
+func verifyRegistryPullAccess(reg *commonmodels.RegistryNamespace, testImage string, log *zap.SugaredLogger) error {
+	var regService registry.Service
+	if reg.AdvancedSetting != nil {
+		regService = registry.NewV2Service(reg.RegProvider, reg.AdvancedSetting.TLSEnabled, reg.AdvancedSetting.TLSCert)
+	} else {
+		regService = registry.NewV2Service(reg.RegProvider, true, "")
+	}
+
+	name, tag, _ := strings.Cut(testImage, ":")
+	_, err := regService.GetImageInfo(registry.GetRepoImageDetailOption{
+		Endpoint: registry.Endpoint{
+			Addr:      reg.RegAddr,
+			Ak:        reg.AccessKey,
+			Sk:        reg.SecretKey,
+			Namespace: reg.Namespace,
+			Region:    reg.Region,
+		},
+		Image: name,
+		Tag:   tag,
+	}, log)
+	return err
+}
+
+// propagateRegistryCredential updates the default pull secret of the environments using the registry and
+// the registry specific pull secret wherever it has been created
+func propagateRegistryCredential(reg *commonmodels.RegistryNamespace, log *zap.SugaredLogger) (*RegistryRotationResult, error) {
+	envs, err := commonrepo.NewProductColl().List(&commonrepo.ProductListOptions{
+		ExcludeStatus: []string{setting.ProductStatusDeleting},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list environments: %s", err)
+	}
+
+	registrySecretName, err := kube.GenRegistrySecretName(reg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate registry secret name: %s", err)
+	}
+
+	result := &RegistryRotationResult{
+		RegistryID: reg.ID.Hex(),
+		Secrets:    make([]*RegistrySecretRotation, 0),
+	}
+	for _, env := range envs {
+		if env.Namespace == "" {
+			continue
+		}
+		usesRegistry := env.RegistryID == reg.ID.Hex() || (env.RegistryID == "" && reg.IsDefault)
+
+		kubeClient, err := kubeclient.GetKubeClient(config.HubServerAddress(), env.ClusterID)
+		if err != nil {
+			if usesRegistry {
+				result.add(env, setting.DefaultImagePullSecret, fmt.Errorf("failed to get kube client: %s", err))
+			}
+			continue
+		}
+
+		if usesRegistry {
+			err := kube.CreateOrUpdateDefaultRegistrySecret(env.Namespace, reg, kubeClient)
+			result.add(env, setting.DefaultImagePullSecret, err)
+		}
+		if registrySecretName == setting.DefaultImagePullSecret {
+			continue
+		}
+		if _, found, err := getter.GetSecret(env.Namespace, registrySecretName, kubeClient); err != nil || !found {
+			continue
+		}
+		err = kube.CreateOrUpdateRegistrySecret(env.Namespace, reg, false, kubeClient)
+		result.add(env, registrySecretName, err)
+	}
+
+	for _, item := range result.Secrets {
+		if item.Error != "" {
+			log.Errorf("failed to update secret %s in namespace %s of cluster %s, err: %s", item.SecretName, item.Namespace, item.ClusterID, item.Error)
+		}
+	}
+	return result, nil
+}
+
+func (r *RegistryRotationResult) add(env *commonmodels.Product, secretName string, err error) {
+	item := &RegistrySecretRotation{
+		ProjectName: env.ProductName,
+		EnvName:     env.EnvName,
+		ClusterID:   env.ClusterID,
+		Namespace:   env.Namespace,
+		SecretName:  secretName,
+	}
+	if err != nil {
+		item.Error = err.Error()
+		r.Failed++
+	}
+	r.Secrets = append(r.Secrets, item)
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+type ShareStorageUsageReport struct {
+	ClusterID      string                       `json:"cluster_id"`
+	ClusterName    string                       `json:"cluster_name"`
+	PVC            string                       `json:"pvc"`
+	RetentionHours int                          `json:"retention_hours"`
+	QuotaInMiB     int64                        `json:"quota_in_mib"`
+	TotalInKiB     int64                        `json:"total_in_kib"`
+	Workflows      []*WorkflowShareStorageUsage `json:"workflows"`
+}
+
+type WorkflowShareStorageUsage struct {
+	WorkflowName string                            `json:"workflow_name"`
+	ProjectName  string                            `json:"project_name"`
+	TotalInKiB   int64                             `json:"total_in_kib"`
+	Tasks        []*commonmodels.ShareStorageUsage `json:"tasks"`
+}
+
+// GetShareStorageUsage returns the usage of the share storage of the clusters measured by the last garbage collection,
+// grouped by workflow
+func GetShareStorageUsage(clusterID string) ([]*ShareStorageUsageReport, error) {
+	clusters, err := commonrepo.NewK8SClusterColl().List(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clusters: %s", err)
+	}
+	resp := make([]*ShareStorageUsageReport, 0)
+	for _, cluster := range clusters {
+		if cluster.ShareStorage.MediumType != types.NFSMedium || cluster.ShareStorage.NFSProperties.PVC == "" {
+			continue
+		}
+		if clusterID != "" && cluster.ID.Hex() != clusterID {
+			continue
+		}
+		usages, err := commonrepo.NewShareStorageUsageColl().List(&commonrepo.ShareStorageUsageListOption{ClusterID: cluster.ID.Hex()})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list share storage usages of cluster %s: %s", cluster.Name, err)
+		}
+		report := &ShareStorageUsageReport{
+			ClusterID:      cluster.ID.Hex(),
+			ClusterName:    cluster.Name,
+			PVC:            cluster.ShareStorage.NFSProperties.PVC,
+			RetentionHours: cluster.ShareStorage.RetentionHours,
+			QuotaInMiB:     cluster.ShareStorage.QuotaInMiB,
+			Workflows:      make([]*WorkflowShareStorageUsage, 0),
+		}
+		workflowMap := make(map[string]*WorkflowShareStorageUsage)
+		for _, usage := range usages {
+			workflowUsage, ok := workflowMap[usage.WorkflowName]
+			if !ok {
+				workflowUsage = &WorkflowShareStorageUsage{WorkflowName: usage.WorkflowName}
+				workflowMap[usage.WorkflowName] = workflowUsage
+				report.Workflows = append(report.Workflows, workflowUsage)
+			}
+			if usage.ProjectName != "" {
+				workflowUsage.ProjectName = usage.ProjectName
+			}
+			workflowUsage.TotalInKiB += usage.SizeInKiB
+			workflowUsage.Tasks = append(workflowUsage.Tasks, usage)
+			report.TotalInKiB += usage.SizeInKiB
+		}
+		resp = append(resp, report)
+	}
+	return resp, nil
+}
+
+// TriggerShareStorageGC runs the garbage collection of the share storage in the background
+func TriggerShareStorageGC() {
+	go workflowcontroller.GCShareStorage()
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func ListTenants(logger *zap.SugaredLogger) ([]*commonmodels.Tenant, error) {
+	tenants, err := commonrepo.NewTenantColl().List()
+	if err != nil {
+		logger.Errorf("failed to list tenants, err: %s", err)
+		return nil, e.ErrListTenant.AddErr(err)
+	}
+	return tenants, nil
+}
+
+func GetTenant(name string, logger *zap.SugaredLogger) (*commonmodels.Tenant, error) {
+	tenant, err := commonrepo.NewTenantColl().Find(name)
+	if err != nil {
+		logger.Errorf("failed to find tenant %s, err: %s", name, err)
+		return nil, e.ErrGetTenant.AddErr(err)
+	}
+	return tenant, nil
+}
+
+func CreateTenant(args *commonmodels.Tenant, userName string, logger *zap.SugaredLogger) error {
+	if err := validateTenant(args); err != nil {
+		return e.ErrCreateTenant.AddErr(err)
+	}
+	args.CreatedBy = userName
+	args.UpdatedBy = userName
+	if err := commonrepo.NewTenantColl().Create(args); err != nil {
+		logger.Errorf("failed to create tenant %s, err: %s", args.Name, err)
+		return e.ErrCreateTenant.AddErr(err)
+	}
+	return nil
+}
+
+func UpdateTenant(name string, args *commonmodels.Tenant, userName string, logger *zap.SugaredLogger) error {
+	args.Name = name
+	if err := validateTenant(args); err != nil {
+		return e.ErrUpdateTenant.AddErr(err)
+	}
+	args.UpdatedBy = userName
+	if err := commonrepo.NewTenantColl().Update(args); err != nil {
+		logger.Errorf("failed to update tenant %s, err: %s", name, err)
+		return e.ErrUpdateTenant.AddErr(err)
+	}
+	return nil
+}
+
+func DeleteTenant(name string, logger *zap.SugaredLogger) error {
+	if err := commonrepo.NewTenantColl().Delete(name); err != nil {
+		logger.Errorf("failed to delete tenant %s, err: %s", name, err)
+		return e.ErrDeleteTenant.AddErr(err)
+	}
+	return nil
+}
+
+// validateTenant checks the quota and that every project exists and belongs to no other tenant
+func validateTenant(args *commonmodels.Tenant) error {
+	if args.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if args.Quota == nil {
+		args.Quota = &commonmodels.TenantQuota{}
+	}
+	if args.Quota.ConcurrentTasks < 0 || args.Quota.Environments < 0 || args.Quota.StorageGi < 0 {
+		return fmt.Errorf("quota can not be negative")
+	}
+
+	projects := sets.NewString(args.Projects...)
+	args.Projects = projects.List()
+	for _, project := range args.Projects {
+		if _, err := templaterepo.NewProductColl().Find(project); err != nil {
+			return fmt.Errorf("project %s not found", project)
+		}
+		tenant, err := commonrepo.NewTenantColl().FindByProject(project)
+		if err == nil && tenant.Name != args.Name {
+			return fmt.Errorf("project %s already belongs to tenant %s", project, tenant.Name)
+		}
+		if err != nil && err != mongo.ErrNoDocuments {
+			return err
+		}
+	}
+	return nil
+}
+
+type TenantUsageItem struct {
+	Used     int64 `json:"used"`
+	Limit    int64 `json:"limit"`
+	Exceeded bool  `json:"exceeded"`
+}
+
+func newTenantUsageItem(used, limit int64) *TenantUsageItem {
+	return &TenantUsageItem{Used: used, Limit: limit, Exceeded: limit > 0 && used >= limit}
+}
+
+// TenantUsage is the usage of a tenant against its quota, a limit of 0 means unlimited and the storage is in bytes
+type TenantUsage struct {
+	Name            string           `json:"name"`
+	DisplayName     string           `json:"display_name"`
+	Projects        []string         `json:"projects"`
+	ConcurrentTasks *TenantUsageItem `json:"concurrent_tasks"`
+	WaitingTasks    int              `json:"waiting_tasks"`
+	Environments    *TenantUsageItem `json:"environments"`
+	Storage         *TenantUsageItem `json:"storage"`
+	// StorageError is set if the storage usage can not be collected from the clusters
+	StorageError string `json:"storage_error,omitempty"`
+}
+
+func GetTenantUsage(name string, logger *zap.SugaredLogger) (*TenantUsage, error) {
+	tenant, err := commonrepo.NewTenantColl().Find(name)
+	if err != nil {
+		logger.Errorf("failed to find tenant %s, err: %s", name, err)
+		return nil, e.ErrGetTenant.AddErr(err)
+	}
+	usage, err := getTenantUsage(tenant, workflowcontroller.ListTasks())
+	if err != nil {
+		logger.Errorf("failed to get the usage of tenant %s, err: %s", name, err)
+		return nil, e.ErrGetTenantUsage.AddErr(err)
+	}
+	return usage, nil
+}
+
+func ListTenantUsages(logger *zap.SugaredLogger) ([]*TenantUsage, error) {
+	tenants, err := commonrepo.NewTenantColl().List()
+	if err != nil {
+		logger.Errorf("failed to list tenants, err: %s", err)
+		return nil, e.ErrListTenant.AddErr(err)
+	}
+	queues := workflowcontroller.ListTasks()
+	resp := make([]*TenantUsage, 0, len(tenants))
+	for _, tenant := range tenants {
+		usage, err := getTenantUsage(tenant, queues)
+		if err != nil {
+			logger.Errorf("failed to get the usage of tenant %s, err: %s", tenant.Name, err)
+			return nil, e.ErrGetTenantUsage.AddErr(err)
+		}
+		resp = append(resp, usage)
+	}
+	return resp, nil
+}
+
+func getTenantUsage(tenant *commonmodels.Tenant, queues []*commonmodels.WorkflowQueue) (*TenantUsage, error) {
+	quota := tenant.Quota
+	if quota == nil {
+		quota = &commonmodels.TenantQuota{}
+	}
+	usage := &TenantUsage{
+		Name:        tenant.Name,
+		DisplayName: tenant.DisplayName,
+		Projects:    tenant.Projects,
+	}
+
+	projects := sets.NewString(tenant.Projects...)
+	var running int64
+	for _, queue := range queues {
+		if !projects.Has(queue.ProjectName) {
+			continue
+		}
+		switch queue.Status {
+		case config.StatusRunning, config.StatusQueued:
+			running++
+		case config.StatusWaiting:
+			usage.WaitingTasks++
+		}
+	}
+	usage.ConcurrentTasks = newTenantUsageItem(running, int64(quota.ConcurrentTasks))
+
+	envs, err := commonservice.ListTenantEnvironments(tenant)
+	if err != nil {
+		return nil, err
+	}
+	usage.Environments = newTenantUsageItem(int64(len(envs)), int64(quota.Environments))
+
+	storage, err := commonservice.GetTenantStorageUsage(envs)
+	if err != nil {
+		usage.StorageError = err.Error()
+	}
+	usage.Storage = newTenantUsageItem(storage, quota.StorageGi*commonservice.Gi)
+	return usage, nil
+}
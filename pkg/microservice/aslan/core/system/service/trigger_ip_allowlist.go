@@ -0,0 +1,72 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"regexp"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/config"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/util"
+)
+
+var countryCodeRegex = regexp.MustCompile(`^[A-Za-z]{2}$`)
+
+func GetTriggerIPAllowlistSetting(logger *zap.SugaredLogger) (*models.TriggerIPAllowlistConfig, error) {
+	systemSetting, err := commonrepo.NewSystemSettingColl().Get()
+	if err != nil {
+		logger.Errorf("failed to get system setting, err: %s", err)
+		return nil, e.ErrInternalError.AddErr(err)
+	}
+	if systemSetting.TriggerIPAllowlist == nil {
+		return &models.TriggerIPAllowlistConfig{CIDRs: make([]string, 0), AllowedCountries: make([]string, 0)}, nil
+	}
+	return systemSetting.TriggerIPAllowlist, nil
+}
+
+func UpdateTriggerIPAllowlistSetting(args *models.TriggerIPAllowlistConfig, logger *zap.SugaredLogger) error {
+	if _, err := util.ParseIPAllowlist(args.CIDRs); err != nil {
+		return e.ErrInvalidParam.AddErr(err)
+	}
+	if args.Enabled && len(args.CIDRs) == 0 && len(args.AllowedCountries) == 0 {
+		return e.ErrInvalidParam.AddDesc("at least one CIDR or country is required when the restriction is enabled")
+	}
+	for _, country := range args.AllowedCountries {
+		if !countryCodeRegex.MatchString(country) {
+			return e.ErrInvalidParam.AddDesc(fmt.Sprintf("invalid ISO 3166 country code %q", country))
+		}
+	}
+	// the country header of the requests not coming from the trusted gateways is ignored, all of them would be rejected
+	if len(args.AllowedCountries) > 0 {
+		if args.CountryHeader == "" {
+			return e.ErrInvalidParam.AddDesc("the country header is required to restrict the countries")
+		}
+		if len(config.TrustedProxies()) == 0 {
+			return e.ErrInvalidParam.AddDesc("the gateways setting the country header must be configured by TRUSTED_PROXIES")
+		}
+	}
+	if err := commonrepo.NewSystemSettingColl().UpdateTriggerIPAllowlistSetting(args); err != nil {
+		logger.Errorf("failed to update trigger ip allowlist setting, err: %s", err)
+		return e.ErrInternalError.AddErr(err)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/tool/truststore"
+)
+
+// SetTrustStore loads the trust store setting into the HTTP clients of the integrations
+func SetTrustStore() {
+	systemSetting, err := commonrepo.NewSystemSettingColl().Get()
+	if err != nil {
+		log.Errorf("failed to get trust store setting, err: %s", err)
+		return
+	}
+	if err := applyTrustStore(systemSetting.TrustStore); err != nil {
+		log.Errorf("failed to apply trust store setting, err: %s", err)
+	}
+}
+
+func applyTrustStore(cfg *models.TrustStoreConfig) error {
+	if cfg == nil {
+		return truststore.Set(nil, nil)
+	}
+	bundles := make([]string, 0, len(cfg.CABundles))
+	for _, bundle := range cfg.CABundles {
+		bundles = append(bundles, bundle.Certificate)
+	}
+	return truststore.Set(bundles, cfg.InsecureSkipVerify)
+}
+
+func GetTrustStoreSetting(logger *zap.SugaredLogger) (*models.TrustStoreConfig, error) {
+	systemSetting, err := commonrepo.NewSystemSettingColl().Get()
+	if err != nil {
+		logger.Errorf("failed to get system setting, err: %s", err)
+		return nil, e.ErrInternalError.AddErr(err)
+	}
+	if systemSetting.TrustStore == nil {
+		return &models.TrustStoreConfig{
+			CABundles:          make([]*models.CABundle, 0),
+			InsecureSkipVerify: make([]string, 0),
+		}, nil
+	}
+	return systemSetting.TrustStore, nil
+}
+
+func UpdateTrustStoreSetting(args *models.TrustStoreConfig, username string, logger *zap.SugaredLogger) error {
+	for _, integration := range args.InsecureSkipVerify {
+		if !truststore.Integrations.Has(integration) {
+			return e.ErrInvalidParam.AddDesc(fmt.Sprintf("unsupported integration %q, supported: %s", integration, strings.Join(truststore.Integrations.List(), ", ")))
+		}
+	}
+
+	existed := make(map[string]*models.CABundle)
+	if current, err := GetTrustStoreSetting(logger); err == nil {
+		for _, bundle := range current.CABundles {
+			existed[bundle.Name] = bundle
+		}
+	}
+
+	names := sets.NewString()
+	for _, bundle := range args.CABundles {
+		bundle.Name = strings.TrimSpace(bundle.Name)
+		if bundle.Name == "" {
+			return e.ErrInvalidParam.AddDesc("name of the CA bundle is required")
+		}
+		if names.Has(bundle.Name) {
+			return e.ErrInvalidParam.AddDesc(fmt.Sprintf("duplicated CA bundle %q", bundle.Name))
+		}
+		names.Insert(bundle.Name)
+
+		certs, err := truststore.ParseBundle(bundle.Certificate)
+		if err != nil {
+			return e.ErrInvalidParam.AddDesc(fmt.Sprintf("invalid CA bundle %q: %s", bundle.Name, err))
+		}
+		bundle.Subjects = make([]string, 0, len(certs))
+		bundle.NotAfter = 0
+		for _, cert := range certs {
+			bundle.Subjects = append(bundle.Subjects, cert.Subject.String())
+			if bundle.NotAfter == 0 || cert.NotAfter.Unix() < bundle.NotAfter {
+				bundle.NotAfter = cert.NotAfter.Unix()
+			}
+		}
+
+		if old, ok := existed[bundle.Name]; ok && old.Certificate == bundle.Certificate {
+			bundle.UpdateBy = old.UpdateBy
+			bundle.UpdateTime = old.UpdateTime
+		} else {
+			bundle.UpdateBy = username
+			bundle.UpdateTime = time.Now().Unix()
+		}
+	}
+
+	if err := commonrepo.NewSystemSettingColl().UpdateTrustStoreSetting(args); err != nil {
+		logger.Errorf("failed to update trust store setting, err: %s", err)
+		return e.ErrInternalError.AddErr(err)
+	}
+	if err := applyTrustStore(args); err != nil {
+		logger.Errorf("failed to apply trust store setting, err: %s", err)
+		return e.ErrInternalError.AddErr(err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func GetWebhookRateLimitSetting(logger *zap.SugaredLogger) (*models.WebhookRateLimitConfig, error) {
+	systemSetting, err := commonrepo.NewSystemSettingColl().Get()
+	if err != nil {
+		logger.Errorf("failed to get system setting, err: %s", err)
+		return nil, e.ErrInternalError.AddErr(err)
+	}
+	if systemSetting.WebhookRateLimit == nil {
+		return &models.WebhookRateLimitConfig{Rules: make([]*models.WebhookRateLimitRule, 0)}, nil
+	}
+	return systemSetting.WebhookRateLimit, nil
+}
+
+func UpdateWebhookRateLimitSetting(args *models.WebhookRateLimitConfig, logger *zap.SugaredLogger) error {
+	sources := sets.NewString()
+	for _, rule := range args.Rules {
+		if sources.Has(rule.Source) {
+			return e.ErrInvalidParam.AddDesc(fmt.Sprintf("duplicated rule of source %q", rule.Source))
+		}
+		sources.Insert(rule.Source)
+		if rule.KeyBy != "ip" && rule.KeyBy != "hook" {
+			return e.ErrInvalidParam.AddDesc("key_by must be ip or hook")
+		}
+		if rule.Rate <= 0 {
+			return e.ErrInvalidParam.AddDesc("rate must be greater than 0")
+		}
+		if rule.Burst <= 0 {
+			return e.ErrInvalidParam.AddDesc("burst must be greater than 0")
+		}
+	}
+	if err := commonrepo.NewSystemSettingColl().UpdateWebhookRateLimitSetting(args); err != nil {
+		logger.Errorf("failed to update webhook rate limit setting, err: %s", err)
+		return e.ErrInternalError.AddErr(err)
+	}
+	return nil
+}
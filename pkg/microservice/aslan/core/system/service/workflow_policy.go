@@ -0,0 +1,116 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowpolicy"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func ListWorkflowPolicies(logger *zap.SugaredLogger) ([]*models.WorkflowPolicy, error) {
+	policies, err := commonrepo.NewWorkflowPolicyColl().List(false)
+	if err != nil {
+		logger.Errorf("failed to list workflow policies, err: %s", err)
+		return nil, e.ErrInternalError.AddErr(err)
+	}
+	return policies, nil
+}
+
+func GetWorkflowPolicy(name string, logger *zap.SugaredLogger) (*models.WorkflowPolicy, error) {
+	policy, err := commonrepo.NewWorkflowPolicyColl().Find(name)
+	if err != nil {
+		logger.Errorf("failed to find workflow policy %s, err: %s", name, err)
+		return nil, e.ErrNotFound.AddErr(err)
+	}
+	return policy, nil
+}
+
+// CreateWorkflowPolicy saves the policy after its module is compiled by the opa server
+func CreateWorkflowPolicy(user string, args *models.WorkflowPolicy, logger *zap.SugaredLogger) error {
+	if err := workflowpolicy.Validate(args); err != nil {
+		return e.ErrInvalidParam.AddErr(err)
+	}
+	if _, err := commonrepo.NewWorkflowPolicyColl().Find(args.Name); err == nil {
+		return e.ErrInvalidParam.AddDesc("workflow policy " + args.Name + " already exists")
+	} else if err != mongo.ErrNoDocuments {
+		return e.ErrInternalError.AddErr(err)
+	}
+	if err := syncWorkflowPolicy(args); err != nil {
+		return e.ErrInvalidParam.AddErr(err)
+	}
+
+	now := time.Now().Unix()
+	args.CreatedBy, args.UpdatedBy = user, user
+	args.CreateTime, args.UpdateTime = now, now
+	if err := commonrepo.NewWorkflowPolicyColl().Create(args); err != nil {
+		logger.Errorf("failed to create workflow policy %s, err: %s", args.Name, err)
+		return e.ErrInternalError.AddErr(err)
+	}
+	return nil
+}
+
+func UpdateWorkflowPolicy(name, user string, args *models.WorkflowPolicy, logger *zap.SugaredLogger) error {
+	args.Name = name
+	if err := workflowpolicy.Validate(args); err != nil {
+		return e.ErrInvalidParam.AddErr(err)
+	}
+	if _, err := commonrepo.NewWorkflowPolicyColl().Find(name); err != nil {
+		return e.ErrNotFound.AddErr(err)
+	}
+	if err := syncWorkflowPolicy(args); err != nil {
+		return e.ErrInvalidParam.AddErr(err)
+	}
+
+	args.UpdatedBy = user
+	args.UpdateTime = time.Now().Unix()
+	if err := commonrepo.NewWorkflowPolicyColl().Update(name, args); err != nil {
+		logger.Errorf("failed to update workflow policy %s, err: %s", name, err)
+		return e.ErrInternalError.AddErr(err)
+	}
+	return nil
+}
+
+func DeleteWorkflowPolicy(name string, logger *zap.SugaredLogger) error {
+	if err := workflowpolicy.Remove(name); err != nil {
+		logger.Errorf("failed to remove workflow policy %s from opa, err: %s", name, err)
+		return e.ErrInternalError.AddErr(err)
+	}
+	if err := commonrepo.NewWorkflowPolicyColl().Delete(name); err != nil {
+		logger.Errorf("failed to delete workflow policy %s, err: %s", name, err)
+		return e.ErrInternalError.AddErr(err)
+	}
+	return nil
+}
+
+// syncWorkflowPolicy uploads the policy to the opa server to compile it, the disabled policy is removed again so
+// that its rules are not merged into the enabled policies of the same package
+func syncWorkflowPolicy(policy *models.WorkflowPolicy) error {
+	if err := workflowpolicy.Save(policy); err != nil {
+		return err
+	}
+	if !policy.Enabled {
+		return workflowpolicy.Remove(policy.Name)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func GetWorkflowTrashSetting(logger *zap.SugaredLogger) (*models.WorkflowTrashConfig, error) {
+	systemSetting, err := commonrepo.NewSystemSettingColl().Get()
+	if err != nil {
+		logger.Errorf("failed to get system setting, err: %s", err)
+		return nil, e.ErrInternalError.AddErr(err)
+	}
+	if systemSetting.WorkflowTrash == nil {
+		return &models.WorkflowTrashConfig{RetentionDays: 7}, nil
+	}
+	return systemSetting.WorkflowTrash, nil
+}
+
+func UpdateWorkflowTrashSetting(args *models.WorkflowTrashConfig, logger *zap.SugaredLogger) error {
+	if args.RetentionDays <= 0 {
+		return e.ErrInvalidParam.AddDesc("retention_days must be greater than 0")
+	}
+	if err := commonrepo.NewSystemSettingColl().UpdateWorkflowTrashSetting(args); err != nil {
+		logger.Errorf("failed to update workflow trash setting, err: %s", err)
+		return e.ErrInternalError.AddErr(err)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/vm/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+// @Summary Register VM Agent
+// @Description Called by the vm agent when it starts, the jobs left running by the previous run of the agent are failed
+// @Tags 	vm
+// @Accept 	json
+// @Produce json
+// @Param 	body 		body 		types.VMAgentRegisterArgs 			true 	"body"
+// @Success 200 		{object} 	types.VMAgentRegisterResp
+// @Router /api/aslan/vm/agent/register [post]
+func RegisterVMAgent(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	agent, err := service.AuthenticateVMAgent(c.GetHeader(types.VMAgentTokenHeader))
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+
+	args := new(types.VMAgentRegisterArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.RegisterVMAgent(agent, args)
+}
+
+// @Summary VM Agent Heartbeat
+// @Description Called by the vm agent periodically, the running jobs to be stopped are returned
+// @Tags 	vm
+// @Accept 	json
+// @Produce json
+// @Param 	body 		body 		types.VMAgentHeartbeatArgs 			true 	"body"
+// @Success 200 		{object} 	types.VMAgentHeartbeatResp
+// @Router /api/aslan/vm/agent/heartbeat [post]
+func VMAgentHeartbeat(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	agent, err := service.AuthenticateVMAgent(c.GetHeader(types.VMAgentTokenHeader))
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+
+	args := new(types.VMAgentHeartbeatArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.VMAgentHeartbeat(agent, args)
+}
+
+// @Summary Poll VM Agent Job
+// @Description Called by the idle vm agent to pick up the earliest queued job matching its labels, the id is empty if there is none
+// @Tags 	vm
+// @Accept 	json
+// @Produce json
+// @Success 200 		{object} 	types.VMAgentJob
+// @Router /api/aslan/vm/agent/job/poll [post]
+func PollVMAgentJob(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	agent, err := service.AuthenticateVMAgent(c.GetHeader(types.VMAgentTokenHeader))
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.PollVMAgentJob(agent)
+}
+
+// @Summary Append VM Job Log
+// @Description Called by the vm agent to stream the log of the job
+// @Tags 	vm
+// @Accept 	json
+// @Produce json
+// @Param 	id 			path 		string 								true 	"job id"
+// @Param 	body 		body 		types.VMJobLogArgs 					true 	"body"
+// @Success 200
+// @Router /api/aslan/vm/agent/job/{id}/log [post]
+func AppendVMJobLog(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	agent, err := service.AuthenticateVMAgent(c.GetHeader(types.VMAgentTokenHeader))
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+
+	args := new(types.VMJobLogArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	ctx.Err = service.AppendVMJobLog(agent, c.Param("id"), args)
+}
+
+// @Summary Report VM Job Result
+// @Description Called by the vm agent when the job ends
+// @Tags 	vm
+// @Accept 	json
+// @Produce json
+// @Param 	id 			path 		string 								true 	"job id"
+// @Param 	body 		body 		types.VMJobResultArgs 				true 	"body"
+// @Success 200
+// @Router /api/aslan/vm/agent/job/{id}/result [post]
+func ReportVMJobResult(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	agent, err := service.AuthenticateVMAgent(c.GetHeader(types.VMAgentTokenHeader))
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+
+	args := new(types.VMJobResultArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	ctx.Err = service.ReportVMJobResult(agent, c.Param("id"), args)
+}
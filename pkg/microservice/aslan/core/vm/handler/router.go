@@ -0,0 +1,44 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+type Router struct{}
+
+func (*Router) Inject(router *gin.RouterGroup) {
+	agents := router.Group("agents")
+	{
+		agents.GET("", ListVMAgents)
+		agents.POST("", CreateVMAgent)
+		agents.PUT("/:id", UpdateVMAgent)
+		agents.DELETE("/:id", DeleteVMAgent)
+		agents.POST("/:id/token", ResetVMAgentToken)
+	}
+
+	// called by the vm agents, they are authenticated by the agent token instead of the user token
+	agent := router.Group("agent")
+	{
+		agent.POST("/register", RegisterVMAgent)
+		agent.POST("/heartbeat", VMAgentHeartbeat)
+		agent.POST("/job/poll", PollVMAgentJob)
+		agent.POST("/job/:id/log", AppendVMJobLog)
+		agent.POST("/job/:id/result", ReportVMJobResult)
+	}
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/vm/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+// @Summary List VM Agents
+// @Description List the vm agents with their online status
+// @Tags 	vm
+// @Accept 	json
+// @Produce json
+// @Success 200 		{array} 	service.VMAgentResp
+// @Router /api/aslan/vm/agents [get]
+func ListVMAgents(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	// authorization checks
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.ListVMAgents()
+}
+
+// @Summary Create VM Agent
+// @Description Create a vm agent, the returned token is used to start the agent on the vm
+// @Tags 	vm
+// @Accept 	json
+// @Produce json
+// @Param 	body 		body 		commonmodels.VMAgent 				true 	"body"
+// @Success 200 		{object} 	service.VMAgentTokenResp
+// @Router /api/aslan/vm/agents [post]
+func CreateVMAgent(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	// authorization checks
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	args := new(commonmodels.VMAgent)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.CreateVMAgent(args, ctx.UserName)
+}
+
+// @Summary Update VM Agent
+// @Description Update the name, the description and the labels of a vm agent
+// @Tags 	vm
+// @Accept 	json
+// @Produce json
+// @Param 	id 			path 		string 								true 	"agent id"
+// @Param 	body 		body 		commonmodels.VMAgent 				true 	"body"
+// @Success 200
+// @Router /api/aslan/vm/agents/{id} [put]
+func UpdateVMAgent(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	// authorization checks
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	args := new(commonmodels.VMAgent)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	ctx.Err = service.UpdateVMAgent(c.Param("id"), args, ctx.UserName)
+}
+
+// @Summary Delete VM Agent
+// @Description Delete a vm agent, the agent can no longer pick up jobs
+// @Tags 	vm
+// @Accept 	json
+// @Produce json
+// @Param 	id 			path 		string 								true 	"agent id"
+// @Success 200
+// @Router /api/aslan/vm/agents/{id} [delete]
+func DeleteVMAgent(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	// authorization checks
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	ctx.Err = service.DeleteVMAgent(c.Param("id"))
+}
+
+// @Summary Reset VM Agent Token
+// @Description Generate a new token for a vm agent, the old token is rejected afterwards
+// @Tags 	vm
+// @Accept 	json
+// @Produce json
+// @Param 	id 			path 		string 								true 	"agent id"
+// @Success 200 		{object} 	service.VMAgentTokenResp
+// @Router /api/aslan/vm/agents/{id}/token [post]
+func ResetVMAgentToken(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	// authorization checks
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.ResetVMAgentToken(c.Param("id"), ctx.UserName)
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+// AuthenticateVMAgent finds the agent by the token in the request of the agent
+func AuthenticateVMAgent(token string) (*commonmodels.VMAgent, error) {
+	if token == "" {
+		return nil, e.ErrVMAgentUnauthorized.AddDesc("token is empty")
+	}
+	agent, err := commonrepo.NewVMAgentColl().GetByTokenHash(hashVMAgentToken(token))
+	if err != nil {
+		return nil, e.ErrVMAgentUnauthorized.AddDesc("invalid token")
+	}
+	return agent, nil
+}
+
+func RegisterVMAgent(agent *commonmodels.VMAgent, args *types.VMAgentRegisterArgs) (*types.VMAgentRegisterResp, error) {
+	hostInfo := &commonmodels.VMAgent{
+		Hostname:     args.Hostname,
+		IP:           args.IP,
+		Platform:     args.Platform,
+		Architecture: args.Architecture,
+		AgentVersion: args.AgentVersion,
+	}
+	if err := commonrepo.NewVMAgentColl().UpdateHeartbeat(agent.ID, hostInfo); err != nil {
+		return nil, e.ErrUpdateVMAgent.AddErr(err)
+	}
+	// the jobs left running by the previous run of the agent are lost
+	jobs, err := commonrepo.NewVMJobColl().ListByAgent(agent.ID.Hex(), config.StatusRunning)
+	if err != nil {
+		return nil, e.ErrVMAgentJob.AddErr(err)
+	}
+	for _, job := range jobs {
+		if _, err := commonrepo.NewVMJobColl().Finish(job.ID, config.StatusFailed, "", "the vm agent is restarted"); err != nil {
+			return nil, e.ErrVMAgentJob.AddErr(err)
+		}
+	}
+	return &types.VMAgentRegisterResp{
+		ID:     agent.ID.Hex(),
+		Name:   agent.Name,
+		Labels: agent.Labels,
+	}, nil
+}
+
+// VMAgentHeartbeat keeps the agent online, the running jobs which have been ended by zadig, for instance cancelled
+// or timed out, are returned so that the agent stops them
+func VMAgentHeartbeat(agent *commonmodels.VMAgent, args *types.VMAgentHeartbeatArgs) (*types.VMAgentHeartbeatResp, error) {
+	if err := commonrepo.NewVMAgentColl().UpdateHeartbeat(agent.ID, nil); err != nil {
+		return nil, e.ErrUpdateVMAgent.AddErr(err)
+	}
+	resp := &types.VMAgentHeartbeatResp{StoppedJobs: make([]string, 0)}
+	for _, id := range args.RunningJobs {
+		job, err := commonrepo.NewVMJobColl().GetByID(id)
+		if err != nil || job.AgentID != agent.ID.Hex() || job.Status != config.StatusRunning {
+			resp.StoppedJobs = append(resp.StoppedJobs, id)
+		}
+	}
+	return resp, nil
+}
+
+// PollVMAgentJob assigns the earliest queued job matching the labels of the agent, the id is empty if there is none
+func PollVMAgentJob(agent *commonmodels.VMAgent) (*types.VMAgentJob, error) {
+	job, err := commonrepo.NewVMJobColl().Assign(agent.ID.Hex(), agent.Labels)
+	if err != nil {
+		return nil, e.ErrVMAgentJob.AddErr(err)
+	}
+	if job == nil {
+		return &types.VMAgentJob{}, nil
+	}
+	return &types.VMAgentJob{ID: job.ID.Hex(), JobCtx: job.JobCtx}, nil
+}
+
+func AppendVMJobLog(agent *commonmodels.VMAgent, jobID string, args *types.VMJobLogArgs) error {
+	if _, err := getAgentJob(agent, jobID); err != nil {
+		return err
+	}
+	err := commonrepo.NewVMJobLogColl().Append(&commonmodels.VMJobLog{
+		JobID:      jobID,
+		Seq:        args.Seq,
+		Content:    args.Content,
+		CreateTime: time.Now().Unix(),
+	})
+	if err != nil {
+		return e.ErrVMAgentJob.AddErr(err)
+	}
+	return nil
+}
+
+// ReportVMJobResult ends the job with the result reported by the agent, it is ignored if the job has already ended
+func ReportVMJobResult(agent *commonmodels.VMAgent, jobID string, args *types.VMJobResultArgs) error {
+	job, err := getAgentJob(agent, jobID)
+	if err != nil {
+		return err
+	}
+	status := config.StatusPassed
+	if args.Status != types.JobSuccess {
+		status = config.StatusFailed
+	}
+	if _, err := commonrepo.NewVMJobColl().Finish(job.ID, status, args.Outputs, args.Error); err != nil {
+		return e.ErrVMAgentJob.AddErr(err)
+	}
+	return nil
+}
+
+func getAgentJob(agent *commonmodels.VMAgent, jobID string) (*commonmodels.VMJob, error) {
+	job, err := commonrepo.NewVMJobColl().GetByID(jobID)
+	if err != nil {
+		return nil, e.ErrVMAgentJob.AddErr(err)
+	}
+	if job.AgentID != agent.ID.Hex() {
+		return nil, e.ErrVMAgentJob.AddErr(fmt.Errorf("job %s is not assigned to agent %s", jobID, agent.Name))
+	}
+	return job, nil
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const (
+	VMAgentStatusOnline  = "online"
+	VMAgentStatusOffline = "offline"
+)
+
+type VMAgentResp struct {
+	*commonmodels.VMAgent `json:",inline"`
+	Status                string `json:"status"`
+}
+
+// VMAgentTokenResp returns the token of the agent, it is only shown when the agent is created or the token is reset
+type VMAgentTokenResp struct {
+	ID    string `json:"id"`
+	Token string `json:"token"`
+}
+
+func ListVMAgents() ([]*VMAgentResp, error) {
+	agents, err := commonrepo.NewVMAgentColl().List()
+	if err != nil {
+		return nil, e.ErrListVMAgent.AddErr(err)
+	}
+	resp := make([]*VMAgentResp, 0, len(agents))
+	for _, agent := range agents {
+		status := VMAgentStatusOffline
+		if agent.Online() {
+			status = VMAgentStatusOnline
+		}
+		resp = append(resp, &VMAgentResp{VMAgent: agent, Status: status})
+	}
+	return resp, nil
+}
+
+func CreateVMAgent(args *commonmodels.VMAgent, username string) (*VMAgentTokenResp, error) {
+	if args.Name == "" {
+		return nil, e.ErrCreateVMAgent.AddDesc("name is empty")
+	}
+	token, err := generateVMAgentToken()
+	if err != nil {
+		return nil, e.ErrCreateVMAgent.AddErr(err)
+	}
+	agent := &commonmodels.VMAgent{
+		Name:        args.Name,
+		Description: args.Description,
+		Labels:      args.Labels,
+		TokenHash:   hashVMAgentToken(token),
+		CreatedBy:   username,
+		CreateTime:  time.Now().Unix(),
+		UpdatedBy:   username,
+		UpdateTime:  time.Now().Unix(),
+	}
+	if err := commonrepo.NewVMAgentColl().Create(agent); err != nil {
+		return nil, e.ErrCreateVMAgent.AddErr(err)
+	}
+	return &VMAgentTokenResp{ID: agent.ID.Hex(), Token: token}, nil
+}
+
+// UpdateVMAgent updates the name, the description and the labels of the agent, the new labels take effect on the next job
+func UpdateVMAgent(id string, args *commonmodels.VMAgent, username string) error {
+	agent, err := commonrepo.NewVMAgentColl().GetByID(id)
+	if err != nil {
+		return e.ErrUpdateVMAgent.AddErr(err)
+	}
+	if args.Name == "" {
+		return e.ErrUpdateVMAgent.AddDesc("name is empty")
+	}
+	agent.Name = args.Name
+	agent.Description = args.Description
+	agent.Labels = args.Labels
+	agent.UpdatedBy = username
+	agent.UpdateTime = time.Now().Unix()
+	if err := commonrepo.NewVMAgentColl().Update(agent.ID, agent); err != nil {
+		return e.ErrUpdateVMAgent.AddErr(err)
+	}
+	return nil
+}
+
+func DeleteVMAgent(id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return e.ErrDeleteVMAgent.AddErr(err)
+	}
+	if err := commonrepo.NewVMAgentColl().DeleteByID(oid); err != nil {
+		return e.ErrDeleteVMAgent.AddErr(err)
+	}
+	return nil
+}
+
+// ResetVMAgentToken replaces the token of the agent, the agent using the old token is rejected
+func ResetVMAgentToken(id string, username string) (*VMAgentTokenResp, error) {
+	agent, err := commonrepo.NewVMAgentColl().GetByID(id)
+	if err != nil {
+		return nil, e.ErrUpdateVMAgent.AddErr(err)
+	}
+	token, err := generateVMAgentToken()
+	if err != nil {
+		return nil, e.ErrUpdateVMAgent.AddErr(err)
+	}
+	agent.TokenHash = hashVMAgentToken(token)
+	agent.UpdatedBy = username
+	agent.UpdateTime = time.Now().Unix()
+	if err := commonrepo.NewVMAgentColl().Update(agent.ID, agent); err != nil {
+		return nil, e.ErrUpdateVMAgent.AddErr(err)
+	}
+	return &VMAgentTokenResp{ID: id, Token: token}, nil
+}
+
+func generateVMAgentToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %s", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashVMAgentToken returns the digest of the token kept in the db, the token has enough entropy so no salt is needed
+func hashVMAgentToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
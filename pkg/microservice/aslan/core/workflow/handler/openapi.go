@@ -10,8 +10,10 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	workflowservice "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow"
+	"github.com/koderover/zadig/pkg/setting"
 	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
 	e "github.com/koderover/zadig/pkg/tool/errors"
 	"github.com/koderover/zadig/pkg/tool/log"
@@ -36,6 +38,10 @@ func CreateCustomWorkflowTask(c *gin.Context) {
 		ctx.Err = e.ErrInvalidParam.AddDesc(err.Error())
 		return
 	}
+	args.IdempotencyKey = c.GetHeader(setting.IdempotencyKeyHeader)
+	if denyTriggerSource(c, ctx, args.ProjectName, "(OpenAPI)自定义工作流", args.WorkflowName, nil) {
+		return
+	}
 
 	ctx.Resp, ctx.Err = workflowservice.CreateCustomWorkflowTask(ctx.UserName, args, ctx.Logger)
 }
@@ -175,6 +181,9 @@ func OpenAPICreateProductWorkflowTask(c *gin.Context) {
 		ctx.Err = e.ErrInvalidParam.AddDesc(err.Error())
 		return
 	}
+	if denyTriggerSource(c, ctx, args.ProjectName, "(OpenAPI)产品工作流", args.WorkflowName, nil) {
+		return
+	}
 
 	ctx.Resp, ctx.Err = workflowservice.OpenAPICreateProductWorkflowTask(ctx.UserName, args, ctx.Logger)
 }
@@ -191,7 +200,7 @@ func OpenAPIDeleteCustomWorkflowV4(c *gin.Context) {
 	}
 	internalhandler.InsertOperationLog(c, ctx.UserName, projectKey, "(OpenAPI)"+"删除", "自定义工作流", workflowKey, "", ctx.Logger)
 
-	ctx.Err = workflowservice.OpenAPIDeleteCustomWorkflowV4(workflowKey, projectKey, ctx.Logger)
+	ctx.Err = workflowservice.OpenAPIDeleteCustomWorkflowV4(workflowKey, projectKey, ctx.UserName, ctx.Logger)
 }
 
 func OpenAPIGetCustomWorkflowV4(c *gin.Context) {
@@ -349,7 +358,7 @@ func OpenAPIApproveStage(c *gin.Context) {
 		return
 	}
 
-	ctx.Err = workflowservice.ApproveStage(args.WorkflowName, args.StageName, ctx.UserName, ctx.UserID, args.Comment, args.TaskID, args.Approve, ctx.Logger)
+	ctx.Err = workflowservice.ApproveStage(args.WorkflowName, args.StageName, ctx.UserName, ctx.UserID, args.Comment, args.TaskID, args.Approve, config.ApprovalSourceOpenAPI, ctx.Logger)
 }
 
 func generalRequestValidate(c *gin.Context) (string, int64, error) {
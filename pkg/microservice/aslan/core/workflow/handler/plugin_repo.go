@@ -30,7 +30,7 @@ func ListPluginTemplates(c *gin.Context) {
 	ctx := internalhandler.NewContext(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
 
-	ctx.Resp, ctx.Err = workflow.ListPluginTemplates(ctx.Logger)
+	ctx.Resp, ctx.Err = workflow.ListPluginTemplates(c.Query("projectName"), ctx.Logger)
 }
 
 func ListUnofficalPluginRepositories(c *gin.Context) {
@@ -96,3 +96,73 @@ func UpsertEnterprisePluginRepository(c *gin.Context) {
 	}
 	ctx.Err = workflow.UpsertEnterprisePluginRepository(req, ctx.Logger)
 }
+
+func GetProjectPlugins(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	projectName := c.Param("name")
+	if !ctx.Resources.IsSystemAdmin {
+		if _, ok := ctx.Resources.ProjectAuthInfo[projectName]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+	}
+
+	ctx.Resp, ctx.Err = workflow.GetProjectPlugins(projectName, ctx.Logger)
+}
+
+func UpdateProjectPlugins(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	projectName := c.Param("name")
+	if !ctx.Resources.IsSystemAdmin {
+		if _, ok := ctx.Resources.ProjectAuthInfo[projectName]; !ok || !ctx.Resources.ProjectAuthInfo[projectName].IsProjectAdmin {
+			ctx.UnAuthorized = true
+			return
+		}
+	}
+
+	req := new(commonmodels.ProjectPlugin)
+	if err := c.ShouldBindJSON(req); err != nil {
+		ctx.Err = errors.ErrInvalidParam.AddDesc(err.Error())
+		return
+	}
+	req.ProjectName = projectName
+	req.UpdateBy = ctx.UserName
+	ctx.Err = workflow.UpdateProjectPlugins(req, ctx.Logger)
+}
+
+func EnableAllProjectPlugins(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	projectName := c.Param("name")
+	if !ctx.Resources.IsSystemAdmin {
+		if _, ok := ctx.Resources.ProjectAuthInfo[projectName]; !ok || !ctx.Resources.ProjectAuthInfo[projectName].IsProjectAdmin {
+			ctx.UnAuthorized = true
+			return
+		}
+	}
+
+	ctx.Err = workflow.EnableAllProjectPlugins(projectName, ctx.Logger)
+}
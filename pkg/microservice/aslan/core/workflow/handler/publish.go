@@ -0,0 +1,141 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+// @Summary List Published Files
+// @Description List the files and directories published by the job of the workflow task under the given path
+// @Tags 	workflow
+// @Accept 	json
+// @Produce json
+// @Param 	workflowName	path		string							true	"workflow name"
+// @Param 	taskID			path		string							true	"task id"
+// @Param 	jobName			path		string							true	"job name"
+// @Param 	path			query		string							false	"directory relative to the publish root"
+// @Success 200 			{array} 	workflow.PublishedFile
+// @Router /api/aslan/workflow/v4/workflowtask/workflow/{workflowName}/task/{taskID}/job/{jobName}/publish [get]
+func ListWorkflowTaskV4PublishedFiles(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	workflowName := c.Param("workflowName")
+	if !checkPublishedFilesPermission(ctx, workflowName) {
+		return
+	}
+
+	taskID, err := strconv.ParseInt(c.Param("taskID"), 10, 64)
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid task id")
+		return
+	}
+
+	ctx.Resp, ctx.Err = workflow.ListWorkflowTaskPublishedFiles(workflowName, c.Param("jobName"), c.Query("path"), taskID, ctx.Logger)
+}
+
+// @Summary Download Published File
+// @Description Download the file published by the job of the workflow task
+// @Tags 	workflow
+// @Accept 	json
+// @Produce octet-stream
+// @Param 	workflowName	path		string							true	"workflow name"
+// @Param 	taskID			path		string							true	"task id"
+// @Param 	jobName			path		string							true	"job name"
+// @Param 	path			query		string							true	"file path relative to the publish root"
+// @Success 200
+// @Router /api/aslan/workflow/v4/workflowtask/workflow/{workflowName}/task/{taskID}/job/{jobName}/publish/download [get]
+func DownloadWorkflowTaskV4PublishedFile(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		internalhandler.JSONResponse(c, ctx)
+		return
+	}
+
+	workflowName := c.Param("workflowName")
+	if !checkPublishedFilesPermission(ctx, workflowName) {
+		internalhandler.JSONResponse(c, ctx)
+		return
+	}
+
+	taskID, err := strconv.ParseInt(c.Param("taskID"), 10, 64)
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid task id")
+		internalhandler.JSONResponse(c, ctx)
+		return
+	}
+
+	filePath := c.Query("path")
+	reader, size, err := workflow.DownloadWorkflowTaskPublishedFile(workflowName, c.Param("jobName"), filePath, taskID, ctx.Logger)
+	if err != nil {
+		ctx.Err = err
+		internalhandler.JSONResponse(c, ctx)
+		return
+	}
+	defer reader.Close()
+
+	c.DataFromReader(http.StatusOK, size, "application/octet-stream", reader, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="%s"`, path.Base(filePath)),
+	})
+}
+
+func checkPublishedFilesPermission(ctx *internalhandler.Context, workflowName string) bool {
+	w, err := workflow.FindWorkflowV4Raw(workflowName, ctx.Logger)
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return false
+	}
+
+	// authorization check
+	if !ctx.Resources.IsSystemAdmin {
+		if _, ok := ctx.Resources.ProjectAuthInfo[w.Project]; !ok {
+			ctx.UnAuthorized = true
+			return false
+		}
+
+		if !ctx.Resources.ProjectAuthInfo[w.Project].IsProjectAdmin &&
+			!ctx.Resources.ProjectAuthInfo[w.Project].Workflow.View {
+			// check if the permission is given by collaboration mode
+			permitted, err := internalhandler.GetCollaborationModePermission(ctx.UserID, w.Project, types.ResourceTypeWorkflow, workflowName, types.WorkflowActionView)
+			if err != nil || !permitted {
+				ctx.UnAuthorized = true
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -29,6 +29,9 @@ func (*Router) Inject(router *gin.RouterGroup) {
 	webhook := router.Group("webhook")
 	{
 		webhook.POST("", ProcessWebHook)
+		webhook.GET("/delivery", ListWebhookDeliveries)
+		webhook.GET("/delivery/:id", GetWebhookDelivery)
+		webhook.POST("/delivery/:id/redeliver", RedeliverWebhook)
 	}
 
 	build := router.Group("build")
@@ -171,15 +174,33 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		workflowV4.POST("", CreateWorkflowV4)
 		workflowV4.POST("/:name/workflowtask/field", SetWorkflowTasksCustomFields)
 		workflowV4.GET("/:name/workflowtask/field", GetWorkflowTasksCustomFields)
+		workflowV4.GET("/customfield/default", GetProjectWorkflowCustomFields)
+		workflowV4.PUT("/customfield/default", UpdateProjectWorkflowCustomFields)
 		workflowV4.GET("", ListWorkflowV4)
 		workflowV4.GET("/trigger", ListWorkflowV4CanTrigger)
+		workflowV4.GET("/retainedpod", ListRetainedJobPods)
+		workflowV4.DELETE("/retainedpod/:id", CleanRetainedJobPod)
 		workflowV4.POST("/lint", LintWorkflowV4)
+		workflowV4.GET("/schema", GetWorkflowV4Schema)
+		workflowV4.GET("/graph/:name", GetWorkflowV4Graph)
+		workflowV4.GET("/dependencies", GetProjectWorkflowDependencies)
 		workflowV4.POST("/check/:name", CheckWorkflowV4Approval)
 		workflowV4.POST("/output/:jobName", GetWorkflowGlobalVars)
+		workflowV4.POST("/variables/:jobName", ListWorkflowVariables)
 		workflowV4.POST("/repo/:jobName", GetWorkflowRepoIndex)
 		workflowV4.GET("/name/:name", FindWorkflowV4)
 		workflowV4.PUT("/:name", UpdateWorkflowV4)
 		workflowV4.DELETE("/:name", DeleteWorkflowV4)
+		workflowV4.GET("/trash", ListWorkflowV4Trash)
+		workflowV4.GET("/references", ListAssetReferences)
+		workflowV4.POST("/batch/patch", BatchPatchWorkflowV4)
+		workflowV4.POST("/copy", BulkCopyWorkflowV4)
+		workflowV4.GET("/copy/:name/mapping", GetWorkflowV4CloneMapping)
+		workflowV4.GET("/counter", ListWorkflowTaskCounters)
+		workflowV4.GET("/counter/:name", GetWorkflowTaskCounter)
+		workflowV4.POST("/counter/:name/fastforward", FastForwardWorkflowTaskCounter)
+		workflowV4.POST("/trash/:name/restore", RestoreWorkflowV4)
+		workflowV4.DELETE("/trash/:name", PurgeWorkflowV4)
 		workflowV4.GET("/preset/:name", GetWorkflowV4Preset)
 		workflowV4.GET("/webhook/preset", GetWebhookForWorkflowV4Preset)
 		workflowV4.GET("/webhook", ListWebhookForWorkflowV4)
@@ -202,8 +223,19 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		workflowV4.PUT("/generalhook/:workflowName", UpdateGeneralHookForWorkflowV4)
 		workflowV4.DELETE("/generalhook/:workflowName/:hookName", DeleteGeneralHookForWorkflowV4)
 		workflowV4.POST("/generalhook/:workflowName/:hookName/webhook", GeneralHookEventHandler)
+		workflowV4.GET("/registryhook/preset", GetRegistryHookForWorkflowV4Preset)
+		workflowV4.GET("/registryhook/:workflowName", ListRegistryHookForWorkflowV4)
+		workflowV4.POST("/registryhook/:workflowName", CreateRegistryHookForWorkflowV4)
+		workflowV4.PUT("/registryhook/:workflowName", UpdateRegistryHookForWorkflowV4)
+		workflowV4.DELETE("/registryhook/:workflowName/:hookName", DeleteRegistryHookForWorkflowV4)
+		workflowV4.POST("/registryhook/:workflowName/:hookName/webhook", RegistryHookEventHandler)
+		workflowV4.POST("/trigger/pause", PauseProjectTriggers)
+		workflowV4.POST("/trigger/resume", ResumeProjectTriggers)
+		workflowV4.POST("/trigger/:workflowName/pause", PauseWorkflowV4Triggers)
+		workflowV4.POST("/trigger/:workflowName/resume", ResumeWorkflowV4Triggers)
 		workflowV4.GET("/cron/preset", GetCronForWorkflowV4Preset)
 		workflowV4.GET("/cron", ListCronForWorkflowV4)
+		workflowV4.GET("/cron/preview", PreviewCronForWorkflowV4)
 		workflowV4.POST("/cron/:workflowName", CreateCronForWorkflowV4)
 		workflowV4.PUT("/cron", UpdateCronForWorkflowV4)
 		workflowV4.DELETE("/cron/:workflowName/trigger/:cronID", DeleteCronForWorkflowV4)
@@ -226,7 +258,9 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		taskV4.POST("", CreateWorkflowTaskV4)
 		taskV4.GET("/filter/workflow/:name", GetWorkflowTaskFilters)
 		taskV4.GET("", ListWorkflowTaskV4ByFilter)
+		taskV4.GET("/export", ExportWorkflowTaskV4)
 		taskV4.GET("/workflow/:workflowName/task/:taskID", GetWorkflowTaskV4)
+		taskV4.GET("/workflow/:workflowName/task/:taskID/timeline", GetWorkflowTaskV4Timeline)
 		taskV4.DELETE("/workflow/:workflowName/task/:taskID", CancelWorkflowTaskV4)
 		taskV4.GET("/clone/workflow/:workflowName/task/:taskID", CloneWorkflowTaskV4)
 		taskV4.POST("/retry/workflow/:workflowName/task/:taskID", RetryWorkflowTaskV4)
@@ -234,7 +268,11 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		taskV4.POST("/debug/:workflowName/task/:taskID", EnableDebugWorkflowTaskV4)
 		taskV4.DELETE("/debug/:workflowName/:jobName/task/:taskID/:position", StopDebugWorkflowTaskJobV4)
 		taskV4.POST("/approve", ApproveStage)
+		taskV4.GET("/approve/records/export", ExportApprovalRecords)
+		taskV4.GET("/approve/records/verify", VerifyApprovalRecords)
 		taskV4.GET("/workflow/:workflowName/taskId/:taskId/job/:jobName", GetWorkflowV4ArtifactFileContent)
+		taskV4.GET("/workflow/:workflowName/task/:taskID/job/:jobName/publish", ListWorkflowTaskV4PublishedFiles)
+		taskV4.GET("/workflow/:workflowName/task/:taskID/job/:jobName/publish/download", DownloadWorkflowTaskV4PublishedFile)
 		taskV4.POST("/trigger", CreateWorkflowTaskV4ByBuildInTrigger)
 	}
 
@@ -260,6 +298,9 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		plugin.POST("/enterprise", UpsertEnterprisePluginRepository)
 		plugin.GET("", ListUnofficalPluginRepositories)
 		plugin.DELETE("/:id", DeletePluginRepo)
+		plugin.GET("/project/:name", GetProjectPlugins)
+		plugin.PUT("/project/:name", UpdateProjectPlugins)
+		plugin.DELETE("/project/:name", EnableAllProjectPlugins)
 	}
 
 	bundles := router.Group("bundle-resources")
@@ -17,76 +17,129 @@ limitations under the License.
 package handler
 
 import (
-	"net/http"
+	"fmt"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/go-github/v35/github"
-	"github.com/hashicorp/go-multierror"
-	"github.com/xanzy/go-gitlab"
-	"go.uber.org/zap"
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/webhook"
 	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
-	"github.com/koderover/zadig/pkg/tool/codehub"
-	"github.com/koderover/zadig/pkg/tool/gitee"
+	e "github.com/koderover/zadig/pkg/tool/errors"
 )
 
 func ProcessWebHook(c *gin.Context) {
 	ctx := internalhandler.NewContext(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
 
+	source := webhook.GetWebhookSource(c.Request)
+	// the webhooks of all the hooks are sent to the same endpoint, the ip allowlists are checked per hook after
+	// the hooks are matched so that the allowlist of a hook takes precedence over the global one
+	if country := webhook.GetTriggerSourceCountry(c.Request.Header, c.RemoteIP()); !webhook.AllowTriggerCountry(country) {
+		rejectTriggerSource(c, ctx, "", "webhook", source, country)
+		return
+	}
+	if !webhook.AllowWebhookRequest(source, source, c.ClientIP()) {
+		c.Header("Retry-After", "1")
+		ctx.Err = e.ErrTooManyRequests.AddDesc(fmt.Sprintf("too many webhook requests of %s", source))
+		return
+	}
+
 	payload, err := c.GetRawData()
 	if err != nil {
 		ctx.Err = err
 		return
 	}
-	if github.WebHookType(c.Request) != "" {
-		ctx.Err = processGithub(payload, c.Request, ctx.RequestID, ctx.Logger)
-	} else if gitlab.HookEventType(c.Request) != "" {
-		ctx.Err = webhook.ProcessGitlabHook(payload, c.Request, ctx.RequestID, ctx.Logger)
-	} else if codehub.HookEventType(c.Request) != "" {
-		ctx.Err = webhook.ProcessCodehubHook(payload, c.Request, ctx.RequestID, ctx.Logger)
-	} else if gitee.HookEventType(c.Request) != "" {
-		ctx.Err = webhook.ProcessGiteeHook(payload, c.Request, ctx.RequestID, ctx.Logger)
-	} else {
-		ctx.Err = webhook.ProcessGerritHook(payload, c.Request, ctx.RequestID, ctx.Logger)
+	ctx.Err = webhook.ProcessWebhook(payload, c.Request, ctx.RequestID, c.ClientIP(), ctx.Logger)
+}
+
+// denyTriggerSource rejects the request and records an operation log if the client is not allowed by the country
+// restriction or the allowlist of the hook, the global trigger allowlist is used if the allowlist is empty
+func denyTriggerSource(c *gin.Context, ctx *internalhandler.Context, projectName, function, name string, allowlist []string) bool {
+	country := webhook.GetTriggerSourceCountry(c.Request.Header, c.RemoteIP())
+	if webhook.AllowTriggerSource(c.ClientIP(), country, allowlist) {
+		return false
 	}
+	rejectTriggerSource(c, ctx, projectName, function, name, country)
+	return true
 }
 
-func processGithub(payload []byte, req *http.Request, requestID string, log *zap.SugaredLogger) error {
-	errs := &multierror.Error{}
+func rejectTriggerSource(c *gin.Context, ctx *internalhandler.Context, projectName, function, name, country string) {
+	clientIP := c.ClientIP()
+	ctx.Logger.Warnf("trigger request from %s (country: %q) to %s %s is rejected by the trigger restrictions", clientIP, country, function, name)
+	internalhandler.InsertOperationLog(c, clientIP, projectName, "拒绝触发", function, name, fmt.Sprintf("country:%s", country), ctx.Logger)
+	ctx.Err = e.ErrForbidden.AddDesc(fmt.Sprintf("source %s is not allowed to trigger %s", clientIP, name))
+}
+
+func ListWebhookDeliveries(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
 
-	// trigger classic pipeline
-	_, err := webhook.ProcessGithubHook(payload, req, requestID, log)
 	if err != nil {
-		log.Errorf("error happens to trigger classic pipeline %v", err)
-		errs = multierror.Append(errs, err)
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
 	}
 
-	// trigger workflow
-	err = webhook.ProcessGithubWebHook(payload, req, requestID, log)
+	args := new(webhook.ListWebhookDeliveriesArgs)
+	if err := c.ShouldBindQuery(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc(err.Error())
+		return
+	}
 
-	if err != nil {
-		log.Errorf("error happens to trigger workflow %v", err)
-		errs = multierror.Append(errs, err)
+	// authorization check
+	if !ctx.Resources.IsSystemAdmin && !canViewProjectWorkflows(ctx, args.ProjectName) {
+		ctx.UnAuthorized = true
+		return
 	}
-	//测试管理webhook
-	err = webhook.ProcessGithubWebHookForTest(payload, req, requestID, log)
+
+	ctx.Resp, ctx.Err = webhook.ListWebhookDeliveries(args)
+}
+
+func GetWebhookDelivery(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
 	if err != nil {
-		log.Errorf("error happens to trigger ProcessGithubWebHookForTest %v", err)
-		errs = multierror.Append(errs, err)
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
 	}
-	// webhooks for scanning task
-	err = webhook.ProcessGithubWebhookForScanning(payload, req, requestID, log)
-	if err != nil {
-		log.Errorf("error happens to trigger Scanning for github %v", err)
-		errs = multierror.Append(errs, err)
+
+	projectName := c.Query("projectName")
+	// authorization check
+	if !ctx.Resources.IsSystemAdmin && !canViewProjectWorkflows(ctx, projectName) {
+		ctx.UnAuthorized = true
+		return
 	}
-	// webhooks for workflow v4
-	err = webhook.ProcessGithubWebHookForWorkflowV4(payload, req, requestID, log)
+
+	ctx.Resp, ctx.Err = webhook.GetWebhookDelivery(c.Param("id"), projectName)
+}
+
+func RedeliverWebhook(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
 	if err != nil {
-		log.Errorf("error happens to trigger workflowV4 for github %v", err)
-		errs = multierror.Append(errs, err)
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	// the event may trigger the workflows of any project, only the system admin can redeliver it
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	ctx.Resp, ctx.Err = webhook.RedeliverWebhook(c.Param("id"), ctx.RequestID, ctx.Logger)
+}
+
+func canViewProjectWorkflows(ctx *internalhandler.Context, projectName string) bool {
+	if projectName == "" {
+		return false
+	}
+	authInfo, ok := ctx.Resources.ProjectAuthInfo[projectName]
+	if !ok {
+		return false
 	}
-	return errs.ErrorOrNil()
+	return authInfo.IsProjectAdmin || authInfo.Workflow.View
 }
@@ -21,6 +21,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
+	"os"
 	"strconv"
 	"strings"
 
@@ -28,6 +30,7 @@ import (
 
 	"github.com/koderover/zadig/pkg/types"
 
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow"
 	"github.com/koderover/zadig/pkg/setting"
@@ -95,9 +98,10 @@ func CreateWorkflowTaskV4(c *gin.Context) {
 	}
 
 	ctx.Resp, ctx.Err = workflow.CreateWorkflowTaskV4(&workflow.CreateWorkflowTaskV4Args{
-		Name:    ctx.UserName,
-		Account: ctx.Account,
-		UserID:  ctx.UserID,
+		Name:           ctx.UserName,
+		Account:        ctx.Account,
+		UserID:         ctx.UserID,
+		IdempotencyKey: c.GetHeader(setting.IdempotencyKeyHeader),
 	}, args, ctx.Logger)
 }
 
@@ -169,6 +173,74 @@ func ListWorkflowTaskV4ByFilter(c *gin.Context) {
 	ctx.Err = err
 }
 
+func ExportWorkflowTaskV4(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	args := &workflow.TaskExportArgs{}
+	if err := c.ShouldBindQuery(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	// authorization check
+	if !ctx.Resources.IsSystemAdmin {
+		if _, ok := ctx.Resources.ProjectAuthInfo[args.ProjectName]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+
+		if !ctx.Resources.ProjectAuthInfo[args.ProjectName].IsProjectAdmin &&
+			!ctx.Resources.ProjectAuthInfo[args.ProjectName].Workflow.View {
+			// check if the permission is given by collaboration mode
+			permitted, err := internalhandler.GetCollaborationModePermission(ctx.UserID, args.ProjectName, types.ResourceTypeWorkflow, args.WorkflowName, types.WorkflowActionView)
+			if err != nil || !permitted {
+				ctx.UnAuthorized = true
+				return
+			}
+		}
+	}
+
+	exporter, err := workflow.NewWorkflowTaskExporter(args, ctx.Logger)
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+	// the export is spooled to a temp file so that a failure in the middle is returned instead of a partial file
+	file, err := os.CreateTemp("", "workflow-task-export-*")
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+	defer func() {
+		file.Close()
+		os.Remove(file.Name())
+	}()
+	if err := exporter.Export(c.Request.Context(), file); err != nil {
+		ctx.Logger.Errorf("failed to export tasks of workflow %s, err: %s", args.WorkflowName, err)
+		ctx.Err = err
+		return
+	}
+	info, err := file.Stat()
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		ctx.Err = err
+		return
+	}
+	c.DataFromReader(http.StatusOK, info.Size(), exporter.ContentType, file, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="%s"`, exporter.FileName),
+	})
+}
+
 func GetWorkflowTaskV4(c *gin.Context) {
 	ctx, err := internalhandler.NewContextWithAuthorization(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
@@ -216,6 +288,53 @@ func GetWorkflowTaskV4(c *gin.Context) {
 	ctx.Resp, ctx.Err = workflow.GetWorkflowTaskV4(workflowName, taskID, ctx.Logger)
 }
 
+// GetWorkflowTaskV4Timeline returns the phase durations of the jobs in the task
+func GetWorkflowTaskV4Timeline(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	taskID, err := strconv.ParseInt(c.Param("taskID"), 10, 64)
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid task id")
+		return
+	}
+
+	workflowName := c.Param("workflowName")
+
+	w, err := workflow.FindWorkflowV4Raw(workflowName, ctx.Logger)
+	if err != nil {
+		ctx.Logger.Errorf("GetWorkflowTaskV4Timeline error: %v", err)
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	// authorization check
+	if !ctx.Resources.IsSystemAdmin {
+		if _, ok := ctx.Resources.ProjectAuthInfo[w.Project]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+
+		if !ctx.Resources.ProjectAuthInfo[w.Project].IsProjectAdmin &&
+			!ctx.Resources.ProjectAuthInfo[w.Project].Workflow.View {
+			// check if the permission is given by collaboration mode
+			permitted, err := internalhandler.GetCollaborationModePermission(ctx.UserID, w.Project, types.ResourceTypeWorkflow, w.Name, types.WorkflowActionView)
+			if err != nil || !permitted {
+				ctx.UnAuthorized = true
+				return
+			}
+		}
+	}
+
+	ctx.Resp, ctx.Err = workflow.GetWorkflowTaskV4Timeline(workflowName, taskID, ctx.Logger)
+}
+
 func CancelWorkflowTaskV4(c *gin.Context) {
 	ctx, err := internalhandler.NewContextWithAuthorization(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
@@ -519,7 +638,7 @@ func ApproveStage(c *gin.Context) {
 		return
 	}
 
-	ctx.Err = workflow.ApproveStage(args.WorkflowName, args.StageName, ctx.UserName, ctx.UserID, args.Comment, args.TaskID, args.Approve, ctx.Logger)
+	ctx.Err = workflow.ApproveStage(args.WorkflowName, args.StageName, ctx.UserName, ctx.UserID, args.Comment, args.TaskID, args.Approve, config.ApprovalSourceZadig, ctx.Logger)
 }
 
 func GetWorkflowV4ArtifactFileContent(c *gin.Context) {
@@ -21,6 +21,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"gopkg.in/yaml.v3"
@@ -29,6 +31,8 @@ import (
 	"github.com/koderover/zadig/pkg/types"
 
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/webhook"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow"
 	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
 	"github.com/koderover/zadig/pkg/tool/errors"
@@ -83,6 +87,10 @@ func CreateWorkflowV4(c *gin.Context) {
 
 	args := new(commonmodels.WorkflowV4)
 	data := getBody(c)
+	if err := workflow.ValidateWorkflowV4Schema([]byte(data)); err != nil {
+		ctx.Err = err
+		return
+	}
 	if err := yaml.Unmarshal([]byte(data), args); err != nil {
 		log.Errorf("CreateWorkflowv4 yaml.Unmarshal err : %s", err)
 		ctx.Err = e.ErrInvalidParam.AddDesc(err.Error())
@@ -174,6 +182,55 @@ func GetWorkflowTasksCustomFields(c *gin.Context) {
 	ctx.Resp, ctx.Err = workflow.GetWorkflowTasksCustomFields(projectName, workflowName, ctx.Logger)
 }
 
+func GetProjectWorkflowCustomFields(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	projectName := c.Query("projectName")
+	if projectName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("projectName can not be empty")
+		return
+	}
+
+	ctx.Resp, ctx.Err = workflow.GetProjectWorkflowCustomFields(projectName, ctx.Logger)
+}
+
+func UpdateProjectWorkflowCustomFields(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	projectKey := c.Query("projectName")
+	if projectKey == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("projectName can not be empty")
+		return
+	}
+	args := new(commonmodels.CustomField)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc(err.Error())
+		return
+	}
+
+	// authorization check
+	if !ctx.Resources.IsSystemAdmin {
+		if _, ok := ctx.Resources.ProjectAuthInfo[projectKey]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+		if !ctx.Resources.ProjectAuthInfo[projectKey].IsProjectAdmin {
+			ctx.UnAuthorized = true
+			return
+		}
+	}
+
+	ctx.Err = workflow.UpdateProjectWorkflowCustomFields(projectKey, ctx.UserName, args, ctx.Logger)
+}
+
 func LintWorkflowV4(c *gin.Context) {
 	ctx := internalhandler.NewContext(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
@@ -183,6 +240,9 @@ func LintWorkflowV4(c *gin.Context) {
 	if err != nil {
 		log.Errorf("CreateWorkflowv4 c.GetRawData() err : %s", err)
 	}
+	if ctx.Err = workflow.ValidateWorkflowV4Schema(data); ctx.Err != nil {
+		return
+	}
 	if err = yaml.Unmarshal(data, args); err != nil {
 		log.Errorf("CreateWorkflowv4 json.Unmarshal err : %s", err)
 	}
@@ -193,7 +253,121 @@ func LintWorkflowV4(c *gin.Context) {
 		ctx.Err = e.ErrInvalidParam.AddDesc(err.Error())
 		return
 	}
-	ctx.Err = workflow.LintWorkflowV4(args, ctx.Logger)
+	if ctx.Err = workflow.LintWorkflowV4(args, ctx.Logger); ctx.Err != nil {
+		return
+	}
+	if ctx.Err = workflow.LintWorkflowV4OnSave(args, ctx.Logger); ctx.Err != nil {
+		return
+	}
+	ctx.Err = workflow.LintWorkflowV4Policies(args, ctx.Logger)
+}
+
+// GetWorkflowV4Schema returns the JSON Schema of the workflow yaml, the current version is returned if the version is not set
+func GetWorkflowV4Schema(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = workflow.GetWorkflowV4Schema(c.Query("version"))
+}
+
+// GetWorkflowV4Graph returns the stage and job graph of the workflow as json, or in the graphviz dot language if
+// the format is dot
+func GetWorkflowV4Graph(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	projectKey := c.Query("projectName")
+	if projectKey == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("projectName can not be empty")
+		return
+	}
+
+	// authorization check
+	if !ctx.Resources.IsSystemAdmin {
+		if _, ok := ctx.Resources.ProjectAuthInfo[projectKey]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+
+		if !ctx.Resources.ProjectAuthInfo[projectKey].IsProjectAdmin &&
+			!ctx.Resources.ProjectAuthInfo[projectKey].Workflow.View {
+			// check if the permission is given by collaboration mode
+			permitted, err := internalhandler.GetCollaborationModePermission(ctx.UserID, projectKey, types.ResourceTypeWorkflow, c.Param("name"), types.WorkflowActionView)
+			if err != nil || !permitted {
+				ctx.UnAuthorized = true
+				return
+			}
+		}
+	}
+
+	graph, err := workflow.GetWorkflowV4Graph(projectKey, c.Param("name"), ctx.Logger)
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+	switch c.Query("format") {
+	case "", "json":
+		ctx.Resp = graph
+	case "dot":
+		c.Data(http.StatusOK, "text/vnd.graphviz; charset=utf-8", []byte(graph.DOT()))
+	default:
+		ctx.Err = e.ErrInvalidParam.AddDesc("format should be json or dot")
+	}
+}
+
+// GetProjectWorkflowDependencies returns which workflows of the project trigger each other, build or deploy the
+// same services and deploy to the same envs
+func GetProjectWorkflowDependencies(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	projectKey := c.Query("projectName")
+	if projectKey == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("projectName can not be empty")
+		return
+	}
+
+	// authorization check
+	if !ctx.Resources.IsSystemAdmin {
+		if _, ok := ctx.Resources.ProjectAuthInfo[projectKey]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+
+		if !ctx.Resources.ProjectAuthInfo[projectKey].IsProjectAdmin &&
+			!ctx.Resources.ProjectAuthInfo[projectKey].Workflow.View {
+			ctx.UnAuthorized = true
+			return
+		}
+	}
+
+	// the workflows of other projects are only shown if the user can view them, nil means all of them
+	var visibleProjects []string
+	if !ctx.Resources.IsSystemAdmin {
+		projects, found, err := internalhandler.ListAuthorizedProjectsByResourceAndVerb(ctx.UserID, types.ResourceTypeWorkflow, types.WorkflowActionView)
+		if err != nil {
+			ctx.Err = e.ErrInternalError.AddErr(err)
+			return
+		}
+		visibleProjects = []string{projectKey}
+		if found {
+			visibleProjects = append(visibleProjects, projects...)
+		}
+	}
+
+	ctx.Resp, ctx.Err = workflow.GetProjectWorkflowDependencies(projectKey, visibleProjects, ctx.Logger)
 }
 
 func ListWorkflowV4(c *gin.Context) {
@@ -287,6 +461,10 @@ func UpdateWorkflowV4(c *gin.Context) {
 
 	args := new(commonmodels.WorkflowV4)
 	data := getBody(c)
+	if err := workflow.ValidateWorkflowV4Schema([]byte(data)); err != nil {
+		ctx.Err = err
+		return
+	}
 	if err := yaml.Unmarshal([]byte(data), args); err != nil {
 		log.Errorf("UpdateWorkflowV4 yaml.Unmarshal err : %s", err)
 		ctx.Err = e.ErrInvalidParam.AddDesc(err.Error())
@@ -349,7 +527,102 @@ func DeleteWorkflowV4(c *gin.Context) {
 		}
 	}
 
-	ctx.Err = workflow.DeleteWorkflowV4(c.Param("name"), ctx.Logger)
+	ctx.Err = workflow.DeleteWorkflowV4(c.Param("name"), ctx.UserName, ctx.Logger)
+}
+
+func ListWorkflowV4Trash(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	projectKey := c.Query("projectName")
+	if projectKey == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("projectName can not be empty")
+		return
+	}
+
+	// authorization check
+	if !ctx.Resources.IsSystemAdmin {
+		if _, ok := ctx.Resources.ProjectAuthInfo[projectKey]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+	}
+
+	ctx.Resp, ctx.Err = workflow.ListWorkflowV4Trash(projectKey, ctx.Logger)
+}
+
+func RestoreWorkflowV4(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	trash, err := workflow.FindWorkflowV4Trash(c.Param("name"), ctx.Logger)
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, trash.ProjectName, "恢复", "自定义工作流", trash.WorkflowName, "", ctx.Logger)
+
+	// authorization check
+	if !ctx.Resources.IsSystemAdmin {
+		if _, ok := ctx.Resources.ProjectAuthInfo[trash.ProjectName]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+
+		if !ctx.Resources.ProjectAuthInfo[trash.ProjectName].IsProjectAdmin &&
+			!ctx.Resources.ProjectAuthInfo[trash.ProjectName].Workflow.Edit {
+			ctx.UnAuthorized = true
+			return
+		}
+	}
+
+	ctx.Err = workflow.RestoreWorkflowV4(trash.WorkflowName, ctx.Logger)
+}
+
+func PurgeWorkflowV4(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	trash, err := workflow.FindWorkflowV4Trash(c.Param("name"), ctx.Logger)
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, trash.ProjectName, "彻底删除", "自定义工作流", trash.WorkflowName, "", ctx.Logger)
+
+	// authorization check
+	if !ctx.Resources.IsSystemAdmin {
+		if _, ok := ctx.Resources.ProjectAuthInfo[trash.ProjectName]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+
+		if !ctx.Resources.ProjectAuthInfo[trash.ProjectName].IsProjectAdmin &&
+			!ctx.Resources.ProjectAuthInfo[trash.ProjectName].Workflow.Edit {
+			ctx.UnAuthorized = true
+			return
+		}
+	}
+
+	ctx.Err = workflow.PurgeWorkflowV4(trash.WorkflowName, ctx.Logger)
 }
 
 func FindWorkflowV4(c *gin.Context) {
@@ -388,6 +661,7 @@ func FindWorkflowV4(c *gin.Context) {
 		}
 	}
 
+	workflow.MaskRegistryHookSecrets(resp)
 	c.YAML(200, resp)
 }
 
@@ -978,24 +1252,30 @@ func DeleteGeneralHookForWorkflowV4(c *gin.Context) {
 func GeneralHookEventHandler(c *gin.Context) {
 	ctx := internalhandler.NewContext(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
-	ctx.Err = workflow.GeneralHookEventHandler(c.Param("workflowName"), c.Param("hookName"), ctx.Logger)
-}
-
-func GetCronForWorkflowV4Preset(c *gin.Context) {
-	ctx := internalhandler.NewContext(c)
-	defer func() { internalhandler.JSONResponse(c, ctx) }()
-
-	ctx.Resp, ctx.Err = workflow.GetCronForWorkflowV4Preset(c.Query("workflowName"), c.Query("cronID"), ctx.Logger)
-}
-
-func ListCronForWorkflowV4(c *gin.Context) {
-	ctx := internalhandler.NewContext(c)
-	defer func() { internalhandler.JSONResponse(c, ctx) }()
 
-	ctx.Resp, ctx.Err = workflow.ListCronForWorkflowV4(c.Query("workflowName"), ctx.Logger)
+	hook := fmt.Sprintf("%s/%s", c.Param("workflowName"), c.Param("hookName"))
+	var projectName string
+	var allowlist []string
+	if w, err := workflow.FindWorkflowV4Raw(c.Param("workflowName"), ctx.Logger); err == nil {
+		projectName = w.Project
+		for _, generalHook := range w.GeneralHookCtls {
+			if generalHook.Name == c.Param("hookName") {
+				allowlist = generalHook.IPAllowlist
+			}
+		}
+	}
+	if denyTriggerSource(c, ctx, projectName, "自定义工作流-generalhook", hook, allowlist) {
+		return
+	}
+	if !webhook.AllowWebhookRequest(webhook.RateLimitSourceGeneralHook, hook, c.ClientIP()) {
+		c.Header("Retry-After", "1")
+		ctx.Err = e.ErrTooManyRequests.AddDesc(fmt.Sprintf("too many requests to general hook %s", hook))
+		return
+	}
+	ctx.Err = workflow.GeneralHookEventHandler(c.Param("workflowName"), c.Param("hookName"), ctx.Logger)
 }
 
-func CreateCronForWorkflowV4(c *gin.Context) {
+func CreateRegistryHookForWorkflowV4(c *gin.Context) {
 	ctx, err := internalhandler.NewContextWithAuthorization(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
 
@@ -1006,18 +1286,18 @@ func CreateCronForWorkflowV4(c *gin.Context) {
 		return
 	}
 
-	req := new(commonmodels.Cronjob)
-	if err := c.ShouldBindJSON(req); err != nil {
+	hook := new(commonmodels.RegistryHook)
+	if err := c.ShouldBindJSON(hook); err != nil {
 		ctx.Err = e.ErrInvalidParam.AddDesc(err.Error())
 		return
 	}
 	w, err := workflow.FindWorkflowV4Raw(c.Param("workflowName"), ctx.Logger)
 	if err != nil {
-		ctx.Logger.Errorf("CreateCronForWorkflowV4 error: %v", err)
-		ctx.Err = e.ErrUpsertCronjob.AddErr(err)
+		ctx.Logger.Errorf("CreateRegistryHookForWorkflowV4 error: %v", err)
+		ctx.Err = e.ErrCreateRegistryHook.AddErr(err)
 		return
 	}
-	internalhandler.InsertOperationLog(c, ctx.UserName, w.Project, "新建", "自定义工作流-cron", w.Name, getBody(c), ctx.Logger)
+	internalhandler.InsertOperationLog(c, ctx.UserName, w.Project, "新建", "自定义工作流-registryhook", w.Name, getBody(c), ctx.Logger)
 
 	// authorization check
 	if !ctx.Resources.IsSystemAdmin {
@@ -1037,10 +1317,10 @@ func CreateCronForWorkflowV4(c *gin.Context) {
 		}
 	}
 
-	ctx.Err = workflow.CreateCronForWorkflowV4(c.Param("workflowName"), req, ctx.Logger)
+	ctx.Resp, ctx.Err = workflow.CreateRegistryHookForWorkflowV4(c.Param("workflowName"), hook, ctx.Logger)
 }
 
-func UpdateCronForWorkflowV4(c *gin.Context) {
+func GetRegistryHookForWorkflowV4Preset(c *gin.Context) {
 	ctx, err := internalhandler.NewContextWithAuthorization(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
 
@@ -1051,24 +1331,24 @@ func UpdateCronForWorkflowV4(c *gin.Context) {
 		return
 	}
 
-	req := new(commonmodels.Cronjob)
-	if err := c.ShouldBindJSON(req); err != nil {
-		ctx.Err = e.ErrInvalidParam.AddDesc(err.Error())
+	w, err := workflow.FindWorkflowV4Raw(c.Query("workflowName"), ctx.Logger)
+	if err != nil {
+		ctx.Logger.Errorf("GetRegistryHookForWorkflowV4Preset error: %v", err)
+		ctx.Err = e.ErrGetRegistryHook.AddErr(err)
 		return
 	}
-	internalhandler.InsertOperationLog(c, ctx.UserName, req.WorkflowV4Args.Project, "更新", "自定义工作流-cron", req.WorkflowV4Args.Name, getBody(c), ctx.Logger)
 
 	// authorization check
 	if !ctx.Resources.IsSystemAdmin {
-		if _, ok := ctx.Resources.ProjectAuthInfo[req.WorkflowV4Args.Project]; !ok {
+		if _, ok := ctx.Resources.ProjectAuthInfo[w.Project]; !ok {
 			ctx.UnAuthorized = true
 			return
 		}
 
-		if !ctx.Resources.ProjectAuthInfo[req.WorkflowV4Args.Project].IsProjectAdmin &&
-			!ctx.Resources.ProjectAuthInfo[req.WorkflowV4Args.Project].Workflow.Edit {
+		if !ctx.Resources.ProjectAuthInfo[w.Project].IsProjectAdmin &&
+			!ctx.Resources.ProjectAuthInfo[w.Project].Workflow.Edit {
 			// check if the permission is given by collaboration mode
-			permitted, err := internalhandler.GetCollaborationModePermission(ctx.UserID, req.WorkflowV4Args.Project, types.ResourceTypeWorkflow, req.WorkflowV4Args.Name, types.WorkflowActionEdit)
+			permitted, err := internalhandler.GetCollaborationModePermission(ctx.UserID, w.Project, types.ResourceTypeWorkflow, w.Name, types.WorkflowActionEdit)
 			if err != nil || !permitted {
 				ctx.UnAuthorized = true
 				return
@@ -1076,10 +1356,10 @@ func UpdateCronForWorkflowV4(c *gin.Context) {
 		}
 	}
 
-	ctx.Err = workflow.UpdateCronForWorkflowV4(req, ctx.Logger)
+	ctx.Resp, ctx.Err = workflow.GetRegistryHookForWorkflowV4Preset(c.Query("workflowName"), c.Query("hookName"), ctx.Logger)
 }
 
-func DeleteCronForWorkflowV4(c *gin.Context) {
+func ListRegistryHookForWorkflowV4(c *gin.Context) {
 	ctx, err := internalhandler.NewContextWithAuthorization(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
 
@@ -1092,11 +1372,10 @@ func DeleteCronForWorkflowV4(c *gin.Context) {
 
 	w, err := workflow.FindWorkflowV4Raw(c.Param("workflowName"), ctx.Logger)
 	if err != nil {
-		ctx.Logger.Errorf("CreateCronForWorkflowV4 error: %v", err)
-		ctx.Err = e.ErrUpsertCronjob.AddErr(err)
+		ctx.Logger.Errorf("ListRegistryHookForWorkflowV4 error: %v", err)
+		ctx.Err = e.ErrListRegistryHook.AddErr(err)
 		return
 	}
-	internalhandler.InsertOperationLog(c, ctx.UserName, w.Project, "删除", "自定义工作流-cron", w.Name, "", ctx.Logger)
 
 	// authorization check
 	if !ctx.Resources.IsSystemAdmin {
@@ -1116,11 +1395,274 @@ func DeleteCronForWorkflowV4(c *gin.Context) {
 		}
 	}
 
-	ctx.Err = workflow.DeleteCronForWorkflowV4(c.Param("workflowName"), c.Param("cronID"), ctx.Logger)
+	ctx.Resp, ctx.Err = workflow.ListRegistryHookForWorkflowV4(c.Param("workflowName"), ctx.Logger)
 }
 
-func GetPatchParams(c *gin.Context) {
-	ctx := internalhandler.NewContext(c)
+func UpdateRegistryHookForWorkflowV4(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	hook := new(commonmodels.RegistryHook)
+	if err := c.ShouldBindJSON(hook); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc(err.Error())
+		return
+	}
+	w, err := workflow.FindWorkflowV4Raw(c.Param("workflowName"), ctx.Logger)
+	if err != nil {
+		ctx.Logger.Errorf("UpdateRegistryHookForWorkflowV4 error: %v", err)
+		ctx.Err = e.ErrUpdateRegistryHook.AddErr(err)
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, w.Project, "更新", "自定义工作流-registryhook", w.Name, getBody(c), ctx.Logger)
+
+	// authorization check
+	if !ctx.Resources.IsSystemAdmin {
+		if _, ok := ctx.Resources.ProjectAuthInfo[w.Project]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+
+		if !ctx.Resources.ProjectAuthInfo[w.Project].IsProjectAdmin &&
+			!ctx.Resources.ProjectAuthInfo[w.Project].Workflow.Edit {
+			// check if the permission is given by collaboration mode
+			permitted, err := internalhandler.GetCollaborationModePermission(ctx.UserID, w.Project, types.ResourceTypeWorkflow, w.Name, types.WorkflowActionEdit)
+			if err != nil || !permitted {
+				ctx.UnAuthorized = true
+				return
+			}
+		}
+	}
+
+	ctx.Err = workflow.UpdateRegistryHookForWorkflowV4(c.Param("workflowName"), hook, ctx.Logger)
+}
+
+func DeleteRegistryHookForWorkflowV4(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	w, err := workflow.FindWorkflowV4Raw(c.Param("workflowName"), ctx.Logger)
+	if err != nil {
+		ctx.Logger.Errorf("DeleteRegistryHookForWorkflowV4 error: %v", err)
+		ctx.Err = e.ErrDeleteRegistryHook.AddErr(err)
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, w.Project, "删除", "自定义工作流-registryhook", w.Name, "", ctx.Logger)
+
+	// authorization check
+	if !ctx.Resources.IsSystemAdmin {
+		if _, ok := ctx.Resources.ProjectAuthInfo[w.Project]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+
+		if !ctx.Resources.ProjectAuthInfo[w.Project].IsProjectAdmin &&
+			!ctx.Resources.ProjectAuthInfo[w.Project].Workflow.Edit {
+			// check if the permission is given by collaboration mode
+			permitted, err := internalhandler.GetCollaborationModePermission(ctx.UserID, w.Project, types.ResourceTypeWorkflow, w.Name, types.WorkflowActionEdit)
+			if err != nil || !permitted {
+				ctx.UnAuthorized = true
+				return
+			}
+		}
+	}
+
+	ctx.Err = workflow.DeleteRegistryHookForWorkflowV4(c.Param("workflowName"), c.Param("hookName"), ctx.Logger)
+}
+
+func RegistryHookEventHandler(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	hook := fmt.Sprintf("%s/%s", c.Param("workflowName"), c.Param("hookName"))
+	if denyTriggerSource(c, ctx, "", "自定义工作流-registryhook", hook, nil) {
+		return
+	}
+	if !webhook.AllowWebhookRequest(webhook.RateLimitSourceRegistryHook, hook, c.ClientIP()) {
+		c.Header("Retry-After", "1")
+		ctx.Err = e.ErrTooManyRequests.AddDesc(fmt.Sprintf("too many requests to registry hook %s", hook))
+		return
+	}
+
+	payload, err := c.GetRawData()
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	// harbor sends the auth header configured in the webhook policy, the other registries pass the token in the url
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if token == "" {
+		token = c.Query("token")
+	}
+	ctx.Err = workflow.RegistryHookEventHandler(c.Param("workflowName"), c.Param("hookName"), token, payload, ctx.Logger)
+}
+
+func GetCronForWorkflowV4Preset(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = workflow.GetCronForWorkflowV4Preset(c.Query("workflowName"), c.Query("cronID"), ctx.Logger)
+}
+
+func ListCronForWorkflowV4(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	ctx.Resp, ctx.Err = workflow.ListCronForWorkflowV4(c.Query("workflowName"), ctx.Logger)
+}
+
+func PreviewCronForWorkflowV4(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(workflow.CronPreviewArgs)
+	if err := c.ShouldBindQuery(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc(err.Error())
+		return
+	}
+
+	ctx.Resp, ctx.Err = workflow.PreviewCronSchedule(args)
+}
+
+func CreateCronForWorkflowV4(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	req := new(commonmodels.Cronjob)
+	if err := c.ShouldBindJSON(req); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc(err.Error())
+		return
+	}
+	w, err := workflow.FindWorkflowV4Raw(c.Param("workflowName"), ctx.Logger)
+	if err != nil {
+		ctx.Logger.Errorf("CreateCronForWorkflowV4 error: %v", err)
+		ctx.Err = e.ErrUpsertCronjob.AddErr(err)
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, w.Project, "新建", "自定义工作流-cron", w.Name, getBody(c), ctx.Logger)
+
+	// authorization check
+	if !ctx.Resources.IsSystemAdmin {
+		if _, ok := ctx.Resources.ProjectAuthInfo[w.Project]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+
+		if !ctx.Resources.ProjectAuthInfo[w.Project].IsProjectAdmin &&
+			!ctx.Resources.ProjectAuthInfo[w.Project].Workflow.Edit {
+			// check if the permission is given by collaboration mode
+			permitted, err := internalhandler.GetCollaborationModePermission(ctx.UserID, w.Project, types.ResourceTypeWorkflow, w.Name, types.WorkflowActionEdit)
+			if err != nil || !permitted {
+				ctx.UnAuthorized = true
+				return
+			}
+		}
+	}
+
+	ctx.Err = workflow.CreateCronForWorkflowV4(c.Param("workflowName"), req, ctx.Logger)
+}
+
+func UpdateCronForWorkflowV4(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	req := new(commonmodels.Cronjob)
+	if err := c.ShouldBindJSON(req); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc(err.Error())
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, req.WorkflowV4Args.Project, "更新", "自定义工作流-cron", req.WorkflowV4Args.Name, getBody(c), ctx.Logger)
+
+	// authorization check
+	if !ctx.Resources.IsSystemAdmin {
+		if _, ok := ctx.Resources.ProjectAuthInfo[req.WorkflowV4Args.Project]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+
+		if !ctx.Resources.ProjectAuthInfo[req.WorkflowV4Args.Project].IsProjectAdmin &&
+			!ctx.Resources.ProjectAuthInfo[req.WorkflowV4Args.Project].Workflow.Edit {
+			// check if the permission is given by collaboration mode
+			permitted, err := internalhandler.GetCollaborationModePermission(ctx.UserID, req.WorkflowV4Args.Project, types.ResourceTypeWorkflow, req.WorkflowV4Args.Name, types.WorkflowActionEdit)
+			if err != nil || !permitted {
+				ctx.UnAuthorized = true
+				return
+			}
+		}
+	}
+
+	ctx.Err = workflow.UpdateCronForWorkflowV4(req, ctx.Logger)
+}
+
+func DeleteCronForWorkflowV4(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	w, err := workflow.FindWorkflowV4Raw(c.Param("workflowName"), ctx.Logger)
+	if err != nil {
+		ctx.Logger.Errorf("CreateCronForWorkflowV4 error: %v", err)
+		ctx.Err = e.ErrUpsertCronjob.AddErr(err)
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, w.Project, "删除", "自定义工作流-cron", w.Name, "", ctx.Logger)
+
+	// authorization check
+	if !ctx.Resources.IsSystemAdmin {
+		if _, ok := ctx.Resources.ProjectAuthInfo[w.Project]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+
+		if !ctx.Resources.ProjectAuthInfo[w.Project].IsProjectAdmin &&
+			!ctx.Resources.ProjectAuthInfo[w.Project].Workflow.Edit {
+			// check if the permission is given by collaboration mode
+			permitted, err := internalhandler.GetCollaborationModePermission(ctx.UserID, w.Project, types.ResourceTypeWorkflow, w.Name, types.WorkflowActionEdit)
+			if err != nil || !permitted {
+				ctx.UnAuthorized = true
+				return
+			}
+		}
+	}
+
+	ctx.Err = workflow.DeleteCronForWorkflowV4(c.Param("workflowName"), c.Param("cronID"), ctx.Logger)
+}
+
+func GetPatchParams(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
 	req := &commonmodels.PatchItem{}
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -1144,6 +1686,19 @@ func GetWorkflowGlobalVars(c *gin.Context) {
 	ctx.Resp = workflow.GetWorkflowGlabalVars(args, c.Param("jobName"), ctx.Logger)
 }
 
+func ListWorkflowVariables(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(commonmodels.WorkflowV4)
+
+	if err := c.ShouldBindYAML(&args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc(err.Error())
+		return
+	}
+	ctx.Resp = workflow.ListWorkflowVariables(args, c.Param("jobName"), ctx.Logger)
+}
+
 func GetWorkflowRepoIndex(c *gin.Context) {
 	ctx := internalhandler.NewContext(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
@@ -1306,3 +1861,398 @@ func getBody(c *gin.Context) string {
 	}
 	return string(b)
 }
+
+type pauseTriggersReq struct {
+	Reason string `json:"reason"`
+}
+
+func PauseWorkflowV4Triggers(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	req := new(pauseTriggersReq)
+	data := getBody(c)
+	if data != "" {
+		if err := json.Unmarshal([]byte(data), req); err != nil {
+			ctx.Err = e.ErrInvalidParam.AddDesc(err.Error())
+			return
+		}
+	}
+	w, err := workflow.FindWorkflowV4Raw(c.Param("workflowName"), ctx.Logger)
+	if err != nil {
+		ctx.Logger.Errorf("PauseWorkflowV4Triggers error: %v", err)
+		ctx.Err = e.ErrFindWorkflow.AddErr(err)
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, w.Project, "暂停", "自定义工作流-触发器", w.Name, data, ctx.Logger)
+
+	// authorization check
+	if !ctx.Resources.IsSystemAdmin {
+		if _, ok := ctx.Resources.ProjectAuthInfo[w.Project]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+
+		if !ctx.Resources.ProjectAuthInfo[w.Project].IsProjectAdmin &&
+			!ctx.Resources.ProjectAuthInfo[w.Project].Workflow.Edit {
+			// check if the permission is given by collaboration mode
+			permitted, err := internalhandler.GetCollaborationModePermission(ctx.UserID, w.Project, types.ResourceTypeWorkflow, w.Name, types.WorkflowActionEdit)
+			if err != nil || !permitted {
+				ctx.UnAuthorized = true
+				return
+			}
+		}
+	}
+
+	ctx.Err = workflow.PauseWorkflowTriggers(w.Name, ctx.UserName, req.Reason, ctx.Logger)
+}
+
+func ResumeWorkflowV4Triggers(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	w, err := workflow.FindWorkflowV4Raw(c.Param("workflowName"), ctx.Logger)
+	if err != nil {
+		ctx.Logger.Errorf("ResumeWorkflowV4Triggers error: %v", err)
+		ctx.Err = e.ErrFindWorkflow.AddErr(err)
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, w.Project, "恢复", "自定义工作流-触发器", w.Name, "", ctx.Logger)
+
+	// authorization check
+	if !ctx.Resources.IsSystemAdmin {
+		if _, ok := ctx.Resources.ProjectAuthInfo[w.Project]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+
+		if !ctx.Resources.ProjectAuthInfo[w.Project].IsProjectAdmin &&
+			!ctx.Resources.ProjectAuthInfo[w.Project].Workflow.Edit {
+			// check if the permission is given by collaboration mode
+			permitted, err := internalhandler.GetCollaborationModePermission(ctx.UserID, w.Project, types.ResourceTypeWorkflow, w.Name, types.WorkflowActionEdit)
+			if err != nil || !permitted {
+				ctx.UnAuthorized = true
+				return
+			}
+		}
+	}
+
+	ctx.Err = workflow.ResumeWorkflowTriggers(w.Name, ctx.Logger)
+}
+
+func PauseProjectTriggers(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	projectKey := c.Query("projectName")
+	if projectKey == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("projectName can not be empty")
+		return
+	}
+	req := new(pauseTriggersReq)
+	data := getBody(c)
+	if data != "" {
+		if err := json.Unmarshal([]byte(data), req); err != nil {
+			ctx.Err = e.ErrInvalidParam.AddDesc(err.Error())
+			return
+		}
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, projectKey, "暂停", "项目-触发器", projectKey, data, ctx.Logger)
+
+	if !ctx.Resources.IsSystemAdmin {
+		if projectInfo, ok := ctx.Resources.ProjectAuthInfo[projectKey]; !ok || !projectInfo.IsProjectAdmin {
+			ctx.UnAuthorized = true
+			return
+		}
+	}
+
+	ctx.Resp, ctx.Err = workflow.PauseProjectTriggers(projectKey, ctx.UserName, req.Reason, ctx.Logger)
+}
+
+func ResumeProjectTriggers(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	projectKey := c.Query("projectName")
+	if projectKey == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("projectName can not be empty")
+		return
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, projectKey, "恢复", "项目-触发器", projectKey, "", ctx.Logger)
+
+	if !ctx.Resources.IsSystemAdmin {
+		if projectInfo, ok := ctx.Resources.ProjectAuthInfo[projectKey]; !ok || !projectInfo.IsProjectAdmin {
+			ctx.UnAuthorized = true
+			return
+		}
+	}
+
+	ctx.Resp, ctx.Err = workflow.ResumeProjectTriggers(projectKey, ctx.Logger)
+}
+
+// ListAssetReferences lists the workflow jobs using a build, testing, scanning, registry or cluster, only the
+// workflows of the projects the user has access to are returned
+func ListAssetReferences(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	assetType := commonservice.AssetType(c.Query("type"))
+	name := c.Query("name")
+	if name == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("name can not be empty")
+		return
+	}
+
+	references, err := commonservice.GetAssetReferences(assetType, c.Query("projectName"), name)
+	if err != nil {
+		ctx.Err = e.ErrListAssetReferences.AddErr(err)
+		return
+	}
+	if ctx.Resources.IsSystemAdmin {
+		ctx.Resp = references
+		return
+	}
+	resp := make([]*commonservice.AssetReference, 0)
+	for _, reference := range references {
+		if _, ok := ctx.Resources.ProjectAuthInfo[reference.ProjectName]; ok {
+			resp = append(resp, reference)
+		}
+	}
+	ctx.Resp = resp
+}
+
+func BatchPatchWorkflowV4(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	args := new(workflow.BatchPatchWorkflowV4Args)
+	data := getBody(c)
+	if err := json.Unmarshal([]byte(data), args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc(err.Error())
+		return
+	}
+
+	for _, name := range args.Workflows {
+		w, err := workflow.FindWorkflowV4Raw(name, ctx.Logger)
+		if err != nil {
+			ctx.Logger.Errorf("BatchPatchWorkflowV4 error: %v", err)
+			ctx.Err = e.ErrFindWorkflow.AddErr(err)
+			return
+		}
+		if !args.Preview {
+			internalhandler.InsertOperationLog(c, ctx.UserName, w.Project, "批量更新", "自定义工作流", w.Name, data, ctx.Logger)
+		}
+
+		// authorization check
+		if ctx.Resources.IsSystemAdmin {
+			continue
+		}
+		if _, ok := ctx.Resources.ProjectAuthInfo[w.Project]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+		if !ctx.Resources.ProjectAuthInfo[w.Project].IsProjectAdmin &&
+			!ctx.Resources.ProjectAuthInfo[w.Project].Workflow.Edit {
+			// check if the permission is given by collaboration mode
+			permitted, err := internalhandler.GetCollaborationModePermission(ctx.UserID, w.Project, types.ResourceTypeWorkflow, w.Name, types.WorkflowActionEdit)
+			if err != nil || !permitted {
+				ctx.UnAuthorized = true
+				return
+			}
+		}
+	}
+
+	ctx.Resp, ctx.Err = workflow.BatchPatchWorkflowV4(ctx.UserName, args, ctx.Logger)
+}
+
+func GetWorkflowV4CloneMapping(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	targetProject := c.Query("targetProjectName")
+	if targetProject == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("targetProjectName can not be empty")
+		return
+	}
+	w, err := workflow.FindWorkflowV4Raw(c.Param("name"), ctx.Logger)
+	if err != nil {
+		ctx.Logger.Errorf("GetWorkflowV4CloneMapping error: %v", err)
+		ctx.Err = e.ErrFindWorkflow.AddErr(err)
+		return
+	}
+
+	// authorization check
+	if !ctx.Resources.IsSystemAdmin {
+		if _, ok := ctx.Resources.ProjectAuthInfo[w.Project]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+		if _, ok := ctx.Resources.ProjectAuthInfo[targetProject]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+	}
+
+	ctx.Resp, ctx.Err = workflow.GetWorkflowV4CloneMapping(w.Name, targetProject, ctx.Logger)
+}
+
+func BulkCopyWorkflowV4(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	args := new(workflow.BulkCopyWorkflowArgs)
+	data := getBody(c)
+	if err := json.Unmarshal([]byte(data), args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc(err.Error())
+		return
+	}
+
+	for _, item := range args.Items {
+		targetProject := item.ProjectName
+		if item.TargetProjectName != "" {
+			targetProject = item.TargetProjectName
+		}
+		internalhandler.InsertOperationLog(c, ctx.UserName, targetProject, "复制", "自定义工作流", item.New, data, ctx.Logger)
+
+		// authorization check
+		if ctx.Resources.IsSystemAdmin {
+			continue
+		}
+		if _, ok := ctx.Resources.ProjectAuthInfo[item.ProjectName]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+		if _, ok := ctx.Resources.ProjectAuthInfo[targetProject]; !ok {
+			ctx.UnAuthorized = true
+			return
+		}
+		if !ctx.Resources.ProjectAuthInfo[targetProject].IsProjectAdmin &&
+			!ctx.Resources.ProjectAuthInfo[targetProject].Workflow.Create {
+			ctx.UnAuthorized = true
+			return
+		}
+	}
+
+	ctx.Err = workflow.BulkCopyWorkflowV4(*args, ctx.UserName, ctx.Logger)
+}
+
+func ListWorkflowTaskCounters(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	projectName := c.Query("projectName")
+	if projectName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("projectName can not be empty")
+		return
+	}
+	ctx.Resp, ctx.Err = workflow.ListWorkflowTaskCounters(projectName, ctx.Logger)
+}
+
+func GetWorkflowTaskCounter(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	ctx.Resp, ctx.Err = workflow.GetWorkflowTaskCounter(c.Param("name"), ctx.Logger)
+}
+
+type fastForwardCounterReq struct {
+	Seq int64 `json:"seq"`
+}
+
+func FastForwardWorkflowTaskCounter(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return
+	}
+
+	req := new(fastForwardCounterReq)
+	data := getBody(c)
+	if data != "" {
+		if err := json.Unmarshal([]byte(data), req); err != nil {
+			ctx.Err = e.ErrInvalidParam.AddDesc(err.Error())
+			return
+		}
+	}
+	internalhandler.InsertOperationLog(c, ctx.UserName, "", "更新", "自定义工作流-任务计数器", c.Param("name"), data, ctx.Logger)
+
+	ctx.Resp, ctx.Err = workflow.FastForwardWorkflowTaskCounter(c.Param("name"), req.Seq, ctx.Logger)
+}
@@ -153,7 +153,7 @@ func AutoCancelWorkflowV4Task(autoCancelOpt *AutoCancelOpt, log *zap.SugaredLogg
 		return nil
 	}
 
-	tasks, err := commonrepo.NewworkflowTaskv4Coll().FindTodoTasksByWorkflowName(autoCancelOpt.WorkflowName)
+	tasks, err := commonrepo.NewWorkflowTaskV4Store().FindTodoTasksByWorkflowName(autoCancelOpt.WorkflowName)
 	if err != nil {
 		log.Errorf("find [InCompletedWorkflowV4Tasks] error: %v", err)
 		return err
@@ -0,0 +1,333 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v35/github"
+	"github.com/hashicorp/go-multierror"
+	"github.com/xanzy/go-gitlab"
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	gitservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/git"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/codehub"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/gitee"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+const (
+	DeliverySourceGithub  = "github"
+	DeliverySourceGitlab  = "gitlab"
+	DeliverySourceCodehub = "codehub"
+	DeliverySourceGitee   = "gitee"
+	DeliverySourceGerrit  = "gerrit"
+
+	// the payload larger than it is not kept, the delivery can not be redelivered then
+	maxDeliveryPayloadSize   = 4 << 20
+	webhookDeliveryRetention = 7 * 24 * time.Hour
+
+	deliveryReasonNotMatch = "the event does not match the branch, event types or file filters of the trigger"
+)
+
+// the headers carrying the webhook secret, they are not recorded and are filled with the current secret on redelivery
+var deliverySecretHeaders = []string{"X-Gitlab-Token", "X-Gitee-Token", "X-Codehub-Token"}
+
+type deliveryRecorder struct {
+	sync.Mutex
+	triggers []*commonmodels.WebhookTrigger
+	// idempotencyKey identifies the delivery of the code host, the retries of the delivery have the same key
+	idempotencyKey string
+	// clientIP is the source of the delivery, it is empty for the redeliveries requested by the users
+	clientIP string
+}
+
+// deliveryRecorders keeps the recorders of the deliveries being processed by request id
+var deliveryRecorders sync.Map
+
+// recordDeliveryTrigger records the result of a workflow trigger to the delivery of the request, reason is
+// empty if the task is created
+func recordDeliveryTrigger(requestID string, workflow *commonmodels.WorkflowV4, hookName string, taskID int64, reason string) {
+	v, ok := deliveryRecorders.Load(requestID)
+	if !ok {
+		return
+	}
+	recorder := v.(*deliveryRecorder)
+	recorder.Lock()
+	defer recorder.Unlock()
+	recorder.triggers = append(recorder.triggers, &commonmodels.WebhookTrigger{
+		ProjectName:  workflow.Project,
+		WorkflowName: workflow.Name,
+		HookName:     hookName,
+		TaskID:       taskID,
+		Reason:       reason,
+	})
+}
+
+// allowDeliveryTrigger checks the source of the delivery against the allowlist of the hook, the global trigger
+// allowlist is used if it is empty. The rejection is recorded to the delivery
+func allowDeliveryTrigger(requestID string, workflow *commonmodels.WorkflowV4, hook *commonmodels.WorkflowV4Hook) bool {
+	v, ok := deliveryRecorders.Load(requestID)
+	if !ok {
+		return true
+	}
+	clientIP := v.(*deliveryRecorder).clientIP
+	if clientIP == "" || allowTriggerIP(clientIP, hook.IPAllowlist) {
+		return true
+	}
+	log.Warnf("webhook request from %s to hook %s of workflow %s is rejected by the ip allowlist", clientIP, hook.Name, workflow.Name)
+	recordDeliveryTrigger(requestID, workflow, hook.Name, 0, fmt.Sprintf("the source ip %s is not in the allowlist of the trigger", clientIP))
+	return false
+}
+
+// the headers identifying the deliveries of the code hosts, the explicit idempotency key is preferred
+var deliveryIDHeaders = []string{setting.IdempotencyKeyHeader, "X-GitHub-Delivery", "X-Gitlab-Event-UUID"}
+
+func getDeliveryIdempotencyKey(req *http.Request) string {
+	for _, header := range deliveryIDHeaders {
+		if value := req.Header.Get(header); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// getTriggerIdempotencyKey returns the idempotency key of the task created by the trigger for the delivery of
+// the request, it is empty if the delivery can not be identified
+func getTriggerIdempotencyKey(requestID, hookName string) string {
+	v, ok := deliveryRecorders.Load(requestID)
+	if !ok {
+		return ""
+	}
+	key := v.(*deliveryRecorder).idempotencyKey
+	if key == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s", key, hookName)
+}
+
+// ProcessWebhook processes the event of the code hosts and records the delivery
+func ProcessWebhook(payload []byte, req *http.Request, requestID, clientIP string, log *zap.SugaredLogger) error {
+	_, err := processWebhookDelivery(payload, req, requestID, "", clientIP, log)
+	return err
+}
+
+func processWebhookDelivery(payload []byte, req *http.Request, requestID, redeliveryOf, clientIP string, log *zap.SugaredLogger) (*commonmodels.WebhookDelivery, error) {
+	digest := sha256.Sum256(payload)
+	delivery := &commonmodels.WebhookDelivery{
+		RequestID:     requestID,
+		RequestURI:    req.RequestURI,
+		ClientIP:      clientIP,
+		Headers:       getDeliveryHeaders(req),
+		PayloadDigest: hex.EncodeToString(digest[:]),
+		RedeliveryOf:  redeliveryOf,
+		CreateTime:    time.Now().Unix(),
+	}
+	if len(payload) <= maxDeliveryPayloadSize {
+		delivery.Payload = string(payload)
+	}
+
+	recorder := &deliveryRecorder{clientIP: clientIP}
+	// the redeliveries requested by the users create tasks again
+	if redeliveryOf == "" {
+		recorder.idempotencyKey = getDeliveryIdempotencyKey(req)
+	}
+	deliveryRecorders.Store(requestID, recorder)
+	defer deliveryRecorders.Delete(requestID)
+
+	var err error
+	if eventType := github.WebHookType(req); eventType != "" {
+		delivery.Source, delivery.Event = DeliverySourceGithub, eventType
+		err = processGithub(payload, req, requestID, log)
+	} else if eventType := gitlab.HookEventType(req); eventType != "" {
+		delivery.Source, delivery.Event = DeliverySourceGitlab, string(eventType)
+		err = ProcessGitlabHook(payload, req, requestID, log)
+	} else if eventType := codehub.HookEventType(req); eventType != "" {
+		delivery.Source, delivery.Event = DeliverySourceCodehub, string(eventType)
+		err = ProcessCodehubHook(payload, req, requestID, log)
+	} else if eventType := gitee.HookEventType(req); eventType != "" {
+		delivery.Source, delivery.Event = DeliverySourceGitee, string(eventType)
+		err = ProcessGiteeHook(payload, req, requestID, log)
+	} else {
+		delivery.Source = DeliverySourceGerrit
+		event := new(gerritTypeEvent)
+		if json.Unmarshal(payload, event) == nil {
+			delivery.Event = event.Type
+		}
+		err = ProcessGerritHook(payload, req, requestID, log)
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+
+	recorder.Lock()
+	delivery.Triggers = recorder.triggers
+	recorder.Unlock()
+	if createErr := commonrepo.NewWebhookDeliveryColl().Create(delivery); createErr != nil {
+		log.Errorf("failed to record webhook delivery of request %s, err: %s", requestID, createErr)
+	}
+	return delivery, err
+}
+
+func processGithub(payload []byte, req *http.Request, requestID string, log *zap.SugaredLogger) error {
+	errs := &multierror.Error{}
+
+	// trigger classic pipeline
+	_, err := ProcessGithubHook(payload, req, requestID, log)
+	if err != nil {
+		log.Errorf("error happens to trigger classic pipeline %v", err)
+		errs = multierror.Append(errs, err)
+	}
+
+	// trigger workflow
+	err = ProcessGithubWebHook(payload, req, requestID, log)
+
+	if err != nil {
+		log.Errorf("error happens to trigger workflow %v", err)
+		errs = multierror.Append(errs, err)
+	}
+	//测试管理webhook
+	err = ProcessGithubWebHookForTest(payload, req, requestID, log)
+	if err != nil {
+		log.Errorf("error happens to trigger ProcessGithubWebHookForTest %v", err)
+		errs = multierror.Append(errs, err)
+	}
+	// webhooks for scanning task
+	err = ProcessGithubWebhookForScanning(payload, req, requestID, log)
+	if err != nil {
+		log.Errorf("error happens to trigger Scanning for github %v", err)
+		errs = multierror.Append(errs, err)
+	}
+	// webhooks for workflow v4
+	err = ProcessGithubWebHookForWorkflowV4(payload, req, requestID, log)
+	if err != nil {
+		log.Errorf("error happens to trigger workflowV4 for github %v", err)
+		errs = multierror.Append(errs, err)
+	}
+	return errs.ErrorOrNil()
+}
+
+func getDeliveryHeaders(req *http.Request) map[string]string {
+	headers := make(map[string]string)
+	for key := range req.Header {
+		headers[key] = req.Header.Get(key)
+	}
+	for _, key := range deliverySecretHeaders {
+		if _, ok := headers[key]; ok {
+			headers[key] = "******"
+		}
+	}
+	return headers
+}
+
+type ListWebhookDeliveriesArgs struct {
+	Source       string `form:"source"`
+	ProjectName  string `form:"projectName"`
+	WorkflowName string `form:"workflowName"`
+	Triggered    *bool  `form:"triggered"`
+	PageNum      int64  `form:"pageNum"`
+	PageSize     int64  `form:"pageSize"`
+}
+
+type ListWebhookDeliveriesResp struct {
+	Total      int64                           `json:"total"`
+	Deliveries []*commonmodels.WebhookDelivery `json:"deliveries"`
+}
+
+func ListWebhookDeliveries(args *ListWebhookDeliveriesArgs) (*ListWebhookDeliveriesResp, error) {
+	deliveries, total, err := commonrepo.NewWebhookDeliveryColl().List(&commonrepo.ListWebhookDeliveryOption{
+		Source:       args.Source,
+		ProjectName:  args.ProjectName,
+		WorkflowName: args.WorkflowName,
+		Triggered:    args.Triggered,
+		PageNum:      args.PageNum,
+		PageSize:     args.PageSize,
+	})
+	if err != nil {
+		return nil, e.ErrListWebhookDelivery.AddErr(err)
+	}
+	return &ListWebhookDeliveriesResp{Total: total, Deliveries: deliveries}, nil
+}
+
+// GetWebhookDelivery returns the delivery, only the triggers of the project are kept if projectName is set
+func GetWebhookDelivery(id, projectName string) (*commonmodels.WebhookDelivery, error) {
+	delivery, err := commonrepo.NewWebhookDeliveryColl().Get(id)
+	if err != nil {
+		return nil, e.ErrGetWebhookDelivery.AddErr(err)
+	}
+	if projectName == "" {
+		return delivery, nil
+	}
+
+	triggers := make([]*commonmodels.WebhookTrigger, 0)
+	for _, trigger := range delivery.Triggers {
+		if trigger.ProjectName == projectName {
+			triggers = append(triggers, trigger)
+		}
+	}
+	if len(triggers) == 0 {
+		return nil, e.ErrGetWebhookDelivery.AddDesc(fmt.Sprintf("delivery %s does not trigger the workflows of project %s", id, projectName))
+	}
+	delivery.Triggers = triggers
+	return delivery, nil
+}
+
+// RedeliverWebhook processes the recorded event again as a new delivery and returns it, the error of
+// processing the event is kept in the delivery
+func RedeliverWebhook(id, requestID string, log *zap.SugaredLogger) (*commonmodels.WebhookDelivery, error) {
+	delivery, err := commonrepo.NewWebhookDeliveryColl().Get(id)
+	if err != nil {
+		return nil, e.ErrRedeliverWebhook.AddErr(err)
+	}
+	if delivery.Payload == "" {
+		return nil, e.ErrRedeliverWebhook.AddDesc("the payload of the delivery is not recorded")
+	}
+
+	req, err := http.NewRequest(http.MethodPost, delivery.RequestURI, bytes.NewReader([]byte(delivery.Payload)))
+	if err != nil {
+		return nil, e.ErrRedeliverWebhook.AddErr(err)
+	}
+	req.RequestURI = delivery.RequestURI
+	for key, value := range delivery.Headers {
+		req.Header.Set(key, value)
+	}
+	for _, key := range deliverySecretHeaders {
+		if _, ok := delivery.Headers[key]; ok {
+			req.Header.Set(key, gitservice.GetHookSecret())
+		}
+	}
+
+	redelivery, _ := processWebhookDelivery([]byte(delivery.Payload), req, requestID, delivery.ID.Hex(), "", log)
+	return redelivery, nil
+}
+
+func CleanExpiredWebhookDeliveries() {
+	if err := commonrepo.NewWebhookDeliveryColl().DeleteBefore(time.Now().Add(-webhookDeliveryRetention).Unix()); err != nil {
+		log.Errorf("failed to clean expired webhook deliveries, err: %s", err)
+	}
+}
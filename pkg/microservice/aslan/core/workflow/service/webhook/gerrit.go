@@ -53,6 +53,7 @@ import (
 const (
 	changeMergedEventType    = "change-merged"
 	patchsetCreatedEventType = "patchset-created"
+	commentAddedEventType    = "comment-added"
 )
 
 type gerritTypeEvent struct {
@@ -145,6 +145,100 @@ func (gpcem *gerritPatchsetCreatedEventMatcherForWorkflowV4) GetHookRepo(hookRep
 	}
 }
 
+const defaultGerritRetestComment = "retest"
+
+type commentAddedEvent struct {
+	Author         UploaderInfo  `json:"author"`
+	Comment        string        `json:"comment"`
+	PatchSet       PatchSetInfo  `json:"patchSet"`
+	Change         ChangeInfo    `json:"change"`
+	Project        ProjectInfo   `json:"project"`
+	RefName        string        `json:"refName"`
+	ChangeKey      ChangeKeyInfo `json:"changeKey"`
+	Type           string        `json:"type"`
+	EventCreatedOn int           `json:"eventCreatedOn"`
+}
+
+// gerritCommentAddedEventMatcherForWorkflowV4 matches the retest comments on a change, the change is tested again
+// with the patch set commented
+type gerritCommentAddedEventMatcherForWorkflowV4 struct {
+	Log      *zap.SugaredLogger
+	Item     *commonmodels.WorkflowV4Hook
+	Workflow *commonmodels.WorkflowV4
+	Event    *commentAddedEvent
+}
+
+func (gcaem *gerritCommentAddedEventMatcherForWorkflowV4) Match(hookRepo *commonmodels.MainHookRepo) (bool, error) {
+	event := gcaem.Event
+	if event == nil {
+		return false, fmt.Errorf("event doesn't match")
+	}
+	if event.Project.Name != gcaem.Item.MainRepo.RepoName {
+		return false, nil
+	}
+
+	existEventNames := sets.NewString()
+	for _, eventName := range gcaem.Item.MainRepo.Events {
+		existEventNames.Insert(string(eventName))
+	}
+	if !existEventNames.Has(event.Type) || !isGerritRetestComment(event.Comment, gcaem.Item.RetestComment) {
+		return false, nil
+	}
+
+	refName := event.Change.Branch
+	isRegular := gcaem.Item.MainRepo.IsRegular
+	if !isRegular && hookRepo.Branch != refName {
+		return false, nil
+	}
+	if isRegular {
+		// Do not use regexp.MustCompile to avoid panic
+		matched, err := regexp.MatchString(gcaem.Item.MainRepo.Branch, refName)
+		if err != nil || !matched {
+			return false, nil
+		}
+	}
+	hookRepo.Branch = refName
+	hookRepo.Committer = event.Author.Username
+	return true, nil
+}
+
+func (gcaem *gerritCommentAddedEventMatcherForWorkflowV4) GetHookRepo(hookRepo *commonmodels.MainHookRepo) *types.Repository {
+	return &types.Repository{
+		CodehostID:    hookRepo.CodehostID,
+		RepoName:      hookRepo.RepoName,
+		RepoOwner:     hookRepo.RepoOwner,
+		RepoNamespace: hookRepo.GetRepoNamespace(),
+		Branch:        hookRepo.Branch,
+		PR:            gcaem.Event.Change.Number,
+		Source:        hookRepo.Source,
+	}
+}
+
+// isGerritRetestComment checks if a line of the comment is the retest comment, gerrit prefixes
+// the comment with the patch set, e.g. "Patch Set 2:\n\nretest"
+func isGerritRetestComment(comment, retestComment string) bool {
+	if retestComment == "" {
+		retestComment = defaultGerritRetestComment
+	}
+	for _, line := range strings.Split(comment, "\n") {
+		if strings.EqualFold(strings.TrimSpace(line), strings.TrimSpace(retestComment)) {
+			return true
+		}
+	}
+	return false
+}
+
+// getGerritChangeFromMatcher returns the change and the patch set of the events on an open change
+func getGerritChangeFromMatcher(matcher gerritEventMatcherForWorkflowV4) (*ChangeInfo, *PatchSetInfo, bool) {
+	switch m := matcher.(type) {
+	case *gerritPatchsetCreatedEventMatcherForWorkflowV4:
+		return &m.Event.Change, &m.Event.PatchSet, true
+	case *gerritCommentAddedEventMatcherForWorkflowV4:
+		return &m.Event.Change, &m.Event.PatchSet, true
+	}
+	return nil, nil, false
+}
+
 func createGerritEventMatcherForWorkflowV4(event *gerritTypeEvent, body []byte, item *commonmodels.WorkflowV4Hook, workflow *commonmodels.WorkflowV4, log *zap.SugaredLogger) gerritEventMatcherForWorkflowV4 {
 	switch event.Type {
 	case changeMergedEventType:
@@ -169,6 +263,17 @@ func createGerritEventMatcherForWorkflowV4(event *gerritTypeEvent, body []byte,
 			Log:      log,
 			Event:    &ev,
 		}
+	case commentAddedEventType:
+		var ev commentAddedEvent
+		if err := json.Unmarshal(body, &ev); err != nil {
+			log.Errorf("createGerritEventMatcher json.Unmarshal err : %v", err)
+		}
+		return &gerritCommentAddedEventMatcherForWorkflowV4{
+			Workflow: workflow,
+			Item:     item,
+			Log:      log,
+			Event:    &ev,
+		}
 	}
 
 	return nil
@@ -226,21 +331,26 @@ func TriggerWorkflowV4ByGerritEvent(event *gerritTypeEvent, body []byte, uri, ba
 			if !isMatch {
 				continue
 			}
+			if !allowDeliveryTrigger(requestID, workflow, item) {
+				continue
+			}
 			log.Infof("event match hook %v of %s", item.MainRepo, workflow.Name)
 			eventRepo := matcher.GetHookRepo(item.MainRepo)
 
 			var mergeRequestID, commitID string
-			if m, ok := matcher.(*gerritPatchsetCreatedEventMatcherForWorkflowV4); ok {
-				if item.CheckPatchSetChange {
+			if change, patchSet, ok := getGerritChangeFromMatcher(matcher); ok {
+				_, isRetest := matcher.(*gerritCommentAddedEventMatcherForWorkflowV4)
+				if item.CheckPatchSetChange && !isRetest {
 					// for different patch sets under the same pr, if the updated contents of the two patch sets are exactly the same, and the task triggered by the previous patch set is executed successfully, the new patch set will no longer trigger the task.
 					if checkLatestTaskStaus(workflow.Name, mergeRequestID, commitID, detail, log) {
 						log.Infof("last patchset has already triggered task, workflowName:%s, mergeRequestID:%s, PatchSetID:%s", workflow.Name, mergeRequestID, commitID)
+						recordDeliveryTrigger(requestID, workflow, item.Name, 0, "the patchset has the same changes as the last one which has triggered a task")
 						continue
 					}
 				}
 
-				mergeRequestID = strconv.Itoa(m.Event.Change.Number)
-				commitID = strconv.Itoa(m.Event.PatchSet.Number)
+				mergeRequestID = strconv.Itoa(change.Number)
+				commitID = strconv.Itoa(patchSet.Number)
 				autoCancelOpt := &AutoCancelOpt{
 					MergeRequestID: mergeRequestID,
 					CommitID:       commitID,
@@ -259,9 +369,9 @@ func TriggerWorkflowV4ByGerritEvent(event *gerritTypeEvent, body []byte, uri, ba
 					// gerrit has no repo owner
 					mainRepo := item.MainRepo
 					mainRepo.RepoOwner = ""
-					mainRepo.Revision = m.Event.PatchSet.Revision
+					mainRepo.Revision = patchSet.Revision
 					notification, _ = scmnotify.NewService().SendInitWebhookComment(
-						mainRepo, m.Event.Change.Number, baseURI, false, false, false, true, log,
+						mainRepo, change.Number, baseURI, false, false, false, true, log,
 					)
 				}
 
@@ -273,18 +383,21 @@ func TriggerWorkflowV4ByGerritEvent(event *gerritTypeEvent, body []byte, uri, ba
 					CodehostID:     item.MainRepo.CodehostID,
 					MergeRequestID: mergeRequestID,
 					CommitID:       commitID,
+					GerritVotes:    item.GerritVotes,
 				}
 			}
 			if err := job.MergeArgs(workflow, item.WorkflowArg); err != nil {
 				errMsg := fmt.Sprintf("merge workflow args error: %v", err)
 				log.Error(errMsg)
 				errorList = multierror.Append(errorList, fmt.Errorf(errMsg))
+				recordDeliveryTrigger(requestID, workflow, item.Name, 0, errMsg)
 				continue
 			}
 			if err := job.MergeWebhookRepo(workflow, eventRepo); err != nil {
 				errMsg := fmt.Sprintf("merge webhook repo info to workflowargs error: %v", err)
 				log.Error(errMsg)
 				errorList = multierror.Append(errorList, fmt.Errorf(errMsg))
+				recordDeliveryTrigger(requestID, workflow, item.Name, 0, errMsg)
 				continue
 			}
 			if notification != nil {
@@ -292,12 +405,15 @@ func TriggerWorkflowV4ByGerritEvent(event *gerritTypeEvent, body []byte, uri, ba
 			}
 			workflow.HookPayload = hookPayload
 			if resp, err := workflowservice.CreateWorkflowTaskV4(&workflowservice.CreateWorkflowTaskV4Args{
-				Name: setting.WebhookTaskCreator,
+				Name:           setting.WebhookTaskCreator,
+				IdempotencyKey: getTriggerIdempotencyKey(requestID, item.Name),
 			}, workflow, log); err != nil {
 				errMsg := fmt.Sprintf("failed to create workflow task when receive push event due to %v ", err)
 				log.Error(errMsg)
 				errorList = multierror.Append(errorList, fmt.Errorf(errMsg))
+				recordDeliveryTrigger(requestID, workflow, item.Name, 0, errMsg)
 			} else {
+				recordDeliveryTrigger(requestID, workflow, item.Name, resp.TaskID, "")
 				log.Infof("succeed to create task %v", resp)
 			}
 
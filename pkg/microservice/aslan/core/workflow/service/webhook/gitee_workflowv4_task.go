@@ -204,6 +204,18 @@ func createGiteeEventMatcherForWorkflowV4(
 	return nil
 }
 
+func getGiteeEventRepoFullName(event interface{}) string {
+	switch evt := event.(type) {
+	case *gitee.PushEvent:
+		return evt.Repository.FullName
+	case *gitee.PullRequestEvent:
+		return evt.PullRequest.Base.Repo.FullName
+	case *gitee.TagPushEvent:
+		return evt.Repository.FullName
+	}
+	return ""
+}
+
 func TriggerWorkflowV4ByGiteeEvent(event interface{}, baseURI, requestID string, log *zap.SugaredLogger) error {
 	workflows, _, err := commonrepo.NewWorkflowV4Coll().List(&commonrepo.ListWorkflowV4Option{}, 0, 0)
 	if err != nil {
@@ -218,6 +230,7 @@ func TriggerWorkflowV4ByGiteeEvent(event interface{}, baseURI, requestID string,
 	}
 	var hookPayload *commonmodels.HookPayload
 	var notification *commonmodels.Notification
+	repoFullName := getGiteeEventRepoFullName(event)
 
 	for _, workflow := range workflows {
 		if workflow.HookCtls == nil {
@@ -239,6 +252,12 @@ func TriggerWorkflowV4ByGiteeEvent(event interface{}, baseURI, requestID string,
 				mErr = multierror.Append(mErr, err)
 			}
 			if !matches {
+				if (item.MainRepo.RepoOwner + "/" + item.MainRepo.RepoName) == repoFullName {
+					recordDeliveryTrigger(requestID, workflow, item.Name, 0, deliveryReasonNotMatch)
+				}
+				continue
+			}
+			if !allowDeliveryTrigger(requestID, workflow, item) {
 				continue
 			}
 
@@ -271,6 +290,7 @@ func TriggerWorkflowV4ByGiteeEvent(event interface{}, baseURI, requestID string,
 					MergeRequestID: mergeRequestID,
 					CommitID:       commitID,
 					EventType:      eventType,
+					CommitAuthors:  getCommitAuthors(ev.PullRequest.User.Login, ev.PullRequest.User.Name, ev.PullRequest.User.Email),
 				}
 			case *gitee.PushEvent:
 				eventType = EventTypePush
@@ -289,6 +309,11 @@ func TriggerWorkflowV4ByGiteeEvent(event interface{}, baseURI, requestID string,
 					CommitID:   commitID,
 					EventType:  eventType,
 				}
+				for _, commit := range ev.Commits {
+					if commit.ID == ev.After {
+						hookPayload.CommitAuthors = getCommitAuthors(commit.Author.Username, commit.Author.Name, commit.Author.Email)
+					}
+				}
 			case *gitee.TagPushEvent:
 				eventType = EventTypeTag
 			}
@@ -313,12 +338,14 @@ func TriggerWorkflowV4ByGiteeEvent(event interface{}, baseURI, requestID string,
 				errMsg := fmt.Sprintf("merge workflow args error: %v", err)
 				log.Error(errMsg)
 				mErr = multierror.Append(mErr, fmt.Errorf(errMsg))
+				recordDeliveryTrigger(requestID, workflow, item.Name, 0, errMsg)
 				continue
 			}
 			if err := job.MergeWebhookRepo(workflow, eventRepo); err != nil {
 				errMsg := fmt.Sprintf("merge webhook repo info to workflowargs error: %v", err)
 				log.Error(errMsg)
 				mErr = multierror.Append(mErr, fmt.Errorf(errMsg))
+				recordDeliveryTrigger(requestID, workflow, item.Name, 0, errMsg)
 				continue
 			}
 			if notification != nil {
@@ -326,12 +353,15 @@ func TriggerWorkflowV4ByGiteeEvent(event interface{}, baseURI, requestID string,
 			}
 			workflow.HookPayload = hookPayload
 			if resp, err := workflowservice.CreateWorkflowTaskV4(&workflowservice.CreateWorkflowTaskV4Args{
-				Name: setting.WebhookTaskCreator,
+				Name:           setting.WebhookTaskCreator,
+				IdempotencyKey: getTriggerIdempotencyKey(requestID, item.Name),
 			}, workflow, log); err != nil {
 				errMsg := fmt.Sprintf("failed to create workflow task when receive push event due to %v ", err)
 				log.Error(errMsg)
 				mErr = multierror.Append(mErr, fmt.Errorf(errMsg))
+				recordDeliveryTrigger(requestID, workflow, item.Name, 0, errMsg)
 			} else {
+				recordDeliveryTrigger(requestID, workflow, item.Name, resp.TaskID, "")
 				log.Infof("succeed to create task %v", resp)
 			}
 		}
@@ -214,6 +214,18 @@ func createGithubEventMatcherForWorkflowV4(
 	return nil
 }
 
+func getGithubEventRepoFullName(event interface{}) string {
+	switch evt := event.(type) {
+	case *github.PushEvent:
+		return evt.GetRepo().GetFullName()
+	case *github.PullRequestEvent:
+		return evt.GetPullRequest().GetBase().GetRepo().GetFullName()
+	case *github.CreateEvent:
+		return evt.GetRepo().GetFullName()
+	}
+	return ""
+}
+
 func TriggerWorkflowV4ByGithubEvent(event interface{}, baseURI, deliveryID, requestID string, log *zap.SugaredLogger) error {
 	workflows, _, err := commonrepo.NewWorkflowV4Coll().List(&commonrepo.ListWorkflowV4Option{}, 0, 0)
 	if err != nil {
@@ -227,6 +239,7 @@ func TriggerWorkflowV4ByGithubEvent(event interface{}, baseURI, deliveryID, requ
 		return findChangedFilesOfPullRequest(pullRequestEvent, codehostId)
 	}
 	hookPayload := &commonmodels.HookPayload{}
+	repoFullName := getGithubEventRepoFullName(event)
 
 	for _, workflow := range workflows {
 		if workflow.HookCtls == nil {
@@ -248,6 +261,12 @@ func TriggerWorkflowV4ByGithubEvent(event interface{}, baseURI, deliveryID, requ
 				mErr = multierror.Append(mErr, err)
 			}
 			if !matches {
+				if checkRepoNamespaceMatch(item.MainRepo, repoFullName) {
+					recordDeliveryTrigger(requestID, workflow, item.Name, 0, deliveryReasonNotMatch)
+				}
+				continue
+			}
+			if !allowDeliveryTrigger(requestID, workflow, item) {
 				continue
 			}
 
@@ -277,6 +296,7 @@ func TriggerWorkflowV4ByGithubEvent(event interface{}, baseURI, deliveryID, requ
 					MergeRequestID: mergeRequestID,
 					CommitID:       commitID,
 					EventType:      eventType,
+					CommitAuthors:  getCommitAuthors(ev.GetPullRequest().GetUser().GetLogin()),
 				}
 			case *github.PushEvent:
 				if ev.GetRef() != "" && ev.GetHeadCommit().GetID() != "" {
@@ -295,6 +315,8 @@ func TriggerWorkflowV4ByGithubEvent(event interface{}, baseURI, deliveryID, requ
 						DeliveryID: deliveryID,
 						CommitID:   commitID,
 						EventType:  eventType,
+						CommitAuthors: getCommitAuthors(ev.GetHeadCommit().GetAuthor().GetName(),
+							ev.GetHeadCommit().GetAuthor().GetEmail(), ev.GetHeadCommit().GetAuthor().GetLogin()),
 					}
 				}
 			case *github.CreateEvent:
@@ -317,22 +339,27 @@ func TriggerWorkflowV4ByGithubEvent(event interface{}, baseURI, deliveryID, requ
 				errMsg := fmt.Sprintf("merge workflow args error: %v", err)
 				log.Error(errMsg)
 				mErr = multierror.Append(mErr, fmt.Errorf(errMsg))
+				recordDeliveryTrigger(requestID, workflow, item.Name, 0, errMsg)
 				continue
 			}
 			if err := job.MergeWebhookRepo(workflow, eventRepo); err != nil {
 				errMsg := fmt.Sprintf("merge webhook repo info to workflowargs error: %v", err)
 				log.Error(errMsg)
 				mErr = multierror.Append(mErr, fmt.Errorf(errMsg))
+				recordDeliveryTrigger(requestID, workflow, item.Name, 0, errMsg)
 				continue
 			}
 			workflow.HookPayload = hookPayload
 			if resp, err := workflowservice.CreateWorkflowTaskV4(&workflowservice.CreateWorkflowTaskV4Args{
-				Name: setting.WebhookTaskCreator,
+				Name:           setting.WebhookTaskCreator,
+				IdempotencyKey: getTriggerIdempotencyKey(requestID, item.Name),
 			}, workflow, log); err != nil {
 				errMsg := fmt.Sprintf("failed to create workflow task when receive push event due to %v ", err)
 				log.Error(errMsg)
 				mErr = multierror.Append(mErr, fmt.Errorf(errMsg))
+				recordDeliveryTrigger(requestID, workflow, item.Name, 0, errMsg)
 			} else {
+				recordDeliveryTrigger(requestID, workflow, item.Name, resp.TaskID, "")
 				if workflow.HookPayload.IsPr {
 					// Updating the comment in the git repository, this will not cause the function to return error if this function call fails
 					if err := scmnotify.NewService().CreateGitCheckForWorkflowV4(workflow, resp.TaskID, log); err != nil {
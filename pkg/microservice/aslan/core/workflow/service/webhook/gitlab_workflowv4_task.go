@@ -331,6 +331,10 @@ func TriggerWorkflowV4ByGitlabEvent(event interface{}, baseURI, requestID string
 				mErr = multierror.Append(mErr, err)
 			}
 			if !matches {
+				recordDeliveryTrigger(requestID, workflow, item.Name, 0, deliveryReasonNotMatch)
+				continue
+			}
+			if !allowDeliveryTrigger(requestID, workflow, item) {
 				continue
 			}
 			log.Infof("event match hook %v of %s", item.MainRepo, workflow.Name)
@@ -362,6 +366,8 @@ func TriggerWorkflowV4ByGitlabEvent(event interface{}, baseURI, requestID string
 					CommitID:       commitID,
 					CodehostID:     eventRepo.CodehostID,
 					EventType:      eventType,
+					RepoNamespace:  eventRepo.GetRepoNamespace(),
+					CommitAuthors:  getCommitAuthors(ev.ObjectAttributes.LastCommit.Author.Name, ev.ObjectAttributes.LastCommit.Author.Email),
 				}
 			case *gitlab.PushEvent:
 				eventType = EventTypePush
@@ -380,6 +386,11 @@ func TriggerWorkflowV4ByGitlabEvent(event interface{}, baseURI, requestID string
 					CodehostID: eventRepo.CodehostID,
 					EventType:  eventType,
 				}
+				for _, commit := range ev.Commits {
+					if commit != nil && commit.ID == ev.After {
+						hookPayload.CommitAuthors = getCommitAuthors(commit.Author.Name, commit.Author.Email)
+					}
+				}
 			case *gitlab.TagEvent:
 				eventType = EventTypeTag
 				hookPayload = &commonmodels.HookPayload{
@@ -403,12 +414,14 @@ func TriggerWorkflowV4ByGitlabEvent(event interface{}, baseURI, requestID string
 				errMsg := fmt.Sprintf("merge workflow args error: %v", err)
 				log.Error(errMsg)
 				mErr = multierror.Append(mErr, fmt.Errorf(errMsg))
+				recordDeliveryTrigger(requestID, workflow, item.Name, 0, errMsg)
 				continue
 			}
 			if err := job.MergeWebhookRepo(workflow, eventRepo); err != nil {
 				errMsg := fmt.Sprintf("merge webhook repo info to workflowargs error: %v", err)
 				log.Error(errMsg)
 				mErr = multierror.Append(mErr, fmt.Errorf(errMsg))
+				recordDeliveryTrigger(requestID, workflow, item.Name, 0, errMsg)
 				continue
 			}
 			if notification != nil {
@@ -416,12 +429,15 @@ func TriggerWorkflowV4ByGitlabEvent(event interface{}, baseURI, requestID string
 			}
 			workflow.HookPayload = hookPayload
 			if resp, err := workflowservice.CreateWorkflowTaskV4(&workflowservice.CreateWorkflowTaskV4Args{
-				Name: setting.WebhookTaskCreator,
+				Name:           setting.WebhookTaskCreator,
+				IdempotencyKey: getTriggerIdempotencyKey(requestID, item.Name),
 			}, workflow, log); err != nil {
 				errMsg := fmt.Sprintf("failed to create workflow task when receive push event due to %v ", err)
 				log.Error(errMsg)
 				mErr = multierror.Append(mErr, fmt.Errorf(errMsg))
+				recordDeliveryTrigger(requestID, workflow, item.Name, 0, errMsg)
 			} else {
+				recordDeliveryTrigger(requestID, workflow, item.Name, resp.TaskID, "")
 				log.Infof("succeed to create task %v", resp)
 			}
 		}
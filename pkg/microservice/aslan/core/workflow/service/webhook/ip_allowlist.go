@@ -0,0 +1,100 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/koderover/zadig/pkg/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/util"
+)
+
+type triggerAllowlistCache struct {
+	sync.Mutex
+	config     *commonmodels.TriggerIPAllowlistConfig
+	configTime time.Time
+}
+
+var triggerAllowlist = &triggerAllowlistCache{}
+
+// AllowTriggerSource returns true if the client is allowed to trigger the workflows. The country must be allowed by
+// the global setting, the ip must be in the allowlist of the hook if it is not empty, otherwise in the global one
+func AllowTriggerSource(clientIP, country string, allowlist []string) bool {
+	return AllowTriggerCountry(country) && allowTriggerIP(clientIP, allowlist)
+}
+
+// AllowTriggerCountry returns true if the country is allowed by the global setting, the unknown country is rejected
+// if the countries are restricted
+func AllowTriggerCountry(country string) bool {
+	cfg := triggerAllowlist.get()
+	if cfg == nil || !cfg.Enabled || len(cfg.AllowedCountries) == 0 {
+		return true
+	}
+	for _, allowed := range cfg.AllowedCountries {
+		if strings.EqualFold(allowed, country) {
+			return true
+		}
+	}
+	return false
+}
+
+func allowTriggerIP(clientIP string, allowlist []string) bool {
+	if len(allowlist) > 0 {
+		return util.IPInAllowlist(clientIP, allowlist)
+	}
+	cfg := triggerAllowlist.get()
+	if cfg == nil || !cfg.Enabled || len(cfg.CIDRs) == 0 {
+		return true
+	}
+	return util.IPInAllowlist(clientIP, cfg.CIDRs)
+}
+
+// GetTriggerSourceCountry returns the country of the client set by the gateway, it is empty if the header is not
+// configured or the request does not come from a trusted gateway because the header can be spoofed
+func GetTriggerSourceCountry(header http.Header, remoteIP string) string {
+	cfg := triggerAllowlist.get()
+	if cfg == nil || cfg.CountryHeader == "" {
+		return ""
+	}
+	proxies := config.TrustedProxies()
+	if len(proxies) == 0 || !util.IPInAllowlist(remoteIP, proxies) {
+		return ""
+	}
+	return strings.TrimSpace(header.Get(cfg.CountryHeader))
+}
+
+// the setting is cached in the same way as the rate limit so the rejected requests do not reach the database
+func (c *triggerAllowlistCache) get() *commonmodels.TriggerIPAllowlistConfig {
+	c.Lock()
+	defer c.Unlock()
+	if c.configTime.IsZero() || time.Since(c.configTime) > rateLimitSettingTTL {
+		c.configTime = time.Now()
+		systemSetting, err := commonrepo.NewSystemSettingColl().Get()
+		if err != nil {
+			log.Errorf("failed to get trigger ip allowlist setting, err: %s", err)
+		} else {
+			c.config = systemSetting.TriggerIPAllowlist
+		}
+	}
+	return c.config
+}
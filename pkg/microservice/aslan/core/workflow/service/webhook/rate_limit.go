@@ -0,0 +1,161 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v35/github"
+	"github.com/juju/ratelimit"
+	"github.com/xanzy/go-gitlab"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/tool/codehub"
+	"github.com/koderover/zadig/pkg/tool/gitee"
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/tool/metrics"
+)
+
+const (
+	RateLimitSourceGeneralHook  = "general_hook"
+	RateLimitSourceRegistryHook = "registry_hook"
+
+	RateLimitKeyByIP   = "ip"
+	RateLimitKeyByHook = "hook"
+
+	// the setting is cached so the webhook storms do not reach the database
+	rateLimitSettingTTL = 30 * time.Second
+	// the buckets not used for the time are dropped
+	rateLimitBucketIdleTime = 10 * time.Minute
+)
+
+type rateLimitBucket struct {
+	bucket   *ratelimit.Bucket
+	rate     float64
+	burst    int64
+	lastUsed time.Time
+}
+
+type webhookRateLimiter struct {
+	sync.Mutex
+	config      *commonmodels.WebhookRateLimitConfig
+	configTime  time.Time
+	buckets     map[string]*rateLimitBucket
+	lastCleanup time.Time
+}
+
+var rateLimiter = &webhookRateLimiter{buckets: make(map[string]*rateLimitBucket)}
+
+// GetWebhookSource returns the code host sending the webhook request
+func GetWebhookSource(req *http.Request) string {
+	switch {
+	case github.WebHookType(req) != "":
+		return DeliverySourceGithub
+	case gitlab.HookEventType(req) != "":
+		return DeliverySourceGitlab
+	case codehub.HookEventType(req) != "":
+		return DeliverySourceCodehub
+	case gitee.HookEventType(req) != "":
+		return DeliverySourceGitee
+	default:
+		return DeliverySourceGerrit
+	}
+}
+
+// AllowWebhookRequest takes a token from the bucket of the request, it returns false if the request exceeds the
+// rate limit of the source, hook identifies the hook the request is sent to
+func AllowWebhookRequest(source, hook, clientIP string) bool {
+	rule := rateLimiter.getRule(source)
+	if rule == nil {
+		return true
+	}
+	key := fmt.Sprintf("%s/%s/%s", source, RateLimitKeyByIP, clientIP)
+	if rule.KeyBy == RateLimitKeyByHook {
+		key = fmt.Sprintf("%s/%s/%s", source, RateLimitKeyByHook, hook)
+	}
+	if rateLimiter.take(key, rule) {
+		return true
+	}
+	metrics.IncWebhookRateLimited(source)
+	log.Warnf("webhook request of %s from %s to %s is rate limited", source, clientIP, hook)
+	return false
+}
+
+func (l *webhookRateLimiter) getRule(source string) *commonmodels.WebhookRateLimitRule {
+	l.Lock()
+	defer l.Unlock()
+	if l.configTime.IsZero() || time.Since(l.configTime) > rateLimitSettingTTL {
+		l.configTime = time.Now()
+		systemSetting, err := commonrepo.NewSystemSettingColl().Get()
+		if err != nil {
+			log.Errorf("failed to get webhook rate limit setting, err: %s", err)
+		} else {
+			l.config = systemSetting.WebhookRateLimit
+		}
+	}
+	if l.config == nil || !l.config.Enabled {
+		return nil
+	}
+	var defaultRule *commonmodels.WebhookRateLimitRule
+	for _, rule := range l.config.Rules {
+		if rule.Rate <= 0 {
+			continue
+		}
+		if rule.Source == source {
+			return rule
+		}
+		if rule.Source == "" {
+			defaultRule = rule
+		}
+	}
+	return defaultRule
+}
+
+func (l *webhookRateLimiter) take(key string, rule *commonmodels.WebhookRateLimitRule) bool {
+	l.Lock()
+	defer l.Unlock()
+	now := time.Now()
+	if now.Sub(l.lastCleanup) > rateLimitBucketIdleTime {
+		for k, b := range l.buckets {
+			if now.Sub(b.lastUsed) > rateLimitBucketIdleTime {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastCleanup = now
+	}
+
+	burst := rule.Burst
+	if burst <= 0 {
+		burst = 1
+	}
+	b, ok := l.buckets[key]
+	// the bucket is rebuilt when the rule is changed
+	if !ok || b.rate != rule.Rate || b.burst != burst {
+		b = &rateLimitBucket{
+			bucket: ratelimit.NewBucketWithRate(rule.Rate, burst),
+			rate:   rule.Rate,
+			burst:  burst,
+		}
+		l.buckets[key] = b
+	}
+	b.lastUsed = now
+	return b.bucket.TakeAvailable(1) == 1
+}
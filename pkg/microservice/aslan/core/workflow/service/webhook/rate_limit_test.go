@@ -0,0 +1,114 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+func newTestRateLimiter(config *commonmodels.WebhookRateLimitConfig) *webhookRateLimiter {
+	// the fresh config time keeps the setting from being loaded from the database
+	return &webhookRateLimiter{
+		config:      config,
+		configTime:  time.Now(),
+		buckets:     make(map[string]*rateLimitBucket),
+		lastCleanup: time.Now(),
+	}
+}
+
+func takeN(l *webhookRateLimiter, key string, rule *commonmodels.WebhookRateLimitRule, n int) int {
+	taken := 0
+	for i := 0; i < n; i++ {
+		if l.take(key, rule) {
+			taken++
+		}
+	}
+	return taken
+}
+
+var _ = Describe("Webhook rate limit", func() {
+	Context("token bucket", func() {
+		It("allows the burst and rejects the rest", func() {
+			l := newTestRateLimiter(nil)
+			rule := &commonmodels.WebhookRateLimitRule{Rate: 0.01, Burst: 3}
+			Expect(takeN(l, "github/ip/1.1.1.1", rule, 5)).To(Equal(3))
+		})
+
+		It("keeps a bucket for each key", func() {
+			l := newTestRateLimiter(nil)
+			rule := &commonmodels.WebhookRateLimitRule{Rate: 0.01, Burst: 1}
+			Expect(l.take("github/ip/1.1.1.1", rule)).To(BeTrue())
+			Expect(l.take("github/ip/1.1.1.1", rule)).To(BeFalse())
+			Expect(l.take("github/ip/2.2.2.2", rule)).To(BeTrue())
+		})
+
+		It("takes a burst of one if the burst is not set", func() {
+			l := newTestRateLimiter(nil)
+			rule := &commonmodels.WebhookRateLimitRule{Rate: 0.01}
+			Expect(takeN(l, "gitlab/hook/a", rule, 3)).To(Equal(1))
+		})
+
+		It("refills the bucket at the rate", func() {
+			l := newTestRateLimiter(nil)
+			rule := &commonmodels.WebhookRateLimitRule{Rate: 1000, Burst: 1}
+			Expect(takeN(l, "gitlab/hook/a", rule, 2)).To(Equal(1))
+			Eventually(func() bool { return l.take("gitlab/hook/a", rule) }, time.Second, 5*time.Millisecond).Should(BeTrue())
+		})
+
+		It("rebuilds the bucket when the rule is changed", func() {
+			l := newTestRateLimiter(nil)
+			Expect(takeN(l, "gitee/ip/1.1.1.1", &commonmodels.WebhookRateLimitRule{Rate: 0.01, Burst: 1}, 2)).To(Equal(1))
+			Expect(takeN(l, "gitee/ip/1.1.1.1", &commonmodels.WebhookRateLimitRule{Rate: 0.01, Burst: 2}, 3)).To(Equal(2))
+		})
+
+		It("drops the idle buckets", func() {
+			l := newTestRateLimiter(nil)
+			rule := &commonmodels.WebhookRateLimitRule{Rate: 0.01, Burst: 1}
+			Expect(l.take("github/ip/1.1.1.1", rule)).To(BeTrue())
+			l.buckets["github/ip/1.1.1.1"].lastUsed = time.Now().Add(-2 * rateLimitBucketIdleTime)
+			l.lastCleanup = time.Now().Add(-2 * rateLimitBucketIdleTime)
+
+			Expect(l.take("github/ip/2.2.2.2", rule)).To(BeTrue())
+			Expect(l.buckets).NotTo(HaveKey("github/ip/1.1.1.1"))
+		})
+	})
+
+	Context("rule of the source", func() {
+		githubRule := &commonmodels.WebhookRateLimitRule{Source: DeliverySourceGithub, KeyBy: RateLimitKeyByIP, Rate: 10}
+		defaultRule := &commonmodels.WebhookRateLimitRule{KeyBy: RateLimitKeyByHook, Rate: 5}
+		disabledRule := &commonmodels.WebhookRateLimitRule{Source: DeliverySourceGitlab, Rate: 0}
+
+		DescribeTable("getRule",
+			func(enabled bool, source string, expected *commonmodels.WebhookRateLimitRule) {
+				l := newTestRateLimiter(&commonmodels.WebhookRateLimitConfig{
+					Enabled: enabled,
+					Rules:   []*commonmodels.WebhookRateLimitRule{githubRule, defaultRule, disabledRule},
+				})
+				Expect(l.getRule(source)).To(BeIdenticalTo(expected))
+			},
+			Entry("rule of the source", true, DeliverySourceGithub, githubRule),
+			Entry("default rule", true, RateLimitSourceGeneralHook, defaultRule),
+			Entry("rule without a rate falls back to the default rule", true, DeliverySourceGitlab, defaultRule),
+			Entry("rate limit disabled", false, DeliverySourceGithub, nil),
+		)
+	})
+})
@@ -665,3 +665,14 @@ func subElem(parent, sub string) bool {
 	}
 	return false
 }
+
+// getCommitAuthors returns the non-empty identities of the commit author, they are checked by the two-person rule
+func getCommitAuthors(identities ...string) []string {
+	authors := make([]string, 0, len(identities))
+	for _, identity := range identities {
+		if identity != "" && !util.InStringArray(identity, authors) {
+			authors = append(authors, identity)
+		}
+	}
+	return authors
+}
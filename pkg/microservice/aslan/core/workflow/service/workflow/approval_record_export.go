@@ -0,0 +1,198 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	approvalservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/approval"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const ApprovalExportFormatJSON = "json"
+
+type ApprovalRecordExportArgs struct {
+	StartTime int64  `json:"start_time" form:"startTime"`
+	EndTime   int64  `json:"end_time"   form:"endTime"`
+	Format    string `json:"format"     form:"format,default=csv"`
+}
+
+// ApprovalRecordVerification is the result of checking the hash chain of the approval records in the time range
+type ApprovalRecordVerification struct {
+	Valid    bool   `json:"valid"`
+	Count    int    `json:"count"`
+	FirstSeq int64  `json:"first_seq"`
+	LastSeq  int64  `json:"last_seq"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ApprovalRecordExport is an exported record with the status of its task, the record is kept as it is so that
+// its hash can be recomputed by the auditors
+type ApprovalRecordExport struct {
+	Record     *commonmodels.ApprovalRecord `json:"record"`
+	TaskStatus config.Status                `json:"task_status"`
+}
+
+// ApprovalRecordExporter writes the approval decisions in a time range as a CSV file, one row for each decision,
+// or as a JSON file with the verification of the hash chain
+type ApprovalRecordExporter struct {
+	FileName    string
+	ContentType string
+
+	format       string
+	records      []*commonmodels.ApprovalRecord
+	verification *ApprovalRecordVerification
+	logger       *zap.SugaredLogger
+}
+
+func NewApprovalRecordExporter(args *ApprovalRecordExportArgs, logger *zap.SugaredLogger) (*ApprovalRecordExporter, error) {
+	if args.Format != TaskExportFormatCSV && args.Format != ApprovalExportFormatJSON {
+		return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("unsupported export format %s", args.Format))
+	}
+	records, verification, err := listAndVerifyApprovalRecords(args.StartTime, args.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	exporter := &ApprovalRecordExporter{
+		FileName:     fmt.Sprintf("approval-records-%s.%s", time.Now().Format("20060102150405"), args.Format),
+		ContentType:  "text/csv; charset=utf-8",
+		format:       args.Format,
+		records:      records,
+		verification: verification,
+		logger:       logger,
+	}
+	if args.Format == ApprovalExportFormatJSON {
+		exporter.ContentType = "application/json"
+	}
+	return exporter, nil
+}
+
+func (exporter *ApprovalRecordExporter) Export(w io.Writer) error {
+	taskStatuses := make(map[string]config.Status)
+	getTaskStatus := func(record *commonmodels.ApprovalRecord) config.Status {
+		key := fmt.Sprintf("%s/%d", record.WorkflowName, record.TaskID)
+		if status, ok := taskStatuses[key]; ok {
+			return status
+		}
+		task, err := commonrepo.NewWorkflowTaskV4Store().Find(record.WorkflowName, record.TaskID)
+		if err != nil {
+			exporter.logger.Warnf("failed to find task %d of workflow %s, err: %s", record.TaskID, record.WorkflowName, err)
+		} else {
+			taskStatuses[key] = task.Status
+		}
+		return taskStatuses[key]
+	}
+
+	if exporter.format == ApprovalExportFormatJSON {
+		exports := make([]*ApprovalRecordExport, 0, len(exporter.records))
+		for _, record := range exporter.records {
+			exports = append(exports, &ApprovalRecordExport{Record: record, TaskStatus: getTaskStatus(record)})
+		}
+		return json.NewEncoder(w).Encode(map[string]interface{}{
+			"verification": exporter.verification,
+			"records":      exports,
+		})
+	}
+
+	rw, err := newTaskRowWriter(TaskExportFormatCSV, w)
+	if err != nil {
+		return err
+	}
+	header := []string{"序号", "项目", "工作流", "任务 ID", "阶段", "审批类型", "审批结果", "审批开始时间", "审批结束时间",
+		"审批人", "审批操作", "审批意见", "审批时间", "任务状态", "Hash", "Prev Hash"}
+	if err := rw.WriteRow(header); err != nil {
+		return err
+	}
+	for _, record := range exporter.records {
+		decisions := record.Decisions
+		// the approval without any approver, e.g. timed out before the approvers are resolved, is still exported
+		if len(decisions) == 0 {
+			decisions = []*commonmodels.ApprovalDecision{{}}
+		}
+		for _, decision := range decisions {
+			row := []string{
+				fmt.Sprintf("%d", record.Seq),
+				record.ProjectName,
+				record.WorkflowDisplayName,
+				fmt.Sprintf("%d", record.TaskID),
+				record.StageName,
+				string(record.ApprovalType),
+				string(record.Status),
+				formatExportTime(record.StartTime),
+				formatExportTime(record.EndTime),
+				decision.Approver,
+				string(decision.Action),
+				decision.Comment,
+				formatExportTime(decision.Time),
+				string(getTaskStatus(record)),
+				record.Hash,
+				record.PrevHash,
+			}
+			if err := rw.WriteRow(row); err != nil {
+				return err
+			}
+		}
+	}
+	return rw.Close()
+}
+
+// VerifyApprovalRecords checks the hash chain of the approval records created in the time range
+func VerifyApprovalRecords(startTime, endTime int64) (*ApprovalRecordVerification, error) {
+	_, verification, err := listAndVerifyApprovalRecords(startTime, endTime)
+	return verification, err
+}
+
+func listAndVerifyApprovalRecords(startTime, endTime int64) ([]*commonmodels.ApprovalRecord, *ApprovalRecordVerification, error) {
+	if endTime == 0 {
+		endTime = time.Now().Unix()
+	}
+	if startTime > endTime {
+		return nil, nil, e.ErrInvalidParam.AddDesc("startTime must not be later than endTime")
+	}
+	records, err := commonrepo.NewApprovalRecordColl().ListByCreateTime(startTime, endTime)
+	if err != nil {
+		return nil, nil, e.ErrInternalError.AddErr(fmt.Errorf("failed to list approval records: %s", err))
+	}
+
+	verification := &ApprovalRecordVerification{Valid: true, Count: len(records)}
+	if len(records) == 0 {
+		return records, verification, nil
+	}
+	verification.FirstSeq, verification.LastSeq = records[0].Seq, records[len(records)-1].Seq
+	var prev *commonmodels.ApprovalRecord
+	if records[0].Seq > 1 {
+		prev, err = commonrepo.NewApprovalRecordColl().GetBySeq(records[0].Seq - 1)
+		if err != nil {
+			verification.Valid = false
+			verification.Error = fmt.Sprintf("the approval record %d before the range is missing", records[0].Seq-1)
+			return records, verification, nil
+		}
+	}
+	if err := approvalservice.VerifyApprovalRecords(prev, records); err != nil {
+		verification.Valid = false
+		verification.Error = err.Error()
+	}
+	return records, verification, nil
+}
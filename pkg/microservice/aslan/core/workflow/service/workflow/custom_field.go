@@ -0,0 +1,154 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func GetProjectWorkflowCustomFields(projectName string, logger *zap.SugaredLogger) (*models.CustomField, error) {
+	fields, err := getProjectDefaultCustomField(projectName)
+	if err != nil {
+		logger.Errorf("failed to get default custom fields of project %s, err: %s", projectName, err)
+		return nil, e.ErrGetProduct.AddErr(err)
+	}
+	return fields, nil
+}
+
+func UpdateProjectWorkflowCustomFields(projectName, username string, args *models.CustomField, logger *zap.SugaredLogger) error {
+	if err := validateCustomField(args); err != nil {
+		return e.ErrInvalidParam.AddErr(err)
+	}
+	err := commonrepo.NewProjectCustomFieldColl().Upsert(&models.ProjectCustomField{
+		ProjectName: projectName,
+		CustomField: args,
+		UpdateBy:    username,
+		UpdateTime:  time.Now().Unix(),
+	})
+	if err != nil {
+		logger.Errorf("failed to update default custom fields of project %s, err: %s", projectName, err)
+		return e.ErrUpdateProduct.AddErr(err)
+	}
+	return nil
+}
+
+// getProjectDefaultCustomField returns the custom field configuration of the project, or the built-in one
+// showing the basic columns if the project has none
+func getProjectDefaultCustomField(projectName string) (*models.CustomField, error) {
+	projectField, err := commonrepo.NewProjectCustomFieldColl().Find(projectName)
+	if err == nil && projectField.CustomField != nil {
+		return projectField.CustomField, nil
+	}
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+	return &models.CustomField{
+		TaskID:                 1,
+		Status:                 1,
+		Duration:               1,
+		Executor:               1,
+		BuildServiceComponent:  make(map[string]int),
+		BuildCodeMsg:           make(map[string]int),
+		DeployServiceComponent: make(map[string]int),
+		DeployEnv:              make(map[string]int),
+		TestResult:             make(map[string]int),
+	}, nil
+}
+
+func validateCustomField(fields *models.CustomField) error {
+	if fields == nil {
+		return fmt.Errorf("custom fields can not be empty")
+	}
+	keys := sets.NewString()
+	for _, field := range fields.UserDefined {
+		if field.Key == "" || field.Source == "" {
+			return fmt.Errorf("key and source of custom field can not be empty")
+		}
+		if keys.Has(field.Key) {
+			return fmt.Errorf("duplicated custom field key %s", field.Key)
+		}
+		keys.Insert(field.Key)
+		switch field.Type {
+		case "":
+			field.Type = models.CustomFieldTypeText
+		case models.CustomFieldTypeText, models.CustomFieldTypeNumber, models.CustomFieldTypeLink, models.CustomFieldTypeTime:
+		default:
+			return fmt.Errorf("unsupported type %s of custom field %s", field.Type, field.Key)
+		}
+		if field.Label == "" {
+			field.Label = field.Key
+		}
+	}
+	return nil
+}
+
+// getTaskCustomFieldValues renders the enabled user-defined fields with the variables of the task,
+// a field whose variable is not found in the task has an empty value
+func getTaskCustomFieldValues(task *models.WorkflowTask, fields []*models.UserDefinedField) map[string]string {
+	if len(fields) == 0 {
+		return nil
+	}
+	params := map[string]string{
+		"project":               task.ProjectName,
+		"workflow.name":         task.WorkflowName,
+		"workflow.task.id":      fmt.Sprintf("%d", task.TaskID),
+		"workflow.task.creator": task.TaskCreator,
+	}
+	taskParams := task.Params
+	if task.WorkflowArgs != nil {
+		taskParams = task.WorkflowArgs.Params
+	}
+	for _, param := range taskParams {
+		if param.IsCredential {
+			continue
+		}
+		params[strings.Join([]string{"workflow", "params", param.Name}, ".")] = param.Value
+	}
+
+	resp := make(map[string]string)
+	for _, field := range fields {
+		if field.Enabled == 0 {
+			continue
+		}
+		// job outputs are saved in the global context with the dots replaced
+		if contextValue, ok := task.GlobalContext[workflowcontroller.GetContextKey(field.Source)]; ok {
+			resp[field.Key] = contextValue
+			continue
+		}
+		value := field.Source
+		for name, paramValue := range params {
+			value = strings.ReplaceAll(value, fmt.Sprintf(setting.RenderValueTemplate, name), paramValue)
+		}
+		if strings.Contains(value, "{{.") {
+			value = ""
+		}
+		resp[field.Key] = value
+	}
+	return resp
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/webhook"
+)
+
+// hookStep is a step of a trigger change, undo reverts the step when a later step fails.
+// The code hosts can not join a mongo transaction, so a trigger change is run as steps with compensation.
+type hookStep struct {
+	name string
+	do   func() error
+	undo func() error
+}
+
+// runHookSteps runs the steps in order, the finished steps are undone in the reverse order if a step fails
+// so that a failed change does not leave the triggers half registered
+func runHookSteps(steps []*hookStep, logger *zap.SugaredLogger) error {
+	for i, step := range steps {
+		err := step.do()
+		if err == nil {
+			continue
+		}
+		for j := i - 1; j >= 0; j-- {
+			if steps[j].undo == nil {
+				continue
+			}
+			if undoErr := steps[j].undo(); undoErr != nil {
+				logger.Errorf("failed to undo step %s, the trigger may need a manual fix, err: %s", steps[j].name, undoErr)
+			}
+		}
+		return fmt.Errorf("failed to %s: %s", step.name, err)
+	}
+	return nil
+}
+
+// registerWebhookStep registers the added hooks to the code hosts and removes the removed ones
+func registerWebhookStep(workflowName string, added, removed []*commonmodels.WorkflowV4Hook, logger *zap.SugaredLogger) *hookStep {
+	ref := webhook.WorkflowV4Prefix + workflowName
+	return &hookStep{
+		name: "register webhook",
+		do: func() error {
+			return commonservice.ProcessWebhook(added, removed, ref, logger)
+		},
+		undo: func() error {
+			return commonservice.ProcessWebhook(removed, added, ref, logger)
+		},
+	}
+}
+
+// saveWorkflowStep saves the workflow if it is not changed by others since it is read, hash is the one read
+// from the db. It is the last step so it has nothing to undo.
+func saveWorkflowStep(workflow *commonmodels.WorkflowV4, hash string) *hookStep {
+	return &hookStep{
+		name: "save workflow",
+		do: func() error {
+			return commonrepo.NewWorkflowV4Coll().UpdateIfUnchanged(workflow.ID.Hex(), hash, workflow)
+		},
+	}
+}
@@ -28,11 +28,14 @@ import (
 
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonutil "github.com/koderover/zadig/pkg/microservice/aslan/core/common/util"
 	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/log"
 	"github.com/koderover/zadig/pkg/types"
 	"github.com/koderover/zadig/pkg/types/job"
 )
@@ -40,6 +43,7 @@ import (
 const (
 	OutputNameRegexString = "^[a-zA-Z0-9_]{1,64}$"
 	JobNameKey            = "job_name"
+	BuildArchKey          = "arch"
 )
 
 var (
@@ -87,6 +91,10 @@ func InitJobCtl(job *commonmodels.Job, workflow *commonmodels.WorkflowV4) (JobCt
 		resp = &K8sPacthJob{job: job, workflow: workflow}
 	case config.JobZadigScanning:
 		resp = &ScanningJob{job: job, workflow: workflow}
+	case config.JobZadigSonar:
+		resp = &SonarJob{job: job, workflow: workflow}
+	case config.JobZadigPerformanceTest:
+		resp = &PerformanceTestJob{job: job, workflow: workflow}
 	case config.JobZadigDistributeImage:
 		resp = &ImageDistributeJob{job: job, workflow: workflow}
 	case config.JobIstioRelease:
@@ -111,6 +119,16 @@ func InitJobCtl(job *commonmodels.Job, workflow *commonmodels.WorkflowV4) (JobCt
 		resp = &MseGrayOfflineJob{job: job, workflow: workflow}
 	case config.JobGuanceyunCheck:
 		resp = &GuanceyunCheckJob{job: job, workflow: workflow}
+	case config.JobHarborReplication:
+		resp = &HarborReplicationJob{job: job, workflow: workflow}
+	case config.JobStaticDistribute:
+		resp = &StaticDistributeJob{job: job, workflow: workflow}
+	case config.JobHTTPCallback:
+		resp = &HTTPCallbackJob{job: job, workflow: workflow}
+	case config.JobFeatureFlag:
+		resp = &FeatureFlagJob{job: job, workflow: workflow}
+	case config.JobFeatureFlagRollback:
+		resp = &FeatureFlagRollbackJob{job: job, workflow: workflow}
 	default:
 		return resp, fmt.Errorf("job type not found %s", job.JobType)
 	}
@@ -173,6 +191,96 @@ func LintJob(job *commonmodels.Job, workflow *commonmodels.WorkflowV4) error {
 	return jobCtl.LintJob()
 }
 
+// LintJobOnSave runs the checks of the workflow definition, like the output references, and the ones which depend on
+// the data out of the workflow, like the plugin registry, they only run when the workflow is saved so that they don't
+// cost the task creation or block the tasks of the saved workflows after a later change of the data
+func LintJobOnSave(job *commonmodels.Job, workflow *commonmodels.WorkflowV4) error {
+	if err := lintOutputReferences(job, workflow); err != nil {
+		return warpJobError(job.Name, err)
+	}
+	switch job.JobType {
+	case config.JobPlugin:
+		spec := &commonmodels.PluginJobSpec{}
+		if err := commonmodels.IToiYaml(job.Spec, spec); err != nil {
+			return warpJobError(job.Name, err)
+		}
+		if err := checkPluginCompatibility(workflow.Project, spec.Plugin); err != nil {
+			return warpJobError(job.Name, err)
+		}
+	}
+	return nil
+}
+
+var outputReferenceRegex = regexp.MustCompile(`\{\{\s*\.job\.([^.\s{}|()"]+)(?:\.[^.\s{}|()"]+)*\.output\.([A-Za-z0-9_]+)`)
+
+// lintOutputReferences makes sure every job output referenced in the job spec is
+// declared by a job which runs before the current one. The scripts are free-form
+// and not checked.
+func lintOutputReferences(job *commonmodels.Job, workflow *commonmodels.WorkflowV4) error {
+	spec, err := json.Marshal(job.Spec)
+	if err != nil {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(spec, &value); err != nil {
+		return nil
+	}
+	matches := [][]string{}
+	for _, str := range collectNonScriptStrings(value) {
+		matches = append(matches, outputReferenceRegex.FindAllStringSubmatch(str, -1)...)
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+	jobRankMap := getJobRankMap(workflow.Stages)
+	declared := map[string]sets.String{}
+	for _, output := range ListJobOutputs(workflow, job.Name, log.SugaredLogger()) {
+		if _, ok := declared[output.JobName]; !ok {
+			declared[output.JobName] = sets.NewString()
+		}
+		declared[output.JobName].Insert(output.Name)
+	}
+	for _, match := range matches {
+		refJob, outputName := match[1], match[2]
+		rank, ok := jobRankMap[refJob]
+		if !ok {
+			return fmt.Errorf("output %s references job %s which does not exist", strings.TrimLeft(match[0], "{ ."), refJob)
+		}
+		if rank >= jobRankMap[job.Name] {
+			return fmt.Errorf("output %s references job %s which does not run before job %s", strings.TrimLeft(match[0], "{ ."), refJob, job.Name)
+		}
+		if !declared[refJob].Has(outputName) {
+			return fmt.Errorf("job %s does not declare output %s", refJob, outputName)
+		}
+	}
+	return nil
+}
+
+func isScriptField(key string) bool {
+	return key == "script" || key == "scripts" || strings.HasSuffix(key, "_script") || strings.HasSuffix(key, "_scripts")
+}
+
+// collectNonScriptStrings returns the strings in the decoded json value except the ones under the script fields
+func collectNonScriptStrings(value interface{}) []string {
+	resp := []string{}
+	switch v := value.(type) {
+	case string:
+		resp = append(resp, v)
+	case []interface{}:
+		for _, item := range v {
+			resp = append(resp, collectNonScriptStrings(item)...)
+		}
+	case map[string]interface{}:
+		for key, item := range v {
+			if isScriptField(key) {
+				continue
+			}
+			resp = append(resp, collectNonScriptStrings(item)...)
+		}
+	}
+	return resp
+}
+
 func MergeWebhookRepo(workflow *commonmodels.WorkflowV4, repo *types.Repository) error {
 	for _, stage := range workflow.Stages {
 		for _, job := range stage.Jobs {
@@ -200,13 +308,42 @@ func MergeWebhookRepo(workflow *commonmodels.WorkflowV4, repo *types.Repository)
 					return warpJobError(job.Name, err)
 				}
 			}
+			if job.JobType == config.JobZadigSonar {
+				jobCtl := &SonarJob{job: job, workflow: workflow}
+				if err := jobCtl.MergeWebhookRepo(repo); err != nil {
+					return warpJobError(job.Name, err)
+				}
+			}
+			if job.JobType == config.JobZadigPerformanceTest {
+				jobCtl := &PerformanceTestJob{job: job, workflow: workflow}
+				if err := jobCtl.MergeWebhookRepo(repo); err != nil {
+					return warpJobError(job.Name, err)
+				}
+			}
 		}
 	}
 	return nil
 }
 
-func GetWorkflowOutputs(workflow *commonmodels.WorkflowV4, currentJobName string, log *zap.SugaredLogger) []string {
-	resp := []string{}
+// WorkflowVariable is a variable that can be referenced by a job, e.g. an output
+// declared by a job that runs before it.
+type WorkflowVariable struct {
+	Key         string `json:"key"`
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description,omitempty"`
+	JobName     string `json:"job_name,omitempty"`
+	JobType     string `json:"job_type,omitempty"`
+}
+
+// outputDeclarer is implemented by jobs that declare outputs for subsequent jobs.
+type outputDeclarer interface {
+	GetOutputs(log *zap.SugaredLogger) []*WorkflowVariable
+}
+
+// ListJobOutputs returns the outputs declared by the jobs which run before currentJobName.
+func ListJobOutputs(workflow *commonmodels.WorkflowV4, currentJobName string, log *zap.SugaredLogger) []*WorkflowVariable {
+	resp := []*WorkflowVariable{}
 	jobRankMap := getJobRankMap(workflow.Stages)
 	for _, stage := range workflow.Stages {
 		for _, job := range stage.Jobs {
@@ -214,34 +351,27 @@ func GetWorkflowOutputs(workflow *commonmodels.WorkflowV4, currentJobName string
 			if jobRankMap[job.Name] >= jobRankMap[currentJobName] {
 				return resp
 			}
-			switch job.JobType {
-			case config.JobZadigBuild:
-				jobCtl := &BuildJob{job: job, workflow: workflow}
-				resp = append(resp, jobCtl.GetOutPuts(log)...)
-			case config.JobFreestyle:
-				jobCtl := &FreeStyleJob{job: job, workflow: workflow}
-				resp = append(resp, jobCtl.GetOutPuts(log)...)
-			case config.JobZadigTesting:
-				jobCtl := &TestingJob{job: job, workflow: workflow}
-				resp = append(resp, jobCtl.GetOutPuts(log)...)
-			case config.JobZadigScanning:
-				jobCtl := &ScanningJob{job: job, workflow: workflow}
-				resp = append(resp, jobCtl.GetOutPuts(log)...)
-			case config.JobZadigDistributeImage:
-				jobCtl := &ImageDistributeJob{job: job, workflow: workflow}
-				resp = append(resp, jobCtl.GetOutPuts(log)...)
-			case config.JobPlugin:
-				jobCtl := &PluginJob{job: job, workflow: workflow}
-				resp = append(resp, jobCtl.GetOutPuts(log)...)
-			case config.JobZadigDeploy:
-				jobCtl := &DeployJob{job: job, workflow: workflow}
-				resp = append(resp, jobCtl.GetOutPuts(log)...)
+			jobCtl, err := InitJobCtl(job, workflow)
+			if err != nil {
+				log.Errorf("init job %s failed, err: %v", job.Name, err)
+				continue
+			}
+			if declarer, ok := jobCtl.(outputDeclarer); ok {
+				resp = append(resp, declarer.GetOutputs(log)...)
 			}
 		}
 	}
 	return resp
 }
 
+func GetWorkflowOutputs(workflow *commonmodels.WorkflowV4, currentJobName string, log *zap.SugaredLogger) []string {
+	resp := []string{}
+	for _, output := range ListJobOutputs(workflow, currentJobName, log) {
+		resp = append(resp, output.Key)
+	}
+	return resp
+}
+
 type RepoIndex struct {
 	JobName       string `json:"job_name"`
 	ServiceName   string `json:"service_name"`
@@ -331,6 +461,22 @@ func GetRepos(workflow *commonmodels.WorkflowV4) ([]*types.Repository, error) {
 				}
 				repos = append(repos, scanningRepos...)
 			}
+			if job.JobType == config.JobZadigSonar {
+				jobCtl := &SonarJob{job: job, workflow: workflow}
+				sonarRepos, err := jobCtl.GetRepos()
+				if err != nil {
+					return repos, warpJobError(job.Name, err)
+				}
+				repos = append(repos, sonarRepos...)
+			}
+			if job.JobType == config.JobZadigPerformanceTest {
+				jobCtl := &PerformanceTestJob{job: job, workflow: workflow}
+				performanceRepos, err := jobCtl.GetRepos()
+				if err != nil {
+					return repos, warpJobError(job.Name, err)
+				}
+				repos = append(repos, performanceRepos...)
+			}
 		}
 	}
 	newRepos := []*types.Repository{}
@@ -456,6 +602,7 @@ func RenderGlobalVariables(workflow *commonmodels.WorkflowV4, taskID int64, crea
 		return fmt.Errorf("get workflow default params error: %v", err)
 	}
 	replacedString := renderMultiLineString(string(b), setting.RenderValueTemplate, params)
+	replacedString = renderExpressions(replacedString, params)
 	return json.Unmarshal([]byte(replacedString), &workflow)
 }
 
@@ -469,9 +616,19 @@ func RenderStageVariables(workflow *commonmodels.WorkflowV4, taskID int64, creat
 		return fmt.Errorf("get workflow stage params error: %v", err)
 	}
 	replacedString := renderMultiLineString(string(b), setting.RenderValueTemplate, params)
+	replacedString = renderExpressions(replacedString, params)
 	return json.Unmarshal([]byte(replacedString), &workflow)
 }
 
+// renderExpressions evaluates the expressions with functions such as {{upper .workflow.params.env}}
+func renderExpressions(value string, inputs []*commonmodels.Param) string {
+	variables := make(map[string]string, len(inputs))
+	for _, input := range inputs {
+		variables[input.Name] = input.Value
+	}
+	return commonutil.RenderExpressions(value, variables)
+}
+
 func renderString(value, template string, inputs []*commonmodels.Param) string {
 	for _, input := range inputs {
 		value = strings.ReplaceAll(value, fmt.Sprintf(template, input.Name), input.Value)
@@ -578,10 +735,20 @@ func getJobRankMap(stages []*commonmodels.WorkflowStage) map[string]int {
 	return resp
 }
 
-func getOutputKey(jobKey string, outputs []*commonmodels.Output) []string {
-	resp := []string{}
+func newJobOutputs(jobInfo *commonmodels.Job, jobKey string, outputs []*commonmodels.Output) []*WorkflowVariable {
+	resp := []*WorkflowVariable{}
 	for _, output := range outputs {
-		resp = append(resp, job.GetJobOutputKey(jobKey, output.Name))
+		outputType := output.Type
+		if outputType == "" {
+			outputType = commonmodels.OutputTypeString
+		}
+		resp = append(resp, &WorkflowVariable{
+			Key:     job.GetJobOutputKey(jobKey, output.Name),
+			Name:    output.Name,
+			Type:    outputType,
+			JobName: jobInfo.Name,
+			JobType: string(jobInfo.JobType),
+		})
 	}
 	return resp
 }
@@ -595,11 +762,42 @@ func outputScript(outputs []*commonmodels.Output) []string {
 	return resp
 }
 
+// generate powershell script to save outputs variable to file, the outputs are read from the environment variables
+func outputPowerShellScript(outputs []*commonmodels.Output) []string {
+	resp := []string{"$ErrorActionPreference = 'Continue'"}
+	for _, output := range outputs {
+		resp = append(resp, fmt.Sprintf(`Set-Content -NoNewline -Path %s -Value "$env:%s"`, path.Join(job.JobOutputDir, output.Name), output.Name))
+	}
+	return resp
+}
+
+// checkVMJob rejects the settings depending on the job pod for the jobs running on the vm agents
+func checkVMJob(properties *commonmodels.JobProperties) error {
+	if len(properties.ServiceContainers) > 0 {
+		return fmt.Errorf("service containers are not supported on vm")
+	}
+	if properties.UseHostDockerDaemon {
+		return fmt.Errorf("host docker daemon is not supported on vm")
+	}
+	if properties.RetainPodOnFailure {
+		return fmt.Errorf("retaining the job pod is not supported on vm")
+	}
+	if len(properties.ShareStorageDetails) > 0 || (properties.ShareStorageInfo != nil && properties.ShareStorageInfo.Enabled) {
+		return fmt.Errorf("share storage is not supported on vm")
+	}
+	return nil
+}
+
 func checkOutputNames(outputs []*commonmodels.Output) error {
 	for _, output := range outputs {
 		if match := OutputNameRegex.MatchString(output.Name); !match {
 			return fmt.Errorf("output name must match %s", OutputNameRegexString)
 		}
+		switch output.Type {
+		case "", commonmodels.OutputTypeString, commonmodels.OutputTypeNumber, commonmodels.OutputTypeBoolean, commonmodels.OutputTypeJSON, commonmodels.OutputTypeImage:
+		default:
+			return fmt.Errorf("output %s has unsupported type %s", output.Name, output.Type)
+		}
 	}
 	return nil
 }
@@ -23,14 +23,19 @@ import (
 	"strings"
 
 	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	configbase "github.com/koderover/zadig/pkg/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/template"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
 	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/repository"
 	templ "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/template"
+	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/tool/log"
 	"github.com/koderover/zadig/pkg/types"
 	"github.com/koderover/zadig/pkg/types/job"
@@ -41,6 +46,8 @@ const (
 	IMAGEKEY    = "IMAGE"
 	IMAGETAGKEY = "imageTag"
 	PKGFILEKEY  = "PKG_FILE"
+	// BUILDVERSIONKEY is the output of the version calculated by the versioning of the build job
+	BUILDVERSIONKEY = "VERSION"
 )
 
 type BuildJob struct {
@@ -199,9 +206,32 @@ func (j *BuildJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 	if err != nil {
 		return resp, fmt.Errorf("find default s3 storage error: %v", err)
 	}
+	project, err := templaterepo.NewProductColl().Find(j.workflow.Project)
+	if err != nil {
+		return resp, fmt.Errorf("find project: %s error: %v", j.workflow.Project, err)
+	}
 
 	for _, build := range j.spec.ServiceAndBuilds {
 		imageTag := commonservice.ReleaseCandidate(build.Repos, taskID, j.workflow.Project, build.ServiceModule, "", build.ImageName, "image")
+		version := ""
+		if j.spec.Versioning != nil {
+			// the version is kept in the spec of the task, a retry of the task reuses it instead of taking a new one
+			version = build.Version
+			if version == "" {
+				version, err = calculateBuildVersion(j.spec.Versioning, build, j.workflow.Name, j.job.Name, logger)
+				if err != nil {
+					return resp, fmt.Errorf("calculate version of build %s error: %v", build.BuildName, err)
+				}
+				build.Version = version
+			}
+			if j.spec.Versioning.UseAsImageTag {
+				imageName := build.ImageName
+				if imageName == "" {
+					imageName = build.ServiceModule
+				}
+				imageTag = fmt.Sprintf("%s:%s", imageName, version)
+			}
+		}
 
 		image := fmt.Sprintf("%s/%s", registry.RegAddr, imageTag)
 		if len(registry.Namespace) > 0 {
@@ -221,212 +251,323 @@ func (j *BuildJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 		if err := fillBuildDetail(buildInfo, build.ServiceName, build.ServiceModule); err != nil {
 			return resp, err
 		}
-		basicImage, err := commonrepo.NewBasicImageColl().Find(buildInfo.PreBuild.ImageID)
-		if err != nil {
-			return resp, fmt.Errorf("find base image: %s error: %v", buildInfo.PreBuild.ImageID, err)
+		// builds on vm run on the host of the agent instead of the basic image
+		basicImage := &commonmodels.BasicImage{}
+		if buildInfo.PreBuild.Infrastructure != setting.JobVMInfrastructure {
+			basicImage, err = commonrepo.NewBasicImageColl().Find(buildInfo.PreBuild.ImageID)
+			if err != nil {
+				return resp, fmt.Errorf("find base image: %s error: %v", buildInfo.PreBuild.ImageID, err)
+			}
+		}
+		windows := basicImage.OS == setting.OSWindows
+		if windows {
+			if err := checkWindowsBuild(buildInfo); err != nil {
+				return resp, fmt.Errorf("build %s: %v", build.BuildName, err)
+			}
+		}
+		if len(j.spec.Archs) > 0 {
+			if err := checkMultiArchBuild(buildInfo, basicImage.OS); err != nil {
+				return resp, fmt.Errorf("build %s: %v", build.BuildName, err)
+			}
 		}
 		registries, err := commonservice.ListRegistryNamespaces("", true, logger)
 		if err != nil {
 			return resp, err
 		}
-		outputs := ensureBuildInOutputs(buildInfo.Outputs)
-		jobTaskSpec := &commonmodels.JobTaskFreestyleSpec{}
-		jobTask := &commonmodels.JobTask{
-			Name: jobNameFormat(build.ServiceName + "-" + build.ServiceModule + "-" + j.job.Name),
-			JobInfo: map[string]string{
+		jobName := build.ServiceName + "-" + build.ServiceModule + "-" + j.job.Name
+		jobKey := strings.Join([]string{j.job.Name, build.ServiceName, build.ServiceModule}, ".")
+		// multi-arch builds run a job for each arch, and the job finishing last assembles the manifest list
+		for _, arch := range getBuildArchs(j.spec.Archs) {
+			outputs := ensureBuildInOutputs(buildInfo.Outputs)
+			if j.spec.Versioning != nil {
+				outputs = ensureVersionInOutputs(outputs)
+			}
+			jobTaskSpec := &commonmodels.JobTaskFreestyleSpec{}
+			jobInfo := map[string]string{
 				"service_name":   build.ServiceName,
 				"service_module": build.ServiceModule,
 				JobNameKey:       j.job.Name,
-			},
-			Key:     strings.Join([]string{j.job.Name, build.ServiceName, build.ServiceModule}, "."),
-			JobType: string(config.JobZadigBuild),
-			Spec:    jobTaskSpec,
-			Timeout: int64(buildInfo.Timeout),
-			Outputs: outputs,
-		}
-		jobTaskSpec.Properties = commonmodels.JobProperties{
-			Timeout:             int64(buildInfo.Timeout),
-			ResourceRequest:     buildInfo.PreBuild.ResReq,
-			ResReqSpec:          buildInfo.PreBuild.ResReqSpec,
-			CustomEnvs:          renderKeyVals(build.KeyVals, buildInfo.PreBuild.Envs),
-			ClusterID:           buildInfo.PreBuild.ClusterID,
-			StrategyID:          buildInfo.PreBuild.StrategyID,
-			BuildOS:             basicImage.Value,
-			ImageFrom:           buildInfo.PreBuild.ImageFrom,
-			Registries:          registries,
-			ShareStorageDetails: getShareStorageDetail(j.workflow.ShareStorages, build.ShareStorageInfo, j.workflow.Name, taskID),
-		}
-		clusterInfo, err := commonrepo.NewK8SClusterColl().Get(buildInfo.PreBuild.ClusterID)
-		if err != nil {
-			return resp, fmt.Errorf("find cluster: %s error: %v", buildInfo.PreBuild.ClusterID, err)
-		}
-
-		if clusterInfo.Cache.MediumType == "" {
-			jobTaskSpec.Properties.CacheEnable = false
-		} else {
-			jobTaskSpec.Properties.Cache = clusterInfo.Cache
-			jobTaskSpec.Properties.CacheEnable = buildInfo.CacheEnable
-			jobTaskSpec.Properties.CacheDirType = buildInfo.CacheDirType
-			jobTaskSpec.Properties.CacheUserDir = buildInfo.CacheUserDir
-		}
-		jobTaskSpec.Properties.Envs = append(jobTaskSpec.Properties.CustomEnvs, getBuildJobVariables(build, taskID, j.workflow.Project, j.workflow.Name, image, registry, logger)...)
-		jobTaskSpec.Properties.UseHostDockerDaemon = buildInfo.PreBuild.UseHostDockerDaemon
-
-		if jobTaskSpec.Properties.CacheEnable && jobTaskSpec.Properties.Cache.MediumType == types.NFSMedium {
-			jobTaskSpec.Properties.CacheUserDir = renderEnv(jobTaskSpec.Properties.CacheUserDir, jobTaskSpec.Properties.Envs)
-			jobTaskSpec.Properties.Cache.NFSProperties.Subpath = renderEnv(jobTaskSpec.Properties.Cache.NFSProperties.Subpath, jobTaskSpec.Properties.Envs)
-		}
-
-		// for other job refer current latest image.
-		build.Image = job.GetJobOutputKey(jobTask.Key, "IMAGE")
-		log.Infof("BuildJob ToJobs %d: workflow %s service %s, module %s, image %s",
-			taskID, j.workflow.Name, build.ServiceName, build.ServiceModule, build.Image)
-
-		// init tools install step
-		tools := []*step.Tool{}
-		for _, tool := range buildInfo.PreBuild.Installs {
-			tools = append(tools, &step.Tool{
-				Name:    tool.Name,
-				Version: tool.Version,
-			})
-		}
-		toolInstallStep := &commonmodels.StepTask{
-			Name:     fmt.Sprintf("%s-%s", build.ServiceName, "tool-install"),
-			JobName:  jobTask.Name,
-			StepType: config.StepTools,
-			Spec:     step.StepToolInstallSpec{Installs: tools},
-		}
-		jobTaskSpec.Steps = append(jobTaskSpec.Steps, toolInstallStep)
-		// init git clone step
-		gitStep := &commonmodels.StepTask{
-			Name:     build.ServiceName + "-git",
-			JobName:  jobTask.Name,
-			StepType: config.StepGit,
-			Spec:     step.StepGitSpec{Repos: renderRepos(build.Repos, buildInfo.Repos, jobTaskSpec.Properties.Envs)},
-		}
-		jobTaskSpec.Steps = append(jobTaskSpec.Steps, gitStep)
-		// init debug before step
-		debugBeforeStep := &commonmodels.StepTask{
-			Name:     build.ServiceName + "-debug_before",
-			JobName:  jobTask.Name,
-			StepType: config.StepDebugBefore,
-		}
-		jobTaskSpec.Steps = append(jobTaskSpec.Steps, debugBeforeStep)
-		// init shell step
-		dockerLoginCmd := `docker login -u "$DOCKER_REGISTRY_AK" -p "$DOCKER_REGISTRY_SK" "$DOCKER_REGISTRY_HOST" &> /dev/null`
-		scripts := append([]string{dockerLoginCmd}, strings.Split(replaceWrapLine(buildInfo.Scripts), "\n")...)
-		scripts = append(scripts, outputScript(outputs)...)
-		shellStep := &commonmodels.StepTask{
-			Name:     build.ServiceName + "-shell",
-			JobName:  jobTask.Name,
-			StepType: config.StepShell,
-			Spec: &step.StepShellSpec{
-				Scripts: scripts,
-			},
-		}
-		jobTaskSpec.Steps = append(jobTaskSpec.Steps, shellStep)
-		// init debug after step
-		debugAfterStep := &commonmodels.StepTask{
-			Name:     build.ServiceName + "-debug_after",
-			JobName:  jobTask.Name,
-			StepType: config.StepDebugAfter,
-		}
-		jobTaskSpec.Steps = append(jobTaskSpec.Steps, debugAfterStep)
-		// init docker build step
-		if buildInfo.PostBuild != nil && buildInfo.PostBuild.DockerBuild != nil {
-			dockefileContent := ""
-			if buildInfo.PostBuild.DockerBuild.TemplateID != "" {
-				if dockerfileDetail, err := templ.GetDockerfileTemplateDetail(buildInfo.PostBuild.DockerBuild.TemplateID, logger); err == nil {
-					dockefileContent = dockerfileDetail.Content
+			}
+			jobTask := &commonmodels.JobTask{
+				Name:    jobNameFormat(jobName),
+				JobInfo: jobInfo,
+				Key:     jobKey,
+				JobType: string(config.JobZadigBuild),
+				Spec:    jobTaskSpec,
+				Timeout: int64(buildInfo.Timeout),
+				Outputs: outputs,
+			}
+			jobTaskSpec.Properties = commonmodels.JobProperties{
+				Timeout:             int64(buildInfo.Timeout),
+				ResourceRequest:     buildInfo.PreBuild.ResReq,
+				ResReqSpec:          buildInfo.PreBuild.ResReqSpec,
+				CustomEnvs:          renderKeyVals(build.KeyVals, buildInfo.PreBuild.Envs),
+				ClusterID:           buildInfo.PreBuild.ClusterID,
+				StrategyID:          buildInfo.PreBuild.StrategyID,
+				SchedulingHints:     buildInfo.PreBuild.SchedulingHints,
+				ServiceContainers:   buildInfo.PreBuild.ServiceContainers,
+				Infrastructure:      buildInfo.PreBuild.Infrastructure,
+				VMLabels:            buildInfo.PreBuild.VMLabels,
+				WorkloadIdentity:    buildInfo.PreBuild.WorkloadIdentity,
+				BuildOS:             basicImage.Value,
+				OS:                  basicImage.OS,
+				ImageFrom:           buildInfo.PreBuild.ImageFrom,
+				Registries:          registries,
+				ShareStorageDetails: getShareStorageDetail(j.workflow.ShareStorages, build.ShareStorageInfo, j.workflow.Name, taskID),
+			}
+			archImage := image
+			if arch != nil {
+				// the image of each arch is pushed with the arch suffixed tag, the outputs of all the arch jobs
+				// are written under the key of the build so that the other jobs refer to the manifest list
+				jobTask.Name = jobNameFormat(jobName + "-" + arch.Arch)
+				jobTask.Key = jobKey + "." + arch.Arch
+				jobTask.OutputKey = jobKey
+				jobInfo[BuildArchKey] = arch.Arch
+				archImage = getArchImage(image, arch.Arch)
+				setBuildArchProperties(&jobTaskSpec.Properties, arch)
+			}
+			clusterInfo, err := commonrepo.NewK8SClusterColl().Get(buildInfo.PreBuild.ClusterID)
+			if err != nil {
+				return resp, fmt.Errorf("find cluster: %s error: %v", buildInfo.PreBuild.ClusterID, err)
+			}
+
+			if clusterInfo.Cache.MediumType == "" {
+				jobTaskSpec.Properties.CacheEnable = false
+			} else {
+				jobTaskSpec.Properties.Cache = clusterInfo.Cache
+				jobTaskSpec.Properties.CacheEnable = buildInfo.CacheEnable
+				jobTaskSpec.Properties.CacheDirType = buildInfo.CacheDirType
+				jobTaskSpec.Properties.CacheUserDir = buildInfo.CacheUserDir
+				jobTaskSpec.Properties.CacheRules = buildInfo.CacheRules
+			}
+			jobTaskSpec.Properties.Envs = append(jobTaskSpec.Properties.CustomEnvs, getBuildJobVariables(build, taskID, j.workflow.Project, j.workflow.Name, archImage, registry, logger)...)
+			if j.spec.Versioning != nil {
+				jobTaskSpec.Properties.Envs = append(jobTaskSpec.Properties.Envs, &commonmodels.KeyVal{Key: BUILDVERSIONKEY, Value: version, IsCredential: false})
+			}
+			jobTaskSpec.Properties.UseHostDockerDaemon = buildInfo.PreBuild.UseHostDockerDaemon
+			if jobTaskSpec.Properties.Infrastructure == setting.JobVMInfrastructure {
+				if err := checkVMJob(&jobTaskSpec.Properties); err != nil {
+					return resp, fmt.Errorf("build %s: %v", build.BuildName, err)
 				}
 			}
 
-			dockerBuildStep := &commonmodels.StepTask{
-				Name:     build.ServiceName + "-docker-build",
-				JobName:  jobTask.Name,
-				StepType: config.StepDockerBuild,
-				Spec: step.StepDockerBuildSpec{
-					Source:                buildInfo.PostBuild.DockerBuild.Source,
-					WorkDir:               buildInfo.PostBuild.DockerBuild.WorkDir,
-					DockerFile:            buildInfo.PostBuild.DockerBuild.DockerFile,
-					ImageName:             "$IMAGE",
-					ImageReleaseTag:       imageTag,
-					BuildArgs:             buildInfo.PostBuild.DockerBuild.BuildArgs,
-					DockerTemplateContent: dockefileContent,
-					DockerRegistry: &step.DockerRegistry{
-						DockerRegistryID: j.spec.DockerRegistryID,
-						Host:             registry.RegAddr,
-						UserName:         registry.AccessKey,
-						Password:         registry.SecretKey,
-						Namespace:        registry.Namespace,
-					},
-				},
+			if jobTaskSpec.Properties.CacheEnable && jobTaskSpec.Properties.Cache.MediumType == types.NFSMedium {
+				jobTaskSpec.Properties.CacheUserDir = renderEnv(jobTaskSpec.Properties.CacheUserDir, jobTaskSpec.Properties.Envs)
+				jobTaskSpec.Properties.Cache.NFSProperties.Subpath = renderEnv(jobTaskSpec.Properties.Cache.NFSProperties.Subpath, jobTaskSpec.Properties.Envs)
 			}
-			jobTaskSpec.Steps = append(jobTaskSpec.Steps, dockerBuildStep)
-		}
 
-		// init archive step
-		if buildInfo.PostBuild != nil && buildInfo.PostBuild.FileArchive != nil && buildInfo.PostBuild.FileArchive.FileLocation != "" {
-			uploads := []*step.Upload{
-				{
-					FilePath:        path.Join(buildInfo.PostBuild.FileArchive.FileLocation, build.Package),
-					DestinationPath: path.Join(j.workflow.Name, fmt.Sprint(taskID), jobTask.Name, "archive"),
-				},
+			// for other job refer current latest image.
+			build.Image = job.GetJobOutputKey(jobKey, "IMAGE")
+			log.Infof("BuildJob ToJobs %d: workflow %s service %s, module %s, image %s",
+				taskID, j.workflow.Name, build.ServiceName, build.ServiceModule, build.Image)
+
+			// init tools install step
+			tools := []*step.Tool{}
+			for _, tool := range buildInfo.PreBuild.Installs {
+				tools = append(tools, &step.Tool{
+					Name:    tool.Name,
+					Version: tool.Version,
+				})
 			}
-			archiveStep := &commonmodels.StepTask{
-				Name:     build.ServiceName + "-archive",
+			toolInstallStep := &commonmodels.StepTask{
+				Name:     fmt.Sprintf("%s-%s", build.ServiceName, "tool-install"),
 				JobName:  jobTask.Name,
-				StepType: config.StepArchive,
-				Spec: step.StepArchiveSpec{
-					UploadDetail: uploads,
-					S3:           modelS3toS3(defaultS3),
-				},
+				StepType: config.StepTools,
+				Spec:     step.StepToolInstallSpec{Installs: tools},
 			}
-			jobTaskSpec.Steps = append(jobTaskSpec.Steps, archiveStep)
-		}
-
-		// init object storage step
-		if buildInfo.PostBuild != nil && buildInfo.PostBuild.ObjectStorageUpload != nil && buildInfo.PostBuild.ObjectStorageUpload.Enabled {
-			modelS3, err := commonrepo.NewS3StorageColl().Find(buildInfo.PostBuild.ObjectStorageUpload.ObjectStorageID)
-			if err != nil {
-				return resp, fmt.Errorf("find object storage: %s failed, err: %v", buildInfo.PostBuild.ObjectStorageUpload.ObjectStorageID, err)
-			}
-			s3 := modelS3toS3(modelS3)
-			s3.Subfolder = ""
-			uploads := []*step.Upload{}
-			for _, detail := range buildInfo.PostBuild.ObjectStorageUpload.UploadDetail {
-				uploads = append(uploads, &step.Upload{
-					FilePath:        detail.FilePath,
-					DestinationPath: detail.DestinationPath,
+			jobTaskSpec.Steps = append(jobTaskSpec.Steps, toolInstallStep)
+			// init git clone step
+			repos := renderRepos(build.Repos, buildInfo.Repos, jobTaskSpec.Properties.Envs)
+			gitStep := &commonmodels.StepTask{
+				Name:     build.ServiceName + "-git",
+				JobName:  jobTask.Name,
+				StepType: config.StepGit,
+				Spec:     step.StepGitSpec{Repos: repos},
+			}
+			jobTaskSpec.Steps = append(jobTaskSpec.Steps, gitStep)
+			// init restore cache step
+			cacheSpec := getCacheStepSpec(jobTaskSpec.Properties, j.workflow.Project, build.BuildName)
+			if cacheSpec != nil {
+				jobTaskSpec.Steps = append(jobTaskSpec.Steps, &commonmodels.StepTask{
+					Name:     build.ServiceName + "-restore-cache",
+					JobName:  jobTask.Name,
+					StepType: config.StepRestoreCache,
+					Spec:     cacheSpec,
 				})
 			}
-			archiveStep := &commonmodels.StepTask{
-				Name:     build.ServiceName + "-object-storage",
+			// init debug before step
+			debugBeforeStep := &commonmodels.StepTask{
+				Name:     build.ServiceName + "-debug_before",
 				JobName:  jobTask.Name,
-				StepType: config.StepArchive,
-				Spec: step.StepArchiveSpec{
-					UploadDetail:    uploads,
-					ObjectStorageID: buildInfo.PostBuild.ObjectStorageUpload.ObjectStorageID,
-					S3:              s3,
-				},
+				StepType: config.StepDebugBefore,
+			}
+			jobTaskSpec.Steps = append(jobTaskSpec.Steps, debugBeforeStep)
+			// init service ready step
+			if len(jobTaskSpec.Properties.ServiceContainers) > 0 {
+				jobTaskSpec.Steps = append(jobTaskSpec.Steps, getServiceReadyStep(build.ServiceName, jobTask.Name, jobTaskSpec.Properties.ServiceContainers))
+			}
+			// init shell step
+			dockerLoginCmd := `docker login -u "$DOCKER_REGISTRY_AK" -p "$DOCKER_REGISTRY_SK" "$DOCKER_REGISTRY_HOST" &> /dev/null`
+			scripts := append([]string{dockerLoginCmd}, strings.Split(replaceWrapLine(buildInfo.Scripts), "\n")...)
+			scripts = append(scripts, outputScript(outputs)...)
+			shellStepType := config.StepShell
+			if windows {
+				// the windows agents run the scripts with powershell and do not log in to the registry
+				scripts = append(strings.Split(replaceWrapLine(buildInfo.Scripts), "\n"), outputPowerShellScript(outputs)...)
+				shellStepType = config.StepPowerShell
 			}
-			jobTaskSpec.Steps = append(jobTaskSpec.Steps, archiveStep)
-		}
-
-		// init post build shell step
-		if buildInfo.PostBuild != nil && buildInfo.PostBuild.Scripts != "" {
-			scripts := append([]string{dockerLoginCmd}, strings.Split(replaceWrapLine(buildInfo.PostBuild.Scripts), "\n")...)
 			shellStep := &commonmodels.StepTask{
-				Name:     build.ServiceName + "-post-shell",
+				Name:     build.ServiceName + "-shell",
 				JobName:  jobTask.Name,
-				StepType: config.StepShell,
+				StepType: shellStepType,
 				Spec: &step.StepShellSpec{
 					Scripts: scripts,
 				},
 			}
 			jobTaskSpec.Steps = append(jobTaskSpec.Steps, shellStep)
+			// init save cache step
+			if cacheSpec != nil {
+				jobTaskSpec.Steps = append(jobTaskSpec.Steps, &commonmodels.StepTask{
+					Name:     build.ServiceName + "-save-cache",
+					JobName:  jobTask.Name,
+					StepType: config.StepSaveCache,
+					Spec:     cacheSpec,
+				})
+			}
+			// init coverage step
+			coverageStep := getCoverageStep(buildInfo.Coverage, build.BuildName, build.ServiceName, build.ServiceModule, build.Repos, jobTask.Name, path.Join(j.workflow.Name, fmt.Sprint(taskID), jobTask.Name, "coverage"))
+			if coverageStep != nil {
+				jobTaskSpec.Steps = append(jobTaskSpec.Steps, coverageStep)
+			}
+			publishStep, err := getPublishStep(j.spec.PublishPaths, jobTask.Name, path.Join(j.workflow.Name, fmt.Sprint(taskID), jobTask.Name, "publish"))
+			if err != nil {
+				return resp, err
+			}
+			if publishStep != nil {
+				jobTaskSpec.Steps = append(jobTaskSpec.Steps, publishStep)
+			}
+			// init debug after step
+			debugAfterStep := &commonmodels.StepTask{
+				Name:     build.ServiceName + "-debug_after",
+				JobName:  jobTask.Name,
+				StepType: config.StepDebugAfter,
+			}
+			jobTaskSpec.Steps = append(jobTaskSpec.Steps, debugAfterStep)
+			// init docker build step
+			if buildInfo.PostBuild != nil && buildInfo.PostBuild.DockerBuild != nil {
+				dockefileContent := ""
+				if buildInfo.PostBuild.DockerBuild.TemplateID != "" {
+					if dockerfileDetail, err := templ.GetDockerfileTemplateDetail(buildInfo.PostBuild.DockerBuild.TemplateID, logger); err == nil {
+						dockefileContent = dockerfileDetail.Content
+					}
+				}
+
+				dockerBuildStep := &commonmodels.StepTask{
+					Name:     build.ServiceName + "-docker-build",
+					JobName:  jobTask.Name,
+					StepType: config.StepDockerBuild,
+					Spec: step.StepDockerBuildSpec{
+						Source:                buildInfo.PostBuild.DockerBuild.Source,
+						WorkDir:               buildInfo.PostBuild.DockerBuild.WorkDir,
+						DockerFile:            buildInfo.PostBuild.DockerBuild.DockerFile,
+						ImageName:             "$IMAGE",
+						ImageReleaseTag:       imageTag,
+						BuildArgs:             buildInfo.PostBuild.DockerBuild.BuildArgs,
+						DockerTemplateContent: dockefileContent,
+						DockerRegistry: &step.DockerRegistry{
+							DockerRegistryID: j.spec.DockerRegistryID,
+							Host:             registry.RegAddr,
+							UserName:         registry.AccessKey,
+							Password:         registry.SecretKey,
+							Namespace:        registry.Namespace,
+						},
+					},
+				}
+				if j.spec.UseBuildkit {
+					dockerBuildSpec := dockerBuildStep.Spec.(step.StepDockerBuildSpec)
+					dockerBuildSpec.Builder = step.DockerBuilderBuildkit
+					dockerBuildSpec.CacheRef, dockerBuildSpec.CacheMode = getLayerCacheRef(project.ImageBuildConfig, registry, build.ServiceName, build.ServiceModule)
+					dockerBuildStep.Spec = dockerBuildSpec
+				}
+				jobTaskSpec.Steps = append(jobTaskSpec.Steps, dockerBuildStep)
+				if arch != nil {
+					jobTaskSpec.Steps = append(jobTaskSpec.Steps, &commonmodels.StepTask{
+						Name:     build.ServiceName + "-manifest",
+						JobName:  jobTask.Name,
+						StepType: config.StepShell,
+						Spec: &step.StepShellSpec{
+							Scripts: append([]string{dockerLoginCmd}, getManifestScripts(image, arch.Arch, j.spec.Archs, repos)...),
+						},
+					})
+				}
+			}
+
+			// init archive step
+			if buildInfo.PostBuild != nil && buildInfo.PostBuild.FileArchive != nil && buildInfo.PostBuild.FileArchive.FileLocation != "" {
+				uploads := []*step.Upload{
+					{
+						FilePath:        path.Join(buildInfo.PostBuild.FileArchive.FileLocation, build.Package),
+						DestinationPath: path.Join(j.workflow.Name, fmt.Sprint(taskID), jobTask.Name, "archive"),
+					},
+				}
+				archiveStep := &commonmodels.StepTask{
+					Name:     build.ServiceName + "-archive",
+					JobName:  jobTask.Name,
+					StepType: config.StepArchive,
+					Spec: step.StepArchiveSpec{
+						UploadDetail: uploads,
+						S3:           modelS3toS3(defaultS3),
+					},
+				}
+				jobTaskSpec.Steps = append(jobTaskSpec.Steps, archiveStep)
+			}
+
+			// init object storage step
+			if buildInfo.PostBuild != nil && buildInfo.PostBuild.ObjectStorageUpload != nil && buildInfo.PostBuild.ObjectStorageUpload.Enabled {
+				modelS3, err := commonrepo.NewS3StorageColl().Find(buildInfo.PostBuild.ObjectStorageUpload.ObjectStorageID)
+				if err != nil {
+					return resp, fmt.Errorf("find object storage: %s failed, err: %v", buildInfo.PostBuild.ObjectStorageUpload.ObjectStorageID, err)
+				}
+				s3 := modelS3toS3(modelS3)
+				s3.Subfolder = ""
+				uploads := []*step.Upload{}
+				for _, detail := range buildInfo.PostBuild.ObjectStorageUpload.UploadDetail {
+					uploads = append(uploads, &step.Upload{
+						FilePath:        detail.FilePath,
+						DestinationPath: detail.DestinationPath,
+					})
+				}
+				archiveStep := &commonmodels.StepTask{
+					Name:     build.ServiceName + "-object-storage",
+					JobName:  jobTask.Name,
+					StepType: config.StepArchive,
+					Spec: step.StepArchiveSpec{
+						UploadDetail:    uploads,
+						ObjectStorageID: buildInfo.PostBuild.ObjectStorageUpload.ObjectStorageID,
+						S3:              s3,
+					},
+				}
+				jobTaskSpec.Steps = append(jobTaskSpec.Steps, archiveStep)
+			}
+
+			// init post build shell step
+			if buildInfo.PostBuild != nil && buildInfo.PostBuild.Scripts != "" {
+				scripts := append([]string{dockerLoginCmd}, strings.Split(replaceWrapLine(buildInfo.PostBuild.Scripts), "\n")...)
+				if windows {
+					scripts = strings.Split(replaceWrapLine(buildInfo.PostBuild.Scripts), "\n")
+				}
+				shellStep := &commonmodels.StepTask{
+					Name:     build.ServiceName + "-post-shell",
+					JobName:  jobTask.Name,
+					StepType: shellStepType,
+					Spec: &step.StepShellSpec{
+						Scripts: scripts,
+					},
+				}
+				jobTaskSpec.Steps = append(jobTaskSpec.Steps, shellStep)
+			}
+			resp = append(resp, jobTask)
 		}
-		resp = append(resp, jobTask)
 	}
 	j.job.Spec = j.spec
 	return resp, nil
@@ -530,6 +671,8 @@ func fillBuildDetail(moduleBuild *commonmodels.Build, serviceName, serviceModule
 	moduleBuild.CacheEnable = buildTemplate.CacheEnable
 	moduleBuild.CacheDirType = buildTemplate.CacheDirType
 	moduleBuild.CacheUserDir = buildTemplate.CacheUserDir
+	moduleBuild.CacheRules = buildTemplate.CacheRules
+	moduleBuild.Coverage = buildTemplate.Coverage
 	moduleBuild.AdvancedSettingsModified = buildTemplate.AdvancedSettingsModified
 	moduleBuild.Outputs = buildTemplate.Outputs
 
@@ -547,6 +690,247 @@ func fillBuildDetail(moduleBuild *commonmodels.Build, serviceName, serviceModule
 	return nil
 }
 
+func getServiceReadyStep(name, jobName string, containers []*commonmodels.ServiceContainer) *commonmodels.StepTask {
+	services := make([]*step.ServiceReadyCheck, 0)
+	for _, container := range containers {
+		services = append(services, &step.ServiceReadyCheck{
+			Name:    container.Name,
+			Port:    container.ReadinessPort,
+			Path:    container.ReadinessPath,
+			Timeout: container.ReadinessTimeout,
+		})
+	}
+	return &commonmodels.StepTask{
+		Name:     name + "-service-ready",
+		JobName:  jobName,
+		StepType: config.StepServiceReady,
+		Spec:     &step.StepServiceReadySpec{Services: services},
+	}
+}
+
+// checkWindowsBuild rejects the build settings depending on the linux tool chain of the job pod
+func checkWindowsBuild(buildInfo *commonmodels.Build) error {
+	if err := checkWindowsExecutorImage(); err != nil {
+		return err
+	}
+	if len(buildInfo.PreBuild.Installs) > 0 {
+		return fmt.Errorf("tool installation is not supported on windows")
+	}
+	if buildInfo.PreBuild.UseHostDockerDaemon {
+		return fmt.Errorf("host docker daemon is not supported on windows")
+	}
+	if len(buildInfo.PreBuild.ServiceContainers) > 0 {
+		return fmt.Errorf("service containers are not supported on windows")
+	}
+	if buildInfo.PostBuild != nil && buildInfo.PostBuild.DockerBuild != nil {
+		return fmt.Errorf("docker build is not supported on windows")
+	}
+	return nil
+}
+
+// checkWindowsExecutorImage makes sure the image carrying the windows executor is configured, otherwise the job pod
+// can never start on the windows nodes
+func checkWindowsExecutorImage() error {
+	if config.WindowsExecutorImage() == "" {
+		return fmt.Errorf("the windows executor image is not configured, please set %s of aslan", setting.ENVWindowsExecutorImage)
+	}
+	return nil
+}
+
+// checkBuildArchs makes sure each arch of a multi-arch build is supported and appears only once
+func checkBuildArchs(archs []*commonmodels.BuildArch) error {
+	seen := sets.NewString()
+	for _, arch := range archs {
+		if arch.Arch != setting.ArchAMD64 && arch.Arch != setting.ArchARM64 {
+			return fmt.Errorf("unsupported arch: %s", arch.Arch)
+		}
+		if seen.Has(arch.Arch) {
+			return fmt.Errorf("duplicated arch: %s", arch.Arch)
+		}
+		seen.Insert(arch.Arch)
+	}
+	return nil
+}
+
+// checkMultiArchBuild rejects the builds that can not assemble a manifest list
+func checkMultiArchBuild(buildInfo *commonmodels.Build, os string) error {
+	if buildInfo.PostBuild == nil || buildInfo.PostBuild.DockerBuild == nil {
+		return fmt.Errorf("multi-arch build requires docker build")
+	}
+	if os == setting.OSWindows {
+		return fmt.Errorf("multi-arch build is not supported on windows")
+	}
+	if buildInfo.PreBuild.Infrastructure == setting.JobVMInfrastructure {
+		return fmt.Errorf("multi-arch build is not supported on vm")
+	}
+	return nil
+}
+
+// getBuildArchs returns a nil arch for the single-arch builds so that they run one job with the default settings
+func getBuildArchs(archs []*commonmodels.BuildArch) []*commonmodels.BuildArch {
+	if len(archs) == 0 {
+		return []*commonmodels.BuildArch{nil}
+	}
+	return archs
+}
+
+func getArchImage(image, arch string) string {
+	return image + "-" + arch
+}
+
+// setBuildArchProperties schedules the job on the nodes of the arch, in the node pool of the arch if it is set
+func setBuildArchProperties(properties *commonmodels.JobProperties, arch *commonmodels.BuildArch) {
+	if arch.StrategyID != "" {
+		properties.StrategyID = arch.StrategyID
+	}
+	hints := &commonmodels.SchedulingHints{}
+	if properties.SchedulingHints != nil {
+		*hints = *properties.SchedulingHints
+	}
+	nodeSelector := map[string]string{corev1.LabelArchStable: arch.Arch}
+	for k, v := range hints.NodeSelector {
+		if k != corev1.LabelArchStable {
+			nodeSelector[k] = v
+		}
+	}
+	hints.NodeSelector = nodeSelector
+	properties.SchedulingHints = hints
+}
+
+// getManifestScripts assembles the manifest list once the images of all the archs are pushed, every arch job runs it
+// and only the one finishing last finds all the images, all of them output the manifest list as the image.
+// Each job tags its image with the revision of the checked out repos, the images of the other archs count only
+// if they are tagged with the same revision so that a stale image pushed by a previous build is never assembled.
+func getManifestScripts(image, currentArch string, archs []*commonmodels.BuildArch, repos []*types.Repository) []string {
+	revisionCmds := make([]string, 0, len(repos))
+	for _, repo := range repos {
+		workDir := repo.RepoName
+		if repo.CheckoutPath != "" {
+			workDir = repo.CheckoutPath
+		}
+		revisionCmds = append(revisionCmds, fmt.Sprintf(`git -C "%s" rev-parse HEAD;`, workDir))
+	}
+	archImages := make([]string, 0, len(archs))
+	for _, arch := range archs {
+		archImages = append(archImages, fmt.Sprintf(`"%s"`, getArchImage(image, arch.Arch)))
+	}
+	currentImage := getArchImage(image, currentArch)
+	return []string{
+		"set +e",
+		fmt.Sprintf(`REVISION=$( (echo "%s"; %s) 2>/dev/null | sha1sum | cut -c1-12)`, image, strings.Join(revisionCmds, " ")),
+		fmt.Sprintf(`docker manifest create --amend "%[1]s-$REVISION" "%[1]s" && docker manifest push --purge "%[1]s-$REVISION" || exit 1`, currentImage),
+		"MISSING_IMAGES=0",
+		fmt.Sprintf("for ARCH_IMAGE in %s; do", strings.Join(archImages, " ")),
+		`  if ! docker manifest inspect "$ARCH_IMAGE-$REVISION" > /dev/null 2>&1; then`,
+		`    echo "$ARCH_IMAGE of revision $REVISION is not pushed yet, the manifest list is assembled by the job of the last arch"`,
+		"    MISSING_IMAGES=1",
+		"  fi",
+		"done",
+		`if [ "$MISSING_IMAGES" = "0" ]; then`,
+		fmt.Sprintf(`  docker manifest create --amend "%s" %s || exit 1`, image, strings.Join(archImages, " ")),
+		fmt.Sprintf(`  docker manifest push --purge "%s" || exit 1`, image),
+		fmt.Sprintf(`  echo "manifest list %s is pushed"`, image),
+		"fi",
+		fmt.Sprintf("echo %s > %s", image, path.Join(job.JobOutputDir, IMAGEKEY)),
+	}
+}
+
+// getPublishStep uploads the publish paths of the job to the default object storage, it runs even if the job fails
+// so that the files can be inspected, the paths which do not exist are skipped
+func getPublishStep(paths []string, jobName, s3DestDir string) (*commonmodels.StepTask, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	defaultS3, err := commonrepo.NewS3StorageColl().FindDefault()
+	if err != nil {
+		return nil, fmt.Errorf("failed to find default s3 storage: %v", err)
+	}
+	uploads := make([]*step.Upload, 0, len(paths))
+	for _, p := range paths {
+		uploads = append(uploads, &step.Upload{
+			FilePath:        p,
+			DestinationPath: s3DestDir,
+			PreservePath:    true,
+			IgnoreNotExist:  true,
+		})
+	}
+	return &commonmodels.StepTask{
+		Name:      jobName + "-publish",
+		JobName:   jobName,
+		StepType:  config.StepArchive,
+		Onfailure: true,
+		Spec: step.StepArchiveSpec{
+			UploadDetail: uploads,
+			S3:           modelS3toS3(defaultS3),
+		},
+	}, nil
+}
+
+// getCoverageStep returns the coverage step with the quality gates, nil if the coverage is not enabled.
+// The coverage of the base branch for the regression gate is filled in when the step runs.
+func getCoverageStep(cfg *commonmodels.CoverageConfig, name, serviceName, serviceModule string, repos []*types.Repository, jobName, s3DestDir string) *commonmodels.StepTask {
+	if cfg == nil || !cfg.Enabled || cfg.ReportPath == "" {
+		return nil
+	}
+	spec := &step.StepCoverageSpec{
+		ReportPath:    cfg.ReportPath,
+		Format:        cfg.Format,
+		MinCoverage:   cfg.MinCoverage,
+		MaxRegression: cfg.MaxRegression,
+		BaseBranch:    cfg.BaseBranch,
+		Name:          name,
+		ServiceName:   serviceName,
+		ServiceModule: serviceModule,
+		S3DestDir:     s3DestDir,
+	}
+	for _, repo := range repos {
+		if repo.Branch != "" {
+			spec.Branch = repo.Branch
+			// the branch of a pull request build is the target branch, the coverage is not the one of the branch
+			spec.IsPR = repo.PR != 0 || len(repo.PRs) > 0
+			break
+		}
+	}
+	return &commonmodels.StepTask{
+		Name:     name + "-coverage",
+		JobName:  jobName,
+		StepType: config.StepCoverage,
+		Spec:     spec,
+	}
+}
+
+// getLayerCacheRef returns the registry ref and mode of the buildkit layer cache of a service module,
+// empty ref means the layer cache is disabled in the project.
+func getLayerCacheRef(cfg *template.ProjectImageBuildConfig, registry *commonmodels.RegistryNamespace, serviceName, serviceModule string) (string, string) {
+	if cfg == nil || !cfg.LayerCacheEnabled || cfg.CacheRepo == "" {
+		return "", ""
+	}
+	ref := fmt.Sprintf("%s/%s", registry.RegAddr, cfg.CacheRepo)
+	if len(registry.Namespace) > 0 {
+		ref = fmt.Sprintf("%s/%s/%s", registry.RegAddr, registry.Namespace, cfg.CacheRepo)
+	}
+	ref = strings.TrimPrefix(ref, "http://")
+	ref = strings.TrimPrefix(ref, "https://")
+	return fmt.Sprintf("%s:%s-%s", ref, serviceName, serviceModule), cfg.CacheMode
+}
+
+// getCacheStepSpec returns the spec of the declarative cache steps, nil if no cache rule is configured
+func getCacheStepSpec(properties commonmodels.JobProperties, project, buildName string) *step.StepCacheSpec {
+	if !properties.CacheEnable || len(properties.CacheRules) == 0 {
+		return nil
+	}
+	spec := &step.StepCacheSpec{
+		Rules:      properties.CacheRules,
+		MediumType: properties.Cache.MediumType,
+		CacheDir:   path.Join(types.BuildCacheMountDir, project, buildName),
+		S3DestDir:  path.Join("cache", project, buildName),
+	}
+	if properties.Cache.EvictionPolicy != nil {
+		spec.MaxAgeDays = properties.Cache.EvictionPolicy.MaxAgeDays
+	}
+	return spec
+}
+
 func renderEnv(data string, kvs []*commonmodels.KeyVal) string {
 	mapper := func(data string) string {
 		for _, envar := range kvs {
@@ -588,11 +972,21 @@ func mergeRepos(templateRepos []*types.Repository, customRepos []*types.Reposito
 }
 
 func (j *BuildJob) LintJob() error {
-	return nil
+	j.spec = &commonmodels.ZadigBuildJobSpec{}
+	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	if err := lintBuildImages(j.job.Name, j.workflow.Project, j.spec.ServiceAndBuilds); err != nil {
+		return err
+	}
+	if err := lintBuildVersioning(j.spec.Versioning); err != nil {
+		return err
+	}
+	return checkBuildArchs(j.spec.Archs)
 }
 
-func (j *BuildJob) GetOutPuts(log *zap.SugaredLogger) []string {
-	resp := []string{}
+func (j *BuildJob) GetOutputs(log *zap.SugaredLogger) []*WorkflowVariable {
+	resp := []*WorkflowVariable{}
 	j.spec = &commonmodels.ZadigBuildJobSpec{}
 	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
 		return resp
@@ -604,16 +998,20 @@ func (j *BuildJob) GetOutPuts(log *zap.SugaredLogger) []string {
 			log.Errorf("found build %s failed, err: %s", build.BuildName, err)
 			continue
 		}
-		if buildInfo.TemplateID == "" {
-			resp = append(resp, getOutputKey(jobKey, ensureBuildInOutputs(buildInfo.Outputs))...)
-			continue
+		outputs := buildInfo.Outputs
+		if buildInfo.TemplateID != "" {
+			buildTemplate, err := commonrepo.NewBuildTemplateColl().Find(&commonrepo.BuildTemplateQueryOption{ID: buildInfo.TemplateID})
+			if err != nil {
+				log.Errorf("found build template %s failed, err: %s", buildInfo.TemplateID, err)
+				continue
+			}
+			outputs = buildTemplate.Outputs
 		}
-		buildTemplate, err := commonrepo.NewBuildTemplateColl().Find(&commonrepo.BuildTemplateQueryOption{ID: buildInfo.TemplateID})
-		if err != nil {
-			log.Errorf("found build template %s failed, err: %s", buildInfo.TemplateID, err)
-			continue
+		outputs = ensureBuildInOutputs(outputs)
+		if j.spec.Versioning != nil {
+			outputs = ensureVersionInOutputs(outputs)
 		}
-		resp = append(resp, getOutputKey(jobKey, ensureBuildInOutputs(buildTemplate.Outputs))...)
+		resp = append(resp, newJobOutputs(j.job, jobKey, outputs)...)
 	}
 	return resp
 }
@@ -626,16 +1024,19 @@ func ensureBuildInOutputs(outputs []*commonmodels.Output) []*commonmodels.Output
 	if _, ok := keyMap[IMAGEKEY]; !ok {
 		outputs = append(outputs, &commonmodels.Output{
 			Name: IMAGEKEY,
+			Type: commonmodels.OutputTypeImage,
 		})
 	}
 	if _, ok := keyMap[IMAGETAGKEY]; !ok {
 		outputs = append(outputs, &commonmodels.Output{
 			Name: IMAGETAGKEY,
+			Type: commonmodels.OutputTypeString,
 		})
 	}
 	if _, ok := keyMap[PKGFILEKEY]; !ok {
 		outputs = append(outputs, &commonmodels.Output{
 			Name: PKGFILEKEY,
+			Type: commonmodels.OutputTypeString,
 		})
 	}
 	return outputs
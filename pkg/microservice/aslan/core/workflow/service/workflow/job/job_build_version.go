@@ -0,0 +1,241 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package job
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/blang/semver/v4"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/code/client"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/code/client/open"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+const (
+	defaultInitialVersion = "0.1.0"
+	// the page size of listing the tags from the code host to find the latest version
+	versionTagsPerPage = 100
+)
+
+var (
+	// e.g. feat(api)!: xxx, fix!: xxx
+	conventionalBreakingRegex = regexp.MustCompile(`^\w+(\([^)]*\))?!:`)
+	conventionalFeatRegex     = regexp.MustCompile(`^feat(\([^)]*\))?:`)
+)
+
+func lintBuildVersioning(versioning *commonmodels.BuildVersioning) error {
+	if versioning == nil {
+		return nil
+	}
+	switch versioning.Strategy {
+	case commonmodels.BuildVersioningGitTag, commonmodels.BuildVersioningCounter:
+	default:
+		return fmt.Errorf("unsupported versioning strategy: %s", versioning.Strategy)
+	}
+	if versioning.InitialVersion != "" {
+		if _, err := semver.Parse(versioning.InitialVersion); err != nil {
+			return fmt.Errorf("invalid initial version %s: %v", versioning.InitialVersion, err)
+		}
+	}
+	return nil
+}
+
+// calculateBuildVersion calculates the next version of the build, the version is prefixed with the tag prefix.
+func calculateBuildVersion(versioning *commonmodels.BuildVersioning, build *commonmodels.ServiceAndBuild, workflowName, jobName string, log *zap.SugaredLogger) (string, error) {
+	initial := defaultInitialVersion
+	if versioning.InitialVersion != "" {
+		initial = versioning.InitialVersion
+	}
+	initialVersion, err := semver.Parse(initial)
+	if err != nil {
+		return "", fmt.Errorf("invalid initial version %s: %v", initial, err)
+	}
+
+	var next semver.Version
+	switch versioning.Strategy {
+	case commonmodels.BuildVersioningCounter:
+		seq, err := commonrepo.NewCounterColl().GetNextSeq(fmt.Sprintf(setting.BuildVersionFmt, workflowName, jobName, build.ServiceName, build.ServiceModule))
+		if err != nil {
+			return "", fmt.Errorf("failed to get the build version counter: %v", err)
+		}
+		next = initialVersion
+		next.Patch += uint64(seq - 1)
+	case commonmodels.BuildVersioningGitTag:
+		repo := getPrimaryRepo(build.Repos)
+		if repo == nil {
+			return "", fmt.Errorf("no repo is configured for build %s", build.BuildName)
+		}
+		ch, err := systemconfig.New().GetCodeHost(repo.CodehostID)
+		if err != nil {
+			return "", fmt.Errorf("failed to get codehost %d: %v", repo.CodehostID, err)
+		}
+		cli, err := open.OpenClient(ch, log)
+		if err != nil {
+			return "", fmt.Errorf("failed to open codehost %d: %v", repo.CodehostID, err)
+		}
+		latest, latestTag, err := getLatestVersionTag(cli, repo, versioning.TagPrefix)
+		if err != nil {
+			return "", err
+		}
+		if latest == nil {
+			next = initialVersion
+		} else {
+			next = bumpVersion(*latest, getCommitMessagesSince(cli, repo, latestTag, log))
+		}
+		// the tag is not pushed, so the builds before the next release tag share the bumped version and are told
+		// apart by the build number, e.g. 1.3.0-build.2
+		seq, err := commonrepo.NewCounterColl().GetNextSeq(fmt.Sprintf(setting.BuildVersionOfTagFmt, workflowName, jobName, build.ServiceName, build.ServiceModule, next.String()))
+		if err != nil {
+			return "", fmt.Errorf("failed to get the build number of version %s: %v", next.String(), err)
+		}
+		next.Pre = []semver.PRVersion{{VersionStr: "build"}, {VersionNum: uint64(seq), IsNum: true}}
+	default:
+		return "", fmt.Errorf("unsupported versioning strategy: %s", versioning.Strategy)
+	}
+	return versioning.TagPrefix + next.String(), nil
+}
+
+func getPrimaryRepo(repos []*types.Repository) *types.Repository {
+	for _, repo := range repos {
+		if repo.IsPrimary {
+			return repo
+		}
+	}
+	if len(repos) > 0 {
+		return repos[0]
+	}
+	return nil
+}
+
+// getLatestVersionTag returns the greatest released version tagged in the repo and the tag, nil if there is none.
+func getLatestVersionTag(cli client.CodeHostClient, repo *types.Repository, prefix string) (*semver.Version, string, error) {
+	var latest *semver.Version
+	latestTag := ""
+	firstTag := ""
+	for page := 1; ; page++ {
+		tags, err := cli.ListTags(client.ListOpt{
+			Namespace:   repo.GetRepoNamespace(),
+			ProjectName: repo.RepoName,
+			Page:        page,
+			PerPage:     versionTagsPerPage,
+		})
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to list tags of repo %s/%s: %v", repo.GetRepoNamespace(), repo.RepoName, err)
+		}
+		// some code hosts ignore the paging and return all the tags on every page
+		if len(tags) == 0 || (page > 1 && tags[0].Name == firstTag) {
+			break
+		}
+		if page == 1 {
+			firstTag = tags[0].Name
+		}
+
+		for _, tag := range tags {
+			if !strings.HasPrefix(tag.Name, prefix) {
+				continue
+			}
+			version, err := semver.Parse(strings.TrimPrefix(tag.Name, prefix))
+			// pre-releases are not taken as the base of the next version
+			if err != nil || len(version.Pre) > 0 {
+				continue
+			}
+			if latest == nil || version.GT(*latest) {
+				latest = &version
+				latestTag = tag.Name
+			}
+		}
+		if len(tags) != versionTagsPerPage {
+			break
+		}
+	}
+	return latest, latestTag, nil
+}
+
+// getCommitMessagesSince returns the messages of the commits built since the tag, only the message of the built
+// commit is returned if the code host can't compare the refs.
+func getCommitMessagesSince(cli client.CodeHostClient, repo *types.Repository, tag string, log *zap.SugaredLogger) []string {
+	head := repo.CommitID
+	if head == "" {
+		head = repo.Branch
+	}
+	comparer, ok := cli.(client.CommitComparer)
+	if !ok || head == "" {
+		return []string{repo.CommitMessage}
+	}
+	commits, err := comparer.CompareCommits(client.CompareOpt{
+		Namespace:   repo.GetRepoNamespace(),
+		ProjectName: repo.RepoName,
+		Base:        tag,
+		Head:        head,
+	})
+	if err != nil {
+		log.Warnf("failed to compare %s with %s of repo %s/%s, only the built commit is checked: %v", head, tag, repo.GetRepoNamespace(), repo.RepoName, err)
+		return []string{repo.CommitMessage}
+	}
+	resp := make([]string, 0, len(commits))
+	for _, commit := range commits {
+		resp = append(resp, commit.Message)
+	}
+	return resp
+}
+
+// bumpVersion bumps the version according to the conventional commit messages, the greatest bump of them wins.
+func bumpVersion(version semver.Version, commitMessages []string) semver.Version {
+	breaking, feat := false, false
+	for _, commitMessage := range commitMessages {
+		message := strings.TrimSpace(commitMessage)
+		switch {
+		case conventionalBreakingRegex.MatchString(message) || strings.Contains(message, "BREAKING CHANGE"):
+			breaking = true
+		case conventionalFeatRegex.MatchString(message):
+			feat = true
+		}
+	}
+	switch {
+	case breaking:
+		version.Major++
+		version.Minor = 0
+		version.Patch = 0
+	case feat:
+		version.Minor++
+		version.Patch = 0
+	default:
+		version.Patch++
+	}
+	version.Build = nil
+	return version
+}
+
+func ensureVersionInOutputs(outputs []*commonmodels.Output) []*commonmodels.Output {
+	for _, output := range outputs {
+		if output.Name == BUILDVERSIONKEY {
+			return outputs
+		}
+	}
+	return append(outputs, &commonmodels.Output{
+		Name: BUILDVERSIONKEY,
+		Type: commonmodels.OutputTypeString,
+	})
+}
@@ -0,0 +1,47 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package job
+
+import (
+	"github.com/blang/semver/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = DescribeTable("bumpVersion",
+	func(version, message, expected string) {
+		Expect(bumpVersion(semver.MustParse(version), []string{message}).String()).To(Equal(expected))
+	},
+	Entry("fix bumps the patch", "1.2.3", "fix: a bug", "1.2.4"),
+	Entry("unconventional message bumps the patch", "1.2.3", "update readme", "1.2.4"),
+	Entry("feat bumps the minor", "1.2.3", "feat: a feature", "1.3.0"),
+	Entry("scoped feat bumps the minor", "1.2.3", "feat(build): a feature", "1.3.0"),
+	Entry("feat in the body bumps the patch", "1.2.3", "fix: a bug\n\nfeat: a feature", "1.2.4"),
+	Entry("bang bumps the major", "1.2.3", "refactor(api)!: drop v1", "2.0.0"),
+	Entry("breaking change footer bumps the major", "1.2.3", "fix: a bug\n\nBREAKING CHANGE: the api changes", "2.0.0"),
+	Entry("leading blank lines are ignored", "1.2.3", "\n  feat: a feature", "1.3.0"),
+	Entry("build metadata is dropped", "1.2.3+abc", "fix: a bug", "1.2.4"),
+)
+
+var _ = DescribeTable("bumpVersion of the commits since the tag",
+	func(version string, messages []string, expected string) {
+		Expect(bumpVersion(semver.MustParse(version), messages).String()).To(Equal(expected))
+	},
+	Entry("no commits bumps the patch", "1.2.3", []string{}, "1.2.4"),
+	Entry("feat before the built commit bumps the minor", "1.2.3", []string{"feat: a feature", "fix: a bug"}, "1.3.0"),
+	Entry("breaking change wins over feat", "1.2.3", []string{"feat: a feature", "fix!: drop v1", "docs: readme"}, "2.0.0"),
+)
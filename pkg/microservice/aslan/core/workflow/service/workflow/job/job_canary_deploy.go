@@ -141,6 +141,7 @@ func (j *CanaryDeployJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error)
 				CanaryPercentage: target.CanaryPercentage,
 				CanaryReplica:    int(canaryReplica),
 				Image:            target.Image,
+				Analysis:         j.spec.Analysis,
 			},
 		}
 		resp = append(resp, task)
@@ -155,6 +156,9 @@ func (j *CanaryDeployJob) LintJob() error {
 	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
 		return err
 	}
+	if err := lintCanaryAnalysis(j.spec.Analysis); err != nil {
+		return err
+	}
 	quoteJobs := []*commonmodels.Job{}
 	for _, stage := range j.workflow.Stages {
 		for _, job := range stage.Jobs {
@@ -170,6 +174,13 @@ func (j *CanaryDeployJob) LintJob() error {
 			}
 		}
 	}
+	// the canary is promoted or rolled back by the analysis, a release job quoting it would race with the analysis
+	if j.spec.Analysis != nil {
+		if len(quoteJobs) > 0 {
+			return fmt.Errorf("canary deploy job %s with analysis is released automatically, it can not be quoted by canary release job %s", j.job.Name, quoteJobs[0].Name)
+		}
+		return nil
+	}
 	if len(quoteJobs) == 0 {
 		return fmt.Errorf("no canary release job quote canary deploy job %s", j.job.Name)
 	}
@@ -182,3 +193,27 @@ func (j *CanaryDeployJob) LintJob() error {
 	}
 	return nil
 }
+
+func lintCanaryAnalysis(analysis *commonmodels.CanaryAnalysis) error {
+	if analysis == nil {
+		return nil
+	}
+	if analysis.ObservabilityID == "" {
+		return fmt.Errorf("observability of the canary analysis is required")
+	}
+	if analysis.IntervalSeconds < 0 || analysis.Iterations < 0 || analysis.MaxFailures < 0 {
+		return fmt.Errorf("interval, iterations and max failures of the canary analysis can not be negative")
+	}
+	if len(analysis.Criteria) == 0 {
+		return fmt.Errorf("at least one criterion is required for the canary analysis")
+	}
+	for _, criterion := range analysis.Criteria {
+		if criterion.Name == "" || criterion.Query == "" {
+			return fmt.Errorf("name and query of the canary analysis criterion are required")
+		}
+		if criterion.MaxValue == nil && criterion.MaxBaselineIncrease == nil {
+			return fmt.Errorf("criterion %s should set max value or max baseline increase", criterion.Name)
+		}
+	}
+	return nil
+}
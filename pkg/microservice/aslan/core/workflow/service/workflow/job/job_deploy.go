@@ -23,11 +23,13 @@ import (
 	"github.com/pkg/errors"
 	"go.uber.org/zap"
 	"golang.org/x/exp/slices"
+	"k8s.io/apimachinery/pkg/util/intstr"
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/kube"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/repository"
 	commontypes "github.com/koderover/zadig/pkg/microservice/aslan/core/common/types"
@@ -189,6 +191,13 @@ func (j *DeployJob) MergeArgs(args *commonmodels.Job) error {
 		if j.spec.Source == config.SourceRuntime {
 			j.spec.ServiceAndImages = argsSpec.ServiceAndImages
 		}
+		if argsSpec.UpdateStrategy != nil {
+			j.spec.UpdateStrategy = argsSpec.UpdateStrategy
+		}
+		// protected resources can be confirmed when running the workflow
+		if argsSpec.ConfirmProtected {
+			j.spec.ConfirmProtected = true
+		}
 
 		j.job.Spec = j.spec
 	}
@@ -256,6 +265,16 @@ func (j *DeployJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 		j.spec.ServiceAndImages = targets
 	}
 
+	if product.Production {
+		images := make([]string, 0, len(j.spec.ServiceAndImages))
+		for _, deploy := range j.spec.ServiceAndImages {
+			images = append(images, deploy.Image)
+		}
+		if err := commonservice.CheckPromotionPolicy(j.workflow.Project, envName, images); err != nil {
+			return resp, err
+		}
+	}
+
 	serviceMap := map[string]*commonmodels.DeployService{}
 	for _, service := range j.spec.Services {
 		serviceMap[service.ServiceName] = service
@@ -283,6 +302,11 @@ func (j *DeployJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 				Production:         j.spec.Production,
 				DeployContents:     j.spec.DeployContents,
 				Timeout:            timeout,
+				UpdateStrategy:     j.spec.UpdateStrategy,
+				SkipUnchanged:      j.spec.SkipUnchanged,
+				ConfirmProtected:   j.spec.ConfirmProtected,
+				RestartCheck:       j.spec.RestartCheck,
+				HealthCheck:        j.spec.HealthCheck,
 			}
 
 			for _, deploy := range deploys {
@@ -346,6 +370,20 @@ func (j *DeployJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 						filterdKV = append(filterdKV, jobKV)
 					}
 					jobTaskSpec.VariableKVs = filterdKV
+
+					if slices.Contains(j.spec.DeployContents, config.DeployVars) {
+						findOpt := &commonrepo.ServiceFindOption{ProductName: product.ProductName, ServiceName: serviceName}
+						if productSvc, ok := productServiceMap[serviceName]; ok && !service.UpdateConfig {
+							findOpt.Revision = productSvc.Revision
+						}
+						svcTemplate, err := repository.QueryTemplateService(findOpt, product.Production)
+						if err != nil {
+							return nil, fmt.Errorf("failed to find service template %s, err: %w", serviceName, err)
+						}
+						if err := commontypes.ValidateVariableKVsBySchema(svcTemplate.ServiceVariableKVs, jobTaskSpec.VariableKVs); err != nil {
+							return nil, fmt.Errorf("invalid variables of service %s, err: %w", serviceName, err)
+						}
+					}
 				}
 				// if only deploy images, clear keyvals
 				if onlyDeployImage(j.spec.DeployContents) {
@@ -454,6 +492,8 @@ func (j *DeployJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 				ReleaseName:        releaseName,
 				Timeout:            timeout,
 				IsProduction:       j.spec.Production,
+				SkipUnchanged:      j.spec.SkipUnchanged,
+				ConfirmProtected:   j.spec.ConfirmProtected,
 			}
 
 			for _, deploy := range deploys {
@@ -499,11 +539,60 @@ func checkServiceExsistsInEnv(serviceMap map[string]*commonmodels.ProductService
 	return nil
 }
 
+func lintDeployUpdateStrategy(strategy *commonmodels.DeployUpdateStrategy) error {
+	if strategy == nil {
+		return nil
+	}
+	switch strategy.Type {
+	case "", config.DeployStrategyRolling:
+	case config.DeployStrategyRecreate:
+		if strategy.MaxSurge != "" || strategy.MaxUnavailable != "" {
+			return fmt.Errorf("max surge and max unavailable can not be set for the recreate strategy")
+		}
+	default:
+		return fmt.Errorf("invalid update strategy type: %s", strategy.Type)
+	}
+	for _, val := range []string{strategy.MaxSurge, strategy.MaxUnavailable} {
+		if val == "" {
+			continue
+		}
+		intOrPercent := intstr.Parse(val)
+		if scaled, err := intstr.GetScaledValueFromIntOrPercent(&intOrPercent, 100, true); err != nil || scaled < 0 {
+			return fmt.Errorf("invalid max surge or max unavailable: %s, it should be a number or a percentage", val)
+		}
+	}
+	return nil
+}
+
+func lintDeployRestartCheck(check *commonmodels.DeployRestartCheck) error {
+	if check == nil {
+		return nil
+	}
+	if check.ObserveSeconds < 0 || check.MaxRestarts < 0 {
+		return fmt.Errorf("observe seconds and max restarts of the restart check can not be negative")
+	}
+	return nil
+}
+
 func (j *DeployJob) LintJob() error {
 	j.spec = &commonmodels.ZadigDeployJobSpec{}
 	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
 		return err
 	}
+	if j.spec.Production {
+		if err := j.lintPromotionPolicy(); err != nil {
+			return err
+		}
+	}
+	if err := lintDeployTarget(j.job.Name, j.workflow.Project, j.spec.Env); err != nil {
+		return err
+	}
+	if err := lintDeployUpdateStrategy(j.spec.UpdateStrategy); err != nil {
+		return err
+	}
+	if err := lintDeployRestartCheck(j.spec.RestartCheck); err != nil {
+		return err
+	}
 	if j.spec.Source != config.SourceFromJob {
 		return nil
 	}
@@ -515,10 +604,47 @@ func (j *DeployJob) LintJob() error {
 	return nil
 }
 
-func (j *DeployJob) GetOutPuts(log *zap.SugaredLogger) []string {
-	return getOutputKey(j.job.Name, ensureDeployInOutputs())
+// lintPromotionPolicy checks the preset images of the production deploy job, whether the images have been
+// deployed to a non-production environment is checked when the task is created.
+func (j *DeployJob) lintPromotionPolicy() error {
+	policy, err := commonservice.GetPromotionPolicy(j.workflow.Project)
+	if err != nil || policy == nil {
+		return err
+	}
+	envName := strings.ReplaceAll(j.spec.Env, setting.FixedValueMark, "")
+	if j.spec.Source == config.SourceFromJob {
+		if policy.RequirePromotion {
+			return commonservice.NewPromotionPolicyError(envName, []string{
+				fmt.Sprintf("images built by job %s in the same task have not been deployed to any non-production environment", j.spec.JobName),
+			})
+		}
+		return nil
+	}
+
+	images := make([]string, 0, len(j.spec.ServiceAndImages))
+	for _, deploy := range j.spec.ServiceAndImages {
+		images = append(images, deploy.Image)
+	}
+	violations, err := commonservice.CheckImageMutableTags(policy, images)
+	if err != nil {
+		return err
+	}
+	return commonservice.NewPromotionPolicyError(envName, violations)
+}
+
+func (j *DeployJob) GetOutputs(log *zap.SugaredLogger) []*WorkflowVariable {
+	resp := newJobOutputs(j.job, j.job.Name, ensureDeployInOutputs())
+	j.spec = &commonmodels.ZadigDeployJobSpec{}
+	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
+		return resp
+	}
+	for _, svc := range j.spec.ServiceAndImages {
+		jobKey := strings.Join([]string{j.job.Name, svc.ServiceName, svc.ServiceModule}, ".")
+		resp = append(resp, newJobOutputs(j.job, jobKey, []*commonmodels.Output{{Name: IMAGEKEY, Type: commonmodels.OutputTypeImage}})...)
+	}
+	return resp
 }
 
 func ensureDeployInOutputs() []*commonmodels.Output {
-	return []*commonmodels.Output{{Name: ENVNAMEKEY}}
+	return []*commonmodels.Output{{Name: ENVNAMEKEY, Type: commonmodels.OutputTypeString}}
 }
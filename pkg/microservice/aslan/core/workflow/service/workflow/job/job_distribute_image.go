@@ -308,15 +308,15 @@ func getTimeout(timeout int64) int64 {
 	return timeout
 }
 
-func (j *ImageDistributeJob) GetOutPuts(log *zap.SugaredLogger) []string {
-	resp := []string{}
+func (j *ImageDistributeJob) GetOutputs(log *zap.SugaredLogger) []*WorkflowVariable {
+	resp := []*WorkflowVariable{}
 	j.spec = &commonmodels.ZadigDistributeImageJobSpec{}
 	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
 		return resp
 	}
 	for _, target := range j.spec.Targets {
 		targetKey := strings.Join([]string{j.job.Name, target.ServiceName, target.ServiceModule}, ".")
-		resp = append(resp, getOutputKey(targetKey, []*commonmodels.Output{{Name: "IMAGE"}})...)
+		resp = append(resp, newJobOutputs(j.job, targetKey, []*commonmodels.Output{{Name: IMAGEKEY, Type: commonmodels.OutputTypeImage}})...)
 	}
 	return resp
 }
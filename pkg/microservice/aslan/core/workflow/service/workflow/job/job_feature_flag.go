@@ -0,0 +1,201 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package job
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/tool/featureflag"
+)
+
+type FeatureFlagJob struct {
+	job      *commonmodels.Job
+	workflow *commonmodels.WorkflowV4
+	spec     *commonmodels.FeatureFlagJobSpec
+}
+
+func (j *FeatureFlagJob) Instantiate() error {
+	j.spec = &commonmodels.FeatureFlagJobSpec{}
+	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	j.job.Spec = j.spec
+	return nil
+}
+
+func (j *FeatureFlagJob) SetPreset() error {
+	j.spec = &commonmodels.FeatureFlagJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	j.job.Spec = j.spec
+	return nil
+}
+
+func (j *FeatureFlagJob) MergeArgs(args *commonmodels.Job) error {
+	j.spec = &commonmodels.FeatureFlagJobSpec{}
+	if err := commonmodels.IToi(args.Spec, j.spec); err != nil {
+		return err
+	}
+	j.job.Spec = j.spec
+	return nil
+}
+
+func (j *FeatureFlagJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
+	j.spec = &commonmodels.FeatureFlagJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return nil, err
+	}
+	j.job.Spec = j.spec
+
+	taskSpec := &commonmodels.JobTaskFeatureFlagSpec{
+		ExternalSystemID: j.spec.ExternalSystemID,
+		Provider:         j.spec.Provider,
+	}
+	for _, change := range j.spec.Flags {
+		taskSpec.Flags = append(taskSpec.Flags, &commonmodels.FeatureFlagTaskChange{FeatureFlagChange: *change})
+	}
+	jobTask := &commonmodels.JobTask{
+		Name: j.job.Name,
+		Key:  j.job.Name,
+		JobInfo: map[string]string{
+			JobNameKey: j.job.Name,
+		},
+		JobType: string(config.JobFeatureFlag),
+		Spec:    taskSpec,
+	}
+	return []*commonmodels.JobTask{jobTask}, nil
+}
+
+func (j *FeatureFlagJob) LintJob() error {
+	j.spec = &commonmodels.FeatureFlagJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	if err := lintFeatureFlagProvider(j.job.Name, j.spec.ExternalSystemID, j.spec.Provider); err != nil {
+		return err
+	}
+	if len(j.spec.Flags) == 0 {
+		return errors.Errorf("flags of job %s can not be empty", j.job.Name)
+	}
+	for _, change := range j.spec.Flags {
+		if change.Flag == "" || change.Environment == "" {
+			return errors.Errorf("flag and environment of job %s can not be empty", j.job.Name)
+		}
+		if change.Rollout == nil {
+			continue
+		}
+		if *change.Rollout < 0 || *change.Rollout > 100 {
+			return errors.Errorf("rollout of flag %s in job %s must be between 0 and 100", change.Flag, j.job.Name)
+		}
+		if j.spec.Provider == featureflag.ProviderFlagsmith {
+			return errors.Errorf("rollout of flag %s in job %s is not supported by flagsmith", change.Flag, j.job.Name)
+		}
+	}
+	return nil
+}
+
+type FeatureFlagRollbackJob struct {
+	job      *commonmodels.Job
+	workflow *commonmodels.WorkflowV4
+	spec     *commonmodels.FeatureFlagRollbackJobSpec
+}
+
+func (j *FeatureFlagRollbackJob) Instantiate() error {
+	j.spec = &commonmodels.FeatureFlagRollbackJobSpec{}
+	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	j.job.Spec = j.spec
+	return nil
+}
+
+func (j *FeatureFlagRollbackJob) SetPreset() error {
+	j.spec = &commonmodels.FeatureFlagRollbackJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	j.job.Spec = j.spec
+	return nil
+}
+
+func (j *FeatureFlagRollbackJob) MergeArgs(args *commonmodels.Job) error {
+	j.spec = &commonmodels.FeatureFlagRollbackJobSpec{}
+	if err := commonmodels.IToi(args.Spec, j.spec); err != nil {
+		return err
+	}
+	j.job.Spec = j.spec
+	return nil
+}
+
+func (j *FeatureFlagRollbackJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
+	j.spec = &commonmodels.FeatureFlagRollbackJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return nil, err
+	}
+	j.job.Spec = j.spec
+
+	taskSpec := &commonmodels.JobTaskFeatureFlagRollbackSpec{
+		ExternalSystemID: j.spec.ExternalSystemID,
+		Provider:         j.spec.Provider,
+	}
+	for _, target := range j.spec.Flags {
+		taskSpec.Flags = append(taskSpec.Flags, &commonmodels.FeatureFlagTaskRollback{FeatureFlagTarget: *target})
+	}
+	jobTask := &commonmodels.JobTask{
+		Name: j.job.Name,
+		Key:  j.job.Name,
+		JobInfo: map[string]string{
+			JobNameKey: j.job.Name,
+		},
+		JobType: string(config.JobFeatureFlagRollback),
+		Spec:    taskSpec,
+	}
+	return []*commonmodels.JobTask{jobTask}, nil
+}
+
+func (j *FeatureFlagRollbackJob) LintJob() error {
+	j.spec = &commonmodels.FeatureFlagRollbackJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	if err := lintFeatureFlagProvider(j.job.Name, j.spec.ExternalSystemID, j.spec.Provider); err != nil {
+		return err
+	}
+	if len(j.spec.Flags) == 0 {
+		return errors.Errorf("flags of job %s can not be empty", j.job.Name)
+	}
+	for _, target := range j.spec.Flags {
+		if target.Flag == "" || target.Environment == "" {
+			return errors.Errorf("flag and environment of job %s can not be empty", j.job.Name)
+		}
+	}
+	return nil
+}
+
+func lintFeatureFlagProvider(jobName, externalSystemID, provider string) error {
+	if provider != featureflag.ProviderUnleash && provider != featureflag.ProviderFlagsmith {
+		return errors.Errorf("unsupported feature flag provider %s of job %s", provider, jobName)
+	}
+	if _, err := mongodb.NewExternalSystemColl().GetByID(externalSystemID); err != nil {
+		return errors.Errorf("failed to find external system %s of job %s: %v", externalSystemID, jobName, err)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package job
+
+import (
+	"fmt"
+	"path"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+// lintJobFiles checks the file outputs of the job and that every file input refers to an output
+// declared by a freestyle job in a previous stage
+func lintJobFiles(workflow *commonmodels.WorkflowV4, jobName string, properties *commonmodels.JobProperties) error {
+	if properties == nil {
+		return nil
+	}
+	outputNames := sets.NewString()
+	for _, output := range properties.FileOutputs {
+		if !OutputNameRegex.MatchString(output.Name) {
+			return fmt.Errorf("file output name must match %s", OutputNameRegexString)
+		}
+		if outputNames.Has(output.Name) {
+			return fmt.Errorf("duplicate file output %s in job %s", output.Name, jobName)
+		}
+		if output.Path == "" {
+			return fmt.Errorf("path of file output %s in job %s can not be empty", output.Name, jobName)
+		}
+		outputNames.Insert(output.Name)
+	}
+	if len(properties.FileInputs) == 0 {
+		return nil
+	}
+
+	rankMap := getJobRankMap(workflow.Stages)
+	for _, input := range properties.FileInputs {
+		rank, ok := rankMap[input.JobName]
+		if !ok {
+			return fmt.Errorf("job %s of file input %s not found", input.JobName, input.Name)
+		}
+		if rank >= rankMap[jobName] {
+			return fmt.Errorf("job %s of file input %s must run before job %s", input.JobName, input.Name, jobName)
+		}
+		outputs, err := getJobFileOutputs(workflow, input.JobName)
+		if err != nil {
+			return err
+		}
+		if !outputs.Has(input.Name) {
+			return fmt.Errorf("job %s does not declare file output %s", input.JobName, input.Name)
+		}
+	}
+	return nil
+}
+
+func getJobFileOutputs(workflow *commonmodels.WorkflowV4, jobName string) (sets.String, error) {
+	resp := sets.NewString()
+	for _, stage := range workflow.Stages {
+		for _, job := range stage.Jobs {
+			if job.Name != jobName {
+				continue
+			}
+			if job.JobType != config.JobFreestyle {
+				return resp, fmt.Errorf("file outputs are only supported by freestyle jobs, job %s is %s", jobName, job.JobType)
+			}
+			spec := &commonmodels.FreestyleJobSpec{}
+			if err := commonmodels.IToiYaml(job.Spec, spec); err != nil {
+				return resp, err
+			}
+			if spec.Properties == nil {
+				return resp, nil
+			}
+			for _, output := range spec.Properties.FileOutputs {
+				resp.Insert(output.Name)
+			}
+			return resp, nil
+		}
+	}
+	return resp, nil
+}
+
+// getJobFileSteps returns the step downloading the file inputs and the step uploading the file outputs of the job,
+// nil if there is nothing to pass. The files of the task are stored in the default object storage.
+func getJobFileSteps(workflow *commonmodels.WorkflowV4, jobName string, properties *commonmodels.JobProperties, taskID int64) (*commonmodels.StepTask, *commonmodels.StepTask, error) {
+	if properties == nil || (len(properties.FileInputs) == 0 && len(properties.FileOutputs) == 0) {
+		return nil, nil, nil
+	}
+	if err := lintJobFiles(workflow, jobName, properties); err != nil {
+		return nil, nil, err
+	}
+	defaultS3, err := commonrepo.NewS3StorageColl().FindDefault()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find default s3 storage: %v", err)
+	}
+	s3DestDir := path.Join(workflow.Name, fmt.Sprint(taskID), "files")
+
+	var downloadStep, uploadStep *commonmodels.StepTask
+	if len(properties.FileInputs) > 0 {
+		files := make([]*step.JobFile, 0, len(properties.FileInputs))
+		for _, input := range properties.FileInputs {
+			files = append(files, &step.JobFile{Name: input.Name, Path: input.Path, JobName: input.JobName})
+		}
+		downloadStep = &commonmodels.StepTask{
+			Name:     jobName + "-download-files",
+			JobName:  jobName,
+			StepType: config.StepDownloadJobFiles,
+			Spec:     &step.StepJobFileSpec{Files: files, S3DestDir: s3DestDir, S3Storage: modelS3toS3(defaultS3)},
+		}
+	}
+	if len(properties.FileOutputs) > 0 {
+		files := make([]*step.JobFile, 0, len(properties.FileOutputs))
+		for _, output := range properties.FileOutputs {
+			files = append(files, &step.JobFile{Name: output.Name, Path: output.Path, JobName: jobName})
+		}
+		uploadStep = &commonmodels.StepTask{
+			Name:     jobName + "-upload-files",
+			JobName:  jobName,
+			StepType: config.StepUploadJobFiles,
+			Spec:     &step.StepJobFileSpec{Files: files, S3DestDir: s3DestDir, S3Storage: modelS3toS3(defaultS3)},
+		}
+	}
+	return downloadStep, uploadStep, nil
+}
+
+// insertAfterCheckout inserts the step after the leading tools and git steps so that the files
+// are not in the way of the code checkout
+func insertAfterCheckout(steps []*commonmodels.StepTask, stepTask *commonmodels.StepTask) []*commonmodels.StepTask {
+	index := 0
+	for index < len(steps) && (steps[index].StepType == config.StepTools || steps[index].StepType == config.StepGit) {
+		index++
+	}
+	resp := make([]*commonmodels.StepTask, 0, len(steps)+1)
+	resp = append(resp, steps[:index]...)
+	resp = append(resp, stepTask)
+	return append(resp, steps[index:]...)
+}
@@ -18,9 +18,11 @@ package job
 
 import (
 	"fmt"
+	"path"
 	"strings"
 
 	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	configbase "github.com/koderover/zadig/pkg/config"
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
@@ -28,6 +30,7 @@ import (
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/util"
+	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/tool/log"
 	"github.com/koderover/zadig/pkg/types"
 	steptypes "github.com/koderover/zadig/pkg/types/step"
@@ -63,7 +66,7 @@ func (j *FreeStyleJob) Instantiate() error {
 				return fmt.Errorf("parse git step spec error: %v", err)
 			}
 			step.Spec = stepSpec
-		case config.StepShell:
+		case config.StepShell, config.StepPowerShell:
 			stepSpec := &steptypes.StepShellSpec{}
 			if err := commonmodels.IToiYaml(step.Spec, stepSpec); err != nil {
 				return fmt.Errorf("parse shell step spec error: %v", err)
@@ -75,6 +78,18 @@ func (j *FreeStyleJob) Instantiate() error {
 				return fmt.Errorf("parse archive step spec error: %v", err)
 			}
 			step.Spec = stepSpec
+		case config.StepDockerBuild:
+			stepSpec := &steptypes.StepDockerBuildSpec{}
+			if err := commonmodels.IToiYaml(step.Spec, stepSpec); err != nil {
+				return fmt.Errorf("parse docker build step spec error: %v", err)
+			}
+			step.Spec = stepSpec
+		case config.StepTarArchive:
+			stepSpec := &steptypes.StepTarArchiveSpec{}
+			if err := commonmodels.IToiYaml(step.Spec, stepSpec); err != nil {
+				return fmt.Errorf("parse file archive step spec error: %v", err)
+			}
+			step.Spec = stepSpec
 		default:
 			return fmt.Errorf("freestyle job step type %s not supported", step.StepType)
 		}
@@ -125,6 +140,15 @@ func (j *FreeStyleJob) MergeArgs(args *commonmodels.Job) error {
 		}
 		j.spec.Properties.Envs = renderKeyVals(j.spec.Properties.Envs, argsSpec.Properties.Envs)
 
+		for _, step := range j.spec.Steps {
+			for _, stepArgs := range argsSpec.Steps {
+				if stepArgs.Name == step.Name {
+					step.Inputs = renderKeyVals(step.Inputs, stepArgs.Inputs)
+					break
+				}
+			}
+		}
+
 		for _, step := range j.spec.Steps {
 			if step.StepType != config.StepGit {
 				continue
@@ -184,7 +208,7 @@ func (j *FreeStyleJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 	j.job.Spec = j.spec
 	jobTaskSpec := &commonmodels.JobTaskFreestyleSpec{
 		Properties: *j.spec.Properties,
-		Steps:      j.stepsToStepTasks(j.spec.Steps),
+		Steps:      j.stepsToStepTasks(j.spec.Steps, taskID),
 	}
 	jobTask := &commonmodels.JobTask{
 		Name: j.job.Name,
@@ -195,7 +219,7 @@ func (j *FreeStyleJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 		JobType: string(config.JobFreestyle),
 		Spec:    jobTaskSpec,
 		Timeout: j.spec.Properties.Timeout,
-		Outputs: j.spec.Outputs,
+		Outputs: j.getOutputs(),
 	}
 	registries, err := commonservice.ListRegistryNamespaces("", true, logger)
 	if err != nil {
@@ -209,20 +233,54 @@ func (j *FreeStyleJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 		return resp, fmt.Errorf("failed to find base image: %s,error :%v", jobTaskSpec.Properties.ImageID, err)
 	}
 	jobTaskSpec.Properties.BuildOS = basicImage.Value
+	jobTaskSpec.Properties.OS = basicImage.OS
+	if basicImage.OS == setting.OSWindows {
+		if err := checkWindowsExecutorImage(); err != nil {
+			return resp, err
+		}
+	}
+	downloadFilesStep, uploadFilesStep, err := getJobFileSteps(j.workflow, j.job.Name, j.spec.Properties, taskID)
+	if err != nil {
+		return resp, err
+	}
+	if downloadFilesStep != nil {
+		jobTaskSpec.Steps = insertAfterCheckout(jobTaskSpec.Steps, downloadFilesStep)
+	}
+	if uploadFilesStep != nil {
+		jobTaskSpec.Steps = append(jobTaskSpec.Steps, uploadFilesStep)
+	}
+	publishStep, err := getPublishStep(j.spec.Properties.PublishPaths, jobTask.Name, path.Join(j.workflow.Name, fmt.Sprint(taskID), jobTask.Name, "publish"))
+	if err != nil {
+		return resp, err
+	}
+	if publishStep != nil {
+		jobTaskSpec.Steps = append(jobTaskSpec.Steps, publishStep)
+	}
 	// save user defined variables.
 	jobTaskSpec.Properties.CustomEnvs = jobTaskSpec.Properties.Envs
 	jobTaskSpec.Properties.Envs = append(jobTaskSpec.Properties.Envs, getfreestyleJobVariables(jobTaskSpec.Steps, taskID, j.workflow.Project, j.workflow.Name)...)
 	return []*commonmodels.JobTask{jobTask}, nil
 }
 
-func (j *FreeStyleJob) stepsToStepTasks(step []*commonmodels.Step) []*commonmodels.StepTask {
+// getOutputs returns the outputs of the job and the outputs of its steps
+func (j *FreeStyleJob) getOutputs() []*commonmodels.Output {
+	outputs := append([]*commonmodels.Output{}, j.spec.Outputs...)
+	for _, step := range j.spec.Steps {
+		outputs = append(outputs, step.Outputs...)
+	}
+	return outputs
+}
+
+func (j *FreeStyleJob) stepsToStepTasks(step []*commonmodels.Step, taskID int64) []*commonmodels.StepTask {
 	logger := log.SugaredLogger()
 	resp := []*commonmodels.StepTask{}
 	for _, step := range step {
 		stepTask := &commonmodels.StepTask{
 			Name:     step.Name,
+			JobName:  j.job.Name,
 			StepType: step.StepType,
 			Spec:     step.Spec,
+			Inputs:   step.Inputs,
 		}
 		if stepTask.StepType == config.StepGit {
 			stepTaskSpec := &steptypes.StepGitSpec{}
@@ -273,7 +331,7 @@ func (j *FreeStyleJob) stepsToStepTasks(step []*commonmodels.Step) []*commonmode
 			if err := commonmodels.IToi(stepTask.Spec, stepTaskSpec); err != nil {
 				continue
 			}
-			stepTaskSpec.Scripts = append(strings.Split(replaceWrapLine(stepTaskSpec.Script), "\n"), outputScript(j.spec.Outputs)...)
+			stepTaskSpec.Scripts = append(strings.Split(replaceWrapLine(stepTaskSpec.Script), "\n"), outputScript(append(append([]*commonmodels.Output{}, j.spec.Outputs...), step.Outputs...))...)
 			stepTask.Spec = stepTaskSpec
 			// add debug step before shell step
 			debugBeforeStep := &commonmodels.StepTask{
@@ -282,6 +340,25 @@ func (j *FreeStyleJob) stepsToStepTasks(step []*commonmodels.Step) []*commonmode
 			}
 			resp = append(resp, debugBeforeStep)
 		}
+		if stepTask.StepType == config.StepPowerShell {
+			stepTaskSpec := &steptypes.StepShellSpec{}
+			if err := commonmodels.IToi(stepTask.Spec, stepTaskSpec); err != nil {
+				continue
+			}
+			stepTaskSpec.Scripts = append(strings.Split(replaceWrapLine(stepTaskSpec.Script), "\n"), outputPowerShellScript(append(append([]*commonmodels.Output{}, j.spec.Outputs...), step.Outputs...))...)
+			stepTask.Spec = stepTaskSpec
+		}
+
+		if stepTask.StepType == config.StepTarArchive {
+			stepTaskSpec := &steptypes.StepTarArchiveSpec{}
+			if err := commonmodels.IToi(stepTask.Spec, stepTaskSpec); err != nil {
+				continue
+			}
+			if stepTaskSpec.S3DestDir == "" {
+				stepTaskSpec.S3DestDir = path.Join(j.workflow.Name, fmt.Sprint(taskID), j.job.Name, "archive")
+			}
+			stepTask.Spec = stepTaskSpec
+		}
 
 		resp = append(resp, stepTask)
 		if stepTask.StepType == config.StepShell {
@@ -323,17 +400,47 @@ func (j *FreeStyleJob) LintJob() error {
 	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
 		return err
 	}
-	return checkOutputNames(j.spec.Outputs)
+	stepNames := sets.NewString()
+	for _, step := range j.spec.Steps {
+		if step.Name == "" {
+			return fmt.Errorf("step name of job %s can not be empty", j.job.Name)
+		}
+		if stepNames.Has(step.Name) {
+			return fmt.Errorf("duplicate step name %s in job %s", step.Name, j.job.Name)
+		}
+		stepNames.Insert(step.Name)
+		if len(step.Outputs) > 0 && step.StepType != config.StepShell && step.StepType != config.StepPowerShell {
+			return fmt.Errorf("only shell and powershell step can have outputs, step %s in job %s", step.Name, j.job.Name)
+		}
+		for _, input := range step.Inputs {
+			if input.Key == "" {
+				return fmt.Errorf("input key of step %s in job %s can not be empty", step.Name, j.job.Name)
+			}
+		}
+	}
+	if err := lintJobFiles(j.workflow, j.job.Name, j.spec.Properties); err != nil {
+		return err
+	}
+	if j.spec.Properties != nil && j.spec.Properties.Infrastructure == setting.JobVMInfrastructure {
+		if err := checkVMJob(j.spec.Properties); err != nil {
+			return fmt.Errorf("job %s: %v", j.job.Name, err)
+		}
+	} else if j.spec.Properties != nil {
+		if err := lintJobImage(j.job.Name, j.spec.Properties.ImageID, j.spec.Properties.ImageFrom); err != nil {
+			return err
+		}
+	}
+	return checkOutputNames(j.getOutputs())
 }
 
-func (j *FreeStyleJob) GetOutPuts(log *zap.SugaredLogger) []string {
-	resp := []string{}
+func (j *FreeStyleJob) GetOutputs(log *zap.SugaredLogger) []*WorkflowVariable {
+	resp := []*WorkflowVariable{}
 	j.spec = &commonmodels.FreestyleJobSpec{}
 	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
 		return resp
 	}
 
 	jobKey := j.job.Name
-	resp = append(resp, getOutputKey(jobKey, j.spec.Outputs)...)
+	resp = append(resp, newJobOutputs(j.job, jobKey, j.getOutputs())...)
 	return resp
 }
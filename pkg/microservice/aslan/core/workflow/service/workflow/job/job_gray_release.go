@@ -145,6 +145,7 @@ func (j *GrayReleaseJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
 				GrayScale:        j.spec.GrayScale,
 				TotalReplica:     target.Replica,
 				GrayReplica:      int(grayReplica),
+				Analysis:         j.spec.Analysis,
 			},
 		}
 		resp = append(resp, jobTask)
@@ -161,6 +162,12 @@ func (j *GrayReleaseJob) LintJob() error {
 	if j.spec.GrayScale > 100 {
 		return fmt.Errorf("release job: [%s] release percentage cannot largger than 100", j.job.Name)
 	}
+	if j.spec.Analysis != nil && j.spec.GrayScale >= 100 {
+		return fmt.Errorf("release job: [%s] is full released, there is nothing left to analyze", j.job.Name)
+	}
+	if err := lintCanaryAnalysis(j.spec.Analysis); err != nil {
+		return err
+	}
 	// from job was empty means it is the first deploy job.
 	if j.spec.FromJob == "" {
 		if err := lintFirstGrayReleaseJob(j.job.Name, j.workflow.Stages); err != nil {
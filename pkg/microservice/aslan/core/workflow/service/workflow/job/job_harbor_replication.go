@@ -0,0 +1,103 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package job
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+)
+
+type HarborReplicationJob struct {
+	job      *commonmodels.Job
+	workflow *commonmodels.WorkflowV4
+	spec     *commonmodels.HarborReplicationJobSpec
+}
+
+func (j *HarborReplicationJob) Instantiate() error {
+	j.spec = &commonmodels.HarborReplicationJobSpec{}
+	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	j.job.Spec = j.spec
+	return nil
+}
+
+func (j *HarborReplicationJob) SetPreset() error {
+	j.spec = &commonmodels.HarborReplicationJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	j.job.Spec = j.spec
+	return nil
+}
+
+func (j *HarborReplicationJob) MergeArgs(args *commonmodels.Job) error {
+	j.spec = &commonmodels.HarborReplicationJobSpec{}
+	if err := commonmodels.IToi(args.Spec, j.spec); err != nil {
+		return err
+	}
+	j.job.Spec = j.spec
+	return nil
+}
+
+func (j *HarborReplicationJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
+	j.spec = &commonmodels.HarborReplicationJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return nil, err
+	}
+	j.job.Spec = j.spec
+
+	jobTask := &commonmodels.JobTask{
+		Name: j.job.Name,
+		Key:  j.job.Name,
+		JobInfo: map[string]string{
+			JobNameKey: j.job.Name,
+		},
+		JobType: string(config.JobHarborReplication),
+		Spec: &commonmodels.JobTaskHarborReplicationSpec{
+			RegistryID:       j.spec.RegistryID,
+			PolicyName:       j.spec.PolicyName,
+			Timeout:          j.spec.Timeout,
+			RetentionProject: j.spec.RetentionProject,
+		},
+	}
+	return []*commonmodels.JobTask{jobTask}, nil
+}
+
+func (j *HarborReplicationJob) LintJob() error {
+	j.spec = &commonmodels.HarborReplicationJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	if j.spec.PolicyName == "" {
+		return errors.Errorf("replication policy of job %s can not be empty", j.job.Name)
+	}
+	if j.spec.Timeout <= 0 {
+		return errors.Errorf("timeout of job %s must be greater than 0", j.job.Name)
+	}
+	registry, err := mongodb.NewRegistryNamespaceColl().Find(&mongodb.FindRegOps{ID: j.spec.RegistryID})
+	if err != nil {
+		return errors.Errorf("failed to find registry %s of job %s: %v", j.spec.RegistryID, j.job.Name, err)
+	}
+	if registry.RegProvider != config.RegistryProviderHarbor {
+		return errors.Errorf("registry %s of job %s is not a harbor registry", registry.RegAddr, j.job.Name)
+	}
+	return nil
+}
@@ -151,5 +151,5 @@ func (j *HelmChartDeployJob) LintJob() error {
 	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
 		return err
 	}
-	return nil
+	return lintDeployTarget(j.job.Name, j.workflow.Project, j.spec.Env)
 }
@@ -0,0 +1,157 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package job
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+const (
+	HTTPStatusCodeKey = "STATUS_CODE"
+
+	defaultHTTPCallbackTimeout       = 30
+	defaultHTTPCallbackRetryInterval = 5
+	minHTTPCallbackRetryInterval     = 5
+)
+
+type HTTPCallbackJob struct {
+	job      *commonmodels.Job
+	workflow *commonmodels.WorkflowV4
+	spec     *commonmodels.HTTPCallbackJobSpec
+}
+
+func (j *HTTPCallbackJob) Instantiate() error {
+	j.spec = &commonmodels.HTTPCallbackJobSpec{}
+	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	j.job.Spec = j.spec
+	return nil
+}
+
+func (j *HTTPCallbackJob) SetPreset() error {
+	j.spec = &commonmodels.HTTPCallbackJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	j.job.Spec = j.spec
+	return nil
+}
+
+func (j *HTTPCallbackJob) MergeArgs(args *commonmodels.Job) error {
+	j.spec = &commonmodels.HTTPCallbackJobSpec{}
+	if err := commonmodels.IToi(args.Spec, j.spec); err != nil {
+		return err
+	}
+	j.job.Spec = j.spec
+	return nil
+}
+
+func (j *HTTPCallbackJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
+	j.spec = &commonmodels.HTTPCallbackJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return nil, err
+	}
+	j.job.Spec = j.spec
+
+	spec := *j.spec
+	spec.Method = strings.ToUpper(spec.Method)
+	if spec.Method == "" {
+		spec.Method = http.MethodPost
+	}
+	if spec.Timeout <= 0 {
+		spec.Timeout = defaultHTTPCallbackTimeout
+	}
+	if spec.Retry > 0 && spec.RetryInterval <= 0 {
+		spec.RetryInterval = defaultHTTPCallbackRetryInterval
+	}
+	if spec.Retry > 0 && spec.RetryInterval < minHTTPCallbackRetryInterval {
+		spec.RetryInterval = minHTTPCallbackRetryInterval
+	}
+	jobTask := &commonmodels.JobTask{
+		Name: j.job.Name,
+		Key:  j.job.Name,
+		JobInfo: map[string]string{
+			JobNameKey: j.job.Name,
+		},
+		JobType: string(config.JobHTTPCallback),
+		Spec:    &commonmodels.JobTaskHTTPCallbackSpec{HTTPCallbackJobSpec: spec},
+	}
+	return []*commonmodels.JobTask{jobTask}, nil
+}
+
+func (j *HTTPCallbackJob) LintJob() error {
+	j.spec = &commonmodels.HTTPCallbackJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	switch strings.ToUpper(j.spec.Method) {
+	case "", http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return errors.Errorf("method %s of job %s is not supported", j.spec.Method, j.job.Name)
+	}
+	if j.spec.URL == "" {
+		return errors.Errorf("url of job %s can not be empty", j.job.Name)
+	}
+	if j.spec.Retry < 0 || j.spec.RetryInterval < 0 || j.spec.Timeout < 0 {
+		return errors.Errorf("timeout, retry and retry interval of job %s can not be negative", j.job.Name)
+	}
+	if j.spec.Retry > 0 && j.spec.RetryInterval > 0 && j.spec.RetryInterval < minHTTPCallbackRetryInterval {
+		return errors.Errorf("retry interval of job %s can not be less than %d seconds", j.job.Name, minHTTPCallbackRetryInterval)
+	}
+	// the body with variables is checked after it is rendered
+	if j.spec.Body != "" && !strings.Contains(j.spec.Body, "{{") && !json.Valid([]byte(j.spec.Body)) {
+		return errors.Errorf("body of job %s is not a valid json", j.job.Name)
+	}
+	if j.spec.Assertion != nil && j.spec.Assertion.JSONValue != "" && j.spec.Assertion.JSONPath == "" {
+		return errors.Errorf("json path of the assertion of job %s can not be empty", j.job.Name)
+	}
+	outputs := map[string]bool{HTTPStatusCodeKey: true}
+	for _, output := range j.spec.Outputs {
+		if !OutputNameRegex.MatchString(output.Name) {
+			return errors.Errorf("output name %s of job %s must match %s", output.Name, j.job.Name, OutputNameRegexString)
+		}
+		if outputs[output.Name] {
+			return errors.Errorf("output %s of job %s is duplicated", output.Name, j.job.Name)
+		}
+		if output.JSONPath == "" {
+			return errors.Errorf("json path of output %s of job %s can not be empty", output.Name, j.job.Name)
+		}
+		outputs[output.Name] = true
+	}
+	return nil
+}
+
+func (j *HTTPCallbackJob) GetOutputs(log *zap.SugaredLogger) []*WorkflowVariable {
+	j.spec = &commonmodels.HTTPCallbackJobSpec{}
+	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
+		return []*WorkflowVariable{}
+	}
+	outputs := []*commonmodels.Output{{Name: HTTPStatusCodeKey, Type: commonmodels.OutputTypeNumber}}
+	for _, output := range j.spec.Outputs {
+		outputs = append(outputs, &commonmodels.Output{Name: output.Name})
+	}
+	return newJobOutputs(j.job, j.job.Name, outputs)
+}
@@ -139,6 +139,7 @@ func (j *IstioReleaseJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error)
 				ReplicaPercentage: j.spec.ReplicaPercentage,
 				Replicas:          int64(newReplicaCount),
 				Targets:           target,
+				MirrorPercentage:  j.spec.MirrorPercentage,
 			},
 		}
 		resp = append(resp, jobTask)
@@ -154,6 +155,12 @@ func (j *IstioReleaseJob) LintJob() error {
 	if j.spec.Weight > 100 {
 		return fmt.Errorf("istio release job: [%s] weight cannot be more than 100", j.job.Name)
 	}
+	if j.spec.MirrorPercentage < 0 || j.spec.MirrorPercentage > 100 {
+		return fmt.Errorf("istio release job: [%s] mirror percentage should be between 0 and 100", j.job.Name)
+	}
+	if j.spec.MirrorPercentage > 0 && j.spec.Weight == 100 {
+		return fmt.Errorf("istio release job: [%s] routes all the traffic to the new version, nothing is left to mirror", j.job.Name)
+	}
 
 	//from job was empty means it is the first deploy job.
 	if j.spec.FromJob == "" {
@@ -0,0 +1,232 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package job
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/util"
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/types"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+type PerformanceTestJob struct {
+	job      *commonmodels.Job
+	workflow *commonmodels.WorkflowV4
+	spec     *commonmodels.ZadigPerformanceTestJobSpec
+}
+
+func (j *PerformanceTestJob) Instantiate() error {
+	j.spec = &commonmodels.ZadigPerformanceTestJobSpec{}
+	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	if j.spec.Properties == nil {
+		return fmt.Errorf("properties of performance test job %s is empty", j.job.Name)
+	}
+	if err := util.CheckDefineResourceParam(j.spec.Properties.ResourceRequest, j.spec.Properties.ResReqSpec); err != nil {
+		return err
+	}
+	j.job.Spec = j.spec
+	return nil
+}
+
+func (j *PerformanceTestJob) SetPreset() error {
+	j.spec = &commonmodels.ZadigPerformanceTestJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	j.job.Spec = j.spec
+	return nil
+}
+
+func (j *PerformanceTestJob) GetRepos() ([]*types.Repository, error) {
+	j.spec = &commonmodels.ZadigPerformanceTestJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return []*types.Repository{}, err
+	}
+	return j.spec.Repos, nil
+}
+
+func (j *PerformanceTestJob) MergeArgs(args *commonmodels.Job) error {
+	if j.job.Name == args.Name && j.job.JobType == args.JobType {
+		j.spec = &commonmodels.ZadigPerformanceTestJobSpec{}
+		if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+			return err
+		}
+		argsSpec := &commonmodels.ZadigPerformanceTestJobSpec{}
+		if err := commonmodels.IToi(args.Spec, argsSpec); err != nil {
+			return err
+		}
+		j.spec.Repos = mergeRepos(j.spec.Repos, argsSpec.Repos)
+		// the baseline can be chosen when the workflow is run
+		if argsSpec.BaselineTaskID > 0 {
+			j.spec.BaselineTaskID = argsSpec.BaselineTaskID
+		}
+		if argsSpec.Properties != nil {
+			j.spec.Properties.Envs = renderKeyVals(j.spec.Properties.Envs, argsSpec.Properties.Envs)
+		}
+		j.job.Spec = j.spec
+	}
+	return nil
+}
+
+func (j *PerformanceTestJob) MergeWebhookRepo(webhookRepo *types.Repository) error {
+	j.spec = &commonmodels.ZadigPerformanceTestJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	j.spec.Repos = mergeRepos(j.spec.Repos, []*types.Repository{webhookRepo})
+	j.job.Spec = j.spec
+	return nil
+}
+
+func (j *PerformanceTestJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
+	logger := log.SugaredLogger()
+	resp := []*commonmodels.JobTask{}
+
+	j.spec = &commonmodels.ZadigPerformanceTestJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return resp, err
+	}
+	j.job.Spec = j.spec
+
+	basicImage, err := commonrepo.NewBasicImageColl().Find(j.spec.Properties.ImageID)
+	if err != nil {
+		return resp, fmt.Errorf("failed to find base image: %s, error: %v", j.spec.Properties.ImageID, err)
+	}
+	registries, err := commonservice.ListRegistryNamespaces("", true, logger)
+	if err != nil {
+		return resp, err
+	}
+
+	performanceSpec := &step.StepPerformanceSpec{
+		Tool:                    j.spec.Tool,
+		ResultPath:              j.spec.ResultPath,
+		MaxErrorRate:            j.spec.MaxErrorRate,
+		MaxLatencyRegression:    j.spec.MaxLatencyRegression,
+		MaxThroughputRegression: j.spec.MaxThroughputRegression,
+		S3DestDir:               path.Join(j.workflow.Name, fmt.Sprint(taskID), j.job.Name, "performance"),
+	}
+	if j.spec.BaselineTaskID > 0 {
+		baseline, err := commonrepo.NewPerformanceRecordColl().Find(j.workflow.Project, j.workflow.Name, j.job.Name, j.spec.BaselineTaskID)
+		switch {
+		case err == mongo.ErrNoDocuments:
+			// the baseline task may not have finished the job yet, the task runs without the comparison
+			logger.Warnf("no performance result of job %s found in baseline task %d, skip the comparison", j.job.Name, j.spec.BaselineTaskID)
+		case err != nil:
+			return resp, fmt.Errorf("failed to find performance result of job %s in baseline task %d: %s", j.job.Name, j.spec.BaselineTaskID, err)
+		default:
+			performanceSpec.BaselineTaskID = j.spec.BaselineTaskID
+			performanceSpec.Baseline = baseline.Metrics
+		}
+	}
+
+	jobTaskSpec := &commonmodels.JobTaskFreestyleSpec{Properties: *j.spec.Properties}
+	jobTask := &commonmodels.JobTask{
+		Name: j.job.Name,
+		Key:  j.job.Name,
+		JobInfo: map[string]string{
+			JobNameKey: j.job.Name,
+		},
+		JobType: string(config.JobZadigPerformanceTest),
+		Spec:    jobTaskSpec,
+		Timeout: j.spec.Properties.Timeout,
+	}
+	jobTaskSpec.Properties.Registries = registries
+	jobTaskSpec.Properties.ShareStorageDetails = getShareStorageDetail(j.workflow.ShareStorages, j.spec.Properties.ShareStorageInfo, j.workflow.Name, taskID)
+	jobTaskSpec.Properties.BuildOS = basicImage.Value
+	jobTaskSpec.Properties.CustomEnvs = jobTaskSpec.Properties.Envs
+
+	repos := []*types.Repository{}
+	for _, repo := range j.spec.Repos {
+		if repo.SourceFrom == types.RepoSourceParam {
+			paramRepo, err := findMatchedRepoFromParams(j.workflow.Params, repo.GlobalParamName)
+			if err != nil {
+				logger.Errorf("findMatchedRepoFromParams error: %v", err)
+				continue
+			}
+			repo = paramRepo
+		}
+		repos = append(repos, repo)
+	}
+
+	jobTaskSpec.Steps = append(jobTaskSpec.Steps, &commonmodels.StepTask{
+		Name:     j.job.Name + "-git",
+		JobName:  jobTask.Name,
+		StepType: config.StepGit,
+		Spec:     step.StepGitSpec{Repos: repos},
+	})
+	jobTaskSpec.Steps = append(jobTaskSpec.Steps, &commonmodels.StepTask{
+		Name:     j.job.Name + "-debug-before",
+		JobName:  jobTask.Name,
+		StepType: config.StepDebugBefore,
+	})
+	jobTaskSpec.Steps = append(jobTaskSpec.Steps, &commonmodels.StepTask{
+		Name:     j.job.Name + "-shell",
+		JobName:  jobTask.Name,
+		StepType: config.StepShell,
+		Spec: &step.StepShellSpec{
+			Scripts:     strings.Split(replaceWrapLine(j.spec.Script), "\n"),
+			SkipPrepare: true,
+		},
+	})
+	jobTaskSpec.Steps = append(jobTaskSpec.Steps, &commonmodels.StepTask{
+		Name:     j.job.Name + "-performance",
+		JobName:  jobTask.Name,
+		StepType: config.StepPerformance,
+		Spec:     performanceSpec,
+	})
+	publishStep, err := getPublishStep(j.spec.Properties.PublishPaths, jobTask.Name, path.Join(j.workflow.Name, fmt.Sprint(taskID), jobTask.Name, "publish"))
+	if err != nil {
+		return resp, err
+	}
+	if publishStep != nil {
+		jobTaskSpec.Steps = append(jobTaskSpec.Steps, publishStep)
+	}
+	jobTaskSpec.Steps = append(jobTaskSpec.Steps, &commonmodels.StepTask{
+		Name:     j.job.Name + "-debug-after",
+		JobName:  jobTask.Name,
+		StepType: config.StepDebugAfter,
+	})
+
+	jobTaskSpec.Properties.Envs = append(jobTaskSpec.Properties.Envs, getfreestyleJobVariables(jobTaskSpec.Steps, taskID, j.workflow.Project, j.workflow.Name)...)
+	return []*commonmodels.JobTask{jobTask}, nil
+}
+
+func (j *PerformanceTestJob) LintJob() error {
+	j.spec = &commonmodels.ZadigPerformanceTestJobSpec{}
+	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	if j.spec.Tool != step.PerformanceToolK6 && j.spec.Tool != step.PerformanceToolJMeter {
+		return fmt.Errorf("unsupported performance test tool %s in job %s", j.spec.Tool, j.job.Name)
+	}
+	if j.spec.Script == "" || j.spec.ResultPath == "" {
+		return fmt.Errorf("script and result path of job %s can not be empty", j.job.Name)
+	}
+	return nil
+}
@@ -17,12 +17,16 @@ limitations under the License.
 package job
 
 import (
+	"fmt"
 	"strings"
 
+	"github.com/blang/semver/v4"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/zap"
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
 	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
 	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/tool/log"
@@ -130,14 +134,82 @@ func (j *PluginJob) LintJob() error {
 	return checkOutputNames(j.spec.Plugin.Outputs)
 }
 
-func (j *PluginJob) GetOutPuts(log *zap.SugaredLogger) []string {
-	resp := []string{}
+// checkPluginCompatibility makes sure the plugin version of the job is still in the registry, enabled in the project
+// and supported by this zadig, and that the inputs of the job are the ones of the plugin
+func checkPluginCompatibility(projectName string, plugin *commonmodels.PluginTemplate) error {
+	if plugin == nil {
+		return fmt.Errorf("plugin is not set")
+	}
+	repos, err := commonrepo.NewPluginRepoColl().List(nil)
+	if err != nil {
+		return fmt.Errorf("list plugin repos error: %v", err)
+	}
+	var registered *commonmodels.PluginTemplate
+	repoID := ""
+	for _, repo := range repos {
+		// the jobs added before the plugins have ids are matched by the name
+		if plugin.RepoID != "" && plugin.RepoID != repo.ID.Hex() {
+			continue
+		}
+		for _, template := range repo.PluginTemplates {
+			if template.Version != plugin.Version {
+				continue
+			}
+			if (plugin.ID != "" && template.ID == plugin.ID) || (plugin.ID == "" && template.Name == plugin.Name) {
+				registered, repoID = template, repo.ID.Hex()
+				break
+			}
+		}
+		if registered != nil {
+			break
+		}
+	}
+	if registered == nil {
+		return fmt.Errorf("plugin %s %s is not found in the plugin registry", plugin.Name, plugin.Version)
+	}
+
+	projectPlugin, err := commonrepo.NewProjectPluginColl().Find(projectName)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return fmt.Errorf("find plugins of project %s error: %v", projectName, err)
+	}
+	if err == nil && !projectPlugin.Enabled(repoID, registered.ID) {
+		return fmt.Errorf("plugin %s is not enabled in project %s", registered.Name, projectName)
+	}
+
+	if registered.MinZadigVersion != "" && config.ZadigVersion() != "" {
+		minVersion, err := semver.ParseTolerant(registered.MinZadigVersion)
+		if err != nil {
+			return fmt.Errorf("invalid min zadig version %s of plugin %s", registered.MinZadigVersion, registered.Name)
+		}
+		if current, err := semver.ParseTolerant(config.ZadigVersion()); err == nil && current.LT(minVersion) {
+			return fmt.Errorf("plugin %s %s requires zadig %s or later", registered.Name, registered.Version, registered.MinZadigVersion)
+		}
+	}
+
+	inputs := make(map[string]*commonmodels.Param)
+	for _, input := range registered.Inputs {
+		inputs[input.Name] = input
+	}
+	for _, input := range plugin.Inputs {
+		registeredInput, ok := inputs[input.Name]
+		if !ok {
+			return fmt.Errorf("input %s is not defined by plugin %s %s", input.Name, registered.Name, registered.Version)
+		}
+		if registeredInput.ParamsType != input.ParamsType {
+			return fmt.Errorf("input %s of plugin %s %s should be %s", input.Name, registered.Name, registered.Version, registeredInput.ParamsType)
+		}
+	}
+	return nil
+}
+
+func (j *PluginJob) GetOutputs(log *zap.SugaredLogger) []*WorkflowVariable {
+	resp := []*WorkflowVariable{}
 	j.spec = &commonmodels.PluginJobSpec{}
 	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
 		return resp
 	}
 
 	jobKey := j.job.Name
-	resp = append(resp, getOutputKey(jobKey, j.spec.Plugin.Outputs)...)
+	resp = append(resp, newJobOutputs(j.job, jobKey, j.spec.Plugin.Outputs)...)
 	return resp
 }
@@ -0,0 +1,82 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package job
+
+import (
+	"fmt"
+	"strings"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/jobpolicy"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+// lintJobImage checks the base image of the build or freestyle job against the job policy
+func lintJobImage(jobName, imageID, imageFrom string) error {
+	if imageID == "" || imageFrom != setting.ImageFromCustom {
+		return nil
+	}
+	if policy, err := jobpolicy.GetPolicy(); err != nil || policy == nil || len(policy.AllowedImages) == 0 {
+		return err
+	}
+	basicImage, err := commonrepo.NewBasicImageColl().Find(imageID)
+	if err != nil {
+		return fmt.Errorf("failed to find base image %s of job %s: %v", imageID, jobName, err)
+	}
+	return jobpolicy.CheckJobImage(jobName, basicImage.Value, imageFrom)
+}
+
+// lintDeployTarget checks the cluster and namespace of the configured env of the deploy job against the job policy,
+// the envs from variables or changed at runtime are checked when the job runs
+func lintDeployTarget(jobName, projectName, envName string) error {
+	envName = strings.ReplaceAll(envName, setting.FixedValueMark, "")
+	if envName == "" || strings.Contains(envName, "{{") {
+		return nil
+	}
+	if policy, err := jobpolicy.GetPolicy(); err != nil || policy == nil || len(policy.AllowedDeployTargets) == 0 {
+		return err
+	}
+	env, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: envName})
+	if err != nil {
+		return nil
+	}
+	return jobpolicy.CheckDeployTarget(jobName, env.ClusterID, env.Namespace)
+}
+
+// lintBuildImages checks the base images of the builds of the build job
+func lintBuildImages(jobName, projectName string, builds []*commonmodels.ServiceAndBuild) error {
+	if policy, err := jobpolicy.GetPolicy(); err != nil || policy == nil || len(policy.AllowedImages) == 0 {
+		return err
+	}
+	for _, build := range builds {
+		buildInfo, err := commonrepo.NewBuildColl().Find(&commonrepo.BuildFindOption{Name: build.BuildName, ProductName: projectName})
+		if err != nil {
+			return fmt.Errorf("find build: %s error: %v", build.BuildName, err)
+		}
+		if err := fillBuildDetail(buildInfo, build.ServiceName, build.ServiceModule); err != nil {
+			return err
+		}
+		if buildInfo.PreBuild == nil || buildInfo.PreBuild.Infrastructure == setting.JobVMInfrastructure {
+			continue
+		}
+		if err := lintJobImage(jobName, buildInfo.PreBuild.ImageID, buildInfo.PreBuild.ImageFrom); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package job
+
+import (
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+// JobSpecs returns an empty spec of each job type supported by InitJobCtl, they are used to generate the
+// schema of the workflow
+func JobSpecs() map[config.JobType]interface{} {
+	return map[config.JobType]interface{}{
+		config.JobZadigBuild:           &commonmodels.ZadigBuildJobSpec{},
+		config.JobZadigDeploy:          &commonmodels.ZadigDeployJobSpec{},
+		config.JobZadigHelmChartDeploy: &commonmodels.ZadigHelmChartDeployJobSpec{},
+		config.JobPlugin:               &commonmodels.PluginJobSpec{},
+		config.JobFreestyle:            &commonmodels.FreestyleJobSpec{},
+		config.JobCustomDeploy:         &commonmodels.CustomDeployJobSpec{},
+		config.JobK8sBlueGreenDeploy:   &commonmodels.BlueGreenDeployV2JobSpec{},
+		config.JobK8sBlueGreenRelease:  &commonmodels.BlueGreenReleaseV2JobSpec{},
+		config.JobK8sCanaryDeploy:      &commonmodels.CanaryDeployJobSpec{},
+		config.JobK8sCanaryRelease:     &commonmodels.CanaryReleaseJobSpec{},
+		config.JobZadigTesting:         &commonmodels.ZadigTestingJobSpec{},
+		config.JobK8sGrayRelease:       &commonmodels.GrayReleaseJobSpec{},
+		config.JobK8sGrayRollback:      &commonmodels.GrayRollbackJobSpec{},
+		config.JobK8sPatch:             &commonmodels.K8sPatchJobSpec{},
+		config.JobZadigScanning:        &commonmodels.ZadigScanningJobSpec{},
+		config.JobZadigSonar:           &commonmodels.ZadigSonarJobSpec{},
+		config.JobZadigPerformanceTest: &commonmodels.ZadigPerformanceTestJobSpec{},
+		config.JobZadigDistributeImage: &commonmodels.ZadigDistributeImageJobSpec{},
+		config.JobIstioRelease:         &commonmodels.IstioJobSpec{},
+		config.JobIstioRollback:        &commonmodels.IstioRollBackJobSpec{},
+		config.JobJira:                 &commonmodels.JiraJobSpec{},
+		config.JobNacos:                &commonmodels.NacosJobSpec{},
+		config.JobApollo:               &commonmodels.ApolloJobSpec{},
+		config.JobMeegoTransition:      &commonmodels.MeegoTransitionJobSpec{},
+		config.JobWorkflowTrigger:      &commonmodels.WorkflowTriggerJobSpec{},
+		config.JobOfflineService:       &commonmodels.OfflineServiceJobSpec{},
+		config.JobMseGrayRelease:       &commonmodels.MseGrayReleaseJobSpec{},
+		config.JobMseGrayOffline:       &commonmodels.MseGrayOfflineJobSpec{},
+		config.JobGuanceyunCheck:       &commonmodels.GuanceyunCheckJobSpec{},
+		config.JobHarborReplication:    &commonmodels.HarborReplicationJobSpec{},
+		config.JobStaticDistribute:     &commonmodels.StaticDistributeJobSpec{},
+		config.JobHTTPCallback:         &commonmodels.HTTPCallbackJobSpec{},
+		config.JobFeatureFlag:          &commonmodels.FeatureFlagJobSpec{},
+		config.JobFeatureFlagRollback:  &commonmodels.FeatureFlagRollbackJobSpec{},
+	}
+}
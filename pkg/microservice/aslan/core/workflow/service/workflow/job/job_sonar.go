@@ -0,0 +1,273 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package job
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/util"
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/tool/sonar"
+	"github.com/koderover/zadig/pkg/types"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+type SonarJob struct {
+	job      *commonmodels.Job
+	workflow *commonmodels.WorkflowV4
+	spec     *commonmodels.ZadigSonarJobSpec
+}
+
+func (j *SonarJob) Instantiate() error {
+	j.spec = &commonmodels.ZadigSonarJobSpec{}
+	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	if j.spec.Properties == nil {
+		return fmt.Errorf("properties of sonar job %s is empty", j.job.Name)
+	}
+	if err := util.CheckDefineResourceParam(j.spec.Properties.ResourceRequest, j.spec.Properties.ResReqSpec); err != nil {
+		return err
+	}
+	j.job.Spec = j.spec
+	return nil
+}
+
+func (j *SonarJob) SetPreset() error {
+	j.spec = &commonmodels.ZadigSonarJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	j.job.Spec = j.spec
+	return nil
+}
+
+func (j *SonarJob) GetRepos() ([]*types.Repository, error) {
+	j.spec = &commonmodels.ZadigSonarJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return []*types.Repository{}, err
+	}
+	return j.spec.Repos, nil
+}
+
+func (j *SonarJob) MergeArgs(args *commonmodels.Job) error {
+	if j.job.Name == args.Name && j.job.JobType == args.JobType {
+		j.spec = &commonmodels.ZadigSonarJobSpec{}
+		if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+			return err
+		}
+		argsSpec := &commonmodels.ZadigSonarJobSpec{}
+		if err := commonmodels.IToi(args.Spec, argsSpec); err != nil {
+			return err
+		}
+		j.spec.Repos = mergeRepos(j.spec.Repos, argsSpec.Repos)
+		if argsSpec.Properties != nil {
+			j.spec.Properties.Envs = renderKeyVals(j.spec.Properties.Envs, argsSpec.Properties.Envs)
+		}
+		j.job.Spec = j.spec
+	}
+	return nil
+}
+
+func (j *SonarJob) MergeWebhookRepo(webhookRepo *types.Repository) error {
+	j.spec = &commonmodels.ZadigSonarJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	j.spec.Repos = mergeRepos(j.spec.Repos, []*types.Repository{webhookRepo})
+	j.job.Spec = j.spec
+	return nil
+}
+
+func (j *SonarJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
+	logger := log.SugaredLogger()
+	resp := []*commonmodels.JobTask{}
+
+	j.spec = &commonmodels.ZadigSonarJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return resp, err
+	}
+	j.job.Spec = j.spec
+
+	sonarInfo, err := commonrepo.NewSonarIntegrationColl().GetByID(context.TODO(), j.spec.SonarID)
+	if err != nil {
+		return resp, fmt.Errorf("failed to get sonar integration: %s, error: %s", j.spec.SonarID, err)
+	}
+	basicImage, err := commonrepo.NewBasicImageColl().Find(j.spec.Properties.ImageID)
+	if err != nil {
+		return resp, fmt.Errorf("failed to find base image: %s, error: %v", j.spec.Properties.ImageID, err)
+	}
+	registries, err := commonservice.ListRegistryNamespaces("", true, logger)
+	if err != nil {
+		return resp, err
+	}
+
+	jobTaskSpec := &commonmodels.JobTaskFreestyleSpec{Properties: *j.spec.Properties}
+	jobTask := &commonmodels.JobTask{
+		Name: j.job.Name,
+		Key:  j.job.Name,
+		JobInfo: map[string]string{
+			JobNameKey: j.job.Name,
+		},
+		JobType: string(config.JobZadigSonar),
+		Spec:    jobTaskSpec,
+		Timeout: j.spec.Properties.Timeout,
+	}
+	jobTaskSpec.Properties.Registries = registries
+	jobTaskSpec.Properties.ShareStorageDetails = getShareStorageDetail(j.workflow.ShareStorages, j.spec.Properties.ShareStorageInfo, j.workflow.Name, taskID)
+	jobTaskSpec.Properties.BuildOS = basicImage.Value
+	jobTaskSpec.Properties.CustomEnvs = jobTaskSpec.Properties.Envs
+
+	repos := []*types.Repository{}
+	for _, repo := range j.spec.Repos {
+		if repo.SourceFrom == types.RepoSourceParam {
+			paramRepo, err := findMatchedRepoFromParams(j.workflow.Params, repo.GlobalParamName)
+			if err != nil {
+				logger.Errorf("findMatchedRepoFromParams error: %v", err)
+				continue
+			}
+			repo = paramRepo
+		}
+		repos = append(repos, repo)
+	}
+	repoName, branch := "", ""
+	if len(repos) > 0 {
+		repoName = repos[0].RepoName
+		branch = repos[0].Branch
+	}
+
+	projectKey := sonar.GetSonarProjectKeyFromConfig(j.spec.Parameter)
+	resultAddr, err := sonar.GetSonarAddressWithProjectKey(sonarInfo.ServerAddress, projectKey)
+	if err != nil {
+		logger.Errorf("failed to get sonar address with project key, error: %s", err)
+	}
+	jobTaskSpec.Properties.Envs = append(jobTaskSpec.Properties.Envs, &commonmodels.KeyVal{Key: "SONAR_LINK", Value: resultAddr})
+
+	gitStep := &commonmodels.StepTask{
+		Name:     j.job.Name + "-git",
+		JobName:  jobTask.Name,
+		StepType: config.StepGit,
+		Spec:     step.StepGitSpec{Repos: repos},
+	}
+	jobTaskSpec.Steps = append(jobTaskSpec.Steps, gitStep)
+	jobTaskSpec.Steps = append(jobTaskSpec.Steps, &commonmodels.StepTask{
+		Name:     j.job.Name + "-debug-before",
+		JobName:  jobTask.Name,
+		StepType: config.StepDebugBefore,
+	})
+	if j.spec.PreScript != "" {
+		jobTaskSpec.Steps = append(jobTaskSpec.Steps, &commonmodels.StepTask{
+			Name:     j.job.Name + "-shell",
+			JobName:  jobTask.Name,
+			StepType: config.StepShell,
+			Spec: &step.StepShellSpec{
+				Scripts:     strings.Split(replaceWrapLine(j.spec.PreScript), "\n"),
+				SkipPrepare: true,
+			},
+		})
+	}
+
+	sonarConfig := fmt.Sprintf("sonar.login=%s\nsonar.host.url=%s\n%s", sonarInfo.Token, sonarInfo.ServerAddress, j.spec.Parameter)
+	sonarConfig = strings.ReplaceAll(sonarConfig, "$branch", branch)
+	sonarScript := fmt.Sprintf("set -e\ncd %s\ncat > sonar-project.properties << EOF\n%s\nEOF\nsonar-scanner", repoName, sonarConfig)
+	jobTaskSpec.Steps = append(jobTaskSpec.Steps, &commonmodels.StepTask{
+		Name:     j.job.Name + "-sonar-shell",
+		JobName:  jobTask.Name,
+		StepType: config.StepShell,
+		Spec: &step.StepShellSpec{
+			Scripts:     strings.Split(replaceWrapLine(sonarScript), "\n"),
+			SkipPrepare: true,
+		},
+	})
+
+	if j.spec.CheckQualityGate || j.spec.PRDecoration {
+		checkSpec := &step.StepSonarCheckSpec{
+			Parameter:        j.spec.Parameter,
+			CheckDir:         repoName,
+			SonarToken:       sonarInfo.Token,
+			SonarServer:      sonarInfo.ServerAddress,
+			WaitTimeout:      j.spec.GateTimeout,
+			IgnoreGateStatus: !j.spec.CheckQualityGate,
+			Branch:           branch,
+		}
+		if j.spec.PRDecoration {
+			checkSpec.S3DestDir = path.Join(j.workflow.Name, fmt.Sprint(taskID), jobTask.Name, "sonar")
+			checkSpec.PRDecoration = getSonarPRDecorationInfo(repos)
+		}
+		jobTaskSpec.Steps = append(jobTaskSpec.Steps, &commonmodels.StepTask{
+			Name:     j.job.Name + "-sonar-check",
+			JobName:  jobTask.Name,
+			StepType: config.StepSonarCheck,
+			Spec:     checkSpec,
+		})
+	}
+	publishStep, err := getPublishStep(j.spec.Properties.PublishPaths, jobTask.Name, path.Join(j.workflow.Name, fmt.Sprint(taskID), jobTask.Name, "publish"))
+	if err != nil {
+		return nil, err
+	}
+	if publishStep != nil {
+		jobTaskSpec.Steps = append(jobTaskSpec.Steps, publishStep)
+	}
+	jobTaskSpec.Steps = append(jobTaskSpec.Steps, &commonmodels.StepTask{
+		Name:     j.job.Name + "-debug-after",
+		JobName:  jobTask.Name,
+		StepType: config.StepDebugAfter,
+	})
+
+	jobTaskSpec.Properties.Envs = append(jobTaskSpec.Properties.Envs, getfreestyleJobVariables(jobTaskSpec.Steps, taskID, j.workflow.Project, j.workflow.Name)...)
+	return []*commonmodels.JobTask{jobTask}, nil
+}
+
+// getSonarPRDecorationInfo returns the pull request of the first repo built with one, nil if there is none
+func getSonarPRDecorationInfo(repos []*types.Repository) *step.SonarPRDecorationInfo {
+	for _, repo := range repos {
+		pr := repo.PR
+		if pr == 0 && len(repo.PRs) > 0 {
+			pr = repo.PRs[0]
+		}
+		if pr == 0 {
+			continue
+		}
+		return &step.SonarPRDecorationInfo{
+			CodehostID:    repo.CodehostID,
+			Source:        repo.Source,
+			RepoOwner:     repo.RepoOwner,
+			RepoNamespace: repo.GetRepoNamespace(),
+			RepoName:      repo.RepoName,
+			PR:            pr,
+		}
+	}
+	return nil
+}
+
+func (j *SonarJob) LintJob() error {
+	j.spec = &commonmodels.ZadigSonarJobSpec{}
+	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	if j.spec.SonarID == "" {
+		return fmt.Errorf("sonar integration of job %s is not selected", j.job.Name)
+	}
+	return nil
+}
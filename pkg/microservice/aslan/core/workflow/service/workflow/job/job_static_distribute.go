@@ -0,0 +1,172 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package job
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/pkg/errors"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/tool/cdn"
+)
+
+type StaticDistributeJob struct {
+	job      *commonmodels.Job
+	workflow *commonmodels.WorkflowV4
+	spec     *commonmodels.StaticDistributeJobSpec
+}
+
+func (j *StaticDistributeJob) Instantiate() error {
+	j.spec = &commonmodels.StaticDistributeJobSpec{}
+	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	j.job.Spec = j.spec
+	return nil
+}
+
+func (j *StaticDistributeJob) SetPreset() error {
+	j.spec = &commonmodels.StaticDistributeJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	j.job.Spec = j.spec
+	return nil
+}
+
+func (j *StaticDistributeJob) MergeArgs(args *commonmodels.Job) error {
+	if j.job.Name == args.Name && j.job.JobType == args.JobType {
+		j.spec = &commonmodels.StaticDistributeJobSpec{}
+		if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+			return err
+		}
+		argsSpec := &commonmodels.StaticDistributeJobSpec{}
+		if err := commonmodels.IToi(args.Spec, argsSpec); err != nil {
+			return err
+		}
+		// the services to distribute can be chosen when the workflow is run
+		j.spec.Targets = argsSpec.Targets
+		j.job.Spec = j.spec
+	}
+	return nil
+}
+
+func (j *StaticDistributeJob) ToJobs(taskID int64) ([]*commonmodels.JobTask, error) {
+	j.spec = &commonmodels.StaticDistributeJobSpec{}
+	if err := commonmodels.IToi(j.job.Spec, j.spec); err != nil {
+		return nil, err
+	}
+	j.job.Spec = j.spec
+
+	packages, err := j.getBuildPackageKeys(taskID)
+	if err != nil {
+		return nil, err
+	}
+	jobTaskSpec := &commonmodels.JobTaskStaticDistributeSpec{
+		ObjectStorageID:   j.spec.ObjectStorageID,
+		CacheControl:      j.spec.CacheControl,
+		CacheControlRules: j.spec.CacheControlRules,
+		CDNPurge:          j.spec.CDNPurge,
+	}
+	for _, target := range j.spec.Targets {
+		key, ok := packages[target.ServiceName+"/"+target.ServiceModule]
+		if !ok {
+			return nil, errors.Errorf("service %s/%s not found in build job %s", target.ServiceName, target.ServiceModule, j.spec.JobName)
+		}
+		jobTaskSpec.Targets = append(jobTaskSpec.Targets, &commonmodels.StaticDistributeTaskTarget{
+			ServiceName:   target.ServiceName,
+			ServiceModule: target.ServiceModule,
+			SourceKey:     key,
+			SourcePath:    target.SourcePath,
+			DestPath:      target.DestPath,
+		})
+	}
+
+	jobTask := &commonmodels.JobTask{
+		Name: j.job.Name,
+		Key:  j.job.Name,
+		JobInfo: map[string]string{
+			JobNameKey: j.job.Name,
+		},
+		JobType: string(config.JobStaticDistribute),
+		Spec:    jobTaskSpec,
+	}
+	return []*commonmodels.JobTask{jobTask}, nil
+}
+
+// getBuildPackageKeys returns the object keys of the packages archived by the build job, keyed by service/module
+func (j *StaticDistributeJob) getBuildPackageKeys(taskID int64) (map[string]string, error) {
+	resp := make(map[string]string)
+	for _, stage := range j.workflow.Stages {
+		for _, job := range stage.Jobs {
+			if job.Name != j.spec.JobName || job.JobType != config.JobZadigBuild {
+				continue
+			}
+			buildSpec := &commonmodels.ZadigBuildJobSpec{}
+			if err := commonmodels.IToi(job.Spec, buildSpec); err != nil {
+				return nil, err
+			}
+			for _, build := range buildSpec.ServiceAndBuilds {
+				// same as the destination of the file archive step in the build job
+				buildJobName := jobNameFormat(build.ServiceName + "-" + build.ServiceModule + "-" + job.Name)
+				resp[build.ServiceName+"/"+build.ServiceModule] = path.Join(j.workflow.Name, fmt.Sprint(taskID), buildJobName, "archive", build.Package)
+			}
+			return resp, nil
+		}
+	}
+	return nil, errors.Errorf("build job %s not found", j.spec.JobName)
+}
+
+func (j *StaticDistributeJob) LintJob() error {
+	j.spec = &commonmodels.StaticDistributeJobSpec{}
+	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
+		return err
+	}
+	jobRankMap := getJobRankMap(j.workflow.Stages)
+	buildJobRank, ok := jobRankMap[j.spec.JobName]
+	if !ok || buildJobRank >= jobRankMap[j.job.Name] {
+		return errors.Errorf("can not quote job %s in job %s", j.spec.JobName, j.job.Name)
+	}
+	if _, err := commonrepo.NewS3StorageColl().Find(j.spec.ObjectStorageID); err != nil {
+		return errors.Errorf("failed to find object storage %s of job %s: %v", j.spec.ObjectStorageID, j.job.Name, err)
+	}
+	for _, rule := range j.spec.CacheControlRules {
+		if _, err := path.Match(rule.Pattern, ""); err != nil {
+			return errors.Errorf("invalid cache control pattern %s in job %s", rule.Pattern, j.job.Name)
+		}
+	}
+	if j.spec.CDNPurge == nil || !j.spec.CDNPurge.Enabled {
+		return nil
+	}
+	switch j.spec.CDNPurge.Provider {
+	case cdn.ProviderCloudFront:
+		if j.spec.CDNPurge.DistributionID == "" {
+			return errors.Errorf("cloudfront distribution id of job %s can not be empty", j.job.Name)
+		}
+	case cdn.ProviderWebhook:
+		if j.spec.CDNPurge.WebhookURL == "" {
+			return errors.Errorf("cdn purge webhook of job %s can not be empty", j.job.Name)
+		}
+	default:
+		return errors.Errorf("unsupported cdn provider %s in job %s", j.spec.CDNPurge.Provider, j.job.Name)
+	}
+	return nil
+}
@@ -340,6 +340,8 @@ func (j *TestingJob) toJobtask(testing *commonmodels.TestModule, defaultS3 *comm
 		CustomEnvs:          renderKeyVals(testing.KeyVals, testingInfo.PreTest.Envs),
 		ClusterID:           testingInfo.PreTest.ClusterID,
 		StrategyID:          testingInfo.PreTest.StrategyID,
+		SchedulingHints:     testingInfo.PreTest.SchedulingHints,
+		ServiceContainers:   testingInfo.PreTest.ServiceContainers,
 		BuildOS:             basicImage.Value,
 		ImageFrom:           testingInfo.PreTest.ImageFrom,
 		Registries:          registries,
@@ -395,6 +397,10 @@ func (j *TestingJob) toJobtask(testing *commonmodels.TestModule, defaultS3 *comm
 		StepType: config.StepDebugBefore,
 	}
 	jobTaskSpec.Steps = append(jobTaskSpec.Steps, debugBeforeStep)
+	// init service ready step
+	if len(jobTaskSpec.Properties.ServiceContainers) > 0 {
+		jobTaskSpec.Steps = append(jobTaskSpec.Steps, getServiceReadyStep(testing.Name, jobTask.Name, jobTaskSpec.Properties.ServiceContainers))
+	}
 	// init shell step
 	shellStep := &commonmodels.StepTask{
 		Name:     testing.Name + "-shell",
@@ -405,6 +411,18 @@ func (j *TestingJob) toJobtask(testing *commonmodels.TestModule, defaultS3 *comm
 		},
 	}
 	jobTaskSpec.Steps = append(jobTaskSpec.Steps, shellStep)
+	// init coverage step
+	coverageStep := getCoverageStep(testingInfo.Coverage, testing.Name, serviceName, serviceModule, testing.Repos, jobTask.Name, path.Join(j.workflow.Name, fmt.Sprint(taskID), jobTask.Name, "coverage"))
+	if coverageStep != nil {
+		jobTaskSpec.Steps = append(jobTaskSpec.Steps, coverageStep)
+	}
+	publishStep, err := getPublishStep(j.spec.PublishPaths, jobTask.Name, path.Join(j.workflow.Name, fmt.Sprint(taskID), jobTask.Name, "publish"))
+	if err != nil {
+		return nil, err
+	}
+	if publishStep != nil {
+		jobTaskSpec.Steps = append(jobTaskSpec.Steps, publishStep)
+	}
 	// init debug after step
 	debugAfterStep := &commonmodels.StepTask{
 		Name:     testing.Name + "-debug_after",
@@ -516,8 +534,8 @@ func (j *TestingJob) LintJob() error {
 	return nil
 }
 
-func (j *TestingJob) GetOutPuts(log *zap.SugaredLogger) []string {
-	resp := []string{}
+func (j *TestingJob) GetOutputs(log *zap.SugaredLogger) []*WorkflowVariable {
+	resp := []*WorkflowVariable{}
 	j.spec = &commonmodels.ZadigTestingJobSpec{}
 	if err := commonmodels.IToiYaml(j.job.Spec, j.spec); err != nil {
 		return resp
@@ -533,7 +551,7 @@ func (j *TestingJob) GetOutPuts(log *zap.SugaredLogger) []string {
 	}
 	for _, testInfo := range testingInfos {
 		jobKey := strings.Join([]string{j.job.Name, testInfo.Name}, ".")
-		resp = append(resp, getOutputKey(jobKey, testInfo.Outputs)...)
+		resp = append(resp, newJobOutputs(j.job, jobKey, testInfo.Outputs)...)
 	}
 	return resp
 }
@@ -86,7 +86,8 @@ func CreateCustomWorkflowTask(username string, args *OpenAPICreateCustomWorkflow
 	}
 
 	return CreateWorkflowTaskV4(&CreateWorkflowTaskV4Args{
-		Name: username,
+		Name:           username,
+		IdempotencyKey: args.IdempotencyKey,
 	}, workflow, log)
 }
 
@@ -412,8 +413,8 @@ func getInputUpdater(job *commonmodels.Job, input interface{}) (CustomJobInput,
 	}
 }
 
-func OpenAPIDeleteCustomWorkflowV4(workflowName, projectName string, logger *zap.SugaredLogger) error {
-	return DeleteWorkflowV4(workflowName, logger)
+func OpenAPIDeleteCustomWorkflowV4(workflowName, projectName, userName string, logger *zap.SugaredLogger) error {
+	return DeleteWorkflowV4(workflowName, userName, logger)
 }
 
 func OpenAPIGetCustomWorkflowV4(workflowName, projectName string, logger *zap.SugaredLogger) (*OpenAPIWorkflowV4Detail, error) {
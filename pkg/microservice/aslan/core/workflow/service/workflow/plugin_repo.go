@@ -22,14 +22,21 @@ import (
 	"io/fs"
 	"os"
 	"path"
+	"regexp"
 
+	"github.com/blang/semver/v4"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/zap"
 	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/util/sets"
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/command"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/registry"
+	jobctl "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow/job"
 	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
 	e "github.com/koderover/zadig/pkg/tool/errors"
 )
@@ -39,8 +46,14 @@ const (
 	OfficalRepoName  = "zadig"
 	OfficalRepoURL   = "https://github.com/" + OfficalRepoOwner + "/" + OfficalRepoName
 	OfficalBranch    = "main"
+
+	PluginRepoTypeOCI = "oci"
+	// PluginDescriptorMediaType is the media type of the layer carrying the plugin descriptor in the oci artifacts
+	PluginDescriptorMediaType = "application/vnd.zadig.plugin.descriptor.v1+yaml"
 )
 
+var pluginIDRegex = regexp.MustCompile("^[a-z0-9]([-a-z0-9]*[a-z0-9])?$")
+
 //go:embed plugins
 var officalPluginRepoFiles embed.FS
 
@@ -49,6 +62,9 @@ func UpsertUserPluginRepository(args *commonmodels.PluginRepo, log *zap.SugaredL
 	args.IsOffical = false
 	args.PluginTemplates = []*commonmodels.PluginTemplate{}
 	args.Error = ""
+	if args.RepoType == PluginRepoTypeOCI {
+		return upsertOCIPluginRepository(args, log)
+	}
 
 	codehost, err := systemconfig.New().GetCodeHost(args.CodehostID)
 	if err != nil {
@@ -75,7 +91,7 @@ func UpsertUserPluginRepository(args *commonmodels.PluginRepo, log *zap.SugaredL
 		return fmt.Errorf(errMsg)
 	}
 
-	plugins, err := loadPluginRepoInfos(checkoutPath, args.IsOffical, os.ReadDir, os.ReadFile)
+	plugins, err := loadPluginRepoInfos(checkoutPath, args.IsOffical, os.ReadDir, os.ReadFile, log)
 	if err != nil {
 		errMsg := fmt.Sprintf("load plugin from user user repo error: %s", err)
 		log.Error(errMsg)
@@ -86,6 +102,143 @@ func UpsertUserPluginRepository(args *commonmodels.PluginRepo, log *zap.SugaredL
 	return nil
 }
 
+// upsertOCIPluginRepository loads the descriptors of all the versions of the plugin pushed to the oci repo,
+// the tags which are not semantic versions are skipped
+func upsertOCIPluginRepository(args *commonmodels.PluginRepo, log *zap.SugaredLogger) error {
+	if args.RegistryID == "" || args.OCIRepo == "" {
+		return e.ErrUpsertPluginRepo.AddDesc("registry and oci repo are required")
+	}
+	defer func() {
+		if err := commonrepo.NewPluginRepoColl().Upsert(args); err != nil {
+			log.Errorf("upsert plugin repo error: %v", err)
+		}
+	}()
+
+	plugins, err := loadOCIPluginRepoInfos(args, log)
+	if err != nil {
+		errMsg := fmt.Sprintf("load plugin from oci repo error: %s", err)
+		log.Error(errMsg)
+		args.Error = errMsg
+		return fmt.Errorf(errMsg)
+	}
+	args.PluginTemplates = plugins
+	return nil
+}
+
+func loadOCIPluginRepoInfos(args *commonmodels.PluginRepo, log *zap.SugaredLogger) ([]*commonmodels.PluginTemplate, error) {
+	resp := []*commonmodels.PluginTemplate{}
+	reg, _, err := commonservice.FindRegistryById(args.RegistryID, true, log)
+	if err != nil {
+		return resp, fmt.Errorf("find registry %s error: %v", args.RegistryID, err)
+	}
+	option := registry.ArtifactOption{
+		Endpoint: registry.Endpoint{
+			Addr:      reg.RegAddr,
+			Ak:        reg.AccessKey,
+			Sk:        reg.SecretKey,
+			Namespace: reg.Namespace,
+			Region:    reg.Region,
+		},
+		Repo:        args.OCIRepo,
+		EnableHTTPS: true,
+	}
+	if reg.AdvancedSetting != nil {
+		option.EnableHTTPS = reg.AdvancedSetting.TLSEnabled
+		option.CustomCert = reg.AdvancedSetting.TLSCert
+	}
+	tags, err := registry.ListArtifactTags(option, log)
+	if err != nil {
+		return resp, fmt.Errorf("list tags error: %v", err)
+	}
+	for _, tag := range tags {
+		if _, err := semver.ParseTolerant(tag); err != nil {
+			continue
+		}
+		content, err := registry.GetArtifactLayer(option, tag, PluginDescriptorMediaType, log)
+		if err != nil {
+			log.Warnf("skip plugin %s:%s, get descriptor error: %v", args.OCIRepo, tag, err)
+			continue
+		}
+		pluginTemplate := &commonmodels.PluginTemplate{}
+		if err := yaml.Unmarshal(content, pluginTemplate); err != nil {
+			log.Warnf("skip plugin %s:%s, unmarshal descriptor error: %v", args.OCIRepo, tag, err)
+			continue
+		}
+		if pluginTemplate.ID == "" {
+			pluginTemplate.ID = path.Base(args.OCIRepo)
+		}
+		if pluginTemplate.Version == "" {
+			pluginTemplate.Version = tag
+		}
+		if pluginTemplate.Version != tag {
+			log.Warnf("skip plugin %s:%s, version %s of the descriptor does not match the tag", args.OCIRepo, tag, pluginTemplate.Version)
+			continue
+		}
+		resp = append(resp, pluginTemplate)
+	}
+	return filterPluginTemplates(resp, log), nil
+}
+
+// filterPluginTemplates drops the invalid descriptors of a repo and keeps the first one of a duplicated version,
+// each dropped descriptor is logged
+func filterPluginTemplates(plugins []*commonmodels.PluginTemplate, log *zap.SugaredLogger) []*commonmodels.PluginTemplate {
+	resp := make([]*commonmodels.PluginTemplate, 0, len(plugins))
+	versions := sets.NewString()
+	for _, plugin := range plugins {
+		if err := validatePluginTemplate(plugin); err != nil {
+			log.Warnf("skip plugin %s %s: %v", plugin.ID, plugin.Version, err)
+			continue
+		}
+		key := plugin.ID + "@" + plugin.Version
+		if versions.Has(key) {
+			log.Warnf("skip plugin %s %s, it is duplicated", plugin.ID, plugin.Version)
+			continue
+		}
+		versions.Insert(key)
+		resp = append(resp, plugin)
+	}
+	return resp
+}
+
+func validatePluginTemplate(plugin *commonmodels.PluginTemplate) error {
+	if !pluginIDRegex.MatchString(plugin.ID) {
+		return fmt.Errorf("invalid id %q", plugin.ID)
+	}
+	if plugin.Name == "" || plugin.Image == "" {
+		return fmt.Errorf("name and image are required")
+	}
+	if _, err := semver.ParseTolerant(plugin.Version); err != nil {
+		return fmt.Errorf("invalid version: %v", err)
+	}
+	if plugin.MinZadigVersion != "" {
+		if _, err := semver.ParseTolerant(plugin.MinZadigVersion); err != nil {
+			return fmt.Errorf("invalid min zadig version: %v", err)
+		}
+	}
+	inputs := sets.NewString()
+	for _, input := range plugin.Inputs {
+		if inputs.Has(input.Name) {
+			return fmt.Errorf("input %s is duplicated", input.Name)
+		}
+		inputs.Insert(input.Name)
+		switch input.ParamsType {
+		case "string", "text":
+		case "choice":
+			if len(input.ChoiceOption) == 0 {
+				return fmt.Errorf("choice input %s has no options", input.Name)
+			}
+		default:
+			return fmt.Errorf("input %s has unsupported type %s", input.Name, input.ParamsType)
+		}
+	}
+	for _, output := range plugin.Outputs {
+		if !jobctl.OutputNameRegex.MatchString(output.Name) {
+			return fmt.Errorf("invalid output name %s", output.Name)
+		}
+	}
+	return nil
+}
+
 func UpsertEnterprisePluginRepository(args *commonmodels.PluginRepo, log *zap.SugaredLogger) error {
 	if err := commonrepo.NewPluginRepoColl().Upsert(args); err != nil {
 		errMsg := fmt.Sprintf("upsert enterprise plugin repo error: %v", err)
@@ -103,7 +256,7 @@ func UpdateOfficalPluginRepository(log *zap.SugaredLogger) {
 		RepoURL:   OfficalRepoURL,
 		IsOffical: true,
 	}
-	plugins, err := loadPluginRepoInfos("plugins", officalPluginRepo.IsOffical, officalPluginRepoFiles.ReadDir, officalPluginRepoFiles.ReadFile)
+	plugins, err := loadPluginRepoInfos("plugins", officalPluginRepo.IsOffical, officalPluginRepoFiles.ReadDir, officalPluginRepoFiles.ReadFile, log)
 	if err != nil {
 		log.Errorf("load offical plugin repo error: %v", err)
 		return
@@ -118,7 +271,9 @@ func UpdateOfficalPluginRepository(log *zap.SugaredLogger) {
 type readDir func(name string) ([]fs.DirEntry, error)
 type readFile func(name string) ([]byte, error)
 
-func loadPluginRepoInfos(baseDir string, isOffical bool, readDir readDir, readFile readFile) ([]*commonmodels.PluginTemplate, error) {
+// loadPluginRepoInfos loads the descriptors of the repo, an invalid descriptor is logged and skipped so that it
+// doesn't hide the other plugins of the repo
+func loadPluginRepoInfos(baseDir string, isOffical bool, readDir readDir, readFile readFile, log *zap.SugaredLogger) ([]*commonmodels.PluginTemplate, error) {
 	resp := []*commonmodels.PluginTemplate{}
 	dirs, err := readDir(baseDir)
 	if err != nil {
@@ -147,20 +302,26 @@ func loadPluginRepoInfos(baseDir string, isOffical bool, readDir readDir, readFi
 				if file.Name() != dir.Name()+".yaml" {
 					continue
 				}
-				yamlFilebyte, err := readFile(path.Join(baseDir, dir.Name(), subDir.Name(), file.Name()))
+				filePath := path.Join(baseDir, dir.Name(), subDir.Name(), file.Name())
+				yamlFilebyte, err := readFile(filePath)
 				if err != nil {
-					return resp, fmt.Errorf("read yaml files error: %v", err)
+					log.Warnf("skip plugin %s, read yaml file error: %v", filePath, err)
+					continue
 				}
 				pluginTemplate := &commonmodels.PluginTemplate{}
 				if err := yaml.Unmarshal(yamlFilebyte, pluginTemplate); err != nil {
-					return resp, fmt.Errorf("unmarshal yaml files error: %v", err)
+					log.Warnf("skip plugin %s, unmarshal yaml file error: %v", filePath, err)
+					continue
 				}
 				pluginTemplate.IsOffical = isOffical
+				if pluginTemplate.ID == "" {
+					pluginTemplate.ID = dir.Name()
+				}
 				resp = append(resp, pluginTemplate)
 			}
 		}
 	}
-	return resp, nil
+	return filterPluginTemplates(resp, log), nil
 }
 
 func ListUnofficalPluginRepositories(log *zap.SugaredLogger) ([]*commonmodels.PluginRepo, error) {
@@ -181,16 +342,35 @@ func DeletePluginRepo(id string, log *zap.SugaredLogger) error {
 	return nil
 }
 
-func ListPluginTemplates(log *zap.SugaredLogger) ([]*commonmodels.PluginTemplate, error) {
+// ListPluginTemplates lists all the versions of the plugins, only the ones enabled in the project if it is set
+func ListPluginTemplates(projectName string, log *zap.SugaredLogger) ([]*commonmodels.PluginTemplate, error) {
 	resp := []*commonmodels.PluginTemplate{}
 	repos, err := commonrepo.NewPluginRepoColl().List(nil)
 	if err != nil {
 		log.Errorf("list plugin templates error: %v", err)
 		return resp, e.ErrListPluginRepo.AddDesc(err.Error())
 	}
+	var projectPlugin *commonmodels.ProjectPlugin
+	if projectName != "" {
+		projectPlugin, err = commonrepo.NewProjectPluginColl().Find(projectName)
+		if err != nil && err != mongo.ErrNoDocuments {
+			log.Errorf("find plugins of project %s error: %v", projectName, err)
+			return resp, e.ErrListPluginRepo.AddDesc(err.Error())
+		}
+		if err == mongo.ErrNoDocuments {
+			projectPlugin = nil
+		}
+	}
 	for _, repo := range repos {
 		for _, template := range repo.PluginTemplates {
+			if projectPlugin != nil && !projectPlugin.Enabled(repo.ID.Hex(), template.ID) {
+				continue
+			}
+			template.RepoID = repo.ID.Hex()
 			template.RepoURL = fmt.Sprintf("%s/%s", repo.RepoOwner, repo.RepoName)
+			if repo.RepoType == PluginRepoTypeOCI {
+				template.RepoURL = repo.OCIRepo
+			}
 			for _, input := range template.Inputs {
 				input.Value = input.Default
 			}
@@ -199,3 +379,34 @@ func ListPluginTemplates(log *zap.SugaredLogger) ([]*commonmodels.PluginTemplate
 	}
 	return resp, nil
 }
+
+// GetProjectPlugins returns nil if all the plugins are enabled in the project
+func GetProjectPlugins(projectName string, log *zap.SugaredLogger) (*commonmodels.ProjectPlugin, error) {
+	resp, err := commonrepo.NewProjectPluginColl().Find(projectName)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		log.Errorf("find plugins of project %s error: %v", projectName, err)
+		return nil, e.ErrListPluginRepo.AddDesc(err.Error())
+	}
+	return resp, nil
+}
+
+// UpdateProjectPlugins restricts the plugins of the project to the enabled ones
+func UpdateProjectPlugins(args *commonmodels.ProjectPlugin, log *zap.SugaredLogger) error {
+	if err := commonrepo.NewProjectPluginColl().Upsert(args); err != nil {
+		log.Errorf("update plugins of project %s error: %v", args.ProjectName, err)
+		return e.ErrUpsertPluginRepo.AddDesc(err.Error())
+	}
+	return nil
+}
+
+// EnableAllProjectPlugins lifts the restriction of the plugins of the project
+func EnableAllProjectPlugins(projectName string, log *zap.SugaredLogger) error {
+	if err := commonrepo.NewProjectPluginColl().Delete(projectName); err != nil {
+		log.Errorf("delete plugins of project %s error: %v", projectName, err)
+		return e.ErrUpsertPluginRepo.AddDesc(err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/s3"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	s3tool "github.com/koderover/zadig/pkg/tool/s3"
+)
+
+// PublishedFile is a file or a directory published by the job, Path is relative to the publish root
+type PublishedFile struct {
+	Name         string `json:"name"`
+	Path         string `json:"path"`
+	IsDir        bool   `json:"is_dir"`
+	Size         int64  `json:"size"`
+	ModifiedTime int64  `json:"modified_time"`
+}
+
+// ListWorkflowTaskPublishedFiles lists the files and directories published by the job under the given directory
+func ListWorkflowTaskPublishedFiles(workflowName, jobName, dir string, taskID int64, logger *zap.SugaredLogger) ([]*PublishedFile, error) {
+	storage, client, err := getPublishStorage()
+	if err != nil {
+		logger.Errorf("failed to get the storage of published files: %s", err)
+		return nil, e.ErrInternalError.AddErr(err)
+	}
+
+	dir = cleanPublishPath(dir)
+	root := storage.GetObjectPath(path.Join(workflowName, fmt.Sprint(taskID), jobName, "publish"))
+	prefix := path.Join(root, dir) + "/"
+	dirs, files, err := client.ListDir(storage.Bucket, prefix)
+	if err != nil {
+		logger.Errorf("failed to list published files of %s #%d job %s: %s", workflowName, taskID, jobName, err)
+		return nil, e.ErrInternalError.AddErr(err)
+	}
+
+	resp := make([]*PublishedFile, 0, len(dirs)+len(files))
+	for _, d := range dirs {
+		name := path.Base(strings.TrimSuffix(d, "/"))
+		resp = append(resp, &PublishedFile{
+			Name:  name,
+			Path:  path.Join(dir, name),
+			IsDir: true,
+		})
+	}
+	for _, f := range files {
+		name := path.Base(f.Key)
+		resp = append(resp, &PublishedFile{
+			Name:         name,
+			Path:         path.Join(dir, name),
+			Size:         f.Size,
+			ModifiedTime: f.LastModified.Unix(),
+		})
+	}
+	return resp, nil
+}
+
+// DownloadWorkflowTaskPublishedFile returns the content of the file published by the job, the caller closes the reader
+func DownloadWorkflowTaskPublishedFile(workflowName, jobName, filePath string, taskID int64, logger *zap.SugaredLogger) (io.ReadCloser, int64, error) {
+	filePath = cleanPublishPath(filePath)
+	if filePath == "" {
+		return nil, 0, e.ErrInvalidParam.AddDesc("file path can not be empty")
+	}
+	storage, client, err := getPublishStorage()
+	if err != nil {
+		logger.Errorf("failed to get the storage of published files: %s", err)
+		return nil, 0, e.ErrInternalError.AddErr(err)
+	}
+
+	objectKey := storage.GetObjectPath(path.Join(workflowName, fmt.Sprint(taskID), jobName, "publish", filePath))
+	object, err := client.GetFile(storage.Bucket, objectKey, &s3tool.DownloadOption{RetryNum: 2, IgnoreNotExistError: true})
+	if err != nil {
+		logger.Errorf("failed to get published file %s: %s", objectKey, err)
+		return nil, 0, e.ErrInternalError.AddErr(err)
+	}
+	if object == nil {
+		return nil, 0, e.ErrNotFound.AddDesc(fmt.Sprintf("file %s is not found", filePath))
+	}
+	var size int64
+	if object.ContentLength != nil {
+		size = *object.ContentLength
+	}
+	return object.Body, size, nil
+}
+
+func getPublishStorage() (*s3.S3, *s3tool.Client, error) {
+	storage, err := s3.FindDefaultS3()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find default s3: %s", err)
+	}
+	forcedPathStyle := true
+	if storage.Provider == setting.ProviderSourceAli {
+		forcedPathStyle = false
+	}
+	client, err := s3tool.NewClient(storage.Endpoint, storage.Ak, storage.Sk, storage.Region, storage.Insecure, forcedPathStyle)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create s3 client: %s", err)
+	}
+	return storage, client, nil
+}
+
+// cleanPublishPath makes the path relative to the publish root so that it can not escape from it
+func cleanPublishPath(p string) string {
+	return strings.TrimPrefix(path.Clean("/"+p), "/")
+}
@@ -0,0 +1,400 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/hashicorp/go-multierror"
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	jobctl "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow/job"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+// registryPushEvent is an image pushed to the registry, it is parsed from the webhook payload of each registry
+type registryPushEvent struct {
+	Repository string
+	Tag        string
+	Image      string
+}
+
+type harborPushPayload struct {
+	Type      string `json:"type"`
+	EventData struct {
+		Resources []struct {
+			Tag         string `json:"tag"`
+			ResourceURL string `json:"resource_url"`
+		} `json:"resources"`
+		Repository struct {
+			RepoFullName string `json:"repo_full_name"`
+		} `json:"repository"`
+	} `json:"event_data"`
+}
+
+// dockerPushPayload is the payload of both ACR and Dockerhub, they share the same layout
+type dockerPushPayload struct {
+	PushData struct {
+		Tag string `json:"tag"`
+	} `json:"push_data"`
+	Repository struct {
+		Region       string `json:"region"`
+		RepoFullName string `json:"repo_full_name"`
+		RepoName     string `json:"repo_name"`
+	} `json:"repository"`
+}
+
+// CreateRegistryHookForWorkflowV4 returns the created hook, it is the only response carrying the secret so that
+// it can be configured in the registry, the other APIs return the masked secret.
+func CreateRegistryHookForWorkflowV4(workflowName string, arg *commonmodels.RegistryHook, logger *zap.SugaredLogger) (*commonmodels.RegistryHook, error) {
+	if err := jobctl.InstantiateWorkflow(arg.WorkflowArg); err != nil {
+		logger.Errorf("instantiate hook args error: %s", err)
+		return nil, e.ErrCreateRegistryHook.AddErr(err)
+	}
+	if err := validateRegistryHook(arg); err != nil {
+		return nil, e.ErrCreateRegistryHook.AddErr(err)
+	}
+
+	workflow, err := commonrepo.NewWorkflowV4Coll().Find(workflowName)
+	if err != nil {
+		logger.Errorf("Failed to find WorkflowV4: %s, the error is: %v", workflowName, err)
+		return nil, e.ErrCreateRegistryHook.AddErr(err)
+	}
+	for _, hook := range workflow.RegistryHookCtls {
+		if hook.Name == arg.Name {
+			errMsg := fmt.Sprintf("registry hook %s already exists", arg.Name)
+			logger.Error(errMsg)
+			return nil, e.ErrCreateRegistryHook.AddDesc(errMsg)
+		}
+	}
+	if err := validateHookNames([]string{arg.Name}); err != nil {
+		logger.Errorf(err.Error())
+		return nil, e.ErrCreateRegistryHook.AddErr(err)
+	}
+	if arg.Secret == "" || arg.Secret == setting.MaskValue {
+		if arg.Secret, err = generateRegistryHookSecret(); err != nil {
+			return nil, e.ErrCreateRegistryHook.AddErr(err)
+		}
+	}
+	workflow.RegistryHookCtls = append(workflow.RegistryHookCtls, arg)
+	if err := commonrepo.NewWorkflowV4Coll().Update(workflow.ID.Hex(), workflow); err != nil {
+		errMsg := fmt.Sprintf("failed to create registry hook for workflow %s, the error is: %v", workflowName, err)
+		logger.Error(errMsg)
+		return nil, e.ErrCreateRegistryHook.AddDesc(errMsg)
+	}
+	return arg, nil
+}
+
+func GetRegistryHookForWorkflowV4Preset(workflowName, hookName string, logger *zap.SugaredLogger) (*commonmodels.RegistryHook, error) {
+	workflow, err := commonrepo.NewWorkflowV4Coll().Find(workflowName)
+	if err != nil {
+		logger.Errorf("Failed to find WorkflowV4: %s, the error is: %v", workflowName, err)
+		return nil, e.ErrGetRegistryHook.AddErr(err)
+	}
+	registryHook := &commonmodels.RegistryHook{}
+	for _, hook := range workflow.RegistryHookCtls {
+		if hook.Name == hookName {
+			registryHook = maskRegistryHookSecret(hook)
+		}
+	}
+	if err := jobctl.MergeArgs(workflow, registryHook.WorkflowArg); err != nil {
+		errMsg := fmt.Sprintf("merge workflow args error: %v", err)
+		logger.Error(errMsg)
+		return nil, e.ErrGetRegistryHook.AddDesc(errMsg)
+	}
+	registryHook.WorkflowArg = workflow
+	clearWorkflowV4Triggers(registryHook.WorkflowArg)
+	return registryHook, nil
+}
+
+func ListRegistryHookForWorkflowV4(workflowName string, logger *zap.SugaredLogger) ([]*commonmodels.RegistryHook, error) {
+	workflow, err := commonrepo.NewWorkflowV4Coll().Find(workflowName)
+	if err != nil {
+		logger.Errorf("Failed to find WorkflowV4: %s, the error is: %v", workflowName, err)
+		return nil, e.ErrListRegistryHook.AddErr(err)
+	}
+	resp := make([]*commonmodels.RegistryHook, 0, len(workflow.RegistryHookCtls))
+	for _, hook := range workflow.RegistryHookCtls {
+		resp = append(resp, maskRegistryHookSecret(hook))
+	}
+	return resp, nil
+}
+
+// MaskRegistryHookSecrets masks the secrets of the registry hooks of a workflow returned by the APIs
+func MaskRegistryHookSecrets(workflow *commonmodels.WorkflowV4) {
+	for i, hook := range workflow.RegistryHookCtls {
+		workflow.RegistryHookCtls[i] = maskRegistryHookSecret(hook)
+	}
+}
+
+// maskRegistryHookSecret returns a copy of the hook with the secret masked
+func maskRegistryHookSecret(hook *commonmodels.RegistryHook) *commonmodels.RegistryHook {
+	masked := *hook
+	if masked.Secret != "" {
+		masked.Secret = setting.MaskValue
+	}
+	return &masked
+}
+
+func UpdateRegistryHookForWorkflowV4(workflowName string, arg *commonmodels.RegistryHook, logger *zap.SugaredLogger) error {
+	if err := jobctl.InstantiateWorkflow(arg.WorkflowArg); err != nil {
+		logger.Errorf("instantiate hook args error: %s", err)
+		return e.ErrUpdateRegistryHook.AddErr(err)
+	}
+	if err := validateRegistryHook(arg); err != nil {
+		return e.ErrUpdateRegistryHook.AddErr(err)
+	}
+
+	workflow, err := commonrepo.NewWorkflowV4Coll().Find(workflowName)
+	if err != nil {
+		logger.Errorf("Failed to find WorkflowV4: %s, the error is: %v", workflowName, err)
+		return e.ErrUpdateRegistryHook.AddErr(err)
+	}
+	updated := false
+	for i, hook := range workflow.RegistryHookCtls {
+		if hook.Name == arg.Name {
+			// the secret is kept if it is not changed, the hooks created without a secret get a new one
+			if arg.Secret == "" || arg.Secret == setting.MaskValue {
+				arg.Secret = hook.Secret
+			}
+			if arg.Secret == "" {
+				if arg.Secret, err = generateRegistryHookSecret(); err != nil {
+					return e.ErrUpdateRegistryHook.AddErr(err)
+				}
+			}
+			workflow.RegistryHookCtls[i] = arg
+			updated = true
+		}
+	}
+	if !updated {
+		errMsg := fmt.Sprintf("failed to find registry hook %s", arg.Name)
+		logger.Error(errMsg)
+		return e.ErrUpdateRegistryHook.AddDesc(errMsg)
+	}
+	if err := commonrepo.NewWorkflowV4Coll().Update(workflow.ID.Hex(), workflow); err != nil {
+		errMsg := fmt.Sprintf("failed to update registry hook for workflow %s, the error is: %v", workflowName, err)
+		logger.Error(errMsg)
+		return e.ErrUpdateRegistryHook.AddDesc(errMsg)
+	}
+	return nil
+}
+
+func DeleteRegistryHookForWorkflowV4(workflowName, hookName string, logger *zap.SugaredLogger) error {
+	workflow, err := commonrepo.NewWorkflowV4Coll().Find(workflowName)
+	if err != nil {
+		logger.Errorf("Failed to find WorkflowV4: %s, the error is: %v", workflowName, err)
+		return e.ErrDeleteRegistryHook.AddErr(err)
+	}
+	var list []*commonmodels.RegistryHook
+	for _, ctl := range workflow.RegistryHookCtls {
+		if ctl.Name == hookName {
+			continue
+		}
+		list = append(list, ctl)
+	}
+	if len(list) == len(workflow.RegistryHookCtls) {
+		errMsg := fmt.Sprintf("registry hook %s not found", hookName)
+		logger.Error(errMsg)
+		return e.ErrDeleteRegistryHook.AddDesc(errMsg)
+	}
+	workflow.RegistryHookCtls = list
+	if err := commonrepo.NewWorkflowV4Coll().Update(workflow.ID.Hex(), workflow); err != nil {
+		errMsg := fmt.Sprintf("failed to delete registry hook for workflow %s, the error is: %v", workflowName, err)
+		logger.Error(errMsg)
+		return e.ErrDeleteRegistryHook.AddDesc(errMsg)
+	}
+	return nil
+}
+
+// RegistryHookEventHandler creates a workflow task for each pushed image matching the repository and the tag
+// pattern of the hook, the image is mapped into the workflow params. The request is rejected unless the token
+// matches the secret of the hook, a failed event doesn't stop the others.
+func RegistryHookEventHandler(workflowName, hookName, token string, payload []byte, logger *zap.SugaredLogger) error {
+	hook, err := findRegistryHook(workflowName, hookName)
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	if !verifyRegistryHookToken(hook, token) {
+		logger.Warnf("HandleRegistryHookEvent: invalid token for workflow-%s hook-%s", workflowName, hookName)
+		return e.ErrUnauthorized.AddDesc(fmt.Sprintf("invalid token of registry hook %s", hookName))
+	}
+	if !hook.Enabled {
+		return fmt.Errorf("registry hook %s is not enabled", hookName)
+	}
+	events, err := parseRegistryPushEvents(hook.Source, payload)
+	if err != nil {
+		logger.Errorf("failed to parse %s push event: %s", hook.Source, err)
+		return e.ErrInvalidParam.AddErr(err)
+	}
+	tagRegex, err := regexp.Compile(hook.TagPattern)
+	if err != nil {
+		return fmt.Errorf("invalid tag pattern %s: %s", hook.TagPattern, err)
+	}
+
+	var errs *multierror.Error
+	for _, event := range events {
+		if hook.Repository != "" && hook.Repository != event.Repository {
+			continue
+		}
+		if !tagRegex.MatchString(event.Tag) {
+			continue
+		}
+		// reload the hook for every event, the params of the workflow args are changed by the previous event
+		eventHook, err := findRegistryHook(workflowName, hookName)
+		if err != nil {
+			errs = multierror.Append(errs, err)
+			continue
+		}
+		setRegistryHookParams(eventHook, event)
+		if _, err := CreateWorkflowTaskV4ByBuildInTrigger(setting.RegistryHookTaskCreator, eventHook.WorkflowArg, logger); err != nil {
+			errMsg := fmt.Errorf("HandleRegistryHookEvent: failed to create workflow task for image %s: %s", event.Image, err)
+			logger.Error(errMsg)
+			errs = multierror.Append(errs, errMsg)
+			continue
+		}
+		logger.Infof("HandleRegistryHookEvent: workflow-%s hook-%s image-%s create workflow task success", workflowName, hookName, event.Image)
+	}
+	return errs.ErrorOrNil()
+}
+
+// verifyRegistryHookToken compares the token in constant time, the hooks created before the secret was
+// introduced are rejected until they are updated
+func verifyRegistryHookToken(hook *commonmodels.RegistryHook, token string) bool {
+	if hook.Secret == "" || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(hook.Secret), []byte(token)) == 1
+}
+
+func generateRegistryHookSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %s", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func findRegistryHook(workflowName, hookName string) (*commonmodels.RegistryHook, error) {
+	workflow, err := commonrepo.NewWorkflowV4Coll().Find(workflowName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find WorkflowV4: %s, the error is: %v", workflowName, err)
+	}
+	for _, hook := range workflow.RegistryHookCtls {
+		if hook.Name == hookName {
+			return hook, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to find registry hook %s", hookName)
+}
+
+func validateRegistryHook(hook *commonmodels.RegistryHook) error {
+	switch hook.Source {
+	case setting.RegistryHookSourceHarbor, setting.RegistryHookSourceACR, setting.RegistryHookSourceDockerhub:
+	default:
+		return fmt.Errorf("unsupported registry source: %s", hook.Source)
+	}
+	if _, err := regexp.Compile(hook.TagPattern); err != nil {
+		return fmt.Errorf("invalid tag pattern %s: %s", hook.TagPattern, err)
+	}
+	for _, name := range []string{hook.RepoParam, hook.TagParam, hook.ImageParam} {
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, param := range hook.WorkflowArg.Params {
+			if param.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("param %s not found in workflow %s", name, hook.WorkflowArg.Name)
+		}
+	}
+	return nil
+}
+
+func setRegistryHookParams(hook *commonmodels.RegistryHook, event *registryPushEvent) {
+	values := map[string]string{}
+	if hook.RepoParam != "" {
+		values[hook.RepoParam] = event.Repository
+	}
+	if hook.TagParam != "" {
+		values[hook.TagParam] = event.Tag
+	}
+	if hook.ImageParam != "" {
+		values[hook.ImageParam] = event.Image
+	}
+	for _, param := range hook.WorkflowArg.Params {
+		if value, ok := values[param.Name]; ok {
+			param.Value = value
+		}
+	}
+}
+
+func parseRegistryPushEvents(source string, payload []byte) ([]*registryPushEvent, error) {
+	resp := make([]*registryPushEvent, 0)
+	switch source {
+	case setting.RegistryHookSourceHarbor:
+		harborPayload := &harborPushPayload{}
+		if err := json.Unmarshal(payload, harborPayload); err != nil {
+			return nil, err
+		}
+		if harborPayload.Type != "PUSH_ARTIFACT" && harborPayload.Type != "pushImage" {
+			return resp, nil
+		}
+		for _, resource := range harborPayload.EventData.Resources {
+			if resource.Tag == "" {
+				continue
+			}
+			resp = append(resp, &registryPushEvent{
+				Repository: harborPayload.EventData.Repository.RepoFullName,
+				Tag:        resource.Tag,
+				Image:      resource.ResourceURL,
+			})
+		}
+	case setting.RegistryHookSourceACR, setting.RegistryHookSourceDockerhub:
+		dockerPayload := &dockerPushPayload{}
+		if err := json.Unmarshal(payload, dockerPayload); err != nil {
+			return nil, err
+		}
+		if dockerPayload.PushData.Tag == "" {
+			return resp, nil
+		}
+		event := &registryPushEvent{Tag: dockerPayload.PushData.Tag}
+		if source == setting.RegistryHookSourceACR {
+			event.Repository = dockerPayload.Repository.RepoFullName
+			event.Image = fmt.Sprintf("registry.%s.aliyuncs.com/%s:%s", dockerPayload.Repository.Region, event.Repository, event.Tag)
+		} else {
+			event.Repository = dockerPayload.Repository.RepoName
+			event.Image = fmt.Sprintf("docker.io/%s:%s", event.Repository, event.Tag)
+		}
+		resp = append(resp, event)
+	default:
+		return nil, fmt.Errorf("unsupported registry source: %s", source)
+	}
+	return resp, nil
+}
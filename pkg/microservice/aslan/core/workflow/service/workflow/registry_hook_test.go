@@ -0,0 +1,47 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+)
+
+var _ = Describe("Testing registry hook", func() {
+
+	DescribeTable("verifyRegistryHookToken",
+		func(secret, token string, expected bool) {
+			Expect(verifyRegistryHookToken(&commonmodels.RegistryHook{Secret: secret}, token)).To(Equal(expected))
+		},
+		Entry("matched token", "s3cret", "s3cret", true),
+		Entry("wrong token", "s3cret", "s3cre", false),
+		Entry("empty token", "s3cret", "", false),
+		Entry("hook without a secret", "", "", false),
+		Entry("hook without a secret and any token", "", "s3cret", false),
+	)
+
+	It("should generate different secrets", func() {
+		first, err := generateRegistryHookSecret()
+		Expect(err).ShouldNot(HaveOccurred())
+		second, err := generateRegistryHookSecret()
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(first).To(HaveLen(48))
+		Expect(first).NotTo(Equal(second))
+	})
+})
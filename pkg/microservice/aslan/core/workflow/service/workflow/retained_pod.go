@@ -0,0 +1,55 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller/jobcontroller"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func ListRetainedJobPods(projectName, workflowName string, logger *zap.SugaredLogger) ([]*commonmodels.RetainedJobPod, error) {
+	pods, err := commonrepo.NewRetainedJobPodColl().List(&commonrepo.RetainedJobPodListOption{
+		ProjectName:  projectName,
+		WorkflowName: workflowName,
+	})
+	if err != nil {
+		logger.Errorf("failed to list retained job pods of project %s: %s", projectName, err)
+		return nil, e.ErrListWorkflow.AddErr(err)
+	}
+	return pods, nil
+}
+
+func CleanRetainedJobPod(projectName, id string, logger *zap.SugaredLogger) error {
+	pod, err := commonrepo.NewRetainedJobPodColl().GetByID(id)
+	if err != nil {
+		return e.ErrInvalidParam.AddDesc(fmt.Sprintf("retained pod %s not found", id))
+	}
+	if pod.ProjectName != projectName {
+		return e.ErrInvalidParam.AddDesc(fmt.Sprintf("retained pod %s does not belong to project %s", id, projectName))
+	}
+	if err := jobcontroller.CleanRetainedJobPod(pod); err != nil {
+		logger.Errorf("failed to clean retained pod %s: %s", pod.PodName, err)
+		return e.ErrInternalError.AddErr(err)
+	}
+	return nil
+}
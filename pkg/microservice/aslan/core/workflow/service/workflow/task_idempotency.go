@@ -0,0 +1,107 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+// the task created with the same idempotency key in the window is returned instead of creating a new one
+const taskIdempotencyWindow = 24 * time.Hour
+
+// createWorkflowTaskV4Idempotently creates the task once for the idempotency key of the args
+func createWorkflowTaskV4Idempotently(args *CreateWorkflowTaskV4Args, workflow *commonmodels.WorkflowV4, log *zap.SugaredLogger) (*CreateTaskV4Resp, error) {
+	reservation := &commonmodels.TaskIdempotency{
+		WorkflowName: workflow.Name,
+		Key:          args.IdempotencyKey,
+		CreateTime:   time.Now().Unix(),
+	}
+	existed, err := reserveTaskIdempotencyKey(reservation)
+	if err != nil {
+		log.Errorf("failed to reserve idempotency key %s of workflow %s, err: %s", args.IdempotencyKey, workflow.Name, err)
+		return nil, e.ErrCreateTask.AddErr(err)
+	}
+	if existed != nil {
+		if existed.TaskID == 0 {
+			return nil, e.ErrCreateTask.AddDesc("the task with the same idempotency key is being created")
+		}
+		log.Infof("task %d of workflow %s has been created with idempotency key %s", existed.TaskID, workflow.Name, args.IdempotencyKey)
+		return &CreateTaskV4Resp{
+			ProjectName:  workflow.Project,
+			WorkflowName: workflow.Name,
+			TaskID:       existed.TaskID,
+			Duplicated:   true,
+		}, nil
+	}
+
+	resp, err := createWorkflowTaskV4(args, workflow, log)
+	if err != nil {
+		// the key can be retried after the creation failed
+		if err := commonrepo.NewTaskIdempotencyColl().Delete(reservation.ID); err != nil {
+			log.Errorf("failed to release idempotency key %s of workflow %s, err: %s", args.IdempotencyKey, workflow.Name, err)
+		}
+		return resp, err
+	}
+	if err := commonrepo.NewTaskIdempotencyColl().SetTaskID(reservation.ID, resp.TaskID); err != nil {
+		log.Errorf("failed to save task of idempotency key %s of workflow %s, err: %s", args.IdempotencyKey, workflow.Name, err)
+	}
+	return resp, nil
+}
+
+// reserveTaskIdempotencyKey reserves the key, the reservation of the key in the window is returned if it exists
+func reserveTaskIdempotencyKey(reservation *commonmodels.TaskIdempotency) (*commonmodels.TaskIdempotency, error) {
+	coll := commonrepo.NewTaskIdempotencyColl()
+	for i := 0; i < 2; i++ {
+		reserved, err := coll.Reserve(reservation)
+		if err != nil {
+			return nil, err
+		}
+		if reserved {
+			return nil, nil
+		}
+		existed, err := coll.Find(reservation.WorkflowName, reservation.Key)
+		if err == mongo.ErrNoDocuments {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if existed.CreateTime >= reservation.CreateTime-int64(taskIdempotencyWindow.Seconds()) {
+			return existed, nil
+		}
+		// the reservation is out of the window and is not cleaned yet
+		if err := coll.Delete(existed.ID); err != nil {
+			return nil, err
+		}
+	}
+	return nil, e.ErrCreateTask.AddDesc("failed to reserve the idempotency key")
+}
+
+// CleanExpiredTaskIdempotencyKeys removes the idempotency keys out of the window
+func CleanExpiredTaskIdempotencyKeys() {
+	if err := commonrepo.NewTaskIdempotencyColl().DeleteBefore(time.Now().Add(-taskIdempotencyWindow).Unix()); err != nil {
+		log.Errorf("failed to clean expired task idempotency keys, err: %s", err)
+	}
+}
@@ -0,0 +1,258 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models/msg_queue"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+type TriggerPauseResult struct {
+	WorkflowName string `json:"workflow_name"`
+	Skipped      bool   `json:"skipped"`
+	Error        string `json:"error,omitempty"`
+}
+
+// PauseWorkflowTriggers disables all the webhooks, hooks and cronjobs of the workflow and records the enabled ones,
+// ResumeWorkflowTriggers enables them again
+func PauseWorkflowTriggers(workflowName, userName, reason string, logger *zap.SugaredLogger) error {
+	workflow, err := commonrepo.NewWorkflowV4Coll().Find(workflowName)
+	if err != nil {
+		logger.Errorf("Failed to find WorkflowV4: %s, the error is: %v", workflowName, err)
+		return e.ErrFindWorkflow.AddErr(err)
+	}
+	if workflow.TriggerPause != nil {
+		return e.ErrInvalidParam.AddDesc(fmt.Sprintf("triggers of workflow %s are already paused", workflowName))
+	}
+	return pauseWorkflowTriggers(workflow, userName, reason, logger)
+}
+
+func pauseWorkflowTriggers(workflow *commonmodels.WorkflowV4, userName, reason string, logger *zap.SugaredLogger) error {
+	pause := &commonmodels.TriggerPause{
+		PausedBy:  userName,
+		PauseTime: time.Now().Unix(),
+		Reason:    reason,
+	}
+	for _, hook := range workflow.HookCtls {
+		if hook.Enabled {
+			pause.Webhooks = append(pause.Webhooks, hook.Name)
+			hook.Enabled = false
+		}
+	}
+	for _, hook := range workflow.JiraHookCtls {
+		if hook.Enabled {
+			pause.JiraHooks = append(pause.JiraHooks, hook.Name)
+			hook.Enabled = false
+		}
+	}
+	for _, hook := range workflow.MeegoHookCtls {
+		if hook.Enabled {
+			pause.MeegoHooks = append(pause.MeegoHooks, hook.Name)
+			hook.Enabled = false
+		}
+	}
+	for _, hook := range workflow.GeneralHookCtls {
+		if hook.Enabled {
+			pause.GeneralHooks = append(pause.GeneralHooks, hook.Name)
+			hook.Enabled = false
+		}
+	}
+	for _, hook := range workflow.RegistryHookCtls {
+		if hook.Enabled {
+			pause.RegistryHooks = append(pause.RegistryHooks, hook.Name)
+			hook.Enabled = false
+		}
+	}
+
+	crons, err := listWorkflowV4Crons(workflow.Name)
+	if err != nil {
+		logger.Errorf("Failed to list WorkflowV4: %s cron jobs, the error is: %v", workflow.Name, err)
+		return e.ErrUpsertWorkflow.AddErr(err)
+	}
+	// the workflow is saved before the cronjobs, so that a failure in between can be resumed
+	for _, cron := range crons {
+		if cron.Enabled {
+			pause.CronIDs = append(pause.CronIDs, cron.ID.Hex())
+		}
+	}
+	workflow.TriggerPause = pause
+	if err := commonrepo.NewWorkflowV4Coll().Update(workflow.ID.Hex(), workflow); err != nil {
+		logger.Errorf("Failed to pause triggers of workflow %s, the error is: %v", workflow.Name, err)
+		return e.ErrUpsertWorkflow.AddErr(err)
+	}
+	for _, cron := range crons {
+		if !cron.Enabled {
+			continue
+		}
+		cron.Enabled = false
+		if err := setWorkflowV4CronEnabled(cron); err != nil {
+			logger.Errorf("Failed to disable cron job %s of workflow %s, the error is: %v", cron.ID.Hex(), workflow.Name, err)
+			return e.ErrUpsertCronjob.AddErr(err)
+		}
+	}
+	return nil
+}
+
+func ResumeWorkflowTriggers(workflowName string, logger *zap.SugaredLogger) error {
+	workflow, err := commonrepo.NewWorkflowV4Coll().Find(workflowName)
+	if err != nil {
+		logger.Errorf("Failed to find WorkflowV4: %s, the error is: %v", workflowName, err)
+		return e.ErrFindWorkflow.AddErr(err)
+	}
+	if workflow.TriggerPause == nil {
+		return e.ErrInvalidParam.AddDesc(fmt.Sprintf("triggers of workflow %s are not paused", workflowName))
+	}
+	return resumeWorkflowTriggers(workflow, logger)
+}
+
+// resumeWorkflowTriggers only enables the triggers recorded on pause, the ones deleted since then are ignored
+func resumeWorkflowTriggers(workflow *commonmodels.WorkflowV4, logger *zap.SugaredLogger) error {
+	pause := workflow.TriggerPause
+	crons, err := listWorkflowV4Crons(workflow.Name)
+	if err != nil {
+		logger.Errorf("Failed to list WorkflowV4: %s cron jobs, the error is: %v", workflow.Name, err)
+		return e.ErrUpsertWorkflow.AddErr(err)
+	}
+	cronIDs := sets.NewString(pause.CronIDs...)
+	for _, cron := range crons {
+		if cron.Enabled || !cronIDs.Has(cron.ID.Hex()) {
+			continue
+		}
+		cron.Enabled = true
+		if err := setWorkflowV4CronEnabled(cron); err != nil {
+			logger.Errorf("Failed to enable cron job %s of workflow %s, the error is: %v", cron.ID.Hex(), workflow.Name, err)
+			return e.ErrUpsertCronjob.AddErr(err)
+		}
+	}
+
+	names := sets.NewString(pause.Webhooks...)
+	for _, hook := range workflow.HookCtls {
+		if names.Has(hook.Name) {
+			hook.Enabled = true
+		}
+	}
+	names = sets.NewString(pause.JiraHooks...)
+	for _, hook := range workflow.JiraHookCtls {
+		if names.Has(hook.Name) {
+			hook.Enabled = true
+		}
+	}
+	names = sets.NewString(pause.MeegoHooks...)
+	for _, hook := range workflow.MeegoHookCtls {
+		if names.Has(hook.Name) {
+			hook.Enabled = true
+		}
+	}
+	names = sets.NewString(pause.GeneralHooks...)
+	for _, hook := range workflow.GeneralHookCtls {
+		if names.Has(hook.Name) {
+			hook.Enabled = true
+		}
+	}
+	names = sets.NewString(pause.RegistryHooks...)
+	for _, hook := range workflow.RegistryHookCtls {
+		if names.Has(hook.Name) {
+			hook.Enabled = true
+		}
+	}
+	workflow.TriggerPause = nil
+	if err := commonrepo.NewWorkflowV4Coll().Update(workflow.ID.Hex(), workflow); err != nil {
+		logger.Errorf("Failed to resume triggers of workflow %s, the error is: %v", workflow.Name, err)
+		return e.ErrUpsertWorkflow.AddErr(err)
+	}
+	return nil
+}
+
+// PauseProjectTriggers pauses the triggers of all the workflows of the project, the paused ones are skipped
+func PauseProjectTriggers(projectName, userName, reason string, logger *zap.SugaredLogger) ([]*TriggerPauseResult, error) {
+	workflows, _, err := commonrepo.NewWorkflowV4Coll().List(&commonrepo.ListWorkflowV4Option{ProjectName: projectName}, 0, 0)
+	if err != nil {
+		logger.Errorf("Failed to list workflows of project %s, the error is: %v", projectName, err)
+		return nil, e.ErrListWorkflow.AddErr(err)
+	}
+	resp := make([]*TriggerPauseResult, 0, len(workflows))
+	for _, workflow := range workflows {
+		result := &TriggerPauseResult{WorkflowName: workflow.Name}
+		if workflow.TriggerPause != nil {
+			result.Skipped = true
+		} else if err := pauseWorkflowTriggers(workflow, userName, reason, logger); err != nil {
+			result.Error = err.Error()
+		}
+		resp = append(resp, result)
+	}
+	return resp, nil
+}
+
+// ResumeProjectTriggers resumes the triggers of all the paused workflows of the project
+func ResumeProjectTriggers(projectName string, logger *zap.SugaredLogger) ([]*TriggerPauseResult, error) {
+	workflows, _, err := commonrepo.NewWorkflowV4Coll().List(&commonrepo.ListWorkflowV4Option{ProjectName: projectName}, 0, 0)
+	if err != nil {
+		logger.Errorf("Failed to list workflows of project %s, the error is: %v", projectName, err)
+		return nil, e.ErrListWorkflow.AddErr(err)
+	}
+	resp := make([]*TriggerPauseResult, 0, len(workflows))
+	for _, workflow := range workflows {
+		result := &TriggerPauseResult{WorkflowName: workflow.Name}
+		if workflow.TriggerPause == nil {
+			result.Skipped = true
+		} else if err := resumeWorkflowTriggers(workflow, logger); err != nil {
+			result.Error = err.Error()
+		}
+		resp = append(resp, result)
+	}
+	return resp, nil
+}
+
+func listWorkflowV4Crons(workflowName string) ([]*commonmodels.Cronjob, error) {
+	return commonrepo.NewCronjobColl().List(&commonrepo.ListCronjobParam{
+		ParentName: workflowName,
+		ParentType: config.WorkflowV4Cronjob,
+	})
+}
+
+func setWorkflowV4CronEnabled(cron *commonmodels.Cronjob) error {
+	if err := commonrepo.NewCronjobColl().Update(cron); err != nil {
+		return err
+	}
+	payload := &commonservice.CronjobPayload{
+		Name:    cron.Name,
+		JobType: config.WorkflowV4Cronjob,
+		Action:  setting.TypeEnableCronjob,
+	}
+	if !cron.Enabled {
+		payload.DeleteList = []string{cron.ID.Hex()}
+	} else {
+		payload.JobList = []*commonmodels.Schedule{cronJobToSchedule(cron)}
+	}
+	pl, _ := json.Marshal(payload)
+	return commonrepo.NewMsgQueueCommonColl().Create(&msg_queue.MsgQueueCommon{
+		Payload:   string(pl),
+		QueueType: setting.TopicCronjob,
+	})
+}
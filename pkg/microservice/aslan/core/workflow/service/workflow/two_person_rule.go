@@ -0,0 +1,127 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/shared/client/user"
+)
+
+// checkTwoPersonRule rejects the approval of a production deploy stage by the creator of the task or the author
+// of the commit which triggers the task, when the two-person rule of the promotion policy is enabled.
+// It is only checked for the native approvals, the approvers of the lark and dingtalk approvals are the users of
+// the im apps which can not be matched to the zadig users, so the rule is not enforced for them.
+func checkTwoPersonRule(workflowName, stageName, userName, userID string, taskID int64) error {
+	task, err := commonrepo.NewWorkflowTaskV4Store().Find(workflowName, taskID)
+	if err != nil {
+		return fmt.Errorf("failed to find task %s-%d: %v", workflowName, taskID, err)
+	}
+	policy, err := commonservice.GetPromotionPolicy(task.ProjectName)
+	if err != nil {
+		return err
+	}
+	if policy == nil || !policy.TwoPersonRule {
+		return nil
+	}
+
+	var stage *commonmodels.StageTask
+	for _, s := range task.Stages {
+		if s.Name == stageName {
+			stage = s
+			break
+		}
+	}
+	if stage == nil {
+		return nil
+	}
+	production, err := isProductionDeployStage(task.ProjectName, stage)
+	if err != nil || !production {
+		return err
+	}
+
+	if userName != "" && userName == task.TaskCreator {
+		return fmt.Errorf("two-person rule of production deploy stage %s: the approver %s is the creator of the task", stageName, userName)
+	}
+	if task.WorkflowArgs == nil || task.WorkflowArgs.HookPayload == nil || len(task.WorkflowArgs.HookPayload.CommitAuthors) == 0 {
+		return nil
+	}
+	identities := []string{userName}
+	if userID != "" {
+		info, err := user.New().GetUserByID(userID)
+		if err != nil {
+			return fmt.Errorf("failed to find the approver %s: %v", userName, err)
+		}
+		identities = append(identities, info.Name, info.Account, info.Email)
+	}
+	for _, author := range task.WorkflowArgs.HookPayload.CommitAuthors {
+		for _, identity := range identities {
+			if identity != "" && strings.EqualFold(identity, author) {
+				return fmt.Errorf("two-person rule of production deploy stage %s: the approver %s is the author of the commit %s", stageName, userName, task.WorkflowArgs.HookPayload.CommitID)
+			}
+		}
+	}
+	return nil
+}
+
+// isProductionDeployStage returns true if any job of the stage deploys to a production environment
+func isProductionDeployStage(projectName string, stage *commonmodels.StageTask) (bool, error) {
+	for _, job := range stage.Jobs {
+		envName := ""
+		switch job.JobType {
+		case string(config.JobZadigDeploy):
+			spec := &commonmodels.JobTaskDeploySpec{}
+			if err := commonmodels.IToi(job.Spec, spec); err != nil {
+				return false, err
+			}
+			if spec.Production {
+				return true, nil
+			}
+			envName = spec.Env
+		case string(config.JobZadigHelmDeploy):
+			spec := &commonmodels.JobTaskHelmDeploySpec{}
+			if err := commonmodels.IToi(job.Spec, spec); err != nil {
+				return false, err
+			}
+			envName = spec.Env
+		case string(config.JobZadigHelmChartDeploy):
+			spec := &commonmodels.JobTaskHelmChartDeploySpec{}
+			if err := commonmodels.IToi(job.Spec, spec); err != nil {
+				return false, err
+			}
+			envName = spec.Env
+		default:
+			continue
+		}
+		if envName == "" {
+			continue
+		}
+		env, err := commonrepo.NewProductColl().Find(&commonrepo.ProductFindOptions{Name: projectName, EnvName: envName})
+		if err != nil {
+			return false, fmt.Errorf("failed to find env %s of project %s: %v", envName, projectName, err)
+		}
+		if env.Production {
+			return true, nil
+		}
+	}
+	return false, nil
+}
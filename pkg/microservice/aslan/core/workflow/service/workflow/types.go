@@ -55,6 +55,8 @@ type OpenAPICreateCustomWorkflowTaskArgs struct {
 	WorkflowName string                      `json:"workflow_key"`
 	ProjectName  string                      `json:"project_key"`
 	Inputs       []*CreateCustomTaskJobInput `json:"inputs"`
+	// IdempotencyKey is read from the header
+	IdempotencyKey string `json:"-"`
 }
 
 type CreateCustomTaskJobInput struct {
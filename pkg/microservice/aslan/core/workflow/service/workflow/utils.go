@@ -18,10 +18,12 @@ package workflow
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"k8s.io/apimachinery/pkg/util/sets"
 
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
 	"github.com/koderover/zadig/pkg/setting"
 )
 
@@ -43,6 +45,26 @@ func validateHookNames(hookNames []string) error {
 	return nil
 }
 
+// validateGerritVotes checks the labels voted by a webhook are unique and the scores are integers
+func validateGerritVotes(votes []*commonmodels.GerritVote) error {
+	labels := sets.NewString()
+	for _, vote := range votes {
+		if vote.Label == "" {
+			return fmt.Errorf("gerrit vote label can not be empty")
+		}
+		if labels.Has(vote.Label) {
+			return fmt.Errorf("duplicated gerrit vote label found: %s", vote.Label)
+		}
+		labels.Insert(vote.Label)
+		for _, score := range []string{vote.PassedScore, vote.FailedScore} {
+			if _, err := strconv.Atoi(score); err != nil {
+				return fmt.Errorf("invalid score %q of gerrit vote label %s", score, vote.Label)
+			}
+		}
+	}
+	return nil
+}
+
 func CheckFixedMarkReturnNoFixedEnv(envName string) (string, bool) {
 	if strings.Contains(envName, setting.FixedValueMark) {
 		return strings.ReplaceAll(envName, setting.FixedValueMark, ""), true
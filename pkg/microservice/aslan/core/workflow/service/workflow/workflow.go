@@ -871,6 +871,11 @@ type WorkflowCopyItem struct {
 	New            string `json:"new"`
 	NewDisplayName string `json:"new_display_name"`
 	BaseName       string `json:"base_name"`
+
+	// TargetProjectName clones the custom workflow into another project, the resources referenced by the jobs are
+	// renamed by the Mapping and the ones not in the Mapping keep their names
+	TargetProjectName string                `json:"target_project_name"`
+	Mapping           *WorkflowCloneMapping `json:"mapping"`
 }
 
 type BulkCopyWorkflowArgs struct {
@@ -0,0 +1,226 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+type WorkflowJobPatchOp string
+
+const (
+	// WorkflowJobPatchReplace sets the field at the path of the job spec, e.g. properties.cluster_id, the arrays on the
+	// path are patched element by element and the fields missing in the spec are not added
+	WorkflowJobPatchReplace WorkflowJobPatchOp = "replace"
+	// WorkflowJobPatchKeyVal sets the value of the variables named by the key in the key_vals and envs of the job spec
+	WorkflowJobPatchKeyVal WorkflowJobPatchOp = "key_val"
+)
+
+type WorkflowJobPatch struct {
+	Op WorkflowJobPatchOp `json:"op"`
+	// JobType and JobName select the jobs to patch, all the jobs are patched if they are empty
+	JobType config.JobType `json:"job_type"`
+	JobName string         `json:"job_name"`
+	Path    string         `json:"path"`
+	Key     string         `json:"key"`
+	// From only patches the fields whose value is From if it is set, e.g. to swap a registry
+	From  interface{} `json:"from"`
+	Value interface{} `json:"value"`
+}
+
+type BatchPatchWorkflowV4Args struct {
+	Workflows []string            `json:"workflows"`
+	Patches   []*WorkflowJobPatch `json:"patches"`
+	// Preview lints the patched workflows without saving them
+	Preview bool `json:"preview"`
+}
+
+type BatchPatchWorkflowV4Result struct {
+	WorkflowName string   `json:"workflow_name"`
+	ProjectName  string   `json:"project_name"`
+	Jobs         []string `json:"jobs"`
+	LintError    string   `json:"lint_error,omitempty"`
+	Updated      bool     `json:"updated"`
+}
+
+// BatchPatchWorkflowV4 applies the patches to the jobs of the workflows, nothing is saved if the preview is asked or
+// any patched workflow fails the lint
+func BatchPatchWorkflowV4(userName string, args *BatchPatchWorkflowV4Args, logger *zap.SugaredLogger) ([]*BatchPatchWorkflowV4Result, error) {
+	if len(args.Workflows) == 0 || len(args.Patches) == 0 {
+		return nil, e.ErrInvalidParam.AddDesc("workflows and patches can not be empty")
+	}
+	for _, patch := range args.Patches {
+		switch patch.Op {
+		case WorkflowJobPatchReplace:
+			if patch.Path == "" {
+				return nil, e.ErrInvalidParam.AddDesc("path of the replace patch can not be empty")
+			}
+		case WorkflowJobPatchKeyVal:
+			if patch.Key == "" {
+				return nil, e.ErrInvalidParam.AddDesc("key of the key_val patch can not be empty")
+			}
+		default:
+			return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("unsupported patch op %s", patch.Op))
+		}
+	}
+
+	resp := make([]*BatchPatchWorkflowV4Result, 0, len(args.Workflows))
+	patched := make([]*commonmodels.WorkflowV4, 0)
+	lintFailed := false
+	for _, name := range args.Workflows {
+		workflow, err := commonrepo.NewWorkflowV4Coll().Find(name)
+		if err != nil {
+			logger.Errorf("Failed to find WorkflowV4: %s, the error is: %v", name, err)
+			return nil, e.ErrFindWorkflow.AddErr(err)
+		}
+		result := &BatchPatchWorkflowV4Result{WorkflowName: workflow.Name, ProjectName: workflow.Project, Jobs: make([]string, 0)}
+		resp = append(resp, result)
+		if result.Jobs, err = patchWorkflowJobs(workflow, args.Patches); err != nil {
+			return nil, e.ErrUpsertWorkflow.AddErr(err)
+		}
+		if len(result.Jobs) == 0 {
+			continue
+		}
+		if err := LintWorkflowV4(workflow, logger); err != nil {
+			result.LintError = err.Error()
+			lintFailed = true
+			continue
+		}
+		if err := LintWorkflowV4OnSave(workflow, logger); err != nil {
+			result.LintError = err.Error()
+			lintFailed = true
+			continue
+		}
+		patched = append(patched, workflow)
+	}
+	if args.Preview {
+		return resp, nil
+	}
+	if lintFailed {
+		return resp, e.ErrUpsertWorkflow.AddDesc("some patched workflows failed the lint, nothing is saved")
+	}
+
+	for i, workflow := range patched {
+		if err := UpdateWorkflowV4(workflow.Name, userName, workflow, logger); err != nil {
+			logger.Errorf("Failed to update WorkflowV4: %s, the error is: %v", workflow.Name, err)
+			return resp, e.ErrUpsertWorkflow.AddDesc(fmt.Sprintf("%d workflows are updated, failed to update workflow %s: %s", i, workflow.Name, err))
+		}
+		for _, result := range resp {
+			if result.WorkflowName == workflow.Name {
+				result.Updated = true
+			}
+		}
+	}
+	return resp, nil
+}
+
+// patchWorkflowJobs returns the names of the patched jobs
+func patchWorkflowJobs(workflow *commonmodels.WorkflowV4, patches []*WorkflowJobPatch) ([]string, error) {
+	jobs := make([]string, 0)
+	for _, stage := range workflow.Stages {
+		for _, job := range stage.Jobs {
+			spec := make(map[string]interface{})
+			if err := commonmodels.IToi(job.Spec, &spec); err != nil {
+				return nil, fmt.Errorf("failed to decode job %s, err: %s", job.Name, err)
+			}
+			changed := false
+			for _, patch := range patches {
+				if (patch.JobType != "" && patch.JobType != job.JobType) || (patch.JobName != "" && patch.JobName != job.Name) {
+					continue
+				}
+				switch patch.Op {
+				case WorkflowJobPatchReplace:
+					changed = replaceSpecField(spec, strings.Split(patch.Path, "."), patch.From, patch.Value) || changed
+				case WorkflowJobPatchKeyVal:
+					changed = setSpecKeyVal(spec, patch.Key, fmt.Sprint(patch.Value)) || changed
+				}
+			}
+			if changed {
+				job.Spec = spec
+				jobs = append(jobs, job.Name)
+			}
+		}
+	}
+	return jobs, nil
+}
+
+func replaceSpecField(node interface{}, path []string, from, value interface{}) bool {
+	switch obj := node.(type) {
+	case []interface{}:
+		changed := false
+		for _, item := range obj {
+			changed = replaceSpecField(item, path, from, value) || changed
+		}
+		return changed
+	case map[string]interface{}:
+		current, ok := obj[path[0]]
+		if !ok {
+			return false
+		}
+		if len(path) > 1 {
+			return replaceSpecField(current, path[1:], from, value)
+		}
+		if from != nil && fmt.Sprint(current) != fmt.Sprint(from) {
+			return false
+		}
+		if fmt.Sprint(current) == fmt.Sprint(value) {
+			return false
+		}
+		obj[path[0]] = value
+		return true
+	}
+	return false
+}
+
+// setSpecKeyVal walks the spec for the variable lists, they are named key_vals or envs
+func setSpecKeyVal(node interface{}, key, value string) bool {
+	changed := false
+	switch obj := node.(type) {
+	case []interface{}:
+		for _, item := range obj {
+			changed = setSpecKeyVal(item, key, value) || changed
+		}
+	case map[string]interface{}:
+		for name, field := range obj {
+			if name != "key_vals" && name != "envs" {
+				changed = setSpecKeyVal(field, key, value) || changed
+				continue
+			}
+			kvs, ok := field.([]interface{})
+			if !ok {
+				continue
+			}
+			for _, item := range kvs {
+				kv, ok := item.(map[string]interface{})
+				if !ok || kv["key"] != key || kv["value"] == value {
+					continue
+				}
+				kv["value"] = value
+				changed = true
+			}
+		}
+	}
+	return changed
+}
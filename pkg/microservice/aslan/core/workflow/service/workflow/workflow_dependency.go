@@ -0,0 +1,230 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const (
+	// WorkflowDependencyTrigger links a workflow to a workflow triggered by one of its trigger jobs
+	WorkflowDependencyTrigger = "trigger"
+	// WorkflowDependencySharedService links two workflows building or deploying the same services
+	WorkflowDependencySharedService = "shared_service"
+	// WorkflowDependencySharedEnv links two workflows deploying to the same envs
+	WorkflowDependencySharedEnv = "shared_env"
+)
+
+// WorkflowDependencyGraph is the project wide map of the dependencies between the workflows, the workflows of
+// other projects triggering or triggered by the project workflows are external nodes
+type WorkflowDependencyGraph struct {
+	Project   string                    `json:"project"`
+	Workflows []*WorkflowDependencyNode `json:"workflows"`
+	Edges     []*WorkflowDependencyEdge `json:"edges"`
+}
+
+type WorkflowDependencyNode struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	DisplayName string   `json:"display_name"`
+	Project     string   `json:"project"`
+	Services    []string `json:"services"`
+	Envs        []string `json:"envs"`
+	External    bool     `json:"external,omitempty"`
+}
+
+// WorkflowDependencyEdge is directed for the trigger type, the shared types are undirected and From is the
+// smaller ID of the pair, Details are the trigger jobs, the shared services or the shared envs
+type WorkflowDependencyEdge struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	Type    string   `json:"type"`
+	Details []string `json:"details"`
+}
+
+// workflowResources is the services and the envs a workflow builds or deploys to
+type workflowResources struct {
+	services sets.String
+	envs     sets.String
+}
+
+// GetProjectWorkflowDependencies returns the dependency map of the project, the workflows of other projects are only
+// included if their projects are in visibleProjects, all of them are included if it is nil
+func GetProjectWorkflowDependencies(projectName string, visibleProjects []string, logger *zap.SugaredLogger) (*WorkflowDependencyGraph, error) {
+	workflows, _, err := commonrepo.NewWorkflowV4Coll().List(&commonrepo.ListWorkflowV4Option{ProjectName: projectName}, 0, 0)
+	if err != nil {
+		logger.Errorf("failed to list workflows of project %s, err: %s", projectName, err)
+		return nil, e.ErrListWorkflow.AddErr(err)
+	}
+	// the trigger jobs of other projects may trigger the workflows of this project
+	triggerWorkflows, _, err := commonrepo.NewWorkflowV4Coll().List(&commonrepo.ListWorkflowV4Option{JobTypes: []config.JobType{config.JobWorkflowTrigger}}, 0, 0)
+	if err != nil {
+		logger.Errorf("failed to list workflows with trigger jobs, err: %s", err)
+		return nil, e.ErrListWorkflow.AddErr(err)
+	}
+	var visible sets.String
+	if visibleProjects != nil {
+		visible = sets.NewString(visibleProjects...)
+	}
+	return buildWorkflowDependencyGraph(projectName, visible, workflows, triggerWorkflows), nil
+}
+
+func buildWorkflowDependencyGraph(projectName string, visibleProjects sets.String, workflows, triggerWorkflows []*commonmodels.WorkflowV4) *WorkflowDependencyGraph {
+	graph := &WorkflowDependencyGraph{
+		Project:   projectName,
+		Workflows: make([]*WorkflowDependencyNode, 0),
+		Edges:     make([]*WorkflowDependencyEdge, 0),
+	}
+	nodes := make(map[string]*WorkflowDependencyNode)
+	addNode := func(project, name, displayName string) *WorkflowDependencyNode {
+		id := graphWorkflowNodeID(project, name)
+		if node, ok := nodes[id]; ok {
+			return node
+		}
+		node := &WorkflowDependencyNode{
+			ID:          id,
+			Name:        name,
+			DisplayName: displayName,
+			Project:     project,
+			Services:    make([]string, 0),
+			Envs:        make([]string, 0),
+			External:    project != projectName,
+		}
+		nodes[id] = node
+		graph.Workflows = append(graph.Workflows, node)
+		return node
+	}
+
+	resources := make(map[string]*workflowResources)
+	for _, workflow := range workflows {
+		node := addNode(workflow.Project, workflow.Name, workflow.DisplayName)
+		res := getWorkflowResources(workflow)
+		resources[node.ID] = res
+		node.Services = res.services.List()
+		node.Envs = res.envs.List()
+	}
+
+	// trigger edges, keyed by from and to to merge the trigger jobs of a workflow
+	triggerEdges := make(map[string]*WorkflowDependencyEdge)
+	scanned := make([]*commonmodels.WorkflowV4, 0, len(workflows)+len(triggerWorkflows))
+	scanned = append(append(scanned, workflows...), triggerWorkflows...)
+	for _, workflow := range scanned {
+		for _, stage := range workflow.Stages {
+			for _, job := range stage.Jobs {
+				if job.JobType != config.JobWorkflowTrigger {
+					continue
+				}
+				for _, target := range getTriggeredWorkflows(workflow.Project, job) {
+					// only keep the edges touching the project
+					if workflow.Project != projectName && target.ProjectName != projectName {
+						continue
+					}
+					// hide the workflows of the projects the user can not view
+					if visibleProjects != nil && (!visibleProjects.Has(workflow.Project) || !visibleProjects.Has(target.ProjectName)) {
+						continue
+					}
+					from := addNode(workflow.Project, workflow.Name, workflow.DisplayName)
+					to := addNode(target.ProjectName, target.WorkflowName, "")
+					key := from.ID + "->" + to.ID
+					edge, ok := triggerEdges[key]
+					if !ok {
+						edge = &WorkflowDependencyEdge{From: from.ID, To: to.ID, Type: WorkflowDependencyTrigger, Details: make([]string, 0)}
+						triggerEdges[key] = edge
+						graph.Edges = append(graph.Edges, edge)
+					}
+					if !sets.NewString(edge.Details...).Has(job.Name) {
+						edge.Details = append(edge.Details, job.Name)
+					}
+				}
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(resources))
+	for id := range resources {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for i := range ids {
+		for j := i + 1; j < len(ids); j++ {
+			a, b := resources[ids[i]], resources[ids[j]]
+			if shared := a.services.Intersection(b.services); shared.Len() > 0 {
+				graph.Edges = append(graph.Edges, &WorkflowDependencyEdge{From: ids[i], To: ids[j], Type: WorkflowDependencySharedService, Details: shared.List()})
+			}
+			if shared := a.envs.Intersection(b.envs); shared.Len() > 0 {
+				graph.Edges = append(graph.Edges, &WorkflowDependencyEdge{From: ids[i], To: ids[j], Type: WorkflowDependencySharedEnv, Details: shared.List()})
+			}
+		}
+	}
+	return graph
+}
+
+// getWorkflowResources collects the services built or deployed by the workflow and the envs it deploys to, the
+// envs decided at runtime by a variable are skipped
+func getWorkflowResources(workflow *commonmodels.WorkflowV4) *workflowResources {
+	res := &workflowResources{services: sets.NewString(), envs: sets.NewString()}
+	addEnv := func(env string) {
+		env = strings.TrimPrefix(env, setting.FixedValueMark)
+		if env != "" && !strings.Contains(env, "{{") {
+			res.envs.Insert(env)
+		}
+	}
+	for _, stage := range workflow.Stages {
+		for _, job := range stage.Jobs {
+			switch job.JobType {
+			case config.JobZadigBuild:
+				spec := &commonmodels.ZadigBuildJobSpec{}
+				if err := commonmodels.IToiYaml(job.Spec, spec); err != nil {
+					continue
+				}
+				for _, build := range spec.ServiceAndBuilds {
+					res.services.Insert(build.ServiceName)
+				}
+			case config.JobZadigDeploy:
+				spec := &commonmodels.ZadigDeployJobSpec{}
+				if err := commonmodels.IToiYaml(job.Spec, spec); err != nil {
+					continue
+				}
+				addEnv(spec.Env)
+				for _, svc := range spec.ServiceAndImages {
+					res.services.Insert(svc.ServiceName)
+				}
+				for _, svc := range spec.Services {
+					res.services.Insert(svc.ServiceName)
+				}
+			case config.JobZadigHelmChartDeploy:
+				spec := &commonmodels.ZadigHelmChartDeployJobSpec{}
+				if err := commonmodels.IToiYaml(job.Spec, spec); err != nil {
+					continue
+				}
+				addEnv(spec.Env)
+			}
+		}
+	}
+	res.services.Delete("")
+	return res
+}
@@ -0,0 +1,256 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const (
+	WorkflowGraphNodeWorkflow = "workflow"
+	WorkflowGraphNodeStage    = "stage"
+	WorkflowGraphNodeJob      = "job"
+
+	// WorkflowGraphEdgeNext links the stages and the jobs of a serial stage in the order they run
+	WorkflowGraphEdgeNext = "next"
+	// WorkflowGraphEdgeContains links the workflow to its stages and a stage to its jobs
+	WorkflowGraphEdgeContains = "contains"
+	// WorkflowGraphEdgeTrigger links a trigger job to the workflows it triggers
+	WorkflowGraphEdgeTrigger = "trigger"
+)
+
+// WorkflowGraph is the stage and job topology of a workflow, the workflows triggered by its trigger jobs are
+// external nodes
+type WorkflowGraph struct {
+	Workflow string               `json:"workflow"`
+	Project  string               `json:"project"`
+	Nodes    []*WorkflowGraphNode `json:"nodes"`
+	Edges    []*WorkflowGraphEdge `json:"edges"`
+}
+
+type WorkflowGraphNode struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Label    string `json:"label"`
+	JobType  string `json:"job_type,omitempty"`
+	Parallel bool   `json:"parallel,omitempty"`
+	Approval bool   `json:"approval,omitempty"`
+	Project  string `json:"project,omitempty"`
+	External bool   `json:"external,omitempty"`
+}
+
+type WorkflowGraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Type string `json:"type"`
+}
+
+func GetWorkflowV4Graph(projectName, workflowName string, logger *zap.SugaredLogger) (*WorkflowGraph, error) {
+	workflow, err := commonrepo.NewWorkflowV4Coll().Find(workflowName)
+	if err != nil {
+		logger.Errorf("failed to find workflow %s, err: %s", workflowName, err)
+		return nil, e.ErrFindWorkflow.AddErr(err)
+	}
+	if projectName != "" && workflow.Project != projectName {
+		return nil, e.ErrFindWorkflow.AddDesc(fmt.Sprintf("workflow %s not found in project %s", workflowName, projectName))
+	}
+	return buildWorkflowGraph(workflow), nil
+}
+
+func buildWorkflowGraph(workflow *commonmodels.WorkflowV4) *WorkflowGraph {
+	graph := &WorkflowGraph{
+		Workflow: workflow.Name,
+		Project:  workflow.Project,
+		Nodes:    make([]*WorkflowGraphNode, 0),
+		Edges:    make([]*WorkflowGraphEdge, 0),
+	}
+	addEdge := func(from, to, edgeType string) {
+		graph.Edges = append(graph.Edges, &WorkflowGraphEdge{From: from, To: to, Type: edgeType})
+	}
+
+	workflowID := graphWorkflowNodeID(workflow.Project, workflow.Name)
+	label := workflow.DisplayName
+	if label == "" {
+		label = workflow.Name
+	}
+	graph.Nodes = append(graph.Nodes, &WorkflowGraphNode{ID: workflowID, Type: WorkflowGraphNodeWorkflow, Label: label, Project: workflow.Project})
+
+	externals := sets.NewString()
+	prevStageID := ""
+	for _, stage := range workflow.Stages {
+		stageID := "stage/" + stage.Name
+		graph.Nodes = append(graph.Nodes, &WorkflowGraphNode{
+			ID:       stageID,
+			Type:     WorkflowGraphNodeStage,
+			Label:    stage.Name,
+			Parallel: stage.Parallel,
+			Approval: stage.Approval != nil && stage.Approval.Enabled,
+		})
+		addEdge(workflowID, stageID, WorkflowGraphEdgeContains)
+		if prevStageID != "" {
+			addEdge(prevStageID, stageID, WorkflowGraphEdgeNext)
+		}
+		prevStageID = stageID
+
+		prevJobID := ""
+		for _, job := range stage.Jobs {
+			jobID := "job/" + job.Name
+			graph.Nodes = append(graph.Nodes, &WorkflowGraphNode{ID: jobID, Type: WorkflowGraphNodeJob, Label: job.Name, JobType: string(job.JobType)})
+			addEdge(stageID, jobID, WorkflowGraphEdgeContains)
+			if !stage.Parallel && prevJobID != "" {
+				addEdge(prevJobID, jobID, WorkflowGraphEdgeNext)
+			}
+			prevJobID = jobID
+
+			if job.JobType != config.JobWorkflowTrigger {
+				continue
+			}
+			for _, target := range getTriggeredWorkflows(workflow.Project, job) {
+				targetID := graphWorkflowNodeID(target.ProjectName, target.WorkflowName)
+				if !externals.Has(targetID) && targetID != workflowID {
+					externals.Insert(targetID)
+					graph.Nodes = append(graph.Nodes, &WorkflowGraphNode{
+						ID:       targetID,
+						Type:     WorkflowGraphNodeWorkflow,
+						Label:    target.WorkflowName,
+						Project:  target.ProjectName,
+						External: true,
+					})
+				}
+				addEdge(jobID, targetID, WorkflowGraphEdgeTrigger)
+			}
+		}
+	}
+	return graph
+}
+
+// getTriggeredWorkflows returns the distinct workflows triggered by the trigger job
+func getTriggeredWorkflows(projectName string, job *commonmodels.Job) []*commonmodels.ServiceTriggerWorkflowInfo {
+	spec := &commonmodels.WorkflowTriggerJobSpec{}
+	if err := commonmodels.IToiYaml(job.Spec, spec); err != nil {
+		return nil
+	}
+	infos := spec.FixedWorkflowList
+	if spec.TriggerType != config.WorkflowTriggerTypeFixed {
+		infos = spec.ServiceTriggerWorkflow
+	}
+
+	resp := make([]*commonmodels.ServiceTriggerWorkflowInfo, 0)
+	seen := sets.NewString()
+	for _, info := range infos {
+		if info.WorkflowName == "" {
+			continue
+		}
+		target := &commonmodels.ServiceTriggerWorkflowInfo{WorkflowName: info.WorkflowName, ProjectName: info.ProjectName}
+		if target.ProjectName == "" {
+			target.ProjectName = projectName
+		}
+		if key := target.ProjectName + "/" + target.WorkflowName; !seen.Has(key) {
+			seen.Insert(key)
+			resp = append(resp, target)
+		}
+	}
+	return resp
+}
+
+func graphWorkflowNodeID(projectName, workflowName string) string {
+	return "workflow/" + projectName + "/" + workflowName
+}
+
+// DOT renders the graph in the graphviz dot language, each stage is a cluster of its jobs
+func (g *WorkflowGraph) DOT() string {
+	nodes := make(map[string]*WorkflowGraphNode, len(g.Nodes))
+	for _, node := range g.Nodes {
+		nodes[node.ID] = node
+	}
+	stageJobs := make(map[string][]string)
+	for _, edge := range g.Edges {
+		if edge.Type == WorkflowGraphEdgeContains && nodes[edge.To] != nil && nodes[edge.To].Type == WorkflowGraphNodeJob {
+			stageJobs[edge.From] = append(stageJobs[edge.From], edge.To)
+		}
+	}
+
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "digraph %s {\n", dotQuote(g.Workflow))
+	b.WriteString("  rankdir=LR;\n  compound=true;\n  node [shape=box, style=rounded];\n")
+	for _, node := range g.Nodes {
+		switch node.Type {
+		case WorkflowGraphNodeWorkflow:
+			style := "bold"
+			if node.External {
+				style = "dashed"
+			}
+			fmt.Fprintf(b, "  %s [label=%s, shape=ellipse, style=%s];\n", dotQuote(node.ID), dotQuote(node.Project+"/"+node.Label), style)
+		case WorkflowGraphNodeStage:
+			label := node.Label
+			if node.Approval {
+				label += " (approval)"
+			}
+			fmt.Fprintf(b, "  subgraph %s {\n    label=%s;\n", dotQuote("cluster_"+node.ID), dotQuote(label))
+			// the stage node is an invisible anchor of the edges between the stages
+			fmt.Fprintf(b, "    %s [label=\"\", shape=point, style=invis];\n", dotQuote(node.ID))
+			for _, jobID := range stageJobs[node.ID] {
+				job := nodes[jobID]
+				fmt.Fprintf(b, "    %s [label=%s];\n", dotQuote(job.ID), dotQuote(job.Label+"\n"+job.JobType))
+			}
+			b.WriteString("  }\n")
+		}
+	}
+	for _, edge := range g.Edges {
+		from, to := nodes[edge.From], nodes[edge.To]
+		if from == nil || to == nil {
+			continue
+		}
+		switch {
+		case edge.Type == WorkflowGraphEdgeNext && from.Type == WorkflowGraphNodeStage:
+			fmt.Fprintf(b, "  %s -> %s [ltail=%s, lhead=%s];\n", dotQuote(edge.From), dotQuote(edge.To), dotQuote("cluster_"+edge.From), dotQuote("cluster_"+edge.To))
+		case edge.Type == WorkflowGraphEdgeNext:
+			fmt.Fprintf(b, "  %s -> %s;\n", dotQuote(edge.From), dotQuote(edge.To))
+		case edge.Type == WorkflowGraphEdgeContains && from.Type == WorkflowGraphNodeWorkflow:
+			// only the first stage is linked to the workflow, the others follow it
+			if to.ID == firstStageID(g) {
+				fmt.Fprintf(b, "  %s -> %s [lhead=%s];\n", dotQuote(edge.From), dotQuote(edge.To), dotQuote("cluster_"+edge.To))
+			}
+		case edge.Type == WorkflowGraphEdgeTrigger:
+			fmt.Fprintf(b, "  %s -> %s [style=dashed, label=\"trigger\"];\n", dotQuote(edge.From), dotQuote(edge.To))
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func firstStageID(g *WorkflowGraph) string {
+	for _, node := range g.Nodes {
+		if node.Type == WorkflowGraphNodeStage {
+			return node.ID
+		}
+	}
+	return ""
+}
+
+func dotQuote(s string) string {
+	return "\"" + strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n").Replace(s) + "\""
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	"github.com/koderover/zadig/pkg/setting"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+// WorkflowTaskCounter is the task id counter of a custom workflow compared with its tasks
+type WorkflowTaskCounter struct {
+	WorkflowName string `json:"workflow_name"`
+	ProjectName  string `json:"project_name"`
+	CounterName  string `json:"counter_name"`
+	// Seq is the last task id given by the counter, the next task gets Seq+1
+	Seq       int64 `json:"seq"`
+	MaxTaskID int64 `json:"max_task_id"`
+	TaskCount int64 `json:"task_count"`
+	// DuplicateTaskIDs are used by more than one task
+	DuplicateTaskIDs []int64 `json:"duplicate_task_ids"`
+	// Behind means the counter gives the task ids already used, e.g. after the tasks are restored from a backup
+	Behind bool `json:"behind"`
+}
+
+func GetWorkflowTaskCounter(workflowName string, logger *zap.SugaredLogger) (*WorkflowTaskCounter, error) {
+	workflow, err := commonrepo.NewWorkflowV4Coll().Find(workflowName)
+	if err != nil {
+		logger.Errorf("Failed to find WorkflowV4: %s, the error is: %v", workflowName, err)
+		return nil, e.ErrFindWorkflow.AddErr(err)
+	}
+	return getWorkflowTaskCounter(workflow.Name, workflow.Project)
+}
+
+func ListWorkflowTaskCounters(projectName string, logger *zap.SugaredLogger) ([]*WorkflowTaskCounter, error) {
+	workflows, _, err := commonrepo.NewWorkflowV4Coll().List(&commonrepo.ListWorkflowV4Option{ProjectName: projectName}, 0, 0)
+	if err != nil {
+		logger.Errorf("Failed to list WorkflowV4 of project %s, the error is: %v", projectName, err)
+		return nil, e.ErrListWorkflow.AddErr(err)
+	}
+	resp := make([]*WorkflowTaskCounter, 0, len(workflows))
+	for _, workflow := range workflows {
+		counter, err := getWorkflowTaskCounter(workflow.Name, workflow.Project)
+		if err != nil {
+			return nil, err
+		}
+		resp = append(resp, counter)
+	}
+	return resp, nil
+}
+
+// FastForwardWorkflowTaskCounter moves the counter to the seq, the max task id of the workflow is used if the seq
+// is 0. The counter never goes backward or below the max task id so that no task id is given twice.
+func FastForwardWorkflowTaskCounter(workflowName string, seq int64, logger *zap.SugaredLogger) (*WorkflowTaskCounter, error) {
+	counter, err := GetWorkflowTaskCounter(workflowName, logger)
+	if err != nil {
+		return nil, err
+	}
+	if seq == 0 {
+		seq = counter.MaxTaskID
+	}
+	if seq < counter.MaxTaskID {
+		return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("seq %d is less than the max task id %d", seq, counter.MaxTaskID))
+	}
+	if seq <= counter.Seq {
+		return counter, nil
+	}
+
+	if err := commonrepo.NewCounterColl().FastForward(counter.CounterName, seq); err != nil {
+		logger.Errorf("Failed to fast forward counter %s to %d, the error is: %v", counter.CounterName, seq, err)
+		return nil, e.ErrUpdateCounter.AddErr(err)
+	}
+	logger.Infof("counter %s is fast forwarded from %d to %d", counter.CounterName, counter.Seq, seq)
+	return getWorkflowTaskCounter(counter.WorkflowName, counter.ProjectName)
+}
+
+func getWorkflowTaskCounter(workflowName, projectName string) (*WorkflowTaskCounter, error) {
+	resp := &WorkflowTaskCounter{
+		WorkflowName: workflowName,
+		ProjectName:  projectName,
+		CounterName:  fmt.Sprintf(setting.WorkflowTaskV4Fmt, workflowName),
+	}
+	counter, err := commonrepo.NewCounterColl().Find(resp.CounterName)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, e.ErrGetCounter.AddErr(err)
+	}
+	if counter != nil {
+		resp.Seq = counter.Seq
+	}
+
+	stat, err := commonrepo.NewWorkflowTaskV4Store().GetTaskIDStat(workflowName)
+	if err != nil {
+		return nil, e.ErrGetCounter.AddErr(fmt.Errorf("failed to get the task ids of workflow %s: %s", workflowName, err))
+	}
+	resp.MaxTaskID = stat.MaxTaskID
+	resp.TaskCount = stat.TaskCount
+	if resp.DuplicateTaskIDs, err = commonrepo.NewWorkflowTaskV4Store().ListDuplicateTaskIDs(workflowName); err != nil {
+		return nil, e.ErrGetCounter.AddErr(fmt.Errorf("failed to find the duplicate task ids of workflow %s: %s", workflowName, err))
+	}
+	resp.Behind = resp.Seq < resp.MaxTaskID
+	return resp, nil
+}
@@ -0,0 +1,366 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const (
+	TaskExportFormatCSV  = "csv"
+	TaskExportFormatXLSX = "xlsx"
+
+	// the buffered csv rows are flushed in batches
+	taskExportFlushSize = 100
+)
+
+type TaskExportArgs struct {
+	TaskHistoryFilter
+	Format string `json:"format" form:"format,default=csv"`
+	// Fields: comma separated keys of the columns to export, all the enabled custom fields by default
+	Fields string `json:"fields" form:"fields"`
+}
+
+type taskExportColumn struct {
+	Key   string
+	Title string
+	Value func(task *commonmodels.WorkflowTask, preview *commonmodels.WorkflowTaskPreview) string
+}
+
+// WorkflowTaskExporter writes the task history of a workflow as a CSV or XLSX file, the tasks are read
+// with a cursor and written row by row so that the whole history is never loaded at once
+type WorkflowTaskExporter struct {
+	FileName    string
+	ContentType string
+
+	format            string
+	columns           []*taskExportColumn
+	userDefinedFields []*commonmodels.UserDefinedField
+	testSuiteLoader   *commonservice.LocalTestSuiteLoader
+	filter            *commonrepo.WorkFlowTaskFilter
+	logger            *zap.SugaredLogger
+}
+
+func NewWorkflowTaskExporter(args *TaskExportArgs, logger *zap.SugaredLogger) (*WorkflowTaskExporter, error) {
+	if args.Format != TaskExportFormatCSV && args.Format != TaskExportFormatXLSX {
+		return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("unsupported export format %s", args.Format))
+	}
+	customFields, err := GetWorkflowTasksCustomFields(args.ProjectName, args.WorkflowName, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := getTaskExportColumns(customFields)
+	if args.Fields != "" {
+		selected := sets.NewString(strings.Split(args.Fields, ",")...)
+		filtered := make([]*taskExportColumn, 0)
+		for _, column := range columns {
+			if selected.Has(column.Key) {
+				filtered = append(filtered, column)
+			}
+		}
+		columns = filtered
+	}
+	if len(columns) == 0 {
+		return nil, e.ErrInvalidParam.AddDesc("no column to export")
+	}
+
+	exporter := &WorkflowTaskExporter{
+		FileName:          fmt.Sprintf("%s-%s.%s", args.WorkflowName, time.Now().Format("20060102150405"), args.Format),
+		ContentType:       "text/csv; charset=utf-8",
+		format:            args.Format,
+		columns:           columns,
+		userDefinedFields: customFields.UserDefined,
+		testSuiteLoader:   commonservice.NewLocalTestSuiteLoader(),
+		filter:            getTaskHistoryListOption(&args.TaskHistoryFilter, strings.Split(args.Filters, ",")),
+		logger:            logger,
+	}
+	if args.Format == TaskExportFormatXLSX {
+		exporter.ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return exporter, nil
+}
+
+func (exporter *WorkflowTaskExporter) Export(ctx context.Context, w io.Writer) error {
+	rw, err := newTaskRowWriter(exporter.format, w)
+	if err != nil {
+		return err
+	}
+	header := make([]string, 0, len(exporter.columns))
+	for _, column := range exporter.columns {
+		header = append(header, column.Title)
+	}
+	if err := rw.WriteRow(header); err != nil {
+		return err
+	}
+
+	count := 0
+	err = commonrepo.NewWorkflowTaskV4Store().ForEachByFilter(ctx, exporter.filter, func(task *commonmodels.WorkflowTask) error {
+		preview, err := newWorkflowTaskPreview(task, exporter.userDefinedFields, exporter.testSuiteLoader, exporter.logger)
+		if err != nil {
+			return err
+		}
+		row := make([]string, 0, len(exporter.columns))
+		for _, column := range exporter.columns {
+			row = append(row, column.Value(task, preview))
+		}
+		if err := rw.WriteRow(row); err != nil {
+			return err
+		}
+
+		count++
+		if count%taskExportFlushSize == 0 {
+			if err := rw.Flush(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list workflow tasks: %s", err)
+	}
+	return rw.Close()
+}
+
+func getTaskExportColumns(fields *commonmodels.CustomField) []*taskExportColumn {
+	columns := make([]*taskExportColumn, 0)
+	if fields.TaskID == 1 {
+		columns = append(columns, &taskExportColumn{Key: "task_id", Title: "任务 ID", Value: func(task *commonmodels.WorkflowTask, _ *commonmodels.WorkflowTaskPreview) string {
+			return fmt.Sprintf("%d", task.TaskID)
+		}})
+	}
+	if fields.Status == 1 {
+		columns = append(columns, &taskExportColumn{Key: "status", Title: "状态", Value: func(task *commonmodels.WorkflowTask, _ *commonmodels.WorkflowTaskPreview) string {
+			return string(task.Status)
+		}})
+	}
+	columns = append(columns, &taskExportColumn{Key: "start_time", Title: "开始时间", Value: func(task *commonmodels.WorkflowTask, _ *commonmodels.WorkflowTaskPreview) string {
+		return formatExportTime(task.CreateTime)
+	}})
+	if fields.Duration == 1 {
+		columns = append(columns, &taskExportColumn{Key: "duration", Title: "持续时间", Value: func(task *commonmodels.WorkflowTask, _ *commonmodels.WorkflowTaskPreview) string {
+			if task.StartTime == 0 || task.EndTime < task.StartTime {
+				return ""
+			}
+			return (time.Duration(task.EndTime-task.StartTime) * time.Second).String()
+		}})
+	}
+	if fields.Executor == 1 {
+		columns = append(columns, &taskExportColumn{Key: "executor", Title: "执行人", Value: func(task *commonmodels.WorkflowTask, _ *commonmodels.WorkflowTaskPreview) string {
+			return task.TaskCreator
+		}})
+	}
+
+	columns = append(columns, getJobExportColumns("build_service_component", "服务组件", fields.BuildServiceComponent, formatExportServiceModules)...)
+	columns = append(columns, getJobExportColumns("build_code_msg", "代码信息", fields.BuildCodeMsg, formatExportCodeInfo)...)
+	columns = append(columns, getJobExportColumns("deploy_service_component", "服务组件", fields.DeployServiceComponent, formatExportServiceModules)...)
+	columns = append(columns, getJobExportColumns("deploy_env", "环境", fields.DeployEnv, func(job *commonmodels.JobPreview) string {
+		if job.Envs == nil {
+			return ""
+		}
+		return job.Envs.EnvName
+	})...)
+	columns = append(columns, getJobExportColumns("test_result", "测试结果", fields.TestResult, func(job *commonmodels.JobPreview) string {
+		results := make([]string, 0, len(job.TestModules))
+		for _, module := range job.TestModules {
+			results = append(results, fmt.Sprintf("%s: %d/%d", module.TestName, module.SuccessCaseNum, module.TestCaseNum))
+		}
+		return strings.Join(results, "\n")
+	})...)
+
+	for _, field := range fields.UserDefined {
+		if field.Enabled == 0 {
+			continue
+		}
+		key := field.Key
+		columns = append(columns, &taskExportColumn{Key: "user_defined." + key, Title: field.Label, Value: func(_ *commonmodels.WorkflowTask, preview *commonmodels.WorkflowTaskPreview) string {
+			return preview.CustomFields[key]
+		}})
+	}
+	return columns
+}
+
+// getJobExportColumns returns a column for each enabled job of the field, the key is the field name joined with the job name
+func getJobExportColumns(fieldName, title string, jobs map[string]int, format func(job *commonmodels.JobPreview) string) []*taskExportColumn {
+	jobNames := sets.NewString()
+	for jobName, enabled := range jobs {
+		if enabled == 1 {
+			jobNames.Insert(jobName)
+		}
+	}
+	columns := make([]*taskExportColumn, 0, jobNames.Len())
+	for _, jobName := range jobNames.List() {
+		jobName := jobName
+		columns = append(columns, &taskExportColumn{
+			Key:   fieldName + "." + jobName,
+			Title: fmt.Sprintf("%s %s", jobName, title),
+			Value: func(_ *commonmodels.WorkflowTask, preview *commonmodels.WorkflowTaskPreview) string {
+				for _, stage := range preview.Stages {
+					for _, job := range stage.Jobs {
+						if job.Name == jobName {
+							return format(job)
+						}
+					}
+				}
+				return ""
+			},
+		})
+	}
+	return columns
+}
+
+func formatExportServiceModules(job *commonmodels.JobPreview) string {
+	modules := make([]string, 0, len(job.ServiceModules))
+	for _, module := range job.ServiceModules {
+		modules = append(modules, fmt.Sprintf("%s(%s)", module.ServiceModule, module.ServiceName))
+	}
+	return strings.Join(modules, "\n")
+}
+
+func formatExportCodeInfo(job *commonmodels.JobPreview) string {
+	infos := make([]string, 0)
+	for _, module := range job.ServiceModules {
+		for _, repo := range module.CodeInfo {
+			ref := repo.Branch
+			if repo.Tag != "" {
+				ref = repo.Tag
+			}
+			info := fmt.Sprintf("%s/%s %s", repo.RepoOwner, repo.RepoName, ref)
+			if repo.CommitID != "" {
+				info = fmt.Sprintf("%s %s", info, repo.CommitID)
+			}
+			infos = append(infos, info)
+		}
+	}
+	return strings.Join(infos, "\n")
+}
+
+func formatExportTime(timestamp int64) string {
+	if timestamp == 0 {
+		return ""
+	}
+	return time.Unix(timestamp, 0).Format("2006-01-02 15:04:05")
+}
+
+type taskRowWriter interface {
+	WriteRow(row []string) error
+	Flush() error
+	Close() error
+}
+
+const taskExportSheetName = "tasks"
+
+func newTaskRowWriter(format string, w io.Writer) (taskRowWriter, error) {
+	if format == TaskExportFormatXLSX {
+		file := excelize.NewFile()
+		if err := file.SetSheetName(file.GetSheetName(0), taskExportSheetName); err != nil {
+			return nil, err
+		}
+		sw, err := file.NewStreamWriter(taskExportSheetName)
+		if err != nil {
+			return nil, err
+		}
+		return &xlsxRowWriter{w: w, file: file, sw: sw}, nil
+	}
+	// the BOM makes excel recognize the encoding of the csv file
+	if _, err := io.WriteString(w, "\xEF\xBB\xBF"); err != nil {
+		return nil, err
+	}
+	return &csvRowWriter{w: csv.NewWriter(w)}, nil
+}
+
+type csvRowWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvRowWriter) WriteRow(row []string) error {
+	escaped := make([]string, 0, len(row))
+	for _, cell := range row {
+		escaped = append(escaped, escapeCSVCell(cell))
+	}
+	return c.w.Write(escaped)
+}
+
+func (c *csvRowWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvRowWriter) Close() error {
+	return c.Flush()
+}
+
+// escapeCSVCell prefixes the cells starting with a formula character with a single quote, so that the
+// values like commit messages and custom fields are never evaluated as formulas by the spreadsheets
+func escapeCSVCell(cell string) string {
+	if cell == "" {
+		return cell
+	}
+	switch cell[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		return "'" + cell
+	}
+	return cell
+}
+
+// xlsxRowWriter keeps the rows in the stream writer of excelize, which spills them to a temp file when the
+// sheet grows large, the workbook is written out when the writer is closed
+type xlsxRowWriter struct {
+	w    io.Writer
+	file *excelize.File
+	sw   *excelize.StreamWriter
+	rows int
+}
+
+func (x *xlsxRowWriter) WriteRow(row []string) error {
+	x.rows++
+	cell, err := excelize.CoordinatesToCellName(1, x.rows)
+	if err != nil {
+		return err
+	}
+	values := make([]interface{}, 0, len(row))
+	for _, value := range row {
+		values = append(values, value)
+	}
+	return x.sw.SetRow(cell, values)
+}
+
+func (x *xlsxRowWriter) Flush() error {
+	return nil
+}
+
+func (x *xlsxRowWriter) Close() error {
+	defer x.file.Close()
+	if err := x.sw.Flush(); err != nil {
+		return err
+	}
+	return x.file.Write(x.w)
+}
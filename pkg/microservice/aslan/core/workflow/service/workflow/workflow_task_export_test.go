@@ -0,0 +1,72 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/xuri/excelize/v2"
+)
+
+var _ = Describe("Testing task export", func() {
+
+	DescribeTable("escapeCSVCell",
+		func(cell, expected string) {
+			Expect(escapeCSVCell(cell)).To(Equal(expected))
+		},
+		Entry("empty cell", "", ""),
+		Entry("plain text", "main 1a2b3c", "main 1a2b3c"),
+		Entry("formula", "=HYPERLINK(\"http://x\")", "'=HYPERLINK(\"http://x\")"),
+		Entry("plus sign", "+1", "'+1"),
+		Entry("minus sign", "-1+2", "'-1+2"),
+		Entry("at sign", "@SUM(A1)", "'@SUM(A1)"),
+		Entry("tab", "\t=1", "'\t=1"),
+		Entry("formula character in the middle", "a=b", "a=b"),
+	)
+
+	It("should escape the cells written to the csv", func() {
+		buf := new(bytes.Buffer)
+		rw, err := newTaskRowWriter(TaskExportFormatCSV, buf)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(rw.WriteRow([]string{"1", "=cmd|' /C calc'!A0"})).To(Succeed())
+		Expect(rw.Close()).To(Succeed())
+
+		records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(buf.String(), "\xEF\xBB\xBF"))).ReadAll()
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(records).To(Equal([][]string{{"1", "'=cmd|' /C calc'!A0"}}))
+	})
+
+	It("should write the rows to the xlsx", func() {
+		buf := new(bytes.Buffer)
+		rw, err := newTaskRowWriter(TaskExportFormatXLSX, buf)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(rw.WriteRow([]string{"任务 ID", "状态"})).To(Succeed())
+		Expect(rw.WriteRow([]string{"1", "passed"})).To(Succeed())
+		Expect(rw.Close()).To(Succeed())
+
+		file, err := excelize.OpenReader(buf)
+		Expect(err).ShouldNot(HaveOccurred())
+		defer file.Close()
+		rows, err := file.GetRows(taskExportSheetName)
+		Expect(err).ShouldNot(HaveOccurred())
+		Expect(rows).To(Equal([][]string{{"任务 ID", "状态"}, {"1", "passed"}}))
+	})
+})
@@ -17,6 +17,8 @@ limitations under the License.
 package workflow
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"math"
@@ -71,6 +73,8 @@ type CreateTaskV4Resp struct {
 	ProjectName  string `json:"project_name"`
 	WorkflowName string `json:"workflow_name"`
 	TaskID       int64  `json:"task_id"`
+	// Duplicated means the task was created before with the same idempotency key
+	Duplicated bool `json:"duplicated,omitempty"`
 }
 
 type WorkflowTaskPreview struct {
@@ -100,6 +104,8 @@ type StageTaskPreview struct {
 	Approval  *commonmodels.Approval `bson:"approval"      json:"approval"`
 	Jobs      []*JobTaskPreview      `bson:"jobs"          json:"jobs"`
 	Error     string                 `bson:"error" json:"error""`
+
+	Timeline []*commonmodels.StageTimelineEvent `bson:"timeline"      json:"timeline,omitempty"`
 }
 
 type JobTaskPreview struct {
@@ -180,6 +186,8 @@ type K8sCanaryDeployJobSpec struct {
 	ContainerName  string               `bson:"container_name"               json:"container_name"`
 	CanaryReplica  int                  `bson:"canary_replica"               json:"canary_replica"`
 	Events         *commonmodels.Events `bson:"events"                       json:"events"`
+
+	AnalysisResult *commonmodels.CanaryAnalysisResult `bson:"analysis_result,omitempty" json:"analysis_result,omitempty"`
 }
 
 type K8sCanaryReleaseJobSpec struct {
@@ -306,6 +314,8 @@ type CreateWorkflowTaskV4Args struct {
 	Name    string
 	Account string
 	UserID  string
+	// IdempotencyKey deduplicates the creations of the workflow, it is optional
+	IdempotencyKey string
 }
 
 func CreateWorkflowTaskV4ByBuildInTrigger(triggerName string, args *commonmodels.WorkflowV4, log *zap.SugaredLogger) (*CreateTaskV4Resp, error) {
@@ -324,10 +334,18 @@ func CreateWorkflowTaskV4ByBuildInTrigger(triggerName string, args *commonmodels
 		log.Error(errMsg)
 		return resp, e.ErrCreateTask.AddDesc(errMsg)
 	}
+	workflow.MeegoWorkItems = args.MeegoWorkItems
 	return CreateWorkflowTaskV4(&CreateWorkflowTaskV4Args{Name: triggerName}, workflow, log)
 }
 
 func CreateWorkflowTaskV4(args *CreateWorkflowTaskV4Args, workflow *commonmodels.WorkflowV4, log *zap.SugaredLogger) (*CreateTaskV4Resp, error) {
+	if args.IdempotencyKey != "" {
+		return createWorkflowTaskV4Idempotently(args, workflow, log)
+	}
+	return createWorkflowTaskV4(args, workflow, log)
+}
+
+func createWorkflowTaskV4(args *CreateWorkflowTaskV4Args, workflow *commonmodels.WorkflowV4, log *zap.SugaredLogger) (*CreateTaskV4Resp, error) {
 	resp := &CreateTaskV4Resp{
 		ProjectName:  workflow.Project,
 		WorkflowName: workflow.Name,
@@ -346,6 +364,8 @@ func CreateWorkflowTaskV4(args *CreateWorkflowTaskV4Args, workflow *commonmodels
 		log.Errorf("cannot find workflow %s, the error is: %v", workflow.Name, err)
 		return nil, e.ErrFindWorkflow.AddDesc(err.Error())
 	}
+	// the meego sync is configured in the workflow, not by the args of the task
+	workflow.MeegoSync = dbWorkflow.MeegoSync
 
 	if err := jobctl.InstantiateWorkflow(workflow); err != nil {
 		log.Errorf("instantiate workflow error: %s", err)
@@ -374,6 +394,7 @@ func CreateWorkflowTaskV4(args *CreateWorkflowTaskV4Args, workflow *commonmodels
 	originTaskArgs.MeegoHookCtls = nil
 	originTaskArgs.JiraHookCtls = nil
 	originTaskArgs.GeneralHookCtls = nil
+	originTaskArgs.RegistryHookCtls = nil
 	workflowTask.OriginWorkflowArgs = originTaskArgs
 	nextTaskID, err := commonrepo.NewCounterColl().GetNextSeq(fmt.Sprintf(setting.WorkflowTaskV4Fmt, workflow.Name))
 	if err != nil {
@@ -461,7 +482,7 @@ func CreateWorkflowTaskV4(args *CreateWorkflowTaskV4Args, workflow *commonmodels
 			// add breakpoint_before when workflowTask is debug mode
 			for _, jobTask := range jobs {
 				switch config.JobType(jobTask.JobType) {
-				case config.JobFreestyle, config.JobZadigTesting, config.JobZadigBuild, config.JobZadigScanning:
+				case config.JobFreestyle, config.JobZadigTesting, config.JobZadigBuild, config.JobZadigScanning, config.JobZadigSonar, config.JobZadigPerformanceTest:
 					if workflowTask.IsDebug {
 						jobTask.BreakpointBefore = true
 					}
@@ -483,6 +504,7 @@ func CreateWorkflowTaskV4(args *CreateWorkflowTaskV4Args, workflow *commonmodels
 	workflow.JiraHookCtls = nil
 	workflow.MeegoHookCtls = nil
 	workflow.GeneralHookCtls = nil
+	workflow.RegistryHookCtls = nil
 	workflowTask.WorkflowArgs = workflow
 	workflowTask.Status = config.StatusCreated
 	workflowTask.StartTime = time.Now().Unix()
@@ -513,7 +535,7 @@ func CreateWorkflowTaskV4(args *CreateWorkflowTaskV4Args, workflow *commonmodels
 }
 
 func CloneWorkflowTaskV4(workflowName string, taskID int64, logger *zap.SugaredLogger) (*commonmodels.WorkflowV4, error) {
-	task, err := commonrepo.NewworkflowTaskv4Coll().Find(workflowName, taskID)
+	task, err := commonrepo.NewWorkflowTaskV4Store().Find(workflowName, taskID)
 	if err != nil {
 		logger.Errorf("find workflowTaskV4 error: %s", err)
 		return nil, e.ErrGetTask.AddErr(err)
@@ -522,7 +544,7 @@ func CloneWorkflowTaskV4(workflowName string, taskID int64, logger *zap.SugaredL
 }
 
 func RetryWorkflowTaskV4(workflowName string, taskID int64, logger *zap.SugaredLogger) error {
-	task, err := commonrepo.NewworkflowTaskv4Coll().Find(workflowName, taskID)
+	task, err := commonrepo.NewWorkflowTaskV4Store().Find(workflowName, taskID)
 	if err != nil {
 		logger.Errorf("find workflowTaskV4 error: %s", err)
 		return e.ErrGetTask.AddErr(err)
@@ -576,6 +598,7 @@ func RetryWorkflowTaskV4(workflowName string, taskID int64, logger *zap.SugaredL
 			jobTask.StartTime = 0
 			jobTask.EndTime = 0
 			jobTask.Error = ""
+			jobTask.ResumeState = nil
 			if t, ok := jobTaskMap[jobTask.Key]; ok {
 				jobTask.Spec = t.Spec
 			} else {
@@ -854,6 +877,32 @@ type TaskHistoryFilter struct {
 }
 
 func ListWorkflowTaskV4ByFilter(filter *TaskHistoryFilter, filterList []string, logger *zap.SugaredLogger) ([]*commonmodels.WorkflowTaskPreview, int64, error) {
+	listTaskOpt := getTaskHistoryListOption(filter, filterList)
+	tasks, total, err := commonrepo.NewWorkflowTaskV4Store().ListByFilter(listTaskOpt, filter.PageNum, filter.PageSize)
+	if err != nil {
+		logger.Errorf("list workflowTaskV4 error: %s", err)
+		return nil, total, err
+	}
+
+	customFields, err := GetWorkflowTasksCustomFields(filter.ProjectName, filter.WorkflowName, logger)
+	if err != nil {
+		return nil, total, err
+	}
+
+	taskPreviews := make([]*commonmodels.WorkflowTaskPreview, 0)
+	testSuiteLoader := service.NewLocalTestSuiteLoader()
+	for _, task := range tasks {
+		preview, err := newWorkflowTaskPreview(task, customFields.UserDefined, testSuiteLoader, logger)
+		if err != nil {
+			return nil, 0, err
+		}
+		taskPreviews = append(taskPreviews, preview)
+	}
+	cleanWorkflowV4TasksPreviews(taskPreviews)
+	return taskPreviews, total, nil
+}
+
+func getTaskHistoryListOption(filter *TaskHistoryFilter, filterList []string) *mongodb.WorkFlowTaskFilter {
 	var listTaskOpt *mongodb.WorkFlowTaskFilter
 	switch filter.QueryType {
 	case "creator":
@@ -888,176 +937,172 @@ func ListWorkflowTaskV4ByFilter(filter *TaskHistoryFilter, filterList []string,
 			ProjectName:  filter.ProjectName,
 		}
 	}
-	tasks, total, err := commonrepo.NewworkflowTaskv4Coll().ListByFilter(listTaskOpt, filter.PageNum, filter.PageSize)
-	if err != nil {
-		logger.Errorf("list workflowTaskV4 error: %s", err)
-		return nil, total, err
+	return listTaskOpt
+}
+
+// newWorkflowTaskPreview converts the task to the preview shown in the task history, the loader is shared
+// by the tasks so that the test reports are loaded without finding the task and the storage again
+func newWorkflowTaskPreview(task *commonmodels.WorkflowTask, userDefinedFields []*commonmodels.UserDefinedField, testSuiteLoader *service.LocalTestSuiteLoader, logger *zap.SugaredLogger) (*commonmodels.WorkflowTaskPreview, error) {
+	preview := &commonmodels.WorkflowTaskPreview{
+		TaskID:              task.TaskID,
+		TaskCreator:         task.TaskCreator,
+		ProjectName:         task.ProjectName,
+		WorkflowName:        task.WorkflowName,
+		WorkflowDisplayName: task.WorkflowDisplayName,
+		Status:              task.Status,
+		CreateTime:          task.CreateTime,
+		StartTime:           task.StartTime,
+		EndTime:             task.EndTime,
+		CustomFields:        getTaskCustomFieldValues(task, userDefinedFields),
 	}
 
-	taskPreviews := make([]*commonmodels.WorkflowTaskPreview, 0)
-	for _, task := range tasks {
-		preview := &commonmodels.WorkflowTaskPreview{
-			TaskID:              task.TaskID,
-			TaskCreator:         task.TaskCreator,
-			ProjectName:         task.ProjectName,
-			WorkflowName:        task.WorkflowName,
-			WorkflowDisplayName: task.WorkflowDisplayName,
-			Status:              task.Status,
-			CreateTime:          task.CreateTime,
-			StartTime:           task.StartTime,
-			EndTime:             task.EndTime,
-		}
-
-		stagePreviews := make([]*commonmodels.StagePreview, 0)
-		for _, stage := range task.WorkflowArgs.Stages {
-			stagePreview := &commonmodels.StagePreview{
-				Name: stage.Name,
+	stagePreviews := make([]*commonmodels.StagePreview, 0)
+	for _, stage := range task.WorkflowArgs.Stages {
+		stagePreview := &commonmodels.StagePreview{
+			Name: stage.Name,
+		}
+		for _, job := range stage.Jobs {
+			if job.Skipped {
+				continue
 			}
-			for _, job := range stage.Jobs {
-				if job.Skipped {
-					continue
-				}
-				jobPreview := &commonmodels.JobPreview{
-					Name:    job.Name,
-					JobType: string(job.JobType),
+			jobPreview := &commonmodels.JobPreview{
+				Name:    job.Name,
+				JobType: string(job.JobType),
+			}
+			switch job.JobType {
+			case config.JobZadigBuild:
+				build := new(commonmodels.ZadigBuildJobSpec)
+				if err := commonmodels.IToi(job.Spec, build); err != nil {
+					return nil, err
 				}
-				switch job.JobType {
-				case config.JobZadigBuild:
-					build := new(commonmodels.ZadigBuildJobSpec)
-					if err := commonmodels.IToi(job.Spec, build); err != nil {
-						return nil, 0, err
+				serviceModules := make([]*commonmodels.WorkflowServiceModule, 0)
+				for _, serviceAndBuild := range build.ServiceAndBuilds {
+					sm := &commonmodels.WorkflowServiceModule{
+						ServiceName:   serviceAndBuild.ServiceName,
+						ServiceModule: serviceAndBuild.ServiceModule,
 					}
-					serviceModules := make([]*commonmodels.WorkflowServiceModule, 0)
-					for _, serviceAndBuild := range build.ServiceAndBuilds {
-						sm := &commonmodels.WorkflowServiceModule{
-							ServiceName:   serviceAndBuild.ServiceName,
-							ServiceModule: serviceAndBuild.ServiceModule,
-						}
-						for _, repo := range serviceAndBuild.Repos {
-							sm.CodeInfo = append(sm.CodeInfo, repo)
-						}
-						serviceModules = append(serviceModules, sm)
+					for _, repo := range serviceAndBuild.Repos {
+						sm.CodeInfo = append(sm.CodeInfo, repo)
 					}
-					jobPreview.ServiceModules = serviceModules
-				case config.JobZadigDeploy:
-					deploy := new(commonmodels.ZadigDeployJobSpec)
-					if err := commonmodels.IToi(job.Spec, deploy); err != nil {
-						return nil, 0, err
-					}
-					serviceModules := make([]*commonmodels.WorkflowServiceModule, 0)
-					for _, service := range deploy.ServiceAndImages {
-						sm := &commonmodels.WorkflowServiceModule{
-							ServiceName:   service.ServiceName,
-							ServiceModule: service.ServiceModule,
-						}
-						serviceModules = append(serviceModules, sm)
+					serviceModules = append(serviceModules, sm)
+				}
+				jobPreview.ServiceModules = serviceModules
+			case config.JobZadigDeploy:
+				deploy := new(commonmodels.ZadigDeployJobSpec)
+				if err := commonmodels.IToi(job.Spec, deploy); err != nil {
+					return nil, err
+				}
+				serviceModules := make([]*commonmodels.WorkflowServiceModule, 0)
+				for _, service := range deploy.ServiceAndImages {
+					sm := &commonmodels.WorkflowServiceModule{
+						ServiceName:   service.ServiceName,
+						ServiceModule: service.ServiceModule,
 					}
-					jobPreview.ServiceModules = serviceModules
-					jobPreview.Envs = &commonmodels.WorkflowEnv{
-						EnvName:    deploy.Env,
-						Production: deploy.Production,
+					serviceModules = append(serviceModules, sm)
+				}
+				jobPreview.ServiceModules = serviceModules
+				jobPreview.Envs = &commonmodels.WorkflowEnv{
+					EnvName:    deploy.Env,
+					Production: deploy.Production,
+				}
+			case config.JobZadigTesting:
+				test := new(commonmodels.ZadigTestingJobSpec)
+				if err := commonmodels.IToi(job.Spec, test); err != nil {
+					return nil, err
+				}
+
+				serviceModules := make([]*commonmodels.WorkflowServiceModule, 0)
+				for _, service := range test.ServiceAndTests {
+					sm := &commonmodels.WorkflowServiceModule{
+						ServiceName:   service.ServiceName,
+						ServiceModule: service.ServiceModule,
 					}
-				case config.JobZadigTesting:
-					test := new(commonmodels.ZadigTestingJobSpec)
-					if err := commonmodels.IToi(job.Spec, test); err != nil {
-						return nil, 0, err
+					for _, repo := range service.Repos {
+						sm.CodeInfo = append(sm.CodeInfo, repo)
 					}
+					serviceModules = append(serviceModules, sm)
+				}
+				jobPreview.ServiceModules = serviceModules
 
-					serviceModules := make([]*commonmodels.WorkflowServiceModule, 0)
-					for _, service := range test.ServiceAndTests {
-						sm := &commonmodels.WorkflowServiceModule{
-							ServiceName:   service.ServiceName,
-							ServiceModule: service.ServiceModule,
-						}
-						for _, repo := range service.Repos {
-							sm.CodeInfo = append(sm.CodeInfo, repo)
-						}
-						serviceModules = append(serviceModules, sm)
+				// get test report
+				testModules := make([]*commonmodels.WorkflowTestModule, 0)
+				for _, runningStage := range task.Stages {
+					if runningStage.Name != stage.Name {
+						continue
 					}
-					jobPreview.ServiceModules = serviceModules
-
-					// get test report
-					testModules := make([]*commonmodels.WorkflowTestModule, 0)
-					for _, runningStage := range task.Stages {
-						if runningStage.Name != stage.Name {
+					for _, runningJob := range runningStage.Jobs {
+						if runningJob.JobType != string(config.JobZadigTesting) {
 							continue
 						}
-						for _, runningJob := range runningStage.Jobs {
-							if runningJob.JobType != string(config.JobZadigTesting) {
-								continue
-							}
-							jobInfo := new(commonmodels.TaskJobInfo)
-							if err := commonmodels.IToi(runningJob.JobInfo, jobInfo); err != nil {
-								return nil, 0, err
-							}
+						jobInfo := new(commonmodels.TaskJobInfo)
+						if err := commonmodels.IToi(runningJob.JobInfo, jobInfo); err != nil {
+							return nil, err
+						}
 
-							if job.Name == jobInfo.JobName {
-								result, _ := service.GetWorkflowV4LocalTestSuite(task.WorkflowName, runningJob.Name, task.TaskID, logger)
-								if result != nil && result.FunctionTestSuite != nil {
-									duration := 0.0
-									for _, testCase := range result.FunctionTestSuite.TestCases {
-										duration += testCase.Time
-									}
-									testModule := &commonmodels.WorkflowTestModule{
-										RunningJobName: runningJob.Name,
-										Type:           "function",
-										TestName:       result.FunctionTestSuite.Name,
-										TestCaseNum:    result.FunctionTestSuite.Tests,
-										SuccessCaseNum: result.FunctionTestSuite.Successes,
-									}
-									if testModule.TestName == "" {
-										keys := strings.Split(runningJob.Key, ".")
-										testModule.TestName = keys[len(keys)-1]
-									}
-									if result.FunctionTestSuite.Time == 0 {
-										result.FunctionTestSuite.Time = math.Round(duration*1000) / 1000
-									}
-									testModule.TestTime = result.FunctionTestSuite.Time
-									testModules = append(testModules, testModule)
+						if job.Name == jobInfo.JobName {
+							result, _ := testSuiteLoader.Load(task, runningJob.Name, logger)
+							if result != nil && result.FunctionTestSuite != nil {
+								duration := 0.0
+								for _, testCase := range result.FunctionTestSuite.TestCases {
+									duration += testCase.Time
+								}
+								testModule := &commonmodels.WorkflowTestModule{
+									RunningJobName: runningJob.Name,
+									Type:           "function",
+									TestName:       result.FunctionTestSuite.Name,
+									TestCaseNum:    result.FunctionTestSuite.Tests,
+									SuccessCaseNum: result.FunctionTestSuite.Successes,
 								}
+								if testModule.TestName == "" {
+									keys := strings.Split(runningJob.Key, ".")
+									testModule.TestName = keys[len(keys)-1]
+								}
+								if result.FunctionTestSuite.Time == 0 {
+									result.FunctionTestSuite.Time = math.Round(duration*1000) / 1000
+								}
+								testModule.TestTime = result.FunctionTestSuite.Time
+								testModules = append(testModules, testModule)
 							}
 						}
 					}
-					jobPreview.TestModules = testModules
-				case config.JobZadigDistributeImage:
-					distribute := new(commonmodels.ZadigDistributeImageJobSpec)
-					if err := commonmodels.IToi(job.Spec, distribute); err != nil {
-						return nil, 0, err
-					}
-					serviceModules := make([]*commonmodels.WorkflowServiceModule, 0)
-					for _, target := range distribute.Targets {
-						sm := &commonmodels.WorkflowServiceModule{
-							ServiceName:   target.ServiceName,
-							ServiceModule: target.ServiceModule,
-						}
-						serviceModules = append(serviceModules, sm)
+				}
+				jobPreview.TestModules = testModules
+			case config.JobZadigDistributeImage:
+				distribute := new(commonmodels.ZadigDistributeImageJobSpec)
+				if err := commonmodels.IToi(job.Spec, distribute); err != nil {
+					return nil, err
+				}
+				serviceModules := make([]*commonmodels.WorkflowServiceModule, 0)
+				for _, target := range distribute.Targets {
+					sm := &commonmodels.WorkflowServiceModule{
+						ServiceName:   target.ServiceName,
+						ServiceModule: target.ServiceModule,
 					}
+					serviceModules = append(serviceModules, sm)
 				}
-				stagePreview.Jobs = append(stagePreview.Jobs, jobPreview)
-			}
-			if len(stagePreview.Jobs) > 0 {
-				stagePreviews = append(stagePreviews, stagePreview)
 			}
+			stagePreview.Jobs = append(stagePreview.Jobs, jobPreview)
 		}
+		if len(stagePreview.Jobs) > 0 {
+			stagePreviews = append(stagePreviews, stagePreview)
+		}
+	}
 
-		for _, stage := range task.Stages {
-			for _, stagePreview := range stagePreviews {
-				if stagePreview.Name == stage.Name {
-					stagePreview.Status = stage.Status
-					stagePreview.StartTime = stage.StartTime
-					stagePreview.EndTime = stage.EndTime
-					stagePreview.Approval = stage.Approval
-					stagePreview.Parallel = stage.Parallel
-					stagePreview.Error = stage.Error
-					break
-				}
+	for _, stage := range task.Stages {
+		for _, stagePreview := range stagePreviews {
+			if stagePreview.Name == stage.Name {
+				stagePreview.Status = stage.Status
+				stagePreview.StartTime = stage.StartTime
+				stagePreview.EndTime = stage.EndTime
+				stagePreview.Approval = stage.Approval
+				stagePreview.Parallel = stage.Parallel
+				stagePreview.Error = stage.Error
+				break
 			}
 		}
-		preview.Stages = stagePreviews
-		taskPreviews = append(taskPreviews, preview)
 	}
-	cleanWorkflowV4TasksPreviews(taskPreviews)
-	return taskPreviews, total, nil
+	preview.Stages = stagePreviews
+	return preview, nil
 }
 
 // clean extra message for list workflow
@@ -1093,7 +1138,7 @@ func cleanWorkflowV4TasksPreviews(workflows []*commonmodels.WorkflowTaskPreview)
 }
 
 func getLatestWorkflowTaskV4(workflowName string) (*commonmodels.WorkflowTask, error) {
-	resp, err := commonrepo.NewworkflowTaskv4Coll().GetLatest(workflowName)
+	resp, err := commonrepo.NewWorkflowTaskV4Store().GetLatest(workflowName)
 	if err != nil {
 		return nil, err
 	}
@@ -1147,7 +1192,7 @@ func CancelWorkflowTaskV4(userName, workflowName string, taskID int64, logger *z
 }
 
 func GetWorkflowTaskV4(workflowName string, taskID int64, logger *zap.SugaredLogger) (*WorkflowTaskPreview, error) {
-	task, err := commonrepo.NewworkflowTaskv4Coll().Find(workflowName, taskID)
+	task, err := commonrepo.NewWorkflowTaskV4Store().Find(workflowName, taskID)
 	if err != nil {
 		logger.Errorf("find workflowTaskV4 error: %s", err)
 		return nil, err
@@ -1168,6 +1213,14 @@ func GetWorkflowTaskV4(workflowName string, taskID int64, logger *zap.SugaredLog
 		IsRestart:           task.IsRestart,
 		Debug:               task.IsDebug,
 	}
+	timelines := make(map[string][]*commonmodels.StageTimelineEvent)
+	stageTimelines, err := commonrepo.NewWorkflowTaskStageTimelineColl().ListByTask(task.WorkflowName, task.TaskID)
+	if err != nil {
+		logger.Errorf("failed to list the stage timelines of workflow %s task %d: %v", task.WorkflowName, task.TaskID, err)
+	}
+	for _, timeline := range stageTimelines {
+		timelines[timeline.StageName] = timeline.Events
+	}
 	timeNow := time.Now().Unix()
 	for _, stage := range task.Stages {
 		resp.Stages = append(resp.Stages, &StageTaskPreview{
@@ -1179,24 +1232,128 @@ func GetWorkflowTaskV4(workflowName string, taskID int64, logger *zap.SugaredLog
 			Approval:  stage.Approval,
 			Jobs:      jobsToJobPreviews(stage.Jobs, task.GlobalContext, timeNow, task.ProjectName),
 			Error:     stage.Error,
+			Timeline:  timelines[stage.Name],
 		})
 	}
 	return resp, nil
 }
 
-func ApproveStage(workflowName, stageName, userName, userID, comment string, taskID int64, approve bool, logger *zap.SugaredLogger) error {
+func ApproveStage(workflowName, stageName, userName, userID, comment string, taskID int64, approve bool, source config.ApprovalSource, logger *zap.SugaredLogger) error {
 	if workflowName == "" || stageName == "" || taskID == 0 {
 		errMsg := fmt.Sprintf("can not find approved workflow: %s, taskID: %d,stage: %s", workflowName, taskID, stageName)
 		logger.Error(errMsg)
 		return e.ErrApproveTask.AddDesc(errMsg)
 	}
-	if err := workflowcontroller.ApproveStage(workflowName, stageName, userName, userID, comment, taskID, approve); err != nil {
+	if approve {
+		if err := checkTwoPersonRule(workflowName, stageName, userName, userID, taskID); err != nil {
+			logger.Error(err)
+			return e.ErrApproveTask.AddErr(err)
+		}
+	}
+	if err := workflowcontroller.ApproveStage(workflowName, stageName, userName, userID, comment, taskID, approve, source); err != nil {
 		logger.Error(err)
 		return e.ErrApproveTask.AddErr(err)
 	}
 	return nil
 }
 
+// HandleLarkApprovalCardAction approves or rejects the native approval stage from the card sent by the lark app. The
+// lark user clicking the button must be the approver the card was sent to, the approval is done as that zadig user.
+func HandleLarkApprovalCardAction(app *commonmodels.IMApp, event *lark.CardActionEvent, logger *zap.SugaredLogger) (*lark.CardToast, error) {
+	value := &instantmessage.ApprovalCardActionValue{}
+	if err := json.Unmarshal(event.Action.Value, value); err != nil || value.Action != instantmessage.ApprovalCardActionLark {
+		return nil, errors.New("unsupported card action")
+	}
+
+	task, err := commonrepo.NewWorkflowTaskV4Store().Find(value.WorkflowName, value.TaskID)
+	if err != nil {
+		logger.Errorf("find workflow %s task %d failed: %v", value.WorkflowName, value.TaskID, err)
+		return nil, errors.New("工作流任务不存在")
+	}
+	var approver *commonmodels.User
+	for _, stage := range task.Stages {
+		if stage.Name != value.StageName || stage.Approval == nil || stage.Approval.NativeApproval == nil {
+			continue
+		}
+		if stage.Approval.NativeApproval.LarkAppID != app.ID.Hex() {
+			return nil, errors.New("审批未关联该飞书应用")
+		}
+		for _, user := range stage.Approval.NativeApproval.ApproveUsers {
+			if user.Type != "group" && user.UserID == value.UserID {
+				approver = user
+				break
+			}
+		}
+	}
+	if approver == nil {
+		return nil, errors.New("您不是该阶段的审批人")
+	}
+
+	openID, err := lark.GetUserOpenIDByZadigUser(larktool.NewClient(app.AppID, app.AppSecret), approver.UserID)
+	if err != nil {
+		logger.Errorf("find lark user of approver %s failed: %v", approver.UserName, err)
+		return nil, errors.New("未找到审批人对应的飞书用户")
+	}
+	if openID != event.Operator.OpenID {
+		logger.Warnf("lark user %s operated the approval card of %s", event.Operator.OpenID, approver.UserName)
+		return nil, errors.New("您不是该阶段的审批人")
+	}
+
+	if err := ApproveStage(value.WorkflowName, value.StageName, approver.UserName, approver.UserID, "", value.TaskID, value.Approve, config.ApprovalSourceLark, logger); err != nil {
+		return nil, err
+	}
+	content := "审批已通过"
+	if !value.Approve {
+		content = "审批已拒绝"
+	}
+	return &lark.CardToast{Type: "success", Content: content}, nil
+}
+
+// HandleDingTalkApprovalAction handles the approve or reject button clicked on the native approval card sent by a dingtalk app
+func HandleDingTalkApprovalAction(appKey, token string, logger *zap.SugaredLogger) (string, error) {
+	app, err := commonrepo.NewIMAppColl().GetDingTalkByAppKey(context.TODO(), appKey)
+	if err != nil {
+		logger.Errorf("find dingtalk app %s failed: %v", appKey, err)
+		return "", errors.New("钉钉应用不存在")
+	}
+	action, err := dingtalk.ParseApprovalAction(app.DingTalkAppSecret, token)
+	if err != nil {
+		return "", err
+	}
+
+	task, err := commonrepo.NewWorkflowTaskV4Store().Find(action.WorkflowName, action.TaskID)
+	if err != nil {
+		logger.Errorf("find workflow %s task %d failed: %v", action.WorkflowName, action.TaskID, err)
+		return "", errors.New("工作流任务不存在")
+	}
+	var approver *commonmodels.User
+	for _, stage := range task.Stages {
+		if stage.Name != action.StageName || stage.Approval == nil || stage.Approval.NativeApproval == nil {
+			continue
+		}
+		if stage.Approval.NativeApproval.DingTalkAppID != app.ID.Hex() {
+			return "", errors.New("审批未关联该钉钉应用")
+		}
+		for _, user := range stage.Approval.NativeApproval.ApproveUsers {
+			if user.Type != "group" && user.UserID == action.UserID {
+				approver = user
+				break
+			}
+		}
+	}
+	if approver == nil {
+		return "", errors.New("您不是该阶段的审批人")
+	}
+
+	if err := ApproveStage(action.WorkflowName, action.StageName, approver.UserName, approver.UserID, "", action.TaskID, action.Approve, config.ApprovalSourceDingTalk, logger); err != nil {
+		return "", err
+	}
+	if !action.Approve {
+		return "审批已拒绝", nil
+	}
+	return "审批已通过", nil
+}
+
 func jobsToJobPreviews(jobs []*commonmodels.JobTask, context map[string]string, now int64, projectName string) []*JobTaskPreview {
 	resp := []*JobTaskPreview{}
 
@@ -1254,7 +1411,11 @@ func jobsToJobPreviews(jobs []*commonmodels.JobTask, context map[string]string,
 				}
 			}
 			// get image from global context
-			imageContextKey := workflowcontroller.GetContextKey(jobspec.GetJobOutputKey(job.Key, "IMAGE"))
+			outputKey := job.Key
+			if job.OutputKey != "" {
+				outputKey = job.OutputKey
+			}
+			imageContextKey := workflowcontroller.GetContextKey(jobspec.GetJobOutputKey(outputKey, "IMAGE"))
 			if context != nil {
 				spec.Image = context[imageContextKey]
 			}
@@ -1430,6 +1591,13 @@ func jobsToJobPreviews(jobs []*commonmodels.JobTask, context map[string]string,
 				continue
 			}
 			jobPreview.Spec = taskJobSpec.Plugin
+		case string(config.JobHTTPCallback):
+			taskJobSpec := &commonmodels.JobTaskHTTPCallbackSpec{}
+			if err := commonmodels.IToi(job.Spec, taskJobSpec); err != nil {
+				continue
+			}
+			taskJobSpec.Headers = maskHTTPCallbackHeaders(taskJobSpec.Headers)
+			jobPreview.Spec = taskJobSpec
 		case string(config.JobCustomDeploy):
 			spec := CustomDeployJobSpec{}
 			taskJobSpec := &commonmodels.JobTaskCustomDeploySpec{}
@@ -1459,6 +1627,7 @@ func jobsToJobPreviews(jobs []*commonmodels.JobTask, context map[string]string,
 				ContainerName:  taskJobSpec.ContainerName,
 				CanaryReplica:  taskJobSpec.CanaryReplica,
 				Events:         taskJobSpec.Events,
+				AnalysisResult: taskJobSpec.AnalysisResult,
 			}
 			cluster, err := commonrepo.NewK8SClusterColl().Get(taskJobSpec.ClusterID)
 			if err != nil {
@@ -1494,6 +1663,24 @@ func jobsToJobPreviews(jobs []*commonmodels.JobTask, context map[string]string,
 	return resp
 }
 
+// maskHTTPCallbackHeaders masks the values of the headers carrying the credentials, like the authorization header
+// and the api keys, the task keeps the values for the request
+func maskHTTPCallbackHeaders(headers []*commonmodels.KV) []*commonmodels.KV {
+	resp := make([]*commonmodels.KV, 0, len(headers))
+	for _, header := range headers {
+		name := strings.ToLower(header.Key)
+		masked := &commonmodels.KV{Key: header.Key, Value: header.Value}
+		for _, keyword := range []string{"authorization", "cookie", "token", "secret", "password", "key", "signature"} {
+			if strings.Contains(name, keyword) {
+				masked.Value = setting.MaskValue
+				break
+			}
+		}
+		resp = append(resp, masked)
+	}
+	return resp
+}
+
 func setZadigParamRepos(workflow *commonmodels.WorkflowV4, logger *zap.SugaredLogger) {
 	for _, param := range workflow.Params {
 		if param.ParamsType != "repo" {
@@ -1625,7 +1812,7 @@ func workflowTaskLint(workflowTask *commonmodels.WorkflowTask, logger *zap.Sugar
 }
 
 func GetWorkflowV4ArtifactFileContent(workflowName, jobName string, taskID int64, log *zap.SugaredLogger) ([]byte, error) {
-	workflowTask, err := commonrepo.NewworkflowTaskv4Coll().Find(workflowName, taskID)
+	workflowTask, err := commonrepo.NewWorkflowTaskV4Store().Find(workflowName, taskID)
 	if err != nil {
 		return []byte{}, fmt.Errorf("cannot find workflow task, workflow name: %s, task id: %d", workflowName, taskID)
 	}
@@ -1702,7 +1889,7 @@ func ListWorkflowFilterInfo(project, workflow, typeName string, jobName string,
 
 	switch typeName {
 	case "creator":
-		resp, err := commonrepo.NewworkflowTaskv4Coll().ListCreator(project, workflow)
+		resp, err := commonrepo.NewWorkflowTaskV4Store().ListCreator(project, workflow)
 		if err != nil {
 			logger.Errorf("ListWorkflowTaskCreator ListCreator err:%v", err)
 			return []string{}, fmt.Errorf("ListCreator err: %v", err)
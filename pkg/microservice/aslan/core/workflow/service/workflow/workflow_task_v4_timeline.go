@@ -0,0 +1,146 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const (
+	JobPhaseQueued     = "queued"
+	JobPhaseScheduling = "scheduling"
+	JobPhasePulling    = "pulling"
+	JobPhaseRunning    = "running"
+	JobPhaseCollecting = "collecting"
+)
+
+// WorkflowTaskTimeline shows where the time of the task was spent, QueueDuration is the seconds the task
+// waited in the task queue before it started
+type WorkflowTaskTimeline struct {
+	TaskID        int64          `json:"task_id"`
+	Status        config.Status  `json:"status"`
+	CreateTime    int64          `json:"create_time"`
+	StartTime     int64          `json:"start_time"`
+	EndTime       int64          `json:"end_time"`
+	QueueDuration int64          `json:"queue_duration"`
+	Jobs          []*JobTimeline `json:"jobs"`
+}
+
+type JobTimeline struct {
+	Name      string        `json:"name"`
+	StageName string        `json:"stage_name"`
+	JobType   string        `json:"job_type"`
+	Status    config.Status `json:"status"`
+	StartTime int64         `json:"start_time"`
+	EndTime   int64         `json:"end_time"`
+	Phases    []*JobPhase   `json:"phases"`
+}
+
+// JobPhase is a phase of the job, Duration is in seconds, the phase is still going on if EndTime is 0
+type JobPhase struct {
+	Name      string `json:"name"`
+	StartTime int64  `json:"start_time"`
+	EndTime   int64  `json:"end_time"`
+	Duration  int64  `json:"duration"`
+}
+
+func GetWorkflowTaskV4Timeline(workflowName string, taskID int64, logger *zap.SugaredLogger) (*WorkflowTaskTimeline, error) {
+	task, err := commonrepo.NewWorkflowTaskV4Store().Find(workflowName, taskID)
+	if err != nil {
+		logger.Errorf("find workflowTaskV4 error: %s", err)
+		return nil, e.ErrGetTask.AddErr(err)
+	}
+
+	now := time.Now().Unix()
+	resp := &WorkflowTaskTimeline{
+		TaskID:     task.TaskID,
+		Status:     task.Status,
+		CreateTime: task.CreateTime,
+		StartTime:  task.StartTime,
+		EndTime:    task.EndTime,
+		Jobs:       make([]*JobTimeline, 0),
+	}
+	if task.StartTime > 0 {
+		resp.QueueDuration = task.StartTime - task.CreateTime
+	} else if task.Status == config.StatusQueued || task.Status == config.StatusCreated {
+		resp.QueueDuration = now - task.CreateTime
+	}
+
+	for _, stage := range task.Stages {
+		for _, job := range stage.Jobs {
+			resp.Jobs = append(resp.Jobs, &JobTimeline{
+				Name:      job.Name,
+				StageName: stage.Name,
+				JobType:   job.JobType,
+				Status:    job.Status,
+				StartTime: job.StartTime,
+				EndTime:   job.EndTime,
+				Phases:    getJobPhases(job, now),
+			})
+		}
+	}
+	return resp, nil
+}
+
+// getJobPhases splits the job time by the timeline, a phase ends when the next reached phase starts
+func getJobPhases(job *commonmodels.JobTask, now int64) []*JobPhase {
+	if job.Timeline == nil {
+		if job.StartTime == 0 {
+			return []*JobPhase{}
+		}
+		return newJobPhases([]*JobPhase{{Name: JobPhaseRunning, StartTime: job.StartTime}}, job.EndTime, now)
+	}
+	phases := make([]*JobPhase, 0)
+	for _, phase := range []*JobPhase{
+		{Name: JobPhaseQueued, StartTime: job.Timeline.QueuedTime},
+		{Name: JobPhaseScheduling, StartTime: job.Timeline.PodCreatedTime},
+		{Name: JobPhasePulling, StartTime: job.Timeline.PodScheduledTime},
+		{Name: JobPhaseRunning, StartTime: job.Timeline.RunningTime},
+		{Name: JobPhaseCollecting, StartTime: job.Timeline.CollectingTime},
+	} {
+		if phase.StartTime > 0 {
+			phases = append(phases, phase)
+		}
+	}
+	return newJobPhases(phases, job.EndTime, now)
+}
+
+func newJobPhases(phases []*JobPhase, endTime, now int64) []*JobPhase {
+	for i, phase := range phases {
+		if i+1 < len(phases) {
+			phase.EndTime = phases[i+1].StartTime
+		} else {
+			phase.EndTime = endTime
+		}
+		if phase.EndTime > 0 {
+			phase.Duration = phase.EndTime - phase.StartTime
+		} else {
+			phase.Duration = now - phase.StartTime
+		}
+		if phase.Duration < 0 {
+			phase.Duration = 0
+		}
+	}
+	return phases
+}
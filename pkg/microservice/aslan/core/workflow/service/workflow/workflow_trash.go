@@ -0,0 +1,223 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowcontroller"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+const defaultWorkflowTrashRetentionDays = 7
+
+// DeleteWorkflowV4 moves the workflow to the trash, its triggers are paused and its tasks are kept until the
+// retention window expires, the workflow can't be deleted while the trigger jobs of other workflows reference it
+func DeleteWorkflowV4(name, userName string, logger *zap.SugaredLogger) error {
+	workflow, err := commonrepo.NewWorkflowV4Coll().Find(name)
+	if err != nil {
+		logger.Errorf("Failed to delete WorkflowV4: %s, the error is: %v", name, err)
+		return e.ErrDeleteWorkflow.AddErr(err)
+	}
+	referrers, err := getTriggerJobReferrers(name)
+	if err != nil {
+		logger.Errorf("Failed to find the workflows referencing WorkflowV4: %s, the error is: %v", name, err)
+		return e.ErrDeleteWorkflow.AddErr(err)
+	}
+	if len(referrers) > 0 {
+		return e.ErrDeleteWorkflow.AddDesc(fmt.Sprintf("工作流被以下工作流的触发任务引用: %s", strings.Join(referrers, ", ")))
+	}
+	if _, err := commonrepo.NewWorkflowV4TrashColl().Find(name); err == nil {
+		return e.ErrDeleteWorkflow.AddDesc(fmt.Sprintf("workflow %s already exists in the trash", name))
+	}
+
+	queues, err := commonrepo.NewWorkflowQueueColl().List(&commonrepo.ListWorfklowQueueOption{WorkflowName: name})
+	if err != nil {
+		logger.Errorf("Failed to list queued tasks of WorkflowV4: %s, the error is: %v", name, err)
+		return e.ErrDeleteWorkflow.AddErr(err)
+	}
+	for _, queue := range queues {
+		if err := workflowcontroller.CancelWorkflowTask(userName, queue.WorkflowName, queue.TaskID, logger); err != nil {
+			logger.Errorf("Failed to cancel task %d of WorkflowV4: %s, the error is: %v", queue.TaskID, name, err)
+		}
+	}
+
+	triggersPaused := false
+	if workflow.TriggerPause == nil {
+		if err := pauseWorkflowTriggers(workflow, userName, "workflow deleted", logger); err != nil {
+			return e.ErrDeleteWorkflow.AddErr(err)
+		}
+		triggersPaused = true
+	}
+
+	now := time.Now()
+	trash := &commonmodels.WorkflowV4Trash{
+		WorkflowName:   workflow.Name,
+		DisplayName:    workflow.DisplayName,
+		ProjectName:    workflow.Project,
+		Workflow:       workflow,
+		TriggersPaused: triggersPaused,
+		DeletedBy:      userName,
+		DeleteTime:     now.Unix(),
+		ExpireTime:     now.AddDate(0, 0, int(getWorkflowTrashRetentionDays())).Unix(),
+	}
+	if err := commonrepo.NewWorkflowV4TrashColl().Create(trash); err != nil {
+		logger.Errorf("Failed to move WorkflowV4: %s to the trash, the error is: %v", name, err)
+		return e.ErrDeleteWorkflow.AddErr(err)
+	}
+	if err := commonrepo.NewWorkflowV4Coll().DeleteByID(workflow.ID.Hex()); err != nil {
+		logger.Errorf("Failed to delete WorkflowV4: %s, the error is: %v", name, err)
+		return e.ErrDeleteWorkflow.AddErr(err)
+	}
+	return nil
+}
+
+func ListWorkflowV4Trash(projectName string, logger *zap.SugaredLogger) ([]*commonmodels.WorkflowV4Trash, error) {
+	resp, err := commonrepo.NewWorkflowV4TrashColl().List(projectName)
+	if err != nil {
+		logger.Errorf("Failed to list the trashed workflows of project %s, the error is: %v", projectName, err)
+		return nil, e.ErrListWorkflow.AddErr(err)
+	}
+	return resp, nil
+}
+
+func FindWorkflowV4Trash(name string, logger *zap.SugaredLogger) (*commonmodels.WorkflowV4Trash, error) {
+	trash, err := commonrepo.NewWorkflowV4TrashColl().Find(name)
+	if err != nil {
+		logger.Errorf("Failed to find the trashed workflow %s, the error is: %v", name, err)
+		return nil, e.ErrFindWorkflow.AddErr(err)
+	}
+	return trash, nil
+}
+
+// RestoreWorkflowV4 moves the workflow back from the trash and resumes the triggers paused by the deletion
+func RestoreWorkflowV4(name string, logger *zap.SugaredLogger) error {
+	trash, err := FindWorkflowV4Trash(name, logger)
+	if err != nil {
+		return err
+	}
+	if existed, err := commonrepo.NewWorkflowV4Coll().Find(name); err == nil {
+		return e.ErrUpsertWorkflow.AddDesc(fmt.Sprintf("与项目 [%s] 中的工作流 [%s] 标识相同", existed.Project, existed.DisplayName))
+	} else if err != mongo.ErrNoDocuments {
+		return e.ErrUpsertWorkflow.AddErr(err)
+	}
+	existedWorkflows, _, _ := commonrepo.NewWorkflowV4Coll().List(&commonrepo.ListWorkflowV4Option{ProjectName: trash.ProjectName, DisplayName: trash.DisplayName}, 0, 0)
+	if len(existedWorkflows) > 0 {
+		return e.ErrUpsertWorkflow.AddDesc(fmt.Sprintf("当前项目已存在工作流 [%s]", trash.DisplayName))
+	}
+
+	workflow := trash.Workflow
+	if _, err := commonrepo.NewWorkflowV4Coll().Create(workflow); err != nil {
+		logger.Errorf("Failed to restore WorkflowV4: %s, the error is: %v", name, err)
+		return e.ErrUpsertWorkflow.AddErr(err)
+	}
+	if err := commonrepo.NewWorkflowV4TrashColl().Delete(name); err != nil {
+		logger.Errorf("Failed to remove WorkflowV4: %s from the trash, the error is: %v", name, err)
+		return e.ErrUpsertWorkflow.AddErr(err)
+	}
+	if trash.TriggersPaused && workflow.TriggerPause != nil {
+		return resumeWorkflowTriggers(workflow, logger)
+	}
+	return nil
+}
+
+// PurgeWorkflowV4 permanently deletes the trashed workflow with its tasks
+func PurgeWorkflowV4(name string, logger *zap.SugaredLogger) error {
+	trash, err := FindWorkflowV4Trash(name, logger)
+	if err != nil {
+		return err
+	}
+	return purgeWorkflowV4Trash(trash, logger)
+}
+
+func purgeWorkflowV4Trash(trash *commonmodels.WorkflowV4Trash, logger *zap.SugaredLogger) error {
+	if err := commonservice.PurgeWorkflowV4(trash.Workflow, logger); err != nil {
+		return err
+	}
+	if err := commonrepo.NewWorkflowV4TrashColl().Delete(trash.WorkflowName); err != nil {
+		logger.Errorf("Failed to remove WorkflowV4: %s from the trash, the error is: %v", trash.WorkflowName, err)
+		return e.ErrDeleteWorkflow.AddErr(err)
+	}
+	return nil
+}
+
+// CleanExpiredWorkflowV4Trash purges the workflows whose retention window has expired
+func CleanExpiredWorkflowV4Trash() {
+	trashes, err := commonrepo.NewWorkflowV4TrashColl().ListExpired(time.Now().Unix())
+	if err != nil {
+		log.Errorf("failed to list the expired trashed workflows, err: %s", err)
+		return
+	}
+	logger := log.SugaredLogger()
+	for _, trash := range trashes {
+		if err := purgeWorkflowV4Trash(trash, logger); err != nil {
+			log.Errorf("failed to purge the trashed workflow %s, err: %s", trash.WorkflowName, err)
+		}
+	}
+}
+
+func getWorkflowTrashRetentionDays() int64 {
+	systemSetting, err := commonrepo.NewSystemSettingColl().Get()
+	if err != nil || systemSetting.WorkflowTrash == nil || systemSetting.WorkflowTrash.RetentionDays <= 0 {
+		return defaultWorkflowTrashRetentionDays
+	}
+	return systemSetting.WorkflowTrash.RetentionDays
+}
+
+// getTriggerJobReferrers returns the workflows whose trigger jobs trigger the workflow
+func getTriggerJobReferrers(workflowName string) ([]string, error) {
+	workflows, _, err := commonrepo.NewWorkflowV4Coll().List(&commonrepo.ListWorkflowV4Option{
+		JobTypes: []config.JobType{config.JobWorkflowTrigger},
+	}, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	referrers := sets.NewString()
+	for _, workflow := range workflows {
+		if workflow.Name == workflowName {
+			continue
+		}
+		for _, stage := range workflow.Stages {
+			for _, job := range stage.Jobs {
+				if job.JobType != config.JobWorkflowTrigger {
+					continue
+				}
+				spec := new(commonmodels.WorkflowTriggerJobSpec)
+				if err := commonmodels.IToi(job.Spec, spec); err != nil {
+					return nil, err
+				}
+				for _, info := range append(spec.FixedWorkflowList, spec.ServiceTriggerWorkflow...) {
+					if info.WorkflowName == workflowName {
+						referrers.Insert(workflow.Name)
+					}
+				}
+			}
+		}
+	}
+	return referrers.List(), nil
+}
@@ -27,8 +27,11 @@ import (
 	"strings"
 	"sync"
 	"time"
+	// the images do not ship the zoneinfo, the time zones of the crons are loaded from the embedded database
+	_ "time/tzdata"
 
 	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
 	"github.com/samber/lo"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -60,7 +63,7 @@ import (
 	larkservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/lark"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/repository"
 	commomtemplate "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/template"
-	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/webhook"
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/common/service/workflowpolicy"
 	commontypes "github.com/koderover/zadig/pkg/microservice/aslan/core/common/types"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow/job"
 	jobctl "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow/job"
@@ -76,6 +79,7 @@ import (
 	"github.com/koderover/zadig/pkg/tool/lark"
 	"github.com/koderover/zadig/pkg/tool/log"
 	"github.com/koderover/zadig/pkg/types"
+	"github.com/koderover/zadig/pkg/util"
 )
 
 func CreateWorkflowV4(user string, workflow *commonmodels.WorkflowV4, logger *zap.SugaredLogger) error {
@@ -84,6 +88,10 @@ func CreateWorkflowV4(user string, workflow *commonmodels.WorkflowV4, logger *za
 		errStr := fmt.Sprintf("与项目 [%s] 中的工作流 [%s] 标识相同", existedWorkflow.Project, existedWorkflow.DisplayName)
 		return e.ErrUpsertWorkflow.AddDesc(errStr)
 	}
+	if trash, err := commonrepo.NewWorkflowV4TrashColl().Find(workflow.Name); err == nil {
+		errStr := fmt.Sprintf("与回收站中项目 [%s] 的工作流 [%s] 标识相同", trash.ProjectName, trash.DisplayName)
+		return e.ErrUpsertWorkflow.AddDesc(errStr)
+	}
 	existedWorkflows, _, _ := commonrepo.NewWorkflowV4Coll().List(&commonrepo.ListWorkflowV4Option{ProjectName: workflow.Project, DisplayName: workflow.DisplayName}, 0, 0)
 	if len(existedWorkflows) > 0 {
 		errStr := fmt.Sprintf("当前项目已存在工作流 [%s]", workflow.DisplayName)
@@ -92,6 +100,12 @@ func CreateWorkflowV4(user string, workflow *commonmodels.WorkflowV4, logger *za
 	if err := LintWorkflowV4(workflow, logger); err != nil {
 		return err
 	}
+	if err := LintWorkflowV4OnSave(workflow, logger); err != nil {
+		return err
+	}
+	if err := LintWorkflowV4Policies(workflow, logger); err != nil {
+		return err
+	}
 	// lark approval different node type need different approval definition
 	// check whether lark approvals in workflow need to create lark approval definition
 	if err := createLarkApprovalDefinition(workflow); err != nil {
@@ -116,6 +130,9 @@ func CreateWorkflowV4(user string, workflow *commonmodels.WorkflowV4, logger *za
 }
 
 func SetWorkflowTasksCustomFields(projectName, workflowName string, args *models.CustomField, logger *zap.SugaredLogger) error {
+	if err := validateCustomField(args); err != nil {
+		return e.ErrInvalidParam.AddErr(err)
+	}
 	err := commonrepo.NewWorkflowV4Coll().SetCustomFields(commonrepo.SetCustomFieldsOptions{
 		ProjectName:  projectName,
 		WorkflowName: workflowName,
@@ -139,16 +156,10 @@ func GetWorkflowTasksCustomFields(projectName, workflowName string, logger *zap.
 
 	fields := workflow.CustomField
 	if fields == nil {
-		fields = &models.CustomField{
-			TaskID:                 1,
-			Status:                 1,
-			Duration:               1,
-			Executor:               1,
-			BuildServiceComponent:  make(map[string]int),
-			BuildCodeMsg:           make(map[string]int),
-			DeployServiceComponent: make(map[string]int),
-			DeployEnv:              make(map[string]int),
-			TestResult:             make(map[string]int),
+		fields, err = getProjectDefaultCustomField(projectName)
+		if err != nil {
+			logger.Errorf("Failed to get project: %s default custom fields, the error is: %s", projectName, err)
+			return nil, e.ErrGetProduct.AddErr(err)
 		}
 	}
 
@@ -256,6 +267,12 @@ func UpdateWorkflowV4(name, user string, inputWorkflow *commonmodels.WorkflowV4,
 	if err := LintWorkflowV4(inputWorkflow, logger); err != nil {
 		return err
 	}
+	if err := LintWorkflowV4OnSave(inputWorkflow, logger); err != nil {
+		return err
+	}
+	if err := LintWorkflowV4Policies(inputWorkflow, logger); err != nil {
+		return err
+	}
 
 	inputWorkflow.UpdatedBy = user
 	inputWorkflow.UpdateTime = time.Now().Unix()
@@ -263,8 +280,11 @@ func UpdateWorkflowV4(name, user string, inputWorkflow *commonmodels.WorkflowV4,
 	inputWorkflow.HookCtls = workflow.HookCtls
 	inputWorkflow.JiraHookCtls = workflow.JiraHookCtls
 	inputWorkflow.GeneralHookCtls = workflow.GeneralHookCtls
+	inputWorkflow.RegistryHookCtls = workflow.RegistryHookCtls
 	inputWorkflow.MeegoHookCtls = workflow.MeegoHookCtls
 	inputWorkflow.CustomField = workflow.CustomField
+	inputWorkflow.MeegoWorkItems = nil
+	inputWorkflow.TriggerPause = workflow.TriggerPause
 
 	for _, stage := range inputWorkflow.Stages {
 		for _, job := range stage.Jobs {
@@ -280,8 +300,10 @@ func UpdateWorkflowV4(name, user string, inputWorkflow *commonmodels.WorkflowV4,
 		return errors.Wrap(err, "create lark approval definition")
 	}
 
-	if err := commonrepo.NewWorkflowV4Coll().Update(
+	// the triggers copied above must not overwrite the ones changed meanwhile
+	if err := commonrepo.NewWorkflowV4Coll().UpdateIfUnchanged(
 		workflow.ID.Hex(),
+		workflow.Hash,
 		inputWorkflow,
 	); err != nil {
 		logger.Errorf("update workflowV4 error: %s", err)
@@ -316,26 +338,6 @@ func FindWorkflowV4Raw(name string, logger *zap.SugaredLogger) (*commonmodels.Wo
 	return workflow, err
 }
 
-func DeleteWorkflowV4(name string, logger *zap.SugaredLogger) error {
-	workflow, err := commonrepo.NewWorkflowV4Coll().Find(name)
-	if err != nil {
-		logger.Errorf("Failed to delete WorkflowV4: %s, the error is: %v", name, err)
-		return e.ErrDeleteWorkflow.AddErr(err)
-	}
-	if err := commonrepo.NewWorkflowV4Coll().DeleteByID(workflow.ID.Hex()); err != nil {
-		logger.Errorf("Failed to delete WorkflowV4: %s, the error is: %v", name, err)
-		return e.ErrDeleteWorkflow.AddErr(err)
-	}
-	if err := commonrepo.NewworkflowTaskv4Coll().DeleteByWorkflowName(name); err != nil {
-		logger.Errorf("Failed to delete WorkflowV4 task: %s, the error is: %v", name, err)
-		return e.ErrDeleteWorkflow.AddErr(err)
-	}
-	if err := commonrepo.NewCounterColl().Delete("WorkflowTaskV4:" + name); err != nil {
-		log.Errorf("Counter.Delete error: %s", err)
-	}
-	return nil
-}
-
 func ListWorkflowV4(projectName, viewName, userID string, names, v4Names []string, policyFound bool, logger *zap.SugaredLogger) ([]*Workflow, error) {
 	resp := make([]*Workflow, 0)
 	var err error
@@ -402,7 +404,7 @@ func ListWorkflowV4(projectName, viewName, userID string, names, v4Names []strin
 		wg.Add(1)
 		go func(workflowName string) {
 			defer wg.Done()
-			resp, _, err2 := commonrepo.NewworkflowTaskv4Coll().List(&commonrepo.ListWorkflowTaskV4Option{
+			resp, _, err2 := commonrepo.NewWorkflowTaskV4Store().List(&commonrepo.ListWorkflowTaskV4Option{
 				WorkflowName: workflowName,
 				Limit:        10,
 			})
@@ -700,6 +702,7 @@ func clearWorkflowV4Triggers(workflow *commonmodels.WorkflowV4) {
 	workflow.MeegoHookCtls = nil
 	workflow.GeneralHookCtls = nil
 	workflow.JiraHookCtls = nil
+	workflow.RegistryHookCtls = nil
 }
 
 func ensureWorkflowV4Resp(encryptedKey string, workflow *commonmodels.WorkflowV4, logger *zap.SugaredLogger) error {
@@ -870,6 +873,10 @@ func LintWorkflowV4(workflow *commonmodels.WorkflowV4, logger *zap.SugaredLogger
 			return e.ErrUpsertWorkflow.AddDesc("common workflow only support k8s and helm project")
 		}
 	}
+	if err := lintMeegoSync(workflow.MeegoSync); err != nil {
+		logger.Errorf("meego sync of workflow %s error: %v", workflow.Name, err)
+		return e.ErrUpsertWorkflow.AddDesc(fmt.Sprintf("meego sync error: %v", err))
+	}
 	stageNameMap := make(map[string]bool)
 	jobNameMap := make(map[string]string)
 
@@ -909,6 +916,47 @@ func LintWorkflowV4(workflow *commonmodels.WorkflowV4, logger *zap.SugaredLogger
 	return nil
 }
 
+// LintWorkflowV4OnSave runs the lint of the jobs which only runs when the workflow is saved, not when a task is created
+func LintWorkflowV4OnSave(workflow *commonmodels.WorkflowV4, logger *zap.SugaredLogger) error {
+	for _, stage := range workflow.Stages {
+		for _, job := range stage.Jobs {
+			if err := jobctl.LintJobOnSave(job, workflow); err != nil {
+				logger.Errorf("lint job %s failed: %v", job.Name, err)
+				return e.ErrUpsertWorkflow.AddErr(err)
+			}
+		}
+	}
+	return nil
+}
+
+// LintWorkflowV4Policies evaluates the workflow policies uploaded by the administrators, the violations are
+// returned in the extra of the error
+func LintWorkflowV4Policies(workflow *commonmodels.WorkflowV4, logger *zap.SugaredLogger) error {
+	violations, err := workflowpolicy.Evaluate(workflow)
+	if err != nil {
+		logger.Errorf("failed to evaluate policies of workflow %s: %v", workflow.Name, err)
+		return e.ErrUpsertWorkflow.AddErr(err)
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return e.NewWithExtras(e.ErrUpsertWorkflow, fmt.Sprintf("workflow policies are violated: %s", strings.Join(violations, "; ")),
+		map[string]interface{}{"violations": violations})
+}
+
+func lintMeegoSync(meegoSync *commonmodels.MeegoSyncConfig) error {
+	if meegoSync == nil || !meegoSync.Enabled {
+		return nil
+	}
+	if _, err := commonrepo.NewProjectManagementColl().GetMeegoByID(meegoSync.MeegoID); err != nil {
+		return errors.Errorf("failed to find meego integration %s: %v", meegoSync.MeegoID, err)
+	}
+	if meegoSync.PassedStateKey == "" && meegoSync.FailedStateKey == "" && !meegoSync.Comment {
+		return errors.New("neither the target state nor the comment is set")
+	}
+	return nil
+}
+
 func lintApprovals(approval *commonmodels.Approval) error {
 	if approval == nil {
 		return nil
@@ -1057,14 +1105,20 @@ func CreateWebhookForWorkflowV4(workflowName string, input *commonmodels.Workflo
 		logger.Errorf(err.Error())
 		return e.ErrCreateWebhook.AddErr(err)
 	}
-	err = commonservice.ProcessWebhook([]*models.WorkflowV4Hook{input}, nil, webhook.WorkflowV4Prefix+workflowName, logger)
-	if err != nil {
-		errMsg := fmt.Sprintf("failed to create webhook for workflow %s, the error is: %v", workflowName, err)
-		log.Error(errMsg)
-		return e.ErrCreateWebhook.AddDesc(errMsg)
+	if err := validateGerritVotes(input.GerritVotes); err != nil {
+		logger.Errorf(err.Error())
+		return e.ErrCreateWebhook.AddErr(err)
+	}
+	if _, err := util.ParseIPAllowlist(input.IPAllowlist); err != nil {
+		return e.ErrCreateWebhook.AddErr(err)
 	}
+	hash := workflow.Hash
 	workflow.HookCtls = append(workflow.HookCtls, input)
-	if err := commonrepo.NewWorkflowV4Coll().Update(workflow.ID.Hex(), workflow); err != nil {
+	err = runHookSteps([]*hookStep{
+		registerWebhookStep(workflowName, []*models.WorkflowV4Hook{input}, nil, logger),
+		saveWorkflowStep(workflow, hash),
+	}, logger)
+	if err != nil {
 		errMsg := fmt.Sprintf("failed to create webhook for workflow %s, the error is: %v", workflowName, err)
 		log.Error(errMsg)
 		return e.ErrCreateWebhook.AddDesc(errMsg)
@@ -1107,14 +1161,20 @@ func UpdateWebhookForWorkflowV4(workflowName string, input *commonmodels.Workflo
 		logger.Errorf(err.Error())
 		return e.ErrUpdateWebhook.AddErr(err)
 	}
-	err = commonservice.ProcessWebhook([]*models.WorkflowV4Hook{input}, []*models.WorkflowV4Hook{existHook}, webhook.WorkflowV4Prefix+workflowName, logger)
-	if err != nil {
-		errMsg := fmt.Sprintf("failed to update webhook for workflow %s, the error is: %v", workflowName, err)
-		log.Error(errMsg)
-		return e.ErrUpdateWebhook.AddDesc(errMsg)
+	if err := validateGerritVotes(input.GerritVotes); err != nil {
+		logger.Errorf(err.Error())
+		return e.ErrUpdateWebhook.AddErr(err)
+	}
+	if _, err := util.ParseIPAllowlist(input.IPAllowlist); err != nil {
+		return e.ErrUpdateWebhook.AddErr(err)
 	}
+	hash := workflow.Hash
 	workflow.HookCtls = updatedHooks
-	if err := commonrepo.NewWorkflowV4Coll().Update(workflow.ID.Hex(), workflow); err != nil {
+	err = runHookSteps([]*hookStep{
+		registerWebhookStep(workflowName, []*models.WorkflowV4Hook{input}, []*models.WorkflowV4Hook{existHook}, logger),
+		saveWorkflowStep(workflow, hash),
+	}, logger)
+	if err != nil {
 		errMsg := fmt.Sprintf("failed to update webhook for workflow %s, the error is: %v", workflowName, err)
 		log.Error(errMsg)
 		return e.ErrUpdateWebhook.AddDesc(errMsg)
@@ -1173,6 +1233,7 @@ func GetWebhookForWorkflowV4Preset(workflowName, triggerName string, logger *zap
 	workflowHook.WorkflowArg.JiraHookCtls = nil
 	workflowHook.WorkflowArg.MeegoHookCtls = nil
 	workflowHook.WorkflowArg.GeneralHookCtls = nil
+	workflowHook.WorkflowArg.RegistryHookCtls = nil
 	workflowHook.WorkflowArg.HookCtls = nil
 	return workflowHook, nil
 }
@@ -1197,14 +1258,13 @@ func DeleteWebhookForWorkflowV4(workflowName, triggerName string, logger *zap.Su
 		logger.Error(errMsg)
 		return e.ErrDeleteWebhook.AddDesc(errMsg)
 	}
-	err = commonservice.ProcessWebhook(nil, []*models.WorkflowV4Hook{existHook}, webhook.WorkflowV4Prefix+workflowName, logger)
-	if err != nil {
-		errMsg := fmt.Sprintf("failed to delete webhook for workflow %s, the error is: %v", workflowName, err)
-		log.Error(errMsg)
-		return e.ErrDeleteWebhook.AddDesc(errMsg)
-	}
+	hash := workflow.Hash
 	workflow.HookCtls = updatedHooks
-	if err := commonrepo.NewWorkflowV4Coll().Update(workflow.ID.Hex(), workflow); err != nil {
+	err = runHookSteps([]*hookStep{
+		registerWebhookStep(workflowName, nil, []*models.WorkflowV4Hook{existHook}, logger),
+		saveWorkflowStep(workflow, hash),
+	}, logger)
+	if err != nil {
 		errMsg := fmt.Sprintf("failed to delete webhook for workflow %s, the error is: %v", workflowName, err)
 		log.Error(errMsg)
 		return e.ErrDeleteWebhook.AddDesc(errMsg)
@@ -1237,6 +1297,9 @@ func CreateGeneralHookForWorkflowV4(workflowName string, arg *models.GeneralHook
 		logger.Errorf(err.Error())
 		return e.ErrCreateGeneralHook.AddErr(err)
 	}
+	if _, err := util.ParseIPAllowlist(arg.IPAllowlist); err != nil {
+		return e.ErrCreateGeneralHook.AddErr(err)
+	}
 	workflow.GeneralHookCtls = append(workflow.GeneralHookCtls, arg)
 	if err := commonrepo.NewWorkflowV4Coll().Update(workflow.ID.Hex(), workflow); err != nil {
 		errMsg := fmt.Sprintf("failed to create general hook for workflow %s, the error is: %v", workflowName, err)
@@ -1267,6 +1330,7 @@ func GetGeneralHookForWorkflowV4Preset(workflowName, hookName string, logger *za
 	gHook.WorkflowArg.JiraHookCtls = nil
 	gHook.WorkflowArg.MeegoHookCtls = nil
 	gHook.WorkflowArg.GeneralHookCtls = nil
+	gHook.WorkflowArg.RegistryHookCtls = nil
 	gHook.WorkflowArg.HookCtls = nil
 	return gHook, nil
 }
@@ -1291,6 +1355,9 @@ func UpdateGeneralHookForWorkflowV4(workflowName string, arg *models.GeneralHook
 		logger.Errorf("Failed to find WorkflowV4: %s, the error is: %v", workflowName, err)
 		return e.ErrUpdateGeneralHook.AddErr(err)
 	}
+	if _, err := util.ParseIPAllowlist(arg.IPAllowlist); err != nil {
+		return e.ErrUpdateGeneralHook.AddErr(err)
+	}
 	updated := false
 	for i, hook := range workflow.GeneralHookCtls {
 		if hook.Name == arg.Name {
@@ -1394,6 +1461,10 @@ func CreateJiraHookForWorkflowV4(workflowName string, arg *models.JiraHook, logg
 		logger.Errorf(err.Error())
 		return e.ErrCreateJiraHook.AddErr(err)
 	}
+	if err := validateJiraHook(arg); err != nil {
+		logger.Errorf(err.Error())
+		return e.ErrCreateJiraHook.AddErr(err)
+	}
 	workflow.JiraHookCtls = append(workflow.JiraHookCtls, arg)
 	if err := commonrepo.NewWorkflowV4Coll().Update(workflow.ID.Hex(), workflow); err != nil {
 		errMsg := fmt.Sprintf("failed to create jira hook for workflow %s, the error is: %v", workflowName, err)
@@ -1424,6 +1495,7 @@ func GetJiraHookForWorkflowV4Preset(workflowName, hookName string, logger *zap.S
 	jiraHook.WorkflowArg.JiraHookCtls = nil
 	jiraHook.WorkflowArg.MeegoHookCtls = nil
 	jiraHook.WorkflowArg.GeneralHookCtls = nil
+	jiraHook.WorkflowArg.RegistryHookCtls = nil
 	jiraHook.WorkflowArg.HookCtls = nil
 	return jiraHook, nil
 }
@@ -1437,12 +1509,34 @@ func ListJiraHookForWorkflowV4(workflowName string, logger *zap.SugaredLogger) (
 	return workflow.JiraHookCtls, nil
 }
 
+// validateJiraHook checks the field mappings of the jira hook are set to the params of the workflow
+func validateJiraHook(hook *models.JiraHook) error {
+	params := sets.NewString()
+	for _, param := range hook.WorkflowArg.Params {
+		params.Insert(param.Name)
+	}
+	for _, mapping := range hook.FieldMappings {
+		if mapping.JSONPath == "" {
+			return fmt.Errorf("json path of the field mapping to param %s can not be empty", mapping.Param)
+		}
+		if !params.Has(mapping.Param) {
+			return fmt.Errorf("param %s not found in workflow %s", mapping.Param, hook.WorkflowArg.Name)
+		}
+	}
+	return nil
+}
+
 func UpdateJiraHookForWorkflowV4(workflowName string, arg *models.JiraHook, logger *zap.SugaredLogger) error {
 	if err := jobctl.InstantiateWorkflow(arg.WorkflowArg); err != nil {
 		logger.Errorf("instantiate hook args error: %s", err)
 		return e.ErrUpdateJiraHook.AddErr(err)
 	}
 
+	if err := validateJiraHook(arg); err != nil {
+		logger.Errorf(err.Error())
+		return e.ErrUpdateJiraHook.AddErr(err)
+	}
+
 	workflow, err := commonrepo.NewWorkflowV4Coll().Find(workflowName)
 	if err != nil {
 		logger.Errorf("Failed to find WorkflowV4: %s, the error is: %v", workflowName, err)
@@ -1547,6 +1641,7 @@ func GetMeegoHookForWorkflowV4Preset(workflowName, hookName string, logger *zap.
 	meegoHook.WorkflowArg.JiraHookCtls = nil
 	meegoHook.WorkflowArg.MeegoHookCtls = nil
 	meegoHook.WorkflowArg.GeneralHookCtls = nil
+	meegoHook.WorkflowArg.RegistryHookCtls = nil
 	meegoHook.WorkflowArg.HookCtls = nil
 	return meegoHook, nil
 }
@@ -1639,6 +1734,7 @@ func BulkCopyWorkflowV4(args BulkCopyWorkflowArgs, username string, log *zap.Sug
 		workflowMap[workflow.Project+"-"+workflow.Name] = workflow
 	}
 	var newWorkflows []*commonmodels.WorkflowV4
+	cloneTargets := make(map[string]*workflowCloneTarget)
 	for _, workflow := range args.Items {
 		if item, ok := workflowMap[workflow.ProjectName+"-"+workflow.Old]; ok {
 			newItem := *item
@@ -1649,6 +1745,16 @@ func BulkCopyWorkflowV4(args BulkCopyWorkflowArgs, username string, log *zap.Sug
 			newItem.ID = primitive.NewObjectID()
 			// do not copy webhook triggers.
 			newItem.HookCtls = []*commonmodels.WorkflowV4Hook{}
+			if workflow.TargetProjectName != "" && workflow.TargetProjectName != workflow.ProjectName {
+				if err := cloneWorkflowV4ToProject(&newItem, workflow, cloneTargets); err != nil {
+					log.Error(err)
+					return e.ErrInvalidParam.AddErr(err)
+				}
+				if err := jobctl.InstantiateWorkflow(&newItem); err != nil {
+					log.Errorf("failed to instantiate workflow %s, err: %s", newItem.Name, err)
+					return e.ErrInvalidParam.AddErr(err)
+				}
+			}
 
 			newWorkflows = append(newWorkflows, &newItem)
 		} else {
@@ -1663,6 +1769,15 @@ func CreateCronForWorkflowV4(workflowName string, input *commonmodels.Cronjob, l
 		logger.Errorf("instantiate hook args error: %s", err)
 		return e.ErrUpsertCronjob.AddErr(err)
 	}
+	if err := applyCronParamOverrides(input); err != nil {
+		return e.ErrUpsertCronjob.AddErr(err)
+	}
+	if err := setCronPolicies(input); err != nil {
+		return e.ErrUpsertCronjob.AddErr(err)
+	}
+	if _, err := time.LoadLocation(input.Timezone); err != nil {
+		return e.ErrUpsertCronjob.AddDesc(fmt.Sprintf("invalid timezone %s: %s", input.Timezone, err))
+	}
 
 	if !input.ID.IsZero() {
 		return e.ErrUpsertCronjob.AddDesc("cronjob id is not empty")
@@ -1703,6 +1818,15 @@ func UpdateCronForWorkflowV4(input *commonmodels.Cronjob, logger *zap.SugaredLog
 		logger.Errorf("instantiate hook args error: %s", err)
 		return e.ErrUpsertCronjob.AddErr(err)
 	}
+	if err := applyCronParamOverrides(input); err != nil {
+		return e.ErrUpsertCronjob.AddErr(err)
+	}
+	if err := setCronPolicies(input); err != nil {
+		return e.ErrUpsertCronjob.AddErr(err)
+	}
+	if _, err := time.LoadLocation(input.Timezone); err != nil {
+		return e.ErrUpsertCronjob.AddDesc(fmt.Sprintf("invalid timezone %s: %s", input.Timezone, err))
+	}
 
 	_, err := commonrepo.NewCronjobColl().GetByID(input.ID)
 	if err != nil {
@@ -1747,6 +1871,16 @@ func ListCronForWorkflowV4(workflowName string, logger *zap.SugaredLogger) ([]*c
 		logger.Errorf("Failed to list WorkflowV4 : %s cron jobs, the error is: %v", workflowName, err)
 		return crons, e.ErrUpsertCronjob.AddErr(err)
 	}
+	for _, cronjob := range crons {
+		if !cronjob.Enabled || cronjob.JobType != setting.CrontabCronjob {
+			continue
+		}
+		nextRunTimes, err := getCronNextRunTimes(cronjob.Cron, cronjob.Timezone, 1)
+		if err != nil || len(nextRunTimes) == 0 {
+			continue
+		}
+		cronjob.NextRunTime = nextRunTimes[0].Format(cronTimeLayout)
+	}
 	return crons, nil
 }
 
@@ -1815,17 +1949,124 @@ func DeleteCronForWorkflowV4(workflowName, cronID string, logger *zap.SugaredLog
 	return nil
 }
 
+// applyCronParamOverrides sets the params of the cron onto the workflow args, so that each cron of
+// the workflow can run with its own params
+func applyCronParamOverrides(input *commonmodels.Cronjob) error {
+	if input.WorkflowV4Args == nil {
+		return nil
+	}
+	for _, override := range input.Params {
+		found := false
+		for _, param := range input.WorkflowV4Args.Params {
+			if param.Name == override.Name {
+				param.Value = override.Value
+				if param.ParamsType == "repo" && override.Repo != nil {
+					param.Repo = override.Repo
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("param %s not found in workflow %s", override.Name, input.WorkflowV4Args.Name)
+		}
+	}
+	return nil
+}
+
+// setCronPolicies validates the concurrency and misfire policies of the cron and fills in the defaults,
+// the last fire time is only reported by the cron service
+func setCronPolicies(input *commonmodels.Cronjob) error {
+	switch input.ConcurrencyPolicy {
+	case "":
+		input.ConcurrencyPolicy = setting.CronConcurrencyQueue
+	case setting.CronConcurrencyQueue, setting.CronConcurrencySkip, setting.CronConcurrencyCancelPrevious:
+	default:
+		return fmt.Errorf("invalid concurrency policy: %s", input.ConcurrencyPolicy)
+	}
+	switch input.MisfirePolicy {
+	case "":
+		input.MisfirePolicy = setting.CronMisfireSkip
+	case setting.CronMisfireSkip, setting.CronMisfireFireNow:
+	default:
+		return fmt.Errorf("invalid misfire policy: %s", input.MisfirePolicy)
+	}
+	input.LastFireTime = 0
+	return nil
+}
+
+const cronTimeLayout = "2006-01-02 15:04:05 MST"
+
+type CronPreviewArgs struct {
+	Cron     string `json:"cron"     form:"cron"`
+	Timezone string `json:"timezone" form:"timezone"`
+	Count    int    `json:"count"    form:"count,default=5"`
+}
+
+type CronFireTime struct {
+	Timestamp int64  `json:"timestamp"`
+	Time      string `json:"time"`
+}
+
+// PreviewCronSchedule returns the next fire times of the cron expression in its time zone, it is used
+// to validate the expression before saving the cron
+func PreviewCronSchedule(args *CronPreviewArgs) ([]*CronFireTime, error) {
+	if args.Count <= 0 || args.Count > 50 {
+		return nil, e.ErrInvalidParam.AddDesc("count should be between 1 and 50")
+	}
+	nextRunTimes, err := getCronNextRunTimes(args.Cron, args.Timezone, args.Count)
+	if err != nil {
+		return nil, e.ErrInvalidParam.AddErr(err)
+	}
+	resp := make([]*CronFireTime, 0, len(nextRunTimes))
+	for _, next := range nextRunTimes {
+		resp = append(resp, &CronFireTime{
+			Timestamp: next.Unix(),
+			Time:      next.Format(cronTimeLayout),
+		})
+	}
+	return resp, nil
+}
+
+// getCronNextRunTimes returns the next fire times of the crontab expression, the times are in the
+// time zone of the cron so that they are displayed as the scheduler resolves them
+func getCronNextRunTimes(spec, timezone string, count int) ([]time.Time, error) {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %s: %s", timezone, err)
+	}
+	if timezone == "" {
+		location = time.Local
+	}
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron expression %s: %s", spec, err)
+	}
+	resp := make([]time.Time, 0, count)
+	next := time.Now().In(location)
+	for i := 0; i < count; i++ {
+		next = schedule.Next(next)
+		if next.IsZero() {
+			break
+		}
+		resp = append(resp, next)
+	}
+	return resp, nil
+}
+
 func cronJobToSchedule(input *commonmodels.Cronjob) *commonmodels.Schedule {
 	return &commonmodels.Schedule{
-		ID:             input.ID,
-		Number:         input.Number,
-		Frequency:      input.Frequency,
-		Time:           input.Time,
-		MaxFailures:    input.MaxFailure,
-		WorkflowV4Args: input.WorkflowV4Args,
-		Type:           config.ScheduleType(input.JobType),
-		Cron:           input.Cron,
-		Enabled:        input.Enabled,
+		ID:                input.ID,
+		Number:            input.Number,
+		Frequency:         input.Frequency,
+		Time:              input.Time,
+		MaxFailures:       input.MaxFailure,
+		WorkflowV4Args:    input.WorkflowV4Args,
+		Type:              config.ScheduleType(input.JobType),
+		Cron:              input.Cron,
+		Enabled:           input.Enabled,
+		ConcurrencyPolicy: input.ConcurrencyPolicy,
+		Timezone:          input.Timezone,
 	}
 }
 
@@ -1856,10 +2097,33 @@ func GetWorkflowRepoIndex(workflow *commonmodels.WorkflowV4, currentJobName stri
 	return jobctl.GetWorkflowRepoIndex(workflow, currentJobName, log)
 }
 
+// GetWorkflowGlabalVars is kept for compatibility, use ListWorkflowVariables for typed variables instead.
 func GetWorkflowGlabalVars(workflow *commonmodels.WorkflowV4, currentJobName string, log *zap.SugaredLogger) []string {
 	return append(getDefaultVars(workflow, currentJobName), jobctl.GetWorkflowOutputs(workflow, currentJobName, log)...)
 }
 
+// ListWorkflowVariables lists the variables available to currentJobName, including the typed
+// outputs declared by the jobs which run before it.
+func ListWorkflowVariables(workflow *commonmodels.WorkflowV4, currentJobName string, log *zap.SugaredLogger) []*jobctl.WorkflowVariable {
+	numberVars := sets.NewString(
+		fmt.Sprintf(setting.RenderValueTemplate, "workflow.task.timestamp"),
+		fmt.Sprintf(setting.RenderValueTemplate, "workflow.task.id"),
+	)
+	resp := []*jobctl.WorkflowVariable{}
+	for _, key := range getDefaultVars(workflow, currentJobName) {
+		varType := commonmodels.OutputTypeString
+		if numberVars.Has(key) {
+			varType = commonmodels.OutputTypeNumber
+		}
+		resp = append(resp, &jobctl.WorkflowVariable{
+			Key:  key,
+			Name: strings.TrimSuffix(strings.TrimPrefix(key, "{{."), "}}"),
+			Type: varType,
+		})
+	}
+	return append(resp, jobctl.ListJobOutputs(workflow, currentJobName, log)...)
+}
+
 func getDefaultVars(workflow *commonmodels.WorkflowV4, currentJobName string) []string {
 	vars := []string{}
 	vars = append(vars, fmt.Sprintf(setting.RenderValueTemplate, "project"))
@@ -0,0 +1,352 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	templaterepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb/template"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const (
+	cloneRefService  = "service"
+	cloneRefBuild    = "build"
+	cloneRefEnv      = "env"
+	cloneRefTesting  = "testing"
+	cloneRefScanning = "scanning"
+)
+
+// WorkflowCloneMapping renames the resources referenced by the jobs when a workflow is cloned into another project,
+// the key is the name in the source project and the value is the name in the target project
+type WorkflowCloneMapping struct {
+	Services  map[string]string `json:"services"`
+	Builds    map[string]string `json:"builds"`
+	Envs      map[string]string `json:"envs"`
+	Testings  map[string]string `json:"testings"`
+	Scannings map[string]string `json:"scannings"`
+}
+
+func (m *WorkflowCloneMapping) get(refType string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	switch refType {
+	case cloneRefService:
+		return m.Services
+	case cloneRefBuild:
+		return m.Builds
+	case cloneRefEnv:
+		return m.Envs
+	case cloneRefTesting:
+		return m.Testings
+	case cloneRefScanning:
+		return m.Scannings
+	}
+	return nil
+}
+
+// WorkflowCloneReference is a resource referenced by the workflow, Target is the mapped name or the same name if
+// the target project has it, Candidates are the resources of the same type in the target project
+type WorkflowCloneReference struct {
+	Type       string   `json:"type"`
+	Source     string   `json:"source"`
+	Target     string   `json:"target"`
+	Candidates []string `json:"candidates"`
+}
+
+// workflowCloneRemapper walks the job specs as generic documents, the resources are found by the field names
+// so that all the job types are covered
+type workflowCloneRemapper struct {
+	sourceProject string
+	targetProject string
+	mapping       *WorkflowCloneMapping
+	// refs are the renamed resources keyed by the type
+	refs map[string]sets.String
+	// serviceModules and buildTargets are service/module and build/service/module in the target project
+	serviceModules sets.String
+	buildTargets   sets.String
+}
+
+func newWorkflowCloneRemapper(sourceProject, targetProject string, mapping *WorkflowCloneMapping) *workflowCloneRemapper {
+	return &workflowCloneRemapper{
+		sourceProject:  sourceProject,
+		targetProject:  targetProject,
+		mapping:        mapping,
+		refs:           make(map[string]sets.String),
+		serviceModules: sets.NewString(),
+		buildTargets:   sets.NewString(),
+	}
+}
+
+func (r *workflowCloneRemapper) remapWorkflow(workflow *commonmodels.WorkflowV4) error {
+	workflow.Project = r.targetProject
+	// the stages and jobs are copied since they are shared with the source workflow
+	stages := make([]*commonmodels.WorkflowStage, 0, len(workflow.Stages))
+	for _, stage := range workflow.Stages {
+		newStage := *stage
+		newStage.Jobs = make([]*commonmodels.Job, 0, len(stage.Jobs))
+		for _, job := range stage.Jobs {
+			newJob := *job
+			spec := make(map[string]interface{})
+			if err := commonmodels.IToi(job.Spec, &spec); err != nil {
+				return fmt.Errorf("failed to decode job %s, err: %s", job.Name, err)
+			}
+			r.remap(spec, "")
+			newJob.Spec = spec
+			newStage.Jobs = append(newStage.Jobs, &newJob)
+		}
+		stages = append(stages, &newStage)
+	}
+	workflow.Stages = stages
+	return nil
+}
+
+func (r *workflowCloneRemapper) remap(node interface{}, parentKey string) {
+	switch obj := node.(type) {
+	case []interface{}:
+		for _, item := range obj {
+			r.remap(item, parentKey)
+		}
+	case map[string]interface{}:
+		for key, field := range obj {
+			value, ok := field.(string)
+			if !ok {
+				r.remap(field, key)
+				continue
+			}
+			switch {
+			case key == "service_name":
+				obj[key] = r.rename(cloneRefService, value)
+			case key == "build_name":
+				obj[key] = r.rename(cloneRefBuild, value)
+			case key == "env":
+				obj[key] = r.rename(cloneRefEnv, value)
+			case key == "name" && (parentKey == "test_modules" || parentKey == "service_and_tests"):
+				obj[key] = r.rename(cloneRefTesting, value)
+			case key == "name" && parentKey == "scannings":
+				obj[key] = r.rename(cloneRefScanning, value)
+			case key == "project_name" && value == r.sourceProject:
+				obj[key] = r.targetProject
+			}
+		}
+		service, _ := obj["service_name"].(string)
+		module, _ := obj["service_module"].(string)
+		if service == "" || module == "" || isCloneVariable(service) || isCloneVariable(module) {
+			return
+		}
+		r.serviceModules.Insert(service + "/" + module)
+		if build, _ := obj["build_name"].(string); build != "" && !isCloneVariable(build) {
+			r.buildTargets.Insert(build + "/" + service + "/" + module)
+		}
+	}
+}
+
+func (r *workflowCloneRemapper) rename(refType, name string) string {
+	if name == "" || isCloneVariable(name) {
+		return name
+	}
+	if r.refs[refType] == nil {
+		r.refs[refType] = sets.NewString()
+	}
+	r.refs[refType].Insert(name)
+	if target, ok := r.mapping.get(refType)[name]; ok && target != "" {
+		return target
+	}
+	return name
+}
+
+// isCloneVariable skips the values rendered at runtime, e.g. {{.workflow.params.env}}
+func isCloneVariable(value string) bool {
+	return strings.Contains(value, "{{")
+}
+
+// workflowCloneTarget is the resources of the target project
+type workflowCloneTarget struct {
+	services map[string]sets.String
+	builds   map[string]*commonmodels.Build
+	names    map[string]sets.String
+}
+
+func getWorkflowCloneTarget(projectName string) (*workflowCloneTarget, error) {
+	if _, err := templaterepo.NewProductColl().Find(projectName); err != nil {
+		return nil, fmt.Errorf("failed to find project %s, err: %s", projectName, err)
+	}
+	target := &workflowCloneTarget{
+		services: make(map[string]sets.String),
+		builds:   make(map[string]*commonmodels.Build),
+		names:    make(map[string]sets.String),
+	}
+	for _, refType := range []string{cloneRefService, cloneRefBuild, cloneRefEnv, cloneRefTesting, cloneRefScanning} {
+		target.names[refType] = sets.NewString()
+	}
+
+	services, err := commonrepo.NewServiceColl().ListMaxRevisionsByProduct(projectName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list services, err: %s", err)
+	}
+	productionServices, err := commonrepo.NewProductionServiceColl().ListMaxRevisionsByProduct(projectName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list production services, err: %s", err)
+	}
+	for _, service := range append(services, productionServices...) {
+		target.names[cloneRefService].Insert(service.ServiceName)
+		if target.services[service.ServiceName] == nil {
+			target.services[service.ServiceName] = sets.NewString()
+		}
+		for _, container := range service.Containers {
+			target.services[service.ServiceName].Insert(container.Name)
+		}
+	}
+
+	builds, err := commonrepo.NewBuildColl().List(&commonrepo.BuildListOption{ProductName: projectName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list builds, err: %s", err)
+	}
+	for _, build := range builds {
+		target.names[cloneRefBuild].Insert(build.Name)
+		target.builds[build.Name] = build
+	}
+
+	envs, err := commonrepo.NewProductColl().List(&commonrepo.ProductListOptions{Name: projectName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list envs, err: %s", err)
+	}
+	for _, env := range envs {
+		target.names[cloneRefEnv].Insert(env.EnvName)
+	}
+
+	testings, err := commonrepo.NewTestingColl().List(&commonrepo.ListTestOption{ProductName: projectName})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list testings, err: %s", err)
+	}
+	for _, testing := range testings {
+		target.names[cloneRefTesting].Insert(testing.Name)
+	}
+
+	scannings, _, err := commonrepo.NewScanningColl().List(&commonrepo.ScanningListOption{ProjectName: projectName}, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scannings, err: %s", err)
+	}
+	for _, scanning := range scannings {
+		target.names[cloneRefScanning].Insert(scanning.Name)
+	}
+	return target, nil
+}
+
+// validate checks the remapped workflow only references the resources in the target project, the services must
+// have the containers and the builds must build the services referenced by the jobs
+func (t *workflowCloneTarget) validate(r *workflowCloneRemapper) error {
+	var errs *multierror.Error
+	for refType, names := range r.refs {
+		for _, name := range names.List() {
+			target := name
+			if mapped, ok := r.mapping.get(refType)[name]; ok && mapped != "" {
+				target = mapped
+			}
+			if !t.names[refType].Has(target) {
+				errs = multierror.Append(errs, fmt.Errorf("%s %s not found in project %s", refType, target, r.targetProject))
+			}
+		}
+	}
+	for _, serviceModule := range r.serviceModules.List() {
+		parts := strings.SplitN(serviceModule, "/", 2)
+		if modules, ok := t.services[parts[0]]; ok && !modules.Has(parts[1]) {
+			errs = multierror.Append(errs, fmt.Errorf("service %s of project %s has no module %s", parts[0], r.targetProject, parts[1]))
+		}
+	}
+	for _, buildTarget := range r.buildTargets.List() {
+		parts := strings.SplitN(buildTarget, "/", 3)
+		build, ok := t.builds[parts[0]]
+		if !ok {
+			continue
+		}
+		found := false
+		for _, target := range build.Targets {
+			if target.ServiceName == parts[1] && target.ServiceModule == parts[2] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = multierror.Append(errs, fmt.Errorf("build %s of project %s does not build %s/%s", parts[0], r.targetProject, parts[1], parts[2]))
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+// GetWorkflowV4CloneMapping lists the resources referenced by the workflow for the mapping of the clone
+func GetWorkflowV4CloneMapping(workflowName, targetProject string, logger *zap.SugaredLogger) ([]*WorkflowCloneReference, error) {
+	workflow, err := commonrepo.NewWorkflowV4Coll().Find(workflowName)
+	if err != nil {
+		logger.Errorf("Failed to find WorkflowV4: %s, the error is: %v", workflowName, err)
+		return nil, e.ErrFindWorkflow.AddErr(err)
+	}
+	target, err := getWorkflowCloneTarget(targetProject)
+	if err != nil {
+		return nil, e.ErrFindWorkflow.AddErr(err)
+	}
+	remapper := newWorkflowCloneRemapper(workflow.Project, targetProject, nil)
+	if err := remapper.remapWorkflow(workflow); err != nil {
+		return nil, e.ErrFindWorkflow.AddErr(err)
+	}
+
+	resp := make([]*WorkflowCloneReference, 0)
+	for _, refType := range []string{cloneRefService, cloneRefBuild, cloneRefEnv, cloneRefTesting, cloneRefScanning} {
+		for _, name := range remapper.refs[refType].List() {
+			ref := &WorkflowCloneReference{Type: refType, Source: name, Candidates: target.names[refType].List()}
+			if target.names[refType].Has(name) {
+				ref.Target = name
+			}
+			resp = append(resp, ref)
+		}
+	}
+	return resp, nil
+}
+
+// cloneWorkflowV4ToProject remaps the copied workflow into the target project of the item
+func cloneWorkflowV4ToProject(workflow *commonmodels.WorkflowV4, item WorkflowCopyItem, targets map[string]*workflowCloneTarget) error {
+	target, ok := targets[item.TargetProjectName]
+	if !ok {
+		var err error
+		if target, err = getWorkflowCloneTarget(item.TargetProjectName); err != nil {
+			return err
+		}
+		targets[item.TargetProjectName] = target
+	}
+
+	remapper := newWorkflowCloneRemapper(workflow.Project, item.TargetProjectName, item.Mapping)
+	if err := remapper.remapWorkflow(workflow); err != nil {
+		return err
+	}
+	if err := target.validate(remapper); err != nil {
+		return fmt.Errorf("workflow %s is not compatible with project %s: %s", item.Old, item.TargetProjectName, err)
+	}
+	// the triggers are bound to the resources of the source project
+	workflow.JiraHookCtls = nil
+	workflow.MeegoHookCtls = nil
+	workflow.GeneralHookCtls = nil
+	workflow.TriggerPause = nil
+	return nil
+}
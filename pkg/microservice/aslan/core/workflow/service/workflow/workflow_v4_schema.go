@@ -0,0 +1,107 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workflow
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	jobctl "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow/job"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+	"github.com/koderover/zadig/pkg/util/jsonschema"
+)
+
+// WorkflowV4SchemaVersion is bumped when a change of the workflow document is incompatible with the published schema
+const WorkflowV4SchemaVersion = "v1"
+
+var (
+	workflowV4SchemaOnce sync.Once
+	workflowV4Schema     *jsonschema.Schema
+)
+
+// GetWorkflowV4Schema returns the JSON Schema of the workflow yaml, an empty version means the current one
+func GetWorkflowV4Schema(version string) (*jsonschema.Schema, error) {
+	if version != "" && version != WorkflowV4SchemaVersion {
+		return nil, e.ErrNotFound.AddDesc(fmt.Sprintf("workflow schema %s not found, the current version is %s", version, WorkflowV4SchemaVersion))
+	}
+	return getWorkflowV4Schema(), nil
+}
+
+// ValidateWorkflowV4Schema validates the workflow yaml or json against the schema, the path of each violation
+// is returned in the extra of the error
+func ValidateWorkflowV4Schema(data []byte) error {
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return e.ErrInvalidParam.AddDesc(err.Error())
+	}
+	errs := getWorkflowV4Schema().Validate(doc)
+	if len(errs) == 0 {
+		return nil
+	}
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	return e.NewWithExtras(e.ErrInvalidParam, fmt.Sprintf("workflow does not match schema %s: %s", WorkflowV4SchemaVersion, strings.Join(msgs, "; ")),
+		map[string]interface{}{"schema_version": WorkflowV4SchemaVersion, "errors": errs})
+}
+
+func getWorkflowV4Schema() *jsonschema.Schema {
+	workflowV4SchemaOnce.Do(func() {
+		g := jsonschema.NewGenerator()
+		g.Require(commonmodels.WorkflowV4{}, "name", "project", "stages")
+		g.Require(commonmodels.WorkflowStage{}, "name", "jobs")
+		g.Require(commonmodels.Job{}, "name", "type")
+		root := g.Generate(&commonmodels.WorkflowV4{})
+
+		// the spec of a job is validated by the schema of its type
+		specs := jobctl.JobSpecs()
+		jobTypes := make([]string, 0, len(specs))
+		for jobType := range specs {
+			jobTypes = append(jobTypes, string(jobType))
+		}
+		sort.Strings(jobTypes)
+		job := g.Defs()["Job"]
+		for _, jobType := range jobTypes {
+			job.Properties["type"].Enum = append(job.Properties["type"].Enum, jobType)
+			job.AllOf = append(job.AllOf, &jsonschema.Schema{
+				If: &jsonschema.Schema{
+					Properties: map[string]*jsonschema.Schema{"type": {Const: jobType}},
+					Required:   []string{"type"},
+				},
+				Then: &jsonschema.Schema{Properties: map[string]*jsonschema.Schema{
+					"spec": g.Generate(specs[config.JobType(jobType)]),
+				}},
+			})
+		}
+
+		workflowV4Schema = &jsonschema.Schema{
+			Schema: jsonschema.Draft,
+			ID:     "urn:zadig:workflow-v4:" + WorkflowV4SchemaVersion,
+			Title:  "Zadig WorkflowV4 " + WorkflowV4SchemaVersion,
+			Ref:    root.Ref,
+			Defs:   g.Defs(),
+		}
+	})
+	return workflowV4Schema
+}
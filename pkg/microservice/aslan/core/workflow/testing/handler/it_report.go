@@ -88,3 +88,42 @@ func GetTestLocalTestSuite(c *gin.Context) {
 
 	ctx.Resp, ctx.Err = service.GetTestLocalTestSuite(c.Param("serviceName"), ctx.Logger)
 }
+
+func GetTestPassRateTrend(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	projectKey := c.Query("projectName")
+	if projectKey == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("projectName can not be empty")
+		return
+	}
+	runs, _ := strconv.Atoi(c.Query("runs"))
+	ctx.Resp, ctx.Err = service.GetTestPassRateTrend(projectKey, c.Param("testName"), runs, ctx.Logger)
+}
+
+func ListFlakyTestCases(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	projectKey := c.Query("projectName")
+	if projectKey == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("projectName can not be empty")
+		return
+	}
+	runs, _ := strconv.Atoi(c.Query("runs"))
+	ctx.Resp, ctx.Err = service.ListFlakyTestCases(projectKey, c.Param("testName"), runs, ctx.Logger)
+}
+
+func GetTestCaseHistory(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	projectKey := c.Query("projectName")
+	if projectKey == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("projectName can not be empty")
+		return
+	}
+	runs, _ := strconv.Atoi(c.Query("runs"))
+	ctx.Resp, ctx.Err = service.GetTestCaseHistory(projectKey, c.Param("testName"), c.Query("className"), c.Query("caseName"), runs, ctx.Logger)
+}
@@ -0,0 +1,59 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/testing/service"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+func ListPerformanceHistory(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(service.PerformanceHistoryArgs)
+	if err := c.ShouldBindQuery(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	if args.ProjectName == "" || args.WorkflowName == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("projectName and workflowName can not be empty")
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.ListPerformanceHistory(args, ctx.Logger)
+}
+
+func ComparePerformance(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	args := new(service.PerformanceCompareArgs)
+	if err := c.ShouldBindQuery(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+	if args.ProjectName == "" || args.WorkflowName == "" || args.JobName == "" || args.TaskID == 0 || args.BaselineTaskID == 0 {
+		ctx.Err = e.ErrInvalidParam.AddDesc("projectName, workflowName, jobName, taskID and baselineTaskID can not be empty")
+		return
+	}
+
+	ctx.Resp, ctx.Err = service.ComparePerformance(args, ctx.Logger)
+}
@@ -39,6 +39,9 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		itReport.GET("/workflowv4/:workflowName/id/:id/job/:jobName", GetWorkflowV4LocalTestSuite)
 		itReport.GET("/workflow/:pipelineName/id/:id/names/:testName/service/:serviceName", GetWorkflowLocalTestSuite)
 		itReport.GET("/latest/service/:serviceName", GetTestLocalTestSuite)
+		itReport.GET("/test/:testName/trend", GetTestPassRateTrend)
+		itReport.GET("/test/:testName/flaky", ListFlakyTestCases)
+		itReport.GET("/test/:testName/case/history", GetTestCaseHistory)
 	}
 
 	// ---------------------------------------------------------------------------------------
@@ -79,6 +82,17 @@ func (*Router) Inject(router *gin.RouterGroup) {
 	//	testStat.GET("", ListTestStat)
 	//}
 
+	coverage := router.Group("coverage")
+	{
+		coverage.GET("/history", ListCoverageHistory)
+	}
+
+	performance := router.Group("performance")
+	{
+		performance.GET("/history", ListPerformanceHistory)
+		performance.GET("/compare", ComparePerformance)
+	}
+
 	testDetail := router.Group("testdetail")
 	{
 		testDetail.GET("", ListDetailTestModules)
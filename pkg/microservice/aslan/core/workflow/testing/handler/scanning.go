@@ -33,6 +33,7 @@ import (
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonservice "github.com/koderover/zadig/pkg/microservice/aslan/core/common/service"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/testing/service"
 	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
 	"github.com/koderover/zadig/pkg/tool/log"
@@ -295,6 +296,18 @@ func DeleteScanningModule(c *gin.Context) {
 		return
 	}
 
+	if c.Query("force") != "true" {
+		scanning, err := service.GetScanningModuleByID(id, ctx.Logger)
+		if err != nil {
+			ctx.Err = err
+			return
+		}
+		if err := commonservice.CheckAssetReferences(commonservice.AssetTypeScanning, scanning.ProjectName, scanning.Name); err != nil {
+			ctx.Err = e.ErrAssetReferenced.AddErr(err)
+			return
+		}
+	}
+
 	ctx.Err = service.DeleteScanningModuleByID(id, ctx.Logger)
 }
 
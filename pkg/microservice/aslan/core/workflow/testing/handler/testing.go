@@ -273,6 +273,13 @@ func DeleteTestModule(c *gin.Context) {
 		return
 	}
 
+	if c.Query("force") != "true" {
+		if err := commonservice.CheckAssetReferences(commonservice.AssetTypeTesting, projectKey, name); err != nil {
+			ctx.Err = e.ErrAssetReferenced.AddErr(err)
+			return
+		}
+	}
+
 	ctx.Err = commonservice.DeleteTestModule(name, projectKey, ctx.RequestID, ctx.Logger)
 }
 
@@ -0,0 +1,54 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+type CoverageHistoryArgs struct {
+	ProjectName   string `form:"projectName"`
+	Name          string `form:"name"`
+	ServiceName   string `form:"serviceName"`
+	ServiceModule string `form:"serviceModule"`
+	Branch        string `form:"branch"`
+	Limit         int64  `form:"limit"`
+}
+
+// ListCoverageHistory returns the coverage records of a service, the latest ones come first
+func ListCoverageHistory(args *CoverageHistoryArgs, log *zap.SugaredLogger) ([]*commonmodels.CoverageRecord, error) {
+	if args.Limit <= 0 || args.Limit > maxTestReportRuns {
+		args.Limit = defaultTestReportRuns
+	}
+	resp, err := commonrepo.NewCoverageRecordColl().List(&commonrepo.CoverageRecordListOption{
+		ProjectName:   args.ProjectName,
+		Name:          args.Name,
+		ServiceName:   args.ServiceName,
+		ServiceModule: args.ServiceModule,
+		Branch:        args.Branch,
+		Limit:         args.Limit,
+	})
+	if err != nil {
+		log.Errorf("failed to list coverage records of project %s, err: %s", args.ProjectName, err)
+		return nil, e.ErrInternalError.AddErr(err)
+	}
+	return resp, nil
+}
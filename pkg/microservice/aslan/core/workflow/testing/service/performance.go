@@ -0,0 +1,101 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+type PerformanceHistoryArgs struct {
+	ProjectName  string `form:"projectName"`
+	WorkflowName string `form:"workflowName"`
+	JobName      string `form:"jobName"`
+	Limit        int64  `form:"limit"`
+}
+
+type PerformanceCompareArgs struct {
+	ProjectName    string `form:"projectName"`
+	WorkflowName   string `form:"workflowName"`
+	JobName        string `form:"jobName"`
+	TaskID         int64  `form:"taskID"`
+	BaselineTaskID int64  `form:"baselineTaskID"`
+}
+
+// PerformanceComparison is the change of the metrics from the baseline, in percentage
+type PerformanceComparison struct {
+	Current             *commonmodels.PerformanceRecord `json:"current"`
+	Baseline            *commonmodels.PerformanceRecord `json:"baseline"`
+	ThroughputChange    float64                         `json:"throughput_change"`
+	AvgLatencyChange    float64                         `json:"avg_latency_change"`
+	P95LatencyChange    float64                         `json:"p95_latency_change"`
+	P99LatencyChange    float64                         `json:"p99_latency_change"`
+	ErrorRateDifference float64                         `json:"error_rate_difference"`
+}
+
+// ListPerformanceHistory returns the performance test results of a workflow, the latest ones come first
+func ListPerformanceHistory(args *PerformanceHistoryArgs, log *zap.SugaredLogger) ([]*commonmodels.PerformanceRecord, error) {
+	if args.Limit <= 0 || args.Limit > maxTestReportRuns {
+		args.Limit = defaultTestReportRuns
+	}
+	resp, err := commonrepo.NewPerformanceRecordColl().List(&commonrepo.PerformanceRecordListOption{
+		ProjectName:  args.ProjectName,
+		WorkflowName: args.WorkflowName,
+		JobName:      args.JobName,
+		Limit:        args.Limit,
+	})
+	if err != nil {
+		log.Errorf("failed to list performance records of workflow %s, err: %s", args.WorkflowName, err)
+		return nil, e.ErrInternalError.AddErr(err)
+	}
+	return resp, nil
+}
+
+func ComparePerformance(args *PerformanceCompareArgs, log *zap.SugaredLogger) (*PerformanceComparison, error) {
+	coll := commonrepo.NewPerformanceRecordColl()
+	current, err := coll.Find(args.ProjectName, args.WorkflowName, args.JobName, args.TaskID)
+	if err != nil {
+		return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("no performance result of job %s found in task %d", args.JobName, args.TaskID))
+	}
+	baseline, err := coll.Find(args.ProjectName, args.WorkflowName, args.JobName, args.BaselineTaskID)
+	if err != nil {
+		return nil, e.ErrInvalidParam.AddDesc(fmt.Sprintf("no performance result of job %s found in task %d", args.JobName, args.BaselineTaskID))
+	}
+
+	resp := &PerformanceComparison{Current: current, Baseline: baseline}
+	if current.Metrics == nil || baseline.Metrics == nil {
+		return resp, nil
+	}
+	resp.ThroughputChange = changePercentage(current.Metrics.Throughput, baseline.Metrics.Throughput)
+	resp.AvgLatencyChange = changePercentage(current.Metrics.AvgLatency, baseline.Metrics.AvgLatency)
+	resp.P95LatencyChange = changePercentage(current.Metrics.P95Latency, baseline.Metrics.P95Latency)
+	resp.P99LatencyChange = changePercentage(current.Metrics.P99Latency, baseline.Metrics.P99Latency)
+	resp.ErrorRateDifference = current.Metrics.ErrorRate - baseline.Metrics.ErrorRate
+	return resp, nil
+}
+
+func changePercentage(current, base float64) float64 {
+	if base == 0 {
+		return 0
+	}
+	return (current - base) * 100 / base
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package service
+
+import (
+	"sort"
+
+	"go.uber.org/zap"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	commonrepo "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/mongodb"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+const (
+	defaultTestReportRuns = 20
+	maxTestReportRuns     = 200
+)
+
+type TestPassRate struct {
+	*commonmodels.TestRunStat
+	// PassRate is the percentage of passed cases in the executed (not skipped) ones
+	PassRate float64 `json:"pass_rate"`
+}
+
+type FlakyTestCase struct {
+	ClassName string `json:"class_name"`
+	CaseName  string `json:"case_name"`
+	Runs      int    `json:"runs"`
+	Passed    int    `json:"passed"`
+	Failed    int    `json:"failed"`
+	// Flips is the number of times the case changed between passed and failed in consecutive runs
+	Flips       int     `json:"flips"`
+	FlakyRate   float64 `json:"flaky_rate"`
+	LastStatus  string  `json:"last_status"`
+	LastFailure string  `json:"last_failure"`
+}
+
+func normalizeTestReportRuns(runs int) int64 {
+	if runs <= 0 {
+		return defaultTestReportRuns
+	}
+	if runs > maxTestReportRuns {
+		return maxTestReportRuns
+	}
+	return int64(runs)
+}
+
+// GetTestPassRateTrend returns the pass rate of the latest runs of a test, in chronological order
+func GetTestPassRateTrend(projectName, testName string, runs int, log *zap.SugaredLogger) ([]*TestPassRate, error) {
+	stats, err := commonrepo.NewTestCaseResultColl().ListRunStats(projectName, testName, normalizeTestReportRuns(runs))
+	if err != nil {
+		log.Errorf("failed to list run stats of test %s, err: %s", testName, err)
+		return nil, e.ErrInternalError.AddErr(err)
+	}
+
+	resp := make([]*TestPassRate, 0, len(stats))
+	for i := len(stats) - 1; i >= 0; i-- {
+		rate := &TestPassRate{TestRunStat: stats[i]}
+		if executed := stats[i].Total - stats[i].Skipped; executed > 0 {
+			rate.PassRate = float64(stats[i].Passed) * 100 / float64(executed)
+		}
+		resp = append(resp, rate)
+	}
+	return resp, nil
+}
+
+// ListFlakyTestCases finds the cases which both passed and failed in the latest runs of a test
+func ListFlakyTestCases(projectName, testName string, runs int, log *zap.SugaredLogger) ([]*FlakyTestCase, error) {
+	coll := commonrepo.NewTestCaseResultColl()
+	stats, err := coll.ListRunStats(projectName, testName, normalizeTestReportRuns(runs))
+	if err != nil {
+		log.Errorf("failed to list run stats of test %s, err: %s", testName, err)
+		return nil, e.ErrInternalError.AddErr(err)
+	}
+	if len(stats) == 0 {
+		return make([]*FlakyTestCase, 0), nil
+	}
+
+	results, err := coll.List(&commonrepo.TestCaseResultListOption{
+		ProjectName: projectName,
+		TestName:    testName,
+		StartTime:   stats[len(stats)-1].CreateTime,
+	})
+	if err != nil {
+		log.Errorf("failed to list case results of test %s, err: %s", testName, err)
+		return nil, e.ErrInternalError.AddErr(err)
+	}
+	return detectFlakyTestCases(results), nil
+}
+
+// detectFlakyTestCases expects the results sorted by create time in descending order
+func detectFlakyTestCases(results []*commonmodels.TestCaseResult) []*FlakyTestCase {
+	type caseKey struct {
+		className string
+		caseName  string
+	}
+	caseMap := make(map[caseKey]*FlakyTestCase)
+	lastFailed := make(map[caseKey]bool)
+
+	for i := len(results) - 1; i >= 0; i-- {
+		result := results[i]
+		if result.Status == commonmodels.TestCaseStatusSkipped {
+			continue
+		}
+		key := caseKey{className: result.ClassName, caseName: result.CaseName}
+		flaky, ok := caseMap[key]
+		if !ok {
+			flaky = &FlakyTestCase{ClassName: result.ClassName, CaseName: result.CaseName}
+			caseMap[key] = flaky
+		}
+		failed := result.Status != commonmodels.TestCaseStatusPassed
+		if flaky.Runs > 0 && failed != lastFailed[key] {
+			flaky.Flips++
+		}
+		lastFailed[key] = failed
+		flaky.Runs++
+		flaky.LastStatus = result.Status
+		if failed {
+			flaky.Failed++
+			flaky.LastFailure = result.Message
+		} else {
+			flaky.Passed++
+		}
+	}
+
+	resp := make([]*FlakyTestCase, 0)
+	for _, flaky := range caseMap {
+		if flaky.Passed == 0 || flaky.Failed == 0 {
+			continue
+		}
+		flaky.FlakyRate = float64(flaky.Flips) / float64(flaky.Runs-1)
+		resp = append(resp, flaky)
+	}
+	sort.SliceStable(resp, func(i, j int) bool {
+		if resp[i].FlakyRate != resp[j].FlakyRate {
+			return resp[i].FlakyRate > resp[j].FlakyRate
+		}
+		return resp[i].ClassName+resp[i].CaseName < resp[j].ClassName+resp[j].CaseName
+	})
+	return resp
+}
+
+// GetTestCaseHistory returns the results of a case across the latest task runs
+func GetTestCaseHistory(projectName, testName, className, caseName string, runs int, log *zap.SugaredLogger) ([]*commonmodels.TestCaseResult, error) {
+	if caseName == "" {
+		return nil, e.ErrInvalidParam.AddDesc("case name is required")
+	}
+	resp, err := commonrepo.NewTestCaseResultColl().List(&commonrepo.TestCaseResultListOption{
+		ProjectName: projectName,
+		TestName:    testName,
+		ClassName:   className,
+		CaseName:    caseName,
+		Limit:       normalizeTestReportRuns(runs),
+	})
+	if err != nil {
+		log.Errorf("failed to list history of case %s in test %s, err: %s", caseName, testName, err)
+		return nil, e.ErrInternalError.AddErr(err)
+	}
+	return resp, nil
+}
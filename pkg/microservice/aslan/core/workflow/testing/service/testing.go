@@ -386,7 +386,7 @@ func GetHTMLTestReport(pipelineName, pipelineType, taskIDStr, testName string, l
 }
 
 func GetWorkflowV4HTMLTestReport(workflowName, jobName string, taskID int64, log *zap.SugaredLogger) (string, error) {
-	workflowTask, err := mongodb.NewworkflowTaskv4Coll().Find(workflowName, taskID)
+	workflowTask, err := mongodb.NewWorkflowTaskV4Store().Find(workflowName, taskID)
 	if err != nil {
 		return "", fmt.Errorf("cannot find workflow task, workflow name: %s, task id: %d", workflowName, taskID)
 	}
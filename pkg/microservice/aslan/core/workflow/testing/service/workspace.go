@@ -134,7 +134,7 @@ func GetTestArtifactInfo(pipelineName, dir string, taskID int64, log *zap.Sugare
 
 func GetWorkflowV4TestArtifactInfo(workflowName, jobName string, taskID int64, log *zap.SugaredLogger) (*GetTestArtifactInfoResp, error) {
 	resp := new(GetTestArtifactInfoResp)
-	workflowTask, err := mongodb.NewworkflowTaskv4Coll().Find(workflowName, taskID)
+	workflowTask, err := mongodb.NewWorkflowTaskV4Store().Find(workflowName, taskID)
 	if err != nil {
 		return resp, fmt.Errorf("cannot find workflow task, workflow name: %s, task id: %d", workflowName, taskID)
 	}
@@ -41,6 +41,7 @@ import (
 	stathandler "github.com/koderover/zadig/pkg/microservice/aslan/core/stat/handler"
 	systemhandler "github.com/koderover/zadig/pkg/microservice/aslan/core/system/handler"
 	templatehandler "github.com/koderover/zadig/pkg/microservice/aslan/core/templatestore/handler"
+	vmhandler "github.com/koderover/zadig/pkg/microservice/aslan/core/vm/handler"
 	workflowhandler "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/handler"
 	"github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/service/workflow"
 	testinghandler "github.com/koderover/zadig/pkg/microservice/aslan/core/workflow/testing/handler"
@@ -64,6 +65,9 @@ func init() {
 
 	metrics.Metrics.MustRegister(metrics.RunningWorkflows)
 	metrics.Metrics.MustRegister(metrics.PendingWorkflows)
+	metrics.Metrics.MustRegister(metrics.QueueWaitSeconds)
+	metrics.Metrics.MustRegister(metrics.QueueWaitAlerts)
+	metrics.Metrics.MustRegister(metrics.WebhookRateLimited)
 	metrics.Metrics.MustRegister(metrics.RequestTotal)
 	metrics.Metrics.MustRegister(metrics.CPU)
 	metrics.Metrics.MustRegister(metrics.Memory)
@@ -92,6 +96,8 @@ func (s *engine) injectRouterGroup(router *gin.RouterGroup) {
 		})
 		public.GET("/health", commonhandler.Health)
 		public.POST("/callback", commonhandler.HandleCallback)
+		public.GET("/oidc/.well-known/openid-configuration", commonhandler.GetOIDCDiscovery)
+		public.GET("/oidc/jwks", commonhandler.GetOIDCJWKS)
 	}
 
 	for name, r := range map[string]injector{
@@ -134,6 +140,7 @@ func (s *engine) injectRouterGroup(router *gin.RouterGroup) {
 		"/api/label":         new(labelhandler.Router),
 		"/api/stat":          new(stathandler.Router),
 		"/api/cache":         cachehandler.NewRouter(),
+		"/api/vm":            new(vmhandler.Router),
 	} {
 		r.Inject(router.Group(name))
 	}
@@ -154,6 +161,7 @@ func (s *engine) injectRouterGroup(router *gin.RouterGroup) {
 		podexec.GET("/:productName/:podName/:containerName/podExec/:envName", podexecservice.ServeWs)
 		podexec.GET("/production/:productName/:podName/:containerName/podExec/:envName", podexecservice.ServeWs)
 		podexec.GET("/debug/:workflowName/:jobName/task/:taskID", podexecservice.DebugWorkflow)
+		podexec.GET("/workflow/:workflowName/:jobName/task/:taskID", podexecservice.ExecWorkflowJob)
 	}
 
 	// inject picket APIs
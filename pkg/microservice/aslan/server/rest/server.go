@@ -48,6 +48,15 @@ func (s *engine) injectMiddlewares() {
 		s.Engine = g
 	}()
 
+	// the trigger ip allowlists and the webhook rate limits are keyed by the client ip, the forwarded header
+	// is only trusted if the request comes from a configured gateway, otherwise it can be spoofed. All the
+	// proxies are trusted as before if no gateway is configured
+	if proxies := config.TrustedProxies(); len(proxies) > 0 {
+		if err := g.SetTrustedProxies(proxies); err != nil {
+			log.Fatalf("failed to set trusted proxies, err: %s", err)
+		}
+	}
+
 	if s.mode == gin.TestMode {
 		return
 	}
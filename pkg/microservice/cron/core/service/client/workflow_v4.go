@@ -0,0 +1,58 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"fmt"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+// The cronjobs, including their concurrency and misfire policies, are delivered to the cron service through
+// MsgQueueCommon, which only goes from aslan to cron. The running tasks are owned by the workflow controller
+// of aslan, so they are checked and cancelled through the aslan api like the scheduled calls.
+
+// ListWorkflowV4TodoTasks returns the ids of the unfinished tasks of the workflow started by the cron trigger
+func (c *Client) ListWorkflowV4TodoTasks(workflowName string) ([]int64, error) {
+	url := fmt.Sprintf("%s/cron/cronjob/workflow/v4/%s/task", c.APIBase, workflowName)
+	resp := make([]int64, 0)
+	_, err := httpclient.Get(url, httpclient.SetResult(&resp))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unfinished tasks of workflow %s: %s", workflowName, err)
+	}
+	return resp, nil
+}
+
+func (c *Client) CancelWorkflowV4Task(workflowName string, taskID int64) error {
+	url := fmt.Sprintf("%s/cron/cronjob/workflow/v4/%s/task/%d", c.APIBase, workflowName, taskID)
+	if _, err := httpclient.Delete(url); err != nil {
+		return fmt.Errorf("failed to cancel task %d of workflow %s: %s", taskID, workflowName, err)
+	}
+	return nil
+}
+
+type updateCronjobFireTimeReq struct {
+	LastFireTime int64 `json:"last_fire_time"`
+}
+
+func (c *Client) UpdateCronjobFireTime(id string, fireTime int64) error {
+	url := fmt.Sprintf("%s/cron/cronjob/%s/fire", c.APIBase, id)
+	if _, err := httpclient.Put(url, httpclient.SetBody(&updateCronjobFireTimeReq{LastFireTime: fireTime})); err != nil {
+		return fmt.Errorf("failed to update fire time of cronjob %s: %s", id, err)
+	}
+	return nil
+}
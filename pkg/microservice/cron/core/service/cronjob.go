@@ -41,6 +41,11 @@ type Cronjob struct {
 	WorkflowV4Args *WorkflowV4       `json:"workflow_v4_args"`
 	JobType        string            `json:"job_type"`
 	Enabled        bool              `json:"enabled"`
+	// ConcurrencyPolicy and MisfirePolicy are only supported by the workflow v4 cronjob
+	ConcurrencyPolicy string `json:"concurrency_policy,omitempty"`
+	MisfirePolicy     string `json:"misfire_policy,omitempty"`
+	LastFireTime      int64  `json:"last_fire_time,omitempty"`
+	Timezone          string `json:"timezone,omitempty"`
 }
 
 // param type: cronjob的执行内容类型
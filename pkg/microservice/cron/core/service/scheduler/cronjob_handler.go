@@ -25,8 +25,11 @@ import (
 	"path"
 	"strings"
 	"time"
+	// the image does not ship the zoneinfo, the time zones of the crons are loaded from the embedded database
+	_ "time/tzdata"
 
 	"github.com/rfyiamcool/cronlib"
+	"github.com/robfig/cron/v3"
 
 	"github.com/koderover/zadig/pkg/microservice/cron/core/service"
 	"github.com/koderover/zadig/pkg/microservice/cron/core/service/client"
@@ -39,8 +42,13 @@ import (
 const (
 	InitializeThreshold = 5 * time.Minute
 	PullInterval        = 3 * time.Second
+
+	cronTimezonePrefix = "CRON_TZ="
+	everyMinuteSpec    = "0 * * * * *"
 )
 
+var cronParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
 type CronjobHandler struct {
 	aslanCli  *client.Client
 	Scheduler *cronlib.CronSchduler
@@ -166,6 +174,7 @@ func (h *CronjobHandler) updateCronjob(name, productName, jobType string, jobLis
 		} else {
 			cron = fmt.Sprintf("%s%s", "0 ", job.Cron)
 		}
+		cron = withTimezone(cron, job.Timezone)
 		switch jobType {
 		case setting.WorkflowCronjob:
 			err := h.registerWorkFlowJob(name, cron, job)
@@ -258,7 +267,7 @@ func (h *CronjobHandler) registerWorkFlowJob(name, schedule string, job *service
 		args.Tests = job.WorkflowArgs.Tests
 		args.DistributeEnabled = job.WorkflowArgs.DistributeEnabled
 	}
-	scheduleJob, err := cronlib.NewJobModel(schedule, func() {
+	scheduleJob, err := newCronJobModel(schedule, func() {
 		if err := h.aslanCli.ScheduleCall(path.Join("workflow/workflowtask", args.WorkflowName), args, log.SugaredLogger()); err != nil {
 			log.Errorf("[%s]RunScheduledTask err: %v", name, err)
 		}
@@ -281,10 +290,8 @@ func (h *CronjobHandler) registerWorkFlowV4Job(name, schedule string, job *servi
 	if job.WorkflowV4Args == nil {
 		return nil
 	}
-	scheduleJob, err := cronlib.NewJobModel(schedule, func() {
-		if err := h.aslanCli.ScheduleCall(fmt.Sprintf("workflow/v4/workflowtask/trigger?triggerName=%s", setting.CronTaskCreator), job.WorkflowV4Args, log.SugaredLogger()); err != nil {
-			log.Errorf("[%s]RunScheduledTask err: %v", name, err)
-		}
+	scheduleJob, err := newCronJobModel(schedule, func() {
+		fireWorkflowV4Job(h.aslanCli, job.ID.Hex(), name, job.ConcurrencyPolicy, job.WorkflowV4Args)
 	})
 	if err != nil {
 		log.Errorf("Failed to create job of ID: %s, the error is: %v", job.ID.Hex(), err)
@@ -306,7 +313,7 @@ func (h *CronjobHandler) registerTestJob(name, productName, schedule string, job
 		ProductName:     productName,
 		TestTaskCreator: setting.CronTaskCreator,
 	}
-	scheduleJob, err := cronlib.NewJobModel(schedule, func() {
+	scheduleJob, err := newCronJobModel(schedule, func() {
 		if err := h.aslanCli.ScheduleCall("testing/testtask", args, log.SugaredLogger()); err != nil {
 			log.Errorf("[%s]RunScheduledTask err: %v", name, err)
 		}
@@ -385,7 +392,7 @@ func registerCronjob(job *service.Cronjob, client *client.Client, scheduler *cro
 		} else {
 			cron, _ = convertCronString(job.JobType, job.Time, job.Frequency, job.Number)
 		}
-		scheduleJob, err := cronlib.NewJobModel(cron, func() {
+		scheduleJob, err := newCronJobModel(cron, func() {
 			if err := client.ScheduleCall(path.Join("workflow/workflowtask", job.WorkflowArgs.WorkflowName), args, log.SugaredLogger()); err != nil {
 				log.Errorf("[%s]RunScheduledTask err: %v", job.Name, err)
 			}
@@ -410,10 +417,9 @@ func registerCronjob(job *service.Cronjob, client *client.Client, scheduler *cro
 		} else {
 			cron, _ = convertCronString(job.JobType, job.Time, job.Frequency, job.Number)
 		}
-		scheduleJob, err := cronlib.NewJobModel(cron, func() {
-			if err := client.ScheduleCall(fmt.Sprintf("workflow/v4/workflowtask/trigger?triggerName=%s", setting.CronTaskCreator), job.WorkflowV4Args, log.SugaredLogger()); err != nil {
-				log.Errorf("[%s]RunScheduledTask err: %v", job.Name, err)
-			}
+		cron = withTimezone(cron, job.Timezone)
+		scheduleJob, err := newCronJobModel(cron, func() {
+			fireWorkflowV4Job(client, job.ID, job.Name, job.ConcurrencyPolicy, job.WorkflowV4Args)
 		})
 		if err != nil {
 			log.Errorf("Failed to generate job of ID: %s to scheduler, the error is: %v", job.ID, err)
@@ -425,6 +431,12 @@ func registerCronjob(job *service.Cronjob, client *client.Client, scheduler *cro
 			log.Errorf("Failed to register job of ID: %s to scheduler, the error is: %v", job.ID, err)
 			return err
 		}
+		// the job is only registered on startup here, so a fire time between the last fire and now was missed
+		// while the cron service was down
+		if job.MisfirePolicy == setting.CronMisfireFireNow && isMisfired(cron, job.LastFireTime) {
+			log.Infof("cronjob %s missed its fire time, firing it now", job.ID)
+			go fireWorkflowV4Job(client, job.ID, job.Name, job.ConcurrencyPolicy, job.WorkflowV4Args)
+		}
 	case setting.TestingCronjob:
 		args := &service.TestTaskArgs{
 			TestName:        job.Name,
@@ -437,7 +449,7 @@ func registerCronjob(job *service.Cronjob, client *client.Client, scheduler *cro
 		} else {
 			cron, _ = convertCronString(job.JobType, job.Time, job.Frequency, job.Number)
 		}
-		scheduleJob, err := cronlib.NewJobModel(cron, func() {
+		scheduleJob, err := newCronJobModel(cron, func() {
 			if err := client.ScheduleCall("testing/testtask", args, log.SugaredLogger()); err != nil {
 				log.Errorf("[%s]RunScheduledTask err: %v", job.Name, err)
 			}
@@ -463,7 +475,7 @@ func (h *CronjobHandler) registerEnvAnalysisJob(name, schedule string, job *serv
 	if job.EnvAnalysisArgs == nil {
 		return nil
 	}
-	scheduleJob, err := cronlib.NewJobModel(schedule, func() {
+	scheduleJob, err := newCronJobModel(schedule, func() {
 		base := "environment/environments/"
 		if job.EnvAnalysisArgs.Production {
 			base = "environment/production/environments/"
@@ -492,7 +504,7 @@ func (h *CronjobHandler) registerEnvSleepJob(name, schedule string, job *service
 	if job.EnvArgs == nil {
 		return nil
 	}
-	scheduleJob, err := cronlib.NewJobModel(schedule, func() {
+	scheduleJob, err := newCronJobModel(schedule, func() {
 		base := "environment/environments/"
 		if job.EnvArgs.Production {
 			base = "environment/production/environments/"
@@ -522,3 +534,79 @@ func (h *CronjobHandler) registerEnvSleepJob(name, schedule string, job *service
 	}
 	return nil
 }
+
+// fireWorkflowV4Job creates the workflow v4 task of the cronjob according to its concurrency policy and
+// reports the fire time to aslan
+func fireWorkflowV4Job(cli *client.Client, jobID, name, concurrencyPolicy string, args *service.WorkflowV4) {
+	if err := cli.UpdateCronjobFireTime(jobID, time.Now().Unix()); err != nil {
+		log.Warnf("[%s]update fire time err: %v", name, err)
+	}
+
+	if concurrencyPolicy == setting.CronConcurrencySkip || concurrencyPolicy == setting.CronConcurrencyCancelPrevious {
+		taskIDs, err := cli.ListWorkflowV4TodoTasks(args.Name)
+		if err != nil {
+			log.Errorf("[%s]check unfinished tasks err: %v", name, err)
+			// a task may be running, skipping is safer than running the tasks concurrently
+			if concurrencyPolicy == setting.CronConcurrencySkip {
+				return
+			}
+		}
+		if len(taskIDs) > 0 && concurrencyPolicy == setting.CronConcurrencySkip {
+			log.Infof("[%s]tasks %v are still running, skip the scheduled task", name, taskIDs)
+			return
+		}
+		for _, taskID := range taskIDs {
+			if err := cli.CancelWorkflowV4Task(args.Name, taskID); err != nil {
+				log.Errorf("[%s]cancel previous task err: %v", name, err)
+			}
+		}
+	}
+
+	if err := cli.ScheduleCall(fmt.Sprintf("workflow/v4/workflowtask/trigger?triggerName=%s", setting.CronTaskCreator), args, log.SugaredLogger()); err != nil {
+		log.Errorf("[%s]RunScheduledTask err: %v", name, err)
+	}
+}
+
+// withTimezone makes the scheduler fire the cron in the IANA time zone instead of the server local time
+func withTimezone(spec, timezone string) string {
+	if timezone == "" {
+		return spec
+	}
+	return fmt.Sprintf("%s%s %s", cronTimezonePrefix, timezone, spec)
+}
+
+// newCronJobModel creates the scheduler job of the spec. cronlib only fires the cron in the server local time,
+// so a spec with a time zone ticks every minute and the next fire time is computed in its time zone.
+func newCronJobModel(spec string, f func()) (*cronlib.JobModel, error) {
+	if !strings.HasPrefix(spec, cronTimezonePrefix) {
+		return cronlib.NewJobModel(spec, f)
+	}
+	schedule, err := cronParser.Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	// the job model runs the function synchronously, the next fire time is not accessed concurrently
+	next := schedule.Next(time.Now())
+	return cronlib.NewJobModel(everyMinuteSpec, func() {
+		now := time.Now()
+		if now.Before(next) {
+			return
+		}
+		next = schedule.Next(now)
+		f()
+	})
+}
+
+// isMisfired returns true if the cron should have been fired between the last fire time and now
+func isMisfired(spec string, lastFireTime int64) bool {
+	if lastFireTime == 0 {
+		return false
+	}
+	schedule, err := cronParser.Parse(spec)
+	if err != nil {
+		log.Warnf("failed to parse cron %s: %v", spec, err)
+		return false
+	}
+	return schedule.Next(time.Unix(lastFireTime, 0)).Before(time.Now())
+}
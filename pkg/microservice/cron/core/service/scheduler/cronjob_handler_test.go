@@ -0,0 +1,122 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/koderover/zadig/pkg/microservice/cron/core/service"
+	"github.com/koderover/zadig/pkg/microservice/cron/core/service/client"
+	"github.com/koderover/zadig/pkg/setting"
+)
+
+var _ = Describe("Cron with a time zone", func() {
+	DescribeTable("next fire time",
+		func(spec, timezone string, from, expected time.Time) {
+			schedule, err := cronParser.Parse(withTimezone(spec, timezone))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(schedule.Next(from).Equal(expected)).To(BeTrue())
+		},
+		Entry("server local time", "0 30 9 * * *", "", time.Date(2023, 1, 1, 0, 0, 0, 0, time.Local), time.Date(2023, 1, 1, 9, 30, 0, 0, time.Local)),
+		Entry("asia/shanghai", "0 30 9 * * *", "Asia/Shanghai", time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2023, 1, 1, 1, 30, 0, 0, time.UTC)),
+		Entry("next day", "0 30 9 * * *", "Asia/Shanghai", time.Date(2023, 1, 1, 2, 0, 0, 0, time.UTC), time.Date(2023, 1, 2, 1, 30, 0, 0, time.UTC)),
+		Entry("new york in winter", "0 0 8 * * 1-5", "America/New_York", time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC), time.Date(2023, 1, 2, 13, 0, 0, 0, time.UTC)),
+		Entry("new york in summer", "0 0 8 * * 1-5", "America/New_York", time.Date(2023, 7, 3, 0, 0, 0, 0, time.UTC), time.Date(2023, 7, 3, 12, 0, 0, 0, time.UTC)),
+	)
+
+	DescribeTable("registering the cron",
+		func(spec, timezone string, valid bool) {
+			job, err := newCronJobModel(withTimezone(spec, timezone), func() {})
+			if !valid {
+				Expect(err).To(HaveOccurred())
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+			Expect(job).NotTo(BeNil())
+		},
+		Entry("without a time zone", "0 30 9 * * *", "", true),
+		Entry("with a time zone", "0 30 9 * * *", "Asia/Shanghai", true),
+		Entry("with a fixed gap", "0 */15 * * * *", "Europe/Berlin", true),
+		Entry("with an unknown time zone", "0 30 9 * * *", "Mars/Olympus", false),
+		Entry("with an invalid cron", "0 30 25 * * *", "Asia/Shanghai", false),
+	)
+})
+
+var _ = Describe("Workflow v4 cronjob", func() {
+	DescribeTable("misfire",
+		func(spec string, lastFire time.Duration, misfired bool) {
+			lastFireTime := int64(0)
+			if lastFire > 0 {
+				lastFireTime = time.Now().Add(-lastFire).Unix()
+			}
+			Expect(isMisfired(spec, lastFireTime)).To(Equal(misfired))
+		},
+		Entry("never fired", "0 * * * * *", time.Duration(0), false),
+		Entry("missed fire time", "0 * * * * *", time.Hour, true),
+		Entry("next fire time not reached", "0 0 0 1 1 *", time.Second, false),
+		Entry("missed fire time in a time zone", "CRON_TZ=Asia/Shanghai 0 * * * * *", time.Hour, true),
+		Entry("invalid cron", "0 0 25 * * *", time.Hour, false),
+	)
+
+	// a nil list of the running tasks makes aslan fail to list them
+	DescribeTable("concurrency policy",
+		func(policy string, running []int64, expected []string) {
+			var (
+				mu       sync.Mutex
+				requests []string
+			)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				requests = append(requests, r.Method+" "+r.URL.Path)
+				mu.Unlock()
+				w.Header().Set("Content-Type", "application/json")
+				if r.Method == http.MethodGet {
+					if running == nil {
+						w.WriteHeader(http.StatusInternalServerError)
+						return
+					}
+					_ = json.NewEncoder(w).Encode(running)
+					return
+				}
+				_, _ = w.Write([]byte("{}"))
+			}))
+			defer server.Close()
+
+			fireWorkflowV4Job(client.NewAslanClient(server.URL), "job", "cron", policy, &service.WorkflowV4{Name: "wf"})
+			Expect(requests).To(Equal(expected))
+		},
+		Entry("queue does not check the running tasks", setting.CronConcurrencyQueue, []int64{1},
+			[]string{"PUT /cron/cronjob/job/fire", "POST /workflow/v4/workflowtask/trigger"}),
+		Entry("skip with a running task", setting.CronConcurrencySkip, []int64{1},
+			[]string{"PUT /cron/cronjob/job/fire", "GET /cron/cronjob/workflow/v4/wf/task"}),
+		Entry("skip when the running tasks can't be checked", setting.CronConcurrencySkip, nil,
+			[]string{"PUT /cron/cronjob/job/fire", "GET /cron/cronjob/workflow/v4/wf/task"}),
+		Entry("skip without a running task", setting.CronConcurrencySkip, []int64{},
+			[]string{"PUT /cron/cronjob/job/fire", "GET /cron/cronjob/workflow/v4/wf/task", "POST /workflow/v4/workflowtask/trigger"}),
+		Entry("cancel previous cancels the running tasks", setting.CronConcurrencyCancelPrevious, []int64{3, 4},
+			[]string{"PUT /cron/cronjob/job/fire", "GET /cron/cronjob/workflow/v4/wf/task",
+				"DELETE /cron/cronjob/workflow/v4/wf/task/3", "DELETE /cron/cronjob/workflow/v4/wf/task/4",
+				"POST /workflow/v4/workflowtask/trigger"}),
+	)
+})
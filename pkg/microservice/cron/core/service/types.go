@@ -93,7 +93,9 @@ type Schedule struct {
 	Cron            string             `bson:"cron"                          json:"cron"`
 	IsModified      bool               `bson:"-"                             json:"-"`
 	// 自由编排工作流的开关是放在schedule里面的
-	Enabled bool `bson:"enabled"                       json:"enabled"`
+	Enabled           bool   `bson:"enabled"                       json:"enabled"`
+	ConcurrencyPolicy string `bson:"concurrency_policy,omitempty"  json:"concurrency_policy,omitempty"`
+	Timezone          string `bson:"timezone,omitempty"            json:"timezone,omitempty"`
 }
 
 // Validate validate schedule setting
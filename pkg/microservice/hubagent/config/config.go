@@ -44,6 +44,11 @@ func AslanBaseAddr() string {
 	return viper.GetString(setting.AslanBaseAddr)
 }
 
+// HubAgentVersion is the version of the agent reported to the hub server when connecting
+func HubAgentVersion() string {
+	return viper.GetString(setting.HubAgentVersion)
+}
+
 func ScheduleWorkflow() string {
 	return viper.GetString(setting.ScheduleWorkflow)
 }
@@ -74,6 +74,7 @@ type ClusterInfo struct {
 	Address string `json:"address"`
 	Token   string `json:"token"`
 	CACert  string `json:"caCert"`
+	Version string `json:"version"`
 }
 
 func (c *Client) getParams() (*input, error) {
@@ -92,6 +93,7 @@ func (c *Client) getParams() (*input, error) {
 			Address: fmt.Sprintf("https://%s:%s", c.ServiceHost, c.ServicePort),
 			Token:   strings.TrimSpace(string(token)),
 			CACert:  base64.StdEncoding.EncodeToString(caData),
+			Version: config.HubAgentVersion(),
 		},
 	}, nil
 }
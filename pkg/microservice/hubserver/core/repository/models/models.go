@@ -44,6 +44,7 @@ type K8SCluster struct {
 	Token              string                  `json:"token"                     bson:"-"`
 	Local              bool                    `json:"local"                     bson:"local"`
 	LastConnectionTime int64                   `json:"last_connection_time"      bson:"last_connection_time"`
+	AgentVersion       string                  `json:"agent_version"             bson:"agent_version"`
 
 	// new field in 1.14, intended to enable kubeconfig for cluster management
 	Type       string `json:"type"           bson:"type"` // either agent or kubeconfig supported
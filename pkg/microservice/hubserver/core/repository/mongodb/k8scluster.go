@@ -71,6 +71,7 @@ func (c *K8sClusterColl) UpdateStatus(cluster *models.K8SCluster) error {
 	update := bson.M{"$set": bson.M{
 		"last_connection_time": cluster.LastConnectionTime,
 		"status":               cluster.Status,
+		"agent_version":        cluster.AgentVersion,
 	}}
 
 	_, err := c.UpdateOne(context.TODO(), query, update)
@@ -85,6 +85,7 @@ func Authorize(req *http.Request) (clientKey string, authed bool, err error) {
 
 	cluster.Status = "normal"
 	cluster.LastConnectionTime = time.Now().Unix()
+	cluster.AgentVersion = input.Cluster.Version
 	err = mongodb.NewK8sClusterColl().UpdateStatus(cluster)
 	if err != nil {
 		log.Errorf("failed to update clusters status %s %v", cluster.Name, err)
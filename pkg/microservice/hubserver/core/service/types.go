@@ -29,4 +29,5 @@ type ClusterInfo struct {
 	Address string `json:"address"`
 	Token   string `json:"token"`
 	CACert  string `json:"caCert"`
+	Version string `json:"version"`
 }
@@ -42,6 +42,10 @@ func NewEngine(handler *remotedialer.Server) *engine {
 func (s *engine) injectRouters(handler *remotedialer.Server) {
 	r := s.Router
 
+	r.HandleFunc("/health", func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
 	r.Handle("/connect", handler)
 
 	r.HandleFunc("/disconnect/{id}", func(rw http.ResponseWriter, req *http.Request) {
@@ -18,6 +18,7 @@ package configmap
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -73,3 +74,35 @@ func (u *updater) UpdateWithRetry(cm *v1.ConfigMap, retryCount int, retryInterva
 	}
 	return err
 }
+
+type memoryUpdater struct {
+	mu sync.Mutex
+	cm *v1.ConfigMap
+}
+
+// NewMemoryUpdater keeps the job context configmap in memory, it is used when the job runs outside kubernetes
+func NewMemoryUpdater(cmName string) Updater {
+	return &memoryUpdater{
+		cm: &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: cmName},
+			Data:       map[string]string{},
+		},
+	}
+}
+
+func (u *memoryUpdater) Get() (*v1.ConfigMap, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.cm.DeepCopy(), nil
+}
+
+func (u *memoryUpdater) Update(cm *v1.ConfigMap) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.cm = cm.DeepCopy()
+	return nil
+}
+
+func (u *memoryUpdater) UpdateWithRetry(cm *v1.ConfigMap, retryCount int, retryInterval time.Duration) error {
+	return u.Update(cm)
+}
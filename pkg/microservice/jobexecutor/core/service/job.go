@@ -63,7 +63,7 @@ func NewJob() (*Job, error) {
 	}
 
 	if ctx.Paths != "" {
-		ctx.Paths = fmt.Sprintf("%s:%s", config.Path(), ctx.Paths)
+		ctx.Paths = fmt.Sprintf("%s%c%s", config.Path(), os.PathListSeparator, ctx.Paths)
 	} else {
 		ctx.Paths = config.Path()
 	}
@@ -88,9 +88,26 @@ func NewJob() (*Job, error) {
 		job.UserEnvs[items[0]] = items[1]
 	}
 
+	if err := job.writeOIDCToken(); err != nil {
+		return nil, fmt.Errorf("failed to write the oidc token: %s", err)
+	}
+
 	return job, nil
 }
 
+// writeOIDCToken writes the workload identity token into the file read by the cloud sdks
+func (j *Job) writeOIDCToken() error {
+	tokenFile, token := j.UserEnvs[job.OIDCTokenFileEnv], j.UserEnvs[job.OIDCTokenEnv]
+	if tokenFile == "" || token == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(tokenFile), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(tokenFile, []byte(token), 0600)
+}
+
 func (j *Job) EnsureActiveWorkspace(workspace string) error {
 	if workspace == "" {
 		tempWorkspace, err := ioutil.TempDir(os.TempDir(), "jobexecutor")
@@ -146,9 +163,20 @@ func (j *Job) Run(ctx context.Context) error {
 		if hasFailed && !stepInfo.Onfailure {
 			continue
 		}
-		if err := step.RunStep(ctx, stepInfo, j.ActiveWorkspace, j.Ctx.Paths, j.getUserEnvs(), j.Ctx.SecretEnvs, j.ConfigMapUpdater); err != nil {
+		envs, secretEnvs := j.getUserEnvs(), append([]string{}, j.Ctx.SecretEnvs...)
+		for _, input := range stepInfo.Inputs {
+			env := fmt.Sprintf("%s=%s", input.Key, input.Value)
+			envs = append(envs, env)
+			if input.IsCredential {
+				secretEnvs = append(secretEnvs, env)
+			}
+		}
+		fmt.Println(job.LogSectionStart(stepInfo.Name))
+		err := step.RunStep(ctx, stepInfo, j.ActiveWorkspace, j.Ctx.Paths, envs, secretEnvs, j.ConfigMapUpdater)
+		fmt.Println(job.LogSectionEnd(stepInfo.Name, err != nil))
+		if err != nil {
 			hasFailed = true
-			respErr = err
+			respErr = fmt.Errorf("step %s failed: %v", stepInfo.Name, err)
 		}
 	}
 	return respErr
@@ -185,7 +213,7 @@ func (j *Job) getJobOutputVars(ctx context.Context) ([]*job.JobOutput, error) {
 		} else if err != nil {
 			return outputs, err
 		}
-		value := strings.Trim(string(fileContents), "\n")
+		value := strings.TrimRight(string(fileContents), "\r\n")
 		outputs = append(outputs, &job.JobOutput{Name: outputName, Value: value})
 	}
 	return outputs, nil
@@ -46,6 +46,11 @@ func RunStep(ctx context.Context, step *meta.Step, workspace, paths string, envs
 		if err != nil {
 			return err
 		}
+	case "powershell":
+		stepInstance, err = NewPowerShellStep(step.Spec, workspace, envs, secretEnvs)
+		if err != nil {
+			return err
+		}
 	case "git":
 		stepInstance, err = NewGitStep(step.Spec, workspace, envs, secretEnvs)
 		if err != nil {
@@ -86,6 +91,41 @@ func RunStep(ctx context.Context, step *meta.Step, workspace, paths string, envs
 		if err != nil {
 			return err
 		}
+	case "restore_cache":
+		stepInstance, err = NewRestoreCacheStep(step.Spec, workspace, envs, secretEnvs)
+		if err != nil {
+			return err
+		}
+	case "save_cache":
+		stepInstance, err = NewSaveCacheStep(step.Spec, workspace, envs, secretEnvs)
+		if err != nil {
+			return err
+		}
+	case "coverage":
+		stepInstance, err = NewCoverageStep(step.Spec, workspace, envs, secretEnvs)
+		if err != nil {
+			return err
+		}
+	case "performance":
+		stepInstance, err = NewPerformanceStep(step.Spec, workspace, envs, secretEnvs)
+		if err != nil {
+			return err
+		}
+	case "service_ready":
+		stepInstance, err = NewServiceReadyStep(step.Spec)
+		if err != nil {
+			return err
+		}
+	case "upload_job_files":
+		stepInstance, err = NewUploadJobFilesStep(step.Spec, workspace, envs, secretEnvs)
+		if err != nil {
+			return err
+		}
+	case "download_job_files":
+		stepInstance, err = NewDownloadJobFilesStep(step.Spec, workspace, envs, secretEnvs)
+		if err != nil {
+			return err
+		}
 	case "debug_before":
 		stepInstance, err = NewDebugStep("before", workspace, envs, secretEnvs, updater)
 		if err != nil {
@@ -0,0 +1,87 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+
+	"github.com/koderover/zadig/pkg/microservice/reaper/core/service/meta"
+)
+
+// allureResultSuffix is the suffix of the result files generated by allure adapters
+const allureResultSuffix = "-result.json"
+
+type allureResult struct {
+	Name          string `json:"name"`
+	FullName      string `json:"fullName"`
+	Status        string `json:"status"`
+	StatusDetails struct {
+		Message string `json:"message"`
+		Trace   string `json:"trace"`
+	} `json:"statusDetails"`
+	Start  int64 `json:"start"`
+	Stop   int64 `json:"stop"`
+	Labels []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"labels"`
+}
+
+func isAllureResultFile(name string) bool {
+	return strings.HasSuffix(name, allureResultSuffix)
+}
+
+// parseAllureResult converts an allure result file into a junit test case,
+// a broken case is reported as an error and a failed one as a failure.
+func parseAllureResult(filePath string) (*meta.TestCase, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	result := &allureResult{}
+	if err := json.Unmarshal(content, result); err != nil {
+		return nil, err
+	}
+
+	testCase := &meta.TestCase{
+		Name: result.Name,
+		Time: float64(result.Stop-result.Start) / 1000,
+	}
+	for _, label := range result.Labels {
+		if label.Name == "suite" || (label.Name == "testClass" && testCase.ClassName == "") {
+			testCase.ClassName = label.Value
+		}
+	}
+	if testCase.ClassName == "" {
+		testCase.ClassName = strings.TrimSuffix(result.FullName, "."+result.Name)
+	}
+	if testCase.Time < 0 {
+		testCase.Time = 0
+	}
+
+	switch result.Status {
+	case "failed":
+		testCase.Failure = &meta.Failure{Message: result.StatusDetails.Message, Text: result.StatusDetails.Trace}
+	case "broken":
+		testCase.Error = &meta.Error{Message: result.StatusDetails.Message, Text: result.StatusDetails.Trace}
+	case "skipped", "unknown":
+		testCase.Skipped = &meta.Skipped{}
+	}
+	return testCase, nil
+}
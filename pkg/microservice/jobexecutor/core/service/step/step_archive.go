@@ -83,17 +83,28 @@ func (s *ArchiveStep) Run(ctx context.Context) error {
 		}
 
 		info, err := os.Stat(upload.AbsFilePath)
+		if err != nil && os.IsNotExist(err) && upload.IgnoreNotExist {
+			log.Warnf("file path %s does not exist, skip it", upload.FilePath)
+			continue
+		}
 		if err != nil {
 			return fmt.Errorf("failed to upload file path [%s] to destination [%s], the error is: %s", upload.AbsFilePath, upload.DestinationPath, err)
 		}
 		// if the given path is a directory
 		if info.IsDir() {
-			err := client.UploadDir(s.spec.S3.Bucket, upload.AbsFilePath, upload.DestinationPath)
+			destPath := upload.DestinationPath
+			if upload.PreservePath {
+				destPath = filepath.Join(destPath, filepath.Clean("/"+upload.FilePath))
+			}
+			err := client.UploadDir(s.spec.S3.Bucket, upload.AbsFilePath, destPath)
 			if err != nil {
 				return err
 			}
 		} else {
 			key := filepath.Join(upload.DestinationPath, info.Name())
+			if upload.PreservePath {
+				key = filepath.Join(upload.DestinationPath, filepath.Clean("/"+upload.FilePath))
+			}
 			err := client.Upload(s.spec.S3.Bucket, upload.AbsFilePath, key)
 			if err != nil {
 				return err
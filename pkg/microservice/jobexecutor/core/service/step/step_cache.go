@@ -0,0 +1,298 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/tool/s3"
+	"github.com/koderover/zadig/pkg/types"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+var (
+	checksumRegexp = regexp.MustCompile(`\{\{\s*checksum\s+"([^"]+)"\s*\}\}`)
+	invalidKeyChar = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+)
+
+type cacheStep struct {
+	spec       *step.StepCacheSpec
+	envs       []string
+	secretEnvs []string
+	workspace  string
+}
+
+type RestoreCacheStep struct {
+	*cacheStep
+}
+
+type SaveCacheStep struct {
+	*cacheStep
+}
+
+func newCacheStep(spec interface{}, workspace string, envs, secretEnvs []string) (*cacheStep, error) {
+	s := &cacheStep{workspace: workspace, envs: envs, secretEnvs: secretEnvs}
+	yamlBytes, err := yaml.Marshal(spec)
+	if err != nil {
+		return s, fmt.Errorf("marshal spec %+v failed", spec)
+	}
+	if err := yaml.Unmarshal(yamlBytes, &s.spec); err != nil {
+		return s, fmt.Errorf("unmarshal spec %s to cache spec failed", yamlBytes)
+	}
+	return s, nil
+}
+
+func NewRestoreCacheStep(spec interface{}, workspace string, envs, secretEnvs []string) (*RestoreCacheStep, error) {
+	s, err := newCacheStep(spec, workspace, envs, secretEnvs)
+	return &RestoreCacheStep{cacheStep: s}, err
+}
+
+func NewSaveCacheStep(spec interface{}, workspace string, envs, secretEnvs []string) (*SaveCacheStep, error) {
+	s, err := newCacheStep(spec, workspace, envs, secretEnvs)
+	return &SaveCacheStep{cacheStep: s}, err
+}
+
+// Run restores the caches, a missing or broken cache never fails the job
+func (s *RestoreCacheStep) Run(ctx context.Context) error {
+	for _, rule := range s.spec.Rules {
+		fileName, err := s.archiveName(rule)
+		if err != nil {
+			log.Warnf("skip restoring cache %s: %s", rule.Key, err)
+			continue
+		}
+		localFile := filepath.Join(os.TempDir(), fileName)
+		found, err := s.fetch(fileName, localFile)
+		if err != nil {
+			log.Warnf("failed to fetch cache %s: %s", fileName, err)
+			continue
+		}
+		if !found {
+			log.Infof("Cache %s not found.", fileName)
+			continue
+		}
+		cmd := exec.Command("tar", "-xzf", localFile, "-C", s.workspace)
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			log.Warnf("failed to extract cache %s: %s", fileName, err)
+			continue
+		}
+		_ = os.Remove(localFile)
+		log.Infof("Cache %s restored.", fileName)
+	}
+	return nil
+}
+
+// Run saves the caches which do not exist yet and evicts the expired ones, failures are only logged
+func (s *SaveCacheStep) Run(ctx context.Context) error {
+	for _, rule := range s.spec.Rules {
+		fileName, err := s.archiveName(rule)
+		if err != nil {
+			log.Warnf("skip saving cache %s: %s", rule.Key, err)
+			continue
+		}
+		exist, err := s.exist(fileName)
+		if err != nil {
+			log.Warnf("failed to check cache %s, skip saving: %s", fileName, err)
+			continue
+		}
+		if exist {
+			log.Infof("Cache %s already exists, skip saving.", fileName)
+			continue
+		}
+
+		args := []string{"-czf", filepath.Join(os.TempDir(), fileName), "-C", s.workspace}
+		for _, cachePath := range rule.Paths {
+			cachePath = strings.TrimPrefix(s.render(cachePath), "/")
+			if _, err := os.Stat(filepath.Join(s.workspace, cachePath)); err != nil {
+				log.Warnf("cache path %s does not exist, skip it", cachePath)
+				continue
+			}
+			args = append(args, cachePath)
+		}
+		if len(args) < 5 {
+			continue
+		}
+		cmd := exec.Command("tar", args...)
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			log.Warnf("failed to archive cache %s: %s", fileName, err)
+			continue
+		}
+		if err := s.store(fileName, filepath.Join(os.TempDir(), fileName)); err != nil {
+			log.Warnf("failed to save cache %s: %s", fileName, err)
+			continue
+		}
+		log.Infof("Cache %s saved.", fileName)
+	}
+	s.evict()
+	return nil
+}
+
+func (s *cacheStep) render(str string) string {
+	return replaceEnvWithValue(str, makeEnvMap(s.envs, s.secretEnvs))
+}
+
+// archiveName renders the key of the rule into the file name of the cache archive, it fails if a checksum file
+// can not be read since the keys of different inputs would be the same
+func (s *cacheStep) archiveName(rule *types.CacheRule) (string, error) {
+	var checksumErr error
+	key := checksumRegexp.ReplaceAllStringFunc(rule.Key, func(match string) string {
+		file := checksumRegexp.FindStringSubmatch(match)[1]
+		content, err := os.ReadFile(filepath.Join(s.workspace, strings.TrimPrefix(s.render(file), "/")))
+		if err != nil {
+			checksumErr = fmt.Errorf("failed to read %s for cache key checksum: %s", file, err)
+			return ""
+		}
+		sum := sha256.Sum256(content)
+		return hex.EncodeToString(sum[:])[:16]
+	})
+	if checksumErr != nil {
+		return "", checksumErr
+	}
+	key = invalidKeyChar.ReplaceAllString(s.render(key), "-")
+	return key + ".tar.gz", nil
+}
+
+func (s *cacheStep) s3Client() (*s3.Client, error) {
+	if s.spec.S3Storage == nil {
+		return nil, fmt.Errorf("no object storage for build cache")
+	}
+	forcedPathStyle := true
+	if s.spec.S3Storage.Provider == setting.ProviderSourceAli {
+		forcedPathStyle = false
+	}
+	return s3.NewClient(s.spec.S3Storage.Endpoint, s.spec.S3Storage.Ak, s.spec.S3Storage.Sk, s.spec.S3Storage.Region, s.spec.S3Storage.Insecure, forcedPathStyle)
+}
+
+func (s *cacheStep) objectKey(fileName string) string {
+	return path.Join(s.spec.S3Storage.Subfolder, s.spec.S3DestDir, fileName)
+}
+
+func (s *cacheStep) exist(fileName string) (bool, error) {
+	if s.spec.MediumType == types.NFSMedium {
+		_, err := os.Stat(filepath.Join(s.spec.CacheDir, fileName))
+		return err == nil, nil
+	}
+	client, err := s.s3Client()
+	if err != nil {
+		return false, err
+	}
+	files, err := client.ListFiles(s.spec.S3Storage.Bucket, s.objectKey(fileName), false)
+	if err != nil {
+		return false, err
+	}
+	return len(files) > 0, nil
+}
+
+func (s *cacheStep) fetch(fileName, dest string) (bool, error) {
+	if s.spec.MediumType == types.NFSMedium {
+		src := filepath.Join(s.spec.CacheDir, fileName)
+		if _, err := os.Stat(src); err != nil {
+			return false, nil
+		}
+		return true, exec.Command("cp", src, dest).Run()
+	}
+	client, err := s.s3Client()
+	if err != nil {
+		return false, err
+	}
+	if err := client.DownloadWithOption(s.spec.S3Storage.Bucket, s.objectKey(fileName), dest, &s3.DownloadOption{IgnoreNotExistError: true, RetryNum: 2}); err != nil {
+		return false, err
+	}
+	_, err = os.Stat(dest)
+	return err == nil, nil
+}
+
+func (s *cacheStep) store(fileName, src string) error {
+	defer os.Remove(src)
+	if s.spec.MediumType == types.NFSMedium {
+		if err := os.MkdirAll(s.spec.CacheDir, 0755); err != nil {
+			return err
+		}
+		// copy then rename so that a concurrent job never reads a partial archive
+		tmp := filepath.Join(s.spec.CacheDir, "."+fileName+".tmp")
+		if err := exec.Command("cp", src, tmp).Run(); err != nil {
+			return err
+		}
+		return os.Rename(tmp, filepath.Join(s.spec.CacheDir, fileName))
+	}
+	client, err := s.s3Client()
+	if err != nil {
+		return err
+	}
+	return client.Upload(s.spec.S3Storage.Bucket, src, s.objectKey(fileName))
+}
+
+func (s *cacheStep) evict() {
+	if s.spec.MaxAgeDays <= 0 {
+		return
+	}
+	before := time.Now().AddDate(0, 0, -s.spec.MaxAgeDays)
+	if s.spec.MediumType == types.NFSMedium {
+		entries, err := os.ReadDir(s.spec.CacheDir)
+		if err != nil {
+			return
+		}
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil || entry.IsDir() || !info.ModTime().Before(before) {
+				continue
+			}
+			if err := os.Remove(filepath.Join(s.spec.CacheDir, entry.Name())); err == nil {
+				log.Infof("Expired cache %s evicted.", entry.Name())
+			}
+		}
+		return
+	}
+
+	client, err := s.s3Client()
+	if err != nil {
+		return
+	}
+	keys, err := client.ListFilesModifiedBefore(s.spec.S3Storage.Bucket, s.objectKey("")+"/", before)
+	if err != nil {
+		return
+	}
+	// a delete request takes at most 1000 keys
+	for i := 0; i < len(keys); i += 1000 {
+		end := i + 1000
+		if end > len(keys) {
+			end = len(keys)
+		}
+		if err := client.DeleteObjects(s.spec.S3Storage.Bucket, keys[i:end]); err != nil {
+			log.Warnf("failed to evict expired caches: %s", err)
+			return
+		}
+	}
+	if len(keys) > 0 {
+		log.Infof("%d expired caches evicted.", len(keys))
+	}
+}
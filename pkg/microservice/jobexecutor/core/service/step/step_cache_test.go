@@ -0,0 +1,60 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/koderover/zadig/pkg/types"
+)
+
+var _ = Describe("Cache archive name", func() {
+	var s *cacheStep
+
+	BeforeEach(func() {
+		workspace, err := os.MkdirTemp("", "cache")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, workspace)
+		Expect(os.WriteFile(filepath.Join(workspace, "go.sum"), []byte("sum"), 0644)).To(Succeed())
+		s = &cacheStep{workspace: workspace, envs: []string{"BRANCH=feature/login"}}
+	})
+
+	It("renders the variables and the checksums", func() {
+		name, err := s.archiveName(&types.CacheRule{Key: `go-$BRANCH-{{ checksum "go.sum" }}`})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(name).To(MatchRegexp(`^go-feature-login-[0-9a-f]{16}\.tar\.gz$`))
+	})
+
+	It("changes with the content of the checksum file", func() {
+		rule := &types.CacheRule{Key: `go-{{ checksum "go.sum" }}`}
+		before, err := s.archiveName(rule)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(filepath.Join(s.workspace, "go.sum"), []byte("changed"), 0644)).To(Succeed())
+		after, err := s.archiveName(rule)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(after).NotTo(Equal(before))
+	})
+
+	It("fails if the checksum file can not be read", func() {
+		_, err := s.archiveName(&types.CacheRule{Key: `go-{{ checksum "missing.sum" }}`})
+		Expect(err).To(HaveOccurred())
+	})
+})
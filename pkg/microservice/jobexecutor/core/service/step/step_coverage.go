@@ -0,0 +1,247 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/tool/s3"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+type CoverageStep struct {
+	spec       *step.StepCoverageSpec
+	envs       []string
+	secretEnvs []string
+	workspace  string
+}
+
+func NewCoverageStep(spec interface{}, workspace string, envs, secretEnvs []string) (*CoverageStep, error) {
+	coverageStep := &CoverageStep{workspace: workspace, envs: envs, secretEnvs: secretEnvs}
+	yamlBytes, err := yaml.Marshal(spec)
+	if err != nil {
+		return coverageStep, fmt.Errorf("marshal spec %+v failed", spec)
+	}
+	if err := yaml.Unmarshal(yamlBytes, &coverageStep.spec); err != nil {
+		return coverageStep, fmt.Errorf("unmarshal spec %s to coverage spec failed", yamlBytes)
+	}
+	return coverageStep, nil
+}
+
+func (s *CoverageStep) Run(ctx context.Context) error {
+	reportPath := replaceEnvWithValue(s.spec.ReportPath, makeEnvMap(s.envs, s.secretEnvs))
+	reportPath = filepath.Join(s.workspace, strings.TrimPrefix(reportPath, "/"))
+	content, err := os.ReadFile(reportPath)
+	if err != nil {
+		return fmt.Errorf("failed to read coverage report %s: %s", reportPath, err)
+	}
+
+	summary, err := parseCoverageReport(s.spec.Format, content)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s coverage report %s: %s", s.spec.Format, reportPath, err)
+	}
+	log.Infof("Line coverage: %.2f%% (%d/%d)", summary.Coverage, summary.LinesCovered, summary.LinesTotal)
+
+	// the summary is archived before the gates are checked, so that a failed run is still recorded
+	if err := s.uploadSummary(summary); err != nil {
+		log.Warnf("failed to upload coverage summary: %s", err)
+	}
+
+	if s.spec.MinCoverage > 0 && summary.Coverage < s.spec.MinCoverage {
+		return fmt.Errorf("coverage %.2f%% is below the threshold %.2f%%", summary.Coverage, s.spec.MinCoverage)
+	}
+	if s.spec.HasBase && s.spec.MaxRegression > 0 && s.spec.BaseCoverage-summary.Coverage > s.spec.MaxRegression {
+		return fmt.Errorf("coverage %.2f%% regresses more than %.2f%% from %.2f%% of the base branch %s",
+			summary.Coverage, s.spec.MaxRegression, s.spec.BaseCoverage, s.spec.BaseBranch)
+	}
+	return nil
+}
+
+func (s *CoverageStep) uploadSummary(summary *step.CoverageSummary) error {
+	if s.spec.S3Storage == nil || s.spec.S3DestDir == "" {
+		return nil
+	}
+	forcedPathStyle := true
+	if s.spec.S3Storage.Provider == setting.ProviderSourceAli {
+		forcedPathStyle = false
+	}
+	client, err := s3.NewClient(s.spec.S3Storage.Endpoint, s.spec.S3Storage.Ak, s.spec.S3Storage.Sk, s.spec.S3Storage.Region, s.spec.S3Storage.Insecure, forcedPathStyle)
+	if err != nil {
+		return err
+	}
+
+	content, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	localFile := filepath.Join(os.TempDir(), step.CoverageSummaryFile)
+	if err := os.WriteFile(localFile, content, 0644); err != nil {
+		return err
+	}
+	defer os.Remove(localFile)
+
+	key := path.Join(s.spec.S3Storage.Subfolder, s.spec.S3DestDir, step.CoverageSummaryFile)
+	return client.Upload(s.spec.S3Storage.Bucket, localFile, key)
+}
+
+func parseCoverageReport(format string, content []byte) (*step.CoverageSummary, error) {
+	summary := &step.CoverageSummary{}
+	var err error
+	switch format {
+	case step.CoverageFormatLcov:
+		summary.LinesCovered, summary.LinesTotal, err = parseLcov(content)
+	case step.CoverageFormatCobertura:
+		summary.LinesCovered, summary.LinesTotal, err = parseCobertura(content)
+	case step.CoverageFormatGoCover:
+		summary.LinesCovered, summary.LinesTotal, err = parseGoCover(content)
+	default:
+		return nil, fmt.Errorf("unsupported coverage format %s", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if summary.LinesTotal > 0 {
+		summary.Coverage = float64(summary.LinesCovered) * 100 / float64(summary.LinesTotal)
+	}
+	return summary, nil
+}
+
+// parseLcov sums up the LH/LF records of all the source files
+func parseLcov(content []byte) (int, int, error) {
+	covered, total := 0, 0
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "LF:"):
+			n, err := strconv.Atoi(strings.TrimPrefix(line, "LF:"))
+			if err != nil {
+				return 0, 0, fmt.Errorf("invalid record %s", line)
+			}
+			total += n
+		case strings.HasPrefix(line, "LH:"):
+			n, err := strconv.Atoi(strings.TrimPrefix(line, "LH:"))
+			if err != nil {
+				return 0, 0, fmt.Errorf("invalid record %s", line)
+			}
+			covered += n
+		}
+	}
+	return covered, total, scanner.Err()
+}
+
+type coberturaReport struct {
+	LinesCovered int `xml:"lines-covered,attr"`
+	LinesValid   int `xml:"lines-valid,attr"`
+	Packages     []struct {
+		Classes []struct {
+			Filename string `xml:"filename,attr"`
+			Lines    []struct {
+				Number int `xml:"number,attr"`
+				Hits   int `xml:"hits,attr"`
+			} `xml:"lines>line"`
+		} `xml:"classes>class"`
+	} `xml:"packages>package"`
+}
+
+// parseCobertura reads the line counts of the report, they are counted from the lines of the classes if the
+// generator leaves them out. A line in several classes of the same file is covered if any of them covers it
+func parseCobertura(content []byte) (int, int, error) {
+	report := &coberturaReport{}
+	if err := xml.Unmarshal(content, report); err != nil {
+		return 0, 0, err
+	}
+	if report.LinesValid > 0 {
+		return report.LinesCovered, report.LinesValid, nil
+	}
+
+	lines := make(map[string]bool)
+	for _, pkg := range report.Packages {
+		for _, class := range pkg.Classes {
+			for _, line := range class.Lines {
+				key := fmt.Sprintf("%s:%d", class.Filename, line.Number)
+				lines[key] = lines[key] || line.Hits > 0
+			}
+		}
+	}
+	covered := 0
+	for _, hit := range lines {
+		if hit {
+			covered++
+		}
+	}
+	return covered, len(lines), nil
+}
+
+// parseGoCover counts the statements of a go cover profile, a block appearing in several
+// profiles merged into one file is covered if any of them covers it
+func parseGoCover(content []byte) (int, int, error) {
+	type block struct {
+		statements int
+		covered    bool
+	}
+	blocks := make(map[string]*block)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "mode:") {
+			continue
+		}
+		// format: name.go:line.column,line.column numberOfStatements count
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return 0, 0, fmt.Errorf("invalid profile line %s", line)
+		}
+		statements, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid profile line %s", line)
+		}
+		count, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid profile line %s", line)
+		}
+		b, ok := blocks[fields[0]]
+		if !ok {
+			b = &block{statements: statements}
+			blocks[fields[0]] = b
+		}
+		b.covered = b.covered || count > 0
+	}
+
+	covered, total := 0, 0
+	for _, b := range blocks {
+		total += b.statements
+		if b.covered {
+			covered += b.statements
+		}
+	}
+	return covered, total, scanner.Err()
+}
@@ -34,7 +34,10 @@ import (
 	"github.com/koderover/zadig/pkg/util/fs"
 )
 
-const dockerExe = "docker"
+const (
+	dockerExe           = "docker"
+	buildkitBuilderName = "zadig-buildkit"
+)
 
 type DockerBuildStep struct {
 	spec       *step.StepDockerBuildSpec
@@ -129,6 +132,21 @@ func (s *DockerBuildStep) runDockerBuild() error {
 
 func (s *DockerBuildStep) dockerCommands() []*exec.Cmd {
 	cmds := make([]*exec.Cmd, 0)
+	if s.spec.Builder == step.DockerBuilderBuildkit {
+		return append(
+			cmds,
+			buildkitBuilderCmd(),
+			buildkitBuildCmd(
+				s.spec.GetDockerFile(),
+				s.spec.ImageName,
+				s.spec.WorkDir,
+				s.spec.BuildArgs,
+				s.spec.CacheRef,
+				s.spec.CacheMode,
+				s.spec.IgnoreCache,
+			),
+		)
+	}
 	cmds = append(
 		cmds,
 		dockerBuildCmd(
@@ -163,6 +181,41 @@ func dockerBuildCmd(dockerfile, fullImage, ctx, buildArgs string, ignoreCache bo
 	return exec.Command("sh", args...)
 }
 
+// buildkitBuilderCmd makes sure a docker-container builder is in use, which is required to export the cache to registry
+func buildkitBuilderCmd() *exec.Cmd {
+	args := []string{"-c"}
+	builderCommand := fmt.Sprintf("docker buildx use %[1]s 2>/dev/null || docker buildx create --use --name %[1]s --driver docker-container", buildkitBuilderName)
+	args = append(args, builderCommand)
+	return exec.Command("sh", args...)
+}
+
+// buildkitBuildCmd builds and pushes the image in one command since the docker-container builder does not load images into the docker daemon
+func buildkitBuildCmd(dockerfile, fullImage, ctx, buildArgs, cacheRef, cacheMode string, ignoreCache bool) *exec.Cmd {
+	args := []string{"-c"}
+	buildkitCommand := "docker buildx build --push"
+	if ignoreCache {
+		buildkitCommand += " --no-cache"
+	}
+	if cacheRef != "" {
+		if cacheMode == "" {
+			cacheMode = "max"
+		}
+		if !ignoreCache {
+			buildkitCommand += fmt.Sprintf(" --cache-from type=registry,ref=%s", cacheRef)
+		}
+		buildkitCommand += fmt.Sprintf(" --cache-to type=registry,ref=%s,mode=%s", cacheRef, cacheMode)
+	}
+
+	for _, val := range strings.Fields(buildArgs) {
+		if val != "" {
+			buildkitCommand = buildkitCommand + " " + val
+		}
+	}
+	buildkitCommand = buildkitCommand + " -t " + fullImage + " -f " + dockerfile + " " + ctx
+	args = append(args, buildkitCommand)
+	return exec.Command("sh", args...)
+}
+
 func dockerPush(fullImage string) *exec.Cmd {
 	args := []string{"-c"}
 	dockerPushCommand := "docker push " + fullImage
@@ -0,0 +1,193 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/tool/s3"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+type jobFileStep struct {
+	spec       *step.StepJobFileSpec
+	envs       []string
+	secretEnvs []string
+	workspace  string
+}
+
+type UploadJobFilesStep struct {
+	*jobFileStep
+}
+
+type DownloadJobFilesStep struct {
+	*jobFileStep
+}
+
+func newJobFileStep(spec interface{}, workspace string, envs, secretEnvs []string) (*jobFileStep, error) {
+	s := &jobFileStep{workspace: workspace, envs: envs, secretEnvs: secretEnvs}
+	yamlBytes, err := yaml.Marshal(spec)
+	if err != nil {
+		return s, fmt.Errorf("marshal spec %+v failed", spec)
+	}
+	if err := yaml.Unmarshal(yamlBytes, &s.spec); err != nil {
+		return s, fmt.Errorf("unmarshal spec %s to job file spec failed", yamlBytes)
+	}
+	return s, nil
+}
+
+func NewUploadJobFilesStep(spec interface{}, workspace string, envs, secretEnvs []string) (*UploadJobFilesStep, error) {
+	s, err := newJobFileStep(spec, workspace, envs, secretEnvs)
+	return &UploadJobFilesStep{jobFileStep: s}, err
+}
+
+func NewDownloadJobFilesStep(spec interface{}, workspace string, envs, secretEnvs []string) (*DownloadJobFilesStep, error) {
+	s, err := newJobFileStep(spec, workspace, envs, secretEnvs)
+	return &DownloadJobFilesStep{jobFileStep: s}, err
+}
+
+// Run archives the declared outputs with their checksums, a missing output fails the job
+func (s *UploadJobFilesStep) Run(ctx context.Context) error {
+	client, err := s.s3Client()
+	if err != nil {
+		return err
+	}
+	for _, file := range s.spec.Files {
+		src := filepath.Join(s.workspace, strings.TrimPrefix(s.render(file.Path), "/"))
+		if _, err := os.Stat(src); err != nil {
+			return fmt.Errorf("output file %s: %s does not exist", file.Name, file.Path)
+		}
+
+		archive := filepath.Join(os.TempDir(), file.Name+".tar.gz")
+		cmd := exec.Command("tar", "-czf", archive, "-C", filepath.Dir(src), filepath.Base(src))
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("failed to archive output file %s: %s", file.Name, err)
+		}
+		checksum, err := fileChecksum(archive)
+		if err != nil {
+			return fmt.Errorf("failed to calculate checksum of output file %s: %s", file.Name, err)
+		}
+		checksumFile := archive + ".sha256"
+		if err := os.WriteFile(checksumFile, []byte(checksum), 0644); err != nil {
+			return err
+		}
+
+		key := s.objectKey(file)
+		if err := client.Upload(s.spec.S3Storage.Bucket, archive, key); err != nil {
+			return fmt.Errorf("failed to upload output file %s: %s", file.Name, err)
+		}
+		if err := client.Upload(s.spec.S3Storage.Bucket, checksumFile, key+".sha256"); err != nil {
+			return fmt.Errorf("failed to upload checksum of output file %s: %s", file.Name, err)
+		}
+		_ = os.Remove(archive)
+		_ = os.Remove(checksumFile)
+		log.Infof("Output file %s uploaded, sha256: %s.", file.Name, checksum)
+	}
+	return nil
+}
+
+// Run downloads the outputs of the previous jobs and verifies their checksums before extracting them
+func (s *DownloadJobFilesStep) Run(ctx context.Context) error {
+	client, err := s.s3Client()
+	if err != nil {
+		return err
+	}
+	for _, file := range s.spec.Files {
+		key := s.objectKey(file)
+		archive := filepath.Join(os.TempDir(), file.JobName+"-"+file.Name+".tar.gz")
+		checksumFile := archive + ".sha256"
+		option := &s3.DownloadOption{RetryNum: 2}
+		if err := client.DownloadWithOption(s.spec.S3Storage.Bucket, key, archive, option); err != nil {
+			return fmt.Errorf("failed to download file %s of job %s: %s", file.Name, file.JobName, err)
+		}
+		if err := client.DownloadWithOption(s.spec.S3Storage.Bucket, key+".sha256", checksumFile, option); err != nil {
+			return fmt.Errorf("failed to download checksum of file %s of job %s: %s", file.Name, file.JobName, err)
+		}
+
+		expected, err := os.ReadFile(checksumFile)
+		if err != nil {
+			return err
+		}
+		checksum, err := fileChecksum(archive)
+		if err != nil {
+			return fmt.Errorf("failed to calculate checksum of file %s of job %s: %s", file.Name, file.JobName, err)
+		}
+		if checksum != strings.TrimSpace(string(expected)) {
+			return fmt.Errorf("checksum mismatch of file %s of job %s, expected %s, got %s", file.Name, file.JobName, strings.TrimSpace(string(expected)), checksum)
+		}
+
+		dest := filepath.Join(s.workspace, strings.TrimPrefix(s.render(file.Path), "/"))
+		if err := os.MkdirAll(dest, 0755); err != nil {
+			return err
+		}
+		cmd := exec.Command("tar", "-xzf", archive, "-C", dest)
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("failed to extract file %s of job %s: %s", file.Name, file.JobName, err)
+		}
+		_ = os.Remove(archive)
+		_ = os.Remove(checksumFile)
+		log.Infof("File %s of job %s extracted to %s.", file.Name, file.JobName, dest)
+	}
+	return nil
+}
+
+func (s *jobFileStep) render(str string) string {
+	return replaceEnvWithValue(str, makeEnvMap(s.envs, s.secretEnvs))
+}
+
+func (s *jobFileStep) s3Client() (*s3.Client, error) {
+	if s.spec.S3Storage == nil {
+		return nil, fmt.Errorf("no object storage for job files")
+	}
+	forcedPathStyle := true
+	if s.spec.S3Storage.Provider == setting.ProviderSourceAli {
+		forcedPathStyle = false
+	}
+	return s3.NewClient(s.spec.S3Storage.Endpoint, s.spec.S3Storage.Ak, s.spec.S3Storage.Sk, s.spec.S3Storage.Region, s.spec.S3Storage.Insecure, forcedPathStyle)
+}
+
+func (s *jobFileStep) objectKey(file *step.JobFile) string {
+	return path.Join(s.spec.S3Storage.Subfolder, s.spec.S3DestDir, file.JobName, file.Name+".tar.gz")
+}
+
+func fileChecksum(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
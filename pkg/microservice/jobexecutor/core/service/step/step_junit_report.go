@@ -144,6 +144,31 @@ func mergeGinkgoTestResults(testResultFile, testResultPath, testUploadPath strin
 		return files[i].ModTime().Before(files[j].ModTime())
 	})
 	for _, file := range files {
+		if isAllureResultFile(file.Name()) {
+			filePath := path.Join(testResultPath, file.Name())
+			tc, err2 := parseAllureResult(filePath)
+			if err2 != nil {
+				log.Warningf("Parse allure result file [%s], error: %v", filePath, err2)
+				continue
+			}
+			switch {
+			case tc.Skipped != nil:
+				summaryResult.Skips++
+			case tc.Failure != nil:
+				summaryResult.Tests++
+				summaryResult.Failures++
+			case tc.Error != nil:
+				summaryResult.Tests++
+				summaryResult.Errors++
+			default:
+				summaryResult.Tests++
+			}
+			summaryResult.TestCases = append(summaryResult.TestCases, *tc)
+			if summaryResult.SuiteType == "" {
+				summaryResult.SuiteType = ReploaceTestSuite
+			}
+			continue
+		}
 		if filepath.Ext(file.Name()) == ".xml" {
 			filePath := path.Join(testResultPath, file.Name())
 			log.Infof("name %s mod time: %v", file.Name(), file.ModTime())
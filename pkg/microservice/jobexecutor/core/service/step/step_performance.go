@@ -0,0 +1,275 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/tool/s3"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+type PerformanceStep struct {
+	spec       *step.StepPerformanceSpec
+	envs       []string
+	secretEnvs []string
+	workspace  string
+}
+
+func NewPerformanceStep(spec interface{}, workspace string, envs, secretEnvs []string) (*PerformanceStep, error) {
+	performanceStep := &PerformanceStep{workspace: workspace, envs: envs, secretEnvs: secretEnvs}
+	yamlBytes, err := yaml.Marshal(spec)
+	if err != nil {
+		return performanceStep, fmt.Errorf("marshal spec %+v failed", spec)
+	}
+	if err := yaml.Unmarshal(yamlBytes, &performanceStep.spec); err != nil {
+		return performanceStep, fmt.Errorf("unmarshal spec %s to performance spec failed", yamlBytes)
+	}
+	return performanceStep, nil
+}
+
+func (s *PerformanceStep) Run(ctx context.Context) error {
+	resultPath := replaceEnvWithValue(s.spec.ResultPath, makeEnvMap(s.envs, s.secretEnvs))
+	resultPath = filepath.Join(s.workspace, strings.TrimPrefix(resultPath, "/"))
+	content, err := os.ReadFile(resultPath)
+	if err != nil {
+		return fmt.Errorf("failed to read %s result %s: %s", s.spec.Tool, resultPath, err)
+	}
+
+	var metrics *step.PerformanceMetrics
+	switch s.spec.Tool {
+	case step.PerformanceToolK6:
+		metrics, err = parseK6Summary(content)
+	case step.PerformanceToolJMeter:
+		metrics, err = parseJMeterResult(content)
+	default:
+		return fmt.Errorf("unsupported performance test tool %s", s.spec.Tool)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse %s result %s: %s", s.spec.Tool, resultPath, err)
+	}
+	log.Infof("Requests: %d, throughput: %.2f/s, error rate: %.2f%%", metrics.Requests, metrics.Throughput, metrics.ErrorRate)
+	log.Infof("Latency avg: %.2fms, p90: %.2fms, p95: %.2fms, p99: %.2fms, max: %.2fms",
+		metrics.AvgLatency, metrics.P90Latency, metrics.P95Latency, metrics.P99Latency, metrics.MaxLatency)
+
+	regressions := s.checkRegressions(metrics)
+	// the summary is archived before the gates are checked, so that a failed run is still recorded
+	summary := &step.PerformanceSummary{Tool: s.spec.Tool, Metrics: metrics, Regressions: regressions}
+	if err := s.uploadSummary(summary); err != nil {
+		log.Warnf("failed to upload performance summary: %s", err)
+	}
+
+	if len(regressions) > 0 {
+		return fmt.Errorf("performance gate failed: %s", strings.Join(regressions, "; "))
+	}
+	return nil
+}
+
+func (s *PerformanceStep) checkRegressions(metrics *step.PerformanceMetrics) []string {
+	regressions := make([]string, 0)
+	if s.spec.MaxErrorRate > 0 && metrics.ErrorRate > s.spec.MaxErrorRate {
+		regressions = append(regressions, fmt.Sprintf("error rate %.2f%% exceeds %.2f%%", metrics.ErrorRate, s.spec.MaxErrorRate))
+	}
+
+	base := s.spec.Baseline
+	if base == nil {
+		return regressions
+	}
+	log.Infof("Baseline of task #%d, throughput: %.2f/s, p95 latency: %.2fms", s.spec.BaselineTaskID, base.Throughput, base.P95Latency)
+	if s.spec.MaxLatencyRegression > 0 && base.P95Latency > 0 {
+		if rise := (metrics.P95Latency - base.P95Latency) * 100 / base.P95Latency; rise > s.spec.MaxLatencyRegression {
+			regressions = append(regressions, fmt.Sprintf("p95 latency %.2fms is %.2f%% higher than %.2fms of task #%d, the limit is %.2f%%",
+				metrics.P95Latency, rise, base.P95Latency, s.spec.BaselineTaskID, s.spec.MaxLatencyRegression))
+		}
+	}
+	if s.spec.MaxThroughputRegression > 0 && base.Throughput > 0 {
+		if drop := (base.Throughput - metrics.Throughput) * 100 / base.Throughput; drop > s.spec.MaxThroughputRegression {
+			regressions = append(regressions, fmt.Sprintf("throughput %.2f/s is %.2f%% lower than %.2f/s of task #%d, the limit is %.2f%%",
+				metrics.Throughput, drop, base.Throughput, s.spec.BaselineTaskID, s.spec.MaxThroughputRegression))
+		}
+	}
+	return regressions
+}
+
+func (s *PerformanceStep) uploadSummary(summary *step.PerformanceSummary) error {
+	if s.spec.S3Storage == nil || s.spec.S3DestDir == "" {
+		return nil
+	}
+	forcedPathStyle := true
+	if s.spec.S3Storage.Provider == setting.ProviderSourceAli {
+		forcedPathStyle = false
+	}
+	client, err := s3.NewClient(s.spec.S3Storage.Endpoint, s.spec.S3Storage.Ak, s.spec.S3Storage.Sk, s.spec.S3Storage.Region, s.spec.S3Storage.Insecure, forcedPathStyle)
+	if err != nil {
+		return err
+	}
+
+	content, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	localFile := filepath.Join(os.TempDir(), step.PerformanceSummaryFile)
+	if err := os.WriteFile(localFile, content, 0644); err != nil {
+		return err
+	}
+	defer os.Remove(localFile)
+
+	key := path.Join(s.spec.S3Storage.Subfolder, s.spec.S3DestDir, step.PerformanceSummaryFile)
+	return client.Upload(s.spec.S3Storage.Bucket, localFile, key)
+}
+
+// k6Metric is a metric in the output of k6 run --summary-export, trend metrics have the
+// latency fields, counter metrics have count and rate, rate metrics have value
+type k6Metric struct {
+	Count float64 `json:"count"`
+	Rate  float64 `json:"rate"`
+	Value float64 `json:"value"`
+	Avg   float64 `json:"avg"`
+	Max   float64 `json:"max"`
+	P90   float64 `json:"p(90)"`
+	P95   float64 `json:"p(95)"`
+	P99   float64 `json:"p(99)"`
+}
+
+func parseK6Summary(content []byte) (*step.PerformanceMetrics, error) {
+	summary := struct {
+		Metrics map[string]*k6Metric `json:"metrics"`
+	}{}
+	if err := json.Unmarshal(content, &summary); err != nil {
+		return nil, err
+	}
+	reqs, ok := summary.Metrics["http_reqs"]
+	if !ok {
+		return nil, fmt.Errorf("metric http_reqs not found")
+	}
+	metrics := &step.PerformanceMetrics{
+		Requests:   int64(reqs.Count),
+		Throughput: reqs.Rate,
+	}
+	if duration, ok := summary.Metrics["http_req_duration"]; ok {
+		metrics.AvgLatency = duration.Avg
+		metrics.P90Latency = duration.P90
+		metrics.P95Latency = duration.P95
+		metrics.P99Latency = duration.P99
+		metrics.MaxLatency = duration.Max
+	}
+	if failed, ok := summary.Metrics["http_req_failed"]; ok {
+		metrics.ErrorRate = failed.Value * 100
+	}
+	return metrics, nil
+}
+
+// parseJMeterResult computes the metrics from the samples of a jtl file saved in csv format with the header line
+func parseJMeterResult(content []byte) (*step.PerformanceMetrics, error) {
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %s", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[name] = i
+	}
+	for _, name := range []string{"timeStamp", "elapsed", "success"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("column %s not found", name)
+		}
+	}
+
+	var (
+		latencies      []float64
+		failures       int64
+		start, end     int64
+		totalLatencies float64
+	)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(record) != len(header) {
+			continue
+		}
+		timestamp, err := strconv.ParseInt(record[columns["timeStamp"]], 10, 64)
+		if err != nil {
+			continue
+		}
+		elapsed, err := strconv.ParseFloat(record[columns["elapsed"]], 64)
+		if err != nil {
+			continue
+		}
+		if record[columns["success"]] != "true" {
+			failures++
+		}
+		if start == 0 || timestamp < start {
+			start = timestamp
+		}
+		if finish := timestamp + int64(elapsed); finish > end {
+			end = finish
+		}
+		latencies = append(latencies, elapsed)
+		totalLatencies += elapsed
+	}
+	if len(latencies) == 0 {
+		return nil, fmt.Errorf("no sample found")
+	}
+
+	sort.Float64s(latencies)
+	count := int64(len(latencies))
+	metrics := &step.PerformanceMetrics{
+		Requests:   count,
+		ErrorRate:  float64(failures) * 100 / float64(count),
+		AvgLatency: totalLatencies / float64(count),
+		P90Latency: percentile(latencies, 90),
+		P95Latency: percentile(latencies, 95),
+		P99Latency: percentile(latencies, 99),
+		MaxLatency: latencies[len(latencies)-1],
+	}
+	if end > start {
+		metrics.Throughput = float64(count) * 1000 / float64(end-start)
+	}
+	return metrics, nil
+}
+
+// percentile returns the nearest-rank percentile of the sorted values
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+const k6Summary = `{
+  "metrics": {
+    "http_reqs": {"count": 1200, "rate": 40.5},
+    "http_req_duration": {"avg": 120.5, "min": 10, "med": 100, "max": 900, "p(90)": 200, "p(95)": 300, "p(99)": 800},
+    "http_req_failed": {"passes": 12, "fails": 1188, "value": 0.01}
+  }
+}`
+
+const jmeterResult = `timeStamp,elapsed,label,responseCode,success
+1000,100,home,200,true
+1100,200,home,200,true
+bad,150,home,200,true
+1200,300,login,500,false
+1300,400,login,200,true
+`
+
+var _ = Describe("parseK6Summary", func() {
+	It("reads the http metrics", func() {
+		metrics, err := parseK6Summary([]byte(k6Summary))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(metrics).To(Equal(&step.PerformanceMetrics{
+			Requests:   1200,
+			ErrorRate:  1,
+			Throughput: 40.5,
+			AvgLatency: 120.5,
+			P90Latency: 200,
+			P95Latency: 300,
+			P99Latency: 800,
+			MaxLatency: 900,
+		}))
+	})
+
+	It("requires the http_reqs metric", func() {
+		_, err := parseK6Summary([]byte(`{"metrics": {"iterations": {"count": 10}}}`))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects invalid json", func() {
+		_, err := parseK6Summary([]byte(`metrics`))
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("parseJMeterResult", func() {
+	It("computes the metrics of the valid samples", func() {
+		metrics, err := parseJMeterResult([]byte(jmeterResult))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(metrics.Requests).To(Equal(int64(4)))
+		Expect(metrics.ErrorRate).To(Equal(25.0))
+		Expect(metrics.AvgLatency).To(Equal(250.0))
+		Expect(metrics.P90Latency).To(Equal(400.0))
+		Expect(metrics.MaxLatency).To(Equal(400.0))
+		// 4 samples from 1000ms to the end of the last one at 1700ms
+		Expect(metrics.Throughput).To(BeNumerically("~", 4000.0/700, 1e-9))
+	})
+
+	It("requires the columns of the samples", func() {
+		_, err := parseJMeterResult([]byte("timeStamp,label,success\n1000,home,true\n"))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("requires a sample", func() {
+		_, err := parseJMeterResult([]byte("timeStamp,elapsed,success\n"))
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = DescribeTable("percentile",
+	func(sorted []float64, p, expected float64) {
+		Expect(percentile(sorted, p)).To(Equal(expected))
+	},
+	Entry("median", []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 50.0, 5.0),
+	Entry("p90", []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 90.0, 9.0),
+	Entry("rank is rounded up", []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 95.0, 10.0),
+	Entry("zero takes the minimum", []float64{1, 2, 3}, 0.0, 1.0),
+	Entry("single value", []float64{42}, 99.0, 42.0),
+)
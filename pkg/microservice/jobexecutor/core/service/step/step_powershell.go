@@ -0,0 +1,109 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/types/step"
+	"github.com/koderover/zadig/pkg/util"
+)
+
+// PowerShellStep runs the user scripts with powershell on the windows build agents
+type PowerShellStep struct {
+	spec       *step.StepShellSpec
+	envs       []string
+	secretEnvs []string
+	workspace  string
+}
+
+func NewPowerShellStep(spec interface{}, workspace string, envs, secretEnvs []string) (*PowerShellStep, error) {
+	powerShellStep := &PowerShellStep{workspace: workspace, envs: envs, secretEnvs: secretEnvs}
+	yamlBytes, err := yaml.Marshal(spec)
+	if err != nil {
+		return powerShellStep, fmt.Errorf("marshal spec %+v failed", spec)
+	}
+	if err := yaml.Unmarshal(yamlBytes, &powerShellStep.spec); err != nil {
+		return powerShellStep, fmt.Errorf("unmarshal spec %s to powershell spec failed", yamlBytes)
+	}
+	return powerShellStep, nil
+}
+
+func (s *PowerShellStep) Run(ctx context.Context) error {
+	start := time.Now()
+	log.Infof("Executing user powershell script.")
+	defer func() {
+		log.Infof("Script Execution ended. Duration: %.2f seconds.", time.Since(start).Seconds())
+	}()
+
+	if len(s.spec.Scripts) == 0 {
+		return nil
+	}
+	// stop at the first failed cmdlet and return the exit code of the last native command
+	scripts := []string{"$ErrorActionPreference = 'Stop'"}
+	scripts = append(scripts, s.spec.Scripts...)
+	scripts = append(scripts, "exit $LASTEXITCODE")
+
+	userScriptFile := filepath.Join(os.TempDir(), "user_script.ps1")
+	if err := os.WriteFile(userScriptFile, []byte(strings.Join(scripts, "\r\n")), 0700); err != nil {
+		return fmt.Errorf("write script file error: %v", err)
+	}
+
+	cmd := exec.Command("powershell.exe", "-NoLogo", "-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-File", userScriptFile)
+	cmd.Dir = s.workspace
+	cmd.Env = s.envs
+
+	fileName := filepath.Join(os.TempDir(), "user_script.log")
+	util.WriteFile(fileName, []byte{}, 0700)
+
+	var wg sync.WaitGroup
+	cmdStdoutReader, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		handleCmdOutput(cmdStdoutReader, true, fileName, s.secretEnvs)
+	}()
+
+	cmdStdErrReader, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		handleCmdOutput(cmdStdErrReader, true, fileName, s.secretEnvs)
+	}()
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	wg.Wait()
+	return cmd.Wait()
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/types/step"
+)
+
+const (
+	defaultServiceReadyTimeout = 120
+	serviceReadyInterval       = 2 * time.Second
+)
+
+type ServiceReadyStep struct {
+	spec *step.StepServiceReadySpec
+}
+
+func NewServiceReadyStep(spec interface{}) (*ServiceReadyStep, error) {
+	serviceReadyStep := &ServiceReadyStep{}
+	yamlBytes, err := yaml.Marshal(spec)
+	if err != nil {
+		return serviceReadyStep, fmt.Errorf("marshal spec %+v failed", spec)
+	}
+	if err := yaml.Unmarshal(yamlBytes, &serviceReadyStep.spec); err != nil {
+		return serviceReadyStep, fmt.Errorf("unmarshal spec %s to service ready spec failed", yamlBytes)
+	}
+	return serviceReadyStep, nil
+}
+
+func (s *ServiceReadyStep) Run(ctx context.Context) error {
+	for _, service := range s.spec.Services {
+		if service.Port <= 0 {
+			continue
+		}
+		log.Infof("Waiting for service %s to be ready.", service.Name)
+		start := time.Now()
+		if err := waitServiceReady(ctx, service); err != nil {
+			return err
+		}
+		log.Infof("Service %s is ready. Duration: %.2f seconds.", service.Name, time.Since(start).Seconds())
+	}
+	return nil
+}
+
+func waitServiceReady(ctx context.Context, service *step.ServiceReadyCheck) error {
+	timeout := service.Timeout
+	if timeout <= 0 {
+		timeout = defaultServiceReadyTimeout
+	}
+	deadline := time.After(time.Duration(timeout) * time.Second)
+	address := fmt.Sprintf("127.0.0.1:%d", service.Port)
+	for {
+		if serviceReady(address, service.Path) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline:
+			return fmt.Errorf("service %s is not ready after %d seconds", service.Name, timeout)
+		case <-time.After(serviceReadyInterval):
+		}
+	}
+}
+
+func serviceReady(address, path string) bool {
+	if path == "" {
+		conn, err := net.DialTimeout("tcp", address, time.Second)
+		if err != nil {
+			return false
+		}
+		_ = conn.Close()
+		return true
+	}
+
+	client := &http.Client{Timeout: time.Second}
+	resp, err := client.Get(fmt.Sprintf("http://%s/%s", address, strings.TrimPrefix(path, "/")))
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
+}
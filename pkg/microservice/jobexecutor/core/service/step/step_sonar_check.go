@@ -18,15 +18,20 @@ package step
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"path"
 	"path/filepath"
 	"time"
 
 	"gopkg.in/yaml.v2"
 
+	"github.com/koderover/zadig/pkg/setting"
 	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/tool/s3"
 	"github.com/koderover/zadig/pkg/tool/sonar"
 	"github.com/koderover/zadig/pkg/types/step"
 )
@@ -72,7 +77,11 @@ func (s *SonarCheckStep) Run(ctx context.Context) error {
 		log.Error("can not get sonar ce task ID")
 		return errors.New("can not get sonar ce task ID")
 	}
-	analysisID, err := client.WaitForCETaskTobeDone(ceTaskID, time.Minute*10)
+	waitTimeout := time.Minute * 10
+	if s.spec.WaitTimeout > 0 {
+		waitTimeout = time.Minute * time.Duration(s.spec.WaitTimeout)
+	}
+	analysisID, err := client.WaitForCETaskTobeDone(ceTaskID, waitTimeout)
 	if err != nil {
 		log.Error(err)
 		return err
@@ -84,8 +93,59 @@ func (s *SonarCheckStep) Run(ctx context.Context) error {
 	}
 	log.Infof("Sonar quality gate status: %s", gateInfo.ProjectStatus.Status)
 	sonar.PrintSonarConditionTables(gateInfo.ProjectStatus.Conditions)
+
+	if err := s.archiveResult(client, gateInfo); err != nil {
+		log.Warnf("failed to archive sonar quality gate result: %s", err)
+	}
+	if s.spec.IgnoreGateStatus {
+		return nil
+	}
 	if gateInfo.ProjectStatus.Status != sonar.QualityGateOK && gateInfo.ProjectStatus.Status != sonar.QualityGateNone {
 		return fmt.Errorf("sonar quality gate status was: %s", gateInfo.ProjectStatus.Status)
 	}
 	return nil
 }
+
+// archiveResult uploads the quality gate result together with the top open issues for the pr decoration
+func (s *SonarCheckStep) archiveResult(client *sonar.Client, gateInfo *sonar.ProjectInfo) error {
+	if s.spec.S3Storage == nil || s.spec.S3DestDir == "" {
+		return nil
+	}
+	projectKey := sonar.GetSonarProjectKeyFromConfig(s.spec.Parameter)
+	result := &sonar.QualityGateResult{
+		ProjectKey: projectKey,
+		Status:     gateInfo.ProjectStatus.Status,
+		Conditions: gateInfo.ProjectStatus.Conditions,
+	}
+	result.Link, _ = sonar.GetSonarAddressWithProjectKey(s.spec.SonarServer, projectKey)
+	if projectKey != "" {
+		issues, err := client.SearchOpenIssues(projectKey, s.spec.Branch, 10)
+		if err != nil {
+			log.Warnf("failed to search sonar issues: %s", err)
+		} else {
+			result.TotalIssues = issues.Total
+			result.Issues = issues.Issues
+		}
+	}
+
+	content, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	localFile := filepath.Join(os.TempDir(), step.SonarResultFile)
+	if err := os.WriteFile(localFile, content, 0644); err != nil {
+		return err
+	}
+	defer os.Remove(localFile)
+
+	forcedPathStyle := true
+	if s.spec.S3Storage.Provider == setting.ProviderSourceAli {
+		forcedPathStyle = false
+	}
+	s3client, err := s3.NewClient(s.spec.S3Storage.Endpoint, s.spec.S3Storage.Ak, s.spec.S3Storage.Sk, s.spec.S3Storage.Region, s.spec.S3Storage.Insecure, forcedPathStyle)
+	if err != nil {
+		return err
+	}
+	key := path.Join(s.spec.S3Storage.Subfolder, s.spec.S3DestDir, step.SonarResultFile)
+	return s3client.Upload(s.spec.S3Storage.Bucket, localFile, key)
+}
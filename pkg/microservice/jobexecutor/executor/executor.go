@@ -18,6 +18,7 @@ package executor
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"time"
@@ -40,7 +41,7 @@ import (
 // 	ZadigLifeCycleFile = ZadigContextDir + "lifecycle"
 // )
 
-func Execute(ctx context.Context) error {
+func initLog() {
 	log.Init(&log.Config{
 		Level:       commonconfig.LogLevel(),
 		NoCaller:    true,
@@ -49,16 +50,12 @@ func Execute(ctx context.Context) error {
 		// SendToFile:  true,
 		// Filename:    ZadigLogFile,
 	})
+}
 
-	start := time.Now()
-
-	excutor := "job-executor"
+func Execute(ctx context.Context) error {
+	initLog()
 
-	var (
-		err error
-		j   *job.Job
-	)
-	j, err = job.NewJob()
+	j, err := job.NewJob()
 	if err != nil {
 		return err
 	}
@@ -80,6 +77,43 @@ func Execute(ctx context.Context) error {
 	}
 
 	j.ConfigMapUpdater = configmap.NewUpdater(j.Ctx.ConfigMapName, string(ns), clientset)
+	err = run(ctx, j)
+	if j.Ctx.HasServiceContainers {
+		stopServiceContainers()
+	}
+	return err
+}
+
+// ExecuteOnVM runs the job on a vm outside kubernetes, the job context configmap is kept in memory and its data,
+// including the job result and the outputs, is written to resultFile in json when the job ends
+func ExecuteOnVM(ctx context.Context, resultFile string) error {
+	initLog()
+
+	j, err := job.NewJob()
+	if err != nil {
+		return err
+	}
+	j.ConfigMapUpdater = configmap.NewMemoryUpdater(j.Ctx.ConfigMapName)
+
+	err = run(ctx, j)
+	cm, getErr := j.ConfigMapUpdater.Get()
+	if getErr != nil {
+		return getErr
+	}
+	data, marshalErr := json.Marshal(cm.Data)
+	if marshalErr != nil {
+		return marshalErr
+	}
+	if writeErr := os.WriteFile(resultFile, data, 0644); writeErr != nil {
+		return writeErr
+	}
+	return err
+}
+
+func run(ctx context.Context, j *job.Job) (err error) {
+	start := time.Now()
+
+	excutor := "job-executor"
 
 	defer func() {
 		resultMsg := types.JobSuccess
@@ -103,6 +137,15 @@ func Execute(ctx context.Context) error {
 		}
 		log.Infof("Job result ConfigMap is updated successfully")
 		fmt.Printf("====================== %s End. Duration: %.2f seconds ======================\n", excutor, time.Since(start).Seconds())
+
+		// keep the pod alive so that the workspace of the failed job can be inspected, zadig deletes the pod when it expires
+		if resultMsg == types.JobFail && j.Ctx.RetainPodTTL > 0 {
+			fmt.Printf("The pod is retained for %d minutes for debugging.\n", j.Ctx.RetainPodTTL)
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Duration(j.Ctx.RetainPodTTL) * time.Minute):
+			}
+		}
 	}()
 
 	fmt.Printf("====================== %s Start ======================\n", excutor)
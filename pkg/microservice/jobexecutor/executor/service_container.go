@@ -0,0 +1,99 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+const serviceContainerStopTimeout = 10 * time.Second
+
+// stopServiceContainers terminates the processes of the service containers so that the job pod completes.
+// The pod shares the process namespace, every process other than the pause process and the ancestors of
+// the job executor belongs to the service containers or is left behind by the job steps.
+func stopServiceContainers() {
+	pids := listOtherProcesses()
+	if len(pids) == 0 {
+		return
+	}
+	log.Infof("Stopping %d processes of the service containers.", len(pids))
+	signalProcesses(pids, syscall.SIGTERM)
+
+	deadline := time.Now().Add(serviceContainerStopTimeout)
+	for time.Now().Before(deadline) {
+		if len(listOtherProcesses()) == 0 {
+			return
+		}
+		time.Sleep(time.Second)
+	}
+	signalProcesses(listOtherProcesses(), syscall.SIGKILL)
+}
+
+func signalProcesses(pids []int, sig syscall.Signal) {
+	for _, pid := range pids {
+		process, err := os.FindProcess(pid)
+		if err != nil {
+			continue
+		}
+		if err := process.Signal(sig); err != nil {
+			log.Warnf("failed to signal process %d, err: %s", pid, err)
+		}
+	}
+}
+
+func listOtherProcesses() []int {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+	own := map[int]bool{1: true}
+	for pid := os.Getpid(); pid > 0 && !own[pid]; pid = parentPid(pid) {
+		own[pid] = true
+	}
+
+	resp := make([]int, 0)
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil || own[pid] {
+			continue
+		}
+		resp = append(resp, pid)
+	}
+	return resp
+}
+
+// parentPid reads the parent pid from /proc/<pid>/stat, zero means unknown
+func parentPid(pid int) int {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return 0
+	}
+	// the command in the second field may contain spaces, the fields after it start behind the last ')'
+	fields := strings.Fields(string(data[strings.LastIndex(string(data), ")")+1:]))
+	if len(fields) < 2 {
+		return 0
+	}
+	ppid, _ := strconv.Atoi(fields[1])
+	return ppid
+}
@@ -0,0 +1,55 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package opa
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+// PutPolicy creates or updates the policy module, the module is compiled by the opa server
+func (c *Client) PutPolicy(ctx context.Context, id, module string) error {
+	url := fmt.Sprintf("v1/policies/%s", id)
+	_, err := c.Put(url, httpclient.SetContext(ctx), httpclient.SetHeader("Content-Type", "text/plain"), httpclient.SetBody(module))
+	return err
+}
+
+// DeletePolicy deletes the policy module, it is ignored if the module does not exist
+func (c *Client) DeletePolicy(ctx context.Context, id string) error {
+	url := fmt.Sprintf("v1/policies/%s", id)
+	_, err := c.Delete(url, httpclient.SetContext(ctx))
+	if httpclient.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// EvaluateData evaluates the document of the path with the input, the result of the undefined document is not set
+func (c *Client) EvaluateData(ctx context.Context, path string, input, result interface{}) error {
+	req := struct {
+		Input interface{} `json:"input"`
+	}{
+		Input: input,
+	}
+
+	url := fmt.Sprintf("v1/data/%s", strings.ReplaceAll(path, ".", "/"))
+	_, err := c.Post(url, httpclient.SetContext(ctx), httpclient.SetBody(req), httpclient.SetResult(result))
+	return err
+}
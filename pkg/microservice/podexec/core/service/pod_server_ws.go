@@ -35,6 +35,7 @@ import (
 	e "github.com/koderover/zadig/pkg/tool/errors"
 	"github.com/koderover/zadig/pkg/tool/kube/getter"
 	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/types"
 )
 
 func ServeWs(c *gin.Context) {
@@ -105,17 +106,60 @@ func ServeWs(c *gin.Context) {
 }
 
 func DebugWorkflow(c *gin.Context) {
-	ctx := internalhandler.NewContext(c)
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
-	logger := ctx.Logger
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	workflowName, jobName := c.Param("workflowName"), c.Param("jobName")
 	taskID, err := strconv.ParseInt(c.Param("taskID"), 10, 64)
 	if err != nil {
 		ctx.Err = e.ErrInvalidParam.AddDesc("无效 task ID")
 		return
 	}
 
-	ctx.Err = debugWorkflow(c, c.Param("workflowName"), c.Param("jobName"), taskID, logger)
-	return
+	project, err := authorizeWorkflowDebug(ctx, workflowName)
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+	if ctx.UnAuthorized {
+		return
+	}
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, project, "调试", "自定义工作流任务-调试", fmt.Sprintf("%s#%d/%s", workflowName, taskID, jobName), "", ctx.Logger)
+
+	ctx.Err = debugWorkflow(c, workflowName, jobName, taskID, ctx.Logger)
+}
+
+// authorizeWorkflowDebug checks whether the user can debug the jobs of the workflow, it sets ctx.UnAuthorized if not
+// and returns the project of the workflow
+func authorizeWorkflowDebug(ctx *internalhandler.Context, workflowName string) (string, error) {
+	w, err := commonrepo.NewWorkflowV4Coll().Find(workflowName)
+	if err != nil {
+		return "", e.ErrInvalidParam.AddDesc(fmt.Sprintf("workflow %s not found", workflowName))
+	}
+
+	if ctx.Resources.IsSystemAdmin {
+		return w.Project, nil
+	}
+	if _, ok := ctx.Resources.ProjectAuthInfo[w.Project]; !ok {
+		ctx.UnAuthorized = true
+		return w.Project, nil
+	}
+	if !ctx.Resources.ProjectAuthInfo[w.Project].IsProjectAdmin &&
+		!ctx.Resources.ProjectAuthInfo[w.Project].Workflow.Debug {
+		// check if the permission is given by collaboration mode
+		permitted, err := internalhandler.GetCollaborationModePermission(ctx.UserID, w.Project, types.ResourceTypeWorkflow, workflowName, types.WorkflowActionDebug)
+		if err != nil || !permitted {
+			ctx.UnAuthorized = true
+		}
+	}
+	return w.Project, nil
 }
 
 func debugWorkflow(c *gin.Context, workflowName, jobName string, taskID int64, logger *zap.SugaredLogger) error {
@@ -220,3 +264,71 @@ FOR:
 	}
 	return nil
 }
+
+// terminalJobTypes are the job types running the user scripts in a job pod which can be debugged in a web terminal
+var terminalJobTypes = map[string]bool{
+	string(config.JobZadigBuild):           true,
+	string(config.JobZadigTesting):         true,
+	string(config.JobZadigScanning):        true,
+	string(config.JobZadigPerformanceTest): true,
+	string(config.JobFreestyle):            true,
+}
+
+// ExecWorkflowJob opens a web terminal into the container of a running build or test job, the user must have
+// the permission to debug the workflow and every session is recorded in the operation logs.
+func ExecWorkflowJob(c *gin.Context) {
+	ctx, err := internalhandler.NewContextWithAuthorization(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if err != nil {
+		ctx.Err = fmt.Errorf("authorization Info Generation failed: err %s", err)
+		ctx.UnAuthorized = true
+		return
+	}
+
+	workflowName, jobName := c.Param("workflowName"), c.Param("jobName")
+	taskID, err := strconv.ParseInt(c.Param("taskID"), 10, 64)
+	if err != nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc("invalid task id")
+		return
+	}
+
+	project, err := authorizeWorkflowDebug(ctx, workflowName)
+	if err != nil {
+		ctx.Err = err
+		return
+	}
+	if ctx.UnAuthorized {
+		return
+	}
+
+	runningTask := workflowcontroller.GetWorkflowTaskInMap(workflowName, taskID)
+	if runningTask == nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc(fmt.Sprintf("task %s #%d is not running", workflowName, taskID))
+		return
+	}
+	var job *commonmodels.JobTask
+	for _, stage := range runningTask.WorkflowTask.Stages {
+		for _, jobTask := range stage.Jobs {
+			if jobTask.Name == jobName {
+				job = jobTask
+			}
+		}
+	}
+	if job == nil {
+		ctx.Err = e.ErrInvalidParam.AddDesc(fmt.Sprintf("job %s not found in task %s #%d", jobName, workflowName, taskID))
+		return
+	}
+	if !terminalJobTypes[job.JobType] {
+		ctx.Err = e.ErrInvalidParam.AddDesc(fmt.Sprintf("web terminal is not supported by %s job", job.JobType))
+		return
+	}
+	if job.Status != config.StatusRunning {
+		ctx.Err = e.ErrInvalidParam.AddDesc(fmt.Sprintf("job %s is %s, only the running job can be debugged", jobName, job.Status))
+		return
+	}
+
+	internalhandler.InsertOperationLog(c, ctx.UserName, project, "调试", "自定义工作流任务-终端", fmt.Sprintf("%s#%d/%s", workflowName, taskID, jobName), "", ctx.Logger)
+
+	ctx.Err = debugWorkflow(c, workflowName, jobName, taskID, ctx.Logger)
+}
@@ -102,6 +102,43 @@ func DeleteProjectRoles(c *gin.Context) {
 	ctx.Err = permission.DeleteAllRolesInNamespace(namespace, ctx.Logger)
 }
 
+// RenameProjectRoles moves the roles and the role bindings of the project when the project key is renamed
+func RenameProjectRoles(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	namespace := c.Query("namespace")
+	newNamespace := c.Query("newNamespace")
+	if namespace == "" || newNamespace == "" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("args namespace and newNamespace can't be empty")
+		return
+	}
+	if namespace == "*" || newNamespace == "*" {
+		ctx.Err = e.ErrInvalidParam.AddDesc("args namespace can't be *")
+		return
+	}
+
+	err := userhandler.GenerateUserAuthInfo(ctx)
+	if err != nil {
+		ctx.UnAuthorized = true
+		ctx.Err = fmt.Errorf("failed to generate user authorization info, error: %s", err)
+		return
+	}
+
+	// authorization checks
+	if !ctx.Resources.IsSystemAdmin {
+		if authInfo, ok := ctx.Resources.ProjectAuthInfo[namespace]; !ok {
+			ctx.UnAuthorized = true
+			return
+		} else if !authInfo.IsProjectAdmin {
+			ctx.UnAuthorized = true
+			return
+		}
+	}
+
+	ctx.Err = permission.RenameRoleNamespace(namespace, newNamespace, ctx.Logger)
+}
+
 func SetProjectVisibility(c *gin.Context) {
 	ctx := internalhandler.NewContext(c)
 	defer func() { internalhandler.JSONResponse(c, ctx) }()
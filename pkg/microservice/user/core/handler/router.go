@@ -55,6 +55,15 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		usergroups.POST("/:id/bulk-delete-users", user.BulkRemoveUserFromUserGroup)
 	}
 
+	ldapGroupMappings := router.Group("ldap-group-mappings")
+	{
+		ldapGroupMappings.GET("", user.ListLDAPGroupRoleMappings)
+		ldapGroupMappings.POST("", user.CreateLDAPGroupRoleMapping)
+		ldapGroupMappings.PUT("/:id", user.UpdateLDAPGroupRoleMapping)
+		ldapGroupMappings.DELETE("/:id", user.DeleteLDAPGroupRoleMapping)
+		ldapGroupMappings.POST("/sync", user.SyncLDAPGroupRoleMappings)
+	}
+
 	// =======================================================
 	// User Authorization APIs, internal use ONLY
 	// =======================================================
@@ -120,6 +129,7 @@ func (*Router) Inject(router *gin.RouterGroup) {
 		{
 			internalPolicyApis.POST("initializeProject", permission.InitializeProject)
 			internalPolicyApis.POST("deleteProjectRole", permission.DeleteProjectRoles)
+			internalPolicyApis.POST("renameProjectRole", permission.RenameProjectRoles)
 			internalPolicyApis.POST("setProjectVisibility", permission.SetProjectVisibility)
 		}
 	}
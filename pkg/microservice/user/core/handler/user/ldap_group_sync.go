@@ -0,0 +1,110 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package user
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/koderover/zadig/pkg/microservice/user/core/service/user"
+	internalhandler "github.com/koderover/zadig/pkg/shared/handler"
+	e "github.com/koderover/zadig/pkg/tool/errors"
+)
+
+// authorizeSystemAdmin fills the authorization info of the context and returns false if the user is not a system admin
+func authorizeSystemAdmin(ctx *internalhandler.Context) bool {
+	err := GenerateUserAuthInfo(ctx)
+	if err != nil {
+		ctx.UnAuthorized = true
+		ctx.Err = fmt.Errorf("failed to generate user authorization info, error: %s", err)
+		return false
+	}
+
+	if !ctx.Resources.IsSystemAdmin {
+		ctx.UnAuthorized = true
+		return false
+	}
+	return true
+}
+
+func ListLDAPGroupRoleMappings(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if !authorizeSystemAdmin(ctx) {
+		return
+	}
+
+	ctx.Resp, ctx.Err = user.ListLDAPGroupRoleMappings(c.Query("connectorId"), ctx.Logger)
+}
+
+func CreateLDAPGroupRoleMapping(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if !authorizeSystemAdmin(ctx) {
+		return
+	}
+
+	args := new(user.LDAPGroupRoleMappingArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	ctx.Err = user.CreateLDAPGroupRoleMapping(ctx.UserName, args, ctx.Logger)
+}
+
+func UpdateLDAPGroupRoleMapping(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if !authorizeSystemAdmin(ctx) {
+		return
+	}
+
+	args := new(user.LDAPGroupRoleMappingArgs)
+	if err := c.ShouldBindJSON(args); err != nil {
+		ctx.Err = e.ErrInvalidParam.AddErr(err)
+		return
+	}
+
+	ctx.Err = user.UpdateLDAPGroupRoleMapping(c.Param("id"), ctx.UserName, args, ctx.Logger)
+}
+
+func DeleteLDAPGroupRoleMapping(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if !authorizeSystemAdmin(ctx) {
+		return
+	}
+
+	ctx.Err = user.DeleteLDAPGroupRoleMapping(c.Param("id"), ctx.Logger)
+}
+
+func SyncLDAPGroupRoleMappings(c *gin.Context) {
+	ctx := internalhandler.NewContext(c)
+	defer func() { internalhandler.JSONResponse(c, ctx) }()
+
+	if !authorizeSystemAdmin(ctx) {
+		return
+	}
+
+	ctx.Err = user.SyncLDAPGroupRoleMappings(ctx.Logger)
+}
@@ -0,0 +1,40 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// LDAPGroupRoleMapping binds the members of a group in the LDAP connector to a role of a project
+type LDAPGroupRoleMapping struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"   json:"id,omitempty"`
+	ConnectorID string             `bson:"connector_id"    json:"connector_id"`
+	GroupName   string             `bson:"group_name"      json:"group_name"`
+	Namespace   string             `bson:"namespace"       json:"namespace"`
+	RoleName    string             `bson:"role_name"       json:"role_name"`
+	// SyncedUIDs are the users whose role bindings are created by the sync, only these bindings are
+	// removed when the users leave the group so that the bindings created manually are kept
+	SyncedUIDs      []string `bson:"synced_uids"       json:"synced_uids"`
+	LastSyncTime    int64    `bson:"last_sync_time"    json:"last_sync_time"`
+	LastSyncStatus  string   `bson:"last_sync_status"  json:"last_sync_status"`
+	LastSyncMessage string   `bson:"last_sync_message" json:"last_sync_message"`
+	UpdateBy        string   `bson:"update_by"         json:"update_by"`
+	UpdateTime      int64    `bson:"update_time"       json:"update_time"`
+}
+
+func (LDAPGroupRoleMapping) TableName() string {
+	return "ldap_group_role_mapping"
+}
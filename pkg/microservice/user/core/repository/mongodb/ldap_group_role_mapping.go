@@ -0,0 +1,135 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongodb
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/koderover/zadig/pkg/microservice/aslan/config"
+	"github.com/koderover/zadig/pkg/microservice/user/core/repository/models"
+	mongotool "github.com/koderover/zadig/pkg/tool/mongo"
+)
+
+type LDAPGroupRoleMappingColl struct {
+	*mongo.Collection
+
+	coll string
+}
+
+func NewLDAPGroupRoleMappingColl() *LDAPGroupRoleMappingColl {
+	name := models.LDAPGroupRoleMapping{}.TableName()
+	return &LDAPGroupRoleMappingColl{Collection: mongotool.Database(config.MongoDatabase()).Collection(name), coll: name}
+}
+
+func (c *LDAPGroupRoleMappingColl) GetCollectionName() string {
+	return c.coll
+}
+
+func (c *LDAPGroupRoleMappingColl) EnsureIndex(ctx context.Context) error {
+	mod := mongo.IndexModel{
+		Keys: bson.D{
+			bson.E{Key: "connector_id", Value: 1},
+			bson.E{Key: "group_name", Value: 1},
+			bson.E{Key: "namespace", Value: 1},
+			bson.E{Key: "role_name", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := c.Indexes().CreateOne(ctx, mod)
+
+	return err
+}
+
+func (c *LDAPGroupRoleMappingColl) Create(args *models.LDAPGroupRoleMapping) error {
+	args.UpdateTime = time.Now().Unix()
+	_, err := c.InsertOne(context.TODO(), args)
+	return err
+}
+
+func (c *LDAPGroupRoleMappingColl) Get(id string) (*models.LDAPGroupRoleMapping, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := new(models.LDAPGroupRoleMapping)
+	err = c.FindOne(context.TODO(), bson.M{"_id": oid}).Decode(resp)
+	return resp, err
+}
+
+// List lists the mappings of the connector, all the mappings are listed if the connector id is empty
+func (c *LDAPGroupRoleMappingColl) List(connectorID string) ([]*models.LDAPGroupRoleMapping, error) {
+	query := bson.M{}
+	if connectorID != "" {
+		query["connector_id"] = connectorID
+	}
+
+	resp := make([]*models.LDAPGroupRoleMapping, 0)
+	cursor, err := c.Find(context.TODO(), query, options.Find().SetSort(bson.D{{"connector_id", 1}, {"group_name", 1}}))
+	if err != nil {
+		return nil, err
+	}
+	err = cursor.All(context.TODO(), &resp)
+	return resp, err
+}
+
+// Update updates the mapping rule, the sync result is kept
+func (c *LDAPGroupRoleMappingColl) Update(id string, args *models.LDAPGroupRoleMapping) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	change := bson.M{"$set": bson.M{
+		"connector_id": args.ConnectorID,
+		"group_name":   args.GroupName,
+		"namespace":    args.Namespace,
+		"role_name":    args.RoleName,
+		"update_by":    args.UpdateBy,
+		"update_time":  time.Now().Unix(),
+	}}
+	_, err = c.UpdateOne(context.TODO(), bson.M{"_id": oid}, change)
+	return err
+}
+
+func (c *LDAPGroupRoleMappingColl) UpdateSyncResult(id primitive.ObjectID, syncedUIDs []string, status, message string) error {
+	change := bson.M{"$set": bson.M{
+		"synced_uids":       syncedUIDs,
+		"last_sync_time":    time.Now().Unix(),
+		"last_sync_status":  status,
+		"last_sync_message": message,
+	}}
+	_, err := c.UpdateOne(context.TODO(), bson.M{"_id": id}, change)
+	return err
+}
+
+func (c *LDAPGroupRoleMappingColl) Delete(id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.DeleteOne(context.TODO(), bson.M{"_id": oid})
+	return err
+}
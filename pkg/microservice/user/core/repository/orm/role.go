@@ -214,6 +214,14 @@ func DeleteRoleByName(name, namespace string, db *gorm.DB) error {
 		Error
 }
 
+// UpdateRoleNamespace moves the roles to the new namespace, the user and group bindings of the roles move with them
+func UpdateRoleNamespace(oldNamespace, newNamespace string, db *gorm.DB) error {
+	return db.Model(&models.NewRole{}).
+		Where("namespace = ?", oldNamespace).
+		Update("namespace", newNamespace).
+		Error
+}
+
 func DeleteRoleByNameSpace(namespace string, db *gorm.DB) error {
 	var role models.NewRole
 
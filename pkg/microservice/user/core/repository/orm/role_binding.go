@@ -78,6 +78,11 @@ func GetRoleBinding(roleID uint, uid string, db *gorm.DB) (*models.NewRoleBindin
 	return resp, nil
 }
 
+// DeleteRoleBinding deletes the binding between the user and the role
+func DeleteRoleBinding(roleID uint, uid string, db *gorm.DB) error {
+	return db.Where("role_id = ? AND uid = ?", roleID, uid).Delete(&models.NewRoleBinding{}).Error
+}
+
 func ListRoleBindingByNamespace(namespace string, db *gorm.DB) ([]*models.NewRoleBinding, error) {
 	resp := make([]*models.NewRoleBinding, 0)
 
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"time"
 
+	newgoCron "github.com/go-co-op/gocron"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/google/uuid"
 	configbase "github.com/koderover/zadig/pkg/config"
@@ -32,6 +33,7 @@ import (
 	"github.com/koderover/zadig/pkg/microservice/user/core/repository/mongodb"
 	"github.com/koderover/zadig/pkg/microservice/user/core/repository/orm"
 	permissionservice "github.com/koderover/zadig/pkg/microservice/user/core/service/permission"
+	userservice "github.com/koderover/zadig/pkg/microservice/user/core/service/user"
 	"github.com/koderover/zadig/pkg/setting"
 	gormtool "github.com/koderover/zadig/pkg/tool/gorm"
 	"github.com/koderover/zadig/pkg/tool/log"
@@ -50,6 +52,17 @@ func Start(_ context.Context) {
 
 	permissionservice.GenerateOPABundle()
 	initDatabase()
+	initCron()
+}
+
+var Scheduler *newgoCron.Scheduler
+
+func initCron() {
+	Scheduler = newgoCron.NewScheduler(time.Local)
+
+	Scheduler.Every(1).Hours().Do(userservice.RunLDAPGroupRoleSync)
+
+	Scheduler.StartAsync()
 }
 
 func initDatabase() {
@@ -99,6 +112,7 @@ func initDatabase() {
 }
 
 func Stop(_ context.Context) {
+	Scheduler.Stop()
 	gormtool.Close()
 }
 
@@ -274,6 +274,34 @@ func DeleteAllRolesInNamespace(namespace string, log *zap.SugaredLogger) error {
 	return nil
 }
 
+// RenameRoleNamespace moves the roles and their bindings of the project to the new project key
+func RenameRoleNamespace(oldNamespace, newNamespace string, log *zap.SugaredLogger) error {
+	tx := repository.DB.Begin()
+
+	roles, err := orm.ListRoleByNamespace(newNamespace, tx)
+	if err != nil {
+		tx.Rollback()
+		log.Errorf("failed to list roles under namespace %s, error: %s", newNamespace, err)
+		return fmt.Errorf("failed to list roles under namespace %s, error: %s", newNamespace, err)
+	}
+	if len(roles) > 0 {
+		tx.Rollback()
+		return fmt.Errorf("namespace %s already has roles", newNamespace)
+	}
+
+	if err := orm.UpdateRoleNamespace(oldNamespace, newNamespace, tx); err != nil {
+		tx.Rollback()
+		log.Errorf("failed to move roles from namespace %s to %s, error: %s", oldNamespace, newNamespace, err)
+		return fmt.Errorf("failed to move roles from namespace %s to %s, error: %s", oldNamespace, newNamespace, err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		log.Errorf("failed to commit the role namespace change, error: %s", err)
+		return fmt.Errorf("failed to commit the role namespace change, error: %s", err)
+	}
+	return nil
+}
+
 func convertDBRoleType(tid int64) string {
 	if tid == int64(setting.RoleTypeSystem) {
 		return string(setting.ResourceTypeSystem)
@@ -0,0 +1,429 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package user
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/dexidp/dex/connector/ldap"
+	ldapv3 "github.com/go-ldap/ldap/v3"
+	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	commonmodels "github.com/koderover/zadig/pkg/microservice/aslan/core/common/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/user/core/repository"
+	"github.com/koderover/zadig/pkg/microservice/user/core/repository/models"
+	"github.com/koderover/zadig/pkg/microservice/user/core/repository/mongodb"
+	"github.com/koderover/zadig/pkg/microservice/user/core/repository/orm"
+	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+const (
+	LDAPGroupSyncStatusSuccess = "success"
+	LDAPGroupSyncStatusFailed  = "failed"
+
+	ldapConnectorType = "ldap"
+)
+
+// ldapGroupSyncLock makes sure that only one sync is running in the instance
+var ldapGroupSyncLock sync.Mutex
+
+type LDAPGroupRoleMappingArgs struct {
+	ConnectorID string `json:"connector_id"`
+	GroupName   string `json:"group_name"`
+	Namespace   string `json:"namespace"`
+	RoleName    string `json:"role_name"`
+}
+
+// ldapGroupMemberConfig is the part of the ldap connector config telling how the group members match the users,
+// the deprecated userAttr and groupAttr are used if no user matcher is configured
+type ldapGroupMemberConfig struct {
+	GroupSearch struct {
+		UserAttr     string             `json:"userAttr"`
+		GroupAttr    string             `json:"groupAttr"`
+		UserMatchers []*ldapUserMatcher `json:"userMatchers"`
+	} `json:"groupSearch"`
+}
+
+type ldapUserMatcher struct {
+	UserAttr  string `json:"userAttr"`
+	GroupAttr string `json:"groupAttr"`
+}
+
+func ListLDAPGroupRoleMappings(connectorID string, logger *zap.SugaredLogger) ([]*models.LDAPGroupRoleMapping, error) {
+	mappings, err := mongodb.NewLDAPGroupRoleMappingColl().List(connectorID)
+	if err != nil {
+		logger.Errorf("failed to list ldap group role mappings, error: %s", err)
+		return nil, fmt.Errorf("failed to list ldap group role mappings, error: %s", err)
+	}
+	return mappings, nil
+}
+
+func CreateLDAPGroupRoleMapping(username string, args *LDAPGroupRoleMappingArgs, logger *zap.SugaredLogger) error {
+	if err := validateLDAPGroupRoleMapping(args); err != nil {
+		return err
+	}
+
+	err := mongodb.NewLDAPGroupRoleMappingColl().Create(&models.LDAPGroupRoleMapping{
+		ConnectorID: args.ConnectorID,
+		GroupName:   args.GroupName,
+		Namespace:   args.Namespace,
+		RoleName:    args.RoleName,
+		SyncedUIDs:  make([]string, 0),
+		UpdateBy:    username,
+	})
+	if err != nil {
+		logger.Errorf("failed to create ldap group role mapping, error: %s", err)
+		return fmt.Errorf("failed to create ldap group role mapping, error: %s", err)
+	}
+	return nil
+}
+
+// UpdateLDAPGroupRoleMapping updates the mapping, the role bindings created by the previous rule are removed
+// if the role is changed so that the next sync creates the bindings of the new role
+func UpdateLDAPGroupRoleMapping(id, username string, args *LDAPGroupRoleMappingArgs, logger *zap.SugaredLogger) error {
+	if err := validateLDAPGroupRoleMapping(args); err != nil {
+		return err
+	}
+
+	coll := mongodb.NewLDAPGroupRoleMappingColl()
+	mapping, err := coll.Get(id)
+	if err != nil {
+		return fmt.Errorf("failed to find ldap group role mapping %s, error: %s", id, err)
+	}
+
+	if mapping.Namespace != args.Namespace || mapping.RoleName != args.RoleName {
+		if err := removeSyncedRoleBindings(mapping); err != nil {
+			logger.Errorf("failed to remove the role bindings of ldap group %s, error: %s", mapping.GroupName, err)
+			return err
+		}
+		if err := coll.UpdateSyncResult(mapping.ID, []string{}, mapping.LastSyncStatus, mapping.LastSyncMessage); err != nil {
+			return fmt.Errorf("failed to reset the sync result of ldap group role mapping %s, error: %s", id, err)
+		}
+	}
+
+	err = coll.Update(id, &models.LDAPGroupRoleMapping{
+		ConnectorID: args.ConnectorID,
+		GroupName:   args.GroupName,
+		Namespace:   args.Namespace,
+		RoleName:    args.RoleName,
+		UpdateBy:    username,
+	})
+	if err != nil {
+		logger.Errorf("failed to update ldap group role mapping %s, error: %s", id, err)
+		return fmt.Errorf("failed to update ldap group role mapping %s, error: %s", id, err)
+	}
+	return nil
+}
+
+// DeleteLDAPGroupRoleMapping deletes the mapping along with the role bindings created by it
+func DeleteLDAPGroupRoleMapping(id string, logger *zap.SugaredLogger) error {
+	coll := mongodb.NewLDAPGroupRoleMappingColl()
+	mapping, err := coll.Get(id)
+	if err != nil {
+		return fmt.Errorf("failed to find ldap group role mapping %s, error: %s", id, err)
+	}
+
+	if err := removeSyncedRoleBindings(mapping); err != nil {
+		logger.Errorf("failed to remove the role bindings of ldap group %s, error: %s", mapping.GroupName, err)
+		return err
+	}
+	return coll.Delete(id)
+}
+
+func validateLDAPGroupRoleMapping(args *LDAPGroupRoleMappingArgs) error {
+	if args.ConnectorID == "" || args.GroupName == "" || args.Namespace == "" || args.RoleName == "" {
+		return fmt.Errorf("connector_id, group_name, namespace and role_name are required")
+	}
+
+	connector, err := systemconfig.New().GetLDAPConnector(args.ConnectorID)
+	if err != nil {
+		return fmt.Errorf("failed to find connector %s, error: %s", args.ConnectorID, err)
+	}
+	if connector.Type != ldapConnectorType {
+		return fmt.Errorf("connector %s is not a ldap connector", args.ConnectorID)
+	}
+
+	role, err := orm.GetRole(args.RoleName, args.Namespace, repository.DB)
+	if err != nil || role.ID == 0 {
+		return fmt.Errorf("failed to find role %s in namespace %s, error: %v", args.RoleName, args.Namespace, err)
+	}
+	return nil
+}
+
+// SyncLDAPGroupRoleMappings syncs the members of the ldap groups into the role bindings of all the mappings
+func SyncLDAPGroupRoleMappings(logger *zap.SugaredLogger) error {
+	ldapGroupSyncLock.Lock()
+	defer ldapGroupSyncLock.Unlock()
+
+	mappings, err := mongodb.NewLDAPGroupRoleMappingColl().List("")
+	if err != nil {
+		logger.Errorf("failed to list ldap group role mappings, error: %s", err)
+		return fmt.Errorf("failed to list ldap group role mappings, error: %s", err)
+	}
+
+	connectorMappings := make(map[string][]*models.LDAPGroupRoleMapping)
+	for _, mapping := range mappings {
+		connectorMappings[mapping.ConnectorID] = append(connectorMappings[mapping.ConnectorID], mapping)
+	}
+
+	failed := make([]string, 0)
+	for connectorID, mappings := range connectorMappings {
+		if err := syncLDAPConnectorGroups(connectorID, mappings, logger); err != nil {
+			logger.Errorf("failed to sync the groups of ldap connector %s, error: %s", connectorID, err)
+			failed = append(failed, connectorID)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to sync the groups of ldap connectors: %s", strings.Join(failed, ","))
+	}
+	return nil
+}
+
+// RunLDAPGroupRoleSync is the cron job of the ldap group sync
+func RunLDAPGroupRoleSync() {
+	if err := SyncLDAPGroupRoleMappings(log.SugaredLogger()); err != nil {
+		log.Errorf("[CRONJOB] failed to sync ldap group role mappings, error: %s", err)
+	}
+}
+
+func syncLDAPConnectorGroups(connectorID string, mappings []*models.LDAPGroupRoleMapping, logger *zap.SugaredLogger) error {
+	config, memberConfig, err := getLDAPConnectorConfig(connectorID)
+	if err == nil {
+		err = func() error {
+			l, err := ldapv3.Dial("tcp", config.Host)
+			if err != nil {
+				return fmt.Errorf("ldap dial host:%s error, error msg:%s", config.Host, err)
+			}
+			defer l.Close()
+
+			if err := l.Bind(config.BindDN, config.BindPW); err != nil {
+				return fmt.Errorf("ldap bind host:%s error, error msg:%s", config.Host, err)
+			}
+
+			for _, mapping := range mappings {
+				members, err := searchLDAPGroupMembers(l, config, memberConfig, mapping.GroupName)
+				if err == nil {
+					err = syncLDAPGroupRoleBindings(connectorID, mapping, members, logger)
+				}
+				updateLDAPGroupSyncResult(mapping, err, logger)
+			}
+			return nil
+		}()
+	}
+
+	// the mappings can't be synced if the ldap server is unavailable
+	if err != nil {
+		for _, mapping := range mappings {
+			updateLDAPGroupSyncResult(mapping, err, logger)
+		}
+	}
+	return err
+}
+
+func updateLDAPGroupSyncResult(mapping *models.LDAPGroupRoleMapping, syncErr error, logger *zap.SugaredLogger) {
+	status, message := LDAPGroupSyncStatusSuccess, ""
+	if syncErr != nil {
+		status, message = LDAPGroupSyncStatusFailed, syncErr.Error()
+		logger.Errorf("failed to sync ldap group %s to role %s of %s, error: %s", mapping.GroupName, mapping.RoleName, mapping.Namespace, syncErr)
+	}
+	if err := mongodb.NewLDAPGroupRoleMappingColl().UpdateSyncResult(mapping.ID, mapping.SyncedUIDs, status, message); err != nil {
+		logger.Errorf("failed to update the sync result of ldap group role mapping %s, error: %s", mapping.ID.Hex(), err)
+	}
+}
+
+func getLDAPConnectorConfig(connectorID string) (*ldap.Config, *ldapGroupMemberConfig, error) {
+	connector, err := systemconfig.New().GetLDAPConnector(connectorID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find connector %s, error: %s", connectorID, err)
+	}
+	if connector == nil || connector.Config == nil || connector.Type != ldapConnectorType {
+		return nil, nil, fmt.Errorf("ldap connector %s not found", connectorID)
+	}
+
+	config := new(ldap.Config)
+	if err := commonmodels.IToi(connector.Config, config); err != nil {
+		return nil, nil, err
+	}
+	memberConfig := new(ldapGroupMemberConfig)
+	if err := commonmodels.IToi(connector.Config, memberConfig); err != nil {
+		return nil, nil, err
+	}
+	return config, memberConfig, nil
+}
+
+// searchLDAPGroupMembers returns the members of the group, the users are resolved by the user matchers of the connector
+func searchLDAPGroupMembers(l *ldapv3.Conn, config *ldap.Config, memberConfig *ldapGroupMemberConfig, groupName string) ([]*SyncUserInfo, error) {
+	matchers := memberConfig.GroupSearch.UserMatchers
+	if len(matchers) == 0 && memberConfig.GroupSearch.GroupAttr != "" {
+		matchers = []*ldapUserMatcher{{UserAttr: memberConfig.GroupSearch.UserAttr, GroupAttr: memberConfig.GroupSearch.GroupAttr}}
+	}
+	if len(matchers) == 0 {
+		return nil, fmt.Errorf("no group member attribute is configured in the ldap connector")
+	}
+
+	groupAttrs := make([]string, 0, len(matchers))
+	for _, matcher := range matchers {
+		groupAttrs = append(groupAttrs, matcher.GroupAttr)
+	}
+	groupFilter := fmt.Sprintf("(%s=%s)", config.GroupSearch.NameAttr, ldapv3.EscapeFilter(groupName))
+	if config.GroupSearch.Filter != "" {
+		groupFilter = fmt.Sprintf("(&%s%s)", config.GroupSearch.Filter, groupFilter)
+	}
+	sr, err := l.Search(ldapv3.NewSearchRequest(
+		config.GroupSearch.BaseDN,
+		ldapv3.ScopeWholeSubtree, ldapv3.NeverDerefAliases, 0, 0, false,
+		groupFilter,
+		groupAttrs,
+		nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search ldap group %s, error: %s", groupName, err)
+	}
+	if len(sr.Entries) == 0 {
+		return nil, fmt.Errorf("ldap group %s not found", groupName)
+	}
+
+	accountAttr := config.UserSearch.PreferredUsernameAttrAttr
+	nameAttr := accountAttr
+	if len(config.UserSearch.NameAttr) != 0 {
+		nameAttr = config.UserSearch.NameAttr
+	}
+	userAttrs := []string{accountAttr, nameAttr, config.UserSearch.EmailAttr}
+
+	members := make([]*SyncUserInfo, 0)
+	accounts := sets.NewString()
+	for _, entry := range sr.Entries {
+		for _, matcher := range matchers {
+			for _, value := range entry.GetAttributeValues(matcher.GroupAttr) {
+				var userRequest *ldapv3.SearchRequest
+				if strings.EqualFold(matcher.UserAttr, "DN") {
+					filter := config.UserSearch.Filter
+					if filter == "" {
+						filter = "(objectClass=*)"
+					}
+					userRequest = ldapv3.NewSearchRequest(value, ldapv3.ScopeBaseObject, ldapv3.NeverDerefAliases, 0, 0, false, filter, userAttrs, nil)
+				} else {
+					filter := fmt.Sprintf("(%s=%s)", matcher.UserAttr, ldapv3.EscapeFilter(value))
+					if config.UserSearch.Filter != "" {
+						filter = fmt.Sprintf("(&%s%s)", config.UserSearch.Filter, filter)
+					}
+					userRequest = ldapv3.NewSearchRequest(config.UserSearch.BaseDN, ldapv3.ScopeWholeSubtree, ldapv3.NeverDerefAliases, 0, 0, false, filter, userAttrs, nil)
+				}
+
+				userResult, err := l.Search(userRequest)
+				if err != nil {
+					// the member may be a nested group or a user out of the user search
+					if ldapv3.IsErrorWithCode(err, ldapv3.LDAPResultNoSuchObject) {
+						continue
+					}
+					return nil, fmt.Errorf("failed to search the member %s of ldap group %s, error: %s", value, groupName, err)
+				}
+				for _, userEntry := range userResult.Entries {
+					account := userEntry.GetAttributeValue(accountAttr)
+					if account == "" || accounts.Has(account) {
+						continue
+					}
+					accounts.Insert(account)
+					members = append(members, &SyncUserInfo{
+						Account: account,
+						Name:    userEntry.GetAttributeValue(nameAttr),
+						Email:   userEntry.GetAttributeValue(config.UserSearch.EmailAttr),
+					})
+				}
+			}
+		}
+	}
+	return members, nil
+}
+
+// syncLDAPGroupRoleBindings binds the role to the group members and unbinds the users who left the group,
+// the users who haven't logged in yet are created like the ldap user sync does
+func syncLDAPGroupRoleBindings(connectorID string, mapping *models.LDAPGroupRoleMapping, members []*SyncUserInfo, logger *zap.SugaredLogger) error {
+	role, err := orm.GetRole(mapping.RoleName, mapping.Namespace, repository.DB)
+	if err != nil || role.ID == 0 {
+		return fmt.Errorf("failed to find role %s in namespace %s, error: %v", mapping.RoleName, mapping.Namespace, err)
+	}
+
+	synced := sets.NewString(mapping.SyncedUIDs...)
+	// the bindings created before a failure are still recorded so that they are removed later
+	defer func() { mapping.SyncedUIDs = synced.List() }()
+
+	memberUIDs := sets.NewString()
+	for _, member := range members {
+		member.IdentityType = connectorID
+		user, err := orm.GetUser(member.Account, connectorID, repository.DB)
+		if err != nil {
+			return fmt.Errorf("failed to find user %s, error: %s", member.Account, err)
+		}
+		if user == nil {
+			user, err = SyncUser(member, false, logger)
+			if err != nil {
+				return fmt.Errorf("failed to sync user %s, error: %s", member.Account, err)
+			}
+		}
+		memberUIDs.Insert(user.UID)
+
+		binding, err := orm.GetRoleBinding(role.ID, user.UID, repository.DB)
+		if err != nil {
+			return fmt.Errorf("failed to find the role binding of user %s, error: %s", member.Account, err)
+		}
+		// the binding created manually is not managed by the sync
+		if binding.ID != 0 {
+			continue
+		}
+		if err := orm.CreateRoleBinding(&models.NewRoleBinding{UID: user.UID, RoleID: role.ID}, repository.DB); err != nil {
+			return fmt.Errorf("failed to bind role %s to user %s, error: %s", role.Name, member.Account, err)
+		}
+		synced.Insert(user.UID)
+	}
+
+	for _, uid := range synced.Difference(memberUIDs).List() {
+		if err := orm.DeleteRoleBinding(role.ID, uid, repository.DB); err != nil {
+			return fmt.Errorf("failed to unbind role %s from user %s, error: %s", role.Name, uid, err)
+		}
+		synced.Delete(uid)
+	}
+	return nil
+}
+
+func removeSyncedRoleBindings(mapping *models.LDAPGroupRoleMapping) error {
+	if len(mapping.SyncedUIDs) == 0 {
+		return nil
+	}
+
+	role, err := orm.GetRole(mapping.RoleName, mapping.Namespace, repository.DB)
+	if err != nil {
+		return fmt.Errorf("failed to find role %s in namespace %s, error: %s", mapping.RoleName, mapping.Namespace, err)
+	}
+	// the bindings are gone along with the role
+	if role.ID == 0 {
+		return nil
+	}
+
+	tx := repository.DB.Begin()
+	for _, uid := range mapping.SyncedUIDs {
+		if err := orm.DeleteRoleBinding(role.ID, uid, tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to unbind role %s from user %s, error: %s", role.Name, uid, err)
+		}
+	}
+	return tx.Commit().Error
+}
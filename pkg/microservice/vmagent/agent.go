@@ -0,0 +1,252 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vmagent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/koderover/zadig/pkg/setting"
+	"github.com/koderover/zadig/pkg/shared/client/aslan"
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/types"
+	jobtypes "github.com/koderover/zadig/pkg/types/job"
+)
+
+// Version is the version of the agent reported to zadig
+const Version = "1.0.0"
+
+type Config struct {
+	// ServerURL is the address of zadig, for example https://zadig.example.com
+	ServerURL string
+	Token     string
+	// WorkDir holds the workspaces of the jobs, a workspace is removed when its job ends
+	WorkDir           string
+	PollInterval      time.Duration
+	HeartbeatInterval time.Duration
+}
+
+// Agent runs the jobs assigned by zadig one at a time, each job is run by the job executor in a child process
+// whose output is streamed to zadig as the job log
+type Agent struct {
+	cfg    *Config
+	client *aslan.Client
+
+	mu      sync.Mutex
+	running map[string]context.CancelFunc
+}
+
+func New(cfg *Config) *Agent {
+	return &Agent{
+		cfg:     cfg,
+		client:  aslan.NewVMAgent(cfg.ServerURL, cfg.Token),
+		running: make(map[string]context.CancelFunc),
+	}
+}
+
+func (a *Agent) Run(ctx context.Context) error {
+	if err := os.MkdirAll(a.cfg.WorkDir, os.ModePerm); err != nil {
+		return fmt.Errorf("failed to create work dir %s: %s", a.cfg.WorkDir, err)
+	}
+
+	hostname, _ := os.Hostname()
+	resp, err := a.client.RegisterVMAgent(&types.VMAgentRegisterArgs{
+		Hostname:     hostname,
+		IP:           getHostIP(),
+		Platform:     runtime.GOOS,
+		Architecture: runtime.GOARCH,
+		AgentVersion: Version,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to register agent: %s", err)
+	}
+	log.Infof("agent %s is registered with labels %v", resp.Name, resp.Labels)
+
+	go a.heartbeat(ctx)
+
+	ticker := time.NewTicker(a.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		job, err := a.client.PollVMAgentJob()
+		if err != nil {
+			log.Errorf("failed to poll job: %s", err)
+			continue
+		}
+		if job.ID == "" {
+			continue
+		}
+		a.runJob(ctx, job)
+	}
+}
+
+func (a *Agent) heartbeat(ctx context.Context) {
+	ticker := time.NewTicker(a.cfg.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		a.mu.Lock()
+		args := &types.VMAgentHeartbeatArgs{RunningJobs: make([]string, 0, len(a.running))}
+		for id := range a.running {
+			args.RunningJobs = append(args.RunningJobs, id)
+		}
+		a.mu.Unlock()
+
+		resp, err := a.client.VMAgentHeartbeat(args)
+		if err != nil {
+			log.Errorf("failed to send heartbeat: %s", err)
+			continue
+		}
+		for _, id := range resp.StoppedJobs {
+			a.stopJob(id)
+		}
+	}
+}
+
+func (a *Agent) stopJob(id string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if cancel, ok := a.running[id]; ok {
+		log.Infof("job %s is ended by zadig, stopping it", id)
+		cancel()
+		delete(a.running, id)
+	}
+}
+
+func (a *Agent) runJob(ctx context.Context, job *types.VMAgentJob) {
+	log.Infof("start to run job %s", job.ID)
+	jobCtx, cancel := context.WithCancel(ctx)
+	a.mu.Lock()
+	a.running[job.ID] = cancel
+	a.mu.Unlock()
+	defer a.stopJob(job.ID)
+
+	result := a.executeJob(jobCtx, job)
+	if jobCtx.Err() != nil {
+		// the job is cancelled or timed out in zadig, or the agent is stopping
+		log.Infof("job %s is stopped", job.ID)
+		return
+	}
+	if err := a.client.ReportVMJobResult(job.ID, result); err != nil {
+		log.Errorf("failed to report the result of job %s: %s", job.ID, err)
+		return
+	}
+	log.Infof("job %s finished with %s", job.ID, result.Status)
+}
+
+// executeJob runs the job executor in a child process with the workspace under the work dir of the agent
+func (a *Agent) executeJob(ctx context.Context, job *types.VMAgentJob) *types.VMJobResultArgs {
+	failed := func(err error) *types.VMJobResultArgs {
+		return &types.VMJobResultArgs{Status: types.JobFail, Error: err.Error()}
+	}
+
+	jobDir := filepath.Join(a.cfg.WorkDir, "jobs", job.ID)
+	if err := os.MkdirAll(jobDir, os.ModePerm); err != nil {
+		return failed(fmt.Errorf("failed to create job dir: %s", err))
+	}
+	defer func() {
+		if err := os.RemoveAll(jobDir); err != nil {
+			log.Warnf("failed to remove job dir %s: %s", jobDir, err)
+		}
+	}()
+
+	// the outputs are written to the same dir by all the jobs
+	if err := os.RemoveAll(jobtypes.JobOutputDir); err != nil {
+		return failed(fmt.Errorf("failed to clean the outputs of the last job: %s", err))
+	}
+
+	jobCtxFile := filepath.Join(jobDir, "job.yaml")
+	content, err := setJobWorkspace(job.JobCtx, filepath.Join(jobDir, "workspace"))
+	if err != nil {
+		return failed(err)
+	}
+	if err := os.WriteFile(jobCtxFile, content, 0600); err != nil {
+		return failed(fmt.Errorf("failed to write job context: %s", err))
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return failed(fmt.Errorf("failed to find the agent executable: %s", err))
+	}
+	resultFile := filepath.Join(jobDir, "result.json")
+	logs := newLogStreamer(a.client, job.ID)
+	cmd := exec.CommandContext(ctx, executable, "execute", "--result-file", resultFile)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("%s=%s", setting.JobConfigFile, jobCtxFile))
+	cmd.Stdout = logs
+	cmd.Stderr = logs
+	runErr := cmd.Run()
+	logs.Close()
+
+	data, err := os.ReadFile(resultFile)
+	if err != nil {
+		if runErr != nil {
+			return failed(fmt.Errorf("job executor exited: %s", runErr))
+		}
+		return failed(fmt.Errorf("failed to read job result: %s", err))
+	}
+	cmData := make(map[string]string)
+	if err := json.Unmarshal(data, &cmData); err != nil {
+		return failed(fmt.Errorf("failed to unmarshal job result: %s", err))
+	}
+	return &types.VMJobResultArgs{
+		Status:  types.JobStatus(cmData[types.JobResultKey]),
+		Outputs: cmData[types.JobOutputsKey],
+	}
+}
+
+// setJobWorkspace replaces the workspace of the job context which is the dir in the job pod
+func setJobWorkspace(jobCtx, workspace string) ([]byte, error) {
+	ctx := make(map[string]interface{})
+	if err := yaml.Unmarshal([]byte(jobCtx), &ctx); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal job context: %s", err)
+	}
+	ctx["workspace"] = workspace
+	return yaml.Marshal(ctx)
+}
+
+func getHostIP() string {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return ""
+	}
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+			return ipNet.IP.String()
+		}
+	}
+	return ""
+}
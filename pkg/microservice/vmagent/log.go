@@ -0,0 +1,109 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vmagent
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/koderover/zadig/pkg/shared/client/aslan"
+	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+const (
+	logFlushInterval = time.Second
+	// the buffer is flushed at once if it exceeds the size
+	logFlushSize = 64 * 1024
+	logRetry     = 3
+)
+
+// logStreamer sends the output of the job to zadig in chunks, a chunk failing to be sent is retried and then dropped
+type logStreamer struct {
+	client *aslan.Client
+	jobID  string
+
+	mu   sync.Mutex
+	buf  bytes.Buffer
+	seq  int64
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newLogStreamer(client *aslan.Client, jobID string) *logStreamer {
+	s := &logStreamer{
+		client: client,
+		jobID:  jobID,
+		done:   make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go s.loop()
+	return s
+}
+
+func (s *logStreamer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	n, err := s.buf.Write(p)
+	full := s.buf.Len() >= logFlushSize
+	s.mu.Unlock()
+	if full {
+		s.flush()
+	}
+	return n, err
+}
+
+// Close sends the rest of the output, it must be called after the process exits
+func (s *logStreamer) Close() {
+	close(s.done)
+	s.wg.Wait()
+	s.flush()
+}
+
+func (s *logStreamer) loop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(logFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.flush()
+		}
+	}
+}
+
+func (s *logStreamer) flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.buf.Len() == 0 {
+		return
+	}
+	s.seq++
+	args := &types.VMJobLogArgs{Seq: s.seq, Content: s.buf.String()}
+	s.buf.Reset()
+
+	var err error
+	for i := 0; i < logRetry; i++ {
+		if err = s.client.AppendVMJobLog(s.jobID, args); err == nil {
+			return
+		}
+		time.Sleep(time.Second)
+	}
+	log.Errorf("failed to send the log of job %s: %s", s.jobID, err)
+}
@@ -30,11 +30,22 @@ const (
 	ENVAslanDBName             = "ASLAN_DB"
 	ENVHubAgentImage           = "HUB_AGENT_IMAGE"
 	ENVExecutorImage           = "EXECUTOR_IMAGE"
+	ENVWindowsExecutorImage    = "WINDOWS_EXECUTOR_IMAGE"
 	ENVMysqlUser               = "MYSQL_USER"
 	ENVMysqlPassword           = "MYSQL_PASSWORD"
 	ENVMysqlHost               = "MYSQL_HOST"
 	ENVMysqlUserDb             = "MYSQL_USER_DB"
 
+	// mongodb query controls, the timeout is in seconds and the slow query threshold is in milliseconds
+	ENVMongoDBReadPreference     = "MONGODB_READ_PREFERENCE"
+	ENVMongoDBQueryTimeout       = "MONGODB_QUERY_TIMEOUT"
+	ENVMongoDBSlowQueryThreshold = "MONGODB_SLOW_QUERY_THRESHOLD"
+
+	// storage backend of the workflow tasks, mongodb by default or postgresql
+	ENVTaskStorageBackend      = "TASK_STORAGE_BACKEND"
+	ENVTaskStoragePostgresDSN  = "TASK_STORAGE_POSTGRES_DSN"
+	TaskStorageBackendPostgres = "postgresql"
+
 	// Aslan
 	ENVPodName              = "BE_POD_NAME"
 	ENVNamespace            = "BE_POD_NAMESPACE"
@@ -46,6 +57,12 @@ const (
 	ENVAslanRegAccessKey    = "DEFAULT_REGISTRY_AK"
 	ENVAslanRegSecretKey    = "DEFAULT_REGISTRY_SK"
 	ENVAslanRegNamespace    = "DEFAULT_REGISTRY_NAMESPACE"
+	// comma separated ips or cidrs of the gateways in front of aslan, only the forwarded client ip set by them is trusted
+	ENVTrustedProxies = "TRUSTED_PROXIES"
+	// comma separated cidrs the http callback jobs can not request, the loopback, link-local and private ranges
+	// and the service network of the cluster by default
+	ENVHTTPCallbackDeniedCIDRs = "HTTP_CALLBACK_DENIED_CIDRS"
+	ENVClusterServiceCIDR      = "CLUSTER_SERVICE_CIDR"
 
 	ENVGithubSSHKey    = "GITHUB_SSH_KEY"
 	ENVGithubKnownHost = "GITHUB_KNOWN_HOST"
@@ -74,6 +91,7 @@ const (
 	ENVRootToken = "ROOT_TOKEN"
 
 	ENVKodespaceVersion = "KODESPACE_VERSION"
+	ENVZadigVersion     = "ZADIG_VERSION"
 
 	// hubagent
 	HubAgentToken         = "HUB_AGENT_TOKEN"
@@ -84,6 +102,7 @@ const (
 	Params                = "X-API-Tunnel-Params"
 	AslanBaseAddr         = "ASLAN_BASE_ADDR"
 	ScheduleWorkflow      = "SCHEDULE_WORKFLOW"
+	HubAgentVersion       = "HUB_AGENT_VERSION"
 
 	// warpdrive
 	WarpDrivePodName    = "WD_POD_NAME"
@@ -128,6 +147,7 @@ const (
 	// initconfig
 	ENVAdminEmail    = "ADMIN_EMAIL"
 	ENVAdminPassword = "ADMIN_PASSWORD"
+	ENVBootstrapFile = "BOOTSTRAP_FILE"
 	PresetAccount    = "admin"
 )
 
@@ -176,6 +196,9 @@ const (
 	ModifiedByAnnotation            = companyLabel + "/" + "last-modified-by"
 	EditorIDAnnotation              = companyLabel + "/" + "editor-id"
 	LastUpdateTimeAnnotation        = companyLabel + "/" + "last-update-time"
+	// ProtectedResourceKey can be set as an annotation or a label on cluster resources managed by hand,
+	// the value decides how deploy and env update operations treat them
+	ProtectedResourceKey = companyLabel + "/" + "protected"
 
 	JobLabelTaskKey  = "s-task"
 	JobLabelNameKey  = "s-name"
@@ -183,6 +206,10 @@ const (
 
 	LabelValueTrue = "true"
 
+	// values of ProtectedResourceKey, any other non-empty value is treated as ProtectedResourceConfirm
+	ProtectedResourceSkip    = "skip"
+	ProtectedResourceConfirm = "confirm"
+
 	// Pod status
 	PodRunning                 = "Running"
 	PodError                   = "Error"
@@ -272,6 +299,8 @@ const (
 	SourceFromChartRepo   = "chartRepo"
 	SourceFromCustomEdit  = "customEdit"
 	SourceFromVariableSet = "variableSet"
+	// SourceFromKustomize The k8s yaml service is built from a kustomize base and per-env overlays in git repo
+	SourceFromKustomize = "kustomize"
 
 	// SourceFromGUI The configuration source is gui
 	SourceFromGUI = "gui"
@@ -325,6 +354,8 @@ const (
 
 const (
 	AuthorizationHeader = "Authorization"
+	// IdempotencyKeyHeader is the client supplied key deduplicating the task creations
+	IdempotencyKeyHeader = "Idempotency-Key"
 )
 
 // install script constants
@@ -369,6 +400,11 @@ const (
 	ServiceTaskFmt    = "ServiceTask:%s"
 	ScanningTaskFmt   = "ScanningTask:%s"
 	ReleasePlanFmt    = "ReleasePlan:default"
+	// BuildVersionFmt is the counter of the build versions, args are workflow, job, service and service module
+	BuildVersionFmt = "BuildVersion:%s:%s:%s:%s"
+	// BuildVersionOfTagFmt is the counter of the builds of a version bumped from the git tags, args are workflow, job,
+	// service, service module and version
+	BuildVersionOfTagFmt = "BuildVersion:%s:%s:%s:%s:%s"
 )
 
 // Product Status
@@ -471,6 +507,8 @@ const (
 	MeegoHookTaskCreator = "meego_hook"
 	// GeneralHookTaskCreator ...
 	GeneralHookTaskCreator = "general_hook"
+	// RegistryHookTaskCreator ...
+	RegistryHookTaskCreator = "registry_hook"
 	// CronTaskCreator ...
 	CronTaskCreator = "timer"
 	// DefaultTaskRevoker ...
@@ -537,6 +575,21 @@ const (
 	EnvRecyclePolicyNever      = "never"
 )
 
+// OSWindows is the os of the basic images and the jobs running on windows nodes, an empty os means linux
+const OSWindows = "windows"
+
+// archs of the multi-arch builds, they are the values of the kubernetes.io/arch node label
+const (
+	ArchAMD64 = "amd64"
+	ArchARM64 = "arm64"
+)
+
+// infrastructures the freestyle and build jobs run on
+const (
+	JobK8sInfrastructure = "kubernetes"
+	JobVMInfrastructure  = "vm"
+)
+
 const (
 	ImageFromCustom     = "custom"
 	FixedDayTimeCronjob = "timing"
@@ -549,12 +602,30 @@ const (
 	EnvAnalysisCronjob = "env_analysis"
 	EnvSleepCronjob    = "env_sleep"
 
+	// CronConcurrencyQueue creates the task anyway and let it wait in the queue while the previous run is executing
+	CronConcurrencyQueue          = "queue"
+	CronConcurrencySkip           = "skip"
+	CronConcurrencyCancelPrevious = "cancel_previous"
+
+	// CronMisfireSkip ignores the fire times missed while the cron service was down
+	CronMisfireSkip    = "skip"
+	CronMisfireFireNow = "fire_now"
+
 	TopicProcess      = "task.process"
 	TopicCancel       = "task.cancel"
 	TopicAck          = "task.ack"
 	TopicItReport     = "task.it.report"
 	TopicNotification = "task.notification"
 	TopicCronjob      = "cronjob"
+	// TopicWorkflowV4Control forwards the cancel and approve operations to the controller instance running the workflow task
+	TopicWorkflowV4Control = "workflowv4.control"
+)
+
+// registry hook sources, the push events of these registries are supported
+const (
+	RegistryHookSourceHarbor    = "harbor"
+	RegistryHookSourceACR       = "acr"
+	RegistryHookSourceDockerhub = "dockerhub"
 )
 
 // S3 related constants
@@ -0,0 +1,90 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aslan
+
+import (
+	"fmt"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+	"github.com/koderover/zadig/pkg/types"
+)
+
+// NewVMAgent creates the client used by the vm agents, the requests are authenticated by the agent token
+func NewVMAgent(host, token string) *Client {
+	c := httpclient.New(
+		httpclient.SetClientHeader(types.VMAgentTokenHeader, token),
+		httpclient.SetHostURL(host+"/api/aslan"),
+	)
+
+	return &Client{
+		Client:   c,
+		host:     host,
+		token:    token,
+		external: true,
+	}
+}
+
+func (c *Client) RegisterVMAgent(args *types.VMAgentRegisterArgs) (*types.VMAgentRegisterResp, error) {
+	url := "/vm/agent/register"
+
+	res := new(types.VMAgentRegisterResp)
+	_, err := c.Post(url, httpclient.SetBody(args), httpclient.SetResult(res))
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (c *Client) VMAgentHeartbeat(args *types.VMAgentHeartbeatArgs) (*types.VMAgentHeartbeatResp, error) {
+	url := "/vm/agent/heartbeat"
+
+	res := new(types.VMAgentHeartbeatResp)
+	_, err := c.Post(url, httpclient.SetBody(args), httpclient.SetResult(res))
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// PollVMAgentJob picks up a queued job, the id of the job is empty if there is none
+func (c *Client) PollVMAgentJob() (*types.VMAgentJob, error) {
+	url := "/vm/agent/job/poll"
+
+	res := new(types.VMAgentJob)
+	_, err := c.Post(url, httpclient.SetResult(res))
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (c *Client) AppendVMJobLog(jobID string, args *types.VMJobLogArgs) error {
+	url := fmt.Sprintf("/vm/agent/job/%s/log", jobID)
+
+	_, err := c.Post(url, httpclient.SetBody(args))
+	return err
+}
+
+func (c *Client) ReportVMJobResult(jobID string, args *types.VMJobResultArgs) error {
+	url := fmt.Sprintf("/vm/agent/job/%s/result", jobID)
+
+	_, err := c.Post(url, httpclient.SetBody(args))
+	return err
+}
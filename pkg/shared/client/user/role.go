@@ -38,6 +38,19 @@ func (c *Client) ListRoles(namespace, uid string) ([]*types.Role, error) {
 	return resp, nil
 }
 
+// RenameProjectRoles moves the roles and the role bindings of the project to the new project key
+func (c *Client) RenameProjectRoles(namespace, newNamespace string) error {
+	url := "/policy/internal/renameProjectRole"
+
+	query := map[string]string{
+		"namespace":    namespace,
+		"newNamespace": newNamespace,
+	}
+
+	_, err := c.Post(url, httpclient.SetQueryParams(query))
+	return err
+}
+
 func (c *Client) DeleteAllProjectRoles(namespace string) error {
 	url := "/policy/internal/deleteProjectRole"
 
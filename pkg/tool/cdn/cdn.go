@@ -0,0 +1,90 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cdn
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudfront"
+
+	"github.com/koderover/zadig/pkg/tool/httpclient"
+)
+
+const (
+	ProviderCloudFront = "cloudfront"
+	// ProviderWebhook posts the paths to a custom endpoint which purges the cache of the cdn
+	ProviderWebhook = "webhook"
+)
+
+type PurgeArgs struct {
+	Provider string
+	// DistributionID, AK and SK are used by CloudFront
+	DistributionID string
+	AK             string
+	SK             string
+	WebhookURL     string
+	Paths          []string
+}
+
+type webhookPurgeRequest struct {
+	Paths []string `json:"paths"`
+}
+
+// Purge invalidates the cached paths in the cdn and returns the id of the purge request if there is one
+func Purge(args *PurgeArgs) (string, error) {
+	if len(args.Paths) == 0 {
+		return "", nil
+	}
+	switch args.Provider {
+	case ProviderCloudFront:
+		return purgeCloudFront(args)
+	case ProviderWebhook:
+		_, err := httpclient.Post(args.WebhookURL, httpclient.SetBody(&webhookPurgeRequest{Paths: args.Paths}))
+		return "", err
+	default:
+		return "", fmt.Errorf("unsupported cdn provider %s", args.Provider)
+	}
+}
+
+func purgeCloudFront(args *PurgeArgs) (string, error) {
+	sess, err := session.NewSession(&aws.Config{
+		Credentials: credentials.NewStaticCredentials(args.AK, args.SK, ""),
+		// cloudfront is a global service
+		Region: aws.String("us-east-1"),
+	})
+	if err != nil {
+		return "", err
+	}
+	resp, err := cloudfront.New(sess).CreateInvalidation(&cloudfront.CreateInvalidationInput{
+		DistributionId: aws.String(args.DistributionID),
+		InvalidationBatch: &cloudfront.InvalidationBatch{
+			CallerReference: aws.String(fmt.Sprint(time.Now().UnixNano())),
+			Paths: &cloudfront.Paths{
+				Quantity: aws.Int64(int64(len(args.Paths))),
+				Items:    aws.StringSlice(args.Paths),
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(resp.Invalidation.Id), nil
+}
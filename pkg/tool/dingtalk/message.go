@@ -0,0 +1,47 @@
+/*
+ * Copyright 2023 The KodeRover Authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dingtalk
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// ActionCard is an action card with two buttons sent to users by the robot of the app
+type ActionCard struct {
+	Title        string `json:"title"`
+	Text         string `json:"text"`
+	ActionTitle1 string `json:"actionTitle1"`
+	ActionURL1   string `json:"actionURL1"`
+	ActionTitle2 string `json:"actionTitle2"`
+	ActionURL2   string `json:"actionURL2"`
+}
+
+func (c *Client) SendActionCard(userIDs []string, card *ActionCard) error {
+	param, err := json.Marshal(card)
+	if err != nil {
+		return errors.Wrap(err, "marshal action card")
+	}
+	_, err = c.R().SetBodyJsonMarshal(map[string]interface{}{
+		"robotCode": c.AppKey,
+		"userIds":   userIDs,
+		"msgKey":    "sampleActionCard2",
+		"msgParam":  string(param),
+	}).Post("https://api.dingtalk.com/v1.0/robot/oToMessages/batchSend")
+	return err
+}
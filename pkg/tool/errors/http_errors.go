@@ -29,6 +29,8 @@ var (
 	ErrForbidden = NewHTTPError(403, "Forbidden")
 	// ErrNotFound ...
 	ErrNotFound = NewHTTPError(404, "Request Not Found")
+	// ErrTooManyRequests ...
+	ErrTooManyRequests = NewHTTPError(429, "Too Many Requests")
 	// ErrInternalError ...
 	ErrInternalError = NewHTTPError(500, "Internal Error")
 
@@ -410,6 +412,8 @@ var (
 	// ErrListImages ...
 	ErrListImages   = NewHTTPError(6280, "列出镜像失败")
 	ErrFindRegistry = NewHTTPError(6281, "找不到指定的镜像仓库")
+	// ErrRotateRegistryCredential ...
+	ErrRotateRegistryCredential = NewHTTPError(6282, "轮换镜像仓库凭证失败")
 
 	//-----------------------------------------------------------------------------------------------
 	// Insghts APIs Range: 6300 - 6399
@@ -596,6 +600,7 @@ var (
 	ErrClusterNotFound       = NewHTTPError(6643, "未找到指定集群")
 	ErrDeleteCluster         = NewHTTPError(6644, "删除集群失败")
 	ErrDeleteClusterStrategy = NewHTTPError(6645, "删除集群调度策略失败")
+	ErrUpgradeAgent          = NewHTTPError(6646, "升级集群 agent 失败")
 
 	//-----------------------------------------------------------------------------------------------
 	// operation APIs Range: 6650 - 6659
@@ -750,11 +755,14 @@ var (
 	//-----------------------------------------------------------------------------------------------
 	// webhook releated Error Range: 6880 - 6889
 	//-----------------------------------------------------------------------------------------------
-	ErrGetWebhook    = NewHTTPError(6880, "获取webhook详情失败")
-	ErrListWebhook   = NewHTTPError(6881, "列出webhook失败")
-	ErrCreateWebhook = NewHTTPError(6882, "创建webhook失败")
-	ErrUpdateWebhook = NewHTTPError(6883, "更新webhook失败")
-	ErrDeleteWebhook = NewHTTPError(6884, "删除webhook失败")
+	ErrGetWebhook          = NewHTTPError(6880, "获取webhook详情失败")
+	ErrListWebhook         = NewHTTPError(6881, "列出webhook失败")
+	ErrCreateWebhook       = NewHTTPError(6882, "创建webhook失败")
+	ErrUpdateWebhook       = NewHTTPError(6883, "更新webhook失败")
+	ErrDeleteWebhook       = NewHTTPError(6884, "删除webhook失败")
+	ErrListWebhookDelivery = NewHTTPError(6885, "列出webhook投递记录失败")
+	ErrGetWebhookDelivery  = NewHTTPError(6886, "获取webhook投递记录失败")
+	ErrRedeliverWebhook    = NewHTTPError(6887, "重新投递webhook失败")
 
 	//-----------------------------------------------------------------------------------------------
 	// workflow view releated Error Range: 6890 - 6899
@@ -877,4 +885,45 @@ var (
 	ErrUpdateObservabilityIntegration = NewHTTPError(7022, "更新 观测工具 集成失败")
 	ErrDeleteObservabilityIntegration = NewHTTPError(7023, "删除 观测工具 集成失败")
 	ErrGetObservabilityIntegration    = NewHTTPError(7024, "获取 观测工具 集成详情失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// registry hook releated Error Range: 7030 - 7039
+	//-----------------------------------------------------------------------------------------------
+	ErrGetRegistryHook    = NewHTTPError(7030, "获取镜像仓库 hook 详情失败")
+	ErrListRegistryHook   = NewHTTPError(7031, "列出镜像仓库 hook 失败")
+	ErrCreateRegistryHook = NewHTTPError(7032, "创建镜像仓库 hook 失败")
+	ErrUpdateRegistryHook = NewHTTPError(7033, "更新镜像仓库 hook 失败")
+	ErrDeleteRegistryHook = NewHTTPError(7034, "删除镜像仓库 hook 失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// vm agent releated Error Range: 7040 - 7049
+	//-----------------------------------------------------------------------------------------------
+	ErrListVMAgent         = NewHTTPError(7040, "列出 vm agent 失败")
+	ErrCreateVMAgent       = NewHTTPError(7041, "创建 vm agent 失败")
+	ErrUpdateVMAgent       = NewHTTPError(7042, "更新 vm agent 失败")
+	ErrDeleteVMAgent       = NewHTTPError(7043, "删除 vm agent 失败")
+	ErrVMAgentUnauthorized = NewHTTPError(7044, "vm agent 认证失败")
+	ErrVMAgentJob          = NewHTTPError(7045, "vm agent 任务处理失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// asset reference releated Error Range: 7050 - 7059
+	//-----------------------------------------------------------------------------------------------
+	ErrListAssetReferences = NewHTTPError(7050, "查询引用关系失败")
+	ErrAssetReferenced     = NewHTTPError(7051, "资源被工作流引用，无法删除")
+
+	//-----------------------------------------------------------------------------------------------
+	// configuration backup releated Error Range: 7060 - 7069
+	//-----------------------------------------------------------------------------------------------
+	ErrBackupConfiguration  = NewHTTPError(7060, "备份系统配置失败")
+	ErrRestoreConfiguration = NewHTTPError(7061, "恢复系统配置失败")
+
+	//-----------------------------------------------------------------------------------------------
+	// tenant releated Error Range: 7070 - 7079
+	//-----------------------------------------------------------------------------------------------
+	ErrListTenant     = NewHTTPError(7070, "列出租户失败")
+	ErrGetTenant      = NewHTTPError(7071, "获取租户详情失败")
+	ErrCreateTenant   = NewHTTPError(7072, "创建租户失败")
+	ErrUpdateTenant   = NewHTTPError(7073, "更新租户失败")
+	ErrDeleteTenant   = NewHTTPError(7074, "删除租户失败")
+	ErrGetTenantUsage = NewHTTPError(7075, "获取租户资源用量失败")
 )
@@ -0,0 +1,86 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package featureflag
+
+import (
+	"strings"
+
+	"github.com/imroc/req/v3"
+	"github.com/pkg/errors"
+
+	"github.com/koderover/zadig/pkg/tool/truststore"
+)
+
+// supported feature flag providers
+const (
+	ProviderUnleash   = "unleash"
+	ProviderFlagsmith = "flagsmith"
+)
+
+// Flag locates a flag in the provider, Project is the project of unleash and is ignored by flagsmith, Environment is the
+// environment name of unleash or the environment key of flagsmith
+type Flag struct {
+	Project     string
+	Environment string
+	Name        string
+}
+
+// State is the state of a flag in an environment, Rollout is the percentage of users the flag is enabled for, nil
+// means the flag is not rolled out by percentage
+type State struct {
+	Enabled bool
+	Rollout *int
+}
+
+type Client interface {
+	GetState(flag *Flag) (*State, error)
+	SetState(flag *Flag, state *State) error
+}
+
+// NewClient returns the client of the provider, the headers carry the credentials of the provider, such as
+// "Authorization: <token>" for unleash and "Authorization: Token <key>" for flagsmith
+func NewClient(provider, server string, headers map[string]string) (Client, error) {
+	client := req.C().
+		SetCommonHeaders(headers).
+		OnAfterResponse(func(client *req.Client, resp *req.Response) error {
+			if resp.Err != nil {
+				resp.Err = errors.Wrapf(resp.Err, "body: %s", resp.String())
+				return nil
+			}
+			if !resp.IsSuccessState() {
+				resp.Err = errors.Errorf("unexpected status code %d, body: %s", resp.GetStatusCode(), resp.String())
+				return nil
+			}
+			return nil
+		})
+	if tlsConfig := truststore.TLSConfig(truststore.IntegrationFeatureFlag); tlsConfig != nil {
+		client.SetTLSClientConfig(tlsConfig)
+	}
+	if !strings.HasPrefix(server, "http://") && !strings.HasPrefix(server, "https://") {
+		server = "https://" + server
+	}
+	server = strings.TrimSuffix(server, "/")
+
+	switch provider {
+	case ProviderUnleash:
+		return &unleashClient{Client: client, BaseURL: server + "/api/admin"}, nil
+	case ProviderFlagsmith:
+		return &flagsmithClient{Client: client, BaseURL: server + "/api/v1"}, nil
+	default:
+		return nil, errors.Errorf("unsupported feature flag provider %s", provider)
+	}
+}
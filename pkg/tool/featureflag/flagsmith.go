@@ -0,0 +1,78 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package featureflag
+
+import (
+	"fmt"
+
+	"github.com/imroc/req/v3"
+	"github.com/pkg/errors"
+)
+
+type flagsmithClient struct {
+	*req.Client
+	BaseURL string
+}
+
+type flagsmithFeatureStates struct {
+	Results []*flagsmithFeatureState `json:"results"`
+}
+
+type flagsmithFeatureState struct {
+	ID             int64  `json:"id"`
+	Enabled        bool   `json:"enabled"`
+	Identity       *int64 `json:"identity"`
+	FeatureSegment *int64 `json:"feature_segment"`
+}
+
+// getFeatureState returns the environment default state of the flag, the identity and segment overrides are ignored
+func (c *flagsmithClient) getFeatureState(flag *Flag) (*flagsmithFeatureState, error) {
+	states := new(flagsmithFeatureStates)
+	_, err := c.R().SetQueryParam("feature_name", flag.Name).SetSuccessResult(states).
+		Get(fmt.Sprintf("%s/environments/%s/featurestates/", c.BaseURL, flag.Environment))
+	if err != nil {
+		return nil, err
+	}
+	for _, state := range states.Results {
+		if state.Identity == nil && state.FeatureSegment == nil {
+			return state, nil
+		}
+	}
+	return nil, errors.Errorf("flag %s not found in environment %s", flag.Name, flag.Environment)
+}
+
+// GetState never returns a rollout since the percentage split of flagsmith is configured by segments
+func (c *flagsmithClient) GetState(flag *Flag) (*State, error) {
+	state, err := c.getFeatureState(flag)
+	if err != nil {
+		return nil, err
+	}
+	return &State{Enabled: state.Enabled}, nil
+}
+
+func (c *flagsmithClient) SetState(flag *Flag, state *State) error {
+	if state.Rollout != nil {
+		return errors.Errorf("percentage rollout of flag %s is not supported by flagsmith", flag.Name)
+	}
+	featureState, err := c.getFeatureState(flag)
+	if err != nil {
+		return err
+	}
+	_, err = c.R().SetBodyJsonMarshal(map[string]bool{"enabled": state.Enabled}).
+		Patch(fmt.Sprintf("%s/environments/%s/featurestates/%d/", c.BaseURL, flag.Environment, featureState.ID))
+	return errors.Wrapf(err, "update flag %s", flag.Name)
+}
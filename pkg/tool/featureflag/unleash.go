@@ -0,0 +1,152 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package featureflag
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/imroc/req/v3"
+	"github.com/pkg/errors"
+)
+
+const (
+	unleashDefaultProject  = "default"
+	unleashFlexibleRollout = "flexibleRollout"
+)
+
+type unleashClient struct {
+	*req.Client
+	BaseURL string
+}
+
+type unleashFeature struct {
+	Name         string                `json:"name"`
+	Environments []*unleashEnvironment `json:"environments"`
+}
+
+type unleashEnvironment struct {
+	Name       string             `json:"name"`
+	Enabled    bool               `json:"enabled"`
+	Strategies []*unleashStrategy `json:"strategies"`
+}
+
+type unleashStrategy struct {
+	ID         string            `json:"id,omitempty"`
+	Name       string            `json:"name"`
+	Parameters map[string]string `json:"parameters"`
+}
+
+func (c *unleashClient) environmentURL(flag *Flag) string {
+	project := flag.Project
+	if project == "" {
+		project = unleashDefaultProject
+	}
+	return fmt.Sprintf("%s/projects/%s/features/%s/environments/%s", c.BaseURL, project, flag.Name, flag.Environment)
+}
+
+func (c *unleashClient) getEnvironment(flag *Flag) (*unleashEnvironment, error) {
+	project := flag.Project
+	if project == "" {
+		project = unleashDefaultProject
+	}
+	feature := new(unleashFeature)
+	_, err := c.R().SetSuccessResult(feature).Get(fmt.Sprintf("%s/projects/%s/features/%s", c.BaseURL, project, flag.Name))
+	if err != nil {
+		return nil, err
+	}
+	for _, env := range feature.Environments {
+		if env.Name == flag.Environment {
+			return env, nil
+		}
+	}
+	return nil, errors.Errorf("environment %s of flag %s not found", flag.Environment, flag.Name)
+}
+
+// GetState returns the rollout of the first flexible rollout strategy as the rollout of the flag
+func (c *unleashClient) GetState(flag *Flag) (*State, error) {
+	env, err := c.getEnvironment(flag)
+	if err != nil {
+		return nil, err
+	}
+	state := &State{Enabled: env.Enabled}
+	for _, strategy := range env.Strategies {
+		if strategy.Name != unleashFlexibleRollout {
+			continue
+		}
+		rollout, err := strconv.Atoi(strategy.Parameters["rollout"])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid rollout of flag %s", flag.Name)
+		}
+		state.Rollout = &rollout
+		break
+	}
+	return state, nil
+}
+
+// SetState updates the first flexible rollout strategy or creates one for the rollout, the flexible rollout strategies
+// are removed if the rollout is nil
+func (c *unleashClient) SetState(flag *Flag, state *State) error {
+	env, err := c.getEnvironment(flag)
+	if err != nil {
+		return err
+	}
+	var rolloutStrategies []*unleashStrategy
+	for _, strategy := range env.Strategies {
+		if strategy.Name == unleashFlexibleRollout {
+			rolloutStrategies = append(rolloutStrategies, strategy)
+		}
+	}
+
+	strategiesURL := c.environmentURL(flag) + "/strategies"
+	if state.Rollout != nil {
+		if len(rolloutStrategies) > 0 {
+			// only the rollout is changed, the stickiness and the group of the strategy are kept
+			strategy := rolloutStrategies[0]
+			if strategy.Parameters == nil {
+				strategy.Parameters = make(map[string]string)
+			}
+			strategy.Parameters["rollout"] = strconv.Itoa(*state.Rollout)
+			_, err = c.R().SetBodyJsonMarshal(strategy).Put(strategiesURL + "/" + strategy.ID)
+		} else {
+			_, err = c.R().SetBodyJsonMarshal(&unleashStrategy{
+				Name: unleashFlexibleRollout,
+				Parameters: map[string]string{
+					"rollout":    strconv.Itoa(*state.Rollout),
+					"stickiness": "default",
+					"groupId":    flag.Name,
+				},
+			}).Post(strategiesURL)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "set rollout of flag %s", flag.Name)
+		}
+	} else {
+		for _, strategy := range rolloutStrategies {
+			if _, err := c.R().Delete(strategiesURL + "/" + strategy.ID); err != nil {
+				return errors.Wrapf(err, "delete rollout strategy of flag %s", flag.Name)
+			}
+		}
+	}
+
+	action := "off"
+	if state.Enabled {
+		action = "on"
+	}
+	_, err = c.R().Post(c.environmentURL(flag) + "/" + action)
+	return errors.Wrapf(err, "turn %s flag %s", action, flag.Name)
+}
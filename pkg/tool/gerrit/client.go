@@ -207,13 +207,18 @@ func (c *Client) GetCurrentVersionByChangeID(name string, pr int) (*gerrit.Chang
 }
 
 func (c *Client) SetReview(projectName string, changeID int, m, label, score, revision string) error {
-	projectName = Unescape(projectName)
 	var labels map[string]string
 	if len(label) != 0 {
 		labels = map[string]string{
 			label: score,
 		}
 	}
+	return c.SetReviewLabels(projectName, changeID, m, labels, revision)
+}
+
+// SetReviewLabels posts a review message and votes on several labels of the revision at once
+func (c *Client) SetReviewLabels(projectName string, changeID int, m string, labels map[string]string, revision string) error {
+	projectName = Unescape(projectName)
 	_, _, err := c.cli.Changes.SetReview(
 		fmt.Sprintf("%s~%d", url.QueryEscape(projectName), changeID),
 		revision,
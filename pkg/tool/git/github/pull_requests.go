@@ -91,3 +91,12 @@ func (c *Client) ListFiles(ctx context.Context, owner string, repo string, numbe
 
 	return res, err
 }
+
+func (c *Client) CreateIssueComment(ctx context.Context, owner string, repo string, number int, body string) (*github.IssueComment, error) {
+	comment, err := wrap(c.Issues.CreateComment(ctx, owner, repo, number, &github.IssueComment{Body: &body}))
+	if ic, ok := comment.(*github.IssueComment); ok {
+		return ic, err
+	}
+
+	return nil, err
+}
@@ -56,6 +56,16 @@ func (c *Client) ListRepositoriesForAuthenticatedUser(ctx context.Context, user
 	return res, err
 }
 
+// CompareCommits compares the head with the base, the commits reachable from the head but not from the base are
+// returned in the comparison, at most 250 of them
+func (c *Client) CompareCommits(ctx context.Context, owner, repo, base, head string) (*github.CommitsComparison, error) {
+	comparison, res, err := c.Repositories.CompareCommits(ctx, owner, repo, base, head)
+	if err := wrapError(res, err); err != nil {
+		return nil, err
+	}
+	return comparison, nil
+}
+
 func (c *Client) ListBranches(ctx context.Context, owner, repo string, opts *ListOptions) ([]*github.Branch, error) {
 	branches, err := wrap(paginated(func(o *github.ListOptions) ([]interface{}, *github.Response, error) {
 		bs, r, err := c.Repositories.ListBranches(ctx, owner, repo, &github.BranchListOptions{ListOptions: *o})
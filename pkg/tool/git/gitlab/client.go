@@ -25,6 +25,7 @@ import (
 	"github.com/xanzy/go-gitlab"
 
 	"github.com/koderover/zadig/pkg/tool/httpclient"
+	"github.com/koderover/zadig/pkg/tool/truststore"
 )
 
 // TODO: LOU: unify the github/gitlab helpers
@@ -52,8 +53,10 @@ func NewClient(id int, address, accessToken, proxyAddr string, enableProxy bool)
 		if err != nil {
 			return nil, err
 		}
-		transport := &http.Transport{Proxy: http.ProxyURL(proxyURL)}
+		transport := &http.Transport{Proxy: http.ProxyURL(proxyURL), TLSClientConfig: truststore.TLSConfig(truststore.IntegrationGitLab)}
 		client = &http.Client{Transport: transport}
+	} else if truststore.TLSConfig(truststore.IntegrationGitLab) != nil {
+		client = &http.Client{Transport: truststore.Transport(truststore.IntegrationGitLab)}
 	} else {
 		client = http.DefaultClient
 	}
@@ -24,6 +24,7 @@ import (
 	"github.com/koderover/zadig/pkg/shared/client/systemconfig"
 	"github.com/koderover/zadig/pkg/tool/httpclient"
 	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/tool/truststore"
 )
 
 type AccessToken struct {
@@ -104,6 +105,9 @@ func refreshAccessToken(address, clientID, clientSecret, refreshToken string) (*
 	httpClient := httpclient.New(
 		httpclient.SetHostURL(address),
 	)
+	if tlsConfig := truststore.TLSConfig(truststore.IntegrationGitLab); tlsConfig != nil {
+		httpClient.SetTLSClientConfig(tlsConfig)
+	}
 	url := "/oauth/token"
 	queryParams := make(map[string]string)
 	queryParams["grant_type"] = "refresh_token"
@@ -0,0 +1,76 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harbor
+
+import (
+	"crypto/tls"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/imroc/req/v3"
+	"github.com/pkg/errors"
+
+	"github.com/koderover/zadig/pkg/tool/truststore"
+)
+
+type Client struct {
+	*req.Client
+	BaseURL string
+}
+
+func NewClient(host, username, password string, insecure bool) *Client {
+	client := req.C().
+		SetCommonBasicAuth(username, password).
+		OnAfterResponse(func(client *req.Client, resp *req.Response) error {
+			if resp.Err != nil {
+				resp.Err = errors.Wrapf(resp.Err, "body: %s", resp.String())
+				return nil
+			}
+			if !resp.IsSuccessState() {
+				resp.Err = errors.Errorf("unexpected status code %d, body: %s", resp.GetStatusCode(), resp.String())
+				return nil
+			}
+			return nil
+		})
+	if insecure {
+		client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: true})
+	} else if tlsConfig := truststore.TLSConfig(truststore.IntegrationHarbor); tlsConfig != nil {
+		client.SetTLSClientConfig(tlsConfig)
+	}
+	if !strings.HasPrefix(host, "http://") && !strings.HasPrefix(host, "https://") {
+		host = "https://" + host
+	}
+	return &Client{
+		Client:  client,
+		BaseURL: strings.TrimSuffix(host, "/") + "/api/v2.0",
+	}
+}
+
+// getCreatedID returns the id of the created resource from the Location header, harbor does not return
+// the created resource in the body
+func getCreatedID(resp *req.Response) (int64, error) {
+	location := resp.GetHeader("Location")
+	if location == "" {
+		return 0, errors.New("location header not found in the response")
+	}
+	id, err := strconv.ParseInt(path.Base(location), 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid location %s", location)
+	}
+	return id, nil
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harbor
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// replication execution status
+const (
+	ExecutionStatusInProgress = "InProgress"
+	ExecutionStatusSucceed    = "Succeed"
+	ExecutionStatusFailed     = "Failed"
+	ExecutionStatusStopped    = "Stopped"
+)
+
+type ReplicationPolicy struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+}
+
+type ReplicationExecution struct {
+	ID         int64  `json:"id"`
+	PolicyID   int64  `json:"policy_id"`
+	Status     string `json:"status"`
+	StatusText string `json:"status_text"`
+	Total      int    `json:"total"`
+	Failed     int    `json:"failed"`
+	Succeed    int    `json:"succeed"`
+	InProgress int    `json:"in_progress"`
+	Stopped    int    `json:"stopped"`
+}
+
+func (c *Client) ListReplicationPolicies(name string) ([]*ReplicationPolicy, error) {
+	resp := make([]*ReplicationPolicy, 0)
+	_, err := c.R().SetQueryParam("name", name).SetQueryParam("page_size", "100").SetSuccessResult(&resp).
+		Get(c.BaseURL + "/replication/policies")
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// StartReplication starts an execution of the replication policy and returns the id of the execution
+func (c *Client) StartReplication(policyID int64) (int64, error) {
+	resp, err := c.R().SetBodyJsonMarshal(map[string]int64{"policy_id": policyID}).
+		Post(c.BaseURL + "/replication/executions")
+	if err != nil {
+		return 0, err
+	}
+	return getCreatedID(resp)
+}
+
+func (c *Client) GetReplicationExecution(executionID int64) (*ReplicationExecution, error) {
+	resp := new(ReplicationExecution)
+	_, err := c.R().SetSuccessResult(resp).
+		Get(fmt.Sprintf("%s/replication/executions/%d", c.BaseURL, executionID))
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *Client) StopReplicationExecution(executionID int64) error {
+	_, err := c.R().Put(fmt.Sprintf("%s/replication/executions/%d", c.BaseURL, executionID))
+	return errors.Wrapf(err, "stop replication execution %d", executionID)
+}
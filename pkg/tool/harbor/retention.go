@@ -0,0 +1,85 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package harbor
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// retention execution status
+const (
+	RetentionStatusRunning = "Running"
+	RetentionStatusSucceed = "Succeed"
+	RetentionStatusFailed  = "Failed"
+	RetentionStatusStopped = "Stopped"
+)
+
+type Project struct {
+	ProjectID int64             `json:"project_id"`
+	Name      string            `json:"name"`
+	Metadata  map[string]string `json:"metadata"`
+}
+
+type RetentionExecution struct {
+	ID       int64  `json:"id"`
+	PolicyID int64  `json:"policy_id"`
+	Status   string `json:"status"`
+	Trigger  string `json:"trigger"`
+	DryRun   bool   `json:"dry_run"`
+}
+
+// GetProjectRetentionID returns the id of the retention policy of the project
+func (c *Client) GetProjectRetentionID(projectName string) (int64, error) {
+	project := new(Project)
+	_, err := c.R().SetSuccessResult(project).Get(fmt.Sprintf("%s/projects/%s", c.BaseURL, projectName))
+	if err != nil {
+		return 0, err
+	}
+	retentionID, ok := project.Metadata["retention_id"]
+	if !ok || retentionID == "" {
+		return 0, errors.Errorf("no retention policy found in project %s", projectName)
+	}
+	return strconv.ParseInt(retentionID, 10, 64)
+}
+
+// StartRetention runs the retention policy and returns the id of the execution
+func (c *Client) StartRetention(retentionID int64, dryRun bool) (int64, error) {
+	resp, err := c.R().SetBodyJsonMarshal(map[string]bool{"dry_run": dryRun}).
+		Post(fmt.Sprintf("%s/retentions/%d/executions", c.BaseURL, retentionID))
+	if err != nil {
+		return 0, err
+	}
+	return getCreatedID(resp)
+}
+
+func (c *Client) GetRetentionExecution(retentionID, executionID int64) (*RetentionExecution, error) {
+	executions := make([]*RetentionExecution, 0)
+	_, err := c.R().SetQueryParam("page_size", "100").SetSuccessResult(&executions).
+		Get(fmt.Sprintf("%s/retentions/%d/executions", c.BaseURL, retentionID))
+	if err != nil {
+		return nil, err
+	}
+	for _, execution := range executions {
+		if execution.ID == executionID {
+			return execution, nil
+		}
+	}
+	return nil, errors.Errorf("retention execution %d not found", executionID)
+}
@@ -40,6 +40,7 @@ import (
 	"helm.sh/helm/v3/pkg/cli"
 	"helm.sh/helm/v3/pkg/downloader"
 	"helm.sh/helm/v3/pkg/plugin"
+	"helm.sh/helm/v3/pkg/registry"
 	"helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/releaseutil"
 	"helm.sh/helm/v3/pkg/repo"
@@ -108,6 +109,11 @@ func NewClient() (*HelmClient, error) {
 	}, nil
 }
 
+// KubeClient returns the kube client of the cluster, it is only initialized for the clients created by NewClientFromNamespace
+func (hClient *HelmClient) KubeClient() client.Client {
+	return hClient.kubeClient
+}
+
 // NewClientFromNamespace returns a new Helm client constructed with the provided clusterID and namespace
 // a kubeClient will be initialized to support necessary k8s operations when install/upgrade helm charts
 func NewClientFromNamespace(clusterID, namespace string) (*HelmClient, error) {
@@ -462,10 +468,13 @@ func (hClient *HelmClient) getChart(chartName string, chartPathOptions *action.C
 	return helmChart, chartPath, err
 }
 
-func (hClient *HelmClient) installChart(ctx context.Context, spec *hc.ChartSpec) (*release.Release, error) {
+func (hClient *HelmClient) installChart(ctx context.Context, spec *hc.ChartSpec, opts *hc.GenericHelmOptions) (*release.Release, error) {
 	c := hClient.HelmClient
 	install := action.NewInstall(c.ActionConfig)
 	mergeInstallOptions(spec, install)
+	if opts != nil && opts.PostRenderer != nil {
+		install.PostRenderer = opts.PostRenderer
+	}
 
 	if install.Version == "" {
 		install.Version = ">0.0.0-0"
@@ -518,10 +527,13 @@ func (hClient *HelmClient) installChart(ctx context.Context, spec *hc.ChartSpec)
 	return rel, nil
 }
 
-func (hClient *HelmClient) upgradeChart(ctx context.Context, spec *hc.ChartSpec) (*release.Release, error) {
+func (hClient *HelmClient) upgradeChart(ctx context.Context, spec *hc.ChartSpec, opts *hc.GenericHelmOptions) (*release.Release, error) {
 	c := hClient.HelmClient
 	upgrade := action.NewUpgrade(c.ActionConfig)
 	mergeUpgradeOptions(spec, upgrade)
+	if opts != nil && opts.PostRenderer != nil {
+		upgrade.PostRenderer = opts.PostRenderer
+	}
 
 	if upgrade.Version == "" {
 		upgrade.Version = ">0.0.0-0"
@@ -567,9 +579,9 @@ func (hClient *HelmClient) InstallOrUpgradeChart(ctx context.Context, spec *hc.C
 	}
 
 	if install {
-		return hClient.installChart(ctx, spec)
+		return hClient.installChart(ctx, spec, opts)
 	} else {
-		return hClient.upgradeChart(ctx, spec)
+		return hClient.upgradeChart(ctx, spec, opts)
 	}
 }
 
@@ -608,9 +620,57 @@ func (hClient *HelmClient) UpdateChartRepo(repoEntry *repo.Entry) (string, error
 	return indexFilePath, err
 }
 
+// IsOCIRepo returns true if the chart repo is an OCI registry, e.g. oci://registry.example.com/charts
+func IsOCIRepo(repoEntry *repo.Entry) bool {
+	return repoEntry != nil && registry.IsOCI(repoEntry.URL)
+}
+
+// ociChartRef converts a chart reference in the form of `repoName/chartName` to `oci://host/path/chartName`
+func ociChartRef(repoEntry *repo.Entry, chartRef string) string {
+	chartName := strings.TrimPrefix(chartRef, repoEntry.Name+"/")
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(repoEntry.URL, "/"), chartName)
+}
+
+// newRegistryClient creates a registry client for OCI registries, login is performed if credentials are provided
+func (hClient *HelmClient) newRegistryClient(repoEntry *repo.Entry) (*registry.Client, error) {
+	registryClient, err := registry.NewClient(
+		registry.ClientOptCredentialsFile(hClient.Settings.RegistryConfig),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create registry client, err: %w", err)
+	}
+	if repoEntry.Username == "" && repoEntry.Password == "" {
+		return registryClient, nil
+	}
+
+	repoUrl, err := url.Parse(repoEntry.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse repo url: %s, err: %w", repoEntry.URL, err)
+	}
+	if err = registryClient.Login(repoUrl.Host, registry.LoginOptBasicAuth(repoEntry.Username, repoEntry.Password)); err != nil {
+		return nil, fmt.Errorf("failed to login registry: %s, err: %w", repoUrl.Host, err)
+	}
+	return registryClient, nil
+}
+
+// ListOCIChartVersions lists all semver compliant versions of the chart in OCI registry, sorted in descending order
+func (hClient *HelmClient) ListOCIChartVersions(repoEntry *repo.Entry, chartName string) ([]string, error) {
+	hClient.lock.Lock()
+	defer hClient.lock.Unlock()
+	registryClient, err := hClient.newRegistryClient(repoEntry)
+	if err != nil {
+		return nil, err
+	}
+	return registryClient.Tags(strings.TrimPrefix(ociChartRef(repoEntry, chartName), fmt.Sprintf("%s://", registry.OCIScheme)))
+}
+
 // FetchIndexYaml fetch index.yaml from remote chart repo
 // `helm repo add` and `helm repo update` will be executed
+// OCI registries have no index.yaml, use ListOCIChartVersions instead
 func (hClient *HelmClient) FetchIndexYaml(repoEntry *repo.Entry) (*repo.IndexFile, error) {
+	if IsOCIRepo(repoEntry) {
+		return nil, fmt.Errorf("index.yaml is not available for OCI registry: %s", repoEntry.URL)
+	}
 	hClient.lock.Lock()
 	defer hClient.lock.Unlock()
 	indexFilePath, err := hClient.UpdateChartRepo(repoEntry)
@@ -623,14 +683,16 @@ func (hClient *HelmClient) FetchIndexYaml(repoEntry *repo.Entry) (*repo.IndexFil
 }
 
 // DownloadChart works like executing `helm pull repoName/chartName --version=version'
-// since pulling from OCI Registry is still considered as an EXPERIMENTAL feature
-// we DO NOT support pulling charts by pulling OCI Artifacts from OCI Registry
+// for OCI registries it works like executing `helm pull oci://host/path/chartName --version=version'
 // NOTE consider using os.execCommand('helm pull') to reduce code complexity of offering compatibility since third-party plugins CANNOT be used as SDK
 // if unTar is true, no need to mkdir for destDir
 // if unTar is no, your need to mkdir for destDir yourself
 func (hClient *HelmClient) DownloadChart(repoEntry *repo.Entry, chartRef string, chartVersion string, destDir string, unTar bool) error {
 	hClient.lock.Lock()
 	defer hClient.lock.Unlock()
+	if IsOCIRepo(repoEntry) {
+		return hClient.downloadOCIChart(repoEntry, chartRef, chartVersion, destDir, unTar)
+	}
 	_, err := hClient.UpdateChartRepo(repoEntry)
 	if err != nil {
 		return nil
@@ -647,6 +709,34 @@ func (hClient *HelmClient) DownloadChart(repoEntry *repo.Entry, chartRef string,
 	return err
 }
 
+func (hClient *HelmClient) downloadOCIChart(repoEntry *repo.Entry, chartRef string, chartVersion string, destDir string, unTar bool) error {
+	registryClient, err := hClient.newRegistryClient(repoEntry)
+	if err != nil {
+		return err
+	}
+	pull := action.NewPullWithOpts(action.WithConfig(&action.Configuration{RegistryClient: registryClient}))
+	pull.Version = chartVersion
+	pull.Settings = generalSettings
+	pull.DestDir = destDir
+	pull.UntarDir = destDir
+	pull.Untar = unTar
+	_, err = pull.Run(ociChartRef(repoEntry, chartRef))
+	return err
+}
+
+func (hClient *HelmClient) pushOCIChart(repoEntry *repo.Entry, chartPath string) error {
+	registryClient, err := hClient.newRegistryClient(repoEntry)
+	if err != nil {
+		return err
+	}
+	push := action.NewPushWithOpts(action.WithPushConfig(&action.Configuration{RegistryClient: registryClient}))
+	push.Settings = generalSettings
+	if _, err = push.Run(chartPath, strings.TrimSuffix(repoEntry.URL, "/")); err != nil {
+		return fmt.Errorf("failed to push chart: %s to OCI registry, error: %w", chartPath, err)
+	}
+	return nil
+}
+
 func (hClient *HelmClient) pushAcrChart(repoEntry *repo.Entry, chartPath string) error {
 	base := filepath.Join(hClient.Settings.PluginsDirectory, "helm-acr")
 	prog := exec.Command(filepath.Join(base, "bin/helm-cm-push"), chartPath, repoEntry.Name)
@@ -713,6 +803,9 @@ func handlePushResponse(resp *http.Response) error {
 func (hClient *HelmClient) PushChart(repoEntry *repo.Entry, chartPath string) error {
 	hClient.lock.Lock()
 	defer hClient.lock.Unlock()
+	if IsOCIRepo(repoEntry) {
+		return hClient.pushOCIChart(repoEntry, chartPath)
+	}
 	_, err := hClient.UpdateChartRepo(repoEntry)
 	if err != nil {
 		return nil
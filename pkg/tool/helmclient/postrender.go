@@ -0,0 +1,114 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package helmclient
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+
+	"sigs.k8s.io/kustomize/api/krusty"
+	kustomizetypes "sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	"sigs.k8s.io/kustomize/kyaml/resid"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	kustomizeBase          = "/kustomize"
+	kustomizeResourcesFile = "resources.yaml"
+)
+
+// KustomizePostRenderer applies kustomize patches to the manifests rendered by helm, works like `helm upgrade --post-renderer`
+// it enables last-mile overrides of the rendered manifests without forking the charts
+type KustomizePostRenderer struct {
+	patches []kustomizetypes.Patch
+}
+
+// NewKustomizePostRenderer creates a post-renderer with strategic merge patches or json 6902 patches
+// strategic merge patches without target are only applied to the resources matching their kind and name
+func NewKustomizePostRenderer(patches []kustomizetypes.Patch) (*KustomizePostRenderer, error) {
+	ret := &KustomizePostRenderer{}
+	for _, patch := range patches {
+		if patch.Target == nil {
+			target, err := getPatchTarget(patch.Patch)
+			if err != nil {
+				return nil, err
+			}
+			patch.Target = target
+		}
+		ret.patches = append(ret.patches, patch)
+	}
+	return ret, nil
+}
+
+// getPatchTarget uses the kind and name of the strategic merge patch as the target, so the patch won't fail
+// if there are no matched resources in the release
+func getPatchTarget(patch string) (*kustomizetypes.Selector, error) {
+	obj := &struct {
+		Kind     string `json:"kind"`
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+	}{}
+	if err := yaml.Unmarshal([]byte(patch), obj); err != nil {
+		return nil, fmt.Errorf("failed to parse kustomize patch, a target is required for json 6902 patches, err: %s", err)
+	}
+	if obj.Kind == "" || obj.Metadata.Name == "" {
+		return nil, fmt.Errorf("kind and metadata.name are required in strategic merge patch without target")
+	}
+	return &kustomizetypes.Selector{
+		ResId: resid.ResId{
+			Gvk:  resid.Gvk{Kind: obj.Kind},
+			Name: obj.Metadata.Name,
+		},
+	}, nil
+}
+
+func (r *KustomizePostRenderer) Run(renderedManifests *bytes.Buffer) (*bytes.Buffer, error) {
+	if len(r.patches) == 0 {
+		return renderedManifests, nil
+	}
+
+	kustomization, err := yaml.Marshal(map[string]interface{}{
+		"apiVersion": kustomizetypes.KustomizationVersion,
+		"kind":       kustomizetypes.KustomizationKind,
+		"resources":  []string{kustomizeResourcesFile},
+		"patches":    r.patches,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate kustomization, err: %s", err)
+	}
+
+	fSys := filesys.MakeFsInMemory()
+	if err = fSys.WriteFile(filepath.Join(kustomizeBase, kustomizeResourcesFile), renderedManifests.Bytes()); err != nil {
+		return nil, err
+	}
+	if err = fSys.WriteFile(filepath.Join(kustomizeBase, "kustomization.yaml"), kustomization); err != nil {
+		return nil, err
+	}
+
+	resMap, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(fSys, kustomizeBase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run kustomize post-renderer, err: %s", err)
+	}
+	out, err := resMap.AsYaml()
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewBuffer(out), nil
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package httpclient
 
 import (
+	"context"
 	"net/http"
 	"net/url"
 
@@ -31,6 +32,12 @@ func SetResult(res interface{}) RequestFunc {
 	}
 }
 
+func SetContext(ctx context.Context) RequestFunc {
+	return func(r *resty.Request) {
+		r.SetContext(ctx)
+	}
+}
+
 func SetBody(body interface{}) RequestFunc {
 	return func(r *resty.Request) {
 		r.SetBody(body)
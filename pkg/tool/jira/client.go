@@ -21,6 +21,7 @@ import (
 
 	"github.com/koderover/zadig/pkg/microservice/aslan/config"
 	"github.com/koderover/zadig/pkg/tool/log"
+	"github.com/koderover/zadig/pkg/tool/truststore"
 )
 
 // Client is jira RPC client
@@ -54,6 +55,10 @@ func NewJiraClientWithBasicAuth(host, username, password string) *Client {
 		}),
 	}
 
+	if tlsConfig := truststore.TLSConfig(truststore.IntegrationJira); tlsConfig != nil {
+		c.Client.SetTLSClientConfig(tlsConfig)
+	}
+
 	c.Issue = &IssueService{client: c}
 	c.Project = &ProjectService{client: c}
 	c.Board = &BoardService{client: c}
@@ -71,6 +76,10 @@ func NewJiraClientWithBearerToken(host, token string) *Client {
 		}),
 	}
 
+	if tlsConfig := truststore.TLSConfig(truststore.IntegrationJira); tlsConfig != nil {
+		c.Client.SetTLSClientConfig(tlsConfig)
+	}
+
 	c.Issue = &IssueService{client: c}
 	c.Project = &ProjectService{client: c}
 	c.Board = &BoardService{client: c}
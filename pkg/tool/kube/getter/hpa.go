@@ -0,0 +1,37 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package getter
+
+import (
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func ListHorizontalPodAutoscalers(ns string, selector labels.Selector, cl client.Client) ([]*autoscalingv2.HorizontalPodAutoscaler, error) {
+	ss := &autoscalingv2.HorizontalPodAutoscalerList{}
+	err := ListResourceInCache(ns, selector, nil, ss, cl)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []*autoscalingv2.HorizontalPodAutoscaler
+	for i := range ss.Items {
+		res = append(res, &ss.Items[i])
+	}
+	return res, err
+}
@@ -19,6 +19,7 @@ package updater
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"text/template"
 	"time"
@@ -90,6 +91,20 @@ func UpdateDeploymentImage(ns, name, container, image string, cl client.Client)
 	return PatchDeployment(ns, name, patchBytes, cl)
 }
 
+// UpdateDeploymentStrategy patches the spec.strategy of the deployment, it takes effect from the next rollout
+func UpdateDeploymentStrategy(ns, name string, strategy map[string]interface{}, cl client.Client) error {
+	patchBytes, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"strategy": strategy,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	return PatchDeployment(ns, name, patchBytes, cl)
+}
+
 func ScaleDeployment(ns, name string, replicas int, cl client.Client) error {
 	patchBytes := []byte(fmt.Sprintf(`{"spec":{"replicas": %d}}`, replicas))
 	return PatchDeployment(ns, name, patchBytes, cl)
@@ -0,0 +1,60 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+const buildRoot = "/kustomize"
+
+// Build works like executing `kustomize build dir`, all the files in fsys are loaded into memory
+// so the overlays can refer to the bases with relative paths
+func Build(fsys fs.FS, dir string) (string, error) {
+	memFs := filesys.MakeFsInMemory()
+	err := fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return memFs.MkdirAll(filepath.Join(buildRoot, path))
+		}
+		content, err := fs.ReadFile(fsys, path)
+		if err != nil {
+			return err
+		}
+		return memFs.WriteFile(filepath.Join(buildRoot, path), content)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to load kustomize files, err: %s", err)
+	}
+
+	resMap, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(memFs, filepath.Join(buildRoot, dir))
+	if err != nil {
+		return "", fmt.Errorf("failed to build kustomization %s, err: %s", dir, err)
+	}
+	out, err := resMap.AsYaml()
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
@@ -18,16 +18,19 @@ package llm
 import (
 	"context"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"fmt"
 
 	"github.com/koderover/zadig/pkg/tool/cache"
+	"github.com/koderover/zadig/pkg/tool/log"
 )
 
 var (
 	clients = map[string]ILLM{
 		"openai":      &OpenAIClient{},
 		"azureopenai": &OpenAIClient{},
+		"ollama":      &OllamaClient{},
 	}
 )
 
@@ -38,6 +41,11 @@ type ILLM interface {
 	GetName() string
 }
 
+// Register adds a llm provider, it makes it possible to plug in other self-hosted models
+func Register(provider string, client ILLM) {
+	clients[provider] = client
+}
+
 func NewClient(provider string) (ILLM, error) {
 	if c, ok := clients[provider]; !ok {
 		return nil, fmt.Errorf("provider %s not supported", provider)
@@ -86,3 +94,38 @@ func GetCacheKey(provider string, sEnc string) string {
 
 	return hex.EncodeToString(hash[:])
 }
+
+func parseWithCache(ctx context.Context, client ILLM, prompt string, cache cache.ICache, options ...ParamOption) (string, error) {
+	// Check for cached data
+	cacheKey := GetCacheKey(client.GetName(), prompt)
+
+	if !cache.IsCacheDisabled() && cache.Exists(cacheKey) {
+		response, err := cache.Load(cacheKey)
+		if err != nil {
+			return "", err
+		}
+
+		if response != "" {
+			output, err := base64.StdEncoding.DecodeString(response)
+			if err != nil {
+				log.Errorf("error decoding cached data: %v", err)
+				return "", nil
+			}
+			return string(output), nil
+		}
+	}
+
+	response, err := client.GetCompletion(ctx, prompt, options...)
+	if err != nil {
+		return "", err
+	}
+
+	err = cache.Store(cacheKey, base64.StdEncoding.EncodeToString([]byte(response)))
+
+	if err != nil {
+		log.Errorf("error storing value to cache: %v", err)
+		return "", nil
+	}
+
+	return response, nil
+}
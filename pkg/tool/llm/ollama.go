@@ -0,0 +1,170 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/koderover/zadig/pkg/tool/cache"
+)
+
+const (
+	DefaultOllamaBaseURL = "http://localhost:11434"
+	DefaultOllamaModel   = "llama3"
+)
+
+// OllamaClient talks to a self-hosted model served by ollama, so that the
+// llm features can be used without sending any data out of the cluster.
+type OllamaClient struct {
+	name    string
+	model   string
+	token   string
+	baseURL string
+	client  *http.Client
+}
+
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ollamaChatRequest struct {
+	Model    string                 `json:"model"`
+	Messages []ollamaMessage        `json:"messages"`
+	Stream   bool                   `json:"stream"`
+	Options  map[string]interface{} `json:"options,omitempty"`
+}
+
+type ollamaChatResponse struct {
+	Message ollamaMessage `json:"message"`
+	Error   string        `json:"error"`
+}
+
+func (c *OllamaClient) Configure(config LLMConfig) error {
+	c.baseURL = strings.TrimSuffix(config.GetBaseURL(), "/")
+	if c.baseURL == "" {
+		c.baseURL = DefaultOllamaBaseURL
+	}
+	if _, err := url.Parse(c.baseURL); err != nil {
+		return fmt.Errorf("invalid base url %s", c.baseURL)
+	}
+
+	c.client = &http.Client{Timeout: 5 * time.Minute}
+	if config.GetProxy() != "" {
+		proxyUrl, err := url.Parse(config.GetProxy())
+		if err != nil {
+			return fmt.Errorf("invalid proxy url %s", config.GetProxy())
+		}
+		c.client.Transport = &http.Transport{
+			Proxy: http.ProxyURL(proxyUrl),
+		}
+	}
+
+	c.name = config.GetName()
+	c.model = config.GetModel()
+	// the token is optional, it is only sent when ollama is exposed behind an authenticating proxy
+	c.token = config.GetToken()
+	return nil
+}
+
+func (c *OllamaClient) GetCompletion(ctx context.Context, prompt string, options ...ParamOption) (string, error) {
+	opts := ParamOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	opts = ValidOptions(opts)
+
+	// model names of the openai api are meaningless for ollama, only the configured model is used
+	model := c.model
+	if model == "" {
+		model = DefaultOllamaModel
+	}
+
+	modelOptions := map[string]interface{}{}
+	if opts.Temperature != 0 {
+		modelOptions["temperature"] = opts.Temperature
+	}
+	if opts.MaxTokens != 0 {
+		modelOptions["num_predict"] = opts.MaxTokens
+	}
+	if len(opts.StopWords) > 0 {
+		modelOptions["stop"] = opts.StopWords
+	}
+
+	body, err := json.Marshal(&ollamaChatRequest{
+		Model: model,
+		Messages: []ollamaMessage{
+			{
+				Role:    "user",
+				Content: prompt,
+			},
+		},
+		Options: modelOptions,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/chat", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("create chat completion failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("read chat completion response failed: %v", err)
+	}
+
+	result := new(ollamaChatResponse)
+	if err := json.Unmarshal(respBody, result); err != nil {
+		return "", fmt.Errorf("create chat completion failed, status: %d, response: %s", resp.StatusCode, string(respBody))
+	}
+	if resp.StatusCode != http.StatusOK || result.Error != "" {
+		return "", fmt.Errorf("create chat completion failed, status: %d, error: %s", resp.StatusCode, result.Error)
+	}
+
+	return result.Message.Content, nil
+}
+
+func (c *OllamaClient) Parse(ctx context.Context, prompt string, cache cache.ICache, options ...ParamOption) (string, error) {
+	return parseWithCache(ctx, c, prompt, cache, options...)
+}
+
+func (c *OllamaClient) GetName() string {
+	if c.name == "" {
+		return "ollama"
+	}
+	return c.name
+}
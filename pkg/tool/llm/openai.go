@@ -17,7 +17,6 @@ package llm
 
 import (
 	"context"
-	"encoding/base64"
 	"errors"
 	"fmt"
 	"net/http"
@@ -58,6 +57,10 @@ func (c *OpenAIClient) Configure(config LLMConfig) error {
 		// config.GetAPIType() == "OPEN_AI"
 		c.apiType = "OPEN_AI"
 		defaultConfig = openai.DefaultConfig(token)
+		// a custom base url points to a self-hosted service compatible with the openai api, e.g. vLLM or LocalAI
+		if config.GetBaseURL() != "" {
+			defaultConfig.BaseURL = config.GetBaseURL()
+		}
 	}
 
 	if config.GetProxy() != "" {
@@ -137,38 +140,7 @@ func (c *OpenAIClient) GetCompletion(ctx context.Context, prompt string, options
 }
 
 func (a *OpenAIClient) Parse(ctx context.Context, prompt string, cache cache.ICache, options ...ParamOption) (string, error) {
-	// Check for cached data
-	cacheKey := GetCacheKey(a.GetName(), prompt)
-
-	if !cache.IsCacheDisabled() && cache.Exists(cacheKey) {
-		response, err := cache.Load(cacheKey)
-		if err != nil {
-			return "", err
-		}
-
-		if response != "" {
-			output, err := base64.StdEncoding.DecodeString(response)
-			if err != nil {
-				log.Errorf("error decoding cached data: %v", err)
-				return "", nil
-			}
-			return string(output), nil
-		}
-	}
-
-	response, err := a.GetCompletion(ctx, prompt, options...)
-	if err != nil {
-		return "", err
-	}
-
-	err = cache.Store(cacheKey, base64.StdEncoding.EncodeToString([]byte(response)))
-
-	if err != nil {
-		log.Errorf("error storing value to cache: %v", err)
-		return "", nil
-	}
-
-	return response, nil
+	return parseWithCache(ctx, a, prompt, cache, options...)
 }
 
 func (a *OpenAIClient) GetName() string {
@@ -55,6 +55,28 @@ var (
 		},
 	)
 
+	QueueWaitSeconds = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "queue_wait_seconds",
+			Help: "The longest time in seconds a workflow task has waited in the queue",
+		},
+	)
+
+	QueueWaitAlerts = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "queue_wait_alerts_total",
+			Help: "Number of alerts fired for workflow tasks waiting in the queue too long",
+		},
+	)
+
+	WebhookRateLimited = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "webhook_rate_limited_total",
+			Help: "Number of inbound webhook requests rejected by the rate limits",
+		},
+		[]string{"source"},
+	)
+
 	RequestTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "request_total",
@@ -97,6 +119,14 @@ func SetPendingWorkflows(value int64) {
 	PendingWorkflows.Set(float64(value))
 }
 
+func SetQueueWaitSeconds(value int64) {
+	QueueWaitSeconds.Set(float64(value))
+}
+
+func IncWebhookRateLimited(source string) {
+	WebhookRateLimited.WithLabelValues(source).Inc()
+}
+
 func RegisterRequest(startTime int64, method, handler string, status int) {
 	RequestTotal.WithLabelValues(method, handler, fmt.Sprintf("%d", status)).Inc()
 	ResponseTime.WithLabelValues(method, handler, fmt.Sprintf("%d", status)).Observe(float64(time.Now().UnixMilli()-startTime) / 1000)
@@ -60,7 +60,7 @@ func Init(ctx context.Context, uri string) {
 		if err != nil {
 			log.Fatalf("Failed to initialize mongo db connection, err: %v", err)
 		}
-		opt := options.Client().ApplyURI(uri).SetRegistry(reg)
+		opt := options.Client().ApplyURI(uri).SetRegistry(reg).SetMonitor(newSlowQueryMonitor())
 		// By default the client will discover the mongodb cluster topology (if exists) and try to
 		// connect to ALL hosts in the cluster.
 		// If NONE of the host is discoverable by its host name (private network host name),
@@ -0,0 +1,91 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// IndexDrift is the difference between the indexes defined in the code and the ones in the collection,
+// the indexes are identified by their keys, e.g. project_1_name_1
+type IndexDrift struct {
+	Collection string
+	Missing    []string
+	Unexpected []string
+}
+
+func (d *IndexDrift) HasDrift() bool {
+	return len(d.Missing) > 0 || len(d.Unexpected) > 0
+}
+
+// IndexKeyName returns the keys of the index in the format of the default index name
+func IndexKeyName(keys interface{}) string {
+	parts := make([]string, 0)
+	switch k := keys.(type) {
+	case bson.D:
+		for _, e := range k {
+			parts = append(parts, fmt.Sprintf("%s_%v", e.Key, e.Value))
+		}
+	case bson.M:
+		for key, value := range k {
+			parts = append(parts, fmt.Sprintf("%s_%v", key, value))
+		}
+		sort.Strings(parts)
+	}
+	return strings.Join(parts, "_")
+}
+
+// AuditIndexes compares the indexes of the collection with the expected ones, the _id index is ignored
+func AuditIndexes(ctx context.Context, collection string, view mongo.IndexView, expected []mongo.IndexModel) (*IndexDrift, error) {
+	cursor, err := view.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	indexes := make([]struct {
+		Key bson.D `bson:"key"`
+	}, 0)
+	if err := cursor.All(ctx, &indexes); err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]bool)
+	for _, index := range indexes {
+		existing[IndexKeyName(index.Key)] = true
+	}
+	expectedKeys := make(map[string]bool)
+	drift := &IndexDrift{Collection: collection}
+	for _, model := range expected {
+		key := IndexKeyName(model.Keys)
+		expectedKeys[key] = true
+		if !existing[key] {
+			drift.Missing = append(drift.Missing, key)
+		}
+	}
+	for key := range existing {
+		if key != "_id_1" && !expectedKeys[key] {
+			drift.Unexpected = append(drift.Unexpected, key)
+		}
+	}
+	sort.Strings(drift.Unexpected)
+	return drift, nil
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+
+	"github.com/koderover/zadig/pkg/tool/log"
+)
+
+const slowQueryCommandLimit = 512
+
+var (
+	readPreference     *readpref.ReadPref
+	queryTimeout       time.Duration
+	slowQueryThreshold time.Duration
+	// startedCommands keeps the started commands by the request id for the slow query log
+	startedCommands sync.Map
+)
+
+type QueryOptions struct {
+	// ReadPreference is used by the heavy reads such as the lists and the statistics, e.g. secondaryPreferred,
+	// the reads go to the primary if it is empty
+	ReadPreference string
+	// Timeout is the max time of the queries using QueryContext, no limit if it is 0
+	Timeout time.Duration
+	// SlowQueryThreshold logs the commands slower than it, disabled if it is 0
+	SlowQueryThreshold time.Duration
+}
+
+// SetQueryOptions should be called before the queries are made
+func SetQueryOptions(opt *QueryOptions) error {
+	if opt.ReadPreference != "" {
+		mode, err := readpref.ModeFromString(opt.ReadPreference)
+		if err != nil {
+			return fmt.Errorf("invalid read preference %s: %s", opt.ReadPreference, err)
+		}
+		if readPreference, err = readpref.New(mode); err != nil {
+			return fmt.Errorf("invalid read preference %s: %s", opt.ReadPreference, err)
+		}
+	}
+	queryTimeout = opt.Timeout
+	slowQueryThreshold = opt.SlowQueryThreshold
+	return nil
+}
+
+// ReadDatabase returns the database using the read preference of the heavy reads so that they do not
+// compete with the writes on the primary
+func ReadDatabase(name string) *mongo.Database {
+	if readPreference == nil {
+		return Database(name)
+	}
+	return Client().Database(name, options.Database().SetReadPreference(readPreference))
+}
+
+// QueryContext limits the query by the query timeout
+func QueryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, queryTimeout)
+}
+
+func newSlowQueryMonitor() *event.CommandMonitor {
+	finish := func(e event.CommandFinishedEvent, failure string) {
+		command, ok := startedCommands.LoadAndDelete(e.RequestID)
+		if !ok {
+			return
+		}
+		duration := time.Duration(e.DurationNanos)
+		if slowQueryThreshold <= 0 || duration < slowQueryThreshold {
+			return
+		}
+		log.Warnf("slow mongo query %s took %s, failure: %s, %s", e.CommandName, duration, failure, command)
+	}
+
+	return &event.CommandMonitor{
+		Started: func(_ context.Context, e *event.CommandStartedEvent) {
+			if slowQueryThreshold <= 0 {
+				return
+			}
+			startedCommands.Store(e.RequestID, commandSummary(e.CommandName, e.Command))
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			finish(e.CommandFinishedEvent, "")
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			finish(e.CommandFinishedEvent, e.Failure)
+		},
+	}
+}
+
+// slowQuerySkippedFields are the fields of the commands which are not about the shape of the query
+var slowQuerySkippedFields = map[string]bool{"lsid": true, "$clusterTime": true, "$db": true, "txnNumber": true, "$readPreference": true}
+
+// commandSummary returns the collection and the shape of the command for the slow query log, the values are
+// replaced by ? since the commands may carry the secrets and the personal data in the documents
+func commandSummary(name string, command bson.Raw) string {
+	collection := ""
+	if value, err := command.LookupErr(name); err == nil {
+		collection, _ = value.StringValueOK()
+	}
+	elements, err := command.Elements()
+	if err != nil {
+		return fmt.Sprintf("collection: %s", collection)
+	}
+	fields := make([]string, 0, len(elements))
+	for _, element := range elements {
+		key := element.Key()
+		if key == name || slowQuerySkippedFields[key] {
+			continue
+		}
+		fields = append(fields, key+": "+valueShape(element.Value()))
+	}
+	shape := "{" + strings.Join(fields, ", ") + "}"
+	if len(shape) > slowQueryCommandLimit {
+		shape = shape[:slowQueryCommandLimit] + "..."
+	}
+	return fmt.Sprintf("collection: %s, shape: %s", collection, shape)
+}
+
+// valueShape keeps the keys of the documents and the first element of the arrays, the scalar values are redacted
+func valueShape(value bson.RawValue) string {
+	switch value.Type {
+	case bsontype.EmbeddedDocument:
+		elements, err := value.Document().Elements()
+		if err != nil {
+			return "?"
+		}
+		fields := make([]string, 0, len(elements))
+		for _, element := range elements {
+			fields = append(fields, element.Key()+": "+valueShape(element.Value()))
+		}
+		return "{" + strings.Join(fields, ", ") + "}"
+	case bsontype.Array:
+		values, err := value.Array().Values()
+		if err != nil || len(values) == 0 {
+			return "[]"
+		}
+		if len(values) == 1 {
+			return "[" + valueShape(values[0]) + "]"
+		}
+		return fmt.Sprintf("[%s, ...%d]", valueShape(values[0]), len(values))
+	default:
+		return "?"
+	}
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package pluginsdk helps writing the images of the zadig plugins. The descriptor of a plugin maps its inputs to
+// environment variables, the plugin reads them with Input and saves the declared outputs with SetOutput so that
+// the jobs after it can refer to them as {{.job.<job name>.output.<output name>}}.
+package pluginsdk
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/koderover/zadig/pkg/types/job"
+)
+
+// Input returns the value of the environment variable the descriptor maps an input to
+func Input(env string) string {
+	return os.Getenv(env)
+}
+
+// MustInput is Input failing the job if the value is empty
+func MustInput(env string) string {
+	value := os.Getenv(env)
+	if value == "" {
+		Fail("%s is required", env)
+	}
+	return value
+}
+
+// SetOutput saves an output declared in the descriptor, the ones not declared are ignored
+func SetOutput(name, value string) error {
+	if err := os.MkdirAll(job.JobOutputDir, 0755); err != nil {
+		return fmt.Errorf("create output dir error: %v", err)
+	}
+	return os.WriteFile(path.Join(job.JobOutputDir, name), []byte(value), 0644)
+}
+
+// Fail prints the message and exits with a non-zero code which fails the job
+func Fail(format string, a ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", a...)
+	os.Exit(1)
+}
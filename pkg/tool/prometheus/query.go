@@ -0,0 +1,103 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prometheus
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type QueryResponse struct {
+	Status    string    `json:"status"`
+	Data      QueryData `json:"data"`
+	ErrorType string    `json:"errorType"`
+	Error     string    `json:"error"`
+}
+
+type QueryData struct {
+	ResultType string          `json:"resultType"`
+	Result     json.RawMessage `json:"result"`
+}
+
+type VectorSample struct {
+	Metric map[string]string `json:"metric"`
+	Value  []interface{}     `json:"value"`
+}
+
+// QueryValue runs an instant query at the given time and returns the single value of the result,
+// false is returned if the result is empty. The query should be aggregated into one series if it returns a vector.
+func (c *Client) QueryValue(query string, ts time.Time) (float64, bool, error) {
+	resp := new(QueryResponse)
+	params := map[string]string{
+		"query": query,
+		"time":  strconv.FormatInt(ts.Unix(), 10),
+	}
+	_, err := c.R().SetQueryParams(params).SetSuccessResult(resp).Get(c.BaseURL + "/api/v1/query")
+	if err != nil {
+		return 0, false, err
+	}
+	if resp.Status != "success" {
+		return 0, false, errors.Errorf("query failed, %s: %s", resp.ErrorType, resp.Error)
+	}
+
+	switch resp.Data.ResultType {
+	case "scalar":
+		value := make([]interface{}, 0)
+		if err := json.Unmarshal(resp.Data.Result, &value); err != nil {
+			return 0, false, err
+		}
+		return parseSampleValue(value)
+	case "vector":
+		samples := make([]*VectorSample, 0)
+		if err := json.Unmarshal(resp.Data.Result, &samples); err != nil {
+			return 0, false, err
+		}
+		if len(samples) == 0 {
+			return 0, false, nil
+		}
+		if len(samples) > 1 {
+			return 0, false, errors.Errorf("query returns %d series, aggregate it into one series", len(samples))
+		}
+		return parseSampleValue(samples[0].Value)
+	default:
+		return 0, false, errors.Errorf("unsupported result type: %s", resp.Data.ResultType)
+	}
+}
+
+// parseSampleValue parses the sample in the format of [timestamp, "value"]
+func parseSampleValue(sample []interface{}) (float64, bool, error) {
+	if len(sample) != 2 {
+		return 0, false, errors.Errorf("invalid sample: %v", sample)
+	}
+	str, ok := sample[1].(string)
+	if !ok {
+		return 0, false, errors.Errorf("invalid sample value: %v", sample[1])
+	}
+	value, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return 0, false, errors.Wrapf(err, "invalid sample value: %s", str)
+	}
+	// NaN is returned when dividing by zero, e.g. the error rate without any request
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, false, nil
+	}
+	return value, true, nil
+}
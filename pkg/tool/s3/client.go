@@ -22,6 +22,7 @@ import (
 	"mime"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
@@ -50,6 +51,11 @@ var defaultDownloadOption = &DownloadOption{
 	RetryNum: 3,
 }
 
+type UploadOption struct {
+	// CacheControl is set as the Cache-Control header of the object if it is not empty
+	CacheControl string
+}
+
 func NewClient(endpoint, ak, sk, region string, insecure, forcedPathStyle bool) (*Client, error) {
 	creds := credentials.NewStaticCredentials(ak, sk, "")
 	config := &aws.Config{
@@ -198,6 +204,49 @@ func (c *Client) RemoveFiles(bucketName string, prefixList []string) {
 	}
 }
 
+// RemoveFilesWithPrefix removes all the files with the given prefix, unlike RemoveFiles it returns the error so
+// the caller knows whether the files are really gone
+func (c *Client) RemoveFilesWithPrefix(bucketName, prefix string) error {
+	keys := make([]string, 0)
+	input := &s3.ListObjectsInput{
+		Bucket: aws.String(bucketName),
+		Prefix: aws.String(prefix),
+	}
+	err := c.ListObjectsPages(input, func(output *s3.ListObjectsOutput, lastPage bool) bool {
+		for _, item := range output.Contents {
+			keys = append(keys, *item.Key)
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list the objects with prefix %s: %s", prefix, err)
+	}
+
+	// a DeleteObjects request takes at most 1000 keys
+	for start := 0; start < len(keys); start += 1000 {
+		end := start + 1000
+		if end > len(keys) {
+			end = len(keys)
+		}
+		ids := make([]*s3.ObjectIdentifier, 0, end-start)
+		for _, key := range keys[start:end] {
+			ids = append(ids, &s3.ObjectIdentifier{Key: aws.String(key)})
+		}
+		output, err := c.S3.DeleteObjects(&s3.DeleteObjectsInput{
+			Bucket: aws.String(bucketName),
+			Delete: &s3.Delete{Objects: ids},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete the objects with prefix %s: %s", prefix, err)
+		}
+		if len(output.Errors) > 0 {
+			return fmt.Errorf("failed to delete %d objects with prefix %s, the first error: %s",
+				len(output.Errors), prefix, aws.StringValue(output.Errors[0].Message))
+		}
+	}
+	return nil
+}
+
 func detectMimetype(path string) string {
 	fileext := filepath.Ext(path)
 	if fileext == "" {
@@ -208,10 +257,15 @@ func detectMimetype(path string) string {
 
 // Upload uploads a file from src to the bucket with the specified objectKey
 func (c *Client) Upload(bucketName, src string, objectKey string) error {
+	return c.UploadWithOption(bucketName, src, objectKey, nil)
+}
+
+func (c *Client) UploadWithOption(bucketName, src string, objectKey string, option *UploadOption) error {
 	file, err := os.OpenFile(src, os.O_RDONLY, 0600)
 	if err != nil {
 		return err
 	}
+	defer file.Close()
 	// TODO: add md5 check for file integrity
 	input := &s3.PutObjectInput{
 		Body:   file,
@@ -222,6 +276,9 @@ func (c *Client) Upload(bucketName, src string, objectKey string) error {
 	if mimetype != "" {
 		input.ContentType = &mimetype
 	}
+	if option != nil && option.CacheControl != "" {
+		input.CacheControl = aws.String(option.CacheControl)
+	}
 	_, err = c.PutObject(input)
 	return err
 }
@@ -295,3 +352,66 @@ func (c *Client) ListFiles(bucketName, prefix string, recursive bool) ([]string,
 
 	return ret, nil
 }
+
+// ListFilesModifiedBefore lists the files with given prefix which are last modified before the given time
+func (c *Client) ListFilesModifiedBefore(bucketName, prefix string, before time.Time) ([]string, error) {
+	ret := make([]string, 0)
+
+	input := &s3.ListObjectsInput{
+		Bucket: aws.String(bucketName),
+		Prefix: aws.String(prefix),
+	}
+	err := c.ListObjectsPages(input, func(output *s3.ListObjectsOutput, lastPage bool) bool {
+		for _, item := range output.Contents {
+			if item.LastModified != nil && item.LastModified.Before(before) {
+				ret = append(ret, *item.Key)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		log.Errorf("bucket [%s] listing objects with prefix [%v] failed, error: %v", bucketName, prefix, err)
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// ObjectInfo is the brief of an object in the bucket
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// ListDir lists the sub directories and the files directly under the given prefix like a file system, prefix should
+// end with a slash
+func (c *Client) ListDir(bucketName, prefix string) ([]string, []*ObjectInfo, error) {
+	dirs := make([]string, 0)
+	files := make([]*ObjectInfo, 0)
+
+	input := &s3.ListObjectsInput{
+		Bucket:    aws.String(bucketName),
+		Prefix:    aws.String(prefix),
+		Delimiter: aws.String("/"),
+	}
+	err := c.ListObjectsPages(input, func(output *s3.ListObjectsOutput, lastPage bool) bool {
+		for _, commonPrefix := range output.CommonPrefixes {
+			dirs = append(dirs, aws.StringValue(commonPrefix.Prefix))
+		}
+		for _, item := range output.Contents {
+			files = append(files, &ObjectInfo{
+				Key:          aws.StringValue(item.Key),
+				Size:         aws.Int64Value(item.Size),
+				LastModified: aws.TimeValue(item.LastModified),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		log.Errorf("bucket [%s] listing objects with prefix [%v] failed, error: %v", bucketName, prefix, err)
+		return nil, nil, err
+	}
+
+	return dirs, files, nil
+}
@@ -18,7 +18,6 @@ package sonar
 
 import (
 	"encoding/base64"
-	"errors"
 	"fmt"
 	"net/url"
 	"strings"
@@ -27,6 +26,7 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/koderover/zadig/pkg/tool/httpclient"
+	"github.com/koderover/zadig/pkg/tool/truststore"
 )
 
 type Client struct {
@@ -48,6 +48,9 @@ func NewSonarClient(host, token string) *Client {
 		httpclient.SetAuthScheme("Basic"),
 		httpclient.SetHostURL(host),
 	)
+	if tlsConfig := truststore.TLSConfig(truststore.IntegrationSonar); tlsConfig != nil {
+		c.SetTLSClientConfig(tlsConfig)
+	}
 
 	return &Client{
 		Client: c,
@@ -133,7 +136,7 @@ func (c *Client) WaitForCETaskTobeDone(taskID string, timeout time.Duration) (st
 	for {
 		select {
 		case <-timeouts:
-			return "", errors.New("sonar ce task excution timeout 10m")
+			return "", fmt.Errorf("sonar ce task excution timeout %s", timeout)
 		case <-ticker.C:
 			taskInfo, err := c.GetCETaskInfo(taskID)
 			if err != nil {
@@ -149,6 +152,51 @@ func (c *Client) WaitForCETaskTobeDone(taskID string, timeout time.Duration) (st
 	}
 }
 
+type Issue struct {
+	Key       string `json:"key"`
+	Rule      string `json:"rule"`
+	Severity  string `json:"severity"`
+	Component string `json:"component"`
+	Line      int    `json:"line"`
+	Message   string `json:"message"`
+	Type      string `json:"type"`
+}
+
+type IssueSearchResult struct {
+	Total  int     `json:"total"`
+	Issues []Issue `json:"issues"`
+}
+
+// SearchOpenIssues returns the unresolved issues of a project sorted by severity, at most pageSize of them
+func (c *Client) SearchOpenIssues(projectKey, branch string, pageSize int) (*IssueSearchResult, error) {
+	url := "/api/issues/search"
+	params := map[string]string{
+		"componentKeys": projectKey,
+		"resolved":      "false",
+		"s":             "SEVERITY",
+		"asc":           "false",
+		"ps":            fmt.Sprint(pageSize),
+	}
+	if branch != "" {
+		params["branch"] = branch
+	}
+	res := &IssueSearchResult{}
+	if _, err := c.Client.Get(url, httpclient.SetQueryParams(params), httpclient.SetResult(res)); err != nil {
+		return nil, fmt.Errorf("search sonar issues of project: %s error: %v", projectKey, err)
+	}
+	return res, nil
+}
+
+// QualityGateResult is the outcome of an analysis, it is archived by the job for the pr decoration
+type QualityGateResult struct {
+	ProjectKey  string            `json:"project_key"`
+	Status      QualityGateStatus `json:"status"`
+	Conditions  []Condition       `json:"conditions"`
+	TotalIssues int               `json:"total_issues"`
+	Issues      []Issue           `json:"issues"`
+	Link        string            `json:"link"`
+}
+
 func GetSonarWorkDir(content string) string {
 	return getKeyValue(content, SonarWorkDirKey)
 }
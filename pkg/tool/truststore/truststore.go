@@ -0,0 +1,121 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package truststore keeps the CA bundles trusted by the HTTP clients calling the external integrations,
+// in addition to the system roots, so that the endpoints signed by a private CA can be reached.
+package truststore
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// integrations which can skip the verification of the server certificate
+const (
+	IntegrationGitLab      = "gitlab"
+	IntegrationHarbor      = "harbor"
+	IntegrationJira        = "jira"
+	IntegrationSonar       = "sonar"
+	IntegrationWebhook     = "webhook"
+	IntegrationFeatureFlag = "featureflag"
+)
+
+var Integrations = sets.NewString(IntegrationGitLab, IntegrationHarbor, IntegrationJira, IntegrationSonar, IntegrationWebhook, IntegrationFeatureFlag)
+
+var store = struct {
+	sync.RWMutex
+	// pool is nil if no CA bundle is configured, the system roots are used then
+	pool     *x509.CertPool
+	insecure sets.String
+}{insecure: sets.NewString()}
+
+// Set replaces the trusted CA bundles and the integrations skipping the certificate verification
+func Set(bundles []string, insecureIntegrations []string) error {
+	var pool *x509.CertPool
+	if len(bundles) > 0 {
+		systemPool, err := x509.SystemCertPool()
+		if err != nil || systemPool == nil {
+			systemPool = x509.NewCertPool()
+		}
+		for _, bundle := range bundles {
+			if !systemPool.AppendCertsFromPEM([]byte(bundle)) {
+				return fmt.Errorf("no valid certificate found in the bundle")
+			}
+		}
+		pool = systemPool
+	}
+
+	store.Lock()
+	defer store.Unlock()
+	store.pool = pool
+	store.insecure = sets.NewString(insecureIntegrations...)
+	return nil
+}
+
+// TLSConfig returns the tls config of the integration, it is nil if neither a CA bundle nor
+// the insecure override is configured so that the default of the client is kept
+func TLSConfig(integration string) *tls.Config {
+	store.RLock()
+	defer store.RUnlock()
+
+	if store.pool == nil && !store.insecure.Has(integration) {
+		return nil
+	}
+	return &tls.Config{
+		RootCAs:            store.pool,
+		InsecureSkipVerify: store.insecure.Has(integration),
+	}
+}
+
+// Transport returns a clone of the default transport using the tls config of the integration
+func Transport(integration string) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsConfig := TLSConfig(integration); tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	return transport
+}
+
+// ParseBundle returns the certificates of the PEM encoded bundle
+func ParseBundle(bundle string) ([]*x509.Certificate, error) {
+	certs := make([]*x509.Certificate, 0)
+	rest := []byte(bundle)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %s", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate found in the bundle")
+	}
+	return certs, nil
+}
@@ -48,11 +48,28 @@ type NFSProperties struct {
 }
 
 type Cache struct {
-	MediumType       MediumType       `json:"medium_type"       bson:"medium_type"`
-	ObjectProperties ObjectProperties `json:"object_properties" bson:"object_properties"`
-	NFSProperties    NFSProperties    `json:"nfs_properties"    bson:"nfs_properties"`
+	MediumType       MediumType           `json:"medium_type"                bson:"medium_type"`
+	ObjectProperties ObjectProperties     `json:"object_properties"          bson:"object_properties"`
+	NFSProperties    NFSProperties        `json:"nfs_properties"             bson:"nfs_properties"`
+	EvictionPolicy   *CacheEvictionPolicy `json:"eviction_policy,omitempty"  bson:"eviction_policy,omitempty"`
 }
 
+// CacheEvictionPolicy decides when a saved build cache is removed, zero value means never.
+type CacheEvictionPolicy struct {
+	MaxAgeDays int `json:"max_age_days" bson:"max_age_days" yaml:"max_age_days"`
+}
+
+// CacheRule is a declarative build cache, the archive of Paths is saved and restored by Key.
+// Key supports variables like $BRANCH and the {{checksum "path/to/file"}} function,
+// Paths are relative to the workspace.
+type CacheRule struct {
+	Key   string   `json:"key"   bson:"key"   yaml:"key"`
+	Paths []string `json:"paths" bson:"paths" yaml:"paths"`
+}
+
+// BuildCacheMountDir is the dir in the job pod where the cache pvc is mounted for declarative build caches.
+const BuildCacheMountDir = "/zadig/build-cache"
+
 type CacheDirType string
 
 const (
@@ -26,6 +26,12 @@ import (
 const (
 	JobOutputDir       = "/zadig/results/"
 	JobTerminationFile = "/zadig/termination"
+
+	// OIDCTokenEnv is the secret env carrying the workload identity token of the job, the job executor
+	// writes it into the file named by OIDCTokenFileEnv so that the cloud sdks read it from the file
+	OIDCTokenEnv     = "ZADIG_OIDC_TOKEN"
+	OIDCTokenFileEnv = "ZADIG_OIDC_TOKEN_FILE"
+	OIDCTokenFile    = "/zadig/oidc/token"
 )
 
 type JobOutput struct {
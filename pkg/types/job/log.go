@@ -0,0 +1,103 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package job
+
+import (
+	"strings"
+)
+
+// the markers are printed by the job executor around each step, a section is the log of one step
+const (
+	LogSectionStartMarker = "::zadig-section-start::"
+	LogSectionEndMarker   = "::zadig-section-end::"
+	logSectionFailedFlag  = "::failed"
+)
+
+type LogSection struct {
+	Name string `json:"name"`
+	// StartLine and EndLine are the line numbers of the markers, counting from 1
+	StartLine int  `json:"start_line"`
+	EndLine   int  `json:"end_line"`
+	Failed    bool `json:"failed"`
+}
+
+func LogSectionStart(name string) string {
+	return LogSectionStartMarker + name
+}
+
+func LogSectionEnd(name string, failed bool) string {
+	if failed {
+		return LogSectionEndMarker + name + logSectionFailedFlag
+	}
+	return LogSectionEndMarker + name
+}
+
+// ParseLogSections returns the sections in the log lines, a section without end marker ends at the last line
+func ParseLogSections(lines []string) []*LogSection {
+	resp := make([]*LogSection, 0)
+	var current *LogSection
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, LogSectionStartMarker):
+			if current != nil {
+				current.EndLine = i
+			}
+			current = &LogSection{Name: strings.TrimPrefix(line, LogSectionStartMarker), StartLine: i + 1}
+			resp = append(resp, current)
+		case strings.HasPrefix(line, LogSectionEndMarker) && current != nil:
+			current.EndLine = i + 1
+			current.Failed = strings.HasSuffix(line, logSectionFailedFlag)
+			current = nil
+		}
+	}
+	if current != nil {
+		current.EndLine = len(lines)
+	}
+	return resp
+}
+
+// FindLogSection returns the section which the line belongs to, the line number counts from 1
+func FindLogSection(sections []*LogSection, line int) string {
+	for _, section := range sections {
+		if line >= section.StartLine && line <= section.EndLine {
+			return section.Name
+		}
+	}
+	return ""
+}
+
+// LogSectionTracker finds the sections of the log lines read one by one, it agrees with ParseLogSections
+// and FindLogSection without keeping the whole log
+type LogSectionTracker struct {
+	current string
+}
+
+// Next returns the section which the line belongs to, the lines must be passed in order
+func (t *LogSectionTracker) Next(line string) string {
+	line = strings.TrimSpace(line)
+	switch {
+	case strings.HasPrefix(line, LogSectionStartMarker):
+		t.current = strings.TrimPrefix(line, LogSectionStartMarker)
+		return t.current
+	case strings.HasPrefix(line, LogSectionEndMarker) && t.current != "":
+		section := t.current
+		t.current = ""
+		return section
+	}
+	return t.current
+}
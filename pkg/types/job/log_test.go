@@ -0,0 +1,54 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package job
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Testing log sections", func() {
+
+	DescribeTable("LogSectionTracker agrees with FindLogSection",
+		func(lines []string) {
+			sections := ParseLogSections(lines)
+			tracker := &LogSectionTracker{}
+			for i, line := range lines {
+				Expect(tracker.Next(line)).To(Equal(FindLogSection(sections, i+1)), "line %d: %s", i+1, line)
+			}
+		},
+		Entry("no section", []string{"a", "b"}),
+		Entry("closed sections", []string{
+			"before", LogSectionStart("git"), "clone", LogSectionEnd("git", false),
+			"between", LogSectionStart("script"), "make", LogSectionEnd("script", true), "after",
+		}),
+		Entry("section without end marker", []string{LogSectionStart("git"), "clone", LogSectionStart("script"), "make"}),
+		Entry("indented markers", []string{"  " + LogSectionStart("git"), "clone", "  " + LogSectionEnd("git", false), "after"}),
+		Entry("end marker out of a section", []string{LogSectionEnd("git", false), "a"}),
+	)
+
+	It("should find the failed section", func() {
+		lines := strings.Split(strings.Join([]string{LogSectionStart("script"), "make", LogSectionEnd("script", true)}, "\n"), "\n")
+		sections := ParseLogSections(lines)
+		Expect(sections).To(HaveLen(1))
+		Expect(sections[0].Failed).To(BeTrue())
+		Expect(sections[0].StartLine).To(Equal(1))
+		Expect(sections[0].EndLine).To(Equal(3))
+	})
+})
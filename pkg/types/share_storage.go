@@ -19,15 +19,31 @@ package types
 import (
 	"fmt"
 	"path"
+	"regexp"
+	"strconv"
+	"strings"
 )
 
 const (
 	pathPrefix = "zadig-share-storage"
 )
 
+// shareStorageTaskDirRegex matches the task dirs, the dirs are passed to the shell of the gc job so nothing else is accepted
+var shareStorageTaskDirRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*-[0-9]+$`)
+
 type ShareStorage struct {
 	MediumType    MediumType    `json:"medium_type"       bson:"medium_type"           yaml:"medium_type"`
 	NFSProperties NFSProperties `json:"nfs_properties"    bson:"nfs_properties"        yaml:"nfs_properties"`
+	// RetentionHours keeps the dirs of the finished tasks for the given hours, they are removed by the garbage collection,
+	// 0 means the dirs are removed as soon as the task finishes
+	RetentionHours int `json:"retention_hours,omitempty" bson:"retention_hours,omitempty" yaml:"retention_hours,omitempty"`
+	// QuotaInMiB is the max usage of the task dirs, the dirs of the oldest finished tasks are removed first when it is exceeded
+	QuotaInMiB int64 `json:"quota_in_mib,omitempty"    bson:"quota_in_mib,omitempty"    yaml:"quota_in_mib,omitempty"`
+}
+
+// GetShareStorageRoot returns the dir holding the dirs of all the tasks in the share storage
+func GetShareStorageRoot() string {
+	return pathPrefix
 }
 
 func GetShareStorageSubPath(workflowName, storageName string, taskID int64) string {
@@ -37,3 +53,21 @@ func GetShareStorageSubPath(workflowName, storageName string, taskID int64) stri
 func GetShareStorageSubPathPrefix(workflowName string, taskID int64) string {
 	return path.Join(pathPrefix, fmt.Sprintf("%s-%d", workflowName, taskID))
 }
+
+// ParseShareStorageTaskDir returns the workflow name and the task id of a task dir created by GetShareStorageSubPathPrefix,
+// it returns false for the dirs not matching the pattern
+func ParseShareStorageTaskDir(dir string) (string, int64, bool) {
+	dir = path.Base(dir)
+	if !shareStorageTaskDirRegex.MatchString(dir) {
+		return "", 0, false
+	}
+	index := strings.LastIndex(dir, "-")
+	if index <= 0 {
+		return "", 0, false
+	}
+	taskID, err := strconv.ParseInt(dir[index+1:], 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return dir[:index], taskID, true
+}
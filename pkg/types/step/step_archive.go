@@ -26,4 +26,8 @@ type Upload struct {
 	FilePath        string `bson:"file_path"                              json:"file_path"                                 yaml:"file_path"`
 	AbsFilePath     string `bson:"abs_file_path"                          json:"aabs_file_pathk"                           yaml:"abs_file_path"`
 	DestinationPath string `bson:"dest_path"                              json:"dest_path"                                 yaml:"dest_path"`
+	// PreservePath keeps the path relative to the workspace under the destination path
+	PreservePath bool `bson:"preserve_path,omitempty"    json:"preserve_path,omitempty"    yaml:"preserve_path,omitempty"`
+	// IgnoreNotExist skips the file path which does not exist instead of failing the step
+	IgnoreNotExist bool `bson:"ignore_not_exist,omitempty" json:"ignore_not_exist,omitempty" yaml:"ignore_not_exist,omitempty"`
 }
@@ -0,0 +1,30 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+import "github.com/koderover/zadig/pkg/types"
+
+// StepCacheSpec is shared by the restore_cache and save_cache steps.
+// CacheDir is the dir of the cache pvc in the job pod for nfs medium, S3DestDir is the object prefix for object medium.
+type StepCacheSpec struct {
+	Rules      []*types.CacheRule `bson:"rules"                      json:"rules"                         yaml:"rules"`
+	MediumType types.MediumType   `bson:"medium_type"                json:"medium_type"                   yaml:"medium_type"`
+	CacheDir   string             `bson:"cache_dir"                  json:"cache_dir"                     yaml:"cache_dir"`
+	S3DestDir  string             `bson:"s3_dest_dir"                json:"s3_dest_dir"                   yaml:"s3_dest_dir"`
+	S3Storage  *S3                `bson:"s3_storage"                 json:"s3_storage"                    yaml:"s3_storage"`
+	MaxAgeDays int                `bson:"max_age_days"               json:"max_age_days"                  yaml:"max_age_days"`
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+const (
+	CoverageFormatLcov      = "lcov"
+	CoverageFormatCobertura = "cobertura"
+	CoverageFormatGoCover   = "gocover"
+
+	// CoverageSummaryFile is the file name of the coverage summary uploaded to the object storage
+	CoverageSummaryFile = "coverage.json"
+)
+
+// StepCoverageSpec parses the coverage report and fails the step when a quality gate is not met.
+// MinCoverage and MaxRegression are percentages, zero disables the gate.
+// BaseCoverage is the latest coverage of the base branch, it is only valid when HasBase is true.
+type StepCoverageSpec struct {
+	ReportPath    string  `bson:"report_path"               json:"report_path"                  yaml:"report_path"`
+	Format        string  `bson:"format"                    json:"format"                       yaml:"format"`
+	MinCoverage   float64 `bson:"min_coverage"              json:"min_coverage"                 yaml:"min_coverage"`
+	MaxRegression float64 `bson:"max_regression"            json:"max_regression"               yaml:"max_regression"`
+	BaseBranch    string  `bson:"base_branch"               json:"base_branch"                  yaml:"base_branch"`
+	BaseCoverage  float64 `bson:"base_coverage"             json:"base_coverage"                yaml:"base_coverage"`
+	HasBase       bool    `bson:"has_base"                  json:"has_base"                     yaml:"has_base"`
+	Name          string  `bson:"name"                      json:"name"                         yaml:"name"`
+	ServiceName   string  `bson:"service_name"              json:"service_name"                 yaml:"service_name"`
+	ServiceModule string  `bson:"service_module"            json:"service_module"               yaml:"service_module"`
+	Branch        string  `bson:"branch"                    json:"branch"                       yaml:"branch"`
+	IsPR          bool    `bson:"is_pr"                     json:"is_pr"                        yaml:"is_pr"`
+	S3DestDir     string  `bson:"s3_dest_dir"               json:"s3_dest_dir"                  yaml:"s3_dest_dir"`
+	S3Storage     *S3     `bson:"s3_storage"                json:"s3_storage"                   yaml:"s3_storage"`
+}
+
+// CoverageSummary is the line coverage computed from a coverage report
+type CoverageSummary struct {
+	LinesCovered int     `json:"lines_covered"`
+	LinesTotal   int     `json:"lines_total"`
+	Coverage     float64 `json:"coverage"`
+}
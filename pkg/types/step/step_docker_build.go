@@ -34,8 +34,16 @@ type StepDockerBuildSpec struct {
 	Proxy                 *Proxy          `bson:"proxy"                               json:"proxy"                                  yaml:"proxy"`
 	IgnoreCache           bool            `bson:"ignore_cache"                        json:"ignore_cache"                           yaml:"ignore_cache"`
 	DockerRegistry        *DockerRegistry `bson:"docker_registry"                     json:"docker_registry"                        yaml:"docker_registry"`
+	Builder               string          `bson:"builder,omitempty"                   json:"builder,omitempty"                      yaml:"builder,omitempty"`
+	CacheRef              string          `bson:"cache_ref,omitempty"                 json:"cache_ref,omitempty"                    yaml:"cache_ref,omitempty"` // registry ref of the buildkit layer cache
+	CacheMode             string          `bson:"cache_mode,omitempty"                json:"cache_mode,omitempty"                   yaml:"cache_mode,omitempty"`
 }
 
+const (
+	DockerBuilderDocker   = "docker"
+	DockerBuilderBuildkit = "buildkit"
+)
+
 type DockerRegistry struct {
 	DockerRegistryID string `bson:"docker_registry_id"                json:"docker_registry_id"                   yaml:"docker_registry_id"`
 	Host             string `bson:"host"                              json:"host"                                 yaml:"host"`
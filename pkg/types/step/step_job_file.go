@@ -0,0 +1,33 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+// JobFile is a file or directory passed between the jobs of a workflow task.
+// For the upload step Path is the source in the workspace, for the download step it is the dir to extract into.
+type JobFile struct {
+	Name    string `bson:"name"                      json:"name"                              yaml:"name"`
+	Path    string `bson:"path"                      json:"path"                              yaml:"path"`
+	JobName string `bson:"job_name"                  json:"job_name"                          yaml:"job_name"`
+}
+
+// StepJobFileSpec is shared by the upload_job_files and download_job_files steps.
+// The files are stored as S3DestDir/<job name>/<name>.tar.gz along with a sha256 checksum file.
+type StepJobFileSpec struct {
+	Files     []*JobFile `bson:"files"                     json:"files"                             yaml:"files"`
+	S3DestDir string     `bson:"s3_dest_dir"               json:"s3_dest_dir"                       yaml:"s3_dest_dir"`
+	S3Storage *S3        `bson:"s3_storage"                json:"s3_storage"                        yaml:"s3_storage"`
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+const (
+	PerformanceToolK6     = "k6"
+	PerformanceToolJMeter = "jmeter"
+
+	// PerformanceSummaryFile is the file name of the performance summary uploaded to the object storage
+	PerformanceSummaryFile = "performance.json"
+)
+
+// StepPerformanceSpec parses the result of a load test and fails the step on regression.
+// ResultPath is the k6 summary export or the JMeter jtl file. MaxErrorRate, MaxLatencyRegression and
+// MaxThroughputRegression are percentages, zero disables the gate. The regression gates are only
+// checked against Baseline, which is nil when no baseline task is chosen.
+type StepPerformanceSpec struct {
+	Tool                    string              `bson:"tool"                       json:"tool"                       yaml:"tool"`
+	ResultPath              string              `bson:"result_path"                json:"result_path"                yaml:"result_path"`
+	MaxErrorRate            float64             `bson:"max_error_rate"             json:"max_error_rate"             yaml:"max_error_rate"`
+	MaxLatencyRegression    float64             `bson:"max_latency_regression"     json:"max_latency_regression"     yaml:"max_latency_regression"`
+	MaxThroughputRegression float64             `bson:"max_throughput_regression"  json:"max_throughput_regression"  yaml:"max_throughput_regression"`
+	BaselineTaskID          int64               `bson:"baseline_task_id"           json:"baseline_task_id"           yaml:"baseline_task_id"`
+	Baseline                *PerformanceMetrics `bson:"baseline"                   json:"baseline"                   yaml:"baseline"`
+	S3DestDir               string              `bson:"s3_dest_dir"                json:"s3_dest_dir"                yaml:"s3_dest_dir"`
+	S3Storage               *S3                 `bson:"s3_storage"                 json:"s3_storage"                 yaml:"s3_storage"`
+}
+
+// PerformanceMetrics is the latency and throughput of a load test run, latencies are in milliseconds,
+// Throughput is in requests per second and ErrorRate is a percentage.
+type PerformanceMetrics struct {
+	Requests   int64   `bson:"requests"    json:"requests"    yaml:"requests"`
+	ErrorRate  float64 `bson:"error_rate"  json:"error_rate"  yaml:"error_rate"`
+	Throughput float64 `bson:"throughput"  json:"throughput"  yaml:"throughput"`
+	AvgLatency float64 `bson:"avg_latency" json:"avg_latency" yaml:"avg_latency"`
+	P90Latency float64 `bson:"p90_latency" json:"p90_latency" yaml:"p90_latency"`
+	P95Latency float64 `bson:"p95_latency" json:"p95_latency" yaml:"p95_latency"`
+	P99Latency float64 `bson:"p99_latency" json:"p99_latency" yaml:"p99_latency"`
+	MaxLatency float64 `bson:"max_latency" json:"max_latency" yaml:"max_latency"`
+}
+
+// PerformanceSummary is uploaded by the job, Regressions are the gates which are not met
+type PerformanceSummary struct {
+	Tool        string              `json:"tool"`
+	Metrics     *PerformanceMetrics `json:"metrics"`
+	Regressions []string            `json:"regressions"`
+}
@@ -0,0 +1,29 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package step
+
+type StepServiceReadySpec struct {
+	Services []*ServiceReadyCheck `bson:"services"                  json:"services"                     yaml:"services"`
+}
+
+// ServiceReadyCheck checks the tcp port of a service container, or the http path on the port if Path is set.
+type ServiceReadyCheck struct {
+	Name    string `bson:"name"                      json:"name"                         yaml:"name"`
+	Port    int    `bson:"port"                      json:"port"                         yaml:"port"`
+	Path    string `bson:"path"                      json:"path"                         yaml:"path"`
+	Timeout int64  `bson:"timeout"                   json:"timeout"                      yaml:"timeout"`
+}
@@ -16,6 +16,7 @@ limitations under the License.
 
 package step
 
+// StepShellSpec is shared by the shell step and the powershell step running on windows
 type StepShellSpec struct {
 	Scripts     []string `bson:"scripts"                              json:"scripts"                                 yaml:"scripts,omitempty"`
 	Script      string   `bson:"script"                               json:"script"                                  yaml:"script"`
@@ -16,9 +16,30 @@ limitations under the License.
 
 package step
 
+// SonarResultFile is the file name of the quality gate result uploaded to the object storage
+const SonarResultFile = "sonar-result.json"
+
 type StepSonarCheckSpec struct {
 	Parameter   string `bson:"parameter"       json:"parameter"         yaml:"parameter"`
 	SonarToken  string `bson:"sonar_token"     json:"sonar_token"       yaml:"sonar_token"`
 	SonarServer string `bson:"sonar_server"    json:"sonar_server"      yaml:"sonar_server"`
 	CheckDir    string `bson:"check_dir"       json:"check_dir"         yaml:"check_dir"`
+	// WaitTimeout is the max minutes to wait for the analysis, 10 by default
+	WaitTimeout int64 `bson:"wait_timeout,omitempty"         json:"wait_timeout,omitempty"         yaml:"wait_timeout,omitempty"`
+	// IgnoreGateStatus only reports the quality gate without failing the step
+	IgnoreGateStatus bool                   `bson:"ignore_gate_status,omitempty"   json:"ignore_gate_status,omitempty"   yaml:"ignore_gate_status,omitempty"`
+	Branch           string                 `bson:"branch,omitempty"               json:"branch,omitempty"               yaml:"branch,omitempty"`
+	S3DestDir        string                 `bson:"s3_dest_dir,omitempty"          json:"s3_dest_dir,omitempty"          yaml:"s3_dest_dir,omitempty"`
+	S3Storage        *S3                    `bson:"s3_storage,omitempty"           json:"s3_storage,omitempty"           yaml:"s3_storage,omitempty"`
+	PRDecoration     *SonarPRDecorationInfo `bson:"pr_decoration,omitempty"        json:"pr_decoration,omitempty"        yaml:"pr_decoration,omitempty"`
+}
+
+// SonarPRDecorationInfo is the pull request the quality gate result is posted back to
+type SonarPRDecorationInfo struct {
+	CodehostID    int    `bson:"codehost_id"     json:"codehost_id"     yaml:"codehost_id"`
+	Source        string `bson:"source"          json:"source"          yaml:"source"`
+	RepoOwner     string `bson:"repo_owner"      json:"repo_owner"      yaml:"repo_owner"`
+	RepoNamespace string `bson:"repo_namespace"  json:"repo_namespace"  yaml:"repo_namespace"`
+	RepoName      string `bson:"repo_name"       json:"repo_name"       yaml:"repo_name"`
+	PR            int    `bson:"pr"              json:"pr"              yaml:"pr"`
 }
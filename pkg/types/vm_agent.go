@@ -0,0 +1,63 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package types
+
+// VMAgentTokenHeader carries the token of the vm agent in the requests of the agent
+const VMAgentTokenHeader = "X-Zadig-Agent-Token"
+
+// VMAgentRegisterArgs is the host info sent by the vm agent when it starts
+type VMAgentRegisterArgs struct {
+	Hostname     string `json:"hostname"`
+	IP           string `json:"ip"`
+	Platform     string `json:"platform"`
+	Architecture string `json:"architecture"`
+	AgentVersion string `json:"agent_version"`
+}
+
+type VMAgentRegisterResp struct {
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Labels []string `json:"labels"`
+}
+
+// VMAgentHeartbeatArgs reports the jobs the agent is running, the jobs ended by zadig are returned to be stopped
+type VMAgentHeartbeatArgs struct {
+	RunningJobs []string `json:"running_jobs"`
+}
+
+type VMAgentHeartbeatResp struct {
+	StoppedJobs []string `json:"stopped_jobs"`
+}
+
+// VMAgentJob is a job assigned to the agent, JobCtx is the job context of the job executor in yaml
+type VMAgentJob struct {
+	ID     string `json:"id"`
+	JobCtx string `json:"job_ctx"`
+}
+
+// VMJobLogArgs is a chunk of the job log, Seq starts from 1 and increases by 1 for each chunk of the job
+type VMJobLogArgs struct {
+	Seq     int64  `json:"seq"`
+	Content string `json:"content"`
+}
+
+// VMJobResultArgs is the result of the job, Outputs are the job outputs in json
+type VMJobResultArgs struct {
+	Status  JobStatus `json:"status"`
+	Outputs string    `json:"outputs"`
+	Error   string    `json:"error"`
+}
@@ -19,6 +19,7 @@ package fs
 import (
 	"archive/tar"
 	"compress/gzip"
+	"fmt"
 	"io"
 	"io/fs"
 	"os"
@@ -55,6 +56,15 @@ func ShortenFileBase(baseDir, fullPath string) string {
 	return fullPath
 }
 
+// IsSubPath returns true if path is base itself or inside base after cleaning, e.g. "a/../../b" is not inside "a".
+func IsSubPath(base, path string) bool {
+	rel, err := filepath.Rel(base, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 // Tar archives the src file system and saves to disk with path dst.
 // src file system is a tree of files from disk, memory or any other places which implement fs.FS.
 func Tar(src fs.FS, dst string) error {
@@ -165,6 +175,10 @@ func Untar(src, dst string) (err error) {
 		}
 
 		dirOrFile := filepath.Join(dst, hdr.Name)
+		// reject the entries like "../x" which would be written out of dst
+		if !IsSubPath(dst, dirOrFile) {
+			return fmt.Errorf("illegal file path in the archive: %s", hdr.Name)
+		}
 
 		switch hdr.Typeflag {
 		case tar.TypeDir:
@@ -17,7 +17,11 @@ limitations under the License.
 package fs_test
 
 import (
+	"archive/tar"
+	"compress/gzip"
 	"fmt"
+	"os"
+	"path/filepath"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -100,3 +104,57 @@ func ExampleShortenFileBase() {
 	//b/c.go
 	//b/c.go
 }
+
+var _ = Describe("Testing sub path", func() {
+
+	DescribeTable("Testing IsSubPath",
+		func(base, path string, expected bool) {
+			Expect(fs.IsSubPath(base, path)).To(Equal(expected))
+		},
+		Entry("same path", "/tmp/a", "/tmp/a", true),
+		Entry("child path", "/tmp/a", "/tmp/a/b/c", true),
+		Entry("child path with dots", "/tmp/a", "/tmp/a/b/../c", true),
+		Entry("file named with dots", "/tmp/a", "/tmp/a/..b", true),
+		Entry("parent path", "/tmp/a", "/tmp", false),
+		Entry("sibling path", "/tmp/a", "/tmp/ab", false),
+		Entry("escaped path", "/tmp/a", "/tmp/a/../../etc/passwd", false),
+	)
+})
+
+var _ = Describe("Testing untar", func() {
+
+	writeTar := func(path string, names ...string) {
+		f, err := os.Create(path)
+		Expect(err).NotTo(HaveOccurred())
+		defer f.Close()
+		gw := gzip.NewWriter(f)
+		defer gw.Close()
+		tw := tar.NewWriter(gw)
+		defer tw.Close()
+		for _, name := range names {
+			Expect(tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: 2, Typeflag: tar.TypeReg})).To(Succeed())
+			_, err := tw.Write([]byte("ok"))
+			Expect(err).NotTo(HaveOccurred())
+		}
+	}
+
+	It("should extract the files in the archive", func() {
+		dir := GinkgoT().TempDir()
+		writeTar(filepath.Join(dir, "a.tar.gz"), "a.txt")
+		dst := filepath.Join(dir, "dst")
+		Expect(os.MkdirAll(dst, 0755)).To(Succeed())
+
+		Expect(fs.Untar(filepath.Join(dir, "a.tar.gz"), dst)).To(Succeed())
+		Expect(filepath.Join(dst, "a.txt")).To(BeAnExistingFile())
+	})
+
+	It("should reject the files out of the destination", func() {
+		dir := GinkgoT().TempDir()
+		writeTar(filepath.Join(dir, "a.tar.gz"), "../evil.txt")
+		dst := filepath.Join(dir, "dst")
+		Expect(os.MkdirAll(dst, 0755)).To(Succeed())
+
+		Expect(fs.Untar(filepath.Join(dir, "a.tar.gz"), dst)).NotTo(Succeed())
+		Expect(filepath.Join(dir, "evil.txt")).NotTo(BeAnExistingFile())
+	})
+})
@@ -0,0 +1,187 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package jsonschema generates JSON Schemas from the go types decoded with yaml and validates the decoded
+// documents against them. Only the keywords used by the generated schemas are supported.
+package jsonschema
+
+import (
+	"encoding"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	ID                   string             `json:"$id,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Const                interface{}        `json:"const,omitempty"`
+	Default              interface{}        `json:"default,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+	If                   *Schema            `json:"if,omitempty"`
+	Then                 *Schema            `json:"then,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+}
+
+// Generator generates the schemas of the go types, the named structs are put in the definitions and referenced
+// so that the recursive types are supported
+type Generator struct {
+	defs  map[string]*Schema
+	names map[reflect.Type]string
+	// required are the required properties of the structs
+	required map[reflect.Type][]string
+}
+
+func NewGenerator() *Generator {
+	return &Generator{
+		defs:     make(map[string]*Schema),
+		names:    make(map[reflect.Type]string),
+		required: make(map[reflect.Type][]string),
+	}
+}
+
+// Require marks the properties of the struct of v as required
+func (g *Generator) Require(v interface{}, properties ...string) {
+	g.required[indirect(reflect.TypeOf(v))] = properties
+}
+
+// Generate returns the schema of the type of v
+func (g *Generator) Generate(v interface{}) *Schema {
+	return g.generate(reflect.TypeOf(v))
+}
+
+// Defs returns the definitions of the structs generated so far
+func (g *Generator) Defs() map[string]*Schema {
+	return g.defs
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	yamlUnmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+func (g *Generator) generate(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	t = indirect(t)
+	// the types decoding themselves accept any document
+	if reflect.PtrTo(t).Implements(yamlUnmarshalerType) {
+		return &Schema{}
+	}
+	if t == timeType || reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: g.generate(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.generate(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.generateStruct(t)
+		}
+		name, ok := g.names[t]
+		if !ok {
+			name = g.defName(t)
+			g.names[t] = name
+			g.defs[name] = &Schema{}
+			*g.defs[name] = *g.generateStruct(t)
+		}
+		return &Schema{Ref: "#/$defs/" + name}
+	}
+	return &Schema{}
+}
+
+func (g *Generator) generateStruct(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.addFields(schema, t)
+	schema.Required = g.required[t]
+	return schema
+}
+
+// addFields adds the fields in the same way as yaml.v3, the key is the name in the yaml tag or the lowercased
+// field name, the inlined structs and maps are merged
+func (g *Generator) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		tag := field.Tag.Get("yaml")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if strings.Contains(opts, "inline") {
+			ft := indirect(field.Type)
+			switch ft.Kind() {
+			case reflect.Struct:
+				g.addFields(schema, ft)
+			case reflect.Map:
+				schema.AdditionalProperties = g.generate(ft.Elem())
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		schema.Properties[name] = g.generate(field.Type)
+	}
+}
+
+func (g *Generator) defName(t reflect.Type) string {
+	name := t.Name()
+	if _, ok := g.defs[name]; !ok {
+		return name
+	}
+	pkg := t.PkgPath()
+	return pkg[strings.LastIndex(pkg, "/")+1:] + "." + name
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
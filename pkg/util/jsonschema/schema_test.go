@@ -0,0 +1,68 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jsonschema
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+)
+
+func TestJSONSchema(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "JSON Schema Suite")
+}
+
+type testStep struct {
+	Name  string            `yaml:"name"`
+	Count int               `yaml:"count"`
+	Envs  map[string]string `yaml:"envs"`
+	Next  *testStep         `yaml:"next"`
+}
+
+func validate(doc string) []*ValidationError {
+	g := NewGenerator()
+	g.Require(testStep{}, "name")
+	root := g.Generate(&testStep{})
+	schema := &Schema{Ref: root.Ref, Defs: g.Defs()}
+
+	var v interface{}
+	Expect(yaml.Unmarshal([]byte(doc), &v)).To(Succeed())
+	return schema.Validate(v)
+}
+
+var _ = Describe("Validate", func() {
+	It("accepts a valid document", func() {
+		Expect(validate("name: a\ncount: 1\nenvs: {k: v}\nnext: {name: b}")).To(BeEmpty())
+	})
+
+	It("returns the path of the invalid values", func() {
+		errs := validate("name: a\ncount: x\nnext: {name: 1, envs: {k: [1]}}")
+		Expect(errs).To(HaveLen(2))
+		Expect(errs[0].Path).To(Equal("/count"))
+		Expect(errs[1].Path).To(Equal("/next/envs/k"))
+	})
+
+	It("returns the missing required properties", func() {
+		errs := validate("next: {count: 1}")
+		Expect(errs).To(HaveLen(2))
+		Expect(errs[0].Path).To(Equal(""))
+		Expect(errs[1].Path).To(Equal("/next"))
+	})
+})
@@ -0,0 +1,209 @@
+/*
+Copyright 2023 The KodeRover Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jsonschema
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ValidationError is a violation of the schema, Path is the JSON pointer of the invalid value
+type ValidationError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e *ValidationError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "/"
+	}
+	return fmt.Sprintf("%s: %s", path, e.Message)
+}
+
+// Validate validates the document decoded by yaml.v3 or encoding/json against the schema, the references are
+// resolved in the definitions of the schema
+func (s *Schema) Validate(doc interface{}) []*ValidationError {
+	v := &validator{root: s}
+	v.validate(s, doc, "")
+	return v.errs
+}
+
+type validator struct {
+	root *Schema
+	errs []*ValidationError
+}
+
+func (v *validator) addError(path, format string, args ...interface{}) {
+	v.errs = append(v.errs, &ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) validate(schema *Schema, value interface{}, path string) {
+	if schema == nil {
+		return
+	}
+	if schema.Ref != "" {
+		ref, ok := v.root.Defs[strings.TrimPrefix(schema.Ref, "#/$defs/")]
+		if !ok {
+			v.addError(path, "unknown reference %s", schema.Ref)
+			return
+		}
+		v.validate(ref, value, path)
+	}
+	for _, sub := range schema.AllOf {
+		v.validate(sub, value, path)
+	}
+	if schema.If != nil && schema.Then != nil && v.matches(schema.If, value) {
+		v.validate(schema.Then, value, path)
+	}
+	if schema.Const != nil && !equal(schema.Const, value) {
+		v.addError(path, "must be %v", schema.Const)
+	}
+	if len(schema.Enum) > 0 && value != nil {
+		found := false
+		for _, e := range schema.Enum {
+			if equal(e, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.addError(path, "%v is not one of %v", value, schema.Enum)
+		}
+	}
+	// null is accepted for every type as the zero value, the same as the decoding of yaml
+	if schema.Type == "" || value == nil {
+		return
+	}
+
+	switch schema.Type {
+	case "object":
+		obj, ok := toObject(value)
+		if !ok {
+			v.addError(path, "expected object, got %s", typeName(value))
+			return
+		}
+		for _, name := range schema.Required {
+			if _, ok := obj[name]; !ok {
+				v.addError(path, "missing required property %s", name)
+			}
+		}
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if prop, ok := schema.Properties[key]; ok {
+				v.validate(prop, obj[key], path+"/"+escape(key))
+			} else if schema.AdditionalProperties != nil {
+				v.validate(schema.AdditionalProperties, obj[key], path+"/"+escape(key))
+			}
+		}
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			v.addError(path, "expected array, got %s", typeName(value))
+			return
+		}
+		for i, item := range arr {
+			v.validate(schema.Items, item, path+"/"+strconv.Itoa(i))
+		}
+	case "string":
+		// yaml decodes any scalar into a string, only the objects and arrays are rejected
+		switch value.(type) {
+		case map[string]interface{}, map[interface{}]interface{}, []interface{}:
+			v.addError(path, "expected string, got %s", typeName(value))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			v.addError(path, "expected boolean, got %s", typeName(value))
+		}
+	case "integer":
+		if f, ok := toNumber(value); !ok || f != math.Trunc(f) {
+			v.addError(path, "expected integer, got %s", typeName(value))
+		}
+	case "number":
+		if _, ok := toNumber(value); !ok {
+			v.addError(path, "expected number, got %s", typeName(value))
+		}
+	}
+}
+
+// matches evaluates the schema without collecting the errors
+func (v *validator) matches(schema *Schema, value interface{}) bool {
+	sub := &validator{root: v.root}
+	sub.validate(schema, value, "")
+	return len(sub.errs) == 0
+}
+
+func toObject(value interface{}) (map[string]interface{}, bool) {
+	switch obj := value.(type) {
+	case map[string]interface{}:
+		return obj, true
+	case map[interface{}]interface{}:
+		resp := make(map[string]interface{}, len(obj))
+		for k, v := range obj {
+			resp[fmt.Sprint(k)] = v
+		}
+		return resp, true
+	}
+	return nil, false
+}
+
+func toNumber(value interface{}) (float64, bool) {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+func equal(expected, value interface{}) bool {
+	return fmt.Sprint(expected) == fmt.Sprint(value)
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}, map[interface{}]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	}
+	if _, ok := toNumber(value); ok {
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// escape escapes the key in a JSON pointer
+func escape(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
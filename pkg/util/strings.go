@@ -59,6 +59,29 @@ func ExtractImageName(image string) string {
 	return imageNameStr
 }
 
+// ParseImageTag returns the tag of the image, latest is returned if the image has no tag,
+// the tag is empty if the image is pinned by a digest.
+func ParseImageTag(image string) (string, error) {
+	reference, err := ref.Parse(image)
+	if err != nil {
+		return "", fmt.Errorf("invalid image %s: %v", image, err)
+	}
+	if _, ok := reference.(ref.Digested); ok {
+		return "", nil
+	}
+	if tagged, ok := reference.(ref.Tagged); ok {
+		return tagged.Tag(), nil
+	}
+	return "latest", nil
+}
+
+// ExtractImageTag is ParseImageTag for display, the tag is empty if the image can't be parsed,
+// so the checks on the tag must use ParseImageTag instead.
+func ExtractImageTag(image string) string {
+	tag, _ := ParseImageTag(image)
+	return tag
+}
+
 func GetImageNameFromContainerInfo(imageName, containerName string) string {
 	if imageName == "" {
 		return containerName